@@ -51,6 +51,51 @@ func EvidenceTagsKey(tenantID, evidenceID string) string {
 	return fmt.Sprintf("ev:tags:%s:%s", tenantID, evidenceID)
 }
 
+// auth:tv:<userId>
+func TokenVersionKey(userID string) string {
+	return fmt.Sprintf("auth:tv:%s", userID)
+}
+
+// auth:sess:<sessionId>
+func SessionKey(sessionID string) string {
+	return fmt.Sprintf("auth:sess:%s", sessionID)
+}
+
+// auth:mfa:<challengeId>
+func MFAAttemptsKey(challengeID string) string {
+	return fmt.Sprintf("auth:mfa:%s", challengeID)
+}
+
+// auth:mfa-done:<challengeId>
+func MFAChallengeDoneKey(challengeID string) string {
+	return fmt.Sprintf("auth:mfa-done:%s", challengeID)
+}
+
+// auth:mfa-step:<userId>
+func MFATOTPStepKey(userID string) string {
+	return fmt.Sprintf("auth:mfa-step:%s", userID)
+}
+
+// auth:webauthn:<challengeId>
+func WebAuthnChallengeKey(challengeID string) string {
+	return fmt.Sprintf("auth:webauthn:%s", challengeID)
+}
+
+// auth:sso:<state>
+func SSOStateKey(state string) string {
+	return fmt.Sprintf("auth:sso:%s", state)
+}
+
+// auth:sso:ticket:<ticket>
+func SSOTicketKey(ticket string) string {
+	return fmt.Sprintf("auth:sso:ticket:%s", ticket)
+}
+
+// auth:saml:<tenantId>:<assertionId>
+func SAMLAssertionKey(tenantID, assertionID string) string {
+	return fmt.Sprintf("auth:saml:%s:%s", tenantID, assertionID)
+}
+
 // If you want to reuse your BuildQuerySig output directly, we still hash it to keep keys compact.
 func shaQSIG(s string) string {
 	h := sha256.Sum256([]byte(s))
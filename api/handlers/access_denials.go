@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"aegis-api/services_/auth/authz"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessDenialHandler exposes the tenant's log of refused case access.
+type AccessDenialHandler struct {
+	authz authz.Service
+}
+
+func NewAccessDenialHandler(authz authz.Service) *AccessDenialHandler {
+	return &AccessDenialHandler{authz: authz}
+}
+
+// GET /access-denials?user_id=&case_id=&reason=&since=RFC3339&limit=
+func (h *AccessDenialHandler) ListDenials(c *gin.Context) {
+	f := authz.DenialFilter{
+		UserID: c.Query("user_id"),
+		CaseID: c.Query("case_id"),
+		Reason: c.Query("reason"),
+	}
+	if s := c.Query("since"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			writeError(c, http.StatusBadRequest, "invalid_request", "since must be RFC3339")
+			return
+		}
+		f.Since = &t
+	}
+	f.Limit, _ = strconv.Atoi(c.Query("limit"))
+	denials, err := h.authz.ListDenials(c.GetString("tenantID"), f)
+	if errors.Is(err, authz.ErrInvalidInput) {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if err != nil {
+		writeError(c, http.StatusInternalServerError, "internal_error", "An internal error occurred")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"denials": denials})
+}
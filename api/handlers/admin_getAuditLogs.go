@@ -44,10 +44,11 @@ func (s *AdminService) GetAuditLogs(c *gin.Context) {
 
 	// Parse filters from query params
 	filter := auditlog.AuditLogFilter{
-		Status:  c.DefaultQuery("status", "ALL"),
-		Action:  c.Query("action"),  // e.g., "EXTRACT_IOCS" for IOC retrievals
-		Service: c.Query("service"), // e.g., "timelineai"
-		Limit:   atoiOrDefault(c.Query("limit"), 100),
+		Status:   c.DefaultQuery("status", "ALL"),
+		Action:   c.Query("action"),   // e.g., "EXTRACT_IOCS" for IOC retrievals
+		Service:  c.Query("service"),  // e.g., "timelineai"
+		Severity: c.Query("severity"), // e.g., "high"
+		Limit:    atoiOrDefault(c.Query("limit"), 100),
 	}
 
 	logs, err := s.auditLogService.GetAuditLogs(c.Request.Context(), filter)
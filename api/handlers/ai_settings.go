@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"aegis-api/services_/auditlog"
+	"aegis-api/services_/llm"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// aiContext returns the request context tagged with the caller's tenant so the
+// LLM router can apply that tenant's model selection and data policy.
+func aiContext(c *gin.Context) context.Context {
+	return llm.WithTenant(c.Request.Context(), c.GetString("tenantID"))
+}
+
+type AISettingsHandler struct {
+	router      *llm.Router
+	policies    llm.PolicyStore
+	usage       llm.UsageStore
+	auditLogger *auditlog.AuditLogger
+}
+
+func NewAISettingsHandler(router *llm.Router, policies llm.PolicyStore, usage llm.UsageStore, auditLogger *auditlog.AuditLogger) *AISettingsHandler {
+	return &AISettingsHandler{router: router, policies: policies, usage: usage, auditLogger: auditLogger}
+}
+
+// GET /ai/settings
+func (h *AISettingsHandler) GetSettings(c *gin.Context) {
+	tenantID := c.GetString("tenantID")
+	policy, err := h.policies.GetPolicy(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := gin.H{"providers": h.router.Providers(), "policy": policy}
+	if p, req, err := h.router.Resolve(c.Request.Context(), llm.CompletionRequest{TenantID: tenantID}); err == nil {
+		resp["effective_provider"] = p.Name()
+		resp["effective_model"] = req.Model
+	} else {
+		resp["effective_error"] = err.Error()
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// PUT /ai/settings
+func (h *AISettingsHandler) UpdateSettings(c *gin.Context) {
+	var req struct {
+		Provider     string `json:"provider"`
+		Model        string `json:"model"`
+		NoExternalAI bool   `json:"no_external_ai"`
+		MaxTokens    int    `json:"max_tokens"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	tenantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tenant"})
+		return
+	}
+	providers := h.router.Providers()
+	if req.Provider != "" {
+		external, ok := providers[req.Provider]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown provider"})
+			return
+		}
+		if external && req.NoExternalAI {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "selected provider is external but external AI is disabled"})
+			return
+		}
+	}
+	if req.MaxTokens < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_tokens must not be negative"})
+		return
+	}
+
+	policy := &llm.TenantPolicy{
+		TenantID:     tenantID,
+		Provider:     req.Provider,
+		Model:        req.Model,
+		NoExternalAI: req.NoExternalAI,
+		MaxTokens:    req.MaxTokens,
+		UpdatedBy:    c.GetString("userID"),
+	}
+	status := "SUCCESS"
+	err = h.policies.SavePolicy(c.Request.Context(), policy)
+	if err != nil {
+		status = "FAILED"
+	}
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action: "UPDATE_AI_SETTINGS",
+		Actor: auditlog.Actor{
+			ID:        c.GetString("userID"),
+			Role:      c.GetString("userRole"),
+			Email:     c.GetString("email"),
+			IPAddress: c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		},
+		Target:      auditlog.Target{Type: "tenant", ID: tenantID.String()},
+		Service:     "ai",
+		Status:      status,
+		Description: "AI provider policy updated",
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// GET /ai/usage?since=RFC3339
+func (h *AISettingsHandler) GetUsage(c *gin.Context) {
+	since := time.Now().AddDate(0, 0, -30)
+	if s := c.Query("since"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be RFC3339"})
+			return
+		}
+		since = t
+	}
+	summary, err := h.usage.SummarizeUsage(c.Request.Context(), c.GetString("tenantID"), since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"since": since, "usage": summary})
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"aegis-api/middleware"
+	"aegis-api/services_/auditlog"
+	"aegis-api/services_/auth/apikey"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHandler manages the tenant's service accounts and their API keys.
+type APIKeyHandler struct {
+	keys        apikey.Service
+	auditLogger *auditlog.AuditLogger
+}
+
+func NewAPIKeyHandler(keys apikey.Service, auditLogger *auditlog.AuditLogger) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys, auditLogger: auditLogger}
+}
+
+func apiKeyActor(c *gin.Context) apikey.Actor {
+	return apikey.Actor{UserID: c.GetString("userID"), TenantID: c.GetString("tenantID")}
+}
+
+func (h *APIKeyHandler) audit(c *gin.Context, action string, target auditlog.Target, description string) {
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action:      action,
+		Actor:       auditlog.MakeActor(c),
+		Target:      target,
+		Service:     "auth",
+		Status:      "SUCCESS",
+		Description: description,
+	})
+}
+
+func writeAPIKeyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, apikey.ErrNotFound):
+		writeError(c, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, apikey.ErrInvalidInput):
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+	case errors.Is(err, apikey.ErrDisabled), errors.Is(err, apikey.ErrInactive):
+		writeError(c, http.StatusConflict, "conflict", err.Error())
+	default:
+		writeError(c, http.StatusInternalServerError, "internal_error", "An internal error occurred")
+	}
+}
+
+// GET /service-accounts
+func (h *APIKeyHandler) ListAccounts(c *gin.Context) {
+	accounts, err := h.keys.ListAccounts(c.GetString("tenantID"))
+	if err != nil {
+		writeAPIKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"service_accounts": accounts})
+}
+
+// POST /service-accounts {name, description?, team_id?}
+func (h *APIKeyHandler) CreateAccount(c *gin.Context) {
+	var in apikey.AccountInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	a, err := h.keys.CreateAccount(apiKeyActor(c), in)
+	if err != nil {
+		writeAPIKeyError(c, err)
+		return
+	}
+	h.audit(c, "CREATE_SERVICE_ACCOUNT", auditlog.Target{Type: "service_account", ID: a.ID},
+		fmt.Sprintf("Created service account %q", a.Name))
+	c.JSON(http.StatusCreated, a)
+}
+
+// DELETE /service-accounts/:id
+// Disables the account and revokes all its keys. What it created stays
+// attributed to it.
+func (h *APIKeyHandler) DisableAccount(c *gin.Context) {
+	a, revoked, err := h.keys.DisableAccount(apiKeyActor(c), c.Param("id"))
+	if err != nil {
+		writeAPIKeyError(c, err)
+		return
+	}
+	h.audit(c, "DISABLE_SERVICE_ACCOUNT", auditlog.Target{Type: "service_account", ID: a.ID},
+		fmt.Sprintf("Disabled service account %q; %d key(s) revoked", a.Name, revoked))
+	c.JSON(http.StatusOK, a)
+}
+
+// GET /service-accounts/:id/keys
+func (h *APIKeyHandler) ListKeys(c *gin.Context) {
+	keys, err := h.keys.ListKeys(c.GetString("tenantID"), c.Param("id"))
+	if err != nil {
+		writeAPIKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// POST /service-accounts/:id/keys {name, scopes, case_ids?, expires_in_days?}
+// The key is only shown in this response.
+func (h *APIKeyHandler) CreateKey(c *gin.Context) {
+	var in apikey.KeyInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	issued, err := h.keys.CreateKey(apiKeyActor(c), c.Param("id"), in)
+	if err != nil {
+		writeAPIKeyError(c, err)
+		return
+	}
+	k := issued.Key
+	cases := "every case"
+	if len(in.CaseIDs) > 0 {
+		cases = string(k.CaseIDs)
+	}
+	h.audit(c, "CREATE_API_KEY", auditlog.Target{Type: "api_key", ID: k.ID, AdditionalInfo: map[string]string{"service_account_id": k.ServiceAccountID}},
+		fmt.Sprintf("Created API key %q (%s…) with scopes %s on %s, expiring %s",
+			k.Name, k.Prefix, string(k.Scopes), cases, k.ExpiresAt.UTC().Format(time.RFC3339)))
+	c.JSON(http.StatusCreated, issued)
+}
+
+// POST /service-accounts/:id/keys/:key_id/rotate {grace_hours?}
+// Issues a replacement; the old key keeps working for grace_hours
+// (default 24, 0 to revoke it at once).
+func (h *APIKeyHandler) RotateKey(c *gin.Context) {
+	var in struct {
+		GraceHours *int `json:"grace_hours"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&in); err != nil {
+			writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+	}
+	grace := 24 * time.Hour
+	if in.GraceHours != nil {
+		grace = time.Duration(*in.GraceHours) * time.Hour
+	}
+	issued, old, err := h.keys.RotateKey(apiKeyActor(c), c.Param("id"), c.Param("key_id"), grace)
+	if err != nil {
+		writeAPIKeyError(c, err)
+		return
+	}
+	h.audit(c, "ROTATE_API_KEY", auditlog.Target{Type: "api_key", ID: old.ID, AdditionalInfo: map[string]string{"rotated_to": issued.Key.ID}},
+		fmt.Sprintf("Rotated API key %q (%s… → %s…); the old key stops at %s",
+			old.Name, old.Prefix, issued.Key.Prefix, stopTime(old).UTC().Format(time.RFC3339)))
+	c.JSON(http.StatusCreated, gin.H{"key": issued.Secret, "api_key": issued.Key, "previous": old})
+}
+
+func stopTime(k *apikey.Key) time.Time {
+	if k.RevokedAt != nil {
+		return *k.RevokedAt
+	}
+	return k.ExpiresAt
+}
+
+// DELETE /service-accounts/:id/keys/:key_id
+func (h *APIKeyHandler) RevokeKey(c *gin.Context) {
+	k, err := h.keys.RevokeKey(apiKeyActor(c), c.Param("id"), c.Param("key_id"))
+	if err != nil {
+		writeAPIKeyError(c, err)
+		return
+	}
+	h.audit(c, "REVOKE_API_KEY", auditlog.Target{Type: "api_key", ID: k.ID},
+		fmt.Sprintf("Revoked API key %q (%s…)", k.Name, k.Prefix))
+	c.JSON(http.StatusOK, k)
+}
+
+// GET /api-keys/scopes
+// The permissions keys can be granted, and the endpoints that accept keys.
+func (h *APIKeyHandler) Scopes(c *gin.Context) {
+	scopes, err := h.keys.Scopes()
+	if err != nil {
+		writeAPIKeyError(c, err)
+		return
+	}
+	endpoints := map[string][]string{}
+	for endpoint, permission := range middleware.APIKeyEndpoints() {
+		endpoints[permission] = append(endpoints[permission], endpoint)
+	}
+	c.JSON(http.StatusOK, gin.H{"scopes": scopes, "endpoints": endpoints})
+}
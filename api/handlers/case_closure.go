@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"aegis-api/services_/auditlog"
+	"aegis-api/services_/case/case_closure"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CaseClosureHandler exports a case's closure package: its reports,
+// evidence, custody, timeline, IOCs, chat and audit log in one signed
+// archive.
+type CaseClosureHandler struct {
+	closure     case_closure.Service
+	auditLogger *auditlog.AuditLogger
+}
+
+func NewCaseClosureHandler(closure case_closure.Service, auditLogger *auditlog.AuditLogger) *CaseClosureHandler {
+	return &CaseClosureHandler{closure: closure, auditLogger: auditLogger}
+}
+
+func closureActor(c *gin.Context) case_closure.Actor {
+	return case_closure.Actor{
+		UserID:   c.GetString("userID"),
+		TenantID: c.GetString("tenantID"),
+	}
+}
+
+func (h *CaseClosureHandler) audit(c *gin.Context, action string, target auditlog.Target, status, description string) {
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action:      action,
+		Actor:       auditlog.MakeActor(c),
+		Target:      target,
+		Service:     "case",
+		Status:      status,
+		Description: description,
+	})
+}
+
+func writeClosureError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, case_closure.ErrCaseNotFound):
+		writeError(c, http.StatusNotFound, "case_not_found", err.Error())
+	case errors.Is(err, case_closure.ErrSigningDisabled):
+		writeError(c, http.StatusServiceUnavailable, "signing_disabled", err.Error())
+	case errors.Is(err, case_closure.ErrInvalidSignature):
+		writeError(c, http.StatusBadRequest, "invalid_signature_file", err.Error())
+	case errors.Is(err, case_closure.ErrFileTooLarge):
+		writeError(c, http.StatusRequestEntityTooLarge, "file_too_large", err.Error())
+	default:
+		writeError(c, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+}
+
+// GET /cases/:case_id/closure-package?include_evidence=true
+// Streams the package as a zip. Once streaming has started a failure can
+// only truncate the archive; it is recorded on the package.
+func (h *CaseClosureHandler) Export(c *gin.Context) {
+	caseID := c.Param("case_id")
+	target := auditlog.Target{Type: "case", ID: caseID}
+	includeEvidence, _ := strconv.ParseBool(c.Query("include_evidence"))
+
+	pkg, err := h.closure.Begin(c.Request.Context(), closureActor(c), caseID, case_closure.Options{IncludeEvidence: includeEvidence})
+	if err != nil {
+		h.audit(c, "EXPORT_CLOSURE_PACKAGE", target, "FAILED", err.Error())
+		writeClosureError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", pkg.Name+".zip"))
+	c.Header("X-Closure-Package-ID", pkg.ID)
+	c.Status(http.StatusOK)
+	if err := h.closure.Write(c.Request.Context(), closureActor(c), pkg, c.Writer); err != nil {
+		logWithCtx("error", "closure package export failed", c, map[string]any{"caseID": caseID, "packageID": pkg.ID, "err": err.Error()})
+		h.audit(c, "EXPORT_CLOSURE_PACKAGE", target, "FAILED", fmt.Sprintf("Closure package %s failed: %v", pkg.ID, err))
+		return
+	}
+	h.audit(c, "EXPORT_CLOSURE_PACKAGE", target, "SUCCESS",
+		fmt.Sprintf("Closure package %s exported (%d files, manifest sha256 %s)", pkg.ID, pkg.Items, pkg.ManifestSHA256))
+}
+
+// GET /cases/:case_id/closure-packages
+func (h *CaseClosureHandler) ListPackages(c *gin.Context) {
+	out, err := h.closure.ListPackages(closureActor(c), c.Param("case_id"))
+	if err != nil {
+		writeClosureError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// POST /closure-packages/verify (multipart "manifest" and "signature")
+// Checks a package's manifest.json against its manifest.sig.json and the
+// recorded export.
+func (h *CaseClosureHandler) Verify(c *gin.Context) {
+	target := auditlog.Target{Type: "closure_package"}
+	manifest, ok := readClosureFile(c, "manifest")
+	if !ok {
+		return
+	}
+	signature, ok := readClosureFile(c, "signature")
+	if !ok {
+		return
+	}
+
+	res, err := h.closure.Verify(closureActor(c), manifest, signature)
+	if err != nil {
+		h.audit(c, "VERIFY_CLOSURE_PACKAGE", target, "FAILED", err.Error())
+		writeClosureError(c, err)
+		return
+	}
+	if res.Package != nil {
+		target.ID = res.Package.ID
+	}
+	h.audit(c, "VERIFY_CLOSURE_PACKAGE", target, "SUCCESS",
+		fmt.Sprintf("Manifest %s: signature valid %t, matches record %t", res.ManifestSHA256, res.SignatureValid, res.Recorded))
+	c.JSON(http.StatusOK, res)
+}
+
+// readClosureFile reads an uploaded package file. On failure it writes
+// the error response and returns false.
+func readClosureFile(c *gin.Context, field string) ([]byte, bool) {
+	fh, err := c.FormFile(field)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", fmt.Sprintf("the package's %s file must be uploaded in the %q field", field, field))
+		return nil, false
+	}
+	if fh.Size > case_closure.MaxManifestSize {
+		writeClosureError(c, case_closure.ErrFileTooLarge)
+		return nil, false
+	}
+	f, err := fh.Open()
+	if err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return nil, false
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, case_closure.MaxManifestSize))
+	if err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return nil, false
+	}
+	return data, true
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"aegis-api/services_/auditlog"
+	"aegis-api/services_/case_qa"
+	"aegis-api/services_/llm"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CaseQAHandler struct {
+	service     case_qa.Service
+	auditLogger *auditlog.AuditLogger
+}
+
+func NewCaseQAHandler(service case_qa.Service, auditLogger *auditlog.AuditLogger) *CaseQAHandler {
+	return &CaseQAHandler{service: service, auditLogger: auditLogger}
+}
+
+func caseQARequester(c *gin.Context) case_qa.Requester {
+	return case_qa.Requester{
+		TenantID: c.GetString("tenantID"),
+		UserID:   c.GetString("userID"),
+		Role:     c.GetString("userRole"),
+	}
+}
+
+// POST /cases/:case_id/qa/ask
+// Answers {question} from the case's indexed evidence, timeline, thread
+// messages and report sections. Every attempt is audit logged, including
+// denials.
+func (h *CaseQAHandler) Ask(c *gin.Context) {
+	caseID := c.Param("case_id")
+	var req struct {
+		Question string `json:"question" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "question is required"})
+		return
+	}
+
+	ans, err := h.service.Ask(aiContext(c), caseQARequester(c), caseID, req.Question)
+	if err != nil {
+		h.auditLogger.Log(c, auditlog.AuditLog{
+			Action:      "CASE_QA_ASK",
+			Actor:       auditlog.MakeActor(c),
+			Target:      auditlog.Target{Type: "case", ID: caseID},
+			Service:     "case_qa",
+			Status:      "FAILED",
+			Description: "Case Q&A failed: " + err.Error(),
+		})
+		writeCaseQAError(c, err)
+		return
+	}
+
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action:      "CASE_QA_ASK",
+		Actor:       auditlog.MakeActor(c),
+		Target:      auditlog.Target{Type: "case", ID: caseID},
+		Service:     "case_qa",
+		Status:      "SUCCESS",
+		Description: "Case Q&A exchange " + ans.ExchangeID + " answered with " + strconv.Itoa(len(ans.Citations)) + " citation(s)",
+	})
+	c.JSON(http.StatusOK, ans)
+}
+
+// POST /cases/:case_id/qa/index
+func (h *CaseQAHandler) IndexCase(c *gin.Context) {
+	caseID := c.Param("case_id")
+	report, err := h.service.IndexCase(aiContext(c), caseQARequester(c), caseID)
+	if err != nil {
+		h.auditLogger.Log(c, auditlog.AuditLog{
+			Action:      "CASE_QA_INDEX",
+			Actor:       auditlog.MakeActor(c),
+			Target:      auditlog.Target{Type: "case", ID: caseID},
+			Service:     "case_qa",
+			Status:      "FAILED",
+			Description: "Case Q&A indexing failed: " + err.Error(),
+		})
+		writeCaseQAError(c, err)
+		return
+	}
+
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action:      "CASE_QA_INDEX",
+		Actor:       auditlog.MakeActor(c),
+		Target:      auditlog.Target{Type: "case", ID: caseID},
+		Service:     "case_qa",
+		Status:      "SUCCESS",
+		Description: "Case Q&A index rebuilt with " + strconv.Itoa(report.Chunks) + " chunk(s)",
+	})
+	c.JSON(http.StatusOK, report)
+}
+
+// GET /cases/:case_id/qa/exchanges?limit=50
+func (h *CaseQAHandler) ListExchanges(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	exchanges, err := h.service.ListExchanges(c.Request.Context(), caseQARequester(c), c.Param("case_id"), limit)
+	if err != nil {
+		writeCaseQAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, exchanges)
+}
+
+func writeCaseQAError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, case_qa.ErrEmptyQuestion),
+		errors.Is(err, case_qa.ErrQuestionTooLong),
+		errors.Is(err, case_qa.ErrInvalidRequester):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, case_qa.ErrNotCaseMember),
+		errors.Is(err, llm.ErrExternalAIDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, case_qa.ErrCaseNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -2,6 +2,9 @@ package handlers
 
 import (
 	"aegis-api/services_/chat"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log"
@@ -9,9 +12,11 @@ import (
 	"net/http"
 	"net/textproto"
 	"strconv"
+	"strings"
 	"time"
 
 	"aegis-api/services_/auditlog"
+	"aegis-api/services_/auth/abac"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -19,11 +24,55 @@ import (
 
 type ChatHandler struct {
 	ChatService *chat.ChatService
+	policy      abac.Service // nil: attachments are never checked against labels
 	auditLogger *auditlog.AuditLogger
 }
 
-func NewChatHandler(chatService *chat.ChatService, logger *auditlog.AuditLogger) *ChatHandler {
-	return &ChatHandler{ChatService: chatService, auditLogger: logger}
+func NewChatHandler(chatService *chat.ChatService, policy abac.Service, logger *auditlog.AuditLogger) *ChatHandler {
+	return &ChatHandler{ChatService: chatService, policy: policy, auditLogger: logger}
+}
+
+// attachmentBlocked applies the share policy to an attachment: to the
+// evidence it names and to any evidence with the same SHA-256, so
+// classified files cannot be shared by re-uploading them. The server
+// cannot hash an end-to-end encrypted attachment, so those must declare
+// the SHA-256 of their plaintext. Refusals answer 403 and are audited.
+func (h *ChatHandler) attachmentBlocked(c *gin.Context, evidenceID, file, plaintextSHA256 string, encrypted bool) bool {
+	if h.policy == nil {
+		return false
+	}
+	if evidenceID != "" &&
+		classificationBlocked(c, h.policy, h.auditLogger, "chat", abac.ActionShare, abac.ResourceEvidence, evidenceID) {
+		return true
+	}
+	var checksum string
+	if encrypted {
+		if b, err := hex.DecodeString(plaintextSHA256); err != nil || len(b) != sha256.Size {
+			writeError(c, http.StatusBadRequest, "checksum_required",
+				"Encrypted attachments must give the SHA-256 of their plaintext as plaintext_sha256")
+			return true
+		}
+		checksum = strings.ToLower(plaintextSHA256)
+	} else {
+		data, err := base64.StdEncoding.DecodeString(file)
+		if err != nil {
+			return false // the chat service rejects it
+		}
+		sum := sha256.Sum256(data)
+		checksum = hex.EncodeToString(sum[:])
+	}
+	d, matched, err := h.policy.DecideContent(c.Request.Context(), classificationSubject(c), abac.ActionShare, checksum)
+	if err != nil {
+		writeError(c, http.StatusInternalServerError, "internal_error", "Failed to check classification")
+		return true
+	}
+	if d.Allowed {
+		return false
+	}
+	logClassificationBlock(c, h.auditLogger, "chat", abac.ActionShare, abac.ResourceEvidence, matched, d)
+	writeError(c, http.StatusForbidden, "classified",
+		fmt.Sprintf("The attachment matches classified evidence you may not share (%s)", d.Reason))
+	return true
 }
 
 // ───── Groups ───────────────────────────────────────────────
@@ -389,9 +438,14 @@ func (h *ChatHandler) SendMessage(c *gin.Context) {
 		FileName string `json:"fileName,omitempty"`
 		FileMime string `json:"file_mime,omitempty"`
 		FileSize int64  `json:"file_size,omitempty"`
+		// EvidenceID names the evidence the attachment was taken from.
+		EvidenceID string `json:"evidence_id,omitempty"`
 
 		IsEncrypted bool                   `json:"is_encrypted"`
 		Envelope    *chat.CryptoEnvelopeV1 `json:"envelope,omitempty"`
+		// PlaintextSHA256 is required for encrypted attachments, which the
+		// server cannot hash itself.
+		PlaintextSHA256 string `json:"plaintext_sha256,omitempty"`
 	}
 	var req reqBody
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -410,6 +464,9 @@ func (h *ChatHandler) SendMessage(c *gin.Context) {
 	var msg *chat.Message
 
 	if req.File != "" && req.FileName != "" {
+		if h.attachmentBlocked(c, req.EvidenceID, req.File, req.PlaintextSHA256, req.IsEncrypted) {
+			return
+		}
 		fileHeader := &multipart.FileHeader{
 			Filename: req.FileName,
 			Size:     req.FileSize,
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"aegis-api/services_/auditlog"
+	"aegis-api/services_/auth/abac"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClassificationHandler manages classification labels, user clearances
+// and need-to-know groups.
+type ClassificationHandler struct {
+	policy      abac.Service
+	auditLogger *auditlog.AuditLogger
+}
+
+func NewClassificationHandler(policy abac.Service, auditLogger *auditlog.AuditLogger) *ClassificationHandler {
+	return &ClassificationHandler{policy: policy, auditLogger: auditLogger}
+}
+
+func classificationActor(c *gin.Context) abac.Actor {
+	return abac.Actor{UserID: c.GetString("userID"), TenantID: c.GetString("tenantID")}
+}
+
+func classificationSubject(c *gin.Context) abac.Subject {
+	return abac.Subject{UserID: c.GetString("userID"), TenantID: c.GetString("tenantID")}
+}
+
+// classificationBlocked applies the label of the resource to the caller.
+// When the policy refuses, it answers 403, records a high-severity audit
+// entry and returns true. A nil policy treats everything as unlabelled.
+func classificationBlocked(c *gin.Context, policy abac.Service, logger AuditLogger, service, action, resourceType, id string) bool {
+	if policy == nil {
+		return false
+	}
+	d, err := policy.Decide(c.Request.Context(), classificationSubject(c), action, resourceType, id)
+	if err != nil {
+		writeError(c, http.StatusInternalServerError, "internal_error", "Failed to check classification")
+		return true
+	}
+	if d.Allowed {
+		return false
+	}
+	logClassificationBlock(c, logger, service, action, resourceType, id, d)
+	writeError(c, http.StatusForbidden, "classified",
+		fmt.Sprintf("The %s's classification does not allow you to %s it (%s)", resourceType, action, d.Reason))
+	return true
+}
+
+// logClassificationBlock records a refused attempt on a labelled resource.
+func logClassificationBlock(c *gin.Context, logger AuditLogger, service, action, resourceType, id string, d *abac.Decision) {
+	if logger == nil {
+		return
+	}
+	logger.Log(c, auditlog.AuditLog{
+		Action: "CLASSIFIED_ACCESS_BLOCKED",
+		Actor:  auditlog.MakeActor(c),
+		Target: auditlog.Target{
+			Type:           resourceType,
+			ID:             id,
+			AdditionalInfo: map[string]string{"level": d.Level, "marking": d.Marking},
+		},
+		Service:     service,
+		Status:      "BLOCKED",
+		Severity:    "high",
+		Description: fmt.Sprintf("Blocked %s of %s %s: %s", action, resourceType, id, d.Reason),
+		Metadata:    map[string]string{"attempted_action": action, "reason": d.Reason},
+	})
+}
+
+func (h *ClassificationHandler) audit(c *gin.Context, action string, target auditlog.Target, description string) {
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action:      action,
+		Actor:       auditlog.MakeActor(c),
+		Target:      target,
+		Service:     "admin",
+		Status:      "SUCCESS",
+		Description: description,
+	})
+}
+
+func writeClassificationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, abac.ErrNotFound):
+		writeError(c, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, abac.ErrInvalidInput):
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+	case errors.Is(err, abac.ErrConflict):
+		writeError(c, http.StatusConflict, "conflict", err.Error())
+	default:
+		writeError(c, http.StatusInternalServerError, "internal_error", "An internal error occurred")
+	}
+}
+
+// GET /classification/levels
+func (h *ClassificationHandler) Levels(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"levels": abac.Levels})
+}
+
+// GET /classification/labels/:resource_type/:resource_id
+func (h *ClassificationHandler) GetLabel(c *gin.Context) {
+	l, err := h.policy.GetLabel(c.GetString("tenantID"), c.Param("resource_type"), c.Param("resource_id"))
+	if err != nil {
+		writeClassificationError(c, err)
+		return
+	}
+	// An unlabelled resource answers {"label": null}.
+	c.JSON(http.StatusOK, gin.H{"label": l})
+}
+
+// PUT /classification/labels/:resource_type/:resource_id {level, groups?, withheld?, reason?}
+func (h *ClassificationHandler) SetLabel(c *gin.Context) {
+	var in abac.LabelInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	l, err := h.policy.SetLabel(classificationActor(c), c.Param("resource_type"), c.Param("resource_id"), in)
+	if err != nil {
+		writeClassificationError(c, err)
+		return
+	}
+	h.audit(c, "SET_CLASSIFICATION_LABEL", auditlog.Target{Type: l.ResourceType, ID: l.ResourceID},
+		fmt.Sprintf("Labelled %s %s as %s", l.ResourceType, l.ResourceID, l.Level))
+	c.JSON(http.StatusOK, l)
+}
+
+// DELETE /classification/labels/:resource_type/:resource_id
+func (h *ClassificationHandler) ClearLabel(c *gin.Context) {
+	l, err := h.policy.ClearLabel(classificationActor(c), c.Param("resource_type"), c.Param("resource_id"))
+	if err != nil {
+		writeClassificationError(c, err)
+		return
+	}
+	h.audit(c, "CLEAR_CLASSIFICATION_LABEL", auditlog.Target{Type: l.ResourceType, ID: l.ResourceID},
+		fmt.Sprintf("Removed the %s label from %s %s", l.Level, l.ResourceType, l.ResourceID))
+	c.Status(http.StatusNoContent)
+}
+
+// GET /clearances
+func (h *ClassificationHandler) ListClearances(c *gin.Context) {
+	clearances, err := h.policy.ListClearances(c.GetString("tenantID"))
+	if err != nil {
+		writeClassificationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"clearances": clearances})
+}
+
+// PUT /clearances/:user_id {level, expires_in_days?}
+func (h *ClassificationHandler) SetClearance(c *gin.Context) {
+	var in abac.ClearanceInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	cl, err := h.policy.SetClearance(classificationActor(c), c.Param("user_id"), in)
+	if err != nil {
+		writeClassificationError(c, err)
+		return
+	}
+	h.audit(c, "SET_CLEARANCE", auditlog.Target{Type: "user", ID: cl.UserID},
+		fmt.Sprintf("Granted %s clearance", cl.Level))
+	c.JSON(http.StatusOK, cl)
+}
+
+// DELETE /clearances/:user_id
+func (h *ClassificationHandler) RevokeClearance(c *gin.Context) {
+	cl, err := h.policy.RevokeClearance(classificationActor(c), c.Param("user_id"))
+	if err != nil {
+		writeClassificationError(c, err)
+		return
+	}
+	h.audit(c, "REVOKE_CLEARANCE", auditlog.Target{Type: "user", ID: cl.UserID},
+		fmt.Sprintf("Revoked %s clearance", cl.Level))
+	c.Status(http.StatusNoContent)
+}
+
+// GET /need-to-know-groups
+func (h *ClassificationHandler) ListGroups(c *gin.Context) {
+	groups, err := h.policy.ListGroups(c.GetString("tenantID"))
+	if err != nil {
+		writeClassificationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"groups": groups})
+}
+
+// POST /need-to-know-groups {name, description?}
+func (h *ClassificationHandler) CreateGroup(c *gin.Context) {
+	var in abac.GroupInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	g, err := h.policy.CreateGroup(classificationActor(c), in)
+	if err != nil {
+		writeClassificationError(c, err)
+		return
+	}
+	h.audit(c, "CREATE_NEED_TO_KNOW_GROUP", auditlog.Target{Type: "need_to_know_group", ID: g.ID},
+		fmt.Sprintf("Created need-to-know group %q", g.Name))
+	c.JSON(http.StatusCreated, g)
+}
+
+// DELETE /need-to-know-groups/:id
+func (h *ClassificationHandler) DeleteGroup(c *gin.Context) {
+	g, err := h.policy.DeleteGroup(classificationActor(c), c.Param("id"))
+	if err != nil {
+		writeClassificationError(c, err)
+		return
+	}
+	h.audit(c, "DELETE_NEED_TO_KNOW_GROUP", auditlog.Target{Type: "need_to_know_group", ID: g.ID},
+		fmt.Sprintf("Deleted need-to-know group %q", g.Name))
+	c.Status(http.StatusNoContent)
+}
+
+// GET /need-to-know-groups/:id/members
+func (h *ClassificationHandler) ListMembers(c *gin.Context) {
+	members, err := h.policy.ListMembers(c.GetString("tenantID"), c.Param("id"))
+	if err != nil {
+		writeClassificationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"members": members})
+}
+
+// POST /need-to-know-groups/:id/members {user_id}
+func (h *ClassificationHandler) AddMember(c *gin.Context) {
+	var in struct {
+		UserID string `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	m, err := h.policy.AddMember(classificationActor(c), c.Param("id"), strings.TrimSpace(in.UserID))
+	if err != nil {
+		writeClassificationError(c, err)
+		return
+	}
+	h.audit(c, "ADD_NEED_TO_KNOW_MEMBER", auditlog.Target{Type: "need_to_know_group", ID: m.GroupID},
+		fmt.Sprintf("Added user %s", m.UserID))
+	c.JSON(http.StatusCreated, m)
+}
+
+// DELETE /need-to-know-groups/:id/members/:user_id
+func (h *ClassificationHandler) RemoveMember(c *gin.Context) {
+	groupID, userID := c.Param("id"), c.Param("user_id")
+	if err := h.policy.RemoveMember(classificationActor(c), groupID, userID); err != nil {
+		writeClassificationError(c, err)
+		return
+	}
+	h.audit(c, "REMOVE_NEED_TO_KNOW_MEMBER", auditlog.Target{Type: "need_to_know_group", ID: groupID},
+		fmt.Sprintf("Removed user %s", userID))
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"aegis-api/services_/auditlog"
+	"aegis-api/services_/detection_rules"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxRuleUploadBytes bounds a single uploaded rule file.
+const maxRuleUploadBytes = 1 << 20
+
+type DetectionRuleHandler struct {
+	service     detection_rules.Service
+	auditLogger *auditlog.AuditLogger
+}
+
+func NewDetectionRuleHandler(service detection_rules.Service, auditLogger *auditlog.AuditLogger) *DetectionRuleHandler {
+	return &DetectionRuleHandler{service: service, auditLogger: auditLogger}
+}
+
+// POST /detection-rules
+// Accepts JSON {name, kind, content, description} or a multipart form with a
+// "file" field plus name/kind/description fields.
+func (h *DetectionRuleHandler) UploadRule(c *gin.Context) {
+	var req struct {
+		Name        string `json:"name" form:"name"`
+		Kind        string `json:"kind" form:"kind" binding:"required"`
+		Content     string `json:"content" form:"content"`
+		Description string `json:"description" form:"description"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if fh, err := c.FormFile("file"); err == nil {
+		f, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "could not read uploaded file"})
+			return
+		}
+		defer f.Close()
+		data, err := io.ReadAll(io.LimitReader(f, maxRuleUploadBytes+1))
+		if err != nil || len(data) > maxRuleUploadBytes {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rule file must be at most 1 MiB"})
+			return
+		}
+		req.Content = string(data)
+	}
+	if req.Content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rule content is required"})
+		return
+	}
+
+	rule, err := h.service.UploadRule(c.GetString("tenantID"), c.GetString("userID"), req.Name,
+		detection_rules.RuleKind(req.Kind), req.Content, req.Description)
+	if err != nil {
+		h.auditLogger.Log(c, auditlog.AuditLog{
+			Action:      "UPLOAD_DETECTION_RULE",
+			Actor:       auditlog.MakeActor(c),
+			Target:      auditlog.Target{Type: "detection_rule", ID: req.Name},
+			Service:     "detection",
+			Status:      "FAILED",
+			Description: "Detection rule upload failed: " + err.Error(),
+		})
+		writeDetectionError(c, err)
+		return
+	}
+
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action:      "UPLOAD_DETECTION_RULE",
+		Actor:       auditlog.MakeActor(c),
+		Target:      auditlog.Target{Type: "detection_rule", ID: rule.ID},
+		Service:     "detection",
+		Status:      "SUCCESS",
+		Description: fmt.Sprintf("Uploaded %s rule %s version %d", rule.Kind, rule.Name, rule.Version),
+	})
+	c.JSON(http.StatusCreated, rule)
+}
+
+// GET /detection-rules
+func (h *DetectionRuleHandler) ListRules(c *gin.Context) {
+	rules, err := h.service.ListRules(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// GET /detection-rules/:rule_id
+func (h *DetectionRuleHandler) GetRule(c *gin.Context) {
+	rule, err := h.service.GetRule(c.GetString("tenantID"), c.Param("rule_id"))
+	if err != nil {
+		writeDetectionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+// GET /detection-rules/:rule_id/versions
+func (h *DetectionRuleHandler) ListRuleVersions(c *gin.Context) {
+	tenantID := c.GetString("tenantID")
+	rule, err := h.service.GetRule(tenantID, c.Param("rule_id"))
+	if err != nil {
+		writeDetectionError(c, err)
+		return
+	}
+	versions, err := h.service.ListRuleVersions(tenantID, rule.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, versions)
+}
+
+// PATCH /detection-rules/:rule_id
+func (h *DetectionRuleHandler) SetRuleEnabled(c *gin.Context) {
+	var req struct {
+		Enabled *bool `json:"enabled" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "enabled is required"})
+		return
+	}
+	ruleID := c.Param("rule_id")
+	if err := h.service.SetRuleEnabled(c.GetString("tenantID"), ruleID, *req.Enabled); err != nil {
+		writeDetectionError(c, err)
+		return
+	}
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action:      "UPDATE_DETECTION_RULE",
+		Actor:       auditlog.MakeActor(c),
+		Target:      auditlog.Target{Type: "detection_rule", ID: ruleID},
+		Service:     "detection",
+		Status:      "SUCCESS",
+		Description: fmt.Sprintf("Detection rule enabled=%t", *req.Enabled),
+	})
+	c.Status(http.StatusNoContent)
+}
+
+type detectionScanRequest struct {
+	RuleIDs         []string `json:"rule_ids"`
+	EvidenceIDs     []string `json:"evidence_ids"`
+	IncludeTimeline *bool    `json:"include_timeline"`
+}
+
+// POST /cases/:case_id/detections/yara
+func (h *DetectionRuleHandler) ScanYARA(c *gin.Context) {
+	h.scan(c, detection_rules.RuleKindYARA)
+}
+
+// POST /cases/:case_id/detections/sigma
+func (h *DetectionRuleHandler) ScanSigma(c *gin.Context) {
+	h.scan(c, detection_rules.RuleKindSigma)
+}
+
+func (h *DetectionRuleHandler) scan(c *gin.Context, kind detection_rules.RuleKind) {
+	var body detectionScanRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+	}
+	req := detection_rules.ScanRequest{
+		TenantID:        c.GetString("tenantID"),
+		CaseID:          c.Param("case_id"),
+		UserID:          c.GetString("userID"),
+		RuleIDs:         body.RuleIDs,
+		EvidenceIDs:     body.EvidenceIDs,
+		IncludeTimeline: body.IncludeTimeline == nil || *body.IncludeTimeline,
+	}
+
+	var report *detection_rules.ScanReport
+	var err error
+	action := "SCAN_YARA"
+	if kind == detection_rules.RuleKindYARA {
+		report, err = h.service.ScanEvidenceYARA(c.Request.Context(), req)
+	} else {
+		action = "SCAN_SIGMA"
+		report, err = h.service.ScanLogsSigma(c.Request.Context(), req)
+	}
+	if err != nil {
+		h.auditLogger.Log(c, auditlog.AuditLog{
+			Action:      action,
+			Actor:       auditlog.MakeActor(c),
+			Target:      auditlog.Target{Type: "case", ID: req.CaseID},
+			Service:     "detection",
+			Status:      "FAILED",
+			Description: "Detection scan failed: " + err.Error(),
+		})
+		writeDetectionError(c, err)
+		return
+	}
+
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action:      action,
+		Actor:       auditlog.MakeActor(c),
+		Target:      auditlog.Target{Type: "case", ID: req.CaseID},
+		Service:     "detection",
+		Status:      "SUCCESS",
+		Description: fmt.Sprintf("Applied %d rule(s) to %d item(s): %d match(es)", report.RulesApplied, report.ItemsScanned, len(report.Matches)),
+	})
+	c.JSON(http.StatusOK, report)
+}
+
+// GET /cases/:case_id/detections
+func (h *DetectionRuleHandler) ListMatches(c *gin.Context) {
+	matches, err := h.service.ListMatches(c.GetString("tenantID"), c.Param("case_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, matches)
+}
+
+// POST /cases/:case_id/detections/:match_id/accept
+func (h *DetectionRuleHandler) AcceptSuggestion(c *gin.Context) {
+	event, err := h.service.AcceptSuggestion(c.GetString("tenantID"), c.Param("case_id"), c.Param("match_id"),
+		c.GetString("userID"), c.GetString("fullName"))
+	if err != nil {
+		writeDetectionError(c, err)
+		return
+	}
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action:      "ACCEPT_DETECTION_SUGGESTION",
+		Actor:       auditlog.MakeActor(c),
+		Target:      auditlog.Target{Type: "timeline_event", ID: event.ID},
+		Service:     "detection",
+		Status:      "SUCCESS",
+		Description: "Detection match " + c.Param("match_id") + " added to timeline",
+	})
+	c.JSON(http.StatusCreated, event)
+}
+
+// POST /cases/:case_id/detections/:match_id/dismiss
+func (h *DetectionRuleHandler) DismissSuggestion(c *gin.Context) {
+	if err := h.service.DismissSuggestion(c.GetString("tenantID"), c.Param("case_id"), c.Param("match_id")); err != nil {
+		writeDetectionError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func writeDetectionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, detection_rules.ErrInvalidRuleKind),
+		errors.Is(err, detection_rules.ErrRuleNameRequired),
+		errors.Is(err, detection_rules.ErrInvalidRule),
+		errors.Is(err, detection_rules.ErrRuleKindMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, detection_rules.ErrEvidenceNotInCase),
+		errors.Is(err, detection_rules.ErrMatchNotInCase),
+		errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, detection_rules.ErrSuggestionProcessed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"aegis-api/services_/auditlog"
+	"aegis-api/services_/auth/abac"
 	download "aegis-api/services_/evidence/evidence_download"
 	"io"
 	"log"
@@ -24,26 +25,29 @@ type AuditLogger interface {
 type DownloadHandler struct {
 	service     DownloadService
 	auditLogger AuditLogger
+	policy      abac.Service // nil: evidence is unclassified
 }
 
 // NewDownloadHandler creates a new download handler with concrete types
-func NewDownloadHandler(svc *download.Service, auditLogger *auditlog.AuditLogger) *DownloadHandler {
+func NewDownloadHandler(svc *download.Service, auditLogger *auditlog.AuditLogger, policy abac.Service) *DownloadHandler {
 	return &DownloadHandler{
 		service:     svc,
 		auditLogger: auditLogger,
+		policy:      policy,
 	}
 }
 
 // NewDownloadHandlerWithInterfaces creates a new download handler with interface types (for testing)
-func NewDownloadHandlerWithInterfaces(svc DownloadService, auditLogger AuditLogger) *DownloadHandler {
+func NewDownloadHandlerWithInterfaces(svc DownloadService, auditLogger AuditLogger, policy abac.Service) *DownloadHandler {
 	return &DownloadHandler{
 		service:     svc,
 		auditLogger: auditLogger,
+		policy:      policy,
 	}
 }
 
 func (h *DownloadHandler) Download(c *gin.Context) {
-	idParam := c.Param("id")
+	idParam := c.Param("evidence_id")
 	evidenceID, err := uuid.Parse(idParam)
 	if err != nil {
 		if logErr := h.auditLogger.Log(c, auditlog.AuditLog{
@@ -61,6 +65,10 @@ func (h *DownloadHandler) Download(c *gin.Context) {
 		return
 	}
 
+	if classificationBlocked(c, h.policy, h.auditLogger, "evidence", abac.ActionDownload, abac.ResourceEvidence, evidenceID.String()) {
+		return
+	}
+
 	filename, stream, filetype, err := h.service.DownloadEvidence(evidenceID)
 	if err != nil {
 		log.Printf("❌ Download failed: %v\n", err)
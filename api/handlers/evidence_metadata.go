@@ -3,6 +3,7 @@ package handlers
 import (
 	"aegis-api/cache"
 	"aegis-api/services_/auditlog"
+	"aegis-api/services_/auth/abac"
 	"aegis-api/services_/evidence/metadata"
 	"fmt"
 	"log"
@@ -16,6 +17,7 @@ type MetadataHandler struct {
 	service     metadata.MetadataService
 	auditLogger *auditlog.AuditLogger
 	cacheClient cache.Client
+	Policy      abac.Service // nil: evidence is unclassified
 }
 
 func NewMetadataHandler(svc metadata.MetadataService, logger *auditlog.AuditLogger, c cache.Client) *MetadataHandler {
@@ -156,6 +158,10 @@ func (h *MetadataHandler) GetEvidenceByID(c *gin.Context) {
 		return
 	}
 
+	if classificationBlocked(c, h.Policy, h.auditLogger, "evidence", abac.ActionView, abac.ResourceEvidence, id.String()) {
+		return
+	}
+
 	evidence, err := h.service.FindEvidenceByID(id)
 	if err != nil {
 		h.auditLogger.Log(c, auditlog.AuditLog{
@@ -239,6 +245,26 @@ func (h *MetadataHandler) GetEvidenceByCaseID(c *gin.Context) {
 		return
 	}
 
+	// Evidence the caller is not cleared for is left out of the list
+	if h.Policy != nil && len(evidences) > 0 {
+		ids := make([]string, len(evidences))
+		for i, e := range evidences {
+			ids[i] = e.ID.String()
+		}
+		refused, err := h.Policy.Withheld(c.Request.Context(), classificationSubject(c), abac.ActionView, abac.ResourceEvidence, ids)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check evidence classification"})
+			return
+		}
+		visible := evidences[:0]
+		for _, e := range evidences {
+			if _, ok := refused[e.ID.String()]; !ok {
+				visible = append(visible, e)
+			}
+		}
+		evidences = visible
+	}
+
 	h.auditLogger.Log(c, auditlog.AuditLog{
 		Action: "GET_EVIDENCE_BY_CASE_ID",
 		Actor:  actor,
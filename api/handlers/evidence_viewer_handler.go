@@ -3,6 +3,7 @@ package handlers
 import (
 	"aegis-api/cache"
 	"aegis-api/middleware"
+	"aegis-api/services_/auth/abac"
 	"aegis-api/services_/evidence/evidence_viewer"
 	"encoding/json"
 	"net/http"
@@ -12,12 +13,14 @@ import (
 )
 
 type EvidenceViewerHandler struct {
-	Service *evidence_viewer.EvidenceService
-	Cache   cache.Client // <-- use your cache.Client
+	Service     *evidence_viewer.EvidenceService
+	Cache       cache.Client // <-- use your cache.Client
+	Policy      abac.Service // nil: evidence is unclassified
+	AuditLogger AuditLogger
 }
 
-func NewEvidenceViewerHandler(svc *evidence_viewer.EvidenceService, c cache.Client) *EvidenceViewerHandler {
-	return &EvidenceViewerHandler{Service: svc, Cache: c}
+func NewEvidenceViewerHandler(svc *evidence_viewer.EvidenceService, c cache.Client, policy abac.Service, auditLogger AuditLogger) *EvidenceViewerHandler {
+	return &EvidenceViewerHandler{Service: svc, Cache: c, Policy: policy, AuditLogger: auditLogger}
 
 }
 
@@ -44,6 +47,65 @@ func qsigFromQueryMinimal(c *gin.Context) string {
 	)
 }
 
+// withholdClassified drops the files the caller may not view from a
+// {"files": [...]} body. Bodies are cached per tenant, not per user, so
+// this runs on hits as well as misses.
+func (h *EvidenceViewerHandler) withholdClassified(c *gin.Context, body []byte) ([]byte, error) {
+	if h.Policy == nil {
+		return body, nil
+	}
+	var wire struct {
+		Files []evidence_viewer.EvidenceFile `json:"files"`
+	}
+	if err := json.Unmarshal(body, &wire); err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(wire.Files))
+	for i, f := range wire.Files {
+		ids[i] = f.ID
+	}
+	refused, err := h.Policy.Withheld(c.Request.Context(), classificationSubject(c), abac.ActionView, abac.ResourceEvidence, ids)
+	if err != nil || len(refused) == 0 {
+		return body, err
+	}
+	visible := wire.Files[:0]
+	for _, f := range wire.Files {
+		if _, ok := refused[f.ID]; !ok {
+			visible = append(visible, f)
+		}
+	}
+	return json.Marshal(gin.H{"files": visible})
+}
+
+// withholdOtherCases drops the search hits from cases the caller may not
+// view and reports how many are left. Search bodies are cached per tenant, so
+// this runs on hits as well as misses.
+func withholdOtherCases(c *gin.Context, body []byte) ([]byte, int, error) {
+	var wire struct {
+		Files []evidence_viewer.EvidenceFile `json:"files"`
+	}
+	if err := json.Unmarshal(body, &wire); err != nil {
+		return nil, 0, err
+	}
+	allowed := map[string]bool{}
+	visible := wire.Files[:0]
+	for _, f := range wire.Files {
+		ok, seen := allowed[f.CaseID]
+		if !seen {
+			var err error
+			if ok, err = middleware.CanAccessCase(c, "evidence:view", f.CaseID); err != nil {
+				return nil, 0, err
+			}
+			allowed[f.CaseID] = ok
+		}
+		if ok {
+			visible = append(visible, f)
+		}
+	}
+	out, err := json.Marshal(gin.H{"files": visible})
+	return out, len(visible), err
+}
+
 // ----- 1) LIST: GET /evidence/case/:case_id -----
 // Key: ev:list:<tenantId>:<caseId>:q=<sha> ; TTL 60–120s ; ETag+304 ; Cache-Control: private, max-age=120
 func (h *EvidenceViewerHandler) GetEvidenceByCaseID(c *gin.Context) {
@@ -61,7 +123,12 @@ func (h *EvidenceViewerHandler) GetEvidenceByCaseID(c *gin.Context) {
 
 	// HIT
 	if raw, ok, _ := h.Cache.Get(ctx, key); ok && raw != "" {
-		etag := cache.ListETag([]byte(raw))
+		visible, err := h.withholdClassified(c, []byte(raw))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check evidence classification"})
+			return
+		}
+		etag := cache.ListETag(visible)
 		if middleware.IfNoneMatch(c.Writer, c.Request, etag) {
 			c.Header("X-Cache", "REVALIDATED")
 			return
@@ -69,7 +136,7 @@ func (h *EvidenceViewerHandler) GetEvidenceByCaseID(c *gin.Context) {
 		middleware.SetCacheControl(c.Writer, 120)
 		c.Header("ETag", etag)
 		c.Header("X-Cache", "HIT")
-		c.Data(http.StatusOK, "application/json", []byte(raw))
+		c.Data(http.StatusOK, "application/json", visible)
 		return
 	}
 
@@ -85,11 +152,14 @@ func (h *EvidenceViewerHandler) GetEvidenceByCaseID(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"files": files})
-
 	body, _ := json.Marshal(gin.H{"files": files})
 	_ = h.Cache.Set(ctx, key, string(body), 120*time.Second)
 
+	body, err = h.withholdClassified(c, body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check evidence classification"})
+		return
+	}
 	etag := cache.ListETag(body)
 	if middleware.IfNoneMatch(c.Writer, c.Request, etag) {
 		c.Header("X-Cache", "REVALIDATED")
@@ -111,6 +181,11 @@ func (h *EvidenceViewerHandler) GetEvidenceByID(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing evidence ID"})
 		return
 	}
+	// The cached copy is shared by the tenant, so the label is checked
+	// before it is looked up.
+	if classificationBlocked(c, h.Policy, h.AuditLogger, "evidence", abac.ActionView, abac.ResourceEvidence, evidenceID) {
+		return
+	}
 	tenantID := tenantIDFromCtx(c)
 	key := cache.EvidenceItemKey(tenantID, evidenceID)
 	ctx := c.Request.Context()
@@ -163,6 +238,7 @@ func (h *EvidenceViewerHandler) GetEvidenceByID(c *gin.Context) {
 
 // ----- 3) SEARCH: GET /evidence/search?query= -----
 // Reuse list-style key with caseId = "-" ; TTL 60–120s ; ETag+304 ; Cache-Control: 120
+// Searches the caller's tenant; hits from cases they are not on are dropped.
 func (h *EvidenceViewerHandler) SearchEvidence(c *gin.Context) {
 	query := c.Query("query")
 	if query == "" {
@@ -176,20 +252,39 @@ func (h *EvidenceViewerHandler) SearchEvidence(c *gin.Context) {
 
 	ctx := c.Request.Context()
 
-	if raw, ok, _ := h.Cache.Get(ctx, key); ok && raw != "" {
-		etag := cache.ListETag([]byte(raw))
+	serve := func(body []byte, xcache string) {
+		body, err := h.withholdClassified(c, body)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check evidence classification"})
+			return
+		}
+		body, n, err := withholdOtherCases(c, body)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check case access"})
+			return
+		}
+		// Hits the caller cannot see look the same as no hits at all.
+		if n == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No matching evidence files found"})
+			return
+		}
+		etag := cache.ListETag(body)
 		if middleware.IfNoneMatch(c.Writer, c.Request, etag) {
 			c.Header("X-Cache", "REVALIDATED")
 			return
 		}
 		middleware.SetCacheControl(c.Writer, 120)
 		c.Header("ETag", etag)
-		c.Header("X-Cache", "HIT")
-		c.Data(http.StatusOK, "application/json", []byte(raw))
+		c.Header("X-Cache", xcache)
+		c.Data(http.StatusOK, "application/json", body)
+	}
+
+	if raw, ok, _ := h.Cache.Get(ctx, key); ok && raw != "" {
+		serve([]byte(raw), "HIT")
 		return
 	}
 
-	files, err := h.Service.SearchEvidenceFiles(query)
+	files, err := h.Service.SearchEvidenceFiles(tenantID, query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search evidence files"})
 		return
@@ -202,16 +297,7 @@ func (h *EvidenceViewerHandler) SearchEvidence(c *gin.Context) {
 
 	body, _ := json.Marshal(gin.H{"files": files})
 	_ = h.Cache.Set(ctx, key, string(body), 120*time.Second)
-
-	etag := cache.ListETag(body)
-	if middleware.IfNoneMatch(c.Writer, c.Request, etag) {
-		c.Header("X-Cache", "REVALIDATED")
-		return
-	}
-	middleware.SetCacheControl(c.Writer, 120)
-	c.Header("ETag", etag)
-	c.Header("X-Cache", "MISS")
-	c.Data(http.StatusOK, "application/json", body)
+	serve(body, "MISS")
 }
 
 // ----- 4) FILTER: POST /evidence/case/:case_id/filter -----
@@ -248,7 +334,12 @@ func (h *EvidenceViewerHandler) GetFilteredEvidence(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	if raw, ok, _ := h.Cache.Get(ctx, key); ok && raw != "" {
-		etag := cache.ListETag([]byte(raw))
+		visible, err := h.withholdClassified(c, []byte(raw))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check evidence classification"})
+			return
+		}
+		etag := cache.ListETag(visible)
 		if middleware.IfNoneMatch(c.Writer, c.Request, etag) {
 			c.Header("X-Cache", "REVALIDATED")
 			return
@@ -256,7 +347,7 @@ func (h *EvidenceViewerHandler) GetFilteredEvidence(c *gin.Context) {
 		middleware.SetCacheControl(c.Writer, 120)
 		c.Header("ETag", etag)
 		c.Header("X-Cache", "HIT")
-		c.Data(http.StatusOK, "application/json", []byte(raw))
+		c.Data(http.StatusOK, "application/json", visible)
 		return
 	}
 
@@ -274,6 +365,11 @@ func (h *EvidenceViewerHandler) GetFilteredEvidence(c *gin.Context) {
 	body, _ := json.Marshal(gin.H{"files": files})
 	_ = h.Cache.Set(ctx, key, string(body), 120*time.Second)
 
+	body, err = h.withholdClassified(c, body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check evidence classification"})
+		return
+	}
 	etag := cache.ListETag(body)
 	if middleware.IfNoneMatch(c.Writer, c.Request, etag) {
 		c.Header("X-Cache", "REVALIDATED")
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	graphicalmapping "aegis-api/services_/GraphicalMapping"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type InvestigationGraphHandler struct {
+	service graphicalmapping.InvestigationGraphService
+}
+
+func NewInvestigationGraphHandler(service graphicalmapping.InvestigationGraphService) *InvestigationGraphHandler {
+	return &InvestigationGraphHandler{service: service}
+}
+
+// POST /cases/:case_id/graph/entities
+func (h *InvestigationGraphHandler) AddEntity(c *gin.Context) {
+	var req struct {
+		Type       string            `json:"type" binding:"required"`
+		Label      string            `json:"label" binding:"required"`
+		RefID      string            `json:"ref_id"`
+		Properties map[string]string `json:"properties"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	entity := &graphicalmapping.GraphEntity{
+		TenantID:  c.GetString("tenantID"),
+		CaseID:    c.Param("case_id"),
+		Type:      graphicalmapping.EntityType(req.Type),
+		Label:     req.Label,
+		RefID:     req.RefID,
+		CreatedBy: c.GetString("userID"),
+	}
+	if len(req.Properties) > 0 {
+		b, _ := json.Marshal(req.Properties)
+		entity.Properties = datatypes.JSON(b)
+	}
+
+	created, err := h.service.AddEntity(entity)
+	if err != nil {
+		writeGraphError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// GET /cases/:case_id/graph/entities
+func (h *InvestigationGraphHandler) ListEntities(c *gin.Context) {
+	entities, err := h.service.ListEntities(c.GetString("tenantID"), c.Param("case_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, entities)
+}
+
+// DELETE /cases/:case_id/graph/entities/:entity_id
+func (h *InvestigationGraphHandler) DeleteEntity(c *gin.Context) {
+	if err := h.service.DeleteEntity(c.GetString("tenantID"), c.Param("case_id"), c.Param("entity_id")); err != nil {
+		writeGraphError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// POST /cases/:case_id/graph/relations
+func (h *InvestigationGraphHandler) AddRelation(c *gin.Context) {
+	var req struct {
+		SourceID  string     `json:"source_id" binding:"required"`
+		TargetID  string     `json:"target_id" binding:"required"`
+		Type      string     `json:"type" binding:"required"`
+		ValidFrom *time.Time `json:"valid_from"`
+		ValidTo   *time.Time `json:"valid_to"`
+		Evidence  []string   `json:"evidence"`
+		Notes     string     `json:"notes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	rel := &graphicalmapping.GraphRelation{
+		TenantID:  c.GetString("tenantID"),
+		CaseID:    c.Param("case_id"),
+		SourceID:  req.SourceID,
+		TargetID:  req.TargetID,
+		Type:      graphicalmapping.RelationType(req.Type),
+		ValidFrom: req.ValidFrom,
+		ValidTo:   req.ValidTo,
+		Notes:     req.Notes,
+		CreatedBy: c.GetString("userID"),
+	}
+	if len(req.Evidence) > 0 {
+		b, _ := json.Marshal(req.Evidence)
+		rel.Evidence = datatypes.JSON(b)
+	}
+
+	created, err := h.service.AddRelation(rel)
+	if err != nil {
+		writeGraphError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// DELETE /cases/:case_id/graph/relations/:relation_id
+func (h *InvestigationGraphHandler) DeleteRelation(c *gin.Context) {
+	if err := h.service.DeleteRelation(c.GetString("tenantID"), c.Param("case_id"), c.Param("relation_id")); err != nil {
+		writeGraphError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GET /cases/:case_id/graph?from=RFC3339&to=RFC3339
+// Without a window the whole case graph is returned.
+func (h *InvestigationGraphHandler) GetGraph(c *gin.Context) {
+	tenantID := c.GetString("tenantID")
+	caseID := c.Param("case_id")
+
+	fromStr, toStr := c.Query("from"), c.Query("to")
+	if fromStr == "" && toStr == "" {
+		g, err := h.service.GetCaseGraph(tenantID, caseID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, g)
+		return
+	}
+
+	from, err1 := time.Parse(time.RFC3339, fromStr)
+	to, err2 := time.Parse(time.RFC3339, toStr)
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must both be RFC3339 timestamps"})
+		return
+	}
+	g, err := h.service.SubgraphByTimeWindow(tenantID, caseID, from, to)
+	if err != nil {
+		writeGraphError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, g)
+}
+
+// GET /cases/:case_id/graph/entities/:entity_id/neighbors?hops=2
+func (h *InvestigationGraphHandler) GetNeighbors(c *gin.Context) {
+	hops := 1
+	if v := c.Query("hops"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "hops must be an integer"})
+			return
+		}
+		hops = n
+	}
+
+	g, err := h.service.Neighbors(c.GetString("tenantID"), c.Param("case_id"), c.Param("entity_id"), hops)
+	if err != nil {
+		writeGraphError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, g)
+}
+
+// GET /cases/:case_id/graph/path?from=<entity_id>&to=<entity_id>
+func (h *InvestigationGraphHandler) GetShortestPath(c *gin.Context) {
+	from, to := c.Query("from"), c.Query("to")
+	if from == "" || to == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to entity IDs are required"})
+		return
+	}
+
+	g, err := h.service.ShortestPath(c.GetString("tenantID"), c.Param("case_id"), from, to)
+	if err != nil {
+		writeGraphError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, g)
+}
+
+// GET /cases/:case_id/graph/export?format=graphml|gexf
+func (h *InvestigationGraphHandler) ExportGraph(c *gin.Context) {
+	caseID := c.Param("case_id")
+	format := c.DefaultQuery("format", "graphml")
+
+	var export func(w io.Writer, g *graphicalmapping.InvestigationGraph) error
+	var contentType string
+	switch format {
+	case "graphml":
+		export, contentType = graphicalmapping.ExportGraphML, "application/graphml+xml"
+	case "gexf":
+		export, contentType = graphicalmapping.ExportGEXF, "application/gexf+xml"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be graphml or gexf"})
+		return
+	}
+
+	g, err := h.service.GetCaseGraph(c.GetString("tenantID"), caseID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=case-%s-graph.%s", caseID, format))
+	c.Status(http.StatusOK)
+	if err := export(c.Writer, g); err != nil {
+		c.Error(err)
+	}
+}
+
+func writeGraphError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, graphicalmapping.ErrInvalidEntityType),
+		errors.Is(err, graphicalmapping.ErrInvalidRelationType),
+		errors.Is(err, graphicalmapping.ErrEntityLabelRequired),
+		errors.Is(err, graphicalmapping.ErrInvalidTimeBounds):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, graphicalmapping.ErrEntityNotInCase),
+		errors.Is(err, graphicalmapping.ErrEvidenceNotInCase),
+		errors.Is(err, graphicalmapping.ErrNoPath),
+		errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"aegis-api/services_/auditlog"
+	"aegis-api/services_/case/invitations"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InvitationHandler manages time-boxed invitations of external
+// collaborators to part of a case.
+type InvitationHandler struct {
+	service     invitations.Service
+	auditLogger *auditlog.AuditLogger
+}
+
+func NewInvitationHandler(service invitations.Service, auditLogger *auditlog.AuditLogger) *InvitationHandler {
+	return &InvitationHandler{service: service, auditLogger: auditLogger}
+}
+
+func invitationActor(c *gin.Context) invitations.Actor {
+	return invitations.Actor{UserID: c.GetString("userID"), TenantID: c.GetString("tenantID")}
+}
+
+func invitationGuest(c *gin.Context) invitations.Guest {
+	return invitations.Guest{UserID: c.GetString("userID"), TenantID: c.GetString("tenantID"), Email: c.GetString("email")}
+}
+
+// watermarkRequired refuses a download that cannot carry the watermark
+// RequireCaseAccess set for the caller, e.g. a sealed PDF.
+func watermarkRequired(c *gin.Context, format string) bool {
+	if c.GetString("watermark") == "" {
+		return false
+	}
+	writeError(c, http.StatusForbidden, "watermark_required",
+		"Your invitation only allows watermarked downloads; "+format+" cannot be watermarked")
+	return true
+}
+
+func watermarkNote(watermark string) string {
+	if watermark == "" {
+		return ""
+	}
+	return " with watermark"
+}
+
+func (h *InvitationHandler) audit(c *gin.Context, action string, inv *invitations.Invitation, description string) {
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action:      action,
+		Actor:       auditlog.MakeActor(c),
+		Target:      auditlog.Target{Type: "case_invitation", ID: inv.ID, AdditionalInfo: map[string]string{"case_id": inv.CaseID}},
+		Service:     "case",
+		Status:      "SUCCESS",
+		Description: description,
+	})
+}
+
+func writeInvitationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, invitations.ErrNotFound):
+		writeError(c, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, invitations.ErrInvalidInput):
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+	case errors.Is(err, invitations.ErrConflict):
+		writeError(c, http.StatusConflict, "conflict", err.Error())
+	case errors.Is(err, invitations.ErrForbidden):
+		writeError(c, http.StatusForbidden, "forbidden", err.Error())
+	case errors.Is(err, invitations.ErrDelivery):
+		writeError(c, http.StatusBadGateway, "email_failed", err.Error())
+	default:
+		writeError(c, http.StatusInternalServerError, "internal_error", "An internal error occurred")
+	}
+}
+
+// POST /cases/:case_id/invitations {email, evidence_ids?, report_ids?, starts_at?, ends_at, ip_allowlist?, watermark?}
+func (h *InvitationHandler) Invite(c *gin.Context) {
+	var in invitations.InvitationInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	inv, err := h.service.Invite(invitationActor(c), c.Param("case_id"), in)
+	if err != nil {
+		writeInvitationError(c, err)
+		return
+	}
+	h.audit(c, "INVITE_COLLABORATOR", inv, fmt.Sprintf("Invited %s from %s to %s",
+		inv.Email, inv.StartsAt.Format("2006-01-02 15:04"), inv.EndsAt.Format("2006-01-02 15:04")))
+	c.JSON(http.StatusCreated, inv)
+}
+
+// GET /cases/:case_id/invitations
+func (h *InvitationHandler) ListForCase(c *gin.Context) {
+	list, err := h.service.ListInvitations(c.GetString("tenantID"), c.Param("case_id"))
+	if err != nil {
+		writeInvitationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"invitations": list})
+}
+
+// GET /invitations
+func (h *InvitationHandler) List(c *gin.Context) {
+	list, err := h.service.ListInvitations(c.GetString("tenantID"), "")
+	if err != nil {
+		writeInvitationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"invitations": list})
+}
+
+// POST /invitations/:id/revoke
+func (h *InvitationHandler) Revoke(c *gin.Context) {
+	inv, err := h.service.Revoke(invitationActor(c), c.Param("id"))
+	if err != nil {
+		writeInvitationError(c, err)
+		return
+	}
+	h.audit(c, "REVOKE_COLLABORATOR_INVITATION", inv, "Revoked the invitation of "+inv.Email)
+	c.JSON(http.StatusOK, inv)
+}
+
+// GET /invitations/:id/access-report
+func (h *InvitationHandler) AccessReport(c *gin.Context) {
+	report, err := h.service.AccessReport(c.GetString("tenantID"), c.Param("id"))
+	if err != nil {
+		writeInvitationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// POST /invitations/accept {token}
+func (h *InvitationHandler) Accept(c *gin.Context) {
+	var in struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	inv, err := h.service.Accept(invitationGuest(c), in.Token)
+	if err != nil {
+		writeInvitationError(c, err)
+		return
+	}
+	h.audit(c, "ACCEPT_COLLABORATOR_INVITATION", inv, inv.Email+" accepted the invitation")
+	c.JSON(http.StatusOK, inv)
+}
+
+// GET /invitations/mine
+func (h *InvitationHandler) ListMine(c *gin.Context) {
+	list, err := h.service.ListMine(invitationGuest(c))
+	if err != nil {
+		writeInvitationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"invitations": list})
+}
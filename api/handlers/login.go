@@ -8,8 +8,10 @@ import (
 	"aegis-api/services_/auth/login"
 	"aegis-api/services_/auth/registration"
 	"aegis-api/services_/auth/reset_password"
+	"aegis-api/services_/auth/session"
 	"aegis-api/services_/notification"
 	"aegis-api/structs"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -23,6 +25,7 @@ import (
 
 type AuthHandler struct {
 	authService          *login.AuthService
+	sessions             session.Service
 	passwordResetService *reset_password.PasswordResetService
 	userRepo             registration.UserRepository
 	auditLogger          *auditlog.AuditLogger
@@ -36,12 +39,14 @@ type ChangePasswordRequest struct {
 
 func NewAuthHandler(
 	authService *login.AuthService,
+	sessions session.Service,
 	resetService *reset_password.PasswordResetService,
 	userRepo registration.UserRepository,
 	auditLogger *auditlog.AuditLogger,
 ) *AuthHandler {
 	return &AuthHandler{
 		authService:          authService,
+		sessions:             sessions,
 		passwordResetService: resetService,
 		userRepo:             userRepo,
 		auditLogger:          auditLogger,
@@ -91,6 +96,24 @@ type Handler struct {
 	ChainOfCustodyHandler *ChainOfCustodyHandler
 	X3DHService           *x3dh.BundleService // Add this
 	VerificationHandler   *VerificationHandler
+
+	InvestigationGraphHandler *InvestigationGraphHandler
+	DetectionRuleHandler      *DetectionRuleHandler
+	AISettingsHandler         *AISettingsHandler
+	CaseQAHandler             *CaseQAHandler
+	ReportTemplateHandler     *ReportTemplateHandler
+	ReportArtifactHandler     *ReportArtifactHandler
+	RedactionHandler          *RedactionHandler
+	ReportJobHandler          *ReportJobHandler
+	CaseClosureHandler        *CaseClosureHandler
+	MFAHandler                *MFAHandler
+	WebAuthnHandler           *WebAuthnHandler
+	SSOHandler                *SSOHandler
+	SCIMHandler               *SCIMHandler
+	APIKeyHandler             *APIKeyHandler
+	AccessDenialHandler       *AccessDenialHandler
+	ClassificationHandler     *ClassificationHandler
+	InvitationHandler         *InvitationHandler
 }
 
 func NewHandler(
@@ -136,6 +159,23 @@ func NewHandler(
 	x3dhService *x3dh.BundleService,
 	verificationHandler *VerificationHandler,
 
+	investigationGraphHandler *InvestigationGraphHandler,
+	detectionRuleHandler *DetectionRuleHandler,
+	aiSettingsHandler *AISettingsHandler,
+	caseQAHandler *CaseQAHandler,
+	reportTemplateHandler *ReportTemplateHandler,
+	reportArtifactHandler *ReportArtifactHandler,
+	redactionHandler *RedactionHandler,
+	reportJobHandler *ReportJobHandler,
+	caseClosureHandler *CaseClosureHandler,
+	mfaHandler *MFAHandler,
+	webAuthnHandler *WebAuthnHandler,
+	ssoHandler *SSOHandler,
+	scimHandler *SCIMHandler,
+	apiKeyHandler *APIKeyHandler,
+	accessDenialHandler *AccessDenialHandler,
+	classificationHandler *ClassificationHandler,
+	invitationHandler *InvitationHandler,
 ) *Handler {
 	return &Handler{
 		AdminService:              adminSvc,
@@ -178,22 +218,35 @@ func NewHandler(
 
 		X3DHService:         x3dhService,
 		VerificationHandler: verificationHandler,
+
+		InvestigationGraphHandler: investigationGraphHandler,
+		DetectionRuleHandler:      detectionRuleHandler,
+		AISettingsHandler:         aiSettingsHandler,
+		CaseQAHandler:             caseQAHandler,
+		ReportTemplateHandler:     reportTemplateHandler,
+		ReportArtifactHandler:     reportArtifactHandler,
+		RedactionHandler:          redactionHandler,
+		ReportJobHandler:          reportJobHandler,
+		CaseClosureHandler:        caseClosureHandler,
+		MFAHandler:                mfaHandler,
+		WebAuthnHandler:           webAuthnHandler,
+		SSOHandler:                ssoHandler,
+		SCIMHandler:               scimHandler,
+		APIKeyHandler:             apiKeyHandler,
+		AccessDenialHandler:       accessDenialHandler,
+		ClassificationHandler:     classificationHandler,
+		InvitationHandler:         invitationHandler,
 	}
 }
 
 func (h *AuthHandler) LoginHandler(c *gin.Context) {
-	// Debug: Print all request headers
-	for k, v := range c.Request.Header {
-		fmt.Printf("[DEBUG] Header: %s = %v\n", k, v)
-	}
-
 	var req struct {
-		Email    string `json:"email" binding:"required,email"`
-		Password string `json:"password" binding:"required"`
+		Email      string `json:"email" binding:"required,email"`
+		Password   string `json:"password" binding:"required"`
+		DeviceName string `json:"deviceName"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		fmt.Printf("[DEBUG] Body bind error: %v\n", err)
 		c.JSON(http.StatusBadRequest, structs.ErrorResponse{
 			Error:   "invalid_request",
 			Message: err.Error(),
@@ -201,10 +254,8 @@ func (h *AuthHandler) LoginHandler(c *gin.Context) {
 		return
 	}
 
-	fmt.Printf("[DEBUG] Body: email=%s, password=%s\n", req.Email, req.Password)
-
 	// Add detailed error handling around the service call
-	resp, err := h.authService.Login(req.Email, req.Password)
+	resp, err := h.authService.Login(req.Email, req.Password, sessionClient(c, req.DeviceName))
 	if err != nil {
 		// Log the actual error details
 		fmt.Printf("[ERROR] Login service error: %v\n", err)
@@ -227,7 +278,12 @@ func (h *AuthHandler) LoginHandler(c *gin.Context) {
 		})
 
 		// Return 500 if it's an unexpected error, 401 for auth failures
-		if strings.Contains(err.Error(), "invalid") || strings.Contains(err.Error(), "not found") {
+		if errors.Is(err, login.ErrPasswordLoginDisabled) {
+			c.JSON(http.StatusForbidden, structs.ErrorResponse{
+				Error:   "sso_required",
+				Message: err.Error(),
+			})
+		} else if strings.Contains(err.Error(), "invalid") || strings.Contains(err.Error(), "not found") {
 			c.JSON(http.StatusUnauthorized, structs.ErrorResponse{
 				Error:   "authentication_failed",
 				Message: "Invalid email or password",
@@ -243,6 +299,10 @@ func (h *AuthHandler) LoginHandler(c *gin.Context) {
 
 	// Log successful attempt
 	status := "SUCCESS"
+	description, message := "User logged in successfully", "Login successful"
+	if resp.MFARequired || resp.MFAEnrollmentRequired {
+		description, message = "Password verified; awaiting second factor", "Multi-factor authentication required"
+	}
 	h.auditLogger.Log(c, auditlog.AuditLog{
 		Action: "LOGIN_ATTEMPT",
 		Actor: auditlog.Actor{
@@ -256,12 +316,17 @@ func (h *AuthHandler) LoginHandler(c *gin.Context) {
 		},
 		Service:     "auth",
 		Status:      status,
-		Description: "User logged in successfully",
+		Description: description,
+		Metadata: map[string]string{
+			"session_id": resp.SessionID,
+			"ip_address": c.ClientIP(),
+			"user_agent": c.GetHeader("User-Agent"),
+		},
 	})
 
 	c.JSON(http.StatusOK, structs.SuccessResponse{
 		Success: true,
-		Message: "Login successful",
+		Message: message,
 		Data:    resp,
 	})
 }
@@ -281,6 +346,18 @@ func (h *AuthHandler) LogoutHandler(c *gin.Context) {
 		return
 	}
 
+	// End the session so its refresh token and access tokens stop working
+	sessionID := c.GetString("sessionID")
+	if sessionID != "" {
+		if err := h.sessions.RevokeSession(c.Request.Context(), userID, sessionID, session.ReasonLogout); err != nil {
+			c.JSON(http.StatusInternalServerError, structs.ErrorResponse{
+				Error:   "internal_error",
+				Message: "Failed to end session",
+			})
+			return
+		}
+	}
+
 	// Simple audit log (optional)
 	h.auditLogger.Log(c, auditlog.AuditLog{
 		Action: "USER_LOGOUT",
@@ -296,6 +373,7 @@ func (h *AuthHandler) LogoutHandler(c *gin.Context) {
 		Description: "User logged out",
 		Metadata: map[string]string{
 			"timestamp":  time.Now().Format(time.RFC3339),
+			"session_id": sessionID,
 			"ip_address": c.ClientIP(),
 			"user_agent": c.GetHeader("User-Agent"),
 		},
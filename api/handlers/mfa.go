@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"aegis-api/services_/auditlog"
+	"aegis-api/services_/auth/login"
+	"aegis-api/services_/auth/mfa_policy"
+	"aegis-api/services_/auth/session"
+	"aegis-api/structs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MFAHandler serves the second step of login, MFA enrolment, step-up
+// re-verification and the tenant's MFA policy.
+type MFAHandler struct {
+	auth        *login.AuthService
+	mfa         mfa_policy.Service
+	auditLogger *auditlog.AuditLogger
+}
+
+func NewMFAHandler(auth *login.AuthService, mfa mfa_policy.Service, auditLogger *auditlog.AuditLogger) *MFAHandler {
+	return &MFAHandler{auth: auth, mfa: mfa, auditLogger: auditLogger}
+}
+
+func mfaActor(c *gin.Context) mfa_policy.Actor {
+	return mfa_policy.Actor{
+		UserID:   c.GetString("userID"),
+		TenantID: c.GetString("tenantID"),
+		Role:     c.GetString("userRole"),
+	}
+}
+
+func (h *MFAHandler) audit(c *gin.Context, action string, actor auditlog.Actor, userID, status, description string) {
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action:      action,
+		Actor:       actor,
+		Target:      auditlog.Target{Type: "user", ID: userID},
+		Service:     "auth",
+		Status:      status,
+		Description: description,
+	})
+}
+
+func writeMFAError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, login.ErrInvalidMFAToken):
+		writeError(c, http.StatusUnauthorized, "invalid_mfa_token", err.Error())
+	case errors.Is(err, mfa_policy.ErrInvalidCode):
+		writeError(c, http.StatusUnauthorized, "invalid_mfa_code", err.Error())
+	case errors.Is(err, mfa_policy.ErrCodeReused):
+		writeError(c, http.StatusUnauthorized, "mfa_code_reused", err.Error())
+	case errors.Is(err, mfa_policy.ErrChallengeUsed):
+		writeError(c, http.StatusUnauthorized, "invalid_mfa_token", err.Error())
+	case errors.Is(err, mfa_policy.ErrTooManyAttempts):
+		writeError(c, http.StatusTooManyRequests, "too_many_attempts", err.Error())
+	case errors.Is(err, login.ErrAccessRevoked), errors.Is(err, session.ErrSessionRevoked):
+		writeError(c, http.StatusUnauthorized, "access_revoked", err.Error())
+	case errors.Is(err, mfa_policy.ErrNotEnrolled):
+		writeError(c, http.StatusConflict, "mfa_not_enrolled", err.Error())
+	case errors.Is(err, mfa_policy.ErrAlreadyEnrolled):
+		writeError(c, http.StatusConflict, "mfa_already_enrolled", err.Error())
+	case errors.Is(err, mfa_policy.ErrRequiredByPolicy):
+		writeError(c, http.StatusConflict, "mfa_required_by_policy", err.Error())
+	case errors.Is(err, mfa_policy.ErrInvalidPolicy):
+		writeError(c, http.StatusBadRequest, "invalid_policy", err.Error())
+	default:
+		writeError(c, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+}
+
+type mfaLoginRequest struct {
+	MFAToken   string `json:"mfaToken" binding:"required"`
+	Code       string `json:"code"`
+	DeviceName string `json:"deviceName"`
+}
+
+// anonymousActor identifies a caller who is not signed in yet.
+func anonymousActor(c *gin.Context) auditlog.Actor {
+	return auditlog.Actor{IPAddress: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+// loginActor identifies the user a login response is for.
+func loginActor(c *gin.Context, resp *login.LoginResponse) auditlog.Actor {
+	return auditlog.Actor{
+		ID:        resp.ID,
+		Role:      resp.Role,
+		Email:     resp.Email,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// POST /auth/mfa/verify {mfaToken, code, deviceName?}
+// Second step of login: a TOTP or recovery code for the pre-auth token
+// the password step returned.
+func (h *MFAHandler) Verify(c *gin.Context) {
+	var req mfaLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		writeError(c, http.StatusBadRequest, "invalid_request", "mfaToken and code are required")
+		return
+	}
+	resp, err := h.auth.CompleteMFA(c.Request.Context(), req.MFAToken, req.Code, sessionClient(c, req.DeviceName))
+	if err != nil {
+		h.audit(c, "MFA_VERIFY", anonymousActor(c), "", "FAILED", fmt.Sprintf("Second factor rejected: %v", err))
+		writeMFAError(c, err)
+		return
+	}
+	h.audit(c, "MFA_VERIFY", loginActor(c, resp), resp.ID, "SUCCESS", "User logged in with "+resp.MFAMethod)
+	c.JSON(http.StatusOK, structs.SuccessResponse{Success: true, Message: "Login successful", Data: resp})
+}
+
+// POST /auth/mfa/enroll {mfaToken}
+// For users whose role requires MFA but who have none: returns the TOTP
+// secret and recovery codes to set up.
+func (h *MFAHandler) StartEnrollment(c *gin.Context) {
+	var req mfaLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", "mfaToken is required")
+		return
+	}
+	out, err := h.auth.StartMFAEnrollment(req.MFAToken)
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// POST /auth/mfa/enroll/confirm {mfaToken, code, deviceName?}
+// Enables MFA with a first code and completes the login.
+func (h *MFAHandler) ConfirmEnrollment(c *gin.Context) {
+	var req mfaLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		writeError(c, http.StatusBadRequest, "invalid_request", "mfaToken and code are required")
+		return
+	}
+	resp, err := h.auth.CompleteMFAEnrollment(c.Request.Context(), req.MFAToken, req.Code, sessionClient(c, req.DeviceName))
+	if err != nil {
+		h.audit(c, "MFA_ENROLL", anonymousActor(c), "", "FAILED", err.Error())
+		writeMFAError(c, err)
+		return
+	}
+	h.audit(c, "MFA_ENROLL", loginActor(c, resp), resp.ID, "SUCCESS", "MFA enabled at login as required by tenant policy")
+	c.JSON(http.StatusOK, structs.SuccessResponse{Success: true, Message: "Login successful", Data: resp})
+}
+
+// GET /auth/mfa
+func (h *MFAHandler) Status(c *gin.Context) {
+	st, err := h.mfa.Status(mfaActor(c))
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, st)
+}
+
+// POST /auth/mfa/setup
+func (h *MFAHandler) Setup(c *gin.Context) {
+	out, err := h.mfa.Setup(mfaActor(c), c.GetString("email"))
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// POST /auth/mfa/enable {code}
+func (h *MFAHandler) Enable(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	actor := mfaActor(c)
+	if err := h.mfa.Enable(c.Request.Context(), "enable:"+actor.UserID, actor, req.Code); err != nil {
+		h.audit(c, "MFA_ENROLL", auditlog.MakeActor(c), actor.UserID, "FAILED", err.Error())
+		writeMFAError(c, err)
+		return
+	}
+	h.audit(c, "MFA_ENROLL", auditlog.MakeActor(c), actor.UserID, "SUCCESS", "MFA enabled")
+	c.JSON(http.StatusOK, gin.H{"message": "MFA enabled"})
+}
+
+// POST /auth/mfa/step-up {code}
+// Returns an access token for the current session carrying a fresh
+// step-up claim, as required by sensitive routes.
+func (h *MFAHandler) StepUp(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	userID := c.GetString("userID")
+	resp, err := h.auth.StepUp(c.Request.Context(), userID, c.GetString("sessionID"), req.Code)
+	if err != nil {
+		h.audit(c, "MFA_STEP_UP", auditlog.MakeActor(c), userID, "FAILED", err.Error())
+		writeMFAError(c, err)
+		return
+	}
+	h.audit(c, "MFA_STEP_UP", auditlog.MakeActor(c), userID, "SUCCESS", "Step-up verified with "+resp.MFAMethod)
+	c.JSON(http.StatusOK, structs.SuccessResponse{Success: true, Message: "Step-up verified", Data: resp})
+}
+
+// POST /auth/mfa/recovery-codes (step-up)
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	actor := mfaActor(c)
+	codes, err := h.mfa.RegenerateRecoveryCodes(actor)
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+	h.audit(c, "MFA_RECOVERY_CODES", auditlog.MakeActor(c), actor.UserID, "SUCCESS", "Recovery codes regenerated")
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// DELETE /auth/mfa (step-up)
+func (h *MFAHandler) Disable(c *gin.Context) {
+	actor := mfaActor(c)
+	if err := h.mfa.Disable(actor); err != nil {
+		h.audit(c, "MFA_DISABLE", auditlog.MakeActor(c), actor.UserID, "FAILED", err.Error())
+		writeMFAError(c, err)
+		return
+	}
+	h.audit(c, "MFA_DISABLE", auditlog.MakeActor(c), actor.UserID, "SUCCESS", "MFA disabled")
+	c.Status(http.StatusNoContent)
+}
+
+// GET /mfa-policy
+func (h *MFAHandler) GetPolicy(c *gin.Context) {
+	p, err := h.mfa.GetPolicy(c.GetString("tenantID"))
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// PUT /mfa-policy {required_roles, step_up_minutes}
+func (h *MFAHandler) UpdatePolicy(c *gin.Context) {
+	var in mfa_policy.PolicyInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	target := auditlog.Target{Type: "tenant", ID: c.GetString("tenantID")}
+	p, err := h.mfa.UpdatePolicy(mfaActor(c), in)
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action:      "UPDATE_MFA_POLICY",
+		Actor:       auditlog.MakeActor(c),
+		Target:      target,
+		Service:     "auth",
+		Status:      "SUCCESS",
+		Description: fmt.Sprintf("MFA required for roles %s; step-up lasts %d minutes", string(p.RequiredRoles), p.StepUpMinutes),
+	})
+	c.JSON(http.StatusOK, p)
+}
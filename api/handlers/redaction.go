@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"aegis-api/services_/auditlog"
+	"aegis-api/services_/auth/abac"
+	"aegis-api/services_/redaction"
+	"aegis-api/services_/report"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RedactionHandler manages redaction marks on reports and evidence, the
+// tenant's redaction profiles, and redacted copies of evidence.
+type RedactionHandler struct {
+	redactions  redaction.Service
+	policy      abac.Service // nil: reports and evidence are unclassified
+	auditLogger *auditlog.AuditLogger
+}
+
+func NewRedactionHandler(redactions redaction.Service, policy abac.Service, auditLogger *auditlog.AuditLogger) *RedactionHandler {
+	return &RedactionHandler{redactions: redactions, policy: policy, auditLogger: auditLogger}
+}
+
+func redactionActor(c *gin.Context) redaction.Actor {
+	return redaction.Actor{
+		UserID:   c.GetString("userID"),
+		Role:     c.GetString("userRole"),
+		TenantID: c.GetString("tenantID"),
+		Email:    c.GetString("email"),
+	}
+}
+
+func (h *RedactionHandler) audit(c *gin.Context, action string, target auditlog.Target, status, description string) {
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action:      action,
+		Actor:       auditlog.MakeActor(c),
+		Target:      target,
+		Service:     "redaction",
+		Status:      status,
+		Description: description,
+	})
+}
+
+func writeRedactionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, redaction.ErrTargetNotFound):
+		writeError(c, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, redaction.ErrMarkNotFound):
+		writeError(c, http.StatusNotFound, "mark_not_found", err.Error())
+	case errors.Is(err, redaction.ErrProfileNotFound):
+		writeError(c, http.StatusNotFound, "profile_not_found", err.Error())
+	case errors.Is(err, redaction.ErrProfileExists):
+		writeError(c, http.StatusConflict, "profile_exists", err.Error())
+	case errors.Is(err, redaction.ErrInvalidMark):
+		writeError(c, http.StatusBadRequest, "invalid_mark", err.Error())
+	case errors.Is(err, redaction.ErrInvalidProfile):
+		writeError(c, http.StatusBadRequest, "invalid_profile", err.Error())
+	case errors.Is(err, redaction.ErrNothingToRedact):
+		writeError(c, http.StatusUnprocessableEntity, "nothing_to_redact", err.Error())
+	case errors.Is(err, redaction.ErrUnsupportedEvidence):
+		writeError(c, http.StatusUnprocessableEntity, "unsupported_evidence", err.Error())
+	case errors.Is(err, redaction.ErrFileTooLarge):
+		writeError(c, http.StatusRequestEntityTooLarge, "file_too_large", err.Error())
+	case errors.Is(err, redaction.ErrIntegrity):
+		writeError(c, http.StatusConflict, "integrity_check_failed", err.Error())
+	default:
+		writeError(c, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+}
+
+// GET /redaction-reasons
+func (h *RedactionHandler) ListReasons(c *gin.Context) {
+	c.JSON(http.StatusOK, h.redactions.Reasons())
+}
+
+// GET /reports/:reportID/redactions?withdrawn=true
+func (h *RedactionHandler) ListReportMarks(c *gin.Context) {
+	h.listMarks(c, redaction.TargetReport, c.Param("reportID"))
+}
+
+// POST /reports/:reportID/redactions
+// Body: {"section_id": "...", "quote": "...", "occurrence": 0, "reason": "personal_data", "note": "..."}
+func (h *RedactionHandler) AddReportMark(c *gin.Context) {
+	h.addMark(c, redaction.TargetReport, c.Param("reportID"))
+}
+
+// GET /evidence/:evidence_id/redactions?withdrawn=true
+func (h *RedactionHandler) ListEvidenceMarks(c *gin.Context) {
+	h.listMarks(c, redaction.TargetEvidence, c.Param("evidence_id"))
+}
+
+// POST /evidence/:evidence_id/redactions
+// Body: {"page": 1, "x": 0.1, "y": 0.2, "w": 0.3, "h": 0.05, "reason": "third_party", "note": "..."}
+func (h *RedactionHandler) AddEvidenceMark(c *gin.Context) {
+	h.addMark(c, redaction.TargetEvidence, c.Param("evidence_id"))
+}
+
+func (h *RedactionHandler) listMarks(c *gin.Context, targetType, targetID string) {
+	if classificationBlocked(c, h.policy, h.auditLogger, "redaction", abac.ActionView, targetType, targetID) {
+		return
+	}
+	withdrawn, _ := strconv.ParseBool(c.Query("withdrawn"))
+	marks, err := h.redactions.ListMarks(c.Request.Context(), redactionActor(c), targetType, targetID, withdrawn)
+	if err != nil {
+		writeRedactionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, marks)
+}
+
+func (h *RedactionHandler) addMark(c *gin.Context, targetType, targetID string) {
+	if classificationBlocked(c, h.policy, h.auditLogger, "redaction", abac.ActionView, targetType, targetID) {
+		return
+	}
+	var in redaction.MarkInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	target := auditlog.Target{Type: targetType, ID: targetID}
+	m, err := h.redactions.AddMark(c.Request.Context(), redactionActor(c), targetType, targetID, in)
+	if err != nil {
+		h.audit(c, "ADD_REDACTION_MARK", target, "FAILED", err.Error())
+		writeRedactionError(c, err)
+		return
+	}
+	h.audit(c, "ADD_REDACTION_MARK", target, "SUCCESS",
+		fmt.Sprintf("Redaction mark %s added (%s, reason %s)", m.ID, m.Kind, m.Reason))
+	c.JSON(http.StatusCreated, m)
+}
+
+// DELETE /redactions/:markID
+// Marks are withdrawn rather than deleted so the record of what was
+// redacted, and why, survives.
+func (h *RedactionHandler) WithdrawMark(c *gin.Context) {
+	markID := c.Param("markID")
+	target := auditlog.Target{Type: "redaction_mark", ID: markID}
+	m, err := h.redactions.WithdrawMark(c.Request.Context(), redactionActor(c), markID)
+	if err != nil {
+		h.audit(c, "WITHDRAW_REDACTION_MARK", target, "FAILED", err.Error())
+		writeRedactionError(c, err)
+		return
+	}
+	h.audit(c, "WITHDRAW_REDACTION_MARK", target, "SUCCESS",
+		fmt.Sprintf("Redaction mark on %s %s withdrawn", m.TargetType, m.TargetID))
+	c.JSON(http.StatusOK, m)
+}
+
+// POST /evidence/:evidence_id/redacted-copies?redaction_profile=<id|name|all>
+// Burns the evidence's region marks into a new evidence item of the same
+// case.
+func (h *RedactionHandler) DeriveEvidence(c *gin.Context) {
+	evidenceID := c.Param("evidence_id")
+	target := auditlog.Target{Type: "evidence", ID: evidenceID}
+	if classificationBlocked(c, h.policy, h.auditLogger, "redaction", abac.ActionView, abac.ResourceEvidence, evidenceID) {
+		return
+	}
+	d, derived, err := h.redactions.DeriveEvidence(c.Request.Context(), redactionActor(c), evidenceID, c.Query("redaction_profile"))
+	if err != nil {
+		h.audit(c, "CREATE_REDACTED_EVIDENCE", target, "FAILED", err.Error())
+		writeRedactionError(c, err)
+		return
+	}
+	if err := h.inheritLabel(c, evidenceID, derived.ID.String()); err != nil {
+		h.audit(c, "CREATE_REDACTED_EVIDENCE", target, "FAILED", "Labelling the redacted copy: "+err.Error())
+		writeError(c, http.StatusInternalServerError, "internal_error", "Failed to label the redacted copy")
+		return
+	}
+	h.audit(c, "CREATE_REDACTED_EVIDENCE", target, "SUCCESS",
+		fmt.Sprintf("Redacted copy %s created (sha256 %s)", derived.ID, d.SHA256))
+	c.JSON(http.StatusCreated, gin.H{"derivative": d, "evidence": derived})
+}
+
+// GET /evidence/:evidence_id/redacted-copies
+func (h *RedactionHandler) ListEvidenceDerivatives(c *gin.Context) {
+	h.listDerivatives(c, redaction.TargetEvidence, c.Param("evidence_id"))
+}
+
+// GET /reports/:reportID/redacted-exports
+func (h *RedactionHandler) ListReportDerivatives(c *gin.Context) {
+	h.listDerivatives(c, redaction.TargetReport, c.Param("reportID"))
+}
+
+// inheritLabel gives a redacted copy the classification of its source;
+// its content no longer matches the source's, so nothing else would hold
+// it to that label.
+func (h *RedactionHandler) inheritLabel(c *gin.Context, sourceID, copyID string) error {
+	if h.policy == nil {
+		return nil
+	}
+	tenantID := c.GetString("tenantID")
+	l, err := h.policy.GetLabel(tenantID, abac.ResourceEvidence, sourceID)
+	if err != nil || l == nil {
+		return err
+	}
+	in := abac.LabelInput{Level: l.Level, Reason: "Redacted copy of " + sourceID}
+	_ = json.Unmarshal(l.Groups, &in.Groups)
+	_ = json.Unmarshal(l.Withheld, &in.Withheld)
+	_, err = h.policy.SetLabel(abac.Actor{UserID: c.GetString("userID"), TenantID: tenantID}, abac.ResourceEvidence, copyID, in)
+	return err
+}
+
+func (h *RedactionHandler) listDerivatives(c *gin.Context, sourceType, sourceID string) {
+	if classificationBlocked(c, h.policy, h.auditLogger, "redaction", abac.ActionView, sourceType, sourceID) {
+		return
+	}
+	out, err := h.redactions.ListDerivatives(c.Request.Context(), redactionActor(c), sourceType, sourceID)
+	if err != nil {
+		writeRedactionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// GET /redaction-profiles
+func (h *RedactionHandler) ListProfiles(c *gin.Context) {
+	out, err := h.redactions.ListProfiles(redactionActor(c))
+	if err != nil {
+		writeRedactionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// POST /redaction-profiles
+// Body: {"name": "Press", "audience": "...", "reasons": ["personal_data"], "roles": ["External Collaborator"]}
+func (h *RedactionHandler) CreateProfile(c *gin.Context) {
+	var in redaction.ProfileInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	p, err := h.redactions.CreateProfile(redactionActor(c), in)
+	if err != nil {
+		h.audit(c, "CREATE_REDACTION_PROFILE", auditlog.Target{Type: "redaction_profile"}, "FAILED", err.Error())
+		writeRedactionError(c, err)
+		return
+	}
+	h.audit(c, "CREATE_REDACTION_PROFILE", auditlog.Target{Type: "redaction_profile", ID: p.ID}, "SUCCESS",
+		"Redaction profile "+p.Name+" created")
+	c.JSON(http.StatusCreated, p)
+}
+
+// PUT /redaction-profiles/:profileID
+func (h *RedactionHandler) UpdateProfile(c *gin.Context) {
+	var in redaction.ProfileInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	target := auditlog.Target{Type: "redaction_profile", ID: c.Param("profileID")}
+	p, err := h.redactions.UpdateProfile(redactionActor(c), target.ID, in)
+	if err != nil {
+		h.audit(c, "UPDATE_REDACTION_PROFILE", target, "FAILED", err.Error())
+		writeRedactionError(c, err)
+		return
+	}
+	h.audit(c, "UPDATE_REDACTION_PROFILE", target, "SUCCESS", "Redaction profile "+p.Name+" updated")
+	c.JSON(http.StatusOK, p)
+}
+
+// DELETE /redaction-profiles/:profileID
+func (h *RedactionHandler) DeleteProfile(c *gin.Context) {
+	target := auditlog.Target{Type: "redaction_profile", ID: c.Param("profileID")}
+	if err := h.redactions.DeleteProfile(redactionActor(c), target.ID); err != nil {
+		h.audit(c, "DELETE_REDACTION_PROFILE", target, "FAILED", err.Error())
+		writeRedactionError(c, err)
+		return
+	}
+	h.audit(c, "DELETE_REDACTION_PROFILE", target, "SUCCESS", "Redaction profile deleted")
+	c.Status(http.StatusNoContent)
+}
+
+// ─── Redacted report exports ─────────────────────────────────────────
+
+// exportRenderer returns the merge field renderer for exporting a report
+// and the redaction plan it applies: the profile named by the
+// redaction_profile query parameter together with any bound to the
+// user's role. The plan is nil when nothing is redacted. On a bad profile
+// it writes the error response and returns false.
+func (h *ReportHandler) exportRenderer(c *gin.Context, reportID uuid.UUID) (report.FieldRenderer, *redaction.Plan, bool) {
+	if h.Redactions == nil {
+		return h.Fields, nil, true
+	}
+	plan, err := h.Redactions.PlanFor(c.Request.Context(), redactionActor(c), redaction.TargetReport, reportID.String(), c.Query("redaction_profile"))
+	if err != nil {
+		writeRedactionError(c, err)
+		return nil, nil, false
+	}
+	if plan == nil {
+		return h.Fields, nil, true
+	}
+	return plan.Renderer(h.Fields), plan, true
+}
+
+// recordRedactedExport records a redacted export's hash and returns the
+// note added to its audit entry.
+func (h *ReportHandler) recordRedactedExport(c *gin.Context, reportID uuid.UUID, plan *redaction.Plan, format string, data []byte) string {
+	if plan == nil {
+		return ""
+	}
+	d, err := h.Redactions.RecordReportExport(redactionActor(c), reportID.String(), plan, format, data)
+	if err != nil {
+		logWithCtx("error", "recording redacted export failed", c, map[string]any{"reportID": reportID.String(), "err": err.Error()})
+		return fmt.Sprintf(" with %d redactions", len(plan.Marks))
+	}
+	c.Header("X-Redaction-Derivative-ID", d.ID)
+	c.Header("X-Content-SHA256", d.SHA256)
+	return fmt.Sprintf(" with %d redactions (derivative %s)", len(plan.Marks), d.ID)
+}
@@ -44,6 +44,12 @@ type AuthServiceInterface interface {
 	ResetPasswordHandler(c *gin.Context)
 	LogoutHandler(c *gin.Context)
 	ChangePasswordHandler(c *gin.Context)
+	RefreshHandler(c *gin.Context)
+	LogoutAllHandler(c *gin.Context)
+	ListSessionsHandler(c *gin.Context)
+	RevokeSessionHandler(c *gin.Context)
+	ListUserSessionsHandler(c *gin.Context)
+	RevokeUserSessionsHandler(c *gin.Context)
 }
 
 // type CaseServiceInterface interface {
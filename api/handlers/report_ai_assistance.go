@@ -2,12 +2,16 @@ package handlers
 
 import (
 	reportshared "aegis-api/services_/report/shared"
+	"context"
+	"errors"
 	"net/http"
 
 	"aegis-api/services_/report"
 	"aegis-api/services_/report/report_ai_assistance"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // SuggestSectionPOST godoc
@@ -50,7 +54,7 @@ func (h *ReportAIHandler) SuggestSectionPOST(c *gin.Context) {
 	}
 
 	// Pass contextPayload to the AI service (update service to accept context if needed)
-	suggestion, err := h.Service.GenerateSectionSuggestion(c.Request.Context(), reportIDStr, sectionIDStr)
+	suggestion, err := h.Service.GenerateSectionSuggestion(aiContext(c), reportIDStr, sectionIDStr)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -106,7 +110,7 @@ func (h *ReportAIHandler) SuggestSection(c *gin.Context) {
 		}
 	}
 
-	suggestion, err := h.Service.GenerateSectionSuggestion(c.Request.Context(), reportIDStr, sectionIDStr)
+	suggestion, err := h.Service.GenerateSectionSuggestion(aiContext(c), reportIDStr, sectionIDStr)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -134,7 +138,7 @@ func (h *ReportAIHandler) SubmitFeedback(c *gin.Context) {
 		return
 	}
 
-	if err := h.Service.SaveFeedback(c.Request.Context(), feedback); err != nil {
+	if err := h.Service.SaveFeedback(aiContext(c), feedback); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -179,7 +183,7 @@ func (h *ReportAIHandler) GenerateReferences(c *gin.Context) {
 		CreatedAt: reportObj.CreatedAt,
 		// Add other fields as needed
 	}
-	refs, err := h.Service.GenerateSectionReferences(c.Request.Context(), sectionIDStr, reportShared)
+	refs, err := h.Service.GenerateSectionReferences(aiContext(c), sectionIDStr, reportShared)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -187,3 +191,77 @@ func (h *ReportAIHandler) GenerateReferences(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"references": refs})
 }
+
+// GetSuggestion godoc
+// @Summary Get an AI suggestion with its claims
+// @Description Returns the suggestion, every drafted claim, its supporting references and any validation issue
+// @Tags reports, ai
+// @Produce json
+// @Param suggestionID path string true "Suggestion ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /reports/ai/suggestions/{suggestionID} [get]
+func (h *ReportAIHandler) GetSuggestion(c *gin.Context) {
+	suggestion, err := h.Service.GetSuggestion(c.Request.Context(), c.Param("suggestionID"), c.GetString("tenantID"))
+	if err != nil {
+		writeSuggestionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"suggestion": suggestion})
+}
+
+// AcceptSuggestion godoc
+// @Summary Accept an AI suggestion into its section
+// @Description Appends the grounded claims to the section, marked as machine-drafted, and records their provenance and references
+// @Tags reports, ai
+// @Produce json
+// @Param suggestionID path string true "Suggestion ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /reports/ai/suggestions/{suggestionID}/accept [post]
+func (h *ReportAIHandler) AcceptSuggestion(c *gin.Context) {
+	tenantID, userID := c.GetString("tenantID"), c.GetString("userID")
+	pending, err := h.Service.GetSuggestion(c.Request.Context(), c.Param("suggestionID"), tenantID)
+	if err != nil {
+		writeSuggestionError(c, err)
+		return
+	}
+	reportID, err := uuid.Parse(pending.ReportID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "suggestion has an invalid report ID"})
+		return
+	}
+	sectionID, err := primitive.ObjectIDFromHex(pending.SectionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "suggestion has an invalid section ID"})
+		return
+	}
+
+	// Accepting appends to the section, so it is recorded as a revision.
+	var suggestion *report_ai_assistance.AISuggestion
+	err = h.ReportService.RecordSectionEdit(editorContext(c), reportID, sectionID, report.RevisionAIAccepted,
+		func(ctx context.Context) error {
+			var err error
+			suggestion, err = h.Service.AcceptSuggestion(ctx, pending.ID, tenantID, userID)
+			return err
+		})
+	if err != nil {
+		writeSuggestionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"suggestion": suggestion})
+}
+
+func writeSuggestionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, report_ai_assistance.ErrSuggestionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, report_ai_assistance.ErrSuggestionNotAcceptable),
+		errors.Is(err, report_ai_assistance.ErrSectionChanged),
+		errors.Is(err, report.ErrReportLocked):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
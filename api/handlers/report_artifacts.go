@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"aegis-api/services_/auditlog"
+	"aegis-api/services_/auth/abac"
+	"aegis-api/services_/report"
+	"aegis-api/services_/report/signing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ReportArtifactHandler serves sealed report PDFs, their signature bundles
+// and the tenant keys that verify them.
+type ReportArtifactHandler struct {
+	artifacts   signing.Service
+	policy      abac.Service // nil: reports are unclassified
+	auditLogger *auditlog.AuditLogger
+}
+
+func NewReportArtifactHandler(artifacts signing.Service, policy abac.Service, auditLogger *auditlog.AuditLogger) *ReportArtifactHandler {
+	return &ReportArtifactHandler{artifacts: artifacts, policy: policy, auditLogger: auditLogger}
+}
+
+func signingActor(c *gin.Context) signing.Actor {
+	return signing.Actor{UserID: c.GetString("userID"), TenantID: c.GetString("tenantID")}
+}
+
+func (h *ReportArtifactHandler) audit(c *gin.Context, action string, target auditlog.Target, status, description string) {
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action:      action,
+		Actor:       auditlog.MakeActor(c),
+		Target:      target,
+		Service:     "report",
+		Status:      status,
+		Description: description,
+	})
+}
+
+func writeArtifact(c *gin.Context, a *signing.Artifact) {
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=report_%s_v%d.pdf", a.ReportID, a.Version))
+	c.Header("X-Content-SHA256", a.SHA256)
+	c.Header("X-Report-Artifact-ID", a.ID)
+	c.Data(http.StatusOK, "application/pdf", a.PDF)
+}
+
+// POST /reports/:reportID/artifact
+// Seals the published version; sealing again returns the stored artifact.
+func (h *ReportArtifactHandler) SealReport(c *gin.Context) {
+	reportID, ok := reportIDParam(c)
+	if !ok {
+		return
+	}
+	target := auditlog.Target{Type: "report", ID: reportID.String()}
+	a, err := h.artifacts.Seal(c.Request.Context(), signingActor(c), reportID)
+	if err != nil {
+		h.audit(c, "SEAL_REPORT", target, "FAILED", err.Error())
+		writeSigningError(c, err)
+		return
+	}
+	h.audit(c, "SEAL_REPORT", target, "SUCCESS",
+		fmt.Sprintf("Report version %d sealed (sha256 %s)", a.Version, a.SHA256))
+	c.JSON(http.StatusOK, a)
+}
+
+// GET /reports/:reportID/artifact
+func (h *ReportArtifactHandler) DownloadArtifact(c *gin.Context) {
+	reportID, ok := reportIDParam(c)
+	if !ok {
+		return
+	}
+	if classificationBlocked(c, h.policy, h.auditLogger, "report", abac.ActionExport, abac.ResourceReport, reportID.String()) {
+		return
+	}
+	if watermarkRequired(c, "sealed PDF") {
+		return
+	}
+	target := auditlog.Target{Type: "report", ID: reportID.String()}
+	a, err := h.artifacts.Artifact(c.Request.Context(), signingActor(c), reportID)
+	if err != nil {
+		h.audit(c, "DOWNLOAD_REPORT_ARTIFACT", target, "FAILED", err.Error())
+		writeSigningError(c, err)
+		return
+	}
+	h.audit(c, "DOWNLOAD_REPORT_ARTIFACT", target, "SUCCESS", "Sealed report PDF downloaded (sha256 "+a.SHA256+")")
+	writeArtifact(c, a)
+}
+
+// GET /reports/:reportID/artifact/signature
+// Returns the detached signature bundle for offline verification.
+func (h *ReportArtifactHandler) DownloadSignature(c *gin.Context) {
+	reportID, ok := reportIDParam(c)
+	if !ok {
+		return
+	}
+	a, err := h.artifacts.Artifact(c.Request.Context(), signingActor(c), reportID)
+	if err != nil {
+		writeSigningError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=report_%s_v%d.sig.json", a.ReportID, a.Version))
+	c.Data(http.StatusOK, "application/json", a.Bundle)
+}
+
+// POST /reports/verify (multipart "file")
+// Tells any user of the tenant whether an uploaded PDF is a sealed report.
+func (h *ReportArtifactHandler) VerifyPDF(c *gin.Context) {
+	target := auditlog.Target{Type: "report_artifact"}
+	fh, err := c.FormFile("file")
+	if err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", "a PDF must be uploaded in the \"file\" field")
+		return
+	}
+	if fh.Size > signing.MaxVerifySize {
+		writeSigningError(c, signing.ErrFileTooLarge)
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	defer f.Close()
+
+	res, err := h.artifacts.Verify(c.Request.Context(), c.GetString("tenantID"), f)
+	if err != nil {
+		h.audit(c, "VERIFY_REPORT_PDF", target, "FAILED", err.Error())
+		writeSigningError(c, err)
+		return
+	}
+	target.ID = res.SHA256
+	desc := "Uploaded PDF does not match a sealed report"
+	if res.Match {
+		desc = fmt.Sprintf("Uploaded PDF matches report %s version %d", res.Bundle.ReportID, res.Bundle.Version)
+	}
+	h.audit(c, "VERIFY_REPORT_PDF", target, "SUCCESS", desc)
+	c.JSON(http.StatusOK, res)
+}
+
+// GET /report-signing-keys
+func (h *ReportArtifactHandler) ListKeys(c *gin.Context) {
+	keys, err := h.artifacts.PublicKeys(c.GetString("tenantID"))
+	if err != nil {
+		writeSigningError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+// POST /report-signing-keys/rotate
+// Earlier artifacts stay verifiable with the retired key.
+func (h *ReportArtifactHandler) RotateKey(c *gin.Context) {
+	tenantID := c.GetString("tenantID")
+	target := auditlog.Target{Type: "report_signing_key", ID: tenantID}
+	key, err := h.artifacts.RotateKey(tenantID, c.GetString("userID"))
+	if err != nil {
+		h.audit(c, "ROTATE_REPORT_SIGNING_KEY", target, "FAILED", err.Error())
+		writeSigningError(c, err)
+		return
+	}
+	target.ID = key.ID
+	h.audit(c, "ROTATE_REPORT_SIGNING_KEY", target, "SUCCESS", "Report signing key rotated; fingerprint "+key.Fingerprint)
+	c.JSON(http.StatusCreated, key)
+}
+
+// serveSealedPDF answers a PDF download from the sealed artifact when the
+// report is published. It returns false when the report should be
+// rendered live instead: drafts, and reports published before sealing
+// was configured.
+func (h *ReportHandler) serveSealedPDF(c *gin.Context, actor auditlog.Actor, reportID uuid.UUID) bool {
+	target := auditlog.Target{Type: "report", ID: reportID.String()}
+	a, err := h.Artifacts.Seal(c.Request.Context(), signingActor(c), reportID)
+	switch {
+	case err == nil:
+		h.auditLogger.Log(c, auditlog.AuditLog{
+			Action: "DOWNLOAD_REPORT_PDF", Actor: actor, Target: target, Service: "report",
+			Status: "SUCCESS", Description: "Sealed report PDF downloaded (sha256 " + a.SHA256 + ")",
+		})
+		writeArtifact(c, a)
+		return true
+	case errors.Is(err, signing.ErrNotPublished),
+		errors.Is(err, signing.ErrSigningDisabled),
+		errors.Is(err, report.ErrSnapshotNotFound),
+		errors.Is(err, report.ErrRevisionsDisabled):
+		return false
+	default:
+		h.auditLogger.Log(c, auditlog.AuditLog{
+			Action: "DOWNLOAD_REPORT_PDF", Actor: actor, Target: target, Service: "report",
+			Status: "FAILED", Description: "Failed to seal report PDF: " + err.Error(),
+		})
+		writeSigningError(c, err)
+		return true
+	}
+}
+
+func writeSigningError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, signing.ErrReportNotFound):
+		writeError(c, http.StatusNotFound, "report_not_found", err.Error())
+	case errors.Is(err, signing.ErrArtifactNotFound),
+		errors.Is(err, report.ErrSnapshotNotFound):
+		writeError(c, http.StatusNotFound, "artifact_not_found", err.Error())
+	case errors.Is(err, signing.ErrNotPublished):
+		writeError(c, http.StatusConflict, "report_not_published", err.Error())
+	case errors.Is(err, signing.ErrFileTooLarge):
+		writeError(c, http.StatusRequestEntityTooLarge, "file_too_large", err.Error())
+	case errors.Is(err, signing.ErrSigningDisabled),
+		errors.Is(err, report.ErrRevisionsDisabled):
+		writeError(c, http.StatusServiceUnavailable, "signing_disabled", err.Error())
+	default:
+		writeError(c, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+}
@@ -25,7 +25,7 @@ func (h *ReportAIHandler) EnhanceSummary(c *gin.Context) {
 	}
 
 	// Call AI client to enhance summary with context
-	result, err := h.Service.EnhanceSummary(c.Request.Context(), payload)
+	result, err := h.Service.EnhanceSummary(aiContext(c), payload)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"aegis-api/services_/auditlog"
+	"aegis-api/services_/auth/abac"
+	"aegis-api/services_/report"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	docxContentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	odtContentType  = "application/vnd.oasis.opendocument.text"
+
+	maxWatermarkLen = 80
+)
+
+type editableExport func(ctx context.Context, reportID uuid.UUID, fields report.FieldRenderer, opts report.ExportOptions) ([]byte, error)
+
+// GET /reports/:reportID/download/docx?watermark=true&redaction_profile=<id|name|all>
+func (h *ReportHandler) DownloadReportDOCX(c *gin.Context) {
+	h.downloadEditable(c, "DOCX", "docx", docxContentType, h.ReportService.DownloadReportAsDOCX)
+}
+
+// GET /reports/:reportID/download/odt?watermark=true&redaction_profile=<id|name|all>
+func (h *ReportHandler) DownloadReportODT(c *gin.Context) {
+	h.downloadEditable(c, "ODT", "odt", odtContentType, h.ReportService.DownloadReportAsODT)
+}
+
+// downloadEditable serves an editable export. The watermark query
+// parameter is "true" for the default marking or the text to print.
+func (h *ReportHandler) downloadEditable(c *gin.Context, format, ext, contentType string, export editableExport) {
+	action := "DOWNLOAD_REPORT_" + format
+	reportID, ok := reportIDParam(c)
+	if !ok {
+		return
+	}
+	if classificationBlocked(c, h.Classification, h.auditLogger, "report", abac.ActionExport, abac.ResourceReport, reportID.String()) {
+		return
+	}
+	audit := func(status, description string) {
+		h.auditLogger.Log(c, auditlog.AuditLog{
+			Action:      action,
+			Actor:       auditlog.MakeActor(c),
+			Target:      auditlog.Target{Type: "report", ID: reportID.String()},
+			Service:     "report",
+			Status:      status,
+			Description: description,
+		})
+	}
+
+	var opts report.ExportOptions
+	if wm := strings.TrimSpace(c.Query("watermark")); wm != "" {
+		if on, err := strconv.ParseBool(wm); err == nil {
+			if on {
+				opts.Watermark = report.DefaultExportWatermark
+			}
+		} else if utf8.RuneCountInString(wm) > maxWatermarkLen {
+			writeError(c, http.StatusBadRequest, "invalid_watermark", "watermark must be at most 80 characters")
+			return
+		} else {
+			opts.Watermark = wm
+		}
+	}
+	// An invitation's watermark replaces any the caller asked for.
+	if wm := c.GetString("watermark"); wm != "" {
+		opts.Watermark = wm
+	}
+
+	rpt, err := h.ReportService.GetReportByID(c.Request.Context(), reportID.String())
+	if err != nil || rpt == nil || rpt.TenantID.String() != c.GetString("tenantID") {
+		writeError(c, http.StatusNotFound, "report_not_found", "report not found")
+		return
+	}
+
+	fields, plan, ok := h.exportRenderer(c, reportID)
+	if !ok {
+		return
+	}
+
+	data, err := export(c.Request.Context(), reportID, fields, opts)
+	if err != nil {
+		logWithCtx("error", "editable export failed", c, map[string]any{"reportID": reportID.String(), "format": ext, "err": err.Error()})
+		audit("FAILED", "Failed to generate "+format+": "+err.Error())
+		writeError(c, http.StatusInternalServerError, "export_failed", "failed to generate "+format)
+		return
+	}
+	description := "Report " + format + " downloaded"
+	if opts.Watermark != "" {
+		description += " with watermark"
+	}
+	audit("SUCCESS", description+h.recordRedactedExport(c, reportID, plan, ext, data))
+
+	c.Header("Content-Disposition", "attachment; filename=report_"+reportID.String()+"."+ext)
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, contentType, data)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"aegis-api/services_/auditlog"
+	"aegis-api/services_/report"
+	"aegis-api/services_/report/merge_fields"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GET /reports/fields
+// Lists the merge fields section content may use.
+func (h *ReportHandler) ListMergeFields(c *gin.Context) {
+	c.JSON(http.StatusOK, merge_fields.Catalog())
+}
+
+// GET /reports/:reportID/rendered
+// Returns the report with merge fields expanded, as it would be exported.
+func (h *ReportHandler) GetRenderedReport(c *gin.Context) {
+	reportID, ok := h.reportInTenant(c)
+	if !ok {
+		return
+	}
+	rpt, err := h.ReportService.RenderReport(c.Request.Context(), reportID, h.Fields)
+	if err != nil {
+		writeError(c, http.StatusInternalServerError, "render_failed", "failed to render report")
+		return
+	}
+	c.JSON(http.StatusOK, rpt)
+}
+
+// POST /reports/:reportID/fields/freeze
+// Captures the current value of every merge field so the report no longer
+// changes as the case does.
+func (h *ReportHandler) FreezeReportFields(c *gin.Context) {
+	reportID, ok := h.reportInTenant(c)
+	if !ok {
+		return
+	}
+	rpt, err := h.ReportService.FreezeFields(c.Request.Context(), reportID, h.Fields)
+	if err != nil {
+		h.reportAudit(c, "FREEZE_REPORT_FIELDS", reportID, "FAILED", "Freezing merge fields failed: "+err.Error())
+		if errors.Is(err, report.ErrUnresolvedFields) {
+			writeError(c, http.StatusUnprocessableEntity, "unresolved_fields", err.Error())
+			return
+		}
+		if errors.Is(err, report.ErrReportLocked) {
+			writeError(c, http.StatusConflict, "report_locked", err.Error())
+			return
+		}
+		writeError(c, http.StatusInternalServerError, "freeze_failed", "failed to freeze merge fields")
+		return
+	}
+	h.reportAudit(c, "FREEZE_REPORT_FIELDS", reportID, "SUCCESS", "Merge field values frozen")
+	c.JSON(http.StatusOK, rpt)
+}
+
+// DELETE /reports/:reportID/fields/freeze
+func (h *ReportHandler) ThawReportFields(c *gin.Context) {
+	reportID, ok := h.reportInTenant(c)
+	if !ok {
+		return
+	}
+	if err := h.ReportService.ThawFields(c.Request.Context(), reportID); err != nil {
+		h.reportAudit(c, "THAW_REPORT_FIELDS", reportID, "FAILED", "Unfreezing merge fields failed: "+err.Error())
+		if errors.Is(err, report.ErrReportLocked) {
+			writeError(c, http.StatusConflict, "report_locked", err.Error())
+			return
+		}
+		writeError(c, http.StatusInternalServerError, "thaw_failed", "failed to unfreeze merge fields")
+		return
+	}
+	h.reportAudit(c, "THAW_REPORT_FIELDS", reportID, "SUCCESS", "Merge fields follow live case data again")
+	c.JSON(http.StatusOK, gin.H{"message": "merge fields unfrozen"})
+}
+
+// reportInTenant parses :reportID and checks the report belongs to the
+// caller's tenant, writing the error response itself when it does not.
+func (h *ReportHandler) reportInTenant(c *gin.Context) (uuid.UUID, bool) {
+	reportID, err := uuid.Parse(c.Param("reportID"))
+	if err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_report_id", "invalid report ID")
+		return uuid.Nil, false
+	}
+	rpt, err := h.ReportService.GetReportByID(c.Request.Context(), reportID.String())
+	if err != nil || rpt == nil || rpt.TenantID.String() != c.GetString("tenantID") {
+		writeError(c, http.StatusNotFound, "report_not_found", "report not found")
+		return uuid.Nil, false
+	}
+	return reportID, true
+}
+
+func (h *ReportHandler) reportAudit(c *gin.Context, action string, reportID uuid.UUID, status, description string) {
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action:      action,
+		Actor:       auditlog.MakeActor(c),
+		Target:      auditlog.Target{Type: "report", ID: reportID.String()},
+		Service:     "report",
+		Status:      status,
+		Description: description,
+	})
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"aegis-api/middleware"
 	"aegis-api/services_/auditlog"
+	"aegis-api/services_/auth/abac"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,7 +15,10 @@ import (
 
 	graphicalmapping "aegis-api/services_/GraphicalMapping"
 	"aegis-api/services_/evidence/metadata"
+	"aegis-api/services_/redaction"
 	"aegis-api/services_/report"
+	"aegis-api/services_/report/merge_fields"
+	"aegis-api/services_/report/signing"
 	"aegis-api/services_/timeline"
 
 	// removed duplicate import
@@ -250,7 +255,19 @@ type ReportHandler struct {
 	IOCService interface {
 		ListIOCsByCase(caseID string) ([]*graphicalmapping.IOC, error)
 	}
-	auditLogger *auditlog.AuditLogger
+	// Fields expands merge fields from the services above; nil leaves
+	// section content as stored.
+	Fields report.FieldRenderer
+	// Artifacts serves published reports from their sealed PDF; nil renders
+	// every download live.
+	Artifacts signing.Service
+	// Redactions applies redaction profiles to exports; nil exports
+	// reports unredacted.
+	Redactions redaction.Service
+	// Classification withholds exports of labelled reports; nil exports
+	// every report.
+	Classification abac.Service
+	auditLogger    *auditlog.AuditLogger
 }
 
 // GetReportByID returns a report by its ID
@@ -365,6 +382,7 @@ func NewReportHandlerWithDeps(
 	iocService interface {
 		ListIOCsByCase(caseID string) ([]*graphicalmapping.IOC, error)
 	},
+	custodyService merge_fields.CustodyLookup,
 	auditLogger *auditlog.AuditLogger,
 ) *ReportHandler {
 	return &ReportHandler{
@@ -373,6 +391,7 @@ func NewReportHandlerWithDeps(
 		TimelineService: timelineService,
 		CaseService:     caseService,
 		IOCService:      iocService,
+		Fields:          merge_fields.NewResolver(evidenceService, timelineService, caseService, iocService, custodyService),
 		auditLogger:     auditLogger,
 	}
 }
@@ -544,9 +563,17 @@ func (h *ReportHandler) GenerateReport(c *gin.Context) {
 		return
 	}
 
-	// 2) Otherwise create it
+	// 2) Otherwise create it from the requested template, or the tenant's
+	// default for the case type when none is given
+	templateID := uuid.Nil
+	if raw := c.Query("template_id"); raw != "" {
+		if templateID, err = uuid.Parse(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template_id"})
+			return
+		}
+	}
 	claims := MustClaims(c)
-	rep, genErr := h.ReportService.GenerateReport(ctx, caseID, claims.UserID, claims.TenantID, claims.TeamID)
+	rep, genErr := h.ReportService.GenerateReport(ctx, caseID, claims.UserID, claims.TenantID, claims.TeamID, templateID, c.Query("case_type"))
 	if genErr != nil {
 		// If a parallel request created it, surface the existing one
 		if IsUniqueViolation(genErr) {
@@ -583,6 +610,10 @@ func (h *ReportHandler) GenerateReport(c *gin.Context) {
 			Description: "Failed to generate report: " + genErr.Error(),
 		})
 
+		if errors.Is(genErr, report.ErrTemplateNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": genErr.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate report"})
 		return
 	}
@@ -798,8 +829,12 @@ func (h *ReportHandler) UpdateSectionContent(c *gin.Context) {
 		return
 	}
 
-	if err := h.ReportService.UpdateCustomSectionContent(c.Request.Context(), reportUUID, sectionID, req.Content); err != nil {
+	if err := h.ReportService.UpdateCustomSectionContent(editorContext(c), reportUUID, sectionID, req.Content); err != nil {
 		switch {
+		case errors.Is(err, report.ErrReportLocked):
+			h.reportAudit(c, "UPDATE_SECTION_CONTENT", reportUUID, "FAILED", err.Error())
+			writeError(c, http.StatusConflict, "report_locked", err.Error())
+			return
 		case errors.Is(err, report.ErrReportNotFound), errors.Is(err, report.ErrMongoReportNotFound):
 			logWithCtx("info", "report not found", c, map[string]any{"reportID": reportUUID.String(), "sectionID": sectionID.Hex(), "err": err.Error()})
 			fmt.Printf("[UpdateSectionContent] Report not found: %s\n", reportUUID.String())
@@ -951,8 +986,22 @@ func (h *ReportHandler) DownloadReportPDF(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid report ID"})
 		return
 	}
+	if classificationBlocked(c, h.Classification, h.auditLogger, "report", abac.ActionExport, abac.ResourceReport, reportID.String()) {
+		return
+	}
 
-	pdfBytes, err := h.ReportService.DownloadReportAsPDF(c.Request.Context(), reportID)
+	fields, plan, ok := h.exportRenderer(c, reportID)
+	if !ok {
+		return
+	}
+	// The sealed artifact is the unredacted record; redacted and
+	// watermarked copies are always rendered.
+	watermark := c.GetString("watermark")
+	if plan == nil && watermark == "" && h.Artifacts != nil && h.serveSealedPDF(c, actor, reportID) {
+		return
+	}
+
+	pdfBytes, err := h.ReportService.DownloadReportAsPDFWithOptions(c.Request.Context(), reportID, fields, report.ExportOptions{Watermark: watermark})
 	if err != nil {
 		fmt.Printf("[DownloadReportPDF] Failed to generate PDF: %v\n", err)
 
@@ -982,7 +1031,7 @@ func (h *ReportHandler) DownloadReportPDF(c *gin.Context) {
 		},
 		Service:     "report",
 		Status:      "SUCCESS",
-		Description: "Report PDF downloaded successfully",
+		Description: "Report PDF downloaded successfully" + watermarkNote(watermark) + h.recordRedactedExport(c, reportID, plan, "pdf", pdfBytes),
 	})
 
 	c.Header("Content-Disposition", "attachment; filename=report_"+reportIDStr+".pdf")
@@ -1082,8 +1131,19 @@ func (h *ReportHandler) DownloadReportJSON(c *gin.Context) {
 		writeError(c, http.StatusBadRequest, "invalid_report_id", "invalid report ID")
 		return
 	}
+	if classificationBlocked(c, h.Classification, h.auditLogger, "report", abac.ActionExport, abac.ResourceReport, reportID.String()) {
+		return
+	}
+	if watermarkRequired(c, "JSON") {
+		return
+	}
+
+	fields, plan, ok := h.exportRenderer(c, reportID)
+	if !ok {
+		return
+	}
 
-	jsonBytes, err := h.ReportService.DownloadReportAsJSON(c.Request.Context(), reportID)
+	jsonBytes, err := h.ReportService.DownloadReportAsJSON(c.Request.Context(), reportID, fields)
 	if err != nil {
 		logWithCtx("error", "download json failed", c, map[string]any{"reportID": reportIDStr, "err": err.Error()})
 		fmt.Printf("[DownloadReportJSON] Failed to generate JSON: %v\n", err)
@@ -1114,7 +1174,7 @@ func (h *ReportHandler) DownloadReportJSON(c *gin.Context) {
 		},
 		Service:     "report",
 		Status:      "SUCCESS",
-		Description: "Report JSON downloaded successfully",
+		Description: "Report JSON downloaded successfully" + h.recordRedactedExport(c, reportID, plan, "json", jsonBytes),
 	})
 
 	c.Header("Content-Type", "application/json")
@@ -1181,6 +1241,11 @@ func (h *ReportHandler) DeleteReport(c *gin.Context) {
 
 	if err := h.ReportService.DeleteReportByID(c.Request.Context(), reportID); err != nil {
 		// map known errors if you expose them from the repo/service
+		if errors.Is(err, report.ErrReportLocked) {
+			h.reportAudit(c, "DELETE_REPORT", reportID, "FAILED", err.Error())
+			writeError(c, http.StatusConflict, "report_locked", err.Error())
+			return
+		}
 		if errors.Is(err, report.ErrReportNotFound) {
 			fmt.Printf("[DeleteReport] Report not found: %s\n", reportIDStr)
 
@@ -1352,8 +1417,12 @@ func (h *ReportHandler) AddSection(c *gin.Context) {
 		return
 	}
 
-	if err := h.ReportService.AddCustomSection(c.Request.Context(), reportUUID, req.Title, req.Content, req.Order); err != nil {
+	if err := h.ReportService.AddCustomSection(editorContext(c), reportUUID, req.Title, req.Content, req.Order); err != nil {
 		switch {
+		case errors.Is(err, report.ErrReportLocked):
+			h.reportAudit(c, "ADD_SECTION", reportUUID, "FAILED", err.Error())
+			writeError(c, http.StatusConflict, "report_locked", err.Error())
+			return
 		case errors.Is(err, report.ErrReportNotFound), errors.Is(err, report.ErrMongoReportNotFound):
 			fmt.Printf("[AddSection] Report not found: %s\n", reportIDStr)
 
@@ -1505,7 +1574,7 @@ func (h *ReportHandler) DeleteSection(c *gin.Context) {
 		return
 	}
 
-	err = h.ReportService.DeleteCustomSection(c.Request.Context(), reportUUID, sectionID)
+	err = h.ReportService.DeleteCustomSection(editorContext(c), reportUUID, sectionID)
 	if err != nil {
 		fmt.Printf("[DeleteSection] Failed to delete section: %v\n", err)
 
@@ -1521,6 +1590,10 @@ func (h *ReportHandler) DeleteSection(c *gin.Context) {
 			Description: "Failed to delete section: " + err.Error(),
 		})
 
+		if errors.Is(err, report.ErrSectionRequired) || errors.Is(err, report.ErrReportLocked) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete section"})
 		return
 	}
@@ -1769,8 +1842,12 @@ func (h *ReportHandler) UpdateSectionTitle(c *gin.Context) {
 	}
 
 	// Service
-	if err := h.ReportService.UpdateSectionTitle(c.Request.Context(), reportUUID, sectionID, title); err != nil {
+	if err := h.ReportService.UpdateSectionTitle(editorContext(c), reportUUID, sectionID, title); err != nil {
 		switch {
+		case errors.Is(err, report.ErrReportLocked):
+			h.reportAudit(c, "UPDATE_SECTION_TITLE", reportUUID, "FAILED", err.Error())
+			writeError(c, http.StatusConflict, "report_locked", err.Error())
+			return
 		case errors.Is(err, report.ErrReportNotFound), errors.Is(err, report.ErrMongoReportNotFound):
 			fmt.Printf("[UpdateSectionTitle] Report not found: %s\n", reportIDStr)
 
@@ -1973,8 +2050,12 @@ func (h *ReportHandler) ReorderSection(c *gin.Context) {
 	}
 
 	// Service (rename here if your service method is ReorderCustomSection)
-	if err := h.ReportService.ReorderSection(c.Request.Context(), reportUUID, sectionID, req.NewOrder); err != nil {
+	if err := h.ReportService.ReorderSection(editorContext(c), reportUUID, sectionID, req.NewOrder); err != nil {
 		switch {
+		case errors.Is(err, report.ErrReportLocked):
+			h.reportAudit(c, "REORDER_SECTION", reportUUID, "FAILED", err.Error())
+			writeError(c, http.StatusConflict, "report_locked", err.Error())
+			return
 		case errors.Is(err, report.ErrReportNotFound), errors.Is(err, report.ErrMongoReportNotFound):
 			fmt.Printf("[ReorderSection] Report not found: %s\n", reportIDStr)
 
@@ -2271,6 +2352,9 @@ func (h *ReportHandler) GetRecentReports(c *gin.Context) {
 	}
 
 	items, err := h.ReportService.ListRecentReports(c.Request.Context(), opts)
+	if err == nil {
+		items, err = visibleReports(c, items, func(r report.RecentReport) uuid.UUID { return r.CaseID })
+	}
 	if err != nil {
 		logWithCtx("error", "list recent reports failed", c, map[string]any{"err": err.Error()})
 		fmt.Printf("[GetRecentReports] Failed to load recent reports: %v\n", err)
@@ -2404,6 +2488,10 @@ func (h *ReportHandler) UpdateReportName(c *gin.Context) {
 	updated, err := h.ReportService.UpdateReportName(c.Request.Context(), rid, req.Name)
 	if err != nil {
 		switch {
+		case errors.Is(err, report.ErrReportLocked):
+			h.reportAudit(c, "UPDATE_REPORT_NAME", rid, "FAILED", err.Error())
+			writeError(c, http.StatusConflict, "report_locked", err.Error())
+			return
 		case errors.Is(err, report.ErrInvalidReportName):
 			fmt.Printf("[UpdateReportName] Invalid report name: %v\n", err)
 
@@ -2472,6 +2560,28 @@ func (h *ReportHandler) UpdateReportName(c *gin.Context) {
 	c.JSON(http.StatusOK, updated) // full Report JSON
 }
 
+// visibleReports keeps the reports on cases the caller can view. Listings
+// span many cases, so membership is checked per case rather than per route.
+func visibleReports[T any](c *gin.Context, reports []T, caseOf func(T) uuid.UUID) ([]T, error) {
+	seen := map[uuid.UUID]bool{}
+	visible := reports[:0]
+	for _, r := range reports {
+		caseID := caseOf(r)
+		ok, checked := seen[caseID]
+		if !checked {
+			var err error
+			if ok, err = middleware.CanAccessCase(c, "case:view", caseID.String()); err != nil {
+				return nil, err
+			}
+			seen[caseID] = ok
+		}
+		if ok {
+			visible = append(visible, r)
+		}
+	}
+	return visible, nil
+}
+
 func (h *ReportHandler) GetReportsForTeam(c *gin.Context) {
 	// Grab user details from context
 	userID, _ := c.Get("userID")
@@ -2578,6 +2688,9 @@ func (h *ReportHandler) GetReportsForTeam(c *gin.Context) {
 	}
 
 	reports, err := h.ReportService.GetReportsByTeamID(c.Request.Context(), tenantUUID, teamUUID)
+	if err == nil {
+		reports, err = visibleReports(c, reports, func(r report.ReportWithDetails) uuid.UUID { return r.CaseID })
+	}
 	if err != nil {
 		fmt.Printf("[GetReportsForTeam] Failed to fetch team reports: %v\n", err)
 
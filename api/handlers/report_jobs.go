@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"aegis-api/middleware"
+	"aegis-api/services_/auditlog"
+	"aegis-api/services_/auth/abac"
+	"aegis-api/services_/report/jobs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReportJobHandler manages scheduled and on-demand bulk report generation
+// across cases.
+type ReportJobHandler struct {
+	jobs        jobs.Service
+	policy      abac.Service // nil: reports are unclassified
+	auditLogger *auditlog.AuditLogger
+}
+
+func NewReportJobHandler(jobs jobs.Service, policy abac.Service, auditLogger *auditlog.AuditLogger) *ReportJobHandler {
+	return &ReportJobHandler{jobs: jobs, policy: policy, auditLogger: auditLogger}
+}
+
+func reportJobActor(c *gin.Context) jobs.Actor {
+	return jobs.Actor{
+		UserID:   c.GetString("userID"),
+		Role:     c.GetString("userRole"),
+		TenantID: c.GetString("tenantID"),
+		TeamID:   c.GetString("teamID"),
+	}
+}
+
+func (h *ReportJobHandler) audit(c *gin.Context, action string, target auditlog.Target, status, description string) {
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action:      action,
+		Actor:       auditlog.MakeActor(c),
+		Target:      target,
+		Service:     "report_jobs",
+		Status:      status,
+		Description: description,
+	})
+}
+
+func writeReportJobError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, jobs.ErrJobNotFound):
+		writeError(c, http.StatusNotFound, "job_not_found", err.Error())
+	case errors.Is(err, jobs.ErrRunNotFound):
+		writeError(c, http.StatusNotFound, "run_not_found", err.Error())
+	case errors.Is(err, jobs.ErrItemNotFound):
+		writeError(c, http.StatusNotFound, "item_not_found", err.Error())
+	case errors.Is(err, jobs.ErrInvalidJob), errors.Is(err, jobs.ErrInvalidSchedule):
+		writeError(c, http.StatusBadRequest, "invalid_job", err.Error())
+	case errors.Is(err, jobs.ErrNothingToRetry):
+		writeError(c, http.StatusConflict, "nothing_to_retry", err.Error())
+	case errors.Is(err, jobs.ErrNoArtifact):
+		writeError(c, http.StatusNotFound, "no_artifact", err.Error())
+	case errors.Is(err, jobs.ErrForbidden):
+		writeError(c, http.StatusForbidden, "forbidden", err.Error())
+	default:
+		writeError(c, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+}
+
+// GET /report-jobs
+func (h *ReportJobHandler) ListJobs(c *gin.Context) {
+	out, err := h.jobs.ListJobs(reportJobActor(c))
+	if err != nil {
+		writeReportJobError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// GET /report-jobs/:jobID
+func (h *ReportJobHandler) GetJob(c *gin.Context) {
+	j, err := h.jobs.GetJob(reportJobActor(c), c.Param("jobID"))
+	if err != nil {
+		writeReportJobError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, j)
+}
+
+// POST /report-jobs
+// Body: {"name": "Weekly open cases", "template_id": "...", "format": "pdf",
+// "filter": {"statuses": ["open"], "priorities": ["high"]},
+// "schedule": "0 6 * * 1", "timezone": "Africa/Johannesburg",
+// "recipients": ["<user id>"], "max_attempts": 3}
+// An empty schedule makes an on-demand job.
+func (h *ReportJobHandler) CreateJob(c *gin.Context) {
+	var in jobs.JobInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	j, err := h.jobs.CreateJob(reportJobActor(c), in)
+	if err != nil {
+		h.audit(c, "CREATE_REPORT_JOB", auditlog.Target{Type: "report_job"}, "FAILED", err.Error())
+		writeReportJobError(c, err)
+		return
+	}
+	h.audit(c, "CREATE_REPORT_JOB", auditlog.Target{Type: "report_job", ID: j.ID}, "SUCCESS",
+		"Report job "+j.Name+" created")
+	c.JSON(http.StatusCreated, j)
+}
+
+// PUT /report-jobs/:jobID
+func (h *ReportJobHandler) UpdateJob(c *gin.Context) {
+	var in jobs.JobInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	target := auditlog.Target{Type: "report_job", ID: c.Param("jobID")}
+	j, err := h.jobs.UpdateJob(reportJobActor(c), target.ID, in)
+	if err != nil {
+		h.audit(c, "UPDATE_REPORT_JOB", target, "FAILED", err.Error())
+		writeReportJobError(c, err)
+		return
+	}
+	h.audit(c, "UPDATE_REPORT_JOB", target, "SUCCESS", "Report job "+j.Name+" updated")
+	c.JSON(http.StatusOK, j)
+}
+
+// DELETE /report-jobs/:jobID
+func (h *ReportJobHandler) DeleteJob(c *gin.Context) {
+	target := auditlog.Target{Type: "report_job", ID: c.Param("jobID")}
+	if err := h.jobs.DeleteJob(reportJobActor(c), target.ID); err != nil {
+		h.audit(c, "DELETE_REPORT_JOB", target, "FAILED", err.Error())
+		writeReportJobError(c, err)
+		return
+	}
+	h.audit(c, "DELETE_REPORT_JOB", target, "SUCCESS", "Report job deleted")
+	c.Status(http.StatusNoContent)
+}
+
+// POST /report-jobs/:jobID/runs
+// Starts a run now. It proceeds in the background; poll the run for
+// progress.
+func (h *ReportJobHandler) RunNow(c *gin.Context) {
+	target := auditlog.Target{Type: "report_job", ID: c.Param("jobID")}
+	run, err := h.jobs.RunNow(reportJobActor(c), target.ID)
+	if err != nil {
+		h.audit(c, "RUN_REPORT_JOB", target, "FAILED", err.Error())
+		writeReportJobError(c, err)
+		return
+	}
+	h.audit(c, "RUN_REPORT_JOB", target, "SUCCESS",
+		fmt.Sprintf("Run %s started for %d cases", run.ID, run.Total))
+	c.JSON(http.StatusAccepted, run)
+}
+
+// GET /report-jobs/:jobID/runs
+func (h *ReportJobHandler) ListRuns(c *gin.Context) {
+	out, err := h.jobs.ListRuns(reportJobActor(c), c.Param("jobID"))
+	if err != nil {
+		writeReportJobError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// GET /report-job-runs/:runID
+// Returns the run with the status, attempts and error of each case.
+func (h *ReportJobHandler) GetRun(c *gin.Context) {
+	out, err := h.jobs.GetRun(reportJobActor(c), c.Param("runID"))
+	if err != nil {
+		writeReportJobError(c, err)
+		return
+	}
+	// A run spans many cases; only those the caller can see are listed
+	visible := out.Items[:0]
+	for _, it := range out.Items {
+		ok, err := middleware.CanAccessCase(c, "case:view", it.CaseID)
+		if err != nil {
+			writeReportJobError(c, err)
+			return
+		}
+		if ok {
+			visible = append(visible, it)
+		}
+	}
+	out.Items = visible
+	c.JSON(http.StatusOK, out)
+}
+
+// POST /report-job-runs/:runID/retry
+// Starts a run covering only the cases that failed.
+func (h *ReportJobHandler) RetryFailed(c *gin.Context) {
+	target := auditlog.Target{Type: "report_job_run", ID: c.Param("runID")}
+	run, err := h.jobs.RetryFailed(reportJobActor(c), target.ID)
+	if err != nil {
+		h.audit(c, "RETRY_REPORT_JOB_RUN", target, "FAILED", err.Error())
+		writeReportJobError(c, err)
+		return
+	}
+	h.audit(c, "RETRY_REPORT_JOB_RUN", target, "SUCCESS",
+		fmt.Sprintf("Retry run %s started for %d cases", run.ID, run.Total))
+	c.JSON(http.StatusAccepted, run)
+}
+
+// GET /report-job-runs/:runID/items/:itemID/artifact
+func (h *ReportJobHandler) DownloadArtifact(c *gin.Context) {
+	target := auditlog.Target{Type: "report_job_item", ID: c.Param("itemID")}
+	if h.policy != nil {
+		run, err := h.jobs.GetRun(reportJobActor(c), c.Param("runID"))
+		if err != nil {
+			writeReportJobError(c, err)
+			return
+		}
+		for _, it := range run.Items {
+			if strings.EqualFold(it.ID, target.ID) && it.ReportID != nil &&
+				classificationBlocked(c, h.policy, h.auditLogger, "report_jobs", abac.ActionExport, abac.ResourceReport, *it.ReportID) {
+				return
+			}
+		}
+	}
+	data, name, contentType, err := h.jobs.Artifact(reportJobActor(c), c.Param("runID"), target.ID)
+	if err != nil {
+		writeReportJobError(c, err)
+		return
+	}
+	h.audit(c, "DOWNLOAD_REPORT_JOB_ARTIFACT", target, "SUCCESS", "Downloaded "+name)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	c.Data(http.StatusOK, contentType, data)
+}
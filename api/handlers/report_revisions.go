@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"aegis-api/services_/report"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// editorContext attributes report edits made during the request to the
+// caller, so they show up as the author of the resulting revisions.
+func editorContext(c *gin.Context) context.Context {
+	return report.WithEditor(c.Request.Context(), c.GetString("userID"))
+}
+
+// GET /reports/:reportID/revisions
+// Lists every change to the report, newest first, grouped by report revision.
+func (h *ReportHandler) ListReportRevisions(c *gin.Context) {
+	reportID, ok := h.reportInTenant(c)
+	if !ok {
+		return
+	}
+	revs, err := h.ReportService.ListReportRevisions(c.Request.Context(), reportID)
+	if err != nil {
+		writeRevisionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, revs)
+}
+
+// GET /reports/:reportID/sections/:sectionID/revisions
+func (h *ReportHandler) ListSectionRevisions(c *gin.Context) {
+	reportID, ok := h.reportInTenant(c)
+	if !ok {
+		return
+	}
+	sectionID, ok := sectionParam(c)
+	if !ok {
+		return
+	}
+	revs, err := h.ReportService.ListSectionRevisions(c.Request.Context(), reportID, sectionID)
+	if err != nil {
+		writeRevisionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, revs)
+}
+
+// GET /reports/:reportID/sections/:sectionID/revisions/:revision
+func (h *ReportHandler) GetSectionRevision(c *gin.Context) {
+	reportID, ok := h.reportInTenant(c)
+	if !ok {
+		return
+	}
+	sectionID, ok := sectionParam(c)
+	if !ok {
+		return
+	}
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil || revision < 1 {
+		writeError(c, http.StatusBadRequest, "invalid_revision", "revision must be a positive integer")
+		return
+	}
+	rev, err := h.ReportService.GetSectionRevision(c.Request.Context(), reportID, sectionID, revision)
+	if err != nil {
+		writeRevisionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rev)
+}
+
+// GET /reports/:reportID/sections/:sectionID/diff?from=1&to=3
+// Omitting to compares with the section as it is now.
+func (h *ReportHandler) DiffSection(c *gin.Context) {
+	reportID, ok := h.reportInTenant(c)
+	if !ok {
+		return
+	}
+	sectionID, ok := sectionParam(c)
+	if !ok {
+		return
+	}
+	from, to, ok := diffRange(c)
+	if !ok {
+		return
+	}
+	diff, err := h.ReportService.DiffSection(c.Request.Context(), reportID, sectionID, int(from), int(to))
+	if err != nil {
+		writeRevisionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
+
+// GET /reports/:reportID/diff?from=2&to=5
+// from and to are report revisions; omitting to compares with the current content.
+func (h *ReportHandler) DiffReport(c *gin.Context) {
+	reportID, ok := h.reportInTenant(c)
+	if !ok {
+		return
+	}
+	from, to, ok := diffRange(c)
+	if !ok {
+		return
+	}
+	diff, err := h.ReportService.DiffReport(c.Request.Context(), reportID, from, to)
+	if err != nil {
+		writeRevisionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
+
+type rollbackSectionRequest struct {
+	Revision int `json:"revision" binding:"required,min=1"`
+}
+
+// POST /reports/:reportID/sections/:sectionID/rollback
+func (h *ReportHandler) RollbackSection(c *gin.Context) {
+	reportID, ok := h.reportInTenant(c)
+	if !ok {
+		return
+	}
+	sectionID, ok := sectionParam(c)
+	if !ok {
+		return
+	}
+	var req rollbackSectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if err := h.ReportService.RollbackSection(editorContext(c), reportID, sectionID, req.Revision); err != nil {
+		h.reportAudit(c, "ROLLBACK_REPORT_SECTION", reportID, "FAILED", "Section rollback failed: "+err.Error())
+		writeRevisionError(c, err)
+		return
+	}
+	h.reportAudit(c, "ROLLBACK_REPORT_SECTION", reportID, "SUCCESS",
+		fmt.Sprintf("Section %s rolled back to revision %d", sectionID.Hex(), req.Revision))
+	c.JSON(http.StatusOK, gin.H{"message": "section rolled back"})
+}
+
+type rollbackReportRequest struct {
+	Seq int64 `json:"seq" binding:"required,min=1"`
+}
+
+// POST /reports/:reportID/rollback
+func (h *ReportHandler) RollbackReport(c *gin.Context) {
+	reportID, ok := h.reportInTenant(c)
+	if !ok {
+		return
+	}
+	var req rollbackReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if err := h.ReportService.RollbackReport(editorContext(c), reportID, req.Seq); err != nil {
+		h.reportAudit(c, "ROLLBACK_REPORT", reportID, "FAILED", "Report rollback failed: "+err.Error())
+		writeRevisionError(c, err)
+		return
+	}
+	h.reportAudit(c, "ROLLBACK_REPORT", reportID, "SUCCESS", fmt.Sprintf("Report rolled back to revision %d", req.Seq))
+	c.JSON(http.StatusOK, gin.H{"message": "report rolled back"})
+}
+
+// GET /reports/:reportID/snapshots
+// Lists the snapshots pinned when the report was published.
+func (h *ReportHandler) ListReportSnapshots(c *gin.Context) {
+	reportID, ok := h.reportInTenant(c)
+	if !ok {
+		return
+	}
+	snaps, err := h.ReportService.ListSnapshots(c.Request.Context(), reportID)
+	if err != nil {
+		writeRevisionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, snaps)
+}
+
+// GET /reports/:reportID/snapshots/:snapshotID
+func (h *ReportHandler) GetReportSnapshot(c *gin.Context) {
+	reportID, ok := h.reportInTenant(c)
+	if !ok {
+		return
+	}
+	snap, err := h.ReportService.GetSnapshot(c.Request.Context(), reportID, c.Param("snapshotID"))
+	if err != nil {
+		writeRevisionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, snap)
+}
+
+func sectionParam(c *gin.Context) (primitive.ObjectID, bool) {
+	sectionID, err := primitive.ObjectIDFromHex(c.Param("sectionID"))
+	if err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_section_id", "invalid section ID")
+		return primitive.NilObjectID, false
+	}
+	return sectionID, true
+}
+
+func diffRange(c *gin.Context) (from, to int64, ok bool) {
+	from, err := strconv.ParseInt(c.Query("from"), 10, 64)
+	if err != nil || from < 1 {
+		writeError(c, http.StatusBadRequest, "invalid_range", "from must be a positive integer")
+		return 0, 0, false
+	}
+	if raw := c.Query("to"); raw != "" {
+		if to, err = strconv.ParseInt(raw, 10, 64); err != nil || to < 1 {
+			writeError(c, http.StatusBadRequest, "invalid_range", "to must be a positive integer")
+			return 0, 0, false
+		}
+	}
+	return from, to, true
+}
+
+func writeRevisionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, report.ErrRevisionNotFound), errors.Is(err, report.ErrSnapshotNotFound):
+		writeError(c, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, report.ErrReportLocked):
+		writeError(c, http.StatusConflict, "report_locked", err.Error())
+	case errors.Is(err, report.ErrSectionNotFound):
+		writeError(c, http.StatusNotFound, "section_not_found", err.Error())
+	case errors.Is(err, report.ErrRevisionsDisabled):
+		writeError(c, http.StatusNotImplemented, "revisions_disabled", err.Error())
+	default:
+		writeError(c, http.StatusInternalServerError, "revision_error", "revision operation failed")
+	}
+}
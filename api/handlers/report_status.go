@@ -1,8 +1,15 @@
 package handlers
 
 import (
+	"errors"
+	"io"
 	"net/http"
 
+	"aegis-api/middleware"
+	"aegis-api/services_/auditlog"
+	"aegis-api/services_/report"
+	"aegis-api/services_/report/review"
+	"aegis-api/services_/report/signing"
 	"aegis-api/services_/report/update_status"
 
 	"github.com/gin-gonic/gin"
@@ -11,37 +18,328 @@ import (
 
 type UpdateStatusRequest struct {
 	Status update_status.ReportStatus `json:"status" binding:"required"`
+	// FreezeFields captures merge field values as the report is published.
+	FreezeFields bool `json:"freeze_fields"`
 }
 
+// ReportStatusHandler moves reports through the tenant's review workflow.
+// Status changes are never applied directly: submitting, approving and
+// requesting changes are what move a report between draft, review and
+// published.
 type ReportStatusHandler struct {
-	service update_status.ReportStatusService
+	reviews review.Service
+	// artifacts seals reports as they are published; nil leaves sealing to
+	// the first download.
+	artifacts   signing.Service
+	auditLogger *auditlog.AuditLogger
 }
 
-// Accepts the service instead of the repo
-func NewReportStatusHandler(service update_status.ReportStatusService) *ReportStatusHandler {
-	return &ReportStatusHandler{service: service}
+func NewReportStatusHandler(reviews review.Service, artifacts signing.Service, auditLogger *auditlog.AuditLogger) *ReportStatusHandler {
+	return &ReportStatusHandler{reviews: reviews, artifacts: artifacts, auditLogger: auditLogger}
 }
 
+func reviewActor(c *gin.Context) review.Actor {
+	return review.Actor{
+		UserID:   c.GetString("userID"),
+		Role:     c.GetString("userRole"),
+		TenantID: c.GetString("tenantID"),
+	}
+}
+
+func (h *ReportStatusHandler) audit(c *gin.Context, action string, reportID uuid.UUID, status, description string) {
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action:      action,
+		Actor:       auditlog.MakeActor(c),
+		Target:      auditlog.Target{Type: "report", ID: reportID.String()},
+		Service:     "report",
+		Status:      status,
+		Description: description,
+	})
+}
 
+func reportIDParam(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("reportID"))
+	if err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_report_id", "invalid report ID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// PUT /reports/:reportID/status
+// Kept for existing clients: "review" submits, "draft" withdraws and
+// "published" approves, which only publishes on the final approval.
 func (h *ReportStatusHandler) UpdateStatus(c *gin.Context) {
-	idParam := c.Param("reportID")
-	reportID, err := uuid.Parse(idParam)
+	reportID, ok := reportIDParam(c)
+	if !ok {
+		return
+	}
+	var req UpdateStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	switch req.Status {
+	case update_status.ReportStatusReview:
+		h.submit(c, reportID)
+	case update_status.ReportStatusDraft:
+		h.withdraw(c, reportID)
+	case update_status.ReportStatusPublished:
+		// Publishing is a sensitive action, like the approve route.
+		if !middleware.SteppedUp(c) {
+			return
+		}
+		h.approve(c, reportID, "", req.FreezeFields)
+	default:
+		writeError(c, http.StatusBadRequest, "invalid_status", "unsupported report status")
+	}
+}
+
+// GET /reports/:reportID/review
+func (h *ReportStatusHandler) GetReview(c *gin.Context) {
+	reportID, ok := reportIDParam(c)
+	if !ok {
+		return
+	}
+	st, err := h.reviews.Status(c.Request.Context(), reviewActor(c), reportID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid report ID"})
+		writeReviewError(c, err)
 		return
 	}
+	c.JSON(http.StatusOK, st)
+}
 
-	var req UpdateStatusRequest
+// POST /reports/:reportID/review/submit
+func (h *ReportStatusHandler) Submit(c *gin.Context) {
+	if reportID, ok := reportIDParam(c); ok {
+		h.submit(c, reportID)
+	}
+}
+
+func (h *ReportStatusHandler) submit(c *gin.Context, reportID uuid.UUID) {
+	st, err := h.reviews.Submit(c.Request.Context(), reviewActor(c), reportID)
+	if err != nil {
+		h.audit(c, "SUBMIT_REPORT_REVIEW", reportID, "FAILED", err.Error())
+		writeReviewError(c, err)
+		return
+	}
+	h.audit(c, "SUBMIT_REPORT_REVIEW", reportID, "SUCCESS", "Report submitted for review")
+	c.JSON(http.StatusOK, st)
+}
+
+// POST /reports/:reportID/review/approve
+// Body: {"comment": "...", "freeze_fields": true}
+func (h *ReportStatusHandler) Approve(c *gin.Context) {
+	reportID, ok := reportIDParam(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		Comment      string `json:"comment"`
+		FreezeFields bool   `json:"freeze_fields"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	h.approve(c, reportID, req.Comment, req.FreezeFields)
+}
+
+func (h *ReportStatusHandler) approve(c *gin.Context, reportID uuid.UUID, comment string, freezeFields bool) {
+	st, err := h.reviews.Approve(c.Request.Context(), reviewActor(c), reportID, comment, freezeFields)
+	if err != nil {
+		h.audit(c, "APPROVE_REPORT_REVIEW", reportID, "FAILED", err.Error())
+		writeReviewError(c, err)
+		return
+	}
+	h.audit(c, "APPROVE_REPORT_REVIEW", reportID, "SUCCESS", "Report review stage approved")
+	if st.ReportStatus == string(update_status.ReportStatusPublished) {
+		h.audit(c, "PUBLISH_REPORT", reportID, "SUCCESS", "Report published after completing review")
+		h.seal(c, reportID)
+	}
+	c.JSON(http.StatusOK, st)
+}
+
+// seal stores the signed PDF of a just-published report. Publishing has
+// already succeeded, so a failure is audited and left for the next
+// download to retry.
+func (h *ReportStatusHandler) seal(c *gin.Context, reportID uuid.UUID) {
+	if h.artifacts == nil {
+		return
+	}
+	a, err := h.artifacts.Seal(c.Request.Context(), signingActor(c), reportID)
+	if err != nil {
+		h.audit(c, "SEAL_REPORT", reportID, "FAILED", err.Error())
+		return
+	}
+	h.audit(c, "SEAL_REPORT", reportID, "SUCCESS", "Published report sealed (sha256 "+a.SHA256+")")
+}
+
+// POST /reports/:reportID/review/request-changes
+// Body: {"comment": "..."}
+func (h *ReportStatusHandler) RequestChanges(c *gin.Context) {
+	reportID, ok := reportIDParam(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		Comment string `json:"comment"`
+	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	st, err := h.reviews.RequestChanges(c.Request.Context(), reviewActor(c), reportID, req.Comment)
+	if err != nil {
+		h.audit(c, "REQUEST_REPORT_CHANGES", reportID, "FAILED", err.Error())
+		writeReviewError(c, err)
+		return
+	}
+	h.audit(c, "REQUEST_REPORT_CHANGES", reportID, "SUCCESS", "Changes requested; report returned to draft")
+	c.JSON(http.StatusOK, st)
+}
+
+// POST /reports/:reportID/review/withdraw
+func (h *ReportStatusHandler) Withdraw(c *gin.Context) {
+	if reportID, ok := reportIDParam(c); ok {
+		h.withdraw(c, reportID)
+	}
+}
+
+func (h *ReportStatusHandler) withdraw(c *gin.Context, reportID uuid.UUID) {
+	st, err := h.reviews.Withdraw(c.Request.Context(), reviewActor(c), reportID)
+	if err != nil {
+		h.audit(c, "WITHDRAW_REPORT_REVIEW", reportID, "FAILED", err.Error())
+		writeReviewError(c, err)
+		return
+	}
+	h.audit(c, "WITHDRAW_REPORT_REVIEW", reportID, "SUCCESS", "Report withdrawn from review")
+	c.JSON(http.StatusOK, st)
+}
+
+// GET /reports/:reportID/review/comments?section_id=&include_resolved=true
+func (h *ReportStatusHandler) ListComments(c *gin.Context) {
+	reportID, ok := reportIDParam(c)
+	if !ok {
+		return
+	}
+	comments, err := h.reviews.ListComments(c.Request.Context(), reviewActor(c), reportID,
+		c.Query("section_id"), c.Query("include_resolved") == "true")
+	if err != nil {
+		writeReviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"comments": comments})
+}
+
+// POST /reports/:reportID/review/comments
+// Body: {"section_id": "...", "quote": "...", "body": "..."} or
+// {"parent_id": "...", "body": "..."} for a reply.
+func (h *ReportStatusHandler) AddComment(c *gin.Context) {
+	reportID, ok := reportIDParam(c)
+	if !ok {
+		return
+	}
+	var in review.CommentInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	comment, err := h.reviews.AddComment(c.Request.Context(), reviewActor(c), reportID, in)
+	if err != nil {
+		h.audit(c, "ADD_REVIEW_COMMENT", reportID, "FAILED", err.Error())
+		writeReviewError(c, err)
 		return
 	}
+	h.audit(c, "ADD_REVIEW_COMMENT", reportID, "SUCCESS", "Review comment added on section "+comment.SectionID)
+	c.JSON(http.StatusCreated, comment)
+}
 
-	updated, err := h.service.UpdateStatus(c.Request.Context(), reportID, req.Status)
+// POST /reports/:reportID/review/comments/:commentID/resolve
+func (h *ReportStatusHandler) ResolveComment(c *gin.Context) {
+	reportID, ok := reportIDParam(c)
+	if !ok {
+		return
+	}
+	commentID := c.Param("commentID")
+	if _, err := uuid.Parse(commentID); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_comment_id", "invalid comment ID")
+		return
+	}
+	comment, err := h.reviews.ResolveComment(c.Request.Context(), reviewActor(c), reportID, commentID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.audit(c, "RESOLVE_REVIEW_COMMENT", reportID, "FAILED", err.Error())
+		writeReviewError(c, err)
 		return
 	}
+	h.audit(c, "RESOLVE_REVIEW_COMMENT", reportID, "SUCCESS", "Review comment "+commentID+" resolved")
+	c.JSON(http.StatusOK, comment)
+}
 
-	c.JSON(http.StatusOK, updated)
+// GET /report-review-workflow
+func (h *ReportStatusHandler) GetWorkflow(c *gin.Context) {
+	stages, err := h.reviews.GetWorkflow(c.GetString("tenantID"))
+	if err != nil {
+		writeReviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"stages": stages})
+}
+
+// PUT /report-review-workflow
+// Body: {"stages": [{"name": "...", "roles": ["..."], "min_approvals": 1}]}
+func (h *ReportStatusHandler) SetWorkflow(c *gin.Context) {
+	var req struct {
+		Stages []review.Stage `json:"stages"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	tenantID := c.GetString("tenantID")
+	target := auditlog.Target{Type: "report_review_workflow", ID: tenantID}
+	stages, err := h.reviews.SetWorkflow(tenantID, c.GetString("userID"), req.Stages)
+	if err != nil {
+		h.auditLogger.Log(c, auditlog.AuditLog{
+			Action: "UPDATE_REVIEW_WORKFLOW", Actor: auditlog.MakeActor(c), Target: target,
+			Service: "report", Status: "FAILED", Description: err.Error(),
+		})
+		writeReviewError(c, err)
+		return
+	}
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action: "UPDATE_REVIEW_WORKFLOW", Actor: auditlog.MakeActor(c), Target: target,
+		Service: "report", Status: "SUCCESS", Description: "Report review workflow updated",
+	})
+	c.JSON(http.StatusOK, gin.H{"stages": stages})
+}
+
+func writeReviewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, review.ErrReportNotFound):
+		writeError(c, http.StatusNotFound, "report_not_found", err.Error())
+	case errors.Is(err, review.ErrCommentNotFound):
+		writeError(c, http.StatusNotFound, "comment_not_found", err.Error())
+	case errors.Is(err, report.ErrSectionNotFound):
+		writeError(c, http.StatusNotFound, "section_not_found", err.Error())
+	case errors.Is(err, review.ErrSelfApproval),
+		errors.Is(err, review.ErrNotEligible),
+		errors.Is(err, review.ErrNotSubmitter):
+		writeError(c, http.StatusForbidden, "review_forbidden", err.Error())
+	case errors.Is(err, review.ErrNotDraft),
+		errors.Is(err, review.ErrNotInReview),
+		errors.Is(err, review.ErrAlreadyDecided),
+		errors.Is(err, review.ErrReportPublished),
+		errors.Is(err, review.ErrReviewConflict),
+		errors.Is(err, report.ErrReportLocked):
+		writeError(c, http.StatusConflict, "review_conflict", err.Error())
+	case errors.Is(err, review.ErrInvalidWorkflow),
+		errors.Is(err, review.ErrCommentRequired):
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+	case errors.Is(err, report.ErrUnresolvedFields):
+		writeError(c, http.StatusUnprocessableEntity, "unresolved_fields", err.Error())
+	default:
+		writeError(c, http.StatusInternalServerError, "internal_error", err.Error())
+	}
 }
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"aegis-api/services_/auditlog"
+	"aegis-api/services_/report/report_templates"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReportTemplateHandler struct {
+	service     report_templates.Service
+	auditLogger *auditlog.AuditLogger
+}
+
+func NewReportTemplateHandler(service report_templates.Service, auditLogger *auditlog.AuditLogger) *ReportTemplateHandler {
+	return &ReportTemplateHandler{service: service, auditLogger: auditLogger}
+}
+
+func (h *ReportTemplateHandler) audit(c *gin.Context, action, id, status, description string) {
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action:      action,
+		Actor:       auditlog.MakeActor(c),
+		Target:      auditlog.Target{Type: "report_template", ID: id},
+		Service:     "report",
+		Status:      status,
+		Description: description,
+	})
+}
+
+// GET /report-templates?case_type=&include_archived=
+func (h *ReportTemplateHandler) ListTemplates(c *gin.Context) {
+	includeArchived, _ := strconv.ParseBool(c.Query("include_archived"))
+	templates, err := h.service.ListTemplates(c.GetString("tenantID"), c.Query("case_type"), includeArchived)
+	if err != nil {
+		writeTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+// GET /report-templates/:id?version=
+// Without a version the current one is returned.
+func (h *ReportTemplateHandler) GetTemplate(c *gin.Context) {
+	version := 0
+	if raw := c.Query("version"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "version must be a positive integer"})
+			return
+		}
+		version = v
+	}
+	tmpl, err := h.service.GetTemplate(c.GetString("tenantID"), c.Param("id"), version)
+	if err != nil {
+		writeTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tmpl)
+}
+
+// GET /report-templates/:id/versions
+func (h *ReportTemplateHandler) ListVersions(c *gin.Context) {
+	versions, err := h.service.ListVersions(c.GetString("tenantID"), c.Param("id"))
+	if err != nil {
+		writeTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, versions)
+}
+
+// POST /report-templates
+func (h *ReportTemplateHandler) CreateTemplate(c *gin.Context) {
+	var in report_templates.TemplateInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	tmpl, err := h.service.CreateTemplate(c.GetString("tenantID"), c.GetString("userID"), in)
+	if err != nil {
+		h.audit(c, "CREATE_REPORT_TEMPLATE", in.Name, "FAILED", "Report template creation failed: "+err.Error())
+		writeTemplateError(c, err)
+		return
+	}
+	h.audit(c, "CREATE_REPORT_TEMPLATE", tmpl.ID, "SUCCESS", "Created report template "+tmpl.Name+" for "+tmpl.CaseType)
+	c.JSON(http.StatusCreated, tmpl)
+}
+
+// PUT /report-templates/:id
+// Records the submitted layout as a new version of the template.
+func (h *ReportTemplateHandler) UpdateTemplate(c *gin.Context) {
+	var in report_templates.TemplateInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	id := c.Param("id")
+	tmpl, err := h.service.UpdateTemplate(c.GetString("tenantID"), c.GetString("userID"), id, in)
+	if err != nil {
+		h.audit(c, "UPDATE_REPORT_TEMPLATE", id, "FAILED", "Report template update failed: "+err.Error())
+		writeTemplateError(c, err)
+		return
+	}
+	h.audit(c, "UPDATE_REPORT_TEMPLATE", id, "SUCCESS", "Report template "+tmpl.Name+" is now at version "+strconv.Itoa(tmpl.Version))
+	c.JSON(http.StatusOK, tmpl)
+}
+
+// POST /report-templates/:id/default
+func (h *ReportTemplateHandler) SetDefault(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.service.SetDefault(c.GetString("tenantID"), id); err != nil {
+		h.audit(c, "SET_DEFAULT_REPORT_TEMPLATE", id, "FAILED", "Setting default report template failed: "+err.Error())
+		writeTemplateError(c, err)
+		return
+	}
+	h.audit(c, "SET_DEFAULT_REPORT_TEMPLATE", id, "SUCCESS", "Report template set as default for its case type")
+	c.JSON(http.StatusOK, gin.H{"message": "default template updated"})
+}
+
+// DELETE /report-templates/:id
+// Templates are archived rather than deleted so existing reports keep a
+// resolvable template version.
+func (h *ReportTemplateHandler) ArchiveTemplate(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.service.ArchiveTemplate(c.GetString("tenantID"), id); err != nil {
+		h.audit(c, "ARCHIVE_REPORT_TEMPLATE", id, "FAILED", "Archiving report template failed: "+err.Error())
+		writeTemplateError(c, err)
+		return
+	}
+	h.audit(c, "ARCHIVE_REPORT_TEMPLATE", id, "SUCCESS", "Report template archived")
+	c.JSON(http.StatusOK, gin.H{"message": "template archived"})
+}
+
+func writeTemplateError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, report_templates.ErrInvalidTemplate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, report_templates.ErrTemplateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, report_templates.ErrTemplateArchived),
+		errors.Is(err, report_templates.ErrVersionConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
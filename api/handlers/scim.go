@@ -0,0 +1,397 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"aegis-api/services_/auditlog"
+	"aegis-api/services_/auth/scim"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scimMaxBody caps SCIM request bodies; bulk requests are the largest.
+const scimMaxBody = 4 << 20
+
+// SCIMHandler serves the SCIM 2.0 endpoint tenants' directories provision
+// users and groups through, and its configuration.
+type SCIMHandler struct {
+	scim        scim.Service
+	auditLogger *auditlog.AuditLogger
+}
+
+func NewSCIMHandler(scimService scim.Service, auditLogger *auditlog.AuditLogger) *SCIMHandler {
+	return &SCIMHandler{scim: scimService, auditLogger: auditLogger}
+}
+
+func scimActor(c *gin.Context) scim.Actor {
+	return scim.Actor{UserID: c.GetString("userID"), TenantID: c.GetString("tenantID")}
+}
+
+// directoryActor is the tenant's directory, which acts through the SCIM
+// token rather than as a user.
+func directoryActor(c *gin.Context) auditlog.Actor {
+	return auditlog.Actor{
+		ID:        "scim:" + c.GetString("tenantID"),
+		Role:      "SCIM Directory",
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+func (h *SCIMHandler) audit(c *gin.Context, action string, actor auditlog.Actor, target auditlog.Target, status, description string) {
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action:      action,
+		Actor:       actor,
+		Target:      target,
+		Service:     "auth",
+		Status:      status,
+		Description: description,
+	})
+}
+
+func (h *SCIMHandler) auditEvents(c *gin.Context, events []scim.Event) {
+	for _, e := range events {
+		h.audit(c, e.Action, directoryActor(c), auditlog.Target{Type: e.TargetType, ID: e.TargetID}, "SUCCESS", e.Description)
+	}
+}
+
+func writeSCIM(c *gin.Context, status int, body interface{}) {
+	c.Header("Content-Type", "application/scim+json; charset=utf-8")
+	c.JSON(status, body)
+}
+
+func writeSCIMError(c *gin.Context, err error) {
+	status, body := scim.ErrorOf(err)
+	c.Header("Content-Type", "application/scim+json; charset=utf-8")
+	c.AbortWithStatusJSON(status, body)
+}
+
+func writeSCIMConfigError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, scim.ErrNotConfigured):
+		writeError(c, http.StatusNotFound, "scim_not_configured", err.Error())
+	case errors.Is(err, scim.ErrInvalidConfig):
+		writeError(c, http.StatusBadRequest, "invalid_config", err.Error())
+	default:
+		writeError(c, http.StatusInternalServerError, "internal_error", "An internal error occurred")
+	}
+}
+
+// bindSCIM reads a SCIM request body.
+func bindSCIM(c *gin.Context, v interface{}) bool {
+	if err := c.ShouldBindJSON(v); err != nil {
+		writeSCIMError(c, fmt.Errorf("%w: %v", scim.ErrInvalidValue, err))
+		return false
+	}
+	return true
+}
+
+// RequireSCIMToken authenticates the directory by the tenant's bearer
+// token and scopes the request to that tenant.
+func (h *SCIMHandler) RequireSCIMToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		tenantID, err := h.scim.Authenticate(strings.TrimSpace(token))
+		if !ok || err != nil {
+			if err == nil || !errors.Is(err, scim.ErrUnauthorized) {
+				err = scim.ErrUnauthorized
+			}
+			h.audit(c, "SCIM_AUTH", anonymousActor(c), auditlog.Target{Type: "tenant"}, "FAILED", "SCIM request with an invalid bearer token")
+			writeSCIMError(c, err)
+			return
+		}
+		c.Set("tenantID", tenantID)
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, scimMaxBody)
+		c.Next()
+	}
+}
+
+// scimQuery reads the list parameters of RFC 7644 §3.4.2.
+func scimQuery(c *gin.Context) scim.Query {
+	q := scim.Query{Filter: c.Query("filter")}
+	q.StartIndex, _ = strconv.Atoi(c.Query("startIndex"))
+	q.Count, _ = strconv.Atoi(c.Query("count"))
+	for _, attr := range strings.Split(c.Query("excludedAttributes"), ",") {
+		if strings.EqualFold(strings.TrimSpace(attr), "members") {
+			q.ExcludeMembers = true
+		}
+	}
+	return q
+}
+
+// GET /scim/v2/ServiceProviderConfig
+func (h *SCIMHandler) ServiceProviderConfig(c *gin.Context) {
+	supported := func(v bool) gin.H { return gin.H{"supported": v} }
+	writeSCIM(c, http.StatusOK, gin.H{
+		"schemas":        []string{scim.SchemaServiceConfig},
+		"patch":          supported(true),
+		"bulk":           gin.H{"supported": true, "maxOperations": 1000, "maxPayloadSize": scimMaxBody},
+		"filter":         gin.H{"supported": true, "maxResults": 500},
+		"changePassword": supported(false),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer token",
+			"description": "The tenant's SCIM token, issued in AEGIS",
+		}},
+		"meta": gin.H{"resourceType": "ServiceProviderConfig", "location": h.scim.BaseURL() + "/ServiceProviderConfig"},
+	})
+}
+
+// GET /scim/v2/ResourceTypes
+func (h *SCIMHandler) ResourceTypes(c *gin.Context) {
+	resourceType := func(name, endpoint, schema string) gin.H {
+		return gin.H{
+			"schemas":  []string{scim.SchemaResourceType},
+			"id":       name,
+			"name":     name,
+			"endpoint": endpoint,
+			"schema":   schema,
+			"meta":     gin.H{"resourceType": "ResourceType", "location": h.scim.BaseURL() + "/ResourceTypes/" + name},
+		}
+	}
+	types := []interface{}{
+		resourceType(scim.ResourceTypeUser, "/Users", scim.SchemaUser),
+		resourceType(scim.ResourceTypeGroup, "/Groups", scim.SchemaGroup),
+	}
+	writeSCIM(c, http.StatusOK, scim.ListResponse{
+		Schemas:      []string{scim.SchemaListResponse},
+		TotalResults: len(types),
+		StartIndex:   1,
+		ItemsPerPage: len(types),
+		Resources:    types,
+	})
+}
+
+// GET /scim/v2/Users?filter=&startIndex=&count=
+func (h *SCIMHandler) ListUsers(c *gin.Context) {
+	res, err := h.scim.ListUsers(c.GetString("tenantID"), scimQuery(c))
+	if err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+	writeSCIM(c, http.StatusOK, res)
+}
+
+// GET /scim/v2/Users/:id
+func (h *SCIMHandler) GetUser(c *gin.Context) {
+	u, err := h.scim.GetUser(c.GetString("tenantID"), c.Param("id"))
+	if err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+	writeSCIM(c, http.StatusOK, u)
+}
+
+// POST /scim/v2/Users
+func (h *SCIMHandler) CreateUser(c *gin.Context) {
+	var in scim.UserResource
+	if !bindSCIM(c, &in) {
+		return
+	}
+	u, events, err := h.scim.CreateUser(c.Request.Context(), c.GetString("tenantID"), in)
+	h.auditEvents(c, events)
+	if err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+	c.Header("Location", u.Meta.Location)
+	writeSCIM(c, http.StatusCreated, u)
+}
+
+// PUT /scim/v2/Users/:id
+func (h *SCIMHandler) ReplaceUser(c *gin.Context) {
+	var in scim.UserResource
+	if !bindSCIM(c, &in) {
+		return
+	}
+	u, events, err := h.scim.ReplaceUser(c.Request.Context(), c.GetString("tenantID"), c.Param("id"), in)
+	h.auditEvents(c, events)
+	if err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+	writeSCIM(c, http.StatusOK, u)
+}
+
+// PATCH /scim/v2/Users/:id
+func (h *SCIMHandler) PatchUser(c *gin.Context) {
+	var in scim.PatchRequest
+	if !bindSCIM(c, &in) {
+		return
+	}
+	u, events, err := h.scim.PatchUser(c.Request.Context(), c.GetString("tenantID"), c.Param("id"), in)
+	h.auditEvents(c, events)
+	if err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+	writeSCIM(c, http.StatusOK, u)
+}
+
+// DELETE /scim/v2/Users/:id
+// Deprovisions the user; the account is kept, deactivated.
+func (h *SCIMHandler) DeleteUser(c *gin.Context) {
+	events, err := h.scim.DeleteUser(c.Request.Context(), c.GetString("tenantID"), c.Param("id"))
+	h.auditEvents(c, events)
+	if err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GET /scim/v2/Groups?filter=&startIndex=&count=&excludedAttributes=members
+func (h *SCIMHandler) ListGroups(c *gin.Context) {
+	res, err := h.scim.ListGroups(c.GetString("tenantID"), scimQuery(c))
+	if err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+	writeSCIM(c, http.StatusOK, res)
+}
+
+// GET /scim/v2/Groups/:id
+func (h *SCIMHandler) GetGroup(c *gin.Context) {
+	g, err := h.scim.GetGroup(c.GetString("tenantID"), c.Param("id"))
+	if err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+	if scimQuery(c).ExcludeMembers {
+		g.Members = nil
+	}
+	writeSCIM(c, http.StatusOK, g)
+}
+
+// POST /scim/v2/Groups
+func (h *SCIMHandler) CreateGroup(c *gin.Context) {
+	var in scim.GroupResource
+	if !bindSCIM(c, &in) {
+		return
+	}
+	g, events, err := h.scim.CreateGroup(c.Request.Context(), c.GetString("tenantID"), in)
+	h.auditEvents(c, events)
+	if err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+	c.Header("Location", g.Meta.Location)
+	writeSCIM(c, http.StatusCreated, g)
+}
+
+// PUT /scim/v2/Groups/:id
+func (h *SCIMHandler) ReplaceGroup(c *gin.Context) {
+	var in scim.GroupResource
+	if !bindSCIM(c, &in) {
+		return
+	}
+	g, events, err := h.scim.ReplaceGroup(c.Request.Context(), c.GetString("tenantID"), c.Param("id"), in)
+	h.auditEvents(c, events)
+	if err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+	writeSCIM(c, http.StatusOK, g)
+}
+
+// PATCH /scim/v2/Groups/:id
+func (h *SCIMHandler) PatchGroup(c *gin.Context) {
+	var in scim.PatchRequest
+	if !bindSCIM(c, &in) {
+		return
+	}
+	g, events, err := h.scim.PatchGroup(c.Request.Context(), c.GetString("tenantID"), c.Param("id"), in)
+	h.auditEvents(c, events)
+	if err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+	writeSCIM(c, http.StatusOK, g)
+}
+
+// DELETE /scim/v2/Groups/:id
+func (h *SCIMHandler) DeleteGroup(c *gin.Context) {
+	events, err := h.scim.DeleteGroup(c.Request.Context(), c.GetString("tenantID"), c.Param("id"))
+	h.auditEvents(c, events)
+	if err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// POST /scim/v2/Bulk
+func (h *SCIMHandler) Bulk(c *gin.Context) {
+	var in scim.BulkRequest
+	if !bindSCIM(c, &in) {
+		return
+	}
+	res, events, err := h.scim.Bulk(c.Request.Context(), c.GetString("tenantID"), in)
+	h.auditEvents(c, events)
+	if err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+	writeSCIM(c, http.StatusOK, res)
+}
+
+// ─── Configuration ─────────────────────────────────
+
+// GET /scim/config
+func (h *SCIMHandler) GetConfig(c *gin.Context) {
+	cfg, err := h.scim.GetConfig(c.GetString("tenantID"))
+	if err != nil {
+		writeSCIMConfigError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"config": cfg, "base_url": h.scim.BaseURL()})
+}
+
+// PUT /scim/config {enabled, group_mappings, default_role, default_team_id, reassign_to}
+func (h *SCIMHandler) SaveConfig(c *gin.Context) {
+	var in scim.ConfigInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	cfg, err := h.scim.SaveConfig(scimActor(c), in)
+	if err != nil {
+		writeSCIMConfigError(c, err)
+		return
+	}
+	h.audit(c, "UPDATE_SCIM_CONFIG", auditlog.MakeActor(c), auditlog.Target{Type: "tenant", ID: cfg.TenantID}, "SUCCESS",
+		fmt.Sprintf("SCIM enabled=%t, default role %s, group mappings %s", cfg.Enabled, cfg.DefaultRole, string(cfg.GroupMappings)))
+	c.JSON(http.StatusOK, gin.H{"config": cfg, "base_url": h.scim.BaseURL()})
+}
+
+// DELETE /scim/config
+// Disconnects the directory. Provisioned users keep their accounts.
+func (h *SCIMHandler) DeleteConfig(c *gin.Context) {
+	actor := scimActor(c)
+	if err := h.scim.DeleteConfig(actor); err != nil {
+		writeSCIMConfigError(c, err)
+		return
+	}
+	h.audit(c, "DELETE_SCIM_CONFIG", auditlog.MakeActor(c), auditlog.Target{Type: "tenant", ID: actor.TenantID}, "SUCCESS", "SCIM provisioning removed")
+	c.Status(http.StatusNoContent)
+}
+
+// POST /scim/token
+// Issues a new bearer token for the directory, replacing the old one. The
+// token is only shown in this response.
+func (h *SCIMHandler) IssueToken(c *gin.Context) {
+	token, cfg, err := h.scim.IssueToken(scimActor(c))
+	if err != nil {
+		writeSCIMConfigError(c, err)
+		return
+	}
+	h.audit(c, "ISSUE_SCIM_TOKEN", auditlog.MakeActor(c), auditlog.Target{Type: "tenant", ID: cfg.TenantID}, "SUCCESS",
+		fmt.Sprintf("SCIM token %s… issued", cfg.TokenPrefix))
+	c.JSON(http.StatusCreated, gin.H{"token": token, "config": cfg, "base_url": h.scim.BaseURL()})
+}
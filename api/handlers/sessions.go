@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"aegis-api/services_/auditlog"
+	"aegis-api/services_/auth/login"
+	"aegis-api/services_/auth/session"
+	"aegis-api/structs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sessionClient describes the device a request comes from.
+func sessionClient(c *gin.Context, deviceName string) session.Client {
+	return session.Client{
+		DeviceName: deviceName,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+}
+
+func (h *AuthHandler) auditSession(c *gin.Context, action string, actor auditlog.Actor, userID, status, description string) {
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action:      action,
+		Actor:       actor,
+		Target:      auditlog.Target{Type: "user", ID: userID},
+		Service:     "auth",
+		Status:      status,
+		Description: description,
+	})
+}
+
+// POST /auth/refresh {refreshToken, deviceName?}
+// Exchanges a refresh token for a new access token and refresh token.
+// The old refresh token stops working; using it again revokes the session.
+func (h *AuthHandler) RefreshHandler(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refreshToken" binding:"required"`
+		DeviceName   string `json:"deviceName"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, structs.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	resp, err := h.authService.Refresh(c.Request.Context(), req.RefreshToken, sessionClient(c, req.DeviceName))
+	if err != nil {
+		switch {
+		case errors.Is(err, session.ErrRefreshTokenReused):
+			h.auditSession(c, "REFRESH_TOKEN_REUSE", auditlog.Actor{IPAddress: c.ClientIP(), UserAgent: c.Request.UserAgent()}, "", "FAILED",
+				"A refresh token was presented twice; its session has been revoked")
+			c.JSON(http.StatusUnauthorized, structs.ErrorResponse{Error: "refresh_token_reused", Message: err.Error()})
+		case errors.Is(err, session.ErrInvalidRefreshToken),
+			errors.Is(err, session.ErrSessionRevoked),
+			errors.Is(err, login.ErrAccessRevoked):
+			c.JSON(http.StatusUnauthorized, structs.ErrorResponse{Error: "invalid_refresh_token", Message: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, structs.ErrorResponse{Error: "internal_error", Message: "An internal error occurred"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, structs.SuccessResponse{
+		Success: true,
+		Message: "Token refreshed",
+		Data:    resp,
+	})
+}
+
+// POST /auth/logout-all
+// Ends every session of the current user, this one included.
+func (h *AuthHandler) LogoutAllHandler(c *gin.Context) {
+	userID := c.GetString("userID")
+	n, err := h.sessions.RevokeUser(c.Request.Context(), userID, session.ReasonLogoutAll)
+	if err != nil {
+		h.auditSession(c, "LOGOUT_ALL", auditlog.MakeActor(c), userID, "FAILED", err.Error())
+		c.JSON(http.StatusInternalServerError, structs.ErrorResponse{Error: "internal_error", Message: "Failed to end sessions"})
+		return
+	}
+	h.auditSession(c, "LOGOUT_ALL", auditlog.MakeActor(c), userID, "SUCCESS", fmt.Sprintf("User logged out everywhere (%d sessions)", n))
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out of all sessions", "revoked": n})
+}
+
+// GET /auth/sessions
+func (h *AuthHandler) ListSessionsHandler(c *gin.Context) {
+	out, err := h.sessions.ListSessions(c.GetString("userID"), c.GetString("sessionID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, structs.ErrorResponse{Error: "internal_error", Message: "Failed to list sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// DELETE /auth/sessions/:sessionID
+func (h *AuthHandler) RevokeSessionHandler(c *gin.Context) {
+	userID := c.GetString("userID")
+	sessionID := c.Param("sessionID")
+	err := h.sessions.RevokeSession(c.Request.Context(), userID, sessionID, session.ReasonRevoked)
+	if errors.Is(err, session.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, structs.ErrorResponse{Error: "session_not_found", Message: err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, structs.ErrorResponse{Error: "internal_error", Message: "Failed to revoke session"})
+		return
+	}
+	h.auditSession(c, "SESSION_REVOKE", auditlog.MakeActor(c), userID, "SUCCESS", fmt.Sprintf("Session %s revoked", sessionID))
+	c.Status(http.StatusNoContent)
+}
+
+// tenantUser checks that the :userId of the request belongs to the
+// admin's tenant. On failure it writes the response and returns "".
+func (h *AuthHandler) tenantUser(c *gin.Context) string {
+	userID := c.Param("userId")
+	user, err := h.userRepo.GetUserByID(userID)
+	if err != nil || user == nil || user.TenantID == nil || user.TenantID.String() != c.GetString("tenantID") {
+		c.JSON(http.StatusNotFound, structs.ErrorResponse{Error: "user_not_found", Message: "User not found"})
+		return ""
+	}
+	return userID
+}
+
+// GET /users/:userId/sessions (admin)
+func (h *AuthHandler) ListUserSessionsHandler(c *gin.Context) {
+	userID := h.tenantUser(c)
+	if userID == "" {
+		return
+	}
+	out, err := h.sessions.ListSessions(userID, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, structs.ErrorResponse{Error: "internal_error", Message: "Failed to list sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// DELETE /users/:userId/sessions (admin)
+// Signs the user out everywhere.
+func (h *AuthHandler) RevokeUserSessionsHandler(c *gin.Context) {
+	userID := h.tenantUser(c)
+	if userID == "" {
+		return
+	}
+	n, err := h.sessions.RevokeUser(c.Request.Context(), userID, session.ReasonAdminRevoked)
+	if err != nil {
+		h.auditSession(c, "ADMIN_REVOKE_SESSIONS", auditlog.MakeActor(c), userID, "FAILED", err.Error())
+		c.JSON(http.StatusInternalServerError, structs.ErrorResponse{Error: "internal_error", Message: "Failed to revoke sessions"})
+		return
+	}
+	h.auditSession(c, "ADMIN_REVOKE_SESSIONS", auditlog.MakeActor(c), userID, "SUCCESS", fmt.Sprintf("Administrator revoked %d sessions", n))
+	c.JSON(http.StatusOK, gin.H{"message": "User signed out everywhere", "revoked": n})
+}
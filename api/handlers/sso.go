@@ -0,0 +1,404 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"aegis-api/services_/auditlog"
+	"aegis-api/services_/auth/login"
+	"aegis-api/services_/auth/registration"
+	"aegis-api/services_/auth/session"
+	"aegis-api/services_/auth/sso"
+	"aegis-api/structs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SSOHandler serves single sign-on through the tenant's identity
+// provider and its configuration.
+type SSOHandler struct {
+	auth     *login.AuthService
+	sessions session.Service
+	sso      sso.Service
+	// frontendURL is where browsers return after SAML endpoints the IdP
+	// sends them to.
+	frontendURL string
+	auditLogger *auditlog.AuditLogger
+}
+
+func NewSSOHandler(auth *login.AuthService, sessions session.Service, ssoService sso.Service, frontendURL string, auditLogger *auditlog.AuditLogger) *SSOHandler {
+	return &SSOHandler{
+		auth:        auth,
+		sessions:    sessions,
+		sso:         ssoService,
+		frontendURL: strings.TrimRight(frontendURL, "/"),
+		auditLogger: auditLogger,
+	}
+}
+
+func ssoActor(c *gin.Context) sso.Actor {
+	return sso.Actor{UserID: c.GetString("userID"), TenantID: c.GetString("tenantID")}
+}
+
+func (h *SSOHandler) audit(c *gin.Context, action string, actor auditlog.Actor, target auditlog.Target, status, description string) {
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action:      action,
+		Actor:       actor,
+		Target:      target,
+		Service:     "auth",
+		Status:      status,
+		Description: description,
+	})
+}
+
+func ssoError(err error) (status int, code, message string) {
+	switch {
+	case errors.Is(err, sso.ErrNotConfigured):
+		return http.StatusNotFound, "sso_not_configured", err.Error()
+	case errors.Is(err, sso.ErrSAMLUnavailable):
+		return http.StatusServiceUnavailable, "saml_unavailable", err.Error()
+	case errors.Is(err, sso.ErrInvalidConfig):
+		return http.StatusBadRequest, "invalid_config", err.Error()
+	case errors.Is(err, sso.ErrDiscovery):
+		return http.StatusBadGateway, "provider_unavailable", err.Error()
+	case errors.Is(err, sso.ErrInvalidState):
+		return http.StatusUnauthorized, "sso_state_expired", err.Error()
+	case errors.Is(err, sso.ErrInvalidTicket):
+		return http.StatusUnauthorized, "ticket_expired", err.Error()
+	case errors.Is(err, sso.ErrTokenExchange), errors.Is(err, sso.ErrInvalidIDToken), errors.Is(err, sso.ErrMissingClaim),
+		errors.Is(err, sso.ErrInvalidAssertion), errors.Is(err, sso.ErrAssertionReplayed):
+		return http.StatusUnauthorized, "sso_failed", "The identity provider's response was rejected"
+	case errors.Is(err, sso.ErrEmailNotVerified), errors.Is(err, sso.ErrDomainNotAllowed),
+		errors.Is(err, sso.ErrNoRole), errors.Is(err, sso.ErrTenantMismatch):
+		return http.StatusForbidden, "sso_access_denied", err.Error()
+	case errors.Is(err, login.ErrAccessRevoked):
+		return http.StatusUnauthorized, "access_revoked", err.Error()
+	}
+	return http.StatusInternalServerError, "internal_error", "An internal error occurred"
+}
+
+func writeSSOError(c *gin.Context, err error) {
+	status, code, message := ssoError(err)
+	writeError(c, status, code, message)
+}
+
+// redirectToFrontend sends a browser that came from the IdP back to the
+// client.
+func (h *SSOHandler) redirectToFrontend(c *gin.Context, path string, query url.Values) {
+	u := h.frontendURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	c.Redirect(http.StatusSeeOther, u)
+}
+
+// POST /auth/sso/discover {email}
+// Lists the tenants the email's domain signs in to with SSO, so the login
+// page can offer the provider.
+func (h *SSOHandler) Discover(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", "a valid email is required")
+		return
+	}
+	tenants, err := h.sso.Discover(req.Email)
+	if err != nil {
+		writeSSOError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tenants": tenants})
+}
+
+// GET /auth/sso/oidc/:tenantID/authorize
+// Starts a login: the client sends the browser to authorization_url.
+func (h *SSOHandler) Authorize(c *gin.Context) {
+	auth, err := h.sso.BeginOIDC(c.Request.Context(), c.Param("tenantID"))
+	if err != nil {
+		writeSSOError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, auth)
+}
+
+type ssoCallbackRequest struct {
+	State      string `json:"state" binding:"required"`
+	Code       string `json:"code" binding:"required"`
+	DeviceName string `json:"deviceName"`
+}
+
+// POST /auth/sso/oidc/callback {state, code, deviceName?}
+// Completes a login with the code the provider redirected back with. The
+// response is the same as a password login's, including MFA challenges.
+func (h *SSOHandler) Callback(c *gin.Context) {
+	var req ssoCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", "state and code are required")
+		return
+	}
+	res, err := h.sso.CompleteOIDC(c.Request.Context(), req.State, req.Code)
+	if err != nil {
+		h.audit(c, "SSO_LOGIN", anonymousActor(c), auditlog.Target{Type: "user"}, "FAILED", fmt.Sprintf("SSO login rejected: %v", err))
+		writeSSOError(c, err)
+		return
+	}
+	h.auditResult(c, res)
+	h.login(c, res.User, req.DeviceName)
+}
+
+// auditResult records what an SSO login did to the user's account.
+func (h *SSOHandler) auditResult(c *gin.Context, res *sso.Result) {
+	target := auditlog.Target{Type: "user", ID: res.User.ID.String()}
+	actor := auditlog.Actor{ID: res.User.ID.String(), Role: res.User.Role, Email: res.User.Email, IPAddress: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+	switch {
+	case res.Provisioned:
+		h.audit(c, "SSO_PROVISION_USER", actor, target, "SUCCESS",
+			fmt.Sprintf("Provisioned %s as %s in tenant %s at first %s login", res.User.Email, res.User.Role, res.TenantID, strings.ToUpper(res.Provider)))
+	case res.Linked:
+		h.audit(c, "SSO_LINK_IDENTITY", actor, target, "SUCCESS", "Linked existing account to the tenant's identity provider")
+	}
+	if res.RoleChanged {
+		h.audit(c, "SSO_SYNC_ROLE", actor, target, "SUCCESS", fmt.Sprintf("Role set to %s from identity provider claims", res.User.Role))
+	}
+}
+
+// login starts the session of a user the IdP authenticated, as a
+// password login would.
+func (h *SSOHandler) login(c *gin.Context, user *registration.User, deviceName string) {
+	target := auditlog.Target{Type: "user", ID: user.ID.String()}
+	resp, err := h.auth.LoginSSO(user, sessionClient(c, deviceName))
+	if err != nil {
+		actor := auditlog.Actor{ID: user.ID.String(), Role: user.Role, Email: user.Email, IPAddress: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+		h.audit(c, "SSO_LOGIN", actor, target, "FAILED", fmt.Sprintf("SSO login rejected: %v", err))
+		writeSSOError(c, err)
+		return
+	}
+	description, message := "User logged in with SSO", "Login successful"
+	if resp.MFARequired || resp.MFAEnrollmentRequired {
+		description, message = "SSO verified; awaiting second factor", "Multi-factor authentication required"
+	}
+	h.audit(c, "SSO_LOGIN", loginActor(c, resp), target, "SUCCESS", description)
+	c.JSON(http.StatusOK, structs.SuccessResponse{Success: true, Message: message, Data: resp})
+}
+
+// GET /auth/sso/saml/:tenantID/metadata
+// The service provider metadata to register with the tenant's IdP.
+func (h *SSOHandler) SAMLMetadata(c *gin.Context) {
+	md, err := h.sso.SAMLMetadata(c.Param("tenantID"))
+	if err != nil {
+		writeSSOError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "application/samlmetadata+xml", md)
+}
+
+// GET /auth/sso/saml/:tenantID/authorize
+// Starts a login: the client sends the browser to authorization_url,
+// which carries a signed AuthnRequest.
+func (h *SSOHandler) SAMLAuthorize(c *gin.Context) {
+	auth, err := h.sso.BeginSAML(c.Request.Context(), c.Param("tenantID"))
+	if err != nil {
+		writeSSOError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, auth)
+}
+
+// POST /auth/sso/saml/:tenantID/acs (form: SAMLResponse, RelayState)
+// The IdP posts the browser here. The browser is sent on to the client's
+// /sso/complete page with a ticket, or an error code.
+func (h *SSOHandler) SAMLACS(c *gin.Context) {
+	res, err := h.sso.CompleteSAML(c.Request.Context(), c.Param("tenantID"), c.PostForm("SAMLResponse"), c.PostForm("RelayState"))
+	if err != nil {
+		h.audit(c, "SSO_LOGIN", anonymousActor(c), auditlog.Target{Type: "user"}, "FAILED", fmt.Sprintf("SAML login rejected: %v", err))
+		_, code, _ := ssoError(err)
+		h.redirectToFrontend(c, "/sso/complete", url.Values{"error": {code}})
+		return
+	}
+	h.auditResult(c, res)
+	ticket, err := h.sso.IssueTicket(c.Request.Context(), res)
+	if err != nil {
+		h.redirectToFrontend(c, "/sso/complete", url.Values{"error": {"internal_error"}})
+		return
+	}
+	h.redirectToFrontend(c, "/sso/complete", url.Values{"ticket": {ticket}})
+}
+
+// POST /auth/sso/complete {ticket, deviceName?}
+// Exchanges the ticket from the ACS redirect for the login response.
+func (h *SSOHandler) Complete(c *gin.Context) {
+	var req struct {
+		Ticket     string `json:"ticket" binding:"required"`
+		DeviceName string `json:"deviceName"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", "ticket is required")
+		return
+	}
+	user, err := h.sso.RedeemTicket(c.Request.Context(), req.Ticket)
+	if err != nil {
+		writeSSOError(c, err)
+		return
+	}
+	h.login(c, user, req.DeviceName)
+}
+
+// POST /auth/sso/saml/logout
+// Ends the current session and, when the user signed in with SAML,
+// returns the IdP logout URL to send the browser to.
+func (h *SSOHandler) SAMLLogout(c *gin.Context) {
+	userID, sessionID := c.GetString("userID"), c.GetString("sessionID")
+	if sessionID != "" {
+		if err := h.sessions.RevokeSession(c.Request.Context(), userID, sessionID, session.ReasonLogout); err != nil {
+			writeSSOError(c, err)
+			return
+		}
+	}
+	redirect, err := h.sso.BeginSAMLLogout(c.Request.Context(), c.GetString("tenantID"), userID)
+	if err != nil {
+		writeSSOError(c, err)
+		return
+	}
+	h.audit(c, "USER_LOGOUT", auditlog.MakeActor(c), auditlog.Target{Type: "user", ID: userID}, "SUCCESS", "User logged out (SAML single logout)")
+	c.JSON(http.StatusOK, gin.H{"redirect_url": redirect})
+}
+
+// GET /auth/sso/saml/:tenantID/slo (redirect binding)
+// Logout messages from the IdP. A LogoutRequest ends the user's sessions
+// here and answers the IdP; a LogoutResponse returns to the client.
+func (h *SSOHandler) SAMLSingleLogout(c *gin.Context) {
+	tenantID := c.Param("tenantID")
+	out, err := h.sso.HandleSAMLLogout(c.Request.Context(), tenantID, c.Request.URL.RawQuery)
+	if err != nil {
+		h.audit(c, "SSO_SINGLE_LOGOUT", anonymousActor(c), auditlog.Target{Type: "tenant", ID: tenantID}, "FAILED", fmt.Sprintf("Logout message rejected: %v", err))
+		writeSSOError(c, err)
+		return
+	}
+	for _, userID := range out.UserIDs {
+		n, err := h.sessions.RevokeUser(c.Request.Context(), userID, session.ReasonSingleLogout)
+		status, description := "SUCCESS", fmt.Sprintf("IdP logout ended %d session(s)", n)
+		if err != nil {
+			status, description = "FAILED", fmt.Sprintf("IdP logout could not end sessions: %v", err)
+		}
+		h.audit(c, "SSO_SINGLE_LOGOUT", anonymousActor(c), auditlog.Target{Type: "user", ID: userID}, status, description)
+	}
+	if out.RedirectURL != "" {
+		c.Redirect(http.StatusFound, out.RedirectURL)
+		return
+	}
+	h.redirectToFrontend(c, "/login", nil)
+}
+
+// GET /sso/oidc
+func (h *SSOHandler) GetOIDCConfig(c *gin.Context) {
+	cfg, err := h.sso.GetOIDCConfig(c.GetString("tenantID"))
+	if err != nil {
+		writeSSOError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, cfg)
+}
+
+// PUT /sso/oidc {enabled, issuer, client_id, client_secret?, redirect_uri, scopes,
+// role_claim, role_mappings, default_role, team_claim, team_mappings,
+// default_team_id, allowed_domains, sync_roles}
+func (h *SSOHandler) SaveOIDCConfig(c *gin.Context) {
+	var in sso.OIDCConfigInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	cfg, err := h.sso.SaveOIDCConfig(c.Request.Context(), ssoActor(c), in)
+	if err != nil {
+		writeSSOError(c, err)
+		return
+	}
+	h.audit(c, "UPDATE_SSO_CONFIG", auditlog.MakeActor(c), auditlog.Target{Type: "tenant", ID: cfg.TenantID}, "SUCCESS",
+		fmt.Sprintf("OIDC provider %s (client %s) enabled=%t, roles %s, allowed domains %s",
+			cfg.Issuer, cfg.ClientID, cfg.Enabled, string(cfg.RoleMappings), string(cfg.AllowedDomains)))
+	c.JSON(http.StatusOK, cfg)
+}
+
+// DELETE /sso/oidc
+func (h *SSOHandler) DeleteOIDCConfig(c *gin.Context) {
+	actor := ssoActor(c)
+	if err := h.sso.DeleteOIDCConfig(actor); err != nil {
+		writeSSOError(c, err)
+		return
+	}
+	h.audit(c, "DELETE_SSO_CONFIG", auditlog.MakeActor(c), auditlog.Target{Type: "tenant", ID: actor.TenantID}, "SUCCESS", "OIDC provider removed")
+	c.Status(http.StatusNoContent)
+}
+
+// GET /sso/saml
+func (h *SSOHandler) GetSAMLConfig(c *gin.Context) {
+	cfg, err := h.sso.GetSAMLConfig(c.GetString("tenantID"))
+	if err != nil {
+		writeSSOError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, cfg)
+}
+
+// PUT /sso/saml {enabled, idp_metadata | idp_entity_id, idp_sso_url,
+// idp_slo_url, idp_certificates; email_attribute, name_attribute,
+// role_attribute, role_mappings, default_role, team_attribute,
+// team_mappings, default_team_id, allowed_domains, sync_roles}
+func (h *SSOHandler) SaveSAMLConfig(c *gin.Context) {
+	var in sso.SAMLConfigInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	cfg, err := h.sso.SaveSAMLConfig(ssoActor(c), in)
+	if err != nil {
+		writeSSOError(c, err)
+		return
+	}
+	h.audit(c, "UPDATE_SSO_CONFIG", auditlog.MakeActor(c), auditlog.Target{Type: "tenant", ID: cfg.TenantID}, "SUCCESS",
+		fmt.Sprintf("SAML IdP %s enabled=%t, roles %s, allowed domains %s",
+			cfg.IdPEntityID, cfg.Enabled, string(cfg.RoleMappings), string(cfg.AllowedDomains)))
+	c.JSON(http.StatusOK, cfg)
+}
+
+// DELETE /sso/saml
+func (h *SSOHandler) DeleteSAMLConfig(c *gin.Context) {
+	actor := ssoActor(c)
+	if err := h.sso.DeleteSAMLConfig(actor); err != nil {
+		writeSSOError(c, err)
+		return
+	}
+	h.audit(c, "DELETE_SSO_CONFIG", auditlog.MakeActor(c), auditlog.Target{Type: "tenant", ID: actor.TenantID}, "SUCCESS", "SAML IdP removed")
+	c.Status(http.StatusNoContent)
+}
+
+// GET /auth-settings
+func (h *SSOHandler) GetSettings(c *gin.Context) {
+	st, err := h.sso.GetSettings(c.GetString("tenantID"))
+	if err != nil {
+		writeSSOError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, st)
+}
+
+// PUT /auth-settings {local_passwords_disabled}
+func (h *SSOHandler) UpdateSettings(c *gin.Context) {
+	var in sso.SettingsInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	st, err := h.sso.UpdateSettings(ssoActor(c), in)
+	if err != nil {
+		writeSSOError(c, err)
+		return
+	}
+	h.audit(c, "UPDATE_AUTH_SETTINGS", auditlog.MakeActor(c), auditlog.Target{Type: "tenant", ID: st.TenantID}, "SUCCESS",
+		fmt.Sprintf("Local password login disabled=%t", st.LocalPasswordsDisabled))
+	c.JSON(http.StatusOK, st)
+}
@@ -39,7 +39,7 @@ func (h *TimelineAIHandler) GetEventSuggestions(c *gin.Context) {
 		return
 	}
 
-	result, err := h.Service.GetEventSuggestions(c.Request.Context(), &req)
+	result, err := h.Service.GetEventSuggestions(aiContext(c), &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -72,7 +72,7 @@ func (h *TimelineAIHandler) GetSeverityRecommendation(c *gin.Context) {
 		return
 	}
 
-	severity, confidence, err := h.Service.GetSeverityRecommendation(c.Request.Context(), description)
+	severity, confidence, err := h.Service.GetSeverityRecommendation(aiContext(c), description)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -109,7 +109,7 @@ func (h *TimelineAIHandler) GetTagSuggestions(c *gin.Context) {
 		return
 	}
 
-	tags, err := h.Service.GetTagSuggestions(c.Request.Context(), description)
+	tags, err := h.Service.GetTagSuggestions(aiContext(c), description)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -139,7 +139,7 @@ func (h *TimelineAIHandler) GetNextSteps(c *gin.Context) {
 		return
 	}
 
-	steps, err := h.Service.SuggestNextSteps(c.Request.Context(), caseID)
+	steps, err := h.Service.SuggestNextSteps(aiContext(c), caseID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -177,7 +177,7 @@ func (h *TimelineAIHandler) AnalyzeEvent(c *gin.Context) {
 		return
 	}
 
-	result, err := h.Service.AnalyzeEventContext(c.Request.Context(), caseID, eventText)
+	result, err := h.Service.AnalyzeEventContext(aiContext(c), caseID, eventText)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -214,7 +214,7 @@ func (h *TimelineAIHandler) AnalyzeCaseProgress(c *gin.Context) {
 		return
 	}
 
-	analysis, err := h.Service.AnalyzeCaseProgress(c.Request.Context(), caseID)
+	analysis, err := h.Service.AnalyzeCaseProgress(aiContext(c), caseID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -260,7 +260,7 @@ func (h *TimelineAIHandler) CorrelateEvidence(c *gin.Context) {
 		return
 	}
 
-	correlatedEvidence, err := h.Service.CorrelateEvidence(c.Request.Context(), caseID, eventDescription)
+	correlatedEvidence, err := h.Service.CorrelateEvidence(aiContext(c), caseID, eventDescription)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -300,7 +300,7 @@ func (h *TimelineAIHandler) SubmitFeedback(c *gin.Context) {
 		return
 	}
 
-	err := h.Service.RecordFeedback(c.Request.Context(), feedback.AnalysisID, &feedback)
+	err := h.Service.RecordFeedback(aiContext(c), feedback.AnalysisID, &feedback)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -319,7 +319,7 @@ func (h *TimelineAIHandler) SubmitFeedback(c *gin.Context) {
 // @Failure 500 {object} map[string]string
 // @Router /timeline/ai/status [get]
 func (h *TimelineAIHandler) GetModelStatus(c *gin.Context) {
-	status, err := h.Service.GetModelStatus(c.Request.Context())
+	status, err := h.Service.GetModelStatus(aiContext(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -356,7 +356,7 @@ func (h *TimelineAIHandler) UpdateModelConfig(c *gin.Context) {
 		return
 	}
 
-	err := h.Service.UpdateModelConfig(c.Request.Context(), &config)
+	err := h.Service.UpdateModelConfig(aiContext(c), &config)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -416,7 +416,7 @@ func (h *TimelineAIHandler) ExtractIOCs(c *gin.Context) {
 	}
 
 	// Call service
-	iocs, err := h.Service.ExtractIOCs(c.Request.Context(), req.Text)
+	iocs, err := h.Service.ExtractIOCs(aiContext(c), req.Text)
 	if err != nil {
 		fmt.Printf("[ExtractIOCs] Failed to extract IOCs: %v\n", err)
 
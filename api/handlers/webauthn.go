@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"aegis-api/middleware"
+	"aegis-api/services_/auditlog"
+	"aegis-api/services_/auth/login"
+	"aegis-api/services_/auth/mfa_policy"
+	"aegis-api/services_/auth/webauthn"
+	"aegis-api/structs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebAuthnHandler serves passkey registration and management, passkey
+// login and step-up, and the tenant's passkey policy.
+type WebAuthnHandler struct {
+	auth        *login.AuthService
+	passkeys    webauthn.Service
+	mfa         mfa_policy.Service
+	auditLogger *auditlog.AuditLogger
+}
+
+func NewWebAuthnHandler(auth *login.AuthService, passkeys webauthn.Service, mfa mfa_policy.Service, auditLogger *auditlog.AuditLogger) *WebAuthnHandler {
+	return &WebAuthnHandler{auth: auth, passkeys: passkeys, mfa: mfa, auditLogger: auditLogger}
+}
+
+func webauthnActor(c *gin.Context) webauthn.Actor {
+	return webauthn.Actor{
+		UserID:      c.GetString("userID"),
+		TenantID:    c.GetString("tenantID"),
+		Email:       c.GetString("email"),
+		DisplayName: c.GetString("fullName"),
+	}
+}
+
+func (h *WebAuthnHandler) audit(c *gin.Context, action string, actor auditlog.Actor, target auditlog.Target, status, description string) {
+	h.auditLogger.Log(c, auditlog.AuditLog{
+		Action:      action,
+		Actor:       actor,
+		Target:      target,
+		Service:     "auth",
+		Status:      status,
+		Description: description,
+	})
+}
+
+func writeWebAuthnError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, webauthn.ErrChallengeExpired):
+		writeError(c, http.StatusUnauthorized, "challenge_expired", err.Error())
+	case errors.Is(err, webauthn.ErrInvalidResponse), errors.Is(err, webauthn.ErrInvalidSignature):
+		writeError(c, http.StatusUnauthorized, "invalid_passkey_response", err.Error())
+	case errors.Is(err, webauthn.ErrCredentialRevoked), errors.Is(err, webauthn.ErrCloneDetected):
+		writeError(c, http.StatusUnauthorized, "passkey_revoked", err.Error())
+	case errors.Is(err, webauthn.ErrUserVerificationRequired):
+		writeError(c, http.StatusUnauthorized, "user_verification_required", err.Error())
+	case errors.Is(err, webauthn.ErrCredentialNotFound):
+		writeError(c, http.StatusNotFound, "passkey_not_found", err.Error())
+	case errors.Is(err, webauthn.ErrCredentialExists):
+		writeError(c, http.StatusConflict, "passkey_exists", err.Error())
+	case errors.Is(err, webauthn.ErrAttestation), errors.Is(err, webauthn.ErrAttestationRequired),
+		errors.Is(err, webauthn.ErrAuthenticatorNotAllowed), errors.Is(err, webauthn.ErrUnsupportedKey):
+		writeError(c, http.StatusForbidden, "authenticator_not_accepted", err.Error())
+	case errors.Is(err, webauthn.ErrInvalidName):
+		writeError(c, http.StatusBadRequest, "invalid_name", err.Error())
+	case errors.Is(err, webauthn.ErrInvalidPolicy):
+		writeError(c, http.StatusBadRequest, "invalid_policy", err.Error())
+	default:
+		// Login and step-up errors are shared with TOTP.
+		writeMFAError(c, err)
+	}
+}
+
+type passkeyLoginRequest struct {
+	MFAToken    string                     `json:"mfaToken" binding:"required"`
+	ChallengeID string                     `json:"challengeId"`
+	Credential  webauthn.AssertionResponse `json:"credential"`
+	DeviceName  string                     `json:"deviceName"`
+}
+
+// POST /auth/mfa/webauthn/options {mfaToken}
+func (h *WebAuthnHandler) LoginOptions(c *gin.Context) {
+	var req passkeyLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", "mfaToken is required")
+		return
+	}
+	opts, err := h.auth.BeginPasskeyMFA(c.Request.Context(), req.MFAToken)
+	if err != nil {
+		writeWebAuthnError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, opts)
+}
+
+// POST /auth/mfa/webauthn/verify {mfaToken, challengeId, credential, deviceName?}
+// Second step of login with a passkey.
+func (h *WebAuthnHandler) LoginVerify(c *gin.Context) {
+	var req passkeyLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ChallengeID == "" {
+		writeError(c, http.StatusBadRequest, "invalid_request", "mfaToken, challengeId and credential are required")
+		return
+	}
+	resp, err := h.auth.CompletePasskeyMFA(c.Request.Context(), req.MFAToken, req.ChallengeID, req.Credential, sessionClient(c, req.DeviceName))
+	if err != nil {
+		h.audit(c, "MFA_VERIFY", anonymousActor(c), auditlog.Target{Type: "user"}, "FAILED", fmt.Sprintf("Passkey rejected: %v", err))
+		writeWebAuthnError(c, err)
+		return
+	}
+	h.audit(c, "MFA_VERIFY", loginActor(c, resp), auditlog.Target{Type: "user", ID: resp.ID}, "SUCCESS", "User logged in with a passkey")
+	c.JSON(http.StatusOK, structs.SuccessResponse{Success: true, Message: "Login successful", Data: resp})
+}
+
+type passkeyEnrollRequest struct {
+	MFAToken    string                        `json:"mfaToken" binding:"required"`
+	ChallengeID string                        `json:"challengeId"`
+	Name        string                        `json:"name"`
+	Credential  webauthn.RegistrationResponse `json:"credential"`
+	DeviceName  string                        `json:"deviceName"`
+}
+
+// POST /auth/mfa/webauthn/enroll {mfaToken}
+// For users whose role requires MFA but who have none: options to
+// register a passkey as their first factor.
+func (h *WebAuthnHandler) EnrollOptions(c *gin.Context) {
+	var req passkeyEnrollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", "mfaToken is required")
+		return
+	}
+	opts, err := h.auth.BeginPasskeyEnrollment(c.Request.Context(), req.MFAToken)
+	if err != nil {
+		writeWebAuthnError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, opts)
+}
+
+// POST /auth/mfa/webauthn/enroll/confirm {mfaToken, challengeId, name, credential, deviceName?}
+func (h *WebAuthnHandler) EnrollConfirm(c *gin.Context) {
+	var req passkeyEnrollRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ChallengeID == "" {
+		writeError(c, http.StatusBadRequest, "invalid_request", "mfaToken, challengeId and credential are required")
+		return
+	}
+	resp, err := h.auth.CompletePasskeyEnrollment(c.Request.Context(), req.MFAToken, req.ChallengeID, req.Name, req.Credential, sessionClient(c, req.DeviceName))
+	if err != nil {
+		h.audit(c, "PASSKEY_REGISTER", anonymousActor(c), auditlog.Target{Type: "user"}, "FAILED", err.Error())
+		writeWebAuthnError(c, err)
+		return
+	}
+	h.audit(c, "PASSKEY_REGISTER", loginActor(c, resp), auditlog.Target{Type: "user", ID: resp.ID}, "SUCCESS",
+		fmt.Sprintf("Passkey %q registered at login as required by tenant policy", req.Name))
+	c.JSON(http.StatusOK, structs.SuccessResponse{Success: true, Message: "Login successful", Data: resp})
+}
+
+// requireStepUpIfEnrolled lets a user without MFA register their first
+// passkey, but makes an enrolled user re-verify before adding another:
+// a stolen access token alone must not be able to add a factor.
+func (h *WebAuthnHandler) requireStepUpIfEnrolled(c *gin.Context) bool {
+	st, err := h.mfa.Status(mfaActor(c))
+	if err != nil {
+		writeMFAError(c, err)
+		return false
+	}
+	if st.Enrolled && !middleware.HasFreshMFA(c) {
+		middleware.AbortStepUpRequired(c)
+		return false
+	}
+	return true
+}
+
+// POST /auth/passkeys/register/options
+func (h *WebAuthnHandler) RegisterOptions(c *gin.Context) {
+	if !h.requireStepUpIfEnrolled(c) {
+		return
+	}
+	opts, err := h.passkeys.BeginRegistration(c.Request.Context(), webauthnActor(c))
+	if err != nil {
+		writeWebAuthnError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, opts)
+}
+
+// POST /auth/passkeys/register {challengeId, name, credential}
+func (h *WebAuthnHandler) Register(c *gin.Context) {
+	var req struct {
+		ChallengeID string                        `json:"challengeId" binding:"required"`
+		Name        string                        `json:"name" binding:"required"`
+		Credential  webauthn.RegistrationResponse `json:"credential"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if !h.requireStepUpIfEnrolled(c) {
+		return
+	}
+	actor := webauthnActor(c)
+	cred, err := h.passkeys.FinishRegistration(c.Request.Context(), actor, req.ChallengeID, req.Name, req.Credential)
+	if err != nil {
+		h.audit(c, "PASSKEY_REGISTER", auditlog.MakeActor(c), auditlog.Target{Type: "user", ID: actor.UserID}, "FAILED", err.Error())
+		writeWebAuthnError(c, err)
+		return
+	}
+	h.audit(c, "PASSKEY_REGISTER", auditlog.MakeActor(c), auditlog.Target{Type: "passkey", ID: cred.ID}, "SUCCESS",
+		fmt.Sprintf("Passkey %q registered (attestation %s, AAGUID %s)", cred.Name, cred.AttestationFormat, cred.AAGUID))
+	c.JSON(http.StatusCreated, cred)
+}
+
+// GET /auth/passkeys
+func (h *WebAuthnHandler) List(c *gin.Context) {
+	creds, err := h.passkeys.ListCredentials(c.GetString("userID"))
+	if err != nil {
+		writeWebAuthnError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"passkeys": creds})
+}
+
+// PATCH /auth/passkeys/:passkeyID {name}
+func (h *WebAuthnHandler) Rename(c *gin.Context) {
+	var req struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	cred, err := h.passkeys.RenameCredential(c.GetString("userID"), c.Param("passkeyID"), req.Name)
+	if err != nil {
+		writeWebAuthnError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, cred)
+}
+
+// DELETE /auth/passkeys/:passkeyID (step-up)
+func (h *WebAuthnHandler) Revoke(c *gin.Context) {
+	target := auditlog.Target{Type: "passkey", ID: c.Param("passkeyID")}
+	cred, err := h.passkeys.RevokeCredential(c.GetString("userID"), c.Param("passkeyID"))
+	if err != nil {
+		h.audit(c, "PASSKEY_REVOKE", auditlog.MakeActor(c), target, "FAILED", err.Error())
+		writeWebAuthnError(c, err)
+		return
+	}
+	h.audit(c, "PASSKEY_REVOKE", auditlog.MakeActor(c), target, "SUCCESS", fmt.Sprintf("Passkey %q revoked", cred.Name))
+	c.Status(http.StatusNoContent)
+}
+
+// POST /auth/mfa/step-up/webauthn/options
+func (h *WebAuthnHandler) StepUpOptions(c *gin.Context) {
+	opts, err := h.auth.BeginPasskeyStepUp(c.Request.Context(), c.GetString("userID"))
+	if err != nil {
+		writeWebAuthnError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, opts)
+}
+
+// POST /auth/mfa/step-up/webauthn {challengeId, credential}
+// Step-up with a passkey; answers like POST /auth/mfa/step-up.
+func (h *WebAuthnHandler) StepUp(c *gin.Context) {
+	var req struct {
+		ChallengeID string                     `json:"challengeId" binding:"required"`
+		Credential  webauthn.AssertionResponse `json:"credential"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	userID := c.GetString("userID")
+	target := auditlog.Target{Type: "user", ID: userID}
+	resp, err := h.auth.PasskeyStepUp(c.Request.Context(), userID, c.GetString("sessionID"), req.ChallengeID, req.Credential)
+	if err != nil {
+		h.audit(c, "MFA_STEP_UP", auditlog.MakeActor(c), target, "FAILED", err.Error())
+		writeWebAuthnError(c, err)
+		return
+	}
+	h.audit(c, "MFA_STEP_UP", auditlog.MakeActor(c), target, "SUCCESS", "Step-up verified with a passkey")
+	c.JSON(http.StatusOK, structs.SuccessResponse{Success: true, Message: "Step-up verified", Data: resp})
+}
+
+// GET /webauthn-policy
+func (h *WebAuthnHandler) GetPolicy(c *gin.Context) {
+	p, err := h.passkeys.GetPolicy(c.GetString("tenantID"))
+	if err != nil {
+		writeWebAuthnError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// PUT /webauthn-policy {attestation, allowed_aaguids, user_verification}
+func (h *WebAuthnHandler) UpdatePolicy(c *gin.Context) {
+	var in webauthn.PolicyInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	p, err := h.passkeys.UpdatePolicy(webauthnActor(c), in)
+	if err != nil {
+		writeWebAuthnError(c, err)
+		return
+	}
+	h.audit(c, "UPDATE_WEBAUTHN_POLICY", auditlog.MakeActor(c), auditlog.Target{Type: "tenant", ID: p.TenantID}, "SUCCESS",
+		fmt.Sprintf("Passkey attestation %s, user verification %s, allowed authenticators %s", p.Attestation, p.UserVerification, string(p.AllowedAAGUIDs)))
+	c.JSON(http.StatusOK, p)
+}
@@ -4,6 +4,7 @@ import (
 	"aegis-api/db"
 	"aegis-api/services_/admin/delete_user"
 	"context"
+	"crypto/rsa"
 	"crypto/tls"
 	"encoding/base64"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -28,32 +30,50 @@ import (
 	annotationthreads "aegis-api/services_/annotation_threads/threads"
 	"aegis-api/services_/auditlog"
 	"aegis-api/services_/auth/login"
+	"aegis-api/services_/auth/mfa_policy"
 	"aegis-api/services_/auth/registration"
 	"aegis-api/services_/auth/reset_password"
+	"aegis-api/services_/auth/session"
+	"aegis-api/services_/auth/apikey"
+	"aegis-api/services_/auth/abac"
+	"aegis-api/services_/auth/authz"
+	"aegis-api/services_/auth/scim"
+	"aegis-api/services_/auth/sso"
+	"aegis-api/services_/auth/webauthn"
 	"aegis-api/services_/case/ListActiveCases"
 	"aegis-api/services_/case/ListCases"
 	"aegis-api/services_/case/ListClosedCases"
 	"aegis-api/services_/case/ListUsers"
 	"aegis-api/services_/case/case_assign"
+	"aegis-api/services_/case/case_closure"
 	"aegis-api/services_/case/case_creation"
 	"aegis-api/services_/case/case_deletion"
 	"aegis-api/services_/case/case_evidence_totals"
 	"aegis-api/services_/case/case_tags"
 	update_case "aegis-api/services_/case/case_update"
+	"aegis-api/services_/case/invitations"
 	"aegis-api/services_/case/listArchiveCases"
 	"aegis-api/services_/chain_of_custody"
 	"aegis-api/services_/chat"
 	evidencecount "aegis-api/services_/evidence/evidence_count"
 	"aegis-api/services_/evidence/evidence_download"
+	"aegis-api/services_/detection_rules"
 	"aegis-api/services_/evidence/evidence_tag"
 	"aegis-api/services_/evidence/evidence_viewer"
 	"aegis-api/services_/evidence/metadata"
 	"aegis-api/services_/evidence/upload"
+	"aegis-api/services_/case_qa"
+	"aegis-api/services_/llm"
 	"aegis-api/services_/notification"
 	timelineai "aegis-api/services_/timeline/timeline_ai"
 
+	"aegis-api/services_/redaction"
 	"aegis-api/services_/report"
+	"aegis-api/services_/report/jobs"
 	report_ai_assistance "aegis-api/services_/report/report_ai_assistance"
+	"aegis-api/services_/report/report_templates"
+	"aegis-api/services_/report/review"
+	"aegis-api/services_/report/signing"
 	"aegis-api/services_/report/update_status"
 
 	"aegis-api/services_/timeline"
@@ -213,6 +233,9 @@ func main() {
 
 	//--Gin setup for HTTPS--
 	r := gin.Default()
+	if err := r.SetTrustedProxies(middleware.TrustedProxies()); err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
 	// Enforce HTTPS and add HSTS headers
 	r.Use(gin.Recovery())
 
@@ -244,6 +267,10 @@ func main() {
 	listArchivedCasesRepo := listArchiveCases.NewArchiveCaseRepository(db.DB)
 	listCasesRepo := ListCases.NewGormCaseQueryRepository(db.DB)
 	iocRepo := graphicalmapping.NewIOCRepository(db.DB)
+	entityGraphRepo := graphicalmapping.NewEntityGraphRepository(db.DB)
+	if err := entityGraphRepo.AutoMigrate(); err != nil {
+		log.Fatalf("failed migrating investigation graph: %v", err)
+	}
 
 	//timeline
 	timelineRepo := timeline.NewRepository(db.DB)
@@ -260,7 +287,6 @@ func main() {
 
 	// ─── Services ───────────────────────────────────────────────
 	regService := registration.NewRegistrationService(userRepo, tenantRepo, teamRepo)
-	authService := login.NewAuthService(userRepo)
 	resetService := reset_password.NewPasswordResetService(resetTokenRepo, userRepo, emailSender)
 	caseService := case_creation.NewCaseService(caseRepo, notificationService, hub)
 	caseAssignService := case_assign.NewCaseAssignmentService(caseAssignRepo, adminChecker, userAdapter, notificationService, hub)
@@ -308,6 +334,7 @@ func main() {
 
 	// ioc
 	iocService := graphicalmapping.NewIOCService(iocRepo)
+	investigationGraphService := graphicalmapping.NewInvestigationGraphService(entityGraphRepo)
 	//timeline
 	timelineService := timeline.NewService(timelineRepo)
 
@@ -315,7 +342,6 @@ func main() {
 	auditLogService := auditlog.NewAuditLogService(mongoDatabase, userRepo)
 	// ─── Handlers ───────────────────────────────────────────────
 	adminHandler := handlers.NewAdminService(regService, listUserService, nil, userDeleteService, auditLogger, *auditLogService)
-	authHandler := handlers.NewAuthHandler(authService, resetService, userRepo, auditLogger)
 	// Replace lines 316-321:
 
 	// Get the database as *sqlx.DB
@@ -351,6 +377,76 @@ func main() {
 		cacheClient = cache.NewMemory()
 	}
 
+	// ─── Sessions ───────────────────────────────────────────────
+	sessionRepo := session.NewRepository(db.DB)
+	if err := sessionRepo.AutoMigrate(); err != nil {
+		log.Fatalf("failed migrating sessions: %v", err)
+	}
+	sessionService := session.NewService(sessionRepo, cacheClient, session.Options{})
+	middleware.SetSessionValidator(sessionService)
+	mfaPolicyRepo := mfa_policy.NewRepository(db.DB)
+	if err := mfaPolicyRepo.AutoMigrate(); err != nil {
+		log.Fatalf("failed migrating MFA policies: %v", err)
+	}
+	webauthnRepo := webauthn.NewRepository(db.DB)
+	if err := webauthnRepo.AutoMigrate(); err != nil {
+		log.Fatalf("failed migrating passkeys: %v", err)
+	}
+	webauthnOrigins := []string{"http://localhost:5173", "http://127.0.0.1:5173"}
+	if v := os.Getenv("WEBAUTHN_ORIGINS"); v != "" {
+		webauthnOrigins = strings.Split(v, ",")
+	}
+	webauthnRPID := os.Getenv("WEBAUTHN_RP_ID")
+	if webauthnRPID == "" {
+		webauthnRPID = "localhost"
+	}
+	webauthnService := webauthn.NewService(webauthnRepo, cacheClient, webauthn.Options{
+		RPID:    webauthnRPID,
+		RPName:  "AEGIS",
+		Origins: webauthnOrigins,
+	})
+	mfaPolicyService := mfa_policy.NewService(mfaPolicyRepo, verificationService, webauthnService, cacheClient)
+	middleware.SetMFAEnrollment(mfaPolicyService)
+	ssoRepo := sso.NewRepository(db.DB)
+	if err := ssoRepo.AutoMigrate(); err != nil {
+		log.Fatalf("failed migrating SSO: %v", err)
+	}
+	// SAML requests and logout messages are signed with SAML_SP_CERT_FILE /
+	// SAML_SP_KEY_FILE (RSA); without them only OIDC is offered.
+	samlOptions := sso.SAMLOptions{BaseURL: os.Getenv("SAML_SP_BASE_URL")}
+	if samlOptions.BaseURL == "" {
+		samlOptions.BaseURL = "https://localhost:8443/api/v1/auth/sso/saml"
+	}
+	if certFile, keyFile := os.Getenv("SAML_SP_CERT_FILE"), os.Getenv("SAML_SP_KEY_FILE"); certFile != "" && keyFile != "" {
+		pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			log.Fatalf("❌ SAML SP key pair: %v", err)
+		}
+		key, ok := pair.PrivateKey.(*rsa.PrivateKey)
+		if !ok || pair.Leaf == nil {
+			log.Fatal("❌ SAML SP key must be an RSA key with its certificate")
+		}
+		samlOptions.Key, samlOptions.Certificate = key, pair.Leaf
+	} else {
+		log.Println("⚠️ SAML_SP_CERT_FILE/SAML_SP_KEY_FILE not set; SAML single sign-on is disabled")
+	}
+	ssoService := sso.NewService(ssoRepo, userRepo, regService, cacheClient, sso.Options{SAML: samlOptions})
+	authService := login.NewAuthService(userRepo, sessionService, mfaPolicyService, webauthnService, ssoService)
+	scimRepo := scim.NewRepository(db.DB)
+	if err := scimRepo.AutoMigrate(); err != nil {
+		log.Fatalf("failed migrating SCIM: %v", err)
+	}
+	// SCIM_BASE_URL is the public URL of the SCIM endpoint that resource
+	// locations are rooted at.
+	scimService := scim.NewService(scimRepo, userRepo, regService, sessionService, scim.Options{BaseURL: os.Getenv("SCIM_BASE_URL")})
+	apiKeyRepo := apikey.NewRepository(db.DB)
+	if err := apiKeyRepo.AutoMigrate(); err != nil {
+		log.Fatalf("failed migrating API keys: %v", err)
+	}
+	apiKeyService := apikey.NewService(apiKeyRepo, apikey.Options{})
+	middleware.SetAPIKeyAuthenticator(apiKeyService)
+	authHandler := handlers.NewAuthHandler(authService, sessionService, resetService, userRepo, auditLogger)
+
 	//pass separate services explicitly
 	caseHandler := handlers.NewCaseHandler(
 		caseServices,
@@ -365,9 +461,17 @@ func main() {
 	)
 	//ioc
 	iocHandler := handlers.NewIOCHandler(iocService)
+	investigationGraphHandler := handlers.NewInvestigationGraphHandler(investigationGraphService)
 	//timeline
 	timelineHandler := handlers.NewTimelineHandler(timelineService)
 
+	// ─── Classification (labels, clearances, need-to-know) ──────
+	abacRepo := abac.NewRepository(db.DB)
+	if err := abacRepo.AutoMigrate(); err != nil {
+		log.Fatalf("failed migrating classification policy: %v", err)
+	}
+	abacService := abac.NewService(abacRepo)
+
 	//Timeline
 	// ─── Evidence Upload/Download/Metadata ──────────────────────
 	evidenceHandler := handlers.NewEvidenceHandler(evidenceCountService, cacheClient)
@@ -380,7 +484,8 @@ func main() {
 
 	uploadHandler := handlers.NewUploadHandler(uploadService, auditLogger)
 	metadataHandler := handlers.NewMetadataHandler(metadataService, auditLogger, cacheClient)
-	downloadHandler := handlers.NewDownloadHandler(downloadService, auditLogger)
+	metadataHandler.Policy = abacService
+	downloadHandler := handlers.NewDownloadHandler(downloadService, auditLogger, abacService)
 
 	// ─── Chain of Custody ─────────────────────────────────────
 	chainOfCustodyService := chain_of_custody.NewChainOfCustodyService(chainOfCustodyRepo)
@@ -410,7 +515,28 @@ func main() {
 	ipfsUploader := chat.NewIPFSUploader("http://ipfs:5001", "")
 	wsManager := chat.NewWebSocketManager(userService, chatRepo)
 	chatService := chat.NewChatService(chatRepo, ipfsUploader, wsManager)
-	chatHandler := handlers.NewChatHandler(chatService, auditLogger)
+	authzRepo := authz.NewRepository(db.DB)
+	if err := authzRepo.AutoMigrate(); err != nil {
+		log.Fatalf("failed migrating access denials: %v", err)
+	}
+	// ─── External Collaborator Invitations ──────────────────────
+	invitationRepo := invitations.NewRepository(db.DB)
+	if err := invitationRepo.AutoMigrate(); err != nil {
+		log.Fatalf("failed migrating case invitations: %v", err)
+	}
+	invitationService := invitations.NewService(invitationRepo, invitations.SMTPMailer{}, invitations.Options{
+		AcceptURL: os.Getenv("INVITATION_ACCEPT_URL"),
+	})
+	invitationService.Start(context.Background(), time.Minute)
+	invitationHandler := handlers.NewInvitationHandler(invitationService, auditLogger)
+	// Chat groups live in MongoDB, so the chat repository resolves their
+	// case. Invitations narrow what invited collaborators may open.
+	authzService := authz.NewService(authzRepo, permChecker, authz.Options{
+		Resolvers: map[string]authz.CaseResolver{middleware.ResourceChatGroup: chatRepo.GroupCase},
+		Guests:    invitationService,
+	})
+	middleware.SetCaseAuthorizer(authzService)
+	chatHandler := handlers.NewChatHandler(chatService, abacService, auditLogger)
 
 	// User Profile Service
 	profileRepo := profile.NewGormProfileRepository(db.DB)
@@ -425,13 +551,19 @@ func main() {
 		Service: evidenceTagService,
 	}
 
+	// ─── Detection Rules (YARA / Sigma) ──────────────
+	detectionRuleRepo := detection_rules.NewRepository(db.DB)
+	if err := detectionRuleRepo.AutoMigrate(); err != nil {
+		log.Fatalf("failed migrating detection rules: %v", err)
+	}
+	detectionRuleService := detection_rules.NewService(detectionRuleRepo, metadataService, ipfsClient, evidenceTagService, iocService, timelineService)
+	detectionRuleHandler := handlers.NewDetectionRuleHandler(detectionRuleService, auditLogger)
+
 	// ─── Evidence Viewer ─────────────────────────────
 	viewerIPFSClient := evidence_viewer.NewIPFSClient()
 	evidenceViewerRepo := evidence_viewer.NewPostgresEvidenceRepository(db.DB, viewerIPFSClient)
 	evidenceViewerService := evidence_viewer.NewEvidenceService(evidenceViewerRepo)
-	evidenceViewerHandler := &handlers.EvidenceViewerHandler{
-		Service: evidenceViewerService,
-	}
+	evidenceViewerHandler := handlers.NewEvidenceViewerHandler(evidenceViewerService, cacheClient, abacService, auditLogger)
 
 	// ─── Case Tagging ─────────────────────────────
 	caseTagRepo := case_tags.NewCaseTagRepository(db.DB)
@@ -460,10 +592,27 @@ func main() {
 	reportContentCollection := mongoDatabase.Collection("report_contents")
 	reportMongoRepo := report.NewReportMongoRepo(reportContentCollection)
 	pgSectionRepo := report_ai_assistance.NewGormReportSectionRepo(db.DB)
+
+	// ─── Report Templates ──────────────────────────────────────────
+	reportTemplateRepo := report_templates.NewRepository(db.DB)
+	if err := reportTemplateRepo.AutoMigrate(); err != nil {
+		log.Fatalf("failed migrating report templates: %v", err)
+	}
+	reportTemplateService := report_templates.NewService(reportTemplateRepo)
+	reportTemplateHandler := handlers.NewReportTemplateHandler(reportTemplateService, auditLogger)
+
+	reportRevisionRepo := report.NewRevisionRepository(db.DB)
+	if err := reportRevisionRepo.AutoMigrate(); err != nil {
+		log.Fatalf("failed migrating report revisions: %v", err)
+	}
+
 	reportService := report.NewReportService(
 		reportRepo,
 		reportMongoRepo,
 		pgSectionRepo,
+		reportTemplateService,
+		reportRevisionRepo,
+		abacService,
 	)
 
 	// Evidence metadata service for context autofill
@@ -476,15 +625,56 @@ func main() {
 	timelineService = timeline.NewService(timelineRepo)
 	timelineAIrepo := timelineai.NewAIRepository(db.DB)
 
+	// ─── LLM Provider Router ───────────────────────────────────────
+	// AI_PROVIDER selects the default backend (local | openai | fake); tenants
+	// may override provider/model and opt out of external AI via /ai/settings.
+	llmStore := llm.NewGormStore(db.DB)
+	if err := llmStore.AutoMigrate(); err != nil {
+		log.Fatalf("failed migrating AI provider tables: %v", err)
+	}
+	llmProviders := []llm.Provider{llm.NewLocalFlaskProvider(os.Getenv("AI_LOCAL_URL"), os.Getenv("AI_LOCAL_MODEL"))}
+	if baseURL := os.Getenv("AI_OPENAI_BASE_URL"); baseURL != "" {
+		openaiCfg := llm.OpenAIConfig{
+			BaseURL:      baseURL,
+			APIKey:       os.Getenv("AI_OPENAI_API_KEY"),
+			DefaultModel: os.Getenv("AI_OPENAI_MODEL"),
+		}
+		if v, err := strconv.ParseBool(os.Getenv("AI_OPENAI_EXTERNAL")); err == nil {
+			openaiCfg.External = &v
+		}
+		llmProviders = append(llmProviders, llm.NewOpenAICompatibleProvider(openaiCfg))
+	}
+	if os.Getenv("AI_PROVIDER") == "fake" {
+		llmProviders = append(llmProviders, llm.NewFakeProvider(nil))
+	}
+	llmTimeout := 60 * time.Second
+	if d, err := time.ParseDuration(os.Getenv("AI_TIMEOUT")); err == nil {
+		llmTimeout = d
+	}
+	llmRetries := 2
+	if n, err := strconv.Atoi(os.Getenv("AI_MAX_RETRIES")); err == nil {
+		llmRetries = n
+	}
+	llmRouter := llm.NewRouter(llm.RouterConfig{
+		DefaultProvider: os.Getenv("AI_PROVIDER"),
+		DefaultModel:    os.Getenv("AI_MODEL"),
+		Timeout:         llmTimeout,
+		MaxRetries:      llmRetries,
+		DisableExternal: os.Getenv("AI_DISABLE_EXTERNAL") == "true",
+		// Embeddings stay on the local hash embedder unless a provider is named.
+		EmbeddingProvider: os.Getenv("AI_EMBEDDING_PROVIDER"),
+	}, llmStore, llmStore, llmProviders...)
+	aiSettingsHandler := handlers.NewAISettingsHandler(llmRouter, llmStore, llmStore, auditLogger)
+
 	aiConfig := timelineai.AIModelConfig{
-		ModelName:   "gpt2",
+		ModelName:   os.Getenv("AI_MODEL"),
 		MaxTokens:   1500,
 		Temperature: 0.7,
 		BaseURL:     os.Getenv("AI_BASE_URL"),
 		Enabled:     os.Getenv("AI_ENABLED") == "true",
 	}
 
-	TimelineAIService := timelineai.NewAIService(timelineAIrepo, &aiConfig)
+	TimelineAIService := timelineai.NewAIService(timelineAIrepo, &aiConfig, llmRouter)
 
 	// Instantiate Timeline AI Handler
 	timelineAIHandler := handlers.NewTimelineAIHandler(TimelineAIService, auditLogger)
@@ -499,6 +689,7 @@ func main() {
 		timelineService, // implements ListEvents
 		caseService,     // implements GetCaseByID
 		iocService,      // implements ListIOCsByCase
+		chainOfCustodyService,
 		auditLogger,
 	)
 
@@ -506,9 +697,15 @@ func main() {
 	mongoSectionRepo := report_ai_assistance.NewMongoSectionRepositoryWithPg(mongoDatabase, db.DB)
 	aiSuggestionRepo := report_ai_assistance.NewGormAISuggestionRepo(db.DB)
 	sectionRefsRepo := report_ai_assistance.NewGormSectionRefsRepo(db.DB)
+	if err := aiSuggestionRepo.AutoMigrate(); err != nil {
+		log.Fatalf("failed migrating report AI suggestions: %v", err)
+	}
+	if err := sectionRefsRepo.AutoMigrate(); err != nil {
+		log.Fatalf("failed migrating report section refs: %v", err)
+	}
 	aiFeedbackRepo := report_ai_assistance.NewGormAIFeedbackRepo(db.DB)
 	// Ensure AIClient implementation matches the expected interface signature
-	aiClient := report_ai_assistance.NewAIClientLocalAI("")
+	aiClient := report_ai_assistance.NewAIClientLLM(llmRouter)
 	reportAIService := report_ai_assistance.NewReportService(
 		mongoSectionRepo,
 		aiSuggestionRepo,
@@ -519,11 +716,109 @@ func main() {
 
 	// Instantiate Report AI Handler
 	reportAIHandler := handlers.NewReportAIHandler(reportAIService, reportService)
+
+	// ─── Case Q&A (retrieval over case material) ─────────────────
+	caseQARepo := case_qa.NewRepository(db.DB)
+	if err := caseQARepo.AutoMigrate(); err != nil {
+		log.Fatalf("failed migrating case Q&A: %v", err)
+	}
+	caseQAService := case_qa.NewService(caseQARepo, metadataService, ipfsClient, timelineService,
+		annotationService, messageService, reportService, llmRouter, llmRouter, abacService)
+	caseQAHandler := handlers.NewCaseQAHandler(caseQAService, auditLogger)
 	// ─── Report Status Update ─────────────────────────────
 
 	reportStatusRepo := update_status.NewReportStatusRepository(db.DB)
 	reportStatusService := update_status.NewReportStatusService(reportStatusRepo)
-	reportStatusHandler := handlers.NewReportStatusHandler(reportStatusService)
+	reviewRepo := review.NewRepository(db.DB)
+	if err := reviewRepo.AutoMigrate(); err != nil {
+		log.Fatalf("failed migrating report reviews: %v", err)
+	}
+	reviewService := review.NewService(
+		reviewRepo,
+		reportService,
+		reportStatusService,
+		reportHandler.Fields,
+		review.NewHubNotifier(hub, notificationService),
+	)
+
+	// ─── Sealed Report Artifacts ─────────────────────────────
+	// Tenant signing keys are encrypted with REPORT_SIGNING_KEY_B64; without
+	// it reports are not sealed but earlier artifacts still verify.
+	var reportSigningCipher signing.Cipher
+	if keyB64 := os.Getenv("REPORT_SIGNING_KEY_B64"); keyB64 != "" {
+		key, err := base64.StdEncoding.DecodeString(keyB64)
+		if err != nil || len(key) != 32 {
+			log.Fatal("❌ REPORT_SIGNING_KEY_B64 must be base64 for 32 bytes (AES-256)")
+		}
+		cipher, err := x3dh.NewAESGCMCryptoService(key)
+		if err != nil {
+			log.Fatalf("❌ report signing cipher init failed: %v", err)
+		}
+		reportSigningCipher = cipher
+	} else {
+		log.Println("⚠️ REPORT_SIGNING_KEY_B64 not set; published reports will not be sealed")
+	}
+	reportArtifactRepo := signing.NewRepository(db.DB)
+	if err := reportArtifactRepo.AutoMigrate(); err != nil {
+		log.Fatalf("failed migrating report artifacts: %v", err)
+	}
+	reportArtifactService := signing.NewService(reportArtifactRepo, reportService, reportHandler.Fields, reportSigningCipher)
+	reportArtifactHandler := handlers.NewReportArtifactHandler(reportArtifactService, abacService, auditLogger)
+	reportHandler.Artifacts = reportArtifactService
+
+	reportStatusHandler := handlers.NewReportStatusHandler(reviewService, reportArtifactService, auditLogger)
+
+	// ─── Redaction ───────────────────────────────────────────
+	redactionRepo := redaction.NewRepository(db.DB)
+	if err := redactionRepo.AutoMigrate(); err != nil {
+		log.Fatalf("failed migrating redaction tables: %v", err)
+	}
+	redactionService := redaction.NewService(redactionRepo, reportService, metadataService, ipfsClient,
+		chainOfCustodyService, redaction.PopplerRasterizer{Path: os.Getenv("PDFTOPPM_PATH")})
+	redactionHandler := handlers.NewRedactionHandler(redactionService, abacService, auditLogger)
+	reportHandler.Redactions = redactionService
+	reportHandler.Classification = abacService
+
+	// ─── Report Jobs ─────────────────────────────────────────
+	reportJobRepo := jobs.NewRepository(db.DB)
+	if err := reportJobRepo.AutoMigrate(); err != nil {
+		log.Fatalf("failed migrating report job tables: %v", err)
+	}
+	reportJobService := jobs.NewService(reportJobRepo, reportService, reportHandler.Fields,
+		review.NewHubNotifier(hub, notificationService), jobs.Options{})
+	reportJobService.Start(context.Background(), time.Minute)
+	reportJobHandler := handlers.NewReportJobHandler(reportJobService, abacService, auditLogger)
+
+	// ─── Case Closure Packages ───────────────────────────────
+	caseClosureRepo := case_closure.NewRepository(db.DB)
+	if err := caseClosureRepo.AutoMigrate(); err != nil {
+		log.Fatalf("failed migrating case closure packages: %v", err)
+	}
+	caseClosureService := case_closure.NewService(caseClosureRepo, case_closure.Sources{
+		Reports:  reportService,
+		Fields:   reportHandler.Fields,
+		Signer:   reportArtifactService,
+		Evidence: metadataService,
+		Storage:  ipfsClient,
+		Custody:  chainOfCustodyService,
+		Timeline: timelineService,
+		IOCs:     iocService,
+		Chat:     chatRepo,
+		Audit:    auditLogService,
+		Policy:   abacService,
+	})
+	caseClosureHandler := handlers.NewCaseClosureHandler(caseClosureService, auditLogger)
+	mfaHandler := handlers.NewMFAHandler(authService, mfaPolicyService, auditLogger)
+	webAuthnHandler := handlers.NewWebAuthnHandler(authService, webauthnService, mfaPolicyService, auditLogger)
+	ssoFrontendURL := os.Getenv("SSO_FRONTEND_URL")
+	if ssoFrontendURL == "" {
+		ssoFrontendURL = "http://localhost:5173"
+	}
+	ssoHandler := handlers.NewSSOHandler(authService, sessionService, ssoService, ssoFrontendURL, auditLogger)
+	scimHandler := handlers.NewSCIMHandler(scimService, auditLogger)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService, auditLogger)
+	accessDenialHandler := handlers.NewAccessDenialHandler(authzService)
+	classificationHandler := handlers.NewClassificationHandler(abacService, auditLogger)
 
 	// ─── Health Check Service and Handler ─────────────────────────────
 
@@ -599,6 +894,24 @@ func main() {
 
 		x3dhService, // X3DH Service
 		verificationHandler,
+
+		investigationGraphHandler,
+		detectionRuleHandler,
+		aiSettingsHandler,
+		caseQAHandler,
+		reportTemplateHandler,
+		reportArtifactHandler,
+		redactionHandler,
+		reportJobHandler,
+		caseClosureHandler,
+		mfaHandler,
+		webAuthnHandler,
+		ssoHandler,
+		scimHandler,
+		apiKeyHandler,
+		accessDenialHandler,
+		classificationHandler,
+		invitationHandler,
 	)
 
 	// ─── Set Up Router and Launch ───────────────────────────────
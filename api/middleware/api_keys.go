@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"aegis-api/structs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyPrefix marks service account API keys; bearer tokens with it are
+// not JWTs.
+const APIKeyPrefix = "aegis_sk_"
+
+// ServiceAccountRole is the userRole of requests made with an API key. It
+// matches no human role, so RequireRole never admits them.
+const ServiceAccountRole = "Service Account"
+
+// APIKeyPrincipal is the service account an API key acts as.
+type APIKeyPrincipal struct {
+	KeyID            string
+	KeyPrefix        string
+	ServiceAccountID string
+	Name             string
+	TenantID         string
+	TeamID           string
+	// Scopes are permission names of enum_role_permissions.
+	Scopes []string
+	// CaseIDs limits the key to these cases; empty means any case of the
+	// tenant.
+	CaseIDs []string
+}
+
+// HasScope reports whether the key was granted the permission.
+func (p *APIKeyPrincipal) HasScope(permission string) bool {
+	return slices.Contains(p.Scopes, permission)
+}
+
+// AllowsCase reports whether the key may act on the case.
+func (p *APIKeyPrincipal) AllowsCase(caseID string) bool {
+	return len(p.CaseIDs) == 0 || slices.Contains(p.CaseIDs, strings.ToLower(caseID))
+}
+
+// APIKeyAuthenticator resolves an API key to its service account.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key, ip string) (*APIKeyPrincipal, error)
+}
+
+// API key authenticator set by main.go; when nil API keys are rejected
+var apiKeyAuthenticator APIKeyAuthenticator
+
+// SetAPIKeyAuthenticator sets the authenticator AuthMiddleware uses for
+// API keys
+func SetAPIKeyAuthenticator(a APIKeyAuthenticator) {
+	apiKeyAuthenticator = a
+}
+
+// apiKeyRoute is an endpoint automation may call with an API key.
+type apiKeyRoute struct {
+	permission string
+	// caseForm names the form field that carries the case when the path
+	// has no :case_id.
+	caseForm string
+}
+
+// apiKeyRoutes is the allowlist of endpoints open to API keys, by method
+// and route path. Every other endpoint refuses them.
+var apiKeyRoutes = map[string]apiKeyRoute{
+	"GET /api/v1/cases/:case_id":               {permission: "case:view"},
+	"GET /api/v1/cases/:case_id/collaborators": {permission: "collaboration:view_members"},
+	"GET /api/v1/cases/:case_id/timeline":      {permission: "case:view"},
+	"POST /api/v1/cases/:case_id/timeline":     {permission: "case:update"},
+
+	"POST /api/v1/evidence":                        {permission: "evidence:upload", caseForm: "caseId"},
+	"GET /api/v1/evidence-metadata/:id":            {permission: "evidence:view"},
+	"GET /api/v1/evidence-metadata/case/:case_id":  {permission: "evidence:view"},
+	"GET /api/v1/cases/:case_id/chain_of_custody":  {permission: "evidence:view_logs"},
+	"POST /api/v1/cases/:case_id/chain_of_custody": {permission: "evidence:update_metadata"},
+
+	"GET /api/v1/cases/:case_id/iocs":                        {permission: "ioc:view"},
+	"POST /api/v1/cases/:case_id/iocs":                       {permission: "ioc:create"},
+	"GET /api/v1/tenants/:tenantId/cases/:case_id/ioc-graph": {permission: "ioc:view"},
+	"GET /api/v1/cases/:case_id/graph/entities":              {permission: "ioc:view"},
+	"POST /api/v1/cases/:case_id/graph/entities":             {permission: "ioc:create"},
+	"POST /api/v1/cases/:case_id/graph/relations":            {permission: "ioc:create"},
+
+	"GET /api/v1/detection-rules":           {permission: "evidence:view"},
+	"POST /api/v1/detection-rules":          {permission: "detection:manage_rules"},
+	"GET /api/v1/cases/:case_id/detections": {permission: "evidence:view"},
+}
+
+// APIKeyEndpoints lists the endpoints open to API keys with the
+// permission each needs.
+func APIKeyEndpoints() map[string]string {
+	out := make(map[string]string, len(apiKeyRoutes))
+	for endpoint, r := range apiKeyRoutes {
+		out[endpoint] = r.permission
+	}
+	return out
+}
+
+func abortAPIKey(c *gin.Context, status int, code, message string) {
+	c.JSON(status, structs.ErrorResponse{Error: code, Message: message})
+	c.Abort()
+}
+
+// authenticateAPIKey admits a request made with an API key: the endpoint
+// must be open to API keys, the key must carry its permission, and a
+// case-scoped key must name the case.
+func authenticateAPIKey(c *gin.Context, key string) {
+	if apiKeyAuthenticator == nil {
+		abortAPIKey(c, http.StatusUnauthorized, "unauthorized", "API keys are not enabled")
+		return
+	}
+	p, err := apiKeyAuthenticator.AuthenticateAPIKey(c.Request.Context(), key, c.ClientIP())
+	if err != nil {
+		log.Printf("[ERROR] AuthMiddleware: API key rejected: %v", err)
+		abortAPIKey(c, http.StatusUnauthorized, "unauthorized", "Invalid, expired or revoked API key")
+		return
+	}
+
+	route, ok := apiKeyRoutes[c.Request.Method+" "+c.FullPath()]
+	if !ok {
+		log.Printf("[ERROR] AuthMiddleware: API key %s used on %s %s", p.KeyPrefix, c.Request.Method, c.FullPath())
+		abortAPIKey(c, http.StatusForbidden, "api_key_not_allowed", "This endpoint cannot be called with an API key")
+		return
+	}
+	if !p.HasScope(route.permission) {
+		log.Printf("[ERROR] AuthMiddleware: API key %s lacks scope %s", p.KeyPrefix, route.permission)
+		abortAPIKey(c, http.StatusForbidden, "insufficient_scope", "The API key lacks the "+route.permission+" scope")
+		return
+	}
+	if len(p.CaseIDs) > 0 {
+		caseID := c.Param("case_id")
+		if caseID == "" && route.caseForm != "" {
+			caseID = c.PostForm(route.caseForm)
+		}
+		if caseID == "" || !p.AllowsCase(caseID) {
+			log.Printf("[ERROR] AuthMiddleware: API key %s not scoped to case %q", p.KeyPrefix, caseID)
+			abortAPIKey(c, http.StatusForbidden, "case_not_allowed", "The API key is not scoped to this case")
+			return
+		}
+	}
+
+	c.Set("userID", p.ServiceAccountID)
+	c.Set("email", "")
+	c.Set("userRole", ServiceAccountRole)
+	c.Set("fullName", p.Name)
+	c.Set("tenantID", p.TenantID)
+	c.Set("teamID", p.TeamID)
+	c.Set("apiKeyID", p.KeyID)
+	c.Set("apiKey", p)
+	c.Next()
+}
+
+// APIKeyFromContext returns the API key the request authenticated with,
+// or nil for user sessions.
+func APIKeyFromContext(c *gin.Context) *APIKeyPrincipal {
+	p, _ := c.Get("apiKey")
+	key, _ := p.(*APIKeyPrincipal)
+	return key
+}
@@ -12,6 +12,9 @@ type Claims struct {
 	FullName             string `json:"full_name"`
 	TenantID             string `json:"tenant_id"`
 	TeamID               string `json:"team_id"`
+	SessionID            string `json:"sid"`
+	TokenVersion         int    `json:"token_version"`
+	Type                 string `json:"typ,omitempty"` // set on pre-auth tokens only
 	jwt.RegisteredClaims        // still needed if you want "exp", "iat" etc. validated
 }
 
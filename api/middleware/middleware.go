@@ -462,6 +462,32 @@ func SetRedisClient(client *redis.Client) {
 	RedisClient = client
 }
 
+// SessionValidator checks an access token against revocations: its
+// session, and the user's current token version.
+type SessionValidator interface {
+	ValidateSession(ctx context.Context, userID, sessionID string, tokenVersion int) error
+}
+
+// Session validator set by main.go; when nil tokens are trusted until they expire
+var sessionValidator SessionValidator
+
+// SetSessionValidator sets the validator the auth middlewares consult
+func SetSessionValidator(v SessionValidator) {
+	sessionValidator = v
+}
+
+// validateSession reports whether the token's session is still live
+func validateSession(c *gin.Context, userID, sessionID string, tokenVersion int) bool {
+	if sessionValidator == nil {
+		return true
+	}
+	if err := sessionValidator.ValidateSession(c.Request.Context(), userID, sessionID, tokenVersion); err != nil {
+		log.Printf("[ERROR] Session check failed for user %s session %q: %v", userID, sessionID, err)
+		return false
+	}
+	return true
+}
+
 // IPThrottleMiddleware applies rate limiting based on client IP, endpoint, and method
 func IPThrottleMiddleware(defaultLimit int, window time.Duration, config EndpointLimitConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -625,6 +651,16 @@ func RateLimitMiddleware(defaultLimit int, window time.Duration, config Endpoint
 
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// Already authenticated by an outer group; a second pass would spend
+		// another API-key lookup and last-used write on the same request
+		if _, done := c.Get("userID"); done {
+			return
+		}
+		// API keys are long-lived, so they are kept out of the debug log below
+		if key, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && strings.HasPrefix(key, APIKeyPrefix) {
+			authenticateAPIKey(c, key)
+			return
+		}
 		log.Printf("[DEBUG] AuthMiddleware: Authorization Header: %s", c.GetHeader("Authorization"))
 		authHeader := c.GetHeader("Authorization")
 
@@ -670,6 +706,17 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Pre-auth tokens (awaiting MFA) carry a "typ" and are not access tokens
+		if typ, _ := getStringClaim(claims, "typ"); typ != "" {
+			log.Printf("[ERROR] AuthMiddleware: %s token used as access token", typ)
+			c.JSON(http.StatusUnauthorized, structs.ErrorResponse{
+				Error:   "mfa_required",
+				Message: "Complete multi-factor authentication first",
+			})
+			c.Abort()
+			return
+		}
+
 		// Extract string claims
 		userID, ok1 := getStringClaim(claims, "user_id")
 		email, ok2 := getStringClaim(claims, "email")
@@ -701,6 +748,17 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		sessionID, _ := getStringClaim(claims, "sid")
+		tokenVersion, _ := claims["token_version"].(float64)
+		if !validateSession(c, userID, sessionID, int(tokenVersion)) {
+			c.JSON(http.StatusUnauthorized, structs.ErrorResponse{
+				Error:   "session_revoked",
+				Message: "Session has been revoked or has expired",
+			})
+			c.Abort()
+			return
+		}
+
 		// ✅ Attach claims to context
 		c.Set("userID", userID)
 		c.Set("email", email)
@@ -708,6 +766,9 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Set("fullName", fullName)
 		c.Set("tenantID", tenantID)
 		c.Set("teamID", teamID) // may be empty for Tenant Admin
+		c.Set("sessionID", sessionID)
+		stepUpUntil, _ := claims["step_up_exp"].(float64)
+		c.Set("stepUpUntil", int64(stepUpUntil))
 
 		c.Next()
 	}
@@ -780,6 +841,25 @@ func WebSocketAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Pre-auth tokens (awaiting MFA) carry a "typ" and are not access tokens
+		if claims.Type != "" {
+			log.Printf("❌ WebSocket auth failed: %s token used as access token", claims.Type)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, structs.ErrorResponse{
+				Error:   "mfa_required",
+				Message: "Complete multi-factor authentication first",
+			})
+			return
+		}
+
+		if !validateSession(c, claims.UserID, claims.SessionID, claims.TokenVersion) {
+			log.Println("❌ WebSocket auth failed: Session revoked")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, structs.ErrorResponse{
+				Error:   "session_revoked",
+				Message: "Session has been revoked or has expired",
+			})
+			return
+		}
+
 		// ✅ Inject user data into Gin context
 		c.Set("userID", claims.UserID)
 		c.Set("email", claims.Email)
@@ -787,6 +867,7 @@ func WebSocketAuthMiddleware() gin.HandlerFunc {
 		c.Set("fullName", claims.FullName)
 		c.Set("tenantID", claims.TenantID)
 		c.Set("teamID", claims.TeamID)
+		c.Set("sessionID", claims.SessionID)
 
 		log.Printf("✅ WebSocket auth successful for user %s", claims.UserID)
 		c.Next()
@@ -832,6 +913,81 @@ func RequireRole(allowedRoles ...string) gin.HandlerFunc {
 	}
 }
 
+// HasFreshMFA reports whether the request's token carries a step-up
+// verification that has not run out
+func HasFreshMFA(c *gin.Context) bool {
+	return time.Now().Unix() < c.GetInt64("stepUpUntil")
+}
+
+// AbortStepUpRequired refuses a request that needs a fresh MFA verification
+func AbortStepUpRequired(c *gin.Context) {
+	c.JSON(http.StatusForbidden, structs.ErrorResponse{
+		Error:   "step_up_required",
+		Message: "Re-verify with your second factor (POST /auth/mfa/step-up) and retry with the new token",
+	})
+	c.Abort()
+}
+
+// MFAEnrollment reports whether a user has a second factor enrolled and
+// whether the tenant's policy requires their role to have one.
+type MFAEnrollment interface {
+	Enrollment(userID, tenantID, role string) (enrolled, required bool, err error)
+}
+
+// MFA enrollment lookup set by main.go; when nil every user must step up
+var mfaEnrollment MFAEnrollment
+
+// SetMFAEnrollment sets the lookup RequireStepUp consults
+func SetMFAEnrollment(e MFAEnrollment) {
+	mfaEnrollment = e
+}
+
+// SteppedUp reports whether a sensitive action may go ahead, and otherwise
+// aborts the request. The token of a user with a second factor must come
+// from an MFA verification made within the tenant's step-up window. A user
+// without one cannot step up, so they are let through unless the tenant's
+// policy requires them to enroll.
+func SteppedUp(c *gin.Context) bool {
+	if HasFreshMFA(c) {
+		return true
+	}
+	if mfaEnrollment == nil || APIKeyFromContext(c) != nil {
+		log.Printf("[ERROR] RequireStepUp: no fresh MFA for user %s on %s", c.GetString("userID"), c.FullPath())
+		AbortStepUpRequired(c)
+		return false
+	}
+	enrolled, required, err := mfaEnrollment.Enrollment(c.GetString("userID"), c.GetString("tenantID"), c.GetString("userRole"))
+	switch {
+	case err != nil:
+		log.Printf("[ERROR] RequireStepUp: MFA status of user %s: %v", c.GetString("userID"), err)
+		c.JSON(http.StatusInternalServerError, structs.ErrorResponse{Error: "internal_error", Message: "Failed to check MFA status"})
+		c.Abort()
+		return false
+	case enrolled:
+		log.Printf("[ERROR] RequireStepUp: no fresh MFA for user %s on %s", c.GetString("userID"), c.FullPath())
+		AbortStepUpRequired(c)
+		return false
+	case required:
+		log.Printf("[ERROR] RequireStepUp: user %s has not enrolled the MFA their tenant requires", c.GetString("userID"))
+		c.JSON(http.StatusForbidden, structs.ErrorResponse{
+			Error:   "mfa_enrollment_required",
+			Message: "Your tenant requires multi-factor authentication: enroll a second factor (POST /auth/mfa/setup) and retry",
+		})
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// RequireStepUp guards sensitive routes with SteppedUp
+func RequireStepUp() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if SteppedUp(c) {
+			c.Next()
+		}
+	}
+}
+
 func GetTargetUserID(c *gin.Context) (string, bool) {
 	targetUserID := c.Param("user_id")
 	role, _ := c.Get("userRole")
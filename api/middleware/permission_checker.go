@@ -15,6 +15,17 @@ func RequirePermission(permission string, checker PermissionChecker) gin.Handler
 	return func(c *gin.Context) {
 		log.Printf("[DEBUG] RequirePermission: Checking permission '%s' for userRole '%s'", permission, c.GetString("userRole"))
 		userRole := c.GetString("userRole") // e.g., extracted from JWT or session
+		if key := APIKeyFromContext(c); key != nil {
+			// Service accounts hold the scopes of their key, not a role's permissions
+			if !key.HasScope(permission) {
+				log.Printf("[ERROR] RequirePermission: Forbidden, API key %s lacks scope '%s'", key.KeyPrefix, permission)
+				c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: insufficient permissions"})
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
 		if userRole == "" {
 			log.Printf("[ERROR] RequirePermission: Unauthorized, missing role")
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: missing role"})
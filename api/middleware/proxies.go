@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"os"
+	"strings"
+)
+
+// TrustedProxies returns the proxies allowed to report the client's
+// address in X-Forwarded-For, from TRUSTED_PROXIES: comma-separated
+// addresses or CIDRs. Without it the peer address is the client address,
+// so the header cannot be used to get around IP allowlists.
+func TrustedProxies() []string {
+	var proxies []string
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"aegis-api/handlers"
+	"aegis-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAccessDenialRoutes registers the log of refused case access.
+func RegisterAccessDenialRoutes(rg *gin.RouterGroup, h *handlers.AccessDenialHandler) {
+	rg.GET("/access-denials", middleware.RequireRole("Tenant Admin", "DFIR Admin"), h.ListDenials)
+}
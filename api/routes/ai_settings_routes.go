@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"aegis-api/handlers"
+	"aegis-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterAISettingsRoutes(rg *gin.RouterGroup, h *handlers.AISettingsHandler) {
+	ai := rg.Group("/ai")
+	{
+		ai.GET("/settings", middleware.RequireRole("Tenant Admin", "DFIR Admin"), h.GetSettings)
+		ai.PUT("/settings", middleware.RequireRole("Tenant Admin", "DFIR Admin"), h.UpdateSettings)
+		ai.GET("/usage", middleware.RequireRole("Tenant Admin", "DFIR Admin"), h.GetUsage)
+	}
+}
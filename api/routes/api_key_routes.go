@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"aegis-api/handlers"
+	"aegis-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAPIKeyRoutes registers management of the tenant's service
+// accounts and their API keys.
+func RegisterAPIKeyRoutes(rg *gin.RouterGroup, h *handlers.APIKeyHandler) {
+	admin := middleware.RequireRole("Tenant Admin", "DFIR Admin")
+	rg.GET("/api-keys/scopes", admin, h.Scopes)
+	rg.GET("/service-accounts", admin, h.ListAccounts)
+	rg.POST("/service-accounts", admin, middleware.RequireStepUp(), h.CreateAccount)
+	rg.DELETE("/service-accounts/:id", admin, middleware.RequireStepUp(), h.DisableAccount)
+	rg.GET("/service-accounts/:id/keys", admin, h.ListKeys)
+	rg.POST("/service-accounts/:id/keys", admin, middleware.RequireStepUp(), h.CreateKey)
+	rg.POST("/service-accounts/:id/keys/:key_id/rotate", admin, middleware.RequireStepUp(), h.RotateKey)
+	rg.DELETE("/service-accounts/:id/keys/:key_id", admin, h.RevokeKey)
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"aegis-api/handlers"
+	"aegis-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterCaseClosureRoutes(rg *gin.RouterGroup, h *handlers.CaseClosureHandler) {
+	admin := middleware.RequireRole("Tenant Admin", "DFIR Admin")
+
+	caseView := middleware.RequireCaseAccess("case:view", middleware.ResourceCase, "case_id")
+	rg.GET("/cases/:case_id/closure-package", admin, caseView, h.Export)
+	rg.GET("/cases/:case_id/closure-packages", admin, caseView, h.ListPackages)
+	rg.POST("/closure-packages/verify", h.Verify)
+}
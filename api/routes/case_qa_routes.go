@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"aegis-api/handlers"
+	"aegis-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterCaseQARoutes(rg *gin.RouterGroup, h *handlers.CaseQAHandler, checker middleware.PermissionChecker) {
+	view := middleware.RequireCaseAccess("evidence:view", middleware.ResourceCase, "case_id")
+	qa := rg.Group("/cases/:case_id/qa")
+	{
+		qa.POST("/ask", middleware.RequirePermission("evidence:view", checker), view, h.Ask)
+		qa.POST("/index", middleware.RequirePermission("evidence:view", checker), view, h.IndexCase)
+		qa.GET("/exchanges", middleware.RequirePermission("evidence:view", checker), view, h.ListExchanges)
+	}
+}
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"log"
 	"time"
 
 	"aegis-api/handlers"
@@ -20,10 +21,13 @@ func SetUpRouter(h *handlers.Handler) *gin.Engine {
 			"/api/v1/upload":     8,
 		},
 		"GET": {
-			"/api/v1/download/:cid": 20,
+			"/api/v1/download/:evidence_id": 20,
 		},
 	}
 	router := gin.New()
+	if err := router.SetTrustedProxies(middleware.TrustedProxies()); err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 	router.Use(cors.New(cors.Config{
@@ -35,10 +39,15 @@ func SetUpRouter(h *handlers.Handler) *gin.Engine {
 		MaxAge:           12 * time.Hour,
 	}))
 	api := router.Group("/api/v1")
+	// caseAccess checks the action against the user's role on the :case_id case
+	caseAccess := func(action string) gin.HandlerFunc {
+		return middleware.RequireCaseAccess(action, middleware.ResourceCase, "case_id")
+	}
 	// ─── Auth ─────────────────────────────────────────
 	auth := api.Group("/auth")
 	auth.Use(middleware.IPThrottleMiddleware(20, time.Minute, granularLimits)) // 20 req/min per IP for unauthenticated
 	auth.POST("/login", h.AuthService.LoginHandler)
+	auth.POST("/refresh", h.AuthService.RefreshHandler)
 	auth.POST("/request-password-reset", h.AuthService.RequestPasswordReset)
 	auth.POST("/reset-password", h.AuthService.ResetPasswordHandler)
 	auth.GET("/verify", h.AdminService.VerifyEmail)
@@ -52,9 +61,8 @@ func SetUpRouter(h *handlers.Handler) *gin.Engine {
 	api.GET("/teams", h.GetTeamsByTenant)
 	api.GET("/tenants", h.GetAllTenants)
 
-	// ─── Public Evidence Upload/Download ─────────────
-	api.POST("/upload", middleware.IPThrottleMiddleware(20, time.Minute, granularLimits), h.UploadHandler.Upload)
-	api.GET("/download/:cid", middleware.IPThrottleMiddleware(20, time.Minute, granularLimits), h.DownloadHandler.Download)
+	// ─── Evidence Upload/Download ───────────────────
+	RegisterEvidenceTransferRoutes(api, h.UploadHandler, h.DownloadHandler, granularLimits)
 
 	//________AI Routes________
 	timelineAIGroup := api.Group("/ai")
@@ -81,7 +89,7 @@ func SetUpRouter(h *handlers.Handler) *gin.Engine {
 		// ─── Case Management ──────────────────────────
 		protected.POST("/cases", h.CaseHandler.CreateCase)
 		protected.GET("/cases/active", h.CaseHandler.ListActiveCasesHandler)
-		protected.POST("/cases/assign", middleware.AuthMiddleware(), h.CaseHandler.AssignUserToCase)
+		protected.POST("/cases/assign", h.CaseHandler.AssignUserToCase)
 		protected.GET("/cases/:case_id/collaborators", h.GetCollaboratorsHandler.GetCollaboratorsByCaseID)
 		protected.POST("/cases/unassign", h.CaseHandler.UnassignUserFromCase)
 		protected.GET("/cases/closed", h.CaseHandler.ListClosedCasesHandler)
@@ -92,6 +100,9 @@ func SetUpRouter(h *handlers.Handler) *gin.Engine {
 		protected.GET("/cases/archived", h.CaseHandler.ListArchivedCasesHandler)
 		protected.POST("/auth/verify-admin", h.VerificationHandler.VerifyAdminGin) // Move here
 		protected.POST("/auth/logout", h.AuthService.LogoutHandler)
+		protected.POST("/auth/logout-all", h.AuthService.LogoutAllHandler)
+		protected.GET("/auth/sessions", h.AuthService.ListSessionsHandler)
+		protected.DELETE("/auth/sessions/:sessionID", h.AuthService.RevokeSessionHandler)
 		protected.POST("/auth/change-password", h.AuthService.ChangePasswordHandler)
 		// ─── New List / Filter Cases ──────────────────
 		protected.GET("/cases/all", h.CaseHandler.GetAllCasesHandler)
@@ -99,36 +110,48 @@ func SetUpRouter(h *handlers.Handler) *gin.Engine {
 		protected.GET("/cases/filter", h.CaseHandler.GetFilteredCasesHandler)
 		protected.GET("/cases/:case_id", h.CaseHandler.GetCaseByIDHandler)
 
-		protected.GET("/tenants/:tenantId/cases/:case_id/ioc-graph", middleware.AuthMiddleware(), h.IOCHandler.GetCaseIOCGraph)
-		protected.GET("tenants/:tenantId/ioc-graph", middleware.AuthMiddleware(), h.IOCHandler.GetTenantIOCGraph)
-		protected.POST("/cases/:case_id/iocs", middleware.AuthMiddleware(), h.IOCHandler.AddIOCToCase)
-		protected.GET("/cases/:case_id/iocs", middleware.AuthMiddleware(), h.IOCHandler.GetIOCsByCase)
+		protected.GET("/tenants/:tenantId/cases/:case_id/ioc-graph", caseAccess("ioc:view"), h.IOCHandler.GetCaseIOCGraph)
+		protected.GET("tenants/:tenantId/ioc-graph", h.IOCHandler.GetTenantIOCGraph)
+		protected.POST("/cases/:case_id/iocs", caseAccess("ioc:create"), h.IOCHandler.AddIOCToCase)
+		protected.GET("/cases/:case_id/iocs", caseAccess("ioc:view"), h.IOCHandler.GetIOCsByCase)
+		// ______investigation graph routes______________
+		protected.GET("/cases/:case_id/graph", caseAccess("ioc:view"), h.InvestigationGraphHandler.GetGraph)
+		protected.GET("/cases/:case_id/graph/export", caseAccess("ioc:view"), h.InvestigationGraphHandler.ExportGraph)
+		protected.GET("/cases/:case_id/graph/path", caseAccess("ioc:view"), h.InvestigationGraphHandler.GetShortestPath)
+		protected.GET("/cases/:case_id/graph/entities", caseAccess("ioc:view"), h.InvestigationGraphHandler.ListEntities)
+		protected.POST("/cases/:case_id/graph/entities", caseAccess("ioc:create"), h.InvestigationGraphHandler.AddEntity)
+		protected.DELETE("/cases/:case_id/graph/entities/:entity_id", caseAccess("ioc:delete"), h.InvestigationGraphHandler.DeleteEntity)
+		protected.GET("/cases/:case_id/graph/entities/:entity_id/neighbors", caseAccess("ioc:view"), h.InvestigationGraphHandler.GetNeighbors)
+		protected.POST("/cases/:case_id/graph/relations", caseAccess("ioc:create"), h.InvestigationGraphHandler.AddRelation)
+		protected.DELETE("/cases/:case_id/graph/relations/:relation_id", caseAccess("ioc:delete"), h.InvestigationGraphHandler.DeleteRelation)
 		// ______timeline routes______________
 		// List all events for a case
-		protected.GET("/cases/:case_id/timeline", middleware.AuthMiddleware(), h.TimelineHandler.ListByCase)
+		protected.GET("/cases/:case_id/timeline", caseAccess("case:view"), h.TimelineHandler.ListByCase)
 		// Create new event for a case
-		protected.POST("/cases/:case_id/timeline", middleware.AuthMiddleware(), h.TimelineHandler.Create)
+		protected.POST("/cases/:case_id/timeline", caseAccess("case:update"), h.TimelineHandler.Create)
 		// Update a timeline event by ID
-		protected.PATCH("/timeline/:event_id", middleware.AuthMiddleware(), h.TimelineHandler.Update)
+		protected.PATCH("/timeline/:event_id", middleware.RequireCaseAccess("case:update", middleware.ResourceTimelineEvent, "event_id"), h.TimelineHandler.Update)
 		// Delete a timeline event by ID
-		protected.DELETE("/timeline/:event_id", middleware.AuthMiddleware(), h.TimelineHandler.Delete)
+		protected.DELETE("/timeline/:event_id", middleware.RequireCaseAccess("case:update", middleware.ResourceTimelineEvent, "event_id"), h.TimelineHandler.Delete)
 		// Reorder events for a case
-		protected.POST("/cases/:case_id/timeline/reorder", h.TimelineHandler.Reorder)
+		protected.POST("/cases/:case_id/timeline/reorder", caseAccess("case:update"), h.TimelineHandler.Reorder)
 		//chain of custody
-		protected.POST("/cases/:case_id/chain_of_custody", h.ChainOfCustodyHandler.AddEntry)
-		protected.PUT("/cases/:case_id/chain_of_custody/:id", h.ChainOfCustodyHandler.UpdateEntry)
-		protected.GET("/cases/:case_id/chain_of_custody/:id", h.ChainOfCustodyHandler.GetEntry)
-		protected.GET("/cases/:case_id/chain_of_custody", h.ChainOfCustodyHandler.GetEntries)
+		protected.POST("/cases/:case_id/chain_of_custody", caseAccess("evidence:update_metadata"), h.ChainOfCustodyHandler.AddEntry)
+		protected.PUT("/cases/:case_id/chain_of_custody/:id", caseAccess("evidence:update_metadata"), h.ChainOfCustodyHandler.UpdateEntry)
+		protected.GET("/cases/:case_id/chain_of_custody/:id", caseAccess("evidence:view"), h.ChainOfCustodyHandler.GetEntry)
+		protected.GET("/cases/:case_id/chain_of_custody", caseAccess("evidence:view"), h.ChainOfCustodyHandler.GetEntries)
 		// ─── Metadata Evidence Upload ────────────────
-		protected.POST("/evidence", h.MetadataHandler.UploadEvidence)
+		protected.POST("/evidence", middleware.RequireCaseAccessInBody("evidence:upload", middleware.ResourceCase, "caseId"), h.MetadataHandler.UploadEvidence)
 		// ─── Metadata Evidence Retrieval ─────────────
-		protected.GET("/evidence-metadata/:id", h.MetadataHandler.GetEvidenceByID)
-		protected.GET("/evidence-metadata/case/:case_id", h.MetadataHandler.GetEvidenceByCaseID)
+		protected.GET("/evidence-metadata/:id", middleware.RequireCaseAccess("evidence:view", middleware.ResourceEvidence, "id"), h.MetadataHandler.GetEvidenceByID)
+		protected.GET("/evidence-metadata/case/:case_id", caseAccess("evidence:view"), h.MetadataHandler.GetEvidenceByCaseID)
 		protected.GET("/evidence/count/:tenantId", h.EvidenceHandler.GetEvidenceCount)
 		// ─── Admin: Users ────────────────────────────
 		protected.GET("/users", h.AdminService.ListUsers)
-		protected.GET("tenants/:tenantId/users", middleware.AuthMiddleware(), h.AdminService.ListUsersByTenant)
-		protected.DELETE("/users/:userId", h.AdminService.DeleteUserHandler)
+		protected.GET("tenants/:tenantId/users", h.AdminService.ListUsersByTenant)
+		protected.DELETE("/users/:userId", middleware.RequireStepUp(), h.AdminService.DeleteUserHandler)
+		protected.GET("/users/:userId/sessions", middleware.RequireRole("Tenant Admin", "DFIR Admin"), h.AuthService.ListUserSessionsHandler)
+		protected.DELETE("/users/:userId/sessions", middleware.RequireRole("Tenant Admin", "DFIR Admin"), h.AuthService.RevokeUserSessionsHandler)
 		protected.GET("/audit-logs", h.AdminService.GetAuditLogs)
 
 		// ─── Profile Routes ──────────────────────────
@@ -163,11 +186,51 @@ func SetUpRouter(h *handlers.Handler) *gin.Engine {
 
 		RegisterCaseTagRoutes(protected, h.CaseTagHandler, h.PermissionChecker)
 
+		// ─── Detection Rules (YARA / Sigma) ─────────────────
+		RegisterDetectionRuleRoutes(protected, h.DetectionRuleHandler, h.PermissionChecker)
+
+		// ─── AI Provider Settings ───────────────────────────
+		RegisterAISettingsRoutes(protected, h.AISettingsHandler)
+
+		// ─── Case Q&A (retrieval over case material) ────────
+		RegisterCaseQARoutes(protected, h.CaseQAHandler, h.PermissionChecker)
+
+		// ─── Report Templates ───────────────────────────────
+		RegisterReportTemplateRoutes(protected, h.ReportTemplateHandler)
+
 		// ─── Report Generation ──────────────────────────────
 		RegisterReportRoutes(protected, h.ReportHandler)
 
 		// // ─── Report Status Update ─────────────────────────────
 		RegisterReportStatusRoutes(protected, h.ReportStatusHandler)
+
+		// ─── Sealed Report Artifacts ────────────────────────
+		RegisterReportArtifactRoutes(protected, h.ReportArtifactHandler)
+
+		// ─── Redaction ──────────────────────────────────────
+		RegisterRedactionRoutes(protected, h.RedactionHandler, h.PermissionChecker)
+
+		// ─── Report Jobs ────────────────────────────────────
+		RegisterReportJobRoutes(protected, h.ReportJobHandler)
+
+		// ─── Case Closure Packages ──────────────────────────
+		RegisterCaseClosureRoutes(protected, h.CaseClosureHandler)
+		// ─── MFA ──────────────────────────────────────────
+		RegisterMFARoutes(auth, protected, h.MFAHandler)
+		// ─── Passkeys ───────────────────────────────────
+		RegisterWebAuthnRoutes(auth, protected, h.WebAuthnHandler)
+		// ─── Single Sign-On ─────────────────────────────
+		RegisterSSORoutes(auth, protected, h.SSOHandler)
+		// ─── SCIM Provisioning ──────────────────────────
+		RegisterSCIMRoutes(api, protected, h.SCIMHandler)
+		// ─── Service Accounts & API Keys ────────────────
+		RegisterAPIKeyRoutes(protected, h.APIKeyHandler)
+		// ─── Case Access Denials ────────────────────────
+		RegisterAccessDenialRoutes(protected, h.AccessDenialHandler)
+		// ─── Classification & Need-to-Know ──────────────
+		RegisterClassificationRoutes(protected, h.ClassificationHandler)
+		// ─── External Collaborator Invitations ──────────
+		RegisterInvitationRoutes(protected, h.InvitationHandler)
 		// ─── Report AI Assistance ─────────────────────────────
 		RegisterReportAIRoutes(protected, h.ReportAIHandler)
 
@@ -2,25 +2,29 @@ package routes
 
 import (
 	"aegis-api/handlers"
+	"aegis-api/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
 func RegisterChatRoutes(router *gin.RouterGroup, handler *handlers.ChatHandler) {
+	view := middleware.RequireCaseAccess("case:view", middleware.ResourceChatGroup, "id")
+	message := middleware.RequireCaseAccess("collaboration:message", middleware.ResourceChatGroup, "id")
+
 	chat := router.Group("/chat")
 	{
 		// Groups
-		chat.POST("/groups", handler.CreateGroup)
-		chat.GET("/groups/:id", handler.GetGroupByID)
+		chat.POST("/groups", middleware.RequireCaseAccessInBody("collaboration:message", middleware.ResourceCase, "case_id"), handler.CreateGroup)
+		chat.GET("/groups/:id", view, handler.GetGroupByID)
 		chat.GET("/groups/user/:email", handler.GetUserGroups)
-		chat.PUT("/groups/:id", handler.UpdateGroup)
-		chat.DELETE("/groups/:id", handler.DeleteGroup)
-		chat.POST("/groups/:id/members", handler.AddMemberToGroup)
-		chat.DELETE("/groups/:id/members/:email", handler.RemoveMemberFromGroup)
-		chat.GET("/groups/case/:caseId", handler.GetGroupsByCaseID)
-		chat.PUT("/groups/:id/image", handler.UpdateGroupImage)
+		chat.PUT("/groups/:id", message, handler.UpdateGroup)
+		chat.DELETE("/groups/:id", message, handler.DeleteGroup)
+		chat.POST("/groups/:id/members", message, handler.AddMemberToGroup)
+		chat.DELETE("/groups/:id/members/:email", message, handler.RemoveMemberFromGroup)
+		chat.GET("/groups/case/:caseId", middleware.RequireCaseAccess("case:view", middleware.ResourceCase, "caseId"), handler.GetGroupsByCaseID)
+		chat.PUT("/groups/:id/image", message, handler.UpdateGroupImage)
 		// Messages
-		chat.POST("/groups/:id/messages", handler.SendMessage)
-		chat.GET("/groups/:id/messages", handler.GetMessages)
+		chat.POST("/groups/:id/messages", message, handler.SendMessage)
+		chat.GET("/groups/:id/messages", view, handler.GetMessages)
 	}
 }
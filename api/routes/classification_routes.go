@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"aegis-api/handlers"
+	"aegis-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterClassificationRoutes registers management of classification
+// labels, user clearances and need-to-know groups. Anyone may read a
+// label; changing labels or who may read them takes an admin.
+func RegisterClassificationRoutes(rg *gin.RouterGroup, h *handlers.ClassificationHandler) {
+	admin := middleware.RequireRole("Tenant Admin", "DFIR Admin")
+	rg.GET("/classification/levels", h.Levels)
+	rg.GET("/classification/labels/:resource_type/:resource_id", h.GetLabel)
+	rg.PUT("/classification/labels/:resource_type/:resource_id", admin, middleware.RequireStepUp(), h.SetLabel)
+	rg.DELETE("/classification/labels/:resource_type/:resource_id", admin, middleware.RequireStepUp(), h.ClearLabel)
+
+	rg.GET("/clearances", admin, h.ListClearances)
+	rg.PUT("/clearances/:user_id", admin, middleware.RequireStepUp(), h.SetClearance)
+	rg.DELETE("/clearances/:user_id", admin, h.RevokeClearance)
+
+	rg.GET("/need-to-know-groups", admin, h.ListGroups)
+	rg.POST("/need-to-know-groups", admin, middleware.RequireStepUp(), h.CreateGroup)
+	rg.DELETE("/need-to-know-groups/:id", admin, middleware.RequireStepUp(), h.DeleteGroup)
+	rg.GET("/need-to-know-groups/:id/members", admin, h.ListMembers)
+	rg.POST("/need-to-know-groups/:id/members", admin, middleware.RequireStepUp(), h.AddMember)
+	rg.DELETE("/need-to-know-groups/:id/members/:user_id", admin, h.RemoveMember)
+}
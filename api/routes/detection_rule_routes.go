@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"aegis-api/handlers"
+	"aegis-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterDetectionRuleRoutes(rg *gin.RouterGroup, h *handlers.DetectionRuleHandler, checker middleware.PermissionChecker) {
+	rules := rg.Group("/detection-rules")
+	{
+		rules.GET("", middleware.RequirePermission("evidence:view", checker), h.ListRules)
+		rules.GET("/:rule_id", middleware.RequirePermission("evidence:view", checker), h.GetRule)
+		rules.GET("/:rule_id/versions", middleware.RequirePermission("evidence:view", checker), h.ListRuleVersions)
+
+		rules.POST("", middleware.RequirePermission("detection:manage_rules", checker), h.UploadRule)
+		rules.PATCH("/:rule_id", middleware.RequirePermission("detection:manage_rules", checker), h.SetRuleEnabled)
+	}
+
+	// Matches and scans are read and run on cases the user works on.
+	caseView := middleware.RequireCaseAccess("evidence:view", middleware.ResourceCase, "case_id")
+	caseTag := middleware.RequireCaseAccess("evidence:tag", middleware.ResourceCase, "case_id")
+	detections := rg.Group("/cases/:case_id/detections")
+	{
+		detections.GET("", middleware.RequirePermission("evidence:view", checker), caseView, h.ListMatches)
+
+		// Scans tag evidence, so they need the tagging permission as well as view.
+		detections.POST("/yara",
+			middleware.RequirePermission("evidence:view", checker),
+			middleware.RequirePermission("evidence:tag", checker),
+			caseTag,
+			h.ScanYARA,
+		)
+		detections.POST("/sigma",
+			middleware.RequirePermission("evidence:view", checker),
+			middleware.RequirePermission("evidence:tag", checker),
+			caseTag,
+			h.ScanSigma,
+		)
+
+		detections.POST("/:match_id/accept", middleware.RequirePermission("evidence:tag", checker), caseTag, h.AcceptSuggestion)
+		detections.POST("/:match_id/dismiss", middleware.RequirePermission("evidence:tag", checker), caseTag, h.DismissSuggestion)
+	}
+}
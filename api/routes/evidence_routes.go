@@ -1,6 +1,8 @@
 package routes
 
 import (
+	"time"
+
 	"aegis-api/handlers"
 	"aegis-api/middleware"
 
@@ -17,21 +19,39 @@ func RegisterEvidenceRoutes(
 	// ─── Evidence Viewer ──────────────
 	evidence := r.Group("/evidence")
 	evidence.Use(middleware.RequirePermission("evidence:view", permChecker))
-	evidence.GET("/case/:case_id", viewerHandler.GetEvidenceByCaseID)
-	evidence.GET("/:evidence_id", viewerHandler.GetEvidenceByID)
+	caseEvidence := middleware.RequireCaseAccess("evidence:view", middleware.ResourceCase, "case_id")
+	item := middleware.RequireCaseAccess("evidence:view", middleware.ResourceEvidence, "evidence_id")
+	evidence.GET("/case/:case_id", caseEvidence, viewerHandler.GetEvidenceByCaseID)
+	evidence.GET("/:evidence_id", item, viewerHandler.GetEvidenceByID)
 	evidence.GET("/search", viewerHandler.SearchEvidence)
-	evidence.POST("/case/:case_id/filter", viewerHandler.GetFilteredEvidence)
-	evidence.GET("/:evidence_id/verify-chain", metadataHandler.VerifyEvidenceChain)
+	evidence.POST("/case/:case_id/filter", caseEvidence, viewerHandler.GetFilteredEvidence)
+	evidence.GET("/:evidence_id/verify-chain", item, metadataHandler.VerifyEvidenceChain)
 
 	// ─── Evidence Tags ────────────────
 	// All tagging requires evidence:tag permission
 	tags := r.Group("/evidence-tags")
 	tags.Use(middleware.RequirePermission("evidence:tag", permChecker))
-	tags.POST("/tag", tagHandler.TagEvidence)
-	tags.POST("/untag", tagHandler.UntagEvidence)
+	tagItem := middleware.RequireCaseAccessInBody("evidence:tag", middleware.ResourceEvidence, "evidence_id")
+	tags.POST("/tag", tagItem, tagHandler.TagEvidence)
+	tags.POST("/untag", tagItem, tagHandler.UntagEvidence)
 
 	// Viewing tags just needs view permission
 	tags.GET("/:evidence_id",
 		middleware.RequirePermission("evidence:view", permChecker),
+		middleware.RequireCaseAccess("evidence:view", middleware.ResourceEvidence, "evidence_id"),
 		tagHandler.GetEvidenceTags)
 }
+
+// RegisterEvidenceTransferRoutes mounts evidence upload and download. Both
+// are checked against the user's role on the evidence's case.
+func RegisterEvidenceTransferRoutes(
+	api *gin.RouterGroup,
+	upload *handlers.UploadHandler,
+	download *handlers.DownloadHandler,
+	limits middleware.EndpointLimitConfig,
+) {
+	api.POST("/upload", middleware.IPThrottleMiddleware(20, time.Minute, limits), middleware.AuthMiddleware(),
+		middleware.RequireCaseAccessInBody("evidence:upload", middleware.ResourceCase, "caseId"), upload.Upload)
+	api.GET("/download/:evidence_id", middleware.IPThrottleMiddleware(20, time.Minute, limits), middleware.AuthMiddleware(), middleware.RequireStepUp(),
+		middleware.RequireCaseAccess("evidence:view", middleware.ResourceEvidence, "evidence_id"), download.Download)
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"aegis-api/handlers"
+	"aegis-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterInvitationRoutes registers invitations of external
+// collaborators. Admins invite, revoke and read access reports; the
+// invitee accepts and lists their own invitations.
+func RegisterInvitationRoutes(rg *gin.RouterGroup, h *handlers.InvitationHandler) {
+	admin := middleware.RequireRole("Tenant Admin", "DFIR Admin")
+	addMember := middleware.RequireCaseAccess("collaboration:add_member", middleware.ResourceCase, "case_id")
+	rg.POST("/cases/:case_id/invitations", admin, addMember, middleware.RequireStepUp(), h.Invite)
+	rg.GET("/cases/:case_id/invitations", admin, addMember, h.ListForCase)
+	rg.GET("/invitations", admin, h.List)
+	rg.POST("/invitations/:id/revoke", admin, h.Revoke)
+	rg.GET("/invitations/:id/access-report", admin, h.AccessReport)
+
+	rg.POST("/invitations/accept", h.Accept)
+	rg.GET("/invitations/mine", h.ListMine)
+}
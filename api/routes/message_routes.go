@@ -2,14 +2,19 @@ package routes
 
 import (
 	"aegis-api/handlers"
+	"aegis-api/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
 func RegisterMessageRoutes(r *gin.RouterGroup, h *handlers.MessageHandler) {
-	r.POST("/threads/:threadID/messages", h.SendMessage)
-	r.GET("/threads/:threadID/messages", h.GetMessagesByThread)
-	r.POST("/messages/:messageID/approve", h.ApproveMessage)
-	r.POST("/messages/:messageID/reactions", h.AddReaction)
-	r.DELETE("/messages/:messageID/reactions/:userID", h.RemoveReaction)
+	view := middleware.RequireCaseAccess("case:view", middleware.ResourceThread, "threadID")
+	post := middleware.RequireCaseAccess("thread:create", middleware.ResourceThread, "threadID")
+	message := middleware.RequireCaseAccess("thread:create", middleware.ResourceMessage, "messageID")
+
+	r.POST("/threads/:threadID/messages", post, h.SendMessage)
+	r.GET("/threads/:threadID/messages", view, h.GetMessagesByThread)
+	r.POST("/messages/:messageID/approve", message, h.ApproveMessage)
+	r.POST("/messages/:messageID/reactions", message, h.AddReaction)
+	r.DELETE("/messages/:messageID/reactions/:userID", message, h.RemoveReaction)
 }
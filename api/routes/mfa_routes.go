@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"aegis-api/handlers"
+	"aegis-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterMFARoutes registers the second step of login on the public auth
+// group, and enrolment, step-up and policy on the protected group.
+func RegisterMFARoutes(auth, rg *gin.RouterGroup, h *handlers.MFAHandler) {
+	auth.POST("/mfa/verify", h.Verify)
+	auth.POST("/mfa/enroll", h.StartEnrollment)
+	auth.POST("/mfa/enroll/confirm", h.ConfirmEnrollment)
+
+	mfa := rg.Group("/auth/mfa")
+	{
+		mfa.GET("", h.Status)
+		mfa.POST("/setup", h.Setup)
+		mfa.POST("/enable", h.Enable)
+		mfa.POST("/step-up", h.StepUp)
+		mfa.POST("/recovery-codes", middleware.RequireStepUp(), h.RegenerateRecoveryCodes)
+		mfa.DELETE("", middleware.RequireStepUp(), h.Disable)
+	}
+
+	rg.GET("/mfa-policy", middleware.RequireRole("Tenant Admin", "DFIR Admin"), h.GetPolicy)
+	rg.PUT("/mfa-policy", middleware.RequireRole("Tenant Admin", "DFIR Admin"), middleware.RequireStepUp(), h.UpdatePolicy)
+}
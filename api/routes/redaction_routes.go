@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"aegis-api/handlers"
+	"aegis-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRedactionRoutes(rg *gin.RouterGroup, h *handlers.RedactionHandler, permChecker middleware.PermissionChecker) {
+	rg.GET("/redaction-reasons", h.ListReasons)
+	rg.DELETE("/redactions/:markID", middleware.RequireCaseAccess("case:update", middleware.ResourceRedactionMark, "markID"), h.WithdrawMark)
+
+	reportView := middleware.RequireCaseAccess("case:view", middleware.ResourceReport, "reportID")
+	reportUpdate := middleware.RequireCaseAccess("case:update", middleware.ResourceReport, "reportID")
+	reports := rg.Group("/reports")
+	{
+		reports.GET("/:reportID/redactions", reportView, h.ListReportMarks)
+		reports.POST("/:reportID/redactions", reportUpdate, h.AddReportMark)
+		reports.GET("/:reportID/redacted-exports", reportView, h.ListReportDerivatives)
+	}
+
+	evidence := rg.Group("/evidence")
+	evidence.Use(middleware.RequirePermission("evidence:view", permChecker))
+	evidenceView := middleware.RequireCaseAccess("evidence:view", middleware.ResourceEvidence, "evidence_id")
+	evidenceUpdate := middleware.RequireCaseAccess("case:update", middleware.ResourceEvidence, "evidence_id")
+	{
+		evidence.GET("/:evidence_id/redactions", evidenceView, h.ListEvidenceMarks)
+		evidence.POST("/:evidence_id/redactions", evidenceUpdate, h.AddEvidenceMark)
+		evidence.GET("/:evidence_id/redacted-copies", evidenceView, h.ListEvidenceDerivatives)
+		evidence.POST("/:evidence_id/redacted-copies", evidenceUpdate, h.DeriveEvidence)
+	}
+
+	profiles := rg.Group("/redaction-profiles")
+	{
+		profiles.GET("", h.ListProfiles)
+		profiles.POST("", middleware.RequireRole("Tenant Admin", "DFIR Admin"), h.CreateProfile)
+		profiles.PUT("/:profileID", middleware.RequireRole("Tenant Admin", "DFIR Admin"), h.UpdateProfile)
+		profiles.DELETE("/:profileID", middleware.RequireRole("Tenant Admin", "DFIR Admin"), h.DeleteProfile)
+	}
+}
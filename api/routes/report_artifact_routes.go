@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"aegis-api/handlers"
+	"aegis-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterReportArtifactRoutes(rg *gin.RouterGroup, h *handlers.ReportArtifactHandler) {
+	view := middleware.RequireCaseAccess("case:view", middleware.ResourceReport, "reportID")
+
+	reports := rg.Group("/reports")
+	{
+		reports.POST("/verify", h.VerifyPDF)
+		reports.POST("/:reportID/artifact", middleware.RequireCaseAccess("case:update", middleware.ResourceReport, "reportID"), h.SealReport)
+		reports.GET("/:reportID/artifact", view, h.DownloadArtifact)
+		reports.GET("/:reportID/artifact/signature", view, h.DownloadSignature)
+	}
+
+	keys := rg.Group("/report-signing-keys")
+	{
+		keys.GET("", h.ListKeys)
+		keys.POST("/rotate", middleware.RequireRole("Tenant Admin", "DFIR Admin"), h.RotateKey)
+	}
+}
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"aegis-api/handlers"
+	"aegis-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterReportJobRoutes(rg *gin.RouterGroup, h *handlers.ReportJobHandler) {
+	admin := middleware.RequireRole("Tenant Admin", "DFIR Admin")
+
+	reportJobs := rg.Group("/report-jobs")
+	{
+		reportJobs.GET("", admin, h.ListJobs)
+		reportJobs.POST("", admin, h.CreateJob)
+		reportJobs.GET("/:jobID", admin, h.GetJob)
+		reportJobs.PUT("/:jobID", admin, h.UpdateJob)
+		reportJobs.DELETE("/:jobID", admin, h.DeleteJob)
+		reportJobs.POST("/:jobID/runs", admin, h.RunNow)
+		reportJobs.GET("/:jobID/runs", h.ListRuns)
+	}
+
+	runs := rg.Group("/report-job-runs")
+	{
+		runs.GET("/:runID", h.GetRun)
+		runs.POST("/:runID/retry", admin, h.RetryFailed)
+		runs.GET("/:runID/items/:itemID/artifact",
+			middleware.RequireCaseAccess("case:view", middleware.ResourceReportJobItem, "itemID"), h.DownloadArtifact)
+	}
+}
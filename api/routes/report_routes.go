@@ -2,42 +2,68 @@ package routes
 
 import (
 	"aegis-api/handlers"
+	"aegis-api/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
 // RegisterReportRoutes registers routes for managing reports and their sections.
 func RegisterReportRoutes(router *gin.RouterGroup, handler *handlers.ReportHandler) {
+	// Reports are read and edited with the case they belong to.
+	caseView := middleware.RequireCaseAccess("case:view", middleware.ResourceCase, "caseID")
+	caseUpdate := middleware.RequireCaseAccess("case:update", middleware.ResourceCase, "caseID")
+	view := middleware.RequireCaseAccess("case:view", middleware.ResourceReport, "reportID")
+	update := middleware.RequireCaseAccess("case:update", middleware.ResourceReport, "reportID")
+
 	report := router.Group("/reports")
 	{
 		// Report-level endpoints
-		report.POST("/cases/:caseID", handler.GenerateReport)    // Generate report for case
-		report.GET("/cases/:caseID", handler.GetReportsByCaseID) // Get all reports for a case
+		report.POST("/cases/:caseID", caseUpdate, handler.GenerateReport)  // Generate report for case
+		report.GET("/cases/:caseID", caseView, handler.GetReportsByCaseID) // Get all reports for a case
 		//report.GET("/evidence/:evidenceID", handler.GetReportsByEvidenceID)  // Get all reports for evidence
-		report.GET("/:reportID", handler.GetReportByID) // Get a specific report
+		report.GET("/:reportID", view, handler.GetReportByID) // Get a specific report
 		//report.PUT("/:reportID", handler.UpdateReport)                       // Update a report
-		report.DELETE("/:reportID", handler.DeleteReport) // Delete a report
+		report.DELETE("/:reportID", update, handler.DeleteReport) // Delete a report
 
 		// Download endpoints
-		report.GET("/:reportID/download/pdf", handler.DownloadReportPDF)   // Download PDF
-		report.GET("/:reportID/download/json", handler.DownloadReportJSON) // Download JSON
+		report.GET("/:reportID/download/pdf", view, handler.DownloadReportPDF)   // Download PDF
+		report.GET("/:reportID/download/json", view, handler.DownloadReportJSON) // Download JSON
+		report.GET("/:reportID/download/docx", view, handler.DownloadReportDOCX) // Download editable Word document
+		report.GET("/:reportID/download/odt", view, handler.DownloadReportODT)   // Download editable OpenDocument text
 		//report.POST("/:reportID/download/pdf", handler.DownloadReportPDF) 
 
 		// Section-level endpoints
-		report.POST("/:reportID/sections", handler.AddSection)                             // Add custom section
-		report.PUT("/:reportID/sections/:sectionID/content", handler.UpdateSectionContent) // Update section content
-		report.PUT("/:reportID/sections/:sectionID/title", handler.UpdateSectionTitle)     // Update section title
-		report.PUT("/:reportID/sections/:sectionID/reorder", handler.ReorderSection)       // Reorder section
-		report.DELETE("/:reportID/sections/:sectionID", handler.DeleteSection)
+		report.POST("/:reportID/sections", update, handler.AddSection)                             // Add custom section
+		report.PUT("/:reportID/sections/:sectionID/content", update, handler.UpdateSectionContent) // Update section content
+		report.PUT("/:reportID/sections/:sectionID/title", update, handler.UpdateSectionTitle)     // Update section title
+		report.PUT("/:reportID/sections/:sectionID/reorder", update, handler.ReorderSection)       // Reorder section
+		report.DELETE("/:reportID/sections/:sectionID", update, handler.DeleteSection)
+
+		// Merge fields: catalog, rendered preview, freeze/unfreeze values
+		report.GET("/fields", handler.ListMergeFields)
+		report.GET("/:reportID/rendered", view, handler.GetRenderedReport)
+		report.POST("/:reportID/fields/freeze", update, handler.FreezeReportFields)
+		report.DELETE("/:reportID/fields/freeze", update, handler.ThawReportFields)
+
+		// Revision history, diffs, rollback and published snapshots
+		report.GET("/:reportID/revisions", view, handler.ListReportRevisions)
+		report.GET("/:reportID/diff", view, handler.DiffReport)
+		report.POST("/:reportID/rollback", update, handler.RollbackReport)
+		report.GET("/:reportID/sections/:sectionID/revisions", view, handler.ListSectionRevisions)
+		report.GET("/:reportID/sections/:sectionID/revisions/:revision", view, handler.GetSectionRevision)
+		report.GET("/:reportID/sections/:sectionID/diff", view, handler.DiffSection)
+		report.POST("/:reportID/sections/:sectionID/rollback", update, handler.RollbackSection)
+		report.GET("/:reportID/snapshots", view, handler.ListReportSnapshots)
+		report.GET("/:reportID/snapshots/:snapshotID", view, handler.GetReportSnapshot)
 
 		// Context autofill endpoint
-		report.GET("/:reportID/sections/:sectionID/context", handler.GetSectionContext)
+		report.GET("/:reportID/sections/:sectionID/context", view, handler.GetSectionContext)
 
 		// Recent reports endpoint
 		report.GET("/recent", handler.GetRecentReports) // List recent reports
 
 		// Report name update endpoint
-		report.PUT("/:reportID/name", handler.UpdateReportName) // Update report name
+		report.PUT("/:reportID/name", update, handler.UpdateReportName) // Update report name
 		// 🔹 Team-scoped list — add this near the top
 		report.GET("/teams/:teamID", handler.GetReportsForTeam)
 
@@ -46,19 +72,27 @@ func RegisterReportRoutes(router *gin.RouterGroup, handler *handlers.ReportHandl
 
 // RegisterReportAIRoutes registers routes for AI assistance on reports
 func RegisterReportAIRoutes(router *gin.RouterGroup, handler *handlers.ReportAIHandler) {
+	view := middleware.RequireCaseAccess("case:view", middleware.ResourceReport, "reportID")
+	update := middleware.RequireCaseAccess("case:update", middleware.ResourceReport, "reportID")
+
 	reportAI := router.Group("/reports/ai")
 	{
 		// Generate AI suggestion for a section (GET and POST)
-		reportAI.GET("/:reportID/sections/:sectionID/suggest", handler.SuggestSection)
-		reportAI.POST("/:reportID/sections/:sectionID/suggest", handler.SuggestSectionPOST)
+		reportAI.GET("/:reportID/sections/:sectionID/suggest", view, handler.SuggestSection)
+		reportAI.POST("/:reportID/sections/:sectionID/suggest", update, handler.SuggestSectionPOST)
 
 		// Submit feedback on AI suggestion
-		reportAI.POST("/sections/:sectionID/feedback", handler.SubmitFeedback)
+		reportAI.POST("/sections/:sectionID/feedback",
+			middleware.RequireCaseAccessInBody("case:update", middleware.ResourceAISuggestion, "SuggestionID"), handler.SubmitFeedback)
+
+		// Inspect a suggestion's claims, or accept its grounded claims into the section
+		reportAI.GET("/suggestions/:suggestionID", middleware.RequireCaseAccess("case:view", middleware.ResourceAISuggestion, "suggestionID"), handler.GetSuggestion)
+		reportAI.POST("/suggestions/:suggestionID/accept", middleware.RequireCaseAccess("case:update", middleware.ResourceAISuggestion, "suggestionID"), handler.AcceptSuggestion)
 
 		// Optionally, generate AI references for a section
-		reportAI.GET("/:reportID/sections/:sectionID/references", handler.GenerateReferences)
+		reportAI.GET("/:reportID/sections/:sectionID/references", view, handler.GenerateReferences)
 
 		// Enhance summary endpoint
-		reportAI.POST("/enhance-summary", handler.EnhanceSummary)
+		reportAI.POST("/enhance-summary", middleware.RequireCaseAccessInBody("case:update", middleware.ResourceReport, "report_id"), handler.EnhanceSummary)
 	}
 }
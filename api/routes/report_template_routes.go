@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"aegis-api/handlers"
+	"aegis-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterReportTemplateRoutes(rg *gin.RouterGroup, h *handlers.ReportTemplateHandler) {
+	templates := rg.Group("/report-templates")
+	{
+		templates.GET("", h.ListTemplates)
+		templates.GET("/:id", h.GetTemplate)
+		templates.GET("/:id/versions", h.ListVersions)
+		templates.POST("", middleware.RequireRole("Tenant Admin", "DFIR Admin"), h.CreateTemplate)
+		templates.PUT("/:id", middleware.RequireRole("Tenant Admin", "DFIR Admin"), h.UpdateTemplate)
+		templates.POST("/:id/default", middleware.RequireRole("Tenant Admin", "DFIR Admin"), h.SetDefault)
+		templates.DELETE("/:id", middleware.RequireRole("Tenant Admin", "DFIR Admin"), h.ArchiveTemplate)
+	}
+}
@@ -2,15 +2,33 @@ package routes
 
 import (
 	"aegis-api/handlers"
+	"aegis-api/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
 
 func RegisterReportStatusRoutes(router *gin.RouterGroup, handler *handlers.ReportStatusHandler) {
+	// Reviewers decide on reports of cases they can see; the review
+	// workflow then checks they hold a reviewing role.
+	view := middleware.RequireCaseAccess("case:view", middleware.ResourceReport, "reportID")
+	update := middleware.RequireCaseAccess("case:update", middleware.ResourceReport, "reportID")
+	comment := middleware.RequireCaseAccess("comment:create", middleware.ResourceReport, "reportID")
 	reportStatus := router.Group("/reports")
 	{
 
-		reportStatus.PUT("/:reportID/status", handler.UpdateStatus)
+		reportStatus.PUT("/:reportID/status", update, handler.UpdateStatus)
+
+		reportStatus.GET("/:reportID/review", view, handler.GetReview)
+		reportStatus.POST("/:reportID/review/submit", update, handler.Submit)
+		reportStatus.POST("/:reportID/review/approve", view, middleware.RequireStepUp(), handler.Approve)
+		reportStatus.POST("/:reportID/review/request-changes", view, handler.RequestChanges)
+		reportStatus.POST("/:reportID/review/withdraw", update, handler.Withdraw)
+		reportStatus.GET("/:reportID/review/comments", view, handler.ListComments)
+		reportStatus.POST("/:reportID/review/comments", comment, handler.AddComment)
+		reportStatus.POST("/:reportID/review/comments/:commentID/resolve", comment, handler.ResolveComment)
 	}
+
+	router.GET("/report-review-workflow", handler.GetWorkflow)
+	router.PUT("/report-review-workflow", middleware.RequireRole("Tenant Admin", "DFIR Admin"), handler.SetWorkflow)
 }
@@ -0,0 +1,36 @@
+package routes
+
+import (
+	"aegis-api/handlers"
+	"aegis-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterSCIMRoutes registers the SCIM 2.0 endpoint, which directories
+// authenticate to with the tenant's SCIM token rather than a user session,
+// and its configuration on the protected group.
+func RegisterSCIMRoutes(api, rg *gin.RouterGroup, h *handlers.SCIMHandler) {
+	v2 := api.Group("/scim/v2", h.RequireSCIMToken())
+	v2.GET("/ServiceProviderConfig", h.ServiceProviderConfig)
+	v2.GET("/ResourceTypes", h.ResourceTypes)
+	v2.GET("/Users", h.ListUsers)
+	v2.POST("/Users", h.CreateUser)
+	v2.GET("/Users/:id", h.GetUser)
+	v2.PUT("/Users/:id", h.ReplaceUser)
+	v2.PATCH("/Users/:id", h.PatchUser)
+	v2.DELETE("/Users/:id", h.DeleteUser)
+	v2.GET("/Groups", h.ListGroups)
+	v2.POST("/Groups", h.CreateGroup)
+	v2.GET("/Groups/:id", h.GetGroup)
+	v2.PUT("/Groups/:id", h.ReplaceGroup)
+	v2.PATCH("/Groups/:id", h.PatchGroup)
+	v2.DELETE("/Groups/:id", h.DeleteGroup)
+	v2.POST("/Bulk", h.Bulk)
+
+	admin := middleware.RequireRole("Tenant Admin", "DFIR Admin")
+	rg.GET("/scim/config", admin, h.GetConfig)
+	rg.PUT("/scim/config", admin, middleware.RequireStepUp(), h.SaveConfig)
+	rg.DELETE("/scim/config", admin, middleware.RequireStepUp(), h.DeleteConfig)
+	rg.POST("/scim/token", admin, middleware.RequireStepUp(), h.IssueToken)
+}
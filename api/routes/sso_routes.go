@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"aegis-api/handlers"
+	"aegis-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterSSORoutes registers SSO login on the public auth group, and the
+// tenant's identity provider and sign-in settings on the protected group.
+func RegisterSSORoutes(auth, rg *gin.RouterGroup, h *handlers.SSOHandler) {
+	auth.POST("/sso/discover", h.Discover)
+	auth.GET("/sso/oidc/:tenantID/authorize", h.Authorize)
+	auth.POST("/sso/oidc/callback", h.Callback)
+	auth.GET("/sso/saml/:tenantID/metadata", h.SAMLMetadata)
+	auth.GET("/sso/saml/:tenantID/authorize", h.SAMLAuthorize)
+	auth.POST("/sso/saml/:tenantID/acs", h.SAMLACS)
+	auth.GET("/sso/saml/:tenantID/slo", h.SAMLSingleLogout)
+	auth.POST("/sso/complete", h.Complete)
+	rg.POST("/auth/sso/saml/logout", h.SAMLLogout)
+
+	admin := middleware.RequireRole("Tenant Admin", "DFIR Admin")
+	rg.GET("/sso/oidc", admin, h.GetOIDCConfig)
+	rg.PUT("/sso/oidc", admin, middleware.RequireStepUp(), h.SaveOIDCConfig)
+	rg.DELETE("/sso/oidc", admin, middleware.RequireStepUp(), h.DeleteOIDCConfig)
+	rg.GET("/sso/saml", admin, h.GetSAMLConfig)
+	rg.PUT("/sso/saml", admin, middleware.RequireStepUp(), h.SaveSAMLConfig)
+	rg.DELETE("/sso/saml", admin, middleware.RequireStepUp(), h.DeleteSAMLConfig)
+	rg.GET("/auth-settings", admin, h.GetSettings)
+	rg.PUT("/auth-settings", admin, middleware.RequireStepUp(), h.UpdateSettings)
+}
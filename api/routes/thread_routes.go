@@ -2,17 +2,21 @@ package routes
 
 import (
 	"aegis-api/handlers"
+	"aegis-api/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
 func RegisterThreadRoutes(r *gin.RouterGroup, h *handlers.AnnotationThreadHandler) {
-	r.POST("/threads", h.CreateThread)
-	r.GET("/threads/file/:fileID", h.GetThreadsByFile)
-	r.GET("/threads/case/:caseID", h.GetThreadsByCase)
-	r.GET("/threads/:threadID", h.GetThreadByID)
-	r.PATCH("/threads/:threadID/status", h.UpdateThreadStatus)
-	r.PATCH("/threads/:threadID/priority", h.UpdateThreadPriority)
-	r.POST("/threads/:threadID/participants", h.AddParticipant)
-	r.GET("/threads/:threadID/participants", h.GetThreadParticipants)
+	view := middleware.RequireCaseAccess("case:view", middleware.ResourceThread, "threadID")
+	update := middleware.RequireCaseAccess("thread:create", middleware.ResourceThread, "threadID")
+
+	r.POST("/threads", middleware.RequireCaseAccessInBody("thread:create", middleware.ResourceCase, "case_id"), h.CreateThread)
+	r.GET("/threads/file/:fileID", middleware.RequireCaseAccess("evidence:view", middleware.ResourceEvidence, "fileID"), h.GetThreadsByFile)
+	r.GET("/threads/case/:caseID", middleware.RequireCaseAccess("case:view", middleware.ResourceCase, "caseID"), h.GetThreadsByCase)
+	r.GET("/threads/:threadID", view, h.GetThreadByID)
+	r.PATCH("/threads/:threadID/status", update, h.UpdateThreadStatus)
+	r.PATCH("/threads/:threadID/priority", update, h.UpdateThreadPriority)
+	r.POST("/threads/:threadID/participants", update, h.AddParticipant)
+	r.GET("/threads/:threadID/participants", view, h.GetThreadParticipants)
 }
\ No newline at end of file
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"aegis-api/handlers"
+	"aegis-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterWebAuthnRoutes registers passkey login and enrolment on the
+// public auth group, and passkey management, step-up and policy on the
+// protected group.
+func RegisterWebAuthnRoutes(auth, rg *gin.RouterGroup, h *handlers.WebAuthnHandler) {
+	auth.POST("/mfa/webauthn/options", h.LoginOptions)
+	auth.POST("/mfa/webauthn/verify", h.LoginVerify)
+	auth.POST("/mfa/webauthn/enroll", h.EnrollOptions)
+	auth.POST("/mfa/webauthn/enroll/confirm", h.EnrollConfirm)
+
+	passkeys := rg.Group("/auth/passkeys")
+	{
+		passkeys.GET("", h.List)
+		passkeys.POST("/register/options", h.RegisterOptions)
+		passkeys.POST("/register", h.Register)
+		passkeys.PATCH("/:passkeyID", h.Rename)
+		passkeys.DELETE("/:passkeyID", middleware.RequireStepUp(), h.Revoke)
+	}
+	rg.POST("/auth/mfa/step-up/webauthn/options", h.StepUpOptions)
+	rg.POST("/auth/mfa/step-up/webauthn", h.StepUp)
+
+	rg.GET("/webauthn-policy", middleware.RequireRole("Tenant Admin", "DFIR Admin"), h.GetPolicy)
+	rg.PUT("/webauthn-policy", middleware.RequireRole("Tenant Admin", "DFIR Admin"), middleware.RequireStepUp(), h.UpdatePolicy)
+}
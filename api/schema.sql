@@ -179,7 +179,7 @@ CREATE TABLE case_collaborators (
     invited_by      UUID NOT NULL REFERENCES users(id) ON DELETE SET NULL,
     invited_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
     expires_at      TIMESTAMP WITH TIME ZONE,
-    status          VARCHAR(20) NOT NULL DEFAULT 'active', -- active, expired, revoked
+    status          VARCHAR(20) NOT NULL DEFAULT 'active', -- scheduled, active, expired, revoked
     UNIQUE (case_id, user_id)
 );
 
@@ -574,6 +574,10 @@ INSERT INTO permissions (id, name, description) VALUES
 (gen_random_uuid(), 'ioc:delete', 'Delete an indicator of compromise from a case'),
 (gen_random_uuid(), 'ioc:update', 'Update or edit an existing IOC');
 
+-- Detection Rules
+INSERT INTO permissions (id, name, description) VALUES
+(gen_random_uuid(), 'detection:manage_rules', 'Upload, version and enable/disable YARA and Sigma rules');
+
 -- Maps ENUM roles to permissions directly
 CREATE TABLE enum_role_permissions (
     role user_role NOT NULL,
@@ -615,6 +619,9 @@ WHERE name IN (
   -- IOCs
   'ioc:create', 'ioc:view', 'ioc:delete', 'ioc:update',
 
+  -- Detection Rules
+  'detection:manage_rules',
+
   -- Collaboration
   'collaboration:add_member', 'collaboration:remove_member', 'collaboration:change_role',
   'collaboration:message', 'collaboration:view_members',
@@ -1085,3 +1092,928 @@ CREATE TABLE report_ai_feedback (
 );
 
 CREATE INDEX idx_ai_feedback_suggestion_id ON report_ai_feedback(ai_suggestion_id);
+
+-- Investigation graph: typed entities and analyst-authored relations per case
+CREATE TABLE IF NOT EXISTS graph_entities (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id   UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  case_id     UUID NOT NULL REFERENCES cases(id) ON DELETE CASCADE,
+  type        VARCHAR(50) NOT NULL,   -- person, account, host, ip, domain, file, wallet, evidence, timeline_event
+  label       VARCHAR(255) NOT NULL,
+  ref_id      VARCHAR(64),            -- evidence / timeline event ID for linked node types
+  properties  JSONB NOT NULL DEFAULT '{}'::jsonb,
+  created_by  UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_graph_entities_case ON graph_entities(tenant_id, case_id);
+CREATE INDEX IF NOT EXISTS idx_graph_entities_type ON graph_entities(type);
+CREATE INDEX IF NOT EXISTS idx_graph_entities_ref  ON graph_entities(ref_id);
+
+CREATE TABLE IF NOT EXISTS graph_relations (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id   UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  case_id     UUID NOT NULL REFERENCES cases(id) ON DELETE CASCADE,
+  source_id   UUID NOT NULL REFERENCES graph_entities(id) ON DELETE CASCADE,
+  target_id   UUID NOT NULL REFERENCES graph_entities(id) ON DELETE CASCADE,
+  type        VARCHAR(50) NOT NULL,   -- communicated_with, logged_into, downloaded, resolved_to, owns
+  valid_from  TIMESTAMPTZ,
+  valid_to    TIMESTAMPTZ,
+  evidence    JSONB NOT NULL DEFAULT '[]'::jsonb, -- source evidence IDs
+  notes       TEXT,
+  created_by  UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CONSTRAINT chk_graph_relation_bounds CHECK (valid_to IS NULL OR valid_from IS NULL OR valid_to >= valid_from)
+);
+
+CREATE INDEX IF NOT EXISTS idx_graph_relations_case   ON graph_relations(tenant_id, case_id);
+CREATE INDEX IF NOT EXISTS idx_graph_relations_source ON graph_relations(source_id);
+CREATE INDEX IF NOT EXISTS idx_graph_relations_target ON graph_relations(target_id);
+
+-- Detection rules: versioned YARA / Sigma content per tenant
+CREATE TABLE IF NOT EXISTS detection_rules (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id   UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  name        VARCHAR(255) NOT NULL,
+  version     INT NOT NULL,
+  kind        VARCHAR(10) NOT NULL CHECK (kind IN ('yara', 'sigma')),
+  content     TEXT NOT NULL,
+  description TEXT,
+  enabled     BOOLEAN NOT NULL DEFAULT TRUE,
+  created_by  UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CONSTRAINT uq_detection_rule_version UNIQUE (tenant_id, name, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_detection_rules_kind ON detection_rules(tenant_id, kind);
+
+CREATE TABLE IF NOT EXISTS rule_matches (
+  id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id         UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  case_id           UUID NOT NULL REFERENCES cases(id) ON DELETE CASCADE,
+  rule_id           UUID NOT NULL REFERENCES detection_rules(id) ON DELETE CASCADE,
+  rule_name         VARCHAR(255) NOT NULL,  -- YARA rule identifier or Sigma title
+  rule_version      INT NOT NULL,
+  kind              VARCHAR(10) NOT NULL,
+  evidence_id       VARCHAR(64),
+  child_path        TEXT,                   -- member path inside an expanded archive
+  timeline_event_id VARCHAR(64),
+  offsets           JSONB NOT NULL DEFAULT '[]'::jsonb,
+  summary           TEXT,
+  severity          VARCHAR(20),
+  suggestion_status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, accepted, dismissed, none
+  created_by        UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_rule_matches_case     ON rule_matches(tenant_id, case_id);
+CREATE INDEX IF NOT EXISTS idx_rule_matches_evidence ON rule_matches(evidence_id);
+
+-- Detection content authors
+INSERT INTO enum_role_permissions (role, permission_id)
+SELECT r.role::user_role, p.id
+FROM permissions p,
+     unnest(ARRAY['Malware Analyst', 'Detection Engineer', 'Threat Hunter', 'SIEM Analyst']) AS r(role)
+WHERE p.name = 'detection:manage_rules'
+ON CONFLICT DO NOTHING;
+
+-- ─── LLM provider policy and usage accounting ──────────────────
+CREATE TABLE IF NOT EXISTS ai_tenant_policies (
+  tenant_id      UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+  provider       VARCHAR(50),           -- local, openai, fake; empty = deployment default
+  model          VARCHAR(255),
+  no_external_ai BOOLEAN NOT NULL DEFAULT FALSE,
+  max_tokens     INT NOT NULL DEFAULT 0,
+  updated_by     UUID REFERENCES users(id) ON DELETE SET NULL,
+  updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS ai_usage_records (
+  id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id         VARCHAR(64),
+  provider          VARCHAR(50),
+  model             VARCHAR(255),
+  purpose           VARCHAR(100),         -- e.g. timeline.severity, report.suggestion
+  prompt_tokens     INT NOT NULL DEFAULT 0,
+  completion_tokens INT NOT NULL DEFAULT 0,
+  total_tokens      INT NOT NULL DEFAULT 0,
+  latency_ms        BIGINT NOT NULL DEFAULT 0,
+  success           BOOLEAN NOT NULL DEFAULT TRUE,
+  error             TEXT,
+  created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_usage_tenant_created ON ai_usage_records(tenant_id, created_at);
+
+-- ─── Case Q&A: per-case retrieval index and exchange log ───────
+CREATE TABLE IF NOT EXISTS case_qa_chunks (
+  id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id    UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  case_id      UUID NOT NULL REFERENCES cases(id) ON DELETE CASCADE,
+  source_type  VARCHAR(30) NOT NULL,   -- evidence, timeline_event, thread_message, report_section
+  source_id    VARCHAR(64) NOT NULL,
+  parent_id    VARCHAR(64),            -- thread ID or report ID
+  title        TEXT,
+  ordinal      INT NOT NULL DEFAULT 0,
+  content      TEXT NOT NULL,
+  content_hash CHAR(64) NOT NULL,
+  embedder     VARCHAR(100) NOT NULL,  -- provider/model that produced vector
+  vector       BYTEA NOT NULL,         -- little-endian float32
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_case_qa_chunks_case ON case_qa_chunks(tenant_id, case_id);
+
+CREATE TABLE IF NOT EXISTS case_qa_exchanges (
+  id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id  UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  case_id    UUID NOT NULL REFERENCES cases(id) ON DELETE CASCADE,
+  user_id    UUID NOT NULL REFERENCES users(id),
+  question   TEXT NOT NULL,
+  answer     TEXT,
+  citations  JSONB DEFAULT '[]'::jsonb,
+  provider   VARCHAR(50),
+  model      VARCHAR(255),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_case_qa_exchanges_case ON case_qa_exchanges(tenant_id, case_id);
+
+-- ─── Grounded AI report drafting ───────────────────────────────
+-- Every drafted sentence is stored with the sources it cites; only
+-- supported sentences reach suggestion_text.
+ALTER TABLE report_ai_suggestions ADD COLUMN IF NOT EXISTS claims JSONB DEFAULT '[]'::jsonb;
+ALTER TABLE report_ai_suggestions ADD COLUMN IF NOT EXISTS unsupported INT DEFAULT 0;
+ALTER TABLE report_ai_suggestions ADD COLUMN IF NOT EXISTS accepted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE report_ai_suggestions ADD COLUMN IF NOT EXISTS accepted_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS report_section_refs (
+  id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  section_id    CHAR(24) NOT NULL REFERENCES report_sections(id) ON DELETE CASCADE,
+  ref_type      VARCHAR(100) NOT NULL,   -- evidence, timeline_event, ioc
+  ref_id        VARCHAR(64) NOT NULL,
+  suggestion_id UUID REFERENCES report_ai_suggestions(id) ON DELETE SET NULL,  -- set for machine-drafted text
+  claim_index   INT DEFAULT 0,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_section_refs_section ON report_section_refs(section_id);
+
+-- ─── Report templates ─────────────────────────────────────────
+-- Tenant-managed layouts; editing a template appends an immutable version
+-- and every report records the version it was generated from.
+CREATE TABLE IF NOT EXISTS report_templates (
+  id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id       UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  name            VARCHAR(255) NOT NULL,
+  case_type       VARCHAR(50) NOT NULL,   -- incident_response, ediscovery, expert_witness, ...
+  description     TEXT,
+  current_version INT NOT NULL DEFAULT 1,
+  is_default      BOOLEAN NOT NULL DEFAULT FALSE,
+  archived        BOOLEAN NOT NULL DEFAULT FALSE,
+  created_by      UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_templates_tenant ON report_templates(tenant_id, case_type);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_report_templates_default
+  ON report_templates(tenant_id, case_type) WHERE is_default AND NOT archived;
+
+CREATE TABLE IF NOT EXISTS report_template_versions (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  template_id UUID NOT NULL REFERENCES report_templates(id) ON DELETE CASCADE,
+  version     INT NOT NULL,
+  sections    JSONB NOT NULL,   -- ordered [{title, default_content, required, placeholders}]
+  change_note TEXT,
+  created_by  UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (template_id, version)
+);
+
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS template_id UUID REFERENCES report_templates(id) ON DELETE SET NULL;
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS template_version INT NOT NULL DEFAULT 1;
+
+-- ─── Report merge fields ──────────────────────────────────────
+-- Section content may contain {{ field }} placeholders resolved from live
+-- case data at render time. Freezing stores the resolved values on each
+-- section (report_contents.sections.frozen_fields in MongoDB).
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS fields_frozen_at TIMESTAMP;
+
+-- ─── Report revisions and published snapshots ─────────────────
+-- Every section edit stores an immutable copy of the section. All sections
+-- touched by one change share a report_seq; the first change also records
+-- a baseline of every section as it was before.
+CREATE TABLE IF NOT EXISTS report_section_revisions (
+  id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  report_id     UUID NOT NULL REFERENCES reports(id) ON DELETE CASCADE,
+  report_seq    BIGINT NOT NULL,
+  section_id    CHAR(24) NOT NULL,   -- MongoDB section ObjectID
+  revision      INT NOT NULL,
+  action        VARCHAR(20) NOT NULL, -- baseline, created, edited, retitled, reordered, deleted, restored, ai_accepted
+  title         TEXT,
+  restored_from BIGINT,              -- report_seq a rollback copied from
+  section       JSONB NOT NULL,
+  author_id     UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CONSTRAINT uq_section_revision UNIQUE (section_id, revision)
+);
+
+CREATE INDEX IF NOT EXISTS idx_section_revisions_report_seq ON report_section_revisions(report_id, report_seq);
+
+-- Rendered content pinned when a report is published; never updated.
+CREATE TABLE IF NOT EXISTS report_snapshots (
+  id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  report_id      UUID NOT NULL REFERENCES reports(id) ON DELETE CASCADE,
+  version        INT NOT NULL,
+  report_seq     BIGINT NOT NULL,
+  status         VARCHAR(20) NOT NULL,
+  name           VARCHAR(255),
+  sections       JSONB NOT NULL,
+  content_sha256 CHAR(64) NOT NULL,
+  pinned_by      UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CONSTRAINT uq_report_snapshot_version UNIQUE (report_id, version)
+);
+
+-- ─── Report review workflow ─────────────────
+-- Tenants configure ordered approval stages; tenants without a row use the
+-- built-in single "Peer review" stage.
+CREATE TABLE IF NOT EXISTS report_review_workflows (
+  tenant_id  UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+  stages     JSONB NOT NULL,      -- [{name, roles[], min_approvals}]
+  updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One row per review round. Stages are copied from the workflow at
+-- submission; version is bumped on every update for optimistic locking.
+CREATE TABLE IF NOT EXISTS report_reviews (
+  id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  report_id     UUID NOT NULL REFERENCES reports(id) ON DELETE CASCADE,
+  tenant_id     UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  round         INT NOT NULL,
+  stages        JSONB NOT NULL,
+  current_stage INT NOT NULL DEFAULT 0,
+  state         VARCHAR(20) NOT NULL, -- in_review, changes_requested, withdrawn, published
+  author_id     UUID NOT NULL,
+  submitted_by  UUID NOT NULL,
+  submitted_at  TIMESTAMPTZ NOT NULL,
+  closed_at     TIMESTAMPTZ,
+  version       INT NOT NULL DEFAULT 1,
+  CONSTRAINT uq_report_review_round UNIQUE (report_id, round)
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_reviews_tenant ON report_reviews(tenant_id);
+
+CREATE TABLE IF NOT EXISTS report_review_decisions (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  review_id   UUID NOT NULL REFERENCES report_reviews(id) ON DELETE CASCADE,
+  report_id   UUID NOT NULL REFERENCES reports(id) ON DELETE CASCADE,
+  stage       INT NOT NULL,
+  user_id     UUID NOT NULL REFERENCES users(id),
+  user_role   VARCHAR(100),
+  decision    VARCHAR(20) NOT NULL, -- submitted, approved, changes_requested, withdrawn, published
+  comment     TEXT,
+  snapshot_id UUID REFERENCES report_snapshots(id),
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_review_decisions_review ON report_review_decisions(review_id);
+CREATE INDEX IF NOT EXISTS idx_report_review_decisions_report ON report_review_decisions(report_id);
+
+-- Reviewer comments anchored to a report section, optionally to a quoted
+-- passage. Replies carry the parent's anchor.
+CREATE TABLE IF NOT EXISTS report_review_comments (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  report_id   UUID NOT NULL REFERENCES reports(id) ON DELETE CASCADE,
+  review_id   UUID REFERENCES report_reviews(id) ON DELETE SET NULL,
+  section_id  CHAR(24) NOT NULL,   -- MongoDB section ObjectID
+  quote       TEXT,
+  parent_id   UUID REFERENCES report_review_comments(id) ON DELETE CASCADE,
+  author_id   UUID NOT NULL REFERENCES users(id),
+  body        TEXT NOT NULL,
+  resolved    BOOLEAN NOT NULL DEFAULT FALSE,
+  resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  resolved_at TIMESTAMPTZ,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_review_comments_section ON report_review_comments(report_id, section_id);
+
+-- ─── Sealed report artifacts ─────────────────
+-- Ed25519 keys that sign published report PDFs. Private keys are stored
+-- AES-GCM encrypted; retired keys are kept so older artifacts verify.
+CREATE TABLE IF NOT EXISTS report_signing_keys (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id   UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  algorithm   VARCHAR(20) NOT NULL,
+  public_key  TEXT NOT NULL,
+  private_key TEXT NOT NULL,
+  fingerprint CHAR(64) NOT NULL,   -- SHA-256 of the raw public key
+  active      BOOLEAN NOT NULL DEFAULT TRUE,
+  created_by  UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  retired_at  TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_report_signing_keys_active ON report_signing_keys(tenant_id) WHERE active;
+
+-- The PDF rendered from each published version, exactly as signed.
+-- Rows are written once and never updated.
+CREATE TABLE IF NOT EXISTS report_artifacts (
+  id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id     UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  report_id     UUID NOT NULL REFERENCES reports(id) ON DELETE CASCADE,
+  version       INT NOT NULL,
+  snapshot_id   UUID NOT NULL REFERENCES report_snapshots(id),
+  report_number VARCHAR(255),
+  name          VARCHAR(255),
+  sha256        CHAR(64) NOT NULL,
+  size          BIGINT NOT NULL,
+  pdf           BYTEA NOT NULL,
+  bundle        JSONB NOT NULL,    -- detached signature bundle
+  key_id        UUID NOT NULL REFERENCES report_signing_keys(id),
+  created_by    UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CONSTRAINT uq_report_artifact_version UNIQUE (report_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_artifacts_sha256 ON report_artifacts(tenant_id, sha256);
+
+-- ─── Redaction ─────────────────
+-- Passages of report text (span) and rectangles of evidence images or PDF
+-- pages (region) to redact, with the reason. Withdrawn marks are kept.
+CREATE TABLE IF NOT EXISTS redaction_marks (
+  id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id    UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  case_id      UUID NOT NULL REFERENCES cases(id) ON DELETE CASCADE,
+  target_type  VARCHAR(20) NOT NULL CHECK (target_type IN ('report','evidence')),
+  target_id    UUID NOT NULL,
+  kind         VARCHAR(10) NOT NULL CHECK (kind IN ('span','region')),
+  section_id   VARCHAR(24),         -- span: report section; NULL for the whole report
+  quote        TEXT,                -- span: text to redact
+  occurrence   INT NOT NULL DEFAULT 0,   -- span: nth match only; 0 for all
+  page         INT NOT NULL DEFAULT 0,   -- region: PDF page (1-based); 0 for images / every page
+  x            DOUBLE PRECISION,    -- region, as fractions of the page
+  y            DOUBLE PRECISION,
+  w            DOUBLE PRECISION,
+  h            DOUBLE PRECISION,
+  reason       VARCHAR(40) NOT NULL,
+  note         TEXT,
+  created_by   UUID NOT NULL REFERENCES users(id),
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  withdrawn_by UUID REFERENCES users(id),
+  withdrawn_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_redaction_marks_target ON redaction_marks(tenant_id, target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_redaction_marks_case_id ON redaction_marks(case_id);
+
+-- Audience-specific selections of redaction reasons. Profiles bound to
+-- roles apply to every export by users in those roles.
+CREATE TABLE IF NOT EXISTS redaction_profiles (
+  id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id  UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  name       VARCHAR(120) NOT NULL,
+  audience   TEXT,
+  reasons    JSONB NOT NULL,   -- ["personal_data", ...]
+  roles      JSONB NOT NULL,   -- ["External Collaborator", ...]
+  created_by UUID NOT NULL REFERENCES users(id),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CONSTRAINT idx_redaction_profiles_name UNIQUE (tenant_id, name)
+);
+
+-- Redacted artifacts and the marks applied. Redacted evidence is stored as
+-- new evidence of the same case; the original is never modified.
+CREATE TABLE IF NOT EXISTS redaction_derivatives (
+  id                  UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id           UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  source_type         VARCHAR(20) NOT NULL,
+  source_id           UUID NOT NULL,
+  source_sha256       CHAR(64),
+  profile_ids         JSONB NOT NULL,
+  marks               JSONB NOT NULL,   -- [{"id": ..., "reason": ...}]
+  format              VARCHAR(20) NOT NULL,
+  sha256              CHAR(64) NOT NULL,
+  sha512              CHAR(128) NOT NULL,
+  size                BIGINT NOT NULL,
+  derived_evidence_id UUID REFERENCES evidence(id) ON DELETE SET NULL,
+  created_by          UUID NOT NULL REFERENCES users(id),
+  created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_redaction_derivatives_source ON redaction_derivatives(source_type, source_id);
+CREATE INDEX IF NOT EXISTS idx_redaction_derivatives_sha256 ON redaction_derivatives(sha256);
+CREATE INDEX IF NOT EXISTS idx_redaction_derivatives_tenant_id ON redaction_derivatives(tenant_id);
+
+-- ─── Report jobs ─────────────────
+
+-- Bulk report generation over the cases matching a filter, on a cron
+-- schedule (evaluated in timezone) or on demand.
+CREATE TABLE IF NOT EXISTS report_jobs (
+  id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id    UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  team_id      UUID NOT NULL,
+  name         VARCHAR(120) NOT NULL,
+  template_id  UUID,
+  format       VARCHAR(10) NOT NULL DEFAULT 'pdf',
+  filter       JSONB NOT NULL,   -- {"team_ids": [], "statuses": [], "priorities": [], "stages": []}
+  schedule     VARCHAR(120),
+  timezone     VARCHAR(64) NOT NULL DEFAULT 'UTC',
+  recipients   JSONB NOT NULL,   -- [user id, ...]
+  max_attempts INT NOT NULL DEFAULT 3,
+  enabled      BOOLEAN NOT NULL DEFAULT TRUE,
+  next_run_at  TIMESTAMPTZ,
+  last_run_at  TIMESTAMPTZ,
+  created_by   UUID NOT NULL REFERENCES users(id),
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_jobs_tenant_id ON report_jobs(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_report_jobs_next_run_at ON report_jobs(next_run_at);
+
+CREATE TABLE IF NOT EXISTS report_job_runs (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  job_id      UUID NOT NULL REFERENCES report_jobs(id) ON DELETE CASCADE,
+  tenant_id   UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  trigger     VARCHAR(20) NOT NULL,   -- schedule | manual | retry
+  status      VARCHAR(30) NOT NULL,   -- queued | running | completed | completed_with_errors | failed
+  total       INT NOT NULL,
+  succeeded   INT NOT NULL DEFAULT 0,
+  failed      INT NOT NULL DEFAULT 0,
+  started_by  UUID NOT NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  started_at  TIMESTAMPTZ,
+  finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_job_runs_job_id ON report_job_runs(job_id);
+CREATE INDEX IF NOT EXISTS idx_report_job_runs_tenant_id ON report_job_runs(tenant_id);
+
+-- One row per case in a run, with the exported report once generated.
+CREATE TABLE IF NOT EXISTS report_job_items (
+  id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  run_id     UUID NOT NULL REFERENCES report_job_runs(id) ON DELETE CASCADE,
+  case_id    UUID NOT NULL,
+  case_title TEXT,
+  team_id    UUID,
+  status     VARCHAR(20) NOT NULL,   -- pending | running | retrying | succeeded | failed
+  attempts   INT NOT NULL DEFAULT 0,
+  report_id  UUID,
+  artifact   BYTEA,
+  sha256     CHAR(64),
+  size       BIGINT,
+  error      TEXT,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  done_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_job_items_run_id ON report_job_items(run_id);
+
+-- ─── Case closure packages ─────────────────
+
+-- Each export of a case's closure package. The archive is streamed and not
+-- stored; the manifest hash and its signature (made with the tenant's
+-- report signing key) are kept so a copy can be verified later.
+CREATE TABLE IF NOT EXISTS case_closure_packages (
+  id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id        UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  case_id          UUID NOT NULL REFERENCES cases(id) ON DELETE CASCADE,
+  name             VARCHAR(255) NOT NULL,
+  include_evidence BOOLEAN NOT NULL DEFAULT FALSE,
+  status           VARCHAR(20) NOT NULL,   -- streaming | completed | failed
+  items            INT NOT NULL DEFAULT 0,
+  bytes            BIGINT NOT NULL DEFAULT 0,
+  errors           INT NOT NULL DEFAULT 0,
+  manifest_sha256  CHAR(64),
+  key_id           UUID REFERENCES report_signing_keys(id),
+  signature        TEXT,
+  error            TEXT,
+  created_by       UUID NOT NULL REFERENCES users(id),
+  created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  finished_at      TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_case_closure_packages_case_id ON case_closure_packages(case_id);
+CREATE INDEX IF NOT EXISTS idx_case_closure_packages_manifest_sha256 ON case_closure_packages(manifest_sha256);
+
+-- ─── Auth sessions ─────────────────
+
+-- One row per sign-in. Access tokens are short-lived and carry the session
+-- id ("sid"); they are renewed with single-use refresh tokens. Bumping
+-- users.token_version ends every session started before.
+CREATE TABLE IF NOT EXISTS auth_sessions (
+  id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id        UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  tenant_id      UUID REFERENCES tenants(id) ON DELETE CASCADE,
+  token_version  INT NOT NULL,
+  device_name    VARCHAR(255),
+  ip_address     VARCHAR(64),
+  user_agent     TEXT,
+  created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_active_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at     TIMESTAMPTZ NOT NULL,
+  revoked_at     TIMESTAMPTZ,
+  revoked_reason VARCHAR(50)   -- logout | logout_all | revoked | admin_revoked | refresh_token_reuse | ...
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_user_id ON auth_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_tenant_id ON auth_sessions(tenant_id);
+
+-- Only hashes are stored. A token is exchanged once (used_at); presenting
+-- it again revokes its session.
+CREATE TABLE IF NOT EXISTS auth_refresh_tokens (
+  id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  session_id UUID NOT NULL REFERENCES auth_sessions(id) ON DELETE CASCADE,
+  token_hash CHAR(64) NOT NULL UNIQUE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMPTZ NOT NULL,
+  used_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_refresh_tokens_session_id ON auth_refresh_tokens(session_id);
+
+-- ─── Multi-factor authentication ─────────────────
+
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS mfa_secret             TEXT,
+  ADD COLUMN IF NOT EXISTS mfa_enabled            BOOLEAN DEFAULT FALSE,
+  ADD COLUMN IF NOT EXISTS mfa_setup_completed_at TIMESTAMPTZ;
+
+-- One-time recovery codes. Only a SHA-256 of the normalised code is kept.
+CREATE TABLE IF NOT EXISTS mfa_backup_codes (
+  id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id    UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code_hash  CHAR(64) NOT NULL,
+  used       BOOLEAN NOT NULL DEFAULT FALSE,
+  used_at    TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_backup_codes_user_id ON mfa_backup_codes(user_id);
+
+-- Roles that must sign in with MFA ("*" for everyone) and how long a
+-- step-up verification lasts for sensitive actions.
+CREATE TABLE IF NOT EXISTS tenant_mfa_policies (
+  tenant_id       UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+  required_roles  JSONB NOT NULL DEFAULT '[]',
+  step_up_minutes INT NOT NULL DEFAULT 5,
+  updated_by      UUID,
+  updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- ─── Passkeys ─────────────────
+
+-- WebAuthn credentials. They satisfy the tenant MFA policy like TOTP and
+-- can be used for login and step-up. A signature counter that goes
+-- backwards revokes the credential as a likely clone.
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+  id                 UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id            UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  tenant_id          UUID REFERENCES tenants(id) ON DELETE CASCADE,
+  credential_id      TEXT NOT NULL UNIQUE,  -- base64url
+  public_key         BYTEA NOT NULL,        -- COSE_Key
+  algorithm          INT NOT NULL,
+  sign_count         BIGINT NOT NULL DEFAULT 0,
+  aaguid             VARCHAR(36),
+  attestation_format VARCHAR(32),
+  transports         JSONB,
+  user_verified      BOOLEAN NOT NULL DEFAULT FALSE,
+  name               VARCHAR(100) NOT NULL,
+  created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_used_at       TIMESTAMPTZ,
+  revoked_at         TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user_id ON webauthn_credentials(user_id);
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_tenant_id ON webauthn_credentials(tenant_id);
+
+-- Attestation: none | direct (certificate-backed "packed" attestation,
+-- optionally limited to the listed authenticator AAGUIDs).
+CREATE TABLE IF NOT EXISTS tenant_webauthn_policies (
+  tenant_id         UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+  attestation       VARCHAR(16) NOT NULL DEFAULT 'none',
+  allowed_aaguids   JSONB NOT NULL DEFAULT '[]',
+  user_verification VARCHAR(16) NOT NULL DEFAULT 'preferred',
+  updated_by        UUID,
+  updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- ─── Single sign-on ─────────────────
+
+-- Per-tenant OpenID Connect provider. Login is the authorization code
+-- flow with PKCE; users are provisioned at their first login with the
+-- role and team their claims map to. The client secret is never returned
+-- by the API.
+CREATE TABLE IF NOT EXISTS tenant_oidc_configs (
+  tenant_id       UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+  enabled         BOOLEAN NOT NULL DEFAULT FALSE,
+  issuer          TEXT NOT NULL,
+  client_id       TEXT NOT NULL,
+  client_secret   TEXT,
+  redirect_uri    TEXT NOT NULL,
+  scopes          JSONB NOT NULL DEFAULT '["openid","email","profile"]',
+  role_claim      VARCHAR(255),
+  role_mappings   JSONB NOT NULL DEFAULT '[]',  -- [{value, role}]
+  default_role    VARCHAR(100),
+  team_claim      VARCHAR(255),
+  team_mappings   JSONB NOT NULL DEFAULT '[]',  -- [{value, team_id}]
+  default_team    UUID REFERENCES teams(id) ON DELETE SET NULL,
+  allowed_domains JSONB NOT NULL DEFAULT '[]',
+  sync_roles      BOOLEAN NOT NULL DEFAULT FALSE,
+  updated_by      UUID,
+  updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Per-tenant SAML 2.0 identity provider. Requests are signed with the
+-- service's key; responses must carry a signature from one of the IdP
+-- certificates (PEM).
+CREATE TABLE IF NOT EXISTS tenant_saml_configs (
+  tenant_id         UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+  enabled           BOOLEAN NOT NULL DEFAULT FALSE,
+  idp_entity_id     TEXT NOT NULL,
+  idp_sso_url       TEXT NOT NULL,
+  idp_slo_url       TEXT,
+  idp_certificates  TEXT NOT NULL,
+  email_attribute   VARCHAR(255),
+  name_attribute    VARCHAR(255),
+  role_attribute    VARCHAR(255),
+  role_mappings     JSONB NOT NULL DEFAULT '[]',  -- [{value, role}]
+  default_role      VARCHAR(100),
+  team_attribute    VARCHAR(255),
+  team_mappings     JSONB NOT NULL DEFAULT '[]',  -- [{value, team_id}]
+  default_team      UUID REFERENCES teams(id) ON DELETE SET NULL,
+  allowed_domains   JSONB NOT NULL DEFAULT '[]',
+  sync_roles        BOOLEAN NOT NULL DEFAULT FALSE,
+  updated_by        UUID,
+  updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- SAML logins, kept for single logout: the IdP names the user by NameID
+-- and session index.
+CREATE TABLE IF NOT EXISTS saml_sessions (
+  id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id      UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  name_id        TEXT NOT NULL,
+  name_id_format TEXT,
+  session_index  TEXT,
+  user_id        UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_saml_sessions_name_id ON saml_sessions (tenant_id, name_id);
+CREATE INDEX IF NOT EXISTS idx_saml_sessions_user_id ON saml_sessions (user_id);
+
+-- With local passwords disabled only Tenant Admins may still sign in
+-- with a password, as break-glass accounts.
+CREATE TABLE IF NOT EXISTS tenant_auth_settings (
+  tenant_id                UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+  local_passwords_disabled BOOLEAN NOT NULL DEFAULT FALSE,
+  updated_by               UUID,
+  updated_at               TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Links users to their subject at an identity provider.
+CREATE TABLE IF NOT EXISTS sso_identities (
+  id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  tenant_id     UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  provider      VARCHAR(16) NOT NULL,
+  issuer        TEXT NOT NULL,
+  subject       TEXT NOT NULL,
+  email         VARCHAR(255),
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_login_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sso_identities_subject ON sso_identities(issuer, subject);
+CREATE INDEX IF NOT EXISTS idx_sso_identities_user_id ON sso_identities(user_id);
+
+-- ─── SCIM provisioning ─────────────────
+
+-- Deprovisioned users keep their account and history but cannot sign in.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ;
+
+-- Per-tenant SCIM 2.0 endpoint. The directory authenticates with a bearer
+-- token of which only the SHA-256 is stored; directory groups map to
+-- roles and teams in order, first match wins.
+CREATE TABLE IF NOT EXISTS tenant_scim_configs (
+  tenant_id          UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+  enabled            BOOLEAN NOT NULL DEFAULT FALSE,
+  token_hash         CHAR(64),
+  token_prefix       VARCHAR(16),
+  token_issued_at    TIMESTAMPTZ,
+  token_last_used    TIMESTAMPTZ,
+  group_mappings     JSONB NOT NULL DEFAULT '[]',  -- [{group, role, team_id}]
+  default_role       VARCHAR(100) NOT NULL,
+  default_team       UUID REFERENCES teams(id) ON DELETE SET NULL,
+  reassign_to        UUID REFERENCES users(id) ON DELETE SET NULL,
+  updated_by         UUID,
+  updated_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_tenant_scim_configs_token_hash ON tenant_scim_configs(token_hash);
+
+-- Users the tenant's directory manages.
+CREATE TABLE IF NOT EXISTS scim_users (
+  user_id     UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  tenant_id   UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  external_id TEXT,
+  user_name   TEXT NOT NULL,
+  active      BOOLEAN NOT NULL DEFAULT TRUE,
+  version     INT NOT NULL DEFAULT 1,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_scim_users_tenant_id ON scim_users(tenant_id);
+
+CREATE TABLE IF NOT EXISTS scim_groups (
+  id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id    UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  external_id  TEXT,
+  display_name TEXT NOT NULL,
+  version      INT NOT NULL DEFAULT 1,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_scim_groups_tenant_id ON scim_groups(tenant_id);
+
+CREATE TABLE IF NOT EXISTS scim_group_members (
+  group_id UUID NOT NULL REFERENCES scim_groups(id) ON DELETE CASCADE,
+  user_id  UUID NOT NULL REFERENCES scim_users(user_id) ON DELETE CASCADE,
+  PRIMARY KEY (group_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_scim_group_members_user_id ON scim_group_members(user_id);
+
+-- ─── Service accounts & API keys ─────────────────
+
+-- Service accounts act through API keys. Each is backed by a users row
+-- with this role and no usable password, so what it creates is attributed
+-- to it like a user's.
+ALTER TYPE user_role ADD VALUE IF NOT EXISTS 'Service Account';
+
+CREATE TABLE IF NOT EXISTS service_accounts (
+  id          UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  tenant_id   UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  team_id     UUID REFERENCES teams(id) ON DELETE SET NULL,
+  name        VARCHAR(100) NOT NULL,
+  description TEXT,
+  created_by  UUID NOT NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  disabled_at TIMESTAMPTZ,
+  disabled_by UUID
+);
+CREATE INDEX IF NOT EXISTS idx_service_accounts_tenant_id ON service_accounts(tenant_id);
+
+-- Keys are stored as SHA-256 hashes. Scopes are permission names of
+-- enum_role_permissions; case_ids, when not empty, limit the key to those
+-- cases. Rotation shortens the old key's expiry to a grace period.
+CREATE TABLE IF NOT EXISTS api_keys (
+  id                 UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  service_account_id UUID NOT NULL REFERENCES service_accounts(id) ON DELETE CASCADE,
+  tenant_id          UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  name               VARCHAR(100) NOT NULL,
+  prefix             VARCHAR(24) NOT NULL,
+  hash               CHAR(64) NOT NULL UNIQUE,
+  scopes             JSONB NOT NULL DEFAULT '[]',
+  case_ids           JSONB NOT NULL DEFAULT '[]',
+  expires_at         TIMESTAMPTZ NOT NULL,
+  last_used_at       TIMESTAMPTZ,
+  last_used_ip       VARCHAR(64),
+  created_by         UUID NOT NULL,
+  created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  rotated_to         UUID REFERENCES api_keys(id) ON DELETE SET NULL,
+  revoked_at         TIMESTAMPTZ,
+  revoked_by         UUID
+);
+CREATE INDEX IF NOT EXISTS idx_api_keys_service_account_id ON api_keys(service_account_id);
+CREATE INDEX IF NOT EXISTS idx_api_keys_tenant_id ON api_keys(tenant_id);
+
+-- ─── Case access control ───────────────────────
+
+-- Requests to case resources are checked against the user's role on the
+-- case (case_user_roles, the case author, or an active, unexpired
+-- case_collaborators row). Refused requests are recorded here.
+CREATE TABLE IF NOT EXISTS access_denials (
+  id            UUID PRIMARY KEY,
+  tenant_id     UUID,
+  user_id       UUID,
+  role          TEXT,
+  api_key_id    UUID,
+  action        TEXT NOT NULL,
+  resource_type TEXT NOT NULL,
+  resource_id   TEXT NOT NULL,
+  case_id       UUID,
+  case_role     TEXT,
+  reason        TEXT NOT NULL,
+  method        VARCHAR(10),
+  path          TEXT,
+  ip            VARCHAR(64),
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_access_denials_tenant_id ON access_denials(tenant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_access_denials_user_id ON access_denials(user_id);
+CREATE INDEX IF NOT EXISTS idx_access_denials_case_id ON access_denials(case_id);
+
+-- ─── Classification & need-to-know ─────────────
+
+-- Evidence items and reports may carry a classification label. Viewing,
+-- downloading, exporting or sharing one requires a clearance of at least
+-- its level and membership of every need-to-know group it lists; actions
+-- in withheld are refused to everyone.
+CREATE TABLE IF NOT EXISTS classification_labels (
+  resource_type VARCHAR(20) NOT NULL, -- 'evidence' | 'report'
+  resource_id   UUID NOT NULL,
+  tenant_id     UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  level         TEXT NOT NULL,        -- UNCLASSIFIED < CONFIDENTIAL < SECRET < TOP SECRET
+  groups        JSONB NOT NULL DEFAULT '[]', -- need_to_know_groups ids
+  withheld      JSONB NOT NULL DEFAULT '[]', -- 'download' | 'export' | 'share'
+  reason        TEXT,
+  labelled_by   UUID,
+  labelled_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (resource_type, resource_id)
+);
+CREATE INDEX IF NOT EXISTS idx_classification_labels_tenant_id ON classification_labels(tenant_id);
+
+CREATE TABLE IF NOT EXISTS user_clearances (
+  user_id    UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  tenant_id  UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  level      TEXT NOT NULL,
+  granted_by UUID,
+  granted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_user_clearances_tenant_id ON user_clearances(tenant_id);
+
+CREATE TABLE IF NOT EXISTS need_to_know_groups (
+  id          UUID PRIMARY KEY,
+  tenant_id   UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  name        TEXT NOT NULL,
+  description TEXT,
+  created_by  UUID,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_need_to_know_groups_name ON need_to_know_groups(tenant_id, name);
+
+CREATE TABLE IF NOT EXISTS need_to_know_members (
+  group_id UUID NOT NULL REFERENCES need_to_know_groups(id) ON DELETE CASCADE,
+  user_id  UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  added_by UUID,
+  added_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (group_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_need_to_know_members_user_id ON need_to_know_members(user_id);
+
+-- ─── External collaborator invitations ─────────
+-- An invitation admits an external collaborator to some of a case's
+-- evidence and reports between starts_at and ends_at, optionally only
+-- from the networks in ip_allowlist. Accepting one keeps the invitee's
+-- case_collaborators row, which the expiry job marks expired at ends_at.
+CREATE TABLE IF NOT EXISTS case_invitations (
+  id           UUID PRIMARY KEY,
+  tenant_id    UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  case_id      UUID NOT NULL REFERENCES cases(id) ON DELETE CASCADE,
+  invited_by   UUID NOT NULL,
+  email        TEXT NOT NULL,
+  user_id      UUID REFERENCES users(id) ON DELETE CASCADE,
+  token_hash   TEXT NOT NULL UNIQUE,           -- SHA-256 of the emailed token
+  evidence_ids JSONB NOT NULL DEFAULT '[]',
+  report_ids   JSONB NOT NULL DEFAULT '[]',
+  ip_allowlist JSONB NOT NULL DEFAULT '[]',    -- CIDRs; empty allows any address
+  watermark    BOOLEAN NOT NULL DEFAULT TRUE,
+  starts_at    TIMESTAMPTZ NOT NULL,
+  ends_at      TIMESTAMPTZ NOT NULL,
+  status       VARCHAR(20) NOT NULL,          -- pending, accepted, revoked, expired
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  accepted_at  TIMESTAMPTZ,
+  revoked_at   TIMESTAMPTZ,
+  revoked_by   UUID
+);
+CREATE INDEX IF NOT EXISTS idx_case_invitations_tenant_id ON case_invitations(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_case_invitations_case_id ON case_invitations(case_id);
+CREATE INDEX IF NOT EXISTS idx_case_invitations_user_id ON case_invitations(user_id);
+CREATE INDEX IF NOT EXISTS idx_case_invitations_status ON case_invitations(status);
+CREATE INDEX IF NOT EXISTS idx_case_invitations_ends_at ON case_invitations(ends_at);
+
+-- Every request an invited collaborator makes, allowed or not, for the
+-- inviting admin's access report.
+CREATE TABLE IF NOT EXISTS collaborator_access_events (
+  id            UUID PRIMARY KEY,
+  invitation_id UUID NOT NULL REFERENCES case_invitations(id) ON DELETE CASCADE,
+  tenant_id     UUID NOT NULL,
+  case_id       UUID NOT NULL,
+  user_id       UUID NOT NULL,
+  action        TEXT,
+  resource_type TEXT,
+  resource_id   TEXT,
+  method        VARCHAR(10),
+  path          TEXT,
+  ip            TEXT,
+  allowed       BOOLEAN NOT NULL,
+  reason        TEXT,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_collaborator_access_events_invitation_id ON collaborator_access_events(invitation_id);
+CREATE INDEX IF NOT EXISTS idx_collaborator_access_events_created_at ON collaborator_access_events(created_at);
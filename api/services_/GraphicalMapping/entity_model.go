@@ -0,0 +1,89 @@
+package graphicalmapping
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// EntityType is the kind of thing an investigation graph node represents.
+type EntityType string
+
+const (
+	EntityPerson        EntityType = "person"
+	EntityAccount       EntityType = "account"
+	EntityHost          EntityType = "host"
+	EntityIP            EntityType = "ip"
+	EntityDomain        EntityType = "domain"
+	EntityFile          EntityType = "file"
+	EntityWallet        EntityType = "wallet"
+	EntityEvidence      EntityType = "evidence"
+	EntityTimelineEvent EntityType = "timeline_event"
+)
+
+// RelationType is the analyst-authored meaning of an edge between two entities.
+type RelationType string
+
+const (
+	RelationCommunicatedWith RelationType = "communicated_with"
+	RelationLoggedInto       RelationType = "logged_into"
+	RelationDownloaded       RelationType = "downloaded"
+	RelationResolvedTo       RelationType = "resolved_to"
+	RelationOwns             RelationType = "owns"
+)
+
+var validEntityTypes = map[EntityType]bool{
+	EntityPerson: true, EntityAccount: true, EntityHost: true, EntityIP: true, EntityDomain: true,
+	EntityFile: true, EntityWallet: true, EntityEvidence: true, EntityTimelineEvent: true,
+}
+
+var validRelationTypes = map[RelationType]bool{
+	RelationCommunicatedWith: true, RelationLoggedInto: true, RelationDownloaded: true,
+	RelationResolvedTo: true, RelationOwns: true,
+}
+
+// IsValid reports whether t is one of the supported entity types.
+func (t EntityType) IsValid() bool { return validEntityTypes[t] }
+
+// IsValid reports whether t is one of the supported relation types.
+func (t RelationType) IsValid() bool { return validRelationTypes[t] }
+
+// GraphEntity is a typed node in a case's investigation graph.
+// RefID links evidence and timeline_event nodes back to the underlying record.
+type GraphEntity struct {
+	ID         string         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID   string         `gorm:"type:uuid;index;not null" json:"tenant_id"`
+	CaseID     string         `gorm:"type:uuid;index;not null" json:"case_id"`
+	Type       EntityType     `gorm:"size:50;index;not null" json:"type"`
+	Label      string         `gorm:"size:255;not null" json:"label"`
+	RefID      string         `gorm:"size:64;index" json:"ref_id,omitempty"`
+	Properties datatypes.JSON `gorm:"type:jsonb;default:'{}'::jsonb" json:"properties"`
+	CreatedBy  string         `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+// GraphRelation is a directed, typed edge between two entities of the same case.
+// ValidFrom/ValidTo bound when the relationship held; Evidence is a JSON array
+// of evidence IDs the analyst cites as the source for the edge.
+type GraphRelation struct {
+	ID        string         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID  string         `gorm:"type:uuid;index;not null" json:"tenant_id"`
+	CaseID    string         `gorm:"type:uuid;index;not null" json:"case_id"`
+	SourceID  string         `gorm:"type:uuid;index;not null" json:"source_id"`
+	TargetID  string         `gorm:"type:uuid;index;not null" json:"target_id"`
+	Type      RelationType   `gorm:"size:50;index;not null" json:"type"`
+	ValidFrom *time.Time     `json:"valid_from,omitempty"`
+	ValidTo   *time.Time     `json:"valid_to,omitempty"`
+	Evidence  datatypes.JSON `gorm:"type:jsonb;default:'[]'::jsonb" json:"evidence"`
+	Notes     string         `gorm:"type:text" json:"notes,omitempty"`
+	CreatedBy string         `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// InvestigationGraph is a case-scoped set of entities and the relations between them.
+type InvestigationGraph struct {
+	CaseID string           `json:"case_id"`
+	Nodes  []*GraphEntity   `json:"nodes"`
+	Edges  []*GraphRelation `json:"edges"`
+}
@@ -0,0 +1,95 @@
+package graphicalmapping
+
+import (
+	"gorm.io/gorm"
+)
+
+type entityGraphRepository struct {
+	db *gorm.DB
+}
+
+func NewEntityGraphRepository(db *gorm.DB) EntityGraphRepository {
+	return &entityGraphRepository{db: db}
+}
+
+func (r *entityGraphRepository) AutoMigrate() error {
+	return r.db.AutoMigrate(&GraphEntity{}, &GraphRelation{})
+}
+
+func (r *entityGraphRepository) CreateEntity(e *GraphEntity) error {
+	return r.db.Create(e).Error
+}
+
+func (r *entityGraphRepository) GetEntity(tenantID, id string) (*GraphEntity, error) {
+	var e GraphEntity
+	if err := r.db.Where("tenant_id = ? AND id = ?", tenantID, id).First(&e).Error; err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *entityGraphRepository) ListEntitiesByCase(tenantID, caseID string) ([]*GraphEntity, error) {
+	var entities []*GraphEntity
+	if err := r.db.
+		Where("tenant_id = ? AND case_id = ?", tenantID, caseID).
+		Order("created_at ASC, id ASC").
+		Find(&entities).Error; err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
+// DeleteEntity removes the entity and every relation that touches it.
+func (r *entityGraphRepository) DeleteEntity(tenantID, caseID, id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where("tenant_id = ? AND case_id = ? AND (source_id = ? OR target_id = ?)", tenantID, caseID, id, id).
+			Delete(&GraphRelation{}).Error; err != nil {
+			return err
+		}
+		res := tx.Where("tenant_id = ? AND case_id = ? AND id = ?", tenantID, caseID, id).Delete(&GraphEntity{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (r *entityGraphRepository) CreateRelation(rel *GraphRelation) error {
+	return r.db.Create(rel).Error
+}
+
+func (r *entityGraphRepository) ListRelationsByCase(tenantID, caseID string) ([]*GraphRelation, error) {
+	var relations []*GraphRelation
+	if err := r.db.
+		Where("tenant_id = ? AND case_id = ?", tenantID, caseID).
+		Order("created_at ASC, id ASC").
+		Find(&relations).Error; err != nil {
+		return nil, err
+	}
+	return relations, nil
+}
+
+func (r *entityGraphRepository) DeleteRelation(tenantID, caseID, id string) error {
+	res := r.db.Where("tenant_id = ? AND case_id = ? AND id = ?", tenantID, caseID, id).Delete(&GraphRelation{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *entityGraphRepository) EvidenceInCase(tenantID, caseID string, ids []string) ([]string, error) {
+	var found []string
+	if len(ids) == 0 {
+		return found, nil
+	}
+	err := r.db.Raw("SELECT id::text FROM evidence WHERE tenant_id = ? AND case_id = ? AND id IN ?", tenantID, caseID, ids).
+		Scan(&found).Error
+	return found, err
+}
@@ -0,0 +1,170 @@
+package graphicalmapping
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"gorm.io/datatypes"
+)
+
+var (
+	ErrInvalidEntityType   = errors.New("unsupported entity type")
+	ErrInvalidRelationType = errors.New("unsupported relation type")
+	ErrEntityLabelRequired = errors.New("entity label is required")
+	ErrEntityNotInCase     = errors.New("entity does not belong to this case")
+	ErrEvidenceNotInCase   = errors.New("evidence does not belong to this case")
+	ErrInvalidTimeBounds   = errors.New("valid_to must not be before valid_from")
+	ErrNoPath              = errors.New("no path between the given entities")
+)
+
+type investigationGraphService struct {
+	repo EntityGraphRepository
+}
+
+func NewInvestigationGraphService(repo EntityGraphRepository) InvestigationGraphService {
+	return &investigationGraphService{repo: repo}
+}
+
+func (s *investigationGraphService) AddEntity(e *GraphEntity) (*GraphEntity, error) {
+	if !e.Type.IsValid() {
+		return nil, ErrInvalidEntityType
+	}
+	e.Label = strings.TrimSpace(e.Label)
+	if e.Label == "" {
+		return nil, ErrEntityLabelRequired
+	}
+	if len(e.Properties) == 0 {
+		e.Properties = datatypes.JSON([]byte("{}"))
+	}
+	if e.Type == EntityEvidence && e.RefID != "" {
+		if err := s.checkEvidence(e.TenantID, e.CaseID, []string{e.RefID}); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.repo.CreateEntity(e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (s *investigationGraphService) ListEntities(tenantID, caseID string) ([]*GraphEntity, error) {
+	return s.repo.ListEntitiesByCase(tenantID, caseID)
+}
+
+func (s *investigationGraphService) DeleteEntity(tenantID, caseID, entityID string) error {
+	return s.repo.DeleteEntity(tenantID, caseID, entityID)
+}
+
+// checkEvidence rejects evidence IDs that are not evidence of the case.
+func (s *investigationGraphService) checkEvidence(tenantID, caseID string, ids []string) error {
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil {
+			return ErrEvidenceNotInCase
+		}
+	}
+	found, err := s.repo.EvidenceInCase(tenantID, caseID, ids)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if !slices.ContainsFunc(found, func(f string) bool { return strings.EqualFold(f, id) }) {
+			return ErrEvidenceNotInCase
+		}
+	}
+	return nil
+}
+
+// AddRelation validates that both endpoints and the cited evidence belong
+// to the relation's case before storing it.
+func (s *investigationGraphService) AddRelation(r *GraphRelation) (*GraphRelation, error) {
+	if !r.Type.IsValid() {
+		return nil, ErrInvalidRelationType
+	}
+	if r.ValidFrom != nil && r.ValidTo != nil && r.ValidTo.Before(*r.ValidFrom) {
+		return nil, ErrInvalidTimeBounds
+	}
+	for _, id := range []string{r.SourceID, r.TargetID} {
+		ent, err := s.repo.GetEntity(r.TenantID, id)
+		if err != nil || ent == nil || ent.CaseID != r.CaseID {
+			return nil, ErrEntityNotInCase
+		}
+	}
+	if len(r.Evidence) == 0 {
+		r.Evidence = datatypes.JSON([]byte("[]"))
+	}
+	var cited []string
+	if err := json.Unmarshal(r.Evidence, &cited); err != nil {
+		return nil, ErrEvidenceNotInCase
+	}
+	if err := s.checkEvidence(r.TenantID, r.CaseID, cited); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateRelation(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *investigationGraphService) DeleteRelation(tenantID, caseID, relationID string) error {
+	return s.repo.DeleteRelation(tenantID, caseID, relationID)
+}
+
+func (s *investigationGraphService) GetCaseGraph(tenantID, caseID string) (*InvestigationGraph, error) {
+	nodes, err := s.repo.ListEntitiesByCase(tenantID, caseID)
+	if err != nil {
+		return nil, err
+	}
+	edges, err := s.repo.ListRelationsByCase(tenantID, caseID)
+	if err != nil {
+		return nil, err
+	}
+	if nodes == nil {
+		nodes = []*GraphEntity{}
+	}
+	if edges == nil {
+		edges = []*GraphRelation{}
+	}
+	return &InvestigationGraph{CaseID: caseID, Nodes: nodes, Edges: edges}, nil
+}
+
+func (s *investigationGraphService) Neighbors(tenantID, caseID, entityID string, hops int) (*InvestigationGraph, error) {
+	g, err := s.GetCaseGraph(tenantID, caseID)
+	if err != nil {
+		return nil, err
+	}
+	if !g.hasNode(entityID) {
+		return nil, ErrEntityNotInCase
+	}
+	return g.Neighbors(entityID, hops), nil
+}
+
+func (s *investigationGraphService) ShortestPath(tenantID, caseID, fromID, toID string) (*InvestigationGraph, error) {
+	g, err := s.GetCaseGraph(tenantID, caseID)
+	if err != nil {
+		return nil, err
+	}
+	if !g.hasNode(fromID) || !g.hasNode(toID) {
+		return nil, ErrEntityNotInCase
+	}
+	path, ok := g.ShortestPath(fromID, toID)
+	if !ok {
+		return nil, ErrNoPath
+	}
+	return path, nil
+}
+
+func (s *investigationGraphService) SubgraphByTimeWindow(tenantID, caseID string, from, to time.Time) (*InvestigationGraph, error) {
+	if to.Before(from) {
+		return nil, ErrInvalidTimeBounds
+	}
+	g, err := s.GetCaseGraph(tenantID, caseID)
+	if err != nil {
+		return nil, err
+	}
+	return g.TimeWindow(from, to), nil
+}
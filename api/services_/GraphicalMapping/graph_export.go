@@ -0,0 +1,231 @@
+package graphicalmapping
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// ─── GraphML ────────────────────────────────────────────────
+
+type graphMLDoc struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// ExportGraphML writes the graph as GraphML for tools such as yEd or Gephi.
+func ExportGraphML(w io.Writer, g *InvestigationGraph) error {
+	doc := graphMLDoc{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "n_label", For: "node", AttrName: "label", AttrType: "string"},
+			{ID: "n_type", For: "node", AttrName: "type", AttrType: "string"},
+			{ID: "n_ref", For: "node", AttrName: "ref_id", AttrType: "string"},
+			{ID: "n_props", For: "node", AttrName: "properties", AttrType: "string"},
+			{ID: "e_type", For: "edge", AttrName: "type", AttrType: "string"},
+			{ID: "e_from", For: "edge", AttrName: "valid_from", AttrType: "string"},
+			{ID: "e_to", For: "edge", AttrName: "valid_to", AttrType: "string"},
+			{ID: "e_evidence", For: "edge", AttrName: "evidence", AttrType: "string"},
+			{ID: "e_notes", For: "edge", AttrName: "notes", AttrType: "string"},
+		},
+		Graph: graphMLGraph{ID: "case-" + g.CaseID, EdgeDefault: "directed"},
+	}
+
+	for _, n := range g.Nodes {
+		data := []graphMLData{
+			{Key: "n_label", Value: n.Label},
+			{Key: "n_type", Value: string(n.Type)},
+		}
+		if n.RefID != "" {
+			data = append(data, graphMLData{Key: "n_ref", Value: n.RefID})
+		}
+		if len(n.Properties) > 0 {
+			data = append(data, graphMLData{Key: "n_props", Value: string(n.Properties)})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: n.ID, Data: data})
+	}
+
+	for _, e := range g.Edges {
+		data := []graphMLData{{Key: "e_type", Value: string(e.Type)}}
+		if e.ValidFrom != nil {
+			data = append(data, graphMLData{Key: "e_from", Value: formatGraphTime(*e.ValidFrom)})
+		}
+		if e.ValidTo != nil {
+			data = append(data, graphMLData{Key: "e_to", Value: formatGraphTime(*e.ValidTo)})
+		}
+		if len(e.Evidence) > 0 {
+			data = append(data, graphMLData{Key: "e_evidence", Value: string(e.Evidence)})
+		}
+		if e.Notes != "" {
+			data = append(data, graphMLData{Key: "e_notes", Value: e.Notes})
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{ID: e.ID, Source: e.SourceID, Target: e.TargetID, Data: data})
+	}
+
+	return writeXML(w, doc)
+}
+
+// ─── GEXF ───────────────────────────────────────────────────
+
+type gexfDoc struct {
+	XMLName xml.Name  `xml:"gexf"`
+	XMLNS   string    `xml:"xmlns,attr"`
+	Version string    `xml:"version,attr"`
+	Meta    gexfMeta  `xml:"meta"`
+	Graph   gexfGraph `xml:"graph"`
+}
+
+type gexfMeta struct {
+	Creator     string `xml:"creator"`
+	Description string `xml:"description"`
+}
+
+type gexfGraph struct {
+	DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+	Mode            string           `xml:"mode,attr"`
+	TimeFormat      string           `xml:"timeformat,attr"`
+	Attributes      []gexfAttributes `xml:"attributes"`
+	Nodes           []gexfNode       `xml:"nodes>node"`
+	Edges           []gexfEdge       `xml:"edges>edge"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfNode struct {
+	ID        string         `xml:"id,attr"`
+	Label     string         `xml:"label,attr"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexfEdge struct {
+	ID        string         `xml:"id,attr"`
+	Source    string         `xml:"source,attr"`
+	Target    string         `xml:"target,attr"`
+	Label     string         `xml:"label,attr"`
+	Start     string         `xml:"start,attr,omitempty"`
+	End       string         `xml:"end,attr,omitempty"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// ExportGEXF writes the graph as GEXF 1.3. Edge time bounds are emitted as
+// start/end so Gephi's timeline can replay the relationships.
+func ExportGEXF(w io.Writer, g *InvestigationGraph) error {
+	doc := gexfDoc{
+		XMLNS:   "http://gexf.net/1.3",
+		Version: "1.3",
+		Meta: gexfMeta{
+			Creator:     "AEGIS",
+			Description: "Investigation graph for case " + g.CaseID,
+		},
+		Graph: gexfGraph{
+			DefaultEdgeType: "directed",
+			Mode:            "dynamic",
+			TimeFormat:      "dateTime",
+			Attributes: []gexfAttributes{
+				{Class: "node", Attributes: []gexfAttribute{
+					{ID: "type", Title: "type", Type: "string"},
+					{ID: "ref_id", Title: "ref_id", Type: "string"},
+					{ID: "properties", Title: "properties", Type: "string"},
+				}},
+				{Class: "edge", Attributes: []gexfAttribute{
+					{ID: "evidence", Title: "evidence", Type: "string"},
+					{ID: "notes", Title: "notes", Type: "string"},
+				}},
+			},
+		},
+	}
+
+	for _, n := range g.Nodes {
+		vals := []gexfAttValue{{For: "type", Value: string(n.Type)}}
+		if n.RefID != "" {
+			vals = append(vals, gexfAttValue{For: "ref_id", Value: n.RefID})
+		}
+		if len(n.Properties) > 0 {
+			vals = append(vals, gexfAttValue{For: "properties", Value: string(n.Properties)})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, gexfNode{ID: n.ID, Label: n.Label, AttValues: vals})
+	}
+
+	for _, e := range g.Edges {
+		edge := gexfEdge{ID: e.ID, Source: e.SourceID, Target: e.TargetID, Label: string(e.Type)}
+		if e.ValidFrom != nil {
+			edge.Start = formatGraphTime(*e.ValidFrom)
+		}
+		if e.ValidTo != nil {
+			edge.End = formatGraphTime(*e.ValidTo)
+		}
+		if len(e.Evidence) > 0 {
+			edge.AttValues = append(edge.AttValues, gexfAttValue{For: "evidence", Value: string(e.Evidence)})
+		}
+		if e.Notes != "" {
+			edge.AttValues = append(edge.AttValues, gexfAttValue{For: "notes", Value: e.Notes})
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, edge)
+	}
+
+	return writeXML(w, doc)
+}
+
+func formatGraphTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func writeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
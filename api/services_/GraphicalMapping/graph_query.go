@@ -0,0 +1,178 @@
+package graphicalmapping
+
+import (
+	"sort"
+	"time"
+)
+
+// MaxNeighborHops caps neighbourhood expansion so a single request cannot walk the whole graph.
+const MaxNeighborHops = 5
+
+// adjacency returns, for every node, the relations touching it ordered by the
+// neighbour's ID. Direction is ignored: for traversal an analyst usually cares
+// that two entities are connected, not who initiated the relationship.
+func (g *InvestigationGraph) adjacency() map[string][]*GraphRelation {
+	adj := map[string][]*GraphRelation{}
+	for _, e := range g.Edges {
+		adj[e.SourceID] = append(adj[e.SourceID], e)
+		if e.TargetID != e.SourceID {
+			adj[e.TargetID] = append(adj[e.TargetID], e)
+		}
+	}
+	for id, rels := range adj {
+		id := id
+		sort.SliceStable(rels, func(i, j int) bool {
+			return otherEnd(rels[i], id) < otherEnd(rels[j], id)
+		})
+	}
+	return adj
+}
+
+func otherEnd(r *GraphRelation, id string) string {
+	if r.SourceID == id {
+		return r.TargetID
+	}
+	return r.SourceID
+}
+
+func (g *InvestigationGraph) hasNode(id string) bool {
+	for _, n := range g.Nodes {
+		if n.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// subgraph keeps the nodes in keep and every edge whose endpoints are both kept
+// (or, when edgeFilter is non-nil, only the edges it accepts).
+func (g *InvestigationGraph) subgraph(keep map[string]bool, edgeFilter func(*GraphRelation) bool) *InvestigationGraph {
+	out := &InvestigationGraph{CaseID: g.CaseID, Nodes: []*GraphEntity{}, Edges: []*GraphRelation{}}
+	for _, n := range g.Nodes {
+		if keep[n.ID] {
+			out.Nodes = append(out.Nodes, n)
+		}
+	}
+	for _, e := range g.Edges {
+		if !keep[e.SourceID] || !keep[e.TargetID] {
+			continue
+		}
+		if edgeFilter != nil && !edgeFilter(e) {
+			continue
+		}
+		out.Edges = append(out.Edges, e)
+	}
+	return out
+}
+
+// Neighbors returns the subgraph of entities reachable from id within hops edges.
+func (g *InvestigationGraph) Neighbors(id string, hops int) *InvestigationGraph {
+	if hops < 1 {
+		hops = 1
+	}
+	if hops > MaxNeighborHops {
+		hops = MaxNeighborHops
+	}
+	keep := map[string]bool{}
+	if !g.hasNode(id) {
+		return g.subgraph(keep, nil)
+	}
+
+	adj := g.adjacency()
+	keep[id] = true
+	frontier := []string{id}
+	for depth := 0; depth < hops && len(frontier) > 0; depth++ {
+		var next []string
+		for _, cur := range frontier {
+			for _, rel := range adj[cur] {
+				n := otherEnd(rel, cur)
+				if !keep[n] {
+					keep[n] = true
+					next = append(next, n)
+				}
+			}
+		}
+		frontier = next
+	}
+	return g.subgraph(keep, nil)
+}
+
+// ShortestPath returns the nodes and edges of a fewest-hops path between from
+// and to, or ok=false when the two entities are not connected.
+func (g *InvestigationGraph) ShortestPath(from, to string) (path *InvestigationGraph, ok bool) {
+	empty := &InvestigationGraph{CaseID: g.CaseID, Nodes: []*GraphEntity{}, Edges: []*GraphRelation{}}
+	if !g.hasNode(from) || !g.hasNode(to) {
+		return empty, false
+	}
+
+	adj := g.adjacency()
+	via := map[string]*GraphRelation{from: nil}
+	queue := []string{from}
+	for len(queue) > 0 && via[to] == nil && from != to {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, rel := range adj[cur] {
+			n := otherEnd(rel, cur)
+			if _, seen := via[n]; seen {
+				continue
+			}
+			via[n] = rel
+			queue = append(queue, n)
+		}
+	}
+	if _, reached := via[to]; !reached {
+		return empty, false
+	}
+
+	// Walk back from the target to rebuild the path in order.
+	var ids []string
+	var edges []*GraphRelation
+	for cur := to; ; {
+		ids = append(ids, cur)
+		rel := via[cur]
+		if rel == nil {
+			break
+		}
+		edges = append(edges, rel)
+		cur = otherEnd(rel, cur)
+	}
+
+	byID := map[string]*GraphEntity{}
+	for _, n := range g.Nodes {
+		byID[n.ID] = n
+	}
+	for i := len(ids) - 1; i >= 0; i-- {
+		empty.Nodes = append(empty.Nodes, byID[ids[i]])
+	}
+	for i := len(edges) - 1; i >= 0; i-- {
+		empty.Edges = append(empty.Edges, edges[i])
+	}
+	return empty, true
+}
+
+// TimeWindow returns the relations whose validity interval overlaps [from, to]
+// together with their endpoints. Relations without any time bound cannot be
+// placed on the timeline and are left out.
+func (g *InvestigationGraph) TimeWindow(from, to time.Time) *InvestigationGraph {
+	inWindow := func(r *GraphRelation) bool {
+		if r.ValidFrom == nil && r.ValidTo == nil {
+			return false
+		}
+		if r.ValidFrom != nil && r.ValidFrom.After(to) {
+			return false
+		}
+		if r.ValidTo != nil && r.ValidTo.Before(from) {
+			return false
+		}
+		return true
+	}
+
+	keep := map[string]bool{}
+	for _, e := range g.Edges {
+		if inWindow(e) {
+			keep[e.SourceID] = true
+			keep[e.TargetID] = true
+		}
+	}
+	return g.subgraph(keep, inWindow)
+}
@@ -1,5 +1,7 @@
 package graphicalmapping
 
+import "time"
+
 type IOCRepository interface {
 	Create(ioc *IOC) error
 	GetByID(id string) (*IOC, error)
@@ -16,3 +18,28 @@ type IOCService interface {
 	BuildIOCGraphByCase(tenantID, caseID string) ([]GraphNode, []GraphEdge, error)
 	ListIOCsByCase(caseID string) ([]*IOC, error)
 }
+
+type EntityGraphRepository interface {
+	AutoMigrate() error
+	CreateEntity(e *GraphEntity) error
+	GetEntity(tenantID, id string) (*GraphEntity, error)
+	ListEntitiesByCase(tenantID, caseID string) ([]*GraphEntity, error)
+	DeleteEntity(tenantID, caseID, id string) error
+	CreateRelation(r *GraphRelation) error
+	ListRelationsByCase(tenantID, caseID string) ([]*GraphRelation, error)
+	DeleteRelation(tenantID, caseID, id string) error
+	// EvidenceInCase returns those of ids that are evidence of the case.
+	EvidenceInCase(tenantID, caseID string, ids []string) ([]string, error)
+}
+
+type InvestigationGraphService interface {
+	AddEntity(e *GraphEntity) (*GraphEntity, error)
+	ListEntities(tenantID, caseID string) ([]*GraphEntity, error)
+	DeleteEntity(tenantID, caseID, entityID string) error
+	AddRelation(r *GraphRelation) (*GraphRelation, error)
+	DeleteRelation(tenantID, caseID, relationID string) error
+	GetCaseGraph(tenantID, caseID string) (*InvestigationGraph, error)
+	Neighbors(tenantID, caseID, entityID string, hops int) (*InvestigationGraph, error)
+	ShortestPath(tenantID, caseID, fromID, toID string) (*InvestigationGraph, error)
+	SubgraphByTimeWindow(tenantID, caseID string, from, to time.Time) (*InvestigationGraph, error)
+}
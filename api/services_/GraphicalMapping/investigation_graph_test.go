@@ -0,0 +1,173 @@
+package graphicalmapping_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	graphicalmapping "aegis-api/services_/GraphicalMapping"
+	"aegis-api/tests/fakes"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+)
+
+type graphFixture struct {
+	repo                      *fakes.Graph
+	svc                       graphicalmapping.InvestigationGraphService
+	tenantID, caseID          string
+	person, account, host, ip *graphicalmapping.GraphEntity
+	isolated                  *graphicalmapping.GraphEntity
+	jan1, jan5, jan10         time.Time
+}
+
+// person -owns-> account -logged_into-> host -communicated_with-> ip, plus one isolated domain.
+func newGraphFixture(t *testing.T) *graphFixture {
+	f := &graphFixture{
+		tenantID: uuid.NewString(),
+		caseID:   uuid.NewString(),
+		jan1:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		jan5:     time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC),
+		jan10:    time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC),
+	}
+	f.repo = &fakes.Graph{}
+	f.svc = graphicalmapping.NewInvestigationGraphService(f.repo)
+
+	add := func(typ graphicalmapping.EntityType, label string) *graphicalmapping.GraphEntity {
+		e, err := f.svc.AddEntity(&graphicalmapping.GraphEntity{TenantID: f.tenantID, CaseID: f.caseID, Type: typ, Label: label})
+		require.NoError(t, err)
+		return e
+	}
+	f.person = add(graphicalmapping.EntityPerson, "J. Doe")
+	f.account = add(graphicalmapping.EntityAccount, "jdoe@corp")
+	f.host = add(graphicalmapping.EntityHost, "WS-042")
+	f.ip = add(graphicalmapping.EntityIP, "203.0.113.7")
+	f.isolated = add(graphicalmapping.EntityDomain, "evil.example")
+
+	link := func(src, dst *graphicalmapping.GraphEntity, typ graphicalmapping.RelationType, from, to *time.Time) {
+		_, err := f.svc.AddRelation(&graphicalmapping.GraphRelation{
+			TenantID: f.tenantID, CaseID: f.caseID, SourceID: src.ID, TargetID: dst.ID, Type: typ,
+			ValidFrom: from, ValidTo: to,
+		})
+		require.NoError(t, err)
+	}
+	link(f.person, f.account, graphicalmapping.RelationOwns, nil, nil)
+	link(f.account, f.host, graphicalmapping.RelationLoggedInto, &f.jan1, &f.jan1)
+	link(f.host, f.ip, graphicalmapping.RelationCommunicatedWith, &f.jan10, nil)
+	return f
+}
+
+func TestAddEntityRejectsUnknownType(t *testing.T) {
+	svc := graphicalmapping.NewInvestigationGraphService(&fakes.Graph{})
+	_, err := svc.AddEntity(&graphicalmapping.GraphEntity{Type: "spaceship", Label: "x"})
+	require.ErrorIs(t, err, graphicalmapping.ErrInvalidEntityType)
+}
+
+func TestAddRelationValidation(t *testing.T) {
+	f := newGraphFixture(t)
+
+	_, err := f.svc.AddRelation(&graphicalmapping.GraphRelation{
+		TenantID: f.tenantID, CaseID: uuid.NewString(), SourceID: f.person.ID, TargetID: f.host.ID,
+		Type: graphicalmapping.RelationOwns,
+	})
+	require.ErrorIs(t, err, graphicalmapping.ErrEntityNotInCase)
+
+	_, err = f.svc.AddRelation(&graphicalmapping.GraphRelation{
+		TenantID: f.tenantID, CaseID: f.caseID, SourceID: f.person.ID, TargetID: f.host.ID,
+		Type: graphicalmapping.RelationOwns, ValidFrom: &f.jan10, ValidTo: &f.jan1,
+	})
+	require.ErrorIs(t, err, graphicalmapping.ErrInvalidTimeBounds)
+
+	_, err = f.svc.AddRelation(&graphicalmapping.GraphRelation{
+		TenantID: f.tenantID, CaseID: f.caseID, SourceID: f.person.ID, TargetID: f.host.ID, Type: "likes",
+	})
+	require.ErrorIs(t, err, graphicalmapping.ErrInvalidRelationType)
+}
+
+func TestRelationEvidenceMustBelongToCase(t *testing.T) {
+	f := newGraphFixture(t)
+	own, other := uuid.NewString(), uuid.NewString()
+	f.repo.PlaceEvidence(own, f.caseID)
+	f.repo.PlaceEvidence(other, uuid.NewString())
+	cite := func(ids ...string) error {
+		_, err := f.svc.AddRelation(&graphicalmapping.GraphRelation{
+			TenantID: f.tenantID, CaseID: f.caseID, SourceID: f.person.ID, TargetID: f.host.ID,
+			Type: graphicalmapping.RelationOwns, Evidence: datatypes.JSON(`["` + strings.Join(ids, `","`) + `"]`),
+		})
+		return err
+	}
+
+	require.NoError(t, cite(own))
+	require.ErrorIs(t, cite(own, other), graphicalmapping.ErrEvidenceNotInCase)
+	require.ErrorIs(t, cite("not-a-uuid"), graphicalmapping.ErrEvidenceNotInCase)
+
+	_, err := f.svc.AddEntity(&graphicalmapping.GraphEntity{
+		TenantID: f.tenantID, CaseID: f.caseID, Type: graphicalmapping.EntityEvidence, Label: "disk image", RefID: other,
+	})
+	require.ErrorIs(t, err, graphicalmapping.ErrEvidenceNotInCase)
+}
+
+func TestNeighborsWithinHops(t *testing.T) {
+	f := newGraphFixture(t)
+
+	g, err := f.svc.Neighbors(f.tenantID, f.caseID, f.person.ID, 1)
+	require.NoError(t, err)
+	require.Len(t, g.Nodes, 2)
+	require.Len(t, g.Edges, 1)
+
+	g, err = f.svc.Neighbors(f.tenantID, f.caseID, f.person.ID, 2)
+	require.NoError(t, err)
+	require.Len(t, g.Nodes, 3)
+	require.Len(t, g.Edges, 2)
+}
+
+func TestShortestPath(t *testing.T) {
+	f := newGraphFixture(t)
+
+	g, err := f.svc.ShortestPath(f.tenantID, f.caseID, f.person.ID, f.ip.ID)
+	require.NoError(t, err)
+	require.Len(t, g.Nodes, 4)
+	require.Equal(t, f.person.ID, g.Nodes[0].ID)
+	require.Equal(t, f.ip.ID, g.Nodes[3].ID)
+	require.Len(t, g.Edges, 3)
+
+	_, err = f.svc.ShortestPath(f.tenantID, f.caseID, f.person.ID, f.isolated.ID)
+	require.ErrorIs(t, err, graphicalmapping.ErrNoPath)
+}
+
+func TestSubgraphByTimeWindow(t *testing.T) {
+	f := newGraphFixture(t)
+
+	g, err := f.svc.SubgraphByTimeWindow(f.tenantID, f.caseID, f.jan5, f.jan10.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, g.Edges, 1)
+	require.Equal(t, graphicalmapping.RelationCommunicatedWith, g.Edges[0].Type)
+	require.Len(t, g.Nodes, 2)
+
+	_, err = f.svc.SubgraphByTimeWindow(f.tenantID, f.caseID, f.jan10, f.jan1)
+	require.ErrorIs(t, err, graphicalmapping.ErrInvalidTimeBounds)
+}
+
+func TestExportFormatsAreWellFormedXML(t *testing.T) {
+	f := newGraphFixture(t)
+	g, err := f.svc.GetCaseGraph(f.tenantID, f.caseID)
+	require.NoError(t, err)
+
+	for name, export := range map[string]func(*bytes.Buffer) error{
+		"graphml": func(b *bytes.Buffer) error { return graphicalmapping.ExportGraphML(b, g) },
+		"gexf":    func(b *bytes.Buffer) error { return graphicalmapping.ExportGEXF(b, g) },
+	} {
+		var buf bytes.Buffer
+		require.NoError(t, export(&buf), name)
+
+		var doc struct {
+			XMLName xml.Name
+		}
+		require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc), name)
+		require.Equal(t, name, doc.XMLName.Local)
+		require.Contains(t, buf.String(), f.host.ID, name)
+	}
+}
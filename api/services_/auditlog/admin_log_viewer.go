@@ -15,10 +15,11 @@ type AdminAuditLogReader interface {
 
 // Add to auditlog/types.go or define inline
 type AuditLogFilter struct {
-	Status  string // "SUCCESS", "FAILED", "ALL" (default "ALL")
-	Action  string // e.g., "EXTRACT_IOCS" for IOC retrievals
-	Service string // e.g., "timelineai"
-	Limit   int    // default 100, max 1000
+	Status   string // "SUCCESS", "FAILED", "ALL" (default "ALL")
+	Action   string // e.g., "EXTRACT_IOCS" for IOC retrievals
+	Service  string // e.g., "timelineai"
+	Severity string // e.g., "high" for blocked access to classified data
+	Limit    int    // default 100, max 1000
 	// Add more: DateFrom, DateTo, ActorID, etc.
 }
 
@@ -68,6 +69,9 @@ func (s *AuditLogService) GetAuditLogs(ctx context.Context, filter AuditLogFilte
 			// Note: Service is per log, but collections are service-based; still filter if mismatch
 			query["service"] = filter.Service
 		}
+		if filter.Severity != "" {
+			query["severity"] = filter.Severity
+		}
 
 		// Sort by timestamp descending
 		opts := options.Find().
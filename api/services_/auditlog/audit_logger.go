@@ -17,6 +17,8 @@ func NewAuditLogger(mongo MongoLoggerInterface, zap ZapLoggerInterface) *AuditLo
 	return &AuditLogger{mongo: mongo, zap: zap}
 }
 func (a *AuditLogger) Log(ctx *gin.Context, log AuditLog) error {
+	attributeAPIKey(ctx, &log.Actor)
+
 	// Debug: Incoming log
 	// Debug logging removed because ZapLoggerInterface does not define Debug method
 
@@ -37,6 +39,20 @@ func (a *AuditLogger) Log(ctx *gin.Context, log AuditLog) error {
 	return nil
 }
 
+// attributeAPIKey marks entries of requests made with an API key as the
+// service account's, whatever actor the caller built.
+func attributeAPIKey(ctx *gin.Context, actor *Actor) {
+	keyID := ctx.GetString("apiKeyID")
+	if keyID == "" {
+		return
+	}
+	actor.ID = ctx.GetString("userID")
+	actor.Role = "Service Account"
+	actor.Email = ""
+	actor.APIKeyID = keyID
+	actor.ServiceAccount = ctx.GetString("fullName")
+}
+
 // LogDownloadReport method now accepts context.Context
 
 // LogDownloadReport method now accepts context.Context
@@ -11,6 +11,10 @@ type Actor struct {
 	UserAgent string `bson:"user_agent"`
 	IPAddress string `bson:"ip_address"`
 	Email     string `bson:"email,omitempty" json:"email,omitempty"`
+	// APIKeyID and ServiceAccount are set when a service account acted
+	// through one of its API keys
+	APIKeyID       string `bson:"api_key_id,omitempty" json:"api_key_id,omitempty"`
+	ServiceAccount string `bson:"service_account,omitempty" json:"service_account,omitempty"`
 }
 
 type Target struct {
@@ -27,6 +31,7 @@ type AuditLog struct {
 	Target      Target // resource affected: type, ID, extra info
 	Service     string // service that triggered log, e.g., "chat"
 	Status      string // e.g., "SUCCESS"
+	Severity    string // "high" for blocked attempts on sensitive data; empty otherwise
 	Description string
 	Metadata    map[string]string // route, method, etc.
 }
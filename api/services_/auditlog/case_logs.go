@@ -0,0 +1,64 @@
+package auditlog
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// auditCollections are the collections MongoLogger writes to.
+var auditCollections = []string{
+	"audit_logs_evidence",
+	"audit_logs_case",
+	"audit_logs_user",
+	"audit_logs_admin",
+	"audit_logs_annotation_threads",
+	"audit_logs_chat",
+	"audit_logs_annotation_messages",
+	"audit_logs_general",
+}
+
+// CaseLogQuery selects the audit entries concerning a case: those whose
+// target is the case or one of TargetIDs (its evidence, reports, ...), and
+// those naming the case in their target's additional info.
+type CaseLogQuery struct {
+	CaseID    string
+	TargetIDs []string
+}
+
+// StreamCaseLogs calls fn with each entry matching q across every audit
+// collection, oldest first. Entries are read from a cursor, so a long
+// history is never held in memory.
+func (s *AuditLogService) StreamCaseLogs(ctx context.Context, q CaseLogQuery, fn func(AuditLog) error) error {
+	ids := append([]string{q.CaseID}, q.TargetIDs...)
+	match := bson.D{{Key: "$match", Value: bson.M{"$or": bson.A{
+		bson.M{"target.id": bson.M{"$in": ids}},
+		bson.M{"target.additional_info.case_id": q.CaseID},
+	}}}}
+
+	pipeline := bson.A{match}
+	for _, coll := range auditCollections[1:] {
+		pipeline = append(pipeline, bson.D{{Key: "$unionWith", Value: bson.M{
+			"coll":     coll,
+			"pipeline": bson.A{match},
+		}}})
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$sort", Value: bson.D{{Key: "timestamp", Value: 1}}}})
+
+	cursor, err := s.db.Collection(auditCollections[0]).Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var entry AuditLog
+		if err := cursor.Decode(&entry); err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
@@ -44,6 +44,7 @@ func (z *ZapLogger) Log(log AuditLog) {
 		// Contextual details
 		zap.String("service", log.Service),
 		zap.String("status", log.Status),
+		zap.String("severity", log.Severity),
 		zap.String("description", log.Description),
 		zap.Any("metadata", log.Metadata),
 	)
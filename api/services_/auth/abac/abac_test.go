@@ -0,0 +1,150 @@
+package abac_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"aegis-api/services_/auth/abac"
+	"aegis-api/tests/fakes"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type fixture struct {
+	repo     *fakes.ABAC
+	svc      abac.Service
+	admin    abac.Actor
+	analyst  abac.Subject
+	evidence string
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	repo := &fakes.ABAC{}
+	tenant := uuid.NewString()
+	f := &fixture{
+		repo:     repo,
+		svc:      abac.NewService(repo),
+		admin:    abac.Actor{UserID: uuid.NewString(), TenantID: tenant},
+		analyst:  abac.Subject{UserID: uuid.NewString(), TenantID: tenant},
+		evidence: uuid.NewString(),
+	}
+	repo.AddUser(tenant, f.analyst.UserID)
+	repo.Place(abac.ResourceEvidence, f.evidence, tenant)
+	return f
+}
+
+func (f *fixture) decide(t *testing.T, action string) *abac.Decision {
+	t.Helper()
+	d, err := f.svc.Decide(context.Background(), f.analyst, action, abac.ResourceEvidence, f.evidence)
+	require.NoError(t, err)
+	return d
+}
+
+func TestClearanceAndNeedToKnow(t *testing.T) {
+	f := newFixture(t)
+	require.True(t, f.decide(t, abac.ActionView).Allowed)
+
+	group, err := f.svc.CreateGroup(f.admin, abac.GroupInput{Name: "Legal privilege"})
+	require.NoError(t, err)
+	_, err = f.svc.SetLabel(f.admin, abac.ResourceEvidence, f.evidence, abac.LabelInput{
+		Level: "secret", Groups: []string{group.ID},
+	})
+	require.NoError(t, err)
+
+	d := f.decide(t, abac.ActionView)
+	require.False(t, d.Allowed)
+	require.Equal(t, abac.ReasonClearance, d.Reason)
+	require.Equal(t, "SECRET // LEGAL PRIVILEGE", d.Marking)
+
+	_, err = f.svc.SetClearance(f.admin, f.analyst.UserID, abac.ClearanceInput{Level: "TOP SECRET"})
+	require.NoError(t, err)
+	require.Equal(t, abac.ReasonNeedToKnow, f.decide(t, abac.ActionView).Reason)
+
+	_, err = f.svc.AddMember(f.admin, group.ID, f.analyst.UserID)
+	require.NoError(t, err)
+	require.True(t, f.decide(t, abac.ActionView).Allowed)
+
+	// A group required by a label cannot be deleted
+	_, err = f.svc.DeleteGroup(f.admin, group.ID)
+	require.ErrorIs(t, err, abac.ErrConflict)
+}
+
+func TestHandlingRestrictions(t *testing.T) {
+	f := newFixture(t)
+	_, err := f.svc.SetLabel(f.admin, abac.ResourceEvidence, f.evidence, abac.LabelInput{
+		Level: "CONFIDENTIAL", Withheld: []string{"export", "share"},
+	})
+	require.NoError(t, err)
+	_, err = f.svc.SetClearance(f.admin, f.analyst.UserID, abac.ClearanceInput{Level: "SECRET"})
+	require.NoError(t, err)
+
+	require.True(t, f.decide(t, abac.ActionView).Allowed)
+	require.True(t, f.decide(t, abac.ActionDownload).Allowed)
+	d := f.decide(t, abac.ActionShare)
+	require.False(t, d.Allowed)
+	require.Equal(t, abac.ReasonWithheld, d.Reason)
+
+	// A copy of the evidence is held to the same label
+	f.repo.AddContent("abc", f.evidence, uuid.NewString())
+	d, evidenceID, err := f.svc.DecideContent(context.Background(), f.analyst, abac.ActionShare, "abc")
+	require.NoError(t, err)
+	require.False(t, d.Allowed)
+	require.Equal(t, f.evidence, evidenceID)
+
+	_, err = f.svc.SetLabel(f.admin, abac.ResourceEvidence, f.evidence, abac.LabelInput{
+		Level: "SECRET", Withheld: []string{"view"},
+	})
+	require.ErrorIs(t, err, abac.ErrInvalidInput)
+}
+
+func TestClearanceExpiryAndTenant(t *testing.T) {
+	f := newFixture(t)
+	_, err := f.svc.SetLabel(f.admin, abac.ResourceEvidence, f.evidence, abac.LabelInput{Level: "CONFIDENTIAL"})
+	require.NoError(t, err)
+	c, err := f.svc.SetClearance(f.admin, f.analyst.UserID, abac.ClearanceInput{Level: "SECRET", ExpiresInDays: 30})
+	require.NoError(t, err)
+	require.True(t, f.decide(t, abac.ActionView).Allowed)
+
+	lapsed := time.Now().Add(-time.Minute)
+	c.ExpiresAt = &lapsed
+	require.NoError(t, f.repo.SaveClearance(c))
+	require.Equal(t, abac.ReasonClearance, f.decide(t, abac.ActionView).Reason)
+
+	// Another tenant can neither label the evidence nor clear the user
+	other := abac.Actor{UserID: uuid.NewString(), TenantID: uuid.NewString()}
+	_, err = f.svc.SetLabel(other, abac.ResourceEvidence, f.evidence, abac.LabelInput{Level: "SECRET"})
+	require.ErrorIs(t, err, abac.ErrNotFound)
+	_, err = f.svc.SetClearance(other, f.analyst.UserID, abac.ClearanceInput{Level: "SECRET"})
+	require.ErrorIs(t, err, abac.ErrNotFound)
+	_, err = f.svc.RevokeClearance(other, f.analyst.UserID)
+	require.ErrorIs(t, err, abac.ErrNotFound)
+}
+
+func TestWithheldFiltersBatches(t *testing.T) {
+	f := newFixture(t)
+	open := uuid.NewString()
+	_, err := f.svc.SetLabel(f.admin, abac.ResourceEvidence, f.evidence, abac.LabelInput{Level: "SECRET"})
+	require.NoError(t, err)
+
+	refused, err := f.svc.Withheld(context.Background(), f.analyst, abac.ActionView, abac.ResourceEvidence,
+		[]string{f.evidence, open, "not-a-uuid"})
+	require.NoError(t, err)
+	require.Len(t, refused, 1)
+	require.Equal(t, abac.ReasonClearance, refused[f.evidence].Reason)
+
+	report := uuid.New()
+	f.repo.Place(abac.ResourceReport, report.String(), f.admin.TenantID)
+	marking, err := f.svc.ReportMarking(context.Background(), report)
+	require.NoError(t, err)
+	require.Empty(t, marking)
+	_, err = f.svc.SetLabel(f.admin, abac.ResourceReport, report.String(), abac.LabelInput{
+		Level: "TOP SECRET", Withheld: []string{"export"},
+	})
+	require.NoError(t, err)
+	marking, err = f.svc.ReportMarking(context.Background(), report)
+	require.NoError(t, err)
+	require.Equal(t, "TOP SECRET // NO EXPORT", marking)
+}
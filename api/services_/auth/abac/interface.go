@@ -0,0 +1,75 @@
+package abac
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type Repository interface {
+	AutoMigrate() error
+
+	// GetLabel returns nil for unlabelled resources.
+	GetLabel(resourceType, id string) (*Label, error)
+	ListLabels(resourceType string, ids []string) ([]Label, error)
+	SaveLabel(l *Label) error
+	DeleteLabel(resourceType, id string) error
+	// ResourceTenant returns the tenant of an evidence item or report, or
+	// "" when there is none.
+	ResourceTenant(resourceType, id string) (string, error)
+	// EvidenceByChecksum lists the tenant's evidence with the SHA-256.
+	EvidenceByChecksum(tenantID, checksum string) ([]string, error)
+
+	// GetClearance returns nil when the user holds none.
+	GetClearance(userID string) (*Clearance, error)
+	ListClearances(tenantID string) ([]Clearance, error)
+	SaveClearance(c *Clearance) error
+	DeleteClearance(userID string) error
+	// TenantUser reports whether the user belongs to the tenant.
+	TenantUser(tenantID, userID string) (bool, error)
+
+	CreateGroup(g *Group) error
+	// GetGroup returns nil when the tenant has no such group.
+	GetGroup(tenantID, id string) (*Group, error)
+	ListGroups(tenantID string) ([]Group, error)
+	// GroupInUse reports whether any label requires the group.
+	GroupInUse(id string) (bool, error)
+	// DeleteGroup removes the group and its members.
+	DeleteGroup(id string) error
+	AddMember(m *GroupMember) error
+	RemoveMember(groupID, userID string) error
+	ListMembers(groupID string) ([]GroupMember, error)
+	// UserGroups lists the IDs of the groups the user belongs to.
+	UserGroups(userID string) ([]string, error)
+}
+
+type Service interface {
+	// Decide answers whether the subject may perform action on the
+	// evidence item or report.
+	Decide(ctx context.Context, s Subject, action, resourceType, id string) (*Decision, error)
+	// Withheld returns the refused decision of each resource, of ids,
+	// the subject may not perform action on.
+	Withheld(ctx context.Context, s Subject, action, resourceType string, ids []string) (map[string]*Decision, error)
+	// DecideContent decides action on content by its SHA-256, so a copy
+	// of classified evidence is held to the evidence's label. It returns
+	// the most restrictive refusal, with the evidence it came from.
+	DecideContent(ctx context.Context, s Subject, action, checksum string) (*Decision, string, error)
+	// ReportMarking returns the marking printed on an exported report,
+	// or "" when it is unlabelled. It implements report.MarkingResolver.
+	ReportMarking(ctx context.Context, reportID uuid.UUID) (string, error)
+
+	GetLabel(tenantID, resourceType, id string) (*Label, error)
+	SetLabel(actor Actor, resourceType, id string, in LabelInput) (*Label, error)
+	ClearLabel(actor Actor, resourceType, id string) (*Label, error)
+
+	ListClearances(tenantID string) ([]Clearance, error)
+	SetClearance(actor Actor, userID string, in ClearanceInput) (*Clearance, error)
+	RevokeClearance(actor Actor, userID string) (*Clearance, error)
+
+	ListGroups(tenantID string) ([]Group, error)
+	CreateGroup(actor Actor, in GroupInput) (*Group, error)
+	DeleteGroup(actor Actor, id string) (*Group, error)
+	ListMembers(tenantID, groupID string) ([]GroupMember, error)
+	AddMember(actor Actor, groupID, userID string) (*GroupMember, error)
+	RemoveMember(actor Actor, groupID, userID string) error
+}
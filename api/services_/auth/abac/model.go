@@ -0,0 +1,121 @@
+package abac
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Actions the policy decides.
+const (
+	ActionView     = "view"
+	ActionDownload = "download"
+	ActionExport   = "export"
+	ActionShare    = "share"
+)
+
+// Resource types that carry labels.
+const (
+	ResourceEvidence = "evidence"
+	ResourceReport   = "report"
+)
+
+// Reasons an action is refused.
+const (
+	ReasonClearance  = "insufficient_clearance"
+	ReasonNeedToKnow = "need_to_know"
+	ReasonWithheld   = "handling_restriction"
+)
+
+// Levels lists the classification levels from least to most sensitive.
+// Users without a clearance hold the first.
+var Levels = []string{"UNCLASSIFIED", "CONFIDENTIAL", "SECRET", "TOP SECRET"}
+
+// Label classifies an evidence item or a report. Reading it needs a
+// clearance of at least Level and membership of every need-to-know group
+// in Groups; Withheld actions are refused to everyone.
+type Label struct {
+	ResourceType string         `gorm:"size:20;primaryKey" json:"resource_type"`
+	ResourceID   string         `gorm:"type:uuid;primaryKey" json:"resource_id"`
+	TenantID     string         `gorm:"type:uuid;index;not null" json:"tenant_id"`
+	Level        string         `gorm:"not null" json:"level"`
+	Groups       datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'" json:"groups"`   // []string group IDs
+	Withheld     datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'" json:"withheld"` // []string actions
+	Reason       string         `json:"reason,omitempty"`
+	LabelledBy   string         `gorm:"type:uuid" json:"labelled_by"`
+	LabelledAt   time.Time      `json:"labelled_at"`
+}
+
+func (Label) TableName() string { return "classification_labels" }
+
+// Clearance is the highest level a user may read.
+type Clearance struct {
+	UserID    string     `gorm:"type:uuid;primaryKey" json:"user_id"`
+	TenantID  string     `gorm:"type:uuid;index;not null" json:"tenant_id"`
+	Level     string     `gorm:"not null" json:"level"`
+	GrantedBy string     `gorm:"type:uuid" json:"granted_by"`
+	GrantedAt time.Time  `json:"granted_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func (Clearance) TableName() string { return "user_clearances" }
+
+// Group is a need-to-know group, such as the reviewers cleared for CSAM
+// or the lawyers who may read privileged material.
+type Group struct {
+	ID          string    `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID    string    `gorm:"type:uuid;uniqueIndex:idx_need_to_know_groups_name;not null" json:"tenant_id"`
+	Name        string    `gorm:"uniqueIndex:idx_need_to_know_groups_name;not null" json:"name"`
+	Description string    `json:"description,omitempty"`
+	CreatedBy   string    `gorm:"type:uuid" json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (Group) TableName() string { return "need_to_know_groups" }
+
+type GroupMember struct {
+	GroupID string    `gorm:"type:uuid;primaryKey" json:"group_id"`
+	UserID  string    `gorm:"type:uuid;primaryKey;index" json:"user_id"`
+	AddedBy string    `gorm:"type:uuid" json:"added_by"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+func (GroupMember) TableName() string { return "need_to_know_members" }
+
+type LabelInput struct {
+	Level    string   `json:"level"`
+	Groups   []string `json:"groups"`
+	Withheld []string `json:"withheld"`
+	Reason   string   `json:"reason"`
+}
+
+type ClearanceInput struct {
+	Level         string `json:"level"`
+	ExpiresInDays int    `json:"expires_in_days"`
+}
+
+type GroupInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Subject is the user a decision is for.
+type Subject struct {
+	UserID   string
+	TenantID string
+}
+
+// Actor is the administrator managing labels, clearances and groups.
+type Actor struct {
+	UserID   string
+	TenantID string
+}
+
+// Decision is the policy's answer for one resource.
+type Decision struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+	// Level and Marking are empty for unlabelled resources.
+	Level   string `json:"level,omitempty"`
+	Marking string `json:"marking,omitempty"`
+}
@@ -0,0 +1,150 @@
+package abac
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) AutoMigrate() error {
+	return r.db.AutoMigrate(&Label{}, &Clearance{}, &Group{}, &GroupMember{})
+}
+
+func first[T any](q *gorm.DB) (*T, error) {
+	var v T
+	err := q.First(&v).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *GormRepository) GetLabel(resourceType, id string) (*Label, error) {
+	return first[Label](r.db.Where("resource_type = ? AND resource_id = ?", resourceType, id))
+}
+
+func (r *GormRepository) ListLabels(resourceType string, ids []string) ([]Label, error) {
+	var out []Label
+	if len(ids) == 0 {
+		return out, nil
+	}
+	err := r.db.Where("resource_type = ? AND resource_id IN ?", resourceType, ids).Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) SaveLabel(l *Label) error {
+	return r.db.Save(l).Error
+}
+
+func (r *GormRepository) DeleteLabel(resourceType, id string) error {
+	return r.db.Where("resource_type = ? AND resource_id = ?", resourceType, id).Delete(&Label{}).Error
+}
+
+// resourceTenant maps resource types to the query finding their tenant.
+var resourceTenant = map[string]string{
+	ResourceEvidence: "SELECT tenant_id::text FROM evidence WHERE id = ?",
+	ResourceReport:   "SELECT tenant_id::text FROM reports WHERE id = ?",
+}
+
+func (r *GormRepository) ResourceTenant(resourceType, id string) (string, error) {
+	query, ok := resourceTenant[resourceType]
+	if !ok {
+		return "", nil
+	}
+	var tenants []string
+	if err := r.db.Raw(query, id).Scan(&tenants).Error; err != nil || len(tenants) == 0 {
+		return "", err
+	}
+	return tenants[0], nil
+}
+
+func (r *GormRepository) EvidenceByChecksum(tenantID, checksum string) ([]string, error) {
+	var ids []string
+	err := r.db.Raw("SELECT id::text FROM evidence WHERE tenant_id = ? AND lower(checksum) = lower(?)", tenantID, checksum).
+		Scan(&ids).Error
+	return ids, err
+}
+
+func (r *GormRepository) GetClearance(userID string) (*Clearance, error) {
+	return first[Clearance](r.db.Where("user_id = ?", userID))
+}
+
+func (r *GormRepository) ListClearances(tenantID string) ([]Clearance, error) {
+	var out []Clearance
+	err := r.db.Where("tenant_id = ?", tenantID).Order("granted_at DESC").Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) SaveClearance(c *Clearance) error {
+	return r.db.Save(c).Error
+}
+
+func (r *GormRepository) DeleteClearance(userID string) error {
+	return r.db.Where("user_id = ?", userID).Delete(&Clearance{}).Error
+}
+
+func (r *GormRepository) TenantUser(tenantID, userID string) (bool, error) {
+	var n int64
+	err := r.db.Table("users").Where("id = ? AND tenant_id = ?", userID, tenantID).Count(&n).Error
+	return n > 0, err
+}
+
+func (r *GormRepository) CreateGroup(g *Group) error {
+	return r.db.Create(g).Error
+}
+
+func (r *GormRepository) GetGroup(tenantID, id string) (*Group, error) {
+	return first[Group](r.db.Where("id = ? AND tenant_id = ?", id, tenantID))
+}
+
+func (r *GormRepository) ListGroups(tenantID string) ([]Group, error) {
+	var out []Group
+	err := r.db.Where("tenant_id = ?", tenantID).Order("name").Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) GroupInUse(id string) (bool, error) {
+	var n int64
+	err := r.db.Model(&Label{}).Where("groups @> ?", jsonOf([]string{id})).Count(&n).Error
+	return n > 0, err
+}
+
+func (r *GormRepository) DeleteGroup(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("group_id = ?", id).Delete(&GroupMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&Group{}).Error
+	})
+}
+
+func (r *GormRepository) AddMember(m *GroupMember) error {
+	return r.db.Save(m).Error
+}
+
+func (r *GormRepository) RemoveMember(groupID, userID string) error {
+	return r.db.Where("group_id = ? AND user_id = ?", groupID, userID).Delete(&GroupMember{}).Error
+}
+
+func (r *GormRepository) ListMembers(groupID string) ([]GroupMember, error) {
+	var out []GroupMember
+	err := r.db.Where("group_id = ?", groupID).Order("added_at").Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) UserGroups(userID string) ([]string, error) {
+	var ids []string
+	err := r.db.Model(&GroupMember{}).Where("user_id = ?", userID).Pluck("group_id", &ids).Error
+	return ids, err
+}
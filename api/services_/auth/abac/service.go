@@ -0,0 +1,490 @@
+package abac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+var (
+	ErrNotFound     = errors.New("label, clearance or group not found")
+	ErrInvalidInput = errors.New("invalid input")
+	ErrConflict     = errors.New("conflict")
+)
+
+// withholdable lists the actions a label may refuse to everyone. Viewing
+// is controlled by level and groups alone.
+var withholdable = []string{ActionDownload, ActionExport, ActionShare}
+
+type service struct {
+	repo Repository
+	now  func() time.Time
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo, now: time.Now}
+}
+
+func invalid(format string, a ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidInput, fmt.Sprintf(format, a...))
+}
+
+func jsonOf(v interface{}) datatypes.JSON {
+	raw, _ := json.Marshal(v)
+	return datatypes.JSON(raw)
+}
+
+func stringList(raw datatypes.JSON) []string {
+	var out []string
+	_ = json.Unmarshal(raw, &out)
+	return out
+}
+
+// rank orders levels; -1 for unknown ones.
+func rank(level string) int {
+	return slices.Index(Levels, level)
+}
+
+func normalLevel(level string) (string, error) {
+	level = strings.ToUpper(strings.TrimSpace(level))
+	if rank(level) < 0 {
+		return "", invalid("level must be one of %s", strings.Join(Levels, ", "))
+	}
+	return level, nil
+}
+
+func checkResource(resourceType, id string) error {
+	if resourceType != ResourceEvidence && resourceType != ResourceReport {
+		return invalid("resource type must be %q or %q", ResourceEvidence, ResourceReport)
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return invalid("%q is not a UUID", id)
+	}
+	return nil
+}
+
+// ─── Decisions ─────────────────────────────────────
+
+// attributes are what the policy knows of a subject.
+type attributes struct {
+	rank   int
+	groups map[string]bool
+}
+
+func (s *service) attributesOf(subject Subject) (*attributes, error) {
+	a := &attributes{groups: map[string]bool{}}
+	c, err := s.repo.GetClearance(subject.UserID)
+	if err != nil {
+		return nil, err
+	}
+	// A clearance held in another tenant, or lapsed, counts for nothing.
+	if c != nil && c.TenantID == subject.TenantID && (c.ExpiresAt == nil || s.now().Before(*c.ExpiresAt)) {
+		a.rank = max(rank(c.Level), 0)
+	}
+	groups, err := s.repo.UserGroups(subject.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		a.groups[g] = true
+	}
+	return a, nil
+}
+
+// evaluate applies a label to a subject: clearance first, then every
+// need-to-know group, then the label's handling restrictions.
+func evaluate(a *attributes, l *Label, action string) *Decision {
+	d := &Decision{Level: l.Level}
+	switch {
+	case a.rank < rank(l.Level):
+		d.Reason = ReasonClearance
+	case slices.ContainsFunc(stringList(l.Groups), func(g string) bool { return !a.groups[g] }):
+		d.Reason = ReasonNeedToKnow
+	case slices.Contains(stringList(l.Withheld), action):
+		d.Reason = ReasonWithheld
+	default:
+		d.Allowed = true
+	}
+	return d
+}
+
+func checkAction(action string) error {
+	if !slices.Contains([]string{ActionView, ActionDownload, ActionExport, ActionShare}, action) {
+		return invalid("unknown action %q", action)
+	}
+	return nil
+}
+
+func (s *service) Decide(ctx context.Context, subject Subject, action, resourceType, id string) (*Decision, error) {
+	if err := checkAction(action); err != nil {
+		return nil, err
+	}
+	// Malformed IDs cannot be labelled; the caller reports them missing.
+	if err := checkResource(resourceType, id); err != nil {
+		return &Decision{Allowed: true}, nil
+	}
+	l, err := s.repo.GetLabel(resourceType, strings.ToLower(id))
+	if err != nil {
+		return nil, err
+	}
+	if l == nil {
+		return &Decision{Allowed: true}, nil
+	}
+	a, err := s.attributesOf(subject)
+	if err != nil {
+		return nil, err
+	}
+	d := evaluate(a, l, action)
+	if d.Marking, err = s.marking(l); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *service) Withheld(ctx context.Context, subject Subject, action, resourceType string, ids []string) (map[string]*Decision, error) {
+	if err := checkAction(action); err != nil {
+		return nil, err
+	}
+	out := map[string]*Decision{}
+	valid := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err == nil {
+			valid = append(valid, strings.ToLower(id))
+		}
+	}
+	labels, err := s.repo.ListLabels(resourceType, valid)
+	if err != nil || len(labels) == 0 {
+		return out, err
+	}
+	a, err := s.attributesOf(subject)
+	if err != nil {
+		return nil, err
+	}
+	for i := range labels {
+		if d := evaluate(a, &labels[i], action); !d.Allowed {
+			out[labels[i].ResourceID] = d
+		}
+	}
+	// Callers look up the IDs they passed in.
+	for _, id := range ids {
+		if d, ok := out[strings.ToLower(id)]; ok {
+			out[id] = d
+		}
+	}
+	return out, nil
+}
+
+func (s *service) DecideContent(ctx context.Context, subject Subject, action, checksum string) (*Decision, string, error) {
+	if err := checkAction(action); err != nil {
+		return nil, "", err
+	}
+	ids, err := s.repo.EvidenceByChecksum(subject.TenantID, checksum)
+	if err != nil {
+		return nil, "", err
+	}
+	refused, err := s.Withheld(ctx, subject, action, ResourceEvidence, ids)
+	if err != nil {
+		return nil, "", err
+	}
+	var (
+		worst      *Decision
+		evidenceID string
+	)
+	for id, d := range refused {
+		if worst == nil || rank(d.Level) > rank(worst.Level) {
+			worst, evidenceID = d, id
+		}
+	}
+	if worst == nil {
+		return &Decision{Allowed: true}, "", nil
+	}
+	return worst, evidenceID, nil
+}
+
+func (s *service) ReportMarking(ctx context.Context, reportID uuid.UUID) (string, error) {
+	l, err := s.repo.GetLabel(ResourceReport, reportID.String())
+	if err != nil || l == nil {
+		return "", err
+	}
+	return s.marking(l)
+}
+
+// marking renders a label the way it is printed on exports, e.g.
+// "SECRET // LEGAL PRIVILEGE // NO SHARE".
+func (s *service) marking(l *Label) (string, error) {
+	parts := []string{l.Level}
+	for _, id := range stringList(l.Groups) {
+		g, err := s.repo.GetGroup(l.TenantID, id)
+		if err != nil {
+			return "", err
+		}
+		if g != nil {
+			parts = append(parts, strings.ToUpper(g.Name))
+		}
+	}
+	for _, action := range stringList(l.Withheld) {
+		parts = append(parts, "NO "+strings.ToUpper(action))
+	}
+	return strings.Join(parts, " // "), nil
+}
+
+// ─── Labels ────────────────────────────────────────
+
+// ownedResource checks the resource exists in the tenant.
+func (s *service) ownedResource(tenantID, resourceType, id string) error {
+	if err := checkResource(resourceType, id); err != nil {
+		return err
+	}
+	owner, err := s.repo.ResourceTenant(resourceType, id)
+	if err != nil {
+		return err
+	}
+	if owner == "" || owner != tenantID {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *service) GetLabel(tenantID, resourceType, id string) (*Label, error) {
+	if err := s.ownedResource(tenantID, resourceType, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetLabel(resourceType, strings.ToLower(id))
+}
+
+func (s *service) SetLabel(actor Actor, resourceType, id string, in LabelInput) (*Label, error) {
+	if err := s.ownedResource(actor.TenantID, resourceType, id); err != nil {
+		return nil, err
+	}
+	level, err := normalLevel(in.Level)
+	if err != nil {
+		return nil, err
+	}
+	groups := []string{}
+	for _, g := range in.Groups {
+		if slices.Contains(groups, g) {
+			continue
+		}
+		group, err := s.repo.GetGroup(actor.TenantID, g)
+		if err != nil {
+			return nil, err
+		}
+		if group == nil {
+			return nil, invalid("unknown need-to-know group %q", g)
+		}
+		groups = append(groups, group.ID)
+	}
+	withheld := []string{}
+	for _, action := range in.Withheld {
+		action = strings.ToLower(strings.TrimSpace(action))
+		if !slices.Contains(withholdable, action) {
+			return nil, invalid("only %s can be withheld", strings.Join(withholdable, ", "))
+		}
+		if !slices.Contains(withheld, action) {
+			withheld = append(withheld, action)
+		}
+	}
+	if len(in.Reason) > 500 {
+		return nil, invalid("reason is longer than 500 characters")
+	}
+	l := &Label{
+		ResourceType: resourceType,
+		ResourceID:   strings.ToLower(id),
+		TenantID:     actor.TenantID,
+		Level:        level,
+		Groups:       jsonOf(groups),
+		Withheld:     jsonOf(withheld),
+		Reason:       strings.TrimSpace(in.Reason),
+		LabelledBy:   actor.UserID,
+		LabelledAt:   s.now(),
+	}
+	if err := s.repo.SaveLabel(l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (s *service) ClearLabel(actor Actor, resourceType, id string) (*Label, error) {
+	l, err := s.GetLabel(actor.TenantID, resourceType, id)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil {
+		return nil, ErrNotFound
+	}
+	if err := s.repo.DeleteLabel(l.ResourceType, l.ResourceID); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// ─── Clearances ────────────────────────────────────
+
+func (s *service) ListClearances(tenantID string) ([]Clearance, error) {
+	return s.repo.ListClearances(tenantID)
+}
+
+func (s *service) tenantUser(tenantID, userID string) error {
+	if _, err := uuid.Parse(userID); err != nil {
+		return invalid("%q is not a UUID", userID)
+	}
+	ok, err := s.repo.TenantUser(tenantID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *service) SetClearance(actor Actor, userID string, in ClearanceInput) (*Clearance, error) {
+	if err := s.tenantUser(actor.TenantID, userID); err != nil {
+		return nil, err
+	}
+	level, err := normalLevel(in.Level)
+	if err != nil {
+		return nil, err
+	}
+	if in.ExpiresInDays < 0 {
+		return nil, invalid("expires_in_days must not be negative")
+	}
+	c := &Clearance{
+		UserID:    strings.ToLower(userID),
+		TenantID:  actor.TenantID,
+		Level:     level,
+		GrantedBy: actor.UserID,
+		GrantedAt: s.now(),
+	}
+	if in.ExpiresInDays > 0 {
+		expires := c.GrantedAt.AddDate(0, 0, in.ExpiresInDays)
+		c.ExpiresAt = &expires
+	}
+	if err := s.repo.SaveClearance(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *service) RevokeClearance(actor Actor, userID string) (*Clearance, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, invalid("%q is not a UUID", userID)
+	}
+	c, err := s.repo.GetClearance(userID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil || c.TenantID != actor.TenantID {
+		return nil, ErrNotFound
+	}
+	if err := s.repo.DeleteClearance(c.UserID); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ─── Need-to-know groups ───────────────────────────
+
+func (s *service) ListGroups(tenantID string) ([]Group, error) {
+	return s.repo.ListGroups(tenantID)
+}
+
+func (s *service) CreateGroup(actor Actor, in GroupInput) (*Group, error) {
+	name := strings.TrimSpace(in.Name)
+	if name == "" || len(name) > 100 {
+		return nil, invalid("name must be 1 to 100 characters")
+	}
+	groups, err := s.repo.ListGroups(actor.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		if strings.EqualFold(g.Name, name) {
+			return nil, fmt.Errorf("%w: group %q already exists", ErrConflict, g.Name)
+		}
+	}
+	g := &Group{
+		ID:          uuid.NewString(),
+		TenantID:    actor.TenantID,
+		Name:        name,
+		Description: strings.TrimSpace(in.Description),
+		CreatedBy:   actor.UserID,
+		CreatedAt:   s.now(),
+	}
+	if err := s.repo.CreateGroup(g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (s *service) group(tenantID, id string) (*Group, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+	g, err := s.repo.GetGroup(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if g == nil {
+		return nil, ErrNotFound
+	}
+	return g, nil
+}
+
+// DeleteGroup refuses groups still required by a label: deleting one
+// would lock everyone out of the resource.
+func (s *service) DeleteGroup(actor Actor, id string) (*Group, error) {
+	g, err := s.group(actor.TenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	inUse, err := s.repo.GroupInUse(g.ID)
+	if err != nil {
+		return nil, err
+	}
+	if inUse {
+		return nil, fmt.Errorf("%w: group %q is required by a label", ErrConflict, g.Name)
+	}
+	if err := s.repo.DeleteGroup(g.ID); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (s *service) ListMembers(tenantID, groupID string) ([]GroupMember, error) {
+	g, err := s.group(tenantID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListMembers(g.ID)
+}
+
+func (s *service) AddMember(actor Actor, groupID, userID string) (*GroupMember, error) {
+	g, err := s.group(actor.TenantID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tenantUser(actor.TenantID, userID); err != nil {
+		return nil, err
+	}
+	m := &GroupMember{GroupID: g.ID, UserID: strings.ToLower(userID), AddedBy: actor.UserID, AddedAt: s.now()}
+	if err := s.repo.AddMember(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (s *service) RemoveMember(actor Actor, groupID, userID string) error {
+	g, err := s.group(actor.TenantID, groupID)
+	if err != nil {
+		return err
+	}
+	return s.repo.RemoveMember(g.ID, strings.ToLower(userID))
+}
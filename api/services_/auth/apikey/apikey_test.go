@@ -0,0 +1,188 @@
+package apikey_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"aegis-api/middleware"
+	"aegis-api/services_/auth/apikey"
+	"aegis-api/tests/fakes"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type fixture struct {
+	svc     apikey.Service
+	repo    *fakes.APIKeys
+	admin   apikey.Actor
+	account *apikey.Account
+	caseID  string
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	repo := &fakes.APIKeys{}
+	f := &fixture{
+		svc:    apikey.NewService(repo, apikey.Options{}),
+		repo:   repo,
+		admin:  apikey.Actor{UserID: uuid.NewString(), TenantID: uuid.NewString()},
+		caseID: uuid.NewString(),
+	}
+	repo.AddCase(f.admin.TenantID, f.caseID)
+	teamID := uuid.NewString()
+	repo.AddTeam(f.admin.TenantID, teamID)
+
+	var err error
+	f.account, err = f.svc.CreateAccount(f.admin, apikey.AccountInput{Name: "SOAR", TeamID: &teamID})
+	require.NoError(t, err)
+	return f
+}
+
+func TestCreateAccount(t *testing.T) {
+	f := newFixture(t)
+	require.Equal(t, f.admin.TenantID, f.account.TenantID)
+	require.True(t, strings.HasSuffix(f.repo.Emails[f.account.ID], ".invalid"), "backed by a users row that cannot receive mail")
+
+	_, err := f.svc.CreateAccount(f.admin, apikey.AccountInput{Name: "soar"})
+	require.ErrorIs(t, err, apikey.ErrInvalidInput)
+	other := uuid.NewString()
+	_, err = f.svc.CreateAccount(f.admin, apikey.AccountInput{Name: "Acquisition", TeamID: &other})
+	require.ErrorIs(t, err, apikey.ErrInvalidInput)
+	_, err = f.svc.CreateAccount(f.admin, apikey.AccountInput{Name: " "})
+	require.ErrorIs(t, err, apikey.ErrInvalidInput)
+}
+
+func TestCreateKeyAndAuthenticate(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	issued, err := f.svc.CreateKey(f.admin, f.account.ID, apikey.KeyInput{
+		Name:    "evidence push",
+		Scopes:  []string{"evidence:upload", "case:view", "evidence:upload"},
+		CaseIDs: []string{strings.ToUpper(f.caseID)},
+	})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(issued.Secret, middleware.APIKeyPrefix))
+	require.True(t, strings.HasPrefix(issued.Secret, issued.Key.Prefix))
+	require.NotContains(t, issued.Key.Hash, issued.Secret)
+	require.WithinDuration(t, time.Now().Add(90*24*time.Hour), issued.Key.ExpiresAt, time.Minute)
+
+	p, err := f.svc.AuthenticateAPIKey(ctx, issued.Secret, "10.0.0.5")
+	require.NoError(t, err)
+	require.Equal(t, f.account.ID, p.ServiceAccountID)
+	require.Equal(t, f.admin.TenantID, p.TenantID)
+	require.Equal(t, *f.account.TeamID, p.TeamID)
+	require.Equal(t, []string{"case:view", "evidence:upload"}, p.Scopes)
+	require.True(t, p.HasScope("evidence:upload"))
+	require.False(t, p.HasScope("ioc:create"))
+	require.True(t, p.AllowsCase(f.caseID))
+	require.False(t, p.AllowsCase(uuid.NewString()))
+
+	// Last use is recorded, but not on every request.
+	keys, err := f.svc.ListKeys(f.admin.TenantID, f.account.ID)
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.5", keys[0].LastUsedIP)
+	_, err = f.svc.AuthenticateAPIKey(ctx, issued.Secret, "10.0.0.5")
+	require.NoError(t, err)
+	require.Equal(t, 1, f.repo.Touches)
+
+	_, err = f.svc.AuthenticateAPIKey(ctx, issued.Secret+"x", "10.0.0.5")
+	require.ErrorIs(t, err, apikey.ErrUnauthorized)
+	_, err = f.svc.AuthenticateAPIKey(ctx, "not-a-key", "10.0.0.5")
+	require.ErrorIs(t, err, apikey.ErrUnauthorized)
+}
+
+func TestKeyValidation(t *testing.T) {
+	f := newFixture(t)
+	for name, in := range map[string]apikey.KeyInput{
+		"no scopes":       {Name: "k"},
+		"unknown scope":   {Name: "k", Scopes: []string{"user:delete_everything"}},
+		"foreign case":    {Name: "k", Scopes: []string{"case:view"}, CaseIDs: []string{uuid.NewString()}},
+		"malformed case":  {Name: "k", Scopes: []string{"case:view"}, CaseIDs: []string{"case-1"}},
+		"too long":        {Name: "k", Scopes: []string{"case:view"}, ExpiresInDays: 400},
+		"negative expiry": {Name: "k", Scopes: []string{"case:view"}, ExpiresInDays: -1},
+		"no name":         {Scopes: []string{"case:view"}},
+	} {
+		_, err := f.svc.CreateKey(f.admin, f.account.ID, in)
+		require.ErrorIs(t, err, apikey.ErrInvalidInput, name)
+	}
+
+	other := apikey.Actor{UserID: uuid.NewString(), TenantID: uuid.NewString()}
+	_, err := f.svc.CreateKey(other, f.account.ID, apikey.KeyInput{Name: "k", Scopes: []string{"case:view"}})
+	require.ErrorIs(t, err, apikey.ErrNotFound)
+}
+
+func TestRotateKey(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	issued, err := f.svc.CreateKey(f.admin, f.account.ID, apikey.KeyInput{Name: "iocs", Scopes: []string{"ioc:create"}, ExpiresInDays: 30})
+	require.NoError(t, err)
+
+	rotated, old, err := f.svc.RotateKey(f.admin, f.account.ID, issued.Key.ID, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, rotated.Key.ID, *old.RotatedTo)
+	require.Equal(t, "iocs", rotated.Key.Name)
+	require.JSONEq(t, `["ioc:create"]`, string(rotated.Key.Scopes))
+	require.WithinDuration(t, time.Now().Add(30*24*time.Hour), rotated.Key.ExpiresAt, time.Minute)
+	require.WithinDuration(t, time.Now().Add(time.Hour), old.ExpiresAt, time.Minute)
+
+	// Both keys work during the grace period.
+	_, err = f.svc.AuthenticateAPIKey(ctx, issued.Secret, "")
+	require.NoError(t, err)
+	_, err = f.svc.AuthenticateAPIKey(ctx, rotated.Secret, "")
+	require.NoError(t, err)
+
+	// A rotated key cannot be rotated again.
+	_, _, err = f.svc.RotateKey(f.admin, f.account.ID, issued.Key.ID, 0)
+	require.ErrorIs(t, err, apikey.ErrInactive)
+
+	// Without grace the old key stops at once.
+	again, old, err := f.svc.RotateKey(f.admin, f.account.ID, rotated.Key.ID, 0)
+	require.NoError(t, err)
+	require.NotNil(t, old.RevokedAt)
+	_, err = f.svc.AuthenticateAPIKey(ctx, rotated.Secret, "")
+	require.ErrorIs(t, err, apikey.ErrInactive)
+	_, err = f.svc.AuthenticateAPIKey(ctx, again.Secret, "")
+	require.NoError(t, err)
+
+	_, _, err = f.svc.RotateKey(f.admin, f.account.ID, again.Key.ID, 8*24*time.Hour)
+	require.ErrorIs(t, err, apikey.ErrInvalidInput)
+}
+
+func TestRevokeAndDisable(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	a, err := f.svc.CreateKey(f.admin, f.account.ID, apikey.KeyInput{Name: "a", Scopes: []string{"case:view"}})
+	require.NoError(t, err)
+	b, err := f.svc.CreateKey(f.admin, f.account.ID, apikey.KeyInput{Name: "b", Scopes: []string{"case:view"}})
+	require.NoError(t, err)
+
+	revoked, err := f.svc.RevokeKey(f.admin, f.account.ID, a.Key.ID)
+	require.NoError(t, err)
+	require.NotNil(t, revoked.RevokedAt)
+	_, err = f.svc.AuthenticateAPIKey(ctx, a.Secret, "")
+	require.ErrorIs(t, err, apikey.ErrInactive)
+	_, err = f.svc.RevokeKey(f.admin, f.account.ID, a.Key.ID)
+	require.ErrorIs(t, err, apikey.ErrInactive)
+
+	account, n, err := f.svc.DisableAccount(f.admin, f.account.ID)
+	require.NoError(t, err)
+	require.NotNil(t, account.DisabledAt)
+	require.Equal(t, 1, n)
+	_, err = f.svc.AuthenticateAPIKey(ctx, b.Secret, "")
+	require.Error(t, err)
+	_, err = f.svc.CreateKey(f.admin, f.account.ID, apikey.KeyInput{Name: "c", Scopes: []string{"case:view"}})
+	require.ErrorIs(t, err, apikey.ErrDisabled)
+
+	// A disabled account's name can be reused.
+	_, err = f.svc.CreateAccount(f.admin, apikey.AccountInput{Name: "SOAR"})
+	require.NoError(t, err)
+	accounts, err := f.svc.ListAccounts(f.admin.TenantID)
+	require.NoError(t, err)
+	require.Len(t, accounts, 2)
+	require.True(t, slices.ContainsFunc(accounts, func(a apikey.Account) bool { return a.DisabledAt == nil }))
+}
@@ -0,0 +1,62 @@
+package apikey
+
+import (
+	"context"
+	"time"
+
+	"aegis-api/middleware"
+)
+
+type Repository interface {
+	AutoMigrate() error
+
+	// CreateAccount inserts the account and the users row behind it.
+	CreateAccount(a *Account, email string) error
+	// GetAccount returns nil when the tenant has no such account.
+	GetAccount(tenantID, id string) (*Account, error)
+	ListAccounts(tenantID string) ([]Account, error)
+	// DisableAccount disables the account and revokes its active keys,
+	// returning how many were revoked.
+	DisableAccount(a *Account, by string, at time.Time) (int, error)
+
+	CreateKey(k *Key) error
+	// GetKey returns nil when the account has no such key.
+	GetKey(accountID, id string) (*Key, error)
+	// GetKeyByHash returns nil when no key has the hash.
+	GetKeyByHash(hash string) (*Key, error)
+	ListKeys(accountID string) ([]Key, error)
+	SaveKey(k *Key) error
+	// RotateKey stores the replacement and retires the old key in one
+	// transaction.
+	RotateKey(old, replacement *Key) error
+	TouchKey(id, ip string, at time.Time) error
+
+	// Permissions lists the permission names of enum_role_permissions.
+	Permissions() ([]string, error)
+	// TenantCases returns which of the cases belong to the tenant.
+	TenantCases(tenantID string, caseIDs []string) ([]string, error)
+	// TenantTeam reports whether the team belongs to the tenant.
+	TenantTeam(tenantID, teamID string) (bool, error)
+}
+
+type Service interface {
+	ListAccounts(tenantID string) ([]Account, error)
+	CreateAccount(actor Actor, in AccountInput) (*Account, error)
+	// DisableAccount disables the account and revokes all its keys.
+	DisableAccount(actor Actor, id string) (*Account, int, error)
+
+	ListKeys(tenantID, accountID string) ([]Key, error)
+	CreateKey(actor Actor, accountID string, in KeyInput) (*Issued, error)
+	// RotateKey issues a replacement with the same name and scopes. The
+	// old key keeps working for the grace period, or stops at once when
+	// grace is zero.
+	RotateKey(actor Actor, accountID, keyID string, grace time.Duration) (*Issued, *Key, error)
+	RevokeKey(actor Actor, accountID, keyID string) (*Key, error)
+
+	// Scopes lists the permission names keys can be granted.
+	Scopes() ([]string, error)
+
+	// AuthenticateAPIKey resolves a key to its service account and records
+	// its use. It implements middleware.APIKeyAuthenticator.
+	AuthenticateAPIKey(ctx context.Context, key, ip string) (*middleware.APIKeyPrincipal, error)
+}
@@ -0,0 +1,82 @@
+package apikey
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Account is a tenant-owned service account. It is backed by a users row
+// that cannot sign in, so what it creates is attributed like a user's.
+type Account struct {
+	ID          string     `gorm:"type:uuid;primaryKey" json:"id"` // users.id
+	TenantID    string     `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	TeamID      *string    `gorm:"type:uuid" json:"team_id,omitempty"`
+	Name        string     `gorm:"type:varchar(100);not null" json:"name"`
+	Description string     `gorm:"type:text" json:"description,omitempty"`
+	CreatedBy   string     `gorm:"type:uuid;not null" json:"created_by"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	DisabledAt  *time.Time `json:"disabled_at,omitempty"`
+	DisabledBy  *string    `gorm:"type:uuid" json:"disabled_by,omitempty"`
+}
+
+func (Account) TableName() string { return "service_accounts" }
+
+// Key is a named API key of a service account. Only the SHA-256 of the
+// secret is stored; Prefix identifies the key in lists and logs.
+type Key struct {
+	ID               string         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ServiceAccountID string         `gorm:"type:uuid;not null;index" json:"service_account_id"`
+	TenantID         string         `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	Name             string         `gorm:"type:varchar(100);not null" json:"name"`
+	Prefix           string         `gorm:"type:varchar(24);not null" json:"prefix"`
+	Hash             string         `gorm:"type:char(64);not null;uniqueIndex" json:"-"`
+	Scopes           datatypes.JSON `gorm:"type:jsonb;not null" json:"scopes"`   // []string
+	CaseIDs          datatypes.JSON `gorm:"type:jsonb;not null" json:"case_ids"` // []string; empty = every case
+	ExpiresAt        time.Time      `gorm:"not null" json:"expires_at"`
+	LastUsedAt       *time.Time     `json:"last_used_at,omitempty"`
+	LastUsedIP       string         `gorm:"type:varchar(64)" json:"last_used_ip,omitempty"`
+	CreatedBy        string         `gorm:"type:uuid;not null" json:"created_by"`
+	CreatedAt        time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	// RotatedTo is the key that replaced this one; the old key keeps
+	// working until ExpiresAt, which rotation shortens to the grace period.
+	RotatedTo *string    `gorm:"type:uuid" json:"rotated_to,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy *string    `gorm:"type:uuid" json:"revoked_by,omitempty"`
+}
+
+func (Key) TableName() string { return "api_keys" }
+
+// Active reports whether the key authenticates at t.
+func (k *Key) Active(t time.Time) bool {
+	return k.RevokedAt == nil && t.Before(k.ExpiresAt)
+}
+
+// AccountInput creates a service account.
+type AccountInput struct {
+	Name        string  `json:"name" binding:"required"`
+	Description string  `json:"description"`
+	TeamID      *string `json:"team_id"`
+}
+
+// KeyInput creates a key.
+type KeyInput struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes" binding:"required"`
+	// CaseIDs limits the key to cases of the tenant; empty means every case.
+	CaseIDs []string `json:"case_ids"`
+	// ExpiresInDays defaults to Options.DefaultLifetime.
+	ExpiresInDays int `json:"expires_in_days"`
+}
+
+// Issued is a newly created key with its secret, which is only returned
+// here.
+type Issued struct {
+	Secret string `json:"key"`
+	Key    *Key   `json:"api_key"`
+}
+
+type Actor struct {
+	UserID   string
+	TenantID string
+}
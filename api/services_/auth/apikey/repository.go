@@ -0,0 +1,139 @@
+package apikey
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) AutoMigrate() error {
+	if err := r.db.Exec("ALTER TYPE user_role ADD VALUE IF NOT EXISTS 'Service Account'").Error; err != nil {
+		return err
+	}
+	return r.db.AutoMigrate(&Account{}, &Key{})
+}
+
+func first[T any](q *gorm.DB) (*T, error) {
+	var v T
+	err := q.First(&v).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// CreateAccount inserts the users row first: it owns the ID that evidence,
+// IOCs and audit entries refer to. The row has no usable password.
+func (r *GormRepository) CreateAccount(a *Account, email string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			INSERT INTO users (id, full_name, email, password_hash, role, is_verified, tenant_id, team_id)
+			VALUES (?, ?, ?, '!', 'Service Account', TRUE, ?, ?)`,
+			a.ID, a.Name, email, a.TenantID, a.TeamID).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(a).Error
+	})
+}
+
+func (r *GormRepository) GetAccount(tenantID, id string) (*Account, error) {
+	return first[Account](r.db.Where("tenant_id = ? AND id = ?", tenantID, id))
+}
+
+func (r *GormRepository) ListAccounts(tenantID string) ([]Account, error) {
+	var out []Account
+	err := r.db.Where("tenant_id = ?", tenantID).Order("created_at").Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) DisableAccount(a *Account, by string, at time.Time) (int, error) {
+	var revoked int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(a).Updates(map[string]interface{}{"disabled_at": at, "disabled_by": by}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("UPDATE users SET deactivated_at = ? WHERE id = ?", at, a.ID).Error; err != nil {
+			return err
+		}
+		res := tx.Model(&Key{}).
+			Where("service_account_id = ? AND revoked_at IS NULL AND expires_at > ?", a.ID, at).
+			Updates(map[string]interface{}{"revoked_at": at, "revoked_by": by})
+		revoked = res.RowsAffected
+		return res.Error
+	})
+	return int(revoked), err
+}
+
+func (r *GormRepository) CreateKey(k *Key) error {
+	return r.db.Create(k).Error
+}
+
+func (r *GormRepository) GetKey(accountID, id string) (*Key, error) {
+	return first[Key](r.db.Where("service_account_id = ? AND id = ?", accountID, id))
+}
+
+func (r *GormRepository) GetKeyByHash(hash string) (*Key, error) {
+	return first[Key](r.db.Where("hash = ?", hash))
+}
+
+func (r *GormRepository) ListKeys(accountID string) ([]Key, error) {
+	var out []Key
+	err := r.db.Where("service_account_id = ?", accountID).Order("created_at DESC").Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) SaveKey(k *Key) error {
+	return r.db.Save(k).Error
+}
+
+func (r *GormRepository) RotateKey(old, replacement *Key) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(replacement).Error; err != nil {
+			return err
+		}
+		old.RotatedTo = &replacement.ID
+		return tx.Save(old).Error
+	})
+}
+
+func (r *GormRepository) TouchKey(id, ip string, at time.Time) error {
+	return r.db.Model(&Key{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"last_used_at": at, "last_used_ip": ip}).Error
+}
+
+func (r *GormRepository) Permissions() ([]string, error) {
+	var out []string
+	err := r.db.Raw(`
+		SELECT DISTINCT p.name FROM enum_role_permissions rp
+		JOIN permissions p ON rp.permission_id = p.id
+		ORDER BY p.name`).Scan(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) TenantCases(tenantID string, caseIDs []string) ([]string, error) {
+	var out []string
+	if len(caseIDs) == 0 {
+		return out, nil
+	}
+	err := r.db.Raw("SELECT id::text FROM cases WHERE tenant_id = ? AND id IN ?", tenantID, caseIDs).Scan(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) TenantTeam(tenantID, teamID string) (bool, error) {
+	var n int64
+	err := r.db.Raw("SELECT COUNT(*) FROM teams WHERE tenant_id = ? AND id = ?", tenantID, teamID).Scan(&n).Error
+	return n > 0, err
+}
@@ -0,0 +1,392 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"aegis-api/middleware"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+var (
+	ErrNotFound     = errors.New("service account or key not found")
+	ErrInvalidInput = errors.New("invalid input")
+	ErrDisabled     = errors.New("service account is disabled")
+	ErrInactive     = errors.New("API key is revoked or expired")
+	ErrUnauthorized = errors.New("invalid API key")
+)
+
+// touchEvery throttles last-used updates so busy keys do not write on
+// every request.
+const touchEvery = time.Minute
+
+// Options tunes key lifetimes.
+type Options struct {
+	// DefaultLifetime applies when a key is created without an expiry.
+	// Default: 90 days.
+	DefaultLifetime time.Duration
+	// MaxLifetime caps key expiry. Default: 365 days.
+	MaxLifetime time.Duration
+}
+
+type service struct {
+	repo Repository
+	opts Options
+	now  func() time.Time
+}
+
+func NewService(repo Repository, opts Options) Service {
+	if opts.DefaultLifetime <= 0 {
+		opts.DefaultLifetime = 90 * 24 * time.Hour
+	}
+	if opts.MaxLifetime <= 0 {
+		opts.MaxLifetime = 365 * 24 * time.Hour
+	}
+	return &service{repo: repo, opts: opts, now: time.Now}
+}
+
+func invalid(format string, a ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidInput, fmt.Sprintf(format, a...))
+}
+
+func jsonOf(v interface{}) datatypes.JSON {
+	raw, _ := json.Marshal(v)
+	return datatypes.JSON(raw)
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func stringList(raw datatypes.JSON) []string {
+	var out []string
+	_ = json.Unmarshal(raw, &out)
+	return out
+}
+
+// ─── Service accounts ──────────────────────────────
+
+func (s *service) ListAccounts(tenantID string) ([]Account, error) {
+	return s.repo.ListAccounts(tenantID)
+}
+
+func (s *service) CreateAccount(actor Actor, in AccountInput) (*Account, error) {
+	name := strings.TrimSpace(in.Name)
+	if name == "" || len(name) > 100 {
+		return nil, invalid("name must be 1 to 100 characters")
+	}
+	existing, err := s.repo.ListAccounts(actor.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range existing {
+		if a.DisabledAt == nil && strings.EqualFold(a.Name, name) {
+			return nil, invalid("a service account named %q exists", name)
+		}
+	}
+	if in.TeamID != nil && *in.TeamID != "" {
+		ok, err := s.repo.TenantTeam(actor.TenantID, *in.TeamID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, invalid("team %s is not in the tenant", *in.TeamID)
+		}
+	} else {
+		in.TeamID = nil
+	}
+
+	a := &Account{
+		ID:          uuid.NewString(),
+		TenantID:    actor.TenantID,
+		TeamID:      in.TeamID,
+		Name:        name,
+		Description: strings.TrimSpace(in.Description),
+		CreatedBy:   actor.UserID,
+	}
+	// The users row needs a unique email; .invalid never delivers.
+	email := "svc-" + a.ID + "@service-accounts.invalid"
+	if err := s.repo.CreateAccount(a, email); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *service) account(tenantID, id string) (*Account, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+	a, err := s.repo.GetAccount(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil {
+		return nil, ErrNotFound
+	}
+	return a, nil
+}
+
+func (s *service) DisableAccount(actor Actor, id string) (*Account, int, error) {
+	a, err := s.account(actor.TenantID, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	if a.DisabledAt != nil {
+		return nil, 0, ErrDisabled
+	}
+	now := s.now()
+	revoked, err := s.repo.DisableAccount(a, actor.UserID, now)
+	if err != nil {
+		return nil, 0, err
+	}
+	a.DisabledAt, a.DisabledBy = &now, &actor.UserID
+	return a, revoked, nil
+}
+
+// ─── Keys ──────────────────────────────────────────
+
+func (s *service) ListKeys(tenantID, accountID string) ([]Key, error) {
+	if _, err := s.account(tenantID, accountID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListKeys(accountID)
+}
+
+func (s *service) Scopes() ([]string, error) {
+	return s.repo.Permissions()
+}
+
+// checkScopes validates scopes against the permission catalogue.
+func (s *service) checkScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, invalid("at least one scope is required")
+	}
+	known, err := s.repo.Permissions()
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !slices.Contains(known, scope) {
+			return nil, invalid("unknown scope %q", scope)
+		}
+		if !slices.Contains(out, scope) {
+			out = append(out, scope)
+		}
+	}
+	slices.Sort(out)
+	return out, nil
+}
+
+// checkCases validates that cases belong to the tenant.
+func (s *service) checkCases(tenantID string, caseIDs []string) ([]string, error) {
+	out := []string{}
+	for _, id := range caseIDs {
+		parsed, err := uuid.Parse(strings.TrimSpace(id))
+		if err != nil {
+			return nil, invalid("case %q is not a UUID", id)
+		}
+		if !slices.Contains(out, parsed.String()) {
+			out = append(out, parsed.String())
+		}
+	}
+	found, err := s.repo.TenantCases(tenantID, out)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range out {
+		if !slices.Contains(found, id) {
+			return nil, invalid("case %s is not in the tenant", id)
+		}
+	}
+	return out, nil
+}
+
+// newKey generates a secret and a key row for it.
+func (s *service) newKey(a *Account, createdBy, name string, scopes, caseIDs []string, expires time.Time) (string, *Key, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	key := middleware.APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return key, &Key{
+		ID:               uuid.NewString(),
+		ServiceAccountID: a.ID,
+		TenantID:         a.TenantID,
+		Name:             name,
+		Prefix:           key[:len(middleware.APIKeyPrefix)+8],
+		Hash:             hashKey(key),
+		Scopes:           jsonOf(scopes),
+		CaseIDs:          jsonOf(caseIDs),
+		ExpiresAt:        expires,
+		CreatedBy:        createdBy,
+		CreatedAt:        s.now(),
+	}, nil
+}
+
+func (s *service) CreateKey(actor Actor, accountID string, in KeyInput) (*Issued, error) {
+	a, err := s.account(actor.TenantID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if a.DisabledAt != nil {
+		return nil, ErrDisabled
+	}
+	name := strings.TrimSpace(in.Name)
+	if name == "" || len(name) > 100 {
+		return nil, invalid("name must be 1 to 100 characters")
+	}
+	scopes, err := s.checkScopes(in.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	caseIDs, err := s.checkCases(actor.TenantID, in.CaseIDs)
+	if err != nil {
+		return nil, err
+	}
+	lifetime := s.opts.DefaultLifetime
+	if in.ExpiresInDays < 0 {
+		return nil, invalid("expires_in_days must be positive")
+	}
+	if in.ExpiresInDays > 0 {
+		lifetime = time.Duration(in.ExpiresInDays) * 24 * time.Hour
+	}
+	if lifetime > s.opts.MaxLifetime {
+		return nil, invalid("keys expire within %d days", int(s.opts.MaxLifetime.Hours()/24))
+	}
+
+	secret, k, err := s.newKey(a, actor.UserID, name, scopes, caseIDs, s.now().Add(lifetime))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateKey(k); err != nil {
+		return nil, err
+	}
+	return &Issued{Secret: secret, Key: k}, nil
+}
+
+func (s *service) key(tenantID, accountID, keyID string) (*Account, *Key, error) {
+	a, err := s.account(tenantID, accountID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := uuid.Parse(keyID); err != nil {
+		return nil, nil, ErrNotFound
+	}
+	k, err := s.repo.GetKey(a.ID, keyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if k == nil {
+		return nil, nil, ErrNotFound
+	}
+	return a, k, nil
+}
+
+func (s *service) RotateKey(actor Actor, accountID, keyID string, grace time.Duration) (*Issued, *Key, error) {
+	a, old, err := s.key(actor.TenantID, accountID, keyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if a.DisabledAt != nil {
+		return nil, nil, ErrDisabled
+	}
+	now := s.now()
+	if !old.Active(now) || old.RotatedTo != nil {
+		return nil, nil, ErrInactive
+	}
+	if grace < 0 || grace > 7*24*time.Hour {
+		return nil, nil, invalid("the grace period is at most 7 days")
+	}
+
+	// The replacement lives as long as the old key was issued for.
+	lifetime := old.ExpiresAt.Sub(old.CreatedAt)
+	if lifetime <= 0 || lifetime > s.opts.MaxLifetime {
+		lifetime = s.opts.DefaultLifetime
+	}
+	secret, k, err := s.newKey(a, actor.UserID, old.Name, stringList(old.Scopes), stringList(old.CaseIDs), now.Add(lifetime))
+	if err != nil {
+		return nil, nil, err
+	}
+	if grace == 0 {
+		old.RevokedAt, old.RevokedBy = &now, &actor.UserID
+	} else if until := now.Add(grace); until.Before(old.ExpiresAt) {
+		old.ExpiresAt = until
+	}
+	if err := s.repo.RotateKey(old, k); err != nil {
+		return nil, nil, err
+	}
+	return &Issued{Secret: secret, Key: k}, old, nil
+}
+
+func (s *service) RevokeKey(actor Actor, accountID, keyID string) (*Key, error) {
+	_, k, err := s.key(actor.TenantID, accountID, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if k.RevokedAt != nil {
+		return nil, ErrInactive
+	}
+	now := s.now()
+	k.RevokedAt, k.RevokedBy = &now, &actor.UserID
+	if err := s.repo.SaveKey(k); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// ─── Authentication ────────────────────────────────
+
+func (s *service) AuthenticateAPIKey(ctx context.Context, key, ip string) (*middleware.APIKeyPrincipal, error) {
+	if !strings.HasPrefix(key, middleware.APIKeyPrefix) {
+		return nil, ErrUnauthorized
+	}
+	k, err := s.repo.GetKeyByHash(hashKey(key))
+	if err != nil {
+		return nil, err
+	}
+	if k == nil {
+		return nil, ErrUnauthorized
+	}
+	now := s.now()
+	if !k.Active(now) {
+		return nil, fmt.Errorf("%w: %s", ErrInactive, k.Prefix)
+	}
+	a, err := s.repo.GetAccount(k.TenantID, k.ServiceAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil || a.DisabledAt != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDisabled, k.Prefix)
+	}
+	if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= touchEvery || k.LastUsedIP != ip {
+		_ = s.repo.TouchKey(k.ID, ip, now)
+	}
+
+	p := &middleware.APIKeyPrincipal{
+		KeyID:            k.ID,
+		KeyPrefix:        k.Prefix,
+		ServiceAccountID: a.ID,
+		Name:             a.Name,
+		TenantID:         a.TenantID,
+		Scopes:           stringList(k.Scopes),
+		CaseIDs:          stringList(k.CaseIDs),
+	}
+	if a.TeamID != nil {
+		p.TeamID = *a.TeamID
+	}
+	return p, nil
+}
@@ -0,0 +1,256 @@
+package authz_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"aegis-api/middleware"
+	"aegis-api/services_/auth/authz"
+	"aegis-api/tests/fakes"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type fixture struct {
+	repo   *fakes.Authz
+	svc    authz.Service
+	tenant string
+	team   string
+	caseID string
+	author string
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	repo := &fakes.Authz{}
+	perms := fakes.RolePermissions{
+		"Forensic Analyst":      {"case:view", "case:update", "evidence:view"},
+		"External Collaborator": {"case:view", "evidence:view"},
+		"Legal Counsel":         {"case:view"},
+	}
+	f := &fixture{
+		repo:   repo,
+		tenant: uuid.NewString(),
+		team:   uuid.NewString(),
+		caseID: uuid.NewString(),
+		author: uuid.NewString(),
+	}
+	f.svc = authz.NewService(repo, perms, authz.Options{
+		Resolvers: map[string]authz.CaseResolver{
+			middleware.ResourceChatGroup: func(ctx context.Context, id string) (string, error) {
+				if id == "group-1" {
+					return f.caseID, nil
+				}
+				return "", nil
+			},
+		},
+	})
+	repo.AddCase(&authz.Case{ID: f.caseID, TenantID: &f.tenant, TeamID: &f.team, CreatedBy: &f.author})
+	return f
+}
+
+func (f *fixture) request(userID, role, action, resourceType, resourceID string) middleware.AccessRequest {
+	return middleware.AccessRequest{
+		UserID:       userID,
+		Role:         role,
+		TenantID:     f.tenant,
+		TeamID:       f.team,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Method:       "GET",
+		Path:         "/api/v1/test",
+		IP:           "10.0.0.1",
+	}
+}
+
+func (f *fixture) decide(t *testing.T, req middleware.AccessRequest) *middleware.AccessDecision {
+	t.Helper()
+	d, err := f.svc.AuthorizeCaseAccess(context.Background(), req)
+	require.NoError(t, err)
+	return d
+}
+
+func TestMembershipAndCaseRole(t *testing.T) {
+	f := newFixture(t)
+	analyst := uuid.NewString()
+
+	// A team member who is not on the case is refused
+	d := f.decide(t, f.request(analyst, "Forensic Analyst", "evidence:view", middleware.ResourceCase, f.caseID))
+	require.False(t, d.Allowed)
+	require.Equal(t, authz.ReasonNotMember, d.Reason)
+
+	// Assigned as Legal Counsel, the case role decides, not the global one
+	f.repo.Assign(f.caseID, analyst, "Legal Counsel")
+	d = f.decide(t, f.request(analyst, "Forensic Analyst", "case:view", middleware.ResourceCase, f.caseID))
+	require.True(t, d.Allowed)
+	require.Equal(t, "Legal Counsel", d.CaseRole)
+	d = f.decide(t, f.request(analyst, "Forensic Analyst", "evidence:view", middleware.ResourceCase, f.caseID))
+	require.False(t, d.Allowed)
+	require.Equal(t, authz.ReasonRoleLacksAction, d.Reason)
+
+	// The author acts with their global role
+	d = f.decide(t, f.request(f.author, "Forensic Analyst", "case:update", middleware.ResourceCase, f.caseID))
+	require.True(t, d.Allowed)
+	require.Equal(t, authz.GrantCreator, d.Reason)
+
+	require.Len(t, f.repo.Denials, 2)
+	require.Equal(t, f.caseID, *f.repo.Denials[0].CaseID)
+	require.Equal(t, "10.0.0.1", f.repo.Denials[0].IP)
+}
+
+func TestCollaboratorExpiry(t *testing.T) {
+	f := newFixture(t)
+	external := uuid.NewString()
+	later := time.Now().Add(time.Hour)
+	collab := &authz.Collaborator{
+		CaseID: f.caseID, UserID: external, Role: "External Collaborator", Status: "active", ExpiresAt: &later,
+	}
+	f.repo.AddCollaborator(collab)
+	req := f.request(external, "External Collaborator", "evidence:view", middleware.ResourceCase, f.caseID)
+	req.TeamID = ""
+
+	d := f.decide(t, req)
+	require.True(t, d.Allowed)
+	require.Equal(t, authz.GrantCollaborator, d.Reason)
+
+	earlier := time.Now().Add(-time.Minute)
+	collab.ExpiresAt = &earlier
+	d = f.decide(t, req)
+	require.False(t, d.Allowed)
+	require.Equal(t, authz.ReasonCollabExpired, d.Reason)
+
+	collab.Status = "revoked"
+	d = f.decide(t, req)
+	require.Equal(t, authz.ReasonCollabRevoked, d.Reason)
+
+	// Someone from another team who was never shared the case
+	req = f.request(uuid.NewString(), "Forensic Analyst", "case:view", middleware.ResourceCase, f.caseID)
+	req.TeamID = uuid.NewString()
+	d = f.decide(t, req)
+	require.Equal(t, authz.ReasonTeamBoundary, d.Reason)
+}
+
+func TestTenantAndTeamBoundaries(t *testing.T) {
+	f := newFixture(t)
+
+	req := f.request(uuid.NewString(), "Tenant Admin", "evidence:view", middleware.ResourceCase, f.caseID)
+	require.True(t, f.decide(t, req).Allowed)
+	req.TenantID = uuid.NewString()
+	d := f.decide(t, req)
+	require.False(t, d.Allowed)
+	require.Equal(t, authz.ReasonTenantMismatch, d.Reason)
+
+	// A DFIR Admin oversees their own team's cases only
+	req = f.request(uuid.NewString(), "DFIR Admin", "evidence:view", middleware.ResourceCase, f.caseID)
+	require.True(t, f.decide(t, req).Allowed)
+	req.TeamID = uuid.NewString()
+	require.Equal(t, authz.ReasonTeamBoundary, f.decide(t, req).Reason)
+
+	req.TenantID = ""
+	require.Equal(t, authz.ReasonNoTenant, f.decide(t, req).Reason)
+}
+
+func TestResourcesResolveToTheirCase(t *testing.T) {
+	f := newFixture(t)
+	analyst := uuid.NewString()
+	f.repo.Assign(f.caseID, analyst, "Forensic Analyst")
+	evidenceID := uuid.NewString()
+	f.repo.Place(middleware.ResourceEvidence, evidenceID, f.caseID)
+
+	d := f.decide(t, f.request(analyst, "Forensic Analyst", "evidence:view", middleware.ResourceEvidence, evidenceID))
+	require.True(t, d.Allowed)
+	require.Equal(t, f.caseID, d.CaseID)
+
+	d = f.decide(t, f.request(analyst, "Forensic Analyst", "case:view", middleware.ResourceChatGroup, "group-1"))
+	require.True(t, d.Allowed)
+
+	// Resources named only by a child ID resolve through their parent
+	for _, rt := range []string{middleware.ResourceMessage, middleware.ResourceRedactionMark,
+		middleware.ResourceAISuggestion, middleware.ResourceReportJobItem} {
+		id := uuid.NewString()
+		f.repo.Place(rt, id, f.caseID)
+		d = f.decide(t, f.request(analyst, "Forensic Analyst", "case:view", rt, id))
+		require.True(t, d.Allowed, rt)
+		require.Equal(t, f.caseID, d.CaseID, rt)
+	}
+
+	// Missing resources are not found rather than denied, and not logged
+	d = f.decide(t, f.request(analyst, "Forensic Analyst", "evidence:view", middleware.ResourceEvidence, uuid.NewString()))
+	require.True(t, d.NotFound)
+	d = f.decide(t, f.request(analyst, "Forensic Analyst", "evidence:view", middleware.ResourceReport, "not-a-uuid"))
+	require.True(t, d.NotFound)
+	d = f.decide(t, f.request(analyst, "Forensic Analyst", "case:view", middleware.ResourceChatGroup, "group-2"))
+	require.True(t, d.NotFound)
+	require.Empty(t, f.repo.Denials)
+}
+
+func TestAPIKeyCaseScope(t *testing.T) {
+	f := newFixture(t)
+	req := f.request(uuid.NewString(), middleware.ServiceAccountRole, "evidence:view", middleware.ResourceCase, f.caseID)
+	req.APIKey = &middleware.APIKeyPrincipal{KeyID: uuid.NewString(), TenantID: f.tenant, Scopes: []string{"evidence:view"}}
+	require.True(t, f.decide(t, req).Allowed)
+
+	req.APIKey.CaseIDs = []string{uuid.NewString()}
+	d := f.decide(t, req)
+	require.False(t, d.Allowed)
+	require.Equal(t, authz.ReasonKeyCaseScope, d.Reason)
+	require.Equal(t, req.APIKey.KeyID, *f.repo.Denials[0].APIKeyID)
+
+	denials, err := f.svc.ListDenials(f.tenant, authz.DenialFilter{})
+	require.NoError(t, err)
+	require.Len(t, denials, 1)
+	_, err = f.svc.ListDenials(f.tenant, authz.DenialFilter{CaseID: "x"})
+	require.ErrorIs(t, err, authz.ErrInvalidInput)
+}
+
+// evidenceOnly admits guests to a single piece of evidence.
+type evidenceOnly struct{ id string }
+
+func (g evidenceOnly) AuthorizeGuest(ctx context.Context, req middleware.AccessRequest, caseID string) (*authz.GuestDecision, error) {
+	if req.ResourceType == middleware.ResourceEvidence && req.ResourceID == g.id {
+		return &authz.GuestDecision{Allowed: true, Watermark: "Shared with guest"}, nil
+	}
+	return &authz.GuestDecision{Reason: "outside_invitation_scope"}, nil
+}
+
+func TestGuestPolicyNarrowsCollaborators(t *testing.T) {
+	repo := &fakes.Authz{}
+	tenant, caseID, external, member := uuid.NewString(), uuid.NewString(), uuid.NewString(), uuid.NewString()
+	evidenceID, other := uuid.NewString(), uuid.NewString()
+	repo.AddCase(&authz.Case{ID: caseID, TenantID: &tenant})
+	repo.Place(middleware.ResourceEvidence, evidenceID, caseID)
+	repo.Place(middleware.ResourceEvidence, other, caseID)
+	collab := &authz.Collaborator{CaseID: caseID, UserID: external, Role: "External Collaborator", Status: "scheduled"}
+	repo.AddCollaborator(collab)
+	repo.Assign(caseID, member, "Forensic Analyst")
+	svc := authz.NewService(repo, fakes.RolePermissions{
+		"Forensic Analyst":      {"evidence:view"},
+		"External Collaborator": {"evidence:view"},
+	}, authz.Options{Guests: evidenceOnly{id: evidenceID}})
+	decide := func(userID, resourceID string) *middleware.AccessDecision {
+		d, err := svc.AuthorizeCaseAccess(context.Background(), middleware.AccessRequest{
+			UserID: userID, Role: "External Collaborator", TenantID: tenant,
+			Action: "evidence:view", ResourceType: middleware.ResourceEvidence, ResourceID: resourceID,
+		})
+		require.NoError(t, err)
+		return d
+	}
+
+	require.Equal(t, authz.ReasonCollabScheduled, decide(external, evidenceID).Reason)
+
+	collab.Status = "active"
+	d := decide(external, evidenceID)
+	require.True(t, d.Allowed)
+	require.Equal(t, "Shared with guest", d.Watermark)
+	d = decide(external, other)
+	require.False(t, d.Allowed)
+	require.Equal(t, "outside_invitation_scope", d.Reason)
+
+	// Assigned members are not guests
+	d = decide(member, other)
+	require.True(t, d.Allowed)
+	require.Empty(t, d.Watermark)
+}
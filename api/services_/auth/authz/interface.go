@@ -0,0 +1,50 @@
+package authz
+
+import (
+	"context"
+
+	"aegis-api/middleware"
+)
+
+type Repository interface {
+	AutoMigrate() error
+
+	// CaseOf returns the case a resource belongs to, or "" when there is
+	// no such resource. It covers the resource types stored in Postgres.
+	CaseOf(resourceType, id string) (string, error)
+	// GetCase returns nil when there is no such case.
+	GetCase(id string) (*Case, error)
+	// AssignedRole returns the user's case_user_roles role, or "" when
+	// the user is not assigned to the case.
+	AssignedRole(caseID, userID string) (string, error)
+	// GetCollaborator returns nil when the user was never shared the case.
+	GetCollaborator(caseID, userID string) (*Collaborator, error)
+
+	CreateDenial(d *Denial) error
+	ListDenials(tenantID string, f DenialFilter) ([]Denial, error)
+}
+
+// CaseResolver finds the case of a resource stored outside Postgres; it
+// returns "" when there is no such resource.
+type CaseResolver func(ctx context.Context, id string) (string, error)
+
+// GuestPolicy narrows what a collaborator may do beyond their case role,
+// e.g. to the evidence, reports and dates of an invitation.
+type GuestPolicy interface {
+	AuthorizeGuest(ctx context.Context, req middleware.AccessRequest, caseID string) (*GuestDecision, error)
+}
+
+// GuestDecision is a GuestPolicy's verdict on a collaborator's request.
+type GuestDecision struct {
+	Allowed bool
+	Reason  string
+	// Watermark is printed on documents the collaborator downloads.
+	Watermark string
+}
+
+type Service interface {
+	// AuthorizeCaseAccess implements middleware.CaseAuthorizer. Denials
+	// are recorded.
+	AuthorizeCaseAccess(ctx context.Context, req middleware.AccessRequest) (*middleware.AccessDecision, error)
+	ListDenials(tenantID string, f DenialFilter) ([]Denial, error)
+}
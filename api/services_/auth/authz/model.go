@@ -0,0 +1,79 @@
+package authz
+
+import "time"
+
+// Reasons a request is denied.
+const (
+	ReasonNoTenant        = "no_tenant"
+	ReasonTenantMismatch  = "tenant_mismatch"
+	ReasonTeamBoundary    = "team_boundary"
+	ReasonNotMember       = "not_a_member"
+	ReasonCollabExpired   = "collaborator_expired"
+	ReasonCollabRevoked   = "collaborator_revoked"
+	ReasonCollabScheduled = "collaborator_not_started"
+	ReasonRoleLacksAction = "role_lacks_permission"
+	ReasonKeyCaseScope    = "api_key_case_scope"
+	ReasonUnknownResource = "unknown_resource_type"
+)
+
+// Reasons a request is allowed.
+const (
+	GrantTenantAdmin  = "tenant_admin"
+	GrantTeamAdmin    = "team_admin"
+	GrantAssigned     = "assigned"
+	GrantCollaborator = "collaborator"
+	GrantCreator      = "creator"
+	GrantAPIKey       = "api_key"
+)
+
+// Case is the part of a case access decisions depend on.
+type Case struct {
+	ID        string
+	TenantID  *string
+	TeamID    *string
+	CreatedBy *string
+}
+
+// Collaborator is a case_collaborators row: a per-case role granted by
+// sharing, which lapses at ExpiresAt or when its status changes. A
+// "scheduled" share has not started yet.
+type Collaborator struct {
+	CaseID    string     `gorm:"type:uuid;primaryKey" json:"case_id"`
+	UserID    string     `gorm:"type:uuid;primaryKey" json:"user_id"`
+	Role      string     `json:"role"`
+	Status    string     `json:"status"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func (Collaborator) TableName() string { return "case_collaborators" }
+
+// Denial records a refused request. Only denials are kept: allowed
+// requests are the common case and the audit log covers what they did.
+type Denial struct {
+	ID           string    `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID     *string   `gorm:"type:uuid;index" json:"tenant_id,omitempty"`
+	UserID       string    `gorm:"type:uuid;index" json:"user_id"`
+	Role         string    `json:"role"`
+	APIKeyID     *string   `gorm:"type:uuid" json:"api_key_id,omitempty"`
+	Action       string    `json:"action"`
+	ResourceType string    `json:"resource_type"`
+	ResourceID   string    `json:"resource_id"`
+	CaseID       *string   `gorm:"type:uuid;index" json:"case_id,omitempty"`
+	CaseRole     string    `json:"case_role,omitempty"`
+	Reason       string    `json:"reason"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	IP           string    `json:"ip"`
+	CreatedAt    time.Time `gorm:"index" json:"created_at"`
+}
+
+func (Denial) TableName() string { return "access_denials" }
+
+// DenialFilter narrows ListDenials. Empty fields match everything.
+type DenialFilter struct {
+	UserID string
+	CaseID string
+	Reason string
+	Since  *time.Time
+	Limit  int
+}
@@ -0,0 +1,112 @@
+package authz
+
+import (
+	"errors"
+
+	"aegis-api/middleware"
+
+	"gorm.io/gorm"
+)
+
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &GormRepository{db: db}
+}
+
+// case_collaborators predates this package and is managed by schema.sql.
+func (r *GormRepository) AutoMigrate() error {
+	return r.db.AutoMigrate(&Denial{})
+}
+
+func first[T any](q *gorm.DB) (*T, error) {
+	var v T
+	err := q.First(&v).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// caseOf maps resource types to the query finding their case.
+var caseOf = map[string]string{
+	middleware.ResourceEvidence:      "SELECT case_id::text FROM evidence WHERE id = ?",
+	middleware.ResourceTimelineEvent: "SELECT case_id::text FROM timeline_events WHERE id = ? AND deleted_at IS NULL",
+	middleware.ResourceReport:        "SELECT case_id::text FROM reports WHERE id = ?",
+	middleware.ResourceThread:        "SELECT case_id::text FROM annotation_threads WHERE id = ?",
+	middleware.ResourceMessage: `SELECT t.case_id::text FROM thread_messages m
+		JOIN annotation_threads t ON t.id = m.thread_id WHERE m.id = ?`,
+	middleware.ResourceRedactionMark: "SELECT case_id::text FROM redaction_marks WHERE id = ?",
+	middleware.ResourceAISuggestion: `SELECT r.case_id::text FROM report_ai_suggestions s
+		JOIN reports r ON r.id = s.report_id WHERE s.id = ?`,
+	middleware.ResourceReportJobItem: "SELECT case_id::text FROM report_job_items WHERE id = ?",
+}
+
+func (r *GormRepository) CaseOf(resourceType, id string) (string, error) {
+	query, ok := caseOf[resourceType]
+	if !ok {
+		return "", nil
+	}
+	var caseIDs []string
+	if err := r.db.Raw(query, id).Scan(&caseIDs).Error; err != nil {
+		return "", err
+	}
+	if len(caseIDs) == 0 {
+		return "", nil
+	}
+	return caseIDs[0], nil
+}
+
+func (r *GormRepository) GetCase(id string) (*Case, error) {
+	var rows []Case
+	err := r.db.Raw(`
+		SELECT id::text, tenant_id::text, team_id::text, created_by::text
+		FROM cases WHERE id = ?`, id).Scan(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return &rows[0], nil
+}
+
+func (r *GormRepository) AssignedRole(caseID, userID string) (string, error) {
+	var roles []string
+	err := r.db.Raw("SELECT role::text FROM case_user_roles WHERE case_id = ? AND user_id = ?", caseID, userID).
+		Scan(&roles).Error
+	if err != nil || len(roles) == 0 {
+		return "", err
+	}
+	return roles[0], nil
+}
+
+func (r *GormRepository) GetCollaborator(caseID, userID string) (*Collaborator, error) {
+	return first[Collaborator](r.db.Select("case_id, user_id, role::text AS role, status, expires_at").
+		Where("case_id = ? AND user_id = ?", caseID, userID))
+}
+
+func (r *GormRepository) CreateDenial(d *Denial) error {
+	return r.db.Create(d).Error
+}
+
+func (r *GormRepository) ListDenials(tenantID string, f DenialFilter) ([]Denial, error) {
+	q := r.db.Where("tenant_id = ?", tenantID)
+	if f.UserID != "" {
+		q = q.Where("user_id = ?", f.UserID)
+	}
+	if f.CaseID != "" {
+		q = q.Where("case_id = ?", f.CaseID)
+	}
+	if f.Reason != "" {
+		q = q.Where("reason = ?", f.Reason)
+	}
+	if f.Since != nil {
+		q = q.Where("created_at >= ?", *f.Since)
+	}
+	var out []Denial
+	err := q.Order("created_at DESC").Limit(f.Limit).Find(&out).Error
+	return out, err
+}
@@ -0,0 +1,245 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"aegis-api/middleware"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidInput = errors.New("invalid input")
+
+// Roles that oversee cases without being members: a Tenant Admin every
+// case of the tenant, a DFIR Admin every case of their team.
+const (
+	tenantAdminRole = "Tenant Admin"
+	teamAdminRole   = "DFIR Admin"
+)
+
+// Options wires resource types the repository cannot resolve.
+type Options struct {
+	// Resolvers find the case of resources stored outside Postgres, by
+	// resource type, e.g. chat groups.
+	Resolvers map[string]CaseResolver
+	// MaxDenials caps ListDenials. Default: 500.
+	MaxDenials int
+	// Guests, when set, is consulted for every request a collaborator's
+	// case role allows.
+	Guests GuestPolicy
+}
+
+type service struct {
+	repo        Repository
+	permissions middleware.PermissionChecker
+	opts        Options
+	now         func() time.Time
+}
+
+// NewService returns the case authorizer. permissions decides what a case
+// role may do, so a member's per-case role is checked the same way
+// RequirePermission checks global roles.
+func NewService(repo Repository, permissions middleware.PermissionChecker, opts Options) Service {
+	if opts.MaxDenials <= 0 {
+		opts.MaxDenials = 500
+	}
+	return &service{repo: repo, permissions: permissions, opts: opts, now: time.Now}
+}
+
+func (s *service) ListDenials(tenantID string, f DenialFilter) ([]Denial, error) {
+	for _, id := range []string{f.UserID, f.CaseID} {
+		if _, err := uuid.Parse(id); id != "" && err != nil {
+			return nil, fmt.Errorf("%w: %q is not a UUID", ErrInvalidInput, id)
+		}
+	}
+	if f.Limit <= 0 || f.Limit > s.opts.MaxDenials {
+		f.Limit = s.opts.MaxDenials
+	}
+	return s.repo.ListDenials(tenantID, f)
+}
+
+// caseOf resolves the resource to its case, "" when it does not exist.
+func (s *service) caseOf(ctx context.Context, resourceType, id string) (string, bool, error) {
+	if resolve, ok := s.opts.Resolvers[resourceType]; ok {
+		caseID, err := resolve(ctx, id)
+		return caseID, true, err
+	}
+	switch resourceType {
+	case middleware.ResourceCase:
+		if _, err := uuid.Parse(id); err != nil {
+			return "", true, nil
+		}
+		return strings.ToLower(id), true, nil
+	case middleware.ResourceEvidence, middleware.ResourceTimelineEvent,
+		middleware.ResourceReport, middleware.ResourceThread, middleware.ResourceMessage,
+		middleware.ResourceRedactionMark, middleware.ResourceAISuggestion, middleware.ResourceReportJobItem:
+		if _, err := uuid.Parse(id); err != nil {
+			return "", true, nil
+		}
+		caseID, err := s.repo.CaseOf(resourceType, id)
+		return caseID, true, err
+	}
+	return "", false, nil
+}
+
+func (s *service) AuthorizeCaseAccess(ctx context.Context, req middleware.AccessRequest) (*middleware.AccessDecision, error) {
+	d, err := s.decide(ctx, req)
+	if err != nil || d.Allowed || d.NotFound {
+		return d, err
+	}
+	s.recordDenial(req, d)
+	return d, nil
+}
+
+func (s *service) decide(ctx context.Context, req middleware.AccessRequest) (*middleware.AccessDecision, error) {
+	deny := func(reason string) *middleware.AccessDecision {
+		return &middleware.AccessDecision{Reason: reason}
+	}
+	if req.TenantID == "" {
+		return deny(ReasonNoTenant), nil
+	}
+
+	caseID, known, err := s.caseOf(ctx, req.ResourceType, req.ResourceID)
+	if err != nil {
+		return nil, err
+	}
+	if !known {
+		return deny(ReasonUnknownResource), nil
+	}
+	if caseID == "" {
+		return &middleware.AccessDecision{NotFound: true}, nil
+	}
+	cs, err := s.repo.GetCase(caseID)
+	if err != nil {
+		return nil, err
+	}
+	if cs == nil {
+		return &middleware.AccessDecision{NotFound: true}, nil
+	}
+	d := &middleware.AccessDecision{CaseID: cs.ID}
+	if cs.TenantID == nil || *cs.TenantID != req.TenantID {
+		d.Reason = ReasonTenantMismatch
+		return d, nil
+	}
+
+	// API keys have no case role: AuthMiddleware checked their scopes
+	// against the endpoint, which leaves the cases they are limited to.
+	if key := req.APIKey; key != nil {
+		if key.AllowsCase(cs.ID) {
+			d.Allowed, d.Reason = true, GrantAPIKey
+		} else {
+			d.Reason = ReasonKeyCaseScope
+		}
+		return d, nil
+	}
+
+	sameTeam := cs.TeamID != nil && *cs.TeamID == req.TeamID
+	switch {
+	case req.Role == tenantAdminRole:
+		d.Allowed, d.CaseRole, d.Reason = true, req.Role, GrantTenantAdmin
+		return d, nil
+	case req.Role == teamAdminRole && sameTeam:
+		d.Allowed, d.CaseRole, d.Reason = true, req.Role, GrantTeamAdmin
+		return d, nil
+	}
+
+	role, grant, reason, err := s.caseRole(cs, req.UserID, req.Role)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		if reason == ReasonNotMember && !sameTeam {
+			reason = ReasonTeamBoundary
+		}
+		d.Reason = reason
+		return d, nil
+	}
+	d.CaseRole = role
+	ok, err := s.permissions.RoleHasPermission(role, req.Action)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		d.Reason = ReasonRoleLacksAction
+		return d, nil
+	}
+	if grant == GrantCollaborator && s.opts.Guests != nil {
+		g, err := s.opts.Guests.AuthorizeGuest(ctx, req, cs.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !g.Allowed {
+			d.Reason = g.Reason
+			return d, nil
+		}
+		d.Watermark = g.Watermark
+	}
+	d.Allowed, d.Reason = true, grant
+	return d, nil
+}
+
+// caseRole returns the role the user holds on the case and how they hold
+// it. An assignment or authorship outranks a share, so an expired share
+// does not lock out a team member. Without a role, reason says why.
+func (s *service) caseRole(cs *Case, userID, globalRole string) (role, grant, reason string, err error) {
+	assigned, err := s.repo.AssignedRole(cs.ID, userID)
+	if err != nil {
+		return "", "", "", err
+	}
+	if assigned != "" {
+		return assigned, GrantAssigned, "", nil
+	}
+	if cs.CreatedBy != nil && *cs.CreatedBy == userID {
+		return globalRole, GrantCreator, "", nil
+	}
+	collab, err := s.repo.GetCollaborator(cs.ID, userID)
+	if err != nil {
+		return "", "", "", err
+	}
+	switch {
+	case collab == nil:
+		return "", "", ReasonNotMember, nil
+	case collab.Status == "revoked":
+		return "", "", ReasonCollabRevoked, nil
+	case collab.Status == "scheduled":
+		return "", "", ReasonCollabScheduled, nil
+	case collab.Status != "active", collab.ExpiresAt != nil && !s.now().Before(*collab.ExpiresAt):
+		return "", "", ReasonCollabExpired, nil
+	}
+	return collab.Role, GrantCollaborator, "", nil
+}
+
+func (s *service) recordDenial(req middleware.AccessRequest, d *middleware.AccessDecision) {
+	denial := &Denial{
+		ID:           uuid.NewString(),
+		UserID:       req.UserID,
+		Role:         req.Role,
+		Action:       req.Action,
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceID,
+		CaseRole:     d.CaseRole,
+		Reason:       d.Reason,
+		Method:       req.Method,
+		Path:         req.Path,
+		IP:           req.IP,
+		CreatedAt:    s.now(),
+	}
+	if req.TenantID != "" {
+		denial.TenantID = &req.TenantID
+	}
+	if req.APIKey != nil {
+		denial.APIKeyID = &req.APIKey.KeyID
+	}
+	if d.CaseID != "" {
+		denial.CaseID = &d.CaseID
+	}
+	// A failed write must not turn a denial into an error response.
+	if err := s.repo.CreateDenial(denial); err != nil {
+		log.Printf("[ERROR] authz: recording denial for user %s: %v", req.UserID, err)
+	}
+}
@@ -7,3 +7,9 @@ type UserRepository interface {
 	GetUserByID(userID string) (*registration.User, error)
 	UpdateUserTokenInfo(user *registration.User) error
 }
+
+// PasswordPolicy decides whether a user may sign in with a password,
+// e.g. when their tenant requires SSO.
+type PasswordPolicy interface {
+	LocalPasswordAllowed(tenantID, role string) (bool, error)
+}
@@ -0,0 +1,240 @@
+package login
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"aegis-api/services_/auth/mfa_policy"
+	"aegis-api/services_/auth/registration"
+	"aegis-api/services_/auth/session"
+	mfa "aegis-api/services_/auth/verification"
+	"aegis-api/services_/auth/webauthn"
+)
+
+// mfaChallenge answers the password step of a login that needs a second
+// factor.
+func (s *AuthService) mfaChallenge(user *registration.User, purpose string, methods []string) (*LoginResponse, error) {
+	exp := time.Now().Add(mfaTokenTTL)
+	token, _, err := GenerateMFAToken(user.ID.String(), purpose, exp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token")
+	}
+	return &LoginResponse{
+		ID:                    user.ID.String(),
+		Email:                 user.Email,
+		Role:                  user.Role,
+		IsVerified:            user.IsVerified,
+		MFARequired:           purpose == MFAPurposeVerify,
+		MFAEnrollmentRequired: purpose == MFAPurposeEnroll,
+		MFAToken:              token,
+		MFATokenExpiresAt:     &exp,
+		MFAMethods:            methods,
+	}, nil
+}
+
+// mfaUser resolves a pre-auth token to its user, who must still be
+// allowed in.
+func (s *AuthService) mfaUser(mfaToken, purpose string) (*registration.User, mfa_policy.Actor, string, error) {
+	userID, challengeID, err := ParseMFAToken(mfaToken, purpose)
+	if err != nil {
+		return nil, mfa_policy.Actor{}, "", err
+	}
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil || user == nil {
+		return nil, mfa_policy.Actor{}, "", ErrInvalidMFAToken
+	}
+	if accessEnded(user) {
+		return nil, mfa_policy.Actor{}, "", ErrAccessRevoked
+	}
+	tenantID, _ := userScope(user)
+	return user, mfa_policy.Actor{UserID: userID, TenantID: tenantID, Role: user.Role}, challengeID, nil
+}
+
+// CompleteMFA finishes a login with a TOTP or recovery code and starts
+// the session. Having just verified, the user also holds step-up.
+func (s *AuthService) CompleteMFA(ctx context.Context, mfaToken, code string, client session.Client) (*LoginResponse, error) {
+	user, actor, challengeID, err := s.mfaUser(mfaToken, MFAPurposeVerify)
+	if err != nil {
+		return nil, err
+	}
+	method, err := s.mfa.Verify(ctx, challengeID, actor, code)
+	if err != nil {
+		return nil, err
+	}
+	return s.completeLogin(ctx, user, actor, challengeID, client, method)
+}
+
+// completeLogin starts the session of a user who has just passed MFA and
+// so also holds step-up. The pre-auth token's challenge is used up: the
+// token cannot start another session.
+func (s *AuthService) completeLogin(ctx context.Context, user *registration.User, actor mfa_policy.Actor, challengeID string, client session.Client, method string) (*LoginResponse, error) {
+	if err := s.mfa.CompleteChallenge(ctx, challengeID); err != nil {
+		return nil, err
+	}
+	stepUp := time.Now().Add(s.mfa.StepUpTTL(actor.TenantID))
+	resp, err := s.startSession(user, client, &stepUp)
+	if err != nil {
+		return nil, err
+	}
+	resp.MFAMethod = method
+	return resp, nil
+}
+
+// BeginPasskeyMFA issues a WebAuthn challenge for the second step of
+// login.
+func (s *AuthService) BeginPasskeyMFA(ctx context.Context, mfaToken string) (*webauthn.RequestOptions, error) {
+	_, actor, _, err := s.mfaUser(mfaToken, MFAPurposeVerify)
+	if err != nil {
+		return nil, err
+	}
+	return s.passkeys.BeginAssertion(ctx, actor.UserID, actor.TenantID)
+}
+
+// CompletePasskeyMFA finishes a login with a passkey assertion.
+func (s *AuthService) CompletePasskeyMFA(ctx context.Context, mfaToken, challengeID string, assertion webauthn.AssertionResponse, client session.Client) (*LoginResponse, error) {
+	user, actor, loginChallenge, err := s.mfaUser(mfaToken, MFAPurposeVerify)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.passkeys.FinishAssertion(ctx, actor.UserID, challengeID, assertion); err != nil {
+		return nil, err
+	}
+	return s.completeLogin(ctx, user, actor, loginChallenge, client, mfa_policy.MethodWebAuthn)
+}
+
+// BeginPasskeyEnrollment lets a user whose role requires MFA enrol a
+// passkey instead of TOTP.
+func (s *AuthService) BeginPasskeyEnrollment(ctx context.Context, mfaToken string) (*webauthn.CreationOptions, error) {
+	user, actor, _, err := s.mfaUser(mfaToken, MFAPurposeEnroll)
+	if err != nil {
+		return nil, err
+	}
+	return s.passkeys.BeginRegistration(ctx, passkeyActor(user, actor))
+}
+
+// CompletePasskeyEnrollment registers the passkey and starts the session.
+func (s *AuthService) CompletePasskeyEnrollment(ctx context.Context, mfaToken, challengeID, name string, reg webauthn.RegistrationResponse, client session.Client) (*LoginResponse, error) {
+	user, actor, loginChallenge, err := s.mfaUser(mfaToken, MFAPurposeEnroll)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.passkeys.FinishRegistration(ctx, passkeyActor(user, actor), challengeID, name, reg); err != nil {
+		return nil, err
+	}
+	return s.completeLogin(ctx, user, actor, loginChallenge, client, mfa_policy.MethodWebAuthn)
+}
+
+func passkeyActor(user *registration.User, actor mfa_policy.Actor) webauthn.Actor {
+	return webauthn.Actor{UserID: actor.UserID, TenantID: actor.TenantID, Email: user.Email, DisplayName: user.FullName}
+}
+
+// StartMFAEnrollment begins enrolment for a user whose role requires MFA
+// but who has none yet.
+func (s *AuthService) StartMFAEnrollment(mfaToken string) (*mfa.MFASetupResponse, error) {
+	user, actor, _, err := s.mfaUser(mfaToken, MFAPurposeEnroll)
+	if err != nil {
+		return nil, err
+	}
+	return s.mfa.Setup(actor, user.Email)
+}
+
+// CompleteMFAEnrollment confirms enrolment with a first code and starts
+// the session.
+func (s *AuthService) CompleteMFAEnrollment(ctx context.Context, mfaToken, code string, client session.Client) (*LoginResponse, error) {
+	user, actor, challengeID, err := s.mfaUser(mfaToken, MFAPurposeEnroll)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfa.Enable(ctx, challengeID, actor, code); err != nil {
+		return nil, err
+	}
+	return s.completeLogin(ctx, user, actor, challengeID, client, mfa_policy.MethodTOTP)
+}
+
+// StepUp re-verifies a signed-in user's second factor and issues an access
+// token for the same session that carries a fresh step-up claim.
+func (s *AuthService) StepUp(ctx context.Context, userID, sessionID, code string) (*LoginResponse, error) {
+	user, err := s.stepUpUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	tenantID, _ := userScope(user)
+	actor := mfa_policy.Actor{UserID: userID, TenantID: tenantID, Role: user.Role}
+	method, err := s.mfa.Verify(ctx, "step-up:"+userID, actor, code)
+	if err != nil {
+		return nil, err
+	}
+	return s.stepUpResponse(user, sessionID, method)
+}
+
+// BeginPasskeyStepUp issues a WebAuthn challenge for step-up.
+func (s *AuthService) BeginPasskeyStepUp(ctx context.Context, userID string) (*webauthn.RequestOptions, error) {
+	user, err := s.stepUpUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	tenantID, _ := userScope(user)
+	return s.passkeys.BeginAssertion(ctx, userID, tenantID)
+}
+
+// PasskeyStepUp is StepUp with a passkey assertion.
+func (s *AuthService) PasskeyStepUp(ctx context.Context, userID, sessionID, challengeID string, assertion webauthn.AssertionResponse) (*LoginResponse, error) {
+	user, err := s.stepUpUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.passkeys.FinishAssertion(ctx, userID, challengeID, assertion); err != nil {
+		return nil, err
+	}
+	return s.stepUpResponse(user, sessionID, mfa_policy.MethodWebAuthn)
+}
+
+// stepUpUser loads a signed-in user, who must still be allowed in.
+func (s *AuthService) stepUpUser(userID string) (*registration.User, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil || user == nil {
+		return nil, session.ErrSessionRevoked
+	}
+	if accessEnded(user) {
+		return nil, ErrAccessRevoked
+	}
+	return user, nil
+}
+
+func (s *AuthService) stepUpResponse(user *registration.User, sessionID, method string) (*LoginResponse, error) {
+	userID := user.ID.String()
+	tenantID, teamID := userScope(user)
+	now := time.Now()
+	stepUp := now.Add(s.mfa.StepUpTTL(tenantID))
+	exp := now.Add(s.sessions.AccessTTL())
+	if end := externalExpiry(user); end != nil && end.Before(exp) {
+		exp = *end
+	}
+	token, err := GenerateAccessToken(AccessClaims{
+		UserID:       userID,
+		Email:        user.Email,
+		Role:         user.Role,
+		FullName:     user.FullName,
+		TenantID:     tenantID,
+		TeamID:       teamID,
+		SessionID:    sessionID,
+		TokenVersion: user.TokenVersion,
+		ExpiresAt:    exp,
+		StepUpUntil:  &stepUp,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token")
+	}
+	return &LoginResponse{
+		ID:              userID,
+		Email:           user.Email,
+		Token:           token,
+		Role:            user.Role,
+		IsVerified:      user.IsVerified,
+		ExpiresAt:       &exp,
+		SessionID:       sessionID,
+		StepUpExpiresAt: &stepUp,
+		MFAMethod:       method,
+	}, nil
+}
@@ -1,7 +1,11 @@
 package login
 
 import (
+	"aegis-api/services_/auth/mfa_policy"
 	"aegis-api/services_/auth/registration"
+	"aegis-api/services_/auth/session"
+	"aegis-api/services_/auth/webauthn"
+	"time"
 )
 
 type LoginRequest struct {
@@ -15,11 +19,35 @@ type LoginResponse struct {
 	Token      string `json:"token"`
 	Role       string `json:"role"`
 	IsVerified bool   `json:"isVerified"`
+	// Set when the login started a session: Token then expires at
+	// ExpiresAt and is renewed with RefreshToken.
+	ExpiresAt        *time.Time `json:"expiresAt,omitempty"`
+	SessionID        string     `json:"sessionId,omitempty"`
+	RefreshToken     string     `json:"refreshToken,omitempty"`
+	RefreshExpiresAt *time.Time `json:"refreshExpiresAt,omitempty"`
+	// Set when the user has just completed MFA: routes requiring step-up
+	// accept Token until then.
+	StepUpExpiresAt *time.Time `json:"stepUpExpiresAt,omitempty"`
+	MFAMethod       string     `json:"mfaMethod,omitempty"`
+
+	// Set instead of a session when a second factor is needed: the client
+	// completes it (or enrols first) with MFAToken.
+	MFARequired           bool       `json:"mfaRequired,omitempty"`
+	MFAEnrollmentRequired bool       `json:"mfaEnrollmentRequired,omitempty"`
+	MFAToken              string     `json:"mfaToken,omitempty"`
+	MFATokenExpiresAt     *time.Time `json:"mfaTokenExpiresAt,omitempty"`
+	// MFAMethods the user can complete the second factor with.
+	MFAMethods []string `json:"mfaMethods,omitempty"`
 }
+
 type RegenerateTokenRequest struct {
 	ExpiresInDays int `json:"expires_in_days"` // how many days until it expires
 }
 
 type AuthService struct {
-	repo registration.UserRepository
+	repo      registration.UserRepository
+	sessions  session.Service
+	mfa       mfa_policy.Service
+	passkeys  webauthn.Service
+	passwords PasswordPolicy
 }
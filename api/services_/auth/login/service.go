@@ -3,38 +3,70 @@ package login
 import (
 	//"aegis-api/services/registration"
 	//database "aegis-api/db"
+	"aegis-api/middleware"
+	"aegis-api/services_/auth/mfa_policy"
 	"aegis-api/services_/auth/registration"
+	"aegis-api/services_/auth/session"
+	"aegis-api/services_/auth/webauthn"
+	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
-func NewAuthService(repo registration.UserRepository) *AuthService {
-	return &AuthService{repo: repo}
+var (
+	ErrAccessRevoked         = errors.New("access revoked or expired")
+	ErrPasswordLoginDisabled = errors.New("password login is disabled for this tenant; sign in with SSO")
+)
+
+// NewAuthService builds the login service. passwords may be nil, in
+// which case every user may sign in with a password.
+func NewAuthService(repo registration.UserRepository, sessions session.Service, mfa mfa_policy.Service, passkeys webauthn.Service, passwords PasswordPolicy) *AuthService {
+	return &AuthService{repo: repo, sessions: sessions, mfa: mfa, passkeys: passkeys, passwords: passwords}
 }
 
-func (s *AuthService) Login(email, password string) (*LoginResponse, error) {
+func (s *AuthService) Login(email, password string, client session.Client) (*LoginResponse, error) {
 	user, err := s.repo.GetUserByEmail(email)
-	var tenantID, teamID string
 	if err != nil {
 		return nil, fmt.Errorf("invalid credentials")
 	}
 	if user == nil {
 		return nil, fmt.Errorf("invalid credentials")
 	}
-	if user.TenantID != nil {
-		tenantID = user.TenantID.String()
-	}
-
-	if user.TeamID != nil {
-		teamID = user.TeamID.String()
-	}
+	tenantID, _ := userScope(user)
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
 		return nil, fmt.Errorf("invalid credentials")
 	}
+	if s.passwords != nil {
+		allowed, err := s.passwords.LocalPasswordAllowed(tenantID, user.Role)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check sign-in policy: %w", err)
+		}
+		if !allowed {
+			return nil, ErrPasswordLoginDisabled
+		}
+	}
+
+	return s.authenticated(user, client)
+}
+
+// LoginSSO signs in a user their tenant's identity provider has
+// authenticated. MFA policy applies as it does after a password.
+func (s *AuthService) LoginSSO(user *registration.User, client session.Client) (*LoginResponse, error) {
+	return s.authenticated(user, client)
+}
 
+// authenticated finishes a login once the user's primary credential has
+// been checked.
+func (s *AuthService) authenticated(user *registration.User, client session.Client) (*LoginResponse, error) {
+	tenantID, _ := userScope(user)
+	// Service accounts authenticate with their API keys only.
+	if user.DeactivatedAt != nil || user.Role == middleware.ServiceAccountRole {
+		return nil, ErrAccessRevoked
+	}
 	if user.Role == "External Collaborator" {
 		if user.ExternalTokenStatus == "revoked" {
 			return nil, fmt.Errorf("access revoked by administrator")
@@ -44,28 +76,130 @@ func (s *AuthService) Login(email, password string) (*LoginResponse, error) {
 		}
 	}
 
-	token, err := GenerateJWT(
-		user.ID.String(),
-		user.Email,
-		user.Role,
-		user.FullName,
-		tenantID,
-		teamID,
-		user.TokenVersion,
-		user.ExternalTokenExpiry,
-	)
+	// Users with MFA, or whose role requires it, get a pre-auth token
+	// to complete the second factor with instead of a session.
+	st, err := s.mfa.Status(mfa_policy.Actor{UserID: user.ID.String(), TenantID: tenantID, Role: user.Role})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check MFA: %w", err)
+	}
+	if st.Enrolled {
+		return s.mfaChallenge(user, MFAPurposeVerify, st.Methods)
+	}
+	if st.Required {
+		return s.mfaChallenge(user, MFAPurposeEnroll, []string{mfa_policy.MethodTOTP, mfa_policy.MethodWebAuthn})
+	}
+
+	return s.startSession(user, client, nil)
+}
+
+// startSession opens a session for an authenticated user.
+func (s *AuthService) startSession(user *registration.User, client session.Client, stepUpUntil *time.Time) (*LoginResponse, error) {
+	tenantID, teamID := userScope(user)
+	issued, err := s.sessions.Create(user.ID.String(), tenantID, user.TokenVersion, client, externalExpiry(user))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start session: %w", err)
+	}
+	return s.sessionResponse(user, tenantID, teamID, issued, stepUpUntil)
+}
+
+// userScope returns the user's tenant and team IDs, empty when unset.
+func userScope(user *registration.User) (tenantID, teamID string) {
+	if user.TenantID != nil {
+		tenantID = user.TenantID.String()
+	}
+	if user.TeamID != nil {
+		teamID = user.TeamID.String()
+	}
+	return tenantID, teamID
+}
+
+// Refresh exchanges a refresh token for a new access token and refresh
+// token. The user must still be entitled to the session: a changed token
+// version or revoked external access ends it.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string, client session.Client) (*LoginResponse, error) {
+	issued, err := s.sessions.Rotate(ctx, refreshToken, client)
+	if err != nil {
+		return nil, err
+	}
+	sess := issued.Session
+	user, err := s.repo.GetUserByID(sess.UserID)
+	if err != nil || user == nil {
+		_ = s.sessions.RevokeSession(ctx, sess.UserID, sess.ID, session.ReasonRevoked)
+		return nil, session.ErrSessionRevoked
+	}
+	if user.TokenVersion != sess.TokenVersion {
+		_ = s.sessions.RevokeSession(ctx, sess.UserID, sess.ID, session.ReasonTokenVersion)
+		return nil, session.ErrSessionRevoked
+	}
+	if accessEnded(user) {
+		_ = s.sessions.RevokeSession(ctx, sess.UserID, sess.ID, session.ReasonAccessRevoked)
+		return nil, ErrAccessRevoked
+	}
+
+	tenantID, teamID := userScope(user)
+	return s.sessionResponse(user, tenantID, teamID, issued, nil)
+}
+
+// accessEnded reports whether the user has been deactivated or is an
+// external collaborator whose access has ended.
+func accessEnded(user *registration.User) bool {
+	return user.DeactivatedAt != nil || user.Role == middleware.ServiceAccountRole || externalAccessEnded(user)
+}
+
+// externalAccessEnded reports whether an external collaborator's access
+// has been revoked or has run out.
+func externalAccessEnded(user *registration.User) bool {
+	if user.Role != "External Collaborator" {
+		return false
+	}
+	return user.ExternalTokenStatus == "revoked" || (user.ExternalTokenExpiry != nil && user.ExternalTokenExpiry.Before(time.Now()))
+}
+
+// sessionResponse issues a short-lived access token bound to the session.
+func (s *AuthService) sessionResponse(user *registration.User, tenantID, teamID string, issued *session.Issued, stepUpUntil *time.Time) (*LoginResponse, error) {
+	exp := time.Now().Add(s.sessions.AccessTTL())
+	if issued.Session.ExpiresAt.Before(exp) {
+		exp = issued.Session.ExpiresAt
+	}
+	token, err := GenerateAccessToken(AccessClaims{
+		UserID:       user.ID.String(),
+		Email:        user.Email,
+		Role:         user.Role,
+		FullName:     user.FullName,
+		TenantID:     tenantID,
+		TeamID:       teamID,
+		SessionID:    issued.Session.ID,
+		TokenVersion: user.TokenVersion,
+		ExpiresAt:    exp,
+		StepUpUntil:  stepUpUntil,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token")
 	}
 
 	return &LoginResponse{
-		ID:    user.ID.String(),
-		Email: user.Email,
-		Token: token,
-		Role:  user.Role,
+		ID:               user.ID.String(),
+		Email:            user.Email,
+		Token:            token,
+		Role:             user.Role,
+		IsVerified:       user.IsVerified,
+		ExpiresAt:        &exp,
+		SessionID:        issued.Session.ID,
+		RefreshToken:     issued.RefreshToken,
+		RefreshExpiresAt: &issued.RefreshExpiresAt,
+		StepUpExpiresAt:  stepUpUntil,
 	}, nil
 }
 
+// externalExpiry is the end of an external collaborator's access, which
+// their sessions may not outlive.
+func externalExpiry(user *registration.User) *time.Time {
+	if user.Role != "External Collaborator" {
+		return nil
+	}
+	return user.ExternalTokenExpiry
+}
+
 func (s *AuthService) RegenerateExternalToken(adminID, targetUserID string, req RegenerateTokenRequest) (*LoginResponse, error) {
 	// Verify the admin is actually an admin (optional safety check)
 	admin, err := s.repo.GetUserByID(adminID)
@@ -95,27 +229,10 @@ func (s *AuthService) RegenerateExternalToken(adminID, targetUserID string, req
 	if err != nil {
 		return nil, fmt.Errorf("failed to update user token info")
 	}
+	// Sessions started under the old version end with it.
+	s.sessions.ForgetTokenVersion(context.Background(), user.ID.String())
 
-	// Generate new JWT
-	token, err := GenerateJWT(
-		user.ID.String(),
-		user.Email,
-		user.Role,
-		user.FullName,
-		user.TenantID.String(),
-		user.TeamID.String(),
-		user.TokenVersion,
-		user.ExternalTokenExpiry,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate token")
-	}
-
-	return &LoginResponse{
-		ID:         user.ID.String(),
-		Email:      user.Email,
-		Token:      token,
-		IsVerified: user.IsVerified,
-		Role:       user.Role,
-	}, nil
+	// The new token belongs to a session, so it can be listed and revoked
+	// like any other and ends with the user's external access.
+	return s.startSession(user, session.Client{DeviceName: "Issued by administrator"}, nil)
 }
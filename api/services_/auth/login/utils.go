@@ -1,11 +1,14 @@
 package login
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"aegis-api/middleware"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -49,19 +52,104 @@ func GenerateJWT(userID, email, role string, fullName, tenantID, teamID string,
 		exp = time.Now().Add(24 * time.Hour)
 	}
 
+	return GenerateAccessToken(AccessClaims{
+		UserID:       userID,
+		Email:        email,
+		Role:         role,
+		FullName:     fullName,
+		TenantID:     tenantID,
+		TeamID:       teamID,
+		TokenVersion: tokenVersion,
+		ExpiresAt:    exp,
+	})
+}
+
+// AccessClaims are the claims of an access token.
+type AccessClaims struct {
+	UserID, Email, Role, FullName, TenantID, TeamID string
+	// SessionID binds the token to a session, so revoking the session
+	// revokes the token.
+	SessionID    string
+	TokenVersion int
+	ExpiresAt    time.Time
+	// StepUpUntil is set when the user has just completed MFA; routes
+	// requiring step-up accept the token until then.
+	StepUpUntil *time.Time
+}
+
+// GenerateAccessToken issues an access token with the given claims.
+func GenerateAccessToken(ac AccessClaims) (string, error) {
 	claims := jwt.MapClaims{
-		"user_id":       userID,
-		"email":         email,
-		"role":          role,
-		"full_name":     fullName,
-		"tenant_id":     tenantID,
-		"team_id":       teamID,
-		"token_version": tokenVersion,
+		"user_id":       ac.UserID,
+		"email":         ac.Email,
+		"role":          ac.Role,
+		"full_name":     ac.FullName,
+		"tenant_id":     ac.TenantID,
+		"team_id":       ac.TeamID,
+		"token_version": ac.TokenVersion,
 		"iat":           time.Now().Unix(),
-		"exp":           exp.Unix(),
+		"exp":           ac.ExpiresAt.Unix(),
+	}
+	if ac.SessionID != "" {
+		claims["sid"] = ac.SessionID
+	}
+	if ac.StepUpUntil != nil {
+		claims["step_up_exp"] = ac.StepUpUntil.Unix()
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(middleware.GetJWTSecret())
+}
+
+// MFA token purposes: completing the second factor, or enrolling in MFA
+// because the tenant's policy requires it.
+const (
+	MFAPurposeVerify = "mfa_verify"
+	MFAPurposeEnroll = "mfa_enroll"
+)
+
+// mfaTokenTTL is how long a pre-auth token lasts after the password step.
+const mfaTokenTTL = 5 * time.Minute
 
+var ErrInvalidMFAToken = errors.New("invalid or expired MFA token")
+
+// GenerateMFAToken issues a pre-auth token for the second step of login.
+// Its "typ" claim keeps the auth middleware from accepting it. The
+// returned challenge ID identifies it for attempt counting.
+func GenerateMFAToken(userID, purpose string, exp time.Time) (token, challengeID string, err error) {
+	challengeID = uuid.NewString()
+	claims := jwt.MapClaims{
+		"user_id": userID,
+		"typ":     purpose,
+		"jti":     challengeID,
+		"iat":     time.Now().Unix(),
+		"exp":     exp.Unix(),
+	}
+	token, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(middleware.GetJWTSecret())
+	return token, challengeID, err
+}
+
+// ParseMFAToken checks a pre-auth token issued for purpose and returns its
+// user and challenge ID.
+func ParseMFAToken(tokenString, purpose string) (userID, challengeID string, err error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return middleware.GetJWTSecret(), nil
+	})
+	if err != nil || !token.Valid {
+		return "", "", ErrInvalidMFAToken
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", "", ErrInvalidMFAToken
+	}
+	typ, _ := claims["typ"].(string)
+	userID, _ = claims["user_id"].(string)
+	challengeID, _ = claims["jti"].(string)
+	if typ != purpose || userID == "" || challengeID == "" {
+		return "", "", ErrInvalidMFAToken
+	}
+	return userID, challengeID, nil
 }
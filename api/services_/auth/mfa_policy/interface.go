@@ -0,0 +1,70 @@
+package mfa_policy
+
+import (
+	"context"
+	"time"
+
+	mfa "aegis-api/services_/auth/verification"
+
+	"github.com/google/uuid"
+)
+
+type Repository interface {
+	AutoMigrate() error
+
+	// GetPolicy returns nil when the tenant has not set a policy.
+	GetPolicy(tenantID string) (*Policy, error)
+	SavePolicy(p *Policy) error
+}
+
+// Factors is the part of mfa.MFAService the policy enforces.
+type Factors interface {
+	GenerateSecret(userID uuid.UUID, userEmail string) (*mfa.MFASetupResponse, error)
+	VerifyAndEnableMFA(userID uuid.UUID, code string) error
+	GetMFAStatus(userID uuid.UUID) (bool, error)
+	// ValidateTOTP returns the time step of a valid code.
+	ValidateTOTP(userID uuid.UUID, code string) (step int64, ok bool, err error)
+	UseRecoveryCode(userID uuid.UUID, code string) (bool, error)
+	RegenerateRecoveryCodes(userID uuid.UUID) ([]string, error)
+	RecoveryCodesLeft(userID uuid.UUID) (int, error)
+	DisableMFA(userID uuid.UUID) error
+}
+
+// Passkeys counts a user's WebAuthn credentials, which satisfy the policy
+// like TOTP does.
+type Passkeys interface {
+	CountActive(userID string) (int, error)
+}
+
+type Service interface {
+	// GetPolicy returns the tenant's policy, or the default of no
+	// required roles.
+	GetPolicy(tenantID string) (*Policy, error)
+	UpdatePolicy(actor Actor, in PolicyInput) (*Policy, error)
+	// StepUpTTL is how long a step-up verification lasts in the tenant.
+	StepUpTTL(tenantID string) time.Duration
+
+	Status(actor Actor) (*Status, error)
+	// Enrollment reports whether the user has a second factor and whether
+	// the policy requires one of their role. It implements
+	// middleware.MFAEnrollment.
+	Enrollment(userID, tenantID, role string) (enrolled, required bool, err error)
+	// Setup starts enrolment. MFA is enabled once Enable confirms a code.
+	Setup(actor Actor, email string) (*mfa.MFASetupResponse, error)
+	// Enable confirms enrolment with a first TOTP code. Attempts are
+	// counted against challengeID.
+	Enable(ctx context.Context, challengeID string, actor Actor, code string) error
+	// Verify checks a TOTP or recovery code and returns which it was. A
+	// TOTP code is accepted once. Failed attempts are counted against
+	// challengeID; once MaxAttempts is reached, or the challenge is
+	// completed, the challenge is refused.
+	Verify(ctx context.Context, challengeID string, actor Actor, code string) (string, error)
+	// CompleteChallenge marks a login challenge as used, so its pre-auth
+	// token cannot start a second session. It returns ErrChallengeUsed
+	// when the challenge was already completed.
+	CompleteChallenge(ctx context.Context, challengeID string) error
+	RegenerateRecoveryCodes(actor Actor) ([]string, error)
+	// Disable turns TOTP off, unless the tenant's policy requires MFA and
+	// the user has no passkey left to satisfy it.
+	Disable(actor Actor) error
+}
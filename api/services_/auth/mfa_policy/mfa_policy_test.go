@@ -0,0 +1,199 @@
+package mfa_policy_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"aegis-api/cache"
+	"aegis-api/services_/auth/mfa_policy"
+	"aegis-api/services_/auth/webauthn"
+	"aegis-api/tests/fakes"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func newService() (mfa_policy.Service, mfa_policy.Actor) {
+	repo, factors := &fakes.MFAPolicies{}, &fakes.Factors{}
+	actor := mfa_policy.Actor{UserID: uuid.NewString(), TenantID: uuid.NewString(), Role: "DFIR Admin"}
+	return mfa_policy.NewService(repo, factors, nil, cache.NewMemory()), actor
+}
+
+func TestPolicyRequiresRoles(t *testing.T) {
+	svc, actor := newService()
+
+	p, err := svc.GetPolicy(actor.TenantID)
+	require.NoError(t, err)
+	require.JSONEq(t, `[]`, string(p.RequiredRoles))
+	require.Equal(t, mfa_policy.DefaultStepUpMinutes, p.StepUpMinutes)
+
+	st, err := svc.Status(actor)
+	require.NoError(t, err)
+	require.False(t, st.Required)
+
+	_, err = svc.UpdatePolicy(actor, mfa_policy.PolicyInput{StepUpMinutes: 600})
+	require.ErrorIs(t, err, mfa_policy.ErrInvalidPolicy)
+
+	p, err = svc.UpdatePolicy(actor, mfa_policy.PolicyInput{RequiredRoles: []string{"DFIR Admin", " DFIR Admin "}, StepUpMinutes: 10})
+	require.NoError(t, err)
+	var roles []string
+	require.NoError(t, json.Unmarshal(p.RequiredRoles, &roles))
+	require.Equal(t, []string{"DFIR Admin"}, roles)
+	require.Equal(t, 10*60.0, svc.StepUpTTL(actor.TenantID).Seconds())
+
+	st, err = svc.Status(actor)
+	require.NoError(t, err)
+	require.True(t, st.Required)
+	require.False(t, st.Enrolled)
+
+	other := actor
+	other.Role = "Forensic Analyst"
+	st, err = svc.Status(other)
+	require.NoError(t, err)
+	require.False(t, st.Required)
+
+	_, err = svc.UpdatePolicy(actor, mfa_policy.PolicyInput{RequiredRoles: []string{mfa_policy.AllRoles}})
+	require.NoError(t, err)
+	st, err = svc.Status(other)
+	require.NoError(t, err)
+	require.True(t, st.Required)
+}
+
+func TestEnrolVerifyAndRecoveryCodes(t *testing.T) {
+	svc, actor := newService()
+	ctx := context.Background()
+
+	_, err := svc.Verify(ctx, "c1", actor, "123456")
+	require.ErrorIs(t, err, mfa_policy.ErrNotEnrolled)
+
+	setup, err := svc.Setup(actor, "a@example.com")
+	require.NoError(t, err)
+	require.Len(t, setup.BackupCodes, 2)
+	require.ErrorIs(t, svc.Enable(ctx, "c1", actor, "000000"), mfa_policy.ErrInvalidCode)
+	require.NoError(t, svc.Enable(ctx, "c1", actor, "123456"))
+	_, err = svc.Setup(actor, "a@example.com")
+	require.ErrorIs(t, err, mfa_policy.ErrAlreadyEnrolled)
+
+	method, err := svc.Verify(ctx, "c2", actor, "123456")
+	require.NoError(t, err)
+	require.Equal(t, mfa_policy.MethodTOTP, method)
+
+	method, err = svc.Verify(ctx, "c2", actor, "AAAA-BBBB")
+	require.NoError(t, err)
+	require.Equal(t, mfa_policy.MethodRecoveryCode, method)
+	// Recovery codes work once.
+	_, err = svc.Verify(ctx, "c2", actor, "AAAA-BBBB")
+	require.ErrorIs(t, err, mfa_policy.ErrInvalidCode)
+
+	st, err := svc.Status(actor)
+	require.NoError(t, err)
+	require.Equal(t, 1, st.RecoveryCodesLeft)
+
+	codes, err := svc.RegenerateRecoveryCodes(actor)
+	require.NoError(t, err)
+	require.Equal(t, []string{"EEEE-FFFF"}, codes)
+	_, err = svc.Verify(ctx, "c3", actor, "CCCC-DDDD")
+	require.ErrorIs(t, err, mfa_policy.ErrInvalidCode)
+}
+
+func TestVerifyLimitsAttempts(t *testing.T) {
+	svc, actor := newService()
+	ctx := context.Background()
+	_, err := svc.Setup(actor, "a@example.com")
+	require.NoError(t, err)
+	require.NoError(t, svc.Enable(ctx, "enrol", actor, "123456"))
+
+	for i := 0; i < mfa_policy.MaxAttempts; i++ {
+		_, err := svc.Verify(ctx, "login", actor, "999999")
+		require.ErrorIs(t, err, mfa_policy.ErrInvalidCode)
+	}
+	// Even the right code is refused once the challenge is used up.
+	_, err = svc.Verify(ctx, "login", actor, "123456")
+	require.ErrorIs(t, err, mfa_policy.ErrTooManyAttempts)
+
+	_, err = svc.Verify(ctx, "another-login", actor, "123456")
+	require.NoError(t, err)
+}
+
+func TestVerifyRefusesReplays(t *testing.T) {
+	svc, actor := newService()
+	ctx := context.Background()
+	_, err := svc.Setup(actor, "a@example.com")
+	require.NoError(t, err)
+	require.NoError(t, svc.Enable(ctx, "enrol", actor, "123456"))
+
+	_, err = svc.Verify(ctx, "login", actor, "123456")
+	require.NoError(t, err)
+	require.NoError(t, svc.CompleteChallenge(ctx, "login"))
+
+	// The same pre-auth challenge cannot be completed again...
+	_, err = svc.Verify(ctx, "login", actor, "654321")
+	require.ErrorIs(t, err, mfa_policy.ErrChallengeUsed)
+	require.ErrorIs(t, svc.CompleteChallenge(ctx, "login"), mfa_policy.ErrChallengeUsed)
+
+	// ...and a code already accepted is refused on a new one, as is a code
+	// of an earlier time step.
+	_, err = svc.Verify(ctx, "second-login", actor, "123456")
+	require.ErrorIs(t, err, mfa_policy.ErrCodeReused)
+	_, err = svc.Verify(ctx, "second-login", actor, "654321")
+	require.NoError(t, err)
+	_, err = svc.Verify(ctx, "step-up", actor, "123456")
+	require.ErrorIs(t, err, mfa_policy.ErrCodeReused)
+}
+
+func TestDisableRespectsPolicy(t *testing.T) {
+	svc, actor := newService()
+	ctx := context.Background()
+	require.ErrorIs(t, svc.Disable(actor), mfa_policy.ErrNotEnrolled)
+
+	_, err := svc.Setup(actor, "a@example.com")
+	require.NoError(t, err)
+	require.NoError(t, svc.Enable(ctx, "enrol", actor, "123456"))
+
+	_, err = svc.UpdatePolicy(actor, mfa_policy.PolicyInput{RequiredRoles: []string{"DFIR Admin"}})
+	require.NoError(t, err)
+	require.ErrorIs(t, svc.Disable(actor), mfa_policy.ErrRequiredByPolicy)
+
+	_, err = svc.UpdatePolicy(actor, mfa_policy.PolicyInput{})
+	require.NoError(t, err)
+	require.NoError(t, svc.Disable(actor))
+	st, err := svc.Status(actor)
+	require.NoError(t, err)
+	require.False(t, st.Enrolled)
+}
+
+func TestPasskeysSatisfyPolicy(t *testing.T) {
+	repo, factors := &fakes.MFAPolicies{}, &fakes.Factors{}
+	passkeys := &fakes.Passkeys{}
+	svc := mfa_policy.NewService(repo, factors, passkeys, cache.NewMemory())
+	actor := mfa_policy.Actor{UserID: uuid.NewString(), TenantID: uuid.NewString(), Role: "DFIR Admin"}
+	ctx := context.Background()
+
+	_, err := svc.UpdatePolicy(actor, mfa_policy.PolicyInput{RequiredRoles: []string{"DFIR Admin"}})
+	require.NoError(t, err)
+
+	passkey := &webauthn.Credential{UserID: actor.UserID, CredentialID: "key-1"}
+	require.NoError(t, passkeys.CreateCredential(passkey))
+	st, err := svc.Status(actor)
+	require.NoError(t, err)
+	require.True(t, st.Enrolled)
+	require.False(t, st.TOTP)
+	require.Equal(t, []string{mfa_policy.MethodWebAuthn}, st.Methods)
+
+	// TOTP may be dropped while a passkey still satisfies the policy.
+	_, err = svc.Setup(actor, "a@example.com")
+	require.NoError(t, err)
+	require.NoError(t, svc.Enable(ctx, "enrol", actor, "123456"))
+	st, err = svc.Status(actor)
+	require.NoError(t, err)
+	require.Equal(t, []string{mfa_policy.MethodTOTP, mfa_policy.MethodRecoveryCode, mfa_policy.MethodWebAuthn}, st.Methods)
+	require.NoError(t, svc.Disable(actor))
+
+	revoked := time.Now()
+	passkey.RevokedAt = &revoked
+	require.NoError(t, passkeys.SaveCredential(passkey))
+	require.NoError(t, svc.Enable(ctx, "enrol-2", actor, "123456"))
+	require.ErrorIs(t, svc.Disable(actor), mfa_policy.ErrRequiredByPolicy)
+}
@@ -0,0 +1,58 @@
+package mfa_policy
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// AllRoles in a policy's required roles requires MFA of every user.
+const AllRoles = "*"
+
+// Verification methods.
+const (
+	MethodTOTP         = "totp"
+	MethodRecoveryCode = "recovery_code"
+	MethodWebAuthn     = "webauthn"
+)
+
+// Policy is a tenant's MFA policy.
+type Policy struct {
+	TenantID string `gorm:"type:uuid;primaryKey" json:"tenant_id"`
+	// RequiredRoles must complete MFA at login; users in them who have not
+	// enrolled are made to enrol before they get a session.
+	RequiredRoles datatypes.JSON `gorm:"type:jsonb;not null" json:"required_roles"` // []string
+	// StepUpMinutes is how long a step-up verification lasts.
+	StepUpMinutes int       `gorm:"not null" json:"step_up_minutes"`
+	UpdatedBy     string    `gorm:"type:uuid" json:"updated_by,omitempty"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (Policy) TableName() string { return "tenant_mfa_policies" }
+
+// PolicyInput is an update of a tenant's policy.
+type PolicyInput struct {
+	RequiredRoles []string `json:"required_roles"`
+	StepUpMinutes int      `json:"step_up_minutes"`
+}
+
+// Status is a user's MFA standing.
+type Status struct {
+	// Enrolled is set when the user has TOTP or a passkey.
+	Enrolled bool `json:"enrolled"`
+	// Required is set when the tenant's policy requires MFA of the user.
+	Required          bool `json:"required"`
+	TOTP              bool `json:"totp"`
+	Passkeys          int  `json:"passkeys"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+	StepUpMinutes     int  `json:"step_up_minutes"`
+	// Methods the user can complete MFA with.
+	Methods []string `json:"methods"`
+}
+
+// Actor is the user acting, as the auth middleware identified them.
+type Actor struct {
+	UserID   string
+	TenantID string
+	Role     string
+}
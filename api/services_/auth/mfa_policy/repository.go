@@ -0,0 +1,35 @@
+package mfa_policy
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) AutoMigrate() error {
+	return r.db.AutoMigrate(&Policy{})
+}
+
+func (r *GormRepository) GetPolicy(tenantID string) (*Policy, error) {
+	var p Policy
+	err := r.db.Where("tenant_id = ?", tenantID).First(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *GormRepository) SavePolicy(p *Policy) error {
+	return r.db.Save(p).Error
+}
@@ -0,0 +1,349 @@
+// Package mfa_policy enforces multi-factor authentication: which roles of
+// a tenant must use it, attempt-limited verification of TOTP and recovery
+// codes, and how long a step-up verification lasts.
+package mfa_policy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"aegis-api/cache"
+	mfa "aegis-api/services_/auth/verification"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+var (
+	ErrNotEnrolled      = errors.New("MFA is not enabled for this user")
+	ErrAlreadyEnrolled  = errors.New("MFA is already enabled for this user")
+	ErrInvalidCode      = errors.New("invalid MFA code")
+	ErrTooManyAttempts  = errors.New("too many failed MFA attempts; sign in again")
+	ErrRequiredByPolicy = errors.New("the tenant's policy requires MFA for this role")
+	ErrInvalidPolicy    = errors.New("invalid MFA policy")
+	ErrCodeReused       = errors.New("this MFA code was already used; wait for the next one")
+	ErrChallengeUsed    = errors.New("this MFA challenge was already completed; sign in again")
+)
+
+const (
+	// MaxAttempts is how many wrong codes a challenge allows.
+	MaxAttempts = 5
+	// DefaultStepUpMinutes applies when a tenant has not set a policy.
+	DefaultStepUpMinutes = 5
+	// MaxStepUpMinutes bounds the step-up lifetime a policy may set.
+	MaxStepUpMinutes = 60
+
+	attemptsTTL = 15 * time.Minute
+	// totpStepTTL outlives the window in which a code of the last
+	// accepted step still validates.
+	totpStepTTL = 3 * time.Minute
+)
+
+type service struct {
+	mu       sync.Mutex // serialises the check and record of used codes and challenges
+	repo     Repository
+	factors  Factors
+	passkeys Passkeys
+	cache    cache.Client
+}
+
+// NewService enforces the policy over TOTP factors and, when passkeys is
+// not nil, WebAuthn credentials.
+func NewService(repo Repository, factors Factors, passkeys Passkeys, c cache.Client) Service {
+	if c == nil {
+		c = cache.NewMemory()
+	}
+	return &service{repo: repo, factors: factors, passkeys: passkeys, cache: c}
+}
+
+func (s *service) countPasskeys(userID string) (int, error) {
+	if s.passkeys == nil {
+		return 0, nil
+	}
+	return s.passkeys.CountActive(userID)
+}
+
+func (s *service) GetPolicy(tenantID string) (*Policy, error) {
+	p, err := s.repo.GetPolicy(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		p = &Policy{
+			TenantID:      tenantID,
+			RequiredRoles: datatypes.JSON("[]"),
+			StepUpMinutes: DefaultStepUpMinutes,
+		}
+	}
+	return p, nil
+}
+
+func (s *service) UpdatePolicy(actor Actor, in PolicyInput) (*Policy, error) {
+	if in.StepUpMinutes == 0 {
+		in.StepUpMinutes = DefaultStepUpMinutes
+	}
+	if in.StepUpMinutes < 1 || in.StepUpMinutes > MaxStepUpMinutes {
+		return nil, ErrInvalidPolicy
+	}
+	roles := make([]string, 0, len(in.RequiredRoles))
+	seen := map[string]bool{}
+	for _, r := range in.RequiredRoles {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			return nil, ErrInvalidPolicy
+		}
+		if !seen[r] {
+			seen[r] = true
+			roles = append(roles, r)
+		}
+	}
+	raw, err := json.Marshal(roles)
+	if err != nil {
+		return nil, err
+	}
+	p := &Policy{
+		TenantID:      actor.TenantID,
+		RequiredRoles: datatypes.JSON(raw),
+		StepUpMinutes: in.StepUpMinutes,
+		UpdatedBy:     actor.UserID,
+	}
+	if err := s.repo.SavePolicy(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *service) StepUpTTL(tenantID string) time.Duration {
+	p, err := s.GetPolicy(tenantID)
+	if err != nil || p.StepUpMinutes <= 0 {
+		return DefaultStepUpMinutes * time.Minute
+	}
+	return time.Duration(p.StepUpMinutes) * time.Minute
+}
+
+// required reports whether the policy requires MFA of the role.
+func required(p *Policy, role string) bool {
+	var roles []string
+	_ = json.Unmarshal(p.RequiredRoles, &roles)
+	for _, r := range roles {
+		if r == AllRoles || r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func parseUser(actor Actor) (uuid.UUID, error) {
+	id, err := uuid.Parse(actor.UserID)
+	if err != nil {
+		return uuid.Nil, ErrNotEnrolled
+	}
+	return id, nil
+}
+
+func (s *service) Status(actor Actor) (*Status, error) {
+	userID, err := parseUser(actor)
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.GetPolicy(actor.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	totp, err := s.factors.GetMFAStatus(userID)
+	if err != nil {
+		return nil, err
+	}
+	passkeys, err := s.countPasskeys(actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	st := &Status{
+		Enrolled:      totp || passkeys > 0,
+		Required:      required(p, actor.Role),
+		TOTP:          totp,
+		Passkeys:      passkeys,
+		StepUpMinutes: p.StepUpMinutes,
+		Methods:       []string{},
+	}
+	if totp {
+		if st.RecoveryCodesLeft, err = s.factors.RecoveryCodesLeft(userID); err != nil {
+			return nil, err
+		}
+		st.Methods = append(st.Methods, MethodTOTP)
+		if st.RecoveryCodesLeft > 0 {
+			st.Methods = append(st.Methods, MethodRecoveryCode)
+		}
+	}
+	if passkeys > 0 {
+		st.Methods = append(st.Methods, MethodWebAuthn)
+	}
+	return st, nil
+}
+
+func (s *service) Enrollment(userID, tenantID, role string) (bool, bool, error) {
+	st, err := s.Status(Actor{UserID: userID, TenantID: tenantID, Role: role})
+	if errors.Is(err, ErrNotEnrolled) {
+		// Not a user ID: nothing to enroll, so nothing can be required.
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	return st.Enrolled, st.Required, nil
+}
+
+func (s *service) Setup(actor Actor, email string) (*mfa.MFASetupResponse, error) {
+	userID, err := parseUser(actor)
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.factors.GenerateSecret(userID, email)
+	if errors.Is(err, mfa.ErrMFAAlreadyEnabled) {
+		return nil, ErrAlreadyEnrolled
+	}
+	return out, err
+}
+
+func (s *service) Enable(ctx context.Context, challengeID string, actor Actor, code string) error {
+	userID, err := parseUser(actor)
+	if err != nil {
+		return err
+	}
+	if err := s.checkAttempts(ctx, challengeID); err != nil {
+		return err
+	}
+	err = s.factors.VerifyAndEnableMFA(userID, strings.TrimSpace(code))
+	if errors.Is(err, mfa.ErrInvalidCode) {
+		s.failAttempt(ctx, challengeID)
+		return ErrInvalidCode
+	}
+	return err
+}
+
+func (s *service) Verify(ctx context.Context, challengeID string, actor Actor, code string) (string, error) {
+	userID, err := parseUser(actor)
+	if err != nil {
+		return "", err
+	}
+	if err := s.checkAttempts(ctx, challengeID); err != nil {
+		return "", err
+	}
+	step, ok, err := s.factors.ValidateTOTP(userID, code)
+	if errors.Is(err, mfa.ErrMFANotEnabled) {
+		return "", ErrNotEnrolled
+	}
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		if err := s.acceptStep(ctx, actor.UserID, step); err != nil {
+			s.failAttempt(ctx, challengeID)
+			return "", err
+		}
+		return MethodTOTP, nil
+	}
+	ok, err = s.factors.UseRecoveryCode(userID, code)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return MethodRecoveryCode, nil
+	}
+	s.failAttempt(ctx, challengeID)
+	return "", ErrInvalidCode
+}
+
+// acceptStep records step as the user's last accepted TOTP step. A code
+// of that step or an earlier one is refused, so each code is used once.
+func (s *service) acceptStep(ctx context.Context, userID string, step int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := cache.MFATOTPStepKey(userID)
+	if v, ok, err := s.cache.Get(ctx, key); err != nil {
+		return err
+	} else if last, perr := strconv.ParseInt(v, 10, 64); ok && perr == nil && step <= last {
+		return ErrCodeReused
+	}
+	return s.cache.Set(ctx, key, strconv.FormatInt(step, 10), totpStepTTL)
+}
+
+func (s *service) CompleteChallenge(ctx context.Context, challengeID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := cache.MFAChallengeDoneKey(challengeID)
+	if _, done, err := s.cache.Get(ctx, key); err != nil {
+		return err
+	} else if done {
+		return ErrChallengeUsed
+	}
+	return s.cache.Set(ctx, key, "1", attemptsTTL)
+}
+
+func (s *service) checkAttempts(ctx context.Context, challengeID string) error {
+	if _, done, err := s.cache.Get(ctx, cache.MFAChallengeDoneKey(challengeID)); err == nil && done {
+		return ErrChallengeUsed
+	}
+	v, ok, err := s.cache.Get(ctx, cache.MFAAttemptsKey(challengeID))
+	if err != nil || !ok {
+		return nil
+	}
+	if n, _ := strconv.Atoi(v); n >= MaxAttempts {
+		return ErrTooManyAttempts
+	}
+	return nil
+}
+
+func (s *service) failAttempt(ctx context.Context, challengeID string) {
+	key := cache.MFAAttemptsKey(challengeID)
+	n := 0
+	if v, ok, err := s.cache.Get(ctx, key); err == nil && ok {
+		n, _ = strconv.Atoi(v)
+	}
+	_ = s.cache.Set(ctx, key, strconv.Itoa(n+1), attemptsTTL)
+}
+
+func (s *service) RegenerateRecoveryCodes(actor Actor) ([]string, error) {
+	userID, err := parseUser(actor)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := s.factors.RegenerateRecoveryCodes(userID)
+	if errors.Is(err, mfa.ErrMFANotEnabled) {
+		return nil, ErrNotEnrolled
+	}
+	return codes, err
+}
+
+func (s *service) Disable(actor Actor) error {
+	userID, err := parseUser(actor)
+	if err != nil {
+		return err
+	}
+	p, err := s.GetPolicy(actor.TenantID)
+	if err != nil {
+		return err
+	}
+	if required(p, actor.Role) {
+		passkeys, err := s.countPasskeys(actor.UserID)
+		if err != nil {
+			return err
+		}
+		if passkeys == 0 {
+			return ErrRequiredByPolicy
+		}
+	}
+	enrolled, err := s.factors.GetMFAStatus(userID)
+	if err != nil {
+		return err
+	}
+	if !enrolled {
+		return ErrNotEnrolled
+	}
+	return s.factors.DisableMFA(userID)
+}
@@ -56,8 +56,11 @@ type User struct {
 	TokenVersion        int    `gorm:"default:1"`
 	ExternalTokenStatus string `gorm:"default:''"`
 	ExternalTokenExpiry *time.Time
-	CreatedAt           time.Time
-	UpdatedAt           time.Time
+	// DeactivatedAt is set when the tenant's directory deprovisions the
+	// user; deactivated users cannot sign in.
+	DeactivatedAt *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
 }
 type TenantRepository interface {
 	Exists(id uuid.UUID) bool
@@ -0,0 +1,78 @@
+package registration
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// ProvisionRequest describes a user whose identity a tenant's identity
+// provider vouches for, created just in time at their first SSO login.
+type ProvisionRequest struct {
+	FullName string
+	Email    string
+	Role     string
+	TenantID uuid.UUID
+	TeamID   *uuid.UUID
+}
+
+// ProvisionUser creates an SSO user. The email is taken as verified and
+// the password is random and never disclosed, so the user signs in
+// through the identity provider (or resets it where local passwords are
+// allowed).
+func (s *RegistrationService) ProvisionUser(req ProvisionRequest) (User, error) {
+	if req.Email == "" || req.Role == "" {
+		return User{}, fmt.Errorf("email and role are required")
+	}
+	if !s.tenantRepo.Exists(req.TenantID) {
+		return User{}, fmt.Errorf("tenant not found")
+	}
+	if req.TeamID != nil {
+		team, err := s.teamRepo.FindByID(*req.TeamID)
+		if err != nil || team.TenantID == nil || *team.TenantID != req.TenantID {
+			return User{}, fmt.Errorf("team not found")
+		}
+	}
+	existing, err := s.repo.GetUserByEmail(req.Email)
+	if err == nil && existing != nil {
+		return User{}, fmt.Errorf("user already exists")
+	}
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return User{}, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return User{}, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(base64.RawStdEncoding.EncodeToString(secret)), bcrypt.DefaultCost)
+	if err != nil {
+		return User{}, err
+	}
+
+	tenantID := req.TenantID
+	entity := ModelToEntity(UserModel{
+		FullName:     req.FullName,
+		Email:        req.Email,
+		PasswordHash: string(hash),
+		Role:         req.Role,
+		TenantID:     &tenantID,
+		TeamID:       req.TeamID,
+	}, generateID())
+	now := time.Now()
+	entity.IsVerified = true
+	entity.EmailVerifiedAt = &now
+
+	if err := s.repo.CreateUser(&entity); err != nil {
+		log.Printf(" Provisioning failed for %s: %v", req.Email, err)
+		return User{}, err
+	}
+	log.Printf("✅ Provisioned SSO user: %s (%s %s)", entity.Email, entity.FullName, entity.Role)
+	return entity, nil
+}
@@ -0,0 +1,139 @@
+package scim
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// bulkRef is how an operation refers to a resource created earlier in
+// the same request (RFC 7644 §3.7.2).
+const bulkRef = "bulkId:"
+
+func (s *service) Bulk(ctx context.Context, tenantID string, req BulkRequest) (*BulkResponse, []Event, error) {
+	if len(req.Operations) > s.opts.MaxOperations {
+		return nil, nil, fmt.Errorf("%w: at most %d operations per request", ErrTooMany, s.opts.MaxOperations)
+	}
+	if _, err := s.GetConfig(tenantID); err != nil {
+		return nil, nil, err
+	}
+	resp := &BulkResponse{Schemas: []string{SchemaBulkResponse}, Operations: []BulkOpResponse{}}
+	var events []Event
+	created := map[string]string{}
+	failures := 0
+	for _, op := range req.Operations {
+		if req.FailOnErrors > 0 && failures >= req.FailOnErrors {
+			break
+		}
+		out, ev, err := s.bulkOperation(ctx, tenantID, op, created)
+		events = append(events, ev...)
+		if err != nil {
+			failures++
+			status, body := ErrorOf(err)
+			out = BulkOpResponse{Method: op.Method, BulkID: op.BulkID, Status: strconv.Itoa(status), Response: body}
+		}
+		resp.Operations = append(resp.Operations, out)
+	}
+	return resp, events, nil
+}
+
+func (s *service) bulkOperation(ctx context.Context, tenantID string, op BulkOperation, created map[string]string) (BulkOpResponse, []Event, error) {
+	method := strings.ToUpper(op.Method)
+	out := BulkOpResponse{Method: method, BulkID: op.BulkID}
+
+	path, data := op.Path, op.Data
+	for bulkID, id := range created {
+		path = strings.ReplaceAll(path, bulkRef+bulkID, id)
+		data = bytes.ReplaceAll(data, []byte(bulkRef+bulkID), []byte(id))
+	}
+	if strings.Contains(path, bulkRef) || bytes.Contains(data, []byte(bulkRef)) {
+		return out, nil, fmt.Errorf("%w: unresolved bulkId reference", ErrInvalidValue)
+	}
+	kind, id, _ := strings.Cut(strings.Trim(path, "/"), "/")
+	if (kind != "Users" && kind != "Groups") || (method == http.MethodPost) != (id == "") {
+		return out, nil, fmt.Errorf("%w: bad bulk path %q", ErrInvalidPath, op.Path)
+	}
+	decode := func(v interface{}) error {
+		if err := json.Unmarshal(data, v); err != nil {
+			return fmt.Errorf("%w: bad data: %v", ErrInvalidValue, err)
+		}
+		return nil
+	}
+
+	var (
+		events   []Event
+		err      error
+		location string
+	)
+	switch {
+	case method == http.MethodDelete && kind == "Users":
+		events, err = s.DeleteUser(ctx, tenantID, id)
+	case method == http.MethodDelete:
+		events, err = s.DeleteGroup(ctx, tenantID, id)
+	case method == http.MethodPatch:
+		var p PatchRequest
+		if err = decode(&p); err != nil {
+			break
+		}
+		if kind == "Users" {
+			_, events, err = s.PatchUser(ctx, tenantID, id, p)
+		} else {
+			_, events, err = s.PatchGroup(ctx, tenantID, id, p)
+		}
+		location = s.location(kind, id)
+	case method == http.MethodPost || method == http.MethodPut:
+		if kind == "Users" {
+			var in UserResource
+			if err = decode(&in); err != nil {
+				break
+			}
+			var r *UserResource
+			if method == http.MethodPost {
+				r, events, err = s.CreateUser(ctx, tenantID, in)
+			} else {
+				r, events, err = s.ReplaceUser(ctx, tenantID, id, in)
+			}
+			if r != nil {
+				id = r.ID
+			}
+		} else {
+			var in GroupResource
+			if err = decode(&in); err != nil {
+				break
+			}
+			var r *GroupResource
+			if method == http.MethodPost {
+				r, events, err = s.CreateGroup(ctx, tenantID, in)
+			} else {
+				r, events, err = s.ReplaceGroup(ctx, tenantID, id, in)
+			}
+			if r != nil {
+				id = r.ID
+			}
+		}
+		location = s.location(kind, id)
+	default:
+		err = fmt.Errorf("%w: unsupported method %q", ErrInvalidValue, op.Method)
+	}
+	if err != nil {
+		return out, events, err
+	}
+
+	out.Location = location
+	switch method {
+	case http.MethodPost:
+		out.Status = strconv.Itoa(http.StatusCreated)
+		if op.BulkID != "" {
+			created[op.BulkID] = id
+		}
+	case http.MethodDelete:
+		out.Status = strconv.Itoa(http.StatusNoContent)
+	default:
+		out.Status = strconv.Itoa(http.StatusOK)
+	}
+	return out, events, nil
+}
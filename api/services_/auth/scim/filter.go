@@ -0,0 +1,433 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Filters (RFC 7644 §3.4.2.2) are evaluated against the JSON form of a
+// resource, so any attribute the resource renders can be filtered on.
+// Attribute names and string values compare case-insensitively.
+
+type filter interface {
+	match(resource map[string]interface{}) bool
+}
+
+type logical struct {
+	and         bool
+	left, right filter
+}
+
+func (l logical) match(r map[string]interface{}) bool {
+	if l.and {
+		return l.left.match(r) && l.right.match(r)
+	}
+	return l.left.match(r) || l.right.match(r)
+}
+
+type negation struct{ f filter }
+
+func (n negation) match(r map[string]interface{}) bool { return !n.f.match(r) }
+
+type comparison struct {
+	path  []string
+	op    string
+	value interface{}
+}
+
+func (c comparison) match(r map[string]interface{}) bool {
+	values := resolve(r, c.path)
+	if c.op == "pr" {
+		for _, v := range values {
+			if present(v) {
+				return true
+			}
+		}
+		return false
+	}
+	if c.op == "ne" {
+		return !comparison{path: c.path, op: "eq", value: c.value}.match(r)
+	}
+	for _, v := range values {
+		if compare(v, c.op, c.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// valueFilter is attr[filter]: some element of the multi-valued attribute
+// matches the inner filter.
+type valueFilter struct {
+	path []string
+	f    filter
+}
+
+func (v valueFilter) match(r map[string]interface{}) bool {
+	for _, el := range elements(r, v.path) {
+		if m, ok := el.(map[string]interface{}); ok && v.f.match(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup finds an attribute by case-insensitive name.
+func lookup(m map[string]interface{}, name string) (string, interface{}, bool) {
+	if v, ok := m[name]; ok {
+		return name, v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, name) {
+			return k, v, true
+		}
+	}
+	return "", nil, false
+}
+
+// resolve returns the values at path, flattening multi-valued attributes.
+// A complex value compared directly is compared by its "value".
+func resolve(v interface{}, path []string) []interface{} {
+	switch t := v.(type) {
+	case []interface{}:
+		var out []interface{}
+		for _, el := range t {
+			out = append(out, resolve(el, path)...)
+		}
+		return out
+	case map[string]interface{}:
+		if len(path) == 0 {
+			if inner, ok := t["value"]; ok {
+				return []interface{}{inner}
+			}
+			return nil
+		}
+		_, next, ok := lookup(t, path[0])
+		if !ok {
+			return nil
+		}
+		return resolve(next, path[1:])
+	}
+	if len(path) > 0 {
+		return nil
+	}
+	return []interface{}{v}
+}
+
+// elements returns the values at path like resolve, but leaves complex
+// values whole.
+func elements(v interface{}, path []string) []interface{} {
+	switch t := v.(type) {
+	case []interface{}:
+		var out []interface{}
+		for _, el := range t {
+			out = append(out, elements(el, path)...)
+		}
+		return out
+	case map[string]interface{}:
+		if len(path) == 0 {
+			return []interface{}{t}
+		}
+		_, next, ok := lookup(t, path[0])
+		if !ok {
+			return nil
+		}
+		return elements(next, path[1:])
+	}
+	if len(path) > 0 {
+		return nil
+	}
+	return []interface{}{v}
+}
+
+func present(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case string:
+		return t != ""
+	}
+	return true
+}
+
+func compare(v interface{}, op string, want interface{}) bool {
+	switch w := want.(type) {
+	case string:
+		s, ok := v.(string)
+		if !ok {
+			return false
+		}
+		s, w = strings.ToLower(s), strings.ToLower(w)
+		switch op {
+		case "eq":
+			return s == w
+		case "co":
+			return strings.Contains(s, w)
+		case "sw":
+			return strings.HasPrefix(s, w)
+		case "ew":
+			return strings.HasSuffix(s, w)
+		case "gt":
+			return s > w
+		case "ge":
+			return s >= w
+		case "lt":
+			return s < w
+		case "le":
+			return s <= w
+		}
+	case float64:
+		n, ok := v.(float64)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "eq":
+			return n == w
+		case "gt":
+			return n > w
+		case "ge":
+			return n >= w
+		case "lt":
+			return n < w
+		case "le":
+			return n <= w
+		}
+	case bool:
+		b, ok := v.(bool)
+		return ok && op == "eq" && b == w
+	case nil:
+		return op == "eq" && v == nil
+	}
+	return false
+}
+
+// ─── Parsing ─────────────────────────────────────
+
+var operators = map[string]bool{
+	"eq": true, "ne": true, "co": true, "sw": true, "ew": true,
+	"gt": true, "ge": true, "lt": true, "le": true, "pr": true,
+}
+
+type token struct {
+	text   string
+	quoted bool
+}
+
+func tokenize(s string) ([]token, error) {
+	var out []token
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' || c == ')' || c == '[' || c == ']':
+			out = append(out, token{text: string(c)})
+			i++
+		case c == '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("%w: unterminated string", ErrInvalidFilter)
+			}
+			var str string
+			if err := json.Unmarshal([]byte(s[i:j+1]), &str); err != nil {
+				return nil, fmt.Errorf("%w: bad string %s", ErrInvalidFilter, s[i:j+1])
+			}
+			out = append(out, token{text: str, quoted: true})
+			i = j + 1
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t()[]\"", rune(s[j])) {
+				j++
+			}
+			out = append(out, token{text: s[i:j]})
+			i = j
+		}
+	}
+	return out, nil
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.toks) {
+		return token{}, false
+	}
+	return p.toks[p.pos], true
+}
+
+func (p *parser) keyword(words ...string) (string, bool) {
+	t, ok := p.peek()
+	if !ok || t.quoted {
+		return "", false
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.text, w) {
+			p.pos++
+			return w, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(text string) error {
+	if _, ok := p.keyword(text); !ok {
+		return fmt.Errorf("%w: expected %q", ErrInvalidFilter, text)
+	}
+	return nil
+}
+
+func parseFilter(s string) (filter, error) {
+	toks, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	f, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := p.peek(); ok {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidFilter, t.text)
+	}
+	return f, nil
+}
+
+func (p *parser) or() (filter, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.keyword("or"); !ok {
+			return left, nil
+		}
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = logical{left: left, right: right}
+	}
+}
+
+func (p *parser) and() (filter, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.keyword("and"); !ok {
+			return left, nil
+		}
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = logical{and: true, left: left, right: right}
+	}
+}
+
+func (p *parser) unary() (filter, error) {
+	if _, ok := p.keyword("not"); ok {
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		f, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return negation{f}, p.expect(")")
+	}
+	if _, ok := p.keyword("("); ok {
+		f, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return f, p.expect(")")
+	}
+	return p.attrExp()
+}
+
+func (p *parser) attrExp() (filter, error) {
+	t, ok := p.peek()
+	if !ok || t.quoted {
+		return nil, fmt.Errorf("%w: expected an attribute", ErrInvalidFilter)
+	}
+	p.pos++
+	path, err := attrPath(t.text)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.keyword("["); ok {
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return valueFilter{path: path, f: inner}, p.expect("]")
+	}
+
+	opTok, ok := p.peek()
+	if !ok || opTok.quoted || !operators[strings.ToLower(opTok.text)] {
+		return nil, fmt.Errorf("%w: expected an operator after %q", ErrInvalidFilter, t.text)
+	}
+	p.pos++
+	op := strings.ToLower(opTok.text)
+	if op == "pr" {
+		return comparison{path: path, op: op}, nil
+	}
+	vt, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("%w: expected a value after %q", ErrInvalidFilter, op)
+	}
+	p.pos++
+	value, err := literal(vt)
+	if err != nil {
+		return nil, err
+	}
+	return comparison{path: path, op: op, value: value}, nil
+}
+
+func literal(t token) (interface{}, error) {
+	if t.quoted {
+		return t.text, nil
+	}
+	switch strings.ToLower(t.text) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	var n float64
+	if err := json.Unmarshal([]byte(t.text), &n); err != nil {
+		return nil, fmt.Errorf("%w: bad value %q", ErrInvalidFilter, t.text)
+	}
+	return n, nil
+}
+
+// attrPath splits an attribute path, dropping a core schema URN prefix
+// (urn:...:User:userName).
+func attrPath(s string) ([]string, error) {
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		s = s[i+1:]
+	}
+	if s == "" {
+		return nil, fmt.Errorf("%w: empty attribute", ErrInvalidFilter)
+	}
+	parts := strings.Split(s, ".")
+	for _, part := range parts {
+		if part == "" {
+			return nil, fmt.Errorf("%w: bad attribute %q", ErrInvalidFilter, s)
+		}
+	}
+	return parts, nil
+}
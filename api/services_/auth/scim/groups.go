@@ -0,0 +1,289 @@
+package scim
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"aegis-api/services_/auth/registration"
+
+	"github.com/google/uuid"
+)
+
+func (s *service) renderGroup(g *Group, memberIDs []string, users map[string]*registration.User) GroupResource {
+	r := GroupResource{
+		Schemas:     []string{SchemaGroup},
+		ID:          g.ID,
+		ExternalID:  g.ExternalID,
+		DisplayName: g.DisplayName,
+		Members:     []MultiValue{},
+		Meta:        meta(ResourceTypeGroup, s.location("Groups", g.ID), g.CreatedAt, g.UpdatedAt, g.Version),
+	}
+	for _, id := range memberIDs {
+		m := MultiValue{Value: id, Ref: s.location("Users", id), Type: ResourceTypeUser}
+		if u, ok := users[id]; ok {
+			m.Display = u.FullName
+		}
+		r.Members = append(r.Members, m)
+	}
+	return r
+}
+
+func (s *service) loadGroup(tenantID, id string) (*Group, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+	g, err := s.repo.GetGroup(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if g == nil {
+		return nil, ErrNotFound
+	}
+	return g, nil
+}
+
+// groupResources renders groups with their members.
+func (s *service) groupResources(groups []Group, withMembers bool) ([]GroupResource, error) {
+	ids := make([]string, len(groups))
+	for i, g := range groups {
+		ids[i] = g.ID
+	}
+	memberIDs := map[string][]string{}
+	users := map[string]*registration.User{}
+	if withMembers {
+		members, err := s.repo.Members(ids)
+		if err != nil {
+			return nil, err
+		}
+		var userIDs []string
+		for _, m := range members {
+			memberIDs[m.GroupID] = append(memberIDs[m.GroupID], m.UserID)
+			userIDs = append(userIDs, m.UserID)
+		}
+		found, err := s.repo.FindUsers(userIDs)
+		if err != nil {
+			return nil, err
+		}
+		for i := range found {
+			users[found[i].ID.String()] = &found[i]
+		}
+	}
+	out := make([]GroupResource, len(groups))
+	for i := range groups {
+		out[i] = s.renderGroup(&groups[i], memberIDs[groups[i].ID], users)
+		if !withMembers {
+			out[i].Members = nil
+		}
+	}
+	return out, nil
+}
+
+func (s *service) groupResource(g *Group) (*GroupResource, error) {
+	out, err := s.groupResources([]Group{*g}, true)
+	if err != nil {
+		return nil, err
+	}
+	return &out[0], nil
+}
+
+func (s *service) ListGroups(tenantID string, q Query) (*ListResponse, error) {
+	groups, err := s.repo.ListGroups(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.groupResources(groups, !q.ExcludeMembers)
+	if err != nil {
+		return nil, err
+	}
+	return list(out, q)
+}
+
+func (s *service) GetGroup(tenantID, id string) (*GroupResource, error) {
+	g, err := s.loadGroup(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.groupResource(g)
+}
+
+func (s *service) checkGroupName(tenantID, name, except string) error {
+	groups, err := s.repo.ListGroups(tenantID)
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if g.ID != except && strings.EqualFold(g.DisplayName, name) {
+			return fmt.Errorf("%w: group %q exists", ErrConflict, name)
+		}
+	}
+	return nil
+}
+
+// memberIDs checks that members are users the directory manages in the
+// tenant. Nested groups are not supported.
+func (s *service) memberIDs(tenantID string, members []MultiValue) ([]string, error) {
+	links, err := s.repo.ListUserLinks(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(links))
+	for _, l := range links {
+		known[l.UserID] = true
+	}
+	ids := []string{}
+	for _, m := range members {
+		id := strings.ToLower(strings.TrimSpace(m.Value))
+		if !known[id] {
+			return nil, fmt.Errorf("%w: member %q is not a provisioned user", ErrInvalidValue, m.Value)
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (s *service) CreateGroup(ctx context.Context, tenantID string, in GroupResource) (*GroupResource, []Event, error) {
+	if _, err := s.GetConfig(tenantID); err != nil {
+		return nil, nil, err
+	}
+	name := strings.TrimSpace(in.DisplayName)
+	if name == "" {
+		return nil, nil, fmt.Errorf("%w: displayName is required", ErrInvalidValue)
+	}
+	if err := s.checkGroupName(tenantID, name, ""); err != nil {
+		return nil, nil, err
+	}
+	ids, err := s.memberIDs(tenantID, in.Members)
+	if err != nil {
+		return nil, nil, err
+	}
+	g := &Group{TenantID: tenantID, ExternalID: in.ExternalID, DisplayName: name, Version: 1}
+	if err := s.repo.CreateGroup(g); err != nil {
+		return nil, nil, err
+	}
+	if err := s.repo.SetMembers(g.ID, ids); err != nil {
+		return nil, nil, err
+	}
+	events := []Event{{Action: "SCIM_CREATE_GROUP", TargetType: "scim_group", TargetID: g.ID,
+		Description: fmt.Sprintf("Directory created group %q with %d member(s)", name, len(ids))}}
+	synced, err := s.syncUsers(tenantID, ids)
+	events = append(events, synced...)
+	if err != nil {
+		return nil, events, err
+	}
+	r, err := s.groupResource(g)
+	return r, events, err
+}
+
+func (s *service) ReplaceGroup(ctx context.Context, tenantID, id string, in GroupResource) (*GroupResource, []Event, error) {
+	g, err := s.loadGroup(tenantID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.replaceGroup(g, in)
+}
+
+func (s *service) PatchGroup(ctx context.Context, tenantID, id string, p PatchRequest) (*GroupResource, []Event, error) {
+	g, err := s.loadGroup(tenantID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	current, err := s.groupResource(g)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc := toMap(current)
+	if err := applyPatch(doc, p.Operations); err != nil {
+		return nil, nil, err
+	}
+	var in GroupResource
+	if err := fromMap(doc, &in); err != nil {
+		return nil, nil, err
+	}
+	return s.replaceGroup(g, in)
+}
+
+func (s *service) replaceGroup(g *Group, in GroupResource) (*GroupResource, []Event, error) {
+	name := strings.TrimSpace(in.DisplayName)
+	if name == "" {
+		return nil, nil, fmt.Errorf("%w: displayName is required", ErrInvalidValue)
+	}
+	if !strings.EqualFold(name, g.DisplayName) {
+		if err := s.checkGroupName(g.TenantID, name, g.ID); err != nil {
+			return nil, nil, err
+		}
+	}
+	ids, err := s.memberIDs(g.TenantID, in.Members)
+	if err != nil {
+		return nil, nil, err
+	}
+	members, err := s.repo.Members([]string{g.ID})
+	if err != nil {
+		return nil, nil, err
+	}
+	before := make([]string, len(members))
+	for i, m := range members {
+		before[i] = m.UserID
+	}
+
+	renamed := name != g.DisplayName
+	g.DisplayName, g.ExternalID = name, in.ExternalID
+	g.Version++
+	if err := s.repo.SaveGroup(g); err != nil {
+		return nil, nil, err
+	}
+	if err := s.repo.SetMembers(g.ID, ids); err != nil {
+		return nil, nil, err
+	}
+
+	// Mappings are by group name, so a rename can change every member's
+	// role; otherwise only those who joined or left are affected.
+	var affected []string
+	var added, removed int
+	for _, id := range ids {
+		if !slices.Contains(before, id) {
+			affected, added = append(affected, id), added+1
+		} else if renamed {
+			affected = append(affected, id)
+		}
+	}
+	for _, id := range before {
+		if !slices.Contains(ids, id) {
+			affected, removed = append(affected, id), removed+1
+		}
+	}
+	events := []Event{{Action: "SCIM_UPDATE_GROUP", TargetType: "scim_group", TargetID: g.ID,
+		Description: fmt.Sprintf("Directory updated group %q: %d member(s) added, %d removed", name, added, removed)}}
+	synced, err := s.syncUsers(g.TenantID, affected)
+	events = append(events, synced...)
+	if err != nil {
+		return nil, events, err
+	}
+	r, err := s.groupResource(g)
+	return r, events, err
+}
+
+func (s *service) DeleteGroup(ctx context.Context, tenantID, id string) ([]Event, error) {
+	g, err := s.loadGroup(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.repo.Members([]string{g.ID})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.DeleteGroup(tenantID, g.ID); err != nil {
+		return nil, err
+	}
+	affected := make([]string, len(members))
+	for i, m := range members {
+		affected[i] = m.UserID
+	}
+	events := []Event{{Action: "SCIM_DELETE_GROUP", TargetType: "scim_group", TargetID: g.ID,
+		Description: fmt.Sprintf("Directory deleted group %q", g.DisplayName)}}
+	synced, err := s.syncUsers(tenantID, affected)
+	return append(events, synced...), err
+}
@@ -0,0 +1,106 @@
+package scim
+
+import (
+	"context"
+	"time"
+
+	"aegis-api/services_/auth/registration"
+
+	"github.com/google/uuid"
+)
+
+type Repository interface {
+	AutoMigrate() error
+
+	// GetConfig returns nil when the tenant has none.
+	GetConfig(tenantID string) (*Config, error)
+	// GetConfigByTokenHash returns nil when no tenant has the token.
+	GetConfigByTokenHash(hash string) (*Config, error)
+	SaveConfig(c *Config) error
+	DeleteConfig(tenantID string) error
+	TouchToken(tenantID string, at time.Time) error
+
+	// FindUsers loads users by ID; unknown IDs are skipped.
+	FindUsers(ids []string) ([]registration.User, error)
+
+	ListUserLinks(tenantID string) ([]UserLink, error)
+	// GetUserLink returns nil when the user is not managed by the
+	// tenant's directory.
+	GetUserLink(tenantID, userID string) (*UserLink, error)
+	SaveUserLink(l *UserLink) error
+	// DeleteUserLink also removes the user's group memberships.
+	DeleteUserLink(tenantID, userID string) error
+
+	ListGroups(tenantID string) ([]Group, error)
+	// GetGroup returns nil when the tenant has no such group.
+	GetGroup(tenantID, id string) (*Group, error)
+	CreateGroup(g *Group) error
+	SaveGroup(g *Group) error
+	DeleteGroup(tenantID, id string) error
+	// Members lists the memberships of the groups.
+	Members(groupIDs []string) ([]GroupMember, error)
+	// SetMembers replaces the members of a group.
+	SetMembers(groupID string, userIDs []string) error
+	// GroupsOfUser lists the groups the user is a member of.
+	GroupsOfUser(userID string) ([]Group, error)
+
+	// ReleaseUser deactivates a user, ends their case roles and
+	// collaborations, and reassigns their open cases, draft reports and
+	// open annotation threads to successor. With no successor, open items
+	// stay with the user and are counted as unassigned.
+	ReleaseUser(userID, successor string, at time.Time) (*Release, error)
+}
+
+// Users is the part of the user store SCIM needs.
+type Users interface {
+	GetUserByID(userID string) (*registration.User, error)
+	GetUserByEmail(email string) (*registration.User, error)
+	UpdateUser(user *registration.User) error
+	FindByTeamIDAndRole(teamID uuid.UUID, role string) (*registration.User, error)
+}
+
+// Provisioner creates directory users.
+type Provisioner interface {
+	ProvisionUser(req registration.ProvisionRequest) (registration.User, error)
+}
+
+// Sessions ends the sessions of deprovisioned users.
+type Sessions interface {
+	RevokeUser(ctx context.Context, userID, reason string) (int, error)
+}
+
+type Service interface {
+	GetConfig(tenantID string) (*Config, error)
+	SaveConfig(actor Actor, in ConfigInput) (*Config, error)
+	DeleteConfig(actor Actor) error
+	// IssueToken replaces the tenant's bearer token. The token is only
+	// returned here.
+	IssueToken(actor Actor) (string, *Config, error)
+	// Authenticate resolves a bearer token to its tenant, which must have
+	// SCIM enabled.
+	Authenticate(token string) (tenantID string, err error)
+
+	ListUsers(tenantID string, q Query) (*ListResponse, error)
+	GetUser(tenantID, id string) (*UserResource, error)
+	// CreateUser provisions the user, or takes over the tenant's existing
+	// account with the same email.
+	CreateUser(ctx context.Context, tenantID string, in UserResource) (*UserResource, []Event, error)
+	ReplaceUser(ctx context.Context, tenantID, id string, in UserResource) (*UserResource, []Event, error)
+	PatchUser(ctx context.Context, tenantID, id string, p PatchRequest) (*UserResource, []Event, error)
+	// DeleteUser deprovisions the user and stops managing them; the
+	// account is kept, deactivated, for the record.
+	DeleteUser(ctx context.Context, tenantID, id string) ([]Event, error)
+
+	ListGroups(tenantID string, q Query) (*ListResponse, error)
+	GetGroup(tenantID, id string) (*GroupResource, error)
+	CreateGroup(ctx context.Context, tenantID string, in GroupResource) (*GroupResource, []Event, error)
+	ReplaceGroup(ctx context.Context, tenantID, id string, in GroupResource) (*GroupResource, []Event, error)
+	PatchGroup(ctx context.Context, tenantID, id string, p PatchRequest) (*GroupResource, []Event, error)
+	DeleteGroup(ctx context.Context, tenantID, id string) ([]Event, error)
+
+	// Bulk runs the operations in order, resolving bulkId references.
+	Bulk(ctx context.Context, tenantID string, req BulkRequest) (*BulkResponse, []Event, error)
+
+	// BaseURL is where resource locations are rooted.
+	BaseURL() string
+}
@@ -0,0 +1,277 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Schema and message URNs of RFC 7643 and RFC 7644.
+const (
+	SchemaUser           = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup          = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse   = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp        = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaBulkRequest    = "urn:ietf:params:scim:api:messages:2.0:BulkRequest"
+	SchemaBulkResponse   = "urn:ietf:params:scim:api:messages:2.0:BulkResponse"
+	SchemaError          = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaServiceConfig  = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType   = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	ResourceTypeUser     = "User"
+	ResourceTypeGroup    = "Group"
+	defaultPageSize      = 100
+	maxPageSize          = 500
+	defaultMaxOperations = 1000
+)
+
+// Config is a tenant's SCIM endpoint: the bearer token its directory
+// authenticates with and how directory groups map to AEGIS.
+type Config struct {
+	TenantID string `gorm:"type:uuid;primaryKey" json:"tenant_id"`
+	Enabled  bool   `gorm:"not null" json:"enabled"`
+	// TokenHash is the SHA-256 of the bearer token; the token itself is
+	// only shown when it is issued.
+	TokenHash     string         `gorm:"type:char(64);index" json:"-"`
+	TokenPrefix   string         `gorm:"type:varchar(16)" json:"token_prefix,omitempty"`
+	TokenIssuedAt *time.Time     `json:"token_issued_at,omitempty"`
+	TokenLastUsed *time.Time     `json:"token_last_used_at,omitempty"`
+	GroupMappings datatypes.JSON `gorm:"type:jsonb;not null" json:"group_mappings"` // []GroupMapping
+	DefaultRole   string         `gorm:"type:varchar(100);not null" json:"default_role"`
+	DefaultTeam   *string        `gorm:"type:uuid" json:"default_team_id,omitempty"`
+	// ReassignTo takes over the open cases, draft reports and open
+	// annotation threads of deprovisioned users. When unset they go to
+	// the DFIR Admin of the user's team.
+	ReassignTo *string   `gorm:"type:uuid" json:"reassign_to,omitempty"`
+	UpdatedBy  string    `gorm:"type:uuid" json:"updated_by,omitempty"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (Config) TableName() string { return "tenant_scim_configs" }
+
+// GroupMapping gives the members of the directory group named Group a
+// role and/or a team. Mappings are tried in order; the first one that
+// applies to a user wins.
+type GroupMapping struct {
+	Group  string `json:"group"`
+	Role   string `json:"role,omitempty"`
+	TeamID string `json:"team_id,omitempty"`
+}
+
+// ConfigInput is an update of a tenant's SCIM settings.
+type ConfigInput struct {
+	Enabled       bool           `json:"enabled"`
+	GroupMappings []GroupMapping `json:"group_mappings"`
+	DefaultRole   string         `json:"default_role"`
+	DefaultTeamID *string        `json:"default_team_id"`
+	ReassignTo    *string        `json:"reassign_to"`
+}
+
+// UserLink marks a user as managed by the tenant's directory.
+type UserLink struct {
+	UserID     string    `gorm:"type:uuid;primaryKey"`
+	TenantID   string    `gorm:"type:uuid;not null;index"`
+	ExternalID string    `gorm:"type:text"`
+	UserName   string    `gorm:"type:text;not null"`
+	Active     bool      `gorm:"not null"`
+	Version    int       `gorm:"not null;default:1"`
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime"`
+}
+
+func (UserLink) TableName() string { return "scim_users" }
+
+// Group is a directory group pushed to the tenant.
+type Group struct {
+	ID          string    `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	TenantID    string    `gorm:"type:uuid;not null;index"`
+	ExternalID  string    `gorm:"type:text"`
+	DisplayName string    `gorm:"type:text;not null"`
+	Version     int       `gorm:"not null;default:1"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+}
+
+func (Group) TableName() string { return "scim_groups" }
+
+type GroupMember struct {
+	GroupID string `gorm:"type:uuid;primaryKey"`
+	UserID  string `gorm:"type:uuid;primaryKey;index"`
+}
+
+func (GroupMember) TableName() string { return "scim_group_members" }
+
+// Release is what deprovisioning a user handed over or removed.
+type Release struct {
+	CaseRoles      int
+	Collaborations int
+	Cases          int
+	Reports        int
+	Threads        int
+	// Unassigned counts open items left with the user because there was
+	// no one to reassign them to.
+	Unassigned int
+}
+
+// ─── Resources ─────────────────────────────────────
+
+type Meta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location,omitempty"`
+	Version      string     `json:"version,omitempty"`
+}
+
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// MultiValue is an email, group or member reference.
+type MultiValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary Bool   `json:"primary,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+type UserResource struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	UserName    string       `json:"userName"`
+	Name        *Name        `json:"name,omitempty"`
+	DisplayName string       `json:"displayName,omitempty"`
+	Emails      []MultiValue `json:"emails,omitempty"`
+	// Active is absent-means-true on input.
+	Active *Bool `json:"active,omitempty"`
+	// Groups and Roles are read-only.
+	Groups []MultiValue `json:"groups,omitempty"`
+	Roles  []MultiValue `json:"roles,omitempty"`
+	Meta   *Meta        `json:"meta,omitempty"`
+}
+
+type GroupResource struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []MultiValue `json:"members,omitempty"`
+	Meta        *Meta        `json:"meta,omitempty"`
+}
+
+// Bool accepts "True"/"False" strings as well as JSON booleans; some
+// directories send them quoted.
+type Bool bool
+
+func (b *Bool) UnmarshalJSON(raw []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return err
+	}
+	switch t := v.(type) {
+	case bool:
+		*b = Bool(t)
+	case string:
+		switch strings.ToLower(t) {
+		case "true":
+			*b = true
+		case "false":
+			*b = false
+		default:
+			return fmt.Errorf("%w: %q is not a boolean", ErrInvalidValue, t)
+		}
+	case nil:
+		*b = false
+	default:
+		return fmt.Errorf("%w: %s is not a boolean", ErrInvalidValue, raw)
+	}
+	return nil
+}
+
+func boolPtr(v bool) *Bool {
+	b := Bool(v)
+	return &b
+}
+
+// Query is a list request.
+type Query struct {
+	Filter string
+	// StartIndex is 1-based.
+	StartIndex int
+	// Count is the page size; zero means the default of 100.
+	Count int
+	// ExcludeMembers leaves group members out of the response.
+	ExcludeMembers bool
+}
+
+type ListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+type BulkRequest struct {
+	Schemas      []string        `json:"schemas"`
+	FailOnErrors int             `json:"failOnErrors,omitempty"`
+	Operations   []BulkOperation `json:"Operations"`
+}
+
+type BulkOperation struct {
+	Method string          `json:"method"`
+	BulkID string          `json:"bulkId,omitempty"`
+	Path   string          `json:"path"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+type BulkResponse struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []BulkOpResponse `json:"Operations"`
+}
+
+type BulkOpResponse struct {
+	Method   string      `json:"method"`
+	BulkID   string      `json:"bulkId,omitempty"`
+	Location string      `json:"location,omitempty"`
+	Status   string      `json:"status"`
+	Response interface{} `json:"response,omitempty"`
+}
+
+// Error is a SCIM error response.
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// Event is a change the handler records in the audit log.
+type Event struct {
+	Action      string
+	TargetType  string
+	TargetID    string
+	Description string
+}
+
+type Actor struct {
+	UserID   string
+	TenantID string
+}
@@ -0,0 +1,239 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// PATCH operations (RFC 7644 §3.5.2) are applied to the JSON form of the
+// resource, which is then read back as a replacement. Attributes this
+// service does not keep are accepted and dropped.
+
+type patchPath struct {
+	attr []string
+	// filter selects elements of a multi-valued attr; sub is then the
+	// sub-attribute of those elements the operation applies to.
+	filter filter
+	sub    string
+}
+
+func parsePatchPath(s string) (patchPath, error) {
+	i := strings.IndexByte(s, '[')
+	if i < 0 {
+		attr, err := attrPath(s)
+		if err != nil {
+			return patchPath{}, fmt.Errorf("%w: %q", ErrInvalidPath, s)
+		}
+		return patchPath{attr: attr}, nil
+	}
+	j := strings.LastIndexByte(s, ']')
+	if j < i {
+		return patchPath{}, fmt.Errorf("%w: %q", ErrInvalidPath, s)
+	}
+	attr, err := attrPath(s[:i])
+	if err != nil {
+		return patchPath{}, fmt.Errorf("%w: %q", ErrInvalidPath, s)
+	}
+	f, err := parseFilter(s[i+1 : j])
+	if err != nil {
+		return patchPath{}, fmt.Errorf("%w: %v", ErrInvalidPath, err)
+	}
+	p := patchPath{attr: attr, filter: f}
+	if rest := s[j+1:]; rest != "" {
+		if !strings.HasPrefix(rest, ".") || len(rest) == 1 || strings.Contains(rest[1:], ".") {
+			return patchPath{}, fmt.Errorf("%w: %q", ErrInvalidPath, s)
+		}
+		p.sub = rest[1:]
+	}
+	return p, nil
+}
+
+func applyPatch(doc map[string]interface{}, ops []PatchOperation) error {
+	if len(ops) == 0 {
+		return fmt.Errorf("%w: no operations", ErrInvalidValue)
+	}
+	for _, op := range ops {
+		kind := strings.ToLower(op.Op)
+		if kind != "add" && kind != "replace" && kind != "remove" {
+			return fmt.Errorf("%w: unknown op %q", ErrInvalidValue, op.Op)
+		}
+		var value interface{}
+		if len(op.Value) > 0 {
+			if err := json.Unmarshal(op.Value, &value); err != nil {
+				return fmt.Errorf("%w: bad value for %s", ErrInvalidValue, op.Op)
+			}
+		}
+		if op.Path == "" {
+			if kind == "remove" {
+				return fmt.Errorf("%w: remove needs a path", ErrNoTarget)
+			}
+			attrs, ok := value.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%w: %s without a path needs an object", ErrInvalidValue, op.Op)
+			}
+			for k, v := range attrs {
+				attr, err := attrPath(k)
+				if err != nil {
+					return fmt.Errorf("%w: %q", ErrInvalidPath, k)
+				}
+				if err := applyAt(doc, patchPath{attr: attr}, kind, v); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		p, err := parsePatchPath(op.Path)
+		if err != nil {
+			return err
+		}
+		if err := applyAt(doc, p, kind, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyAt(doc map[string]interface{}, p patchPath, op string, value interface{}) error {
+	parent := doc
+	for _, name := range p.attr[:len(p.attr)-1] {
+		key, next, ok := lookup(parent, name)
+		child, isMap := next.(map[string]interface{})
+		switch {
+		case ok && isMap:
+			parent = child
+		case op == "remove":
+			return nil
+		default:
+			if !ok {
+				key = name
+			}
+			child = map[string]interface{}{}
+			parent[key] = child
+			parent = child
+		}
+	}
+	name := p.attr[len(p.attr)-1]
+	key, current, exists := lookup(parent, name)
+	if !exists {
+		key = name
+	}
+
+	if p.filter == nil {
+		switch op {
+		case "remove":
+			if list, ok := current.([]interface{}); ok && value != nil {
+				// Some directories remove members by value rather
+				// than with a filter.
+				parent[key] = without(list, values(value))
+				return nil
+			}
+			delete(parent, key)
+		case "add":
+			if list, ok := current.([]interface{}); ok {
+				parent[key] = appendUnique(list, asList(value))
+				return nil
+			}
+			parent[key] = value
+		case "replace":
+			parent[key] = value
+		}
+		return nil
+	}
+
+	list, _ := current.([]interface{})
+	matched := false
+	out := list[:0:0]
+	for _, el := range list {
+		m, ok := el.(map[string]interface{})
+		if !ok || !p.filter.match(m) {
+			out = append(out, el)
+			continue
+		}
+		matched = true
+		switch {
+		case op == "remove" && p.sub == "":
+			continue
+		case op == "remove":
+			if k, _, ok := lookup(m, p.sub); ok {
+				delete(m, k)
+			}
+		case p.sub != "":
+			k, _, ok := lookup(m, p.sub)
+			if !ok {
+				k = p.sub
+			}
+			m[k] = value
+		default:
+			if v, ok := value.(map[string]interface{}); ok {
+				for k, x := range v {
+					m[k] = x
+				}
+			}
+		}
+		out = append(out, m)
+	}
+	if !matched && op != "remove" {
+		// Setting a sub-attribute of an element that does not exist yet
+		// (emails[type eq "work"].value) creates it.
+		c, ok := p.filter.(comparison)
+		if !ok || c.op != "eq" || len(c.path) != 1 || p.sub == "" {
+			return fmt.Errorf("%w: no value matches the filter", ErrNoTarget)
+		}
+		out = append(out, map[string]interface{}{c.path[0]: c.value, p.sub: value})
+	}
+	parent[key] = out
+	return nil
+}
+
+func asList(v interface{}) []interface{} {
+	if list, ok := v.([]interface{}); ok {
+		return list
+	}
+	return []interface{}{v}
+}
+
+// values collects the "value" of each element.
+func values(v interface{}) map[string]bool {
+	out := map[string]bool{}
+	for _, el := range asList(v) {
+		if m, ok := el.(map[string]interface{}); ok {
+			if s, ok := m["value"].(string); ok {
+				out[strings.ToLower(s)] = true
+			}
+		}
+	}
+	return out
+}
+
+func without(list []interface{}, drop map[string]bool) []interface{} {
+	out := []interface{}{}
+	for _, el := range list {
+		if drop[strings.ToLower(valueOf(el))] {
+			continue
+		}
+		out = append(out, el)
+	}
+	return out
+}
+
+func appendUnique(list, add []interface{}) []interface{} {
+	seen := values(list)
+	for _, el := range add {
+		v := strings.ToLower(valueOf(el))
+		if v != "" && seen[v] {
+			continue
+		}
+		seen[v] = true
+		list = append(list, el)
+	}
+	return list
+}
+
+func valueOf(el interface{}) string {
+	if m, ok := el.(map[string]interface{}); ok {
+		s, _ := m["value"].(string)
+		return s
+	}
+	return ""
+}
@@ -0,0 +1,220 @@
+package scim
+
+import (
+	"errors"
+	"time"
+
+	"aegis-api/services_/auth/registration"
+
+	"gorm.io/gorm"
+)
+
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) AutoMigrate() error {
+	if err := r.db.AutoMigrate(&Config{}, &UserLink{}, &Group{}, &GroupMember{}); err != nil {
+		return err
+	}
+	return r.db.Exec("ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ").Error
+}
+
+func first[T any](q *gorm.DB) (*T, error) {
+	var v T
+	err := q.First(&v).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *GormRepository) GetConfig(tenantID string) (*Config, error) {
+	return first[Config](r.db.Where("tenant_id = ?", tenantID))
+}
+
+func (r *GormRepository) GetConfigByTokenHash(hash string) (*Config, error) {
+	return first[Config](r.db.Where("token_hash = ?", hash))
+}
+
+func (r *GormRepository) SaveConfig(c *Config) error {
+	return r.db.Save(c).Error
+}
+
+func (r *GormRepository) DeleteConfig(tenantID string) error {
+	return r.db.Where("tenant_id = ?", tenantID).Delete(&Config{}).Error
+}
+
+func (r *GormRepository) TouchToken(tenantID string, at time.Time) error {
+	return r.db.Model(&Config{}).Where("tenant_id = ?", tenantID).UpdateColumn("token_last_used", at).Error
+}
+
+func (r *GormRepository) FindUsers(ids []string) ([]registration.User, error) {
+	var out []registration.User
+	if len(ids) == 0 {
+		return out, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) ListUserLinks(tenantID string) ([]UserLink, error) {
+	var out []UserLink
+	err := r.db.Where("tenant_id = ?", tenantID).Order("created_at, user_id").Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) GetUserLink(tenantID, userID string) (*UserLink, error) {
+	return first[UserLink](r.db.Where("tenant_id = ? AND user_id = ?", tenantID, userID))
+}
+
+func (r *GormRepository) SaveUserLink(l *UserLink) error {
+	return r.db.Save(l).Error
+}
+
+func (r *GormRepository) DeleteUserLink(tenantID, userID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&GroupMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("tenant_id = ? AND user_id = ?", tenantID, userID).Delete(&UserLink{}).Error
+	})
+}
+
+func (r *GormRepository) ListGroups(tenantID string) ([]Group, error) {
+	var out []Group
+	err := r.db.Where("tenant_id = ?", tenantID).Order("created_at, id").Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) GetGroup(tenantID, id string) (*Group, error) {
+	return first[Group](r.db.Where("tenant_id = ? AND id = ?", tenantID, id))
+}
+
+func (r *GormRepository) CreateGroup(g *Group) error {
+	return r.db.Create(g).Error
+}
+
+func (r *GormRepository) SaveGroup(g *Group) error {
+	return r.db.Save(g).Error
+}
+
+func (r *GormRepository) DeleteGroup(tenantID, id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("group_id = ?", id).Delete(&GroupMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("tenant_id = ? AND id = ?", tenantID, id).Delete(&Group{}).Error
+	})
+}
+
+func (r *GormRepository) Members(groupIDs []string) ([]GroupMember, error) {
+	var out []GroupMember
+	if len(groupIDs) == 0 {
+		return out, nil
+	}
+	err := r.db.Where("group_id IN ?", groupIDs).Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) SetMembers(groupID string, userIDs []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("group_id = ?", groupID).Delete(&GroupMember{}).Error; err != nil {
+			return err
+		}
+		if len(userIDs) == 0 {
+			return nil
+		}
+		rows := make([]GroupMember, len(userIDs))
+		for i, id := range userIDs {
+			rows[i] = GroupMember{GroupID: groupID, UserID: id}
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+func (r *GormRepository) GroupsOfUser(userID string) ([]Group, error) {
+	var out []Group
+	err := r.db.
+		Joins("JOIN scim_group_members m ON m.group_id = scim_groups.id").
+		Where("m.user_id = ?", userID).
+		Order("scim_groups.created_at, scim_groups.id").
+		Find(&out).Error
+	return out, err
+}
+
+const (
+	openCases   = "created_by = ? AND LOWER(status::text) NOT IN ('closed', 'archived')"
+	openReports = "examiner_id = ? AND status::text IN ('draft', 'review')"
+	openThreads = "created_by = ? AND status IN ('open', 'pending_approval')"
+)
+
+func (r *GormRepository) ReleaseUser(userID, successor string, at time.Time) (*Release, error) {
+	rel := &Release{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("UPDATE users SET deactivated_at = ?, updated_at = ? WHERE id = ?", at, at, userID).Error; err != nil {
+			return err
+		}
+
+		res := tx.Exec("DELETE FROM case_user_roles WHERE user_id = ?", userID)
+		if res.Error != nil {
+			return res.Error
+		}
+		rel.CaseRoles = int(res.RowsAffected)
+		res = tx.Exec("UPDATE case_collaborators SET status = 'revoked' WHERE user_id = ? AND status = 'active'", userID)
+		if res.Error != nil {
+			return res.Error
+		}
+		rel.Collaborations = int(res.RowsAffected)
+
+		if successor == "" {
+			for _, q := range []struct{ table, where string }{
+				{"cases", openCases}, {"reports", openReports}, {"annotation_threads", openThreads},
+			} {
+				var n int64
+				if err := tx.Table(q.table).Where(q.where, userID).Count(&n).Error; err != nil {
+					return err
+				}
+				rel.Unassigned += int(n)
+			}
+			return nil
+		}
+
+		// The successor is given a role on the cases they take over so
+		// they can work them.
+		if err := tx.Exec(`
+			INSERT INTO case_user_roles (user_id, case_id, role, tenant_id, team_id)
+			SELECT u.id, c.id, u.role, c.tenant_id, c.team_id
+			FROM cases c JOIN users u ON u.id = ?
+			WHERE c.`+openCases+`
+			ON CONFLICT (user_id, case_id) DO NOTHING`, successor, userID).Error; err != nil {
+			return err
+		}
+		for _, q := range []struct {
+			table, column, where string
+			n                    *int
+		}{
+			{"cases", "created_by", openCases, &rel.Cases},
+			{"reports", "examiner_id", openReports, &rel.Reports},
+			{"annotation_threads", "created_by", openThreads, &rel.Threads},
+		} {
+			res := tx.Table(q.table).Where(q.where, userID).UpdateColumn(q.column, successor)
+			if res.Error != nil {
+				return res.Error
+			}
+			*q.n = int(res.RowsAffected)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rel, nil
+}
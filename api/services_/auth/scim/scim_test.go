@@ -0,0 +1,373 @@
+package scim_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"aegis-api/services_/auth/registration"
+	"aegis-api/services_/auth/scim"
+	"aegis-api/services_/auth/session"
+	"aegis-api/tests/fakes"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type fixture struct {
+	svc      scim.Service
+	repo     *fakes.SCIM
+	users    *fakes.Users
+	sessions *fakes.Revocations
+	tenantID string
+	admin    scim.Actor
+	teamA    uuid.UUID
+	teamB    uuid.UUID
+	token    string
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	users := &fakes.Users{}
+	repo := &fakes.SCIM{Users: users}
+	sessions := &fakes.Revocations{}
+	f := &fixture{
+		svc:      scim.NewService(repo, users, users, sessions, scim.Options{BaseURL: "https://aegis.example/api/v1/scim/v2"}),
+		repo:     repo,
+		users:    users,
+		sessions: sessions,
+		tenantID: uuid.NewString(),
+		teamA:    uuid.New(),
+		teamB:    uuid.New(),
+	}
+	f.admin = scim.Actor{UserID: uuid.NewString(), TenantID: f.tenantID}
+
+	_, err := f.svc.SaveConfig(f.admin, scim.ConfigInput{
+		Enabled: true,
+		GroupMappings: []scim.GroupMapping{
+			{Group: "DFIR Leads", Role: "DFIR Admin", TeamID: f.teamA.String()},
+			{Group: "Analysts", Role: "Forensic Analyst"},
+			{Group: "Blue Team", TeamID: f.teamB.String()},
+		},
+		DefaultRole: "Incident Responder",
+	})
+	require.NoError(t, err)
+	f.token, _, err = f.svc.IssueToken(f.admin)
+	require.NoError(t, err)
+	return f
+}
+
+func (f *fixture) createUser(t *testing.T, userName string) *scim.UserResource {
+	t.Helper()
+	u, _, err := f.svc.CreateUser(context.Background(), f.tenantID, scim.UserResource{
+		UserName: userName,
+		Name:     &scim.Name{GivenName: "Ana", FamilyName: strings.Split(userName, "@")[0]},
+		Emails:   []scim.MultiValue{{Value: userName, Primary: true}},
+	})
+	require.NoError(t, err)
+	return u
+}
+
+func patch(t *testing.T, ops string) scim.PatchRequest {
+	t.Helper()
+	var p scim.PatchRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"schemas":["`+scim.SchemaPatchOp+`"],"Operations":`+ops+`}`), &p))
+	return p
+}
+
+func TestConfigAndToken(t *testing.T) {
+	f := newFixture(t)
+
+	tenantID, err := f.svc.Authenticate(f.token)
+	require.NoError(t, err)
+	require.Equal(t, f.tenantID, tenantID)
+	_, err = f.svc.Authenticate("scim_wrong")
+	require.ErrorIs(t, err, scim.ErrUnauthorized)
+
+	c, err := f.svc.GetConfig(f.tenantID)
+	require.NoError(t, err)
+	require.NotContains(t, c.TokenHash, f.token)
+	require.True(t, strings.HasPrefix(f.token, c.TokenPrefix))
+	require.NotNil(t, c.TokenLastUsed)
+
+	// Rotating the token retires the old one.
+	rotated, _, err := f.svc.IssueToken(f.admin)
+	require.NoError(t, err)
+	_, err = f.svc.Authenticate(f.token)
+	require.ErrorIs(t, err, scim.ErrUnauthorized)
+	_, err = f.svc.Authenticate(rotated)
+	require.NoError(t, err)
+
+	_, err = f.svc.SaveConfig(f.admin, scim.ConfigInput{Enabled: false, DefaultRole: "Incident Responder"})
+	require.NoError(t, err)
+	_, err = f.svc.Authenticate(rotated)
+	require.ErrorIs(t, err, scim.ErrUnauthorized)
+
+	_, err = f.svc.SaveConfig(f.admin, scim.ConfigInput{DefaultRole: "System Admin"})
+	require.ErrorIs(t, err, scim.ErrInvalidConfig)
+	_, err = f.svc.SaveConfig(f.admin, scim.ConfigInput{DefaultRole: "Incident Responder",
+		GroupMappings: []scim.GroupMapping{{Group: "Everyone"}}})
+	require.ErrorIs(t, err, scim.ErrInvalidConfig)
+}
+
+func TestCreateUserAndFilter(t *testing.T) {
+	f := newFixture(t)
+	ana := f.createUser(t, "ana@example.com")
+	f.createUser(t, "bo@example.com")
+
+	require.Equal(t, "Ana ana", ana.DisplayName)
+	require.True(t, bool(*ana.Active))
+	require.Equal(t, "Incident Responder", ana.Roles[0].Value)
+	require.Equal(t, "https://aegis.example/api/v1/scim/v2/Users/"+ana.ID, ana.Meta.Location)
+
+	_, _, err := f.svc.CreateUser(context.Background(), f.tenantID, scim.UserResource{UserName: "ANA@example.com"})
+	require.ErrorIs(t, err, scim.ErrConflict)
+	_, _, err = f.svc.CreateUser(context.Background(), f.tenantID, scim.UserResource{UserName: "not-an-email"})
+	require.ErrorIs(t, err, scim.ErrInvalidValue)
+
+	for filter, want := range map[string]int{
+		``:                              2,
+		`userName eq "ANA@example.com"`: 1,
+		`emails.value co "example.com"`: 2,
+		`emails[type eq "work" and value sw "bo"]`:                                1,
+		`userName eq "ana@example.com" or userName eq "bo@example.com"`:           2,
+		`not (userName eq "ana@example.com")`:                                     1,
+		`active eq true and name.formatted pr`:                                    2,
+		`urn:ietf:params:scim:schemas:core:2.0:User:userName ew "bo@example.com"`: 1,
+		`meta.lastModified gt "2000-01-01T00:00:00Z"`:                             2,
+	} {
+		res, err := f.svc.ListUsers(f.tenantID, scim.Query{Filter: filter})
+		require.NoError(t, err, filter)
+		require.Equal(t, want, res.TotalResults, filter)
+	}
+	for _, bad := range []string{`userName`, `userName eq`, `userName zz "a"`, `(userName eq "a"`, `userName eq "a`} {
+		_, err := f.svc.ListUsers(f.tenantID, scim.Query{Filter: bad})
+		require.ErrorIs(t, err, scim.ErrInvalidFilter, bad)
+	}
+
+	page, err := f.svc.ListUsers(f.tenantID, scim.Query{StartIndex: 2, Count: 1})
+	require.NoError(t, err)
+	require.Equal(t, 2, page.TotalResults)
+	require.Equal(t, 1, page.ItemsPerPage)
+	require.Equal(t, "bo@example.com", page.Resources[0].(scim.UserResource).UserName)
+}
+
+func TestExistingAccountIsTakenOver(t *testing.T) {
+	f := newFixture(t)
+	tid := uuid.MustParse(f.tenantID)
+	existing := &registration.User{ID: uuid.New(), FullName: "Cy", Email: "cy@example.com", Role: "Tenant Admin", TenantID: &tid}
+	f.users.Add(existing)
+	other := uuid.New()
+	f.users.Add(&registration.User{ID: uuid.New(), Email: "di@example.com", TenantID: &other})
+
+	u, events, err := f.svc.CreateUser(context.Background(), f.tenantID, scim.UserResource{UserName: "cy@example.com"})
+	require.NoError(t, err)
+	require.Equal(t, existing.ID.String(), u.ID)
+	require.Equal(t, "SCIM_LINK_USER", events[0].Action)
+	require.Equal(t, "Tenant Admin", u.Roles[0].Value)
+
+	_, _, err = f.svc.CreateUser(context.Background(), f.tenantID, scim.UserResource{UserName: "di@example.com"})
+	require.ErrorIs(t, err, scim.ErrConflict)
+}
+
+func TestGroupsMapRolesAndTeams(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	ana := f.createUser(t, "ana@example.com")
+	bo := f.createUser(t, "bo@example.com")
+
+	g, events, err := f.svc.CreateGroup(ctx, f.tenantID, scim.GroupResource{
+		DisplayName: "Analysts",
+		Members:     []scim.MultiValue{{Value: ana.ID}, {Value: bo.ID}},
+	})
+	require.NoError(t, err)
+	require.Len(t, g.Members, 2)
+	require.Equal(t, "Ana ana", g.Members[0].Display)
+	require.Equal(t, "SCIM_SYNC_ROLE", events[len(events)-1].Action)
+	require.Equal(t, "Forensic Analyst", f.users.ByID[ana.ID].Role)
+
+	_, _, err = f.svc.CreateGroup(ctx, f.tenantID, scim.GroupResource{DisplayName: "analysts"})
+	require.ErrorIs(t, err, scim.ErrConflict)
+	_, _, err = f.svc.CreateGroup(ctx, f.tenantID, scim.GroupResource{DisplayName: "X", Members: []scim.MultiValue{{Value: uuid.NewString()}}})
+	require.ErrorIs(t, err, scim.ErrInvalidValue)
+
+	// The first mapping that applies wins: DFIR Leads outranks Analysts.
+	leads, _, err := f.svc.CreateGroup(ctx, f.tenantID, scim.GroupResource{DisplayName: "DFIR Leads"})
+	require.NoError(t, err)
+	_, _, err = f.svc.PatchGroup(ctx, f.tenantID, leads.ID, patch(t, `[{"op":"add","path":"members","value":[{"value":"`+ana.ID+`"}]}]`))
+	require.NoError(t, err)
+	require.Equal(t, "DFIR Admin", f.users.ByID[ana.ID].Role)
+	require.Equal(t, f.teamA, *f.users.ByID[ana.ID].TeamID)
+
+	user, err := f.svc.GetUser(f.tenantID, ana.ID)
+	require.NoError(t, err)
+	require.Len(t, user.Groups, 2)
+
+	// Leaving the group falls back to the next mapping; the team stays.
+	_, _, err = f.svc.PatchGroup(ctx, f.tenantID, leads.ID, patch(t, `[{"op":"remove","path":"members[value eq \"`+ana.ID+`\"]"}]`))
+	require.NoError(t, err)
+	require.Equal(t, "Forensic Analyst", f.users.ByID[ana.ID].Role)
+	require.Equal(t, f.teamA, *f.users.ByID[ana.ID].TeamID)
+
+	// Renaming a group re-applies mappings to its members.
+	_, _, err = f.svc.PatchGroup(ctx, f.tenantID, g.ID, patch(t, `[{"op":"replace","value":{"displayName":"Blue Team"}}]`))
+	require.NoError(t, err)
+	require.Equal(t, "Incident Responder", f.users.ByID[bo.ID].Role)
+	require.Equal(t, f.teamB, *f.users.ByID[bo.ID].TeamID)
+
+	// Some directories remove members by value without a filter.
+	_, _, err = f.svc.PatchGroup(ctx, f.tenantID, g.ID, patch(t, `[{"op":"remove","path":"members","value":[{"value":"`+bo.ID+`"}]}]`))
+	require.NoError(t, err)
+	got, err := f.svc.GetGroup(f.tenantID, g.ID)
+	require.NoError(t, err)
+	require.Len(t, got.Members, 1)
+
+	list, err := f.svc.ListGroups(f.tenantID, scim.Query{Filter: `members.value eq "` + ana.ID + `"`, ExcludeMembers: false})
+	require.NoError(t, err)
+	require.Equal(t, 1, list.TotalResults)
+
+	events, err = f.svc.DeleteGroup(ctx, f.tenantID, g.ID)
+	require.NoError(t, err)
+	require.Equal(t, "SCIM_DELETE_GROUP", events[0].Action)
+	require.Equal(t, "Incident Responder", f.users.ByID[ana.ID].Role)
+	_, err = f.svc.GetGroup(f.tenantID, g.ID)
+	require.ErrorIs(t, err, scim.ErrNotFound)
+}
+
+func TestPatchUser(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	ana := f.createUser(t, "ana@example.com")
+
+	u, events, err := f.svc.PatchUser(ctx, f.tenantID, ana.ID, patch(t, `[
+		{"op":"Replace","path":"name.givenName","value":"Anna"},
+		{"op":"replace","path":"name.formatted","value":"Anna Smith"},
+		{"op":"replace","path":"emails[type eq \"work\"].value","value":"anna@example.com"},
+		{"op":"add","path":"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department","value":"IR"},
+		{"op":"replace","value":{"externalId":"00u1"}}
+	]`))
+	require.NoError(t, err)
+	require.Equal(t, "Anna Smith", u.DisplayName)
+	require.Equal(t, "anna@example.com", u.Emails[0].Value)
+	require.Equal(t, "00u1", u.ExternalID)
+	require.Equal(t, "SCIM_UPDATE_USER", events[0].Action)
+	require.Equal(t, "anna@example.com", f.users.ByID[ana.ID].Email)
+
+	_, _, err = f.svc.PatchUser(ctx, f.tenantID, ana.ID, patch(t, `[{"op":"remove"}]`))
+	require.ErrorIs(t, err, scim.ErrNoTarget)
+	_, _, err = f.svc.PatchUser(ctx, f.tenantID, ana.ID, patch(t, `[{"op":"replace","path":"emails[type eq","value":"x"}]`))
+	require.ErrorIs(t, err, scim.ErrInvalidPath)
+	_, _, err = f.svc.PatchUser(ctx, f.tenantID, ana.ID, patch(t, `[{"op":"replace","path":"active","value":"maybe"}]`))
+	require.ErrorIs(t, err, scim.ErrInvalidValue)
+	_, _, err = f.svc.PatchUser(ctx, f.tenantID, uuid.NewString(), patch(t, `[{"op":"replace","path":"active","value":false}]`))
+	require.ErrorIs(t, err, scim.ErrNotFound)
+}
+
+func TestDeprovisioning(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	tid := uuid.MustParse(f.tenantID)
+	lead := &registration.User{ID: uuid.New(), Email: "lead@example.com", Role: "DFIR Admin", TenantID: &tid, TeamID: &f.teamA}
+	f.users.Add(lead)
+
+	ana := f.createUser(t, "ana@example.com")
+	f.users.ByID[ana.ID].TeamID = &f.teamA
+
+	// Azure AD sends booleans as strings.
+	u, events, err := f.svc.PatchUser(ctx, f.tenantID, ana.ID, patch(t, `[{"op":"Replace","path":"active","value":"False"}]`))
+	require.NoError(t, err)
+	require.False(t, bool(*u.Active))
+	require.Equal(t, session.ReasonDeprovisioned, f.sessions.Revoked[ana.ID])
+	require.Equal(t, lead.ID.String(), f.repo.Released[ana.ID], "open work goes to the team's DFIR Admin")
+	require.Equal(t, "SCIM_DEPROVISION_USER", events[0].Action)
+	require.Contains(t, events[0].Description, "2 session(s) revoked")
+	require.Contains(t, events[0].Description, "3 case(s), 1 report(s)")
+	require.NotNil(t, f.users.ByID[ana.ID].DeactivatedAt)
+
+	// Deactivating again is a no-op; reactivating does not restore access.
+	delete(f.repo.Released, ana.ID)
+	_, events, err = f.svc.PatchUser(ctx, f.tenantID, ana.ID, patch(t, `[{"op":"replace","value":{"active":false}}]`))
+	require.NoError(t, err)
+	require.Empty(t, events)
+	require.NotContains(t, f.repo.Released, ana.ID)
+	_, events, err = f.svc.PatchUser(ctx, f.tenantID, ana.ID, patch(t, `[{"op":"replace","path":"active","value":true}]`))
+	require.NoError(t, err)
+	require.Equal(t, "SCIM_REACTIVATE_USER", events[0].Action)
+	require.Nil(t, f.users.ByID[ana.ID].DeactivatedAt)
+
+	// The configured successor is preferred; without any, work is left
+	// and counted.
+	successor := lead.ID.String()
+	_, err = f.svc.SaveConfig(f.admin, scim.ConfigInput{Enabled: true, DefaultRole: "Incident Responder", ReassignTo: &successor})
+	require.NoError(t, err)
+	bo := f.createUser(t, "bo@example.com")
+	events, err = f.svc.DeleteUser(ctx, f.tenantID, bo.ID)
+	require.NoError(t, err)
+	require.Equal(t, []string{"SCIM_DEPROVISION_USER", "SCIM_DELETE_USER"}, []string{events[0].Action, events[1].Action})
+	require.Equal(t, successor, f.repo.Released[bo.ID])
+	_, err = f.svc.GetUser(f.tenantID, bo.ID)
+	require.ErrorIs(t, err, scim.ErrNotFound)
+	require.NotNil(t, f.users.ByID[bo.ID].DeactivatedAt, "the account is kept")
+
+	_, err = f.svc.SaveConfig(f.admin, scim.ConfigInput{Enabled: true, DefaultRole: "Incident Responder"})
+	require.NoError(t, err)
+	cy := f.createUser(t, "cy@example.com")
+	_, events, err = f.svc.ReplaceUser(ctx, f.tenantID, cy.ID, scim.UserResource{UserName: "cy@example.com", Active: boolPtr(false)})
+	require.NoError(t, err)
+	require.Equal(t, "", f.repo.Released[cy.ID])
+	require.Contains(t, events[len(events)-1].Description, "4 open item(s) left unassigned")
+}
+
+func boolPtr(v bool) *scim.Bool {
+	b := scim.Bool(v)
+	return &b
+}
+
+func TestBulk(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	var req scim.BulkRequest
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"schemas": ["`+scim.SchemaBulkRequest+`"],
+		"Operations": [
+			{"method":"POST","bulkId":"u1","path":"/Users","data":{"userName":"ana@example.com"}},
+			{"method":"POST","bulkId":"u2","path":"/Users","data":{"userName":"bo@example.com"}},
+			{"method":"POST","bulkId":"g1","path":"/Groups","data":{"displayName":"Analysts","members":[{"value":"bulkId:u1"},{"value":"bulkId:u2"}]}},
+			{"method":"PATCH","path":"/Users/bulkId:u2","data":{"Operations":[{"op":"replace","path":"active","value":false}]}},
+			{"method":"POST","path":"/Users","data":{"userName":"ana@example.com"}},
+			{"method":"DELETE","path":"/Groups/bulkId:missing"}
+		]
+	}`), &req))
+	resp, events, err := f.svc.Bulk(ctx, f.tenantID, req)
+	require.NoError(t, err)
+	require.Len(t, resp.Operations, 6)
+	statuses := make([]string, len(resp.Operations))
+	for i, op := range resp.Operations {
+		statuses[i] = op.Status
+	}
+	require.Equal(t, []string{"201", "201", "201", "200", "409", "400"}, statuses)
+	require.Contains(t, resp.Operations[0].Location, "/Users/")
+
+	group, err := f.svc.ListGroups(f.tenantID, scim.Query{Filter: `displayName eq "Analysts"`})
+	require.NoError(t, err)
+	require.Len(t, group.Resources[0].(scim.GroupResource).Members, 2)
+	actions := map[string]int{}
+	for _, e := range events {
+		actions[e.Action]++
+	}
+	require.Equal(t, 2, actions["SCIM_PROVISION_USER"])
+	require.Equal(t, 1, actions["SCIM_DEPROVISION_USER"])
+
+	// failOnErrors stops the request after that many failures.
+	req.FailOnErrors = 1
+	resp, _, err = f.svc.Bulk(ctx, f.tenantID, req)
+	require.NoError(t, err)
+	require.Len(t, resp.Operations, 1)
+	require.Equal(t, "409", resp.Operations[0].Status)
+
+	big := scim.BulkRequest{Operations: make([]scim.BulkOperation, 1001)}
+	_, _, err = f.svc.Bulk(ctx, f.tenantID, big)
+	require.ErrorIs(t, err, scim.ErrTooMany)
+}
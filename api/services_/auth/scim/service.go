@@ -0,0 +1,442 @@
+package scim
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"aegis-api/services_/auth/registration"
+	"aegis-api/services_/auth/session"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+var (
+	ErrNotConfigured = errors.New("SCIM is not configured for this tenant")
+	ErrInvalidConfig = errors.New("invalid SCIM configuration")
+	ErrUnauthorized  = errors.New("invalid or disabled SCIM token")
+	ErrNotFound      = errors.New("resource not found")
+	ErrConflict      = errors.New("resource already exists")
+	ErrInvalidValue  = errors.New("invalid value")
+	ErrInvalidFilter = errors.New("invalid filter")
+	ErrInvalidPath   = errors.New("invalid path")
+	ErrNoTarget      = errors.New("no target")
+	ErrTooMany       = errors.New("too many operations")
+)
+
+// tokenPrefix marks SCIM bearer tokens so they are recognisable in logs
+// and secret scanners.
+const tokenPrefix = "scim_"
+
+// unmappableRoles are platform roles a directory must never grant.
+var unmappableRoles = []string{"Admin", "System Admin"}
+
+// Options tunes the SCIM service.
+type Options struct {
+	// BaseURL is the public URL of the SCIM endpoint, used for resource
+	// locations. Default: "/api/v1/scim/v2".
+	BaseURL string
+	// MaxOperations caps a bulk request. Default: 1000.
+	MaxOperations int
+}
+
+type service struct {
+	repo        Repository
+	users       Users
+	provisioner Provisioner
+	sessions    Sessions
+	opts        Options
+	now         func() time.Time
+}
+
+func NewService(repo Repository, users Users, provisioner Provisioner, sessions Sessions, opts Options) Service {
+	if opts.BaseURL == "" {
+		opts.BaseURL = "/api/v1/scim/v2"
+	}
+	opts.BaseURL = strings.TrimRight(opts.BaseURL, "/")
+	if opts.MaxOperations <= 0 {
+		opts.MaxOperations = defaultMaxOperations
+	}
+	return &service{repo: repo, users: users, provisioner: provisioner, sessions: sessions, opts: opts, now: time.Now}
+}
+
+func (s *service) BaseURL() string { return s.opts.BaseURL }
+
+func invalid(format string, a ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidConfig, fmt.Sprintf(format, a...))
+}
+
+func jsonOf(v interface{}) datatypes.JSON {
+	raw, _ := json.Marshal(v)
+	return datatypes.JSON(raw)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ErrorOf converts an error to its SCIM status and response body.
+func ErrorOf(err error) (int, *Error) {
+	status, scimType, detail := http.StatusInternalServerError, "", "An internal error occurred"
+	switch {
+	case errors.Is(err, ErrUnauthorized):
+		status, detail = http.StatusUnauthorized, err.Error()
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrNotConfigured):
+		status, detail = http.StatusNotFound, err.Error()
+	case errors.Is(err, ErrConflict):
+		status, scimType, detail = http.StatusConflict, "uniqueness", err.Error()
+	case errors.Is(err, ErrInvalidFilter):
+		status, scimType, detail = http.StatusBadRequest, "invalidFilter", err.Error()
+	case errors.Is(err, ErrInvalidPath):
+		status, scimType, detail = http.StatusBadRequest, "invalidPath", err.Error()
+	case errors.Is(err, ErrNoTarget):
+		status, scimType, detail = http.StatusBadRequest, "noTarget", err.Error()
+	case errors.Is(err, ErrInvalidValue), errors.Is(err, ErrInvalidConfig):
+		status, scimType, detail = http.StatusBadRequest, "invalidValue", err.Error()
+	case errors.Is(err, ErrTooMany):
+		status, scimType, detail = http.StatusRequestEntityTooLarge, "tooMany", err.Error()
+	}
+	return status, &Error{Schemas: []string{SchemaError}, Status: strconv.Itoa(status), ScimType: scimType, Detail: detail}
+}
+
+// ─── Configuration ─────────────────────────────────
+
+func (s *service) GetConfig(tenantID string) (*Config, error) {
+	c, err := s.repo.GetConfig(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, ErrNotConfigured
+	}
+	return c, nil
+}
+
+func (s *service) SaveConfig(actor Actor, in ConfigInput) (*Config, error) {
+	if err := checkRole(in.DefaultRole); err != nil {
+		return nil, err
+	}
+	for _, m := range in.GroupMappings {
+		if strings.TrimSpace(m.Group) == "" || (m.Role == "" && m.TeamID == "") {
+			return nil, invalid("group mappings need a group and a role or team")
+		}
+		if m.Role != "" {
+			if err := checkRole(m.Role); err != nil {
+				return nil, err
+			}
+		}
+		if m.TeamID != "" {
+			if _, err := uuid.Parse(m.TeamID); err != nil {
+				return nil, invalid("team_id %q is not a team ID", m.TeamID)
+			}
+		}
+	}
+	if in.DefaultTeamID != nil {
+		if _, err := uuid.Parse(*in.DefaultTeamID); err != nil {
+			return nil, invalid("default_team_id must be a team ID")
+		}
+	}
+	if in.ReassignTo != nil {
+		u, err := s.users.GetUserByID(*in.ReassignTo)
+		if err != nil || u == nil || u.ID == uuid.Nil || u.TenantID == nil || u.TenantID.String() != actor.TenantID {
+			return nil, invalid("reassign_to must be a user of the tenant")
+		}
+	}
+
+	existing, err := s.repo.GetConfig(actor.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{TenantID: actor.TenantID}
+	if existing != nil {
+		c = existing
+	}
+	mappings := in.GroupMappings
+	if mappings == nil {
+		mappings = []GroupMapping{}
+	}
+	c.Enabled = in.Enabled
+	c.GroupMappings = jsonOf(mappings)
+	c.DefaultRole = in.DefaultRole
+	c.DefaultTeam = in.DefaultTeamID
+	c.ReassignTo = in.ReassignTo
+	c.UpdatedBy = actor.UserID
+	if err := s.repo.SaveConfig(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func checkRole(role string) error {
+	if role == "" {
+		return invalid("a default role is required")
+	}
+	if slices.Contains(unmappableRoles, role) {
+		return invalid("role %q cannot be granted through SCIM", role)
+	}
+	return nil
+}
+
+func (s *service) DeleteConfig(actor Actor) error {
+	if _, err := s.GetConfig(actor.TenantID); err != nil {
+		return err
+	}
+	return s.repo.DeleteConfig(actor.TenantID)
+}
+
+func (s *service) IssueToken(actor Actor) (string, *Config, error) {
+	c, err := s.GetConfig(actor.TenantID)
+	if err != nil {
+		return "", nil, err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	token := tokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	now := s.now()
+	c.TokenHash = hashToken(token)
+	c.TokenPrefix = token[:len(tokenPrefix)+6]
+	c.TokenIssuedAt, c.TokenLastUsed = &now, nil
+	c.UpdatedBy = actor.UserID
+	if err := s.repo.SaveConfig(c); err != nil {
+		return "", nil, err
+	}
+	return token, c, nil
+}
+
+func (s *service) Authenticate(token string) (string, error) {
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return "", ErrUnauthorized
+	}
+	c, err := s.repo.GetConfigByTokenHash(hashToken(token))
+	if err != nil {
+		return "", err
+	}
+	if c == nil || !c.Enabled {
+		return "", ErrUnauthorized
+	}
+	_ = s.repo.TouchToken(c.TenantID, s.now())
+	return c.TenantID, nil
+}
+
+// ─── Shared ─────────────────────────────────────
+
+func (s *service) location(kind, id string) string {
+	return s.opts.BaseURL + "/" + kind + "/" + id
+}
+
+func meta(kind, location string, created, modified time.Time, version int) *Meta {
+	return &Meta{
+		ResourceType: kind,
+		Created:      &created,
+		LastModified: &modified,
+		Location:     location,
+		Version:      fmt.Sprintf(`W/"%d"`, version),
+	}
+}
+
+// toMap renders a resource as JSON for filtering and patching.
+func toMap(v interface{}) map[string]interface{} {
+	raw, _ := json.Marshal(v)
+	var m map[string]interface{}
+	_ = json.Unmarshal(raw, &m)
+	return m
+}
+
+// fromMap reads a patched resource back.
+func fromMap(m map[string]interface{}, out interface{}) error {
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		if errors.Is(err, ErrInvalidValue) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", ErrInvalidValue, err)
+	}
+	return nil
+}
+
+// list filters and pages rendered resources.
+func list[T any](items []T, q Query) (*ListResponse, error) {
+	var f filter
+	if q.Filter != "" {
+		var err error
+		if f, err = parseFilter(q.Filter); err != nil {
+			return nil, err
+		}
+	}
+	matched := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		if f == nil || f.match(toMap(item)) {
+			matched = append(matched, item)
+		}
+	}
+	start, count := q.StartIndex, q.Count
+	if start < 1 {
+		start = 1
+	}
+	if count <= 0 {
+		count = defaultPageSize
+	}
+	count = min(count, maxPageSize)
+	page := []interface{}{}
+	if start <= len(matched) {
+		end := min(start-1+count, len(matched))
+		page = matched[start-1 : end]
+	}
+	return &ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: len(matched),
+		StartIndex:   start,
+		ItemsPerPage: len(page),
+		Resources:    page,
+	}, nil
+}
+
+// ─── Deprovisioning and group mappings ─────────────
+
+// deprovision deactivates a user, ends their sessions and case access,
+// and hands their open work to a successor.
+func (s *service) deprovision(ctx context.Context, c *Config, user *registration.User) (Event, error) {
+	userID := user.ID.String()
+	successor := s.successor(c, user)
+	rel, err := s.repo.ReleaseUser(userID, successor, s.now())
+	if err != nil {
+		return Event{}, err
+	}
+	now := s.now()
+	user.DeactivatedAt = &now
+	sessions, err := s.sessions.RevokeUser(ctx, userID, session.ReasonDeprovisioned)
+	if err != nil {
+		return Event{}, fmt.Errorf("deactivated %s but could not end sessions: %w", user.Email, err)
+	}
+
+	description := fmt.Sprintf("Deprovisioned %s: %d session(s) revoked, %d case role(s) and %d collaboration(s) removed",
+		user.Email, sessions, rel.CaseRoles, rel.Collaborations)
+	if successor != "" {
+		description += fmt.Sprintf("; %d case(s), %d report(s) and %d thread(s) reassigned to %s",
+			rel.Cases, rel.Reports, rel.Threads, successor)
+	} else if rel.Unassigned > 0 {
+		description += fmt.Sprintf("; %d open item(s) left unassigned (no successor)", rel.Unassigned)
+	}
+	return Event{Action: "SCIM_DEPROVISION_USER", TargetType: "user", TargetID: userID, Description: description}, nil
+}
+
+// successor is who takes over a deprovisioned user's open work: the
+// tenant's configured user, else the DFIR Admin of the user's team.
+func (s *service) successor(c *Config, user *registration.User) string {
+	active := func(u *registration.User) bool {
+		return u != nil && u.ID != uuid.Nil && u.ID != user.ID && u.DeactivatedAt == nil
+	}
+	if c.ReassignTo != nil {
+		if u, err := s.users.GetUserByID(*c.ReassignTo); err == nil && active(u) {
+			return u.ID.String()
+		}
+	}
+	if user.TeamID != nil {
+		if u, err := s.users.FindByTeamIDAndRole(*user.TeamID, "DFIR Admin"); err == nil && active(u) {
+			return u.ID.String()
+		}
+	}
+	return ""
+}
+
+func (s *service) reactivate(user *registration.User) (Event, error) {
+	user.DeactivatedAt = nil
+	if err := s.users.UpdateUser(user); err != nil {
+		return Event{}, err
+	}
+	return Event{Action: "SCIM_REACTIVATE_USER", TargetType: "user", TargetID: user.ID.String(),
+		Description: fmt.Sprintf("Reactivated %s; case access is not restored", user.Email)}, nil
+}
+
+// mapGroups resolves the role and team the user's groups give. The
+// first mapping that applies wins; without one the defaults apply, and a
+// user with no team mapping keeps their team.
+func mapGroups(c *Config, groups []Group, current *uuid.UUID) (string, *uuid.UUID) {
+	var mappings []GroupMapping
+	_ = json.Unmarshal(c.GroupMappings, &mappings)
+	role, team := "", (*uuid.UUID)(nil)
+	for _, m := range mappings {
+		in := slices.ContainsFunc(groups, func(g Group) bool { return strings.EqualFold(g.DisplayName, m.Group) })
+		if !in {
+			continue
+		}
+		if role == "" && m.Role != "" {
+			role = m.Role
+		}
+		if team == nil && m.TeamID != "" {
+			if id, err := uuid.Parse(m.TeamID); err == nil {
+				team = &id
+			}
+		}
+	}
+	if role == "" {
+		role = c.DefaultRole
+	}
+	if team == nil && c.DefaultTeam != nil {
+		if id, err := uuid.Parse(*c.DefaultTeam); err == nil {
+			team = &id
+		}
+	}
+	if team == nil {
+		team = current
+	}
+	return role, team
+}
+
+// syncUsers re-applies the group mappings to users whose groups changed.
+func (s *service) syncUsers(tenantID string, userIDs []string) ([]Event, error) {
+	c, err := s.GetConfig(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	for _, id := range userIDs {
+		user, err := s.users.GetUserByID(id)
+		if err != nil || user == nil || user.ID == uuid.Nil || user.DeactivatedAt != nil {
+			continue
+		}
+		groups, err := s.repo.GroupsOfUser(id)
+		if err != nil {
+			return events, err
+		}
+		role, team := mapGroups(c, groups, user.TeamID)
+		if role == user.Role && sameTeam(team, user.TeamID) {
+			continue
+		}
+		user.Role, user.TeamID = role, team
+		if err := s.users.UpdateUser(user); err != nil {
+			return events, err
+		}
+		teamDesc := "no team"
+		if team != nil {
+			teamDesc = "team " + team.String()
+		}
+		events = append(events, Event{Action: "SCIM_SYNC_ROLE", TargetType: "user", TargetID: id,
+			Description: fmt.Sprintf("Role %s, %s from directory groups", role, teamDesc)})
+	}
+	return events, nil
+}
+
+func sameTeam(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
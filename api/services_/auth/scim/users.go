@@ -0,0 +1,344 @@
+package scim
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"aegis-api/services_/auth/registration"
+
+	"github.com/google/uuid"
+)
+
+func (s *service) renderUser(l *UserLink, u *registration.User, groups []Group) UserResource {
+	r := UserResource{
+		Schemas:     []string{SchemaUser},
+		ID:          l.UserID,
+		ExternalID:  l.ExternalID,
+		UserName:    l.UserName,
+		Name:        &Name{Formatted: u.FullName},
+		DisplayName: u.FullName,
+		Emails:      []MultiValue{{Value: u.Email, Type: "work", Primary: true}},
+		Active:      boolPtr(u.DeactivatedAt == nil),
+		Roles:       []MultiValue{{Value: u.Role, Primary: true}},
+		Meta:        meta(ResourceTypeUser, s.location("Users", l.UserID), l.CreatedAt, l.UpdatedAt, l.Version),
+	}
+	for _, g := range groups {
+		r.Groups = append(r.Groups, MultiValue{Value: g.ID, Display: g.DisplayName, Ref: s.location("Groups", g.ID)})
+	}
+	return r
+}
+
+func (s *service) loadUser(tenantID, id string) (*UserLink, *registration.User, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, nil, ErrNotFound
+	}
+	l, err := s.repo.GetUserLink(tenantID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if l == nil {
+		return nil, nil, ErrNotFound
+	}
+	u, err := s.users.GetUserByID(id)
+	if err != nil || u == nil || u.ID == uuid.Nil {
+		return nil, nil, ErrNotFound
+	}
+	return l, u, nil
+}
+
+func (s *service) userResource(l *UserLink, u *registration.User) (*UserResource, error) {
+	groups, err := s.repo.GroupsOfUser(l.UserID)
+	if err != nil {
+		return nil, err
+	}
+	r := s.renderUser(l, u, groups)
+	return &r, nil
+}
+
+func (s *service) ListUsers(tenantID string, q Query) (*ListResponse, error) {
+	links, err := s.repo.ListUserLinks(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(links))
+	for i, l := range links {
+		ids[i] = l.UserID
+	}
+	users, err := s.repo.FindUsers(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*registration.User, len(users))
+	for i := range users {
+		byID[users[i].ID.String()] = &users[i]
+	}
+
+	groups, err := s.repo.ListGroups(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	groupByID := make(map[string]Group, len(groups))
+	groupIDs := make([]string, len(groups))
+	for i, g := range groups {
+		groupByID[g.ID], groupIDs[i] = g, g.ID
+	}
+	members, err := s.repo.Members(groupIDs)
+	if err != nil {
+		return nil, err
+	}
+	userGroups := map[string][]Group{}
+	for _, m := range members {
+		userGroups[m.UserID] = append(userGroups[m.UserID], groupByID[m.GroupID])
+	}
+
+	out := make([]UserResource, 0, len(links))
+	for i := range links {
+		if u, ok := byID[links[i].UserID]; ok {
+			out = append(out, s.renderUser(&links[i], u, userGroups[links[i].UserID]))
+		}
+	}
+	return list(out, q)
+}
+
+func (s *service) GetUser(tenantID, id string) (*UserResource, error) {
+	l, u, err := s.loadUser(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.userResource(l, u)
+}
+
+// identity reads the email and name a directory sends. The email is the
+// primary email, else the first, else the userName.
+func identity(in UserResource) (email, name string, err error) {
+	userName := strings.TrimSpace(in.UserName)
+	if userName == "" {
+		return "", "", fmt.Errorf("%w: userName is required", ErrInvalidValue)
+	}
+	email = userName
+	for i, e := range in.Emails {
+		if e.Primary || i == 0 {
+			email = e.Value
+		}
+		if e.Primary {
+			break
+		}
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return "", "", fmt.Errorf("%w: an email address is required in emails or userName", ErrInvalidValue)
+	}
+
+	switch {
+	case in.Name != nil && strings.TrimSpace(in.Name.Formatted) != "":
+		name = in.Name.Formatted
+	case in.Name != nil && strings.TrimSpace(in.Name.GivenName+in.Name.FamilyName) != "":
+		name = in.Name.GivenName + " " + in.Name.FamilyName
+	case in.DisplayName != "":
+		name = in.DisplayName
+	default:
+		name = userName
+	}
+	return email, strings.TrimSpace(name), nil
+}
+
+func (s *service) checkUserName(tenantID, userName, except string) error {
+	links, err := s.repo.ListUserLinks(tenantID)
+	if err != nil {
+		return err
+	}
+	for _, l := range links {
+		if l.UserID != except && strings.EqualFold(l.UserName, strings.TrimSpace(userName)) {
+			return fmt.Errorf("%w: userName %q is taken", ErrConflict, userName)
+		}
+	}
+	return nil
+}
+
+func (s *service) CreateUser(ctx context.Context, tenantID string, in UserResource) (*UserResource, []Event, error) {
+	c, err := s.GetConfig(tenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	email, name, err := identity(in)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkUserName(tenantID, in.UserName, ""); err != nil {
+		return nil, nil, err
+	}
+	tid, err := uuid.Parse(tenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var events []Event
+	user, err := s.users.GetUserByEmail(email)
+	if err == nil && user != nil && user.ID != uuid.Nil {
+		// An account created before the directory was connected is taken
+		// over rather than duplicated.
+		if user.TenantID == nil || *user.TenantID != tid {
+			return nil, nil, fmt.Errorf("%w: email %s is already in use", ErrConflict, email)
+		}
+		existing, err := s.repo.GetUserLink(tenantID, user.ID.String())
+		if err != nil {
+			return nil, nil, err
+		}
+		if existing != nil {
+			return nil, nil, fmt.Errorf("%w: user %s is already provisioned", ErrConflict, email)
+		}
+		events = append(events, Event{Action: "SCIM_LINK_USER", TargetType: "user", TargetID: user.ID.String(),
+			Description: fmt.Sprintf("Directory took over existing account %s", email)})
+	} else {
+		role, team := mapGroups(c, nil, nil)
+		created, err := s.provisioner.ProvisionUser(registration.ProvisionRequest{
+			FullName: name,
+			Email:    email,
+			Role:     role,
+			TenantID: tid,
+			TeamID:   team,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to provision user: %w", err)
+		}
+		user = &created
+		events = append(events, Event{Action: "SCIM_PROVISION_USER", TargetType: "user", TargetID: user.ID.String(),
+			Description: fmt.Sprintf("Provisioned %s as %s", email, role)})
+	}
+
+	l := &UserLink{
+		UserID:     user.ID.String(),
+		TenantID:   tenantID,
+		ExternalID: in.ExternalID,
+		UserName:   strings.TrimSpace(in.UserName),
+		Version:    1,
+	}
+	ev, err := s.setActive(ctx, c, l, user, in.Active == nil || bool(*in.Active))
+	if err != nil {
+		return nil, events, err
+	}
+	events = append(events, ev...)
+	if err := s.repo.SaveUserLink(l); err != nil {
+		return nil, events, err
+	}
+	r, err := s.userResource(l, user)
+	return r, events, err
+}
+
+func (s *service) ReplaceUser(ctx context.Context, tenantID, id string, in UserResource) (*UserResource, []Event, error) {
+	l, u, err := s.loadUser(tenantID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.replaceUser(ctx, l, u, in)
+}
+
+func (s *service) PatchUser(ctx context.Context, tenantID, id string, p PatchRequest) (*UserResource, []Event, error) {
+	l, u, err := s.loadUser(tenantID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc := toMap(s.renderUser(l, u, nil))
+	if err := applyPatch(doc, p.Operations); err != nil {
+		return nil, nil, err
+	}
+	var in UserResource
+	if err := fromMap(doc, &in); err != nil {
+		return nil, nil, err
+	}
+	return s.replaceUser(ctx, l, u, in)
+}
+
+func (s *service) replaceUser(ctx context.Context, l *UserLink, u *registration.User, in UserResource) (*UserResource, []Event, error) {
+	c, err := s.GetConfig(l.TenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	email, name, err := identity(in)
+	if err != nil {
+		return nil, nil, err
+	}
+	userName := strings.TrimSpace(in.UserName)
+	if !strings.EqualFold(userName, l.UserName) {
+		if err := s.checkUserName(l.TenantID, userName, l.UserID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var events []Event
+	var changed []string
+	if email != strings.ToLower(u.Email) {
+		other, err := s.users.GetUserByEmail(email)
+		if err == nil && other != nil && other.ID != uuid.Nil && other.ID != u.ID {
+			return nil, nil, fmt.Errorf("%w: email %s is already in use", ErrConflict, email)
+		}
+		changed = append(changed, fmt.Sprintf("email %s → %s", u.Email, email))
+		u.Email = email
+	}
+	if name != u.FullName {
+		changed = append(changed, fmt.Sprintf("name %q → %q", u.FullName, name))
+		u.FullName = name
+	}
+	if len(changed) > 0 {
+		if err := s.users.UpdateUser(u); err != nil {
+			return nil, nil, err
+		}
+		events = append(events, Event{Action: "SCIM_UPDATE_USER", TargetType: "user", TargetID: l.UserID,
+			Description: "Directory updated " + strings.Join(changed, ", ")})
+	}
+
+	l.UserName, l.ExternalID = userName, in.ExternalID
+	ev, err := s.setActive(ctx, c, l, u, in.Active == nil || bool(*in.Active))
+	if err != nil {
+		return nil, events, err
+	}
+	events = append(events, ev...)
+	l.Version++
+	if err := s.repo.SaveUserLink(l); err != nil {
+		return nil, events, err
+	}
+	r, err := s.userResource(l, u)
+	return r, events, err
+}
+
+// setActive deprovisions or reactivates the user when active changes.
+func (s *service) setActive(ctx context.Context, c *Config, l *UserLink, u *registration.User, active bool) ([]Event, error) {
+	l.Active = active
+	switch {
+	case !active && u.DeactivatedAt == nil:
+		ev, err := s.deprovision(ctx, c, u)
+		if err != nil {
+			return nil, err
+		}
+		return []Event{ev}, nil
+	case active && u.DeactivatedAt != nil:
+		ev, err := s.reactivate(u)
+		if err != nil {
+			return nil, err
+		}
+		return []Event{ev}, nil
+	}
+	return nil, nil
+}
+
+func (s *service) DeleteUser(ctx context.Context, tenantID, id string) ([]Event, error) {
+	l, u, err := s.loadUser(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.GetConfig(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.setActive(ctx, c, l, u, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.DeleteUserLink(tenantID, id); err != nil {
+		return events, err
+	}
+	return append(events, Event{Action: "SCIM_DELETE_USER", TargetType: "user", TargetID: id,
+		Description: fmt.Sprintf("Directory deleted %s; the account is kept deactivated", u.Email)}), nil
+}
@@ -0,0 +1,54 @@
+package session
+
+import "strings"
+
+// deviceName returns the name the client gave, or one guessed from its
+// user agent such as "Firefox on Windows".
+func deviceName(c Client) string {
+	if name := strings.TrimSpace(c.DeviceName); name != "" {
+		if len(name) > 255 {
+			name = name[:255]
+		}
+		return name
+	}
+	ua := c.UserAgent
+	if ua == "" {
+		return "Unknown device"
+	}
+	browser := firstMatch(ua, [][2]string{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+		{"PostmanRuntime/", "Postman"},
+	})
+	os := firstMatch(ua, [][2]string{
+		{"Windows", "Windows"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Android", "Android"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	})
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	}
+	return "Unknown device"
+}
+
+func firstMatch(s string, pairs [][2]string) string {
+	for _, p := range pairs {
+		if strings.Contains(s, p[0]) {
+			return p[1]
+		}
+	}
+	return ""
+}
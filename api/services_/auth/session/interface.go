@@ -0,0 +1,65 @@
+package session
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	AutoMigrate() error
+
+	// CreateSession stores a session with its first refresh token.
+	CreateSession(s *Session, t *RefreshToken) error
+	GetSession(sessionID string) (*Session, error)
+	// ListSessions returns the user's sessions still active at now,
+	// most recently used first.
+	ListSessions(userID string, now time.Time) ([]Session, error)
+	// TouchSession records activity on a session. Empty client fields
+	// are left as they are.
+	TouchSession(sessionID string, at time.Time, c Client) error
+	// RevokeSession returns false if the session was already revoked.
+	RevokeSession(sessionID, reason string, at time.Time) (bool, error)
+	// RevokeUserSessions revokes every active session of the user and
+	// returns their IDs.
+	RevokeUserSessions(userID, reason string, at time.Time) ([]string, error)
+
+	GetRefreshToken(hash string) (*RefreshToken, error)
+	// ExchangeRefreshToken marks a token used and stores its successor.
+	// It returns false if the token had already been used.
+	ExchangeRefreshToken(tokenID string, at time.Time, next *RefreshToken) (bool, error)
+
+	// TokenVersion returns users.token_version.
+	TokenVersion(userID string) (int, error)
+	// BumpTokenVersion increments users.token_version and returns the
+	// new value.
+	BumpTokenVersion(userID string) (int, error)
+}
+
+type Service interface {
+	// Create starts a session for a user who has just authenticated. The
+	// session ends at the configured maximum age, or at notAfter if that
+	// is earlier.
+	Create(userID, tenantID string, tokenVersion int, c Client, notAfter *time.Time) (*Issued, error)
+	// Rotate exchanges a refresh token for a new one. Presenting a token
+	// that was already exchanged revokes its session.
+	Rotate(ctx context.Context, refreshToken string, c Client) (*Issued, error)
+	// ValidateSession checks an access token's user token version and
+	// session against revocations. Tokens without a session ID are
+	// refused. Results are cached for the configured TTL;
+	// revocations through this service take effect at once.
+	ValidateSession(ctx context.Context, userID, sessionID string, tokenVersion int) error
+
+	// ListSessions returns the user's active sessions, marking current.
+	ListSessions(userID, current string) ([]Session, error)
+	// RevokeSession ends one of the user's sessions.
+	RevokeSession(ctx context.Context, userID, sessionID, reason string) error
+	// RevokeUser ends every session of the user and bumps their token
+	// version. It returns the number of sessions revoked.
+	RevokeUser(ctx context.Context, userID, reason string) (int, error)
+	// ForgetTokenVersion drops the cached token version of a user whose
+	// version was changed elsewhere.
+	ForgetTokenVersion(ctx context.Context, userID string)
+
+	// AccessTTL is how long access tokens should live.
+	AccessTTL() time.Duration
+}
@@ -0,0 +1,74 @@
+package session
+
+import "time"
+
+// Revocation reasons recorded on sessions.
+const (
+	ReasonLogout        = "logout"
+	ReasonLogoutAll     = "logout_all"
+	ReasonRevoked       = "revoked"
+	ReasonAdminRevoked  = "admin_revoked"
+	ReasonTokenReuse    = "refresh_token_reuse"
+	ReasonTokenVersion  = "token_version_changed"
+	ReasonAccessRevoked = "access_revoked"
+	ReasonSingleLogout  = "single_logout"
+	ReasonDeprovisioned = "deprovisioned"
+)
+
+// Session is one sign-in of a user on a device. Access tokens carry its
+// ID; it lives on through refresh token rotation until it is revoked or
+// reaches ExpiresAt.
+type Session struct {
+	ID       string `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID   string `gorm:"type:uuid;not null;index" json:"user_id"`
+	TenantID string `gorm:"type:uuid;index" json:"tenant_id,omitempty"`
+	// TokenVersion is the user's token_version at sign-in. Bumping the
+	// user's version ends every session started before.
+	TokenVersion  int        `gorm:"not null" json:"-"`
+	DeviceName    string     `gorm:"type:varchar(255)" json:"device_name"`
+	IPAddress     string     `gorm:"type:varchar(64)" json:"ip_address"`
+	UserAgent     string     `gorm:"type:text" json:"user_agent"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	LastActiveAt  time.Time  `gorm:"not null" json:"last_active_at"`
+	ExpiresAt     time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason string     `gorm:"type:varchar(50)" json:"revoked_reason,omitempty"`
+	// Current marks the session of the request listing the sessions.
+	Current bool `gorm:"-" json:"current"`
+}
+
+func (Session) TableName() string { return "auth_sessions" }
+
+// Active reports whether the session can still be used at now.
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// RefreshToken is one link of a session's refresh token chain. Only its
+// hash is stored. A token is exchanged once; presenting it again means
+// it leaked, and the session is revoked.
+type RefreshToken struct {
+	ID        string    `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	SessionID string    `gorm:"type:uuid;not null;index"`
+	TokenHash string    `gorm:"type:char(64);not null;uniqueIndex"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+}
+
+func (RefreshToken) TableName() string { return "auth_refresh_tokens" }
+
+// Client describes the device a session is used from.
+type Client struct {
+	DeviceName string
+	IPAddress  string
+	UserAgent  string
+}
+
+// Issued is a session with a fresh refresh token. The token itself is
+// only ever returned here.
+type Issued struct {
+	Session          *Session
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
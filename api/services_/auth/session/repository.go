@@ -0,0 +1,139 @@
+package session
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) AutoMigrate() error {
+	return r.db.AutoMigrate(&Session{}, &RefreshToken{})
+}
+
+func (r *GormRepository) CreateSession(s *Session, t *RefreshToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(s).Error; err != nil {
+			return err
+		}
+		t.SessionID = s.ID
+		return tx.Create(t).Error
+	})
+}
+
+func (r *GormRepository) GetSession(sessionID string) (*Session, error) {
+	var s Session
+	err := r.db.Where("id = ?", sessionID).First(&s).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *GormRepository) ListSessions(userID string, now time.Time) ([]Session, error) {
+	var out []Session
+	err := r.db.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_active_at DESC").
+		Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) TouchSession(sessionID string, at time.Time, c Client) error {
+	updates := map[string]any{"last_active_at": at}
+	if c.IPAddress != "" {
+		updates["ip_address"] = c.IPAddress
+	}
+	if c.UserAgent != "" {
+		updates["user_agent"] = c.UserAgent
+	}
+	return r.db.Model(&Session{}).Where("id = ?", sessionID).Updates(updates).Error
+}
+
+func (r *GormRepository) RevokeSession(sessionID, reason string, at time.Time) (bool, error) {
+	res := r.db.Model(&Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Updates(map[string]any{"revoked_at": at, "revoked_reason": reason})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *GormRepository) RevokeUserSessions(userID, reason string, at time.Time) ([]string, error) {
+	var ids []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return tx.Model(&Session{}).
+			Where("id IN ? AND revoked_at IS NULL", ids).
+			Updates(map[string]any{"revoked_at": at, "revoked_reason": reason}).Error
+	})
+	return ids, err
+}
+
+func (r *GormRepository) GetRefreshToken(hash string) (*RefreshToken, error) {
+	var t RefreshToken
+	err := r.db.Where("token_hash = ?", hash).First(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *GormRepository) ExchangeRefreshToken(tokenID string, at time.Time, next *RefreshToken) (bool, error) {
+	exchanged := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", tokenID).
+			Update("used_at", at)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		exchanged = true
+		return tx.Create(next).Error
+	})
+	return exchanged, err
+}
+
+func (r *GormRepository) TokenVersion(userID string) (int, error) {
+	var versions []int
+	if err := r.db.Table("users").Where("id = ?", userID).Pluck("token_version", &versions).Error; err != nil {
+		return 0, err
+	}
+	if len(versions) == 0 {
+		return 0, ErrUserNotFound
+	}
+	return versions[0], nil
+}
+
+func (r *GormRepository) BumpTokenVersion(userID string) (int, error) {
+	var versions []int
+	err := r.db.Raw(
+		`UPDATE users SET token_version = token_version + 1 WHERE id = ? RETURNING token_version`, userID,
+	).Scan(&versions).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(versions) == 0 {
+		return 0, ErrUserNotFound
+	}
+	return versions[0], nil
+}
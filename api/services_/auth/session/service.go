@@ -0,0 +1,294 @@
+// Package session keeps server-side sessions behind the short-lived
+// access tokens: rotating refresh tokens, the list of a user's signed-in
+// devices, and revocation by session or by user.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"aegis-api/cache"
+)
+
+var (
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrSessionRevoked      = errors.New("session has been revoked or has expired")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used; the session has been revoked")
+	ErrUserNotFound        = errors.New("user not found")
+)
+
+// revokedMarker is cached for a session known to be unusable.
+const revokedMarker = "revoked"
+
+// Options tunes token lifetimes and caching.
+type Options struct {
+	// AccessTTL is the lifetime of access tokens. Default 15 minutes.
+	AccessTTL time.Duration
+	// RefreshTTL is how long a refresh token may go unused before the
+	// session lapses. Default 7 days.
+	RefreshTTL time.Duration
+	// MaxAge bounds a session however often it is refreshed. Default 30
+	// days.
+	MaxAge time.Duration
+	// CacheTTL is how long validation results are cached, and so how
+	// often a session's last activity is written. Default 1 minute.
+	CacheTTL time.Duration
+}
+
+type service struct {
+	repo  Repository
+	cache cache.Client
+	opts  Options
+	now   func() time.Time
+}
+
+func NewService(repo Repository, c cache.Client, opts Options) Service {
+	if opts.AccessTTL <= 0 {
+		opts.AccessTTL = 15 * time.Minute
+	}
+	if opts.RefreshTTL <= 0 {
+		opts.RefreshTTL = 7 * 24 * time.Hour
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = 30 * 24 * time.Hour
+	}
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = time.Minute
+	}
+	if c == nil {
+		c = cache.NewMemory()
+	}
+	return &service{repo: repo, cache: c, opts: opts, now: time.Now}
+}
+
+func (s *service) AccessTTL() time.Duration { return s.opts.AccessTTL }
+
+func (s *service) Create(userID, tenantID string, tokenVersion int, c Client, notAfter *time.Time) (*Issued, error) {
+	now := s.now()
+	expires := now.Add(s.opts.MaxAge)
+	if notAfter != nil && notAfter.Before(expires) {
+		expires = *notAfter
+	}
+	if !now.Before(expires) {
+		return nil, ErrSessionRevoked
+	}
+	sess := &Session{
+		UserID:       userID,
+		TenantID:     tenantID,
+		TokenVersion: tokenVersion,
+		DeviceName:   deviceName(c),
+		IPAddress:    c.IPAddress,
+		UserAgent:    c.UserAgent,
+		LastActiveAt: now,
+		ExpiresAt:    expires,
+	}
+	raw, tok, err := s.newRefreshToken(sess, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateSession(sess, tok); err != nil {
+		return nil, err
+	}
+	return &Issued{Session: sess, RefreshToken: raw, RefreshExpiresAt: tok.ExpiresAt}, nil
+}
+
+func (s *service) Rotate(ctx context.Context, refreshToken string, c Client) (*Issued, error) {
+	now := s.now()
+	tok, err := s.repo.GetRefreshToken(hashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	sess, err := s.repo.GetSession(tok.SessionID)
+	if errors.Is(err, ErrSessionNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tok.UsedAt != nil {
+		s.revoke(ctx, sess.ID, ReasonTokenReuse)
+		return nil, ErrRefreshTokenReused
+	}
+	if !sess.Active(now) {
+		return nil, ErrSessionRevoked
+	}
+	if !now.Before(tok.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	raw, next, err := s.newRefreshToken(sess, now)
+	if err != nil {
+		return nil, err
+	}
+	next.SessionID = sess.ID
+	ok, err := s.repo.ExchangeRefreshToken(tok.ID, now, next)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// Used concurrently by someone else.
+		s.revoke(ctx, sess.ID, ReasonTokenReuse)
+		return nil, ErrRefreshTokenReused
+	}
+	if err := s.repo.TouchSession(sess.ID, now, c); err != nil {
+		log.Printf("session: recording activity on %s: %v", sess.ID, err)
+	}
+	sess.LastActiveAt = now
+	if c.IPAddress != "" {
+		sess.IPAddress = c.IPAddress
+	}
+	if c.UserAgent != "" {
+		sess.UserAgent = c.UserAgent
+	}
+	return &Issued{Session: sess, RefreshToken: raw, RefreshExpiresAt: next.ExpiresAt}, nil
+}
+
+// newRefreshToken returns a random token and its record. The token lives
+// for RefreshTTL, but not past the session.
+func (s *service) newRefreshToken(sess *Session, now time.Time) (string, *RefreshToken, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	raw := base64.RawURLEncoding.EncodeToString(b)
+	expires := now.Add(s.opts.RefreshTTL)
+	if sess.ExpiresAt.Before(expires) {
+		expires = sess.ExpiresAt
+	}
+	return raw, &RefreshToken{TokenHash: hashToken(raw), ExpiresAt: expires}, nil
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *service) ValidateSession(ctx context.Context, userID, sessionID string, tokenVersion int) error {
+	// Every access token is issued with a session; one without cannot be
+	// revoked, listed or bounded, so it is not accepted.
+	if sessionID == "" {
+		return ErrSessionRevoked
+	}
+	current, err := s.tokenVersion(ctx, userID)
+	if errors.Is(err, ErrUserNotFound) {
+		return ErrSessionRevoked
+	}
+	if err != nil {
+		return err
+	}
+	if tokenVersion != current {
+		return ErrSessionRevoked
+	}
+
+	key := cache.SessionKey(sessionID)
+	if v, ok, err := s.cache.Get(ctx, key); err == nil && ok {
+		if v != userID {
+			return ErrSessionRevoked
+		}
+		return nil
+	}
+
+	now := s.now()
+	sess, err := s.repo.GetSession(sessionID)
+	if errors.Is(err, ErrSessionNotFound) {
+		return ErrSessionRevoked
+	}
+	if err != nil {
+		return err
+	}
+	if sess.UserID != userID || sess.TokenVersion != current || !sess.Active(now) {
+		_ = s.cache.Set(ctx, key, revokedMarker, s.opts.CacheTTL)
+		return ErrSessionRevoked
+	}
+	// A cache miss is at most once per CacheTTL, which bounds how often
+	// last activity is written.
+	if err := s.repo.TouchSession(sessionID, now, Client{}); err != nil {
+		log.Printf("session: recording activity on %s: %v", sessionID, err)
+	}
+	ttl := s.opts.CacheTTL
+	if left := sess.ExpiresAt.Sub(now); left < ttl {
+		ttl = left
+	}
+	_ = s.cache.Set(ctx, key, userID, ttl)
+	return nil
+}
+
+func (s *service) tokenVersion(ctx context.Context, userID string) (int, error) {
+	key := cache.TokenVersionKey(userID)
+	if v, ok, err := s.cache.Get(ctx, key); err == nil && ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n, nil
+		}
+	}
+	n, err := s.repo.TokenVersion(userID)
+	if err != nil {
+		return 0, err
+	}
+	_ = s.cache.Set(ctx, key, strconv.Itoa(n), s.opts.CacheTTL)
+	return n, nil
+}
+
+func (s *service) ListSessions(userID, current string) ([]Session, error) {
+	out, err := s.repo.ListSessions(userID, s.now())
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i].Current = out[i].ID == current
+	}
+	return out, nil
+}
+
+func (s *service) RevokeSession(ctx context.Context, userID, sessionID, reason string) error {
+	sess, err := s.repo.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if sess.UserID != userID {
+		return ErrSessionNotFound
+	}
+	if _, err := s.repo.RevokeSession(sessionID, reason, s.now()); err != nil {
+		return err
+	}
+	_ = s.cache.Set(ctx, cache.SessionKey(sessionID), revokedMarker, s.opts.CacheTTL)
+	return nil
+}
+
+// revoke ends a session found to be compromised. Failures are logged:
+// the caller is already refusing the request.
+func (s *service) revoke(ctx context.Context, sessionID, reason string) {
+	if _, err := s.repo.RevokeSession(sessionID, reason, s.now()); err != nil {
+		log.Printf("session: revoking %s: %v", sessionID, err)
+		return
+	}
+	_ = s.cache.Set(ctx, cache.SessionKey(sessionID), revokedMarker, s.opts.CacheTTL)
+}
+
+func (s *service) RevokeUser(ctx context.Context, userID, reason string) (int, error) {
+	version, err := s.repo.BumpTokenVersion(userID)
+	if err != nil {
+		return 0, err
+	}
+	_ = s.cache.Set(ctx, cache.TokenVersionKey(userID), strconv.Itoa(version), s.opts.CacheTTL)
+
+	ids, err := s.repo.RevokeUserSessions(userID, reason, s.now())
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		_ = s.cache.Set(ctx, cache.SessionKey(id), revokedMarker, s.opts.CacheTTL)
+	}
+	return len(ids), nil
+}
+
+func (s *service) ForgetTokenVersion(ctx context.Context, userID string) {
+	_, _ = s.cache.Del(ctx, cache.TokenVersionKey(userID))
+}
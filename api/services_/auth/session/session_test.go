@@ -0,0 +1,126 @@
+package session_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"aegis-api/cache"
+	"aegis-api/services_/auth/session"
+	"aegis-api/tests/fakes"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var client = session.Client{IPAddress: "203.0.113.7", UserAgent: "Mozilla/5.0 (Windows NT 10.0) Gecko/20100101 Firefox/128.0"}
+
+func newService(t *testing.T) (session.Service, *fakes.Sessions, string) {
+	t.Helper()
+	repo := &fakes.Sessions{}
+	userID := uuid.NewString()
+	repo.AddUser(userID)
+	return session.NewService(repo, cache.NewMemory(), session.Options{}), repo, userID
+}
+
+func TestCreateAndValidate(t *testing.T) {
+	svc, repo, userID := newService(t)
+	ctx := context.Background()
+
+	issued, err := svc.Create(userID, uuid.NewString(), 1, client, nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, issued.RefreshToken)
+	require.Equal(t, "Firefox on Windows", issued.Session.DeviceName)
+	require.Equal(t, 15*time.Minute, svc.AccessTTL())
+
+	require.NoError(t, svc.ValidateSession(ctx, userID, issued.Session.ID, 1))
+	require.NoError(t, svc.ValidateSession(ctx, userID, issued.Session.ID, 1))
+	require.Equal(t, 1, repo.Lookups, "second check is served from the cache")
+
+	require.ErrorIs(t, svc.ValidateSession(ctx, userID, issued.Session.ID, 0), session.ErrSessionRevoked)
+	require.ErrorIs(t, svc.ValidateSession(ctx, uuid.NewString(), issued.Session.ID, 1), session.ErrSessionRevoked)
+	// Tokens without a session cannot be revoked, so they are refused.
+	require.ErrorIs(t, svc.ValidateSession(ctx, userID, "", 1), session.ErrSessionRevoked)
+
+	list, err := svc.ListSessions(userID, issued.Session.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.True(t, list[0].Current)
+}
+
+func TestSessionEndsAtNotAfter(t *testing.T) {
+	svc, _, userID := newService(t)
+
+	soon := time.Now().Add(time.Hour)
+	issued, err := svc.Create(userID, "", 1, client, &soon)
+	require.NoError(t, err)
+	require.True(t, issued.Session.ExpiresAt.Equal(soon))
+	require.True(t, issued.RefreshExpiresAt.Equal(soon))
+
+	past := time.Now().Add(-time.Minute)
+	_, err = svc.Create(userID, "", 1, client, &past)
+	require.ErrorIs(t, err, session.ErrSessionRevoked)
+}
+
+func TestRotateDetectsReuse(t *testing.T) {
+	svc, repo, userID := newService(t)
+	ctx := context.Background()
+
+	first, err := svc.Create(userID, "", 1, client, nil)
+	require.NoError(t, err)
+	second, err := svc.Rotate(ctx, first.RefreshToken, client)
+	require.NoError(t, err)
+	require.Equal(t, first.Session.ID, second.Session.ID)
+	require.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	require.NoError(t, svc.ValidateSession(ctx, userID, second.Session.ID, 1))
+
+	// The first token turns up again: someone kept a copy.
+	_, err = svc.Rotate(ctx, first.RefreshToken, client)
+	require.ErrorIs(t, err, session.ErrRefreshTokenReused)
+	require.Equal(t, session.ReasonTokenReuse, repo.Sessions[first.Session.ID].RevokedReason)
+
+	_, err = svc.Rotate(ctx, second.RefreshToken, client)
+	require.ErrorIs(t, err, session.ErrSessionRevoked)
+	require.ErrorIs(t, svc.ValidateSession(ctx, userID, second.Session.ID, 1), session.ErrSessionRevoked)
+
+	_, err = svc.Rotate(ctx, "not-a-token", client)
+	require.ErrorIs(t, err, session.ErrInvalidRefreshToken)
+}
+
+func TestRotateRejectsExpiredToken(t *testing.T) {
+	svc, repo, userID := newService(t)
+
+	issued, err := svc.Create(userID, "", 1, client, nil)
+	require.NoError(t, err)
+	for _, tok := range repo.Tokens {
+		tok.ExpiresAt = time.Now().Add(-time.Second)
+	}
+	_, err = svc.Rotate(context.Background(), issued.RefreshToken, client)
+	require.ErrorIs(t, err, session.ErrInvalidRefreshToken)
+}
+
+func TestRevokeTakesEffectDespiteCache(t *testing.T) {
+	svc, _, userID := newService(t)
+	ctx := context.Background()
+
+	a, err := svc.Create(userID, "", 1, client, nil)
+	require.NoError(t, err)
+	b, err := svc.Create(userID, "", 1, client, nil)
+	require.NoError(t, err)
+	require.NoError(t, svc.ValidateSession(ctx, userID, a.Session.ID, 1))
+	require.NoError(t, svc.ValidateSession(ctx, userID, b.Session.ID, 1))
+
+	require.ErrorIs(t, svc.RevokeSession(ctx, uuid.NewString(), a.Session.ID, session.ReasonLogout), session.ErrSessionNotFound)
+	require.NoError(t, svc.RevokeSession(ctx, userID, a.Session.ID, session.ReasonLogout))
+	require.ErrorIs(t, svc.ValidateSession(ctx, userID, a.Session.ID, 1), session.ErrSessionRevoked)
+	require.NoError(t, svc.ValidateSession(ctx, userID, b.Session.ID, 1))
+
+	n, err := svc.RevokeUser(ctx, userID, session.ReasonLogoutAll)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.ErrorIs(t, svc.ValidateSession(ctx, userID, b.Session.ID, 1), session.ErrSessionRevoked)
+
+	list, err := svc.ListSessions(userID, "")
+	require.NoError(t, err)
+	require.Empty(t, list)
+}
@@ -0,0 +1,96 @@
+package sso
+
+import (
+	"context"
+
+	"aegis-api/services_/auth/registration"
+)
+
+type Repository interface {
+	AutoMigrate() error
+
+	// GetOIDCConfig returns nil when the tenant has none.
+	GetOIDCConfig(tenantID string) (*OIDCConfig, error)
+	SaveOIDCConfig(c *OIDCConfig) error
+	DeleteOIDCConfig(tenantID string) error
+	// GetSAMLConfig returns nil when the tenant has none.
+	GetSAMLConfig(tenantID string) (*SAMLConfig, error)
+	SaveSAMLConfig(c *SAMLConfig) error
+	DeleteSAMLConfig(tenantID string) error
+
+	// FindTenantsByDomain lists the enabled providers whose allowed
+	// domains include domain.
+	FindTenantsByDomain(domain string) ([]Discovery, error)
+
+	// GetSettings returns nil when the tenant has none.
+	GetSettings(tenantID string) (*Settings, error)
+	SaveSettings(s *Settings) error
+
+	// GetIdentity returns nil when no user is linked to the subject.
+	GetIdentity(issuer, subject string) (*Identity, error)
+	CreateIdentity(i *Identity) error
+	SaveIdentity(i *Identity) error
+
+	CreateSAMLSession(s *SAMLSession) error
+	// LatestSAMLSession returns nil when the user has none.
+	LatestSAMLSession(tenantID, userID string) (*SAMLSession, error)
+	FindSAMLSessions(tenantID, nameID string) ([]SAMLSession, error)
+	DeleteSAMLSessions(ids []string) error
+}
+
+// Users is the part of the user store SSO needs.
+type Users interface {
+	GetUserByID(userID string) (*registration.User, error)
+	GetUserByEmail(email string) (*registration.User, error)
+	UpdateUser(user *registration.User) error
+}
+
+// Provisioner creates users at their first SSO login.
+type Provisioner interface {
+	ProvisionUser(req registration.ProvisionRequest) (registration.User, error)
+}
+
+type Service interface {
+	GetOIDCConfig(tenantID string) (*OIDCConfig, error)
+	// SaveOIDCConfig validates the configuration, including that the
+	// issuer's discovery document can be read, and stores it.
+	SaveOIDCConfig(ctx context.Context, actor Actor, in OIDCConfigInput) (*OIDCConfig, error)
+	DeleteOIDCConfig(actor Actor) error
+
+	GetSettings(tenantID string) (*Settings, error)
+	UpdateSettings(actor Actor, in SettingsInput) (*Settings, error)
+	// LocalPasswordAllowed reports whether a user of the tenant with role
+	// may sign in with a password.
+	LocalPasswordAllowed(tenantID, role string) (bool, error)
+
+	// Discover lists the tenants a user with email can sign in to with
+	// SSO.
+	Discover(email string) ([]Discovery, error)
+	// BeginOIDC starts an authorization code login with PKCE.
+	BeginOIDC(ctx context.Context, tenantID string) (*Authorization, error)
+	// CompleteOIDC exchanges the code, verifies the ID token and resolves
+	// (or provisions) the user.
+	CompleteOIDC(ctx context.Context, state, code string) (*Result, error)
+
+	GetSAMLConfig(tenantID string) (*SAMLConfig, error)
+	SaveSAMLConfig(actor Actor, in SAMLConfigInput) (*SAMLConfig, error)
+	DeleteSAMLConfig(actor Actor) error
+	// SAMLMetadata is the service provider metadata for a tenant's IdP.
+	SAMLMetadata(tenantID string) ([]byte, error)
+	// BeginSAML starts a login with a signed AuthnRequest.
+	BeginSAML(ctx context.Context, tenantID string) (*Authorization, error)
+	// CompleteSAML validates the IdP's response and resolves (or
+	// provisions) the user.
+	CompleteSAML(ctx context.Context, tenantID, samlResponse, relayState string) (*Result, error)
+	// BeginSAMLLogout ends the user's session at the IdP: it returns the
+	// signed LogoutRequest URL, or "" when there is none to end.
+	BeginSAMLLogout(ctx context.Context, tenantID, userID string) (string, error)
+	// HandleSAMLLogout processes a LogoutRequest or LogoutResponse the IdP
+	// sent with the redirect binding.
+	HandleSAMLLogout(ctx context.Context, tenantID, rawQuery string) (*Logout, error)
+
+	// IssueTicket hands an SSO login over to the client: the ticket is
+	// redeemed once, shortly after, for the login's user.
+	IssueTicket(ctx context.Context, res *Result) (string, error)
+	RedeemTicket(ctx context.Context, ticket string) (*registration.User, error)
+}
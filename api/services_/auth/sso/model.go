@@ -0,0 +1,219 @@
+package sso
+
+import (
+	"time"
+
+	"aegis-api/services_/auth/registration"
+
+	"gorm.io/datatypes"
+)
+
+// Identity providers a user can be linked to.
+const (
+	ProviderOIDC = "oidc"
+	ProviderSAML = "saml"
+)
+
+// OIDCConfig is a tenant's OpenID Connect identity provider.
+type OIDCConfig struct {
+	TenantID string `gorm:"type:uuid;primaryKey" json:"tenant_id"`
+	Enabled  bool   `gorm:"not null" json:"enabled"`
+	// Issuer is the provider's issuer URL; its discovery document is
+	// read from Issuer + "/.well-known/openid-configuration".
+	Issuer       string         `gorm:"type:text;not null" json:"issuer"`
+	ClientID     string         `gorm:"type:text;not null" json:"client_id"`
+	ClientSecret string         `gorm:"type:text" json:"-"`
+	RedirectURI  string         `gorm:"type:text;not null" json:"redirect_uri"`
+	Scopes       datatypes.JSON `gorm:"type:jsonb;not null" json:"scopes"` // []string
+	// RoleClaim names the claim roles are mapped from, e.g. "groups" or
+	// "realm_access.roles"; RoleMappings are tried in order.
+	RoleClaim    string         `gorm:"type:varchar(255)" json:"role_claim"`
+	RoleMappings datatypes.JSON `gorm:"type:jsonb;not null" json:"role_mappings"` // []RoleMapping
+	// DefaultRole is given when no mapping matches; empty refuses
+	// unmapped users.
+	DefaultRole  string         `gorm:"type:varchar(100)" json:"default_role"`
+	TeamClaim    string         `gorm:"type:varchar(255)" json:"team_claim"`
+	TeamMappings datatypes.JSON `gorm:"type:jsonb;not null" json:"team_mappings"` // []TeamMapping
+	DefaultTeam  *string        `gorm:"type:uuid" json:"default_team_id,omitempty"`
+	// AllowedDomains restricts sign-in to these email domains; empty
+	// allows any.
+	AllowedDomains datatypes.JSON `gorm:"type:jsonb;not null" json:"allowed_domains"` // []string
+	// SyncRoles re-applies the mappings at every login rather than only
+	// when the user is provisioned.
+	SyncRoles bool      `gorm:"not null;default:false" json:"sync_roles"`
+	UpdatedBy string    `gorm:"type:uuid" json:"updated_by,omitempty"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (OIDCConfig) TableName() string { return "tenant_oidc_configs" }
+
+// RoleMapping gives Role to users whose role claim contains Value.
+type RoleMapping struct {
+	Value string `json:"value"`
+	Role  string `json:"role"`
+}
+
+// TeamMapping places users whose team claim contains Value in TeamID.
+type TeamMapping struct {
+	Value  string `json:"value"`
+	TeamID string `json:"team_id"`
+}
+
+// OIDCConfigInput is an update of a tenant's provider. An empty
+// ClientSecret keeps the stored one.
+type OIDCConfigInput struct {
+	Enabled        bool          `json:"enabled"`
+	Issuer         string        `json:"issuer"`
+	ClientID       string        `json:"client_id"`
+	ClientSecret   string        `json:"client_secret"`
+	RedirectURI    string        `json:"redirect_uri"`
+	Scopes         []string      `json:"scopes"`
+	RoleClaim      string        `json:"role_claim"`
+	RoleMappings   []RoleMapping `json:"role_mappings"`
+	DefaultRole    string        `json:"default_role"`
+	TeamClaim      string        `json:"team_claim"`
+	TeamMappings   []TeamMapping `json:"team_mappings"`
+	DefaultTeamID  *string       `json:"default_team_id"`
+	AllowedDomains []string      `json:"allowed_domains"`
+	SyncRoles      bool          `json:"sync_roles"`
+}
+
+// SAMLConfig is a tenant's SAML 2.0 identity provider, with AEGIS as the
+// service provider.
+type SAMLConfig struct {
+	TenantID    string `gorm:"type:uuid;primaryKey" json:"tenant_id"`
+	Enabled     bool   `gorm:"not null" json:"enabled"`
+	IdPEntityID string `gorm:"type:text;not null" json:"idp_entity_id"`
+	// IdPSSOURL and IdPSLOURL take the HTTP-Redirect binding; without an
+	// SLO URL logout stays local.
+	IdPSSOURL string `gorm:"type:text;not null" json:"idp_sso_url"`
+	IdPSLOURL string `gorm:"type:text" json:"idp_slo_url"`
+	// IdPCertificates are the PEM certificates the IdP signs with; more
+	// than one allows a key rollover.
+	IdPCertificates string `gorm:"type:text;not null" json:"idp_certificates"`
+	// EmailAttribute names the attribute holding the user's email; empty
+	// uses the NameID.
+	EmailAttribute string         `gorm:"type:varchar(255)" json:"email_attribute"`
+	NameAttribute  string         `gorm:"type:varchar(255)" json:"name_attribute"`
+	RoleAttribute  string         `gorm:"type:varchar(255)" json:"role_attribute"`
+	RoleMappings   datatypes.JSON `gorm:"type:jsonb;not null" json:"role_mappings"` // []RoleMapping
+	DefaultRole    string         `gorm:"type:varchar(100)" json:"default_role"`
+	TeamAttribute  string         `gorm:"type:varchar(255)" json:"team_attribute"`
+	TeamMappings   datatypes.JSON `gorm:"type:jsonb;not null" json:"team_mappings"` // []TeamMapping
+	DefaultTeam    *string        `gorm:"type:uuid" json:"default_team_id,omitempty"`
+	AllowedDomains datatypes.JSON `gorm:"type:jsonb;not null" json:"allowed_domains"` // []string
+	SyncRoles      bool           `gorm:"not null;default:false" json:"sync_roles"`
+	UpdatedBy      string         `gorm:"type:uuid" json:"updated_by,omitempty"`
+	UpdatedAt      time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (SAMLConfig) TableName() string { return "tenant_saml_configs" }
+
+// SAMLConfigInput is an update of a tenant's SAML provider. IdPMetadata,
+// the IdP's metadata document, fills in the entity ID, endpoints and
+// certificates when given.
+type SAMLConfigInput struct {
+	Enabled         bool          `json:"enabled"`
+	IdPMetadata     string        `json:"idp_metadata"`
+	IdPEntityID     string        `json:"idp_entity_id"`
+	IdPSSOURL       string        `json:"idp_sso_url"`
+	IdPSLOURL       string        `json:"idp_slo_url"`
+	IdPCertificates string        `json:"idp_certificates"`
+	EmailAttribute  string        `json:"email_attribute"`
+	NameAttribute   string        `json:"name_attribute"`
+	RoleAttribute   string        `json:"role_attribute"`
+	RoleMappings    []RoleMapping `json:"role_mappings"`
+	DefaultRole     string        `json:"default_role"`
+	TeamAttribute   string        `json:"team_attribute"`
+	TeamMappings    []TeamMapping `json:"team_mappings"`
+	DefaultTeamID   *string       `json:"default_team_id"`
+	AllowedDomains  []string      `json:"allowed_domains"`
+	SyncRoles       bool          `json:"sync_roles"`
+}
+
+// SAMLSession records the IdP session of a SAML login, for single
+// logout.
+type SAMLSession struct {
+	ID           string    `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID     string    `gorm:"type:uuid;not null;index:idx_saml_sessions_name_id" json:"tenant_id"`
+	NameID       string    `gorm:"type:text;not null;index:idx_saml_sessions_name_id" json:"name_id"`
+	NameIDFormat string    `gorm:"type:text" json:"name_id_format"`
+	SessionIndex string    `gorm:"type:text" json:"session_index"`
+	UserID       string    `gorm:"type:uuid;not null;index" json:"user_id"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (SAMLSession) TableName() string { return "saml_sessions" }
+
+// Discovery is a tenant a user can sign in to with SSO, and how.
+type Discovery struct {
+	TenantID string `json:"tenant_id"`
+	Provider string `json:"provider"`
+}
+
+// Logout is the outcome of a logout message from a SAML IdP.
+type Logout struct {
+	// UserIDs are the users whose sessions end.
+	UserIDs []string
+	// RedirectURL is the response to send the browser back to the IdP
+	// with; empty when none is due.
+	RedirectURL string
+}
+
+// Settings are a tenant's sign-in options.
+type Settings struct {
+	TenantID string `gorm:"type:uuid;primaryKey" json:"tenant_id"`
+	// LocalPasswordsDisabled makes members sign in through SSO. Tenant
+	// Admins keep password login as a break-glass account.
+	LocalPasswordsDisabled bool      `gorm:"not null;default:false" json:"local_passwords_disabled"`
+	UpdatedBy              string    `gorm:"type:uuid" json:"updated_by,omitempty"`
+	UpdatedAt              time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (Settings) TableName() string { return "tenant_auth_settings" }
+
+type SettingsInput struct {
+	LocalPasswordsDisabled bool `json:"local_passwords_disabled"`
+}
+
+// Identity links a user to their account at an identity provider.
+type Identity struct {
+	ID          string     `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID      string     `gorm:"type:uuid;not null;index" json:"user_id"`
+	TenantID    string     `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	Provider    string     `gorm:"type:varchar(16);not null" json:"provider"`
+	Issuer      string     `gorm:"type:text;not null;uniqueIndex:idx_sso_identities_subject" json:"issuer"`
+	Subject     string     `gorm:"type:text;not null;uniqueIndex:idx_sso_identities_subject" json:"subject"`
+	Email       string     `gorm:"type:varchar(255)" json:"email"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+func (Identity) TableName() string { return "sso_identities" }
+
+// Authorization starts a login at the identity provider: the browser is
+// sent to URL, and the provider redirects back with State and a code.
+type Authorization struct {
+	URL       string    `json:"authorization_url"`
+	State     string    `json:"state"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Result is the user an SSO login signed in.
+type Result struct {
+	User     *registration.User
+	TenantID string
+	Provider string
+	// Provisioned is set when the user was created by this login, and
+	// Linked when an existing account was first linked to the identity.
+	Provisioned bool
+	Linked      bool
+	// RoleChanged is set when SyncRoles changed the user's role or team.
+	RoleChanged bool
+}
+
+// Actor is the admin changing a tenant's configuration.
+type Actor struct {
+	UserID   string
+	TenantID string
+}
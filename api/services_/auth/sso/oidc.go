@@ -0,0 +1,283 @@
+package sso
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// providerTTL is how long discovery documents and keys are kept before
+// they are read again. Unknown key IDs trigger an early refresh, so
+// provider key rotation is picked up at once.
+const providerTTL = time.Hour
+
+var errUnknownKey = errors.New("ID token signed with an unknown key")
+
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type provider struct {
+	meta    providerMetadata
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// providers caches discovery documents and signing keys by issuer.
+type providers struct {
+	mu     sync.Mutex
+	byIss  map[string]*provider
+	client *http.Client
+}
+
+func (p *providers) get(ctx context.Context, issuer string, refresh bool) (*provider, error) {
+	p.mu.Lock()
+	cached := p.byIss[issuer]
+	p.mu.Unlock()
+	if cached != nil && !refresh && time.Since(cached.fetched) < providerTTL {
+		return cached, nil
+	}
+
+	var meta providerMetadata
+	if err := p.fetchJSON(ctx, strings.TrimRight(issuer, "/")+"/.well-known/openid-configuration", &meta); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+	// The document must be the issuer's own (OpenID Connect Discovery 4.3).
+	if meta.Issuer != issuer {
+		return nil, fmt.Errorf("%w: document names issuer %q", ErrDiscovery, meta.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: incomplete discovery document", ErrDiscovery)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := p.fetchJSON(ctx, meta.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		if pub, err := k.publicKey(); err == nil && (k.Use == "" || k.Use == "sig") {
+			keys[k.Kid] = pub
+		}
+	}
+	prov := &provider{meta: meta, keys: keys, fetched: time.Now()}
+	p.mu.Lock()
+	p.byIss[issuer] = prov
+	p.mu.Unlock()
+	return prov, nil
+}
+
+func (p *providers) fetchJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func b64Int(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := b64Int(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := b64Int(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := b64Int(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := b64Int(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+// pkceChallenge is the S256 code challenge for verifier (RFC 7636).
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func authorizationURL(meta providerMetadata, cfg *OIDCConfig, scopes []string, state, nonce, verifier string) (string, error) {
+	u, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("%w: bad authorization endpoint", ErrDiscovery)
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", cfg.ClientID)
+	q.Set("redirect_uri", cfg.RedirectURI)
+	q.Set("scope", strings.Join(scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", pkceChallenge(verifier))
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// exchangeCode redeems an authorization code for the ID token.
+func (p *providers) exchangeCode(ctx context.Context, meta providerMetadata, cfg *OIDCConfig, code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {cfg.RedirectURI},
+		"client_id":     {cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	if cfg.ClientSecret != "" {
+		form.Set("client_secret", cfg.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrTokenExchange, err)
+	}
+	defer resp.Body.Close()
+	var out struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out); err != nil {
+		return "", fmt.Errorf("%w: %s", ErrTokenExchange, resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK || out.IDToken == "" {
+		return "", fmt.Errorf("%w: %s %s", ErrTokenExchange, out.Error, out.ErrorDescription)
+	}
+	return out.IDToken, nil
+}
+
+// verifyIDToken checks the ID token's signature against the provider's
+// keys and its issuer, audience, lifetime and nonce.
+func (p *providers) verifyIDToken(ctx context.Context, cfg *OIDCConfig, raw, nonce string) (jwt.MapClaims, error) {
+	keyFunc := func(refresh bool) jwt.Keyfunc {
+		return func(t *jwt.Token) (interface{}, error) {
+			kid, _ := t.Header["kid"].(string)
+			prov, err := p.get(ctx, cfg.Issuer, refresh)
+			if err != nil {
+				return nil, err
+			}
+			if key, ok := prov.keys[kid]; ok {
+				return key, nil
+			}
+			return nil, errUnknownKey
+		}
+	}
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "ES256"}),
+		jwt.WithIssuer(cfg.Issuer),
+		jwt.WithAudience(cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	}
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, keyFunc(false), opts...)
+	if errors.Is(err, errUnknownKey) {
+		// The provider may have rotated its keys.
+		claims = jwt.MapClaims{}
+		_, err = jwt.ParseWithClaims(raw, claims, keyFunc(true), opts...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	return claims, nil
+}
+
+// claimValues reads a claim by dotted path, e.g. "realm_access.roles",
+// as a list of strings.
+func claimValues(claims map[string]interface{}, path string) []string {
+	if path == "" {
+		return nil
+	}
+	var cur interface{} = claims
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = m[part]
+	}
+	switch v := cur.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package sso
+
+import (
+	"encoding/json"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) AutoMigrate() error {
+	return r.db.AutoMigrate(&OIDCConfig{}, &SAMLConfig{}, &Settings{}, &Identity{}, &SAMLSession{})
+}
+
+func (r *GormRepository) GetOIDCConfig(tenantID string) (*OIDCConfig, error) {
+	var c OIDCConfig
+	err := r.db.Where("tenant_id = ?", tenantID).First(&c).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *GormRepository) SaveOIDCConfig(c *OIDCConfig) error {
+	return r.db.Save(c).Error
+}
+
+func (r *GormRepository) DeleteOIDCConfig(tenantID string) error {
+	return r.db.Where("tenant_id = ?", tenantID).Delete(&OIDCConfig{}).Error
+}
+
+func (r *GormRepository) GetSAMLConfig(tenantID string) (*SAMLConfig, error) {
+	var c SAMLConfig
+	err := r.db.Where("tenant_id = ?", tenantID).First(&c).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *GormRepository) SaveSAMLConfig(c *SAMLConfig) error {
+	return r.db.Save(c).Error
+}
+
+func (r *GormRepository) DeleteSAMLConfig(tenantID string) error {
+	return r.db.Where("tenant_id = ?", tenantID).Delete(&SAMLConfig{}).Error
+}
+
+func (r *GormRepository) FindTenantsByDomain(domain string) ([]Discovery, error) {
+	needle, err := json.Marshal([]string{domain})
+	if err != nil {
+		return nil, err
+	}
+	var out []Discovery
+	for _, p := range []struct {
+		provider string
+		model    interface{}
+	}{{ProviderOIDC, &OIDCConfig{}}, {ProviderSAML, &SAMLConfig{}}} {
+		var ids []string
+		err := r.db.Model(p.model).
+			Where("enabled AND allowed_domains @> ?", string(needle)).
+			Pluck("tenant_id", &ids).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			out = append(out, Discovery{TenantID: id, Provider: p.provider})
+		}
+	}
+	return out, nil
+}
+
+func (r *GormRepository) GetSettings(tenantID string) (*Settings, error) {
+	var s Settings
+	err := r.db.Where("tenant_id = ?", tenantID).First(&s).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *GormRepository) SaveSettings(s *Settings) error {
+	return r.db.Save(s).Error
+}
+
+func (r *GormRepository) GetIdentity(issuer, subject string) (*Identity, error) {
+	var i Identity
+	err := r.db.Where("issuer = ? AND subject = ?", issuer, subject).First(&i).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+func (r *GormRepository) CreateIdentity(i *Identity) error {
+	return r.db.Create(i).Error
+}
+
+func (r *GormRepository) SaveIdentity(i *Identity) error {
+	return r.db.Save(i).Error
+}
+
+func (r *GormRepository) CreateSAMLSession(s *SAMLSession) error {
+	return r.db.Create(s).Error
+}
+
+func (r *GormRepository) LatestSAMLSession(tenantID, userID string) (*SAMLSession, error) {
+	var s SAMLSession
+	err := r.db.Where("tenant_id = ? AND user_id = ?", tenantID, userID).
+		Order("created_at DESC").First(&s).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *GormRepository) FindSAMLSessions(tenantID, nameID string) ([]SAMLSession, error) {
+	var out []SAMLSession
+	err := r.db.Where("tenant_id = ? AND name_id = ?", tenantID, nameID).Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) DeleteSAMLSessions(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Where("id IN ?", ids).Delete(&SAMLSession{}).Error
+}
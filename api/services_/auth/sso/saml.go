@@ -0,0 +1,724 @@
+package sso
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"aegis-api/cache"
+	"aegis-api/services_/auth/sso/xmldsig"
+
+	"github.com/google/uuid"
+)
+
+const (
+	nsSAMLProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	nsSAMLAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+	nsSAMLMetadata  = "urn:oasis:names:tc:SAML:2.0:metadata"
+
+	bindingRedirect = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	bindingPOST     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	statusSuccess   = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	confirmBearer   = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+	nameIDEmail     = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+
+	// samlClockSkew is tolerated between the IdP's clock and ours.
+	samlClockSkew = 2 * time.Minute
+	// maxSAMLMessage bounds inflated redirect-binding messages.
+	maxSAMLMessage = 1 << 20
+)
+
+func (s *service) samlAvailable() error {
+	if s.opts.SAML.Key == nil || s.opts.SAML.Certificate == nil || s.opts.SAML.BaseURL == "" {
+		return ErrSAMLUnavailable
+	}
+	return nil
+}
+
+// The service provider's endpoints for a tenant. The metadata URL is also
+// the entity ID.
+func (s *service) spEntityID(tenantID string) string {
+	return s.opts.SAML.BaseURL + "/" + tenantID + "/metadata"
+}
+
+func (s *service) acsURL(tenantID string) string {
+	return s.opts.SAML.BaseURL + "/" + tenantID + "/acs"
+}
+
+func (s *service) sloURL(tenantID string) string {
+	return s.opts.SAML.BaseURL + "/" + tenantID + "/slo"
+}
+
+func xmlEscape(v string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(v))
+	return b.String()
+}
+
+// samlID is a message ID; IDs must not start with a digit.
+func samlID() string {
+	b := make([]byte, 20)
+	_, _ = rand.Read(b)
+	return "_" + hex.EncodeToString(b)
+}
+
+func samlInstant(t time.Time) string { return t.UTC().Format("2006-01-02T15:04:05Z") }
+
+// parseCertificates reads PEM certificates, or a single base64 DER
+// certificate as metadata carries them.
+func parseCertificates(text string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(text)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+		if err != nil {
+			return nil, fmt.Errorf("no certificates found")
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+func encodeCertificates(certs []*x509.Certificate) string {
+	var b bytes.Buffer
+	for _, c := range certs {
+		_ = pem.Encode(&b, &pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})
+	}
+	return b.String()
+}
+
+// idpMetadata is what SAMLConfigInput takes from an IdP's metadata.
+type idpMetadata struct {
+	entityID, ssoURL, sloURL string
+	certs                    []string
+}
+
+func parseIdPMetadata(doc string) (*idpMetadata, error) {
+	root, err := xmldsig.Parse([]byte(doc))
+	if err != nil {
+		return nil, invalid("idp_metadata: %v", err)
+	}
+	if root.Space() != nsSAMLMetadata || root.Local != "EntityDescriptor" {
+		return nil, invalid("idp_metadata must be an EntityDescriptor")
+	}
+	idp := root.Child(nsSAMLMetadata, "IDPSSODescriptor")
+	if idp == nil {
+		return nil, invalid("idp_metadata has no IDPSSODescriptor")
+	}
+	m := &idpMetadata{entityID: root.Attr("entityID")}
+	for _, kd := range idp.ChildElements(nsSAMLMetadata, "KeyDescriptor") {
+		if kd.Attr("use") == "encryption" {
+			continue
+		}
+		if ki := kd.Child(xmldsig.NamespaceDSig, "KeyInfo"); ki != nil {
+			for _, xd := range ki.ChildElements(xmldsig.NamespaceDSig, "X509Data") {
+				for _, c := range xd.ChildElements(xmldsig.NamespaceDSig, "X509Certificate") {
+					m.certs = append(m.certs, c.Text())
+				}
+			}
+		}
+	}
+	for _, sso := range idp.ChildElements(nsSAMLMetadata, "SingleSignOnService") {
+		if sso.Attr("Binding") == bindingRedirect {
+			m.ssoURL = sso.Attr("Location")
+		}
+	}
+	for _, slo := range idp.ChildElements(nsSAMLMetadata, "SingleLogoutService") {
+		if slo.Attr("Binding") == bindingRedirect {
+			m.sloURL = slo.Attr("Location")
+		}
+	}
+	return m, nil
+}
+
+func httpsURL(v string) bool {
+	u, err := url.Parse(v)
+	return err == nil && u.Scheme == "https" && u.Host != ""
+}
+
+func (s *service) GetSAMLConfig(tenantID string) (*SAMLConfig, error) {
+	c, err := s.repo.GetSAMLConfig(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, ErrNotConfigured
+	}
+	return c, nil
+}
+
+func (s *service) SaveSAMLConfig(actor Actor, in SAMLConfigInput) (*SAMLConfig, error) {
+	if strings.TrimSpace(in.IdPMetadata) != "" {
+		m, err := parseIdPMetadata(in.IdPMetadata)
+		if err != nil {
+			return nil, err
+		}
+		in.IdPEntityID, in.IdPSSOURL, in.IdPSLOURL = m.entityID, m.ssoURL, m.sloURL
+		var certs []*x509.Certificate
+		for _, c := range m.certs {
+			parsed, err := parseCertificates(c)
+			if err != nil {
+				return nil, invalid("idp_metadata certificate: %v", err)
+			}
+			certs = append(certs, parsed...)
+		}
+		in.IdPCertificates = encodeCertificates(certs)
+	}
+	if strings.TrimSpace(in.IdPEntityID) == "" {
+		return nil, invalid("idp_entity_id is required")
+	}
+	if !httpsURL(in.IdPSSOURL) {
+		return nil, invalid("idp_sso_url must be an https URL")
+	}
+	if in.IdPSLOURL != "" && !httpsURL(in.IdPSLOURL) {
+		return nil, invalid("idp_slo_url must be an https URL")
+	}
+	certs, err := parseCertificates(in.IdPCertificates)
+	if err != nil {
+		return nil, invalid("idp_certificates: %v", err)
+	}
+	domains, err := mappingInput{
+		roleSource: in.RoleAttribute, roleMappings: in.RoleMappings, defaultRole: in.DefaultRole,
+		teamSource: in.TeamAttribute, teamMappings: in.TeamMappings, defaultTeamID: in.DefaultTeamID,
+		allowedDomains: in.AllowedDomains,
+	}.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &SAMLConfig{
+		TenantID:        actor.TenantID,
+		Enabled:         in.Enabled,
+		IdPEntityID:     strings.TrimSpace(in.IdPEntityID),
+		IdPSSOURL:       in.IdPSSOURL,
+		IdPSLOURL:       in.IdPSLOURL,
+		IdPCertificates: encodeCertificates(certs),
+		EmailAttribute:  in.EmailAttribute,
+		NameAttribute:   in.NameAttribute,
+		RoleAttribute:   in.RoleAttribute,
+		RoleMappings:    jsonOf(nonNil(in.RoleMappings)),
+		DefaultRole:     in.DefaultRole,
+		TeamAttribute:   in.TeamAttribute,
+		TeamMappings:    jsonOf(nonNil(in.TeamMappings)),
+		DefaultTeam:     in.DefaultTeamID,
+		AllowedDomains:  jsonOf(domains),
+		SyncRoles:       in.SyncRoles,
+		UpdatedBy:       actor.UserID,
+	}
+	if !c.Enabled {
+		if err := s.requirePasswordsAllowed(actor.TenantID, ProviderSAML); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.repo.SaveSAMLConfig(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *service) DeleteSAMLConfig(actor Actor) error {
+	if err := s.requirePasswordsAllowed(actor.TenantID, ProviderSAML); err != nil {
+		return err
+	}
+	return s.repo.DeleteSAMLConfig(actor.TenantID)
+}
+
+func (s *service) SAMLMetadata(tenantID string) ([]byte, error) {
+	if err := s.samlAvailable(); err != nil {
+		return nil, err
+	}
+	if _, err := uuid.Parse(tenantID); err != nil {
+		return nil, ErrNotConfigured
+	}
+	cert := base64.StdEncoding.EncodeToString(s.opts.SAML.Certificate.Raw)
+	doc := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<md:EntityDescriptor xmlns:md="` + nsSAMLMetadata + `" xmlns:ds="` + xmldsig.NamespaceDSig + `" entityID="` + xmlEscape(s.spEntityID(tenantID)) + `">` +
+		`<md:SPSSODescriptor AuthnRequestsSigned="true" WantAssertionsSigned="true" protocolSupportEnumeration="` + nsSAMLProtocol + `">` +
+		`<md:KeyDescriptor use="signing"><ds:KeyInfo><ds:X509Data><ds:X509Certificate>` + cert + `</ds:X509Certificate></ds:X509Data></ds:KeyInfo></md:KeyDescriptor>` +
+		`<md:SingleLogoutService Binding="` + bindingRedirect + `" Location="` + xmlEscape(s.sloURL(tenantID)) + `"/>` +
+		`<md:NameIDFormat>` + nameIDEmail + `</md:NameIDFormat>` +
+		`<md:AssertionConsumerService Binding="` + bindingPOST + `" Location="` + xmlEscape(s.acsURL(tenantID)) + `" index="0" isDefault="true"/>` +
+		`</md:SPSSODescriptor></md:EntityDescriptor>`
+	return []byte(doc), nil
+}
+
+// redirectURL encodes msg for the HTTP-Redirect binding and signs it
+// (SAML bindings 3.4.4.1).
+func (s *service) redirectURL(endpoint, param string, msg []byte, relayState string) (string, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	q := param + "=" + url.QueryEscape(base64.StdEncoding.EncodeToString(buf.Bytes()))
+	if relayState != "" {
+		q += "&RelayState=" + url.QueryEscape(relayState)
+	}
+	q += "&SigAlg=" + url.QueryEscape(xmldsig.AlgRSASHA256)
+	sig, err := xmldsig.SignData(s.opts.SAML.Key, []byte(q))
+	if err != nil {
+		return "", err
+	}
+	q += "&Signature=" + url.QueryEscape(base64.StdEncoding.EncodeToString(sig))
+	sep := "?"
+	if strings.Contains(endpoint, "?") {
+		sep = "&"
+	}
+	return endpoint + sep + q, nil
+}
+
+// readRedirect verifies and decodes a redirect-binding message. The
+// signature covers the query as sent, so it is checked on the raw
+// parameters.
+func readRedirect(rawQuery string, certs []*x509.Certificate) (param string, msg *xmldsig.Element, relayState string, err error) {
+	raw := map[string]string{}
+	for _, part := range strings.Split(rawQuery, "&") {
+		k, v, _ := strings.Cut(part, "=")
+		if _, dup := raw[k]; dup {
+			return "", nil, "", fmt.Errorf("%w: repeated parameter %s", ErrInvalidAssertion, k)
+		}
+		raw[k] = v
+	}
+	param = "SAMLRequest"
+	if _, ok := raw[param]; !ok {
+		param = "SAMLResponse"
+	}
+	if raw[param] == "" {
+		return "", nil, "", fmt.Errorf("%w: no SAML message", ErrInvalidAssertion)
+	}
+	if raw["Signature"] == "" {
+		return "", nil, "", fmt.Errorf("%w: message is not signed", ErrInvalidAssertion)
+	}
+	signed := param + "=" + raw[param]
+	if rs, ok := raw["RelayState"]; ok {
+		signed += "&RelayState=" + rs
+	}
+	signed += "&SigAlg=" + raw["SigAlg"]
+	alg, err1 := url.QueryUnescape(raw["SigAlg"])
+	sigB64, err2 := url.QueryUnescape(raw["Signature"])
+	sig, err3 := base64.StdEncoding.DecodeString(sigB64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return "", nil, "", fmt.Errorf("%w: bad signature encoding", ErrInvalidAssertion)
+	}
+	if err := xmldsig.VerifySignature(alg, []byte(signed), sig, certs); err != nil {
+		return "", nil, "", fmt.Errorf("%w: %v", ErrInvalidAssertion, err)
+	}
+
+	value, _ := url.QueryUnescape(raw[param])
+	deflated, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", nil, "", fmt.Errorf("%w: bad encoding", ErrInvalidAssertion)
+	}
+	inflated, err := io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(deflated)), maxSAMLMessage))
+	if err != nil {
+		return "", nil, "", fmt.Errorf("%w: bad encoding", ErrInvalidAssertion)
+	}
+	if msg, err = xmldsig.Parse(inflated); err != nil {
+		return "", nil, "", fmt.Errorf("%w: %v", ErrInvalidAssertion, err)
+	}
+	relayState, _ = url.QueryUnescape(raw["RelayState"])
+	return param, msg, relayState, nil
+}
+
+func (s *service) enabledSAMLConfig(tenantID string) (*SAMLConfig, []*x509.Certificate, error) {
+	c, err := s.repo.GetSAMLConfig(tenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c == nil || !c.Enabled {
+		return nil, nil, ErrNotConfigured
+	}
+	certs, err := parseCertificates(c.IdPCertificates)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: stored IdP certificates: %v", ErrInvalidConfig, err)
+	}
+	return c, certs, nil
+}
+
+func (s *service) BeginSAML(ctx context.Context, tenantID string) (*Authorization, error) {
+	if err := s.samlAvailable(); err != nil {
+		return nil, err
+	}
+	c, _, err := s.enabledSAMLConfig(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	st := loginState{TenantID: tenantID, Provider: ProviderSAML, RequestID: samlID()}
+	req := `<samlp:AuthnRequest xmlns:samlp="` + nsSAMLProtocol + `" xmlns:saml="` + nsSAMLAssertion + `"` +
+		` ID="` + st.RequestID + `" Version="2.0" IssueInstant="` + samlInstant(s.now()) + `"` +
+		` Destination="` + xmlEscape(c.IdPSSOURL) + `" AssertionConsumerServiceURL="` + xmlEscape(s.acsURL(tenantID)) + `"` +
+		` ProtocolBinding="` + bindingPOST + `">` +
+		`<saml:Issuer>` + xmlEscape(s.spEntityID(tenantID)) + `</saml:Issuer>` +
+		`<samlp:NameIDPolicy AllowCreate="true"/></samlp:AuthnRequest>`
+	state, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.redirectURL(c.IdPSSOURL, "SAMLRequest", []byte(req), state)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.putState(ctx, state, st); err != nil {
+		return nil, err
+	}
+	return &Authorization{URL: u, State: state, ExpiresAt: s.now().Add(s.opts.StateTTL)}, nil
+}
+
+// assertion is what a validated SAML assertion says about the user.
+type assertion struct {
+	nameID, nameIDFormat, sessionIndex string
+	attributes                         map[string][]string
+}
+
+func parseInstant(v string) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339Nano, v)
+	return t, err == nil
+}
+
+func badAssertion(format string, a ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidAssertion, fmt.Sprintf(format, a...))
+}
+
+// verifyResponse checks a Response to the AuthnRequest requestID: its
+// signature, issuer, audience, validity window, subject confirmation and
+// that its assertion has not been used before. Everything is read from
+// the signed elements only.
+func (s *service) verifyResponse(ctx context.Context, c *SAMLConfig, certs []*x509.Certificate, raw []byte, requestID string) (*assertion, error) {
+	resp, err := xmldsig.Parse(raw)
+	if err != nil {
+		return nil, badAssertion("%v", err)
+	}
+	if resp.Space() != nsSAMLProtocol || resp.Local != "Response" {
+		return nil, badAssertion("not a SAML Response")
+	}
+	acs := s.acsURL(c.TenantID)
+	if d := resp.Attr("Destination"); d != "" && d != acs {
+		return nil, badAssertion("response is for %s", d)
+	}
+	if resp.Attr("InResponseTo") != requestID {
+		return nil, badAssertion("response does not answer this login")
+	}
+	if iss := resp.Child(nsSAMLAssertion, "Issuer"); iss != nil && iss.Text() != c.IdPEntityID {
+		return nil, badAssertion("response issued by %s", iss.Text())
+	}
+	status := resp.Child(nsSAMLProtocol, "Status")
+	if status == nil || status.Child(nsSAMLProtocol, "StatusCode") == nil {
+		return nil, badAssertion("no status")
+	}
+	if code := status.Child(nsSAMLProtocol, "StatusCode").Attr("Value"); code != statusSuccess {
+		return nil, badAssertion("IdP returned %s", code)
+	}
+	if len(resp.ChildElements(nsSAMLAssertion, "EncryptedAssertion")) > 0 {
+		return nil, badAssertion("encrypted assertions are not supported")
+	}
+	assertions := resp.ChildElements(nsSAMLAssertion, "Assertion")
+	if len(assertions) != 1 {
+		return nil, badAssertion("want exactly one assertion")
+	}
+	a := assertions[0]
+
+	// The assertion is signed itself or covered by the response's
+	// signature.
+	responseSigned := len(resp.ChildElements(xmldsig.NamespaceDSig, "Signature")) > 0
+	if responseSigned {
+		if err := xmldsig.Verify(resp, certs); err != nil {
+			return nil, badAssertion("response signature: %v", err)
+		}
+	}
+	if err := xmldsig.Verify(a, certs); err != nil && (!responseSigned || !errors.Is(err, xmldsig.ErrNotSigned)) {
+		return nil, badAssertion("assertion signature: %v", err)
+	}
+
+	if iss := a.Child(nsSAMLAssertion, "Issuer"); iss == nil || iss.Text() != c.IdPEntityID {
+		return nil, badAssertion("assertion is not from the tenant's IdP")
+	}
+	now := s.now()
+	cond := a.Child(nsSAMLAssertion, "Conditions")
+	if cond == nil {
+		return nil, badAssertion("no conditions")
+	}
+	notOnOrAfter, ok := parseInstant(cond.Attr("NotOnOrAfter"))
+	if !ok || !now.Before(notOnOrAfter.Add(samlClockSkew)) {
+		return nil, badAssertion("assertion expired")
+	}
+	if nb := cond.Attr("NotBefore"); nb != "" {
+		if t, ok := parseInstant(nb); !ok || now.Add(samlClockSkew).Before(t) {
+			return nil, badAssertion("assertion not yet valid")
+		}
+	}
+	audienceOK := false
+	restrictions := cond.ChildElements(nsSAMLAssertion, "AudienceRestriction")
+	for _, ar := range restrictions {
+		for _, aud := range ar.ChildElements(nsSAMLAssertion, "Audience") {
+			audienceOK = audienceOK || aud.Text() == s.spEntityID(c.TenantID)
+		}
+	}
+	if len(restrictions) == 0 || !audienceOK {
+		return nil, badAssertion("assertion is not for this service provider")
+	}
+
+	subject := a.Child(nsSAMLAssertion, "Subject")
+	if subject == nil || subject.Child(nsSAMLAssertion, "NameID") == nil {
+		return nil, badAssertion("no subject")
+	}
+	confirmed := false
+	for _, sc := range subject.ChildElements(nsSAMLAssertion, "SubjectConfirmation") {
+		data := sc.Child(nsSAMLAssertion, "SubjectConfirmationData")
+		if sc.Attr("Method") != confirmBearer || data == nil {
+			continue
+		}
+		until, ok := parseInstant(data.Attr("NotOnOrAfter"))
+		if ok && data.Attr("Recipient") == acs && data.Attr("InResponseTo") == requestID && now.Before(until.Add(samlClockSkew)) {
+			confirmed = true
+		}
+	}
+	if !confirmed {
+		return nil, badAssertion("subject confirmation failed")
+	}
+
+	// Replays are caught by the single-use login state too; the assertion
+	// ID is remembered for as long as the assertion is valid as well.
+	id := a.Attr("ID")
+	key := cache.SAMLAssertionKey(c.TenantID, id)
+	if _, seen, err := s.cache.Get(ctx, key); err != nil {
+		return nil, err
+	} else if seen {
+		return nil, ErrAssertionReplayed
+	}
+	if err := s.cache.Set(ctx, key, "1", notOnOrAfter.Add(samlClockSkew).Sub(now)); err != nil {
+		return nil, err
+	}
+
+	nameID := subject.Child(nsSAMLAssertion, "NameID")
+	out := &assertion{nameID: nameID.Text(), nameIDFormat: nameID.Attr("Format"), attributes: map[string][]string{}}
+	if out.nameID == "" {
+		return nil, badAssertion("empty NameID")
+	}
+	if as := a.Child(nsSAMLAssertion, "AuthnStatement"); as != nil {
+		out.sessionIndex = as.Attr("SessionIndex")
+	}
+	for _, st := range a.ChildElements(nsSAMLAssertion, "AttributeStatement") {
+		for _, attr := range st.ChildElements(nsSAMLAssertion, "Attribute") {
+			var values []string
+			for _, v := range attr.ChildElements(nsSAMLAssertion, "AttributeValue") {
+				values = append(values, v.Text())
+			}
+			for _, name := range []string{attr.Attr("Name"), attr.Attr("FriendlyName")} {
+				if name != "" {
+					out.attributes[name] = append(out.attributes[name], values...)
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+func (s *service) CompleteSAML(ctx context.Context, tenantID, samlResponse, relayState string) (*Result, error) {
+	if err := s.samlAvailable(); err != nil {
+		return nil, err
+	}
+	st, err := s.takeState(ctx, relayState, ProviderSAML)
+	if err != nil {
+		return nil, err
+	}
+	if st.TenantID != tenantID {
+		return nil, ErrInvalidState
+	}
+	c, certs, err := s.enabledSAMLConfig(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(samlResponse), ""))
+	if err != nil {
+		return nil, badAssertion("bad encoding")
+	}
+	a, err := s.verifyResponse(ctx, c, certs, raw, st.RequestID)
+	if err != nil {
+		return nil, err
+	}
+
+	email := a.nameID
+	if c.EmailAttribute != "" {
+		email = ""
+		if v := a.attributes[c.EmailAttribute]; len(v) > 0 {
+			email = v[0]
+		}
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !strings.Contains(email, "@") {
+		return nil, ErrMissingClaim
+	}
+	if err := checkDomain(c.AllowedDomains, email); err != nil {
+		return nil, err
+	}
+	name := email
+	if v := a.attributes[c.NameAttribute]; c.NameAttribute != "" && len(v) > 0 && v[0] != "" {
+		name = v[0]
+	}
+
+	res, err := s.resolveUser(account{
+		tenantID: tenantID, provider: ProviderSAML, issuer: c.IdPEntityID, subject: a.nameID,
+		email: email, name: name,
+		role:      mapRole(c.RoleMappings, a.attributes[c.RoleAttribute], c.DefaultRole),
+		team:      mapTeam(c.TeamMappings, a.attributes[c.TeamAttribute], c.DefaultTeam),
+		syncRoles: c.SyncRoles,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.repo.CreateSAMLSession(&SAMLSession{
+		TenantID:     tenantID,
+		NameID:       a.nameID,
+		NameIDFormat: a.nameIDFormat,
+		SessionIndex: a.sessionIndex,
+		UserID:       res.User.ID.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *service) BeginSAMLLogout(ctx context.Context, tenantID, userID string) (string, error) {
+	if s.samlAvailable() != nil {
+		return "", nil
+	}
+	c, err := s.repo.GetSAMLConfig(tenantID)
+	if err != nil || c == nil || !c.Enabled || c.IdPSLOURL == "" {
+		return "", err
+	}
+	sess, err := s.repo.LatestSAMLSession(tenantID, userID)
+	if err != nil || sess == nil {
+		return "", err
+	}
+	nameID := `<saml:NameID>`
+	if sess.NameIDFormat != "" {
+		nameID = `<saml:NameID Format="` + xmlEscape(sess.NameIDFormat) + `">`
+	}
+	req := `<samlp:LogoutRequest xmlns:samlp="` + nsSAMLProtocol + `" xmlns:saml="` + nsSAMLAssertion + `"` +
+		` ID="` + samlID() + `" Version="2.0" IssueInstant="` + samlInstant(s.now()) + `" Destination="` + xmlEscape(c.IdPSLOURL) + `">` +
+		`<saml:Issuer>` + xmlEscape(s.spEntityID(tenantID)) + `</saml:Issuer>` +
+		nameID + xmlEscape(sess.NameID) + `</saml:NameID>`
+	if sess.SessionIndex != "" {
+		req += `<samlp:SessionIndex>` + xmlEscape(sess.SessionIndex) + `</samlp:SessionIndex>`
+	}
+	req += `</samlp:LogoutRequest>`
+	u, err := s.redirectURL(c.IdPSLOURL, "SAMLRequest", []byte(req), "")
+	if err != nil {
+		return "", err
+	}
+	if err := s.repo.DeleteSAMLSessions([]string{sess.ID}); err != nil {
+		return "", err
+	}
+	return u, nil
+}
+
+func (s *service) HandleSAMLLogout(ctx context.Context, tenantID, rawQuery string) (*Logout, error) {
+	if err := s.samlAvailable(); err != nil {
+		return nil, err
+	}
+	c, certs, err := s.enabledSAMLConfig(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	param, msg, relayState, err := readRedirect(rawQuery, certs)
+	if err != nil {
+		return nil, err
+	}
+	if iss := msg.Child(nsSAMLAssertion, "Issuer"); iss == nil || iss.Text() != c.IdPEntityID {
+		return nil, badAssertion("logout message is not from the tenant's IdP")
+	}
+	if param == "SAMLResponse" {
+		// The IdP confirming a logout we started; the local session is
+		// already gone.
+		if msg.Space() != nsSAMLProtocol || msg.Local != "LogoutResponse" {
+			return nil, badAssertion("not a LogoutResponse")
+		}
+		return &Logout{}, nil
+	}
+	if msg.Space() != nsSAMLProtocol || msg.Local != "LogoutRequest" {
+		return nil, badAssertion("not a LogoutRequest")
+	}
+	if until := msg.Attr("NotOnOrAfter"); until != "" {
+		if t, ok := parseInstant(until); !ok || !s.now().Before(t.Add(samlClockSkew)) {
+			return nil, badAssertion("logout request expired")
+		}
+	}
+	nameID := msg.Child(nsSAMLAssertion, "NameID")
+	if nameID == nil || nameID.Text() == "" {
+		return nil, badAssertion("logout request has no NameID")
+	}
+	var indexes []string
+	for _, si := range msg.ChildElements(nsSAMLProtocol, "SessionIndex") {
+		indexes = append(indexes, si.Text())
+	}
+
+	sessions, err := s.repo.FindSAMLSessions(tenantID, nameID.Text())
+	if err != nil {
+		return nil, err
+	}
+	out := &Logout{UserIDs: []string{}}
+	var ids []string
+	for _, sess := range sessions {
+		if len(indexes) > 0 && !slices.Contains(indexes, sess.SessionIndex) {
+			continue
+		}
+		ids = append(ids, sess.ID)
+		if !slices.Contains(out.UserIDs, sess.UserID) {
+			out.UserIDs = append(out.UserIDs, sess.UserID)
+		}
+	}
+	if err := s.repo.DeleteSAMLSessions(ids); err != nil {
+		return nil, err
+	}
+
+	if c.IdPSLOURL != "" {
+		resp := `<samlp:LogoutResponse xmlns:samlp="` + nsSAMLProtocol + `" xmlns:saml="` + nsSAMLAssertion + `"` +
+			` ID="` + samlID() + `" Version="2.0" IssueInstant="` + samlInstant(s.now()) + `"` +
+			` Destination="` + xmlEscape(c.IdPSLOURL) + `" InResponseTo="` + xmlEscape(msg.Attr("ID")) + `">` +
+			`<saml:Issuer>` + xmlEscape(s.spEntityID(tenantID)) + `</saml:Issuer>` +
+			`<samlp:Status><samlp:StatusCode Value="` + statusSuccess + `"/></samlp:Status></samlp:LogoutResponse>`
+		if out.RedirectURL, err = s.redirectURL(c.IdPSLOURL, "SAMLResponse", []byte(resp), relayState); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
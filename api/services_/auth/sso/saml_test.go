@@ -0,0 +1,341 @@
+package sso_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/url"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"aegis-api/cache"
+	"aegis-api/services_/auth/sso"
+	"aegis-api/services_/auth/sso/xmldsig"
+	"aegis-api/tests/fakes"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	idpEntityID = "https://idp.gov.example/saml"
+	idpSLOURL   = "https://idp.gov.example/saml/slo"
+	spBaseURL   = "https://aegis.example/api/v1/auth/sso/saml"
+)
+
+func newKeyPair(t *testing.T, name string) (*rsa.PrivateKey, *x509.Certificate) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return key, cert
+}
+
+type samlFixture struct {
+	svc      sso.Service
+	repo     *fakes.SSO
+	users    *fakes.Users
+	tenantID string
+	teamID   string
+	spCert   *x509.Certificate
+	idpKey   *rsa.PrivateKey
+	idpCert  *x509.Certificate
+}
+
+func newSAMLFixture(t *testing.T) *samlFixture {
+	spKey, spCert := newKeyPair(t, "aegis-sp")
+	idpKey, idpCert := newKeyPair(t, "idp")
+	users := &fakes.Users{}
+	repo := &fakes.SSO{}
+	svc := sso.NewService(repo, users, users, cache.NewMemory(), sso.Options{
+		SAML: sso.SAMLOptions{BaseURL: spBaseURL, Key: spKey, Certificate: spCert},
+	})
+	f := &samlFixture{svc: svc, repo: repo, users: users, tenantID: uuid.NewString(), teamID: uuid.NewString(),
+		spCert: spCert, idpKey: idpKey, idpCert: idpCert}
+
+	_, err := svc.SaveSAMLConfig(sso.Actor{UserID: uuid.NewString(), TenantID: f.tenantID}, sso.SAMLConfigInput{
+		Enabled:         true,
+		IdPEntityID:     idpEntityID,
+		IdPSSOURL:       "https://idp.gov.example/saml/sso",
+		IdPSLOURL:       idpSLOURL,
+		IdPCertificates: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: idpCert.Raw})),
+		NameAttribute:   "displayName",
+		RoleAttribute:   "groups",
+		RoleMappings:    []sso.RoleMapping{{Value: "CN=DFIR,OU=Groups", Role: "Incident Responder"}},
+		TeamAttribute:   "groups",
+		TeamMappings:    []sso.TeamMapping{{Value: "CN=DFIR,OU=Groups", TeamID: f.teamID}},
+		AllowedDomains:  []string{"agency.gov"},
+	})
+	require.NoError(t, err)
+	return f
+}
+
+func (f *samlFixture) acs() string { return spBaseURL + "/" + f.tenantID + "/acs" }
+
+func (f *samlFixture) entityID() string { return spBaseURL + "/" + f.tenantID + "/metadata" }
+
+// inflate reads a redirect-binding message from a URL the service built.
+func inflate(t *testing.T, u, param string) (*xmldsig.Element, url.Values) {
+	parsed, err := url.Parse(u)
+	require.NoError(t, err)
+	q := parsed.Query()
+	deflated, err := base64.StdEncoding.DecodeString(q.Get(param))
+	require.NoError(t, err)
+	raw, err := io.ReadAll(flate.NewReader(bytes.NewReader(deflated)))
+	require.NoError(t, err)
+	msg, err := xmldsig.Parse(raw)
+	require.NoError(t, err)
+	return msg, q
+}
+
+// begin starts a login and returns its relay state and AuthnRequest ID.
+func (f *samlFixture) begin(t *testing.T) (relayState, requestID string) {
+	auth, err := f.svc.BeginSAML(context.Background(), f.tenantID)
+	require.NoError(t, err)
+	req, _ := inflate(t, auth.URL, "SAMLRequest")
+	return auth.State, req.Attr("ID")
+}
+
+type responseParams struct {
+	RequestID, AssertionID, Email, Group string
+	ACS, Audience, Issuer                string
+	NotBefore, NotOnOrAfter, Now         string
+}
+
+var responseTemplate = template.Must(template.New("response").Parse(
+	`<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" ID="_r{{.AssertionID}}" Version="2.0" IssueInstant="{{.Now}}" Destination="{{.ACS}}" InResponseTo="{{.RequestID}}">
+  <saml:Issuer xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion">{{.Issuer}}</saml:Issuer>
+  <samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"/></samlp:Status>
+  <saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" ID="{{.AssertionID}}" Version="2.0" IssueInstant="{{.Now}}">
+    <saml:Issuer>{{.Issuer}}</saml:Issuer>
+    <saml:Subject>
+      <saml:NameID Format="urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress">{{.Email}}</saml:NameID>
+      <saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer">
+        <saml:SubjectConfirmationData NotOnOrAfter="{{.NotOnOrAfter}}" Recipient="{{.ACS}}" InResponseTo="{{.RequestID}}"/>
+      </saml:SubjectConfirmation>
+    </saml:Subject>
+    <saml:Conditions NotBefore="{{.NotBefore}}" NotOnOrAfter="{{.NotOnOrAfter}}">
+      <saml:AudienceRestriction><saml:Audience>{{.Audience}}</saml:Audience></saml:AudienceRestriction>
+    </saml:Conditions>
+    <saml:AuthnStatement AuthnInstant="{{.Now}}" SessionIndex="idx-{{.AssertionID}}"/>
+    <saml:AttributeStatement>
+      <saml:Attribute Name="groups"><saml:AttributeValue xsi:type="xs:string">{{.Group}}</saml:AttributeValue></saml:Attribute>
+      <saml:Attribute Name="displayName"><saml:AttributeValue xsi:type="xs:string">Dana Agent</saml:AttributeValue></saml:Attribute>
+    </saml:AttributeStatement>
+  </saml:Assertion>
+</samlp:Response>`))
+
+func (f *samlFixture) params(requestID string) responseParams {
+	now := time.Now().UTC()
+	return responseParams{
+		RequestID:    requestID,
+		AssertionID:  "_a" + strings.ReplaceAll(uuid.NewString(), "-", ""),
+		Email:        "dana@agency.gov",
+		Group:        "CN=DFIR,OU=Groups",
+		ACS:          f.acs(),
+		Audience:     f.entityID(),
+		Issuer:       idpEntityID,
+		NotBefore:    now.Add(-time.Minute).Format(time.RFC3339),
+		NotOnOrAfter: now.Add(5 * time.Minute).Format(time.RFC3339),
+		Now:          now.Format(time.RFC3339),
+	}
+}
+
+// response renders a Response with its assertion signed by key, then
+// applies edit to the signed XML.
+func response(t *testing.T, p responseParams, key *rsa.PrivateKey, cert *x509.Certificate, edit func(string) string) string {
+	var b bytes.Buffer
+	require.NoError(t, responseTemplate.Execute(&b, p))
+	root, err := xmldsig.Parse(b.Bytes())
+	require.NoError(t, err)
+	if key != nil {
+		require.NoError(t, xmldsig.Sign(root.Child("urn:oasis:names:tc:SAML:2.0:assertion", "Assertion"), key, cert))
+	}
+	doc := string(root.Bytes())
+	if edit != nil {
+		doc = edit(doc)
+	}
+	return base64.StdEncoding.EncodeToString([]byte(doc))
+}
+
+func TestSAMLMetadataAndSignedAuthnRequest(t *testing.T) {
+	f := newSAMLFixture(t)
+
+	md, err := f.svc.SAMLMetadata(f.tenantID)
+	require.NoError(t, err)
+	require.Contains(t, string(md), `entityID="`+f.entityID()+`"`)
+	require.Contains(t, string(md), `Location="`+f.acs()+`"`)
+	require.Contains(t, string(md), base64.StdEncoding.EncodeToString(f.spCert.Raw))
+
+	auth, err := f.svc.BeginSAML(context.Background(), f.tenantID)
+	require.NoError(t, err)
+	req, q := inflate(t, auth.URL, "SAMLRequest")
+	require.Equal(t, "AuthnRequest", req.Local)
+	require.Equal(t, f.acs(), req.Attr("AssertionConsumerServiceURL"))
+	require.Equal(t, auth.State, q.Get("RelayState"))
+
+	// The redirect binding signs the query string as sent.
+	signed := auth.URL[strings.Index(auth.URL, "?")+1 : strings.Index(auth.URL, "&Signature=")]
+	sig, err := base64.StdEncoding.DecodeString(q.Get("Signature"))
+	require.NoError(t, err)
+	require.NoError(t, xmldsig.VerifySignature(q.Get("SigAlg"), []byte(signed), sig, []*x509.Certificate{f.spCert}))
+}
+
+func TestCompleteSAMLProvisionsUser(t *testing.T) {
+	f := newSAMLFixture(t)
+	ctx := context.Background()
+	state, requestID := f.begin(t)
+
+	res, err := f.svc.CompleteSAML(ctx, f.tenantID, response(t, f.params(requestID), f.idpKey, f.idpCert, nil), state)
+	require.NoError(t, err)
+	require.True(t, res.Provisioned)
+	require.Equal(t, sso.ProviderSAML, res.Provider)
+	require.Equal(t, "dana@agency.gov", res.User.Email)
+	require.Equal(t, "Dana Agent", res.User.FullName)
+	require.Equal(t, "Incident Responder", res.User.Role)
+	require.Equal(t, f.teamID, res.User.TeamID.String())
+	require.Len(t, f.repo.SAMLSessions, 1)
+
+	// The browser hands the login to the client with a single-use ticket.
+	ticket, err := f.svc.IssueTicket(ctx, res)
+	require.NoError(t, err)
+	user, err := f.svc.RedeemTicket(ctx, ticket)
+	require.NoError(t, err)
+	require.Equal(t, res.User.ID, user.ID)
+	_, err = f.svc.RedeemTicket(ctx, ticket)
+	require.ErrorIs(t, err, sso.ErrInvalidTicket)
+}
+
+func TestCompleteSAMLRejections(t *testing.T) {
+	f := newSAMLFixture(t)
+	otherKey, otherCert := newKeyPair(t, "attacker")
+	ctx := context.Background()
+
+	cases := []struct {
+		name   string
+		mutate func(*responseParams)
+		key    *rsa.PrivateKey
+		cert   *x509.Certificate
+		edit   func(string) string
+		want   error
+	}{
+		{name: "tampered after signing", edit: func(doc string) string {
+			return strings.Replace(doc, "CN=DFIR,OU=Groups</saml:AttributeValue>", "CN=Admins</saml:AttributeValue>", 1)
+		}, want: sso.ErrInvalidAssertion},
+		{name: "signed by another key", key: otherKey, cert: otherCert, want: sso.ErrInvalidAssertion},
+		{name: "unsigned", key: nil, want: sso.ErrInvalidAssertion},
+		{name: "wrong audience", mutate: func(p *responseParams) { p.Audience = "https://other-sp.example" }, want: sso.ErrInvalidAssertion},
+		{name: "expired", mutate: func(p *responseParams) {
+			p.NotOnOrAfter = time.Now().Add(-10 * time.Minute).UTC().Format(time.RFC3339)
+		}, want: sso.ErrInvalidAssertion},
+		{name: "answers another request", mutate: func(p *responseParams) { p.RequestID = "_other" }, want: sso.ErrInvalidAssertion},
+		{name: "another issuer", mutate: func(p *responseParams) { p.Issuer = "https://evil.example" }, want: sso.ErrInvalidAssertion},
+		{name: "domain not allowed", mutate: func(p *responseParams) { p.Email = "eve@evil.example" }, want: sso.ErrDomainNotAllowed},
+		{name: "signature wrapping", edit: func(doc string) string {
+			// An unsigned assertion for another user next to the signed one.
+			start := strings.Index(doc, "<saml:Assertion")
+			forged := strings.Replace(doc[start:strings.Index(doc, "</samlp:Response>")], "dana@agency.gov", "eve@agency.gov", 1)
+			return doc[:start] + forged[:strings.Index(forged, "<ds:Signature")] + forged[strings.Index(forged, "</ds:Signature>")+len("</ds:Signature>"):] + doc[start:]
+		}, want: sso.ErrInvalidAssertion},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			state, requestID := f.begin(t)
+			p := f.params(requestID)
+			if tc.mutate != nil {
+				tc.mutate(&p)
+			}
+			key, cert := f.idpKey, f.idpCert
+			if tc.key != nil || tc.name == "unsigned" {
+				key, cert = tc.key, tc.cert
+			}
+			_, err := f.svc.CompleteSAML(ctx, f.tenantID, response(t, p, key, cert, tc.edit), state)
+			require.ErrorIs(t, err, tc.want)
+		})
+	}
+	require.Empty(t, f.users.ByID)
+
+	// A response is good for one login, and an assertion for one use.
+	state, requestID := f.begin(t)
+	p := f.params(requestID)
+	_, err := f.svc.CompleteSAML(ctx, f.tenantID, response(t, p, f.idpKey, f.idpCert, nil), state)
+	require.NoError(t, err)
+	_, err = f.svc.CompleteSAML(ctx, f.tenantID, response(t, p, f.idpKey, f.idpCert, nil), state)
+	require.ErrorIs(t, err, sso.ErrInvalidState)
+	state, p.RequestID = f.begin(t)
+	_, err = f.svc.CompleteSAML(ctx, f.tenantID, response(t, p, f.idpKey, f.idpCert, nil), state)
+	require.ErrorIs(t, err, sso.ErrAssertionReplayed)
+}
+
+// idpRedirect signs a redirect-binding message as the IdP.
+func (f *samlFixture) idpRedirect(t *testing.T, msg string, key *rsa.PrivateKey) string {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	require.NoError(t, err)
+	_, _ = w.Write([]byte(msg))
+	require.NoError(t, w.Close())
+	q := "SAMLRequest=" + url.QueryEscape(base64.StdEncoding.EncodeToString(buf.Bytes())) +
+		"&RelayState=rs&SigAlg=" + url.QueryEscape(xmldsig.AlgRSASHA256)
+	sig, err := xmldsig.SignData(key, []byte(q))
+	require.NoError(t, err)
+	return q + "&Signature=" + url.QueryEscape(base64.StdEncoding.EncodeToString(sig))
+}
+
+func TestSAMLSingleLogout(t *testing.T) {
+	f := newSAMLFixture(t)
+	ctx := context.Background()
+	login := func() (*sso.Result, responseParams) {
+		state, requestID := f.begin(t)
+		p := f.params(requestID)
+		res, err := f.svc.CompleteSAML(ctx, f.tenantID, response(t, p, f.idpKey, f.idpCert, nil), state)
+		require.NoError(t, err)
+		return res, p
+	}
+
+	// Logout started here sends a signed LogoutRequest to the IdP.
+	res, p := login()
+	u, err := f.svc.BeginSAMLLogout(ctx, f.tenantID, res.User.ID.String())
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(u, idpSLOURL+"?"))
+	req, _ := inflate(t, u, "SAMLRequest")
+	require.Equal(t, "LogoutRequest", req.Local)
+	require.Equal(t, "dana@agency.gov", req.Child("urn:oasis:names:tc:SAML:2.0:assertion", "NameID").Text())
+	require.Equal(t, "idx-"+p.AssertionID, req.Child("urn:oasis:names:tc:SAML:2.0:protocol", "SessionIndex").Text())
+	require.Empty(t, f.repo.SAMLSessions)
+
+	// Logout started at the IdP ends the user's sessions here.
+	res, p = login()
+	logoutRequest := `<samlp:LogoutRequest xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_lr1" Version="2.0" IssueInstant="` +
+		time.Now().UTC().Format(time.RFC3339) + `"><saml:Issuer>` + idpEntityID + `</saml:Issuer>` +
+		`<saml:NameID>dana@agency.gov</saml:NameID><samlp:SessionIndex>idx-` + p.AssertionID + `</samlp:SessionIndex></samlp:LogoutRequest>`
+
+	otherKey, _ := newKeyPair(t, "attacker")
+	_, err = f.svc.HandleSAMLLogout(ctx, f.tenantID, f.idpRedirect(t, logoutRequest, otherKey))
+	require.ErrorIs(t, err, sso.ErrInvalidAssertion)
+
+	out, err := f.svc.HandleSAMLLogout(ctx, f.tenantID, f.idpRedirect(t, logoutRequest, f.idpKey))
+	require.NoError(t, err)
+	require.Equal(t, []string{res.User.ID.String()}, out.UserIDs)
+	resp, q := inflate(t, out.RedirectURL, "SAMLResponse")
+	require.Equal(t, "_lr1", resp.Attr("InResponseTo"))
+	require.Equal(t, "rs", q.Get("RelayState"))
+	require.Empty(t, f.repo.SAMLSessions)
+}
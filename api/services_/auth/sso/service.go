@@ -0,0 +1,673 @@
+// Package sso signs users in through their tenant's identity provider:
+// per-tenant OpenID Connect (authorization code flow with PKCE) or SAML
+// 2.0 configuration, just-in-time provisioning with claim-to-role and
+// team mapping, and the tenant option to turn local passwords off.
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"aegis-api/cache"
+	"aegis-api/services_/auth/registration"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+var (
+	ErrNotConfigured          = errors.New("single sign-on is not configured for this tenant")
+	ErrInvalidConfig          = errors.New("invalid single sign-on configuration")
+	ErrDiscovery              = errors.New("could not read the identity provider's configuration")
+	ErrTokenExchange          = errors.New("the identity provider refused the authorization code")
+	ErrInvalidIDToken         = errors.New("invalid ID token")
+	ErrInvalidState           = errors.New("SSO login expired or was already completed; start again")
+	ErrMissingClaim           = errors.New("the identity provider did not return a subject and email")
+	ErrEmailNotVerified       = errors.New("the identity provider has not verified this email address")
+	ErrDomainNotAllowed       = errors.New("this email domain may not sign in to the tenant")
+	ErrNoRole                 = errors.New("no role is mapped for this user; ask your administrator for access")
+	ErrTenantMismatch         = errors.New("this account belongs to another tenant")
+	ErrLocalPasswordsDisabled = errors.New("password login is disabled for this tenant; sign in with SSO")
+	ErrSAMLUnavailable        = errors.New("SAML is not available: the service provider has no signing key")
+	ErrInvalidAssertion       = errors.New("invalid SAML response")
+	ErrAssertionReplayed      = errors.New("SAML assertion was already used")
+	ErrInvalidTicket          = errors.New("login ticket expired or was already used")
+)
+
+// breakGlassRole keeps password login when a tenant turns it off, so a
+// misconfigured identity provider cannot lock the tenant out.
+const breakGlassRole = "Tenant Admin"
+
+// unmappableRoles are platform roles SSO must never grant.
+var unmappableRoles = []string{"Admin", "System Admin"}
+
+var defaultScopes = []string{"openid", "email", "profile"}
+
+// Options tunes the SSO service.
+type Options struct {
+	// HTTPClient talks to identity providers. Default: 10s timeout.
+	HTTPClient *http.Client
+	// StateTTL is how long a user has to complete a login at the
+	// provider. Default 10 minutes.
+	StateTTL time.Duration
+	// TicketTTL is how long the client has to redeem a login ticket.
+	// Default 2 minutes.
+	TicketTTL time.Duration
+	SAML      SAMLOptions
+}
+
+// SAMLOptions make AEGIS a SAML service provider.
+type SAMLOptions struct {
+	// BaseURL is the public URL of the SAML endpoints; each tenant's
+	// metadata, ACS and SLO endpoints are below BaseURL/<tenantID>.
+	BaseURL string
+	// Key and Certificate sign AuthnRequests and logout messages; the
+	// certificate is published in the metadata. Without them SAML is
+	// unavailable.
+	Key         *rsa.PrivateKey
+	Certificate *x509.Certificate
+}
+
+// loginState is kept in the cache while the user is at the provider.
+type loginState struct {
+	TenantID string `json:"tenant_id"`
+	Provider string `json:"provider"`
+	// OIDC
+	Nonce    string `json:"nonce,omitempty"`
+	Verifier string `json:"verifier,omitempty"`
+	// SAML: the AuthnRequest the response must answer.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+type service struct {
+	repo        Repository
+	users       Users
+	provisioner Provisioner
+	cache       cache.Client
+	providers   *providers
+	opts        Options
+	now         func() time.Time
+}
+
+func NewService(repo Repository, users Users, provisioner Provisioner, c cache.Client, opts Options) Service {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if opts.StateTTL <= 0 {
+		opts.StateTTL = 10 * time.Minute
+	}
+	if opts.TicketTTL <= 0 {
+		opts.TicketTTL = 2 * time.Minute
+	}
+	opts.SAML.BaseURL = strings.TrimRight(opts.SAML.BaseURL, "/")
+	if c == nil {
+		c = cache.NewMemory()
+	}
+	return &service{
+		repo:        repo,
+		users:       users,
+		provisioner: provisioner,
+		cache:       c,
+		providers:   &providers{byIss: map[string]*provider{}, client: opts.HTTPClient},
+		opts:        opts,
+		now:         time.Now,
+	}
+}
+
+func (s *service) GetOIDCConfig(tenantID string) (*OIDCConfig, error) {
+	c, err := s.repo.GetOIDCConfig(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, ErrNotConfigured
+	}
+	return c, nil
+}
+
+func invalid(format string, a ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidConfig, fmt.Sprintf(format, a...))
+}
+
+func checkRole(role string) error {
+	if slices.Contains(unmappableRoles, role) {
+		return invalid("role %q cannot be granted through SSO", role)
+	}
+	return nil
+}
+
+func jsonOf(v interface{}) datatypes.JSON {
+	raw, _ := json.Marshal(v)
+	return datatypes.JSON(raw)
+}
+
+// mappingInput is the account mapping part of a provider's
+// configuration.
+type mappingInput struct {
+	roleSource     string
+	roleMappings   []RoleMapping
+	defaultRole    string
+	teamSource     string
+	teamMappings   []TeamMapping
+	defaultTeamID  *string
+	allowedDomains []string
+}
+
+// validate checks the mappings and returns the normalized allowed
+// domains.
+func (m mappingInput) validate() ([]string, error) {
+	for _, rm := range m.roleMappings {
+		if rm.Value == "" || rm.Role == "" {
+			return nil, invalid("role mappings need a value and a role")
+		}
+		if err := checkRole(rm.Role); err != nil {
+			return nil, err
+		}
+	}
+	if len(m.roleMappings) > 0 && m.roleSource == "" {
+		return nil, invalid("the claim or attribute roles are mapped from is required")
+	}
+	if m.defaultRole != "" {
+		if err := checkRole(m.defaultRole); err != nil {
+			return nil, err
+		}
+	}
+	if len(m.roleMappings) == 0 && m.defaultRole == "" {
+		return nil, invalid("role mappings or a default role are required")
+	}
+	for _, tm := range m.teamMappings {
+		if _, err := uuid.Parse(tm.TeamID); err != nil || tm.Value == "" {
+			return nil, invalid("team mappings need a value and a team ID")
+		}
+	}
+	if len(m.teamMappings) > 0 && m.teamSource == "" {
+		return nil, invalid("the claim or attribute teams are mapped from is required")
+	}
+	if m.defaultTeamID != nil {
+		if _, err := uuid.Parse(*m.defaultTeamID); err != nil {
+			return nil, invalid("default_team_id must be a team ID")
+		}
+	}
+	domains := make([]string, 0, len(m.allowedDomains))
+	for _, d := range m.allowedDomains {
+		d = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(d, "@")))
+		if d == "" || strings.ContainsAny(d, "@/ ") {
+			return nil, invalid("bad allowed domain %q", d)
+		}
+		if !slices.Contains(domains, d) {
+			domains = append(domains, d)
+		}
+	}
+	return domains, nil
+}
+
+func (s *service) SaveOIDCConfig(ctx context.Context, actor Actor, in OIDCConfigInput) (*OIDCConfig, error) {
+	issuer, err := url.Parse(strings.TrimSpace(in.Issuer))
+	if err != nil || issuer.Scheme != "https" || issuer.Host == "" {
+		return nil, invalid("issuer must be an https URL")
+	}
+	redirect, err := url.Parse(strings.TrimSpace(in.RedirectURI))
+	if err != nil || !redirect.IsAbs() {
+		return nil, invalid("redirect_uri must be an absolute URL")
+	}
+	if strings.TrimSpace(in.ClientID) == "" {
+		return nil, invalid("client_id is required")
+	}
+
+	scopes := []string{"openid"}
+	if len(in.Scopes) == 0 {
+		in.Scopes = defaultScopes
+	}
+	for _, sc := range in.Scopes {
+		if sc = strings.TrimSpace(sc); sc != "" && !slices.Contains(scopes, sc) {
+			scopes = append(scopes, sc)
+		}
+	}
+	domains, err := mappingInput{
+		roleSource: in.RoleClaim, roleMappings: in.RoleMappings, defaultRole: in.DefaultRole,
+		teamSource: in.TeamClaim, teamMappings: in.TeamMappings, defaultTeamID: in.DefaultTeamID,
+		allowedDomains: in.AllowedDomains,
+	}.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.GetOIDCConfig(actor.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	secret := in.ClientSecret
+	if secret == "" && existing != nil {
+		secret = existing.ClientSecret
+	}
+	c := &OIDCConfig{
+		TenantID:       actor.TenantID,
+		Enabled:        in.Enabled,
+		Issuer:         issuer.String(),
+		ClientID:       strings.TrimSpace(in.ClientID),
+		ClientSecret:   secret,
+		RedirectURI:    redirect.String(),
+		Scopes:         jsonOf(scopes),
+		RoleClaim:      in.RoleClaim,
+		RoleMappings:   jsonOf(nonNil(in.RoleMappings)),
+		DefaultRole:    in.DefaultRole,
+		TeamClaim:      in.TeamClaim,
+		TeamMappings:   jsonOf(nonNil(in.TeamMappings)),
+		DefaultTeam:    in.DefaultTeamID,
+		AllowedDomains: jsonOf(domains),
+		SyncRoles:      in.SyncRoles,
+		UpdatedBy:      actor.UserID,
+	}
+	if !c.Enabled {
+		if err := s.requirePasswordsAllowed(actor.TenantID, ProviderOIDC); err != nil {
+			return nil, err
+		}
+	} else if _, err := s.providers.get(ctx, c.Issuer, true); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveOIDCConfig(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func nonNil[T any](v []T) []T {
+	if v == nil {
+		return []T{}
+	}
+	return v
+}
+
+// ssoEnabled reports whether the tenant has an enabled provider other
+// than except.
+func (s *service) ssoEnabled(tenantID, except string) (bool, error) {
+	if except != ProviderOIDC {
+		c, err := s.repo.GetOIDCConfig(tenantID)
+		if err != nil || (c != nil && c.Enabled) {
+			return err == nil, err
+		}
+	}
+	if except != ProviderSAML {
+		c, err := s.repo.GetSAMLConfig(tenantID)
+		if err != nil || (c != nil && c.Enabled) {
+			return err == nil, err
+		}
+	}
+	return false, nil
+}
+
+// requirePasswordsAllowed refuses to switch provider off while it is the
+// only way members can sign in.
+func (s *service) requirePasswordsAllowed(tenantID, provider string) error {
+	st, err := s.repo.GetSettings(tenantID)
+	if err != nil {
+		return err
+	}
+	if st == nil || !st.LocalPasswordsDisabled {
+		return nil
+	}
+	other, err := s.ssoEnabled(tenantID, provider)
+	if err != nil {
+		return err
+	}
+	if !other {
+		return invalid("re-enable local passwords before turning SSO off")
+	}
+	return nil
+}
+
+func (s *service) DeleteOIDCConfig(actor Actor) error {
+	if err := s.requirePasswordsAllowed(actor.TenantID, ProviderOIDC); err != nil {
+		return err
+	}
+	return s.repo.DeleteOIDCConfig(actor.TenantID)
+}
+
+func (s *service) GetSettings(tenantID string) (*Settings, error) {
+	st, err := s.repo.GetSettings(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if st == nil {
+		st = &Settings{TenantID: tenantID}
+	}
+	return st, nil
+}
+
+func (s *service) UpdateSettings(actor Actor, in SettingsInput) (*Settings, error) {
+	if in.LocalPasswordsDisabled {
+		enabled, err := s.ssoEnabled(actor.TenantID, "")
+		if err != nil {
+			return nil, err
+		}
+		if !enabled {
+			return nil, invalid("enable SSO before disabling local passwords")
+		}
+	}
+	st := &Settings{TenantID: actor.TenantID, LocalPasswordsDisabled: in.LocalPasswordsDisabled, UpdatedBy: actor.UserID}
+	if err := s.repo.SaveSettings(st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func (s *service) LocalPasswordAllowed(tenantID, role string) (bool, error) {
+	if tenantID == "" || role == breakGlassRole {
+		return true, nil
+	}
+	st, err := s.repo.GetSettings(tenantID)
+	if err != nil {
+		return false, err
+	}
+	return st == nil || !st.LocalPasswordsDisabled, nil
+}
+
+func emailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(email[at+1:])
+}
+
+func (s *service) Discover(email string) ([]Discovery, error) {
+	domain := emailDomain(strings.TrimSpace(email))
+	if domain == "" {
+		return []Discovery{}, nil
+	}
+	found, err := s.repo.FindTenantsByDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+	return nonNil(found), nil
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (s *service) BeginOIDC(ctx context.Context, tenantID string) (*Authorization, error) {
+	c, err := s.repo.GetOIDCConfig(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil || !c.Enabled {
+		return nil, ErrNotConfigured
+	}
+	prov, err := s.providers.get(ctx, c.Issuer, false)
+	if err != nil {
+		return nil, err
+	}
+	st := loginState{TenantID: tenantID, Provider: ProviderOIDC}
+	state, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	if st.Nonce, err = randomString(); err != nil {
+		return nil, err
+	}
+	if st.Verifier, err = randomString(); err != nil {
+		return nil, err
+	}
+	var scopes []string
+	_ = json.Unmarshal(c.Scopes, &scopes)
+	u, err := authorizationURL(prov.meta, c, scopes, state, st.Nonce, st.Verifier)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.putState(ctx, state, st); err != nil {
+		return nil, err
+	}
+	return &Authorization{URL: u, State: state, ExpiresAt: s.now().Add(s.opts.StateTTL)}, nil
+}
+
+func (s *service) putState(ctx context.Context, state string, st loginState) error {
+	raw, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return s.cache.Set(ctx, cache.SSOStateKey(state), string(raw), s.opts.StateTTL)
+}
+
+// takeState consumes a login state; each completes one login.
+func (s *service) takeState(ctx context.Context, state, provider string) (*loginState, error) {
+	key := cache.SSOStateKey(state)
+	raw, ok, err := s.cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidState
+	}
+	if n, err := s.cache.Del(ctx, key); err != nil || n == 0 {
+		return nil, ErrInvalidState
+	}
+	var st loginState
+	if err := json.Unmarshal([]byte(raw), &st); err != nil || st.Provider != provider {
+		return nil, ErrInvalidState
+	}
+	return &st, nil
+}
+
+func (s *service) CompleteOIDC(ctx context.Context, state, code string) (*Result, error) {
+	st, err := s.takeState(ctx, state, ProviderOIDC)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.repo.GetOIDCConfig(st.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil || !c.Enabled {
+		return nil, ErrNotConfigured
+	}
+	prov, err := s.providers.get(ctx, c.Issuer, false)
+	if err != nil {
+		return nil, err
+	}
+	idToken, err := s.providers.exchangeCode(ctx, prov.meta, c, code, st.Verifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := s.providers.verifyIDToken(ctx, c, idToken, st.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, _ := claims["sub"].(string)
+	email, _ := claims["email"].(string)
+	email = strings.ToLower(strings.TrimSpace(email))
+	if subject == "" || email == "" {
+		return nil, ErrMissingClaim
+	}
+	if verified, ok := claims["email_verified"].(bool); ok && !verified {
+		return nil, ErrEmailNotVerified
+	}
+	if err := checkDomain(c.AllowedDomains, email); err != nil {
+		return nil, err
+	}
+	name, _ := claims["name"].(string)
+	if name == "" {
+		name = email
+	}
+	return s.resolveUser(account{
+		tenantID: c.TenantID, provider: ProviderOIDC, issuer: c.Issuer, subject: subject,
+		email: email, name: name,
+		role:      mapRole(c.RoleMappings, claimValues(claims, c.RoleClaim), c.DefaultRole),
+		team:      mapTeam(c.TeamMappings, claimValues(claims, c.TeamClaim), c.DefaultTeam),
+		syncRoles: c.SyncRoles,
+	})
+}
+
+func checkDomain(allowed datatypes.JSON, email string) error {
+	var domains []string
+	_ = json.Unmarshal(allowed, &domains)
+	if len(domains) > 0 && !slices.Contains(domains, emailDomain(email)) {
+		return ErrDomainNotAllowed
+	}
+	return nil
+}
+
+// mapRole picks the role of the first mapping whose value the user has,
+// falling back to the default role.
+func mapRole(mappings datatypes.JSON, values []string, defaultRole string) string {
+	var ms []RoleMapping
+	_ = json.Unmarshal(mappings, &ms)
+	for _, m := range ms {
+		if slices.Contains(values, m.Value) {
+			return m.Role
+		}
+	}
+	return defaultRole
+}
+
+func mapTeam(mappings datatypes.JSON, values []string, defaultTeam *string) *uuid.UUID {
+	var ms []TeamMapping
+	_ = json.Unmarshal(mappings, &ms)
+	for _, m := range ms {
+		if slices.Contains(values, m.Value) {
+			if id, err := uuid.Parse(m.TeamID); err == nil {
+				return &id
+			}
+		}
+	}
+	if defaultTeam != nil {
+		if id, err := uuid.Parse(*defaultTeam); err == nil {
+			return &id
+		}
+	}
+	return nil
+}
+
+// account is a user as an identity provider asserts them, with the role
+// and team the tenant's mappings give them.
+type account struct {
+	tenantID, provider, issuer, subject string
+	email, name                         string
+	role                                string
+	team                                *uuid.UUID
+	syncRoles                           bool
+}
+
+// resolveUser finds the user linked to the identity, links an existing
+// account of the tenant with the same email, or provisions one.
+func (s *service) resolveUser(a account) (*Result, error) {
+	res := &Result{TenantID: a.tenantID, Provider: a.provider}
+	now := s.now()
+	role, team := a.role, a.team
+
+	ident, err := s.repo.GetIdentity(a.issuer, a.subject)
+	if err != nil {
+		return nil, err
+	}
+	var user *registration.User
+	if ident != nil {
+		user, err = s.users.GetUserByID(ident.UserID)
+		if err != nil || user == nil || user.ID == uuid.Nil {
+			return nil, fmt.Errorf("linked user %s not found", ident.UserID)
+		}
+	} else if user, err = s.users.GetUserByEmail(a.email); err == nil && user != nil {
+		res.Linked = true
+	} else {
+		if role == "" {
+			return nil, ErrNoRole
+		}
+		tenantID, err := uuid.Parse(a.tenantID)
+		if err != nil {
+			return nil, err
+		}
+		created, err := s.provisioner.ProvisionUser(registration.ProvisionRequest{
+			FullName: a.name,
+			Email:    a.email,
+			Role:     role,
+			TenantID: tenantID,
+			TeamID:   team,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to provision user: %w", err)
+		}
+		user = &created
+		res.Provisioned = true
+	}
+	if user.TenantID == nil || user.TenantID.String() != a.tenantID {
+		return nil, ErrTenantMismatch
+	}
+
+	if a.syncRoles && !res.Provisioned {
+		if role == "" {
+			return nil, ErrNoRole
+		}
+		if user.Role != role || (team != nil && (user.TeamID == nil || *user.TeamID != *team)) {
+			user.Role = role
+			if team != nil {
+				user.TeamID = team
+			}
+			if err := s.users.UpdateUser(user); err != nil {
+				return nil, err
+			}
+			res.RoleChanged = true
+		}
+	}
+
+	if ident == nil {
+		ident = &Identity{UserID: user.ID.String(), TenantID: a.tenantID, Provider: a.provider, Issuer: a.issuer, Subject: a.subject}
+		ident.Email, ident.LastLoginAt = a.email, &now
+		if err := s.repo.CreateIdentity(ident); err != nil {
+			return nil, err
+		}
+	} else {
+		ident.Email, ident.LastLoginAt = a.email, &now
+		if err := s.repo.SaveIdentity(ident); err != nil {
+			return nil, err
+		}
+	}
+	res.User = user
+	return res, nil
+}
+
+// IssueTicket and RedeemTicket carry a login from a browser redirect,
+// such as the SAML POST binding, to the client that starts the session.
+func (s *service) IssueTicket(ctx context.Context, res *Result) (string, error) {
+	ticket, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	if err := s.cache.Set(ctx, cache.SSOTicketKey(ticket), res.User.ID.String(), s.opts.TicketTTL); err != nil {
+		return "", err
+	}
+	return ticket, nil
+}
+
+func (s *service) RedeemTicket(ctx context.Context, ticket string) (*registration.User, error) {
+	key := cache.SSOTicketKey(ticket)
+	userID, ok, err := s.cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidTicket
+	}
+	if n, err := s.cache.Del(ctx, key); err != nil || n == 0 {
+		return nil, ErrInvalidTicket
+	}
+	user, err := s.users.GetUserByID(userID)
+	if err != nil || user == nil || user.ID == uuid.Nil {
+		return nil, ErrInvalidTicket
+	}
+	return user, nil
+}
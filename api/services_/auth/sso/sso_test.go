@@ -0,0 +1,284 @@
+package sso_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"aegis-api/cache"
+	"aegis-api/services_/auth/registration"
+	"aegis-api/services_/auth/sso"
+	"aegis-api/tests/fakes"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// mockProvider is a minimal OpenID Connect provider: discovery, JWKS and
+// a token endpoint that enforces PKCE.
+type mockProvider struct {
+	srv      *httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+	mu       sync.Mutex
+	codes    map[string]pendingCode
+}
+
+type pendingCode struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	m := &mockProvider{key: key, clientID: "aegis", codes: map[string]pendingCode{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.srv.URL,
+			"authorization_endpoint": m.srv.URL + "/authorize",
+			"token_endpoint":         m.srv.URL + "/token",
+			"jwks_uri":               m.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		b64 := base64.RawURLEncoding.EncodeToString
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1", "use": "sig",
+			"n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		m.mu.Lock()
+		p, ok := m.codes[r.Form.Get("code")]
+		delete(m.codes, r.Form.Get("code"))
+		m.mu.Unlock()
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != p.challenge || r.Form.Get("client_secret") != "s3cret" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		tok := jwt.NewWithClaims(jwt.SigningMethodRS256, p.claims)
+		tok.Header["kid"] = "k1"
+		signed, _ := tok.SignedString(key)
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": signed, "token_type": "Bearer"})
+	})
+	m.srv = httptest.NewTLSServer(mux)
+	t.Cleanup(m.srv.Close)
+	return m
+}
+
+// authorize stands in for the user signing in at the provider; it
+// returns the code the provider would redirect back with.
+func (m *mockProvider) authorize(t *testing.T, authURL string, claims jwt.MapClaims) string {
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	q := u.Query()
+	require.Equal(t, "S256", q.Get("code_challenge_method"))
+	now := time.Now()
+	full := jwt.MapClaims{
+		"iss": m.srv.URL, "aud": m.clientID, "nonce": q.Get("nonce"),
+		"iat": now.Unix(), "exp": now.Add(5 * time.Minute).Unix(),
+	}
+	for k, v := range claims {
+		full[k] = v
+	}
+	code := uuid.NewString()
+	m.mu.Lock()
+	m.codes[code] = pendingCode{challenge: q.Get("code_challenge"), claims: full}
+	m.mu.Unlock()
+	return code
+}
+
+type fixture struct {
+	svc      sso.Service
+	idp      *mockProvider
+	users    *fakes.Users
+	tenantID uuid.UUID
+	teamID   uuid.UUID
+	admin    sso.Actor
+}
+
+func newFixture(t *testing.T) *fixture {
+	idp := newMockProvider(t)
+	users := &fakes.Users{}
+	repo := &fakes.SSO{}
+	svc := sso.NewService(repo, users, users, cache.NewMemory(), sso.Options{HTTPClient: idp.srv.Client()})
+	f := &fixture{svc: svc, idp: idp, users: users, tenantID: uuid.New(), teamID: uuid.New()}
+	f.admin = sso.Actor{UserID: uuid.NewString(), TenantID: f.tenantID.String()}
+	return f
+}
+
+func (f *fixture) configure(t *testing.T, mutate func(*sso.OIDCConfigInput)) {
+	teamID := f.teamID.String()
+	in := sso.OIDCConfigInput{
+		Enabled:      true,
+		Issuer:       f.idp.srv.URL,
+		ClientID:     "aegis",
+		ClientSecret: "s3cret",
+		RedirectURI:  "https://aegis.example/sso/callback",
+		RoleClaim:    "groups",
+		RoleMappings: []sso.RoleMapping{
+			{Value: "dfir-leads", Role: "DFIR Manager"},
+			{Value: "analysts", Role: "Forensic Analyst"},
+		},
+		TeamClaim:      "groups",
+		TeamMappings:   []sso.TeamMapping{{Value: "analysts", TeamID: teamID}},
+		AllowedDomains: []string{"Example.com"},
+	}
+	if mutate != nil {
+		mutate(&in)
+	}
+	_, err := f.svc.SaveOIDCConfig(context.Background(), f.admin, in)
+	require.NoError(t, err)
+}
+
+func (f *fixture) login(t *testing.T, claims jwt.MapClaims) (*sso.Result, error) {
+	ctx := context.Background()
+	auth, err := f.svc.BeginOIDC(ctx, f.tenantID.String())
+	require.NoError(t, err)
+	code := f.idp.authorize(t, auth.URL, claims)
+	return f.svc.CompleteOIDC(ctx, auth.State, code)
+}
+
+func TestCompleteOIDCProvisionsUserWithMappedRoleAndTeam(t *testing.T) {
+	f := newFixture(t)
+	f.configure(t, nil)
+
+	claims := jwt.MapClaims{"sub": "idp-1", "email": "Ana@example.com", "email_verified": true, "name": "Ana", "groups": []string{"analysts"}}
+	res, err := f.login(t, claims)
+	require.NoError(t, err)
+	require.True(t, res.Provisioned)
+	require.Equal(t, "ana@example.com", res.User.Email)
+	require.Equal(t, "Forensic Analyst", res.User.Role)
+	require.Equal(t, f.tenantID, *res.User.TenantID)
+	require.Equal(t, f.teamID, *res.User.TeamID)
+
+	// The identity is linked; the next login finds the same user.
+	again, err := f.login(t, claims)
+	require.NoError(t, err)
+	require.False(t, again.Provisioned)
+	require.Equal(t, res.User.ID, again.User.ID)
+	require.Len(t, f.users.ByID, 1)
+}
+
+func TestCompleteOIDCLinksExistingUserOfTenant(t *testing.T) {
+	f := newFixture(t)
+	f.configure(t, func(in *sso.OIDCConfigInput) { in.SyncRoles = true })
+	existing := &registration.User{ID: uuid.New(), Email: "lee@example.com", Role: "Forensic Analyst", TenantID: &f.tenantID}
+	f.users.Add(existing)
+
+	res, err := f.login(t, jwt.MapClaims{"sub": "idp-2", "email": "lee@example.com", "groups": []string{"dfir-leads"}})
+	require.NoError(t, err)
+	require.True(t, res.Linked)
+	require.True(t, res.RoleChanged)
+	require.Equal(t, existing.ID, res.User.ID)
+	require.Equal(t, "DFIR Manager", f.users.ByID[existing.ID.String()].Role)
+
+	// Accounts of other tenants are never taken over.
+	other := uuid.New()
+	f.users.Add(&registration.User{ID: uuid.New(), Email: "sam@example.com", Role: "Forensic Analyst", TenantID: &other})
+	_, err = f.login(t, jwt.MapClaims{"sub": "idp-3", "email": "sam@example.com", "groups": []string{"analysts"}})
+	require.ErrorIs(t, err, sso.ErrTenantMismatch)
+}
+
+func TestCompleteOIDCRejections(t *testing.T) {
+	f := newFixture(t)
+	f.configure(t, nil)
+
+	_, err := f.login(t, jwt.MapClaims{"sub": "x", "email": "eve@evil.test", "groups": []string{"analysts"}})
+	require.ErrorIs(t, err, sso.ErrDomainNotAllowed)
+
+	_, err = f.login(t, jwt.MapClaims{"sub": "x", "email": "eve@example.com", "email_verified": false, "groups": []string{"analysts"}})
+	require.ErrorIs(t, err, sso.ErrEmailNotVerified)
+
+	_, err = f.login(t, jwt.MapClaims{"sub": "x", "email": "eve@example.com", "groups": []string{"interns"}})
+	require.ErrorIs(t, err, sso.ErrNoRole)
+	require.Empty(t, f.users.ByID)
+}
+
+func TestCompleteOIDCStateAndPKCE(t *testing.T) {
+	f := newFixture(t)
+	f.configure(t, nil)
+	ctx := context.Background()
+	claims := jwt.MapClaims{"sub": "idp-4", "email": "kim@example.com", "groups": []string{"analysts"}}
+
+	auth, err := f.svc.BeginOIDC(ctx, f.tenantID.String())
+	require.NoError(t, err)
+	code := f.idp.authorize(t, auth.URL, claims)
+	_, err = f.svc.CompleteOIDC(ctx, auth.State, code)
+	require.NoError(t, err)
+
+	// The state is single use.
+	_, err = f.svc.CompleteOIDC(ctx, auth.State, code)
+	require.ErrorIs(t, err, sso.ErrInvalidState)
+
+	// A code issued for another login's PKCE challenge is refused.
+	first, err := f.svc.BeginOIDC(ctx, f.tenantID.String())
+	require.NoError(t, err)
+	second, err := f.svc.BeginOIDC(ctx, f.tenantID.String())
+	require.NoError(t, err)
+	stolen := f.idp.authorize(t, first.URL, claims)
+	_, err = f.svc.CompleteOIDC(ctx, second.State, stolen)
+	require.ErrorIs(t, err, sso.ErrTokenExchange)
+
+	// An ID token carrying another login's nonce is refused.
+	third, err := f.svc.BeginOIDC(ctx, f.tenantID.String())
+	require.NoError(t, err)
+	replayed := f.idp.authorize(t, third.URL, jwt.MapClaims{"sub": "idp-4", "email": "kim@example.com", "nonce": "old"})
+	_, err = f.svc.CompleteOIDC(ctx, third.State, replayed)
+	require.ErrorIs(t, err, sso.ErrInvalidIDToken)
+}
+
+func TestConfigAndLocalPasswordSettings(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	_, err := f.svc.UpdateSettings(f.admin, sso.SettingsInput{LocalPasswordsDisabled: true})
+	require.ErrorIs(t, err, sso.ErrInvalidConfig)
+
+	_, err = f.svc.SaveOIDCConfig(ctx, f.admin, sso.OIDCConfigInput{
+		Enabled: true, Issuer: f.idp.srv.URL, ClientID: "aegis", RedirectURI: "https://aegis.example/cb",
+		DefaultRole: "System Admin",
+	})
+	require.ErrorIs(t, err, sso.ErrInvalidConfig)
+
+	f.configure(t, nil)
+	cfg, err := f.svc.GetOIDCConfig(f.tenantID.String())
+	require.NoError(t, err)
+	var scopes, domains []string
+	require.NoError(t, json.Unmarshal(cfg.Scopes, &scopes))
+	require.NoError(t, json.Unmarshal(cfg.AllowedDomains, &domains))
+	require.Equal(t, []string{"openid", "email", "profile"}, scopes)
+	require.Equal(t, []string{"example.com"}, domains)
+
+	tenants, err := f.svc.Discover("someone@EXAMPLE.com")
+	require.NoError(t, err)
+	require.Equal(t, []sso.Discovery{{TenantID: f.tenantID.String(), Provider: sso.ProviderOIDC}}, tenants)
+
+	_, err = f.svc.UpdateSettings(f.admin, sso.SettingsInput{LocalPasswordsDisabled: true})
+	require.NoError(t, err)
+	ok, err := f.svc.LocalPasswordAllowed(f.tenantID.String(), "Forensic Analyst")
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = f.svc.LocalPasswordAllowed(f.tenantID.String(), "Tenant Admin")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// SSO cannot be switched off while it is the only way in.
+	require.ErrorIs(t, f.svc.DeleteOIDCConfig(f.admin), sso.ErrInvalidConfig)
+}
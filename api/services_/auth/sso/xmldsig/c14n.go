@@ -0,0 +1,104 @@
+package xmldsig
+
+import (
+	"bytes"
+	"sort"
+)
+
+// canonicalize returns the exclusive canonical form, without comments,
+// of el's subtree (https://www.w3.org/TR/xml-exc-c14n/), leaving out
+// skip, the enveloped signature. inclusive is the InclusiveNamespaces
+// prefix list, with "#default" for the default namespace.
+func canonicalize(el *Element, inclusive []string, skip *Element) []byte {
+	var b bytes.Buffer
+	c := canonicalizer{buf: &b, inclusive: inclusive, skip: skip}
+	c.element(el, map[string]string{})
+	return b.Bytes()
+}
+
+type canonicalizer struct {
+	buf       *bytes.Buffer
+	inclusive []string
+	skip      *Element
+}
+
+// element writes el; rendered holds the namespace declarations output
+// ancestors have written.
+func (c *canonicalizer) element(el *Element, rendered map[string]string) {
+	// Declarations are written where a prefix is visibly utilized, by
+	// the element or its attributes, or listed as inclusive.
+	used := map[string]bool{el.Prefix: true}
+	for _, a := range el.Attrs {
+		if a.Prefix != "" && a.Prefix != "xml" {
+			used[a.Prefix] = true
+		}
+	}
+	for _, p := range c.inclusive {
+		if p == "#default" {
+			p = ""
+		}
+		used[p] = true
+	}
+	var decls []NSDecl
+	for p := range used {
+		uri := el.LookupNS(p)
+		prev, ok := rendered[p]
+		if uri == "" && (p != "" || !ok || prev == "") {
+			continue
+		}
+		if ok && prev == uri {
+			continue
+		}
+		decls = append(decls, NSDecl{Prefix: p, URI: uri})
+	}
+	sort.Slice(decls, func(i, j int) bool { return decls[i].Prefix < decls[j].Prefix })
+
+	attrs := make([]Attr, len(el.Attrs))
+	copy(attrs, el.Attrs)
+	attrNS := func(a Attr) string {
+		if a.Prefix == "" {
+			return ""
+		}
+		return el.LookupNS(a.Prefix)
+	}
+	sort.SliceStable(attrs, func(i, j int) bool {
+		si, sj := attrNS(attrs[i]), attrNS(attrs[j])
+		if si != sj {
+			return si < sj
+		}
+		return attrs[i].Local < attrs[j].Local
+	})
+
+	c.buf.WriteString("<" + el.name())
+	for _, d := range decls {
+		writeDecl(c.buf, d)
+	}
+	for _, a := range attrs {
+		c.buf.WriteString(" " + a.name() + `="` + escapeAttr(a.Value) + `"`)
+	}
+	c.buf.WriteString(">")
+
+	inner := rendered
+	if len(decls) > 0 {
+		inner = make(map[string]string, len(rendered)+len(decls))
+		for p, u := range rendered {
+			inner[p] = u
+		}
+		for _, d := range decls {
+			inner[d.Prefix] = d.URI
+		}
+	}
+	for _, n := range el.Children {
+		switch ch := n.(type) {
+		case *Element:
+			if ch != c.skip {
+				c.element(ch, inner)
+			}
+		case Text:
+			c.buf.WriteString(escapeText(string(ch)))
+		case ProcInst:
+			writeProcInst(c.buf, ch)
+		}
+	}
+	c.buf.WriteString("</" + el.name() + ">")
+}
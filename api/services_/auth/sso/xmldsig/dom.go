@@ -0,0 +1,246 @@
+// Package xmldsig verifies and creates enveloped XML signatures with
+// exclusive canonicalization, the profile SAML 2.0 uses. Documents are
+// read into a small DOM that keeps namespace prefixes, which
+// canonicalization needs and encoding/xml's decoded names lose.
+package xmldsig
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const namespaceXML = "http://www.w3.org/XML/1998/namespace"
+
+// Node is an *Element, Text or ProcInst.
+type Node interface{ isNode() }
+
+type Text string
+
+type ProcInst struct {
+	Target string
+	Inst   string
+}
+
+// NSDecl declares Prefix for URI; the empty prefix is the default
+// namespace.
+type NSDecl struct {
+	Prefix string
+	URI    string
+}
+
+type Attr struct {
+	Prefix string
+	Local  string
+	Value  string
+}
+
+type Element struct {
+	Prefix   string
+	Local    string
+	Decls    []NSDecl
+	Attrs    []Attr
+	Children []Node
+	Parent   *Element
+}
+
+func (*Element) isNode() {}
+func (Text) isNode()     {}
+func (ProcInst) isNode() {}
+
+// Parse reads a document. DTDs are refused, and comments dropped.
+func Parse(data []byte) (*Element, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var root, cur *Element
+	for {
+		tok, err := d.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			el := &Element{Prefix: t.Name.Space, Local: t.Name.Local, Parent: cur}
+			for _, a := range t.Attr {
+				switch {
+				case a.Name.Space == "xmlns":
+					el.Decls = append(el.Decls, NSDecl{Prefix: a.Name.Local, URI: a.Value})
+				case a.Name.Space == "" && a.Name.Local == "xmlns":
+					el.Decls = append(el.Decls, NSDecl{URI: a.Value})
+				default:
+					el.Attrs = append(el.Attrs, Attr{Prefix: a.Name.Space, Local: a.Name.Local, Value: a.Value})
+				}
+			}
+			if el.Prefix != "" && el.Space() == "" {
+				return nil, fmt.Errorf("undeclared namespace prefix %q", el.Prefix)
+			}
+			for _, a := range el.Attrs {
+				if a.Prefix != "" && el.LookupNS(a.Prefix) == "" {
+					return nil, fmt.Errorf("undeclared namespace prefix %q", a.Prefix)
+				}
+			}
+			if cur == nil {
+				if root != nil {
+					return nil, errors.New("more than one root element")
+				}
+				root = el
+			} else {
+				cur.Children = append(cur.Children, el)
+			}
+			cur = el
+		case xml.EndElement:
+			if cur == nil || cur.Prefix != t.Name.Space || cur.Local != t.Name.Local {
+				return nil, fmt.Errorf("unexpected end element %s", t.Name.Local)
+			}
+			cur = cur.Parent
+		case xml.CharData:
+			if cur != nil {
+				cur.Children = append(cur.Children, Text(t))
+			} else if len(bytes.TrimSpace(t)) > 0 {
+				return nil, errors.New("text outside the root element")
+			}
+		case xml.ProcInst:
+			if cur != nil {
+				cur.Children = append(cur.Children, ProcInst{Target: t.Target, Inst: string(t.Inst)})
+			}
+		case xml.Directive:
+			return nil, errors.New("DTDs are not allowed")
+		}
+	}
+	if root == nil || cur != nil {
+		return nil, errors.New("incomplete document")
+	}
+	return root, nil
+}
+
+// LookupNS resolves prefix in the element's scope; "" when undeclared.
+func (e *Element) LookupNS(prefix string) string {
+	if prefix == "xml" {
+		return namespaceXML
+	}
+	for el := e; el != nil; el = el.Parent {
+		for _, d := range el.Decls {
+			if d.Prefix == prefix {
+				return d.URI
+			}
+		}
+	}
+	return ""
+}
+
+// Space is the element's namespace URI.
+func (e *Element) Space() string { return e.LookupNS(e.Prefix) }
+
+// Attr returns the value of the unprefixed attribute name.
+func (e *Element) Attr(name string) string {
+	for _, a := range e.Attrs {
+		if a.Prefix == "" && a.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// ChildElements lists the child elements named space and local.
+func (e *Element) ChildElements(space, local string) []*Element {
+	var out []*Element
+	for _, n := range e.Children {
+		if c, ok := n.(*Element); ok && c.Local == local && c.Space() == space {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Child returns the first child element named space and local, or nil.
+func (e *Element) Child(space, local string) *Element {
+	if c := e.ChildElements(space, local); len(c) > 0 {
+		return c[0]
+	}
+	return nil
+}
+
+// Text is the element's character data, with surrounding whitespace
+// trimmed.
+func (e *Element) Text() string {
+	var b strings.Builder
+	for _, n := range e.Children {
+		if t, ok := n.(Text); ok {
+			b.WriteString(string(t))
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func (e *Element) name() string {
+	if e.Prefix == "" {
+		return e.Local
+	}
+	return e.Prefix + ":" + e.Local
+}
+
+func (a Attr) name() string {
+	if a.Prefix == "" {
+		return a.Local
+	}
+	return a.Prefix + ":" + a.Local
+}
+
+// Bytes serializes the element with the namespace declarations it makes
+// itself; ancestors' declarations it relies on are not repeated.
+func (e *Element) Bytes() []byte {
+	var b bytes.Buffer
+	e.write(&b)
+	return b.Bytes()
+}
+
+func (e *Element) write(b *bytes.Buffer) {
+	b.WriteString("<" + e.name())
+	for _, d := range e.Decls {
+		writeDecl(b, d)
+	}
+	for _, a := range e.Attrs {
+		b.WriteString(" " + a.name() + `="` + escapeAttr(a.Value) + `"`)
+	}
+	b.WriteString(">")
+	for _, n := range e.Children {
+		switch c := n.(type) {
+		case *Element:
+			c.write(b)
+		case Text:
+			b.WriteString(escapeText(string(c)))
+		case ProcInst:
+			writeProcInst(b, c)
+		}
+	}
+	b.WriteString("</" + e.name() + ">")
+}
+
+func writeDecl(b *bytes.Buffer, d NSDecl) {
+	if d.Prefix == "" {
+		b.WriteString(` xmlns="` + escapeAttr(d.URI) + `"`)
+	} else {
+		b.WriteString(" xmlns:" + d.Prefix + `="` + escapeAttr(d.URI) + `"`)
+	}
+}
+
+func writeProcInst(b *bytes.Buffer, p ProcInst) {
+	b.WriteString("<?" + p.Target)
+	if p.Inst != "" {
+		b.WriteString(" " + p.Inst)
+	}
+	b.WriteString("?>")
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+)
+
+func escapeText(s string) string { return textEscaper.Replace(s) }
+func escapeAttr(s string) string { return attrEscaper.Replace(s) }
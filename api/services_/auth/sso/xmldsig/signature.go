@@ -0,0 +1,209 @@
+package xmldsig
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Algorithm identifiers.
+const (
+	NamespaceDSig = "http://www.w3.org/2000/09/xmldsig#"
+	AlgExcC14N    = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	AlgEnveloped  = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+	AlgRSASHA256  = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	AlgRSASHA512  = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha512"
+	AlgSHA256     = "http://www.w3.org/2001/04/xmlenc#sha256"
+	AlgSHA512     = "http://www.w3.org/2001/04/xmlenc#sha512"
+)
+
+var (
+	ErrNotSigned            = errors.New("element is not signed")
+	ErrMalformed            = errors.New("malformed signature")
+	ErrUnsupportedAlgorithm = errors.New("unsupported signature algorithm")
+	ErrDigestMismatch       = errors.New("signed content was modified")
+	ErrBadSignature         = errors.New("signature does not verify with a trusted certificate")
+)
+
+// SHA-1 is refused: it no longer resists collisions.
+var (
+	signatureHashes = map[string]crypto.Hash{AlgRSASHA256: crypto.SHA256, AlgRSASHA512: crypto.SHA512}
+	digestHashes    = map[string]crypto.Hash{AlgSHA256: crypto.SHA256, AlgSHA512: crypto.SHA512}
+)
+
+func digest(h crypto.Hash, data []byte) []byte {
+	if h == crypto.SHA512 {
+		sum := sha512.Sum512(data)
+		return sum[:]
+	}
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+}
+
+// VerifySignature checks a signature made with alg over data by one of
+// certs.
+func VerifySignature(alg string, data, sig []byte, certs []*x509.Certificate) error {
+	h, ok := signatureHashes[alg]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
+	}
+	sum := digest(h, data)
+	for _, cert := range certs {
+		if pub, ok := cert.PublicKey.(*rsa.PublicKey); ok && rsa.VerifyPKCS1v15(pub, h, sum, sig) == nil {
+			return nil
+		}
+	}
+	return ErrBadSignature
+}
+
+// SignData signs data with RSA-SHA256, for the SAML redirect binding.
+func SignData(key *rsa.PrivateKey, data []byte) ([]byte, error) {
+	return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest(crypto.SHA256, data))
+}
+
+// inclusivePrefixes reads an InclusiveNamespaces prefix list from a
+// canonicalization method or transform.
+func inclusivePrefixes(method *Element) []string {
+	if in := method.Child(AlgExcC14N, "InclusiveNamespaces"); in != nil {
+		return strings.Fields(in.Attr("PrefixList"))
+	}
+	return nil
+}
+
+// Verify checks el's enveloped signature. Only a signature over el
+// itself, referenced by its ID attribute, is accepted, and the key the
+// signature carries is ignored: it must verify with one of certs.
+func Verify(el *Element, certs []*x509.Certificate) error {
+	sigs := el.ChildElements(NamespaceDSig, "Signature")
+	if len(sigs) == 0 {
+		return ErrNotSigned
+	}
+	if len(sigs) > 1 {
+		return fmt.Errorf("%w: more than one signature", ErrMalformed)
+	}
+	sig := sigs[0]
+	signedInfo := sig.Child(NamespaceDSig, "SignedInfo")
+	if signedInfo == nil {
+		return fmt.Errorf("%w: no SignedInfo", ErrMalformed)
+	}
+	cm := signedInfo.Child(NamespaceDSig, "CanonicalizationMethod")
+	sm := signedInfo.Child(NamespaceDSig, "SignatureMethod")
+	if cm == nil || sm == nil {
+		return fmt.Errorf("%w: no canonicalization or signature method", ErrMalformed)
+	}
+	if cm.Attr("Algorithm") != AlgExcC14N {
+		return fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, cm.Attr("Algorithm"))
+	}
+
+	refs := signedInfo.ChildElements(NamespaceDSig, "Reference")
+	if len(refs) != 1 {
+		return fmt.Errorf("%w: want one reference", ErrMalformed)
+	}
+	ref := refs[0]
+	if id := el.Attr("ID"); id == "" || ref.Attr("URI") != "#"+id {
+		return fmt.Errorf("%w: signature does not reference the signed element", ErrMalformed)
+	}
+	var prefixes []string
+	canonical := false
+	if transforms := ref.Child(NamespaceDSig, "Transforms"); transforms != nil {
+		for _, t := range transforms.ChildElements(NamespaceDSig, "Transform") {
+			switch t.Attr("Algorithm") {
+			case AlgEnveloped:
+			case AlgExcC14N:
+				canonical = true
+				prefixes = inclusivePrefixes(t)
+			default:
+				return fmt.Errorf("%w: transform %s", ErrUnsupportedAlgorithm, t.Attr("Algorithm"))
+			}
+		}
+	}
+	if !canonical {
+		return fmt.Errorf("%w: reference is not exclusively canonicalized", ErrUnsupportedAlgorithm)
+	}
+	dm := ref.Child(NamespaceDSig, "DigestMethod")
+	dv := ref.Child(NamespaceDSig, "DigestValue")
+	if dm == nil || dv == nil {
+		return fmt.Errorf("%w: no digest", ErrMalformed)
+	}
+	h, ok := digestHashes[dm.Attr("Algorithm")]
+	if !ok {
+		return fmt.Errorf("%w: digest %s", ErrUnsupportedAlgorithm, dm.Attr("Algorithm"))
+	}
+	want, err := decodeBase64(dv.Text())
+	if err != nil {
+		return fmt.Errorf("%w: digest value", ErrMalformed)
+	}
+	if subtle.ConstantTimeCompare(digest(h, canonicalize(el, prefixes, sig)), want) != 1 {
+		return ErrDigestMismatch
+	}
+
+	sv := sig.Child(NamespaceDSig, "SignatureValue")
+	if sv == nil {
+		return fmt.Errorf("%w: no signature value", ErrMalformed)
+	}
+	value, err := decodeBase64(sv.Text())
+	if err != nil {
+		return fmt.Errorf("%w: signature value", ErrMalformed)
+	}
+	return VerifySignature(sm.Attr("Algorithm"), canonicalize(signedInfo, inclusivePrefixes(cm), nil), value, certs)
+}
+
+const signatureTemplate = `<ds:Signature xmlns:ds="` + NamespaceDSig + `"><ds:SignedInfo>` +
+	`<ds:CanonicalizationMethod Algorithm="` + AlgExcC14N + `"></ds:CanonicalizationMethod>` +
+	`<ds:SignatureMethod Algorithm="` + AlgRSASHA256 + `"></ds:SignatureMethod>` +
+	`<ds:Reference URI="#%s"><ds:Transforms>` +
+	`<ds:Transform Algorithm="` + AlgEnveloped + `"></ds:Transform>` +
+	`<ds:Transform Algorithm="` + AlgExcC14N + `"></ds:Transform>` +
+	`</ds:Transforms><ds:DigestMethod Algorithm="` + AlgSHA256 + `"></ds:DigestMethod>` +
+	`<ds:DigestValue>%s</ds:DigestValue></ds:Reference></ds:SignedInfo>` +
+	`<ds:SignatureValue></ds:SignatureValue>` +
+	`<ds:KeyInfo><ds:X509Data><ds:X509Certificate>%s</ds:X509Certificate></ds:X509Data></ds:KeyInfo>` +
+	`</ds:Signature>`
+
+// Sign adds an enveloped RSA-SHA256 signature to el, which must have an
+// ID attribute. The signature goes after el's Issuer, where SAML
+// expects it, or first.
+func Sign(el *Element, key *rsa.PrivateKey, cert *x509.Certificate) error {
+	id := el.Attr("ID")
+	if id == "" {
+		return fmt.Errorf("%w: element has no ID", ErrMalformed)
+	}
+	if len(el.ChildElements(NamespaceDSig, "Signature")) > 0 {
+		return fmt.Errorf("%w: element is already signed", ErrMalformed)
+	}
+	sum := digest(crypto.SHA256, canonicalize(el, nil, nil))
+	sig, err := Parse([]byte(fmt.Sprintf(signatureTemplate, escapeAttr(id),
+		base64.StdEncoding.EncodeToString(sum), base64.StdEncoding.EncodeToString(cert.Raw))))
+	if err != nil {
+		return err
+	}
+	sig.Parent = el
+
+	at := 0
+	for i, n := range el.Children {
+		if c, ok := n.(*Element); ok && c.Local == "Issuer" {
+			at = i + 1
+			break
+		}
+	}
+	el.Children = append(el.Children[:at], append([]Node{sig}, el.Children[at:]...)...)
+
+	value, err := SignData(key, canonicalize(sig.Child(NamespaceDSig, "SignedInfo"), nil, nil))
+	if err != nil {
+		return err
+	}
+	sig.Child(NamespaceDSig, "SignatureValue").Children = []Node{Text(base64.StdEncoding.EncodeToString(value))}
+	return nil
+}
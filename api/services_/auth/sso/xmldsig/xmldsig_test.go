@@ -0,0 +1,103 @@
+package xmldsig
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// The example of section 2.2 of the Exclusive XML Canonicalization
+// recommendation: n3 is declared where it is used, not inherited.
+func TestCanonicalizeSpecExample(t *testing.T) {
+	doc := `<n0:local xmlns:n0="foo:bar" xmlns:n3="ftp://example.org">
+  <n1:elem2 xmlns:n1="http://example.net" xml:lang="en">
+    <n3:stuff xmlns:n3="ftp://example.org"/>
+  </n1:elem2>
+</n0:local>`
+	root, err := Parse([]byte(doc))
+	require.NoError(t, err)
+	elem2 := root.Child("http://example.net", "elem2")
+	require.NotNil(t, elem2)
+
+	want := `<n1:elem2 xmlns:n1="http://example.net" xml:lang="en">
+    <n3:stuff xmlns:n3="ftp://example.org"></n3:stuff>
+  </n1:elem2>`
+	require.Equal(t, want, string(canonicalize(elem2, nil, nil)))
+}
+
+func TestCanonicalizeOrderingAndEscaping(t *testing.T) {
+	doc := `<a:root xmlns:a="urn:a" xmlns:b="urn:b" xmlns="urn:d" z="1" b:y="&lt;2&quot;" a:x="3"><child>x &amp; y &gt; z</child><b:other/><plain xmlns=""/></a:root>`
+	root, err := Parse([]byte(doc))
+	require.NoError(t, err)
+	want := `<a:root xmlns:a="urn:a" xmlns:b="urn:b" z="1" a:x="3" b:y="&lt;2&quot;">` +
+		`<child xmlns="urn:d">x &amp; y &gt; z</child><b:other></b:other><plain></plain></a:root>`
+	require.Equal(t, want, string(canonicalize(root, nil, nil)))
+
+	// Inclusive prefixes are declared even when unused.
+	require.True(t, strings.HasPrefix(string(canonicalize(root, []string{"#default"}, nil)),
+		`<a:root xmlns="urn:d" xmlns:a="urn:a" xmlns:b="urn:b"`))
+}
+
+func TestParseRefusesDTD(t *testing.T) {
+	_, err := Parse([]byte(`<!DOCTYPE r [<!ENTITY x "y">]><r>&x;</r>`))
+	require.Error(t, err)
+	_, err = Parse([]byte(`<p:r/>`))
+	require.Error(t, err)
+}
+
+func newCert(t *testing.T) (*rsa.PrivateKey, *x509.Certificate) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "signer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return key, cert
+}
+
+const assertion = `<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" ID="r1">` +
+	`<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="a1">` +
+	"\n  <saml:Issuer>https://idp.example</saml:Issuer>\n" +
+	`  <saml:Subject><saml:NameID>ana@example.com</saml:NameID></saml:Subject>` +
+	"\n</saml:Assertion></samlp:Response>"
+
+func TestSignAndVerify(t *testing.T) {
+	key, cert := newCert(t)
+	_, other := newCert(t)
+
+	root, err := Parse([]byte(assertion))
+	require.NoError(t, err)
+	el := root.Child("urn:oasis:names:tc:SAML:2.0:assertion", "Assertion")
+	require.ErrorIs(t, Verify(el, []*x509.Certificate{cert}), ErrNotSigned)
+	require.NoError(t, Sign(el, key, cert))
+
+	// Serialized and read back, as a relying party would.
+	signed := root.Bytes()
+	root, err = Parse(signed)
+	require.NoError(t, err)
+	el = root.Child("urn:oasis:names:tc:SAML:2.0:assertion", "Assertion")
+	require.NoError(t, Verify(el, []*x509.Certificate{cert}))
+	require.ErrorIs(t, Verify(el, []*x509.Certificate{other}), ErrBadSignature)
+
+	tampered, err := Parse([]byte(strings.Replace(string(signed), "ana@example.com", "eve@example.com", 1)))
+	require.NoError(t, err)
+	require.ErrorIs(t, Verify(tampered.Child("urn:oasis:names:tc:SAML:2.0:assertion", "Assertion"), []*x509.Certificate{cert}), ErrDigestMismatch)
+
+	// A signature is only good for the element it references.
+	moved, err := Parse([]byte(strings.Replace(string(signed), `ID="a1"`, `ID="a2"`, 1)))
+	require.NoError(t, err)
+	require.ErrorIs(t, Verify(moved.Child("urn:oasis:names:tc:SAML:2.0:assertion", "Assertion"), []*x509.Certificate{cert}), ErrMalformed)
+}
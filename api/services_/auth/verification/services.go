@@ -2,10 +2,14 @@ package mfa
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base32"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"math/big"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,6 +19,12 @@ import (
 	"go.uber.org/zap"
 )
 
+var (
+	ErrInvalidCode       = errors.New("invalid MFA code")
+	ErrMFANotEnabled     = errors.New("MFA is not enabled for this user")
+	ErrMFAAlreadyEnabled = errors.New("MFA is already enabled for this user")
+)
+
 // MFAService handles Multi-Factor Authentication operations
 type MFAService struct {
 	db     *sqlx.DB
@@ -34,11 +44,11 @@ type MFAVerificationRequest struct {
 	Code   string `json:"code"`
 }
 
-// BackupCode represents a backup code for MFA
+// BackupCode represents a backup code for MFA. Only the code's hash is stored.
 type BackupCode struct {
 	ID        uuid.UUID  `db:"id" json:"id"`
 	UserID    uuid.UUID  `db:"user_id" json:"user_id"`
-	Code      string     `db:"code" json:"code"`
+	CodeHash  string     `db:"code_hash" json:"-"`
 	Used      bool       `db:"used" json:"used"`
 	UsedAt    *time.Time `db:"used_at" json:"used_at,omitempty"`
 	CreatedAt time.Time  `db:"created_at" json:"created_at"`
@@ -54,9 +64,18 @@ func NewMFAService(db *sqlx.DB, logger *zap.Logger) *MFAService {
 
 // GenerateSecret generates a new TOTP secret for a user
 func (s *MFAService) GenerateSecret(userID uuid.UUID, userEmail string) (*MFASetupResponse, error) {
+	// Re-enrolling would silently replace a working secret
+	enabled, err := s.GetMFAStatus(userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
 	// Generate a random secret
 	secret := make([]byte, 20)
-	_, err := rand.Read(secret)
+	_, err = rand.Read(secret)
 	if err != nil {
 		s.logger.Error("Failed to generate MFA secret", zap.Error(err))
 		return nil, fmt.Errorf("failed to generate MFA secret: %w", err)
@@ -117,7 +136,7 @@ func (s *MFAService) VerifyAndEnableMFA(userID uuid.UUID, code string) error {
 	valid := totp.Validate(code, secret)
 	if !valid {
 		s.logger.Warn("Invalid MFA verification code", zap.String("user_id", userID.String()))
-		return errors.New("invalid MFA code")
+		return ErrInvalidCode
 	}
 
 	// Enable MFA for the user
@@ -169,7 +188,7 @@ func (s *MFAService) VerifyTOTP(userID uuid.UUID, code string) (bool, error) {
 	}
 
 	// If TOTP fails, try backup codes
-	valid, err := s.verifyBackupCode(userID, code)
+	valid, err := s.UseRecoveryCode(userID, code)
 	if err != nil {
 		s.logger.Error("Failed to verify backup code", zap.Error(err))
 		return false, err
@@ -178,6 +197,77 @@ func (s *MFAService) VerifyTOTP(userID uuid.UUID, code string) (bool, error) {
 	return valid, nil
 }
 
+// ValidateTOTP checks a TOTP code only; recovery codes are checked with
+// UseRecoveryCode. It returns the time step the code belongs to, so a
+// caller can refuse a code that was accepted before.
+func (s *MFAService) ValidateTOTP(userID uuid.UUID, code string) (int64, bool, error) {
+	var user struct {
+		MFAEnabled bool   `db:"mfa_enabled"`
+		MFASecret  string `db:"mfa_secret"`
+	}
+	err := s.db.Get(&user, `
+        SELECT 
+            COALESCE(mfa_enabled, false) as mfa_enabled,
+            COALESCE(mfa_secret, '') as mfa_secret
+        FROM users 
+        WHERE id = $1
+    `, userID)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get user MFA details: %w", err)
+	}
+	if !user.MFAEnabled || user.MFASecret == "" {
+		return 0, false, ErrMFANotEnabled
+	}
+	step, ok := matchTOTPStep(strings.TrimSpace(code), user.MFASecret, time.Now())
+	return step, ok, nil
+}
+
+// matchTOTPStep returns the time step, within the one-step skew
+// totp.Validate allows, that code was generated for.
+func matchTOTPStep(code, secret string, now time.Time) (int64, bool) {
+	const period = 30
+	current := now.Unix() / period
+	for _, step := range []int64{current - 1, current, current + 1} {
+		want, err := totp.GenerateCode(secret, time.Unix(step*period, 0))
+		if err == nil && subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// RegenerateRecoveryCodes replaces a user's recovery codes. The new codes
+// are only ever returned here.
+func (s *MFAService) RegenerateRecoveryCodes(userID uuid.UUID) ([]string, error) {
+	enabled, err := s.GetMFAStatus(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrMFANotEnabled
+	}
+	codes, err := s.generateBackupCodes(userID)
+	if err != nil {
+		s.logger.Error("Failed to regenerate backup codes", zap.Error(err))
+		return nil, err
+	}
+	s.logger.Info("Backup codes regenerated", zap.String("user_id", userID.String()))
+	return codes, nil
+}
+
+// RecoveryCodesLeft counts a user's unused recovery codes
+func (s *MFAService) RecoveryCodesLeft(userID uuid.UUID) (int, error) {
+	var n int
+	err := s.db.Get(&n, `
+        SELECT COUNT(*) FROM mfa_backup_codes 
+        WHERE user_id = $1 AND used = false
+    `, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count backup codes: %w", err)
+	}
+	return n, nil
+}
+
 // DisableMFA disables MFA for a user
 func (s *MFAService) DisableMFA(userID uuid.UUID) error {
 	tx, err := s.db.Beginx()
@@ -229,13 +319,20 @@ func (s *MFAService) GetMFAStatus(userID uuid.UUID) (bool, error) {
 	return enabled, nil
 }
 
-// generateBackupCodes generates backup codes for MFA
+// generateBackupCodes replaces a user's backup codes. Codes are shown as
+// XXXX-XXXX and stored hashed.
 func (s *MFAService) generateBackupCodes(userID uuid.UUID) ([]string, error) {
 	const numCodes = 10
 	codes := make([]string, numCodes)
 
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	// Delete existing backup codes
-	_, err := s.db.Exec(`DELETE FROM mfa_backup_codes WHERE user_id = $1`, userID)
+	_, err = tx.Exec(`DELETE FROM mfa_backup_codes WHERE user_id = $1`, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to delete existing backup codes: %w", err)
 	}
@@ -246,23 +343,33 @@ func (s *MFAService) generateBackupCodes(userID uuid.UUID) ([]string, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate backup code: %w", err)
 		}
-		codes[i] = code
+		codes[i] = code[:4] + "-" + code[4:]
 
 		// Store in database
-		_, err = s.db.Exec(`
-            INSERT INTO mfa_backup_codes (id, user_id, code, used, created_at)
+		_, err = tx.Exec(`
+            INSERT INTO mfa_backup_codes (id, user_id, code_hash, used, created_at)
             VALUES ($1, $2, $3, false, NOW())
-        `, uuid.New(), userID, code)
+        `, uuid.New(), userID, hashBackupCode(code))
 		if err != nil {
 			return nil, fmt.Errorf("failed to store backup code: %w", err)
 		}
 	}
 
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return codes, nil
 }
 
-// verifyBackupCode verifies and marks a backup code as used
-func (s *MFAService) verifyBackupCode(userID uuid.UUID, code string) (bool, error) {
+// hashBackupCode hashes a backup code, ignoring case, spaces and dashes
+func hashBackupCode(code string) string {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// UseRecoveryCode verifies and marks a backup code as used
+func (s *MFAService) UseRecoveryCode(userID uuid.UUID, code string) (bool, error) {
 	tx, err := s.db.Beginx()
 	if err != nil {
 		return false, fmt.Errorf("failed to start transaction: %w", err)
@@ -273,8 +380,9 @@ func (s *MFAService) verifyBackupCode(userID uuid.UUID, code string) (bool, erro
 	var backupCodeID uuid.UUID
 	err = tx.Get(&backupCodeID, `
         SELECT id FROM mfa_backup_codes 
-        WHERE user_id = $1 AND code = $2 AND used = false
-    `, userID, code)
+        WHERE user_id = $1 AND code_hash = $2 AND used = false
+        FOR UPDATE
+    `, userID, hashBackupCode(code))
 	if err != nil {
 		// Code not found or already used
 		return false, nil
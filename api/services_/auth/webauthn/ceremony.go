@@ -0,0 +1,269 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ugorji/go/codec"
+)
+
+// COSE algorithms accepted for credentials, in order of preference.
+const (
+	AlgES256 = -7
+	AlgEdDSA = -8
+	AlgRS256 = -257
+)
+
+var supportedAlgorithms = []int{AlgES256, AlgEdDSA, AlgRS256}
+
+// Authenticator data flags.
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttestedData = 0x40
+)
+
+// oidFIDOAAGUID is the certificate extension an attestation certificate
+// names its authenticator model in.
+var oidFIDOAAGUID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
+
+var cborHandle codec.CborHandle
+
+func cborDecode(b []byte, v interface{}) error {
+	return codec.NewDecoderBytes(b, &cborHandle).Decode(v)
+}
+
+// decodeB64 accepts base64url with or without padding, as browsers and
+// client libraries differ.
+func decodeB64(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+func encodeB64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// verifyClientData checks the browser's record of the ceremony: its type,
+// that it answers our challenge, and that it ran on one of our origins.
+func verifyClientData(raw []byte, ceremony, challenge string, origins []string) error {
+	var cd clientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return fmt.Errorf("%w: malformed client data", ErrInvalidResponse)
+	}
+	if cd.Type != ceremony {
+		return fmt.Errorf("%w: unexpected ceremony type %q", ErrInvalidResponse, cd.Type)
+	}
+	got, err := decodeB64(cd.Challenge)
+	if err != nil || encodeB64(got) != challenge {
+		return fmt.Errorf("%w: challenge mismatch", ErrInvalidResponse)
+	}
+	if !slices.Contains(origins, cd.Origin) {
+		return fmt.Errorf("%w: origin %q not allowed", ErrInvalidResponse, cd.Origin)
+	}
+	return nil
+}
+
+type authenticatorData struct {
+	RPIDHash  []byte
+	Flags     byte
+	SignCount uint32
+	// Set when the authenticator attested a new credential.
+	AAGUID       uuid.UUID
+	CredentialID []byte
+	PublicKey    []byte
+}
+
+func (d *authenticatorData) userPresent() bool  { return d.Flags&flagUserPresent != 0 }
+func (d *authenticatorData) userVerified() bool { return d.Flags&flagUserVerified != 0 }
+
+func parseAuthenticatorData(b []byte) (*authenticatorData, error) {
+	if len(b) < 37 {
+		return nil, fmt.Errorf("%w: authenticator data too short", ErrInvalidResponse)
+	}
+	d := &authenticatorData{
+		RPIDHash:  b[:32],
+		Flags:     b[32],
+		SignCount: binary.BigEndian.Uint32(b[33:37]),
+	}
+	if d.Flags&flagAttestedData == 0 {
+		return d, nil
+	}
+	rest := b[37:]
+	if len(rest) < 18 {
+		return nil, fmt.Errorf("%w: attested credential data too short", ErrInvalidResponse)
+	}
+	copy(d.AAGUID[:], rest[:16])
+	n := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if n == 0 || len(rest) < n {
+		return nil, fmt.Errorf("%w: bad credential ID length", ErrInvalidResponse)
+	}
+	d.CredentialID = rest[:n]
+	// The COSE key runs up to any extension data, which we do not use;
+	// parsing it back out re-encodes only the key.
+	var key map[int]interface{}
+	if err := cborDecode(rest[n:], &key); err != nil {
+		return nil, fmt.Errorf("%w: malformed credential public key", ErrInvalidResponse)
+	}
+	var buf []byte
+	if err := codec.NewEncoderBytes(&buf, &cborHandle).Encode(key); err != nil {
+		return nil, err
+	}
+	d.PublicKey = buf
+	return d, nil
+}
+
+func toInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int64:
+		return int(n), true
+	case uint64:
+		return int(n), true
+	case int:
+		return n, true
+	}
+	return 0, false
+}
+
+// parsePublicKey reads a COSE_Key (RFC 9053) of one of the supported
+// algorithms.
+func parsePublicKey(cose []byte) (crypto.PublicKey, int, error) {
+	var key map[int]interface{}
+	if err := cborDecode(cose, &key); err != nil {
+		return nil, 0, fmt.Errorf("%w: malformed public key", ErrUnsupportedKey)
+	}
+	kty, _ := toInt(key[1])
+	alg, ok := toInt(key[3])
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: no algorithm", ErrUnsupportedKey)
+	}
+	crv, _ := toInt(key[-1])
+	x, _ := key[-2].([]byte)
+	switch {
+	case kty == 2 && alg == AlgES256 && crv == 1:
+		y, _ := key[-3].([]byte)
+		if len(x) != 32 || len(y) != 32 {
+			return nil, 0, fmt.Errorf("%w: bad EC2 coordinates", ErrUnsupportedKey)
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, 0, fmt.Errorf("%w: point not on curve", ErrUnsupportedKey)
+		}
+		return pub, alg, nil
+	case kty == 1 && alg == AlgEdDSA && crv == 6:
+		if len(x) != ed25519.PublicKeySize {
+			return nil, 0, fmt.Errorf("%w: bad Ed25519 key", ErrUnsupportedKey)
+		}
+		return ed25519.PublicKey(x), alg, nil
+	case kty == 3 && alg == AlgRS256:
+		n, _ := key[-1].([]byte)
+		e, _ := key[-2].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, 0, fmt.Errorf("%w: bad RSA key", ErrUnsupportedKey)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, alg, nil
+	}
+	return nil, 0, fmt.Errorf("%w: kty %d alg %d", ErrUnsupportedKey, kty, alg)
+}
+
+// verifySignature checks sig over data with a credential or attestation
+// key.
+func verifySignature(pub crypto.PublicKey, alg int, data, sig []byte) error {
+	sum := sha256.Sum256(data)
+	ok := false
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		ok = alg == AlgES256 && ecdsa.VerifyASN1(k, sum[:], sig)
+	case ed25519.PublicKey:
+		ok = alg == AlgEdDSA && ed25519.Verify(k, data, sig)
+	case *rsa.PublicKey:
+		ok = alg == AlgRS256 && rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], sig) == nil
+	}
+	if !ok {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+type attestationObject struct {
+	Format   string                 `codec:"fmt"`
+	AttStmt  map[string]interface{} `codec:"attStmt"`
+	AuthData []byte                 `codec:"authData"`
+}
+
+// verifyPacked checks a "packed" attestation statement. It reports
+// whether the statement was signed by an attestation certificate, as
+// opposed to self attestation with the credential's own key, which says
+// nothing about the authenticator model.
+func verifyPacked(stmt map[string]interface{}, authData []byte, clientDataHash []byte, d *authenticatorData, now time.Time) (bool, error) {
+	alg, ok := toInt(stmt["alg"])
+	sig, _ := stmt["sig"].([]byte)
+	if !ok || len(sig) == 0 {
+		return false, fmt.Errorf("%w: packed statement lacks alg or sig", ErrAttestation)
+	}
+	signed := append(append([]byte{}, authData...), clientDataHash...)
+
+	x5c, _ := stmt["x5c"].([]interface{})
+	if len(x5c) == 0 {
+		pub, credAlg, err := parsePublicKey(d.PublicKey)
+		if err != nil {
+			return false, err
+		}
+		if alg != credAlg {
+			return false, fmt.Errorf("%w: self attestation algorithm mismatch", ErrAttestation)
+		}
+		if err := verifySignature(pub, alg, signed, sig); err != nil {
+			return false, fmt.Errorf("%w: self attestation signature", ErrAttestation)
+		}
+		return false, nil
+	}
+
+	der, _ := x5c[0].([]byte)
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return false, fmt.Errorf("%w: malformed attestation certificate", ErrAttestation)
+	}
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return false, fmt.Errorf("%w: attestation certificate not valid now", ErrAttestation)
+	}
+	if err := verifySignature(cert.PublicKey, alg, signed, sig); err != nil {
+		return false, fmt.Errorf("%w: attestation signature", ErrAttestation)
+	}
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidFIDOAAGUID) {
+			continue
+		}
+		var aaguid []byte
+		if _, err := asn1.Unmarshal(ext.Value, &aaguid); err != nil || !bytes.Equal(aaguid, d.AAGUID[:]) {
+			return false, fmt.Errorf("%w: certificate AAGUID does not match authenticator", ErrAttestation)
+		}
+	}
+	return true, nil
+}
+
+// rpIDHashMatches checks the authenticator scoped the credential to us.
+func rpIDHashMatches(d *authenticatorData, rpID string) bool {
+	want := sha256.Sum256([]byte(rpID))
+	return bytes.Equal(d.RPIDHash, want[:])
+}
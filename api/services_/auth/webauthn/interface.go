@@ -0,0 +1,48 @@
+package webauthn
+
+import "context"
+
+type Repository interface {
+	AutoMigrate() error
+
+	CreateCredential(c *Credential) error
+	// GetCredential finds a credential by its authenticator credential ID.
+	GetCredential(credentialID string) (*Credential, error)
+	// GetUserCredential finds one of a user's credentials by our ID.
+	GetUserCredential(userID, id string) (*Credential, error)
+	// ListCredentials returns the user's credentials, revoked ones
+	// included, oldest first.
+	ListCredentials(userID string) ([]Credential, error)
+	CountActive(userID string) (int, error)
+	SaveCredential(c *Credential) error
+
+	// GetPolicy returns nil when the tenant has not set a policy.
+	GetPolicy(tenantID string) (*Policy, error)
+	SavePolicy(p *Policy) error
+}
+
+type Service interface {
+	// BeginRegistration issues the options for registering a new passkey.
+	BeginRegistration(ctx context.Context, actor Actor) (*CreationOptions, error)
+	// FinishRegistration verifies the authenticator's attestation against
+	// the tenant's policy and stores the credential under name.
+	FinishRegistration(ctx context.Context, actor Actor, challengeID, name string, resp RegistrationResponse) (*Credential, error)
+
+	// BeginAssertion issues the options for the user to sign in, or step
+	// up, with one of their passkeys.
+	BeginAssertion(ctx context.Context, userID, tenantID string) (*RequestOptions, error)
+	// FinishAssertion verifies the signed challenge and returns the
+	// credential used.
+	FinishAssertion(ctx context.Context, userID, challengeID string, resp AssertionResponse) (*Credential, error)
+
+	ListCredentials(userID string) ([]Credential, error)
+	RenameCredential(userID, id, name string) (*Credential, error)
+	RevokeCredential(userID, id string) (*Credential, error)
+	// CountActive is how many usable passkeys the user has.
+	CountActive(userID string) (int, error)
+
+	// GetPolicy returns the tenant's policy, or the default of accepting
+	// any authenticator.
+	GetPolicy(tenantID string) (*Policy, error)
+	UpdatePolicy(actor Actor, in PolicyInput) (*Policy, error)
+}
@@ -0,0 +1,153 @@
+package webauthn
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Attestation conveyance a tenant's policy asks of authenticators.
+const (
+	// AttestationNone accepts any authenticator.
+	AttestationNone = "none"
+	// AttestationDirect requires a verifiable attestation statement and,
+	// when the policy lists AAGUIDs, an authenticator model among them.
+	AttestationDirect = "direct"
+)
+
+// User verification a tenant's policy asks of authenticators.
+const (
+	UserVerificationPreferred = "preferred"
+	UserVerificationRequired  = "required"
+)
+
+// Credential is a passkey registered to a user.
+type Credential struct {
+	ID       string `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID   string `gorm:"type:uuid;not null;index" json:"user_id"`
+	TenantID string `gorm:"type:uuid;index" json:"tenant_id,omitempty"`
+	// CredentialID is the authenticator's credential ID, base64url encoded.
+	CredentialID string `gorm:"type:text;not null;uniqueIndex" json:"credential_id"`
+	// PublicKey is the credential's COSE_Key.
+	PublicKey         []byte         `gorm:"type:bytea;not null" json:"-"`
+	Algorithm         int            `gorm:"not null" json:"algorithm"`
+	SignCount         uint32         `gorm:"not null;default:0" json:"sign_count"`
+	AAGUID            string         `gorm:"type:varchar(36)" json:"aaguid"`
+	AttestationFormat string         `gorm:"type:varchar(32)" json:"attestation_format"`
+	Transports        datatypes.JSON `gorm:"type:jsonb" json:"transports"` // []string
+	UserVerified      bool           `json:"user_verified"`
+	Name              string         `gorm:"type:varchar(100);not null" json:"name"`
+	CreatedAt         time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	LastUsedAt        *time.Time     `json:"last_used_at,omitempty"`
+	RevokedAt         *time.Time     `json:"revoked_at,omitempty"`
+}
+
+func (Credential) TableName() string { return "webauthn_credentials" }
+
+// Policy is a tenant's passkey policy.
+type Policy struct {
+	TenantID    string `gorm:"type:uuid;primaryKey" json:"tenant_id"`
+	Attestation string `gorm:"type:varchar(16);not null" json:"attestation"`
+	// AllowedAAGUIDs restricts direct attestation to these authenticator
+	// models; empty allows any.
+	AllowedAAGUIDs   datatypes.JSON `gorm:"type:jsonb;not null" json:"allowed_aaguids"` // []string
+	UserVerification string         `gorm:"type:varchar(16);not null" json:"user_verification"`
+	UpdatedBy        string         `gorm:"type:uuid" json:"updated_by,omitempty"`
+	UpdatedAt        time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (Policy) TableName() string { return "tenant_webauthn_policies" }
+
+// PolicyInput is an update of a tenant's policy.
+type PolicyInput struct {
+	Attestation      string   `json:"attestation"`
+	AllowedAAGUIDs   []string `json:"allowed_aaguids"`
+	UserVerification string   `json:"user_verification"`
+}
+
+// Actor is the user registering or using a passkey.
+type Actor struct {
+	UserID      string
+	TenantID    string
+	Email       string
+	DisplayName string
+}
+
+// ─── Ceremony messages (WebAuthn Level 2, JSON encoded) ───
+
+type RelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	// ID is the base64url user handle.
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey,omitempty"`
+	UserVerification string `json:"userVerification,omitempty"`
+}
+
+// CreationOptions are passed to navigator.credentials.create({publicKey}).
+// ChallengeID is ours: the client returns it with the response.
+type CreationOptions struct {
+	ChallengeID            string                 `json:"challengeId"`
+	Challenge              string                 `json:"challenge"`
+	RP                     RelyingParty           `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are passed to navigator.credentials.get({publicKey}).
+type RequestOptions struct {
+	ChallengeID      string                 `json:"challengeId"`
+	Challenge        string                 `json:"challenge"`
+	RPID             string                 `json:"rpId"`
+	Timeout          int64                  `json:"timeout"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// RegistrationResponse is the PublicKeyCredential from create(), with
+// binary fields base64url encoded.
+type RegistrationResponse struct {
+	ID       string `json:"id" binding:"required"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON" binding:"required"`
+		AttestationObject string   `json:"attestationObject" binding:"required"`
+		Transports        []string `json:"transports"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the PublicKeyCredential from get(), with binary
+// fields base64url encoded.
+type AssertionResponse struct {
+	ID       string `json:"id" binding:"required"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+		AuthenticatorData string `json:"authenticatorData" binding:"required"`
+		Signature         string `json:"signature" binding:"required"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
@@ -0,0 +1,79 @@
+package webauthn
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) AutoMigrate() error {
+	return r.db.AutoMigrate(&Credential{}, &Policy{})
+}
+
+func (r *GormRepository) CreateCredential(c *Credential) error {
+	return r.db.Create(c).Error
+}
+
+func (r *GormRepository) GetCredential(credentialID string) (*Credential, error) {
+	var c Credential
+	err := r.db.Where("credential_id = ?", credentialID).First(&c).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCredentialNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *GormRepository) GetUserCredential(userID, id string) (*Credential, error) {
+	var c Credential
+	err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&c).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCredentialNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *GormRepository) ListCredentials(userID string) ([]Credential, error) {
+	var out []Credential
+	err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) CountActive(userID string) (int, error) {
+	var n int64
+	err := r.db.Model(&Credential{}).Where("user_id = ? AND revoked_at IS NULL", userID).Count(&n).Error
+	return int(n), err
+}
+
+func (r *GormRepository) SaveCredential(c *Credential) error {
+	return r.db.Save(c).Error
+}
+
+func (r *GormRepository) GetPolicy(tenantID string) (*Policy, error) {
+	var p Policy
+	err := r.db.Where("tenant_id = ?", tenantID).First(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *GormRepository) SavePolicy(p *Policy) error {
+	return r.db.Save(p).Error
+}
@@ -0,0 +1,531 @@
+// Package webauthn registers passkeys (WebAuthn / FIDO2 credentials) and
+// verifies assertions made with them, for login and step-up. Ceremonies
+// are verified here rather than by the browser: client data, relying
+// party scoping, user presence and verification, signature counters and
+// "none" or "packed" attestation under the tenant's policy.
+package webauthn
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"aegis-api/cache"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+var (
+	ErrInvalidResponse          = errors.New("invalid WebAuthn response")
+	ErrInvalidSignature         = errors.New("invalid WebAuthn signature")
+	ErrUnsupportedKey           = errors.New("unsupported credential public key")
+	ErrAttestation              = errors.New("attestation could not be verified")
+	ErrAttestationRequired      = errors.New("the tenant's policy requires a verifiable attestation")
+	ErrAuthenticatorNotAllowed  = errors.New("this authenticator model is not allowed by the tenant's policy")
+	ErrUserVerificationRequired = errors.New("the authenticator must verify the user (PIN or biometric)")
+	ErrChallengeExpired         = errors.New("WebAuthn challenge expired or already used")
+	ErrCredentialNotFound       = errors.New("passkey not found")
+	ErrCredentialExists         = errors.New("passkey is already registered")
+	ErrCredentialRevoked        = errors.New("passkey has been revoked")
+	ErrCloneDetected            = errors.New("passkey signature counter went backwards; the passkey has been revoked")
+	ErrInvalidName              = errors.New("passkey name must be 1-100 characters")
+	ErrInvalidPolicy            = errors.New("invalid passkey policy")
+)
+
+const (
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+
+	maxNameLength = 100
+)
+
+// Options identifies us as the relying party.
+type Options struct {
+	// RPID is the domain passkeys are scoped to, e.g. "aegis.example.org".
+	RPID   string
+	RPName string
+	// Origins are the web origins ceremonies may run on, e.g.
+	// "https://aegis.example.org".
+	Origins []string
+	// Timeout is how long a ceremony may take. Default 5 minutes.
+	Timeout time.Duration
+}
+
+// challengeState is kept in the cache between the two halves of a
+// ceremony.
+type challengeState struct {
+	Challenge        string `json:"challenge"`
+	Ceremony         string `json:"ceremony"`
+	UserID           string `json:"user_id"`
+	TenantID         string `json:"tenant_id"`
+	UserVerification string `json:"user_verification"`
+}
+
+type service struct {
+	repo  Repository
+	cache cache.Client
+	opts  Options
+	now   func() time.Time
+}
+
+func NewService(repo Repository, c cache.Client, opts Options) Service {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Minute
+	}
+	if opts.RPName == "" {
+		opts.RPName = opts.RPID
+	}
+	if c == nil {
+		c = cache.NewMemory()
+	}
+	return &service{repo: repo, cache: c, opts: opts, now: time.Now}
+}
+
+func (s *service) GetPolicy(tenantID string) (*Policy, error) {
+	p, err := s.repo.GetPolicy(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		p = &Policy{
+			TenantID:         tenantID,
+			Attestation:      AttestationNone,
+			AllowedAAGUIDs:   datatypes.JSON("[]"),
+			UserVerification: UserVerificationPreferred,
+		}
+	}
+	return p, nil
+}
+
+func (s *service) UpdatePolicy(actor Actor, in PolicyInput) (*Policy, error) {
+	if in.Attestation == "" {
+		in.Attestation = AttestationNone
+	}
+	if in.UserVerification == "" {
+		in.UserVerification = UserVerificationPreferred
+	}
+	if in.Attestation != AttestationNone && in.Attestation != AttestationDirect {
+		return nil, ErrInvalidPolicy
+	}
+	if in.UserVerification != UserVerificationPreferred && in.UserVerification != UserVerificationRequired {
+		return nil, ErrInvalidPolicy
+	}
+	aaguids := make([]string, 0, len(in.AllowedAAGUIDs))
+	for _, a := range in.AllowedAAGUIDs {
+		id, err := uuid.Parse(strings.TrimSpace(a))
+		if err != nil {
+			return nil, ErrInvalidPolicy
+		}
+		if !slices.Contains(aaguids, id.String()) {
+			aaguids = append(aaguids, id.String())
+		}
+	}
+	if len(aaguids) > 0 && in.Attestation != AttestationDirect {
+		// Without attestation the AAGUID is the authenticator's unproven
+		// claim, so an allowlist would be meaningless.
+		return nil, ErrInvalidPolicy
+	}
+	raw, err := json.Marshal(aaguids)
+	if err != nil {
+		return nil, err
+	}
+	p := &Policy{
+		TenantID:         actor.TenantID,
+		Attestation:      in.Attestation,
+		AllowedAAGUIDs:   datatypes.JSON(raw),
+		UserVerification: in.UserVerification,
+		UpdatedBy:        actor.UserID,
+	}
+	if err := s.repo.SavePolicy(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// newChallenge starts a ceremony, returning its ID and challenge.
+func (s *service) newChallenge(ctx context.Context, st challengeState) (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	st.Challenge = encodeB64(b)
+	raw, err := json.Marshal(st)
+	if err != nil {
+		return "", "", err
+	}
+	id := uuid.NewString()
+	if err := s.cache.Set(ctx, cache.WebAuthnChallengeKey(id), string(raw), s.opts.Timeout); err != nil {
+		return "", "", err
+	}
+	return id, st.Challenge, nil
+}
+
+// takeChallenge consumes a challenge; each can answer one response.
+func (s *service) takeChallenge(ctx context.Context, id, ceremony, userID string) (*challengeState, error) {
+	key := cache.WebAuthnChallengeKey(id)
+	raw, ok, err := s.cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrChallengeExpired
+	}
+	if n, err := s.cache.Del(ctx, key); err != nil || n == 0 {
+		return nil, ErrChallengeExpired
+	}
+	var st challengeState
+	if err := json.Unmarshal([]byte(raw), &st); err != nil {
+		return nil, ErrChallengeExpired
+	}
+	if st.Ceremony != ceremony || st.UserID != userID {
+		return nil, ErrChallengeExpired
+	}
+	return &st, nil
+}
+
+func userHandle(userID string) (string, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return "", fmt.Errorf("%w: bad user ID", ErrInvalidResponse)
+	}
+	return encodeB64(id[:]), nil
+}
+
+func descriptors(creds []Credential) []CredentialDescriptor {
+	out := make([]CredentialDescriptor, 0, len(creds))
+	for _, c := range creds {
+		if c.RevokedAt != nil {
+			continue
+		}
+		var transports []string
+		_ = json.Unmarshal(c.Transports, &transports)
+		out = append(out, CredentialDescriptor{Type: "public-key", ID: c.CredentialID, Transports: transports})
+	}
+	return out
+}
+
+func (s *service) BeginRegistration(ctx context.Context, actor Actor) (*CreationOptions, error) {
+	handle, err := userHandle(actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.GetPolicy(actor.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := s.repo.ListCredentials(actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	id, challenge, err := s.newChallenge(ctx, challengeState{
+		Ceremony:         ceremonyCreate,
+		UserID:           actor.UserID,
+		TenantID:         actor.TenantID,
+		UserVerification: p.UserVerification,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	params := make([]CredentialParameter, 0, len(supportedAlgorithms))
+	for _, alg := range supportedAlgorithms {
+		params = append(params, CredentialParameter{Type: "public-key", Alg: alg})
+	}
+	displayName := actor.DisplayName
+	if displayName == "" {
+		displayName = actor.Email
+	}
+	return &CreationOptions{
+		ChallengeID:        id,
+		Challenge:          challenge,
+		RP:                 RelyingParty{ID: s.opts.RPID, Name: s.opts.RPName},
+		User:               UserEntity{ID: handle, Name: actor.Email, DisplayName: displayName},
+		PubKeyCredParams:   params,
+		Timeout:            s.opts.Timeout.Milliseconds(),
+		ExcludeCredentials: descriptors(creds),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: p.UserVerification,
+		},
+		Attestation: p.Attestation,
+	}, nil
+}
+
+func (s *service) FinishRegistration(ctx context.Context, actor Actor, challengeID, name string, resp RegistrationResponse) (*Credential, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxNameLength {
+		return nil, ErrInvalidName
+	}
+	st, err := s.takeChallenge(ctx, challengeID, ceremonyCreate, actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	clientDataJSON, err := decodeB64(resp.Response.ClientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%w: clientDataJSON", ErrInvalidResponse)
+	}
+	if err := verifyClientData(clientDataJSON, ceremonyCreate, st.Challenge, s.opts.Origins); err != nil {
+		return nil, err
+	}
+	rawAtt, err := decodeB64(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: attestationObject", ErrInvalidResponse)
+	}
+	var att attestationObject
+	if err := cborDecode(rawAtt, &att); err != nil {
+		return nil, fmt.Errorf("%w: malformed attestation object", ErrInvalidResponse)
+	}
+	d, err := parseAuthenticatorData(att.AuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkAuthenticatorData(d, st); err != nil {
+		return nil, err
+	}
+	if d.CredentialID == nil {
+		return nil, fmt.Errorf("%w: no attested credential", ErrInvalidResponse)
+	}
+	if rawID, err := decodeB64(resp.ID); err != nil || encodeB64(rawID) != encodeB64(d.CredentialID) {
+		return nil, fmt.Errorf("%w: credential ID mismatch", ErrInvalidResponse)
+	}
+	_, alg, err := parsePublicKey(d.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := s.GetPolicy(actor.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	attested := false
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	switch att.Format {
+	case "none":
+		if len(att.AttStmt) != 0 {
+			return nil, fmt.Errorf("%w: \"none\" attestation with a statement", ErrAttestation)
+		}
+	case "packed":
+		if attested, err = verifyPacked(att.AttStmt, att.AuthData, clientDataHash[:], d, s.now()); err != nil {
+			return nil, err
+		}
+	default:
+		// Other formats are only accepted where attestation is not
+		// required, and then as if they were "none".
+		if p.Attestation == AttestationDirect {
+			return nil, fmt.Errorf("%w: format %q is not supported", ErrAttestationRequired, att.Format)
+		}
+	}
+	if p.Attestation == AttestationDirect {
+		if !attested {
+			return nil, ErrAttestationRequired
+		}
+		var allowed []string
+		_ = json.Unmarshal(p.AllowedAAGUIDs, &allowed)
+		if len(allowed) > 0 && !slices.Contains(allowed, d.AAGUID.String()) {
+			return nil, ErrAuthenticatorNotAllowed
+		}
+	}
+
+	credentialID := encodeB64(d.CredentialID)
+	if _, err := s.repo.GetCredential(credentialID); err == nil {
+		return nil, ErrCredentialExists
+	} else if !errors.Is(err, ErrCredentialNotFound) {
+		return nil, err
+	}
+	transports, err := json.Marshal(resp.Response.Transports)
+	if err != nil {
+		return nil, err
+	}
+	c := &Credential{
+		UserID:            actor.UserID,
+		TenantID:          actor.TenantID,
+		CredentialID:      credentialID,
+		PublicKey:         d.PublicKey,
+		Algorithm:         alg,
+		SignCount:         d.SignCount,
+		AAGUID:            d.AAGUID.String(),
+		AttestationFormat: att.Format,
+		Transports:        datatypes.JSON(transports),
+		UserVerified:      d.userVerified(),
+		Name:              name,
+	}
+	if err := s.repo.CreateCredential(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// checkAuthenticatorData applies the checks common to both ceremonies.
+func (s *service) checkAuthenticatorData(d *authenticatorData, st *challengeState) error {
+	if !rpIDHashMatches(d, s.opts.RPID) {
+		return fmt.Errorf("%w: credential is scoped to another relying party", ErrInvalidResponse)
+	}
+	if !d.userPresent() {
+		return fmt.Errorf("%w: user not present", ErrInvalidResponse)
+	}
+	if st.UserVerification == UserVerificationRequired && !d.userVerified() {
+		return ErrUserVerificationRequired
+	}
+	return nil
+}
+
+func (s *service) BeginAssertion(ctx context.Context, userID, tenantID string) (*RequestOptions, error) {
+	creds, err := s.repo.ListCredentials(userID)
+	if err != nil {
+		return nil, err
+	}
+	allow := descriptors(creds)
+	if len(allow) == 0 {
+		return nil, ErrCredentialNotFound
+	}
+	p, err := s.GetPolicy(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	id, challenge, err := s.newChallenge(ctx, challengeState{
+		Ceremony:         ceremonyGet,
+		UserID:           userID,
+		TenantID:         tenantID,
+		UserVerification: p.UserVerification,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &RequestOptions{
+		ChallengeID:      id,
+		Challenge:        challenge,
+		RPID:             s.opts.RPID,
+		Timeout:          s.opts.Timeout.Milliseconds(),
+		AllowCredentials: allow,
+		UserVerification: p.UserVerification,
+	}, nil
+}
+
+func (s *service) FinishAssertion(ctx context.Context, userID, challengeID string, resp AssertionResponse) (*Credential, error) {
+	st, err := s.takeChallenge(ctx, challengeID, ceremonyGet, userID)
+	if err != nil {
+		return nil, err
+	}
+	rawID, err := decodeB64(resp.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: credential ID", ErrInvalidResponse)
+	}
+	c, err := s.repo.GetCredential(encodeB64(rawID))
+	if err != nil {
+		return nil, err
+	}
+	if c.UserID != userID {
+		return nil, ErrCredentialNotFound
+	}
+	if c.RevokedAt != nil {
+		return nil, ErrCredentialRevoked
+	}
+	if resp.Response.UserHandle != "" {
+		handle, err := userHandle(userID)
+		if err != nil {
+			return nil, err
+		}
+		if got, err := decodeB64(resp.Response.UserHandle); err != nil || encodeB64(got) != handle {
+			return nil, fmt.Errorf("%w: user handle mismatch", ErrInvalidResponse)
+		}
+	}
+
+	clientDataJSON, err := decodeB64(resp.Response.ClientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%w: clientDataJSON", ErrInvalidResponse)
+	}
+	if err := verifyClientData(clientDataJSON, ceremonyGet, st.Challenge, s.opts.Origins); err != nil {
+		return nil, err
+	}
+	rawAuthData, err := decodeB64(resp.Response.AuthenticatorData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: authenticatorData", ErrInvalidResponse)
+	}
+	d, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkAuthenticatorData(d, st); err != nil {
+		return nil, err
+	}
+	sig, err := decodeB64(resp.Response.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature", ErrInvalidResponse)
+	}
+	pub, alg, err := parsePublicKey(c.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, rawAuthData...), clientDataHash[:]...)
+	if err := verifySignature(pub, alg, signed, sig); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	// Authenticators that count signatures never repeat a value; one that
+	// does has likely been cloned.
+	if (d.SignCount != 0 || c.SignCount != 0) && d.SignCount <= c.SignCount {
+		c.RevokedAt = &now
+		if err := s.repo.SaveCredential(c); err != nil {
+			return nil, err
+		}
+		return nil, ErrCloneDetected
+	}
+	c.SignCount = d.SignCount
+	c.LastUsedAt = &now
+	if d.userVerified() {
+		c.UserVerified = true
+	}
+	if err := s.repo.SaveCredential(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *service) ListCredentials(userID string) ([]Credential, error) {
+	return s.repo.ListCredentials(userID)
+}
+
+func (s *service) RenameCredential(userID, id, name string) (*Credential, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxNameLength {
+		return nil, ErrInvalidName
+	}
+	c, err := s.repo.GetUserCredential(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	c.Name = name
+	if err := s.repo.SaveCredential(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *service) RevokeCredential(userID, id string) (*Credential, error) {
+	c, err := s.repo.GetUserCredential(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.RevokedAt != nil {
+		return nil, ErrCredentialRevoked
+	}
+	now := s.now()
+	c.RevokedAt = &now
+	if err := s.repo.SaveCredential(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *service) CountActive(userID string) (int, error) {
+	return s.repo.CountActive(userID)
+}
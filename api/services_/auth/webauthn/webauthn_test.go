@@ -0,0 +1,323 @@
+package webauthn_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"aegis-api/cache"
+	"aegis-api/services_/auth/webauthn"
+	"aegis-api/tests/fakes"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/ugorji/go/codec"
+)
+
+const (
+	rpID   = "aegis.example.org"
+	origin = "https://aegis.example.org"
+)
+
+// softAuthenticator is a software FIDO2 authenticator with a P-256
+// credential and, optionally, a packed attestation certificate.
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	aaguid       uuid.UUID
+	signCount    uint32
+	userVerified bool
+	// attestation key and certificate for "packed" with x5c.
+	attKey  *ecdsa.PrivateKey
+	attCert []byte
+}
+
+func newAuthenticator(t *testing.T) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	id := make([]byte, 32)
+	_, _ = rand.Read(id)
+	return &softAuthenticator{key: key, credentialID: id, aaguid: uuid.New(), userVerified: true}
+}
+
+func (a *softAuthenticator) withAttestationCert(t *testing.T) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ext, err := asn1.Marshal(a.aaguid[:])
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:    big.NewInt(1),
+		Subject:         pkix.Name{CommonName: "Soft Authenticator Attestation"},
+		NotBefore:       time.Now().Add(-time.Hour),
+		NotAfter:        time.Now().Add(time.Hour),
+		ExtraExtensions: []pkix.Extension{{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}, Value: ext}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	a.attKey, a.attCert = key, der
+	return a
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func cborEncode(t *testing.T, v interface{}) []byte {
+	var out []byte
+	require.NoError(t, codec.NewEncoderBytes(&out, &codec.CborHandle{}).Encode(v))
+	return out
+}
+
+func (a *softAuthenticator) clientData(t *testing.T, typ, challenge, origin string) []byte {
+	raw, err := json.Marshal(map[string]interface{}{"type": typ, "challenge": challenge, "origin": origin, "crossOrigin": false})
+	require.NoError(t, err)
+	return raw
+}
+
+func (a *softAuthenticator) authData(t *testing.T, rp string, attested bool) []byte {
+	rpHash := sha256.Sum256([]byte(rp))
+	flags := byte(0x01)
+	if a.userVerified {
+		flags |= 0x04
+	}
+	if attested {
+		flags |= 0x40
+	}
+	out := append([]byte{}, rpHash[:]...)
+	out = append(out, flags)
+	out = binary.BigEndian.AppendUint32(out, a.signCount)
+	if attested {
+		out = append(out, a.aaguid[:]...)
+		out = binary.BigEndian.AppendUint16(out, uint16(len(a.credentialID)))
+		out = append(out, a.credentialID...)
+		out = append(out, cborEncode(t, map[int]interface{}{
+			1: 2, 3: -7, -1: 1,
+			-2: a.key.X.FillBytes(make([]byte, 32)),
+			-3: a.key.Y.FillBytes(make([]byte, 32)),
+		})...)
+	}
+	return out
+}
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, authData, clientData []byte) []byte {
+	cdHash := sha256.Sum256(clientData)
+	sum := sha256.Sum256(append(append([]byte{}, authData...), cdHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+	require.NoError(t, err)
+	return sig
+}
+
+// register answers a creation ceremony with the given attestation format:
+// "none", "packed" (certificate if the authenticator has one, else self).
+func (a *softAuthenticator) register(t *testing.T, opts *webauthn.CreationOptions, origin, format string) webauthn.RegistrationResponse {
+	cd := a.clientData(t, "webauthn.create", opts.Challenge, origin)
+	ad := a.authData(t, opts.RP.ID, true)
+	stmt := map[string]interface{}{}
+	if format == "packed" {
+		if a.attKey != nil {
+			stmt = map[string]interface{}{"alg": -7, "sig": sign(t, a.attKey, ad, cd), "x5c": []interface{}{a.attCert}}
+		} else {
+			stmt = map[string]interface{}{"alg": -7, "sig": sign(t, a.key, ad, cd)}
+		}
+	}
+	var resp webauthn.RegistrationResponse
+	resp.ID = b64(a.credentialID)
+	resp.Type = "public-key"
+	resp.Response.ClientDataJSON = b64(cd)
+	resp.Response.AttestationObject = b64(cborEncode(t, map[string]interface{}{"fmt": format, "attStmt": stmt, "authData": ad}))
+	resp.Response.Transports = []string{"internal"}
+	return resp
+}
+
+func (a *softAuthenticator) assert(t *testing.T, opts *webauthn.RequestOptions, origin string) webauthn.AssertionResponse {
+	a.signCount++
+	cd := a.clientData(t, "webauthn.get", opts.Challenge, origin)
+	ad := a.authData(t, opts.RPID, false)
+	var resp webauthn.AssertionResponse
+	resp.ID = b64(a.credentialID)
+	resp.Type = "public-key"
+	resp.Response.ClientDataJSON = b64(cd)
+	resp.Response.AuthenticatorData = b64(ad)
+	resp.Response.Signature = b64(sign(t, a.key, ad, cd))
+	return resp
+}
+
+func newService() (webauthn.Service, webauthn.Actor) {
+	svc := webauthn.NewService(&fakes.Passkeys{}, cache.NewMemory(), webauthn.Options{RPID: rpID, RPName: "AEGIS", Origins: []string{origin}})
+	return svc, webauthn.Actor{UserID: uuid.NewString(), TenantID: uuid.NewString(), Email: "analyst@example.org"}
+}
+
+func TestRegisterAndAssert(t *testing.T) {
+	svc, actor := newService()
+	ctx := context.Background()
+	auth := newAuthenticator(t)
+
+	opts, err := svc.BeginRegistration(ctx, actor)
+	require.NoError(t, err)
+	require.Equal(t, rpID, opts.RP.ID)
+	require.Equal(t, webauthn.AttestationNone, opts.Attestation)
+	cred, err := svc.FinishRegistration(ctx, actor, opts.ChallengeID, "YubiKey", auth.register(t, opts, origin, "none"))
+	require.NoError(t, err)
+	require.Equal(t, "YubiKey", cred.Name)
+	require.Equal(t, webauthn.AlgES256, cred.Algorithm)
+	require.Equal(t, auth.aaguid.String(), cred.AAGUID)
+
+	// A challenge answers one response.
+	_, err = svc.FinishRegistration(ctx, actor, opts.ChallengeID, "Again", auth.register(t, opts, origin, "none"))
+	require.ErrorIs(t, err, webauthn.ErrChallengeExpired)
+
+	// The same authenticator cannot register twice.
+	opts, err = svc.BeginRegistration(ctx, actor)
+	require.NoError(t, err)
+	require.Len(t, opts.ExcludeCredentials, 1)
+	_, err = svc.FinishRegistration(ctx, actor, opts.ChallengeID, "Again", auth.register(t, opts, origin, "none"))
+	require.ErrorIs(t, err, webauthn.ErrCredentialExists)
+
+	req, err := svc.BeginAssertion(ctx, actor.UserID, actor.TenantID)
+	require.NoError(t, err)
+	require.Len(t, req.AllowCredentials, 1)
+	used, err := svc.FinishAssertion(ctx, actor.UserID, req.ChallengeID, auth.assert(t, req, origin))
+	require.NoError(t, err)
+	require.Equal(t, cred.ID, used.ID)
+	require.EqualValues(t, 1, used.SignCount)
+	require.NotNil(t, used.LastUsedAt)
+
+	// Another user cannot answer with this user's challenge.
+	req, err = svc.BeginAssertion(ctx, actor.UserID, actor.TenantID)
+	require.NoError(t, err)
+	_, err = svc.FinishAssertion(ctx, uuid.NewString(), req.ChallengeID, auth.assert(t, req, origin))
+	require.ErrorIs(t, err, webauthn.ErrChallengeExpired)
+}
+
+func TestAssertionRejectsPhishingAndForgery(t *testing.T) {
+	svc, actor := newService()
+	ctx := context.Background()
+	auth := newAuthenticator(t)
+
+	opts, err := svc.BeginRegistration(ctx, actor)
+	require.NoError(t, err)
+	_, err = svc.FinishRegistration(ctx, actor, opts.ChallengeID, "Laptop", auth.register(t, opts, "https://aegis.example.org.evil.test", "none"))
+	require.ErrorIs(t, err, webauthn.ErrInvalidResponse)
+
+	opts, err = svc.BeginRegistration(ctx, actor)
+	require.NoError(t, err)
+	_, err = svc.FinishRegistration(ctx, actor, opts.ChallengeID, "Laptop", auth.register(t, opts, origin, "none"))
+	require.NoError(t, err)
+
+	// Wrong origin.
+	req, err := svc.BeginAssertion(ctx, actor.UserID, actor.TenantID)
+	require.NoError(t, err)
+	_, err = svc.FinishAssertion(ctx, actor.UserID, req.ChallengeID, auth.assert(t, req, "https://evil.test"))
+	require.ErrorIs(t, err, webauthn.ErrInvalidResponse)
+
+	// Signed by another key.
+	req, err = svc.BeginAssertion(ctx, actor.UserID, actor.TenantID)
+	require.NoError(t, err)
+	forger := newAuthenticator(t)
+	forger.credentialID = auth.credentialID
+	forger.signCount = 10
+	_, err = svc.FinishAssertion(ctx, actor.UserID, req.ChallengeID, forger.assert(t, req, origin))
+	require.ErrorIs(t, err, webauthn.ErrInvalidSignature)
+
+	// Scoped to another relying party.
+	req, err = svc.BeginAssertion(ctx, actor.UserID, actor.TenantID)
+	require.NoError(t, err)
+	req.RPID = "evil.test"
+	_, err = svc.FinishAssertion(ctx, actor.UserID, req.ChallengeID, auth.assert(t, req, origin))
+	require.ErrorIs(t, err, webauthn.ErrInvalidResponse)
+}
+
+func TestSignCountRegressionRevokesPasskey(t *testing.T) {
+	svc, actor := newService()
+	ctx := context.Background()
+	auth := newAuthenticator(t)
+
+	opts, err := svc.BeginRegistration(ctx, actor)
+	require.NoError(t, err)
+	cred, err := svc.FinishRegistration(ctx, actor, opts.ChallengeID, "Key", auth.register(t, opts, origin, "none"))
+	require.NoError(t, err)
+
+	auth.signCount = 5
+	req, err := svc.BeginAssertion(ctx, actor.UserID, actor.TenantID)
+	require.NoError(t, err)
+	_, err = svc.FinishAssertion(ctx, actor.UserID, req.ChallengeID, auth.assert(t, req, origin))
+	require.NoError(t, err)
+
+	// A clone replays from an older counter.
+	clone := *auth
+	clone.signCount = 2
+	req, err = svc.BeginAssertion(ctx, actor.UserID, actor.TenantID)
+	require.NoError(t, err)
+	_, err = svc.FinishAssertion(ctx, actor.UserID, req.ChallengeID, clone.assert(t, req, origin))
+	require.ErrorIs(t, err, webauthn.ErrCloneDetected)
+
+	n, err := svc.CountActive(actor.UserID)
+	require.NoError(t, err)
+	require.Zero(t, n)
+	_, err = svc.RevokeCredential(actor.UserID, cred.ID)
+	require.ErrorIs(t, err, webauthn.ErrCredentialRevoked)
+	_, err = svc.BeginAssertion(ctx, actor.UserID, actor.TenantID)
+	require.ErrorIs(t, err, webauthn.ErrCredentialNotFound)
+}
+
+func TestAttestationPolicy(t *testing.T) {
+	svc, actor := newService()
+	ctx := context.Background()
+
+	_, err := svc.UpdatePolicy(actor, webauthn.PolicyInput{AllowedAAGUIDs: []string{uuid.NewString()}})
+	require.ErrorIs(t, err, webauthn.ErrInvalidPolicy)
+
+	certified := newAuthenticator(t).withAttestationCert(t)
+	_, err = svc.UpdatePolicy(actor, webauthn.PolicyInput{
+		Attestation:      webauthn.AttestationDirect,
+		AllowedAAGUIDs:   []string{certified.aaguid.String()},
+		UserVerification: webauthn.UserVerificationRequired,
+	})
+	require.NoError(t, err)
+
+	// "none" and self attestation prove nothing about the model.
+	plain := newAuthenticator(t)
+	for _, format := range []string{"none", "packed"} {
+		opts, err := svc.BeginRegistration(ctx, actor)
+		require.NoError(t, err)
+		require.Equal(t, webauthn.AttestationDirect, opts.Attestation)
+		_, err = svc.FinishRegistration(ctx, actor, opts.ChallengeID, "Plain", plain.register(t, opts, origin, format))
+		require.ErrorIs(t, err, webauthn.ErrAttestationRequired, format)
+	}
+
+	// A certified model that is not on the list.
+	other := newAuthenticator(t).withAttestationCert(t)
+	opts, err := svc.BeginRegistration(ctx, actor)
+	require.NoError(t, err)
+	_, err = svc.FinishRegistration(ctx, actor, opts.ChallengeID, "Other", other.register(t, opts, origin, "packed"))
+	require.ErrorIs(t, err, webauthn.ErrAuthenticatorNotAllowed)
+
+	// User verification is required.
+	certified.userVerified = false
+	opts, err = svc.BeginRegistration(ctx, actor)
+	require.NoError(t, err)
+	_, err = svc.FinishRegistration(ctx, actor, opts.ChallengeID, "Certified", certified.register(t, opts, origin, "packed"))
+	require.ErrorIs(t, err, webauthn.ErrUserVerificationRequired)
+
+	certified.userVerified = true
+	opts, err = svc.BeginRegistration(ctx, actor)
+	require.NoError(t, err)
+	cred, err := svc.FinishRegistration(ctx, actor, opts.ChallengeID, "Certified", certified.register(t, opts, origin, "packed"))
+	require.NoError(t, err)
+	require.Equal(t, "packed", cred.AttestationFormat)
+
+	cred, err = svc.RenameCredential(actor.UserID, cred.ID, "  Lab key  ")
+	require.NoError(t, err)
+	require.Equal(t, "Lab key", cred.Name)
+	_, err = svc.RenameCredential(uuid.NewString(), cred.ID, "Mine now")
+	require.ErrorIs(t, err, webauthn.ErrCredentialNotFound)
+}
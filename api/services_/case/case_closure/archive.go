@@ -0,0 +1,136 @@
+package case_closure
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Archive layout. Paths are relative to the package's root folder.
+const (
+	readmePath    = "README.txt"
+	reportsDir    = "01_reports"
+	evidenceDir   = "02_evidence"
+	custodyDir    = "03_chain_of_custody"
+	timelineDir   = "04_timeline"
+	iocsDir       = "05_iocs"
+	chatDir       = "06_communications"
+	auditDir      = "07_audit"
+	manifestPath  = "manifest.json"
+	signaturePath = "manifest.sig.json"
+)
+
+// archive writes zip entries under a root folder, hashing each as it is
+// written so the manifest can be built without keeping anything.
+type archive struct {
+	ctx   context.Context
+	zw    *zip.Writer
+	root  string
+	now   time.Time
+	items []*Item
+}
+
+func newArchive(ctx context.Context, w io.Writer, root string, now time.Time) *archive {
+	return &archive{ctx: ctx, zw: zip.NewWriter(w), root: root, now: now}
+}
+
+// hashWriter hashes and counts what passes through it, and remembers the
+// first write error so it can be told apart from errors of the source.
+type hashWriter struct {
+	w   io.Writer
+	h   hash.Hash
+	n   int64
+	err error
+}
+
+func (hw *hashWriter) Write(p []byte) (int, error) {
+	n, err := hw.w.Write(p)
+	hw.h.Write(p[:n])
+	hw.n += int64(n)
+	if err != nil && hw.err == nil {
+		hw.err = err
+	}
+	return n, err
+}
+
+// create opens an entry and returns a writer hashing its content.
+func (a *archive) create(name string) (*hashWriter, error) {
+	if err := a.ctx.Err(); err != nil {
+		return nil, err
+	}
+	fw, err := a.zw.CreateHeader(&zip.FileHeader{
+		Name:     path.Join(a.root, name),
+		Method:   zip.Deflate,
+		Modified: a.now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &hashWriter{w: fw, h: sha256.New()}, nil
+}
+
+// add writes one listed file. An error from fill is recorded on the item;
+// the returned error means the archive itself can no longer be written.
+func (a *archive) add(name, category, sourceID string, fill func(io.Writer) error) (*Item, error) {
+	hw, err := a.create(name)
+	if err != nil {
+		return nil, err
+	}
+	fillErr := fill(hw)
+	if hw.err != nil {
+		return nil, hw.err
+	}
+	it := &Item{
+		Path:     name,
+		Category: category,
+		SourceID: sourceID,
+		Size:     hw.n,
+		SHA256:   hex.EncodeToString(hw.h.Sum(nil)),
+	}
+	if fillErr != nil {
+		it.Error = fillErr.Error()
+	}
+	a.items = append(a.items, it)
+	return it, nil
+}
+
+// put writes an unlisted file: the manifest and its signature.
+func (a *archive) put(name string, data []byte) error {
+	hw, err := a.create(name)
+	if err != nil {
+		return err
+	}
+	_, err = hw.Write(data)
+	return err
+}
+
+func (a *archive) close() error { return a.zw.Close() }
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// fileName makes s safe as a file name on every platform the archive may
+// be unpacked on.
+func fileName(s string, max int) string {
+	s = strings.Trim(unsafeName.ReplaceAllString(strings.TrimSpace(s), "_"), "._")
+	if len(s) > max {
+		s = strings.TrimRight(s[:max], "._")
+	}
+	if s == "" {
+		return "untitled"
+	}
+	return s
+}
@@ -0,0 +1,291 @@
+package case_closure_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"testing"
+
+	graphicalmapping "aegis-api/services_/GraphicalMapping"
+	"aegis-api/services_/case/case_closure"
+	"aegis-api/services_/chain_of_custody"
+	"aegis-api/services_/evidence/metadata"
+	"aegis-api/services_/report"
+	"aegis-api/services_/report/signing"
+	"aegis-api/services_/timeline"
+	"aegis-api/tests/fakes"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type fixture struct {
+	svc      case_closure.Service
+	repo     *fakes.Closures
+	signer   *fakes.Signer
+	audit    *fakes.CaseLogs
+	policy   *fakes.Policy
+	actor    case_closure.Actor
+	caseID   string
+	evidence []metadata.Evidence
+	reports  []*report.Report
+}
+
+func sha(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tenant, caseID := uuid.NewString(), uuid.New()
+	repo := &fakes.Closures{}
+	repo.AddCase(&case_closure.CaseInfo{ID: caseID.String(), Title: "Ransomware / Finance dept.", Status: "closed", TenantID: tenant})
+
+	good, tampered := []byte("disk image bytes"), []byte("memory dump bytes")
+	evidence := []metadata.Evidence{
+		{ID: uuid.New(), CaseID: caseID, Filename: "disk 01.E01", IpfsCID: "cid-good", Checksum: sha(good)},
+		{ID: uuid.New(), CaseID: caseID, Filename: "mem.raw", IpfsCID: "cid-tampered", Checksum: sha([]byte("original"))},
+		{ID: uuid.New(), CaseID: caseID, Filename: "missing.pcap", IpfsCID: "cid-missing", Checksum: sha([]byte("x"))},
+	}
+	storage := fakes.Blobs{"cid-good": good, "cid-tampered": tampered}
+	custody := &fakes.Custody{}
+	for _, ev := range evidence {
+		custody.Entries = append(custody.Entries, chain_of_custody.ChainOfCustody{ID: uuid.New(), EvidenceID: ev.ID, Custodian: "Dana"})
+	}
+
+	sealedID, draftID := uuid.New(), uuid.New()
+	reports := []*report.Report{
+		{ID: sealedID, CaseID: caseID, Name: "Final report", Status: "published", Version: 2},
+		{ID: draftID, CaseID: caseID, Name: "Addendum", Status: "draft", Version: 1},
+	}
+	reportStore := &fakes.Reports{}
+	for _, r := range reports {
+		reportStore.Add(r)
+	}
+	signer := &fakes.Signer{KeyID: uuid.NewString(), Key: priv, Sealed: map[uuid.UUID]*signing.Artifact{
+		sealedID: {ReportID: sealedID.String(), Version: 2, PDF: []byte("%PDF-sealed"), Bundle: []byte(`{"format":"aegis-report-signature/v1"}`)},
+	}}
+	audit := &fakes.CaseLogs{}
+	policy := &fakes.Policy{}
+
+	svc := case_closure.NewService(repo, case_closure.Sources{
+		Reports:  reportStore,
+		Signer:   signer,
+		Evidence: &fakes.Evidence{Items: evidence},
+		Storage:  storage,
+		Custody:  custody,
+		Timeline: &fakes.Timeline{Events: []*timeline.TimelineEvent{{ID: "e1", CaseID: caseID.String(), Description: "Initial access via phishing"}}},
+		IOCs:     &fakes.IOCs{Items: []*graphicalmapping.IOC{{ID: "i1", CaseID: caseID.String(), Type: "IP", Value: "203.0.113.7"}}},
+		Chat:     fakes.ChatHistory{Messages: 250},
+		Audit:    audit,
+		Policy:   policy,
+	})
+	return &fixture{
+		svc: svc, repo: repo, signer: signer, audit: audit, policy: policy,
+		actor:  case_closure.Actor{UserID: uuid.NewString(), TenantID: tenant},
+		caseID: caseID.String(), evidence: evidence, reports: reports,
+	}
+}
+
+// unzip returns the archive's files by path relative to its root folder.
+func unzip(t *testing.T, data []byte, root string) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		require.True(t, strings.HasPrefix(f.Name, root+"/"), f.Name)
+		rc, err := f.Open()
+		require.NoError(t, err)
+		b, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[strings.TrimPrefix(f.Name, root+"/")] = b
+	}
+	return files
+}
+
+func TestPackageListsEveryFileWithItsHashAndIsSigned(t *testing.T) {
+	ctx := context.Background()
+	fx := newFixture(t)
+
+	pkg, err := fx.svc.Begin(ctx, fx.actor, fx.caseID, case_closure.Options{IncludeEvidence: true})
+	require.NoError(t, err)
+	require.Equal(t, "Ransomware_Finance_dept_"+fx.caseID[:8]+"_closure", pkg.Name)
+
+	var out bytes.Buffer
+	require.NoError(t, fx.svc.Write(ctx, fx.actor, pkg, &out))
+	files := unzip(t, out.Bytes(), pkg.Name)
+
+	var manifest case_closure.Manifest
+	require.NoError(t, json.Unmarshal(files["manifest.json"], &manifest))
+	require.Equal(t, case_closure.ManifestFormat, manifest.Format)
+	require.Equal(t, pkg.ID, manifest.PackageID)
+	require.True(t, manifest.IncludesEvidenceFiles)
+
+	// Every file but the manifest and its signature is listed, with the
+	// hash of what is in the archive.
+	listed := map[string]case_closure.Item{}
+	for _, it := range manifest.Items {
+		listed[it.Path] = it
+		data, ok := files[it.Path]
+		require.True(t, ok, it.Path)
+		require.Equal(t, sha(data), it.SHA256, it.Path)
+		require.EqualValues(t, len(data), it.Size, it.Path)
+	}
+	require.Len(t, files, len(manifest.Items)+2)
+	for _, p := range []string{
+		"README.txt",
+		"01_reports/reports.json",
+		"01_reports/01_Final_report.pdf",
+		"01_reports/01_Final_report.signature.json",
+		"01_reports/02_Addendum.pdf",
+		"02_evidence/evidence_manifest.json",
+		"03_chain_of_custody/chain_of_custody.json",
+		"04_timeline/timeline.json",
+		"05_iocs/iocs.json",
+		"06_communications/chat_messages.jsonl",
+		"07_audit/audit_log.jsonl",
+	} {
+		require.Contains(t, listed, p)
+	}
+
+	// The sealed report is the artifact itself; the draft is rendered.
+	require.Equal(t, "%PDF-sealed", string(files["01_reports/01_Final_report.pdf"]))
+	require.Contains(t, string(files["01_reports/02_Addendum.pdf"]), "%PDF-rendered")
+
+	// Evidence files are checked against their upload checksums.
+	good := listed[path.Join("02_evidence/files", fx.evidence[0].ID.String()+"_disk_01.E01")]
+	require.NotNil(t, good.Verified)
+	require.True(t, *good.Verified)
+	tampered := listed[path.Join("02_evidence/files", fx.evidence[1].ID.String()+"_mem.raw")]
+	require.False(t, *tampered.Verified)
+	require.Contains(t, tampered.Note, "does not match")
+	missing := listed[path.Join("02_evidence/files", fx.evidence[2].ID.String()+"_missing.pcap")]
+	require.False(t, *missing.Verified)
+	require.Contains(t, missing.Error, "not found")
+
+	require.Equal(t, 250, bytes.Count(files["06_communications/chat_messages.jsonl"], []byte("\n")))
+	require.Equal(t, 3, bytes.Count(files["07_audit/audit_log.jsonl"], []byte("\n")))
+	require.ElementsMatch(t, []string{
+		fx.evidence[0].ID.String(), fx.evidence[1].ID.String(), fx.evidence[2].ID.String(),
+		fx.reports[0].ID.String(), fx.reports[1].ID.String(),
+	}, fx.audit.Query.TargetIDs)
+
+	// The signature covers manifest.json as stored.
+	var sig case_closure.ManifestSignature
+	require.NoError(t, json.Unmarshal(files["manifest.sig.json"], &sig))
+	require.Equal(t, sha(files["manifest.json"]), sig.ManifestSHA256)
+	raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+	require.NoError(t, err)
+	require.True(t, ed25519.Verify(fx.signer.Key.Public().(ed25519.PublicKey), files["manifest.json"], raw))
+
+	rec := fx.repo.Packages[pkg.ID]
+	require.Equal(t, case_closure.StatusCompleted, rec.Status)
+	require.Equal(t, sig.ManifestSHA256, rec.ManifestSHA256)
+	require.Equal(t, len(manifest.Items), rec.Items)
+	require.Equal(t, 1, rec.Errors)
+
+	v, err := fx.svc.Verify(fx.actor, files["manifest.json"], files["manifest.sig.json"])
+	require.NoError(t, err)
+	require.True(t, v.SignatureValid)
+	require.True(t, v.Recorded)
+
+	edited := bytes.Replace(files["manifest.json"], []byte(`"closed"`), []byte(`"open"`), 1)
+	v, err = fx.svc.Verify(fx.actor, edited, files["manifest.sig.json"])
+	require.NoError(t, err)
+	require.False(t, v.SignatureValid)
+	require.False(t, v.Recorded)
+}
+
+func TestPackageWithoutEvidenceFiles(t *testing.T) {
+	ctx := context.Background()
+	fx := newFixture(t)
+
+	pkg, err := fx.svc.Begin(ctx, fx.actor, fx.caseID, case_closure.Options{})
+	require.NoError(t, err)
+	var out bytes.Buffer
+	require.NoError(t, fx.svc.Write(ctx, fx.actor, pkg, &out))
+	for name := range unzip(t, out.Bytes(), pkg.Name) {
+		require.False(t, strings.HasPrefix(name, "02_evidence/files/"), name)
+	}
+}
+
+func TestPackageLeavesOutWhatTheUserMayNotExport(t *testing.T) {
+	ctx := context.Background()
+	fx := newFixture(t)
+	fx.policy.Refuse(fx.reports[1].ID.String())
+	fx.policy.RefuseContent(fx.evidence[0].Checksum)
+
+	pkg, err := fx.svc.Begin(ctx, fx.actor, fx.caseID, case_closure.Options{IncludeEvidence: true})
+	require.NoError(t, err)
+	var out bytes.Buffer
+	require.NoError(t, fx.svc.Write(ctx, fx.actor, pkg, &out))
+	files := unzip(t, out.Bytes(), pkg.Name)
+
+	var manifest case_closure.Manifest
+	require.NoError(t, json.Unmarshal(files["manifest.json"], &manifest))
+	require.Equal(t, 2, manifest.Withheld)
+	require.Contains(t, files, "01_reports/01_Final_report.pdf")
+	require.NotContains(t, files, "01_reports/02_Addendum.pdf")
+	require.NotContains(t, string(files["01_reports/reports.json"]), "Addendum")
+	require.NotContains(t, string(files["02_evidence/evidence_manifest.json"]), fx.evidence[0].ID.String())
+	require.NotContains(t, files, path.Join("02_evidence/files", fx.evidence[0].ID.String()+"_disk_01.E01"))
+	require.NotContains(t, string(files["03_chain_of_custody/chain_of_custody.json"]), fx.evidence[0].ID.String())
+}
+
+func TestBeginChecksCaseAndSigning(t *testing.T) {
+	ctx := context.Background()
+	fx := newFixture(t)
+
+	other := fx.actor
+	other.TenantID = uuid.NewString()
+	_, err := fx.svc.Begin(ctx, other, fx.caseID, case_closure.Options{})
+	require.ErrorIs(t, err, case_closure.ErrCaseNotFound)
+	_, err = fx.svc.Begin(ctx, fx.actor, "not-a-uuid", case_closure.Options{})
+	require.ErrorIs(t, err, case_closure.ErrCaseNotFound)
+
+	fx.signer.Disabled = true
+	_, err = fx.svc.Begin(ctx, fx.actor, fx.caseID, case_closure.Options{})
+	require.ErrorIs(t, err, case_closure.ErrSigningDisabled)
+	require.Empty(t, fx.repo.Packages)
+}
+
+// A writer that fails, like a client hanging up, fails the package.
+type brokenWriter struct{ after int }
+
+func (b *brokenWriter) Write(p []byte) (int, error) {
+	if b.after -= len(p); b.after < 0 {
+		return 0, errors.New("connection reset")
+	}
+	return len(p), nil
+}
+
+func TestWriteFailureIsRecorded(t *testing.T) {
+	ctx := context.Background()
+	fx := newFixture(t)
+
+	pkg, err := fx.svc.Begin(ctx, fx.actor, fx.caseID, case_closure.Options{IncludeEvidence: true})
+	require.NoError(t, err)
+	err = fx.svc.Write(ctx, fx.actor, pkg, &brokenWriter{after: 64})
+	require.Error(t, err)
+
+	rec := fx.repo.Packages[pkg.ID]
+	require.Equal(t, case_closure.StatusFailed, rec.Status)
+	require.Empty(t, rec.ManifestSHA256)
+	require.NotNil(t, rec.FinishedAt)
+}
@@ -0,0 +1,110 @@
+package case_closure
+
+import (
+	"context"
+	"io"
+
+	graphicalmapping "aegis-api/services_/GraphicalMapping"
+	"aegis-api/services_/auditlog"
+	"aegis-api/services_/auth/abac"
+	"aegis-api/services_/chain_of_custody"
+	"aegis-api/services_/chat"
+	"aegis-api/services_/evidence/metadata"
+	"aegis-api/services_/report"
+	"aegis-api/services_/report/signing"
+	"aegis-api/services_/timeline"
+
+	"github.com/google/uuid"
+)
+
+type Repository interface {
+	AutoMigrate() error
+
+	// GetCase returns the tenant's case, or ErrCaseNotFound.
+	GetCase(tenantID, caseID string) (*CaseInfo, error)
+	CreatePackage(p *Package) error
+	SavePackage(p *Package) error
+	GetPackage(tenantID, packageID string) (*Package, error)
+	ListPackages(tenantID, caseID string) ([]Package, error)
+}
+
+// Reports is the part of report.ReportService packages need.
+type Reports interface {
+	GetReportsByCaseID(ctx context.Context, caseID uuid.UUID) ([]report.ReportWithDetails, error)
+	DownloadReportAsPDF(ctx context.Context, reportID uuid.UUID, fields report.FieldRenderer) ([]byte, error)
+}
+
+// Signer provides sealed report artifacts and signs manifests with the
+// tenant's report signing key.
+type Signer interface {
+	Enabled() bool
+	Artifact(ctx context.Context, actor signing.Actor, reportID uuid.UUID) (*signing.Artifact, error)
+	Sign(tenantID, userID string, payload []byte) (*signing.Detached, error)
+	VerifyDetached(tenantID string, payload []byte, d *signing.Detached) error
+}
+
+type Evidence interface {
+	GetEvidenceByCaseID(caseID uuid.UUID) ([]metadata.Evidence, error)
+	VerifyEvidenceLogChain(evidenceID uuid.UUID) (bool, string, error)
+}
+
+// Storage reads evidence files.
+type Storage interface {
+	Download(cid string) (io.ReadCloser, error)
+}
+
+type Custody interface {
+	GetEntries(ctx context.Context, evidenceID uuid.UUID) ([]chain_of_custody.ChainOfCustody, error)
+}
+
+type Timeline interface {
+	ListEvents(caseID string) ([]*timeline.TimelineEventResponse, error)
+}
+
+type IOCs interface {
+	ListIOCsByCase(caseID string) ([]*graphicalmapping.IOC, error)
+}
+
+type Chat interface {
+	StreamCaseMessages(ctx context.Context, caseID string, fn func(*chat.ChatGroup, *chat.Message) error) error
+}
+
+type AuditTrail interface {
+	StreamCaseLogs(ctx context.Context, q auditlog.CaseLogQuery, fn func(auditlog.AuditLog) error) error
+}
+
+// Classification decides which labelled reports and evidence may leave
+// the system in a package.
+type Classification interface {
+	Withheld(ctx context.Context, s abac.Subject, action, resourceType string, ids []string) (map[string]*abac.Decision, error)
+	DecideContent(ctx context.Context, s abac.Subject, action, checksum string) (*abac.Decision, string, error)
+}
+
+// Sources are the services a package is assembled from.
+type Sources struct {
+	Reports  Reports
+	Fields   report.FieldRenderer // expands merge fields of unsealed reports; may be nil
+	Signer   Signer
+	Evidence Evidence
+	Storage  Storage
+	Custody  Custody
+	Timeline Timeline
+	IOCs     IOCs
+	Chat     Chat
+	Audit    AuditTrail
+	Policy   Classification // nil: nothing is classified
+}
+
+type Service interface {
+	// Begin checks the case and records a new package. Nothing is
+	// gathered until Write.
+	Begin(ctx context.Context, actor Actor, caseID string, opts Options) (*Package, error)
+	// Write streams the package to w as a zip archive, ending with the
+	// manifest and its signature, and records the outcome on pkg. Items
+	// are read and written one at a time.
+	Write(ctx context.Context, actor Actor, pkg *Package, w io.Writer) error
+	ListPackages(actor Actor, caseID string) ([]Package, error)
+	// Verify checks manifest.json against manifest.sig.json and the
+	// package record.
+	Verify(actor Actor, manifest, signature []byte) (*Verification, error)
+}
@@ -0,0 +1,135 @@
+package case_closure
+
+import (
+	"time"
+
+	"aegis-api/services_/report/signing"
+)
+
+const (
+	// ManifestFormat identifies the manifest layout.
+	ManifestFormat = "aegis-case-closure/v1"
+	// SignatureFormat identifies the manifest signature file layout.
+	SignatureFormat = "aegis-case-closure-signature/v1"
+)
+
+// Package statuses.
+const (
+	StatusStreaming = "streaming"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Item categories, one per folder of the archive.
+const (
+	CategoryReadme   = "readme"
+	CategoryReport   = "report"
+	CategoryEvidence = "evidence"
+	CategoryCustody  = "chain_of_custody"
+	CategoryTimeline = "timeline"
+	CategoryIOCs     = "iocs"
+	CategoryChat     = "communications"
+	CategoryAudit    = "audit"
+)
+
+// Package records one export of a case's closure package. The archive is
+// streamed to the requester and not kept; its manifest hash and signature
+// are, so a copy handed over can later be checked against the record.
+type Package struct {
+	ID              string `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID        string `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	CaseID          string `gorm:"type:uuid;not null;index" json:"case_id"`
+	Name            string `gorm:"type:varchar(255);not null" json:"name"` // root folder, and file name without .zip
+	IncludeEvidence bool   `gorm:"not null;default:false" json:"include_evidence"`
+	Status          string `gorm:"type:varchar(20);not null" json:"status"`
+	Items           int    `gorm:"not null;default:0" json:"items"`
+	// Bytes is the uncompressed size of the listed items.
+	Bytes int64 `gorm:"not null;default:0" json:"bytes"`
+	// Errors counts items written incomplete or replaced by an error note.
+	Errors         int        `gorm:"not null;default:0" json:"errors"`
+	ManifestSHA256 string     `gorm:"column:manifest_sha256;type:char(64);index" json:"manifest_sha256,omitempty"`
+	KeyID          string     `gorm:"type:uuid" json:"key_id,omitempty"`
+	Signature      string     `gorm:"type:text" json:"signature,omitempty"` // base64, over manifest.json
+	Error          string     `gorm:"type:text" json:"error,omitempty"`
+	CreatedBy      string     `gorm:"type:uuid;not null" json:"created_by"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+}
+
+func (Package) TableName() string { return "case_closure_packages" }
+
+// CaseInfo is the case a package is built for.
+type CaseInfo struct {
+	ID                 string    `json:"id"`
+	Title              string    `json:"title"`
+	Description        string    `json:"description,omitempty"`
+	Status             string    `json:"status"`
+	Priority           string    `json:"priority"`
+	InvestigationStage string    `json:"investigation_stage"`
+	TeamName           string    `json:"team_name"`
+	TenantID           string    `json:"tenant_id"`
+	TeamID             string    `json:"team_id"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// Manifest lists every file of a package with its hash. It is written
+// last, as manifest.json, and signed into manifest.sig.json.
+type Manifest struct {
+	Format                string    `json:"format"`
+	PackageID             string    `json:"package_id"`
+	Case                  CaseInfo  `json:"case"`
+	GeneratedAt           time.Time `json:"generated_at"`
+	GeneratedBy           string    `json:"generated_by"`
+	IncludesEvidenceFiles bool      `json:"includes_evidence_files"`
+	// Withheld counts the reports and evidence left out because the
+	// exporting user's clearance does not allow their export.
+	Withheld int    `json:"withheld,omitempty"`
+	Items    []Item `json:"items"`
+}
+
+// Item is one file of a package. Paths are relative to the root folder.
+type Item struct {
+	Path     string `json:"path"`
+	Category string `json:"category"`
+	SourceID string `json:"source_id,omitempty"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+	// Verified is set on evidence files: whether the content matches the
+	// checksum recorded at upload.
+	Verified *bool  `json:"verified,omitempty"`
+	Note     string `json:"note,omitempty"`
+	// Error is set when the file could not be written in full; Size and
+	// SHA256 then describe what was written.
+	Error string `json:"error,omitempty"`
+}
+
+// ManifestSignature is the content of manifest.sig.json. The signature
+// covers the bytes of manifest.json as they are in the archive.
+type ManifestSignature struct {
+	Format         string `json:"format"`
+	PackageID      string `json:"package_id"`
+	ManifestSHA256 string `json:"manifest_sha256"`
+	signing.Detached
+}
+
+// Options control what a package includes.
+type Options struct {
+	// IncludeEvidence adds the evidence files themselves.
+	IncludeEvidence bool
+}
+
+// Verification is the outcome of checking a manifest and its signature.
+type Verification struct {
+	ManifestSHA256 string `json:"manifest_sha256"`
+	SignatureValid bool   `json:"signature_valid"`
+	// Recorded is set when the manifest is the one recorded for the
+	// package it names.
+	Recorded bool     `json:"recorded"`
+	Package  *Package `json:"package,omitempty"`
+}
+
+// Actor is the user exporting or verifying a package.
+type Actor struct {
+	UserID   string
+	TenantID string
+}
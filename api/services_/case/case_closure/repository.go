@@ -0,0 +1,64 @@
+package case_closure
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) AutoMigrate() error {
+	return r.db.AutoMigrate(&Package{})
+}
+
+func (r *GormRepository) GetCase(tenantID, caseID string) (*CaseInfo, error) {
+	var c CaseInfo
+	res := r.db.Table("cases").
+		Select(`id, title, COALESCE(description, '') AS description, status, priority,
+			investigation_stage, COALESCE(team_name, '') AS team_name, tenant_id,
+			COALESCE(team_id::text, '') AS team_id, created_at`).
+		Where("id = ? AND tenant_id = ?", caseID, tenantID).
+		Limit(1).Scan(&c)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrCaseNotFound
+	}
+	return &c, nil
+}
+
+func (r *GormRepository) CreatePackage(p *Package) error {
+	return r.db.Create(p).Error
+}
+
+func (r *GormRepository) SavePackage(p *Package) error {
+	return r.db.Save(p).Error
+}
+
+func (r *GormRepository) GetPackage(tenantID, packageID string) (*Package, error) {
+	var p Package
+	err := r.db.Where("id = ? AND tenant_id = ?", packageID, tenantID).First(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPackageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *GormRepository) ListPackages(tenantID, caseID string) ([]Package, error) {
+	var out []Package
+	err := r.db.Where("tenant_id = ? AND case_id = ?", tenantID, caseID).
+		Order("created_at DESC").
+		Find(&out).Error
+	return out, err
+}
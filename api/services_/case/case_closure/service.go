@@ -0,0 +1,526 @@
+package case_closure
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"aegis-api/services_/auditlog"
+	"aegis-api/services_/auth/abac"
+	"aegis-api/services_/chain_of_custody"
+	"aegis-api/services_/chat"
+	"aegis-api/services_/evidence/metadata"
+	"aegis-api/services_/report/signing"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCaseNotFound     = errors.New("case not found")
+	ErrPackageNotFound  = errors.New("closure package not found")
+	ErrSigningDisabled  = signing.ErrSigningDisabled
+	ErrInvalidSignature = errors.New("not a closure package signature file")
+	ErrFileTooLarge     = errors.New("file is too large to verify")
+)
+
+// MaxManifestSize bounds the files uploaded to Verify.
+const MaxManifestSize = 64 << 20
+
+type service struct {
+	repo Repository
+	src  Sources
+	now  func() time.Time
+}
+
+func NewService(repo Repository, src Sources) Service {
+	return &service{repo: repo, src: src, now: time.Now}
+}
+
+func (s *service) caseInTenant(actor Actor, caseID string) (*CaseInfo, error) {
+	if _, err := uuid.Parse(caseID); err != nil {
+		return nil, ErrCaseNotFound
+	}
+	return s.repo.GetCase(actor.TenantID, caseID)
+}
+
+func (s *service) Begin(ctx context.Context, actor Actor, caseID string, opts Options) (*Package, error) {
+	c, err := s.caseInTenant(actor, caseID)
+	if err != nil {
+		return nil, err
+	}
+	// A package is only useful to legal with its signature, so refuse
+	// before anything is streamed rather than fail at the end.
+	if !s.src.Signer.Enabled() {
+		return nil, ErrSigningDisabled
+	}
+	pkg := &Package{
+		TenantID:        actor.TenantID,
+		CaseID:          c.ID,
+		Name:            fmt.Sprintf("%s_%s_closure", fileName(c.Title, 60), c.ID[:8]),
+		IncludeEvidence: opts.IncludeEvidence,
+		Status:          StatusStreaming,
+		CreatedBy:       actor.UserID,
+	}
+	if err := s.repo.CreatePackage(pkg); err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+func (s *service) Write(ctx context.Context, actor Actor, pkg *Package, w io.Writer) error {
+	err := s.write(ctx, actor, pkg, w)
+	now := s.now().UTC()
+	pkg.FinishedAt = &now
+	pkg.Status = StatusCompleted
+	if err != nil {
+		pkg.Status = StatusFailed
+		pkg.Error = err.Error()
+	}
+	if saveErr := s.repo.SavePackage(pkg); saveErr != nil && err == nil {
+		err = saveErr
+	}
+	return err
+}
+
+func (s *service) write(ctx context.Context, actor Actor, pkg *Package, w io.Writer) error {
+	c, err := s.repo.GetCase(pkg.TenantID, pkg.CaseID)
+	if err != nil {
+		return err
+	}
+	now := s.now().UTC()
+	a := newArchive(ctx, w, pkg.Name, now)
+
+	if _, err := a.add(readmePath, CategoryReadme, "", func(w io.Writer) error {
+		_, err := io.WriteString(w, readme(c, pkg, now))
+		return err
+	}); err != nil {
+		return err
+	}
+	reportIDs, withheldReports, err := s.addReports(ctx, a, actor, c)
+	if err != nil {
+		return err
+	}
+	evidence, withheldEvidence, err := s.addEvidence(ctx, a, actor, c, pkg.IncludeEvidence)
+	if err != nil {
+		return err
+	}
+	if err := s.addCaseRecords(ctx, a, c, evidence, reportIDs); err != nil {
+		return err
+	}
+
+	manifest := Manifest{
+		Format:                ManifestFormat,
+		PackageID:             pkg.ID,
+		Case:                  *c,
+		GeneratedAt:           now,
+		GeneratedBy:           actor.UserID,
+		IncludesEvidenceFiles: pkg.IncludeEvidence,
+		Withheld:              withheldReports + withheldEvidence,
+		Items:                 make([]Item, 0, len(a.items)),
+	}
+	pkg.Items, pkg.Bytes, pkg.Errors = len(a.items), 0, 0
+	for _, it := range a.items {
+		manifest.Items = append(manifest.Items, *it)
+		pkg.Bytes += it.Size
+		if it.Error != "" {
+			pkg.Errors++
+		}
+	}
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, manifest); err != nil {
+		return err
+	}
+	manifestJSON := buf.Bytes()
+	sum := sha256.Sum256(manifestJSON)
+	pkg.ManifestSHA256 = hex.EncodeToString(sum[:])
+
+	detached, err := s.src.Signer.Sign(pkg.TenantID, actor.UserID, manifestJSON)
+	if err != nil {
+		return fmt.Errorf("signing manifest: %w", err)
+	}
+	pkg.KeyID, pkg.Signature = detached.KeyID, detached.Signature
+	var sigJSON bytes.Buffer
+	if err := writeJSON(&sigJSON, ManifestSignature{
+		Format:         SignatureFormat,
+		PackageID:      pkg.ID,
+		ManifestSHA256: pkg.ManifestSHA256,
+		Detached:       *detached,
+	}); err != nil {
+		return err
+	}
+
+	if err := a.put(manifestPath, manifestJSON); err != nil {
+		return err
+	}
+	if err := a.put(signaturePath, sigJSON.Bytes()); err != nil {
+		return err
+	}
+	return a.close()
+}
+
+// reportEntry is a line of the reports index.
+type reportEntry struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+	Status       string `json:"status"`
+	Version      int    `json:"version"`
+	Author       string `json:"author"`
+	LastModified string `json:"last_modified"`
+	File         string `json:"file,omitempty"`
+	Sealed       bool   `json:"sealed"`
+}
+
+// withheld returns the resources of ids the actor may not export.
+func (s *service) withheld(ctx context.Context, actor Actor, resourceType string, ids []string) (map[string]*abac.Decision, error) {
+	if s.src.Policy == nil || len(ids) == 0 {
+		return nil, nil
+	}
+	return s.src.Policy.Withheld(ctx, subject(actor), abac.ActionExport, resourceType, ids)
+}
+
+func subject(actor Actor) abac.Subject {
+	return abac.Subject{UserID: actor.UserID, TenantID: actor.TenantID}
+}
+
+// addReports writes each report of the case as a PDF: the sealed artifact
+// when its current version is sealed, otherwise rendered now. Reports the
+// actor may not export are left out; their number is returned.
+func (s *service) addReports(ctx context.Context, a *archive, actor Actor, c *CaseInfo) ([]string, int, error) {
+	all, listErr := s.src.Reports.GetReportsByCaseID(ctx, uuid.MustParse(c.ID))
+	allIDs := make([]string, len(all))
+	for i, r := range all {
+		allIDs[i] = r.ID.String()
+	}
+	refused, err := s.withheld(ctx, actor, abac.ResourceReport, allIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	reports := all[:0]
+	for _, r := range all {
+		if refused[r.ID.String()] == nil {
+			reports = append(reports, r)
+		}
+	}
+	var ids []string
+	index := make([]reportEntry, 0, len(reports))
+	for i, r := range reports {
+		ids = append(ids, r.ID.String())
+		entry := reportEntry{
+			ID: r.ID.String(), Name: r.Name, Type: r.Type, Status: r.Status, Version: r.Version,
+			Author: r.Author, LastModified: r.LastModified,
+			File: path.Join(reportsDir, fmt.Sprintf("%02d_%s.pdf", i+1, fileName(r.Name, 80))),
+		}
+
+		var pdf, bundle []byte
+		note := "rendered at export from the " + r.Status + " report"
+		if r.Status == "published" {
+			art, err := s.src.Signer.Artifact(ctx, signing.Actor{UserID: actor.UserID, TenantID: c.TenantID}, r.ID)
+			if err == nil {
+				pdf, bundle, entry.Sealed = art.PDF, art.Bundle, true
+				note = fmt.Sprintf("sealed artifact of version %d", art.Version)
+			}
+		}
+		var renderErr error
+		if pdf == nil {
+			pdf, renderErr = s.src.Reports.DownloadReportAsPDF(ctx, r.ID, s.src.Fields)
+		}
+		it, err := a.add(entry.File, CategoryReport, entry.ID, func(w io.Writer) error {
+			if renderErr != nil {
+				return renderErr
+			}
+			_, err := w.Write(pdf)
+			return err
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		it.Note = note
+		if bundle != nil {
+			name := strings.TrimSuffix(entry.File, ".pdf") + ".signature.json"
+			if _, err := a.add(name, CategoryReport, entry.ID, func(w io.Writer) error {
+				_, err := w.Write(bundle)
+				return err
+			}); err != nil {
+				return nil, 0, err
+			}
+		}
+		index = append(index, entry)
+	}
+
+	_, err = a.add(path.Join(reportsDir, "reports.json"), CategoryReport, "", func(w io.Writer) error {
+		if listErr != nil {
+			return listErr
+		}
+		return writeJSON(w, index)
+	})
+	return ids, len(refused), err
+}
+
+// evidenceEntry is a line of the evidence manifest.
+type evidenceEntry struct {
+	ID         string    `json:"id"`
+	Filename   string    `json:"filename"`
+	FileType   string    `json:"file_type"`
+	Size       int64     `json:"file_size"`
+	SHA256     string    `json:"sha256"`
+	IpfsCID    string    `json:"ipfs_cid"`
+	UploadedBy string    `json:"uploaded_by"`
+	UploadedAt time.Time `json:"uploaded_at"`
+	// LogChainValid reports whether the evidence's append-only log still
+	// verifies.
+	LogChainValid bool   `json:"log_chain_valid"`
+	LogChainNote  string `json:"log_chain_note,omitempty"`
+	File          string `json:"file,omitempty"`
+}
+
+// addEvidence writes the evidence manifest and, if asked, the files.
+// Evidence the actor may not export, by its own label or one on the same
+// content, is left out; its number is returned.
+func (s *service) addEvidence(ctx context.Context, a *archive, actor Actor, c *CaseInfo, includeFiles bool) ([]metadata.Evidence, int, error) {
+	all, listErr := s.src.Evidence.GetEvidenceByCaseID(uuid.MustParse(c.ID))
+	evidence, err := s.exportableEvidence(ctx, actor, all)
+	if err != nil {
+		return nil, 0, err
+	}
+	withheld := len(all) - len(evidence)
+	entries := make([]evidenceEntry, 0, len(evidence))
+	for _, ev := range evidence {
+		entry := evidenceEntry{
+			ID: ev.ID.String(), Filename: ev.Filename, FileType: ev.FileType, Size: ev.FileSize,
+			SHA256: ev.Checksum, IpfsCID: ev.IpfsCID, UploadedBy: ev.UploadedBy.String(), UploadedAt: ev.UploadedAt,
+		}
+		valid, note, err := s.src.Evidence.VerifyEvidenceLogChain(ev.ID)
+		if err != nil {
+			note = err.Error()
+		}
+		entry.LogChainValid, entry.LogChainNote = valid && err == nil, note
+		if includeFiles {
+			entry.File = path.Join(evidenceDir, "files", ev.ID.String()+"_"+evidenceFileName(ev.Filename))
+		}
+		entries = append(entries, entry)
+	}
+	if _, err := a.add(path.Join(evidenceDir, "evidence_manifest.json"), CategoryEvidence, "", func(w io.Writer) error {
+		if listErr != nil {
+			return listErr
+		}
+		return writeJSON(w, entries)
+	}); err != nil {
+		return nil, 0, err
+	}
+
+	if !includeFiles {
+		return evidence, withheld, nil
+	}
+	for i, ev := range evidence {
+		it, err := a.add(entries[i].File, CategoryEvidence, ev.ID.String(), func(w io.Writer) error {
+			rc, err := s.src.Storage.Download(ev.IpfsCID)
+			if err != nil {
+				return err
+			}
+			defer rc.Close()
+			_, err = io.Copy(w, rc)
+			return err
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		verified := it.Error == "" && strings.EqualFold(it.SHA256, ev.Checksum)
+		it.Verified = &verified
+		if it.Error == "" && !verified {
+			it.Note = "content does not match the checksum recorded at upload (" + ev.Checksum + ")"
+		}
+	}
+	return evidence, withheld, nil
+}
+
+// exportableEvidence drops the evidence the actor may not export.
+func (s *service) exportableEvidence(ctx context.Context, actor Actor, all []metadata.Evidence) ([]metadata.Evidence, error) {
+	if s.src.Policy == nil {
+		return all, nil
+	}
+	ids := make([]string, len(all))
+	for i, ev := range all {
+		ids[i] = ev.ID.String()
+	}
+	refused, err := s.withheld(ctx, actor, abac.ResourceEvidence, ids)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]metadata.Evidence, 0, len(all))
+	for _, ev := range all {
+		if refused[ev.ID.String()] != nil {
+			continue
+		}
+		if ev.Checksum != "" {
+			d, _, err := s.src.Policy.DecideContent(ctx, subject(actor), abac.ActionExport, ev.Checksum)
+			if err != nil {
+				return nil, err
+			}
+			if !d.Allowed {
+				continue
+			}
+		}
+		out = append(out, ev)
+	}
+	return out, nil
+}
+
+func evidenceFileName(name string) string {
+	ext := path.Ext(name)
+	if ext != "" && len(ext) <= 12 {
+		return fileName(strings.TrimSuffix(name, ext), 80) + "." + fileName(ext, 11)
+	}
+	return fileName(name, 80)
+}
+
+// custodyRecord is the chain of custody of one evidence item.
+type custodyRecord struct {
+	EvidenceID string                            `json:"evidence_id"`
+	Filename   string                            `json:"filename"`
+	Entries    []chain_of_custody.ChainOfCustody `json:"entries"`
+}
+
+// chatLine is a line of the chat export.
+type chatLine struct {
+	GroupID   string `json:"group_id"`
+	GroupName string `json:"group_name"`
+	*chat.Message
+}
+
+// addCaseRecords writes custody, timeline, IOCs, chat and audit records.
+func (s *service) addCaseRecords(ctx context.Context, a *archive, c *CaseInfo, evidence []metadata.Evidence, reportIDs []string) error {
+	if _, err := a.add(path.Join(custodyDir, "chain_of_custody.json"), CategoryCustody, "", func(w io.Writer) error {
+		records := make([]custodyRecord, 0, len(evidence))
+		for _, ev := range evidence {
+			entries, err := s.src.Custody.GetEntries(ctx, ev.ID)
+			if err != nil {
+				return fmt.Errorf("custody of %s: %w", ev.ID, err)
+			}
+			records = append(records, custodyRecord{EvidenceID: ev.ID.String(), Filename: ev.Filename, Entries: entries})
+		}
+		return writeJSON(w, records)
+	}); err != nil {
+		return err
+	}
+
+	if _, err := a.add(path.Join(timelineDir, "timeline.json"), CategoryTimeline, "", func(w io.Writer) error {
+		events, err := s.src.Timeline.ListEvents(c.ID)
+		if err != nil {
+			return err
+		}
+		return writeJSON(w, events)
+	}); err != nil {
+		return err
+	}
+
+	if _, err := a.add(path.Join(iocsDir, "iocs.json"), CategoryIOCs, "", func(w io.Writer) error {
+		iocs, err := s.src.IOCs.ListIOCsByCase(c.ID)
+		if err != nil {
+			return err
+		}
+		return writeJSON(w, iocs)
+	}); err != nil {
+		return err
+	}
+
+	// Messages and audit entries are written one JSON object per line as
+	// they are read.
+	if _, err := a.add(path.Join(chatDir, "chat_messages.jsonl"), CategoryChat, "", func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		return s.src.Chat.StreamCaseMessages(ctx, c.ID, func(g *chat.ChatGroup, m *chat.Message) error {
+			return enc.Encode(chatLine{GroupID: g.ID.Hex(), GroupName: g.Name, Message: m})
+		})
+	}); err != nil {
+		return err
+	}
+
+	targets := make([]string, 0, len(evidence)+len(reportIDs))
+	for _, ev := range evidence {
+		targets = append(targets, ev.ID.String())
+	}
+	targets = append(targets, reportIDs...)
+	_, err := a.add(path.Join(auditDir, "audit_log.jsonl"), CategoryAudit, "", func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		return s.src.Audit.StreamCaseLogs(ctx, auditlog.CaseLogQuery{CaseID: c.ID, TargetIDs: targets}, func(l auditlog.AuditLog) error {
+			return enc.Encode(l)
+		})
+	})
+	return err
+}
+
+func readme(c *CaseInfo, pkg *Package, at time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Case closure package\n\n")
+	fmt.Fprintf(&b, "Case:      %s (%s)\n", c.Title, c.ID)
+	fmt.Fprintf(&b, "Status:    %s\n", c.Status)
+	fmt.Fprintf(&b, "Package:   %s\n", pkg.ID)
+	fmt.Fprintf(&b, "Generated: %s\n\n", at.Format(time.RFC3339))
+	b.WriteString(`Contents
+
+  01_reports/           Case reports as PDF. Sealed reports are the sealed
+                        artifact with its .signature.json bundle.
+  02_evidence/          evidence_manifest.json lists every evidence item with
+                        its SHA-256 and log chain check.`)
+	if pkg.IncludeEvidence {
+		b.WriteString(`
+                        files/ holds the evidence files themselves.`)
+	}
+	b.WriteString(`
+  03_chain_of_custody/  Chain of custody entries per evidence item.
+  04_timeline/          Investigation timeline.
+  05_iocs/              Indicators of compromise.
+  06_communications/    Case chat messages, one JSON object per line.
+  07_audit/             Audit log entries, one JSON object per line.
+  manifest.json         Every file above with its size and SHA-256.
+  manifest.sig.json     Ed25519 signature over manifest.json.
+
+Verifying
+
+  1. Check each file's SHA-256 against manifest.json.
+  2. Check the signature over manifest.json exactly as stored, using
+     public_key_pem from manifest.sig.json, and compare key_fingerprint
+     with the key the organisation published.
+`)
+	return b.String()
+}
+
+func (s *service) ListPackages(actor Actor, caseID string) ([]Package, error) {
+	if _, err := s.caseInTenant(actor, caseID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListPackages(actor.TenantID, caseID)
+}
+
+func (s *service) Verify(actor Actor, manifest, signature []byte) (*Verification, error) {
+	var sig ManifestSignature
+	if err := json.Unmarshal(signature, &sig); err != nil || sig.Format != SignatureFormat {
+		return nil, ErrInvalidSignature
+	}
+	sum := sha256.Sum256(manifest)
+	out := &Verification{ManifestSHA256: hex.EncodeToString(sum[:])}
+	out.SignatureValid = out.ManifestSHA256 == sig.ManifestSHA256 &&
+		s.src.Signer.VerifyDetached(actor.TenantID, manifest, &sig.Detached) == nil
+
+	if _, err := uuid.Parse(sig.PackageID); err != nil {
+		return out, nil
+	}
+	pkg, err := s.repo.GetPackage(actor.TenantID, sig.PackageID)
+	if errors.Is(err, ErrPackageNotFound) {
+		return out, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	out.Package = pkg
+	out.Recorded = pkg.ManifestSHA256 == out.ManifestSHA256
+	return out, nil
+}
@@ -0,0 +1,78 @@
+package invitations
+
+import (
+	"context"
+	"time"
+
+	"aegis-api/middleware"
+	"aegis-api/services_/auth/authz"
+)
+
+type Repository interface {
+	AutoMigrate() error
+
+	CreateInvitation(inv *Invitation) error
+	SaveInvitation(inv *Invitation) error
+	// GetInvitation returns nil when the tenant has no such invitation.
+	GetInvitation(tenantID, id string) (*Invitation, error)
+	// GetByToken returns nil when no invitation has the token hash.
+	GetByToken(tokenHash string) (*Invitation, error)
+	// ListInvitations lists the tenant's invitations, of one case when
+	// caseID is set, newest first.
+	ListInvitations(tenantID, caseID string) ([]Invitation, error)
+	ListUserInvitations(userID string) ([]Invitation, error)
+	// OpenInvitation returns the pending or accepted invitation of the
+	// email to the case, or nil.
+	OpenInvitation(caseID, email string) (*Invitation, error)
+	// AcceptedInvitation returns the user's accepted invitation to the
+	// case, or nil.
+	AcceptedInvitation(caseID, userID string) (*Invitation, error)
+	// EndedInvitations lists pending and accepted invitations of every
+	// tenant whose EndsAt is at or before now.
+	EndedInvitations(now time.Time) ([]Invitation, error)
+
+	// CaseTenant returns the tenant of the case, or "" when there is no
+	// such case.
+	CaseTenant(caseID string) (string, error)
+	// CaseResources returns those of ids that are resources of the type
+	// on the case.
+	CaseResources(caseID, resourceType string, ids []string) ([]string, error)
+
+	// SaveCollaborator inserts or replaces the user's case_collaborators
+	// row.
+	SaveCollaborator(c *Collaborator) error
+	SetCollaboratorStatus(caseID, userID, status string) error
+	// ActivateStarted turns scheduled collaborators of accepted
+	// invitations that have started by now active.
+	ActivateStarted(now time.Time) (int64, error)
+
+	CreateEvent(e *AccessEvent) error
+	// ListEvents returns the invitation's events, oldest first.
+	ListEvents(invitationID string, limit int) ([]AccessEvent, error)
+}
+
+// Mailer sends the invitation link to the invitee.
+type Mailer interface {
+	SendInvitation(to string, inv *Invitation, link string) error
+}
+
+type Service interface {
+	// AuthorizeGuest implements authz.GuestPolicy: a collaborator with an
+	// accepted invitation is held to its dates, networks and resources.
+	// Each such request is recorded for the access report.
+	AuthorizeGuest(ctx context.Context, req middleware.AccessRequest, caseID string) (*authz.GuestDecision, error)
+
+	Invite(actor Actor, caseID string, in InvitationInput) (*Invitation, error)
+	ListInvitations(tenantID, caseID string) ([]Invitation, error)
+	Revoke(actor Actor, id string) (*Invitation, error)
+	AccessReport(tenantID, id string) (*AccessReport, error)
+
+	Accept(guest Guest, token string) (*Invitation, error)
+	ListMine(guest Guest) ([]Invitation, error)
+
+	// Sweep expires invitations that have ended and activates those that
+	// have started.
+	Sweep(now time.Time) error
+	// Start runs Sweep every interval until ctx is done.
+	Start(ctx context.Context, interval time.Duration)
+}
@@ -0,0 +1,256 @@
+package invitations_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"aegis-api/middleware"
+	"aegis-api/services_/case/invitations"
+	"aegis-api/tests/fakes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type fixture struct {
+	repo     *fakes.Invitations
+	mail     *fakes.Mailbox
+	svc      invitations.Service
+	admin    invitations.Actor
+	caseID   string
+	evidence []string
+	report   string
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	f := &fixture{
+		repo:     &fakes.Invitations{},
+		mail:     &fakes.Mailbox{},
+		admin:    invitations.Actor{UserID: uuid.NewString(), TenantID: uuid.NewString()},
+		caseID:   uuid.NewString(),
+		evidence: []string{uuid.NewString(), uuid.NewString()},
+		report:   uuid.NewString(),
+	}
+	f.repo.AddCase(f.admin.TenantID, f.caseID)
+	f.repo.Place(f.caseID, "evidence", f.evidence...)
+	f.repo.Place(f.caseID, "report", f.report)
+	f.svc = invitations.NewService(f.repo, f.mail, invitations.Options{})
+	return f
+}
+
+func (f *fixture) request(userID, resourceType, id, ip string) middleware.AccessRequest {
+	return middleware.AccessRequest{
+		UserID: userID, Role: invitations.CollaboratorRole, TenantID: f.admin.TenantID,
+		Action: "evidence:view", ResourceType: resourceType, ResourceID: id,
+		Method: "GET", Path: "/api/v1/test", IP: ip,
+	}
+}
+
+func TestInviteValidatesScope(t *testing.T) {
+	f := newFixture(t)
+	ends := time.Now().Add(48 * time.Hour)
+
+	_, err := f.svc.Invite(f.admin, f.caseID, invitations.InvitationInput{Email: "counsel@firm.example", EvidenceIDs: []string{uuid.NewString()}, EndsAt: ends})
+	require.ErrorIs(t, err, invitations.ErrInvalidInput)
+	_, err = f.svc.Invite(f.admin, f.caseID, invitations.InvitationInput{Email: "counsel@firm.example", EndsAt: ends})
+	require.ErrorIs(t, err, invitations.ErrInvalidInput)
+	_, err = f.svc.Invite(f.admin, f.caseID, invitations.InvitationInput{Email: "counsel@firm.example", ReportIDs: []string{f.report}, EndsAt: ends, IPAllowlist: []string{"not-an-ip"}})
+	require.ErrorIs(t, err, invitations.ErrInvalidInput)
+	_, err = f.svc.Invite(f.admin, f.caseID, invitations.InvitationInput{Email: "counsel@firm.example", ReportIDs: []string{f.report}, EndsAt: time.Now().Add(200 * 24 * time.Hour)})
+	require.ErrorIs(t, err, invitations.ErrInvalidInput)
+	_, err = f.svc.Invite(invitations.Actor{UserID: f.admin.UserID, TenantID: uuid.NewString()}, f.caseID, invitations.InvitationInput{Email: "counsel@firm.example", ReportIDs: []string{f.report}, EndsAt: ends})
+	require.ErrorIs(t, err, invitations.ErrNotFound)
+
+	inv, err := f.svc.Invite(f.admin, f.caseID, invitations.InvitationInput{
+		Email: "Counsel@Firm.example", EvidenceIDs: []string{f.evidence[0], strings.ToUpper(f.evidence[0])},
+		EndsAt: ends, IPAllowlist: []string{"203.0.113.7", "198.51.100.0/24"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "counsel@firm.example", inv.Email)
+	require.Equal(t, invitations.StatusPending, inv.Status)
+	require.True(t, inv.Watermark)
+	require.JSONEq(t, `["`+f.evidence[0]+`"]`, string(inv.EvidenceIDs))
+	require.JSONEq(t, `["203.0.113.7/32","198.51.100.0/24"]`, string(inv.IPAllowlist))
+	require.NotEmpty(t, f.mail.Token("counsel@firm.example"))
+
+	_, err = f.svc.Invite(f.admin, f.caseID, invitations.InvitationInput{Email: "counsel@firm.example", ReportIDs: []string{f.report}, EndsAt: ends})
+	require.ErrorIs(t, err, invitations.ErrConflict)
+
+	// A failed email withdraws the invitation so it can be resent
+	f.mail.Fail = true
+	_, err = f.svc.Invite(f.admin, f.caseID, invitations.InvitationInput{Email: "expert@lab.example", ReportIDs: []string{f.report}, EndsAt: ends})
+	require.ErrorIs(t, err, invitations.ErrDelivery)
+	list, err := f.svc.ListInvitations(f.admin.TenantID, f.caseID)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, invitations.StatusRevoked, list[1].Status)
+}
+
+func TestAcceptedInvitationScopesAccess(t *testing.T) {
+	f := newFixture(t)
+	inv, err := f.svc.Invite(f.admin, f.caseID, invitations.InvitationInput{
+		Email: "counsel@firm.example", EvidenceIDs: []string{f.evidence[0]}, ReportIDs: []string{f.report},
+		EndsAt: time.Now().Add(24 * time.Hour), IPAllowlist: []string{"203.0.113.0/24"},
+	})
+	require.NoError(t, err)
+	token := f.mail.Token("counsel@firm.example")
+	guest := invitations.Guest{UserID: uuid.NewString(), TenantID: f.admin.TenantID, Email: "counsel@firm.example"}
+
+	_, err = f.svc.Accept(invitations.Guest{UserID: uuid.NewString(), TenantID: f.admin.TenantID, Email: "someone@else.example"}, token)
+	require.ErrorIs(t, err, invitations.ErrForbidden)
+	_, err = f.svc.Accept(guest, "wrong")
+	require.ErrorIs(t, err, invitations.ErrNotFound)
+	accepted, err := f.svc.Accept(guest, token)
+	require.NoError(t, err)
+	require.Equal(t, invitations.StatusAccepted, accepted.Status)
+	c := f.repo.Collaborator(f.caseID, guest.UserID)
+	require.Equal(t, "active", c.Status)
+	require.Equal(t, invitations.CollaboratorRole, c.Role)
+	require.WithinDuration(t, inv.EndsAt, c.ExpiresAt, 0)
+
+	ctx := context.Background()
+	d, err := f.svc.AuthorizeGuest(ctx, f.request(guest.UserID, middleware.ResourceEvidence, f.evidence[0], "203.0.113.9"), f.caseID)
+	require.NoError(t, err)
+	require.True(t, d.Allowed)
+	require.Contains(t, d.Watermark, "counsel@firm.example")
+	d, _ = f.svc.AuthorizeGuest(ctx, f.request(guest.UserID, middleware.ResourceEvidence, f.evidence[0], "203.0.113.9"), f.caseID)
+	require.True(t, d.Allowed)
+	d, _ = f.svc.AuthorizeGuest(ctx, f.request(guest.UserID, middleware.ResourceReport, f.report, "203.0.113.9"), f.caseID)
+	require.True(t, d.Allowed)
+
+	d, _ = f.svc.AuthorizeGuest(ctx, f.request(guest.UserID, middleware.ResourceEvidence, f.evidence[1], "203.0.113.9"), f.caseID)
+	require.Equal(t, invitations.ReasonScope, d.Reason)
+	d, _ = f.svc.AuthorizeGuest(ctx, f.request(guest.UserID, middleware.ResourceCase, f.caseID, "203.0.113.9"), f.caseID)
+	require.Equal(t, invitations.ReasonScope, d.Reason)
+	d, _ = f.svc.AuthorizeGuest(ctx, f.request(guest.UserID, middleware.ResourceEvidence, f.evidence[0], "192.0.2.1"), f.caseID)
+	require.Equal(t, invitations.ReasonIP, d.Reason)
+
+	// Collaborators shared the case without an invitation are not narrowed
+	d, _ = f.svc.AuthorizeGuest(ctx, f.request(uuid.NewString(), middleware.ResourceCase, f.caseID, "192.0.2.1"), f.caseID)
+	require.True(t, d.Allowed)
+	require.Empty(t, d.Watermark)
+
+	report, err := f.svc.AccessReport(f.admin.TenantID, inv.ID)
+	require.NoError(t, err)
+	require.Len(t, report.Events, 6)
+	require.Equal(t, 3, report.Denied)
+	require.Len(t, report.Viewed, 2)
+	require.Equal(t, f.evidence[0], report.Viewed[0].ResourceID)
+	require.Equal(t, 2, report.Viewed[0].Count)
+
+	mine, err := f.svc.ListMine(guest)
+	require.NoError(t, err)
+	require.Len(t, mine, 1)
+}
+
+func TestSweepExpiresAndActivates(t *testing.T) {
+	f := newFixture(t)
+	starts := time.Now().Add(time.Hour)
+	inv, err := f.svc.Invite(f.admin, f.caseID, invitations.InvitationInput{
+		Email: "counsel@firm.example", ReportIDs: []string{f.report}, StartsAt: &starts, EndsAt: starts.Add(24 * time.Hour),
+	})
+	require.NoError(t, err)
+	guest := invitations.Guest{UserID: uuid.NewString(), TenantID: f.admin.TenantID, Email: "counsel@firm.example"}
+	_, err = f.svc.Accept(guest, f.mail.Token("counsel@firm.example"))
+	require.NoError(t, err)
+	require.Equal(t, "scheduled", f.repo.Collaborator(f.caseID, guest.UserID).Status)
+
+	d, err := f.svc.AuthorizeGuest(context.Background(), f.request(guest.UserID, middleware.ResourceReport, f.report, "10.0.0.1"), f.caseID)
+	require.NoError(t, err)
+	require.Equal(t, invitations.ReasonNotStarted, d.Reason)
+
+	require.NoError(t, f.svc.Sweep(time.Now()))
+	require.Equal(t, "scheduled", f.repo.Collaborator(f.caseID, guest.UserID).Status)
+	require.NoError(t, f.svc.Sweep(starts.Add(time.Minute)))
+	require.Equal(t, "active", f.repo.Collaborator(f.caseID, guest.UserID).Status)
+	require.NoError(t, f.svc.Sweep(starts.Add(25*time.Hour)))
+	require.Equal(t, "expired", f.repo.Collaborator(f.caseID, guest.UserID).Status)
+	got, err := f.svc.AccessReport(f.admin.TenantID, inv.ID)
+	require.NoError(t, err)
+	require.Equal(t, invitations.StatusExpired, got.Invitation.Status)
+
+	_, err = f.svc.Revoke(f.admin, inv.ID)
+	require.ErrorIs(t, err, invitations.ErrConflict)
+}
+
+func TestRevokeEndsCollaboration(t *testing.T) {
+	f := newFixture(t)
+	inv, err := f.svc.Invite(f.admin, f.caseID, invitations.InvitationInput{
+		Email: "counsel@firm.example", ReportIDs: []string{f.report}, EndsAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	guest := invitations.Guest{UserID: uuid.NewString(), TenantID: f.admin.TenantID, Email: "counsel@firm.example"}
+	_, err = f.svc.Accept(guest, f.mail.Token("counsel@firm.example"))
+	require.NoError(t, err)
+
+	revoked, err := f.svc.Revoke(f.admin, inv.ID)
+	require.NoError(t, err)
+	require.Equal(t, invitations.StatusRevoked, revoked.Status)
+	require.Equal(t, "revoked", f.repo.Collaborator(f.caseID, guest.UserID).Status)
+	_, err = f.svc.Accept(guest, f.mail.Token("counsel@firm.example"))
+	require.ErrorIs(t, err, invitations.ErrConflict)
+}
+
+// guestAuthorizer admits a request when AuthorizeGuest does.
+type guestAuthorizer struct {
+	f *fixture
+}
+
+func (a guestAuthorizer) AuthorizeCaseAccess(ctx context.Context, req middleware.AccessRequest) (*middleware.AccessDecision, error) {
+	g, err := a.f.svc.AuthorizeGuest(ctx, req, a.f.caseID)
+	if err != nil {
+		return nil, err
+	}
+	return &middleware.AccessDecision{Allowed: g.Allowed, CaseID: a.f.caseID, Reason: g.Reason}, nil
+}
+
+func TestAllowlistIgnoresSpoofedForwardedFor(t *testing.T) {
+	f := newFixture(t)
+	_, err := f.svc.Invite(f.admin, f.caseID, invitations.InvitationInput{
+		Email: "counsel@firm.example", EvidenceIDs: []string{f.evidence[0]},
+		EndsAt: time.Now().Add(24 * time.Hour), IPAllowlist: []string{"203.0.113.0/24"},
+	})
+	require.NoError(t, err)
+	guest := invitations.Guest{UserID: uuid.NewString(), TenantID: f.admin.TenantID, Email: "counsel@firm.example"}
+	_, err = f.svc.Accept(guest, f.mail.Token("counsel@firm.example"))
+	require.NoError(t, err)
+
+	middleware.SetCaseAuthorizer(guestAuthorizer{f})
+	t.Cleanup(func() { middleware.SetCaseAuthorizer(nil) })
+	gin.SetMode(gin.TestMode)
+	get := func(t *testing.T, remoteAddr, forwardedFor string) int {
+		t.Helper()
+		r := gin.New()
+		require.NoError(t, r.SetTrustedProxies(middleware.TrustedProxies()))
+		r.GET("/evidence/:id", func(c *gin.Context) {
+			c.Set("userID", guest.UserID)
+			c.Set("userRole", invitations.CollaboratorRole)
+			c.Set("tenantID", guest.TenantID)
+		}, middleware.RequireCaseAccess("evidence:view", middleware.ResourceEvidence, "id"), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		req := httptest.NewRequest(http.MethodGet, "/evidence/"+f.evidence[0], nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "")
+	require.Equal(t, http.StatusOK, get(t, "203.0.113.9:41000", ""))
+	require.Equal(t, http.StatusForbidden, get(t, "192.0.2.1:41000", "203.0.113.9"))
+
+	// Behind a configured proxy the forwarded address is the client's
+	t.Setenv("TRUSTED_PROXIES", "192.0.2.1, 10.0.0.0/8")
+	require.Equal(t, http.StatusOK, get(t, "192.0.2.1:41000", "203.0.113.9"))
+	require.Equal(t, http.StatusForbidden, get(t, "198.51.100.4:41000", "203.0.113.9"))
+}
@@ -0,0 +1,34 @@
+package invitations
+
+import (
+	"fmt"
+	"net/smtp"
+	"os"
+)
+
+// SMTPMailer sends invitations through the server configured by the
+// SMTP_* environment variables.
+type SMTPMailer struct{}
+
+func (SMTPMailer) SendInvitation(to string, inv *Invitation, link string) error {
+	subject := "AEGIS: You Have Been Invited to Collaborate on a Case"
+	body := fmt.Sprintf(`Hello,
+
+You have been invited to review material from a case on the AEGIS platform.
+
+Your access starts %s and ends %s (UTC). Accept the invitation here:
+%s
+
+Everything you open under this invitation is recorded and reported to the
+investigator who invited you. If this was not expected, please contact the admin.
+
+– The AEGIS Team`, inv.StartsAt.UTC().Format("2 Jan 2006 15:04"), inv.EndsAt.UTC().Format("2 Jan 2006 15:04"), link)
+
+	msg := fmt.Sprintf("From: %s\nTo: %s\nSubject: %s\n\n%s",
+		os.Getenv("SMTP_FROM"), to, subject, body)
+
+	auth := smtp.PlainAuth("", os.Getenv("SMTP_USER"), os.Getenv("SMTP_PASS"), os.Getenv("SMTP_HOST"))
+	addr := fmt.Sprintf("%s:%s", os.Getenv("SMTP_HOST"), os.Getenv("SMTP_PORT"))
+
+	return smtp.SendMail(addr, auth, os.Getenv("SMTP_FROM"), []string{to}, []byte(msg))
+}
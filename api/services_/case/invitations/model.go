@@ -0,0 +1,133 @@
+package invitations
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Invitation statuses. An accepted invitation ends as "expired" at
+// EndsAt, or as "revoked" when an administrator withdraws it.
+const (
+	StatusPending  = "pending"
+	StatusAccepted = "accepted"
+	StatusRevoked  = "revoked"
+	StatusExpired  = "expired"
+)
+
+// Reasons a collaborator's request is refused.
+const (
+	ReasonNotStarted = "invitation_not_started"
+	ReasonEnded      = "invitation_ended"
+	ReasonIP         = "ip_not_allowed"
+	ReasonScope      = "outside_invitation_scope"
+)
+
+// CollaboratorRole is the case role an accepted invitation grants.
+const CollaboratorRole = "External Collaborator"
+
+// Invitation grants an external collaborator access to some of a case's
+// evidence and reports between StartsAt and EndsAt, optionally only from
+// the networks in IPAllowlist.
+type Invitation struct {
+	ID        string  `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID  string  `gorm:"type:uuid;index;not null" json:"tenant_id"`
+	CaseID    string  `gorm:"type:uuid;index;not null" json:"case_id"`
+	InvitedBy string  `gorm:"type:uuid;not null" json:"invited_by"`
+	Email     string  `gorm:"not null" json:"email"`
+	UserID    *string `gorm:"type:uuid;index" json:"user_id,omitempty"`
+	// TokenHash is the SHA-256 of the emailed token.
+	TokenHash   string         `gorm:"uniqueIndex;not null" json:"-"`
+	EvidenceIDs datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'" json:"evidence_ids"` // []string
+	ReportIDs   datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'" json:"report_ids"`   // []string
+	IPAllowlist datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'" json:"ip_allowlist"` // []string CIDRs
+	// Watermark prints the invitation on every report the collaborator
+	// downloads. Evidence is served unaltered so its hashes still verify;
+	// its downloads show in the access report instead.
+	Watermark  bool       `gorm:"not null;default:true" json:"watermark"`
+	StartsAt   time.Time  `gorm:"not null" json:"starts_at"`
+	EndsAt     time.Time  `gorm:"index;not null" json:"ends_at"`
+	Status     string     `gorm:"size:20;index;not null" json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy  *string    `gorm:"type:uuid" json:"revoked_by,omitempty"`
+}
+
+func (Invitation) TableName() string { return "case_invitations" }
+
+// AccessEvent records a request a collaborator made under an accepted
+// invitation, whether or not it was allowed.
+type AccessEvent struct {
+	ID           string    `gorm:"type:uuid;primaryKey" json:"id"`
+	InvitationID string    `gorm:"type:uuid;index;not null" json:"invitation_id"`
+	TenantID     string    `gorm:"type:uuid;not null" json:"tenant_id"`
+	CaseID       string    `gorm:"type:uuid;not null" json:"case_id"`
+	UserID       string    `gorm:"type:uuid;not null" json:"user_id"`
+	Action       string    `json:"action"`
+	ResourceType string    `json:"resource_type"`
+	ResourceID   string    `json:"resource_id"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	IP           string    `json:"ip"`
+	Allowed      bool      `json:"allowed"`
+	Reason       string    `json:"reason,omitempty"`
+	CreatedAt    time.Time `gorm:"index" json:"created_at"`
+}
+
+func (AccessEvent) TableName() string { return "collaborator_access_events" }
+
+// Collaborator is the case_collaborators row an accepted invitation
+// keeps: "scheduled" before StartsAt, then "active" until it expires or
+// is revoked.
+type Collaborator struct {
+	CaseID    string
+	UserID    string
+	Role      string
+	InvitedBy string
+	ExpiresAt time.Time
+	Status    string
+}
+
+type InvitationInput struct {
+	Email       string     `json:"email"`
+	EvidenceIDs []string   `json:"evidence_ids"`
+	ReportIDs   []string   `json:"report_ids"`
+	StartsAt    *time.Time `json:"starts_at"`
+	EndsAt      time.Time  `json:"ends_at"`
+	IPAllowlist []string   `json:"ip_allowlist"`
+	// Watermark defaults to true.
+	Watermark *bool `json:"watermark"`
+}
+
+// Actor is the administrator managing invitations.
+type Actor struct {
+	UserID   string
+	TenantID string
+}
+
+// Guest is the external collaborator accepting or listing invitations.
+type Guest struct {
+	UserID   string
+	TenantID string
+	Email    string
+}
+
+// ViewedResource sums up a collaborator's allowed requests for one
+// resource.
+type ViewedResource struct {
+	ResourceType string    `json:"resource_type"`
+	ResourceID   string    `json:"resource_id"`
+	Actions      []string  `json:"actions"`
+	Count        int       `json:"count"`
+	FirstAt      time.Time `json:"first_at"`
+	LastAt       time.Time `json:"last_at"`
+}
+
+// AccessReport is everything a collaborator did under an invitation.
+type AccessReport struct {
+	Invitation *Invitation      `json:"invitation"`
+	Viewed     []ViewedResource `json:"viewed"`
+	Denied     int              `json:"denied"`
+	Events     []AccessEvent    `json:"events"`
+}
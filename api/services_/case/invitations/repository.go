@@ -0,0 +1,139 @@
+package invitations
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &GormRepository{db: db}
+}
+
+// case_collaborators predates this package and is managed by schema.sql.
+func (r *GormRepository) AutoMigrate() error {
+	return r.db.AutoMigrate(&Invitation{}, &AccessEvent{})
+}
+
+func first[T any](q *gorm.DB) (*T, error) {
+	var v T
+	err := q.First(&v).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *GormRepository) CreateInvitation(inv *Invitation) error {
+	return r.db.Create(inv).Error
+}
+
+func (r *GormRepository) SaveInvitation(inv *Invitation) error {
+	return r.db.Save(inv).Error
+}
+
+func (r *GormRepository) GetInvitation(tenantID, id string) (*Invitation, error) {
+	return first[Invitation](r.db.Where("tenant_id = ? AND id = ?", tenantID, id))
+}
+
+func (r *GormRepository) GetByToken(tokenHash string) (*Invitation, error) {
+	return first[Invitation](r.db.Where("token_hash = ?", tokenHash))
+}
+
+func (r *GormRepository) ListInvitations(tenantID, caseID string) ([]Invitation, error) {
+	q := r.db.Where("tenant_id = ?", tenantID)
+	if caseID != "" {
+		q = q.Where("case_id = ?", caseID)
+	}
+	var out []Invitation
+	err := q.Order("created_at DESC").Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) ListUserInvitations(userID string) ([]Invitation, error) {
+	var out []Invitation
+	err := r.db.Where("user_id = ?", userID).Order("starts_at DESC").Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) OpenInvitation(caseID, email string) (*Invitation, error) {
+	return first[Invitation](r.db.Where("case_id = ? AND lower(email) = lower(?) AND status IN ?",
+		caseID, email, []string{StatusPending, StatusAccepted}))
+}
+
+func (r *GormRepository) AcceptedInvitation(caseID, userID string) (*Invitation, error) {
+	return first[Invitation](r.db.Where("case_id = ? AND user_id = ? AND status = ?", caseID, userID, StatusAccepted))
+}
+
+func (r *GormRepository) EndedInvitations(now time.Time) ([]Invitation, error) {
+	var out []Invitation
+	err := r.db.Where("status IN ? AND ends_at <= ?", []string{StatusPending, StatusAccepted}, now).
+		Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) CaseTenant(caseID string) (string, error) {
+	var tenants []string
+	if err := r.db.Raw("SELECT tenant_id::text FROM cases WHERE id = ?", caseID).Scan(&tenants).Error; err != nil || len(tenants) == 0 {
+		return "", err
+	}
+	return tenants[0], nil
+}
+
+// caseResources maps resource types to the query filtering ids by case.
+var caseResources = map[string]string{
+	"evidence": "SELECT id::text FROM evidence WHERE case_id = ? AND id IN ?",
+	"report":   "SELECT id::text FROM reports WHERE case_id = ? AND id IN ?",
+}
+
+func (r *GormRepository) CaseResources(caseID, resourceType string, ids []string) ([]string, error) {
+	var out []string
+	query, ok := caseResources[resourceType]
+	if !ok || len(ids) == 0 {
+		return out, nil
+	}
+	err := r.db.Raw(query, caseID, ids).Scan(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) SaveCollaborator(c *Collaborator) error {
+	return r.db.Exec(`
+		INSERT INTO case_collaborators (case_id, user_id, role, invited_by, invited_at, expires_at, status)
+		VALUES (?, ?, ?, ?, NOW(), ?, ?)
+		ON CONFLICT (case_id, user_id) DO UPDATE
+		SET role = EXCLUDED.role, invited_by = EXCLUDED.invited_by, invited_at = EXCLUDED.invited_at,
+		    expires_at = EXCLUDED.expires_at, status = EXCLUDED.status`,
+		c.CaseID, c.UserID, c.Role, c.InvitedBy, c.ExpiresAt, c.Status).Error
+}
+
+func (r *GormRepository) SetCollaboratorStatus(caseID, userID, status string) error {
+	return r.db.Exec("UPDATE case_collaborators SET status = ? WHERE case_id = ? AND user_id = ?",
+		status, caseID, userID).Error
+}
+
+func (r *GormRepository) ActivateStarted(now time.Time) (int64, error) {
+	res := r.db.Exec(`
+		UPDATE case_collaborators cc SET status = 'active'
+		FROM case_invitations i
+		WHERE cc.status = 'scheduled' AND i.status = ? AND i.case_id = cc.case_id AND i.user_id = cc.user_id
+		  AND i.starts_at <= ? AND i.ends_at > ?`, StatusAccepted, now, now)
+	return res.RowsAffected, res.Error
+}
+
+func (r *GormRepository) CreateEvent(e *AccessEvent) error {
+	return r.db.Create(e).Error
+}
+
+func (r *GormRepository) ListEvents(invitationID string, limit int) ([]AccessEvent, error) {
+	var out []AccessEvent
+	err := r.db.Where("invitation_id = ?", invitationID).Order("created_at").Limit(limit).Find(&out).Error
+	return out, err
+}
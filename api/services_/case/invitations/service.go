@@ -0,0 +1,488 @@
+package invitations
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"aegis-api/middleware"
+	"aegis-api/services_/auth/authz"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+var (
+	ErrNotFound     = errors.New("invitation not found")
+	ErrInvalidInput = errors.New("invalid input")
+	ErrConflict     = errors.New("conflict")
+	ErrForbidden    = errors.New("forbidden")
+	// ErrDelivery is returned when the invitation email could not be
+	// sent; the invitation is withdrawn so it can be sent again.
+	ErrDelivery = errors.New("invitation email could not be sent")
+)
+
+type Options struct {
+	// AcceptURL is the page the emailed link opens; the token is added
+	// as the "token" query parameter.
+	// Default: https://capstone-aegis.dns.net.za/accept-invitation
+	AcceptURL string
+	// MaxDuration caps how long an invitation may last. Default: 90 days.
+	MaxDuration time.Duration
+	// MaxEvents caps the events of an access report. Default: 5000.
+	MaxEvents int
+}
+
+type service struct {
+	repo   Repository
+	mailer Mailer
+	opts   Options
+	now    func() time.Time
+}
+
+func NewService(repo Repository, mailer Mailer, opts Options) Service {
+	if opts.AcceptURL == "" {
+		opts.AcceptURL = "https://capstone-aegis.dns.net.za/accept-invitation"
+	}
+	if opts.MaxDuration <= 0 {
+		opts.MaxDuration = 90 * 24 * time.Hour
+	}
+	if opts.MaxEvents <= 0 {
+		opts.MaxEvents = 5000
+	}
+	return &service{repo: repo, mailer: mailer, opts: opts, now: time.Now}
+}
+
+func invalid(format string, a ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidInput, fmt.Sprintf(format, a...))
+}
+
+func jsonOf(v interface{}) datatypes.JSON {
+	raw, _ := json.Marshal(v)
+	return datatypes.JSON(raw)
+}
+
+func stringList(raw datatypes.JSON) []string {
+	var out []string
+	_ = json.Unmarshal(raw, &out)
+	return out
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// watermarkOf is printed on reports downloaded under the invitation.
+func watermarkOf(inv *Invitation) string {
+	return fmt.Sprintf("Shared with %s - %s - until %s", inv.Email, inv.ID[:8], inv.EndsAt.UTC().Format("2006-01-02"))
+}
+
+// ─── Invitations ───────────────────────────────────
+
+// scopeIDs checks that ids are resources of the type on the case and
+// returns them deduplicated and lower-cased.
+func (s *service) scopeIDs(caseID, resourceType string, ids []string) ([]string, error) {
+	out := []string{}
+	for _, id := range ids {
+		u, err := uuid.Parse(strings.TrimSpace(id))
+		if err != nil {
+			return nil, invalid("%q is not a UUID", id)
+		}
+		if !slices.Contains(out, u.String()) {
+			out = append(out, u.String())
+		}
+	}
+	found, err := s.repo.CaseResources(caseID, resourceType, out)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range out {
+		if !slices.Contains(found, id) {
+			return nil, invalid("%s %s is not on the case", resourceType, id)
+		}
+	}
+	return out, nil
+}
+
+// allowlist parses CIDRs and single addresses into prefixes.
+func allowlist(entries []string) ([]string, error) {
+	out := []string{}
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		p, err := netip.ParsePrefix(e)
+		if err != nil {
+			addr, aerr := netip.ParseAddr(e)
+			if aerr != nil {
+				return nil, invalid("%q is not an IP address or CIDR", e)
+			}
+			p = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		if s := p.Masked().String(); !slices.Contains(out, s) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func ipAllowed(prefixes []string, ip string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	return slices.ContainsFunc(prefixes, func(p string) bool {
+		prefix, err := netip.ParsePrefix(p)
+		return err == nil && prefix.Contains(addr)
+	})
+}
+
+func (s *service) Invite(actor Actor, caseID string, in InvitationInput) (*Invitation, error) {
+	if _, err := uuid.Parse(caseID); err != nil {
+		return nil, ErrNotFound
+	}
+	tenant, err := s.repo.CaseTenant(caseID)
+	if err != nil {
+		return nil, err
+	}
+	if tenant != actor.TenantID {
+		return nil, fmt.Errorf("%w: no such case", ErrNotFound)
+	}
+	addr, err := mail.ParseAddress(strings.TrimSpace(in.Email))
+	if err != nil {
+		return nil, invalid("email is not a valid address")
+	}
+	email := strings.ToLower(addr.Address)
+
+	evidence, err := s.scopeIDs(caseID, middleware.ResourceEvidence, in.EvidenceIDs)
+	if err != nil {
+		return nil, err
+	}
+	reports, err := s.scopeIDs(caseID, middleware.ResourceReport, in.ReportIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(evidence) == 0 && len(reports) == 0 {
+		return nil, invalid("an invitation must share at least one evidence item or report")
+	}
+	ips, err := allowlist(in.IPAllowlist)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	starts := now
+	if in.StartsAt != nil && in.StartsAt.After(now) {
+		starts = in.StartsAt.UTC()
+	}
+	ends := in.EndsAt.UTC()
+	switch {
+	case !ends.After(starts):
+		return nil, invalid("ends_at must be after starts_at and in the future")
+	case ends.Sub(starts) > s.opts.MaxDuration:
+		return nil, invalid("an invitation may last at most %d days", int(s.opts.MaxDuration.Hours()/24))
+	}
+
+	open, err := s.repo.OpenInvitation(caseID, email)
+	if err != nil {
+		return nil, err
+	}
+	if open != nil {
+		return nil, fmt.Errorf("%w: %s already has an open invitation to the case", ErrConflict, email)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+	inv := &Invitation{
+		ID:          uuid.NewString(),
+		TenantID:    actor.TenantID,
+		CaseID:      strings.ToLower(caseID),
+		InvitedBy:   actor.UserID,
+		Email:       email,
+		TokenHash:   hashToken(token),
+		EvidenceIDs: jsonOf(evidence),
+		ReportIDs:   jsonOf(reports),
+		IPAllowlist: jsonOf(ips),
+		Watermark:   in.Watermark == nil || *in.Watermark,
+		StartsAt:    starts,
+		EndsAt:      ends,
+		Status:      StatusPending,
+		CreatedAt:   now,
+	}
+	if err := s.repo.CreateInvitation(inv); err != nil {
+		return nil, err
+	}
+
+	link := s.opts.AcceptURL + "?token=" + url.QueryEscape(token)
+	if err := s.mailer.SendInvitation(email, inv, link); err != nil {
+		log.Printf("[invitations] sending invitation %s: %v", inv.ID, err)
+		inv.Status, inv.RevokedAt, inv.RevokedBy = StatusRevoked, &now, &actor.UserID
+		if err := s.repo.SaveInvitation(inv); err != nil {
+			return nil, err
+		}
+		return nil, ErrDelivery
+	}
+	return inv, nil
+}
+
+func (s *service) ListInvitations(tenantID, caseID string) ([]Invitation, error) {
+	if _, err := uuid.Parse(caseID); caseID != "" && err != nil {
+		return nil, invalid("%q is not a UUID", caseID)
+	}
+	return s.repo.ListInvitations(tenantID, caseID)
+}
+
+func (s *service) get(tenantID, id string) (*Invitation, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+	inv, err := s.repo.GetInvitation(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if inv == nil {
+		return nil, ErrNotFound
+	}
+	return inv, nil
+}
+
+func (s *service) Revoke(actor Actor, id string) (*Invitation, error) {
+	inv, err := s.get(actor.TenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if inv.Status != StatusPending && inv.Status != StatusAccepted {
+		return nil, fmt.Errorf("%w: the invitation is already %s", ErrConflict, inv.Status)
+	}
+	accepted := inv.Status == StatusAccepted
+	now := s.now().UTC()
+	inv.Status, inv.RevokedAt, inv.RevokedBy = StatusRevoked, &now, &actor.UserID
+	if err := s.repo.SaveInvitation(inv); err != nil {
+		return nil, err
+	}
+	if accepted {
+		if err := s.repo.SetCollaboratorStatus(inv.CaseID, *inv.UserID, "revoked"); err != nil {
+			return nil, err
+		}
+	}
+	return inv, nil
+}
+
+// ─── Invitees ──────────────────────────────────────
+
+func (s *service) Accept(guest Guest, token string) (*Invitation, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, invalid("token is required")
+	}
+	inv, err := s.repo.GetByToken(hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if inv == nil || inv.TenantID != guest.TenantID {
+		return nil, ErrNotFound
+	}
+	if !strings.EqualFold(inv.Email, guest.Email) {
+		return nil, fmt.Errorf("%w: the invitation was sent to another address", ErrForbidden)
+	}
+	now := s.now().UTC()
+	switch {
+	case inv.Status == StatusAccepted && inv.UserID != nil && *inv.UserID == guest.UserID:
+		return inv, nil
+	case inv.Status != StatusPending:
+		return nil, fmt.Errorf("%w: the invitation is %s", ErrConflict, inv.Status)
+	case !now.Before(inv.EndsAt):
+		return nil, fmt.Errorf("%w: the invitation has ended", ErrConflict)
+	}
+
+	inv.UserID, inv.Status, inv.AcceptedAt = &guest.UserID, StatusAccepted, &now
+	if err := s.repo.SaveInvitation(inv); err != nil {
+		return nil, err
+	}
+	status := "active"
+	if now.Before(inv.StartsAt) {
+		status = "scheduled"
+	}
+	err = s.repo.SaveCollaborator(&Collaborator{
+		CaseID:    inv.CaseID,
+		UserID:    guest.UserID,
+		Role:      CollaboratorRole,
+		InvitedBy: inv.InvitedBy,
+		ExpiresAt: inv.EndsAt,
+		Status:    status,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+func (s *service) ListMine(guest Guest) ([]Invitation, error) {
+	all, err := s.repo.ListUserInvitations(guest.UserID)
+	if err != nil {
+		return nil, err
+	}
+	out := []Invitation{}
+	for _, inv := range all {
+		if inv.TenantID == guest.TenantID {
+			out = append(out, inv)
+		}
+	}
+	return out, nil
+}
+
+// ─── Enforcement ───────────────────────────────────
+
+func (s *service) AuthorizeGuest(ctx context.Context, req middleware.AccessRequest, caseID string) (*authz.GuestDecision, error) {
+	inv, err := s.repo.AcceptedInvitation(caseID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	// Shares made before invitations are held to their case role alone.
+	if inv == nil {
+		return &authz.GuestDecision{Allowed: true}, nil
+	}
+	d := &authz.GuestDecision{}
+	now := s.now()
+	id := strings.ToLower(req.ResourceID)
+	switch {
+	case now.Before(inv.StartsAt):
+		d.Reason = ReasonNotStarted
+	case !now.Before(inv.EndsAt):
+		d.Reason = ReasonEnded
+	case !ipAllowed(stringList(inv.IPAllowlist), req.IP):
+		d.Reason = ReasonIP
+	case req.ResourceType == middleware.ResourceEvidence && slices.Contains(stringList(inv.EvidenceIDs), id),
+		req.ResourceType == middleware.ResourceReport && slices.Contains(stringList(inv.ReportIDs), id):
+		d.Allowed = true
+	default:
+		d.Reason = ReasonScope
+	}
+	if d.Allowed && inv.Watermark {
+		d.Watermark = watermarkOf(inv)
+	}
+
+	// A failed write must not turn a request into an error response.
+	err = s.repo.CreateEvent(&AccessEvent{
+		ID:           uuid.NewString(),
+		InvitationID: inv.ID,
+		TenantID:     inv.TenantID,
+		CaseID:       inv.CaseID,
+		UserID:       req.UserID,
+		Action:       req.Action,
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceID,
+		Method:       req.Method,
+		Path:         req.Path,
+		IP:           req.IP,
+		Allowed:      d.Allowed,
+		Reason:       d.Reason,
+		CreatedAt:    now.UTC(),
+	})
+	if err != nil {
+		log.Printf("[invitations] recording access by %s under %s: %v", req.UserID, inv.ID, err)
+	}
+	return d, nil
+}
+
+func (s *service) AccessReport(tenantID, id string) (*AccessReport, error) {
+	inv, err := s.get(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.repo.ListEvents(inv.ID, s.opts.MaxEvents)
+	if err != nil {
+		return nil, err
+	}
+	report := &AccessReport{Invitation: inv, Viewed: []ViewedResource{}, Events: events}
+	index := map[string]int{}
+	for _, e := range events {
+		if !e.Allowed {
+			report.Denied++
+			continue
+		}
+		key := e.ResourceType + "/" + strings.ToLower(e.ResourceID)
+		i, ok := index[key]
+		if !ok {
+			i = len(report.Viewed)
+			index[key] = i
+			report.Viewed = append(report.Viewed, ViewedResource{
+				ResourceType: e.ResourceType,
+				ResourceID:   e.ResourceID,
+				Actions:      []string{},
+				FirstAt:      e.CreatedAt,
+			})
+		}
+		v := &report.Viewed[i]
+		v.Count++
+		v.LastAt = e.CreatedAt
+		if !slices.Contains(v.Actions, e.Action) {
+			v.Actions = append(v.Actions, e.Action)
+		}
+	}
+	return report, nil
+}
+
+// ─── Expiry ────────────────────────────────────────
+
+func (s *service) Sweep(now time.Time) error {
+	ended, err := s.repo.EndedInvitations(now.UTC())
+	if err != nil {
+		return err
+	}
+	for i := range ended {
+		inv := &ended[i]
+		inv.Status = StatusExpired
+		if err := s.repo.SaveInvitation(inv); err != nil {
+			return err
+		}
+		if inv.UserID != nil {
+			if err := s.repo.SetCollaboratorStatus(inv.CaseID, *inv.UserID, "expired"); err != nil {
+				return err
+			}
+		}
+	}
+	started, err := s.repo.ActivateStarted(now.UTC())
+	if err != nil {
+		return err
+	}
+	if len(ended) > 0 || started > 0 {
+		log.Printf("[invitations] expired %d invitations, activated %d collaborators", len(ended), started)
+	}
+	return nil
+}
+
+func (s *service) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-t.C:
+				if err := s.Sweep(now); err != nil {
+					log.Printf("[invitations] expiry sweep: %v", err)
+				}
+			}
+		}
+	}()
+}
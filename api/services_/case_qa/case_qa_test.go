@@ -0,0 +1,236 @@
+package case_qa_test
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"aegis-api/services_/annotation_threads/messages"
+	annotationthreads "aegis-api/services_/annotation_threads/threads"
+	"aegis-api/services_/case_qa"
+	"aegis-api/services_/evidence/metadata"
+	"aegis-api/services_/llm"
+	"aegis-api/services_/report"
+	"aegis-api/services_/timeline"
+	"aegis-api/tests/fakes"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// citingProvider answers by citing every source whose text contains one of
+// the keywords, the way a well-behaved model would.
+type citingProvider struct {
+	*llm.FakeProvider
+	keywords []string
+	prompts  []string
+}
+
+var sourceHeader = regexp.MustCompile(`(?m)^\[S(\d+)\]`)
+
+func (p *citingProvider) Complete(_ context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	p.prompts = append(p.prompts, req.Prompt)
+	blocks := sourceHeader.Split(req.Prompt, -1)[1:]
+	ids := sourceHeader.FindAllStringSubmatch(req.Prompt, -1)
+	var refs []string
+	for i, b := range blocks {
+		for _, k := range p.keywords {
+			if strings.Contains(strings.ToLower(b), k) {
+				refs = append(refs, "S"+ids[i][1])
+				break
+			}
+		}
+	}
+	return &llm.CompletionResponse{Text: "Findings [" + strings.Join(refs, ", ") + "]", Provider: "fake", Model: "fake-model"}, nil
+}
+
+type fixture struct {
+	svc      case_qa.Service
+	repo     *fakes.QAIndex
+	policy   *fakes.Policy
+	provider *citingProvider
+	who      case_qa.Requester
+	caseID   string
+
+	evidenceID uuid.UUID
+	eventID    string
+	messageID  uuid.UUID
+	sectionID  primitive.ObjectID
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	tenant, other := uuid.New(), uuid.New()
+	caseID, user := uuid.New(), uuid.New()
+
+	repo := &fakes.QAIndex{}
+	repo.AddCase(tenant.String(), caseID.String(), user.String())
+
+	f := &fixture{
+		repo:       repo,
+		policy:     &fakes.Policy{},
+		who:        case_qa.Requester{TenantID: tenant.String(), UserID: user.String()},
+		caseID:     caseID.String(),
+		evidenceID: uuid.New(),
+		eventID:    uuid.NewString(),
+		messageID:  uuid.New(),
+		sectionID:  primitive.NewObjectID(),
+	}
+
+	evidence := &fakes.Evidence{Items: []metadata.Evidence{
+		{ID: f.evidenceID, CaseID: caseID, TenantID: tenant, Filename: "auth.log", FileType: "text/plain", IpfsCID: "cid-1",
+			Checksum: "abc"},
+		// Same case ID but a foreign tenant: must never be indexed.
+		{ID: uuid.New(), CaseID: caseID, TenantID: other, Filename: "secret.txt", FileType: "text/plain", IpfsCID: "cid-2"},
+	}}
+	blobs := fakes.Blobs{
+		"cid-1": []byte("Accepted password for root from 203.0.113.9 port 51234 ssh2\nsession opened for user root"),
+		"cid-2": []byte("other tenant payroll database credentials mimikatz"),
+	}
+	tags, _ := json.Marshal([]string{"credential-access"})
+	tl := &fakes.Timeline{Events: []*timeline.TimelineEvent{
+		{ID: f.eventID, CaseID: f.caseID, Description: "mimikatz executed on WS-042 to dump lsass memory", Severity: "critical", AnalystName: "Sam",
+			CreatedAt: time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC), Tags: tags},
+	}}
+	threadID := uuid.New()
+	threads := &fakes.Threads{
+		Threads: []annotationthreads.AnnotationThread{{ID: threadID, CaseID: caseID, Title: "Lateral movement"}},
+		Messages: map[uuid.UUID][]messages.ThreadMessage{
+			threadID: {{ID: f.messageID, ThreadID: threadID, Message: "I think the mimikatz binary arrived over SMB from the file server"}},
+		},
+	}
+	reportID := uuid.New()
+	reports := &fakes.Reports{}
+	reports.Add(&report.Report{ID: reportID, CaseID: caseID, TenantID: tenant},
+		report.ReportSection{ID: f.sectionID, Title: "Scope", Content: "<p>The engagement covers <b>three</b> workstations.</p>"})
+
+	f.provider = &citingProvider{FakeProvider: llm.NewFakeProvider(nil), keywords: []string{"mimikatz"}}
+	f.svc = case_qa.NewService(repo, evidence, blobs, tl, threads, threads, reports, llm.NewHashEmbedder(0), f.provider, f.policy)
+	return f
+}
+
+// ─── tests ────────────────────────────────────────
+
+func TestIndexCase_CollectsAllSourcesWithinTenant(t *testing.T) {
+	f := newFixture(t)
+
+	rep, err := f.svc.IndexCase(context.Background(), f.who, f.caseID)
+	require.NoError(t, err)
+	require.Equal(t, 1, rep.BySource[case_qa.SourceEvidence])
+	require.Equal(t, 1, rep.BySource[case_qa.SourceTimelineEvent])
+	require.Equal(t, 1, rep.BySource[case_qa.SourceThreadMessage])
+	require.Equal(t, 1, rep.BySource[case_qa.SourceReportSection])
+	require.Equal(t, 4, rep.Embedded)
+	require.Equal(t, "hash/hash-1024", rep.Embedder)
+
+	chunks, _ := f.repo.ListChunks(f.who.TenantID, f.caseID)
+	for _, c := range chunks {
+		require.NotContains(t, c.Content, "payroll", "foreign-tenant evidence must not be indexed")
+		if c.SourceType == case_qa.SourceReportSection {
+			require.Equal(t, "The engagement covers three workstations.", c.Content)
+		}
+		if c.SourceType == case_qa.SourceEvidence {
+			require.Contains(t, c.Content, "203.0.113.9")
+		}
+	}
+
+	// Re-indexing unchanged material reuses every stored vector.
+	rep, err = f.svc.IndexCase(context.Background(), f.who, f.caseID)
+	require.NoError(t, err)
+	require.Equal(t, 0, rep.Embedded)
+}
+
+func TestAsk_CitesEvidenceEventsAndMessages(t *testing.T) {
+	f := newFixture(t)
+
+	ans, err := f.svc.Ask(context.Background(), f.who, f.caseID, "Where did mimikatz run and how did it arrive?")
+	require.NoError(t, err)
+	require.Len(t, f.provider.prompts, 1)
+	require.NotContains(t, f.provider.prompts[0], "payroll")
+
+	cited := map[case_qa.SourceType]string{}
+	for _, c := range ans.Citations {
+		cited[c.SourceType] = c.SourceID
+	}
+	require.Equal(t, f.eventID, cited[case_qa.SourceTimelineEvent])
+	require.Equal(t, f.messageID.String(), cited[case_qa.SourceThreadMessage])
+	require.NotEmpty(t, ans.Sources)
+	require.NotEmpty(t, ans.ExchangeID)
+
+	require.Len(t, f.repo.Exchanges, 1)
+	ex := f.repo.Exchanges[0]
+	require.Equal(t, f.who.UserID, ex.UserID)
+	var stored []case_qa.Citation
+	require.NoError(t, json.Unmarshal(ex.Citations, &stored))
+	require.Len(t, stored, len(ans.Citations))
+
+	f.provider.keywords = []string{"203.0.113.9"}
+	ans, err = f.svc.Ask(context.Background(), f.who, f.caseID, "Which IP logged in as root over ssh?")
+	require.NoError(t, err)
+	require.NotEmpty(t, ans.Citations)
+	require.Equal(t, case_qa.SourceEvidence, ans.Citations[0].SourceType)
+	require.Equal(t, f.evidenceID.String(), ans.Citations[0].SourceID)
+}
+
+func TestAsk_EnforcesMembershipAndTenant(t *testing.T) {
+	f := newFixture(t)
+
+	outsider := f.who
+	outsider.UserID = uuid.NewString()
+	_, err := f.svc.Ask(context.Background(), outsider, f.caseID, "what happened?")
+	require.ErrorIs(t, err, case_qa.ErrNotCaseMember)
+
+	otherTenant := f.who
+	otherTenant.TenantID = uuid.NewString()
+	_, err = f.svc.Ask(context.Background(), otherTenant, f.caseID, "what happened?")
+	require.ErrorIs(t, err, case_qa.ErrCaseNotFound)
+
+	_, err = f.svc.IndexCase(context.Background(), outsider, f.caseID)
+	require.ErrorIs(t, err, case_qa.ErrNotCaseMember)
+	_, err = f.svc.ListExchanges(context.Background(), otherTenant, f.caseID, 10)
+	require.ErrorIs(t, err, case_qa.ErrCaseNotFound)
+
+	require.Empty(t, f.provider.prompts, "denied requests must not reach the model")
+	require.Empty(t, f.repo.Chunks)
+
+	_, err = f.svc.Ask(context.Background(), f.who, f.caseID, "   ")
+	require.ErrorIs(t, err, case_qa.ErrEmptyQuestion)
+}
+
+func TestAsk_WithholdsClassifiedEvidence(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	f.provider.keywords = []string{"203.0.113.9"}
+	_, err := f.svc.Ask(ctx, f.who, f.caseID, "Which IP logged in as root over ssh?")
+	require.NoError(t, err)
+
+	// Once the evidence is labelled beyond the caller's clearance it is
+	// neither quoted to the model nor shown in earlier exchanges.
+	f.policy.Refuse(f.evidenceID.String())
+	ans, err := f.svc.Ask(ctx, f.who, f.caseID, "Which IP logged in as root over ssh?")
+	require.NoError(t, err)
+	for _, p := range f.provider.prompts[1:] {
+		require.NotContains(t, p, "203.0.113.9")
+	}
+	for _, s := range ans.Sources {
+		require.NotEqual(t, case_qa.SourceEvidence, s.SourceType)
+	}
+	exchanges, err := f.svc.ListExchanges(ctx, f.who, f.caseID, 10)
+	require.NoError(t, err)
+	require.Len(t, exchanges, 1)
+	require.Equal(t, ans.ExchangeID, exchanges[0].ID)
+}
+
+func TestAsk_NoRelevantMaterialSkipsModel(t *testing.T) {
+	f := newFixture(t)
+
+	ans, err := f.svc.Ask(context.Background(), f.who, f.caseID, "What colour was the getaway bicycle painted yesterday afternoon?")
+	require.NoError(t, err)
+	require.Empty(t, ans.Citations)
+	require.Empty(t, f.provider.prompts)
+	require.Len(t, f.repo.Exchanges, 1)
+}
@@ -0,0 +1,95 @@
+package case_qa
+
+import (
+	"encoding/binary"
+	"html"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	chunkWords   = 200
+	chunkOverlap = 40
+)
+
+// chunkText splits text into overlapping windows of words so that a passage
+// is never cut off from its immediate context.
+func chunkText(text string) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return nil
+	}
+	if len(words) <= chunkWords {
+		return []string{strings.Join(words, " ")}
+	}
+	var out []string
+	for start := 0; start < len(words); start += chunkWords - chunkOverlap {
+		end := start + chunkWords
+		if end > len(words) {
+			end = len(words)
+		}
+		out = append(out, strings.Join(words[start:end], " "))
+		if end == len(words) {
+			break
+		}
+	}
+	return out
+}
+
+var (
+	htmlTagRe   = regexp.MustCompile(`(?s)<(script|style)[^>]*>.*?</(script|style)>|<[^>]*>`)
+	citationRe  = regexp.MustCompile(`\[((?:S\d+)(?:\s*,\s*S\d+)*)\]`)
+	citationRef = regexp.MustCompile(`S(\d+)`)
+)
+
+// stripHTML reduces report section HTML to plain text for indexing.
+func stripHTML(s string) string {
+	s = htmlTagRe.ReplaceAllString(s, " ")
+	return strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+}
+
+// citedRefs returns the source numbers referenced as [S1] or [S1, S3] in
+// answer, in order of first appearance.
+func citedRefs(answer string) []int {
+	seen := map[int]bool{}
+	var out []int
+	for _, m := range citationRe.FindAllStringSubmatch(answer, -1) {
+		for _, ref := range citationRef.FindAllStringSubmatch(m[1], -1) {
+			n, err := strconv.Atoi(ref[1])
+			if err != nil {
+				continue
+			}
+			if !seen[n] {
+				seen[n] = true
+				out = append(out, n)
+			}
+		}
+	}
+	return out
+}
+
+func encodeVector(v []float32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(f))
+	}
+	return b
+}
+
+func decodeVector(b []byte) []float32 {
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v
+}
+
+func excerpt(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
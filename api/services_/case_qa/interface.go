@@ -0,0 +1,77 @@
+package case_qa
+
+import (
+	"context"
+	"io"
+
+	"aegis-api/services_/annotation_threads/messages"
+	annotationthreads "aegis-api/services_/annotation_threads/threads"
+	"aegis-api/services_/auth/abac"
+	"aegis-api/services_/evidence/metadata"
+	"aegis-api/services_/report"
+	"aegis-api/services_/timeline"
+
+	"github.com/google/uuid"
+)
+
+type Repository interface {
+	AutoMigrate() error
+	// CaseInTenant reports whether caseID exists and belongs to tenantID.
+	CaseInTenant(tenantID, caseID string) (bool, error)
+	// IsCaseMember reports whether userID created the case, is assigned to it,
+	// or is an active, unexpired collaborator.
+	IsCaseMember(caseID, userID string) (bool, error)
+	// ReplaceChunks atomically swaps the case's index for chunks.
+	ReplaceChunks(tenantID, caseID string, chunks []*Chunk) error
+	ListChunks(tenantID, caseID string) ([]*Chunk, error)
+	CreateExchange(e *Exchange) error
+	ListExchanges(tenantID, caseID string, limit int) ([]*Exchange, error)
+}
+
+// Requester identifies the caller; every operation is checked against it.
+type Requester struct {
+	TenantID string
+	UserID   string
+	Role     string
+}
+
+type Service interface {
+	IndexCase(ctx context.Context, who Requester, caseID string) (*IndexReport, error)
+	Ask(ctx context.Context, who Requester, caseID, question string) (*Answer, error)
+	// ListExchanges leaves out exchanges drawn from evidence or reports
+	// the caller is not cleared to view.
+	ListExchanges(ctx context.Context, who Requester, caseID string, limit int) ([]*Exchange, error)
+}
+
+// ─── Ports onto other services ──────────────────────────────
+
+type EvidenceReader interface {
+	GetEvidenceByCaseID(caseID uuid.UUID) ([]metadata.Evidence, error)
+}
+
+type BlobFetcher interface {
+	Download(cid string) (io.ReadCloser, error)
+}
+
+type TimelineReader interface {
+	ListEvents(caseID string) ([]*timeline.TimelineEventResponse, error)
+}
+
+type ThreadReader interface {
+	GetThreadsByCase(caseID uuid.UUID) ([]annotationthreads.AnnotationThread, error)
+}
+
+type MessageReader interface {
+	GetMessagesByThread(threadID uuid.UUID) ([]messages.ThreadMessage, error)
+}
+
+// Classification withholds labelled evidence and reports; retrieval
+// skips what the caller is not cleared to view.
+type Classification interface {
+	Withheld(ctx context.Context, s abac.Subject, action, resourceType string, ids []string) (map[string]*abac.Decision, error)
+}
+
+type ReportReader interface {
+	GetReportsByCaseID(ctx context.Context, caseID uuid.UUID) ([]report.ReportWithDetails, error)
+	DownloadReport(ctx context.Context, reportID uuid.UUID) (*report.ReportWithContent, error)
+}
@@ -0,0 +1,89 @@
+package case_qa
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+type SourceType string
+
+const (
+	SourceEvidence      SourceType = "evidence"
+	SourceTimelineEvent SourceType = "timeline_event"
+	SourceThreadMessage SourceType = "thread_message"
+	SourceReportSection SourceType = "report_section"
+)
+
+// Chunk is one indexed passage of case material. The vector is stored as
+// little-endian float32s; Embedder records which model produced it so a
+// change of embedding model forces re-embedding.
+type Chunk struct {
+	ID          string     `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID    string     `gorm:"type:uuid;not null;index:idx_case_qa_chunks_case,priority:1" json:"tenant_id"`
+	CaseID      string     `gorm:"type:uuid;not null;index:idx_case_qa_chunks_case,priority:2" json:"case_id"`
+	SourceType  SourceType `gorm:"type:varchar(30);not null" json:"source_type"`
+	SourceID    string     `gorm:"type:varchar(64);not null" json:"source_id"`
+	ParentID    string     `gorm:"type:varchar(64)" json:"parent_id,omitempty"` // thread ID or report ID
+	Title       string     `gorm:"type:text" json:"title"`
+	Ordinal     int        `gorm:"not null;default:0" json:"ordinal"`
+	Content     string     `gorm:"type:text;not null" json:"content"`
+	ContentHash string     `gorm:"type:char(64);not null" json:"-"`
+	Embedder    string     `gorm:"type:varchar(100);not null" json:"-"`
+	Vector      []byte     `gorm:"type:bytea;not null" json:"-"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (Chunk) TableName() string { return "case_qa_chunks" }
+
+// Citation points an answer back to the material it was drawn from.
+type Citation struct {
+	Ref        string     `json:"ref"` // marker used in the answer text, e.g. "S2"
+	SourceType SourceType `json:"source_type"`
+	SourceID   string     `json:"source_id"`
+	ParentID   string     `json:"parent_id,omitempty"`
+	Title      string     `json:"title"`
+	Excerpt    string     `json:"excerpt"`
+	Score      float64    `json:"score"`
+}
+
+// Exchange is the persisted record of one question and its answer.
+type Exchange struct {
+	ID        string         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID  string         `gorm:"type:uuid;not null;index:idx_case_qa_exchanges_case,priority:1" json:"tenant_id"`
+	CaseID    string         `gorm:"type:uuid;not null;index:idx_case_qa_exchanges_case,priority:2" json:"case_id"`
+	UserID    string         `gorm:"type:uuid;not null" json:"user_id"`
+	Question  string         `gorm:"type:text;not null" json:"question"`
+	Answer    string         `gorm:"type:text" json:"answer"`
+	Citations datatypes.JSON `gorm:"type:jsonb;default:'[]'::jsonb" json:"citations"`
+	// Sources is everything the answer was drawn from, cited or not, so
+	// the exchange can be withheld from users not cleared for any of it.
+	Sources   datatypes.JSON `gorm:"type:jsonb;default:'[]'::jsonb" json:"-"`
+	Provider  string         `gorm:"type:varchar(50)" json:"provider"`
+	Model     string         `gorm:"type:varchar(255)" json:"model"`
+	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (Exchange) TableName() string { return "case_qa_exchanges" }
+
+// Answer is returned to the caller. Citations are the sources the answer
+// actually references; Sources is everything that was retrieved.
+type Answer struct {
+	ExchangeID string     `json:"exchange_id"`
+	Question   string     `json:"question"`
+	Answer     string     `json:"answer"`
+	Citations  []Citation `json:"citations"`
+	Sources    []Citation `json:"sources"`
+	Provider   string     `json:"provider,omitempty"`
+	Model      string     `json:"model,omitempty"`
+}
+
+type IndexReport struct {
+	CaseID    string             `json:"case_id"`
+	Chunks    int                `json:"chunks"`
+	Embedded  int                `json:"embedded"` // chunks whose vector had to be computed
+	BySource  map[SourceType]int `json:"by_source"`
+	Skipped   []string           `json:"skipped,omitempty"`
+	Embedder  string             `json:"embedder"`
+	IndexedAt time.Time          `json:"indexed_at"`
+}
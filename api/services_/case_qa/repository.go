@@ -0,0 +1,69 @@
+package case_qa
+
+import (
+	"gorm.io/gorm"
+)
+
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) AutoMigrate() error {
+	return r.db.AutoMigrate(&Chunk{}, &Exchange{})
+}
+
+func (r *GormRepository) CaseInTenant(tenantID, caseID string) (bool, error) {
+	var n int64
+	err := r.db.Table("cases").Where("id = ? AND tenant_id = ?", caseID, tenantID).Count(&n).Error
+	return n > 0, err
+}
+
+func (r *GormRepository) IsCaseMember(caseID, userID string) (bool, error) {
+	var ok bool
+	err := r.db.Raw(`
+		SELECT EXISTS (SELECT 1 FROM cases WHERE id = ? AND created_by = ?)
+		    OR EXISTS (SELECT 1 FROM case_user_roles WHERE case_id = ? AND user_id = ?)
+		    OR EXISTS (SELECT 1 FROM case_collaborators
+		               WHERE case_id = ? AND user_id = ? AND status = 'active'
+		                 AND (expires_at IS NULL OR expires_at > NOW()))`,
+		caseID, userID, caseID, userID, caseID, userID).Scan(&ok).Error
+	return ok, err
+}
+
+func (r *GormRepository) ReplaceChunks(tenantID, caseID string, chunks []*Chunk) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tenant_id = ? AND case_id = ?", tenantID, caseID).Delete(&Chunk{}).Error; err != nil {
+			return err
+		}
+		if len(chunks) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(chunks, 200).Error
+	})
+}
+
+func (r *GormRepository) ListChunks(tenantID, caseID string) ([]*Chunk, error) {
+	var chunks []*Chunk
+	err := r.db.Where("tenant_id = ? AND case_id = ?", tenantID, caseID).
+		Order("source_type, source_id, ordinal").
+		Find(&chunks).Error
+	return chunks, err
+}
+
+func (r *GormRepository) CreateExchange(e *Exchange) error {
+	return r.db.Create(e).Error
+}
+
+func (r *GormRepository) ListExchanges(tenantID, caseID string, limit int) ([]*Exchange, error) {
+	var out []*Exchange
+	q := r.db.Where("tenant_id = ? AND case_id = ?", tenantID, caseID).Order("created_at DESC")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	err := q.Find(&out).Error
+	return out, err
+}
@@ -0,0 +1,605 @@
+package case_qa
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"aegis-api/services_/auth/abac"
+	"aegis-api/services_/evidence/metadata"
+	"aegis-api/services_/llm"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+var (
+	ErrCaseNotFound     = errors.New("case not found")
+	ErrNotCaseMember    = errors.New("user is not a member of this case")
+	ErrEmptyQuestion    = errors.New("question is required")
+	ErrQuestionTooLong  = errors.New("question is too long")
+	ErrInvalidRequester = errors.New("invalid tenant or user")
+)
+
+const (
+	maxQuestionLen   = 2000
+	maxEvidenceBytes = 2 << 20
+	embedBatchSize   = 64
+	topK             = 6
+	// minRelevance drops hash collisions that share no real terms with the question.
+	minRelevance = 0.05
+)
+
+// textExtensions are evidence files whose content is indexed, not just their metadata.
+var textExtensions = map[string]bool{
+	".txt": true, ".log": true, ".md": true, ".csv": true, ".tsv": true,
+	".json": true, ".jsonl": true, ".ndjson": true, ".xml": true,
+	".html": true, ".htm": true, ".eml": true, ".yaml": true, ".yml": true,
+}
+
+const systemPrompt = `You are assisting a digital forensics investigator with a single case.
+Answer the question using ONLY the numbered sources provided.
+Cite every statement with the marker of the source it comes from, e.g. [S1] or [S2, S4].
+If the sources do not contain the answer, say that the case material does not answer it.`
+
+type service struct {
+	repo     Repository
+	evidence EvidenceReader
+	blobs    BlobFetcher
+	timeline TimelineReader
+	threads  ThreadReader
+	messages MessageReader
+	reports  ReportReader
+	embedder llm.Embedder
+	llm      llm.Provider
+	policy   Classification
+}
+
+func NewService(
+	repo Repository,
+	evidence EvidenceReader,
+	blobs BlobFetcher,
+	timeline TimelineReader,
+	threads ThreadReader,
+	messages MessageReader,
+	reports ReportReader,
+	embedder llm.Embedder,
+	provider llm.Provider,
+	policy Classification, // nil: nothing is classified
+) Service {
+	return &service{
+		repo:     repo,
+		evidence: evidence,
+		blobs:    blobs,
+		timeline: timeline,
+		threads:  threads,
+		messages: messages,
+		reports:  reports,
+		embedder: embedder,
+		llm:      provider,
+		policy:   policy,
+	}
+}
+
+// authorize enforces the tenant boundary first (a case in another tenant is
+// reported as not found) and then case membership.
+func (s *service) authorize(who Requester, caseID string) error {
+	if _, err := uuid.Parse(who.TenantID); err != nil {
+		return ErrInvalidRequester
+	}
+	if _, err := uuid.Parse(who.UserID); err != nil {
+		return ErrInvalidRequester
+	}
+	if _, err := uuid.Parse(caseID); err != nil {
+		return ErrCaseNotFound
+	}
+	ok, err := s.repo.CaseInTenant(who.TenantID, caseID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrCaseNotFound
+	}
+	member, err := s.repo.IsCaseMember(caseID, who.UserID)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return ErrNotCaseMember
+	}
+	return nil
+}
+
+// ─── Indexing ───────────────────────────────────────────────
+
+type document struct {
+	sourceType SourceType
+	sourceID   string
+	parentID   string
+	title      string
+	text       string
+}
+
+func (s *service) IndexCase(ctx context.Context, who Requester, caseID string) (*IndexReport, error) {
+	if err := s.authorize(who, caseID); err != nil {
+		return nil, err
+	}
+	return s.index(llm.WithTenant(ctx, who.TenantID), who.TenantID, caseID)
+}
+
+func (s *service) index(ctx context.Context, tenantID, caseID string) (*IndexReport, error) {
+	report := &IndexReport{CaseID: caseID, BySource: map[SourceType]int{}}
+	docs, skipped := s.collect(ctx, tenantID, caseID)
+	report.Skipped = skipped
+
+	var chunks []*Chunk
+	for _, d := range docs {
+		for i, text := range chunkText(d.text) {
+			sum := sha256.Sum256([]byte(string(d.sourceType) + "\x00" + d.sourceID + "\x00" + d.title + "\x00" + text))
+			chunks = append(chunks, &Chunk{
+				TenantID:    tenantID,
+				CaseID:      caseID,
+				SourceType:  d.sourceType,
+				SourceID:    d.sourceID,
+				ParentID:    d.parentID,
+				Title:       d.title,
+				Ordinal:     i,
+				Content:     text,
+				ContentHash: hex.EncodeToString(sum[:]),
+			})
+		}
+	}
+
+	embedded, embedder, err := s.embedChunks(ctx, tenantID, caseID, chunks)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceChunks(tenantID, caseID, chunks); err != nil {
+		return nil, fmt.Errorf("store case index: %w", err)
+	}
+
+	for _, c := range chunks {
+		report.BySource[c.SourceType]++
+	}
+	report.Chunks = len(chunks)
+	report.Embedded = embedded
+	report.Embedder = embedder
+	report.IndexedAt = time.Now().UTC()
+	return report, nil
+}
+
+// embedChunks fills in vectors, reusing those of unchanged chunks from the
+// existing index when they were produced by the same embedder.
+func (s *service) embedChunks(ctx context.Context, tenantID, caseID string, chunks []*Chunk) (int, string, error) {
+	existing, err := s.repo.ListChunks(tenantID, caseID)
+	if err != nil {
+		return 0, "", err
+	}
+	previous := make(map[string]*Chunk, len(existing))
+	for _, c := range existing {
+		previous[c.ContentHash] = c
+	}
+
+	var missing []*Chunk
+	for _, c := range chunks {
+		if p, ok := previous[c.ContentHash]; ok {
+			c.Vector, c.Embedder = p.Vector, p.Embedder
+		} else {
+			missing = append(missing, c)
+		}
+	}
+
+	name := ""
+	if len(missing) > 0 {
+		if name, err = s.embed(ctx, missing); err != nil {
+			return 0, "", err
+		}
+	} else if len(chunks) > 0 {
+		// Everything was reused; make sure the embedder has not changed since.
+		resp, err := s.embedder.Embed(ctx, []string{chunks[0].Content})
+		if err != nil {
+			return 0, "", fmt.Errorf("embed case material: %w", err)
+		}
+		name = embedderName(resp)
+	}
+
+	var stale []*Chunk
+	for _, c := range chunks {
+		if c.Embedder != name {
+			stale = append(stale, c)
+		}
+	}
+	if len(stale) > 0 {
+		if _, err := s.embed(ctx, stale); err != nil {
+			return 0, "", err
+		}
+	}
+	return len(missing) + len(stale), name, nil
+}
+
+func (s *service) embed(ctx context.Context, chunks []*Chunk) (string, error) {
+	name := ""
+	for start := 0; start < len(chunks); start += embedBatchSize {
+		end := min(start+embedBatchSize, len(chunks))
+		texts := make([]string, 0, end-start)
+		for _, c := range chunks[start:end] {
+			texts = append(texts, c.Title+"\n"+c.Content)
+		}
+		resp, err := s.embedder.Embed(ctx, texts)
+		if err != nil {
+			return "", fmt.Errorf("embed case material: %w", err)
+		}
+		if len(resp.Vectors) != len(texts) {
+			return "", fmt.Errorf("embed case material: expected %d vectors, got %d", len(texts), len(resp.Vectors))
+		}
+		name = embedderName(resp)
+		for i, c := range chunks[start:end] {
+			c.Vector = encodeVector(resp.Vectors[i])
+			c.Embedder = name
+		}
+	}
+	return name, nil
+}
+
+func embedderName(resp *llm.EmbeddingResponse) string {
+	return resp.Provider + "/" + resp.Model
+}
+
+// collect gathers the case's indexable material. Sources that cannot be read
+// are reported rather than failing the whole index.
+func (s *service) collect(ctx context.Context, tenantID, caseID string) ([]document, []string) {
+	var docs []document
+	var skipped []string
+	caseUUID := uuid.MustParse(caseID)
+
+	if evidence, err := s.evidence.GetEvidenceByCaseID(caseUUID); err != nil {
+		skipped = append(skipped, "evidence: "+err.Error())
+	} else {
+		for _, e := range evidence {
+			// Defence in depth: never index evidence that is not this tenant's.
+			if e.CaseID != caseUUID || e.TenantID.String() != tenantID {
+				continue
+			}
+			text, err := s.evidenceText(e)
+			if err != nil {
+				skipped = append(skipped, fmt.Sprintf("%s: %v", e.Filename, err))
+			}
+			docs = append(docs, document{sourceType: SourceEvidence, sourceID: e.ID.String(), title: e.Filename, text: text})
+		}
+	}
+
+	if events, err := s.timeline.ListEvents(caseID); err != nil {
+		skipped = append(skipped, "timeline: "+err.Error())
+	} else {
+		for _, ev := range events {
+			var tags []string
+			_ = json.Unmarshal(ev.Tags, &tags)
+			text := fmt.Sprintf("Timeline event on %s %s (severity %s, analyst %s): %s", ev.Date, ev.Time, ev.Severity, ev.AnalystName, ev.Description)
+			if len(tags) > 0 {
+				text += " Tags: " + strings.Join(tags, ", ")
+			}
+			docs = append(docs, document{sourceType: SourceTimelineEvent, sourceID: ev.ID, title: "Timeline event", text: text})
+		}
+	}
+
+	if threads, err := s.threads.GetThreadsByCase(caseUUID); err != nil {
+		skipped = append(skipped, "threads: "+err.Error())
+	} else {
+		for _, t := range threads {
+			if t.CaseID != caseUUID {
+				continue
+			}
+			msgs, err := s.messages.GetMessagesByThread(t.ID)
+			if err != nil {
+				skipped = append(skipped, fmt.Sprintf("thread %s: %v", t.ID, err))
+				continue
+			}
+			for _, m := range msgs {
+				docs = append(docs, document{
+					sourceType: SourceThreadMessage,
+					sourceID:   m.ID.String(),
+					parentID:   t.ID.String(),
+					title:      t.Title,
+					text:       m.Message,
+				})
+			}
+		}
+	}
+
+	if reports, err := s.reports.GetReportsByCaseID(ctx, caseUUID); err != nil {
+		skipped = append(skipped, "reports: "+err.Error())
+	} else {
+		for _, r := range reports {
+			full, err := s.reports.DownloadReport(ctx, r.ID)
+			if err != nil {
+				skipped = append(skipped, fmt.Sprintf("report %s: %v", r.ID, err))
+				continue
+			}
+			if full.Metadata == nil || full.Metadata.TenantID.String() != tenantID || full.Metadata.CaseID != caseUUID {
+				continue
+			}
+			for _, sec := range full.Content {
+				docs = append(docs, document{
+					sourceType: SourceReportSection,
+					sourceID:   sec.ID.Hex(),
+					parentID:   r.ID.String(),
+					title:      sec.Title,
+					text:       stripHTML(sec.Content),
+				})
+			}
+		}
+	}
+	return docs, skipped
+}
+
+// evidenceText describes an evidence item and, for text formats, includes its
+// content. The description is returned even when the content cannot be read.
+func (s *service) evidenceText(e metadata.Evidence) (string, error) {
+	desc := fmt.Sprintf("Evidence file %s (type %s, %d bytes, sha256 %s).", e.Filename, e.FileType, e.FileSize, e.Checksum)
+	if e.Metadata != "" && e.Metadata != "{}" {
+		desc += " Metadata: " + e.Metadata
+	}
+	if !isTextEvidence(e) || e.IpfsCID == "" || s.blobs == nil {
+		return desc, nil
+	}
+	rc, err := s.blobs.Download(e.IpfsCID)
+	if err != nil {
+		return desc, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxEvidenceBytes))
+	if err != nil {
+		return desc, err
+	}
+	return desc + "\n" + strings.ToValidUTF8(string(data), " "), nil
+}
+
+func isTextEvidence(e metadata.Evidence) bool {
+	if textExtensions[strings.ToLower(path.Ext(e.Filename))] {
+		return true
+	}
+	ft := strings.ToLower(e.FileType)
+	return strings.HasPrefix(ft, "text/") || strings.Contains(ft, "json") || strings.Contains(ft, "xml")
+}
+
+// ─── Question answering ─────────────────────────────────────
+
+type scored struct {
+	chunk *Chunk
+	score float64
+}
+
+func (s *service) Ask(ctx context.Context, who Requester, caseID, question string) (*Answer, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return nil, ErrEmptyQuestion
+	}
+	if len(question) > maxQuestionLen {
+		return nil, ErrQuestionTooLong
+	}
+	if err := s.authorize(who, caseID); err != nil {
+		return nil, err
+	}
+	ctx = llm.WithTenant(ctx, who.TenantID)
+
+	chunks, err := s.repo.ListChunks(who.TenantID, caseID)
+	if err != nil {
+		return nil, err
+	}
+	qresp, err := s.embedder.Embed(ctx, []string{question})
+	if err != nil {
+		return nil, fmt.Errorf("embed question: %w", err)
+	}
+	name := embedderName(qresp)
+
+	// Build the index on first use, or rebuild it if the embedder changed.
+	if len(chunks) == 0 || chunks[0].Embedder != name {
+		if _, err := s.index(ctx, who.TenantID, caseID); err != nil {
+			return nil, err
+		}
+		if chunks, err = s.repo.ListChunks(who.TenantID, caseID); err != nil {
+			return nil, err
+		}
+	}
+
+	if chunks, err = s.cleared(ctx, who, chunks); err != nil {
+		return nil, err
+	}
+	hits := rank(chunks, qresp.Vectors[0], name)
+	sources := make([]Citation, len(hits))
+	for i, h := range hits {
+		sources[i] = Citation{
+			Ref:        fmt.Sprintf("S%d", i+1),
+			SourceType: h.chunk.SourceType,
+			SourceID:   h.chunk.SourceID,
+			ParentID:   h.chunk.ParentID,
+			Title:      h.chunk.Title,
+			Excerpt:    excerpt(h.chunk.Content, 240),
+			Score:      h.score,
+		}
+	}
+
+	answer := &Answer{Question: question, Sources: sources, Citations: []Citation{}}
+	if len(hits) == 0 {
+		answer.Answer = "The indexed case material (evidence, timeline, annotation threads and reports) does not contain anything relevant to this question."
+	} else {
+		resp, err := s.llm.Complete(ctx, llm.CompletionRequest{
+			System:    systemPrompt,
+			Prompt:    buildPrompt(question, hits),
+			MaxTokens: 600,
+			Purpose:   "case_qa.answer",
+		})
+		if err != nil {
+			return nil, err
+		}
+		answer.Answer = resp.Text
+		answer.Provider = resp.Provider
+		answer.Model = resp.Model
+		for _, n := range citedRefs(resp.Text) {
+			if n >= 1 && n <= len(sources) {
+				answer.Citations = append(answer.Citations, sources[n-1])
+			}
+		}
+	}
+
+	citations, _ := json.Marshal(answer.Citations)
+	retrieved, _ := json.Marshal(answer.Sources)
+	ex := &Exchange{
+		TenantID:  who.TenantID,
+		CaseID:    caseID,
+		UserID:    who.UserID,
+		Question:  question,
+		Answer:    answer.Answer,
+		Citations: datatypes.JSON(citations),
+		Sources:   datatypes.JSON(retrieved),
+		Provider:  answer.Provider,
+		Model:     answer.Model,
+	}
+	if err := s.repo.CreateExchange(ex); err != nil {
+		return nil, fmt.Errorf("record exchange: %w", err)
+	}
+	answer.ExchangeID = ex.ID
+	return answer, nil
+}
+
+func rank(chunks []*Chunk, query []float32, embedder string) []scored {
+	var hits []scored
+	for _, c := range chunks {
+		if c.Embedder != embedder {
+			continue
+		}
+		if score := llm.Cosine(query, decodeVector(c.Vector)); score > minRelevance {
+			hits = append(hits, scored{chunk: c, score: score})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].score > hits[j].score })
+	if len(hits) > topK {
+		hits = hits[:topK]
+	}
+	return hits
+}
+
+func buildPrompt(question string, hits []scored) string {
+	var b strings.Builder
+	b.WriteString("Sources:\n")
+	for i, h := range hits {
+		fmt.Fprintf(&b, "[S%d] (%s %s", i+1, h.chunk.SourceType, h.chunk.SourceID)
+		if h.chunk.Title != "" {
+			fmt.Fprintf(&b, ", %q", h.chunk.Title)
+		}
+		fmt.Fprintf(&b, ")\n%s\n\n", h.chunk.Content)
+	}
+	fmt.Fprintf(&b, "Question: %s\nAnswer:", question)
+	return b.String()
+}
+
+func (s *service) ListExchanges(ctx context.Context, who Requester, caseID string, limit int) ([]*Exchange, error) {
+	if err := s.authorize(who, caseID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	exchanges, err := s.repo.ListExchanges(who.TenantID, caseID, limit)
+	if err != nil || s.policy == nil {
+		return exchanges, err
+	}
+	var drawn []Citation
+	from := make([][]Citation, len(exchanges))
+	for i, ex := range exchanges {
+		for _, raw := range []datatypes.JSON{ex.Sources, ex.Citations} {
+			var cs []Citation
+			_ = json.Unmarshal(raw, &cs)
+			from[i] = append(from[i], cs...)
+		}
+		drawn = append(drawn, from[i]...)
+	}
+	refused, err := s.withheld(ctx, who, drawn)
+	if err != nil {
+		return nil, err
+	}
+	visible := exchanges[:0]
+next:
+	for i, ex := range exchanges {
+		for _, c := range from[i] {
+			if refused[classifiedRef(c.SourceType, c.SourceID, c.ParentID)] {
+				continue next
+			}
+		}
+		visible = append(visible, ex)
+	}
+	return visible, nil
+}
+
+// classifiedRef names the labelled resource a source belongs to: the
+// evidence item itself, or the report of a section; "" for the rest.
+func classifiedRef(t SourceType, sourceID, parentID string) string {
+	switch t {
+	case SourceEvidence:
+		return abac.ResourceEvidence + "/" + sourceID
+	case SourceReportSection:
+		return abac.ResourceReport + "/" + parentID
+	}
+	return ""
+}
+
+// withheld returns the classifiedRefs of sources the caller may not view.
+func (s *service) withheld(ctx context.Context, who Requester, sources []Citation) (map[string]bool, error) {
+	out := map[string]bool{}
+	if s.policy == nil {
+		return out, nil
+	}
+	ids := map[string][]string{}
+	seen := map[string]bool{}
+	for _, c := range sources {
+		ref := classifiedRef(c.SourceType, c.SourceID, c.ParentID)
+		if ref == "" || seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		rt, id, _ := strings.Cut(ref, "/")
+		ids[rt] = append(ids[rt], id)
+	}
+	subject := abac.Subject{UserID: who.UserID, TenantID: who.TenantID}
+	for rt, list := range ids {
+		refused, err := s.policy.Withheld(ctx, subject, abac.ActionView, rt, list)
+		if err != nil {
+			return nil, err
+		}
+		for id := range refused {
+			out[rt+"/"+id] = true
+		}
+	}
+	return out, nil
+}
+
+// cleared drops the chunks drawn from evidence or reports the caller may
+// not view, so they are neither ranked nor quoted.
+func (s *service) cleared(ctx context.Context, who Requester, chunks []*Chunk) ([]*Chunk, error) {
+	if s.policy == nil {
+		return chunks, nil
+	}
+	sources := make([]Citation, len(chunks))
+	for i, c := range chunks {
+		sources[i] = Citation{SourceType: c.SourceType, SourceID: c.SourceID, ParentID: c.ParentID}
+	}
+	refused, err := s.withheld(ctx, who, sources)
+	if err != nil || len(refused) == 0 {
+		return chunks, err
+	}
+	out := make([]*Chunk, 0, len(chunks))
+	for _, c := range chunks {
+		if !refused[classifiedRef(c.SourceType, c.SourceID, c.ParentID)] {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
@@ -215,6 +215,48 @@ func (r *MongoRepository) GetGroupsByCaseID(ctx context.Context, caseID primitiv
 	return groups, nil
 }
 
+// StreamCaseMessages calls fn with every message of the case's groups,
+// group by group and oldest first. Inactive groups are included, deleted
+// messages are not. Messages are read from a cursor rather than loaded at
+// once, for exports of long-running cases.
+func (r *MongoRepository) StreamCaseMessages(ctx context.Context, caseID string, fn func(*ChatGroup, *Message) error) error {
+	groups, err := r.db.Collection(GroupsCollection).Find(ctx, bson.M{"case_id": caseID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return fmt.Errorf("failed to get groups for case: %w", err)
+	}
+	var list []*ChatGroup
+	if err := groups.All(ctx, &list); err != nil {
+		return fmt.Errorf("failed to decode groups for case: %w", err)
+	}
+
+	messages := r.db.Collection(MessagesCollection)
+	for _, group := range list {
+		cursor, err := messages.Find(ctx, bson.M{"group_id": group.ID, "is_deleted": false},
+			options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+		if err != nil {
+			return fmt.Errorf("failed to get messages: %w", err)
+		}
+		for cursor.Next(ctx) {
+			var message Message
+			if err := cursor.Decode(&message); err != nil {
+				cursor.Close(ctx)
+				return fmt.Errorf("failed to decode message: %w", err)
+			}
+			if err := fn(group, &message); err != nil {
+				cursor.Close(ctx)
+				return err
+			}
+		}
+		err = cursor.Err()
+		cursor.Close(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *MongoRepository) DeleteGroup(ctx context.Context, groupID primitive.ObjectID) error {
 	collection := r.db.Collection(GroupsCollection)
 
@@ -771,3 +813,24 @@ func (r *MongoRepository) GetUndeliveredMessages(ctx context.Context, userEmail
 
 	return messages, nil
 }
+
+// GroupCase returns the case an active group belongs to, or "" when there
+// is no such group. Case access checks use it to resolve chat groups.
+func (r *MongoRepository) GroupCase(ctx context.Context, groupID string) (string, error) {
+	id, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return "", nil
+	}
+	var group struct {
+		CaseID string `bson:"case_id"`
+	}
+	err = r.db.Collection(GroupsCollection).FindOne(ctx, bson.M{"_id": id, "is_active": true},
+		options.FindOne().SetProjection(bson.M{"case_id": 1})).Decode(&group)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get group: %w", err)
+	}
+	return group.CaseID, nil
+}
@@ -0,0 +1,336 @@
+package detection_rules_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"aegis-api/services_/detection_rules"
+	"aegis-api/services_/evidence/metadata"
+	"aegis-api/services_/timeline"
+	"aegis-api/tests/fakes"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+)
+
+type fixture struct {
+	svc      detection_rules.Service
+	repo     *fakes.Rules
+	store    *fakes.Evidence
+	blobs    fakes.Blobs
+	tagger   fakes.Tags
+	iocs     *fakes.IOCs
+	timeline *fakes.Timeline
+	tenantID string
+	caseID   uuid.UUID
+	userID   string
+}
+
+func newFixture() *fixture {
+	f := &fixture{
+		repo:     &fakes.Rules{},
+		store:    &fakes.Evidence{},
+		blobs:    fakes.Blobs{},
+		tagger:   fakes.Tags{},
+		iocs:     &fakes.IOCs{},
+		timeline: &fakes.Timeline{},
+		tenantID: uuid.NewString(),
+		caseID:   uuid.New(),
+		userID:   uuid.NewString(),
+	}
+	f.svc = detection_rules.NewService(f.repo, f.store, f.blobs, f.tagger, f.iocs, f.timeline)
+	return f
+}
+
+func (f *fixture) addEvidence(name string, data []byte) metadata.Evidence {
+	e := metadata.Evidence{
+		ID:       uuid.New(),
+		CaseID:   f.caseID,
+		TenantID: uuid.MustParse(f.tenantID),
+		Filename: name,
+		IpfsCID:  "cid-" + name,
+	}
+	f.store.Items = append(f.store.Items, e)
+	f.blobs[e.IpfsCID] = data
+	return e
+}
+
+func (f *fixture) scanRequest() detection_rules.ScanRequest {
+	return detection_rules.ScanRequest{TenantID: f.tenantID, CaseID: f.caseID.String(), UserID: f.userID, IncludeTimeline: true}
+}
+
+// ─── YARA engine ────────────────────────────────────────────
+
+const dropperRule = `
+rule Dropper : malware loader {
+  meta:
+    severity = "high"
+    ioc_type = "domain"
+  strings:
+    $mz = { 4D 5A ?? 00 [2-4] 50 45 }
+    $c2 = "evil.example" nocase
+    $ua = /Mozilla\/4\.0 \(compatible; [A-Z]+\)/
+  condition:
+    $mz at 0 and ($c2 or $ua) and filesize < 1MB
+}`
+
+func TestCompileYARAAndScan(t *testing.T) {
+	rules, err := detection_rules.CompileYARA(dropperRule)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	require.Equal(t, []string{"malware", "loader"}, rules[0].Tags)
+
+	data := append([]byte{0x4D, 0x5A, 0x90, 0x00, 0x01, 0x02, 0x50, 0x45}, []byte(" connect to EVIL.example now")...)
+	m := rules[0].Scan(data)
+	require.NotNil(t, m)
+	require.Equal(t, int64(0), m.Offsets[0].Offset)
+	require.Equal(t, "$mz", m.Offsets[0].Identifier)
+	require.Equal(t, 8, m.Offsets[0].Length)
+	require.Equal(t, "$c2", m.Offsets[1].Identifier)
+	require.Equal(t, int64(20), m.Offsets[1].Offset)
+	require.Equal(t, []string{"EVIL.example"}, m.Values)
+
+	require.Nil(t, rules[0].Scan([]byte("evil.example without a header")))
+}
+
+func TestYARAConditionsAndModifiers(t *testing.T) {
+	rules, err := detection_rules.CompileYARA(`
+rule Counts {
+  strings:
+    $a = "ab"
+    $b = "zz" wide
+    $c1 = "x1"
+    $c2 = "x2"
+  condition:
+    #a >= 2 and $b and 1 of ($c*) and not $a at 1
+}`)
+	require.NoError(t, err)
+
+	data := []byte("ab..ab.z\x00z\x00.x2")
+	require.NotNil(t, rules[0].Scan(data))
+	require.Nil(t, rules[0].Scan([]byte("ab.z\x00z\x00.x2")))
+}
+
+func TestCompileYARARejectsUnsupportedSyntax(t *testing.T) {
+	for name, src := range map[string]string{
+		"import":          `import "pe" rule A { condition: pe.is_dll() }`,
+		"undefined":       `rule A { condition: $missing }`,
+		"leading jump":    `rule A { strings: $h = { [2] 4D } condition: $h }`,
+		"duplicate rules": `rule A { condition: true } rule A { condition: false }`,
+	} {
+		_, err := detection_rules.CompileYARA(src)
+		require.Error(t, err, name)
+	}
+}
+
+// ─── Sigma engine ───────────────────────────────────────────
+
+const bruteForceRule = `
+title: Failed Admin Logon
+id: 5c1e1f2a-0000-4000-8000-000000000001
+level: high
+detection:
+  selection:
+    EventID: 4625
+    TargetUserName|contains: admin
+  filter:
+    IpAddress|startswith: '10.'
+  condition: selection and not filter
+fields:
+  - IpAddress
+  - TargetUserName
+`
+
+func TestSigmaMatchesLogRecords(t *testing.T) {
+	rule, err := detection_rules.CompileSigma(bruteForceRule)
+	require.NoError(t, err)
+	require.Equal(t, "high", rule.Severity())
+
+	records, err := detection_rules.ReadLogRecords(bytes.NewBufferString(
+		`{"EventID": 4625, "TargetUserName": "Administrator", "IpAddress": "203.0.113.9"}` + "\n" +
+			`{"EventID": 4625, "TargetUserName": "administrator", "IpAddress": "10.0.0.5"}` + "\n" +
+			`{"EventID": 4624, "TargetUserName": "admin", "IpAddress": "203.0.113.9"}` + "\n" +
+			"plain text line\n"))
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+
+	require.True(t, rule.Match(records[0]))
+	require.False(t, rule.Match(records[1]))
+	require.False(t, rule.Match(records[2]))
+	require.Equal(t, map[string][]string{"IpAddress": {"203.0.113.9"}, "TargetUserName": {"Administrator"}}, rule.FieldValues(records[0]))
+}
+
+func TestSigmaKeywordsAndOfConditions(t *testing.T) {
+	rule, err := detection_rules.CompileSigma(`
+title: Credential Tools
+detection:
+  keywords:
+    - mimikatz
+    - 'sekurlsa::*'
+  sel_proc:
+    Image|endswith: '\procdump.exe'
+  condition: 1 of them
+`)
+	require.NoError(t, err)
+
+	recs, err := detection_rules.ReadLogRecords(bytes.NewBufferString(
+		"ran MIMIKATZ.exe\n" + `{"Image": "C:\\Tools\\ProcDump.exe"}` + "\nnothing\n"))
+	require.NoError(t, err)
+	require.True(t, rule.Match(recs[0]))
+	require.True(t, rule.Match(recs[1]))
+	require.False(t, rule.Match(recs[2]))
+
+	_, err = detection_rules.CompileSigma("title: x\ndetection:\n  sel:\n    a: 1\n  condition: sel | count() > 5\n")
+	require.Error(t, err)
+}
+
+// ─── Service ────────────────────────────────────────────────
+
+func TestUploadRuleVersionsAndValidates(t *testing.T) {
+	f := newFixture()
+
+	v1, err := f.svc.UploadRule(f.tenantID, f.userID, "dropper", detection_rules.RuleKindYARA, dropperRule, "")
+	require.NoError(t, err)
+	v2, err := f.svc.UploadRule(f.tenantID, f.userID, "dropper", detection_rules.RuleKindYARA, dropperRule, "tuned")
+	require.NoError(t, err)
+	require.Equal(t, 1, v1.Version)
+	require.Equal(t, 2, v2.Version)
+
+	sigma, err := f.svc.UploadRule(f.tenantID, f.userID, "", detection_rules.RuleKindSigma, bruteForceRule, "")
+	require.NoError(t, err)
+	require.Equal(t, "Failed Admin Logon", sigma.Name)
+
+	_, err = f.svc.UploadRule(f.tenantID, f.userID, "broken", detection_rules.RuleKindYARA, "rule {", "")
+	require.ErrorIs(t, err, detection_rules.ErrInvalidRule)
+
+	_, err = f.svc.UploadRule(f.tenantID, f.userID, "x", "snort", "alert", "")
+	require.ErrorIs(t, err, detection_rules.ErrInvalidRuleKind)
+}
+
+func TestScanEvidenceYARAExpandsArchives(t *testing.T) {
+	f := newFixture()
+	rule, err := f.svc.UploadRule(f.tenantID, f.userID, "dropper", detection_rules.RuleKindYARA, dropperRule, "")
+	require.NoError(t, err)
+
+	payload := append([]byte{0x4D, 0x5A, 0x90, 0x00, 0xAA, 0xBB, 0x50, 0x45}, []byte("beacon evil.example")...)
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	w, err := zw.Create("bin/payload.exe")
+	require.NoError(t, err)
+	_, err = w.Write(payload)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	archive := f.addEvidence("collection.zip", zipBuf.Bytes())
+	f.addEvidence("notes.txt", []byte("nothing to see"))
+
+	report, err := f.svc.ScanEvidenceYARA(context.Background(), f.scanRequest())
+	require.NoError(t, err)
+	require.Equal(t, 3, report.ItemsScanned)
+	require.Len(t, report.Matches, 1)
+
+	m := report.Matches[0]
+	require.Equal(t, "Dropper", m.RuleName)
+	require.Equal(t, rule.Version, m.RuleVersion)
+	require.Equal(t, archive.ID.String(), m.EvidenceID)
+	require.Equal(t, "bin/payload.exe", m.ChildPath)
+	require.Equal(t, "high", m.Severity)
+	require.Equal(t, detection_rules.SuggestionPending, m.SuggestionStatus)
+
+	var offsets []detection_rules.MatchOffset
+	require.NoError(t, json.Unmarshal(m.Offsets, &offsets))
+	require.Equal(t, int64(0), offsets[0].Offset)
+
+	require.ElementsMatch(t, []string{"yara:dropper", "malware", "loader"}, f.tagger[archive.ID])
+	require.Equal(t, 1, report.IOCsAdded)
+	require.Equal(t, "evil.example", f.iocs.Items[0].Value)
+
+	// A rescan must not duplicate indicators already on the case.
+	report, err = f.svc.ScanEvidenceYARA(context.Background(), f.scanRequest())
+	require.NoError(t, err)
+	require.Equal(t, 0, report.IOCsAdded)
+}
+
+func TestScanRejectsEvidenceFromAnotherCase(t *testing.T) {
+	f := newFixture()
+	_, err := f.svc.UploadRule(f.tenantID, f.userID, "dropper", detection_rules.RuleKindYARA, dropperRule, "")
+	require.NoError(t, err)
+	ev := f.addEvidence("a.bin", []byte("x"))
+
+	req := f.scanRequest()
+	req.CaseID = uuid.NewString()
+	req.EvidenceIDs = []string{ev.ID.String()}
+	_, err = f.svc.ScanEvidenceYARA(context.Background(), req)
+	require.ErrorIs(t, err, detection_rules.ErrEvidenceNotInCase)
+}
+
+func TestScanLogsSigmaAndAcceptSuggestion(t *testing.T) {
+	f := newFixture()
+	_, err := f.svc.UploadRule(f.tenantID, f.userID, "", detection_rules.RuleKindSigma, bruteForceRule, "")
+	require.NoError(t, err)
+
+	logs := f.addEvidence("security.jsonl", []byte(
+		`{"EventID": 4625, "TargetUserName": "admin", "IpAddress": "198.51.100.4"}`+"\n"+
+			`{"EventID": 4624, "TargetUserName": "admin", "IpAddress": "198.51.100.4"}`+"\n"+
+			`{"EventID": 4625, "TargetUserName": "sysadmin", "IpAddress": "198.51.100.4"}`+"\n"))
+	f.addEvidence("image.dd", []byte("EventID 4625 admin"))
+
+	tagsRaw, _ := json.Marshal([]string{})
+	_, err = f.timeline.AddEvent(&timeline.TimelineEvent{
+		CaseID:      f.caseID.String(),
+		Description: "Analyst note",
+		Tags:        datatypes.JSON(tagsRaw),
+	})
+	require.NoError(t, err)
+
+	report, err := f.svc.ScanLogsSigma(context.Background(), f.scanRequest())
+	require.NoError(t, err)
+	require.Equal(t, 4, report.ItemsScanned) // 3 log lines + 1 timeline event
+	require.Len(t, report.Matches, 1)
+
+	m := report.Matches[0]
+	require.Equal(t, logs.ID.String(), m.EvidenceID)
+	var offsets []detection_rules.MatchOffset
+	require.NoError(t, json.Unmarshal(m.Offsets, &offsets))
+	require.Equal(t, []int64{1, 3}, []int64{offsets[0].Offset, offsets[1].Offset})
+	require.Equal(t, []string{"sigma:failed_admin_logon"}, f.tagger[logs.ID])
+	require.Len(t, f.iocs.Items, 1)
+	require.Equal(t, "ip", f.iocs.Items[0].Type)
+
+	event, err := f.svc.AcceptSuggestion(f.tenantID, f.caseID.String(), m.ID, f.userID, "Analyst")
+	require.NoError(t, err)
+	require.Equal(t, "high", event.Severity)
+	require.Contains(t, string(event.Evidence), logs.ID.String())
+	require.Equal(t, detection_rules.SuggestionAccepted, m.SuggestionStatus)
+	require.Equal(t, event.ID, m.TimelineEventID)
+
+	_, err = f.svc.AcceptSuggestion(f.tenantID, f.caseID.String(), m.ID, f.userID, "Analyst")
+	require.ErrorIs(t, err, detection_rules.ErrSuggestionProcessed)
+}
+
+func TestScanTimelineTagsMatchingEvents(t *testing.T) {
+	f := newFixture()
+	_, err := f.svc.UploadRule(f.tenantID, f.userID, "Ransom Note", detection_rules.RuleKindSigma, `
+title: Ransom note observed
+level: critical
+detection:
+  sel:
+    description|contains: ransom
+  condition: sel
+`, "")
+	require.NoError(t, err)
+
+	ev, err := f.timeline.AddEvent(&timeline.TimelineEvent{CaseID: f.caseID.String(), Description: "Found RANSOM.txt on desktop", Tags: datatypes.JSON([]byte(`["triage"]`))})
+	require.NoError(t, err)
+
+	report, err := f.svc.ScanLogsSigma(context.Background(), f.scanRequest())
+	require.NoError(t, err)
+	require.Len(t, report.Matches, 1)
+	require.Equal(t, ev.ID, report.Matches[0].TimelineEventID)
+	require.Equal(t, detection_rules.SuggestionNone, report.Matches[0].SuggestionStatus)
+	require.JSONEq(t, `["triage","sigma:ransom_note"]`, string(ev.Tags))
+}
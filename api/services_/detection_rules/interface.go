@@ -0,0 +1,82 @@
+package detection_rules
+
+import (
+	"context"
+	"io"
+
+	graphicalmapping "aegis-api/services_/GraphicalMapping"
+	"aegis-api/services_/evidence/metadata"
+	"aegis-api/services_/timeline"
+
+	"github.com/google/uuid"
+)
+
+type Repository interface {
+	AutoMigrate() error
+	// CreateRuleVersion stores rule as the next version of rule.Name for its tenant.
+	CreateRuleVersion(rule *DetectionRule) error
+	GetRule(tenantID, id string) (*DetectionRule, error)
+	ListRules(tenantID string) ([]*DetectionRule, error)
+	ListRuleVersions(tenantID, name string) ([]*DetectionRule, error)
+	// ListActiveRules returns the newest version of every rule of kind, if that version is enabled.
+	ListActiveRules(tenantID string, kind RuleKind) ([]*DetectionRule, error)
+	SetRuleEnabled(tenantID, id string, enabled bool) error
+	CreateMatches(matches []*RuleMatch) error
+	ListMatchesByCase(tenantID, caseID string) ([]*RuleMatch, error)
+	GetMatch(tenantID, id string) (*RuleMatch, error)
+	UpdateMatch(m *RuleMatch) error
+}
+
+// ScanRequest selects what to scan. Empty RuleIDs means all active rules of the
+// relevant kind; empty EvidenceIDs means every evidence item in the case.
+type ScanRequest struct {
+	TenantID    string
+	CaseID      string
+	UserID      string
+	RuleIDs     []string
+	EvidenceIDs []string
+	// IncludeTimeline evaluates Sigma rules against the case timeline as well as log evidence.
+	IncludeTimeline bool
+}
+
+type Service interface {
+	UploadRule(tenantID, userID, name string, kind RuleKind, content, description string) (*DetectionRule, error)
+	GetRule(tenantID, id string) (*DetectionRule, error)
+	ListRules(tenantID string) ([]*DetectionRule, error)
+	ListRuleVersions(tenantID, name string) ([]*DetectionRule, error)
+	SetRuleEnabled(tenantID, id string, enabled bool) error
+
+	ScanEvidenceYARA(ctx context.Context, req ScanRequest) (*ScanReport, error)
+	ScanLogsSigma(ctx context.Context, req ScanRequest) (*ScanReport, error)
+
+	ListMatches(tenantID, caseID string) ([]*RuleMatch, error)
+	AcceptSuggestion(tenantID, caseID, matchID, userID, userName string) (*timeline.TimelineEvent, error)
+	DismissSuggestion(tenantID, caseID, matchID string) error
+}
+
+// EvidenceReader is the subset of the metadata service needed to locate evidence.
+type EvidenceReader interface {
+	FindEvidenceByID(id uuid.UUID) (*metadata.Evidence, error)
+	GetEvidenceByCaseID(caseID uuid.UUID) ([]metadata.Evidence, error)
+}
+
+// BlobFetcher downloads evidence content by IPFS CID.
+type BlobFetcher interface {
+	Download(cid string) (io.ReadCloser, error)
+}
+
+type EvidenceTagger interface {
+	TagEvidence(ctx context.Context, userID, evidenceID uuid.UUID, tags []string) error
+}
+
+type IOCRecorder interface {
+	AddIOC(ioc *graphicalmapping.IOC) (*graphicalmapping.IOC, error)
+	ListIOCsByCase(caseID string) ([]*graphicalmapping.IOC, error)
+}
+
+type TimelineStore interface {
+	AddEvent(event *timeline.TimelineEvent) (*timeline.TimelineEvent, error)
+	GetEvent(id string) (*timeline.TimelineEvent, error)
+	UpdateEvent(event *timeline.TimelineEvent) (*timeline.TimelineEvent, error)
+	ListEvents(caseID string) ([]*timeline.TimelineEventResponse, error)
+}
@@ -0,0 +1,81 @@
+package detection_rules
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// RuleKind distinguishes file-content rules from log/event rules.
+type RuleKind string
+
+const (
+	RuleKindYARA  RuleKind = "yara"
+	RuleKindSigma RuleKind = "sigma"
+)
+
+// DetectionRule is one immutable version of a tenant-uploaded rule.
+// Re-uploading a rule with the same name creates a new row with Version+1;
+// earlier versions stay so past matches remain reproducible.
+type DetectionRule struct {
+	ID          string    `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID    string    `gorm:"type:uuid;index:idx_detection_rule_version,unique;not null" json:"tenant_id"`
+	Name        string    `gorm:"size:255;index:idx_detection_rule_version,unique;not null" json:"name"`
+	Version     int       `gorm:"index:idx_detection_rule_version,unique;not null" json:"version"`
+	Kind        RuleKind  `gorm:"size:10;index;not null" json:"kind"`
+	Content     string    `gorm:"type:text;not null" json:"content"`
+	Description string    `gorm:"type:text" json:"description,omitempty"`
+	Enabled     bool      `gorm:"default:true" json:"enabled"`
+	CreatedBy   string    `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// MatchOffset records where a YARA string (or Sigma record) matched.
+// For log evidence Offset is the 1-based line number of the matching record.
+type MatchOffset struct {
+	Identifier string `json:"identifier"`
+	Offset     int64  `json:"offset"`
+	Length     int    `json:"length,omitempty"`
+}
+
+// Suggestion states for the timeline suggestion attached to each match.
+const (
+	SuggestionPending   = "pending"
+	SuggestionAccepted  = "accepted"
+	SuggestionDismissed = "dismissed"
+	// SuggestionNone marks matches on events that are already on the timeline.
+	SuggestionNone = "none"
+)
+
+// RuleMatch is a persisted hit, kept with the exact rule version for reporting.
+type RuleMatch struct {
+	ID               string         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID         string         `gorm:"type:uuid;index;not null" json:"tenant_id"`
+	CaseID           string         `gorm:"type:uuid;index;not null" json:"case_id"`
+	RuleID           string         `gorm:"type:uuid;index;not null" json:"rule_id"`
+	RuleName         string         `gorm:"size:255;not null" json:"rule_name"`
+	RuleVersion      int            `gorm:"not null" json:"rule_version"`
+	Kind             RuleKind       `gorm:"size:10;not null" json:"kind"`
+	EvidenceID       string         `gorm:"size:64;index" json:"evidence_id,omitempty"`
+	ChildPath        string         `gorm:"type:text" json:"child_path,omitempty"` // member path inside an expanded archive
+	TimelineEventID  string         `gorm:"size:64;index" json:"timeline_event_id,omitempty"`
+	Offsets          datatypes.JSON `gorm:"type:jsonb;default:'[]'::jsonb" json:"offsets"`
+	Summary          string         `gorm:"type:text" json:"summary"`
+	Severity         string         `gorm:"size:20" json:"severity,omitempty"`
+	SuggestionStatus string         `gorm:"size:20;default:'pending'" json:"suggestion_status"`
+	CreatedBy        string         `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+}
+
+// ScanReport summarises one scan run for the caller.
+type ScanReport struct {
+	CaseID       string       `json:"case_id"`
+	Kind         RuleKind     `json:"kind"`
+	RulesApplied int          `json:"rules_applied"`
+	ItemsScanned int          `json:"items_scanned"`
+	Matches      []*RuleMatch `json:"matches"`
+	TagsAdded    []string     `json:"tags_added"`
+	IOCsAdded    int          `json:"iocs_added"`
+	// Skipped lists items that could not be scanned, with the reason.
+	Skipped []string `json:"skipped,omitempty"`
+}
@@ -0,0 +1,110 @@
+package detection_rules
+
+import (
+	"gorm.io/gorm"
+)
+
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) AutoMigrate() error {
+	return r.db.AutoMigrate(&DetectionRule{}, &RuleMatch{})
+}
+
+func (r *GormRepository) CreateRuleVersion(rule *DetectionRule) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&DetectionRule{}).
+			Where("tenant_id = ? AND name = ?", rule.TenantID, rule.Name).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error; err != nil {
+			return err
+		}
+		rule.Version = latest + 1
+		return tx.Create(rule).Error
+	})
+}
+
+func (r *GormRepository) GetRule(tenantID, id string) (*DetectionRule, error) {
+	var rule DetectionRule
+	if err := r.db.Where("tenant_id = ? AND id = ?", tenantID, id).First(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *GormRepository) ListRules(tenantID string) ([]*DetectionRule, error) {
+	var rules []*DetectionRule
+	err := r.db.Where("tenant_id = ?", tenantID).
+		Order("name ASC, version DESC").
+		Find(&rules).Error
+	return rules, err
+}
+
+func (r *GormRepository) ListRuleVersions(tenantID, name string) ([]*DetectionRule, error) {
+	var rules []*DetectionRule
+	err := r.db.Where("tenant_id = ? AND name = ?", tenantID, name).
+		Order("version DESC").
+		Find(&rules).Error
+	return rules, err
+}
+
+func (r *GormRepository) ListActiveRules(tenantID string, kind RuleKind) ([]*DetectionRule, error) {
+	var rules []*DetectionRule
+	latest := r.db.Model(&DetectionRule{}).
+		Select("name, MAX(version) AS version").
+		Where("tenant_id = ?", tenantID).
+		Group("name")
+	err := r.db.Table("detection_rules AS d").
+		Select("d.*").
+		Joins("JOIN (?) AS l ON l.name = d.name AND l.version = d.version", latest).
+		Where("d.tenant_id = ? AND d.kind = ? AND d.enabled = ?", tenantID, kind, true).
+		Order("d.name ASC").
+		Find(&rules).Error
+	return rules, err
+}
+
+func (r *GormRepository) SetRuleEnabled(tenantID, id string, enabled bool) error {
+	res := r.db.Model(&DetectionRule{}).
+		Where("tenant_id = ? AND id = ?", tenantID, id).
+		Update("enabled", enabled)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *GormRepository) CreateMatches(matches []*RuleMatch) error {
+	if len(matches) == 0 {
+		return nil
+	}
+	return r.db.Create(&matches).Error
+}
+
+func (r *GormRepository) ListMatchesByCase(tenantID, caseID string) ([]*RuleMatch, error) {
+	var matches []*RuleMatch
+	err := r.db.Where("tenant_id = ? AND case_id = ?", tenantID, caseID).
+		Order("created_at DESC").
+		Find(&matches).Error
+	return matches, err
+}
+
+func (r *GormRepository) GetMatch(tenantID, id string) (*RuleMatch, error) {
+	var m RuleMatch
+	if err := r.db.Where("tenant_id = ? AND id = ?", tenantID, id).First(&m).Error; err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *GormRepository) UpdateMatch(m *RuleMatch) error {
+	return r.db.Save(m).Error
+}
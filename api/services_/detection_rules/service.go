@@ -0,0 +1,712 @@
+package detection_rules
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	graphicalmapping "aegis-api/services_/GraphicalMapping"
+	"aegis-api/services_/evidence/metadata"
+	"aegis-api/services_/timeline"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+var (
+	ErrInvalidRuleKind     = errors.New("rule kind must be yara or sigma")
+	ErrRuleNameRequired    = errors.New("rule name is required")
+	ErrInvalidRule         = errors.New("rule failed to compile")
+	ErrRuleKindMismatch    = errors.New("rule is not of the requested kind")
+	ErrEvidenceNotInCase   = errors.New("evidence does not belong to this case")
+	ErrMatchNotInCase      = errors.New("match does not belong to this case")
+	ErrSuggestionProcessed = errors.New("suggestion has already been handled")
+)
+
+const (
+	// maxScanBytes caps how much of a single evidence file (or archive member) is loaded.
+	maxScanBytes = 64 << 20
+	// maxArchiveDepth limits recursion into archives nested inside archives.
+	maxArchiveDepth = 2
+	// maxArchiveMembers bounds how many children are expanded from one archive.
+	maxArchiveMembers = 1000
+)
+
+var logExtensions = map[string]bool{".log": true, ".jsonl": true, ".ndjson": true, ".json": true, ".txt": true}
+
+type service struct {
+	repo     Repository
+	evidence EvidenceReader
+	blobs    BlobFetcher
+	tagger   EvidenceTagger
+	iocs     IOCRecorder
+	timeline TimelineStore
+}
+
+func NewService(repo Repository, evidence EvidenceReader, blobs BlobFetcher, tagger EvidenceTagger, iocs IOCRecorder, tl TimelineStore) Service {
+	return &service{repo: repo, evidence: evidence, blobs: blobs, tagger: tagger, iocs: iocs, timeline: tl}
+}
+
+// ─── Rule management ────────────────────────────────────────
+
+// UploadRule compiles the content before storing it so broken rules never reach a scan.
+// Sigma rules default their name to the rule title.
+func (s *service) UploadRule(tenantID, userID, name string, kind RuleKind, content, description string) (*DetectionRule, error) {
+	name = strings.TrimSpace(name)
+	switch kind {
+	case RuleKindYARA:
+		if _, err := CompileYARA(content); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+	case RuleKindSigma:
+		rule, err := CompileSigma(content)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+		if name == "" {
+			name = rule.Title
+		}
+	default:
+		return nil, ErrInvalidRuleKind
+	}
+	if name == "" {
+		return nil, ErrRuleNameRequired
+	}
+
+	rule := &DetectionRule{
+		TenantID:    tenantID,
+		Name:        name,
+		Kind:        kind,
+		Content:     content,
+		Description: description,
+		Enabled:     true,
+		CreatedBy:   userID,
+	}
+	if err := s.repo.CreateRuleVersion(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *service) GetRule(tenantID, id string) (*DetectionRule, error) {
+	return s.repo.GetRule(tenantID, id)
+}
+
+func (s *service) ListRules(tenantID string) ([]*DetectionRule, error) {
+	return s.repo.ListRules(tenantID)
+}
+
+func (s *service) ListRuleVersions(tenantID, name string) ([]*DetectionRule, error) {
+	return s.repo.ListRuleVersions(tenantID, name)
+}
+
+func (s *service) SetRuleEnabled(tenantID, id string, enabled bool) error {
+	return s.repo.SetRuleEnabled(tenantID, id, enabled)
+}
+
+// selectRules resolves explicit rule IDs (any version) or falls back to the active set.
+func (s *service) selectRules(tenantID string, kind RuleKind, ids []string) ([]*DetectionRule, error) {
+	if len(ids) == 0 {
+		return s.repo.ListActiveRules(tenantID, kind)
+	}
+	rules := make([]*DetectionRule, 0, len(ids))
+	for _, id := range ids {
+		r, err := s.repo.GetRule(tenantID, id)
+		if err != nil {
+			return nil, err
+		}
+		if r.Kind != kind {
+			return nil, ErrRuleKindMismatch
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// selectEvidence loads the requested evidence, enforcing tenant and case ownership.
+func (s *service) selectEvidence(req ScanRequest) ([]metadata.Evidence, error) {
+	caseUUID, err := uuid.Parse(req.CaseID)
+	if err != nil {
+		return nil, ErrEvidenceNotInCase
+	}
+	if len(req.EvidenceIDs) == 0 {
+		all, err := s.evidence.GetEvidenceByCaseID(caseUUID)
+		if err != nil {
+			return nil, err
+		}
+		var out []metadata.Evidence
+		for _, e := range all {
+			if e.TenantID.String() == req.TenantID {
+				out = append(out, e)
+			}
+		}
+		return out, nil
+	}
+
+	out := make([]metadata.Evidence, 0, len(req.EvidenceIDs))
+	for _, id := range req.EvidenceIDs {
+		evUUID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, ErrEvidenceNotInCase
+		}
+		e, err := s.evidence.FindEvidenceByID(evUUID)
+		if err != nil || e == nil || e.CaseID != caseUUID || e.TenantID.String() != req.TenantID {
+			return nil, ErrEvidenceNotInCase
+		}
+		out = append(out, *e)
+	}
+	return out, nil
+}
+
+// fetch loads evidence content, refusing files above maxScanBytes.
+func (s *service) fetch(e metadata.Evidence) ([]byte, error) {
+	rc, err := s.blobs.Download(e.IpfsCID)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxScanBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxScanBytes {
+		return nil, fmt.Errorf("larger than %d bytes", maxScanBytes)
+	}
+	return data, nil
+}
+
+// ─── YARA ───────────────────────────────────────────────────
+
+type scanItem struct {
+	childPath string
+	data      []byte
+}
+
+type compiledYARA struct {
+	rule  *DetectionRule
+	rules []*YaraRule
+}
+
+// ScanEvidenceYARA runs YARA rules over evidence files and, for ZIP evidence,
+// over every archive member. Each hit is recorded with its rule version and
+// string offsets, the evidence is tagged, and a pending timeline suggestion is created.
+func (s *service) ScanEvidenceYARA(ctx context.Context, req ScanRequest) (*ScanReport, error) {
+	rules, err := s.selectRules(req.TenantID, RuleKindYARA, req.RuleIDs)
+	if err != nil {
+		return nil, err
+	}
+	var compiled []compiledYARA
+	for _, r := range rules {
+		yr, err := CompileYARA(r.Content)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s v%d: %v", ErrInvalidRule, r.Name, r.Version, err)
+		}
+		compiled = append(compiled, compiledYARA{rule: r, rules: yr})
+	}
+
+	evidence, err := s.selectEvidence(req)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ScanReport{CaseID: req.CaseID, Kind: RuleKindYARA, RulesApplied: len(rules), Matches: []*RuleMatch{}, TagsAdded: []string{}}
+	iocs := newIOCCollector(s.iocs, req.TenantID, req.CaseID)
+
+	for _, ev := range evidence {
+		data, err := s.fetch(ev)
+		if err != nil {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("%s: %v", ev.Filename, err))
+			continue
+		}
+		items := append([]scanItem{{data: data}}, expandArchive(data, "", 1)...)
+		report.ItemsScanned += len(items)
+
+		tagSet := map[string]bool{}
+		for _, item := range items {
+			for _, c := range compiled {
+				for _, yr := range c.rules {
+					if yr.Private {
+						continue
+					}
+					hit := yr.Scan(item.data)
+					if hit == nil {
+						continue
+					}
+					report.Matches = append(report.Matches, s.yaraMatch(req, ev, item, c.rule, hit))
+
+					tagSet["yara:"+strings.ToLower(yr.Name)] = true
+					for _, t := range yr.Tags {
+						tagSet[strings.ToLower(t)] = true
+					}
+					if iocType := yr.Meta["ioc_type"]; iocType != "" {
+						for _, v := range hit.Values {
+							iocs.add(iocType, v)
+						}
+					}
+				}
+			}
+		}
+
+		if len(tagSet) > 0 {
+			tags := sortedKeys(tagSet)
+			userUUID, _ := uuid.Parse(req.UserID)
+			if err := s.tagger.TagEvidence(ctx, userUUID, ev.ID, tags); err != nil {
+				return nil, err
+			}
+			report.TagsAdded = mergeSorted(report.TagsAdded, tags)
+		}
+	}
+
+	if err := s.repo.CreateMatches(report.Matches); err != nil {
+		return nil, err
+	}
+	if report.IOCsAdded, err = iocs.flush(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *service) yaraMatch(req ScanRequest, ev metadata.Evidence, item scanItem, rule *DetectionRule, hit *YaraMatch) *RuleMatch {
+	offsets, _ := json.Marshal(hit.Offsets)
+	target := ev.Filename
+	if item.childPath != "" {
+		target += "!" + item.childPath
+	}
+	severity := strings.ToLower(hit.Rule.Meta["severity"])
+	switch severity {
+	case "low", "medium", "high", "critical":
+	default:
+		severity = "medium"
+	}
+	return &RuleMatch{
+		TenantID:         req.TenantID,
+		CaseID:           req.CaseID,
+		RuleID:           rule.ID,
+		RuleName:         hit.Rule.Name,
+		RuleVersion:      rule.Version,
+		Kind:             RuleKindYARA,
+		EvidenceID:       ev.ID.String(),
+		ChildPath:        item.childPath,
+		Offsets:          datatypes.JSON(offsets),
+		Summary:          fmt.Sprintf("YARA rule %s (%s v%d) matched %s", hit.Rule.Name, rule.Name, rule.Version, target),
+		Severity:         severity,
+		SuggestionStatus: SuggestionPending,
+		CreatedBy:        req.UserID,
+	}
+}
+
+// expandArchive returns the members of a ZIP archive (recursively, up to
+// maxArchiveDepth). Non-archives and unreadable members yield nothing.
+func expandArchive(data []byte, prefix string, depth int) []scanItem {
+	if depth > maxArchiveDepth || !bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return nil
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil
+	}
+	var out []scanItem
+	for i, f := range zr.File {
+		if i >= maxArchiveMembers {
+			break
+		}
+		if f.FileInfo().IsDir() || f.UncompressedSize64 > maxScanBytes {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			continue
+		}
+		member, err := io.ReadAll(io.LimitReader(rc, maxScanBytes))
+		rc.Close()
+		if err != nil {
+			continue
+		}
+		childPath := path.Join(prefix, f.Name)
+		out = append(out, scanItem{childPath: childPath, data: member})
+		out = append(out, expandArchive(member, childPath, depth+1)...)
+	}
+	return out
+}
+
+// ─── Sigma ──────────────────────────────────────────────────
+
+type compiledSigma struct {
+	rule  *DetectionRule
+	sigma *SigmaRule
+}
+
+// ScanLogsSigma evaluates Sigma rules over log evidence (JSON lines or plain
+// text, one record per line) and optionally over the case timeline.
+func (s *service) ScanLogsSigma(ctx context.Context, req ScanRequest) (*ScanReport, error) {
+	rules, err := s.selectRules(req.TenantID, RuleKindSigma, req.RuleIDs)
+	if err != nil {
+		return nil, err
+	}
+	var compiled []compiledSigma
+	for _, r := range rules {
+		sr, err := CompileSigma(r.Content)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s v%d: %v", ErrInvalidRule, r.Name, r.Version, err)
+		}
+		compiled = append(compiled, compiledSigma{rule: r, sigma: sr})
+	}
+
+	evidence, err := s.selectEvidence(req)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ScanReport{CaseID: req.CaseID, Kind: RuleKindSigma, RulesApplied: len(rules), Matches: []*RuleMatch{}, TagsAdded: []string{}}
+	iocs := newIOCCollector(s.iocs, req.TenantID, req.CaseID)
+
+	for _, ev := range evidence {
+		if !isLogEvidence(ev) {
+			continue
+		}
+		data, err := s.fetch(ev)
+		if err != nil {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("%s: %v", ev.Filename, err))
+			continue
+		}
+		records, err := ReadLogRecords(bytes.NewReader(data))
+		if err != nil {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("%s: %v", ev.Filename, err))
+			continue
+		}
+		report.ItemsScanned += len(records)
+
+		tagSet := map[string]bool{}
+		for _, c := range compiled {
+			var offsets []MatchOffset
+			for _, rec := range records {
+				if !c.sigma.Match(rec) {
+					continue
+				}
+				offsets = append(offsets, MatchOffset{Identifier: "line", Offset: rec.Line})
+				iocs.addSigmaFields(c.sigma.FieldValues(rec))
+			}
+			if len(offsets) == 0 {
+				continue
+			}
+			raw, _ := json.Marshal(offsets)
+			report.Matches = append(report.Matches, &RuleMatch{
+				TenantID:         req.TenantID,
+				CaseID:           req.CaseID,
+				RuleID:           c.rule.ID,
+				RuleName:         c.sigma.Title,
+				RuleVersion:      c.rule.Version,
+				Kind:             RuleKindSigma,
+				EvidenceID:       ev.ID.String(),
+				Offsets:          datatypes.JSON(raw),
+				Summary:          fmt.Sprintf("Sigma rule %q (v%d) matched %d record(s) in %s", c.sigma.Title, c.rule.Version, len(offsets), ev.Filename),
+				Severity:         c.sigma.Severity(),
+				SuggestionStatus: SuggestionPending,
+				CreatedBy:        req.UserID,
+			})
+			tagSet[sigmaTag(c.rule)] = true
+		}
+
+		if len(tagSet) > 0 {
+			tags := sortedKeys(tagSet)
+			userUUID, _ := uuid.Parse(req.UserID)
+			if err := s.tagger.TagEvidence(ctx, userUUID, ev.ID, tags); err != nil {
+				return nil, err
+			}
+			report.TagsAdded = mergeSorted(report.TagsAdded, tags)
+		}
+	}
+
+	if req.IncludeTimeline {
+		if err := s.scanTimeline(req, compiled, report, iocs); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.CreateMatches(report.Matches); err != nil {
+		return nil, err
+	}
+	if report.IOCsAdded, err = iocs.flush(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// scanTimeline matches Sigma rules against existing timeline events and tags the
+// matching events. These hits are already on the timeline, so they carry no suggestion.
+func (s *service) scanTimeline(req ScanRequest, compiled []compiledSigma, report *ScanReport, iocs *iocCollector) error {
+	events, err := s.timeline.ListEvents(req.CaseID)
+	if err != nil {
+		return err
+	}
+	report.ItemsScanned += len(events)
+
+	for _, ev := range events {
+		rec := timelineRecord(ev)
+		var newTags []string
+		for _, c := range compiled {
+			if !c.sigma.Match(rec) {
+				continue
+			}
+			report.Matches = append(report.Matches, &RuleMatch{
+				TenantID:         req.TenantID,
+				CaseID:           req.CaseID,
+				RuleID:           c.rule.ID,
+				RuleName:         c.sigma.Title,
+				RuleVersion:      c.rule.Version,
+				Kind:             RuleKindSigma,
+				TimelineEventID:  ev.ID,
+				Offsets:          datatypes.JSON([]byte("[]")),
+				Summary:          fmt.Sprintf("Sigma rule %q (v%d) matched timeline event: %s", c.sigma.Title, c.rule.Version, ev.Description),
+				Severity:         c.sigma.Severity(),
+				SuggestionStatus: SuggestionNone,
+				CreatedBy:        req.UserID,
+			})
+			newTags = append(newTags, sigmaTag(c.rule))
+			iocs.addSigmaFields(c.sigma.FieldValues(rec))
+		}
+		if len(newTags) == 0 {
+			continue
+		}
+		added, err := s.tagTimelineEvent(ev.ID, newTags)
+		if err != nil {
+			return err
+		}
+		report.TagsAdded = mergeSorted(report.TagsAdded, added)
+	}
+	return nil
+}
+
+func (s *service) tagTimelineEvent(eventID string, tags []string) ([]string, error) {
+	event, err := s.timeline.GetEvent(eventID)
+	if err != nil {
+		return nil, err
+	}
+	var existing []string
+	_ = json.Unmarshal(event.Tags, &existing)
+	have := map[string]bool{}
+	for _, t := range existing {
+		have[t] = true
+	}
+	var added []string
+	for _, t := range tags {
+		if !have[t] {
+			have[t] = true
+			existing = append(existing, t)
+			added = append(added, t)
+		}
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+	raw, _ := json.Marshal(existing)
+	event.Tags = datatypes.JSON(raw)
+	if _, err := s.timeline.UpdateEvent(event); err != nil {
+		return nil, err
+	}
+	sort.Strings(added)
+	return added, nil
+}
+
+func timelineRecord(ev *timeline.TimelineEventResponse) *LogRecord {
+	rec := &LogRecord{Fields: map[string][]string{
+		"id":           {ev.ID},
+		"description":  {ev.Description},
+		"severity":     {ev.Severity},
+		"analyst_name": {ev.AnalystName},
+	}}
+	var tags []string
+	if json.Unmarshal(ev.Tags, &tags) == nil {
+		rec.Fields["tags"] = tags
+	}
+	var evidence []interface{}
+	if json.Unmarshal(ev.Evidence, &evidence) == nil {
+		for _, e := range evidence {
+			flattenInto(rec.Fields, "evidence", e)
+		}
+	}
+	return rec
+}
+
+func isLogEvidence(e metadata.Evidence) bool {
+	if logExtensions[strings.ToLower(path.Ext(e.Filename))] {
+		return true
+	}
+	ft := strings.ToLower(e.FileType)
+	return strings.Contains(ft, "json") || strings.HasPrefix(ft, "text/")
+}
+
+func sigmaTag(rule *DetectionRule) string {
+	return "sigma:" + strings.ToLower(strings.Join(strings.Fields(rule.Name), "_"))
+}
+
+// ─── Matches & suggestions ──────────────────────────────────
+
+func (s *service) ListMatches(tenantID, caseID string) ([]*RuleMatch, error) {
+	return s.repo.ListMatchesByCase(tenantID, caseID)
+}
+
+func (s *service) caseMatch(tenantID, caseID, matchID string) (*RuleMatch, error) {
+	m, err := s.repo.GetMatch(tenantID, matchID)
+	if err != nil {
+		return nil, err
+	}
+	if m.CaseID != caseID {
+		return nil, ErrMatchNotInCase
+	}
+	if m.SuggestionStatus != SuggestionPending {
+		return nil, ErrSuggestionProcessed
+	}
+	return m, nil
+}
+
+// AcceptSuggestion turns a pending match into a timeline event that references
+// the evidence and the rule that fired.
+func (s *service) AcceptSuggestion(tenantID, caseID, matchID, userID, userName string) (*timeline.TimelineEvent, error) {
+	m, err := s.caseMatch(tenantID, caseID, matchID)
+	if err != nil {
+		return nil, err
+	}
+
+	evidence := []string{}
+	if m.EvidenceID != "" {
+		evidence = append(evidence, m.EvidenceID)
+	}
+	evRaw, _ := json.Marshal(evidence)
+	tagRaw, _ := json.Marshal([]string{string(m.Kind) + ":" + strings.ToLower(m.RuleName)})
+
+	event, err := s.timeline.AddEvent(&timeline.TimelineEvent{
+		CaseID:      caseID,
+		Description: m.Summary,
+		Evidence:    datatypes.JSON(evRaw),
+		Tags:        datatypes.JSON(tagRaw),
+		Severity:    m.Severity,
+		AnalystID:   userID,
+		AnalystName: userName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.SuggestionStatus = SuggestionAccepted
+	m.TimelineEventID = event.ID
+	if err := s.repo.UpdateMatch(m); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (s *service) DismissSuggestion(tenantID, caseID, matchID string) error {
+	m, err := s.caseMatch(tenantID, caseID, matchID)
+	if err != nil {
+		return err
+	}
+	m.SuggestionStatus = SuggestionDismissed
+	return s.repo.UpdateMatch(m)
+}
+
+// ─── Helpers ────────────────────────────────────────────────
+
+// iocCollector de-duplicates indicators within a scan and against those already on the case.
+type iocCollector struct {
+	recorder         IOCRecorder
+	tenantID, caseID string
+	pending          map[string]graphicalmapping.IOC
+}
+
+func newIOCCollector(r IOCRecorder, tenantID, caseID string) *iocCollector {
+	return &iocCollector{recorder: r, tenantID: tenantID, caseID: caseID, pending: map[string]graphicalmapping.IOC{}}
+}
+
+func (c *iocCollector) add(iocType, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" || len(value) > 255 {
+		return
+	}
+	key := strings.ToLower(iocType) + "\x00" + value
+	c.pending[key] = graphicalmapping.IOC{TenantID: c.tenantID, CaseID: c.caseID, Type: iocType, Value: value}
+}
+
+// sigmaIOCFields maps the Sigma field names that carry indicators, in
+// lower case, to IOC types. Other `fields:` entries are display columns.
+var sigmaIOCFields = map[string]string{
+	"ipaddress": "ip", "ip": "ip", "sourceip": "ip", "destinationip": "ip", "src_ip": "ip", "dst_ip": "ip",
+	"clientip": "ip", "c-ip": "ip", "remoteaddress": "ip", "sourceaddress": "ip", "destinationaddress": "ip",
+	"queryname": "domain", "destinationhostname": "domain", "domain": "domain", "cs-host": "domain",
+	"url": "url", "c-uri": "url", "cs-uri": "url", "requesturl": "url",
+	"hashes": "hash", "hash": "hash", "md5": "hash", "sha1": "hash", "sha256": "hash", "imphash": "hash",
+	"email": "email", "sender": "email", "recipient": "email",
+}
+
+// addSigmaFields records the values of indicator fields. Sysmon-style
+// "SHA256=…,MD5=…" hash lists are split into their hashes.
+func (c *iocCollector) addSigmaFields(fields map[string][]string) {
+	for field, vals := range fields {
+		iocType := sigmaIOCFields[strings.ToLower(field)]
+		if iocType == "" {
+			continue
+		}
+		for _, v := range vals {
+			if iocType != "hash" {
+				c.add(iocType, v)
+				continue
+			}
+			for _, h := range strings.Split(v, ",") {
+				if _, value, ok := strings.Cut(h, "="); ok {
+					h = value
+				}
+				c.add(iocType, h)
+			}
+		}
+	}
+}
+
+func (c *iocCollector) flush() (int, error) {
+	if len(c.pending) == 0 {
+		return 0, nil
+	}
+	existing, err := c.recorder.ListIOCsByCase(c.caseID)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range existing {
+		delete(c.pending, strings.ToLower(e.Type)+"\x00"+e.Value)
+	}
+
+	added := 0
+	for _, key := range sortedKeys(c.pending) {
+		ioc := c.pending[key]
+		if _, err := c.recorder.AddIOC(&ioc); err != nil {
+			return added, err
+		}
+		added++
+	}
+	return added, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func mergeSorted(a, b []string) []string {
+	set := map[string]bool{}
+	for _, s := range a {
+		set[s] = true
+	}
+	for _, s := range b {
+		set[s] = true
+	}
+	return sortedKeys(set)
+}
@@ -0,0 +1,563 @@
+package detection_rules
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// This file implements Sigma detection evaluation against flat records built
+// from timeline events and imported JSON-lines/text logs. Field names and values
+// are compared case-insensitively, as most Sigma backends do. Aggregations
+// ("| count() by ...") and correlation rules are not supported.
+
+// SigmaRule is a compiled Sigma rule.
+type SigmaRule struct {
+	Title     string
+	RuleID    string
+	Level     string
+	Tags      []string
+	Fields    []string
+	selection map[string]*sigmaSelection
+	condition sigmaExpr
+}
+
+// LogRecord is one event flattened into field -> values. Keys are lower-cased;
+// nested JSON objects use dotted keys.
+type LogRecord struct {
+	Line   int64
+	Fields map[string][]string
+}
+
+type sigmaDoc struct {
+	Title     string                 `yaml:"title"`
+	ID        string                 `yaml:"id"`
+	Level     string                 `yaml:"level"`
+	Tags      []string               `yaml:"tags"`
+	Fields    []string               `yaml:"fields"`
+	Detection map[string]interface{} `yaml:"detection"`
+}
+
+// CompileSigma parses a single Sigma YAML document.
+func CompileSigma(src string) (*SigmaRule, error) {
+	var doc sigmaDoc
+	if err := yaml.Unmarshal([]byte(src), &doc); err != nil {
+		return nil, fmt.Errorf("sigma: %w", err)
+	}
+	if strings.TrimSpace(doc.Title) == "" {
+		return nil, fmt.Errorf("sigma: title is required")
+	}
+	if len(doc.Detection) == 0 {
+		return nil, fmt.Errorf("sigma: detection is required")
+	}
+
+	rule := &SigmaRule{
+		Title:     doc.Title,
+		RuleID:    doc.ID,
+		Level:     strings.ToLower(doc.Level),
+		Tags:      doc.Tags,
+		Fields:    doc.Fields,
+		selection: map[string]*sigmaSelection{},
+	}
+
+	var conditions []string
+	for name, raw := range doc.Detection {
+		if name == "condition" {
+			switch v := raw.(type) {
+			case string:
+				conditions = append(conditions, v)
+			case []interface{}:
+				for _, c := range v {
+					conditions = append(conditions, fmt.Sprint(c))
+				}
+			default:
+				return nil, fmt.Errorf("sigma: condition must be a string or list")
+			}
+			continue
+		}
+		if name == "timeframe" {
+			return nil, fmt.Errorf("sigma: timeframe aggregations are not supported")
+		}
+		sel, err := compileSelection(raw)
+		if err != nil {
+			return nil, fmt.Errorf("sigma: selection %q: %w", name, err)
+		}
+		rule.selection[name] = sel
+	}
+	if len(conditions) == 0 {
+		return nil, fmt.Errorf("sigma: detection.condition is required")
+	}
+
+	var expr sigmaExpr
+	for _, c := range conditions {
+		e, err := parseSigmaCondition(c, rule.selection)
+		if err != nil {
+			return nil, err
+		}
+		if expr == nil {
+			expr = e
+		} else {
+			expr = sigmaOr{expr, e}
+		}
+	}
+	rule.condition = expr
+	return rule, nil
+}
+
+// Severity maps the Sigma level onto the timeline severity scale.
+func (r *SigmaRule) Severity() string {
+	switch r.Level {
+	case "critical", "high", "medium", "low":
+		return r.Level
+	case "informational":
+		return "low"
+	}
+	return "medium"
+}
+
+// Match reports whether the record satisfies the rule condition.
+func (r *SigmaRule) Match(rec *LogRecord) bool {
+	return r.condition.eval(rec)
+}
+
+// FieldValues returns the values of the rule's `fields:` entries present on rec.
+func (r *SigmaRule) FieldValues(rec *LogRecord) map[string][]string {
+	out := map[string][]string{}
+	for _, f := range r.Fields {
+		if vals := rec.Fields[strings.ToLower(f)]; len(vals) > 0 {
+			out[f] = vals
+		}
+	}
+	return out
+}
+
+// ─── Selections ─────────────────────────────────────────────
+
+// sigmaSelection matches if any keyword matches, or any of its field groups
+// matches in full (a list of maps is OR'd; the fields within a map are AND'd).
+type sigmaSelection struct {
+	keywords []*sigmaValue
+	groups   [][]*sigmaField
+}
+
+type sigmaField struct {
+	name   string
+	all    bool
+	null   bool
+	values []*sigmaValue
+}
+
+type sigmaValue struct {
+	literal string
+	re      *regexp.Regexp
+}
+
+func (v *sigmaValue) match(s string) bool {
+	if v.re != nil {
+		return v.re.MatchString(s)
+	}
+	return strings.ToLower(s) == v.literal
+}
+
+func compileSelection(raw interface{}) (*sigmaSelection, error) {
+	sel := &sigmaSelection{}
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		g, err := compileFieldGroup(v)
+		if err != nil {
+			return nil, err
+		}
+		sel.groups = append(sel.groups, g)
+	case []interface{}:
+		for _, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				g, err := compileFieldGroup(m)
+				if err != nil {
+					return nil, err
+				}
+				sel.groups = append(sel.groups, g)
+				continue
+			}
+			kw, err := compileValue(fmt.Sprint(item), "contains")
+			if err != nil {
+				return nil, err
+			}
+			sel.keywords = append(sel.keywords, kw)
+		}
+	default:
+		kw, err := compileValue(fmt.Sprint(v), "contains")
+		if err != nil {
+			return nil, err
+		}
+		sel.keywords = append(sel.keywords, kw)
+	}
+	return sel, nil
+}
+
+func compileFieldGroup(m map[string]interface{}) ([]*sigmaField, error) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var group []*sigmaField
+	for _, key := range keys {
+		parts := strings.Split(key, "|")
+		f := &sigmaField{name: strings.ToLower(parts[0])}
+		op := "equals"
+		for _, mod := range parts[1:] {
+			switch mod {
+			case "contains", "startswith", "endswith", "re":
+				op = mod
+			case "all":
+				f.all = true
+			default:
+				return nil, fmt.Errorf("unsupported modifier %q", mod)
+			}
+		}
+
+		var raws []interface{}
+		switch v := m[key].(type) {
+		case nil:
+			f.null = true
+		case []interface{}:
+			raws = v
+		default:
+			raws = []interface{}{v}
+		}
+		for _, r := range raws {
+			val, err := compileValue(fmt.Sprint(r), op)
+			if err != nil {
+				return nil, err
+			}
+			f.values = append(f.values, val)
+		}
+		group = append(group, f)
+	}
+	return group, nil
+}
+
+// compileValue turns a Sigma value plus modifier into a matcher. Plain values
+// honour the * and ? wildcards.
+func compileValue(s, op string) (*sigmaValue, error) {
+	if op == "re" {
+		re, err := regexp.Compile("(?i)" + s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %w", s, err)
+		}
+		return &sigmaValue{re: re}, nil
+	}
+
+	var pattern string
+	switch op {
+	case "contains":
+		pattern = "*" + s + "*"
+	case "startswith":
+		pattern = s + "*"
+	case "endswith":
+		pattern = "*" + s
+	default:
+		pattern = s
+	}
+	if !strings.ContainsAny(pattern, "*?") {
+		return &sigmaValue{literal: strings.ToLower(s)}, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("(?is)^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '\\':
+			if i+1 < len(pattern) && strings.ContainsRune("*?\\", rune(pattern[i+1])) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			} else {
+				sb.WriteString(`\\`)
+			}
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return &sigmaValue{re: regexp.MustCompile(sb.String())}, nil
+}
+
+func (s *sigmaSelection) eval(rec *LogRecord) bool {
+	for _, kw := range s.keywords {
+		for _, vals := range rec.Fields {
+			for _, v := range vals {
+				if kw.match(v) {
+					return true
+				}
+			}
+		}
+	}
+	for _, g := range s.groups {
+		if groupMatches(g, rec) {
+			return true
+		}
+	}
+	return false
+}
+
+func groupMatches(group []*sigmaField, rec *LogRecord) bool {
+	for _, f := range group {
+		if !f.matches(rec.Fields[f.name]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *sigmaField) matches(vals []string) bool {
+	if f.null {
+		for _, v := range vals {
+			if v != "" {
+				return false
+			}
+		}
+		return true
+	}
+	anyValue := func(pattern *sigmaValue) bool {
+		for _, v := range vals {
+			if pattern.match(v) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, p := range f.values {
+		hit := anyValue(p)
+		if f.all && !hit {
+			return false
+		}
+		if !f.all && hit {
+			return true
+		}
+	}
+	return f.all
+}
+
+// ─── Conditions ─────────────────────────────────────────────
+
+type sigmaExpr interface {
+	eval(rec *LogRecord) bool
+}
+
+type (
+	sigmaRef struct{ sel *sigmaSelection }
+	sigmaNot struct{ x sigmaExpr }
+	sigmaAnd struct{ l, r sigmaExpr }
+	sigmaOr  struct{ l, r sigmaExpr }
+	sigmaOf  struct {
+		all  bool
+		sels []*sigmaSelection
+	}
+)
+
+func (e sigmaRef) eval(rec *LogRecord) bool { return e.sel.eval(rec) }
+func (e sigmaNot) eval(rec *LogRecord) bool { return !e.x.eval(rec) }
+func (e sigmaAnd) eval(rec *LogRecord) bool { return e.l.eval(rec) && e.r.eval(rec) }
+func (e sigmaOr) eval(rec *LogRecord) bool  { return e.l.eval(rec) || e.r.eval(rec) }
+
+func (e sigmaOf) eval(rec *LogRecord) bool {
+	for _, s := range e.sels {
+		hit := s.eval(rec)
+		if e.all && !hit {
+			return false
+		}
+		if !e.all && hit {
+			return true
+		}
+	}
+	return e.all
+}
+
+type sigmaCondParser struct {
+	toks []string
+	pos  int
+	sels map[string]*sigmaSelection
+}
+
+func parseSigmaCondition(cond string, sels map[string]*sigmaSelection) (sigmaExpr, error) {
+	if strings.Contains(cond, "|") {
+		return nil, fmt.Errorf("sigma: aggregation conditions are not supported")
+	}
+	cond = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(cond)
+	p := &sigmaCondParser{toks: strings.Fields(cond), sels: sels}
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.toks) {
+		return nil, fmt.Errorf("sigma: unexpected %q in condition", p.toks[p.pos])
+	}
+	return expr, nil
+}
+
+func (p *sigmaCondParser) next() string {
+	if p.pos >= len(p.toks) {
+		return ""
+	}
+	t := p.toks[p.pos]
+	p.pos++
+	return t
+}
+
+func (p *sigmaCondParser) accept(word string) bool {
+	if p.pos < len(p.toks) && strings.EqualFold(p.toks[p.pos], word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *sigmaCondParser) or() (sigmaExpr, error) {
+	l, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("or") {
+		r, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l = sigmaOr{l, r}
+	}
+	return l, nil
+}
+
+func (p *sigmaCondParser) and() (sigmaExpr, error) {
+	l, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("and") {
+		r, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		l = sigmaAnd{l, r}
+	}
+	return l, nil
+}
+
+func (p *sigmaCondParser) not() (sigmaExpr, error) {
+	if p.accept("not") {
+		x, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return sigmaNot{x}, nil
+	}
+	return p.primary()
+}
+
+func (p *sigmaCondParser) primary() (sigmaExpr, error) {
+	tok := p.next()
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("sigma: unexpected end of condition")
+	case tok == "(":
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("sigma: missing ')' in condition")
+		}
+		return x, nil
+	case tok == "1" || strings.EqualFold(tok, "any") || strings.EqualFold(tok, "all"):
+		if !p.accept("of") {
+			return nil, fmt.Errorf("sigma: expected 'of' after %q", tok)
+		}
+		target := p.next()
+		var sels []*sigmaSelection
+		for name, s := range p.sels {
+			if target == "them" || matchesSelectionPattern(target, name) {
+				sels = append(sels, s)
+			}
+		}
+		if len(sels) == 0 {
+			return nil, fmt.Errorf("sigma: %q matches no selections", target)
+		}
+		return sigmaOf{all: strings.EqualFold(tok, "all"), sels: sels}, nil
+	}
+	sel, ok := p.sels[tok]
+	if !ok {
+		return nil, fmt.Errorf("sigma: unknown selection %q", tok)
+	}
+	return sigmaRef{sel}, nil
+}
+
+func matchesSelectionPattern(pattern, name string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(name, prefix)
+	}
+	return pattern == name
+}
+
+// ─── Records ────────────────────────────────────────────────
+
+// maxLogLineBytes bounds a single log line; longer lines are truncated by the scanner.
+const maxLogLineBytes = 1 << 20
+
+// ReadLogRecords parses JSON-lines input into records. Lines that are not JSON
+// objects are kept as {"message": line} so keyword selections still apply.
+func ReadLogRecords(r io.Reader) ([]*LogRecord, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxLogLineBytes)
+	var out []*LogRecord
+	var line int64
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		rec := &LogRecord{Line: line, Fields: map[string][]string{}}
+		var obj map[string]interface{}
+		if strings.HasPrefix(text, "{") && json.Unmarshal([]byte(text), &obj) == nil {
+			flattenInto(rec.Fields, "", obj)
+		} else {
+			rec.Fields["message"] = []string{text}
+		}
+		out = append(out, rec)
+	}
+	return out, sc.Err()
+}
+
+func flattenInto(dst map[string][]string, prefix string, v interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			key := strings.ToLower(k)
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			flattenInto(dst, key, child)
+		}
+	case []interface{}:
+		for _, child := range t {
+			flattenInto(dst, prefix, child)
+		}
+	case nil:
+		dst[prefix] = append(dst[prefix], "")
+	case float64:
+		dst[prefix] = append(dst[prefix], strconv.FormatFloat(t, 'f', -1, 64))
+	default:
+		dst[prefix] = append(dst[prefix], fmt.Sprint(t))
+	}
+}
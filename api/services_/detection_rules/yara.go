@@ -0,0 +1,1029 @@
+package detection_rules
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// This file implements the subset of the YARA language our analysts use for
+// triage: text, hex and regex strings plus boolean/count/offset conditions.
+// Module imports (pe, elf, math ...) are rejected at upload time rather than
+// silently evaluated as false.
+
+// maxOffsetsPerString bounds how many hits are recorded for a single string.
+const maxOffsetsPerString = 100
+
+type yaraStringKind int
+
+const (
+	yaraText yaraStringKind = iota
+	yaraHex
+	yaraRegex
+)
+
+type yaraString struct {
+	id      string // includes the leading '$'
+	kind    yaraStringKind
+	text    []byte
+	nocase  bool
+	wide    bool
+	ascii   bool
+	hex     []hexElem
+	re      *regexp.Regexp
+	private bool
+}
+
+// YaraRule is one compiled rule from a source file.
+type YaraRule struct {
+	Name      string
+	Tags      []string
+	Meta      map[string]string
+	Private   bool
+	strings   []*yaraString
+	condition yaraExpr
+}
+
+// YaraMatch is the result of a rule that evaluated true against a buffer.
+type YaraMatch struct {
+	Rule    *YaraRule
+	Offsets []MatchOffset
+	// Values holds the matched bytes of text and regex strings, used for IOC extraction.
+	Values []string
+}
+
+// ─── Parsing ────────────────────────────────────────────────
+
+type yaraParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+// CompileYARA parses a YARA source file into rules.
+func CompileYARA(src string) ([]*YaraRule, error) {
+	p := &yaraParser{src: src, line: 1}
+	var rules []*YaraRule
+	for {
+		p.skipSpace()
+		if p.eof() {
+			break
+		}
+		word := p.ident()
+		switch word {
+		case "import", "include":
+			return nil, p.errorf("%s statements are not supported", word)
+		case "private", "global":
+			p.skipSpace()
+			if p.ident() != "rule" {
+				return nil, p.errorf("expected 'rule' after %q", word)
+			}
+			r, err := p.rule()
+			if err != nil {
+				return nil, err
+			}
+			r.Private = word == "private"
+			rules = append(rules, r)
+		case "rule":
+			r, err := p.rule()
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, r)
+		default:
+			return nil, p.errorf("expected 'rule', got %q", word)
+		}
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("yara: no rules found")
+	}
+	seen := map[string]bool{}
+	for _, r := range rules {
+		if seen[r.Name] {
+			return nil, fmt.Errorf("yara: duplicate rule name %q", r.Name)
+		}
+		seen[r.Name] = true
+	}
+	return rules, nil
+}
+
+func (p *yaraParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("yara line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *yaraParser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *yaraParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *yaraParser) advance() byte {
+	c := p.src[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+func (p *yaraParser) skipSpace() {
+	for !p.eof() {
+		c := p.peek()
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			p.advance()
+		case strings.HasPrefix(p.src[p.pos:], "//"):
+			for !p.eof() && p.peek() != '\n' {
+				p.advance()
+			}
+		case strings.HasPrefix(p.src[p.pos:], "/*"):
+			end := strings.Index(p.src[p.pos+2:], "*/")
+			stop := len(p.src)
+			if end >= 0 {
+				stop = p.pos + 2 + end + 2
+			}
+			for p.pos < stop {
+				p.advance()
+			}
+		default:
+			return
+		}
+	}
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func (p *yaraParser) ident() string {
+	start := p.pos
+	for !p.eof() && isIdentByte(p.peek()) {
+		p.advance()
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *yaraParser) expect(c byte) error {
+	p.skipSpace()
+	if p.peek() != c {
+		return p.errorf("expected %q", c)
+	}
+	p.advance()
+	return nil
+}
+
+func (p *yaraParser) rule() (*YaraRule, error) {
+	p.skipSpace()
+	r := &YaraRule{Name: p.ident(), Meta: map[string]string{}}
+	if r.Name == "" {
+		return nil, p.errorf("rule name expected")
+	}
+	p.skipSpace()
+	if p.peek() == ':' {
+		p.advance()
+		for {
+			p.skipSpace()
+			tag := p.ident()
+			if tag == "" {
+				break
+			}
+			r.Tags = append(r.Tags, tag)
+		}
+	}
+	if err := p.expect('{'); err != nil {
+		return nil, err
+	}
+
+	for {
+		p.skipSpace()
+		if p.peek() == '}' {
+			p.advance()
+			break
+		}
+		section := p.ident()
+		if err := p.expect(':'); err != nil {
+			return nil, err
+		}
+		var err error
+		switch section {
+		case "meta":
+			err = p.meta(r)
+		case "strings":
+			err = p.strings(r)
+		case "condition":
+			err = p.condition(r)
+		default:
+			err = p.errorf("unknown section %q", section)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if r.condition == nil {
+		return nil, fmt.Errorf("yara: rule %q has no condition", r.Name)
+	}
+	return r, nil
+}
+
+// sectionEnds reports whether the next word starts a new section or closes the rule.
+func (p *yaraParser) sectionEnds() bool {
+	p.skipSpace()
+	if p.peek() == '}' || p.eof() {
+		return true
+	}
+	rest := p.src[p.pos:]
+	for _, s := range []string{"meta", "strings", "condition"} {
+		if strings.HasPrefix(rest, s) {
+			after := strings.TrimLeft(rest[len(s):], " \t")
+			if strings.HasPrefix(after, ":") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *yaraParser) meta(r *YaraRule) error {
+	for !p.sectionEnds() {
+		key := p.ident()
+		if key == "" {
+			return p.errorf("meta key expected")
+		}
+		if err := p.expect('='); err != nil {
+			return err
+		}
+		p.skipSpace()
+		if p.peek() == '"' {
+			s, err := p.quoted()
+			if err != nil {
+				return err
+			}
+			r.Meta[key] = string(s)
+			continue
+		}
+		start := p.pos
+		for !p.eof() && (isIdentByte(p.peek()) || p.peek() == '-') {
+			p.advance()
+		}
+		if start == p.pos {
+			return p.errorf("meta value expected for %q", key)
+		}
+		r.Meta[key] = p.src[start:p.pos]
+	}
+	return nil
+}
+
+func (p *yaraParser) quoted() ([]byte, error) {
+	p.advance() // opening quote
+	var out []byte
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return nil, p.errorf("unterminated string")
+		}
+		c := p.advance()
+		switch c {
+		case '"':
+			return out, nil
+		case '\\':
+			if p.eof() {
+				return nil, p.errorf("unterminated escape")
+			}
+			e := p.advance()
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 't':
+				out = append(out, '\t')
+			case 'r':
+				out = append(out, '\r')
+			case '"', '\\':
+				out = append(out, e)
+			case 'x':
+				if p.pos+2 > len(p.src) {
+					return nil, p.errorf("bad \\x escape")
+				}
+				b, err := strconv.ParseUint(p.src[p.pos:p.pos+2], 16, 8)
+				if err != nil {
+					return nil, p.errorf("bad \\x escape")
+				}
+				p.pos += 2
+				out = append(out, byte(b))
+			default:
+				return nil, p.errorf("unknown escape \\%c", e)
+			}
+		default:
+			out = append(out, c)
+		}
+	}
+}
+
+func (p *yaraParser) strings(r *YaraRule) error {
+	for !p.sectionEnds() {
+		if p.peek() != '$' {
+			return p.errorf("string identifier expected")
+		}
+		p.advance()
+		s := &yaraString{id: "$" + p.ident()}
+		if s.id == "$" {
+			return p.errorf("anonymous strings are not supported")
+		}
+		for _, existing := range r.strings {
+			if existing.id == s.id {
+				return p.errorf("duplicate string %s", s.id)
+			}
+		}
+		if err := p.expect('='); err != nil {
+			return err
+		}
+		p.skipSpace()
+
+		var err error
+		switch p.peek() {
+		case '"':
+			s.kind = yaraText
+			s.text, err = p.quoted()
+			if err == nil && len(s.text) == 0 {
+				err = p.errorf("empty string %s", s.id)
+			}
+		case '{':
+			s.kind = yaraHex
+			s.hex, err = p.hexString()
+		case '/':
+			s.kind = yaraRegex
+			s.re, err = p.regex()
+		default:
+			err = p.errorf("string value expected for %s", s.id)
+		}
+		if err != nil {
+			return err
+		}
+
+		for {
+			p.skipSpace()
+			if p.peek() == '$' || p.sectionEnds() {
+				break
+			}
+			mod := p.ident()
+			switch mod {
+			case "nocase":
+				s.nocase = true
+			case "wide":
+				s.wide = true
+			case "ascii":
+				s.ascii = true
+			case "private":
+				s.private = true
+			case "fullword":
+				// accepted for compatibility; treated as a plain match
+			default:
+				return p.errorf("unsupported string modifier %q", mod)
+			}
+		}
+		if s.kind == yaraText && s.nocase {
+			s.text = asciiLower(s.text)
+		}
+		if s.kind == yaraRegex && s.nocase {
+			s.re = regexp.MustCompile("(?i)" + s.re.String())
+		}
+		r.strings = append(r.strings, s)
+	}
+	return nil
+}
+
+func (p *yaraParser) regex() (*regexp.Regexp, error) {
+	p.advance() // opening slash
+	var sb strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return nil, p.errorf("unterminated regular expression")
+		}
+		c := p.advance()
+		if c == '\\' && p.peek() == '/' {
+			sb.WriteByte(p.advance())
+			continue
+		}
+		if c == '\\' && !p.eof() {
+			sb.WriteByte(c)
+			sb.WriteByte(p.advance())
+			continue
+		}
+		if c == '/' {
+			break
+		}
+		sb.WriteByte(c)
+	}
+	flags := ""
+	for !p.eof() && (p.peek() == 'i' || p.peek() == 's') {
+		flags += string(p.advance())
+	}
+	expr := sb.String()
+	if flags != "" {
+		expr = "(?" + flags + ")" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, p.errorf("invalid regular expression: %v", err)
+	}
+	return re, nil
+}
+
+// hexElem is either a masked byte or a jump of jumpMin..jumpMax bytes.
+type hexElem struct {
+	value, mask      byte
+	isJump           bool
+	jumpMin, jumpMax int
+}
+
+// maxHexJump caps open-ended jumps such as [4-].
+const maxHexJump = 4096
+
+func (p *yaraParser) hexString() ([]hexElem, error) {
+	p.advance() // opening brace
+	var elems []hexElem
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil, p.errorf("unterminated hex string")
+		}
+		c := p.peek()
+		if c == '}' {
+			p.advance()
+			break
+		}
+		if c == '[' {
+			p.advance()
+			end := strings.IndexByte(p.src[p.pos:], ']')
+			if end < 0 {
+				return nil, p.errorf("unterminated jump")
+			}
+			spec := strings.ReplaceAll(p.src[p.pos:p.pos+end], " ", "")
+			p.pos += end + 1
+			j := hexElem{isJump: true}
+			lo, hi, found := strings.Cut(spec, "-")
+			var err error
+			if j.jumpMin, err = strconv.Atoi(lo); err != nil && lo != "" {
+				return nil, p.errorf("invalid jump [%s]", spec)
+			}
+			switch {
+			case !found:
+				j.jumpMax = j.jumpMin
+			case hi == "":
+				j.jumpMax = maxHexJump
+			default:
+				if j.jumpMax, err = strconv.Atoi(hi); err != nil || j.jumpMax < j.jumpMin {
+					return nil, p.errorf("invalid jump [%s]", spec)
+				}
+			}
+			elems = append(elems, j)
+			continue
+		}
+		if c == '(' || c == '|' || c == '~' {
+			return nil, p.errorf("hex alternatives and negation are not supported")
+		}
+		if p.pos+2 > len(p.src) {
+			return nil, p.errorf("truncated hex byte")
+		}
+		e, ok := parseHexByte(p.src[p.pos], p.src[p.pos+1])
+		if !ok {
+			return nil, p.errorf("invalid hex byte %q", p.src[p.pos:p.pos+2])
+		}
+		p.pos += 2
+		elems = append(elems, e)
+	}
+	if len(elems) == 0 || elems[0].isJump || elems[len(elems)-1].isJump {
+		return nil, p.errorf("hex strings must start and end with a byte")
+	}
+	return elems, nil
+}
+
+func parseHexByte(hi, lo byte) (hexElem, bool) {
+	var e hexElem
+	for i, c := range []byte{hi, lo} {
+		shift := uint(4 * (1 - i))
+		if c == '?' {
+			continue
+		}
+		v, err := strconv.ParseUint(string(c), 16, 8)
+		if err != nil {
+			return e, false
+		}
+		e.value |= byte(v) << shift
+		e.mask |= 0xF << shift
+	}
+	return e, true
+}
+
+// ─── Condition expressions ──────────────────────────────────
+
+type yaraScanCtx struct {
+	data    []byte
+	matches map[string][]MatchOffset
+}
+
+// yaraExpr evaluates to an integer; boolean results are 0 or 1.
+type yaraExpr interface {
+	eval(ctx *yaraScanCtx) int64
+}
+
+type (
+	yaraConst    int64
+	yaraFilesize struct{}
+	yaraStrRef   struct{ id string }
+	yaraCount    struct{ id string }
+	yaraAt       struct {
+		id  string
+		pos yaraExpr
+	}
+	yaraIn struct {
+		id     string
+		lo, hi yaraExpr
+	}
+	yaraNot   struct{ x yaraExpr }
+	yaraBinOp struct {
+		op   string
+		l, r yaraExpr
+	}
+	yaraOf struct {
+		quantifier string // "all", "any", "none" or "" when n is used
+		n          yaraExpr
+		ids        []string
+	}
+)
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (c yaraConst) eval(*yaraScanCtx) int64      { return int64(c) }
+func (yaraFilesize) eval(ctx *yaraScanCtx) int64 { return int64(len(ctx.data)) }
+func (s yaraStrRef) eval(ctx *yaraScanCtx) int64 { return boolInt(len(ctx.matches[s.id]) > 0) }
+func (s yaraCount) eval(ctx *yaraScanCtx) int64  { return int64(len(ctx.matches[s.id])) }
+func (n yaraNot) eval(ctx *yaraScanCtx) int64    { return boolInt(n.x.eval(ctx) == 0) }
+
+func (a yaraAt) eval(ctx *yaraScanCtx) int64 {
+	want := a.pos.eval(ctx)
+	for _, m := range ctx.matches[a.id] {
+		if m.Offset == want {
+			return 1
+		}
+	}
+	return 0
+}
+
+func (a yaraIn) eval(ctx *yaraScanCtx) int64 {
+	lo, hi := a.lo.eval(ctx), a.hi.eval(ctx)
+	for _, m := range ctx.matches[a.id] {
+		if m.Offset >= lo && m.Offset <= hi {
+			return 1
+		}
+	}
+	return 0
+}
+
+func (b yaraBinOp) eval(ctx *yaraScanCtx) int64 {
+	switch b.op {
+	case "and":
+		return boolInt(b.l.eval(ctx) != 0 && b.r.eval(ctx) != 0)
+	case "or":
+		return boolInt(b.l.eval(ctx) != 0 || b.r.eval(ctx) != 0)
+	}
+	l, r := b.l.eval(ctx), b.r.eval(ctx)
+	switch b.op {
+	case "==":
+		return boolInt(l == r)
+	case "!=":
+		return boolInt(l != r)
+	case "<":
+		return boolInt(l < r)
+	case "<=":
+		return boolInt(l <= r)
+	case ">":
+		return boolInt(l > r)
+	case ">=":
+		return boolInt(l >= r)
+	}
+	return 0
+}
+
+func (o yaraOf) eval(ctx *yaraScanCtx) int64 {
+	hits := 0
+	for _, id := range o.ids {
+		if len(ctx.matches[id]) > 0 {
+			hits++
+		}
+	}
+	switch o.quantifier {
+	case "all":
+		return boolInt(hits == len(o.ids))
+	case "any":
+		return boolInt(hits > 0)
+	case "none":
+		return boolInt(hits == 0)
+	}
+	return boolInt(int64(hits) >= o.n.eval(ctx))
+}
+
+type condParser struct {
+	*yaraParser
+	rule *YaraRule
+}
+
+func (p *yaraParser) condition(r *YaraRule) error {
+	cp := &condParser{yaraParser: p, rule: r}
+	expr, err := cp.or()
+	if err != nil {
+		return err
+	}
+	r.condition = expr
+	return nil
+}
+
+func (p *condParser) keyword(word string) bool {
+	p.skipSpace()
+	rest := p.src[p.pos:]
+	if strings.HasPrefix(rest, word) && (len(rest) == len(word) || !isIdentByte(rest[len(word)])) {
+		p.pos += len(word)
+		return true
+	}
+	return false
+}
+
+func (p *condParser) or() (yaraExpr, error) {
+	l, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		r, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l = yaraBinOp{op: "or", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *condParser) and() (yaraExpr, error) {
+	l, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		r, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		l = yaraBinOp{op: "and", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *condParser) not() (yaraExpr, error) {
+	if p.keyword("not") {
+		x, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return yaraNot{x: x}, nil
+	}
+	return p.comparison()
+}
+
+func (p *condParser) comparison() (yaraExpr, error) {
+	l, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if strings.HasPrefix(p.src[p.pos:], op) {
+			p.pos += len(op)
+			r, err := p.primary()
+			if err != nil {
+				return nil, err
+			}
+			return yaraBinOp{op: op, l: l, r: r}, nil
+		}
+	}
+	return l, nil
+}
+
+func (p *condParser) primary() (yaraExpr, error) {
+	p.skipSpace()
+	c := p.peek()
+	switch {
+	case c == '(':
+		p.advance()
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(')')
+	case c == '$':
+		p.advance()
+		id := "$" + p.ident()
+		if err := p.knownString(id); err != nil {
+			return nil, err
+		}
+		if p.keyword("at") {
+			pos, err := p.primary()
+			if err != nil {
+				return nil, err
+			}
+			return yaraAt{id: id, pos: pos}, nil
+		}
+		if p.keyword("in") {
+			if err := p.expect('('); err != nil {
+				return nil, err
+			}
+			lo, err := p.primary()
+			if err != nil {
+				return nil, err
+			}
+			p.skipSpace()
+			if !strings.HasPrefix(p.src[p.pos:], "..") {
+				return nil, p.errorf("expected '..' in range")
+			}
+			p.pos += 2
+			hi, err := p.primary()
+			if err != nil {
+				return nil, err
+			}
+			return yaraIn{id: id, lo: lo, hi: hi}, p.expect(')')
+		}
+		return yaraStrRef{id: id}, nil
+	case c == '#':
+		p.advance()
+		id := "$" + p.ident()
+		return yaraCount{id: id}, p.knownString(id)
+	case c >= '0' && c <= '9':
+		n, err := p.number()
+		if err != nil {
+			return nil, err
+		}
+		if p.keyword("of") {
+			ids, err := p.stringSet()
+			return yaraOf{n: yaraConst(n), ids: ids}, err
+		}
+		return yaraConst(n), nil
+	}
+
+	word := p.ident()
+	switch word {
+	case "true":
+		return yaraConst(1), nil
+	case "false":
+		return yaraConst(0), nil
+	case "filesize":
+		return yaraFilesize{}, nil
+	case "all", "any", "none":
+		if !p.keyword("of") {
+			return nil, p.errorf("expected 'of' after %q", word)
+		}
+		ids, err := p.stringSet()
+		return yaraOf{quantifier: word, ids: ids}, err
+	case "":
+		return nil, p.errorf("unexpected %q in condition", string(c))
+	}
+	return nil, p.errorf("unsupported condition term %q", word)
+}
+
+func (p *condParser) number() (int64, error) {
+	start := p.pos
+	for !p.eof() && (isIdentByte(p.peek())) {
+		p.advance()
+	}
+	lit := p.src[start:p.pos]
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(lit, "KB"):
+		mult, lit = 1024, strings.TrimSuffix(lit, "KB")
+	case strings.HasSuffix(lit, "MB"):
+		mult, lit = 1024*1024, strings.TrimSuffix(lit, "MB")
+	}
+	n, err := strconv.ParseInt(lit, 0, 64)
+	if err != nil {
+		return 0, p.errorf("invalid number %q", p.src[start:p.pos])
+	}
+	return n * mult, nil
+}
+
+func (p *condParser) knownString(id string) error {
+	for _, s := range p.rule.strings {
+		if s.id == id {
+			return nil
+		}
+	}
+	return p.errorf("undefined string %s", id)
+}
+
+// stringSet parses "them" or "($a, $b*, ...)" into concrete identifiers.
+func (p *condParser) stringSet() ([]string, error) {
+	if p.keyword("them") {
+		ids := make([]string, 0, len(p.rule.strings))
+		for _, s := range p.rule.strings {
+			ids = append(ids, s.id)
+		}
+		if len(ids) == 0 {
+			return nil, p.errorf("'them' used in a rule without strings")
+		}
+		return ids, nil
+	}
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	var ids []string
+	for {
+		p.skipSpace()
+		if p.peek() != '$' {
+			return nil, p.errorf("string identifier expected in set")
+		}
+		p.advance()
+		name := "$" + p.ident()
+		if p.peek() == '*' {
+			p.advance()
+			before := len(ids)
+			for _, s := range p.rule.strings {
+				if strings.HasPrefix(s.id, name) {
+					ids = append(ids, s.id)
+				}
+			}
+			if len(ids) == before {
+				return nil, p.errorf("no strings match %s*", name)
+			}
+		} else {
+			if err := p.knownString(name); err != nil {
+				return nil, err
+			}
+			ids = append(ids, name)
+		}
+		p.skipSpace()
+		if p.peek() == ',' {
+			p.advance()
+			continue
+		}
+		return ids, p.expect(')')
+	}
+}
+
+// ─── Matching ───────────────────────────────────────────────
+
+// Scan evaluates the rule against data. It returns nil when the rule does not match.
+func (r *YaraRule) Scan(data []byte) *YaraMatch {
+	ctx := &yaraScanCtx{data: data, matches: map[string][]MatchOffset{}}
+	values := map[string]bool{}
+	for _, s := range r.strings {
+		offs := s.find(data)
+		ctx.matches[s.id] = offs
+		if s.kind == yaraHex {
+			continue
+		}
+		for _, o := range offs {
+			if v := printable(data[o.Offset : o.Offset+int64(o.Length)]); v != "" {
+				values[v] = true
+			}
+		}
+	}
+	if r.condition.eval(ctx) == 0 {
+		return nil
+	}
+
+	m := &YaraMatch{Rule: r}
+	for _, s := range r.strings {
+		if s.private {
+			continue
+		}
+		m.Offsets = append(m.Offsets, ctx.matches[s.id]...)
+	}
+	sort.SliceStable(m.Offsets, func(i, j int) bool { return m.Offsets[i].Offset < m.Offsets[j].Offset })
+	for v := range values {
+		m.Values = append(m.Values, v)
+	}
+	sort.Strings(m.Values)
+	return m
+}
+
+func (s *yaraString) find(data []byte) []MatchOffset {
+	switch s.kind {
+	case yaraHex:
+		return s.findHex(data)
+	case yaraRegex:
+		var out []MatchOffset
+		for _, loc := range s.re.FindAllIndex(data, maxOffsetsPerString) {
+			out = append(out, MatchOffset{Identifier: s.id, Offset: int64(loc[0]), Length: loc[1] - loc[0]})
+		}
+		return out
+	}
+
+	var out []MatchOffset
+	needles := [][]byte{}
+	if s.ascii || !s.wide {
+		needles = append(needles, s.text)
+	}
+	if s.wide {
+		needles = append(needles, widen(s.text))
+	}
+	haystack := data
+	if s.nocase {
+		haystack = asciiLower(data)
+	}
+	for _, needle := range needles {
+		for from := 0; len(out) < maxOffsetsPerString; {
+			i := bytes.Index(haystack[from:], needle)
+			if i < 0 {
+				break
+			}
+			out = append(out, MatchOffset{Identifier: s.id, Offset: int64(from + i), Length: len(needle)})
+			from += i + 1
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Offset < out[j].Offset })
+	return out
+}
+
+// asciiLower folds only ASCII letters so byte offsets stay aligned with the
+// original data; bytes.ToLower would rewrite invalid UTF-8 sequences.
+func asciiLower(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		out[i] = c
+	}
+	return out
+}
+
+func widen(b []byte) []byte {
+	out := make([]byte, 0, len(b)*2)
+	for _, c := range b {
+		out = append(out, c, 0)
+	}
+	return out
+}
+
+func (s *yaraString) findHex(data []byte) []MatchOffset {
+	var out []MatchOffset
+	first := s.hex[0]
+	for i := 0; i < len(data) && len(out) < maxOffsetsPerString; i++ {
+		if data[i]&first.mask != first.value {
+			continue
+		}
+		if end, ok := matchHex(data, i, s.hex); ok {
+			out = append(out, MatchOffset{Identifier: s.id, Offset: int64(i), Length: end - i})
+		}
+	}
+	return out
+}
+
+// matchHex reports whether elems match data at pos, returning the end offset.
+// Jumps are tried shortest first, mirroring YARA's non-greedy semantics.
+func matchHex(data []byte, pos int, elems []hexElem) (int, bool) {
+	for k, e := range elems {
+		if e.isJump {
+			for skip := e.jumpMin; skip <= e.jumpMax && pos+skip <= len(data); skip++ {
+				if end, ok := matchHex(data, pos+skip, elems[k+1:]); ok {
+					return end, true
+				}
+			}
+			return 0, false
+		}
+		if pos >= len(data) || data[pos]&e.mask != e.value {
+			return 0, false
+		}
+		pos++
+	}
+	return pos, true
+}
+
+// printable returns the match as text if it is a plausible indicator value.
+// UTF-16LE matches are narrowed first.
+func printable(b []byte) string {
+	if len(b) >= 2 && len(b)%2 == 0 && b[1] == 0 {
+		narrow := make([]byte, 0, len(b)/2)
+		for i := 0; i < len(b); i += 2 {
+			narrow = append(narrow, b[i])
+		}
+		b = narrow
+	}
+	s := strings.TrimSpace(string(b))
+	if s == "" || len(s) > 255 {
+		return ""
+	}
+	for _, r := range s {
+		if !unicode.IsPrint(r) {
+			return ""
+		}
+	}
+	return s
+}
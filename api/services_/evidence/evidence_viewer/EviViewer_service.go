@@ -25,6 +25,8 @@ func (s *EvidenceService) GetFilteredEvidenceFiles(
     return s.Repo.GetFilteredEvidenceFiles(caseID, filters, sortField, sortOrder)
 }
 
-func (s *EvidenceService) SearchEvidenceFiles(query string) ([]EvidenceFile, error) {
-    return s.Repo.SearchEvidenceFiles(query)
+// SearchEvidenceFiles searches the tenant's evidence. Callers still have to
+// drop the hits from cases the user is not on.
+func (s *EvidenceService) SearchEvidenceFiles(tenantID, query string) ([]EvidenceFile, error) {
+    return s.Repo.SearchEvidenceFiles(tenantID, query)
 }
\ No newline at end of file
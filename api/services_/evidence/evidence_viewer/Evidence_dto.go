@@ -7,6 +7,7 @@ import (
 type EvidenceDTO struct {
 	ID         string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
 	CaseID     string    `gorm:"type:uuid;not null" json:"case_id"`
+	TenantID   string    `gorm:"type:uuid;not null" json:"tenant_id"`
 	UploadedBy string    `gorm:"not null" json:"uploaded_by"`
 	Filename   string    `gorm:"not null" json:"filename"`
 	FileType   string    `gorm:"not null" json:"file_type"`
@@ -17,7 +18,12 @@ type EvidenceDTO struct {
 	UploadedAt time.Time `gorm:"autoCreateTime" json:"uploaded_at"`
 }
 
+func (EvidenceDTO) TableName() string {
+	return "evidence"
+}
+
 type EvidenceFile struct {
-    ID   string `json:"id"`
-    Data []byte `json:"data"`
+    ID     string `json:"id"`
+    CaseID string `json:"case_id,omitempty"`
+    Data   []byte `json:"data"`
 }
\ No newline at end of file
@@ -24,7 +24,7 @@ func (m *MockEvidenceViewer) GetFilteredEvidenceFiles(caseID string, filters map
 	return args.Get(0).([]EvidenceFile), args.Error(1)
 }
 
-func (m *MockEvidenceViewer) SearchEvidenceFiles(query string) ([]EvidenceFile, error) {
-	args := m.Called(query)
+func (m *MockEvidenceViewer) SearchEvidenceFiles(tenantID, query string) ([]EvidenceFile, error) {
+	args := m.Called(tenantID, query)
 	return args.Get(0).([]EvidenceFile), args.Error(1)
 }
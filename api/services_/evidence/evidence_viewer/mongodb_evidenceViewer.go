@@ -93,12 +93,13 @@ func (repo *MongoEvidenceRepository) GetEvidenceFileByID(evidenceID string) (*Ev
 }
 
 
-func (repo *MongoEvidenceRepository) SearchEvidenceFiles(query string) ([]EvidenceFile, error) {
+func (repo *MongoEvidenceRepository) SearchEvidenceFiles(tenantID, query string) ([]EvidenceFile, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	regex := bson.M{"$regex": query, "$options": "i"}
 	filter := bson.M{
+		"tenant_id": tenantID,
 		"$or": []bson.M{
 			{"filename": regex},
 			{"file_type": regex},
@@ -107,8 +108,9 @@ func (repo *MongoEvidenceRepository) SearchEvidenceFiles(query string) ([]Eviden
 	}
 
 	projection := bson.M{
-		"id":   1,
-		"data": 1,
+		"id":      1,
+		"case_id": 1,
+		"data":    1,
 	}
 
 	opts := options.Find().SetProjection(projection)
@@ -119,8 +121,9 @@ func (repo *MongoEvidenceRepository) SearchEvidenceFiles(query string) ([]Eviden
 	defer cursor.Close(ctx)
 
 	var rawResults []struct {
-		ID   string `bson:"id"`
-		Data []byte `bson:"data"`
+		ID     string `bson:"id"`
+		CaseID string `bson:"case_id"`
+		Data   []byte `bson:"data"`
 	}
 	if err := cursor.All(ctx, &rawResults); err != nil {
 		return nil, err
@@ -129,8 +132,9 @@ func (repo *MongoEvidenceRepository) SearchEvidenceFiles(query string) ([]Eviden
 	var results []EvidenceFile
 	for _, r := range rawResults {
 		results = append(results, EvidenceFile{
-			ID:   r.ID,
-			Data: r.Data,
+			ID:     r.ID,
+			CaseID: r.CaseID,
+			Data:   r.Data,
 		})
 	}
 	return results, nil
@@ -75,12 +75,13 @@ func (repo *PostgresEvidenceRepository) GetEvidenceFilesByCaseID(caseID string)
 }
 
 
-func (repo *PostgresEvidenceRepository) SearchEvidenceFiles(query string) ([]EvidenceFile, error) {
+func (repo *PostgresEvidenceRepository) SearchEvidenceFiles(tenantID, query string) ([]EvidenceFile, error) {
     var pairs []EvidenceCIDPair
 
     pattern := "%" + query + "%"
     result := repo.DB.Model(&EvidenceDTO{}).
-        Select("id, ipfs_cid").
+        Select("id, case_id, ipfs_cid").
+        Where("tenant_id = ?", tenantID).
         Where(
             "filename ILIKE ? OR file_type ILIKE ? OR metadata::text ILIKE ?",
             pattern, pattern, pattern,
@@ -99,8 +100,9 @@ func (repo *PostgresEvidenceRepository) SearchEvidenceFiles(query string) ([]Evi
         }
 
         files = append(files, EvidenceFile{
-            ID:   pair.ID,
-            Data: content,
+            ID:     pair.ID,
+            CaseID: pair.CaseID,
+            Data:   content,
         })
     }
 
@@ -110,6 +112,7 @@ func (repo *PostgresEvidenceRepository) SearchEvidenceFiles(query string) ([]Evi
 
 type EvidenceCIDPair struct {
     ID      string `json:"id"`
+    CaseID  string `json:"case_id"`
     IPFSCID string `json:"ipfs_cid"`
 }
 
@@ -113,9 +113,22 @@ func (s *Service) UploadEvidence(data UploadEvidenceRequest) error {
 		return err
 	}
 
+	return s.AppendLog(&EvidenceLog{
+		EvidenceID: e.ID,
+		Sha256:     sha256Sum,
+		Sha512:     sha512Sum,
+		Action:     "upload",
+		Result:     true,
+		//Timestamp:    time.Now(),
+	})
+}
+
+// AppendLog chains entry onto the evidence's log, filling in its ID and the
+// hash of the previous entry.
+func (s *Service) AppendLog(entry *EvidenceLog) error {
 	// Compute previous hash via interface, never touching Gorm directly
 	var previousHash string
-	lastLog, err := s.repo.GetLastEvidenceLog(e.ID)
+	lastLog, err := s.repo.GetLastEvidenceLog(entry.EvidenceID)
 	if err == nil && lastLog != nil {
 
 		hashInput := lastLog.Sha256 + lastLog.Sha512 + lastLog.Action +
@@ -125,18 +138,14 @@ func (s *Service) UploadEvidence(data UploadEvidenceRequest) error {
 		previousHash = hex.EncodeToString(hashBytes[:])
 	}
 
-	// Append log via interface
-	log := &EvidenceLog{
-		ID:           uuid.New(),
-		EvidenceID:   e.ID,
-		Sha256:       sha256Sum,
-		Sha512:       sha512Sum,
-		Action:       "upload",
-		Result:       true,
-		PreviousHash: previousHash,
-		//Timestamp:    time.Now(),
-	}
-	return s.repo.AppendEvidenceLog(log)
+	entry.ID = uuid.New()
+	entry.PreviousHash = previousHash
+	return s.repo.AppendEvidenceLog(entry)
+}
+
+// SaveEvidence stores an evidence record whose file is already in IPFS.
+func (s *Service) SaveEvidence(e *Evidence) error {
+	return s.repo.SaveEvidence(e)
 }
 
 // GetEvidenceByCaseID returns all evidence records for a given case.
@@ -0,0 +1,233 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// EmbeddingResponse holds one vector per input text, in input order.
+type EmbeddingResponse struct {
+	Vectors  [][]float32 `json:"vectors"`
+	Provider string      `json:"provider"`
+	Model    string      `json:"model"`
+	Usage    Usage       `json:"usage"`
+}
+
+// Embedder turns text into vectors for similarity search.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) (*EmbeddingResponse, error)
+}
+
+// HashEmbedder is a local, dependency-free embedder using feature hashing of
+// word unigrams and bigrams. It is deterministic and never leaves the process,
+// so it is always permitted regardless of tenant AI policy. Retrieval quality
+// is lexical rather than semantic.
+type HashEmbedder struct {
+	Dim int
+}
+
+func NewHashEmbedder(dim int) *HashEmbedder {
+	if dim <= 0 {
+		dim = 1024
+	}
+	return &HashEmbedder{Dim: dim}
+}
+
+func (h *HashEmbedder) Name() string  { return "hash" }
+func (h *HashEmbedder) Model() string { return fmt.Sprintf("hash-%d", h.Dim) }
+
+func (h *HashEmbedder) Embed(_ context.Context, texts []string) (*EmbeddingResponse, error) {
+	out := &EmbeddingResponse{Provider: h.Name(), Model: h.Model(), Vectors: make([][]float32, len(texts))}
+	for i, t := range texts {
+		out.Vectors[i] = h.vector(t)
+		out.Usage.PromptTokens += EstimateTokens(t)
+	}
+	out.Usage.TotalTokens = out.Usage.PromptTokens
+	return out, nil
+}
+
+// stopwords carry no retrieval signal and would otherwise make every English
+// passage look slightly similar to every question.
+var stopwords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`a an and are as at be by did do does for from had has have how i in is it
+		its of on or that the this to was were what when where which who why will with you`) {
+		stopwords[w] = true
+	}
+}
+
+func (h *HashEmbedder) vector(text string) []float32 {
+	v := make([]float32, h.Dim)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' && r != '_' && r != '-'
+	})
+	add := func(feature string, weight float32) {
+		f := fnv.New64a()
+		f.Write([]byte(feature))
+		sum := f.Sum64()
+		idx := int(sum % uint64(h.Dim))
+		// The top bit picks the sign so unrelated features tend to cancel out.
+		if sum>>63 == 1 {
+			weight = -weight
+		}
+		v[idx] += weight
+	}
+	prev := ""
+	for _, w := range words {
+		w = strings.Trim(w, ".-_")
+		if w == "" || stopwords[w] {
+			continue
+		}
+		add(w, 1)
+		if prev != "" {
+			add(prev+" "+w, 0.5)
+		}
+		prev = w
+	}
+	Normalize(v)
+	return v
+}
+
+// Normalize scales v to unit length in place.
+func Normalize(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	n := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= n
+	}
+}
+
+// Cosine returns the cosine similarity of a and b (0 when lengths differ).
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// Embed calls the OpenAI-compatible /embeddings endpoint with the configured
+// EmbeddingModel.
+func (p *OpenAICompatibleProvider) Embed(ctx context.Context, texts []string) (*EmbeddingResponse, error) {
+	model := p.cfg.EmbeddingModel
+	if model == "" {
+		return nil, fmt.Errorf("openai-compatible: no embedding model configured")
+	}
+	raw, err := json.Marshal(map[string]interface{}{"model": model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.BaseURL+"/embeddings", bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.cfg.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.cfg.APIKey)
+	}
+	resp, err := p.do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage *Usage `json:"usage"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<20)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("openai-compatible: invalid embeddings response: %w", err)
+	}
+	if len(out.Data) != len(texts) {
+		return nil, fmt.Errorf("openai-compatible: expected %d embeddings, got %d", len(texts), len(out.Data))
+	}
+	res := &EmbeddingResponse{Provider: p.Name(), Model: model, Vectors: make([][]float32, len(texts))}
+	for _, d := range out.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("openai-compatible: embedding index %d out of range", d.Index)
+		}
+		res.Vectors[d.Index] = d.Embedding
+	}
+	if out.Usage != nil {
+		res.Usage = *out.Usage
+	}
+	return res, nil
+}
+
+// Embed routes an embedding request. RouterConfig.EmbeddingProvider names a
+// registered provider that implements Embedder; when empty the local
+// HashEmbedder is used. The tenant's no-external-AI policy applies as for
+// completions.
+func (r *Router) Embed(ctx context.Context, texts []string) (*EmbeddingResponse, error) {
+	tenantID := TenantFromContext(ctx)
+	var emb Embedder = r.hash
+	name := r.hash.Name()
+	external := false
+
+	if r.cfg.EmbeddingProvider != "" && r.cfg.EmbeddingProvider != r.hash.Name() {
+		p, ok := r.providers[r.cfg.EmbeddingProvider]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, r.cfg.EmbeddingProvider)
+		}
+		e, ok := p.(Embedder)
+		if !ok {
+			return nil, fmt.Errorf("provider %q does not support embeddings", p.Name())
+		}
+		emb, name, external = e, p.Name(), p.External()
+	}
+	if external {
+		noExternal, err := r.noExternal(ctx, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		if noExternal {
+			return nil, ErrExternalAIDisabled
+		}
+	}
+
+	start := time.Now()
+	resp, err := emb.Embed(ctx, texts)
+	if r.usage != nil {
+		rec := &UsageRecord{TenantID: tenantID, Provider: name, Purpose: "embedding", LatencyMs: time.Since(start).Milliseconds(), Success: err == nil}
+		if resp != nil {
+			rec.Model = resp.Model
+			rec.PromptTokens = resp.Usage.PromptTokens
+			rec.TotalTokens = resp.Usage.TotalTokens
+		}
+		if err != nil {
+			rec.Error = err.Error()
+		}
+		if uerr := r.usage.RecordUsage(context.WithoutCancel(ctx), rec); uerr != nil {
+			log.Printf("llm: failed to record usage: %v", uerr)
+		}
+	}
+	return resp, err
+}
@@ -0,0 +1,84 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+)
+
+// FakeProvider is a deterministic provider for tests and offline development.
+// It answers from Responses when a key is contained in the prompt, otherwise
+// with a stable digest of the prompt. Calls are recorded for assertions.
+type FakeProvider struct {
+	Responses map[string]string
+	// Err, if set, is returned from every call.
+	Err error
+	// IsExternal lets tests exercise the no-external-AI policy.
+	IsExternal bool
+
+	mu    sync.Mutex
+	calls []CompletionRequest
+}
+
+func NewFakeProvider(responses map[string]string) *FakeProvider {
+	return &FakeProvider{Responses: responses}
+}
+
+func (p *FakeProvider) Name() string   { return "fake" }
+func (p *FakeProvider) External() bool { return p.IsExternal }
+
+// Calls returns the requests received so far.
+func (p *FakeProvider) Calls() []CompletionRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]CompletionRequest(nil), p.calls...)
+}
+
+func (p *FakeProvider) answer(req CompletionRequest) string {
+	// Longest matching key wins so overlapping keys resolve deterministically.
+	best := ""
+	for k := range p.Responses {
+		if strings.Contains(req.Prompt, k) && len(k) > len(best) {
+			best = k
+		}
+	}
+	if best != "" {
+		return p.Responses[best]
+	}
+	sum := sha256.Sum256([]byte(req.System + "\x00" + req.Prompt))
+	return "fake completion " + hex.EncodeToString(sum[:6])
+}
+
+func (p *FakeProvider) Complete(_ context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	p.mu.Lock()
+	p.calls = append(p.calls, req)
+	p.mu.Unlock()
+	if p.Err != nil {
+		return nil, p.Err
+	}
+	text := p.answer(req)
+	model := req.Model
+	if model == "" {
+		model = "fake-model"
+	}
+	return &CompletionResponse{Text: text, Provider: p.Name(), Model: model, Usage: estimateUsage(req, text)}, nil
+}
+
+// Stream emits the answer word by word.
+func (p *FakeProvider) Stream(ctx context.Context, req CompletionRequest, fn StreamFunc) (*CompletionResponse, error) {
+	resp, err := p.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	words := strings.SplitAfter(resp.Text, " ")
+	for _, w := range words {
+		if err := fn(w); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+func (p *FakeProvider) Health(context.Context) error { return p.Err }
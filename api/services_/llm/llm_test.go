@@ -0,0 +1,250 @@
+package llm_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"aegis-api/services_/llm"
+	"aegis-api/tests/fakes"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+// ─── tests ────────────────────────────────────────
+
+func TestRouter_UsesTenantPolicyAndRecordsUsage(t *testing.T) {
+	store := &fakes.LLMStore{}
+	tenant := uuid.New()
+	require.NoError(t, store.SavePolicy(context.Background(), &llm.TenantPolicy{TenantID: tenant, Provider: "fake", Model: "tenant-model", MaxTokens: 64}))
+
+	local := llm.NewFakeProvider(map[string]string{"severity": "local answer"})
+	fake := llm.NewFakeProvider(map[string]string{"severity": "high"})
+	// Register the second fake under a different name by wrapping it.
+	router := llm.NewRouter(llm.RouterConfig{DefaultProvider: "local", DefaultModel: "base"}, store, store,
+		named{"local", local}, fake)
+
+	ctx := llm.WithTenant(context.Background(), tenant.String())
+	resp, err := router.Complete(ctx, llm.CompletionRequest{Prompt: "classify severity", MaxTokens: 500, Purpose: "test"})
+	require.NoError(t, err)
+	require.Equal(t, "high", resp.Text)
+	require.Empty(t, local.Calls())
+	require.Len(t, fake.Calls(), 1)
+	require.Equal(t, "tenant-model", fake.Calls()[0].Model)
+	require.Equal(t, 64, fake.Calls()[0].MaxTokens, "tenant cap applies")
+
+	recs := store.Usage()
+	require.Len(t, recs, 1)
+	require.Equal(t, tenant.String(), recs[0].TenantID)
+	require.Equal(t, "fake", recs[0].Provider)
+	require.Equal(t, "test", recs[0].Purpose)
+	require.True(t, recs[0].Success)
+	require.Greater(t, recs[0].TotalTokens, 0)
+
+	// Tenants without a policy use the defaults.
+	resp, err = router.Complete(llm.WithTenant(context.Background(), uuid.NewString()), llm.CompletionRequest{Prompt: "severity"})
+	require.NoError(t, err)
+	require.Equal(t, "local answer", resp.Text)
+	require.Equal(t, "base", local.Calls()[0].Model)
+}
+
+type named struct {
+	name string
+	*llm.FakeProvider
+}
+
+func (n named) Name() string { return n.name }
+
+func TestRouter_NoExternalAI(t *testing.T) {
+	store := &fakes.LLMStore{}
+	tenant := uuid.New()
+	external := llm.NewFakeProvider(nil)
+	external.IsExternal = true
+	router := llm.NewRouter(llm.RouterConfig{DefaultProvider: "fake"}, store, store, external)
+
+	_, err := router.Complete(llm.WithTenant(context.Background(), tenant.String()), llm.CompletionRequest{Prompt: "hello"})
+	require.NoError(t, err)
+
+	require.NoError(t, store.SavePolicy(context.Background(), &llm.TenantPolicy{TenantID: tenant, NoExternalAI: true}))
+	_, err = router.Complete(llm.WithTenant(context.Background(), tenant.String()), llm.CompletionRequest{Prompt: "hello"})
+	require.ErrorIs(t, err, llm.ErrExternalAIDisabled)
+	require.Len(t, external.Calls(), 1, "blocked call must not reach the provider")
+
+	global := llm.NewRouter(llm.RouterConfig{DefaultProvider: "fake", DisableExternal: true}, nil, nil, external)
+	_, err = global.Complete(context.Background(), llm.CompletionRequest{Prompt: "hello"})
+	require.ErrorIs(t, err, llm.ErrExternalAIDisabled)
+}
+
+func TestRouter_RetriesTransientFailures(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) < 3 {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"model":   "llama3",
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": " done "}}},
+			"usage":   map[string]int{"prompt_tokens": 7, "completion_tokens": 2, "total_tokens": 9},
+		})
+	}))
+	defer srv.Close()
+
+	store := &fakes.LLMStore{}
+	p := llm.NewOpenAICompatibleProvider(llm.OpenAIConfig{BaseURL: srv.URL + "/v1", DefaultModel: "llama3"})
+	router := llm.NewRouter(llm.RouterConfig{MaxRetries: 2, RetryBackoff: time.Millisecond}, nil, store, p)
+
+	resp, err := router.Complete(context.Background(), llm.CompletionRequest{Prompt: "hi"})
+	require.NoError(t, err)
+	require.Equal(t, "done", resp.Text)
+	require.Equal(t, llm.Usage{PromptTokens: 7, CompletionTokens: 2, TotalTokens: 9}, resp.Usage)
+	require.EqualValues(t, 3, atomic.LoadInt32(&hits))
+	require.Len(t, store.Usage(), 1)
+	require.Equal(t, 9, store.Usage()[0].TotalTokens)
+}
+
+func TestRouter_DoesNotRetryClientErrors(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		http.Error(w, "bad model", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	store := &fakes.LLMStore{}
+	p := llm.NewOpenAICompatibleProvider(llm.OpenAIConfig{BaseURL: srv.URL})
+	router := llm.NewRouter(llm.RouterConfig{MaxRetries: 3, RetryBackoff: time.Millisecond}, nil, store, p)
+
+	_, err := router.Complete(context.Background(), llm.CompletionRequest{Prompt: "hi"})
+	var se *llm.StatusError
+	require.ErrorAs(t, err, &se)
+	require.Equal(t, http.StatusBadRequest, se.Code)
+	require.EqualValues(t, 1, atomic.LoadInt32(&hits))
+	require.False(t, store.Usage()[0].Success)
+}
+
+func TestRouter_TimeoutPerAttempt(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	p := llm.NewLocalFlaskProvider(srv.URL, "")
+	router := llm.NewRouter(llm.RouterConfig{Timeout: 20 * time.Millisecond, MaxRetries: 1, RetryBackoff: time.Millisecond}, nil, nil, p)
+
+	start := time.Now()
+	_, err := router.Complete(context.Background(), llm.CompletionRequest{Prompt: "hi"})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestOpenAIProvider_Stream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Equal(t, true, body["stream"])
+		require.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, part := range []string{"Lateral", " movement", " observed"} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", part)
+		}
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":5,\"completion_tokens\":3,\"total_tokens\":8}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	p := llm.NewOpenAICompatibleProvider(llm.OpenAIConfig{BaseURL: srv.URL, APIKey: "secret", DefaultModel: "m"})
+	var chunks []string
+	resp, err := p.Stream(context.Background(), llm.CompletionRequest{Prompt: "summarise"}, func(d string) error {
+		chunks = append(chunks, d)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Lateral", " movement", " observed"}, chunks)
+	require.Equal(t, "Lateral movement observed", resp.Text)
+	require.Equal(t, 8, resp.Usage.TotalTokens)
+}
+
+type flakyStream struct {
+	*llm.FakeProvider
+	calls int
+}
+
+func (f *flakyStream) Stream(ctx context.Context, req llm.CompletionRequest, fn llm.StreamFunc) (*llm.CompletionResponse, error) {
+	f.calls++
+	if err := fn("partial"); err != nil {
+		return nil, err
+	}
+	return nil, &llm.StatusError{Provider: "fake", Code: http.StatusBadGateway}
+}
+
+func TestRouter_StreamDoesNotRetryAfterDelivery(t *testing.T) {
+	p := &flakyStream{FakeProvider: llm.NewFakeProvider(nil)}
+	router := llm.NewRouter(llm.RouterConfig{MaxRetries: 3, RetryBackoff: time.Millisecond}, nil, nil, p)
+
+	var got []string
+	_, err := router.Stream(context.Background(), llm.CompletionRequest{Prompt: "hi"}, func(d string) error {
+		got = append(got, d)
+		return nil
+	})
+	require.Error(t, err)
+	require.Equal(t, 1, p.calls)
+	require.Equal(t, []string{"partial"}, got)
+}
+
+func TestLocalFlaskProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/generate":
+			var body struct {
+				Prompt    string `json:"prompt"`
+				MaxLength int    `json:"max_length"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			require.Equal(t, 42, body.MaxLength)
+			json.NewEncoder(w).Encode(map[string]string{"text": body.Prompt + " generated"})
+		case "/health":
+			json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+		}
+	}))
+	defer srv.Close()
+
+	p := llm.NewLocalFlaskProvider(srv.URL, "")
+	require.False(t, p.External())
+	resp, err := p.Complete(context.Background(), llm.CompletionRequest{Prompt: "write", MaxTokens: 42})
+	require.NoError(t, err)
+	require.Equal(t, "generated", resp.Text, "echoed prompt is stripped")
+	require.NoError(t, p.Health(context.Background()))
+}
+
+func TestOpenAIProvider_ExternalDetection(t *testing.T) {
+	require.False(t, llm.NewOpenAICompatibleProvider(llm.OpenAIConfig{BaseURL: "http://ollama:11434/v1"}).External())
+	require.False(t, llm.NewOpenAICompatibleProvider(llm.OpenAIConfig{BaseURL: "http://10.0.0.5:8000/v1"}).External())
+	require.True(t, llm.NewOpenAICompatibleProvider(llm.OpenAIConfig{BaseURL: "https://api.openai.com/v1"}).External())
+	require.False(t, llm.NewOpenAICompatibleProvider(llm.OpenAIConfig{BaseURL: "https://api.openai.com/v1", External: boolPtr(false)}).External())
+}
+
+func TestRouter_RejectsEmptyPromptAndUnknownProvider(t *testing.T) {
+	router := llm.NewRouter(llm.RouterConfig{DefaultProvider: "missing"}, nil, nil, llm.NewFakeProvider(nil))
+	_, err := router.Complete(context.Background(), llm.CompletionRequest{Prompt: "  "})
+	require.ErrorIs(t, err, llm.ErrEmptyPrompt)
+	_, err = router.Complete(context.Background(), llm.CompletionRequest{Prompt: "x"})
+	require.True(t, errors.Is(err, llm.ErrUnknownProvider))
+	require.False(t, strings.Contains(err.Error(), "%!"))
+}
@@ -0,0 +1,120 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// LocalFlaskProvider talks to the bundled localai Flask app (POST /generate).
+// The app serves a single model and has no streaming endpoint, so Stream
+// delivers the finished text as one chunk.
+type LocalFlaskProvider struct {
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+// NewLocalFlaskProvider creates a provider for the Flask service at baseURL
+// (e.g. http://localai:5000). model is informational only.
+func NewLocalFlaskProvider(baseURL, model string) *LocalFlaskProvider {
+	if baseURL == "" {
+		baseURL = "http://localai:5000"
+	}
+	if model == "" {
+		model = "flan-t5-base"
+	}
+	return &LocalFlaskProvider{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		model:      model,
+		httpClient: &http.Client{},
+	}
+}
+
+func (p *LocalFlaskProvider) Name() string   { return "local" }
+func (p *LocalFlaskProvider) External() bool { return false }
+
+func (p *LocalFlaskProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	start := time.Now()
+	prompt := req.Prompt
+	if req.System != "" {
+		prompt = req.System + "\n\n" + prompt
+	}
+	maxLength := req.MaxTokens
+	if maxLength <= 0 {
+		maxLength = 200
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"prompt":     prompt,
+		"max_length": maxLength,
+	})
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/generate", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("local AI request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &StatusError{Provider: p.Name(), Code: resp.StatusCode, Body: string(b)}
+	}
+
+	var out struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("local AI: invalid response: %w", err)
+	}
+
+	// Seq2seq pipelines sometimes echo the prompt back.
+	text := strings.TrimSpace(strings.TrimPrefix(out.Text, prompt))
+	return &CompletionResponse{
+		Text:     text,
+		Provider: p.Name(),
+		Model:    p.model,
+		Usage:    estimateUsage(req, text),
+		Latency:  time.Since(start),
+	}, nil
+}
+
+func (p *LocalFlaskProvider) Stream(ctx context.Context, req CompletionRequest, fn StreamFunc) (*CompletionResponse, error) {
+	resp, err := p.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(resp.Text); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (p *LocalFlaskProvider) Health(ctx context.Context) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/health", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{Provider: p.Name(), Code: resp.StatusCode}
+	}
+	return nil
+}
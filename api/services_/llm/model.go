@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TenantPolicy selects the provider/model for a tenant and carries its data
+// handling restrictions. Tenants without a row use the router defaults.
+type TenantPolicy struct {
+	TenantID     uuid.UUID `gorm:"type:uuid;primaryKey" json:"tenant_id"`
+	Provider     string    `gorm:"type:varchar(50)" json:"provider"`
+	Model        string    `gorm:"type:varchar(255)" json:"model"`
+	NoExternalAI bool      `gorm:"not null;default:false" json:"no_external_ai"`
+	// MaxTokens caps a single completion; 0 means no tenant-specific cap.
+	MaxTokens int       `gorm:"not null;default:0" json:"max_tokens"`
+	UpdatedBy string    `gorm:"type:uuid" json:"updated_by,omitempty"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (TenantPolicy) TableName() string { return "ai_tenant_policies" }
+
+// UsageRecord is one provider call, successful or not.
+type UsageRecord struct {
+	ID               uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID         string    `gorm:"type:varchar(64);index" json:"tenant_id"`
+	Provider         string    `gorm:"type:varchar(50)" json:"provider"`
+	Model            string    `gorm:"type:varchar(255)" json:"model"`
+	Purpose          string    `gorm:"type:varchar(100)" json:"purpose"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	TotalTokens      int       `json:"total_tokens"`
+	LatencyMs        int64     `json:"latency_ms"`
+	Success          bool      `json:"success"`
+	Error            string    `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt        time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+}
+
+func (UsageRecord) TableName() string { return "ai_usage_records" }
+
+// UsageSummary aggregates usage per provider/model for reporting.
+type UsageSummary struct {
+	Provider         string `json:"provider"`
+	Model            string `json:"model"`
+	Requests         int64  `json:"requests"`
+	Failures         int64  `json:"failures"`
+	PromptTokens     int64  `json:"prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens"`
+	TotalTokens      int64  `json:"total_tokens"`
+}
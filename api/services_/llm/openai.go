@@ -0,0 +1,239 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OpenAIConfig configures an OpenAI-compatible chat completions endpoint
+// (OpenAI, llama.cpp server, vLLM, Ollama's /v1 API, ...).
+type OpenAIConfig struct {
+	BaseURL      string // e.g. http://ollama:11434/v1
+	APIKey       string
+	DefaultModel string
+	// EmbeddingModel enables Embed; empty means the endpoint is not used for embeddings.
+	EmbeddingModel string
+	// External overrides the network-locality guess made from BaseURL.
+	External *bool
+}
+
+type OpenAICompatibleProvider struct {
+	cfg        OpenAIConfig
+	external   bool
+	httpClient *http.Client
+}
+
+func NewOpenAICompatibleProvider(cfg OpenAIConfig) *OpenAICompatibleProvider {
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	external := !isInternalEndpoint(cfg.BaseURL)
+	if cfg.External != nil {
+		external = *cfg.External
+	}
+	return &OpenAICompatibleProvider{cfg: cfg, external: external, httpClient: &http.Client{}}
+}
+
+func (p *OpenAICompatibleProvider) Name() string   { return "openai" }
+func (p *OpenAICompatibleProvider) External() bool { return p.external }
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model         string        `json:"model"`
+	Messages      []chatMessage `json:"messages"`
+	MaxTokens     int           `json:"max_tokens,omitempty"`
+	Temperature   float64       `json:"temperature"`
+	Stream        bool          `json:"stream,omitempty"`
+	StreamOptions *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options,omitempty"`
+}
+
+type chatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message chatMessage `json:"message"`
+		Delta   chatMessage `json:"delta"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage"`
+}
+
+func (p *OpenAICompatibleProvider) buildRequest(ctx context.Context, req CompletionRequest, stream bool) (*http.Request, string, error) {
+	model := req.Model
+	if model == "" {
+		model = p.cfg.DefaultModel
+	}
+	body := chatRequest{Model: model, MaxTokens: req.MaxTokens, Temperature: req.Temperature, Stream: stream}
+	if req.System != "" {
+		body.Messages = append(body.Messages, chatMessage{Role: "system", Content: req.System})
+	}
+	body.Messages = append(body.Messages, chatMessage{Role: "user", Content: req.Prompt})
+	if stream {
+		body.StreamOptions = &struct {
+			IncludeUsage bool `json:"include_usage"`
+		}{IncludeUsage: true}
+	}
+
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, "", err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.BaseURL+"/chat/completions", bytes.NewReader(raw))
+	if err != nil {
+		return nil, "", err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.cfg.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.cfg.APIKey)
+	}
+	return httpReq, model, nil
+}
+
+func (p *OpenAICompatibleProvider) do(httpReq *http.Request) (*http.Response, error) {
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("openai-compatible request failed: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &StatusError{Provider: p.Name(), Code: resp.StatusCode, Body: string(b)}
+	}
+	return resp, nil
+}
+
+func (p *OpenAICompatibleProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	start := time.Now()
+	httpReq, model, err := p.buildRequest(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("openai-compatible: invalid response: %w", err)
+	}
+	if len(out.Choices) == 0 {
+		return nil, fmt.Errorf("openai-compatible: response has no choices")
+	}
+	if out.Model != "" {
+		model = out.Model
+	}
+
+	text := strings.TrimSpace(out.Choices[0].Message.Content)
+	usage := estimateUsage(req, text)
+	if out.Usage != nil && out.Usage.TotalTokens > 0 {
+		usage = *out.Usage
+	}
+	return &CompletionResponse{Text: text, Provider: p.Name(), Model: model, Usage: usage, Latency: time.Since(start)}, nil
+}
+
+// Stream consumes the server-sent events stream ("data: {...}" lines ending
+// with "data: [DONE]").
+func (p *OpenAICompatibleProvider) Stream(ctx context.Context, req CompletionRequest, fn StreamFunc) (*CompletionResponse, error) {
+	start := time.Now()
+	httpReq, model, err := p.buildRequest(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+	resp, err := p.do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var text strings.Builder
+	var usage *Usage
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+		var chunk chatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("openai-compatible: invalid stream chunk: %w", err)
+		}
+		if chunk.Model != "" {
+			model = chunk.Model
+		}
+		if chunk.Usage != nil && chunk.Usage.TotalTokens > 0 {
+			usage = chunk.Usage
+		}
+		for _, c := range chunk.Choices {
+			if c.Delta.Content == "" {
+				continue
+			}
+			text.WriteString(c.Delta.Content)
+			if err := fn(c.Delta.Content); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	out := &CompletionResponse{Text: strings.TrimSpace(text.String()), Provider: p.Name(), Model: model, Latency: time.Since(start)}
+	if usage != nil {
+		out.Usage = *usage
+	} else {
+		out.Usage = estimateUsage(req, out.Text)
+	}
+	return out, nil
+}
+
+func (p *OpenAICompatibleProvider) Health(ctx context.Context) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.BaseURL+"/models", nil)
+	if err != nil {
+		return err
+	}
+	if p.cfg.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.cfg.APIKey)
+	}
+	resp, err := p.do(httpReq)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// isInternalEndpoint guesses whether baseURL stays inside the deployment:
+// loopback and private addresses, and single-label hosts such as docker
+// compose service names ("ollama", "vllm").
+func isInternalEndpoint(baseURL string) bool {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" || !strings.Contains(host, ".") && net.ParseIP(host) == nil {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsPrivate())
+}
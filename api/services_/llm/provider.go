@@ -0,0 +1,103 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrExternalAIDisabled = errors.New("external AI providers are disabled for this tenant")
+	ErrUnknownProvider    = errors.New("unknown AI provider")
+	ErrEmptyPrompt        = errors.New("prompt is required")
+)
+
+// CompletionRequest is a provider-neutral text generation request.
+// Model may be empty, in which case the tenant policy or provider default applies.
+type CompletionRequest struct {
+	TenantID    string
+	Model       string
+	System      string
+	Prompt      string
+	MaxTokens   int
+	Temperature float64
+	// Purpose labels the caller (e.g. "timeline.severity") for usage accounting.
+	Purpose string
+}
+
+// Usage is the token count for one completion. Providers that do not report
+// usage are filled in with EstimateTokens.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type CompletionResponse struct {
+	Text     string        `json:"text"`
+	Provider string        `json:"provider"`
+	Model    string        `json:"model"`
+	Usage    Usage         `json:"usage"`
+	Latency  time.Duration `json:"latency"`
+}
+
+// StreamFunc receives incremental text. Returning an error aborts the stream.
+type StreamFunc func(delta string) error
+
+// Provider is implemented by every LLM backend.
+type Provider interface {
+	Name() string
+	// External reports whether prompts leave the deployment's own network.
+	External() bool
+	Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error)
+	// Stream delivers the completion through fn as it is generated and returns
+	// the assembled response once finished.
+	Stream(ctx context.Context, req CompletionRequest, fn StreamFunc) (*CompletionResponse, error)
+	Health(ctx context.Context) error
+}
+
+// StatusError is returned when a provider answers with a non-2xx status.
+type StatusError struct {
+	Provider string
+	Code     int
+	Body     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: status %d: %s", e.Provider, e.Code, strings.TrimSpace(e.Body))
+}
+
+// Retryable reports whether the request may succeed if repeated.
+func (e *StatusError) Retryable() bool {
+	return e.Code == 429 || e.Code >= 500
+}
+
+type tenantKey struct{}
+
+// WithTenant attaches the tenant ID so providers reached through services that
+// only receive a context can still apply tenant policy.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+func TenantFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(tenantKey{}).(string)
+	return v
+}
+
+// EstimateTokens approximates a token count (about four characters per token)
+// for providers that do not report usage.
+func EstimateTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	return (len(text) + 3) / 4
+}
+
+func estimateUsage(req CompletionRequest, text string) Usage {
+	u := Usage{PromptTokens: EstimateTokens(req.System + req.Prompt), CompletionTokens: EstimateTokens(text)}
+	u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	return u
+}
@@ -0,0 +1,78 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PolicyStore resolves per-tenant AI settings.
+type PolicyStore interface {
+	GetPolicy(ctx context.Context, tenantID string) (*TenantPolicy, error)
+	SavePolicy(ctx context.Context, p *TenantPolicy) error
+}
+
+// UsageStore persists token accounting.
+type UsageStore interface {
+	RecordUsage(ctx context.Context, rec *UsageRecord) error
+	SummarizeUsage(ctx context.Context, tenantID string, since time.Time) ([]UsageSummary, error)
+}
+
+type GormStore struct {
+	db *gorm.DB
+}
+
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+func (s *GormStore) AutoMigrate() error {
+	return s.db.AutoMigrate(&TenantPolicy{}, &UsageRecord{})
+}
+
+// GetPolicy returns nil, nil when the tenant has no policy row.
+func (s *GormStore) GetPolicy(ctx context.Context, tenantID string) (*TenantPolicy, error) {
+	id, err := uuid.Parse(tenantID)
+	if err != nil {
+		return nil, nil
+	}
+	var p TenantPolicy
+	err = s.db.WithContext(ctx).First(&p, "tenant_id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *GormStore) SavePolicy(ctx context.Context, p *TenantPolicy) error {
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}},
+		UpdateAll: true,
+	}).Create(p).Error
+}
+
+func (s *GormStore) RecordUsage(ctx context.Context, rec *UsageRecord) error {
+	return s.db.WithContext(ctx).Create(rec).Error
+}
+
+func (s *GormStore) SummarizeUsage(ctx context.Context, tenantID string, since time.Time) ([]UsageSummary, error) {
+	var out []UsageSummary
+	err := s.db.WithContext(ctx).Model(&UsageRecord{}).
+		Select(`provider, model, COUNT(*) AS requests,
+			SUM(CASE WHEN success THEN 0 ELSE 1 END) AS failures,
+			SUM(prompt_tokens) AS prompt_tokens,
+			SUM(completion_tokens) AS completion_tokens,
+			SUM(total_tokens) AS total_tokens`).
+		Where("tenant_id = ? AND created_at >= ?", tenantID, since).
+		Group("provider, model").
+		Order("provider, model").
+		Scan(&out).Error
+	return out, err
+}
@@ -0,0 +1,273 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+)
+
+// RouterConfig holds deployment-wide defaults.
+type RouterConfig struct {
+	DefaultProvider string
+	DefaultModel    string
+	// Timeout bounds a single attempt; 0 disables the per-attempt timeout.
+	Timeout    time.Duration
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry; it doubles each time.
+	RetryBackoff time.Duration
+	// DisableExternal blocks external providers for every tenant.
+	DisableExternal bool
+	// EmbeddingProvider names the provider used by Embed; empty selects the
+	// built-in HashEmbedder.
+	EmbeddingProvider string
+}
+
+// Router is the Provider handed to the AI services. It resolves the tenant's
+// provider and model, enforces the no-external-AI switch, applies timeouts and
+// retries, and records token usage for every call.
+type Router struct {
+	cfg       RouterConfig
+	providers map[string]Provider
+	policies  PolicyStore
+	usage     UsageStore
+	hash      *HashEmbedder
+	sleep     func(context.Context, time.Duration) error
+}
+
+// NewRouter builds a router over providers. policies and usage may be nil.
+func NewRouter(cfg RouterConfig, policies PolicyStore, usage UsageStore, providers ...Provider) *Router {
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 500 * time.Millisecond
+	}
+	r := &Router{
+		cfg:       cfg,
+		providers: make(map[string]Provider, len(providers)),
+		policies:  policies,
+		usage:     usage,
+		hash:      NewHashEmbedder(0),
+		sleep:     sleepCtx,
+	}
+	for _, p := range providers {
+		r.providers[p.Name()] = p
+	}
+	if r.cfg.DefaultProvider == "" && len(providers) > 0 {
+		r.cfg.DefaultProvider = providers[0].Name()
+	}
+	return r
+}
+
+func (r *Router) Name() string { return "router" }
+
+// External reports whether the default provider is external.
+func (r *Router) External() bool {
+	p, ok := r.providers[r.cfg.DefaultProvider]
+	return ok && p.External()
+}
+
+// Providers lists the registered provider names and whether each is external.
+func (r *Router) Providers() map[string]bool {
+	out := make(map[string]bool, len(r.providers))
+	for name, p := range r.providers {
+		out[name] = p.External()
+	}
+	return out
+}
+
+// Resolve returns the provider and request that a call for tenantID would use.
+func (r *Router) Resolve(ctx context.Context, req CompletionRequest) (Provider, CompletionRequest, error) {
+	if req.TenantID == "" {
+		req.TenantID = TenantFromContext(ctx)
+	}
+
+	name := r.cfg.DefaultProvider
+	model := r.cfg.DefaultModel
+	noExternal := r.cfg.DisableExternal
+
+	if r.policies != nil && req.TenantID != "" {
+		pol, err := r.policies.GetPolicy(ctx, req.TenantID)
+		if err != nil {
+			return nil, req, fmt.Errorf("load AI policy: %w", err)
+		}
+		if pol != nil {
+			if pol.Provider != "" {
+				name = pol.Provider
+				if pol.Model == "" {
+					model = ""
+				}
+			}
+			if pol.Model != "" {
+				model = pol.Model
+			}
+			if pol.MaxTokens > 0 && (req.MaxTokens <= 0 || req.MaxTokens > pol.MaxTokens) {
+				req.MaxTokens = pol.MaxTokens
+			}
+			noExternal = noExternal || pol.NoExternalAI
+		}
+	}
+
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, req, fmt.Errorf("%w: %q", ErrUnknownProvider, name)
+	}
+	if noExternal && p.External() {
+		return nil, req, ErrExternalAIDisabled
+	}
+	if req.Model == "" {
+		req.Model = model
+	}
+	return p, req, nil
+}
+
+func (r *Router) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	return r.run(ctx, req, func(ctx context.Context, p Provider, req CompletionRequest) (*CompletionResponse, bool, error) {
+		resp, err := p.Complete(ctx, req)
+		return resp, true, err
+	})
+}
+
+// Stream retries only while nothing has been delivered to fn; once a chunk has
+// reached the caller a failure is returned as-is.
+func (r *Router) Stream(ctx context.Context, req CompletionRequest, fn StreamFunc) (*CompletionResponse, error) {
+	return r.run(ctx, req, func(ctx context.Context, p Provider, req CompletionRequest) (*CompletionResponse, bool, error) {
+		delivered := false
+		resp, err := p.Stream(ctx, req, func(delta string) error {
+			delivered = true
+			return fn(delta)
+		})
+		return resp, !delivered, err
+	})
+}
+
+func (r *Router) Health(ctx context.Context) error {
+	p, ok := r.providers[r.cfg.DefaultProvider]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownProvider, r.cfg.DefaultProvider)
+	}
+	return p.Health(ctx)
+}
+
+// noExternal reports whether external providers are blocked for tenantID.
+func (r *Router) noExternal(ctx context.Context, tenantID string) (bool, error) {
+	if r.cfg.DisableExternal {
+		return true, nil
+	}
+	if r.policies == nil || tenantID == "" {
+		return false, nil
+	}
+	pol, err := r.policies.GetPolicy(ctx, tenantID)
+	if err != nil {
+		return false, fmt.Errorf("load AI policy: %w", err)
+	}
+	return pol != nil && pol.NoExternalAI, nil
+}
+
+// HealthFor checks the provider the tenant is routed to.
+func (r *Router) HealthFor(ctx context.Context, tenantID string) (string, error) {
+	p, _, err := r.Resolve(ctx, CompletionRequest{TenantID: tenantID})
+	if err != nil {
+		return "", err
+	}
+	return p.Name(), p.Health(ctx)
+}
+
+type attemptFunc func(ctx context.Context, p Provider, req CompletionRequest) (resp *CompletionResponse, canRetry bool, err error)
+
+func (r *Router) run(ctx context.Context, req CompletionRequest, attempt attemptFunc) (*CompletionResponse, error) {
+	if strings.TrimSpace(req.Prompt) == "" {
+		return nil, ErrEmptyPrompt
+	}
+	p, req, err := r.Resolve(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	backoff := r.cfg.RetryBackoff
+	var resp *CompletionResponse
+	for try := 0; ; try++ {
+		actx, cancel := ctx, context.CancelFunc(func() {})
+		if r.cfg.Timeout > 0 {
+			actx, cancel = context.WithTimeout(ctx, r.cfg.Timeout)
+		}
+		var canRetry bool
+		resp, canRetry, err = attempt(actx, p, req)
+		cancel()
+
+		if err == nil || !canRetry || try >= r.cfg.MaxRetries || !isRetryable(ctx, err) {
+			break
+		}
+		log.Printf("llm: %s attempt %d failed, retrying: %v", p.Name(), try+1, err)
+		if serr := r.sleep(ctx, backoff); serr != nil {
+			err = serr
+			break
+		}
+		backoff *= 2
+	}
+
+	r.record(ctx, p, req, resp, time.Since(start), err)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (r *Router) record(ctx context.Context, p Provider, req CompletionRequest, resp *CompletionResponse, latency time.Duration, callErr error) {
+	if r.usage == nil {
+		return
+	}
+	rec := &UsageRecord{
+		TenantID:  req.TenantID,
+		Provider:  p.Name(),
+		Model:     req.Model,
+		Purpose:   req.Purpose,
+		LatencyMs: latency.Milliseconds(),
+		Success:   callErr == nil,
+	}
+	if resp != nil {
+		if resp.Model != "" {
+			rec.Model = resp.Model
+		}
+		rec.PromptTokens = resp.Usage.PromptTokens
+		rec.CompletionTokens = resp.Usage.CompletionTokens
+		rec.TotalTokens = resp.Usage.TotalTokens
+	}
+	if callErr != nil {
+		rec.Error = callErr.Error()
+	}
+	// Accounting must not fail the caller, and must survive a cancelled request.
+	if err := r.usage.RecordUsage(context.WithoutCancel(ctx), rec); err != nil {
+		log.Printf("llm: failed to record usage: %v", err)
+	}
+}
+
+// isRetryable treats transient network failures, per-attempt timeouts and
+// 429/5xx answers as retryable. Cancellation of the parent context is not.
+func isRetryable(parent context.Context, err error) bool {
+	if parent.Err() != nil {
+		return false
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Retryable()
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne)
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,239 @@
+package redaction
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// span is a passage to redact, given as a quote and which of its
+// occurrences to take.
+type span struct {
+	quote      string
+	occurrence int
+	label      string
+}
+
+// textPos maps one rune of the normalised text back to the bytes of the
+// text node it came from.
+type textPos struct {
+	node       int
+	start, end int
+}
+
+// textIndex is the text of a document normalised for matching: lower
+// case, with every run of whitespace collapsed to one space, even where
+// the run crosses element boundaries.
+type textIndex struct {
+	nodes []*html.Node
+	text  []rune
+	pos   []textPos
+}
+
+func (ix *textIndex) add(n *html.Node) {
+	ix.nodes = append(ix.nodes, n)
+	node := len(ix.nodes) - 1
+	for i, r := range n.Data {
+		size := len(string(r))
+		if unicode.IsSpace(r) {
+			if last := len(ix.text) - 1; last >= 0 && ix.text[last] == ' ' {
+				if ix.pos[last].node == node {
+					ix.pos[last].end = i + size
+				}
+				continue
+			}
+			r = ' '
+		}
+		ix.text = append(ix.text, unicode.ToLower(r))
+		ix.pos = append(ix.pos, textPos{node: node, start: i, end: i + size})
+	}
+}
+
+func normalise(s string) []rune {
+	var out []rune
+	for _, r := range strings.TrimSpace(s) {
+		if unicode.IsSpace(r) {
+			if len(out) > 0 && out[len(out)-1] == ' ' {
+				continue
+			}
+			r = ' '
+		}
+		out = append(out, unicode.ToLower(r))
+	}
+	return out
+}
+
+// find returns the rune offsets of the non-overlapping occurrences of q.
+func (ix *textIndex) find(q []rune) [][2]int {
+	var out [][2]int
+	if len(q) == 0 {
+		return nil
+	}
+	for i := 0; i+len(q) <= len(ix.text); {
+		if equalRunes(ix.text[i:i+len(q)], q) {
+			out = append(out, [2]int{i, i + len(q)})
+			i += len(q)
+			continue
+		}
+		i++
+	}
+	return out
+}
+
+func equalRunes(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type hit struct {
+	from, to int
+	label    string
+}
+
+// redact replaces every selected occurrence of the spans in the indexed
+// text nodes with a "[REDACTED: label]" marker, and reports whether
+// anything changed. A match spanning several nodes leaves the marker in
+// the first and removes the rest of the match from the others.
+func (ix *textIndex) redact(spans []span) bool {
+	var hits []hit
+	for _, sp := range spans {
+		for i, m := range ix.find(normalise(sp.quote)) {
+			if sp.occurrence == 0 || sp.occurrence == i+1 {
+				hits = append(hits, hit{from: m[0], to: m[1], label: sp.label})
+			}
+		}
+	}
+	if len(hits) == 0 {
+		return false
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].from < hits[j].from })
+	merged := hits[:1]
+	for _, h := range hits[1:] {
+		last := &merged[len(merged)-1]
+		if h.from < last.to {
+			if h.to > last.to {
+				last.to = h.to
+			}
+			continue
+		}
+		merged = append(merged, h)
+	}
+
+	type cut struct {
+		start, end int
+		repl       string
+	}
+	cuts := make(map[int][]cut)
+	for _, h := range merged {
+		first := true
+		for i := h.from; i < h.to; {
+			p := ix.pos[i]
+			c := cut{start: p.start, end: p.end}
+			for i++; i < h.to && ix.pos[i].node == p.node; i++ {
+				c.end = ix.pos[i].end
+			}
+			if first {
+				c.repl = "[REDACTED: " + h.label + "]"
+				first = false
+			}
+			cuts[p.node] = append(cuts[p.node], c)
+		}
+	}
+	for node, cs := range cuts {
+		n := ix.nodes[node]
+		// Cuts are in document order; apply them back to front so earlier
+		// offsets stay valid.
+		for i := len(cs) - 1; i >= 0; i-- {
+			n.Data = n.Data[:cs[i].start] + cs[i].repl + n.Data[cs[i].end:]
+		}
+	}
+	return true
+}
+
+// redactHTML redacts spans in a fragment of editor HTML. Attributes
+// carrying a redacted quote (link targets, titles, alt text) are dropped.
+// Content without a match is returned exactly as given.
+func redactHTML(content string, spans []span) string {
+	if len(spans) == 0 || strings.TrimSpace(content) == "" {
+		return content
+	}
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(content), body)
+	if err != nil {
+		// The editor's HTML always parses; if it somehow does not, redact
+		// it as text rather than leak it.
+		return html.EscapeString(redactText(content, spans))
+	}
+	ix := &textIndex{}
+	attrs := false
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			ix.add(n)
+		case html.ElementNode:
+			if dropAttrs(n, spans) {
+				attrs = true
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+	if !ix.redact(spans) && !attrs {
+		return content
+	}
+	var b strings.Builder
+	for _, n := range nodes {
+		if err := html.Render(&b, n); err != nil {
+			return html.EscapeString(redactText(content, spans))
+		}
+	}
+	return b.String()
+}
+
+func dropAttrs(n *html.Node, spans []span) bool {
+	kept := n.Attr[:0]
+	dropped := false
+	for _, a := range n.Attr {
+		if a.Key != "src" && containsAny(a.Val, spans) {
+			dropped = true
+			continue
+		}
+		kept = append(kept, a)
+	}
+	n.Attr = kept
+	return dropped
+}
+
+func containsAny(s string, spans []span) bool {
+	text := string(normalise(s))
+	for _, sp := range spans {
+		if q := string(normalise(sp.quote)); q != "" && strings.Contains(text, q) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactText redacts spans in plain text.
+func redactText(s string, spans []span) string {
+	if len(spans) == 0 || s == "" {
+		return s
+	}
+	n := &html.Node{Type: html.TextNode, Data: s}
+	ix := &textIndex{}
+	ix.add(n)
+	ix.redact(spans)
+	return n.Data
+}
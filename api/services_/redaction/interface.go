@@ -0,0 +1,89 @@
+package redaction
+
+import (
+	"context"
+	"io"
+
+	"aegis-api/services_/chain_of_custody"
+	"aegis-api/services_/evidence/metadata"
+	"aegis-api/services_/report"
+
+	"github.com/google/uuid"
+)
+
+type Repository interface {
+	AutoMigrate() error
+
+	CreateMark(m *Mark) error
+	GetMark(tenantID, markID string) (*Mark, error)
+	// ListMarks returns the target's marks, oldest first; withdrawn marks
+	// are included only if withdrawn is true.
+	ListMarks(tenantID, targetType, targetID string, withdrawn bool) ([]Mark, error)
+	// WithdrawMark withdraws an active mark; a withdrawn or unknown mark
+	// returns ErrMarkNotFound.
+	WithdrawMark(tenantID, markID, userID string) (*Mark, error)
+
+	// CreateProfile returns ErrProfileExists if the name is taken.
+	CreateProfile(p *Profile) error
+	UpdateProfile(p *Profile) error
+	DeleteProfile(tenantID, profileID string) error
+	GetProfile(tenantID, profileID string) (*Profile, error)
+	ListProfiles(tenantID string) ([]Profile, error)
+
+	CreateDerivative(d *Derivative) error
+	ListDerivatives(tenantID, sourceType, sourceID string) ([]Derivative, error)
+	// FindDerivative returns the derivative of the source produced with
+	// the same marks and format, or nil.
+	FindDerivative(tenantID, sourceType, sourceID, sha256Source, format string, marks []byte) (*Derivative, error)
+}
+
+// Reports is the part of report.ReportService redaction needs.
+type Reports interface {
+	GetReportByID(ctx context.Context, reportID string) (*report.Report, error)
+}
+
+// Evidence is the part of the evidence metadata service redaction needs.
+type Evidence interface {
+	FindEvidenceByID(id uuid.UUID) (*metadata.Evidence, error)
+	SaveEvidence(e *metadata.Evidence) error
+	AppendLog(entry *metadata.EvidenceLog) error
+}
+
+// Storage holds evidence files.
+type Storage interface {
+	UploadFile(file io.Reader) (string, error)
+	Download(cid string) (io.ReadCloser, error)
+}
+
+// Custody records chain of custody entries.
+type Custody interface {
+	AddEntry(ctx context.Context, custody *chain_of_custody.ChainOfCustody) error
+}
+
+type Service interface {
+	Reasons() []Reason
+
+	// AddMark records a mark on a report (span marks) or on image or PDF
+	// evidence (region marks).
+	AddMark(ctx context.Context, actor Actor, targetType, targetID string, in MarkInput) (*Mark, error)
+	ListMarks(ctx context.Context, actor Actor, targetType, targetID string, withdrawn bool) ([]Mark, error)
+	WithdrawMark(ctx context.Context, actor Actor, markID string) (*Mark, error)
+
+	CreateProfile(actor Actor, in ProfileInput) (*Profile, error)
+	UpdateProfile(actor Actor, profileID string, in ProfileInput) (*Profile, error)
+	DeleteProfile(actor Actor, profileID string) error
+	ListProfiles(actor Actor) ([]Profile, error)
+
+	// PlanFor selects the marks to apply when actor exports the target:
+	// those whose reason is covered by the requested profile (an ID, a
+	// name or AllMarks) or by any profile bound to the actor's role. It
+	// returns nil when nothing is to be redacted.
+	PlanFor(ctx context.Context, actor Actor, targetType, targetID, profile string) (*Plan, error)
+
+	// RecordReportExport records a redacted report export.
+	RecordReportExport(actor Actor, reportID string, plan *Plan, format string, data []byte) (*Derivative, error)
+	// DeriveEvidence produces a redacted copy of image or PDF evidence as
+	// new evidence of the same case. The original file is not modified.
+	DeriveEvidence(ctx context.Context, actor Actor, evidenceID, profile string) (*Derivative, *metadata.Evidence, error)
+	ListDerivatives(ctx context.Context, actor Actor, sourceType, sourceID string) ([]Derivative, error)
+}
@@ -0,0 +1,164 @@
+package redaction
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Target types.
+const (
+	TargetReport   = "report"
+	TargetEvidence = "evidence"
+)
+
+// Mark kinds.
+const (
+	KindSpan   = "span"
+	KindRegion = "region"
+)
+
+// AllMarks selects every active mark instead of a profile.
+const AllMarks = "all"
+
+// Reason is a recorded ground for a redaction. Profiles select marks by
+// reason.
+type Reason struct {
+	Code  string `json:"code"`
+	Label string `json:"label"`
+}
+
+var Reasons = []Reason{
+	{Code: "personal_data", Label: "Personal data"},
+	{Code: "special_category", Label: "Special category data"},
+	{Code: "legal_privilege", Label: "Legal privilege"},
+	{Code: "third_party", Label: "Third-party confidential"},
+	{Code: "security", Label: "Security sensitive"},
+	{Code: "operational", Label: "Operationally sensitive"},
+	{Code: "other", Label: "Redacted"},
+}
+
+func reasonLabel(code string) string {
+	for _, r := range Reasons {
+		if r.Code == code {
+			return r.Label
+		}
+	}
+	return "Redacted"
+}
+
+// Mark is one redaction: a passage of report text or a rectangle of an
+// evidence image or PDF page. Marks never change the original; they are
+// applied when a redacted derivative is produced.
+type Mark struct {
+	ID         string `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID   string `gorm:"type:uuid;not null;index:idx_redaction_marks_target,priority:1" json:"tenant_id"`
+	CaseID     string `gorm:"type:uuid;not null;index" json:"case_id"`
+	TargetType string `gorm:"type:varchar(20);not null;index:idx_redaction_marks_target,priority:2" json:"target_type"`
+	TargetID   string `gorm:"type:uuid;not null;index:idx_redaction_marks_target,priority:3" json:"target_id"`
+	Kind       string `gorm:"type:varchar(10);not null" json:"kind"`
+
+	// Span marks. SectionID limits the mark to one report section; empty
+	// redacts Quote wherever it appears in the report, exhibits included.
+	SectionID string `gorm:"type:varchar(24)" json:"section_id,omitempty"`
+	Quote     string `gorm:"type:text" json:"quote,omitempty"`
+	// Occurrence redacts only the nth match (1-based); 0 redacts every one.
+	Occurrence int `gorm:"not null;default:0" json:"occurrence,omitempty"`
+
+	// Region marks, as fractions (0-1) of the image or page. Page is
+	// 1-based for PDFs and 0 for images.
+	Page int     `gorm:"not null;default:0" json:"page,omitempty"`
+	X    float64 `json:"x,omitempty"`
+	Y    float64 `json:"y,omitempty"`
+	W    float64 `json:"w,omitempty"`
+	H    float64 `json:"h,omitempty"`
+
+	Reason    string    `gorm:"type:varchar(40);not null" json:"reason"`
+	Note      string    `gorm:"type:text" json:"note,omitempty"`
+	CreatedBy string    `gorm:"type:uuid;not null" json:"created_by"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	// Withdrawn marks stay on record but are no longer applied.
+	WithdrawnBy *string    `gorm:"type:uuid" json:"withdrawn_by,omitempty"`
+	WithdrawnAt *time.Time `json:"withdrawn_at,omitempty"`
+}
+
+func (Mark) TableName() string { return "redaction_marks" }
+
+// Profile is an audience-specific selection of redaction reasons. A
+// profile bound to roles applies automatically to every export by users
+// holding one of them.
+type Profile struct {
+	ID        string         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID  string         `gorm:"type:uuid;not null;uniqueIndex:idx_redaction_profiles_name,priority:1" json:"tenant_id"`
+	Name      string         `gorm:"type:varchar(120);not null;uniqueIndex:idx_redaction_profiles_name,priority:2" json:"name"`
+	Audience  string         `gorm:"type:text" json:"audience,omitempty"`
+	Reasons   datatypes.JSON `gorm:"type:jsonb;not null" json:"reasons"` // []string
+	Roles     datatypes.JSON `gorm:"type:jsonb;not null" json:"roles"`   // []string
+	CreatedBy string         `gorm:"type:uuid;not null" json:"created_by"`
+	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (Profile) TableName() string { return "redaction_profiles" }
+
+// Derivative records a redacted artifact produced from an original, with
+// the marks applied. Redacted evidence is stored as new evidence of the
+// same case; redacted report exports are not stored, as the same report
+// version and marks render to the same bytes.
+type Derivative struct {
+	ID           string         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID     string         `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	SourceType   string         `gorm:"type:varchar(20);not null;index:idx_redaction_derivatives_source,priority:1" json:"source_type"`
+	SourceID     string         `gorm:"type:uuid;not null;index:idx_redaction_derivatives_source,priority:2" json:"source_id"`
+	SourceSHA256 string         `gorm:"type:char(64)" json:"source_sha256,omitempty"`
+	ProfileIDs   datatypes.JSON `gorm:"type:jsonb;not null" json:"profile_ids"` // []string; ["all"] for every mark
+	Marks        datatypes.JSON `gorm:"type:jsonb;not null" json:"marks"`       // []AppliedMark
+	Format       string         `gorm:"type:varchar(20);not null" json:"format"`
+	SHA256       string         `gorm:"type:char(64);not null;index" json:"sha256"`
+	SHA512       string         `gorm:"type:char(128);not null" json:"sha512"`
+	Size         int64          `gorm:"not null" json:"size"`
+	// DerivedEvidenceID is the evidence holding a redacted evidence file.
+	DerivedEvidenceID *string   `gorm:"type:uuid" json:"derived_evidence_id,omitempty"`
+	CreatedBy         string    `gorm:"type:uuid;not null" json:"created_by"`
+	CreatedAt         time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (Derivative) TableName() string { return "redaction_derivatives" }
+
+// AppliedMark is a mark as recorded on a derivative.
+type AppliedMark struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// Actor is the user working with redactions.
+type Actor struct {
+	UserID   string
+	Role     string
+	TenantID string
+	Email    string
+}
+
+// MarkInput is a new mark. Span marks set Quote; region marks set the
+// rectangle.
+type MarkInput struct {
+	Kind       string  `json:"kind"`
+	SectionID  string  `json:"section_id"`
+	Quote      string  `json:"quote"`
+	Occurrence int     `json:"occurrence"`
+	Page       int     `json:"page"`
+	X          float64 `json:"x"`
+	Y          float64 `json:"y"`
+	W          float64 `json:"w"`
+	H          float64 `json:"h"`
+	Reason     string  `json:"reason"`
+	Note       string  `json:"note"`
+}
+
+// ProfileInput creates or replaces a profile.
+type ProfileInput struct {
+	Name     string   `json:"name"`
+	Audience string   `json:"audience"`
+	Reasons  []string `json:"reasons"`
+	Roles    []string `json:"roles"`
+}
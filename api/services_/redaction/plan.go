@@ -0,0 +1,111 @@
+package redaction
+
+import (
+	"context"
+	"encoding/json"
+
+	"aegis-api/services_/report"
+)
+
+// Plan is the set of marks applied to one export.
+type Plan struct {
+	// ProfileIDs are the profiles that selected the marks; AllMarks when
+	// every mark was applied.
+	ProfileIDs []string
+	Marks      []Mark
+}
+
+// Applied lists the plan's marks as recorded on a derivative.
+func (p *Plan) Applied() []AppliedMark {
+	out := make([]AppliedMark, len(p.Marks))
+	for i, m := range p.Marks {
+		out[i] = AppliedMark{ID: m.ID, Reason: m.Reason}
+	}
+	return out
+}
+
+func (p *Plan) fingerprint() ([]byte, []byte, error) {
+	marks, err := json.Marshal(p.Applied())
+	if err != nil {
+		return nil, nil, err
+	}
+	profiles, err := json.Marshal(p.ProfileIDs)
+	return marks, profiles, err
+}
+
+// spans returns the span marks that apply to a report section; sectionID
+// is "" for content that belongs to no section, such as the exhibits.
+func (p *Plan) spans(sectionID string) []span {
+	var out []span
+	for _, m := range p.Marks {
+		if m.Kind != KindSpan || (m.SectionID != "" && m.SectionID != sectionID) {
+			continue
+		}
+		out = append(out, span{quote: m.Quote, occurrence: m.Occurrence, label: reasonLabel(m.Reason)})
+	}
+	return out
+}
+
+// Renderer wraps a merge field renderer so every export built from it,
+// in any format, carries the plan's redactions: section titles, content,
+// and the field values shown alongside them. A nil inner renderer leaves
+// merge fields unexpanded.
+func (p *Plan) Renderer(inner report.FieldRenderer) report.FieldRenderer {
+	return &redactingRenderer{plan: p, inner: inner}
+}
+
+type redactingRenderer struct {
+	plan  *Plan
+	inner report.FieldRenderer
+}
+
+func (r *redactingRenderer) RenderFields(ctx context.Context, rep *report.Report, sections []report.ReportSection) ([]report.ReportSection, map[string][]report.FrozenField, error) {
+	out, values, err := sections, map[string][]report.FrozenField(nil), error(nil)
+	if r.inner != nil {
+		out, values, err = r.inner.RenderFields(ctx, rep, sections)
+	}
+	redacted := make([]report.ReportSection, len(out))
+	for i, sec := range out {
+		id := ""
+		if !sec.ID.IsZero() {
+			id = sec.ID.Hex()
+		}
+		spans := r.plan.spans(id)
+		sec.Title = redactText(sec.Title, spans)
+		sec.Content = redactHTML(sec.Content, spans)
+		sec.FrozenFields = redactFields(sec.FrozenFields, spans)
+		sec.Provenance = redactProvenance(sec.Provenance, spans)
+		redacted[i] = sec
+		if v, ok := values[id]; ok && id != "" {
+			values[id] = redactFields(v, spans)
+		}
+	}
+	return redacted, values, err
+}
+
+func redactFields(fields []report.FrozenField, spans []span) []report.FrozenField {
+	if len(fields) == 0 || len(spans) == 0 {
+		return fields
+	}
+	out := make([]report.FrozenField, len(fields))
+	for i, f := range fields {
+		out[i] = report.FrozenField{Key: f.Key, Value: redactHTML(f.Value, spans)}
+	}
+	return out
+}
+
+func redactProvenance(prov []report.SectionProvenance, spans []span) []report.SectionProvenance {
+	if len(prov) == 0 || len(spans) == 0 {
+		return prov
+	}
+	out := make([]report.SectionProvenance, len(prov))
+	for i, p := range prov {
+		claims := make([]report.ProvenanceClaim, len(p.Claims))
+		for j, c := range p.Claims {
+			claims[j] = report.ProvenanceClaim{Text: redactText(c.Text, spans), Refs: c.Refs}
+		}
+		p.Claims = claims
+		out[i] = p
+	}
+	return out
+}
@@ -0,0 +1,69 @@
+package redaction
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Rasterizer renders each page of a PDF as a PNG image.
+type Rasterizer interface {
+	Rasterize(ctx context.Context, pdf []byte, dpi int) ([][]byte, error)
+}
+
+// PopplerRasterizer runs poppler's pdftoppm.
+type PopplerRasterizer struct {
+	// Path to pdftoppm; empty looks it up on PATH.
+	Path string
+}
+
+func (p PopplerRasterizer) Rasterize(ctx context.Context, pdf []byte, dpi int) ([][]byte, error) {
+	bin := p.Path
+	if bin == "" {
+		bin = "pdftoppm"
+	}
+	dir, err := os.MkdirTemp("", "redact-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	cmd := exec.CommandContext(ctx, bin, "-r", strconv.Itoa(dpi), "-png", "-", filepath.Join(dir, "page"))
+	cmd.Stdin = bytes.NewReader(pdf)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedEvidence, msg)
+		}
+		return nil, fmt.Errorf("pdftoppm: %w", err)
+	}
+
+	// Pages are written as page-1.png, or page-01.png etc. with padding
+	// that depends on the page count.
+	files, err := filepath.Glob(filepath.Join(dir, "page-*.png"))
+	if err != nil {
+		return nil, err
+	}
+	pageNo := func(f string) int {
+		n, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(f), "page-"), ".png"))
+		return n
+	}
+	sort.Slice(files, func(i, j int) bool { return pageNo(files[i]) < pageNo(files[j]) })
+	pages := make([][]byte, len(files))
+	for i, f := range files {
+		if pages[i], err = os.ReadFile(f); err != nil {
+			return nil, err
+		}
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("%w: no pages", ErrUnsupportedEvidence)
+	}
+	return pages, nil
+}
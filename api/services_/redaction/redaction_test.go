@@ -0,0 +1,255 @@
+package redaction_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"aegis-api/services_/evidence/metadata"
+	"aegis-api/services_/redaction"
+	"aegis-api/services_/report"
+	"aegis-api/tests/fakes"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fixture struct {
+	svc      redaction.Service
+	repo     *fakes.Redactions
+	evidence *fakes.Evidence
+	storage  fakes.Blobs
+	custody  *fakes.Custody
+	actor    redaction.Actor
+	rpt      *report.Report
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	tenant := uuid.New()
+	f := &fixture{
+		repo:     &fakes.Redactions{},
+		evidence: &fakes.Evidence{},
+		storage:  fakes.Blobs{},
+		custody:  &fakes.Custody{},
+		actor:    redaction.Actor{UserID: uuid.NewString(), Role: "DFIR Analyst", TenantID: tenant.String(), Email: "analyst@example.com"},
+		rpt:      &report.Report{ID: uuid.New(), CaseID: uuid.New(), TenantID: tenant},
+	}
+	reports := &fakes.Reports{}
+	reports.Add(f.rpt)
+	f.svc = redaction.NewService(f.repo, reports, f.evidence, f.storage, f.custody, fakes.Rasterizer{Page: whitePNG(t, 40, 20), Pages: 2})
+	return f
+}
+
+func (f *fixture) addEvidence(t *testing.T, name string, data []byte) *metadata.Evidence {
+	t.Helper()
+	cid, err := f.storage.UploadFile(bytes.NewReader(data))
+	require.NoError(t, err)
+	sum := sha256.Sum256(data)
+	ev := &metadata.Evidence{
+		ID: uuid.New(), CaseID: f.rpt.CaseID, TenantID: uuid.MustParse(f.actor.TenantID),
+		Filename: name, IpfsCID: cid, Checksum: hex.EncodeToString(sum[:]),
+	}
+	require.NoError(t, f.evidence.SaveEvidence(ev))
+	return ev
+}
+
+func whitePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestRendererRedactsSpans(t *testing.T) {
+	sec1, sec2 := primitive.NewObjectID(), primitive.NewObjectID()
+	plan := &redaction.Plan{Marks: []redaction.Mark{
+		{ID: "m1", Kind: redaction.KindSpan, Quote: "john   SMITH", Reason: "personal_data"},
+		{ID: "m2", Kind: redaction.KindSpan, SectionID: sec2.Hex(), Quote: "10.0.0.5", Occurrence: 2, Reason: "security"},
+	}}
+	sections := []report.ReportSection{
+		{ID: sec1, Title: "Interview of John Smith", Content: `<p>Witness <b>John</b> Smith saw 10.0.0.5 and <a href="mailto:john smith">mail</a>.</p>`},
+		{ID: sec2, Title: "Network", Content: `<p>10.0.0.5 then 10.0.0.5 then 10.0.0.5</p>`,
+			FrozenFields: []report.FrozenField{{Key: "case.title", Value: "John Smith inquiry"}}},
+		{ID: primitive.NewObjectID(), Title: "Untouched", Content: "<p>Nothing&nbsp;here <br>at all</p>"},
+		{Title: "Case", Content: "<p>Matter of John Smith</p>"},
+	}
+
+	out, _, err := plan.Renderer(nil).RenderFields(context.Background(), &report.Report{}, sections)
+	require.NoError(t, err)
+
+	require.Equal(t, "Interview of [REDACTED: Personal data]", out[0].Title)
+	require.NotContains(t, strings.ToLower(out[0].Content), "smith")
+	require.Contains(t, out[0].Content, "[REDACTED: Personal data]")
+	require.Contains(t, out[0].Content, "10.0.0.5", "section-scoped marks stay in their section")
+	require.NotContains(t, out[0].Content, "mailto", "attributes carrying the quote are dropped")
+
+	require.Equal(t, "<p>10.0.0.5 then [REDACTED: Security sensitive] then 10.0.0.5</p>", out[1].Content)
+	require.Equal(t, "[REDACTED: Personal data] inquiry", out[1].FrozenFields[0].Value)
+
+	require.Equal(t, sections[2].Content, out[2].Content, "content without matches is returned as is")
+	require.Equal(t, "<p>Matter of [REDACTED: Personal data]</p>", out[3].Content, "report-wide marks cover exhibits")
+
+	require.Contains(t, sections[0].Content, "Smith", "the original sections are not modified")
+}
+
+func TestPlanForSelectsByProfileAndRole(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	rid := f.rpt.ID.String()
+
+	for _, in := range []redaction.MarkInput{
+		{Quote: "Jane Doe", Reason: "personal_data"},
+		{Quote: "counsel advised", Reason: "legal_privilege"},
+		{Quote: "source X", Reason: "operational"},
+	} {
+		_, err := f.svc.AddMark(ctx, f.actor, redaction.TargetReport, rid, in)
+		require.NoError(t, err)
+	}
+	_, err := f.svc.AddMark(ctx, f.actor, redaction.TargetReport, rid, redaction.MarkInput{Quote: "x", Reason: "gossip"})
+	require.ErrorIs(t, err, redaction.ErrInvalidMark)
+	_, err = f.svc.AddMark(ctx, redaction.Actor{TenantID: uuid.NewString()}, redaction.TargetReport, rid, redaction.MarkInput{Quote: "x", Reason: "other"})
+	require.ErrorIs(t, err, redaction.ErrTargetNotFound)
+
+	plan, err := f.svc.PlanFor(ctx, f.actor, redaction.TargetReport, rid, "")
+	require.NoError(t, err)
+	require.Nil(t, plan, "no profile applies")
+
+	press, err := f.svc.CreateProfile(f.actor, redaction.ProfileInput{Name: "Press", Reasons: []string{"personal_data", "operational"}})
+	require.NoError(t, err)
+	_, err = f.svc.CreateProfile(f.actor, redaction.ProfileInput{Name: "Client", Reasons: []string{"legal_privilege"}, Roles: []string{"External Collaborator"}})
+	require.NoError(t, err)
+	_, err = f.svc.CreateProfile(f.actor, redaction.ProfileInput{Name: "Press", Reasons: []string{"other"}})
+	require.ErrorIs(t, err, redaction.ErrProfileExists)
+
+	plan, err = f.svc.PlanFor(ctx, f.actor, redaction.TargetReport, rid, "press")
+	require.NoError(t, err)
+	require.Equal(t, []string{press.ID}, plan.ProfileIDs)
+	require.Len(t, plan.Marks, 2)
+
+	_, err = f.svc.PlanFor(ctx, f.actor, redaction.TargetReport, rid, "nobody")
+	require.ErrorIs(t, err, redaction.ErrProfileNotFound)
+
+	// A role-bound profile applies without being asked for, on top of any
+	// requested one.
+	external := f.actor
+	external.Role = "External Collaborator"
+	plan, err = f.svc.PlanFor(ctx, external, redaction.TargetReport, rid, "")
+	require.NoError(t, err)
+	require.Len(t, plan.Marks, 1)
+	require.Equal(t, "legal_privilege", plan.Marks[0].Reason)
+	plan, err = f.svc.PlanFor(ctx, external, redaction.TargetReport, rid, press.ID)
+	require.NoError(t, err)
+	require.Len(t, plan.Marks, 3)
+
+	// Withdrawn marks are no longer applied.
+	marks, err := f.svc.ListMarks(ctx, f.actor, redaction.TargetReport, rid, false)
+	require.NoError(t, err)
+	_, err = f.svc.WithdrawMark(ctx, f.actor, marks[0].ID)
+	require.NoError(t, err)
+	plan, err = f.svc.PlanFor(ctx, f.actor, redaction.TargetReport, rid, redaction.AllMarks)
+	require.NoError(t, err)
+	require.Equal(t, []string{redaction.AllMarks}, plan.ProfileIDs)
+	require.Len(t, plan.Marks, 2)
+	_, err = f.svc.WithdrawMark(ctx, f.actor, marks[0].ID)
+	require.ErrorIs(t, err, redaction.ErrMarkNotFound)
+}
+
+func TestDeriveImageEvidence(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	original := whitePNG(t, 100, 50)
+	ev := f.addEvidence(t, "screenshot.png", original)
+	id := ev.ID.String()
+
+	_, _, err := f.svc.DeriveEvidence(ctx, f.actor, id, redaction.AllMarks)
+	require.ErrorIs(t, err, redaction.ErrNothingToRedact)
+
+	_, err = f.svc.AddMark(ctx, f.actor, redaction.TargetEvidence, id, redaction.MarkInput{X: 0.5, Y: 0.5, W: 0.6, H: 0.1, Reason: "third_party"})
+	require.ErrorIs(t, err, redaction.ErrInvalidMark, "regions must fit the image")
+	_, err = f.svc.AddMark(ctx, f.actor, redaction.TargetEvidence, id, redaction.MarkInput{X: 0.1, Y: 0.2, W: 0.3, H: 0.4, Reason: "third_party"})
+	require.NoError(t, err)
+
+	d, derived, err := f.svc.DeriveEvidence(ctx, f.actor, id, redaction.AllMarks)
+	require.NoError(t, err)
+	require.Equal(t, "screenshot (redacted).png", derived.Filename)
+	require.Equal(t, ev.CaseID, derived.CaseID)
+	require.Equal(t, derived.ID.String(), *d.DerivedEvidenceID)
+	require.Equal(t, derived.Checksum, d.SHA256)
+	require.NotEqual(t, ev.Checksum, derived.Checksum)
+	require.Equal(t, original, f.storage[ev.IpfsCID], "the original file is untouched")
+
+	img, err := png.Decode(bytes.NewReader(f.storage[derived.IpfsCID]))
+	require.NoError(t, err)
+	black := func(x, y int) bool {
+		r, g, b, _ := img.At(x, y).RGBA()
+		return r == 0 && g == 0 && b == 0
+	}
+	require.True(t, black(10, 10))
+	require.True(t, black(39, 29))
+	require.False(t, black(9, 10))
+	require.False(t, black(40, 30))
+
+	var meta map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(derived.Metadata), &meta))
+	require.Equal(t, id, meta["redacted_from"])
+
+	require.Len(t, f.evidence.Logs, 2)
+	require.Equal(t, ev.ID, f.evidence.Logs[0].EvidenceID)
+	require.Equal(t, derived.ID, f.evidence.Logs[1].EvidenceID)
+	require.Contains(t, f.evidence.Logs[1].Details, ev.Checksum)
+	require.Len(t, f.custody.Entries, 2)
+	require.Equal(t, "analyst@example.com", f.custody.Entries[1].Custodian)
+
+	// Deriving again with the same marks returns the existing copy.
+	again, _, err := f.svc.DeriveEvidence(ctx, f.actor, id, redaction.AllMarks)
+	require.NoError(t, err)
+	require.Equal(t, d.ID, again.ID)
+	require.Len(t, f.repo.Derivatives, 1)
+
+	// A stored file that no longer matches its checksum is refused.
+	f.storage[ev.IpfsCID] = whitePNG(t, 10, 10)
+	_, err = f.svc.AddMark(ctx, f.actor, redaction.TargetEvidence, id, redaction.MarkInput{X: 0, Y: 0, W: 1, H: 1, Reason: "other"})
+	require.NoError(t, err)
+	_, _, err = f.svc.DeriveEvidence(ctx, f.actor, id, redaction.AllMarks)
+	require.ErrorIs(t, err, redaction.ErrIntegrity)
+}
+
+func TestDerivePDFEvidence(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	ev := f.addEvidence(t, "statement.pdf", []byte("%PDF-1.4\n% not really rendered here\n"))
+	id := ev.ID.String()
+
+	_, err := f.svc.AddMark(ctx, f.actor, redaction.TargetEvidence, id, redaction.MarkInput{Page: 3, X: 0, Y: 0, W: 1, H: 0.1, Reason: "personal_data"})
+	require.NoError(t, err)
+	_, _, err = f.svc.DeriveEvidence(ctx, f.actor, id, redaction.AllMarks)
+	require.ErrorIs(t, err, redaction.ErrInvalidMark, "page 3 of a 2-page document")
+
+	marks, err := f.svc.ListMarks(ctx, f.actor, redaction.TargetEvidence, id, false)
+	require.NoError(t, err)
+	_, err = f.svc.WithdrawMark(ctx, f.actor, marks[0].ID)
+	require.NoError(t, err)
+	_, err = f.svc.AddMark(ctx, f.actor, redaction.TargetEvidence, id, redaction.MarkInput{Page: 2, X: 0, Y: 0, W: 1, H: 0.1, Reason: "personal_data"})
+	require.NoError(t, err)
+
+	d, derived, err := f.svc.DeriveEvidence(ctx, f.actor, id, redaction.AllMarks)
+	require.NoError(t, err)
+	require.Equal(t, redaction.FormatPDF, d.Format)
+	require.Equal(t, "statement (redacted).pdf", derived.Filename)
+	require.True(t, bytes.HasPrefix(f.storage[derived.IpfsCID], []byte("%PDF-")))
+}
@@ -0,0 +1,146 @@
+package redaction
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Evidence formats that region marks can be burned into.
+const (
+	FormatPNG  = "png"
+	FormatJPEG = "jpeg"
+	FormatPDF  = "pdf"
+)
+
+// rasterDPI is the resolution PDF pages are rasterised at before regions
+// are burned in.
+const rasterDPI = 150
+
+// sniffFormat identifies a redactable evidence file by its content.
+func sniffFormat(data []byte) (string, error) {
+	switch http.DetectContentType(data) {
+	case "image/png", "image/gif":
+		return FormatPNG, nil
+	case "image/jpeg":
+		return FormatJPEG, nil
+	case "application/pdf":
+		return FormatPDF, nil
+	}
+	return "", ErrUnsupportedEvidence
+}
+
+// burnImage decodes an image, paints the regions black and re-encodes it.
+// Decoding drops embedded metadata such as EXIF, which may itself
+// identify people or places. GIFs come out as PNG; only the first frame
+// is kept.
+func burnImage(data []byte, format string, regions []Mark) ([]byte, error) {
+	var (
+		img image.Image
+		err error
+	)
+	if http.DetectContentType(data) == "image/gif" {
+		img, err = gif.Decode(bytes.NewReader(data))
+	} else {
+		img, _, err = image.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedEvidence, err)
+	}
+	rgba := burn(img, regions)
+
+	var out bytes.Buffer
+	if format == FormatJPEG {
+		err = jpeg.Encode(&out, rgba, &jpeg.Options{Quality: 92})
+	} else {
+		err = png.Encode(&out, rgba)
+	}
+	return out.Bytes(), err
+}
+
+// burn copies img and fills each region, given as fractions of the image,
+// with black.
+func burn(img image.Image, regions []Mark) *image.RGBA {
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	w, h := float64(b.Dx()), float64(b.Dy())
+	for _, m := range regions {
+		r := image.Rect(
+			int(m.X*w), int(m.Y*h),
+			// Round the far edge up so a region never leaves a sliver.
+			int(m.X*w+m.W*w+0.999), int(m.Y*h+m.H*h+0.999),
+		).Intersect(rgba.Bounds())
+		draw.Draw(rgba, r, &image.Uniform{C: color.Black}, image.Point{}, draw.Src)
+	}
+	return rgba
+}
+
+// burnPDF rasterises every page, burns in the regions marked on it (page 0
+// marks apply to every page) and rebuilds the document from the page
+// images. The result has no text layer, so nothing under a region can be
+// recovered by copying text or removing an overlay.
+func burnPDF(ctx context.Context, r Rasterizer, data []byte, regions []Mark, created time.Time) ([]byte, error) {
+	pages, err := r.Rasterize(ctx, data, rasterDPI)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range regions {
+		if m.Page > len(pages) {
+			return nil, fmt.Errorf("%w: page %d of a %d-page document", ErrInvalidMark, m.Page, len(pages))
+		}
+	}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCreationDate(created.UTC())
+	pdf.SetModificationDate(created.UTC())
+	pdf.SetCatalogSort(true)
+	pdf.SetProducer("AEGIS redaction", false)
+	pdf.SetCompression(true)
+	for i, page := range pages {
+		var onPage []Mark
+		for _, m := range regions {
+			if m.Page == 0 || m.Page == i+1 {
+				onPage = append(onPage, m)
+			}
+		}
+		img, err := png.Decode(bytes.NewReader(page))
+		if err != nil {
+			return nil, fmt.Errorf("rasterised page %d: %w", i+1, err)
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, burn(img, onPage)); err != nil {
+			return nil, err
+		}
+
+		b := img.Bounds()
+		wmm := float64(b.Dx()) / rasterDPI * 25.4
+		hmm := float64(b.Dy()) / rasterDPI * 25.4
+		orientation := "P"
+		if wmm > hmm {
+			orientation = "L"
+		}
+		pdf.AddPageFormat(orientation, gofpdf.SizeType{Wd: wmm, Ht: hmm})
+		name := fmt.Sprintf("page%d", i+1)
+		pdf.RegisterImageOptionsReader(name, gofpdf.ImageOptions{ImageType: "PNG"}, &buf)
+		pdf.ImageOptions(name, 0, 0, wmm, hmm, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	}
+	if err := pdf.Error(); err != nil {
+		return nil, fmt.Errorf("pdf layout: %w", err)
+	}
+	var out bytes.Buffer
+	if err := pdf.Output(&out); err != nil {
+		return nil, fmt.Errorf("pdf output: %w", err)
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,126 @@
+package redaction
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) AutoMigrate() error {
+	return r.db.AutoMigrate(&Mark{}, &Profile{}, &Derivative{})
+}
+
+func (r *GormRepository) CreateMark(m *Mark) error {
+	return r.db.Create(m).Error
+}
+
+func (r *GormRepository) GetMark(tenantID, markID string) (*Mark, error) {
+	var m Mark
+	err := r.db.Where("tenant_id = ? AND id = ?", tenantID, markID).First(&m).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMarkNotFound
+	}
+	return &m, err
+}
+
+func (r *GormRepository) ListMarks(tenantID, targetType, targetID string, withdrawn bool) ([]Mark, error) {
+	q := r.db.Where("tenant_id = ? AND target_type = ? AND target_id = ?", tenantID, targetType, targetID)
+	if !withdrawn {
+		q = q.Where("withdrawn_at IS NULL")
+	}
+	var out []Mark
+	err := q.Order("created_at ASC, id ASC").Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) WithdrawMark(tenantID, markID, userID string) (*Mark, error) {
+	now := time.Now().UTC()
+	res := r.db.Model(&Mark{}).
+		Where("tenant_id = ? AND id = ? AND withdrawn_at IS NULL", tenantID, markID).
+		Updates(map[string]interface{}{"withdrawn_by": userID, "withdrawn_at": now})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrMarkNotFound
+	}
+	return r.GetMark(tenantID, markID)
+}
+
+func (r *GormRepository) CreateProfile(p *Profile) error {
+	err := r.db.Create(p).Error
+	if isUniqueViolation(err) {
+		return ErrProfileExists
+	}
+	return err
+}
+
+func (r *GormRepository) UpdateProfile(p *Profile) error {
+	err := r.db.Save(p).Error
+	if isUniqueViolation(err) {
+		return ErrProfileExists
+	}
+	return err
+}
+
+func (r *GormRepository) DeleteProfile(tenantID, profileID string) error {
+	res := r.db.Where("tenant_id = ? AND id = ?", tenantID, profileID).Delete(&Profile{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrProfileNotFound
+	}
+	return nil
+}
+
+func (r *GormRepository) GetProfile(tenantID, profileID string) (*Profile, error) {
+	var p Profile
+	err := r.db.Where("tenant_id = ? AND id = ?", tenantID, profileID).First(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProfileNotFound
+	}
+	return &p, err
+}
+
+func (r *GormRepository) ListProfiles(tenantID string) ([]Profile, error) {
+	var out []Profile
+	err := r.db.Where("tenant_id = ?", tenantID).Order("name ASC").Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) CreateDerivative(d *Derivative) error {
+	return r.db.Create(d).Error
+}
+
+func (r *GormRepository) ListDerivatives(tenantID, sourceType, sourceID string) ([]Derivative, error) {
+	var out []Derivative
+	err := r.db.Where("tenant_id = ? AND source_type = ? AND source_id = ?", tenantID, sourceType, sourceID).
+		Order("created_at DESC").Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) FindDerivative(tenantID, sourceType, sourceID, sha256Source, format string, marks []byte) (*Derivative, error) {
+	var d Derivative
+	err := r.db.Where("tenant_id = ? AND source_type = ? AND source_id = ? AND source_sha256 = ? AND format = ? AND marks = ?::jsonb",
+		tenantID, sourceType, sourceID, sha256Source, format, string(marks)).
+		Order("created_at DESC").First(&d).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &d, err
+}
+
+func isUniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "duplicate key")
+}
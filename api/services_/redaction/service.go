@@ -0,0 +1,587 @@
+package redaction
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"aegis-api/services_/chain_of_custody"
+	"aegis-api/services_/evidence/metadata"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gorm.io/datatypes"
+)
+
+var (
+	ErrTargetNotFound      = errors.New("redaction target not found")
+	ErrMarkNotFound        = errors.New("redaction mark not found")
+	ErrProfileNotFound     = errors.New("redaction profile not found")
+	ErrProfileExists       = errors.New("a redaction profile with this name already exists")
+	ErrInvalidMark         = errors.New("invalid redaction mark")
+	ErrInvalidProfile      = errors.New("invalid redaction profile")
+	ErrNothingToRedact     = errors.New("no redaction marks apply")
+	ErrUnsupportedEvidence = errors.New("only image and PDF evidence can be redacted")
+	ErrIntegrity           = errors.New("evidence file does not match its recorded checksum")
+	ErrFileTooLarge        = errors.New("evidence file is too large to redact")
+)
+
+// MaxEvidenceSize bounds the evidence files DeriveEvidence loads.
+const MaxEvidenceSize = 200 << 20
+
+const maxQuoteLen = 2000
+
+type service struct {
+	repo       Repository
+	reports    Reports
+	evidence   Evidence
+	storage    Storage
+	custody    Custody
+	rasterizer Rasterizer
+	now        func() time.Time
+}
+
+func NewService(repo Repository, reports Reports, evidence Evidence, storage Storage, custody Custody, rasterizer Rasterizer) Service {
+	return &service{
+		repo:       repo,
+		reports:    reports,
+		evidence:   evidence,
+		storage:    storage,
+		custody:    custody,
+		rasterizer: rasterizer,
+		now:        time.Now,
+	}
+}
+
+func (s *service) Reasons() []Reason {
+	return Reasons
+}
+
+// target resolves a report or evidence of the actor's tenant and returns
+// its case; targets of other tenants are not found.
+func (s *service) target(ctx context.Context, actor Actor, targetType, targetID string) (caseID string, err error) {
+	switch targetType {
+	case TargetReport:
+		if _, err := uuid.Parse(targetID); err != nil {
+			return "", ErrTargetNotFound
+		}
+		rpt, err := s.reports.GetReportByID(ctx, targetID)
+		if err != nil || rpt == nil || rpt.TenantID.String() != actor.TenantID {
+			return "", ErrTargetNotFound
+		}
+		return rpt.CaseID.String(), nil
+	case TargetEvidence:
+		ev, err := s.evidenceInTenant(actor, targetID)
+		if err != nil {
+			return "", err
+		}
+		return ev.CaseID.String(), nil
+	}
+	return "", ErrTargetNotFound
+}
+
+func (s *service) evidenceInTenant(actor Actor, evidenceID string) (*metadata.Evidence, error) {
+	id, err := uuid.Parse(evidenceID)
+	if err != nil {
+		return nil, ErrTargetNotFound
+	}
+	ev, err := s.evidence.FindEvidenceByID(id)
+	if err != nil || ev == nil || ev.TenantID.String() != actor.TenantID {
+		return nil, ErrTargetNotFound
+	}
+	return ev, nil
+}
+
+func validReason(code string) bool {
+	for _, r := range Reasons {
+		if r.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *service) AddMark(ctx context.Context, actor Actor, targetType, targetID string, in MarkInput) (*Mark, error) {
+	caseID, err := s.target(ctx, actor, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if !validReason(in.Reason) {
+		return nil, fmt.Errorf("%w: unknown reason %q", ErrInvalidMark, in.Reason)
+	}
+	m := &Mark{
+		TenantID:   actor.TenantID,
+		CaseID:     caseID,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     in.Reason,
+		Note:       strings.TrimSpace(in.Note),
+		CreatedBy:  actor.UserID,
+	}
+
+	switch targetType {
+	case TargetReport:
+		if in.Kind != "" && in.Kind != KindSpan {
+			return nil, fmt.Errorf("%w: reports take span marks", ErrInvalidMark)
+		}
+		quote := strings.TrimSpace(in.Quote)
+		if quote == "" || len(quote) > maxQuoteLen {
+			return nil, fmt.Errorf("%w: quote must be 1-%d characters", ErrInvalidMark, maxQuoteLen)
+		}
+		if in.SectionID != "" {
+			if _, err := primitive.ObjectIDFromHex(in.SectionID); err != nil {
+				return nil, fmt.Errorf("%w: bad section_id", ErrInvalidMark)
+			}
+		}
+		if in.Occurrence < 0 {
+			return nil, fmt.Errorf("%w: occurrence must not be negative", ErrInvalidMark)
+		}
+		m.Kind, m.SectionID, m.Quote, m.Occurrence = KindSpan, in.SectionID, quote, in.Occurrence
+	case TargetEvidence:
+		if in.Kind != "" && in.Kind != KindRegion {
+			return nil, fmt.Errorf("%w: evidence takes region marks", ErrInvalidMark)
+		}
+		if in.Page < 0 || in.X < 0 || in.Y < 0 || in.W <= 0 || in.H <= 0 || in.X+in.W > 1 || in.Y+in.H > 1 {
+			return nil, fmt.Errorf("%w: region must lie within the page, as fractions of its size", ErrInvalidMark)
+		}
+		m.Kind, m.Page, m.X, m.Y, m.W, m.H = KindRegion, in.Page, in.X, in.Y, in.W, in.H
+	}
+
+	if err := s.repo.CreateMark(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (s *service) ListMarks(ctx context.Context, actor Actor, targetType, targetID string, withdrawn bool) ([]Mark, error) {
+	if _, err := s.target(ctx, actor, targetType, targetID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListMarks(actor.TenantID, targetType, targetID, withdrawn)
+}
+
+func (s *service) WithdrawMark(ctx context.Context, actor Actor, markID string) (*Mark, error) {
+	if _, err := uuid.Parse(markID); err != nil {
+		return nil, ErrMarkNotFound
+	}
+	return s.repo.WithdrawMark(actor.TenantID, markID, actor.UserID)
+}
+
+// ─── Profiles ────────────────────────────────────────────────────────
+
+func (in ProfileInput) validate() error {
+	if name := strings.TrimSpace(in.Name); name == "" || len(name) > 120 {
+		return fmt.Errorf("%w: name must be 1-120 characters", ErrInvalidProfile)
+	}
+	if len(in.Reasons) == 0 {
+		return fmt.Errorf("%w: at least one reason is required", ErrInvalidProfile)
+	}
+	for _, r := range in.Reasons {
+		if !validReason(r) {
+			return fmt.Errorf("%w: unknown reason %q", ErrInvalidProfile, r)
+		}
+	}
+	return nil
+}
+
+func (in ProfileInput) apply(p *Profile) error {
+	reasons, err := json.Marshal(dedupe(in.Reasons))
+	if err != nil {
+		return err
+	}
+	roles, err := json.Marshal(dedupe(in.Roles))
+	if err != nil {
+		return err
+	}
+	p.Name = strings.TrimSpace(in.Name)
+	p.Audience = strings.TrimSpace(in.Audience)
+	p.Reasons = datatypes.JSON(reasons)
+	p.Roles = datatypes.JSON(roles)
+	return nil
+}
+
+func dedupe(in []string) []string {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, v := range in {
+		v = strings.TrimSpace(v)
+		if v != "" && !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (s *service) CreateProfile(actor Actor, in ProfileInput) (*Profile, error) {
+	if err := in.validate(); err != nil {
+		return nil, err
+	}
+	p := &Profile{TenantID: actor.TenantID, CreatedBy: actor.UserID}
+	if err := in.apply(p); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateProfile(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *service) UpdateProfile(actor Actor, profileID string, in ProfileInput) (*Profile, error) {
+	if _, err := uuid.Parse(profileID); err != nil {
+		return nil, ErrProfileNotFound
+	}
+	if err := in.validate(); err != nil {
+		return nil, err
+	}
+	p, err := s.repo.GetProfile(actor.TenantID, profileID)
+	if err != nil {
+		return nil, err
+	}
+	if err := in.apply(p); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateProfile(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *service) DeleteProfile(actor Actor, profileID string) error {
+	if _, err := uuid.Parse(profileID); err != nil {
+		return ErrProfileNotFound
+	}
+	return s.repo.DeleteProfile(actor.TenantID, profileID)
+}
+
+func (s *service) ListProfiles(actor Actor) ([]Profile, error) {
+	return s.repo.ListProfiles(actor.TenantID)
+}
+
+// ─── Applying redactions ─────────────────────────────────────────────
+
+func (s *service) PlanFor(ctx context.Context, actor Actor, targetType, targetID, profile string) (*Plan, error) {
+	if _, err := s.target(ctx, actor, targetType, targetID); err != nil {
+		return nil, err
+	}
+	profiles, err := s.repo.ListProfiles(actor.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	all := profile == AllMarks
+	requested := profile == "" || all
+	var ids []string
+	reasons := map[string]bool{}
+	for _, p := range profiles {
+		var roles, codes []string
+		if err := json.Unmarshal(p.Roles, &roles); err != nil {
+			return nil, fmt.Errorf("corrupt redaction profile %s: %w", p.ID, err)
+		}
+		if err := json.Unmarshal(p.Reasons, &codes); err != nil {
+			return nil, fmt.Errorf("corrupt redaction profile %s: %w", p.ID, err)
+		}
+		picked := p.ID == profile || strings.EqualFold(p.Name, profile)
+		requested = requested || picked
+		if !picked && !contains(roles, actor.Role) {
+			continue
+		}
+		ids = append(ids, p.ID)
+		for _, c := range codes {
+			reasons[c] = true
+		}
+	}
+	if !requested {
+		return nil, ErrProfileNotFound
+	}
+	if all {
+		ids = []string{AllMarks}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	kind := KindSpan
+	if targetType == TargetEvidence {
+		kind = KindRegion
+	}
+	marks, err := s.repo.ListMarks(actor.TenantID, targetType, targetID, false)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{ProfileIDs: ids}
+	for _, m := range marks {
+		if m.Kind == kind && (all || reasons[m.Reason]) {
+			plan.Marks = append(plan.Marks, m)
+		}
+	}
+	if len(plan.Marks) == 0 {
+		return nil, nil
+	}
+	return plan, nil
+}
+
+func contains(list []string, v string) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func hashes(data []byte) (string, string) {
+	s256 := sha256.Sum256(data)
+	s512 := sha512.Sum512(data)
+	return hex.EncodeToString(s256[:]), hex.EncodeToString(s512[:])
+}
+
+func (s *service) RecordReportExport(actor Actor, reportID string, plan *Plan, format string, data []byte) (*Derivative, error) {
+	marks, profiles, err := plan.fingerprint()
+	if err != nil {
+		return nil, err
+	}
+	s256, s512 := hashes(data)
+	d := &Derivative{
+		TenantID:   actor.TenantID,
+		SourceType: TargetReport,
+		SourceID:   reportID,
+		ProfileIDs: datatypes.JSON(profiles),
+		Marks:      datatypes.JSON(marks),
+		Format:     format,
+		SHA256:     s256,
+		SHA512:     s512,
+		Size:       int64(len(data)),
+		CreatedBy:  actor.UserID,
+	}
+	if err := s.repo.CreateDerivative(d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *service) ListDerivatives(ctx context.Context, actor Actor, sourceType, sourceID string) ([]Derivative, error) {
+	if _, err := s.target(ctx, actor, sourceType, sourceID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListDerivatives(actor.TenantID, sourceType, sourceID)
+}
+
+func (s *service) DeriveEvidence(ctx context.Context, actor Actor, evidenceID, profile string) (*Derivative, *metadata.Evidence, error) {
+	ev, err := s.evidenceInTenant(actor, evidenceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	plan, err := s.PlanFor(ctx, actor, TargetEvidence, evidenceID, profile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if plan == nil {
+		return nil, nil, ErrNothingToRedact
+	}
+	marks, profiles, err := plan.fingerprint()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	original, err := s.load(ev)
+	if err != nil {
+		return nil, nil, err
+	}
+	format, err := sniffFormat(original)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The same marks on the same file give the same copy; hand back the
+	// one already made rather than adding a duplicate to the case.
+	if d, err := s.repo.FindDerivative(actor.TenantID, TargetEvidence, evidenceID, ev.Checksum, format, marks); err != nil {
+		return nil, nil, err
+	} else if d != nil && d.DerivedEvidenceID != nil {
+		if derived, err := s.evidence.FindEvidenceByID(uuid.MustParse(*d.DerivedEvidenceID)); err == nil && derived != nil {
+			return d, derived, nil
+		}
+	}
+
+	now := s.now().UTC()
+	var redacted []byte
+	contentType := "image/png"
+	switch format {
+	case FormatPDF:
+		if s.rasterizer == nil {
+			return nil, nil, fmt.Errorf("%w: PDF rasteriser not configured", ErrUnsupportedEvidence)
+		}
+		redacted, err = burnPDF(ctx, s.rasterizer, original, plan.Marks, now)
+		contentType = "application/pdf"
+	case FormatJPEG:
+		redacted, err = burnImage(original, format, plan.Marks)
+		contentType = "image/jpeg"
+	default:
+		redacted, err = burnImage(original, format, plan.Marks)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s256, s512 := hashes(redacted)
+	cid, err := s.storage.UploadFile(bytes.NewReader(redacted))
+	if err != nil {
+		return nil, nil, fmt.Errorf("storing redacted copy: %w", err)
+	}
+
+	derivedID := uuid.New()
+	d := &Derivative{
+		ID:                uuid.NewString(),
+		TenantID:          actor.TenantID,
+		SourceType:        TargetEvidence,
+		SourceID:          evidenceID,
+		SourceSHA256:      ev.Checksum,
+		ProfileIDs:        datatypes.JSON(profiles),
+		Marks:             datatypes.JSON(marks),
+		Format:            format,
+		SHA256:            s256,
+		SHA512:            s512,
+		Size:              int64(len(redacted)),
+		DerivedEvidenceID: ptr(derivedID.String()),
+		CreatedBy:         actor.UserID,
+	}
+	meta, err := json.Marshal(map[string]interface{}{
+		"sha256":               s256,
+		"sha512":               s512,
+		"redacted_from":        ev.ID.String(),
+		"redacted_from_sha256": ev.Checksum,
+		"redaction_derivative": d.ID,
+		"redaction_reasons":    reasonCodes(plan.Marks),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	uploader, err := uuid.Parse(actor.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid user id: %w", err)
+	}
+	derived := &metadata.Evidence{
+		ID:         derivedID,
+		CaseID:     ev.CaseID,
+		UploadedBy: uploader,
+		TenantID:   ev.TenantID,
+		TeamID:     ev.TeamID,
+		Filename:   redactedName(ev.Filename, format),
+		FileType:   contentType,
+		IpfsCID:    cid,
+		FileSize:   int64(len(redacted)),
+		Checksum:   s256,
+		Metadata:   string(meta),
+	}
+	if err := s.evidence.SaveEvidence(derived); err != nil {
+		return nil, nil, err
+	}
+	if err := s.repo.CreateDerivative(d); err != nil {
+		return nil, nil, err
+	}
+	if err := s.link(ctx, actor, ev, original, derived, redacted, d, now); err != nil {
+		return nil, nil, err
+	}
+	return d, derived, nil
+}
+
+// load fetches an evidence file and checks it against its checksum, so a
+// redacted copy is never made from a file that is not the recorded one.
+func (s *service) load(ev *metadata.Evidence) ([]byte, error) {
+	rc, err := s.storage.Download(ev.IpfsCID)
+	if err != nil {
+		return nil, fmt.Errorf("fetching evidence: %w", err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, MaxEvidenceSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetching evidence: %w", err)
+	}
+	if len(data) > MaxEvidenceSize {
+		return nil, ErrFileTooLarge
+	}
+	if sum, _ := hashes(data); !strings.EqualFold(sum, ev.Checksum) {
+		return nil, ErrIntegrity
+	}
+	return data, nil
+}
+
+// link records the derivation in the evidence log and chain of custody of
+// both the original and the redacted copy.
+func (s *service) link(ctx context.Context, actor Actor, original *metadata.Evidence, originalData []byte,
+	derived *metadata.Evidence, derivedData []byte, d *Derivative, at time.Time) error {
+	info, err := json.Marshal(map[string]interface{}{
+		"method":              "redaction",
+		"derivative_id":       d.ID,
+		"source_evidence_id":  original.ID.String(),
+		"source_sha256":       original.Checksum,
+		"derived_evidence_id": derived.ID.String(),
+		"derived_sha256":      derived.Checksum,
+		"marks":               json.RawMessage(d.Marks),
+		"profiles":            json.RawMessage(d.ProfileIDs),
+	})
+	if err != nil {
+		return err
+	}
+
+	o256, o512 := hashes(originalData)
+	r256, r512 := hashes(derivedData)
+	logs := []*metadata.EvidenceLog{
+		{EvidenceID: original.ID, Sha256: o256, Sha512: o512, Action: "redacted_copy_derived", Result: true, Timestamp: at, Details: string(info)},
+		{EvidenceID: derived.ID, Sha256: r256, Sha512: r512, Action: "redacted_copy_created", Result: true, Timestamp: at, Details: string(info)},
+	}
+	for _, l := range logs {
+		if err := s.evidence.AppendLog(l); err != nil {
+			return fmt.Errorf("evidence log: %w", err)
+		}
+	}
+
+	custodian := actor.Email
+	if custodian == "" {
+		custodian = actor.UserID
+	}
+	for _, id := range []uuid.UUID{original.ID, derived.ID} {
+		if err := s.custody.AddEntry(ctx, &chain_of_custody.ChainOfCustody{
+			EvidenceID:      id,
+			Custodian:       custodian,
+			AcquisitionDate: &at,
+			AcquisitionTool: "AEGIS redaction",
+			SystemInfo:      datatypes.JSON("{}"),
+			ForensicInfo:    datatypes.JSON(info),
+		}); err != nil {
+			return fmt.Errorf("chain of custody: %w", err)
+		}
+	}
+	return nil
+}
+
+func reasonCodes(marks []Mark) []string {
+	codes := make([]string, 0, len(marks))
+	for _, m := range marks {
+		codes = append(codes, m.Reason)
+	}
+	return dedupe(codes)
+}
+
+// redactedName names a copy after its original, with the extension of
+// the format it was written in.
+func redactedName(name, format string) string {
+	ext := map[string]string{FormatPNG: ".png", FormatJPEG: ".jpg", FormatPDF: ".pdf"}[format]
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	if strings.EqualFold(filepath.Ext(name), ".jpeg") && format == FormatJPEG {
+		ext = ".jpeg"
+	}
+	return base + " (redacted)" + ext
+}
+
+func ptr(s string) *string { return &s }
@@ -0,0 +1,365 @@
+package docexport
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// The editor's HTML is reduced to the structure both word processor
+// formats can express: paragraphs and headings of formatted runs, nested
+// lists and tables. Images arrive separately on the Section.
+
+type run struct {
+	text                          string
+	bold, italic, underline, mono bool
+	link                          string
+	br                            bool // line break within the paragraph
+}
+
+type block interface{ isBlock() }
+
+type paragraph struct {
+	level int // 1-6 for headings, 0 for body text
+	runs  []run
+	align string // "left", "center", "right", "justify" or ""
+	mono  bool
+	quote bool
+}
+
+type list struct {
+	ordered bool
+	items   [][]block
+}
+
+type table struct {
+	rows [][]cell
+	// header is the number of leading header rows.
+	header int
+}
+
+type cell struct {
+	blocks []block
+	span   int
+	header bool
+}
+
+func (*paragraph) isBlock() {}
+func (*list) isBlock()      {}
+func (*table) isBlock()     {}
+
+var bodyParent = &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+
+func parseHTML(src string) []block {
+	nodes, err := html.ParseFragment(strings.NewReader(src), bodyParent)
+	if err != nil {
+		return []block{&paragraph{runs: []run{{text: src}}}}
+	}
+	p := &parser{}
+	for _, n := range nodes {
+		p.node(n, run{})
+	}
+	p.flush()
+	return p.blocks
+}
+
+type parser struct {
+	blocks []block
+	cur    paragraph
+}
+
+func (p *parser) flush() {
+	runs := trim(p.cur.runs)
+	if len(runs) > 0 {
+		para := p.cur
+		para.runs = runs
+		p.blocks = append(p.blocks, &para)
+	}
+	p.cur = paragraph{align: p.cur.align, mono: p.cur.mono, quote: p.cur.quote}
+}
+
+func (p *parser) add(b block) {
+	p.flush()
+	p.blocks = append(p.blocks, b)
+}
+
+func (p *parser) children(n *html.Node, st run) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		p.node(c, st)
+	}
+}
+
+func (p *parser) sub(n *html.Node, st run) []block {
+	s := &parser{cur: paragraph{align: p.cur.align, mono: p.cur.mono, quote: p.cur.quote}}
+	s.children(n, st)
+	s.flush()
+	return s.blocks
+}
+
+func (p *parser) node(n *html.Node, st run) {
+	switch n.Type {
+	case html.TextNode:
+		p.text(n.Data, st)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Head, atom.Title, atom.Iframe, atom.Object, atom.Embed, atom.Noscript, atom.Svg, atom.Img:
+		// Images are extracted from the section before parsing.
+	case atom.B, atom.Strong, atom.Th:
+		st.bold = true
+		p.children(n, st)
+	case atom.I, atom.Em, atom.Cite, atom.Var:
+		st.italic = true
+		p.children(n, st)
+	case atom.U, atom.Ins:
+		st.underline = true
+		p.children(n, st)
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		st.mono = true
+		p.children(n, st)
+	case atom.A:
+		if href := attr(n, "href"); strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://") || strings.HasPrefix(href, "mailto:") {
+			st.link = href
+		}
+		p.children(n, st)
+	case atom.Br:
+		p.cur.runs = append(p.cur.runs, run{br: true})
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		p.flush()
+		p.cur.level = int(n.Data[1] - '0')
+		saved := p.cur.align
+		p.cur.align = align(n, saved)
+		p.children(n, st)
+		p.flush()
+		p.cur.level, p.cur.align = 0, saved
+	case atom.Ul, atom.Ol:
+		l := &list{ordered: n.DataAtom == atom.Ol}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if c.DataAtom == atom.Li {
+				l.items = append(l.items, p.sub(c, st))
+				continue
+			}
+			s := &parser{}
+			s.node(c, st)
+			s.flush()
+			if len(s.blocks) > 0 {
+				l.items = append(l.items, s.blocks)
+			}
+		}
+		if len(l.items) > 0 {
+			p.add(l)
+		}
+	case atom.Li:
+		p.add(&list{items: [][]block{p.sub(n, st)}})
+	case atom.Table:
+		if t := p.table(n, st); len(t.rows) > 0 {
+			p.add(t)
+		}
+	case atom.Blockquote:
+		p.flush()
+		saved := p.cur.quote
+		p.cur.quote = true
+		p.children(n, st)
+		p.flush()
+		p.cur.quote = saved
+	case atom.Pre:
+		p.flush()
+		saved := p.cur.mono
+		p.cur.mono = true
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			p.preformatted(c, st)
+		}
+		p.flush()
+		p.cur.mono = saved
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer, atom.Main, atom.Aside,
+		atom.Figure, atom.Figcaption, atom.Address, atom.Center, atom.Dl, atom.Dt, atom.Dd, atom.Hr:
+		p.flush()
+		saved := p.cur.align
+		p.cur.align = align(n, saved)
+		p.children(n, st)
+		p.flush()
+		p.cur.align = saved
+	default:
+		p.children(n, st)
+	}
+}
+
+// preformatted keeps the whitespace of <pre> content, turning newlines
+// into line breaks.
+func (p *parser) preformatted(n *html.Node, st run) {
+	if n.Type == html.ElementNode {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			p.preformatted(c, st)
+		}
+		return
+	}
+	if n.Type != html.TextNode {
+		return
+	}
+	for i, line := range strings.Split(n.Data, "\n") {
+		if i > 0 {
+			p.cur.runs = append(p.cur.runs, run{br: true})
+		}
+		if line != "" {
+			r := st
+			r.text, r.mono = line, true
+			p.cur.runs = append(p.cur.runs, r)
+		}
+	}
+}
+
+func (p *parser) text(s string, st run) {
+	s = collapseSpace(s)
+	// Whitespace never doubles up across element boundaries.
+	if n := len(p.cur.runs); strings.HasPrefix(s, " ") && (n == 0 || p.cur.runs[n-1].br || strings.HasSuffix(p.cur.runs[n-1].text, " ")) {
+		s = s[1:]
+	}
+	if s == "" {
+		return
+	}
+	r := st
+	r.text, r.br = s, false
+	if n := len(p.cur.runs); n > 0 && sameStyle(p.cur.runs[n-1], r) {
+		p.cur.runs[n-1].text += s
+		return
+	}
+	p.cur.runs = append(p.cur.runs, r)
+}
+
+// collapseSpace folds runs of HTML whitespace into single spaces.
+func collapseSpace(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+func sameStyle(a, b run) bool {
+	return !a.br && !b.br && a.bold == b.bold && a.italic == b.italic && a.underline == b.underline && a.mono == b.mono && a.link == b.link
+}
+
+func (p *parser) table(n *html.Node, st run) *table {
+	t := &table{}
+	var walk func(n *html.Node, inHead bool)
+	walk = func(n *html.Node, inHead bool) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.DataAtom {
+			case atom.Thead:
+				walk(c, true)
+			case atom.Tbody, atom.Tfoot:
+				walk(c, false)
+			case atom.Tr:
+				var row []cell
+				allTH := true
+				for td := c.FirstChild; td != nil; td = td.NextSibling {
+					if td.Type != html.ElementNode || (td.DataAtom != atom.Td && td.DataAtom != atom.Th) {
+						continue
+					}
+					span, _ := strconv.Atoi(attr(td, "colspan"))
+					th := td.DataAtom == atom.Th
+					allTH = allTH && th
+					cst := st
+					cst.bold = cst.bold || th
+					row = append(row, cell{blocks: p.sub(td, cst), span: min(max(span, 1), 50), header: th})
+				}
+				if len(row) == 0 {
+					continue
+				}
+				if (inHead || allTH) && t.header == len(t.rows) {
+					t.header++
+				}
+				t.rows = append(t.rows, row)
+			case atom.Caption:
+			default:
+				walk(c, inHead)
+			}
+		}
+	}
+	walk(n, false)
+	return t
+}
+
+// columns is the width of the table in grid columns.
+func (t *table) columns() int {
+	cols := 0
+	for _, row := range t.rows {
+		n := 0
+		for _, c := range row {
+			n += c.span
+		}
+		cols = max(cols, n)
+	}
+	return cols
+}
+
+// trim drops whitespace and line breaks at the start and end of a
+// paragraph.
+func trim(runs []run) []run {
+	blank := func(r run) bool { return r.br || strings.TrimSpace(r.text) == "" }
+	for len(runs) > 0 && blank(runs[0]) {
+		runs = runs[1:]
+	}
+	for len(runs) > 0 && blank(runs[len(runs)-1]) {
+		runs = runs[:len(runs)-1]
+	}
+	if len(runs) == 0 {
+		return nil
+	}
+	out := make([]run, len(runs))
+	copy(out, runs)
+	out[0].text = strings.TrimLeft(out[0].text, " ")
+	out[len(out)-1].text = strings.TrimRight(out[len(out)-1].text, " ")
+	return out
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// align reads text-align from the style attribute or the legacy align
+// attribute, falling back to inherited.
+func align(n *html.Node, inherited string) string {
+	if n.DataAtom == atom.Center {
+		return "center"
+	}
+	v := strings.ToLower(attr(n, "align"))
+	for _, decl := range strings.Split(attr(n, "style"), ";") {
+		if k, val, ok := strings.Cut(decl, ":"); ok && strings.TrimSpace(strings.ToLower(k)) == "text-align" {
+			v = strings.TrimSpace(strings.ToLower(val))
+		}
+	}
+	switch v {
+	case "left", "center", "right", "justify":
+		return v
+	}
+	return inherited
+}
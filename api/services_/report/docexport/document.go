@@ -0,0 +1,164 @@
+// Package docexport writes reports as editable word processor documents:
+// Office Open XML (.docx) and OpenDocument Text (.odt).
+//
+// Section HTML is reduced to headings, paragraphs with bold, italic,
+// underline, monospace and links, nested lists and tables; images are
+// placed after the text of their section. Output is deterministic: the
+// archive entries carry the document's modification time.
+package docexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"hash/crc32"
+	stdimage "image"
+	_ "image/jpeg" // registers decoders for DecodeConfig
+	_ "image/png"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Document is everything written to an exported report.
+type Document struct {
+	Title       string
+	Subject     string
+	Author      string
+	Description string
+	Keywords    []string
+	Created     time.Time
+	Modified    time.Time
+	// Revision is the report version.
+	Revision int
+	// Properties are stored as custom document properties, in order.
+	Properties [][2]string
+
+	// Watermark, when set, marks every page: diagonally across the page
+	// in DOCX and in the page header in ODT.
+	Watermark string
+
+	Sections []Section
+}
+
+// Section is a titled block of editor HTML and the images taken out of it.
+type Section struct {
+	Title  string
+	HTML   string
+	Images []Image
+}
+
+// Image is an embedded PNG or JPEG.
+type Image struct {
+	Data        []byte
+	ContentType string // image/png or image/jpeg
+}
+
+// maxImageWidthCM keeps images inside the text column of an A4 page.
+const maxImageWidthCM = 16.0
+
+// picture is an image ready to be placed, with its size in centimetres.
+type picture struct {
+	name          string // file name inside the archive
+	data          []byte
+	contentType   string
+	width, height float64
+}
+
+// pictures validates a section's images and sizes them at 96 dpi, scaled
+// down to the text width. Images that cannot be decoded are skipped.
+func pictures(images []Image, next *int) []picture {
+	var out []picture
+	for _, img := range images {
+		ext := map[string]string{"image/png": "png", "image/jpeg": "jpeg", "image/jpg": "jpeg"}[img.ContentType]
+		if ext == "" {
+			continue
+		}
+		cfg, _, err := stdimage.DecodeConfig(bytes.NewReader(img.Data))
+		if err != nil || cfg.Width == 0 || cfg.Height == 0 {
+			continue
+		}
+		*next++
+		w := float64(cfg.Width) * 2.54 / 96
+		h := float64(cfg.Height) * 2.54 / 96
+		if w > maxImageWidthCM {
+			w, h = maxImageWidthCM, h*maxImageWidthCM/w
+		}
+		out = append(out, picture{
+			name:        "image" + strconv.Itoa(*next) + "." + ext,
+			data:        img.Data,
+			contentType: "image/" + ext,
+			width:       w,
+			height:      h,
+		})
+	}
+	return out
+}
+
+// archive writes zip entries with a fixed modification time so the same
+// document always produces the same bytes.
+type archive struct {
+	zw  *zip.Writer
+	mod time.Time
+	err error
+}
+
+func newArchive(w io.Writer, mod time.Time) *archive {
+	if mod.IsZero() || mod.Year() < 1980 {
+		mod = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return &archive{zw: zip.NewWriter(w), mod: mod.UTC()}
+}
+
+func (a *archive) file(name string, data []byte) {
+	if a.err != nil {
+		return
+	}
+	w, err := a.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: a.mod})
+	if err == nil {
+		_, err = w.Write(data)
+	}
+	a.err = err
+}
+
+// stored writes an uncompressed entry without a data descriptor, as ODF
+// requires for the mimetype entry.
+func (a *archive) stored(name string, data []byte) {
+	if a.err != nil {
+		return
+	}
+	w, err := a.zw.CreateRaw(&zip.FileHeader{
+		Name:               name,
+		Method:             zip.Store,
+		Modified:           a.mod,
+		CRC32:              crc32.ChecksumIEEE(data),
+		CompressedSize64:   uint64(len(data)),
+		UncompressedSize64: uint64(len(data)),
+	})
+	if err == nil {
+		_, err = w.Write(data)
+	}
+	a.err = err
+}
+
+func (a *archive) close() error {
+	if a.err != nil {
+		return a.err
+	}
+	return a.zw.Close()
+}
+
+// esc escapes text for XML character data and attribute values.
+func esc(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func w3cdtf(t time.Time) string {
+	if t.IsZero() {
+		t = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
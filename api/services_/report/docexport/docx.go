@@ -0,0 +1,475 @@
+package docexport
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	nsW   = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+	nsR   = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	relNS = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/"
+
+	emuPerCM   = 360000
+	twipsPerCM = 567
+	// textTwips is the text width of an A4 page with 2.5cm margins.
+	textTwips = 9026
+)
+
+// WriteDOCX writes doc as an Office Open XML word processing document.
+func WriteDOCX(w io.Writer, doc Document) error {
+	d := &docx{}
+	body := d.body(doc)
+
+	a := newArchive(w, doc.Modified)
+	a.file("[Content_Types].xml", []byte(d.contentTypes(doc)))
+	a.file("_rels/.rels", []byte(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+
+		`<Relationship Id="rId1" Type="`+relNS+`officeDocument" Target="word/document.xml"/>`+
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/>`+
+		`<Relationship Id="rId3" Type="`+relNS+`extended-properties" Target="docProps/app.xml"/>`+
+		`<Relationship Id="rId4" Type="`+relNS+`custom-properties" Target="docProps/custom.xml"/>`+
+		`</Relationships>`))
+	a.file("docProps/core.xml", []byte(docxCore(doc)))
+	a.file("docProps/app.xml", []byte(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties"><Application>AEGIS</Application></Properties>`))
+	a.file("docProps/custom.xml", []byte(docxCustom(doc)))
+	a.file("word/document.xml", []byte(body))
+	a.file("word/styles.xml", []byte(docxStyles))
+	a.file("word/numbering.xml", []byte(d.numbering()))
+	a.file("word/footer1.xml", []byte(docxFooter))
+	if doc.Watermark != "" {
+		a.file("word/header1.xml", []byte(docxWatermark(doc.Watermark)))
+	}
+	a.file("word/_rels/document.xml.rels", []byte(d.relationships()))
+	for _, p := range d.pics {
+		a.file("word/media/"+p.name, p.data)
+	}
+	return a.close()
+}
+
+type docxRel struct {
+	id, typ, target string
+	external        bool
+}
+
+type docx struct {
+	b       strings.Builder
+	rels    []docxRel
+	links   map[string]string // href -> relationship ID
+	ordered []bool            // per numbering instance, numId = index+1
+	pics    []picture
+	nextPic int
+}
+
+func (d *docx) rel(typ, target string, external bool) string {
+	id := "rId" + strconv.Itoa(len(d.rels)+1)
+	d.rels = append(d.rels, docxRel{id: id, typ: typ, target: target, external: external})
+	return id
+}
+
+func (d *docx) body(doc Document) string {
+	d.links = map[string]string{}
+	d.rel(relNS+"styles", "styles.xml", false)
+	d.rel(relNS+"numbering", "numbering.xml", false)
+	footer := d.rel(relNS+"footer", "footer1.xml", false)
+	header := ""
+	if doc.Watermark != "" {
+		header = d.rel(relNS+"header", "header1.xml", false)
+	}
+
+	d.b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="` + nsW + `" xmlns:r="` + nsR + `"` +
+		` xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"` +
+		` xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"` +
+		` xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture"><w:body>`)
+	d.para(&paragraph{runs: []run{{text: doc.Title}}}, "Title", nil)
+	if doc.Subject != "" {
+		d.para(&paragraph{runs: []run{{text: doc.Subject}}}, "Subtitle", nil)
+	}
+	for _, sec := range doc.Sections {
+		// Heading 1 starts a new page, so every section does.
+		d.para(&paragraph{runs: []run{{text: sec.Title}}}, "Heading1", nil)
+		blocks := parseHTML(sec.HTML)
+		if len(blocks) == 0 && len(sec.Images) == 0 {
+			blocks = []block{&paragraph{runs: []run{{text: "(No content provided)", italic: true}}}}
+		}
+		d.blocks(blocks, 0)
+		for _, p := range pictures(sec.Images, &d.nextPic) {
+			d.picture(p)
+		}
+	}
+
+	d.b.WriteString(`<w:sectPr>`)
+	if header != "" {
+		d.b.WriteString(`<w:headerReference w:type="default" r:id="` + header + `"/>`)
+	}
+	d.b.WriteString(`<w:footerReference w:type="default" r:id="` + footer + `"/>` +
+		`<w:pgSz w:w="11906" w:h="16838"/>` +
+		`<w:pgMar w:top="1417" w:right="1440" w:bottom="1417" w:left="1440" w:header="708" w:footer="708" w:gutter="0"/>` +
+		`</w:sectPr></w:body></w:document>`)
+	return d.b.String()
+}
+
+func (d *docx) blocks(blocks []block, depth int) {
+	for _, b := range blocks {
+		switch b := b.(type) {
+		case *paragraph:
+			style := ""
+			switch {
+			case b.level > 0:
+				// Section titles are Heading 1, so content headings sit below.
+				style = "Heading" + strconv.Itoa(min(b.level+1, 9))
+			case b.quote:
+				style = "Quote"
+			case b.mono:
+				style = "SourceCode"
+			}
+			d.para(b, style, nil)
+		case *list:
+			d.list(b, depth)
+		case *table:
+			d.table(b)
+		}
+	}
+}
+
+// listItem is the numbering of the first paragraph of a list item.
+type listItem struct {
+	numID, level int
+}
+
+func (d *docx) list(l *list, depth int) {
+	d.ordered = append(d.ordered, l.ordered)
+	numID := len(d.ordered)
+	level := min(depth, 8)
+	for _, item := range l.items {
+		first := true
+		for _, b := range item {
+			switch b := b.(type) {
+			case *paragraph:
+				if first {
+					d.para(b, "ListParagraph", &listItem{numID: numID, level: level})
+				} else {
+					d.para(b, "ListParagraph", &listItem{level: level})
+				}
+			case *list:
+				if first {
+					d.para(&paragraph{}, "ListParagraph", &listItem{numID: numID, level: level})
+				}
+				d.list(b, depth+1)
+			case *table:
+				if first {
+					d.para(&paragraph{}, "ListParagraph", &listItem{numID: numID, level: level})
+				}
+				d.table(b)
+			}
+			first = false
+		}
+		if len(item) == 0 {
+			d.para(&paragraph{}, "ListParagraph", &listItem{numID: numID, level: level})
+		}
+	}
+}
+
+func (d *docx) para(p *paragraph, style string, item *listItem) {
+	var props strings.Builder
+	if style != "" {
+		props.WriteString(`<w:pStyle w:val="` + style + `"/>`)
+	}
+	if item != nil {
+		if item.numID > 0 {
+			fmt.Fprintf(&props, `<w:numPr><w:ilvl w:val="%d"/><w:numId w:val="%d"/></w:numPr>`, item.level, item.numID)
+		} else {
+			fmt.Fprintf(&props, `<w:ind w:left="%d"/>`, 720+360*item.level)
+		}
+	}
+	if jc := map[string]string{"left": "left", "center": "center", "right": "right", "justify": "both"}[p.align]; jc != "" {
+		props.WriteString(`<w:jc w:val="` + jc + `"/>`)
+	}
+	b := &d.b
+	b.WriteString("<w:p>")
+	if props.Len() > 0 {
+		b.WriteString("<w:pPr>" + props.String() + "</w:pPr>")
+	}
+	for _, r := range p.runs {
+		if r.link != "" {
+			id, ok := d.links[r.link]
+			if !ok {
+				id = d.rel(relNS+"hyperlink", r.link, true)
+				d.links[r.link] = id
+			}
+			b.WriteString(`<w:hyperlink r:id="` + id + `">`)
+			d.run(r, "Hyperlink")
+			b.WriteString(`</w:hyperlink>`)
+			continue
+		}
+		d.run(r, "")
+	}
+	b.WriteString("</w:p>")
+}
+
+func (d *docx) run(r run, charStyle string) {
+	b := &d.b
+	b.WriteString("<w:r>")
+	if r.bold || r.italic || r.underline || r.mono || charStyle != "" {
+		b.WriteString("<w:rPr>")
+		if charStyle != "" {
+			b.WriteString(`<w:rStyle w:val="` + charStyle + `"/>`)
+		}
+		if r.mono {
+			b.WriteString(`<w:rFonts w:ascii="Courier New" w:hAnsi="Courier New" w:cs="Courier New"/>`)
+		}
+		if r.bold {
+			b.WriteString("<w:b/>")
+		}
+		if r.italic {
+			b.WriteString("<w:i/>")
+		}
+		if r.underline {
+			b.WriteString(`<w:u w:val="single"/>`)
+		}
+		b.WriteString("</w:rPr>")
+	}
+	if r.br {
+		b.WriteString("<w:br/>")
+	} else {
+		b.WriteString(`<w:t xml:space="preserve">` + esc(r.text) + `</w:t>`)
+	}
+	b.WriteString("</w:r>")
+}
+
+func (d *docx) table(t *table) {
+	b := &d.b
+	cols := t.columns()
+	colW := textTwips / cols
+	b.WriteString(`<w:tbl><w:tblPr><w:tblStyle w:val="TableGrid"/><w:tblW w:w="5000" w:type="pct"/></w:tblPr><w:tblGrid>`)
+	for i := 0; i < cols; i++ {
+		fmt.Fprintf(b, `<w:gridCol w:w="%d"/>`, colW)
+	}
+	b.WriteString("</w:tblGrid>")
+	for r, row := range t.rows {
+		b.WriteString("<w:tr>")
+		if r < t.header {
+			b.WriteString("<w:trPr><w:tblHeader/></w:trPr>")
+		}
+		used := 0
+		for _, c := range row {
+			span := min(c.span, cols-used)
+			if span <= 0 {
+				break
+			}
+			d.cell(c.blocks, span, colW, c.header || r < t.header)
+			used += span
+		}
+		if used < cols {
+			// Short rows are padded so the grid stays rectangular.
+			d.cell(nil, cols-used, colW, false)
+		}
+		b.WriteString("</w:tr>")
+	}
+	b.WriteString("</w:tbl>")
+	// Word needs a paragraph between adjacent tables.
+	b.WriteString("<w:p/>")
+}
+
+func (d *docx) cell(blocks []block, span, colW int, header bool) {
+	b := &d.b
+	fmt.Fprintf(b, `<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/>`, colW*span)
+	if span > 1 {
+		fmt.Fprintf(b, `<w:gridSpan w:val="%d"/>`, span)
+	}
+	if header {
+		b.WriteString(`<w:shd w:val="clear" w:color="auto" w:fill="E6E9EE"/>`)
+	}
+	b.WriteString("</w:tcPr>")
+	d.blocks(blocks, 0)
+	if len(blocks) == 0 {
+		// A cell must hold a paragraph; nested tables are followed by one.
+		b.WriteString("<w:p/>")
+	}
+	b.WriteString("</w:tc>")
+}
+
+func (d *docx) picture(p picture) {
+	d.pics = append(d.pics, p)
+	id := d.rel(relNS+"image", "media/"+p.name, false)
+	n := len(d.pics)
+	cx, cy := int(p.width*emuPerCM), int(p.height*emuPerCM)
+	fmt.Fprintf(&d.b, `<w:p><w:pPr><w:jc w:val="center"/></w:pPr><w:r><w:drawing>`+
+		`<wp:inline distT="0" distB="0" distL="0" distR="0"><wp:extent cx="%d" cy="%d"/>`+
+		`<wp:docPr id="%d" name="Picture %d"/>`+
+		`<a:graphic><a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture">`+
+		`<pic:pic><pic:nvPicPr><pic:cNvPr id="%d" name="%s"/><pic:cNvPicPr/></pic:nvPicPr>`+
+		`<pic:blipFill><a:blip r:embed="%s"/><a:stretch><a:fillRect/></a:stretch></pic:blipFill>`+
+		`<pic:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></pic:spPr>`+
+		`</pic:pic></a:graphicData></a:graphic></wp:inline></w:drawing></w:r></w:p>`,
+		cx, cy, n, n, n, p.name, id, cx, cy)
+}
+
+func (d *docx) numbering() string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:numbering xmlns:w="` + nsW + `">`)
+	bullets := []string{"•", "◦", "▪"}
+	for abs, ordered := range []bool{false, true} {
+		fmt.Fprintf(&b, `<w:abstractNum w:abstractNumId="%d"><w:multiLevelType w:val="hybridMultilevel"/>`, abs)
+		for lvl := 0; lvl < 9; lvl++ {
+			fmt.Fprintf(&b, `<w:lvl w:ilvl="%d"><w:start w:val="1"/>`, lvl)
+			if ordered {
+				fmt.Fprintf(&b, `<w:numFmt w:val="decimal"/><w:lvlText w:val="%%%d."/>`, lvl+1)
+			} else {
+				fmt.Fprintf(&b, `<w:numFmt w:val="bullet"/><w:lvlText w:val="%s"/>`, bullets[lvl%len(bullets)])
+			}
+			fmt.Fprintf(&b, `<w:lvlJc w:val="left"/><w:pPr><w:ind w:left="%d" w:hanging="360"/></w:pPr></w:lvl>`, 720+360*lvl)
+		}
+		b.WriteString("</w:abstractNum>")
+	}
+	// Every list gets its own instance so numbered lists restart at 1.
+	for i, ordered := range d.ordered {
+		abs := 0
+		if ordered {
+			abs = 1
+		}
+		fmt.Fprintf(&b, `<w:num w:numId="%d"><w:abstractNumId w:val="%d"/>`, i+1, abs)
+		if ordered {
+			for lvl := 0; lvl < 9; lvl++ {
+				fmt.Fprintf(&b, `<w:lvlOverride w:ilvl="%d"><w:startOverride w:val="1"/></w:lvlOverride>`, lvl)
+			}
+		}
+		b.WriteString("</w:num>")
+	}
+	b.WriteString("</w:numbering>")
+	return b.String()
+}
+
+func (d *docx) relationships() string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for _, r := range d.rels {
+		mode := ""
+		if r.external {
+			mode = ` TargetMode="External"`
+		}
+		b.WriteString(`<Relationship Id="` + r.id + `" Type="` + r.typ + `" Target="` + esc(r.target) + `"` + mode + `/>`)
+	}
+	b.WriteString("</Relationships>")
+	return b.String()
+}
+
+func (d *docx) contentTypes(doc Document) string {
+	const main = "application/vnd.openxmlformats-officedocument.wordprocessingml."
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Default Extension="png" ContentType="image/png"/>` +
+		`<Default Extension="jpeg" ContentType="image/jpeg"/>` +
+		`<Override PartName="/word/document.xml" ContentType="` + main + `document.main+xml"/>` +
+		`<Override PartName="/word/styles.xml" ContentType="` + main + `styles+xml"/>` +
+		`<Override PartName="/word/numbering.xml" ContentType="` + main + `numbering+xml"/>` +
+		`<Override PartName="/word/footer1.xml" ContentType="` + main + `footer+xml"/>`)
+	if doc.Watermark != "" {
+		b.WriteString(`<Override PartName="/word/header1.xml" ContentType="` + main + `header+xml"/>`)
+	}
+	b.WriteString(`<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>` +
+		`<Override PartName="/docProps/app.xml" ContentType="application/vnd.openxmlformats-officedocument.extended-properties+xml"/>` +
+		`<Override PartName="/docProps/custom.xml" ContentType="application/vnd.openxmlformats-officedocument.custom-properties+xml"/>` +
+		`</Types>`)
+	return b.String()
+}
+
+func docxCore(doc Document) string {
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties"` +
+		` xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/"` +
+		` xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<dc:title>` + esc(doc.Title) + `</dc:title>` +
+		`<dc:subject>` + esc(doc.Subject) + `</dc:subject>` +
+		`<dc:creator>` + esc(doc.Author) + `</dc:creator>` +
+		`<cp:keywords>` + esc(strings.Join(doc.Keywords, ", ")) + `</cp:keywords>` +
+		`<dc:description>` + esc(doc.Description) + `</dc:description>` +
+		`<cp:revision>` + strconv.Itoa(doc.Revision) + `</cp:revision>` +
+		`<dcterms:created xsi:type="dcterms:W3CDTF">` + w3cdtf(doc.Created) + `</dcterms:created>` +
+		`<dcterms:modified xsi:type="dcterms:W3CDTF">` + w3cdtf(doc.Modified) + `</dcterms:modified>` +
+		`</cp:coreProperties>`
+}
+
+func docxCustom(doc Document) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/custom-properties"` +
+		` xmlns:vt="http://schemas.openxmlformats.org/officeDocument/2006/docPropsVTypes">`)
+	for i, kv := range doc.Properties {
+		// pid values start at 2; the fmtid is fixed for user-defined properties.
+		fmt.Fprintf(&b, `<property fmtid="{D5CDD505-2E9C-101B-9397-08002B2CF9AE}" pid="%d" name="%s"><vt:lpwstr>%s</vt:lpwstr></property>`,
+			i+2, esc(kv[0]), esc(kv[1]))
+	}
+	b.WriteString("</Properties>")
+	return b.String()
+}
+
+// docxWatermark is a header holding the classic Word text watermark: a
+// semi-transparent WordArt shape behind the text of every page.
+func docxWatermark(text string) string {
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:hdr xmlns:w="` + nsW + `" xmlns:r="` + nsR + `" xmlns:v="urn:schemas-microsoft-com:vml" xmlns:o="urn:schemas-microsoft-com:office:office">` +
+		`<w:p><w:pPr><w:pStyle w:val="Header"/></w:pPr><w:r><w:pict>` +
+		`<v:shapetype id="_x0000_t136" coordsize="21600,21600" o:spt="136" adj="10800" path="m@7,l@8,m@5,21600l@6,21600e">` +
+		`<v:path textpathok="t" o:connecttype="custom"/><v:textpath on="t" fitshape="t"/></v:shapetype>` +
+		`<v:shape id="AegisWatermark" o:spid="_x0000_s1025" type="#_x0000_t136"` +
+		` style="position:absolute;margin-left:0;margin-top:0;width:468pt;height:78pt;rotation:315;z-index:-251657216;` +
+		`mso-position-horizontal:center;mso-position-horizontal-relative:margin;mso-position-vertical:center;mso-position-vertical-relative:margin"` +
+		` o:allowincell="f" fillcolor="#c0c0c0" stroked="f">` +
+		`<v:fill opacity=".5"/><v:textpath style="font-family:&quot;Calibri&quot;;font-size:1pt" string="` + esc(text) + `"/>` +
+		`</v:shape></w:pict></w:r></w:p></w:hdr>`
+}
+
+// docxFooter prints "Page X of Y" using PAGE and NUMPAGES fields.
+const docxFooter = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:ftr xmlns:w="` + nsW + `"><w:p><w:pPr><w:pStyle w:val="Footer"/><w:jc w:val="right"/></w:pPr>` +
+	`<w:r><w:t xml:space="preserve">Page </w:t></w:r><w:fldSimple w:instr=" PAGE "><w:r><w:t>1</w:t></w:r></w:fldSimple>` +
+	`<w:r><w:t xml:space="preserve"> of </w:t></w:r><w:fldSimple w:instr=" NUMPAGES "><w:r><w:t>1</w:t></w:r></w:fldSimple>` +
+	`</w:p></w:ftr>`
+
+var docxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles xmlns:w="` + nsW + `">` +
+	`<w:docDefaults><w:rPrDefault><w:rPr><w:rFonts w:ascii="Calibri" w:hAnsi="Calibri" w:cs="Calibri"/><w:sz w:val="22"/><w:lang w:val="en-GB"/></w:rPr></w:rPrDefault>` +
+	`<w:pPrDefault><w:pPr><w:spacing w:after="120" w:line="264" w:lineRule="auto"/></w:pPr></w:pPrDefault></w:docDefaults>` +
+	`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/><w:qFormat/></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:spacing w:after="240"/></w:pPr><w:rPr><w:b/><w:sz w:val="48"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Subtitle"><w:name w:val="Subtitle"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:spacing w:after="360"/></w:pPr><w:rPr><w:color w:val="595959"/><w:sz w:val="28"/></w:rPr></w:style>` +
+	docxHeading(1, 32, true) + docxHeading(2, 28, false) + docxHeading(3, 24, false) + docxHeading(4, 22, false) +
+	docxHeading(5, 22, false) + docxHeading(6, 22, false) + docxHeading(7, 22, false) +
+	`<w:style w:type="paragraph" w:styleId="ListParagraph"><w:name w:val="List Paragraph"/><w:basedOn w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:spacing w:after="60"/><w:ind w:left="720"/><w:contextualSpacing/></w:pPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Quote"><w:name w:val="Quote"/><w:basedOn w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:ind w:left="567" w:right="567"/></w:pPr><w:rPr><w:i/><w:color w:val="404040"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="SourceCode"><w:name w:val="Source Code"/><w:basedOn w:val="Normal"/>` +
+	`<w:pPr><w:spacing w:after="0" w:line="240" w:lineRule="auto"/></w:pPr><w:rPr><w:rFonts w:ascii="Courier New" w:hAnsi="Courier New" w:cs="Courier New"/><w:sz w:val="18"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Header"><w:name w:val="header"/><w:basedOn w:val="Normal"/></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Footer"><w:name w:val="footer"/><w:basedOn w:val="Normal"/><w:rPr><w:sz w:val="16"/></w:rPr></w:style>` +
+	`<w:style w:type="character" w:styleId="Hyperlink"><w:name w:val="Hyperlink"/><w:rPr><w:color w:val="1F4EA0"/><w:u w:val="single"/></w:rPr></w:style>` +
+	`<w:style w:type="table" w:styleId="TableGrid"><w:name w:val="Table Grid"/><w:tblPr><w:tblBorders>` +
+	`<w:top w:val="single" w:sz="4" w:space="0" w:color="969696"/><w:left w:val="single" w:sz="4" w:space="0" w:color="969696"/>` +
+	`<w:bottom w:val="single" w:sz="4" w:space="0" w:color="969696"/><w:right w:val="single" w:sz="4" w:space="0" w:color="969696"/>` +
+	`<w:insideH w:val="single" w:sz="4" w:space="0" w:color="969696"/><w:insideV w:val="single" w:sz="4" w:space="0" w:color="969696"/>` +
+	`</w:tblBorders><w:tblCellMar><w:left w:w="85" w:type="dxa"/><w:right w:w="85" w:type="dxa"/></w:tblCellMar></w:tblPr>` +
+	`<w:pPr><w:spacing w:after="0"/></w:pPr><w:rPr><w:sz w:val="18"/></w:rPr></w:style>` +
+	`</w:styles>`
+
+func docxHeading(level, halfPoints int, pageBreak bool) string {
+	brk := ""
+	if pageBreak {
+		brk = "<w:pageBreakBefore/>"
+	}
+	return fmt.Sprintf(`<w:style w:type="paragraph" w:styleId="Heading%d"><w:name w:val="heading %d"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>`+
+		`<w:pPr><w:keepNext/>%s<w:spacing w:before="240" w:after="120"/><w:outlineLvl w:val="%d"/></w:pPr><w:rPr><w:b/><w:sz w:val="%d"/></w:rPr></w:style>`,
+		level, level, brk, level-1, halfPoints)
+}
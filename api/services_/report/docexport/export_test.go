@@ -0,0 +1,144 @@
+package docexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sampleDoc(t *testing.T) Document {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 1200, 300))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+
+	return Document{
+		Title:      "Incident report",
+		Subject:    "Case: Ransomware & co",
+		Author:     "A. Examiner",
+		Keywords:   []string{"forensic report", "RPT-1"},
+		Created:    time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC),
+		Modified:   time.Date(2025, 3, 2, 10, 30, 0, 0, time.UTC),
+		Revision:   3,
+		Properties: [][2]string{{"Report number", "RPT-1"}, {"Status", "published"}},
+		Watermark:  "Editable copy - not the signed record",
+		Sections: []Section{
+			{
+				Title: "1. Summary",
+				HTML: `<h2>Scope</h2><p style="text-align:justify">Seized <b>two</b> <i>laptops</i> <a href="https://example.test/x?a=1&b=2">ref</a>.<br>Second line</p>` +
+					`<ol><li>Imaged<ul><li>nested</li></ul></li><li>Hashed</li></ol><pre>a  b
+	c</pre>`,
+				Images: []Image{{Data: buf.Bytes(), ContentType: "image/png"}, {Data: []byte("junk"), ContentType: "image/png"}},
+			},
+			{
+				Title: "2. Evidence",
+				HTML:  `<table><thead><tr><th colspan="2">Item</th></tr></thead><tbody><tr><td>disk.img</td><td>ab12</td></tr><tr><td>only one</td></tr></tbody></table>`,
+			},
+			{Title: "3. Empty", HTML: "<p><br></p>"},
+		},
+	}
+}
+
+// entries unzips an archive, checking every XML part is well formed.
+func entries(t *testing.T, data []byte) ([]*zip.File, map[string]string) {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		b, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		parts[f.Name] = string(b)
+		if strings.HasSuffix(f.Name, ".xml") || strings.HasSuffix(f.Name, ".rels") {
+			dec := xml.NewDecoder(bytes.NewReader(b))
+			for {
+				_, err := dec.Token()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err, f.Name)
+			}
+		}
+	}
+	return zr.File, parts
+}
+
+func TestWriteDOCX(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteDOCX(&buf, sampleDoc(t)))
+	_, parts := entries(t, buf.Bytes())
+
+	body := parts["word/document.xml"]
+	require.Contains(t, body, `<w:pStyle w:val="Heading1"/>`)
+	require.Contains(t, body, `<w:pStyle w:val="Heading3"/>`, "content headings sit below section titles")
+	require.Contains(t, body, `<w:jc w:val="both"/>`)
+	require.Contains(t, body, `<w:gridSpan w:val="2"/>`)
+	require.Contains(t, body, `<w:tblHeader/>`)
+	require.Contains(t, body, `<w:ilvl w:val="1"/>`, "nested list item")
+	require.Contains(t, body, "(No content provided)")
+	require.Equal(t, 1, strings.Count(body, "<w:drawing>"), "undecodable images are skipped")
+	require.Contains(t, parts, "word/media/image1.png")
+	require.Contains(t, parts["word/_rels/document.xml.rels"], `Target="https://example.test/x?a=1&amp;b=2" TargetMode="External"`)
+	require.Contains(t, parts["word/header1.xml"], `string="Editable copy - not the signed record"`)
+	require.Contains(t, parts["word/numbering.xml"], `<w:startOverride w:val="1"/>`)
+
+	require.Contains(t, parts["docProps/core.xml"], "<dc:title>Incident report</dc:title>")
+	require.Contains(t, parts["docProps/core.xml"], "<dc:subject>Case: Ransomware &amp; co</dc:subject>")
+	require.Contains(t, parts["docProps/core.xml"], `<dcterms:modified xsi:type="dcterms:W3CDTF">2025-03-02T10:30:00Z</dcterms:modified>`)
+	require.Contains(t, parts["docProps/custom.xml"], `name="Report number"><vt:lpwstr>RPT-1</vt:lpwstr>`)
+}
+
+func TestWriteDOCXWithoutWatermark(t *testing.T) {
+	doc := sampleDoc(t)
+	doc.Watermark = ""
+	var buf bytes.Buffer
+	require.NoError(t, WriteDOCX(&buf, doc))
+	_, parts := entries(t, buf.Bytes())
+	require.NotContains(t, parts, "word/header1.xml")
+	require.NotContains(t, parts["[Content_Types].xml"], "header1.xml")
+}
+
+func TestWriteODT(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteODT(&buf, sampleDoc(t)))
+	files, parts := entries(t, buf.Bytes())
+
+	require.Equal(t, "mimetype", files[0].Name)
+	require.Equal(t, zip.Store, files[0].Method)
+	require.Equal(t, odtMimetype, parts["mimetype"])
+
+	content := parts["content.xml"]
+	require.Contains(t, content, `text:outline-level="3"`)
+	require.Contains(t, content, `<text:list text:style-name="L_number">`)
+	require.Contains(t, content, `<table:table-header-rows>`)
+	require.Contains(t, content, `table:number-columns-spanned="2"`)
+	require.Contains(t, content, `<table:covered-table-cell/>`)
+	require.Contains(t, content, `a <text:s text:c="1"/>b`, "preformatted spaces are kept")
+	require.Contains(t, content, `xlink:href="Pictures/image1.png"`)
+	require.Contains(t, parts["META-INF/manifest.xml"], `manifest:full-path="Pictures/image1.png"`)
+	require.Contains(t, parts["styles.xml"], "Editable copy - not the signed record")
+	require.Contains(t, parts["meta.xml"], `<meta:user-defined meta:name="Status">published</meta:user-defined>`)
+	require.Contains(t, parts["meta.xml"], `<meta:keyword>RPT-1</meta:keyword>`)
+}
+
+func TestExportIsDeterministic(t *testing.T) {
+	for _, write := range []func(io.Writer, Document) error{WriteDOCX, WriteODT} {
+		var a, b bytes.Buffer
+		require.NoError(t, write(&a, sampleDoc(t)))
+		require.NoError(t, write(&b, sampleDoc(t)))
+		require.True(t, bytes.Equal(a.Bytes(), b.Bytes()))
+	}
+}
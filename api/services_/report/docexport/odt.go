@@ -0,0 +1,453 @@
+package docexport
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const odtNamespaces = ` xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"` +
+	` xmlns:style="urn:oasis:names:tc:opendocument:xmlns:style:1.0"` +
+	` xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0"` +
+	` xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"` +
+	` xmlns:draw="urn:oasis:names:tc:opendocument:xmlns:drawing:1.0"` +
+	` xmlns:fo="urn:oasis:names:tc:opendocument:xmlns:xsl-fo-compatible:1.0"` +
+	` xmlns:xlink="http://www.w3.org/1999/xlink"` +
+	` xmlns:svg="urn:oasis:names:tc:opendocument:xmlns:svg-compatible:1.0"` +
+	` xmlns:dc="http://purl.org/dc/elements/1.1/"` +
+	` xmlns:meta="urn:oasis:names:tc:opendocument:xmlns:meta:1.0"` +
+	` office:version="1.3"`
+
+const odtMimetype = "application/vnd.oasis.opendocument.text"
+
+// WriteODT writes doc as an OpenDocument text document.
+func WriteODT(w io.Writer, doc Document) error {
+	o := &odt{styles: map[string]string{}}
+	content := o.content(doc)
+
+	a := newArchive(w, doc.Modified)
+	// The mimetype entry must come first and be stored uncompressed.
+	a.stored("mimetype", []byte(odtMimetype))
+	a.file("content.xml", []byte(content))
+	a.file("styles.xml", []byte(odtStyles(doc.Watermark)))
+	a.file("meta.xml", []byte(odtMeta(doc)))
+	a.file("META-INF/manifest.xml", []byte(o.manifest()))
+	for _, p := range o.pics {
+		a.file("Pictures/"+p.name, p.data)
+	}
+	return a.close()
+}
+
+type odt struct {
+	b strings.Builder
+	// styles holds generated automatic styles by name.
+	styles  map[string]string
+	pics    []picture
+	nextPic int
+	tables  int
+}
+
+func (o *odt) content(doc Document) string {
+	o.para(&paragraph{runs: []run{{text: doc.Title}}}, "Title")
+	if doc.Subject != "" {
+		o.para(&paragraph{runs: []run{{text: doc.Subject}}}, "Subtitle")
+	}
+	for _, sec := range doc.Sections {
+		o.headingRuns([]run{{text: sec.Title}}, 1, "")
+		blocks := parseHTML(sec.HTML)
+		if len(blocks) == 0 && len(sec.Images) == 0 {
+			blocks = []block{&paragraph{runs: []run{{text: "(No content provided)", italic: true}}}}
+		}
+		o.blocks(blocks)
+		for _, p := range pictures(sec.Images, &o.nextPic) {
+			o.picture(p)
+		}
+	}
+
+	names := make([]string, 0, len(o.styles))
+	for name := range o.styles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var out strings.Builder
+	out.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content` + odtNamespaces + `><office:automatic-styles>` + odtListStyles + odtTableStyles)
+	for _, name := range names {
+		out.WriteString(o.styles[name])
+	}
+	out.WriteString(`</office:automatic-styles><office:body><office:text>`)
+	out.WriteString(o.b.String())
+	out.WriteString(`</office:text></office:body></office:document-content>`)
+	return out.String()
+}
+
+func (o *odt) blocks(blocks []block) {
+	for _, b := range blocks {
+		switch b := b.(type) {
+		case *paragraph:
+			switch {
+			case b.level > 0:
+				o.headingRuns(b.runs, min(b.level+1, 10), b.align)
+			case b.quote:
+				o.para(b, "Quotations")
+			case b.mono:
+				o.para(b, "Preformatted_20_Text")
+			default:
+				o.para(b, "Text_20_body")
+			}
+		case *list:
+			o.list(b)
+		case *table:
+			o.table(b)
+		}
+	}
+}
+
+// paraStyle returns the style to use for a paragraph of parent with the
+// given alignment, generating an automatic style when needed.
+func (o *odt) paraStyle(parent, align string) string {
+	if align == "" {
+		return parent
+	}
+	name := "P_" + parent + "_" + align
+	if align == "left" {
+		align = "start"
+	} else if align == "right" {
+		align = "end"
+	}
+	o.styles[name] = `<style:style style:name="` + name + `" style:family="paragraph" style:parent-style-name="` + parent + `">` +
+		`<style:paragraph-properties fo:text-align="` + align + `"/></style:style>`
+	return name
+}
+
+func (o *odt) para(p *paragraph, parent string) {
+	o.b.WriteString(`<text:p text:style-name="` + o.paraStyle(parent, p.align) + `">`)
+	o.runs(p.runs)
+	o.b.WriteString(`</text:p>`)
+}
+
+func (o *odt) headingRuns(runs []run, level int, align string) {
+	fmt.Fprintf(&o.b, `<text:h text:style-name="%s" text:outline-level="%d">`, o.paraStyle("Heading_20_"+strconv.Itoa(level), align), level)
+	o.runs(runs)
+	o.b.WriteString(`</text:h>`)
+}
+
+func (o *odt) runs(runs []run) {
+	b := &o.b
+	for _, r := range runs {
+		if r.br {
+			b.WriteString(`<text:line-break/>`)
+			continue
+		}
+		if r.link != "" {
+			b.WriteString(`<text:a xlink:type="simple" xlink:href="` + esc(r.link) + `">`)
+		}
+		style := o.textStyle(r)
+		if style != "" {
+			b.WriteString(`<text:span text:style-name="` + style + `">`)
+		}
+		b.WriteString(odtText(r.text))
+		if style != "" {
+			b.WriteString(`</text:span>`)
+		}
+		if r.link != "" {
+			b.WriteString(`</text:a>`)
+		}
+	}
+}
+
+// textStyle returns an automatic text style for the run's formatting.
+func (o *odt) textStyle(r run) string {
+	if !r.bold && !r.italic && !r.underline && !r.mono {
+		return ""
+	}
+	name, props := "T", ""
+	if r.bold {
+		name += "b"
+		props += ` fo:font-weight="bold"`
+	}
+	if r.italic {
+		name += "i"
+		props += ` fo:font-style="italic"`
+	}
+	if r.underline {
+		name += "u"
+		props += ` style:text-underline-style="solid" style:text-underline-width="auto" style:text-underline-color="font-color"`
+	}
+	if r.mono {
+		name += "m"
+		props += ` style:font-name="Liberation Mono" fo:font-family="'Liberation Mono', 'Courier New', monospace" style:font-family-generic="modern" style:font-pitch="fixed"`
+	}
+	o.styles[name] = `<style:style style:name="` + name + `" style:family="text"><style:text-properties` + props + `/></style:style>`
+	return name
+}
+
+// odtText escapes text, keeping runs of spaces and tabs which ODF would
+// otherwise collapse.
+func odtText(s string) string {
+	var b strings.Builder
+	spaces := 0
+	flush := func() {
+		if spaces > 0 {
+			b.WriteByte(' ')
+			if spaces > 1 {
+				fmt.Fprintf(&b, `<text:s text:c="%d"/>`, spaces-1)
+			}
+			spaces = 0
+		}
+	}
+	for _, r := range s {
+		switch r {
+		case ' ':
+			spaces++
+		case '\t':
+			flush()
+			b.WriteString(`<text:tab/>`)
+		default:
+			flush()
+			b.WriteString(esc(string(r)))
+		}
+	}
+	flush()
+	return b.String()
+}
+
+func (o *odt) list(l *list) {
+	style := "L_bullet"
+	if l.ordered {
+		style = "L_number"
+	}
+	o.b.WriteString(`<text:list text:style-name="` + style + `">`)
+	for _, item := range l.items {
+		o.b.WriteString(`<text:list-item>`)
+		for _, b := range item {
+			switch b := b.(type) {
+			case *paragraph:
+				parent := "List_20_Contents"
+				if b.mono {
+					parent = "Preformatted_20_Text"
+				}
+				if b.level > 0 {
+					b = &paragraph{runs: boldRuns(b.runs), align: b.align}
+				}
+				o.para(b, parent)
+			case *list:
+				o.list(b)
+			case *table:
+				// Tables cannot sit in a list item; close the item around it.
+				o.b.WriteString(`<text:p text:style-name="List_20_Contents"/>`)
+				o.b.WriteString(`</text:list-item></text:list>`)
+				o.table(b)
+				o.b.WriteString(`<text:list text:style-name="` + style + `" text:continue-numbering="true"><text:list-item>`)
+			}
+		}
+		if len(item) == 0 {
+			o.b.WriteString(`<text:p text:style-name="List_20_Contents"/>`)
+		}
+		o.b.WriteString(`</text:list-item>`)
+	}
+	o.b.WriteString(`</text:list>`)
+}
+
+func boldRuns(runs []run) []run {
+	out := make([]run, len(runs))
+	for i, r := range runs {
+		r.bold = true
+		out[i] = r
+	}
+	return out
+}
+
+func (o *odt) table(t *table) {
+	o.tables++
+	cols := t.columns()
+	b := &o.b
+	fmt.Fprintf(b, `<table:table table:name="Table%d" table:style-name="Tbl"><table:table-column table:style-name="TblCol" table:number-columns-repeated="%d"/>`, o.tables, cols)
+	for r, row := range t.rows {
+		if r == 0 && t.header > 0 {
+			b.WriteString(`<table:table-header-rows>`)
+		}
+		b.WriteString(`<table:table-row>`)
+		used := 0
+		for _, c := range row {
+			span := min(c.span, cols-used)
+			if span <= 0 {
+				break
+			}
+			o.cell(c.blocks, span, c.header || r < t.header)
+			used += span
+		}
+		if used < cols {
+			o.cell(nil, cols-used, false)
+		}
+		b.WriteString(`</table:table-row>`)
+		if r == t.header-1 {
+			b.WriteString(`</table:table-header-rows>`)
+		}
+	}
+	b.WriteString(`</table:table>`)
+}
+
+func (o *odt) cell(blocks []block, span int, header bool) {
+	b := &o.b
+	style, para := "TblCell", "Table_20_Contents"
+	if header {
+		style, para = "TblHead", "Table_20_Heading"
+	}
+	b.WriteString(`<table:table-cell table:style-name="` + style + `" office:value-type="string"`)
+	if span > 1 {
+		fmt.Fprintf(b, ` table:number-columns-spanned="%d"`, span)
+	}
+	b.WriteString(`>`)
+	for _, blk := range blocks {
+		if p, ok := blk.(*paragraph); ok && p.level == 0 && !p.mono && !p.quote {
+			o.para(p, para)
+			continue
+		}
+		o.blocks([]block{blk})
+	}
+	if len(blocks) == 0 {
+		b.WriteString(`<text:p text:style-name="` + para + `"/>`)
+	}
+	b.WriteString(`</table:table-cell>`)
+	for i := 1; i < span; i++ {
+		b.WriteString(`<table:covered-table-cell/>`)
+	}
+}
+
+func (o *odt) picture(p picture) {
+	o.pics = append(o.pics, p)
+	fmt.Fprintf(&o.b, `<text:p text:style-name="%s"><draw:frame draw:name="%s" text:anchor-type="as-char" svg:width="%.3fcm" svg:height="%.3fcm" draw:z-index="0">`+
+		`<draw:image xlink:href="Pictures/%s" xlink:type="simple" xlink:show="embed" xlink:actuate="onLoad"/></draw:frame></text:p>`,
+		o.paraStyle("Text_20_body", "center"), esc(p.name), p.width, p.height, esc(p.name))
+}
+
+func (o *odt) manifest() string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<manifest:manifest xmlns:manifest="urn:oasis:names:tc:opendocument:xmlns:manifest:1.0" manifest:version="1.3">` +
+		`<manifest:file-entry manifest:full-path="/" manifest:version="1.3" manifest:media-type="` + odtMimetype + `"/>` +
+		`<manifest:file-entry manifest:full-path="content.xml" manifest:media-type="text/xml"/>` +
+		`<manifest:file-entry manifest:full-path="styles.xml" manifest:media-type="text/xml"/>` +
+		`<manifest:file-entry manifest:full-path="meta.xml" manifest:media-type="text/xml"/>`)
+	for _, p := range o.pics {
+		b.WriteString(`<manifest:file-entry manifest:full-path="Pictures/` + esc(p.name) + `" manifest:media-type="` + p.contentType + `"/>`)
+	}
+	b.WriteString(`</manifest:manifest>`)
+	return b.String()
+}
+
+func odtMeta(doc Document) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<office:document-meta` + odtNamespaces + `><office:meta>` +
+		`<meta:generator>AEGIS</meta:generator>` +
+		`<dc:title>` + esc(doc.Title) + `</dc:title>` +
+		`<dc:subject>` + esc(doc.Subject) + `</dc:subject>` +
+		`<dc:description>` + esc(doc.Description) + `</dc:description>`)
+	for _, k := range doc.Keywords {
+		b.WriteString(`<meta:keyword>` + esc(k) + `</meta:keyword>`)
+	}
+	b.WriteString(`<meta:initial-creator>` + esc(doc.Author) + `</meta:initial-creator>` +
+		`<dc:creator>` + esc(doc.Author) + `</dc:creator>` +
+		`<meta:creation-date>` + w3cdtf(doc.Created) + `</meta:creation-date>` +
+		`<dc:date>` + w3cdtf(doc.Modified) + `</dc:date>` +
+		`<meta:editing-cycles>` + strconv.Itoa(doc.Revision) + `</meta:editing-cycles>`)
+	for _, kv := range doc.Properties {
+		b.WriteString(`<meta:user-defined meta:name="` + esc(kv[0]) + `">` + esc(kv[1]) + `</meta:user-defined>`)
+	}
+	b.WriteString(`</office:meta></office:document-meta>`)
+	return b.String()
+}
+
+var odtListStyles = func() string {
+	var b strings.Builder
+	bullets := []string{"•", "◦", "▪"}
+	for _, ordered := range []bool{false, true} {
+		name := "L_bullet"
+		if ordered {
+			name = "L_number"
+		}
+		b.WriteString(`<text:list-style style:name="` + name + `">`)
+		for lvl := 1; lvl <= 10; lvl++ {
+			indent := fmt.Sprintf(`<style:list-level-properties text:list-level-position-and-space-mode="label-alignment">`+
+				`<style:list-level-label-alignment text:label-followed-by="listtab" text:list-tab-stop-position="%.2fcm" fo:text-indent="-0.63cm" fo:margin-left="%.2fcm"/>`+
+				`</style:list-level-properties>`, 1.27+0.63*float64(lvl-1), 1.27+0.63*float64(lvl-1))
+			if ordered {
+				fmt.Fprintf(&b, `<text:list-level-style-number text:level="%d" style:num-suffix="." style:num-format="1">%s</text:list-level-style-number>`, lvl, indent)
+			} else {
+				fmt.Fprintf(&b, `<text:list-level-style-bullet text:level="%d" text:bullet-char="%s">%s</text:list-level-style-bullet>`, lvl, bullets[(lvl-1)%len(bullets)], indent)
+			}
+		}
+		b.WriteString(`</text:list-style>`)
+	}
+	return b.String()
+}()
+
+const odtTableStyles = `<style:style style:name="Tbl" style:family="table"><style:table-properties style:width="16cm" table:align="margins"/></style:style>` +
+	`<style:style style:name="TblCol" style:family="table-column"/>` +
+	`<style:style style:name="TblCell" style:family="table-cell"><style:table-cell-properties fo:padding="0.1cm" fo:border="0.5pt solid #969696"/></style:style>` +
+	`<style:style style:name="TblHead" style:family="table-cell"><style:table-cell-properties fo:background-color="#e6e9ee" fo:padding="0.1cm" fo:border="0.5pt solid #969696"/></style:style>`
+
+// odtStyles holds the named paragraph styles and the page layout. The
+// watermark is printed in the page header of every page.
+func odtStyles(watermark string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<office:document-styles` + odtNamespaces + `>` +
+		`<office:font-face-decls>` +
+		`<style:font-face style:name="Liberation Sans" svg:font-family="'Liberation Sans'" style:font-family-generic="swiss" style:font-pitch="variable"/>` +
+		`<style:font-face style:name="Liberation Mono" svg:font-family="'Liberation Mono'" style:font-family-generic="modern" style:font-pitch="fixed"/>` +
+		`</office:font-face-decls><office:styles>` +
+		`<style:default-style style:family="paragraph"><style:paragraph-properties fo:margin-bottom="0.21cm"/>` +
+		`<style:text-properties style:font-name="Liberation Sans" fo:font-size="11pt" fo:language="en" fo:country="GB"/></style:default-style>` +
+		`<style:style style:name="Standard" style:family="paragraph" style:class="text"/>` +
+		`<style:style style:name="Text_20_body" style:display-name="Text body" style:family="paragraph" style:parent-style-name="Standard" style:class="text">` +
+		`<style:paragraph-properties fo:margin-top="0cm" fo:margin-bottom="0.21cm" fo:line-height="115%"/></style:style>` +
+		`<style:style style:name="Title" style:family="paragraph" style:parent-style-name="Standard" style:class="chapter">` +
+		`<style:paragraph-properties fo:margin-bottom="0.42cm"/><style:text-properties fo:font-size="24pt" fo:font-weight="bold"/></style:style>` +
+		`<style:style style:name="Subtitle" style:family="paragraph" style:parent-style-name="Standard" style:class="chapter">` +
+		`<style:paragraph-properties fo:margin-bottom="0.64cm"/><style:text-properties fo:font-size="14pt" fo:color="#595959"/></style:style>` +
+		`<style:style style:name="Heading" style:family="paragraph" style:parent-style-name="Standard" style:class="text">` +
+		`<style:paragraph-properties fo:margin-top="0.42cm" fo:margin-bottom="0.21cm" fo:keep-with-next="always"/><style:text-properties fo:font-weight="bold"/></style:style>`)
+	sizes := []string{"16pt", "14pt", "12pt", "11pt", "11pt", "11pt", "11pt", "11pt", "11pt", "11pt"}
+	for i, size := range sizes {
+		lvl := i + 1
+		brk := ""
+		if lvl == 1 {
+			brk = `<style:paragraph-properties fo:break-before="page"/>`
+		}
+		fmt.Fprintf(&b, `<style:style style:name="Heading_20_%d" style:display-name="Heading %d" style:family="paragraph" style:parent-style-name="Heading" style:next-style-name="Text_20_body" style:default-outline-level="%d" style:class="text">%s<style:text-properties fo:font-size="%s"/></style:style>`,
+			lvl, lvl, lvl, brk, size)
+	}
+	b.WriteString(`<style:style style:name="List_20_Contents" style:display-name="List Contents" style:family="paragraph" style:parent-style-name="Standard" style:class="list">` +
+		`<style:paragraph-properties fo:margin-bottom="0.1cm"/></style:style>` +
+		`<style:style style:name="Quotations" style:family="paragraph" style:parent-style-name="Standard" style:class="html">` +
+		`<style:paragraph-properties fo:margin-left="1cm" fo:margin-right="1cm"/><style:text-properties fo:font-style="italic" fo:color="#404040"/></style:style>` +
+		`<style:style style:name="Preformatted_20_Text" style:display-name="Preformatted Text" style:family="paragraph" style:parent-style-name="Standard" style:class="html">` +
+		`<style:paragraph-properties fo:margin-bottom="0cm"/><style:text-properties style:font-name="Liberation Mono" fo:font-size="9pt"/></style:style>` +
+		`<style:style style:name="Table_20_Contents" style:display-name="Table Contents" style:family="paragraph" style:parent-style-name="Standard" style:class="extra">` +
+		`<style:paragraph-properties fo:margin-bottom="0cm"/><style:text-properties fo:font-size="9pt"/></style:style>` +
+		`<style:style style:name="Table_20_Heading" style:display-name="Table Heading" style:family="paragraph" style:parent-style-name="Table_20_Contents" style:class="extra">` +
+		`<style:text-properties fo:font-weight="bold"/></style:style>` +
+		`<style:style style:name="Header" style:family="paragraph" style:parent-style-name="Standard" style:class="extra">` +
+		`<style:paragraph-properties fo:text-align="center"/><style:text-properties fo:font-size="14pt" fo:font-weight="bold" fo:color="#b0b0b0" fo:letter-spacing="0.05cm"/></style:style>` +
+		`<style:style style:name="Footer" style:family="paragraph" style:parent-style-name="Standard" style:class="extra">` +
+		`<style:paragraph-properties fo:text-align="end"/><style:text-properties fo:font-size="8pt"/></style:style>` +
+		`</office:styles><office:automatic-styles>` +
+		`<style:page-layout style:name="pm1"><style:page-layout-properties fo:page-width="21cm" fo:page-height="29.7cm" style:print-orientation="portrait"` +
+		` fo:margin-top="1.5cm" fo:margin-bottom="1.5cm" fo:margin-left="2.54cm" fo:margin-right="2.54cm"/>` +
+		`<style:header-style><style:header-footer-properties fo:min-height="0cm" fo:margin-bottom="0.5cm"/></style:header-style>` +
+		`<style:footer-style><style:header-footer-properties fo:min-height="0cm" fo:margin-top="0.5cm"/></style:footer-style>` +
+		`</style:page-layout></office:automatic-styles><office:master-styles>` +
+		`<style:master-page style:name="Standard" style:page-layout-name="pm1">`)
+	if watermark != "" {
+		b.WriteString(`<style:header><text:p text:style-name="Header">` + esc(watermark) + `</text:p></style:header>`)
+	}
+	b.WriteString(`<style:footer><text:p text:style-name="Footer">Page <text:page-number text:select-page="current">1</text:page-number>` +
+		` of <text:page-count>1</text:page-count></text:p></style:footer>` +
+		`</style:master-page></office:master-styles></office:document-styles>`)
+	return b.String()
+}
@@ -0,0 +1,100 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+
+	"aegis-api/services_/report/docexport"
+
+	"github.com/google/uuid"
+)
+
+// DefaultExportWatermark marks editable exports as secondary to the sealed
+// PDF.
+const DefaultExportWatermark = "Editable copy - not the signed record"
+
+// ExportOptions adjusts DOCX, ODT and watermarked PDF exports.
+type ExportOptions struct {
+	// Watermark is printed on every page; empty exports without one.
+	Watermark string
+}
+
+func (s *ReportServiceImpl) DownloadReportAsDOCX(ctx context.Context, reportID uuid.UUID, fields FieldRenderer, opts ExportOptions) ([]byte, error) {
+	doc, err := s.exportDocument(ctx, reportID, fields, opts)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := docexport.WriteDOCX(&buf, doc); err != nil {
+		return nil, fmt.Errorf("docx export: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *ReportServiceImpl) DownloadReportAsODT(ctx context.Context, reportID uuid.UUID, fields FieldRenderer, opts ExportOptions) ([]byte, error) {
+	doc, err := s.exportDocument(ctx, reportID, fields, opts)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := docexport.WriteODT(&buf, doc); err != nil {
+		return nil, fmt.Errorf("odt export: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// exportDocument lays the rendered report out as the PDF does: numbered
+// sections followed by lettered exhibit appendices.
+func (s *ReportServiceImpl) exportDocument(ctx context.Context, reportID uuid.UUID, fields FieldRenderer, opts ExportOptions) (docexport.Document, error) {
+	rpt, err := s.RenderReport(ctx, reportID, fields)
+	if err != nil {
+		return docexport.Document{}, err
+	}
+	meta := rpt.Metadata
+	classification, err := s.classification(ctx, reportID)
+	if err != nil {
+		return docexport.Document{}, err
+	}
+	caseRef, appendices := CaseExhibits(ctx, fields, meta, rpt.Content)
+
+	doc := docexport.Document{
+		Title:       meta.Name,
+		Subject:     caseRef,
+		Description: fmt.Sprintf("Forensic report %s, version %d (%s)", meta.ReportNumber, meta.Version, meta.Status),
+		Keywords:    []string{"forensic report", meta.ReportNumber},
+		Created:     meta.CreatedAt,
+		Modified:    meta.UpdatedAt,
+		Revision:    meta.Version,
+		Properties: [][2]string{
+			{"Report ID", meta.ID.String()},
+			{"Report number", meta.ReportNumber},
+			{"Case ID", meta.CaseID.String()},
+			{"Version", strconv.Itoa(meta.Version)},
+			{"Status", meta.Status},
+			{"Classification", classification},
+		},
+		Watermark: opts.Watermark,
+	}
+	for i, sec := range rpt.Content {
+		doc.Sections = append(doc.Sections, exportSection(fmt.Sprintf("%d. %s", i+1, sec.Title), sec.Content))
+	}
+	for i, a := range appendices {
+		doc.Sections = append(doc.Sections, exportSection(fmt.Sprintf("Appendix %c - %s", 'A'+i, a.Title), a.HTML))
+	}
+	return doc, nil
+}
+
+func exportSection(title, html string) docexport.Section {
+	cleaned, images := extractDataURLImages(html)
+	sec := docexport.Section{Title: title, HTML: cleaned}
+	for _, im := range images {
+		mime := im.Mimetype
+		if mime == "image/jpg" {
+			mime = "image/jpeg"
+		}
+		sec.Images = append(sec.Images, docexport.Image{Data: im.Data, ContentType: mime})
+	}
+	return sec
+}
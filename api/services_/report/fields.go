@@ -0,0 +1,121 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrUnresolvedFields = errors.New("some merge fields could not be resolved")
+
+// FrozenField is the value a merge field resolved to when the report's
+// fields were frozen. Stored as a list because field keys contain dots.
+type FrozenField struct {
+	Key   string `bson:"key" json:"key"`
+	Value string `bson:"value" json:"value"`
+}
+
+// FieldRenderer expands the merge fields in section content from live case
+// data, using a section's FrozenFields instead where present. values maps
+// section ID hex to the HTML each field key resolved to. When some lookups
+// fail it still returns the rendered sections along with an error wrapping
+// ErrUnresolvedFields.
+type FieldRenderer interface {
+	RenderFields(ctx context.Context, rep *Report, sections []ReportSection) (out []ReportSection, values map[string][]FrozenField, err error)
+}
+
+// RenderReport returns the report with merge fields expanded, as exported.
+// A nil renderer returns the stored content unchanged.
+func (s *ReportServiceImpl) RenderReport(ctx context.Context, reportID uuid.UUID, fields FieldRenderer) (*ReportWithContent, error) {
+	rpt, err := s.DownloadReport(ctx, reportID)
+	if err != nil || fields == nil {
+		return rpt, err
+	}
+	out, _, err := fields.RenderFields(ctx, rpt.Metadata, rpt.Content)
+	if err != nil {
+		if !errors.Is(err, ErrUnresolvedFields) {
+			return nil, err
+		}
+		// Failed fields are rendered as visible markers; exporting still works.
+		log.Printf("[RenderReport] report %s: %v", reportID, err)
+	}
+	rpt.Content = out
+	return rpt, nil
+}
+
+// FreezeFields resolves every merge field from live data and stores the
+// values on each section, so later renders no longer change as the case
+// does. Nothing is stored unless every field resolved.
+func (s *ReportServiceImpl) FreezeFields(ctx context.Context, reportID uuid.UUID, fields FieldRenderer) (*ReportWithContent, error) {
+	if fields == nil {
+		return nil, errors.New("merge field renderer not configured")
+	}
+	rpt, err := s.DownloadReport(ctx, reportID)
+	if err != nil {
+		return nil, err
+	}
+	// Freezing is part of publishing, so it is allowed during review.
+	if rpt.Metadata.Status == "published" {
+		return nil, fmt.Errorf("%w: report is published", ErrReportLocked)
+	}
+	mongoID, err := primitive.ObjectIDFromHex(rpt.Metadata.MongoID)
+	if err != nil {
+		return nil, fmt.Errorf("report has no content document: %w", err)
+	}
+
+	live := make([]ReportSection, len(rpt.Content))
+	copy(live, rpt.Content)
+	for i := range live {
+		live[i].FrozenFields = nil
+	}
+	out, values, err := fields.RenderFields(ctx, rpt.Metadata, live)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, sec := range out {
+		frozen := values[sec.ID.Hex()]
+		if err := s.mongoRepo.SetFrozenFields(ctx, mongoID, sec.ID, frozen,
+			rpt.Metadata.TenantID.String(), rpt.Metadata.TeamID.String()); err != nil {
+			return nil, fmt.Errorf("failed to freeze fields of section %s: %w", sec.ID.Hex(), err)
+		}
+		out[i].FrozenFields = frozen
+	}
+	now := time.Now().UTC()
+	if err := s.repo.SetFieldsFrozenAt(ctx, reportID, &now); err != nil {
+		return nil, err
+	}
+	rpt.Metadata.FieldsFrozenAt = &now
+	rpt.Content = out
+	return rpt, nil
+}
+
+// ThawFields drops frozen values so fields follow live data again.
+func (s *ReportServiceImpl) ThawFields(ctx context.Context, reportID uuid.UUID) error {
+	if err := s.ensureEditable(ctx, reportID); err != nil {
+		return err
+	}
+	rpt, err := s.DownloadReport(ctx, reportID)
+	if err != nil {
+		return err
+	}
+	mongoID, err := primitive.ObjectIDFromHex(rpt.Metadata.MongoID)
+	if err != nil {
+		return fmt.Errorf("report has no content document: %w", err)
+	}
+	for _, sec := range rpt.Content {
+		if len(sec.FrozenFields) == 0 {
+			continue
+		}
+		if err := s.mongoRepo.SetFrozenFields(ctx, mongoID, sec.ID, nil,
+			rpt.Metadata.TenantID.String(), rpt.Metadata.TeamID.String()); err != nil {
+			return err
+		}
+	}
+	return s.repo.SetFieldsFrozenAt(ctx, reportID, nil)
+}
@@ -0,0 +1,219 @@
+// Package htmldiff compares two HTML fragments word by word while keeping
+// markup intact, so a diff of rich-text section content can be shown as
+// <ins>/<del> annotations on the newer version.
+package htmldiff
+
+import (
+	"strings"
+	"unicode"
+)
+
+type Op string
+
+const (
+	OpEqual  Op = "equal"
+	OpInsert Op = "insert"
+	OpDelete Op = "delete"
+)
+
+// Chunk is a run of tokens with the same operation.
+type Chunk struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// Result is the diff of two fragments.
+type Result struct {
+	Chunks     []Chunk `json:"chunks"`
+	HTML       string  `json:"html"` // new version with <ins>/<del> markers
+	Insertions int     `json:"insertions"`
+	Deletions  int     `json:"deletions"`
+}
+
+// maxCells bounds the LCS table; larger inputs (after trimming the common
+// prefix and suffix) are reported as a single replacement.
+const maxCells = 4_000_000
+
+// Diff compares old and new.
+func Diff(oldHTML, newHTML string) Result {
+	a, b := tokenize(oldHTML), tokenize(newHTML)
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []tokenOp
+	for _, t := range a[:prefix] {
+		ops = append(ops, tokenOp{OpEqual, t})
+	}
+	ops = append(ops, lcsDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, t := range a[len(a)-suffix:] {
+		ops = append(ops, tokenOp{OpEqual, t})
+	}
+	return build(ops)
+}
+
+type tokenOp struct {
+	op  Op
+	tok string
+}
+
+func lcsDiff(a, b []string) []tokenOp {
+	var ops []tokenOp
+	if len(a)*len(b) > maxCells {
+		for _, t := range a {
+			ops = append(ops, tokenOp{OpDelete, t})
+		}
+		for _, t := range b {
+			ops = append(ops, tokenOp{OpInsert, t})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, tokenOp{OpEqual, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, tokenOp{OpDelete, a[i]})
+			i++
+		default:
+			ops = append(ops, tokenOp{OpInsert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, tokenOp{OpDelete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, tokenOp{OpInsert, b[j]})
+	}
+	return ops
+}
+
+// build merges token ops into chunks and renders the annotated HTML. Tags
+// are never wrapped: inserted tags are kept, deleted tags are dropped, so
+// the output stays well-formed wherever the new version is.
+func build(ops []tokenOp) Result {
+	var res Result
+	var html strings.Builder
+	open := Op("")
+	closeMarker := func() {
+		switch open {
+		case OpInsert:
+			html.WriteString("</ins>")
+		case OpDelete:
+			html.WriteString("</del>")
+		}
+		open = ""
+	}
+
+	for _, o := range ops {
+		if n := len(res.Chunks); n > 0 && res.Chunks[n-1].Op == o.op {
+			res.Chunks[n-1].Text += o.tok
+		} else {
+			res.Chunks = append(res.Chunks, Chunk{Op: o.op, Text: o.tok})
+		}
+
+		if isTag(o.tok) {
+			closeMarker()
+			if o.op != OpDelete {
+				html.WriteString(o.tok)
+			}
+			continue
+		}
+		blank := strings.TrimSpace(o.tok) == ""
+		switch o.op {
+		case OpInsert:
+			if !blank {
+				res.Insertions++
+			}
+		case OpDelete:
+			if !blank {
+				res.Deletions++
+			}
+		}
+		if o.op != open {
+			closeMarker()
+			switch o.op {
+			case OpInsert:
+				html.WriteString(`<ins class="diff-ins">`)
+			case OpDelete:
+				html.WriteString(`<del class="diff-del">`)
+			}
+			open = o.op
+		}
+		html.WriteString(o.tok)
+	}
+	closeMarker()
+	res.HTML = html.String()
+	return res
+}
+
+// tokenize splits HTML into tags, words, whitespace runs and single
+// punctuation characters. Entities stay attached to the word they are in.
+func tokenize(s string) []string {
+	var out []string
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == '<':
+			end := strings.IndexByte(s[i:], '>')
+			if end < 0 {
+				out = append(out, s[i:])
+				return out
+			}
+			out = append(out, s[i:i+end+1])
+			i += end + 1
+		case isSpace(rune(c)):
+			j := i
+			for j < len(s) && isSpace(rune(s[j])) {
+				j++
+			}
+			out = append(out, s[i:j])
+			i = j
+		default:
+			j := i
+			for j < len(s) && s[j] != '<' && !isSpace(rune(s[j])) && !isPunct(s[j]) {
+				j++
+			}
+			if j == i {
+				j = i + 1
+			}
+			out = append(out, s[i:j])
+			i = j
+		}
+	}
+	return out
+}
+
+func isTag(tok string) bool { return strings.HasPrefix(tok, "<") }
+
+func isSpace(r rune) bool { return unicode.IsSpace(r) }
+
+// isPunct splits on ASCII punctuation other than characters commonly found
+// inside words, identifiers and entities (&amp; 10.0.0.1 user_name).
+func isPunct(c byte) bool {
+	return c < 0x80 && strings.IndexByte(",;:!?()[]{}\"'", c) >= 0
+}
@@ -0,0 +1,43 @@
+package htmldiff_test
+
+import (
+	"strings"
+	"testing"
+
+	"aegis-api/services_/report/htmldiff"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff_WordLevelChangesKeepMarkup(t *testing.T) {
+	res := htmldiff.Diff(
+		"<p>The host contacted 203.0.113.9 at 10:40.</p>",
+		"<p>The <b>workstation</b> contacted 203.0.113.9 at 10:41.</p>",
+	)
+	require.Equal(t,
+		`<p>The <del class="diff-del">host</del><b><ins class="diff-ins">workstation</ins></b> contacted 203.0.113.9 at 10:<del class="diff-del">40.</del><ins class="diff-ins">41.</ins></p>`,
+		res.HTML)
+	require.Equal(t, 2, res.Insertions)
+	require.Equal(t, 2, res.Deletions)
+}
+
+func TestDiff_DeletedTagsAreDropped(t *testing.T) {
+	res := htmldiff.Diff("<p>one</p><p>two</p>", "<p>one</p>")
+	require.Equal(t, `<p>one</p><del class="diff-del">two</del>`, res.HTML)
+	require.Equal(t, 1, res.Deletions)
+}
+
+func TestDiff_IdenticalInputHasNoChanges(t *testing.T) {
+	in := "<ul><li>E01 image &amp; memory dump</li></ul>"
+	res := htmldiff.Diff(in, in)
+	require.Equal(t, in, res.HTML)
+	require.Zero(t, res.Insertions+res.Deletions)
+	require.Len(t, res.Chunks, 1)
+}
+
+func TestDiff_LargeRewriteFallsBackToReplacement(t *testing.T) {
+	old := strings.Repeat("alpha ", 3000)
+	res := htmldiff.Diff(old, strings.Repeat("beta ", 3000))
+	require.Equal(t, 3000, res.Insertions)
+	require.Equal(t, 3000, res.Deletions)
+}
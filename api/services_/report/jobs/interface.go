@@ -0,0 +1,85 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"aegis-api/services_/report"
+
+	"github.com/google/uuid"
+)
+
+type Repository interface {
+	AutoMigrate() error
+
+	CreateJob(j *Job) error
+	UpdateJob(j *Job) error
+	DeleteJob(tenantID, jobID string) error
+	GetJob(tenantID, jobID string) (*Job, error)
+	ListJobs(tenantID string) ([]Job, error)
+	// DueJobs returns enabled scheduled jobs of every tenant whose next
+	// run is at or before now.
+	DueJobs(now time.Time) ([]Job, error)
+	// AdvanceSchedule moves a job's next run from prev to next and records
+	// the run. It returns false if another instance got there first.
+	AdvanceSchedule(jobID string, prev time.Time, next *time.Time, ranAt time.Time) (bool, error)
+
+	// FindCases returns the tenant's cases matching f.
+	FindCases(tenantID string, f CaseFilter) ([]CaseRef, error)
+
+	// CreateRun stores a run with its items.
+	CreateRun(r *Run, items []Item) error
+	GetRun(tenantID, runID string) (*Run, error)
+	ListRuns(tenantID, jobID string, limit int) ([]Run, error)
+	// ListItems returns a run's items without their artifacts.
+	ListItems(runID string) ([]Item, error)
+	GetItem(runID, itemID string) (*Item, error)
+	StartRun(runID string, at time.Time) error
+	UpdateItem(it *Item) error
+	// FinishItem stores the item's outcome and counts it on its run.
+	FinishItem(it *Item) error
+	FinishRun(runID, status string, at time.Time) error
+	// FailInterrupted marks runs left unfinished by a restart as failed,
+	// along with their unfinished items.
+	FailInterrupted(at time.Time) error
+}
+
+// Reports is the part of report.ReportService the jobs need.
+type Reports interface {
+	GenerateReport(ctx context.Context, caseID, examinerID, tenantID, teamID, templateID uuid.UUID, caseType string) (*report.Report, error)
+	UpdateReportName(ctx context.Context, reportID uuid.UUID, name string) (*report.Report, error)
+	DownloadReportAsPDF(ctx context.Context, reportID uuid.UUID, fields report.FieldRenderer) ([]byte, error)
+	DownloadReportAsDOCX(ctx context.Context, reportID uuid.UUID, fields report.FieldRenderer, opts report.ExportOptions) ([]byte, error)
+	DownloadReportAsODT(ctx context.Context, reportID uuid.UUID, fields report.FieldRenderer, opts report.ExportOptions) ([]byte, error)
+}
+
+// Notifier tells a user that a run finished.
+type Notifier interface {
+	Notify(userID, tenantID, teamID, title, message string)
+}
+
+type Service interface {
+	CreateJob(actor Actor, in JobInput) (*Job, error)
+	UpdateJob(actor Actor, jobID string, in JobInput) (*Job, error)
+	DeleteJob(actor Actor, jobID string) error
+	GetJob(actor Actor, jobID string) (*Job, error)
+	ListJobs(actor Actor) ([]Job, error)
+
+	// RunNow starts a run of the job in the background and returns it.
+	RunNow(actor Actor, jobID string) (*Run, error)
+	// RetryFailed starts a run covering only the cases that failed in
+	// runID.
+	RetryFailed(actor Actor, runID string) (*Run, error)
+	ListRuns(actor Actor, jobID string) ([]Run, error)
+	GetRun(actor Actor, runID string) (*RunWithItems, error)
+	// Artifact returns the report generated for an item, with its file
+	// name and content type. Admins and the job's recipients may fetch it.
+	Artifact(actor Actor, runID, itemID string) (data []byte, name, contentType string, err error)
+
+	// Start runs scheduled jobs as they fall due until ctx is done.
+	Start(ctx context.Context, interval time.Duration)
+	// RunDue starts every job due at now and returns the runs started.
+	RunDue(now time.Time) ([]Run, error)
+	// Wait blocks until every run started so far has finished.
+	Wait()
+}
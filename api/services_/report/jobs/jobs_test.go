@@ -0,0 +1,176 @@
+package jobs_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"aegis-api/services_/report/jobs"
+	"aegis-api/tests/fakes"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04", s)
+		require.NoError(t, err)
+		return v
+	}
+	cases := []struct {
+		spec, from, want string
+	}{
+		{"0 8 * * 1", "2026-10-19 08:00", "2026-10-26 08:00"}, // Monday
+		{"0 8 * * 1", "2026-10-18 23:59", "2026-10-19 08:00"},
+		{"*/15 * * * *", "2026-10-19 10:07", "2026-10-19 10:15"},
+		{"30 9 1,15 * *", "2026-10-15 09:30", "2026-11-01 09:30"},
+		{"0 0 * * 7", "2026-10-19 00:00", "2026-10-25 00:00"}, // Sunday as 7
+		{"0 12 * 2 1-5", "2026-10-19 00:00", "2027-02-01 12:00"},
+		{"@daily", "2026-12-31 00:00", "2027-01-01 00:00"},
+		// Both day fields restricted: either matches.
+		{"0 0 13 * 5", "2026-10-01 00:00", "2026-10-02 00:00"},
+	}
+	for _, c := range cases {
+		s, err := jobs.ParseSchedule(c.spec)
+		require.NoError(t, err, c.spec)
+		require.Equal(t, at(c.want), s.Next(at(c.from)), c.spec)
+	}
+
+	never, err := jobs.ParseSchedule("0 0 30 2 *")
+	require.NoError(t, err)
+	require.True(t, never.Next(at("2026-01-01 00:00")).IsZero())
+
+	for _, bad := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := jobs.ParseSchedule(bad)
+		require.ErrorIs(t, err, jobs.ErrInvalidSchedule, bad)
+	}
+}
+
+func setup(t *testing.T, workers int) (jobs.Service, *fakes.ReportJobs, *fakes.Generator, *fakes.Notifier, jobs.Actor) {
+	t.Helper()
+	repo := &fakes.ReportJobs{}
+	reports := &fakes.Generator{}
+	notifier := &fakes.Notifier{}
+	svc := jobs.NewService(repo, reports, nil, notifier, jobs.Options{Workers: workers, RetryDelay: -1})
+	actor := jobs.Actor{UserID: uuid.NewString(), Role: "DFIR Admin", TenantID: uuid.NewString(), TeamID: uuid.NewString()}
+	return svc, repo, reports, notifier, actor
+}
+
+func TestRunRetriesAndReportsItemsIndividually(t *testing.T) {
+	svc, repo, reports, notifier, actor := setup(t, 3)
+	for i := 0; i < 8; i++ {
+		repo.Cases = append(repo.Cases, jobs.CaseRef{ID: uuid.NewString(), Title: "Case " + string(rune('A'+i)), TeamID: actor.TeamID})
+	}
+	flaky := uuid.MustParse(repo.Cases[1].ID)
+	broken := uuid.MustParse(repo.Cases[2].ID)
+	reports.Fail(flaky, 1)
+	reports.Fail(broken, 99)
+
+	recipient := uuid.NewString()
+	job, err := svc.CreateJob(actor, jobs.JobInput{
+		Name:       "Weekly status pack",
+		Filter:     jobs.CaseFilter{Statuses: []string{"open"}},
+		Recipients: []string{recipient},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "pdf", job.Format)
+	require.Nil(t, job.NextRunAt, "jobs without a schedule run on demand only")
+
+	run, err := svc.RunNow(actor, job.ID)
+	require.NoError(t, err)
+	require.Equal(t, 8, run.Total)
+	svc.Wait()
+
+	got, err := svc.GetRun(actor, run.ID)
+	require.NoError(t, err)
+	require.Equal(t, jobs.RunPartial, got.Status)
+	require.Equal(t, 7, got.Succeeded)
+	require.Equal(t, 1, got.Failed)
+	require.LessOrEqual(t, reports.Peak(), int32(3), "the worker pool bounds concurrency")
+
+	for _, it := range got.Items {
+		switch it.CaseID {
+		case flaky.String():
+			require.Equal(t, jobs.ItemSucceeded, it.Status)
+			require.Equal(t, 2, it.Attempts)
+		case broken.String():
+			require.Equal(t, jobs.ItemFailed, it.Status)
+			require.Equal(t, 3, it.Attempts)
+			require.Contains(t, it.Error, "mongo unavailable")
+		default:
+			require.Equal(t, jobs.ItemSucceeded, it.Status)
+			require.Equal(t, 1, it.Attempts)
+			require.NotEmpty(t, it.SHA256)
+		}
+	}
+
+	sent := notifier.Sent()
+	require.Len(t, sent, 1)
+	require.Equal(t, recipient, sent[0].UserID)
+	require.Equal(t, "Report job finished with errors", sent[0].Title)
+	require.Contains(t, sent[0].Message, "7 of 8 reports generated")
+	require.Contains(t, sent[0].Message, "Case C (generating report: mongo unavailable)")
+
+	// The recipient can download the generated reports; other users cannot.
+	var okItem jobs.Item
+	for _, it := range got.Items {
+		if it.Status == jobs.ItemSucceeded {
+			okItem = it
+		}
+	}
+	reader := jobs.Actor{UserID: recipient, Role: "DFIR Analyst", TenantID: actor.TenantID}
+	data, name, ctype, err := svc.Artifact(reader, run.ID, okItem.ID)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(data), "%PDF-"))
+	require.Equal(t, "application/pdf", ctype)
+	require.True(t, strings.HasSuffix(name, ".pdf"))
+	_, _, _, err = svc.Artifact(jobs.Actor{UserID: uuid.NewString(), TenantID: actor.TenantID}, run.ID, okItem.ID)
+	require.ErrorIs(t, err, jobs.ErrForbidden)
+
+	// Retrying the run covers only the failed case.
+	reports.Fail(broken, 0)
+	retry, err := svc.RetryFailed(actor, run.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, retry.Total)
+	require.Equal(t, jobs.TriggerRetry, retry.Trigger)
+	svc.Wait()
+	got, err = svc.GetRun(actor, retry.ID)
+	require.NoError(t, err)
+	require.Equal(t, jobs.RunCompleted, got.Status)
+	require.Equal(t, "Report job finished", notifier.Sent()[1].Title)
+}
+
+func TestRunDueFollowsSchedule(t *testing.T) {
+	svc, repo, _, _, actor := setup(t, 2)
+	repo.Cases = []jobs.CaseRef{{ID: uuid.NewString(), Title: "Only case"}}
+
+	_, err := svc.CreateJob(actor, jobs.JobInput{Name: "Bad", Schedule: "every monday"})
+	require.ErrorIs(t, err, jobs.ErrInvalidSchedule)
+	_, err = svc.CreateJob(actor, jobs.JobInput{Name: "Bad", Timezone: "Mars/Olympus"})
+	require.ErrorIs(t, err, jobs.ErrInvalidJob)
+
+	job, err := svc.CreateJob(actor, jobs.JobInput{Name: "Hourly", Schedule: "@hourly", Format: "docx"})
+	require.NoError(t, err)
+	require.NotNil(t, job.NextRunAt)
+	due := *job.NextRunAt
+
+	runs, err := svc.RunDue(due.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Empty(t, runs)
+
+	runs, err = svc.RunDue(due)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	require.Equal(t, jobs.TriggerSchedule, runs[0].Trigger)
+	require.Equal(t, job.CreatedBy, runs[0].StartedBy)
+
+	// The next slot has moved on, so the same tick does not run it again.
+	runs, err = svc.RunDue(due)
+	require.NoError(t, err)
+	require.Empty(t, runs)
+	stored, err := repo.GetJob(actor.TenantID, job.ID)
+	require.NoError(t, err)
+	require.Equal(t, due.Add(time.Hour), *stored.NextRunAt)
+	svc.Wait()
+}
@@ -0,0 +1,146 @@
+package jobs
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Run statuses.
+const (
+	RunQueued    = "queued"
+	RunRunning   = "running"
+	RunCompleted = "completed"
+	// RunPartial means some items failed after every retry.
+	RunPartial = "completed_with_errors"
+	RunFailed  = "failed"
+)
+
+// Item statuses.
+const (
+	ItemPending   = "pending"
+	ItemRunning   = "running"
+	ItemRetrying  = "retrying"
+	ItemSucceeded = "succeeded"
+	ItemFailed    = "failed"
+)
+
+// Run triggers.
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+	TriggerRetry    = "retry"
+)
+
+// Export formats a job can produce.
+var Formats = []string{"pdf", "docx", "odt"}
+
+// Job generates a report for every case matching Filter, on Schedule or
+// on demand.
+type Job struct {
+	ID       string `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID string `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	TeamID   string `gorm:"type:uuid;not null" json:"team_id"`
+	Name     string `gorm:"type:varchar(120);not null" json:"name"`
+	// TemplateID is the template reports are laid out from; nil uses the
+	// tenant's default.
+	TemplateID *string        `gorm:"type:uuid" json:"template_id,omitempty"`
+	Format     string         `gorm:"type:varchar(10);not null;default:'pdf'" json:"format"`
+	Filter     datatypes.JSON `gorm:"type:jsonb;not null" json:"filter"` // CaseFilter
+	// Schedule is a five-field cron expression evaluated in Timezone; empty
+	// for jobs run only on demand.
+	Schedule    string         `gorm:"type:varchar(120)" json:"schedule,omitempty"`
+	Timezone    string         `gorm:"type:varchar(64);not null;default:'UTC'" json:"timezone"`
+	Recipients  datatypes.JSON `gorm:"type:jsonb;not null" json:"recipients"` // []string user IDs
+	MaxAttempts int            `gorm:"not null;default:3" json:"max_attempts"`
+	Enabled     bool           `gorm:"not null;default:true" json:"enabled"`
+	NextRunAt   *time.Time     `gorm:"index" json:"next_run_at,omitempty"`
+	LastRunAt   *time.Time     `json:"last_run_at,omitempty"`
+	CreatedBy   string         `gorm:"type:uuid;not null" json:"created_by"`
+	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (Job) TableName() string { return "report_jobs" }
+
+// CaseFilter selects the cases of a tenant a job reports on. Empty lists
+// match everything.
+type CaseFilter struct {
+	TeamIDs    []string `json:"team_ids,omitempty"`
+	Statuses   []string `json:"statuses,omitempty"`
+	Priorities []string `json:"priorities,omitempty"`
+	Stages     []string `json:"stages,omitempty"`
+}
+
+// Run is one execution of a job.
+type Run struct {
+	ID         string     `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	JobID      string     `gorm:"type:uuid;not null;index" json:"job_id"`
+	TenantID   string     `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	Trigger    string     `gorm:"type:varchar(20);not null" json:"trigger"`
+	Status     string     `gorm:"type:varchar(30);not null" json:"status"`
+	Total      int        `gorm:"not null" json:"total"`
+	Succeeded  int        `gorm:"not null;default:0" json:"succeeded"`
+	Failed     int        `gorm:"not null;default:0" json:"failed"`
+	StartedBy  string     `gorm:"type:uuid;not null" json:"started_by"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+func (Run) TableName() string { return "report_job_runs" }
+
+// Item is the report for one case in a run.
+type Item struct {
+	ID        string  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	RunID     string  `gorm:"type:uuid;not null;index" json:"run_id"`
+	CaseID    string  `gorm:"type:uuid;not null" json:"case_id"`
+	CaseTitle string  `gorm:"type:text" json:"case_title"`
+	TeamID    string  `gorm:"type:uuid" json:"team_id"`
+	Status    string  `gorm:"type:varchar(20);not null" json:"status"`
+	Attempts  int     `gorm:"not null;default:0" json:"attempts"`
+	ReportID  *string `gorm:"type:uuid" json:"report_id,omitempty"`
+	// The exported report, kept so recipients download what was generated.
+	Artifact  []byte     `gorm:"type:bytea" json:"-"`
+	SHA256    string     `gorm:"type:char(64)" json:"sha256,omitempty"`
+	Size      int64      `json:"size,omitempty"`
+	Error     string     `gorm:"type:text" json:"error,omitempty"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+	DoneAt    *time.Time `json:"done_at,omitempty"`
+}
+
+func (Item) TableName() string { return "report_job_items" }
+
+// CaseRef is a case selected by a job's filter.
+type CaseRef struct {
+	ID     string
+	Title  string
+	TeamID string
+}
+
+// RunWithItems is a run and the progress of each of its cases.
+type RunWithItems struct {
+	Run
+	Items []Item `json:"items"`
+}
+
+// Actor is the user managing jobs.
+type Actor struct {
+	UserID   string
+	Role     string
+	TenantID string
+	TeamID   string
+}
+
+// JobInput creates or replaces a job.
+type JobInput struct {
+	Name        string     `json:"name"`
+	TemplateID  *string    `json:"template_id"`
+	Format      string     `json:"format"`
+	Filter      CaseFilter `json:"filter"`
+	Schedule    string     `json:"schedule"`
+	Timezone    string     `json:"timezone"`
+	Recipients  []string   `json:"recipients"`
+	MaxAttempts int        `json:"max_attempts"`
+	Enabled     *bool      `json:"enabled"`
+}
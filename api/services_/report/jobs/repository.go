@@ -0,0 +1,200 @@
+package jobs
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) AutoMigrate() error {
+	return r.db.AutoMigrate(&Job{}, &Run{}, &Item{})
+}
+
+func (r *GormRepository) CreateJob(j *Job) error {
+	return r.db.Create(j).Error
+}
+
+func (r *GormRepository) UpdateJob(j *Job) error {
+	return r.db.Save(j).Error
+}
+
+func (r *GormRepository) DeleteJob(tenantID, jobID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("tenant_id = ? AND id = ?", tenantID, jobID).Delete(&Job{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrJobNotFound
+		}
+		runs := tx.Model(&Run{}).Select("id").Where("job_id = ?", jobID)
+		if err := tx.Where("run_id IN (?)", runs).Delete(&Item{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("job_id = ?", jobID).Delete(&Run{}).Error
+	})
+}
+
+func (r *GormRepository) GetJob(tenantID, jobID string) (*Job, error) {
+	var j Job
+	err := r.db.Where("tenant_id = ? AND id = ?", tenantID, jobID).First(&j).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrJobNotFound
+	}
+	return &j, err
+}
+
+func (r *GormRepository) ListJobs(tenantID string) ([]Job, error) {
+	var out []Job
+	err := r.db.Where("tenant_id = ?", tenantID).Order("name ASC").Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) DueJobs(now time.Time) ([]Job, error) {
+	var out []Job
+	err := r.db.Where("enabled AND schedule <> '' AND next_run_at <= ?", now).
+		Order("next_run_at ASC").Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) AdvanceSchedule(jobID string, prev time.Time, next *time.Time, ranAt time.Time) (bool, error) {
+	res := r.db.Model(&Job{}).
+		Where("id = ? AND next_run_at = ?", jobID, prev).
+		Updates(map[string]interface{}{"next_run_at": next, "last_run_at": ranAt})
+	return res.RowsAffected == 1, res.Error
+}
+
+func lower(in []string) []string {
+	out := make([]string, len(in))
+	for i, v := range in {
+		out[i] = strings.ToLower(v)
+	}
+	return out
+}
+
+func (r *GormRepository) FindCases(tenantID string, f CaseFilter) ([]CaseRef, error) {
+	q := r.db.Table("cases").Select("id, title, team_id").Where("tenant_id = ?", tenantID)
+	if len(f.TeamIDs) > 0 {
+		q = q.Where("team_id IN ?", f.TeamIDs)
+	}
+	// Case enums mix capitalisations ('Open', 'ongoing'), so compare
+	// case-insensitively.
+	if len(f.Statuses) > 0 {
+		q = q.Where("LOWER(status::text) IN ?", lower(f.Statuses))
+	}
+	if len(f.Priorities) > 0 {
+		q = q.Where("LOWER(priority::text) IN ?", lower(f.Priorities))
+	}
+	if len(f.Stages) > 0 {
+		q = q.Where("LOWER(investigation_stage::text) IN ?", lower(f.Stages))
+	}
+	var out []CaseRef
+	err := q.Order("created_at ASC, id ASC").Scan(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) CreateRun(run *Run, items []Item) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(run).Error; err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].RunID = run.ID
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		return tx.Create(&items).Error
+	})
+}
+
+func (r *GormRepository) GetRun(tenantID, runID string) (*Run, error) {
+	var run Run
+	err := r.db.Where("tenant_id = ? AND id = ?", tenantID, runID).First(&run).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRunNotFound
+	}
+	return &run, err
+}
+
+func (r *GormRepository) ListRuns(tenantID, jobID string, limit int) ([]Run, error) {
+	var out []Run
+	err := r.db.Where("tenant_id = ? AND job_id = ?", tenantID, jobID).
+		Order("created_at DESC").Limit(limit).Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) ListItems(runID string) ([]Item, error) {
+	var out []Item
+	err := r.db.Omit("artifact").Where("run_id = ?", runID).
+		Order("case_title ASC, id ASC").Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) GetItem(runID, itemID string) (*Item, error) {
+	var it Item
+	err := r.db.Where("run_id = ? AND id = ?", runID, itemID).First(&it).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrItemNotFound
+	}
+	return &it, err
+}
+
+func (r *GormRepository) StartRun(runID string, at time.Time) error {
+	return r.db.Model(&Run{}).Where("id = ?", runID).
+		Updates(map[string]interface{}{"status": RunRunning, "started_at": at}).Error
+}
+
+func (r *GormRepository) UpdateItem(it *Item) error {
+	return r.db.Model(it).Select("status", "attempts", "report_id", "error").Updates(it).Error
+}
+
+func (r *GormRepository) FinishItem(it *Item) error {
+	counter := "failed"
+	if it.Status == ItemSucceeded {
+		counter = "succeeded"
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(it).
+			Select("status", "attempts", "report_id", "artifact", "sha256", "size", "error", "done_at").
+			Updates(it).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Run{}).Where("id = ?", it.RunID).
+			Update(counter, gorm.Expr(counter+" + 1")).Error
+	})
+}
+
+func (r *GormRepository) FinishRun(runID, status string, at time.Time) error {
+	return r.db.Model(&Run{}).Where("id = ?", runID).
+		Updates(map[string]interface{}{"status": status, "finished_at": at}).Error
+}
+
+func (r *GormRepository) FailInterrupted(at time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var runs []string
+		if err := tx.Model(&Run{}).Where("status IN ?", []string{RunQueued, RunRunning}).
+			Pluck("id", &runs).Error; err != nil || len(runs) == 0 {
+			return err
+		}
+		if err := tx.Model(&Item{}).
+			Where("run_id IN ? AND status NOT IN ?", runs, []string{ItemSucceeded, ItemFailed}).
+			Updates(map[string]interface{}{"status": ItemFailed, "error": "interrupted by a server restart", "done_at": at}).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`UPDATE report_job_runs r SET
+			failed = (SELECT COUNT(*) FROM report_job_items i WHERE i.run_id = r.id AND i.status = ?),
+			status = ?, finished_at = ?
+			WHERE r.id IN ?`, ItemFailed, RunPartial, at, runs).Error
+	})
+}
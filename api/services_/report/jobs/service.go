@@ -0,0 +1,605 @@
+// Package jobs generates reports in bulk: for every case matching a
+// filter, on a cron schedule or on demand, in a bounded worker pool.
+package jobs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"aegis-api/services_/report"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+var (
+	ErrJobNotFound     = errors.New("report job not found")
+	ErrRunNotFound     = errors.New("report job run not found")
+	ErrItemNotFound    = errors.New("report job item not found")
+	ErrInvalidJob      = errors.New("invalid report job")
+	ErrInvalidSchedule = errors.New("invalid schedule")
+	ErrNothingToRetry  = errors.New("run has no failed items")
+	ErrNoArtifact      = errors.New("no report was generated for this item")
+	ErrForbidden       = errors.New("only administrators and the job's recipients can view its runs")
+)
+
+var adminRoles = []string{"Tenant Admin", "DFIR Admin"}
+
+var contentTypes = map[string]string{
+	"pdf":  "application/pdf",
+	"docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"odt":  "application/vnd.oasis.opendocument.text",
+}
+
+// Options tunes job execution.
+type Options struct {
+	// Workers bounds how many reports are generated at once, across all
+	// runs. Default 4.
+	Workers int
+	// RetryDelay is the wait before the first retry of a failed item; it
+	// doubles with each further attempt. Zero means 30s; negative retries
+	// at once.
+	RetryDelay time.Duration
+}
+
+type service struct {
+	repo       Repository
+	reports    Reports
+	fields     report.FieldRenderer // expands merge fields in exports; may be nil
+	notifier   Notifier             // may be nil
+	slots      chan struct{}
+	retryDelay time.Duration
+	now        func() time.Time
+
+	mu   sync.Mutex
+	ctx  context.Context
+	runs sync.WaitGroup
+}
+
+func NewService(repo Repository, reports Reports, fields report.FieldRenderer, notifier Notifier, opts Options) Service {
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+	if opts.RetryDelay < 0 {
+		opts.RetryDelay = 0
+	} else if opts.RetryDelay == 0 {
+		opts.RetryDelay = 30 * time.Second
+	}
+	return &service{
+		repo:       repo,
+		reports:    reports,
+		fields:     fields,
+		notifier:   notifier,
+		slots:      make(chan struct{}, opts.Workers),
+		retryDelay: opts.RetryDelay,
+		now:        time.Now,
+		ctx:        context.Background(),
+	}
+}
+
+func isAdmin(role string) bool {
+	for _, r := range adminRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// ─── Jobs ────────────────────────────────────────────────────────────
+
+func (s *service) apply(j *Job, in JobInput) error {
+	name := strings.TrimSpace(in.Name)
+	if name == "" || len(name) > 120 {
+		return fmt.Errorf("%w: name must be 1-120 characters", ErrInvalidJob)
+	}
+	if in.TemplateID != nil && *in.TemplateID != "" {
+		if _, err := uuid.Parse(*in.TemplateID); err != nil {
+			return fmt.Errorf("%w: bad template_id", ErrInvalidJob)
+		}
+		j.TemplateID = in.TemplateID
+	} else {
+		j.TemplateID = nil
+	}
+	format := strings.ToLower(strings.TrimSpace(in.Format))
+	if format == "" {
+		format = "pdf"
+	}
+	if _, ok := contentTypes[format]; !ok {
+		return fmt.Errorf("%w: format must be one of %s", ErrInvalidJob, strings.Join(Formats, ", "))
+	}
+	for _, id := range in.Filter.TeamIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			return fmt.Errorf("%w: bad team id %q", ErrInvalidJob, id)
+		}
+	}
+	attempts := in.MaxAttempts
+	if attempts == 0 {
+		attempts = 3
+	}
+	if attempts < 1 || attempts > 10 {
+		return fmt.Errorf("%w: max_attempts must be 1-10", ErrInvalidJob)
+	}
+	tz := strings.TrimSpace(in.Timezone)
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalidJob, tz)
+	}
+	spec := strings.TrimSpace(in.Schedule)
+	var sched *Schedule
+	if spec != "" {
+		if sched, err = ParseSchedule(spec); err != nil {
+			return err
+		}
+	}
+	recipients := in.Recipients
+	if len(recipients) == 0 {
+		recipients = []string{j.CreatedBy}
+	}
+	for _, id := range recipients {
+		if _, err := uuid.Parse(id); err != nil {
+			return fmt.Errorf("%w: bad recipient %q", ErrInvalidJob, id)
+		}
+	}
+
+	filter, err := json.Marshal(in.Filter)
+	if err != nil {
+		return err
+	}
+	rcpt, err := json.Marshal(recipients)
+	if err != nil {
+		return err
+	}
+	j.Name, j.Format, j.Timezone, j.Schedule, j.MaxAttempts = name, format, tz, spec, attempts
+	j.Filter, j.Recipients = datatypes.JSON(filter), datatypes.JSON(rcpt)
+	if in.Enabled != nil {
+		j.Enabled = *in.Enabled
+	}
+	j.NextRunAt = nil
+	if sched != nil && j.Enabled {
+		if next := sched.Next(s.now().In(loc)); !next.IsZero() {
+			next = next.UTC()
+			j.NextRunAt = &next
+		}
+	}
+	return nil
+}
+
+func (s *service) CreateJob(actor Actor, in JobInput) (*Job, error) {
+	j := &Job{TenantID: actor.TenantID, TeamID: actor.TeamID, CreatedBy: actor.UserID, Enabled: true}
+	if err := s.apply(j, in); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateJob(j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func (s *service) UpdateJob(actor Actor, jobID string, in JobInput) (*Job, error) {
+	j, err := s.GetJob(actor, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(j, in); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateJob(j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func (s *service) DeleteJob(actor Actor, jobID string) error {
+	if _, err := uuid.Parse(jobID); err != nil {
+		return ErrJobNotFound
+	}
+	return s.repo.DeleteJob(actor.TenantID, jobID)
+}
+
+func (s *service) GetJob(actor Actor, jobID string) (*Job, error) {
+	if _, err := uuid.Parse(jobID); err != nil {
+		return nil, ErrJobNotFound
+	}
+	return s.repo.GetJob(actor.TenantID, jobID)
+}
+
+func (s *service) ListJobs(actor Actor) ([]Job, error) {
+	return s.repo.ListJobs(actor.TenantID)
+}
+
+// ─── Runs ────────────────────────────────────────────────────────────
+
+func (s *service) RunNow(actor Actor, jobID string) (*Run, error) {
+	j, err := s.GetJob(actor, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return s.start(j, TriggerManual, actor.UserID, nil)
+}
+
+func (s *service) RetryFailed(actor Actor, runID string) (*Run, error) {
+	run, err := s.runInTenant(actor, runID)
+	if err != nil {
+		return nil, err
+	}
+	j, err := s.repo.GetJob(actor.TenantID, run.JobID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.repo.ListItems(run.ID)
+	if err != nil {
+		return nil, err
+	}
+	var cases []CaseRef
+	for _, it := range items {
+		if it.Status == ItemFailed {
+			cases = append(cases, CaseRef{ID: it.CaseID, Title: it.CaseTitle, TeamID: it.TeamID})
+		}
+	}
+	if len(cases) == 0 {
+		return nil, ErrNothingToRetry
+	}
+	return s.start(j, TriggerRetry, actor.UserID, cases)
+}
+
+func (s *service) runInTenant(actor Actor, runID string) (*Run, error) {
+	if _, err := uuid.Parse(runID); err != nil {
+		return nil, ErrRunNotFound
+	}
+	return s.repo.GetRun(actor.TenantID, runID)
+}
+
+// canRead reports whether actor may see a job's runs and reports.
+func canRead(actor Actor, j *Job) bool {
+	if isAdmin(actor.Role) || j.CreatedBy == actor.UserID {
+		return true
+	}
+	var recipients []string
+	_ = json.Unmarshal(j.Recipients, &recipients)
+	for _, r := range recipients {
+		if r == actor.UserID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *service) ListRuns(actor Actor, jobID string) ([]Run, error) {
+	j, err := s.GetJob(actor, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if !canRead(actor, j) {
+		return nil, ErrForbidden
+	}
+	return s.repo.ListRuns(actor.TenantID, jobID, 50)
+}
+
+func (s *service) GetRun(actor Actor, runID string) (*RunWithItems, error) {
+	run, err := s.runInTenant(actor, runID)
+	if err != nil {
+		return nil, err
+	}
+	j, err := s.repo.GetJob(actor.TenantID, run.JobID)
+	if err != nil {
+		return nil, err
+	}
+	if !canRead(actor, j) {
+		return nil, ErrForbidden
+	}
+	items, err := s.repo.ListItems(run.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &RunWithItems{Run: *run, Items: items}, nil
+}
+
+func (s *service) Artifact(actor Actor, runID, itemID string) ([]byte, string, string, error) {
+	run, err := s.runInTenant(actor, runID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	j, err := s.repo.GetJob(actor.TenantID, run.JobID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if !canRead(actor, j) {
+		return nil, "", "", ErrForbidden
+	}
+	if _, err := uuid.Parse(itemID); err != nil {
+		return nil, "", "", ErrItemNotFound
+	}
+	it, err := s.repo.GetItem(run.ID, itemID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if it.Status != ItemSucceeded || len(it.Artifact) == 0 {
+		return nil, "", "", ErrNoArtifact
+	}
+	name := fmt.Sprintf("report_%s_%s.%s", it.CaseID, run.CreatedAt.UTC().Format("20060102"), j.Format)
+	return it.Artifact, name, contentTypes[j.Format], nil
+}
+
+// start records a run over cases (by default, every case matching the
+// job's filter) and executes it in the background.
+func (s *service) start(j *Job, trigger, startedBy string, cases []CaseRef) (*Run, error) {
+	if cases == nil {
+		var filter CaseFilter
+		if err := json.Unmarshal(j.Filter, &filter); err != nil {
+			return nil, fmt.Errorf("corrupt filter on job %s: %w", j.ID, err)
+		}
+		var err error
+		if cases, err = s.repo.FindCases(j.TenantID, filter); err != nil {
+			return nil, err
+		}
+	}
+	items := make([]Item, len(cases))
+	for i, c := range cases {
+		items[i] = Item{CaseID: c.ID, CaseTitle: c.Title, TeamID: c.TeamID, Status: ItemPending}
+	}
+	run := &Run{
+		JobID:     j.ID,
+		TenantID:  j.TenantID,
+		Trigger:   trigger,
+		Status:    RunQueued,
+		Total:     len(items),
+		StartedBy: startedBy,
+	}
+	if err := s.repo.CreateRun(run, items); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	ctx := s.ctx
+	s.runs.Add(1)
+	s.mu.Unlock()
+	job := *j
+	go func() {
+		defer s.runs.Done()
+		s.execute(ctx, &job, run, items)
+	}()
+	return run, nil
+}
+
+func (s *service) execute(ctx context.Context, j *Job, run *Run, items []Item) {
+	if err := s.repo.StartRun(run.ID, s.now().UTC()); err != nil {
+		log.Printf("[report-jobs] run %s: %v", run.ID, err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range items {
+		it := &items[i]
+		select {
+		case s.slots <- struct{}{}:
+		case <-ctx.Done():
+			it.Status, it.Error = ItemFailed, "cancelled: server shutting down"
+			s.finishItem(it)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-s.slots; wg.Done() }()
+			s.process(ctx, j, run, it)
+		}()
+	}
+	wg.Wait()
+
+	var failed []Item
+	for _, it := range items {
+		if it.Status != ItemSucceeded {
+			failed = append(failed, it)
+		}
+	}
+	status := RunCompleted
+	switch {
+	case len(items) > 0 && len(failed) == len(items):
+		status = RunFailed
+	case len(failed) > 0:
+		status = RunPartial
+	}
+	if err := s.repo.FinishRun(run.ID, status, s.now().UTC()); err != nil {
+		log.Printf("[report-jobs] run %s: %v", run.ID, err)
+	}
+	s.notify(j, len(items), failed)
+}
+
+// process generates one case's report, retrying with backoff. A retry
+// reuses the report created by an earlier attempt.
+func (s *service) process(ctx context.Context, j *Job, run *Run, it *Item) {
+	for attempt := 1; attempt <= j.MaxAttempts; attempt++ {
+		it.Attempts, it.Status = attempt, ItemRunning
+		if err := s.repo.UpdateItem(it); err != nil {
+			log.Printf("[report-jobs] item %s: %v", it.ID, err)
+		}
+		err := s.generate(ctx, j, run, it)
+		if err == nil {
+			it.Status, it.Error = ItemSucceeded, ""
+			break
+		}
+		it.Status, it.Error = ItemFailed, err.Error()
+		if attempt == j.MaxAttempts || errors.Is(err, report.ErrTemplateNotFound) {
+			break
+		}
+		it.Status = ItemRetrying
+		if err := s.repo.UpdateItem(it); err != nil {
+			log.Printf("[report-jobs] item %s: %v", it.ID, err)
+		}
+		select {
+		case <-time.After(s.retryDelay << (attempt - 1)):
+		case <-ctx.Done():
+			it.Status = ItemFailed
+			attempt = j.MaxAttempts
+		}
+	}
+	s.finishItem(it)
+}
+
+func (s *service) finishItem(it *Item) {
+	done := s.now().UTC()
+	it.DoneAt = &done
+	if err := s.repo.FinishItem(it); err != nil {
+		log.Printf("[report-jobs] item %s: %v", it.ID, err)
+	}
+}
+
+func (s *service) generate(ctx context.Context, j *Job, run *Run, it *Item) error {
+	if it.ReportID == nil {
+		caseID, err := uuid.Parse(it.CaseID)
+		if err != nil {
+			return err
+		}
+		teamID, err := uuid.Parse(it.TeamID)
+		if err != nil {
+			teamID, _ = uuid.Parse(j.TeamID)
+		}
+		examiner, _ := uuid.Parse(run.StartedBy)
+		tenant, _ := uuid.Parse(j.TenantID)
+		template := uuid.Nil
+		if j.TemplateID != nil {
+			template, _ = uuid.Parse(*j.TemplateID)
+		}
+		rep, err := s.reports.GenerateReport(ctx, caseID, examiner, tenant, teamID, template, "")
+		if err != nil {
+			return fmt.Errorf("generating report: %w", err)
+		}
+		id := rep.ID.String()
+		it.ReportID = &id
+		name := fmt.Sprintf("%s - %s", j.Name, run.CreatedAt.UTC().Format("2006-01-02"))
+		if _, err := s.reports.UpdateReportName(ctx, rep.ID, name); err != nil {
+			log.Printf("[report-jobs] naming report %s: %v", id, err)
+		}
+	}
+
+	reportID := uuid.MustParse(*it.ReportID)
+	var (
+		data []byte
+		err  error
+	)
+	switch j.Format {
+	case "docx":
+		data, err = s.reports.DownloadReportAsDOCX(ctx, reportID, s.fields, report.ExportOptions{})
+	case "odt":
+		data, err = s.reports.DownloadReportAsODT(ctx, reportID, s.fields, report.ExportOptions{})
+	default:
+		data, err = s.reports.DownloadReportAsPDF(ctx, reportID, s.fields)
+	}
+	if err != nil {
+		return fmt.Errorf("exporting report: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	it.Artifact, it.SHA256, it.Size = data, hex.EncodeToString(sum[:]), int64(len(data))
+	return nil
+}
+
+// maxListedFailures bounds the failures named in a notification; the run
+// lists them all.
+const maxListedFailures = 10
+
+func (s *service) notify(j *Job, total int, failed []Item) {
+	if s.notifier == nil {
+		return
+	}
+	var recipients []string
+	if err := json.Unmarshal(j.Recipients, &recipients); err != nil {
+		log.Printf("[report-jobs] job %s: corrupt recipients: %v", j.ID, err)
+		return
+	}
+	title := "Report job finished"
+	msg := fmt.Sprintf("%s: %d of %d reports generated.", j.Name, total-len(failed), total)
+	if len(failed) > 0 {
+		title = "Report job finished with errors"
+		names := make([]string, 0, maxListedFailures)
+		for i, it := range failed {
+			if i == maxListedFailures {
+				names = append(names, fmt.Sprintf("and %d more", len(failed)-maxListedFailures))
+				break
+			}
+			names = append(names, fmt.Sprintf("%s (%s)", it.CaseTitle, it.Error))
+		}
+		msg += " Failed: " + strings.Join(names, "; ") + "."
+	}
+	for _, r := range recipients {
+		s.notifier.Notify(r, j.TenantID, j.TeamID, title, msg)
+	}
+}
+
+// ─── Scheduling ──────────────────────────────────────────────────────
+
+func (s *service) Start(ctx context.Context, interval time.Duration) {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+	if err := s.repo.FailInterrupted(s.now().UTC()); err != nil {
+		log.Printf("[report-jobs] recovering interrupted runs: %v", err)
+	}
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-t.C:
+				if _, err := s.RunDue(now); err != nil {
+					log.Printf("[report-jobs] scheduling: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+func (s *service) RunDue(now time.Time) ([]Run, error) {
+	due, err := s.repo.DueJobs(now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	var started []Run
+	for i := range due {
+		j := &due[i]
+		var next *time.Time
+		valid := false
+		if sched, err := ParseSchedule(j.Schedule); err != nil {
+			log.Printf("[report-jobs] job %s: %v; unscheduling", j.ID, err)
+		} else if loc, err := time.LoadLocation(j.Timezone); err != nil {
+			log.Printf("[report-jobs] job %s: %v; unscheduling", j.ID, err)
+		} else {
+			valid = true
+			if n := sched.Next(now.In(loc)); !n.IsZero() {
+				n = n.UTC()
+				next = &n
+			}
+		}
+		// Claiming the slot first keeps a job from running twice when
+		// several API instances share the database.
+		ok, err := s.repo.AdvanceSchedule(j.ID, *j.NextRunAt, next, now.UTC())
+		if err != nil {
+			return started, err
+		}
+		if !ok || !valid {
+			continue
+		}
+		run, err := s.start(j, TriggerSchedule, j.CreatedBy, nil)
+		if err != nil {
+			log.Printf("[report-jobs] job %s: %v", j.ID, err)
+			continue
+		}
+		started = append(started, *run)
+	}
+	return started, nil
+}
+
+func (s *service) Wait() {
+	s.runs.Wait()
+}
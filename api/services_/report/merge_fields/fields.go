@@ -0,0 +1,126 @@
+package merge_fields
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Field is one {{ name arg="value" }} occurrence in section content.
+type Field struct {
+	Raw   string // the placeholder exactly as written
+	Name  string
+	Args  map[string]string
+	Start int // byte offsets of Raw within the content
+	End   int
+	Err   string // set when the placeholder could not be parsed
+}
+
+// Key is the canonical form of the field, used to store frozen values:
+// the name followed by its arguments in sorted order.
+func (f Field) Key() string {
+	if len(f.Args) == 0 {
+		return f.Name
+	}
+	keys := make([]string, 0, len(f.Args))
+	for k := range f.Args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(f.Name)
+	for _, k := range keys {
+		b.WriteString(" " + k + `="` + f.Args[k] + `"`)
+	}
+	return b.String()
+}
+
+// FieldSpec documents a merge field for the editor's insert menu.
+type FieldSpec struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Args        []string `json:"args,omitempty"`
+	Block       bool     `json:"block"` // renders a table rather than inline text
+}
+
+var catalog = []FieldSpec{
+	{Name: "case.id", Description: "Case identifier"},
+	{Name: "case.title", Description: "Case title"},
+	{Name: "case.description", Description: "Case description"},
+	{Name: "case.status", Description: "Case status"},
+	{Name: "case.priority", Description: "Case priority"},
+	{Name: "case.stage", Description: "Current investigation stage"},
+	{Name: "case.team", Description: "Team handling the case"},
+	{Name: "case.created_at", Description: "Date the case was opened"},
+	{Name: "report.name", Description: "Report name"},
+	{Name: "report.number", Description: "Report number"},
+	{Name: "report.status", Description: "Report status"},
+	{Name: "report.version", Description: "Report version"},
+	{Name: "evidence.count", Description: "Number of evidence items", Args: []string{"type"}},
+	{Name: "evidence.table", Description: "Evidence items with type, size, upload date and SHA-256", Args: []string{"type"}, Block: true},
+	{Name: "evidence.hashes", Description: "Hash listing for every evidence item (algo: sha256, sha512 or checksum)", Args: []string{"algo", "type"}, Block: true},
+	{Name: "custody.history", Description: "Chain of custody entries, optionally for one evidence item", Args: []string{"evidence"}, Block: true},
+	{Name: "timeline.count", Description: "Number of timeline events", Args: []string{"tag", "severity"}},
+	{Name: "timeline.excerpt", Description: "Timeline events filtered by tag or severity", Args: []string{"tag", "severity", "limit"}, Block: true},
+	{Name: "ioc.count", Description: "Number of indicators of compromise", Args: []string{"type"}},
+	{Name: "ioc.table", Description: "Indicators of compromise, optionally of one type", Args: []string{"type"}, Block: true},
+}
+
+// Catalog lists the fields the resolver understands.
+func Catalog() []FieldSpec {
+	out := make([]FieldSpec, len(catalog))
+	copy(out, catalog)
+	return out
+}
+
+// Lookup returns the spec for name.
+func Lookup(name string) (FieldSpec, bool) {
+	for _, s := range catalog {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return FieldSpec{}, false
+}
+
+var (
+	placeholderRe = regexp.MustCompile(`\{\{(.*?)\}\}`)
+	nameRe        = regexp.MustCompile(`^[a-z][a-z0-9_]*(\.[a-z][a-z0-9_]*)*$`)
+	argRe         = regexp.MustCompile(`^([a-z][a-z0-9_]*)\s*=\s*"([^"]*)"`)
+)
+
+// ValidName reports whether name is syntactically a field name.
+func ValidName(name string) bool { return nameRe.MatchString(name) }
+
+// Parse finds every placeholder in content. Editors may store quotes as
+// &quot;, so each placeholder is HTML-unescaped before it is parsed.
+func Parse(content string) []Field {
+	var out []Field
+	for _, loc := range placeholderRe.FindAllStringSubmatchIndex(content, -1) {
+		f := Field{Raw: content[loc[0]:loc[1]], Start: loc[0], End: loc[1]}
+		inner := strings.TrimSpace(html.UnescapeString(content[loc[2]:loc[3]]))
+		name, rest, _ := strings.Cut(inner, " ")
+		f.Name = name
+		if !nameRe.MatchString(name) {
+			f.Err = "invalid field name"
+			out = append(out, f)
+			continue
+		}
+		rest = strings.TrimSpace(rest)
+		for rest != "" {
+			m := argRe.FindStringSubmatch(rest)
+			if m == nil {
+				f.Err = `arguments must look like name="value"`
+				break
+			}
+			if f.Args == nil {
+				f.Args = map[string]string{}
+			}
+			f.Args[m[1]] = m[2]
+			rest = strings.TrimSpace(rest[len(m[0]):])
+		}
+		out = append(out, f)
+	}
+	return out
+}
@@ -0,0 +1,95 @@
+package merge_fields_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	graphicalmapping "aegis-api/services_/GraphicalMapping"
+	"aegis-api/services_/chain_of_custody"
+	"aegis-api/services_/evidence/metadata"
+	"aegis-api/services_/report"
+	"aegis-api/services_/report/merge_fields"
+	"aegis-api/services_/timeline"
+	"aegis-api/tests/fakes"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func newResolver(caseID uuid.UUID, cases *fakes.Cases) *merge_fields.Resolver {
+	cases.Title, cases.Team = "Ransomware <ACME>", "DFIR-1"
+	image := metadata.Evidence{
+		ID: uuid.New(), CaseID: caseID, Filename: "WS-042.E01", FileType: "disk_image", FileSize: 3 << 30,
+		Checksum: "ab12", Metadata: `{"sha256":"e3b0c442","sha512":"cf83e135"}`, UploadedAt: time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC),
+	}
+	at := func(hour, minute int) time.Time { return time.Date(2026, 3, 1, hour, minute, 0, 0, time.UTC) }
+	events := &fakes.Timeline{Events: []*timeline.TimelineEvent{
+		{ID: "1", CaseID: caseID.String(), Description: "Phishing mail opened", Severity: "medium", CreatedAt: at(9, 12), Tags: []byte(`["initial-access"]`)},
+		{ID: "2", CaseID: caseID.String(), Description: "PsExec to FS-01", Severity: "high", CreatedAt: at(10, 40), Tags: []byte(`["Lateral-Movement"]`)},
+		{ID: "3", CaseID: caseID.String(), Description: "RDP to DC-01", Severity: "high", CreatedAt: at(11, 5), Tags: []byte(`["lateral-movement"]`)},
+	}}
+	iocs := &fakes.IOCs{Items: []*graphicalmapping.IOC{
+		{CaseID: caseID.String(), Type: "ip", Value: "203.0.113.9"},
+		{CaseID: caseID.String(), Type: "domain", Value: "evil.example"},
+	}}
+	custody := &fakes.Custody{Entries: []chain_of_custody.ChainOfCustody{{EvidenceID: image.ID, Custodian: "J. Doe", AcquisitionTool: "FTK Imager"}}}
+	return merge_fields.NewResolver(&fakes.Evidence{Items: []metadata.Evidence{image}}, events, cases, iocs, custody)
+}
+
+func section(content string) report.ReportSection {
+	return report.ReportSection{ID: [12]byte{1}, Content: content}
+}
+
+func TestParse_ArgumentsAndEscapedQuotes(t *testing.T) {
+	fields := merge_fields.Parse(`<p>{{ timeline.excerpt limit=&quot;5&quot; tag="lateral-movement" }} and {{Bad Name}}</p>`)
+	require.Len(t, fields, 2)
+	require.Equal(t, "timeline.excerpt", fields[0].Name)
+	require.Equal(t, map[string]string{"tag": "lateral-movement", "limit": "5"}, fields[0].Args)
+	require.Equal(t, `timeline.excerpt limit="5" tag="lateral-movement"`, fields[0].Key())
+	require.NotEmpty(t, fields[1].Err)
+}
+
+func TestRenderFields_ResolvesFromCaseData(t *testing.T) {
+	cases := &fakes.Cases{}
+	rep := &report.Report{CaseID: uuid.New(), Name: "Final Report"}
+	content := `<p>{{ case.title }} ({{case.team}}) - {{ report.name }}</p>` +
+		`{{ timeline.excerpt tag="lateral-movement" }}{{ evidence.hashes algo="sha512" }}` +
+		`{{ ioc.count type="ip" }}{{ custody.history }}{{ case.nonexistent }}`
+
+	out, values, err := newResolver(rep.CaseID, cases).RenderFields(context.Background(), rep, []report.ReportSection{section(content)})
+	require.NoError(t, err)
+	html := out[0].Content
+	require.Contains(t, html, "Ransomware &lt;ACME&gt; (DFIR-1) - Final Report")
+	require.Contains(t, html, "PsExec to FS-01")
+	require.Contains(t, html, "RDP to DC-01")
+	require.NotContains(t, html, "Phishing mail opened")
+	require.Contains(t, html, "<td>WS-042.E01</td><td>cf83e135</td>")
+	require.Contains(t, html, "</table>1<table")
+	require.Contains(t, html, "<td>J. Doe</td>")
+	require.Contains(t, html, `class="merge-field-error"`)
+	require.Equal(t, 1, cases.Calls, "case data is loaded once per render")
+	require.Len(t, values[out[0].ID.Hex()], 8)
+}
+
+func TestRenderFields_UsesFrozenValues(t *testing.T) {
+	sec := section(`Team: {{ case.team }}, IOCs: {{ ioc.count }}`)
+	sec.FrozenFields = []report.FrozenField{{Key: "case.team", Value: "DFIR-OLD"}}
+	rep := &report.Report{CaseID: uuid.New()}
+
+	out, _, err := newResolver(rep.CaseID, &fakes.Cases{}).RenderFields(context.Background(), rep, []report.ReportSection{sec})
+	require.NoError(t, err)
+	require.Equal(t, "Team: DFIR-OLD, IOCs: 2", out[0].Content)
+}
+
+func TestRenderFields_ReportsUnavailableSources(t *testing.T) {
+	rep := &report.Report{CaseID: uuid.New()}
+	cases := &fakes.Cases{Err: errors.New("db down")}
+	out, _, err := newResolver(rep.CaseID, cases).RenderFields(context.Background(), rep,
+		[]report.ReportSection{section(`{{ case.title }} / {{ case.status }} / {{ evidence.count }}`)})
+	require.ErrorIs(t, err, report.ErrUnresolvedFields)
+	require.True(t, strings.HasSuffix(out[0].Content, "/ 1"), "other sources still render")
+	require.Equal(t, 1, cases.Calls, "a failed source is not retried within one render")
+}
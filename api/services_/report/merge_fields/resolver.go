@@ -0,0 +1,456 @@
+package merge_fields
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	graphicalmapping "aegis-api/services_/GraphicalMapping"
+	"aegis-api/services_/chain_of_custody"
+	"aegis-api/services_/evidence/metadata"
+	"aegis-api/services_/report"
+	"aegis-api/services_/timeline"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultExcerptLimit = 20
+	maxExcerptLimit     = 200
+	dateLayout          = "2006-01-02 15:04 UTC"
+)
+
+// The lookups match the dependencies handed to the report handler.
+type (
+	EvidenceLookup interface {
+		FindEvidenceByCaseID(caseID uuid.UUID) ([]metadata.Evidence, error)
+	}
+	TimelineLookup interface {
+		ListEvents(caseID string) ([]*timeline.TimelineEventResponse, error)
+	}
+	CaseLookup interface {
+		GetCaseByID(ctx context.Context, caseID string) (any, error)
+	}
+	IOCLookup interface {
+		ListIOCsByCase(caseID string) ([]*graphicalmapping.IOC, error)
+	}
+	CustodyLookup interface {
+		GetEntries(ctx context.Context, evidenceID uuid.UUID) ([]chain_of_custody.ChainOfCustody, error)
+	}
+)
+
+// Resolver implements report.FieldRenderer. Any lookup may be nil, in
+// which case fields that need it render as unavailable.
+type Resolver struct {
+	evidence EvidenceLookup
+	timeline TimelineLookup
+	cases    CaseLookup
+	iocs     IOCLookup
+	custody  CustodyLookup
+}
+
+func NewResolver(evidence EvidenceLookup, timeline TimelineLookup, cases CaseLookup, iocs IOCLookup, custody CustodyLookup) *Resolver {
+	return &Resolver{evidence: evidence, timeline: timeline, cases: cases, iocs: iocs, custody: custody}
+}
+
+var _ report.FieldRenderer = (*Resolver)(nil)
+
+// RenderFields expands every placeholder in sections. Each case source is
+// loaded at most once per call.
+func (r *Resolver) RenderFields(ctx context.Context, rep *report.Report, sections []report.ReportSection) ([]report.ReportSection, map[string][]report.FrozenField, error) {
+	s := &session{r: r, ctx: ctx, rep: rep, cache: map[string]string{}, loaded: map[string]error{}}
+	out := make([]report.ReportSection, len(sections))
+	values := make(map[string][]report.FrozenField, len(sections))
+	var failed []string
+
+	for i, sec := range sections {
+		out[i] = sec
+		fields := Parse(sec.Content)
+		if len(fields) == 0 {
+			continue
+		}
+		frozen := make(map[string]string, len(sec.FrozenFields))
+		for _, f := range sec.FrozenFields {
+			frozen[f.Key] = f.Value
+		}
+
+		var b strings.Builder
+		seen := map[string]bool{}
+		last := 0
+		for _, f := range fields {
+			b.WriteString(sec.Content[last:f.Start])
+			last = f.End
+
+			key := f.Key()
+			val, ok := frozen[key]
+			if !ok {
+				var err error
+				if val, err = s.resolve(f); err != nil {
+					failed = append(failed, fmt.Sprintf("%s: %v", f.Raw, err))
+					val = errorMarker(f, "unavailable")
+				}
+			}
+			b.WriteString(val)
+			if !seen[key] {
+				seen[key] = true
+				values[sec.ID.Hex()] = append(values[sec.ID.Hex()], report.FrozenField{Key: key, Value: val})
+			}
+		}
+		b.WriteString(sec.Content[last:])
+		out[i].Content = b.String()
+	}
+
+	if len(failed) > 0 {
+		return out, values, fmt.Errorf("%w: %s", report.ErrUnresolvedFields, strings.Join(failed, "; "))
+	}
+	return out, values, nil
+}
+
+// session caches the case sources for one render.
+type session struct {
+	r     *Resolver
+	ctx   context.Context
+	rep   *report.Report
+	cache map[string]string
+
+	loaded   map[string]error // source -> outcome of its one load
+	caseInfo map[string]any
+	evidence []metadata.Evidence
+	events   []*timeline.TimelineEventResponse
+	iocs     []*graphicalmapping.IOC
+}
+
+func (s *session) load(source string, fn func() error) error {
+	err, ok := s.loaded[source]
+	if !ok {
+		err = fn()
+		s.loaded[source] = err
+	}
+	return err
+}
+
+// resolve returns the HTML for f. Author mistakes such as unknown fields
+// render as a marker; an error means case data could not be loaded.
+func (s *session) resolve(f Field) (string, error) {
+	if f.Err != "" {
+		return errorMarker(f, f.Err), nil
+	}
+	if _, ok := Lookup(f.Name); !ok {
+		return errorMarker(f, "unknown field"), nil
+	}
+	key := f.Key()
+	if v, ok := s.cache[key]; ok {
+		return v, nil
+	}
+
+	group, _, _ := strings.Cut(f.Name, ".")
+	var (
+		v   string
+		err error
+	)
+	switch group {
+	case "case":
+		v, err = s.caseField(f)
+	case "report":
+		v = s.reportField(f)
+	case "evidence":
+		v, err = s.evidenceField(f)
+	case "custody":
+		v, err = s.custodyHistory(f)
+	case "timeline":
+		v, err = s.timelineField(f)
+	case "ioc":
+		v, err = s.iocField(f)
+	}
+	if err != nil {
+		return "", err
+	}
+	s.cache[key] = v
+	return v, nil
+}
+
+func (s *session) caseField(f Field) (string, error) {
+	err := s.load("case", func() error {
+		if s.r.cases == nil {
+			return fmt.Errorf("case lookup not configured")
+		}
+		obj, err := s.r.cases.GetCaseByID(s.ctx, s.rep.CaseID.String())
+		if err != nil {
+			return err
+		}
+		raw, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(raw, &s.caseInfo)
+	})
+	if err != nil {
+		return "", err
+	}
+	column := map[string]string{
+		"case.id":          "id",
+		"case.title":       "title",
+		"case.description": "description",
+		"case.status":      "status",
+		"case.priority":    "priority",
+		"case.stage":       "investigation_stage",
+		"case.team":        "team_name",
+		"case.created_at":  "created_at",
+	}[f.Name]
+	v := s.caseInfo[column]
+	if f.Name == "case.created_at" {
+		if str, ok := v.(string); ok {
+			if t, err := time.Parse(time.RFC3339Nano, str); err == nil {
+				return t.UTC().Format("2006-01-02"), nil
+			}
+		}
+	}
+	if v == nil {
+		return "", nil
+	}
+	return html.EscapeString(fmt.Sprint(v)), nil
+}
+
+func (s *session) reportField(f Field) string {
+	var v string
+	switch f.Name {
+	case "report.name":
+		v = s.rep.Name
+	case "report.number":
+		v = s.rep.ReportNumber
+	case "report.status":
+		v = s.rep.Status
+	case "report.version":
+		v = strconv.Itoa(s.rep.Version)
+	}
+	return html.EscapeString(v)
+}
+
+func (s *session) loadEvidence() error {
+	return s.load("evidence", func() error {
+		if s.r.evidence == nil {
+			return fmt.Errorf("evidence lookup not configured")
+		}
+		var err error
+		s.evidence, err = s.r.evidence.FindEvidenceByCaseID(s.rep.CaseID)
+		return err
+	})
+}
+
+func (s *session) filteredEvidence(f Field) []metadata.Evidence {
+	var out []metadata.Evidence
+	for _, ev := range s.evidence {
+		if t := f.Args["type"]; t != "" && !strings.EqualFold(ev.FileType, t) {
+			continue
+		}
+		out = append(out, ev)
+	}
+	return out
+}
+
+func (s *session) evidenceField(f Field) (string, error) {
+	if err := s.loadEvidence(); err != nil {
+		return "", err
+	}
+	items := s.filteredEvidence(f)
+	switch f.Name {
+	case "evidence.count":
+		return strconv.Itoa(len(items)), nil
+	case "evidence.hashes":
+		algo := strings.ToLower(f.Args["algo"])
+		if algo == "" {
+			algo = "sha256"
+		}
+		if algo != "sha256" && algo != "sha512" && algo != "checksum" {
+			return errorMarker(f, "algo must be sha256, sha512 or checksum"), nil
+		}
+		rows := make([][]string, 0, len(items))
+		for _, ev := range items {
+			rows = append(rows, []string{ev.Filename, evidenceHash(ev, algo)})
+		}
+		return table(f, []string{"Evidence", strings.ToUpper(algo)}, rows, "No evidence recorded."), nil
+	default: // evidence.table
+		rows := make([][]string, 0, len(items))
+		for _, ev := range items {
+			rows = append(rows, []string{
+				ev.Filename, ev.FileType, formatSize(ev.FileSize),
+				ev.UploadedAt.UTC().Format(dateLayout), evidenceHash(ev, "sha256"),
+			})
+		}
+		return table(f, []string{"Evidence", "Type", "Size", "Uploaded", "SHA256"}, rows, "No evidence recorded."), nil
+	}
+}
+
+func (s *session) custodyHistory(f Field) (string, error) {
+	if s.r.custody == nil {
+		return "", fmt.Errorf("custody lookup not configured")
+	}
+	if err := s.loadEvidence(); err != nil {
+		return "", err
+	}
+	var items []metadata.Evidence
+	for _, ev := range s.evidence {
+		if id := f.Args["evidence"]; id != "" && ev.ID.String() != id && ev.Filename != id {
+			continue
+		}
+		items = append(items, ev)
+	}
+	var rows [][]string
+	for _, ev := range items {
+		entries, err := s.r.custody.GetEntries(s.ctx, ev.ID)
+		if err != nil {
+			return "", err
+		}
+		for _, e := range entries {
+			acquired := ""
+			if e.AcquisitionDate != nil {
+				acquired = e.AcquisitionDate.UTC().Format(dateLayout)
+			}
+			rows = append(rows, []string{ev.Filename, e.Custodian, acquired, e.AcquisitionTool, e.CreatedAt.UTC().Format(dateLayout)})
+		}
+	}
+	return table(f, []string{"Evidence", "Custodian", "Acquired", "Tool", "Recorded"}, rows, "No chain of custody entries recorded."), nil
+}
+
+func (s *session) timelineField(f Field) (string, error) {
+	err := s.load("timeline", func() error {
+		if s.r.timeline == nil {
+			return fmt.Errorf("timeline lookup not configured")
+		}
+		var err error
+		s.events, err = s.r.timeline.ListEvents(s.rep.CaseID.String())
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var matched []*timeline.TimelineEventResponse
+	for _, ev := range s.events {
+		if sev := f.Args["severity"]; sev != "" && !strings.EqualFold(ev.Severity, sev) {
+			continue
+		}
+		if tag := f.Args["tag"]; tag != "" && !hasTag(ev.Tags, tag) {
+			continue
+		}
+		matched = append(matched, ev)
+	}
+	if f.Name == "timeline.count" {
+		return strconv.Itoa(len(matched)), nil
+	}
+
+	limit := defaultExcerptLimit
+	if raw := f.Args["limit"]; raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			return errorMarker(f, "limit must be a positive number"), nil
+		}
+		limit = min(n, maxExcerptLimit)
+	}
+	if len(matched) > limit {
+		matched = matched[:limit]
+	}
+	rows := make([][]string, 0, len(matched))
+	for _, ev := range matched {
+		rows = append(rows, []string{ev.Date + " " + ev.Time, ev.Severity, ev.Description, ev.AnalystName})
+	}
+	return table(f, []string{"When", "Severity", "Event", "Analyst"}, rows, "No matching timeline events."), nil
+}
+
+func (s *session) iocField(f Field) (string, error) {
+	err := s.load("ioc", func() error {
+		if s.r.iocs == nil {
+			return fmt.Errorf("IOC lookup not configured")
+		}
+		var err error
+		s.iocs, err = s.r.iocs.ListIOCsByCase(s.rep.CaseID.String())
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	var matched []*graphicalmapping.IOC
+	for _, ioc := range s.iocs {
+		if t := f.Args["type"]; t != "" && !strings.EqualFold(ioc.Type, t) {
+			continue
+		}
+		matched = append(matched, ioc)
+	}
+	if f.Name == "ioc.count" {
+		return strconv.Itoa(len(matched)), nil
+	}
+	rows := make([][]string, 0, len(matched))
+	for _, ioc := range matched {
+		rows = append(rows, []string{ioc.Type, ioc.Value, ioc.CreatedAt.UTC().Format(dateLayout)})
+	}
+	return table(f, []string{"Type", "Value", "Recorded"}, rows, "No indicators of compromise recorded."), nil
+}
+
+func evidenceHash(ev metadata.Evidence, algo string) string {
+	if algo == "checksum" {
+		return ev.Checksum
+	}
+	var meta map[string]any
+	if err := json.Unmarshal([]byte(ev.Metadata), &meta); err == nil {
+		if h, ok := meta[algo].(string); ok {
+			return h
+		}
+	}
+	return ""
+}
+
+func hasTag(raw []byte, tag string) bool {
+	var tags []string
+	if err := json.Unmarshal(raw, &tags); err != nil {
+		return false
+	}
+	for _, t := range tags {
+		if strings.EqualFold(strings.TrimSpace(t), tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+func table(f Field, headers []string, rows [][]string, empty string) string {
+	attr := html.EscapeString(f.Key())
+	if len(rows) == 0 {
+		return `<p class="merge-field-empty" data-field="` + attr + `">` + html.EscapeString(empty) + `</p>`
+	}
+	var b strings.Builder
+	b.WriteString(`<table class="merge-field" data-field="` + attr + `"><thead><tr>`)
+	for _, h := range headers {
+		b.WriteString("<th>" + html.EscapeString(h) + "</th>")
+	}
+	b.WriteString("</tr></thead><tbody>")
+	for _, row := range rows {
+		b.WriteString("<tr>")
+		for _, cell := range row {
+			b.WriteString("<td>" + html.EscapeString(cell) + "</td>")
+		}
+		b.WriteString("</tr>")
+	}
+	b.WriteString("</tbody></table>")
+	return b.String()
+}
+
+func errorMarker(f Field, msg string) string {
+	return `<span class="merge-field-error" data-field="` + html.EscapeString(f.Raw) + `">[` +
+		html.EscapeString(f.Name+": "+msg) + `]</span>`
+}
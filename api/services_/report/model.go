@@ -14,6 +14,9 @@ var (
 	ErrMongoReportNotFound = errors.New("mongo report not found")
 	ErrSectionNotFound     = errors.New("section not found")
 	ErrInvalidInput        = errors.New("invalid input")
+	// ErrReportLocked is returned for edits while a report is under review
+	// or after it was published.
+	ErrReportLocked = errors.New("report cannot be edited in its current status")
 )
 
 type Report struct {
@@ -32,8 +35,13 @@ type Report struct {
 	Version      int       `gorm:"not null;default:1" json:"version"`
 	DateExamined time.Time `gorm:"type:date" json:"date_examined"`
 	FilePath     string    `gorm:"type:varchar(255);not null" json:"file_path"`
-	CreatedAt    time.Time `gorm:"type:timestamp;default:current_timestamp" json:"created_at"`
-	UpdatedAt    time.Time `gorm:"type:timestamp;default:current_timestamp;index:idx_reports_tenant_team_updated,priority:3" json:"updated_at"`
+	// Template the report was laid out from; nil for the built-in layout.
+	TemplateID      *uuid.UUID `gorm:"type:uuid" json:"template_id,omitempty"`
+	TemplateVersion int        `gorm:"not null;default:1" json:"template_version"`
+	// Set while merge fields render from frozen values rather than live data.
+	FieldsFrozenAt *time.Time `gorm:"type:timestamp" json:"fields_frozen_at,omitempty"`
+	CreatedAt      time.Time  `gorm:"type:timestamp;default:current_timestamp" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"type:timestamp;default:current_timestamp;index:idx_reports_tenant_team_updated,priority:3" json:"updated_at"`
 }
 
 // ReportInterface defines the methods for managing reports.
@@ -21,6 +21,32 @@ type ReportSection struct {
 	Order     int                `bson:"order" json:"order"`
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	// Required sections come from the report template and cannot be deleted.
+	Required bool `bson:"required,omitempty" json:"required,omitempty"`
+	// Provenance records every machine-drafted block accepted into Content.
+	Provenance []SectionProvenance `bson:"provenance,omitempty" json:"provenance,omitempty"`
+	// FrozenFields pins merge field values captured when fields were frozen.
+	FrozenFields []FrozenField `bson:"frozen_fields,omitempty" json:"frozen_fields,omitempty"`
+}
+
+// SectionProvenance describes one accepted AI draft: who accepted it, and
+// which evidence, timeline events and IOCs each sentence was based on.
+type SectionProvenance struct {
+	SuggestionID string            `bson:"suggestion_id" json:"suggestion_id"`
+	Origin       string            `bson:"origin" json:"origin"` // "ai_draft"
+	AcceptedBy   string            `bson:"accepted_by" json:"accepted_by"`
+	AcceptedAt   time.Time         `bson:"accepted_at" json:"accepted_at"`
+	Claims       []ProvenanceClaim `bson:"claims" json:"claims"`
+}
+
+type ProvenanceClaim struct {
+	Text string          `bson:"text" json:"text"`
+	Refs []ProvenanceRef `bson:"refs" json:"refs"`
+}
+
+type ProvenanceRef struct {
+	Type string `bson:"type" json:"type"` // evidence, timeline_event, ioc
+	ID   string `bson:"id" json:"id"`
 }
 
 // Full report content document
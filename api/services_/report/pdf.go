@@ -0,0 +1,157 @@
+package report
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"aegis-api/services_/report/pdfrender"
+
+	"github.com/google/uuid"
+)
+
+// DefaultClassification marks reports whose case carries no classification.
+const DefaultClassification = "CONFIDENTIAL"
+
+// MarkingResolver returns the classification marking of a labelled
+// report, or "" when it carries no label.
+type MarkingResolver interface {
+	ReportMarking(ctx context.Context, reportID uuid.UUID) (string, error)
+}
+
+// PDFOptions adjusts RenderPDF.
+type PDFOptions struct {
+	// CreatedAt fixes the document dates so the same content always renders
+	// to the same bytes; zero uses the current time.
+	CreatedAt time.Time
+	// Footer is printed at the bottom left of every page.
+	Footer string
+	// Certification, when set, is printed as a final appendix headed
+	// "Examiner certification".
+	Certification string
+	// Classification is printed at the top and bottom of every page;
+	// empty uses DefaultClassification.
+	Classification string
+	// CaseReference heads every page after the title page.
+	CaseReference string
+	// Appendices are printed after the report sections, such as the
+	// exhibits from CaseExhibits.
+	Appendices []pdfrender.Section
+	// Watermark is printed across every page; empty prints none.
+	Watermark string
+}
+
+type embeddedImage struct {
+	Mimetype string
+	Data     []byte
+}
+
+var imgTagRe = regexp.MustCompile(`(?i)<img[^>]+src=["']data:(image/(?:png|jpeg|jpg));base64,([^"']+)["'][^>]*>`)
+
+func extractDataURLImages(html string) (cleanHTML string, imgs []embeddedImage) {
+	out := imgTagRe.ReplaceAllStringFunc(html, func(tag string) string {
+		m := imgTagRe.FindStringSubmatch(tag)
+		if len(m) != 3 {
+			return ""
+		}
+		mime := m[1]
+		b64 := strings.NewReplacer(" ", "", "\n", "").Replace(m[2])
+		data, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil {
+			return ""
+		}
+		imgs = append(imgs, embeddedImage{Mimetype: mime, Data: data})
+		return "" // strip <img> from HTML; images are rendered separately
+	})
+	return out, imgs
+}
+
+// RenderPDF lays out a rendered report as an A4 PDF with a title page,
+// table of contents, running headers and footers and any appendices.
+func RenderPDF(rpt *ReportWithContent, opts PDFOptions) ([]byte, error) {
+	meta := rpt.Metadata
+	classification := opts.Classification
+	if classification == "" {
+		classification = DefaultClassification
+	}
+	doc := pdfrender.Document{
+		Title:          meta.Name,
+		Subtitle:       opts.CaseReference,
+		Classification: classification,
+		HeaderLeft:     opts.CaseReference,
+		HeaderRight:    fmt.Sprintf("%s v%d", meta.ReportNumber, meta.Version),
+		FooterLeft:     opts.Footer,
+		Watermark:      opts.Watermark,
+		TOC:            true,
+		CreatedAt:      opts.CreatedAt,
+		Appendices:     opts.Appendices,
+	}
+	doc.Meta = [][2]string{
+		{"Report number", meta.ReportNumber},
+		{"Version", fmt.Sprintf("%d", meta.Version)},
+		{"Status", meta.Status},
+		{"Classification", classification},
+	}
+	if !meta.DateExamined.IsZero() {
+		doc.Meta = append(doc.Meta, [2]string{"Date examined", meta.DateExamined.Format("2006-01-02")})
+	}
+	for _, sec := range rpt.Content {
+		doc.Sections = append(doc.Sections, pdfrender.Section{Title: sec.Title, HTML: sec.Content})
+	}
+	if opts.Certification != "" {
+		doc.Appendices = append(doc.Appendices[:len(doc.Appendices):len(doc.Appendices)], pdfrender.Section{
+			Title: "Examiner certification",
+			HTML:  "<p>" + html.EscapeString(opts.Certification) + "</p>",
+		})
+	}
+	return pdfrender.Render(doc)
+}
+
+// CaseExhibits resolves the case reference printed in page headers and the
+// exhibit appendices (evidence register and chain of custody) for a report.
+// Values the report's sections froze are reused, so a published report
+// prints the exhibits as they were when it was published. A nil renderer
+// or a failed lookup falls back to the case ID and no appendices.
+func CaseExhibits(ctx context.Context, fields FieldRenderer, rep *Report, sections []ReportSection) (caseRef string, appendices []pdfrender.Section) {
+	caseRef = "Case " + rep.CaseID.String()
+	if fields == nil {
+		return caseRef, nil
+	}
+	var frozen []FrozenField
+	for _, sec := range sections {
+		frozen = append(frozen, sec.FrozenFields...)
+	}
+	synthetic := []ReportSection{
+		{Title: "Case", Content: "{{ case.title }}", FrozenFields: frozen},
+		{Title: "Evidence exhibits", Content: "{{ evidence.table }}", FrozenFields: frozen},
+		{Title: "Chain of custody", Content: "{{ custody.history }}", FrozenFields: frozen},
+	}
+	out, _, err := fields.RenderFields(ctx, rep, synthetic)
+	if err != nil && len(out) != len(synthetic) {
+		return caseRef, nil
+	}
+	if title := plainFieldValue(out[0]); title != "" {
+		caseRef = "Case: " + title
+	}
+	for _, sec := range out[1:] {
+		if !strings.Contains(sec.Content, "merge-field-error") {
+			appendices = append(appendices, pdfrender.Section{Title: sec.Title, HTML: sec.Content})
+		}
+	}
+	return caseRef, appendices
+}
+
+var tagRe = regexp.MustCompile(`<[^>]*>`)
+
+// plainFieldValue returns the text of the single inline field rendered in
+// sec, or "" if it failed.
+func plainFieldValue(sec ReportSection) string {
+	if strings.Contains(sec.Content, "merge-field-error") {
+		return ""
+	}
+	return strings.TrimSpace(html.UnescapeString(tagRe.ReplaceAllString(sec.Content, "")))
+}
@@ -0,0 +1,125 @@
+// Package pdfrender lays out reports written in the editor's HTML as
+// paginated PDF documents.
+//
+// It understands the subset of HTML and inline CSS the rich-text editor and
+// merge fields produce: headings, paragraphs, inline emphasis and links,
+// ordered and unordered lists, tables with header rows and column spans,
+// block quotes, preformatted text and data: URL images. Output is
+// deterministic: the same Document always renders to the same bytes.
+package pdfrender
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Document is everything printed in a report PDF.
+type Document struct {
+	Title    string
+	Subtitle string
+	// Meta is printed on the title page as label/value pairs, in order.
+	Meta [][2]string
+
+	// Classification is printed at the top and bottom of every page.
+	Classification string
+	// HeaderLeft and HeaderRight run along the top of every page after the
+	// title page, typically the case reference and report number.
+	HeaderLeft  string
+	HeaderRight string
+	// FooterLeft runs along the bottom of every page; the right side
+	// carries "Page X of Y".
+	FooterLeft string
+	// Watermark, when set, is printed diagonally across every page,
+	// beneath the content.
+	Watermark string
+
+	// TOC adds a table of contents after the title page listing sections,
+	// their first two heading levels and the appendices.
+	TOC bool
+
+	Sections []Section
+	// Appendices follow the sections, each on a new page, lettered A, B, ...
+	Appendices []Section
+
+	// CreatedAt is recorded as the PDF's creation and modification date.
+	// It must be set for output to be reproducible.
+	CreatedAt time.Time
+}
+
+// Section is a titled block of editor HTML.
+type Section struct {
+	Title string
+	HTML  string
+}
+
+// Render lays out doc as an A4 PDF.
+func Render(doc Document) ([]byte, error) {
+	parsed := make([][]block, len(doc.Sections))
+	for i, s := range doc.Sections {
+		parsed[i] = parseHTML(s.HTML)
+	}
+	appendices := make([][]block, len(doc.Appendices))
+	for i, s := range doc.Appendices {
+		appendices[i] = parseHTML(s.HTML)
+	}
+
+	// The table of contents needs the page of every entry, which is only
+	// known once the body is laid out. Every entry takes exactly one line,
+	// so the second pass paginates exactly like the first.
+	out, pages, err := render(&doc, parsed, appendices, nil)
+	if err != nil || !doc.TOC {
+		return out, err
+	}
+	out, _, err = render(&doc, parsed, appendices, pages)
+	return out, err
+}
+
+func render(doc *Document, sections, appendices [][]block, tocPages []int) ([]byte, []int, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	created := doc.CreatedAt
+	if created.IsZero() {
+		created = time.Now()
+	}
+	pdf.SetCreationDate(created.UTC())
+	pdf.SetModificationDate(created.UTC())
+	pdf.SetCatalogSort(true)
+	pdf.SetProducer("AEGIS report renderer", false)
+	pdf.SetTitle(doc.Title, true)
+	pdf.SetCompression(true)
+
+	w := newWriter(pdf, doc)
+	w.tocPages = tocPages
+	w.titlePage()
+	if doc.TOC {
+		w.contents(sections, appendices)
+	}
+	for i, s := range doc.Sections {
+		if i == 0 {
+			w.newPage()
+		}
+		w.section(fmt.Sprintf("%d. %s", i+1, s.Title), sections[i])
+	}
+	for i, s := range doc.Appendices {
+		w.newPage()
+		w.section(fmt.Sprintf("Appendix %s - %s", appendixLetter(i), s.Title), appendices[i])
+	}
+
+	if err := pdf.Error(); err != nil {
+		return nil, nil, fmt.Errorf("pdf layout: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, nil, fmt.Errorf("pdf output: %w", err)
+	}
+	return buf.Bytes(), w.entryPages, nil
+}
+
+func appendixLetter(i int) string {
+	if i < 26 {
+		return string(rune('A' + i))
+	}
+	return appendixLetter(i/26-1) + string(rune('A'+i%26))
+}
@@ -0,0 +1,801 @@
+package pdfrender
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	stdimage "image"
+	_ "image/gif" // registers decoders for DecodeConfig
+	"image/jpeg"
+	"image/png"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+const (
+	pageW        = 210.0
+	pageH        = 297.0
+	marginX      = 20.0
+	marginTop    = 25.0
+	marginBottom = 22.0
+	textW        = pageW - 2*marginX
+	watermarkW   = 250.0 // longest watermark along the page diagonal
+
+	bodySize   = 10.0 // points
+	smallSize  = 8.0
+	lineFactor = 1.4
+	listIndent = 6.0
+	cellPad    = 1.6
+	ptToMM     = 25.4 / 72
+)
+
+var headingSizes = map[int]float64{1: 15, 2: 13, 3: 11.5, 4: 10.5, 5: 10, 6: 10}
+
+// tocEntry is a line of the table of contents.
+type tocEntry struct {
+	level int
+	title string
+}
+
+type writer struct {
+	pdf *gofpdf.Fpdf
+	doc *Document
+	tr  func(string) string
+
+	y      float64
+	indent float64 // left offset of the current container
+	// marker is a list marker waiting to be printed beside the next line.
+	marker string
+
+	links      []int
+	entry      int   // index of the next TOC entry to be laid out
+	entryPages []int // page each TOC entry landed on
+	tocPages   []int // pages from the previous pass, or nil
+}
+
+func newWriter(pdf *gofpdf.Fpdf, doc *Document) *writer {
+	w := &writer{pdf: pdf, doc: doc, tr: pdf.UnicodeTranslatorFromDescriptor("")}
+	pdf.SetMargins(marginX, marginTop, marginX)
+	pdf.SetAutoPageBreak(false, marginBottom)
+	pdf.AliasNbPages("{nb}")
+	pdf.SetHeaderFuncMode(w.header, false)
+	pdf.SetFooterFunc(w.footer)
+	return w
+}
+
+func (w *writer) header() {
+	pdf := w.pdf
+	// The header is drawn before the page content, which keeps the
+	// watermark underneath it.
+	if w.doc.Watermark != "" {
+		w.font(style{bold: true}, 40)
+		pdf.SetTextColor(225, 225, 225)
+		text := w.tr(w.doc.Watermark)
+		width := pdf.GetStringWidth(text)
+		// Long marks are shrunk to fit along the diagonal.
+		if width > watermarkW {
+			w.font(style{bold: true}, 40*watermarkW/width)
+			width = pdf.GetStringWidth(text)
+		}
+		pdf.TransformBegin()
+		pdf.TransformRotate(45, pageW/2, pageH/2)
+		pdf.Text(pageW/2-width/2, pageH/2, text)
+		pdf.TransformEnd()
+	}
+	if w.doc.Classification != "" {
+		w.font(style{bold: true}, smallSize)
+		pdf.SetTextColor(170, 0, 0)
+		pdf.SetXY(marginX, 8)
+		pdf.CellFormat(textW, 4, w.tr(strings.ToUpper(w.doc.Classification)), "", 0, "C", false, 0, "")
+	}
+	if pdf.PageNo() > 1 && (w.doc.HeaderLeft != "" || w.doc.HeaderRight != "") {
+		w.font(style{}, smallSize)
+		pdf.SetTextColor(90, 90, 90)
+		pdf.SetXY(marginX, 13)
+		pdf.CellFormat(textW/2, 4, w.tr(w.fit(w.doc.HeaderLeft, textW/2-2)), "", 0, "L", false, 0, "")
+		pdf.CellFormat(textW/2, 4, w.tr(w.fit(w.doc.HeaderRight, textW/2-2)), "", 0, "R", false, 0, "")
+		pdf.SetDrawColor(180, 180, 180)
+		pdf.SetLineWidth(0.2)
+		pdf.Line(marginX, 18, pageW-marginX, 18)
+	}
+	pdf.SetTextColor(0, 0, 0)
+}
+
+func (w *writer) footer() {
+	pdf := w.pdf
+	pdf.SetDrawColor(180, 180, 180)
+	pdf.SetLineWidth(0.2)
+	pdf.Line(marginX, pageH-17, pageW-marginX, pageH-17)
+	w.font(style{}, smallSize)
+	pdf.SetTextColor(90, 90, 90)
+	pdf.SetXY(marginX, pageH-16)
+	pdf.CellFormat(textW*0.7, 4, w.tr(w.fit(w.doc.FooterLeft, textW*0.7-2)), "", 0, "L", false, 0, "")
+	pdf.CellFormat(textW*0.3, 4, "Page "+strconv.Itoa(pdf.PageNo())+" of {nb}", "", 0, "R", false, 0, "")
+	if w.doc.Classification != "" {
+		w.font(style{bold: true}, smallSize)
+		pdf.SetTextColor(170, 0, 0)
+		pdf.SetXY(marginX, pageH-11)
+		pdf.CellFormat(textW, 4, w.tr(strings.ToUpper(w.doc.Classification)), "", 0, "C", false, 0, "")
+	}
+	pdf.SetTextColor(0, 0, 0)
+}
+
+func (w *writer) newPage() {
+	w.pdf.AddPage()
+	w.y = marginTop
+}
+
+// ensure starts a new page unless h millimetres fit below the cursor.
+func (w *writer) ensure(h float64) {
+	if w.y+h > pageH-marginBottom && w.y > marginTop {
+		w.newPage()
+	}
+}
+
+func (w *writer) font(st style, size float64) {
+	family := "Helvetica"
+	if st.mono {
+		family = "Courier"
+	}
+	s := ""
+	if st.bold {
+		s += "B"
+	}
+	if st.italic {
+		s += "I"
+	}
+	if st.underline {
+		s += "U"
+	}
+	w.pdf.SetFont(family, s, size)
+}
+
+func (w *writer) width(text string, st style, size float64) float64 {
+	w.font(st, size)
+	return w.pdf.GetStringWidth(w.tr(text))
+}
+
+// fit shortens text with an ellipsis until it fits in width at the current
+// font.
+func (w *writer) fit(text string, width float64) string {
+	if w.pdf.GetStringWidth(w.tr(text)) <= width {
+		return text
+	}
+	r := []rune(text)
+	for len(r) > 0 && w.pdf.GetStringWidth(w.tr(string(r)+"...")) > width {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "..."
+}
+
+func lineHeight(size float64) float64 { return size * ptToMM * lineFactor }
+
+// ─── Title page and contents ─────────────────────────────
+
+func (w *writer) titlePage() {
+	w.newPage()
+	pdf := w.pdf
+	w.y = 80
+	w.flow([]run{{text: w.doc.Title, st: style{bold: true}}}, 20, "C", false)
+	if w.doc.Subtitle != "" {
+		w.y += 2
+		w.flow([]run{{text: w.doc.Subtitle}}, 13, "C", false)
+	}
+	w.y += 12
+	for _, kv := range w.doc.Meta {
+		if kv[1] == "" {
+			continue
+		}
+		w.font(style{bold: true}, bodySize)
+		pdf.SetXY(marginX+20, w.y)
+		pdf.CellFormat(45, lineHeight(bodySize), w.tr(kv[0]), "", 0, "L", false, 0, "")
+		w.font(style{}, bodySize)
+		pdf.CellFormat(textW-65, lineHeight(bodySize), w.tr(w.fit(kv[1], textW-67)), "", 0, "L", false, 0, "")
+		w.y += lineHeight(bodySize) + 1
+	}
+}
+
+// contents prints the table of contents. Entries are listed from the
+// parsed document so their number and order match the body exactly.
+func (w *writer) contents(sections, appendices [][]block) {
+	var entries []tocEntry
+	for i, s := range w.doc.Sections {
+		entries = append(entries, tocEntry{1, strconv.Itoa(i+1) + ". " + s.Title})
+		entries = appendHeadings(entries, sections[i])
+	}
+	for i, s := range w.doc.Appendices {
+		entries = append(entries, tocEntry{1, "Appendix " + appendixLetter(i) + " - " + s.Title})
+		entries = appendHeadings(entries, appendices[i])
+	}
+	w.entryPages = make([]int, len(entries))
+
+	w.newPage()
+	w.flow([]run{{text: "Contents", st: style{bold: true}}}, headingSizes[1], "L", false)
+	w.y += 3
+	pdf := w.pdf
+	lh := lineHeight(bodySize)
+	for i, e := range entries {
+		w.ensure(lh)
+		link := pdf.AddLink()
+		w.links = append(w.links, link)
+		x := marginX + float64(e.level-1)*listIndent
+		page := ""
+		if i < len(w.tocPages) {
+			page = strconv.Itoa(w.tocPages[i])
+		}
+		st := style{bold: e.level == 1}
+		w.font(st, bodySize)
+		numW := 12.0
+		title := w.fit(e.title, pageW-marginX-numW-x-4)
+		titleW := pdf.GetStringWidth(w.tr(title))
+		pdf.SetXY(x, w.y)
+		pdf.CellFormat(titleW, lh, w.tr(title), "", 0, "L", false, link, "")
+		// Dot leaders run from the title to the page number.
+		w.font(style{}, bodySize)
+		dotW := pdf.GetStringWidth(".")
+		if gap := pageW - marginX - numW - (x + titleW) - 2; gap > dotW {
+			pdf.SetTextColor(150, 150, 150)
+			pdf.CellFormat(gap, lh, strings.Repeat(".", int(gap/dotW)), "", 0, "R", false, 0, "")
+			pdf.SetTextColor(0, 0, 0)
+		}
+		pdf.SetXY(pageW-marginX-numW, w.y)
+		pdf.CellFormat(numW, lh, page, "", 0, "R", false, link, "")
+		w.y += lh
+	}
+}
+
+func appendHeadings(entries []tocEntry, blocks []block) []tocEntry {
+	for _, b := range blocks {
+		if h, ok := b.(*heading); ok && h.level <= 2 {
+			entries = append(entries, tocEntry{2, plainText(h.runs)})
+		}
+	}
+	return entries
+}
+
+func plainText(runs []run) string {
+	var b strings.Builder
+	for _, r := range runs {
+		b.WriteString(strings.ReplaceAll(r.text, "\n", " "))
+	}
+	return b.String()
+}
+
+// anchor marks the current position as the target of the next TOC entry.
+func (w *writer) anchor(title string, level int) {
+	w.pdf.Bookmark(truncateRunes(title, 80), level, w.y)
+	if w.entry < len(w.links) {
+		w.pdf.SetLink(w.links[w.entry], w.y, -1)
+		w.entryPages[w.entry] = w.pdf.PageNo()
+	}
+	w.entry++
+}
+
+// ─── Body ────────────────────────────────────────────────
+
+func (w *writer) section(title string, blocks []block) {
+	size := headingSizes[1]
+	w.ensure(lineHeight(size) + 2*lineHeight(bodySize))
+	w.anchor(title, 0)
+	w.flow([]run{{text: title, st: style{bold: true}}}, size, "L", false)
+	w.pdf.SetDrawColor(60, 60, 60)
+	w.pdf.SetLineWidth(0.3)
+	w.pdf.Line(marginX, w.y+0.5, pageW-marginX, w.y+0.5)
+	w.y += 4
+	if len(blocks) == 0 {
+		blocks = []block{&paragraph{runs: []run{{text: "(No content provided)", st: style{italic: true}}}, muted: true}}
+	}
+	w.blocks(blocks)
+	w.y += 4
+}
+
+func (w *writer) blocks(blocks []block) {
+	for _, b := range blocks {
+		switch b := b.(type) {
+		case *paragraph:
+			w.paragraph(b)
+		case *heading:
+			w.heading(b)
+		case *list:
+			w.list(b)
+		case *table:
+			w.table(b)
+		case *image:
+			w.image(b)
+		case *rule:
+			w.ensure(4)
+			w.pdf.SetDrawColor(160, 160, 160)
+			w.pdf.SetLineWidth(0.2)
+			w.pdf.Line(marginX+w.indent, w.y+2, pageW-marginX, w.y+2)
+			w.y += 4
+		case *quote:
+			w.quote(b)
+		case *pageBreak:
+			if w.y > marginTop {
+				w.newPage()
+			}
+		}
+	}
+}
+
+func (w *writer) paragraph(p *paragraph) {
+	if p.muted {
+		w.pdf.SetTextColor(120, 120, 120)
+		defer w.pdf.SetTextColor(0, 0, 0)
+	}
+	size := bodySize
+	if p.pre {
+		size = 9
+	}
+	w.flow(p.runs, size, p.align, p.pre)
+	w.y += 2
+}
+
+func (w *writer) heading(h *heading) {
+	size := headingSizes[h.level]
+	w.y += 2
+	// Keep a heading with the first lines that follow it.
+	w.ensure(lineHeight(size) + 2*lineHeight(bodySize))
+	if h.level <= 2 {
+		w.anchor(plainText(h.runs), 1)
+	}
+	w.flow(h.runs, size, h.align, false)
+	w.y += 1.5
+}
+
+func (w *writer) list(l *list) {
+	saved := w.indent
+	w.indent += listIndent
+	for i, item := range l.items {
+		marker := "•"
+		if l.ordered {
+			marker = strconv.Itoa(l.start+i) + "."
+		}
+		w.marker = marker
+		if len(item) == 0 {
+			item = []block{&paragraph{}}
+		}
+		if _, ok := item[0].(*paragraph); !ok {
+			w.ensure(lineHeight(bodySize))
+			w.printMarker(w.y)
+		}
+		// Item paragraphs sit close together; the list gets the gap.
+		before := w.y
+		w.blocks(item)
+		if w.y > before {
+			w.y -= 1.5
+		}
+	}
+	w.marker = ""
+	w.indent = saved
+	w.y += 2
+}
+
+func (w *writer) printMarker(top float64) {
+	if w.marker == "" {
+		return
+	}
+	w.font(style{}, bodySize)
+	w.pdf.SetTextColor(0, 0, 0)
+	w.pdf.SetXY(marginX+w.indent-listIndent, top)
+	w.pdf.CellFormat(listIndent-1, lineHeight(bodySize), w.tr(w.marker), "", 0, "R", false, 0, "")
+	w.marker = ""
+}
+
+func (w *writer) quote(q *quote) {
+	saved := w.indent
+	w.indent += listIndent
+	startPage, startY := w.pdf.PageNo(), w.y
+	w.blocks(q.blocks)
+	if w.pdf.PageNo() == startPage {
+		w.pdf.SetDrawColor(170, 170, 170)
+		w.pdf.SetLineWidth(0.8)
+		x := marginX + saved + 2
+		w.pdf.Line(x, startY, x, w.y-2)
+	}
+	w.indent = saved
+}
+
+// ─── Inline layout ───────────────────────────────────────
+
+// piece is a stretch of text in one style; a word may span several pieces.
+type piece struct {
+	text  string
+	st    style
+	space bool // preceded by a space
+	br    bool // forced line break
+	w     float64
+}
+
+type line struct {
+	pieces []piece
+	width  float64 // natural width including spaces
+	spaces int
+	hard   bool // ends with a forced break or the paragraph
+}
+
+// flow lays out runs at the current position, breaking pages between
+// lines.
+func (w *writer) flow(runs []run, size float64, align string, pre bool) {
+	avail := textW - w.indent
+	lh := lineHeight(size)
+	for _, ln := range w.wrap(runs, size, avail, pre) {
+		w.ensure(lh)
+		w.printMarker(w.y)
+		w.drawLine(ln, marginX+w.indent, w.y, avail, size, align)
+		w.y += lh
+	}
+}
+
+func (w *writer) pieces(runs []run, size float64, pre bool) []piece {
+	var out []piece
+	space := false
+	for _, r := range runs {
+		if pre {
+			for i, text := range strings.Split(r.text, "\n") {
+				if i > 0 {
+					out = append(out, piece{br: true})
+				}
+				if text != "" {
+					text = strings.ReplaceAll(text, "\t", "    ")
+					out = append(out, piece{text: text, st: r.st, w: w.width(text, r.st, size)})
+				}
+			}
+			continue
+		}
+		start := -1
+		emit := func(end int) {
+			if start >= 0 {
+				text := r.text[start:end]
+				out = append(out, piece{text: text, st: r.st, space: space, w: w.width(text, r.st, size)})
+				space, start = false, -1
+			}
+		}
+		for i, c := range r.text {
+			switch c {
+			case '\n':
+				emit(i)
+				out = append(out, piece{br: true})
+				space = false
+			case ' ':
+				emit(i)
+				space = true
+			default:
+				if start < 0 {
+					start = i
+				}
+			}
+		}
+		emit(len(r.text))
+	}
+	return out
+}
+
+func (w *writer) wrap(runs []run, size, avail float64, pre bool) []line {
+	pieces := w.pieces(runs, size, pre)
+	spaceW := w.width(" ", style{}, size)
+
+	var lines []line
+	cur := line{}
+	push := func(hard bool) {
+		cur.hard = hard
+		lines = append(lines, cur)
+		cur = line{}
+	}
+	for i := 0; i < len(pieces); {
+		p := pieces[i]
+		if p.br {
+			push(true)
+			i++
+			continue
+		}
+		// A word is this piece plus any pieces glued to it.
+		j, wordW := i+1, p.w
+		for j < len(pieces) && !pieces[j].br && !pieces[j].space {
+			wordW += pieces[j].w
+			j++
+		}
+		gap := 0.0
+		if p.space && len(cur.pieces) > 0 {
+			gap = spaceW
+		}
+		if len(cur.pieces) > 0 && cur.width+gap+wordW > avail {
+			push(false)
+			gap = 0
+		}
+		if wordW > avail {
+			// Break words longer than a line, such as hashes and URLs.
+			for k := i; k < j; k++ {
+				for _, chunk := range w.split(pieces[k], size, avail, &cur, &lines) {
+					cur.pieces = append(cur.pieces, chunk)
+					cur.width += chunk.w
+				}
+			}
+			i = j
+			continue
+		}
+		for k := i; k < j; k++ {
+			q := pieces[k]
+			q.space = k == i && gap > 0
+			if q.space {
+				cur.width += gap
+				cur.spaces++
+			}
+			cur.pieces = append(cur.pieces, q)
+			cur.width += q.w
+		}
+		i = j
+	}
+	if len(cur.pieces) > 0 || len(lines) == 0 {
+		push(true)
+	} else if len(lines) > 0 {
+		lines[len(lines)-1].hard = true
+	}
+	return lines
+}
+
+// split breaks an over-long piece into chunks that fit, flushing full lines
+// into lines; the last chunk is returned for the caller to append.
+func (w *writer) split(p piece, size, avail float64, cur *line, lines *[]line) []piece {
+	var chunk []rune
+	chunkW := 0.0
+	var out []piece
+	for _, r := range p.text {
+		rw := w.width(string(r), p.st, size)
+		if cur.width+chunkW+rw > avail && (len(chunk) > 0 || len(cur.pieces) > 0) {
+			if len(chunk) > 0 {
+				cur.pieces = append(cur.pieces, piece{text: string(chunk), st: p.st, w: chunkW})
+				cur.width += chunkW
+			}
+			*lines = append(*lines, *cur)
+			*cur = line{}
+			chunk, chunkW = nil, 0
+		}
+		chunk = append(chunk, r)
+		chunkW += rw
+	}
+	if len(chunk) > 0 {
+		out = append(out, piece{text: string(chunk), st: p.st, w: chunkW})
+	}
+	return out
+}
+
+func (w *writer) drawLine(ln line, x, top, avail, size float64, align string) {
+	pdf := w.pdf
+	extra := 0.0
+	switch align {
+	case "C":
+		x += (avail - ln.width) / 2
+	case "R":
+		x += avail - ln.width
+	case "J":
+		if !ln.hard && ln.spaces > 0 {
+			extra = (avail - ln.width) / float64(ln.spaces)
+		}
+	}
+	spaceW := w.width(" ", style{}, size)
+	baseline := top + size*ptToMM*(lineFactor-1)/2 + size*ptToMM*0.8
+	for _, p := range ln.pieces {
+		if p.space {
+			x += spaceW + extra
+		}
+		w.font(p.st, size)
+		pdf.SetTextColor(p.st.color[0], p.st.color[1], p.st.color[2])
+		pdf.Text(x, baseline, w.tr(p.text))
+		if p.st.link != "" {
+			pdf.LinkString(x, top, p.w, lineHeight(size), p.st.link)
+		}
+		x += p.w
+	}
+	pdf.SetTextColor(0, 0, 0)
+}
+
+// ─── Tables ──────────────────────────────────────────────
+
+func (w *writer) table(t *table) {
+	cols := 0
+	for _, r := range t.rows {
+		n := 0
+		for _, c := range r.cells {
+			n += c.span
+		}
+		cols = max(cols, n)
+	}
+	avail := textW - w.indent
+	widths := w.columnWidths(t, cols, avail)
+	size := 9.0
+	lh := lineHeight(size)
+
+	layout := func(r tableRow) ([][]line, float64) {
+		lines := make([][]line, len(r.cells))
+		h := lh
+		col := 0
+		for i, c := range r.cells {
+			cw := spanWidth(widths, col, c.span)
+			lines[i] = w.wrap(c.runs, size, cw-2*cellPad, false)
+			h = max(h, float64(len(lines[i]))*lh)
+			col += c.span
+		}
+		return lines, h + 2*cellPad
+	}
+	draw := func(r tableRow, lines [][]line, h float64) {
+		pdf := w.pdf
+		x := marginX + w.indent
+		col := 0
+		pdf.SetDrawColor(150, 150, 150)
+		pdf.SetLineWidth(0.2)
+		for i, c := range r.cells {
+			cw := spanWidth(widths, col, c.span)
+			if r.header || c.header {
+				pdf.SetFillColor(230, 233, 238)
+				pdf.Rect(x, w.y, cw, h, "FD")
+			} else {
+				pdf.Rect(x, w.y, cw, h, "D")
+			}
+			align := c.align
+			if align == "" && r.header {
+				align = "L"
+			}
+			for k, ln := range lines[i] {
+				w.drawLine(ln, x+cellPad, w.y+cellPad+float64(k)*lh, cw-2*cellPad, size, align)
+			}
+			x += cw
+			col += c.span
+		}
+		// Rows with fewer cells are padded so the grid stays closed.
+		if col < cols {
+			pdf.Rect(x, w.y, spanWidth(widths, col, cols-col), h, "D")
+		}
+		w.y += h
+	}
+
+	var header []tableRow
+	for _, r := range t.rows {
+		if r.header {
+			header = append(header, r)
+		}
+	}
+	w.y += 1
+	for i, r := range t.rows {
+		lines, h := layout(r)
+		// Clip rows taller than a page rather than looping on page breaks.
+		h = min(h, pageH-marginTop-marginBottom-10)
+		if w.y+h > pageH-marginBottom {
+			w.newPage()
+			if !r.header {
+				for _, hr := range header {
+					hl, hh := layout(hr)
+					draw(hr, hl, hh)
+				}
+			}
+		} else if i == 0 {
+			w.ensure(h)
+		}
+		draw(r, lines, h)
+	}
+	w.y += 3
+}
+
+func spanWidth(widths []float64, col, span int) float64 {
+	total := 0.0
+	for i := col; i < col+span && i < len(widths); i++ {
+		total += widths[i]
+	}
+	return total
+}
+
+// columnWidths shares the available width in proportion to each column's
+// natural width, but never below the width of its longest word.
+func (w *writer) columnWidths(t *table, cols int, avail float64) []float64 {
+	size := 9.0
+	natural := make([]float64, cols)
+	minimum := make([]float64, cols)
+	for _, r := range t.rows {
+		col := 0
+		for _, c := range r.cells {
+			if c.span == 1 && col < cols {
+				lineW, longest := 0.0, 0.0
+				for _, p := range w.pieces(c.runs, size, false) {
+					if p.br {
+						natural[col] = max(natural[col], lineW)
+						lineW = 0
+						continue
+					}
+					lineW += p.w + w.width(" ", style{}, size)
+					longest = max(longest, p.w)
+				}
+				natural[col] = max(natural[col], lineW+2*cellPad)
+				minimum[col] = max(minimum[col], min(longest+2*cellPad, avail/float64(cols)*2))
+			}
+			col += c.span
+		}
+	}
+	total := 0.0
+	for i := range natural {
+		natural[i] = max(natural[i], 8)
+		total += natural[i]
+	}
+	widths := make([]float64, cols)
+	for i := range widths {
+		widths[i] = max(minimum[i], avail*natural[i]/total)
+	}
+	sum := 0.0
+	for _, v := range widths {
+		sum += v
+	}
+	for i := range widths {
+		widths[i] *= avail / sum
+	}
+	return widths
+}
+
+// ─── Images ──────────────────────────────────────────────
+
+func (w *writer) image(img *image) {
+	pdf := w.pdf
+	data, kind := img.data, img.kind
+	cfg, _, err := stdimage.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width == 0 || cfg.Height == 0 {
+		w.paragraph(&paragraph{runs: []run{{text: "[image could not be rendered]", st: style{italic: true}}}, muted: true})
+		return
+	}
+	// Large PNG screenshots are re-encoded to keep reports small.
+	if kind == "PNG" && len(data) > 1_000_000 {
+		if decoded, err := png.Decode(bytes.NewReader(data)); err == nil {
+			var buf bytes.Buffer
+			if jpeg.Encode(&buf, decoded, &jpeg.Options{Quality: 85}) == nil {
+				data, kind = buf.Bytes(), "JPG"
+			}
+		}
+	}
+
+	sum := sha256.Sum256(data)
+	name := "img-" + hex.EncodeToString(sum[:8])
+	opts := gofpdf.ImageOptions{ImageType: kind}
+	pdf.RegisterImageOptionsReader(name, opts, bytes.NewReader(data))
+	if pdf.Err() {
+		pdf.ClearError()
+		w.paragraph(&paragraph{runs: []run{{text: "[image could not be rendered]", st: style{italic: true}}}, muted: true})
+		return
+	}
+
+	avail := textW - w.indent
+	width := float64(cfg.Width) * 25.4 / 96
+	switch {
+	case img.widthMM > 0:
+		width = img.widthMM
+	case img.widthMM < 0:
+		width = avail * -img.widthMM / 100
+	}
+	width = min(width, avail)
+	height := width * float64(cfg.Height) / float64(cfg.Width)
+	if maxH := pageH - marginTop - marginBottom - 20; height > maxH {
+		width, height = width*maxH/height, maxH
+	}
+	captionH := 0.0
+	if img.alt != "" {
+		captionH = lineHeight(smallSize) + 1
+	}
+	w.ensure(height + captionH + 2)
+	x := marginX + w.indent + (avail-width)/2
+	pdf.ImageOptions(name, x, w.y+1, width, height, false, opts, 0, "")
+	w.y += height + 2
+	if img.alt != "" {
+		pdf.SetTextColor(80, 80, 80)
+		w.flow([]run{{text: img.alt, st: style{italic: true}}}, smallSize, "C", false)
+		pdf.SetTextColor(0, 0, 0)
+	}
+	w.y += 2
+}
+
+// truncateRunes shortens bookmark titles, which PDF viewers show in a
+// narrow pane.
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "..."
+}
@@ -0,0 +1,595 @@
+package pdfrender
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// style is the inline formatting of a run of text.
+type style struct {
+	bold, italic, underline, mono bool
+	color                         [3]int
+	link                          string
+}
+
+type run struct {
+	text string
+	st   style
+}
+
+type block interface{ isBlock() }
+
+type paragraph struct {
+	runs  []run
+	align string // "L", "C", "R" or "J"
+	pre   bool   // keep whitespace and line breaks
+	muted bool   // placeholder text
+}
+
+type heading struct {
+	level int
+	runs  []run
+	align string
+}
+
+type list struct {
+	ordered bool
+	start   int
+	items   [][]block
+}
+
+type table struct {
+	rows []tableRow
+}
+
+type tableRow struct {
+	cells  []tableCell
+	header bool
+}
+
+type tableCell struct {
+	runs   []run
+	span   int
+	header bool
+	align  string
+}
+
+type image struct {
+	data    []byte
+	kind    string  // gofpdf image type: PNG, JPG or GIF
+	widthMM float64 // requested width; 0 uses the natural size
+	alt     string
+}
+
+type rule struct{}
+
+type quote struct {
+	blocks []block
+}
+
+type pageBreak struct{}
+
+func (*paragraph) isBlock() {}
+func (*heading) isBlock()   {}
+func (*list) isBlock()      {}
+func (*table) isBlock()     {}
+func (*image) isBlock()     {}
+func (*rule) isBlock()      {}
+func (*quote) isBlock()     {}
+func (*pageBreak) isBlock() {}
+
+var (
+	linkColor  = [3]int{31, 78, 160}
+	bodyParent = &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+)
+
+// parseHTML turns editor HTML into blocks. Unknown elements contribute
+// their text; scripts, styles and embedded objects are dropped.
+func parseHTML(src string) []block {
+	nodes, err := html.ParseFragment(strings.NewReader(src), bodyParent)
+	if err != nil {
+		return []block{&paragraph{runs: []run{{text: src}}}}
+	}
+	p := &parser{}
+	for _, n := range nodes {
+		p.node(n, style{})
+	}
+	p.flush()
+	return p.blocks
+}
+
+// parser collects blocks for one container, buffering inline content until
+// the next block boundary.
+type parser struct {
+	blocks []block
+	runs   []run
+	align  string
+	pre    bool
+}
+
+func (p *parser) flush() {
+	if p.pre {
+		if len(p.runs) > 0 {
+			p.blocks = append(p.blocks, &paragraph{runs: p.runs, align: p.align, pre: true})
+		}
+	} else if runs := trimRuns(p.runs); len(runs) > 0 {
+		p.blocks = append(p.blocks, &paragraph{runs: runs, align: p.align})
+	}
+	p.runs = nil
+}
+
+func (p *parser) add(b block) {
+	p.flush()
+	p.blocks = append(p.blocks, b)
+}
+
+func (p *parser) children(n *html.Node, st style) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		p.node(c, st)
+	}
+}
+
+// sub parses n's children into their own list of blocks.
+func (p *parser) sub(n *html.Node, st style) []block {
+	s := &parser{align: p.align, pre: p.pre}
+	s.children(n, st)
+	s.flush()
+	return s.blocks
+}
+
+func (p *parser) node(n *html.Node, st style) {
+	switch n.Type {
+	case html.TextNode:
+		p.text(n.Data, st)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	css := parseCSS(attr(n, "style"))
+	st = applyCSS(st, css)
+	if css["page-break-before"] == "always" || css["break-before"] == "page" {
+		p.add(&pageBreak{})
+	}
+	defer func() {
+		if css["page-break-after"] == "always" || css["break-after"] == "page" {
+			p.add(&pageBreak{})
+		}
+	}()
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Head, atom.Title, atom.Iframe, atom.Object, atom.Embed, atom.Noscript, atom.Svg, atom.Video, atom.Audio:
+		return
+	case atom.B, atom.Strong:
+		st.bold = true
+		p.children(n, st)
+	case atom.I, atom.Em, atom.Cite, atom.Var, atom.Dfn:
+		st.italic = true
+		p.children(n, st)
+	case atom.U, atom.Ins:
+		st.underline = true
+		p.children(n, st)
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		st.mono = true
+		p.children(n, st)
+	case atom.A:
+		if href := attr(n, "href"); isExternalLink(href) {
+			st.link, st.underline, st.color = href, true, linkColor
+		}
+		p.children(n, st)
+	case atom.Br:
+		p.runs = append(p.runs, run{text: "\n", st: st})
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer, atom.Main, atom.Aside,
+		atom.Figure, atom.Address, atom.Center, atom.Dl, atom.Dt, atom.Dd, atom.Details, atom.Summary:
+		p.flush()
+		saved := p.align
+		p.align = blockAlign(n, css, saved)
+		if n.DataAtom == atom.Center {
+			p.align = "C"
+		}
+		if n.DataAtom == atom.Dt {
+			st.bold = true
+		}
+		p.children(n, st)
+		p.flush()
+		p.align = saved
+	case atom.Figcaption:
+		p.flush()
+		saved := p.align
+		p.align = "C"
+		st.italic = true
+		p.children(n, st)
+		p.flush()
+		p.align = saved
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		st.bold = true
+		if runs := trimRuns(flattenRuns(p.sub(n, st))); len(runs) > 0 {
+			p.add(&heading{level: level, runs: runs, align: blockAlign(n, css, "")})
+		}
+	case atom.Ul, atom.Ol:
+		l := &list{ordered: n.DataAtom == atom.Ol, start: 1}
+		if v, err := strconv.Atoi(attr(n, "start")); err == nil {
+			l.start = v
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch {
+			case c.Type == html.ElementNode && c.DataAtom == atom.Li:
+				l.items = append(l.items, p.sub(c, applyCSS(st, parseCSS(attr(c, "style")))))
+			case c.Type == html.ElementNode:
+				// Stray content inside a list becomes its own item.
+				s := &parser{align: p.align}
+				s.node(c, st)
+				s.flush()
+				if len(s.blocks) > 0 {
+					l.items = append(l.items, s.blocks)
+				}
+			}
+		}
+		if len(l.items) > 0 {
+			p.add(l)
+		}
+	case atom.Li:
+		// A list item outside a list reads as a bulleted item.
+		p.add(&list{start: 1, items: [][]block{p.sub(n, st)}})
+	case atom.Table:
+		if t := p.table(n, st); len(t.rows) > 0 {
+			p.add(t)
+		}
+	case atom.Img:
+		if img := dataImage(n, css); img != nil {
+			p.add(img)
+		} else if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+			p.text("[image: "+alt+"]", st)
+		}
+	case atom.Hr:
+		p.add(&rule{})
+	case atom.Blockquote:
+		st.italic = true
+		p.add(&quote{blocks: p.sub(n, st)})
+	case atom.Pre:
+		p.flush()
+		st.mono = true
+		s := &parser{pre: true}
+		s.children(n, st)
+		s.flush()
+		p.blocks = append(p.blocks, s.blocks...)
+	default:
+		p.children(n, st)
+	}
+}
+
+func (p *parser) text(s string, st style) {
+	if s == "" {
+		return
+	}
+	if !p.pre {
+		s = collapseSpace(s)
+		// Whitespace never doubles up across element boundaries.
+		if strings.HasPrefix(s, " ") && (len(p.runs) == 0 || endsWithSpace(p.runs[len(p.runs)-1].text)) {
+			s = s[1:]
+		}
+		if s == "" {
+			return
+		}
+	}
+	if n := len(p.runs); n > 0 && p.runs[n-1].st == st && p.runs[n-1].text != "\n" {
+		p.runs[n-1].text += s
+		return
+	}
+	p.runs = append(p.runs, run{text: s, st: st})
+}
+
+func (p *parser) table(n *html.Node, st style) *table {
+	t := &table{}
+	var walk func(n *html.Node, inHead bool)
+	walk = func(n *html.Node, inHead bool) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.DataAtom {
+			case atom.Thead:
+				walk(c, true)
+			case atom.Tbody, atom.Tfoot:
+				walk(c, false)
+			case atom.Tr:
+				row := tableRow{header: inHead}
+				allTH := true
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type != html.ElementNode || (cell.DataAtom != atom.Td && cell.DataAtom != atom.Th) {
+						continue
+					}
+					cellCSS := parseCSS(attr(cell, "style"))
+					cst := applyCSS(st, cellCSS)
+					isTH := cell.DataAtom == atom.Th
+					if isTH {
+						cst.bold = true
+					} else {
+						allTH = false
+					}
+					span, _ := strconv.Atoi(attr(cell, "colspan"))
+					if span < 1 {
+						span = 1
+					}
+					row.cells = append(row.cells, tableCell{
+						runs:   trimRuns(flattenRuns(p.sub(cell, cst))),
+						span:   min(span, 50),
+						header: isTH,
+						align:  blockAlign(cell, cellCSS, ""),
+					})
+				}
+				if len(row.cells) == 0 {
+					continue
+				}
+				// A leading row of header cells repeats on every page.
+				if allTH && (len(t.rows) == 0 || t.rows[len(t.rows)-1].header) {
+					row.header = true
+				}
+				t.rows = append(t.rows, row)
+			case atom.Caption:
+			default:
+				walk(c, inHead)
+			}
+		}
+	}
+	walk(n, false)
+	return t
+}
+
+// flattenRuns joins the inline content of blocks, one line per block, for
+// places that only hold text such as headings and table cells.
+func flattenRuns(blocks []block) []run {
+	var out []run
+	add := func(runs []run) {
+		if len(runs) == 0 {
+			return
+		}
+		if len(out) > 0 {
+			out = append(out, run{text: "\n", st: runs[0].st})
+		}
+		out = append(out, runs...)
+	}
+	for _, b := range blocks {
+		switch b := b.(type) {
+		case *paragraph:
+			add(b.runs)
+		case *heading:
+			add(b.runs)
+		case *list:
+			for i, item := range b.items {
+				marker := "• "
+				if b.ordered {
+					marker = strconv.Itoa(b.start+i) + ". "
+				}
+				runs := flattenRuns(item)
+				if len(runs) > 0 {
+					runs = append([]run{{text: marker, st: runs[0].st}}, runs...)
+				}
+				add(runs)
+			}
+		case *quote:
+			add(flattenRuns(b.blocks))
+		case *table:
+			for _, r := range b.rows {
+				var line []run
+				for i, c := range r.cells {
+					if i > 0 {
+						line = append(line, run{text: " | "})
+					}
+					line = append(line, c.runs...)
+				}
+				add(line)
+			}
+		case *image:
+			if b.alt != "" {
+				add([]run{{text: "[image: " + b.alt + "]"}})
+			}
+		}
+	}
+	return out
+}
+
+// trimRuns drops leading and trailing whitespace and line breaks.
+func trimRuns(runs []run) []run {
+	for len(runs) > 0 {
+		r := &runs[0]
+		r.text = strings.TrimLeft(r.text, " \n")
+		if r.text != "" {
+			break
+		}
+		runs = runs[1:]
+	}
+	for len(runs) > 0 {
+		r := &runs[len(runs)-1]
+		r.text = strings.TrimRight(r.text, " \n")
+		if r.text != "" {
+			break
+		}
+		runs = runs[:len(runs)-1]
+	}
+	return runs
+}
+
+func collapseSpace(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		switch r {
+		case ' ', '\t', '\n', '\r', '\f':
+			if !space {
+				b.WriteByte(' ')
+				space = true
+			}
+		case '\u00a0':
+			b.WriteByte(' ')
+			space = false
+		default:
+			b.WriteRune(r)
+			space = false
+		}
+	}
+	return b.String()
+}
+
+func endsWithSpace(s string) bool {
+	return strings.HasSuffix(s, " ") || strings.HasSuffix(s, "\n")
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func isExternalLink(href string) bool {
+	h := strings.ToLower(href)
+	return strings.HasPrefix(h, "http://") || strings.HasPrefix(h, "https://") || strings.HasPrefix(h, "mailto:")
+}
+
+func parseCSS(decl string) map[string]string {
+	if decl == "" {
+		return nil
+	}
+	out := map[string]string{}
+	for _, part := range strings.Split(decl, ";") {
+		k, v, ok := strings.Cut(part, ":")
+		if !ok {
+			continue
+		}
+		out[strings.ToLower(strings.TrimSpace(k))] = strings.ToLower(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(v), "!important")))
+	}
+	return out
+}
+
+func applyCSS(st style, css map[string]string) style {
+	switch w := css["font-weight"]; w {
+	case "bold", "bolder", "600", "700", "800", "900":
+		st.bold = true
+	case "normal", "lighter", "100", "200", "300", "400":
+		st.bold = false
+	}
+	switch css["font-style"] {
+	case "italic", "oblique":
+		st.italic = true
+	case "normal":
+		st.italic = false
+	}
+	if d := css["text-decoration"] + " " + css["text-decoration-line"]; strings.Contains(d, "underline") {
+		st.underline = true
+	} else if strings.Contains(d, "none") {
+		st.underline = false
+	}
+	if strings.Contains(css["font-family"], "mono") || strings.Contains(css["font-family"], "courier") {
+		st.mono = true
+	}
+	if c, ok := parseColor(css["color"]); ok {
+		st.color = c
+	}
+	return st
+}
+
+func blockAlign(n *html.Node, css map[string]string, inherited string) string {
+	v := css["text-align"]
+	if v == "" {
+		v = strings.ToLower(attr(n, "align"))
+	}
+	switch v {
+	case "center":
+		return "C"
+	case "right", "end":
+		return "R"
+	case "justify":
+		return "J"
+	case "left", "start":
+		return "L"
+	}
+	return inherited
+}
+
+var namedColors = map[string][3]int{
+	"black": {0, 0, 0}, "white": {255, 255, 255}, "red": {200, 0, 0}, "green": {0, 128, 0},
+	"blue": {0, 0, 200}, "gray": {128, 128, 128}, "grey": {128, 128, 128}, "orange": {230, 120, 0},
+	"purple": {128, 0, 128}, "maroon": {128, 0, 0}, "navy": {0, 0, 128}, "teal": {0, 128, 128},
+}
+
+func parseColor(v string) ([3]int, bool) {
+	if c, ok := namedColors[v]; ok {
+		return c, true
+	}
+	if strings.HasPrefix(v, "#") {
+		hex := v[1:]
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		if len(hex) != 6 {
+			return [3]int{}, false
+		}
+		n, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return [3]int{}, false
+		}
+		return [3]int{int(n >> 16 & 0xff), int(n >> 8 & 0xff), int(n & 0xff)}, true
+	}
+	if inner, ok := strings.CutPrefix(v, "rgb("); ok {
+		parts := strings.Split(strings.TrimSuffix(inner, ")"), ",")
+		if len(parts) != 3 {
+			return [3]int{}, false
+		}
+		var c [3]int
+		for i, s := range parts {
+			n, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				return [3]int{}, false
+			}
+			c[i] = max(0, min(255, n))
+		}
+		return c, true
+	}
+	return [3]int{}, false
+}
+
+func dataImage(n *html.Node, css map[string]string) *image {
+	src := strings.TrimSpace(attr(n, "src"))
+	meta, payload, ok := strings.Cut(strings.TrimPrefix(src, "data:"), ",")
+	if !ok || !strings.HasPrefix(src, "data:") || !strings.HasSuffix(meta, ";base64") {
+		return nil
+	}
+	kind := map[string]string{
+		"image/png":  "PNG",
+		"image/jpeg": "JPG",
+		"image/jpg":  "JPG",
+		"image/gif":  "GIF",
+	}[strings.ToLower(strings.TrimSuffix(meta, ";base64"))]
+	if kind == "" {
+		return nil
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\n' || r == '\r' || r == '\t' {
+			return -1
+		}
+		return r
+	}, payload))
+	if err != nil {
+		return nil
+	}
+	img := &image{data: data, kind: kind, alt: strings.TrimSpace(attr(n, "alt"))}
+	width := css["width"]
+	if width == "" {
+		width = attr(n, "width")
+	}
+	if px, err := strconv.ParseFloat(strings.TrimSuffix(width, "px"), 64); err == nil && px > 0 {
+		img.widthMM = px * 25.4 / 96
+	} else if pct, err := strconv.ParseFloat(strings.TrimSuffix(width, "%"), 64); err == nil && strings.HasSuffix(width, "%") && pct > 0 {
+		img.widthMM = -pct // resolved against the text width at layout
+	}
+	return img
+}
@@ -0,0 +1,148 @@
+package pdfrender
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sampleDoc() Document {
+	long := strings.Repeat("<p>Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt ut labore.</p>", 40)
+	return Document{
+		Title:          "Incident report",
+		Subtitle:       "Case: Ransomware at ACME",
+		Meta:           [][2]string{{"Report number", "RPT-1"}},
+		Classification: "Confidential",
+		HeaderLeft:     "Case: Ransomware at ACME",
+		HeaderRight:    "RPT-1 v2",
+		FooterLeft:     "sealed",
+		TOC:            true,
+		CreatedAt:      time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+		Sections: []Section{
+			{Title: "Summary", HTML: "<h2>Scope</h2>" + long + "<h2>Findings</h2><ul><li>one</li><li><b>two</b></li></ul>"},
+			{Title: "Evidence", HTML: `<table><thead><tr><th>Name</th><th>SHA-256</th></tr></thead><tbody><tr><td>disk.img</td><td>` + strings.Repeat("ab", 32) + `</td></tr></tbody></table>`},
+			{Title: "Empty", HTML: "<p><br></p>"},
+		},
+		Appendices: []Section{{Title: "Chain of custody", HTML: "<ol><li>Collected</li><li>Imaged</li></ol>"}},
+	}
+}
+
+// pageText inflates every content stream so assertions can look at the
+// text drawn on the pages.
+func pageText(t *testing.T, pdf []byte) string {
+	t.Helper()
+	var out strings.Builder
+	re := regexp.MustCompile(`(?s)stream\r?\n(.*?)\r?\nendstream`)
+	for _, m := range re.FindAllSubmatch(pdf, -1) {
+		r, err := zlib.NewReader(bytes.NewReader(m[1]))
+		if err != nil {
+			continue
+		}
+		b, _ := io.ReadAll(r)
+		out.Write(b)
+	}
+	return out.String()
+}
+
+func TestRenderIsDeterministic(t *testing.T) {
+	a, err := Render(sampleDoc())
+	require.NoError(t, err)
+	b, err := Render(sampleDoc())
+	require.NoError(t, err)
+	require.True(t, bytes.Equal(a, b), "same document must render to identical bytes")
+	require.True(t, bytes.HasPrefix(a, []byte("%PDF-")))
+}
+
+func TestRenderContentsAndRunningFooter(t *testing.T) {
+	doc := sampleDoc()
+	out, err := Render(doc)
+	require.NoError(t, err)
+	text := pageText(t, out)
+
+	// Page numbers carry the total; the {nb} alias must be substituted.
+	require.NotContains(t, text, "{nb}")
+	pages := regexp.MustCompile(`Page 1 of (\d+)`).FindStringSubmatch(text)
+	require.NotNil(t, pages)
+	require.Contains(t, text, "Page "+pages[1]+" of "+pages[1])
+	require.Contains(t, text, "CONFIDENTIAL")
+	require.Contains(t, text, "RPT-1 v2")
+
+	// Title page, contents, then the first section on page 3. The long
+	// summary pushes the second section to a later page.
+	_, pageOf, err := render(&doc, parseAll(doc.Sections), parseAll(doc.Appendices), nil)
+	require.NoError(t, err)
+	require.Equal(t, 3, pageOf[0], "section 1")
+	require.Equal(t, 3, pageOf[1], "Scope heading")
+	require.Greater(t, pageOf[3], pageOf[1], "Evidence follows the long summary")
+	require.Equal(t, "Appendix A - Chain of custody", "Appendix "+appendixLetter(0)+" - "+doc.Appendices[0].Title)
+	require.Len(t, pageOf, 6) // 3 sections, 2 headings, 1 appendix
+}
+
+func parseAll(sections []Section) [][]block {
+	out := make([][]block, len(sections))
+	for i, s := range sections {
+		out[i] = parseHTML(s.HTML)
+	}
+	return out
+}
+
+func TestRenderWatermark(t *testing.T) {
+	doc := sampleDoc()
+	plain, err := Render(doc)
+	require.NoError(t, err)
+	require.NotContains(t, pageText(t, plain), "Shared with guest@example.com")
+
+	doc.Watermark = "Shared with guest@example.com"
+	marked, err := Render(doc)
+	require.NoError(t, err)
+	text := pageText(t, marked)
+	// Once per page, rotated about the page centre
+	require.Equal(t, strings.Count(text, "Page "), strings.Count(text, "(Shared with guest@example.com) Tj"))
+	require.Contains(t, text, " cm")
+}
+
+func TestParseHTMLSubset(t *testing.T) {
+	blocks := parseHTML(`<h2>Title</h2><p style="text-align:center">a <b>bold</b> <a href="https://x.test">link</a></p>` +
+		`<ol start="3"><li>x<ul><li>nested</li></ul></li><li>y</li></ol>` +
+		`<table><tr><th colspan="2">H</th></tr><tr><td>1</td><td>2</td></tr></table><hr><img src="data:image/png;base64,!!"><img src="data:image/png;base64,aGVsbG8=">`)
+
+	require.Len(t, blocks, 6)
+	h := blocks[0].(*heading)
+	require.Equal(t, 2, h.level)
+	p := blocks[1].(*paragraph)
+	require.Equal(t, "C", p.align)
+	require.Equal(t, "a bold link", plainText(p.runs))
+	var bold, linked bool
+	for _, r := range p.runs {
+		bold = bold || (r.st.bold && r.text == "bold")
+		linked = linked || r.st.link == "https://x.test"
+	}
+	require.True(t, bold)
+	require.True(t, linked)
+
+	l := blocks[2].(*list)
+	require.True(t, l.ordered)
+	require.Equal(t, 3, l.start)
+	require.Len(t, l.items, 2)
+	require.IsType(t, &list{}, l.items[0][1])
+
+	tb := blocks[3].(*table)
+	require.Len(t, tb.rows, 2)
+	require.True(t, tb.rows[0].header)
+	require.Equal(t, 2, tb.rows[0].cells[0].span)
+	require.IsType(t, &rule{}, blocks[4])
+	require.IsType(t, &image{}, blocks[5], "invalid data URLs are dropped")
+}
+
+func TestRenderSurvivesBadImage(t *testing.T) {
+	doc := sampleDoc()
+	doc.Sections = []Section{{Title: "Images", HTML: `<img src="data:image/png;base64,aGVsbG8=" alt="broken">`}}
+	_, err := Render(doc)
+	require.NoError(t, err)
+}
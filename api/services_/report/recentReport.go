@@ -9,6 +9,7 @@ import (
 
 type RecentReport struct {
 	ID           uuid.UUID `json:"id"`
+	CaseID       uuid.UUID `json:"caseId"`
 	Title        string    `json:"title"`        // maps from Report.Name
 	Status       string    `json:"status"`       // 'draft' | 'review' | 'published'
 	LastModified time.Time `json:"lastModified"` // RFC3339 in handler
@@ -0,0 +1,125 @@
+package report_ai_assistance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	reportpkg "aegis-api/services_/report"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSuggestionNotFound      = errors.New("suggestion not found")
+	ErrSuggestionNotAcceptable = errors.New("suggestion has no grounded claims or was already accepted")
+	ErrSectionChanged          = errors.New("section was modified while the suggestion was being accepted; retry")
+)
+
+// GetSuggestion returns a suggestion with its claims if its report belongs
+// to tenantID.
+func (s *reportService) GetSuggestion(ctx context.Context, suggestionID, tenantID string) (*AISuggestion, error) {
+	sug, _, err := s.suggestionInTenant(ctx, suggestionID, tenantID)
+	return sug, err
+}
+
+// AcceptSuggestion appends the supported claims of a suggestion to its
+// section as a block marked as machine-drafted, stores which sources each
+// sentence was based on, and writes matching SectionRef rows. Unsupported
+// claims are never carried into the report.
+func (s *reportService) AcceptSuggestion(ctx context.Context, suggestionID, tenantID, userID string) (*AISuggestion, error) {
+	sug, rep, err := s.suggestionInTenant(ctx, suggestionID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if sug.Status != SuggestionPending && sug.Status != SuggestionFlagged {
+		return nil, ErrSuggestionNotAcceptable
+	}
+
+	var claims []DraftClaim
+	if err := json.Unmarshal(sug.Claims, &claims); err != nil {
+		return nil, fmt.Errorf("failed to decode claims: %w", err)
+	}
+	accepted := provenanceClaims(claims)
+	if len(accepted) == 0 {
+		return nil, ErrSuggestionNotAcceptable
+	}
+
+	reportMongoID, err := primitive.ObjectIDFromHex(rep.MongoID)
+	if err != nil {
+		return nil, fmt.Errorf("report has no content document: %w", err)
+	}
+	sectionMongoID, err := primitive.ObjectIDFromHex(sug.SectionID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid section ObjectID: %w", err)
+	}
+	section, err := s.sectionRepo.GetSectionByID(ctx, reportMongoID, sectionMongoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get section: %w", err)
+	}
+
+	now := time.Now().UTC()
+	previous := sug.Status
+	if err := s.aiRepo.MarkAccepted(ctx, sug.ID, userID, now); err != nil {
+		return nil, err
+	}
+
+	prov := reportpkg.SectionProvenance{
+		SuggestionID: sug.ID,
+		Origin:       "ai_draft",
+		AcceptedBy:   userID,
+		AcceptedAt:   now,
+		Claims:       accepted,
+	}
+	content := section.Content + RenderDraftHTML(sug.ID, claims)
+	if err := s.sectionRepo.AppendAcceptedDraft(ctx, reportMongoID, sectionMongoID, section.UpdatedAt, content, prov); err != nil {
+		if rerr := s.aiRepo.ReopenSuggestion(ctx, sug.ID, previous); rerr != nil {
+			log.Printf("[AI Suggestion] failed to reopen suggestion %s: %v", sug.ID, rerr)
+		}
+		return nil, err
+	}
+
+	// The provenance on the section is authoritative; the refs table is an
+	// index over it, so a failed row is logged rather than undoing the accept.
+	idx := 0
+	for _, c := range claims {
+		if !c.Supported {
+			continue
+		}
+		for _, r := range c.Refs {
+			ref := &SectionRef{SectionID: sug.SectionID, RefType: r.RefType, RefID: r.RefID, SuggestionID: &sug.ID, ClaimIndex: idx}
+			if err := s.refsRepo.CreateRef(ctx, ref); err != nil {
+				log.Printf("[AI Suggestion] failed to record %s ref %s for suggestion %s: %v", r.RefType, r.RefID, sug.ID, err)
+			}
+		}
+		idx++
+	}
+
+	sug.Status = SuggestionAccepted
+	sug.AcceptedBy = &userID
+	sug.AcceptedAt = &now
+	return sug, nil
+}
+
+func (s *reportService) suggestionInTenant(ctx context.Context, suggestionID, tenantID string) (*AISuggestion, *reportpkg.Report, error) {
+	sug, err := s.aiRepo.GetSuggestionByID(ctx, suggestionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrSuggestionNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var rep reportpkg.Report
+	err = s.sectionRepo.pgDB.WithContext(ctx).First(&rep, "id = ?", sug.ReportID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && rep.TenantID.String() != tenantID) {
+		return nil, nil, ErrSuggestionNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return sug, &rep, nil
+}
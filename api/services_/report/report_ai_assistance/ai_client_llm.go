@@ -0,0 +1,191 @@
+package report_ai_assistance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	graphicalmapping "aegis-api/services_/GraphicalMapping"
+	"aegis-api/services_/case/case_creation"
+	"aegis-api/services_/evidence/metadata"
+	"aegis-api/services_/llm"
+	reportshared "aegis-api/services_/report/shared"
+	"aegis-api/services_/timeline"
+)
+
+// AIClientLLM implements AIClient on top of an llm.Provider, so report
+// drafting shares provider selection, tenant policy and usage accounting with
+// the rest of the platform.
+type AIClientLLM struct {
+	provider llm.Provider
+}
+
+// NewAIClientLLM returns an AI client backed by provider
+func NewAIClientLLM(provider llm.Provider) *AIClientLLM {
+	log.Printf("[AIClientLLM] Initializing report AI client (provider: %s)", provider.Name())
+	return &AIClientLLM{provider: provider}
+}
+
+// AISuggestionInput provides context for AI suggestion generation
+type AISuggestionInput struct {
+	Case     *case_creation.Case
+	Section  *reportshared.ReportSection
+	Timeline []timeline.TimelineEvent
+	IOCs     []graphicalmapping.IOC
+	Evidence []metadata.Evidence
+	// Sources is the labelled set of records the draft may cite.
+	Sources *SourceCatalog
+}
+
+// GenerateSuggestion generates a draft for a report section
+func (c *AIClientLLM) GenerateSuggestion(ctx context.Context, input AISuggestionInput) (string, error) {
+	prompt := buildAISuggestionPrompt(input)
+	log.Printf("[AIClientLLM] GenerateSuggestion called. Prompt:\n%s", prompt)
+	result, err := c.complete(ctx, "report.suggestion", prompt, 350)
+	if err != nil {
+		log.Printf("[AIClientLLM] Error in GenerateSuggestion: %v", err)
+	}
+	cleaned := removePromptEcho(result, prompt)
+	return cleaned, err
+}
+
+// RefineSuggestion refines an existing suggestion using user feedback
+func (c *AIClientLLM) RefineSuggestion(ctx context.Context, existing string, feedback string) (string, error) {
+	prompt := fmt.Sprintf("Refine the following forensic report section based on feedback:\n\nSection:\n%s\n\nFeedback:\n%s\n\nRewrite the section accordingly.", existing, feedback)
+	log.Printf("[AIClientLLM] RefineSuggestion called. Prompt:\n%s", prompt)
+	return c.complete(ctx, "report.refine", prompt, 300)
+}
+
+// SummarizeEvidence generates a summary from evidence, IOCs, and timeline
+func (c *AIClientLLM) SummarizeEvidence(ctx context.Context, evidence []metadata.Evidence, iocs []graphicalmapping.IOC, timeline []timeline.TimelineEvent) (string, error) {
+	evidenceList := ""
+	for _, e := range evidence {
+		evidenceList += fmt.Sprintf("- %s (%s)\n", e.ID, e.FileType)
+	}
+	timelineRefs := ""
+	for _, ev := range timeline {
+		if ev.Description != "" {
+			timelineRefs += fmt.Sprintf("- %s\n", ev.Description)
+		}
+	}
+	iocList := ""
+	for _, ioc := range iocs {
+		iocList += fmt.Sprintf("- %s: %s\n", ioc.Type, ioc.Value)
+	}
+	prompt := fmt.Sprintf(
+		"Summarize the following evidence and timeline for inclusion in a DFIR report.\n\nEvidence:\n%s\nTimeline:\n%s\nIOCs:\n%s\n\nWrite a concise, professional forensic summary.",
+		evidenceList, timelineRefs, iocList)
+	log.Printf("[AIClientLLM] SummarizeEvidence called. Prompt:\n%s", prompt)
+	return c.complete(ctx, "report.summary", prompt, 250)
+}
+
+// GenerateRecommendations provides next steps or mitigation strategies
+func (c *AIClientLLM) GenerateRecommendations(ctx context.Context, caseData *case_creation.Case, analysisSummary string) (string, error) {
+	prompt := fmt.Sprintf(
+		"Based on the following forensic case and analysis, suggest next steps and recommendations.\n\nCase: %+v\n\nAnalysis Summary:\n%s\n\nProvide actionable, concise recommendations.",
+		caseData, analysisSummary)
+	log.Printf("[AIClientLLM] GenerateRecommendations called. Prompt:\n%s", prompt)
+	return c.complete(ctx, "report.recommendations", prompt, 200)
+}
+
+// EvaluateFeedback logs user feedback
+func (c *AIClientLLM) EvaluateFeedback(ctx context.Context, suggestionID string, feedback string) error {
+	log.Printf("[AIClientLLM] Feedback received for suggestion %s: %s", suggestionID, feedback)
+	return nil
+}
+
+// GenerateSectionReferences provides citations or reference links for a section
+func (c *AIClientLLM) GenerateSectionReferences(ctx context.Context, sectionName string, report *reportshared.Report) ([]string, error) {
+	prompt := fmt.Sprintf(
+		"Provide relevant references or citations for the '%s' section of this forensic report:\nCase: %+v",
+		sectionName, report)
+	log.Printf("[AIClientLLM] GenerateSectionReferences called. Prompt:\n%s", prompt)
+
+	refs, err := c.complete(ctx, "report.references", prompt, 200)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := SplitLines(refs)
+	var out []string
+	for _, l := range lines {
+		trimmed := TrimWhitespace(l)
+		if trimmed != "" {
+			out = append(out, trimmed)
+		}
+	}
+	return out, nil
+}
+
+// ----------------- Helpers -----------------
+
+// sectionInstructions gives the drafting task for each standard section.
+var sectionInstructions = map[string]string{
+	"Case Identification":         "Summarize the case purpose and key dates.",
+	"Evidence Summary":            "Summarize the evidence collected, how it relates to the timeline and any indicators of compromise.",
+	"Scope and Objectives":        "State the scope of the investigation as clear, professional objectives.",
+	"Tools and Methodologies":     "Explain the methodology and tools used.",
+	"Findings":                    "Summarize the investigation findings.",
+	"Interpretation and Analysis": "Provide an analytical narrative of what the evidence shows.",
+	"Limitations":                 "List any evidence gaps, constraints, or disclaimers.",
+	"Conclusion":                  "Summarize the overall outcome.",
+	"Appendices":                  "Describe the chain-of-custody logs and evidence tables.",
+	"Certifications":              "List investigator roles and qualifications.",
+}
+
+// buildAISuggestionPrompt asks for a grounded draft: one sentence per line,
+// each ending with the labels of the sources from input.Sources behind it.
+func buildAISuggestionPrompt(input AISuggestionInput) string {
+	sectionTitle := ""
+	if input.Section != nil {
+		sectionTitle = input.Section.Title
+	}
+	instruction, ok := sectionInstructions[sectionTitle]
+	if !ok {
+		instruction = "Use the sources below to create a professional draft."
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Write the '%s' section of a DFIR report. %s\n\n", sectionTitle, instruction)
+	if c := input.Case; c != nil {
+		fmt.Fprintf(&b, "Case: %s (%s), created %s, status %s.\n\n", c.Title, c.ID, c.CreatedAt.Format("2006-01-02"), c.Status)
+	}
+	b.WriteString("Sources:\n")
+	if input.Sources.Len() == 0 {
+		b.WriteString("(none)\n")
+	} else {
+		for _, src := range input.Sources.Sources {
+			fmt.Fprintf(&b, "[%s] %s: %s\n", src.Label, src.RefType, src.Summary)
+		}
+	}
+	b.WriteString("\nRules:\n" +
+		"- Write one factual sentence per line.\n" +
+		"- End every sentence with the labels of the sources that support it, for example [E1] or [T2, I1].\n" +
+		"- Use only the sources listed above and do not state anything they do not support.\n" +
+		"- Do not invent labels, identifiers, names, dates or values.\n")
+	return b.String()
+}
+
+// removePromptEcho trims the prompt text if it is echoed back in the result.
+func removePromptEcho(result, prompt string) string {
+	if len(result) >= len(prompt) && result[:len(prompt)] == prompt {
+		return result[len(prompt):]
+	}
+	return result
+}
+
+// complete runs prompt through the provider. The tenant is read from ctx.
+func (c *AIClientLLM) complete(ctx context.Context, purpose, prompt string, maxTokens int) (string, error) {
+	resp, err := c.provider.Complete(ctx, llm.CompletionRequest{
+		Prompt:    prompt,
+		MaxTokens: maxTokens,
+		Purpose:   purpose,
+	})
+	if err != nil {
+		log.Printf("[AIClientLLM] %s failed: %v", purpose, err)
+		return "", err
+	}
+	log.Printf("[AIClientLLM] %s answered by %s/%s in %s (%d tokens)", purpose, resp.Provider, resp.Model, resp.Latency, resp.Usage.TotalTokens)
+	return resp.Text, nil
+}
@@ -0,0 +1,290 @@
+package report_ai_assistance
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	graphicalmapping "aegis-api/services_/GraphicalMapping"
+	"aegis-api/services_/evidence/metadata"
+	"aegis-api/services_/report"
+	"aegis-api/services_/timeline"
+)
+
+// Reference types a drafted claim can point at.
+const (
+	RefEvidence      = "evidence"
+	RefTimelineEvent = "timeline_event"
+	RefIOC           = "ioc"
+)
+
+// maxSourcesPerKind keeps the grounding block within the model's context.
+const maxSourcesPerKind = 40
+
+// GroundingSource is one item the model may cite, under a short label such
+// as E1, T3 or I2 that maps back to the real record ID.
+type GroundingSource struct {
+	Label   string
+	RefType string
+	RefID   string
+	Summary string
+}
+
+// SourceCatalog is the closed set of sources a draft may cite. Labels are
+// assigned deterministically from the order of the inputs.
+type SourceCatalog struct {
+	Sources []GroundingSource
+	byLabel map[string]GroundingSource
+}
+
+func NewSourceCatalog(evidence []metadata.Evidence, events []timeline.TimelineEvent, iocs []graphicalmapping.IOC) *SourceCatalog {
+	c := &SourceCatalog{byLabel: map[string]GroundingSource{}}
+	for i, e := range evidence {
+		if i == maxSourcesPerKind {
+			break
+		}
+		summary := e.Filename
+		if e.FileType != "" {
+			summary += " (" + e.FileType + ")"
+		}
+		if e.Checksum != "" {
+			summary += ", checksum " + e.Checksum
+		}
+		c.add("E", RefEvidence, e.ID.String(), summary)
+	}
+	for i, ev := range events {
+		if i == maxSourcesPerKind {
+			break
+		}
+		summary := ev.Description
+		if !ev.CreatedAt.IsZero() {
+			summary = ev.CreatedAt.Format("2006-01-02 15:04") + ": " + summary
+		}
+		if ev.Severity != "" {
+			summary += " [severity " + ev.Severity + "]"
+		}
+		c.add("T", RefTimelineEvent, ev.ID, summary)
+	}
+	for i, ioc := range iocs {
+		if i == maxSourcesPerKind {
+			break
+		}
+		c.add("I", RefIOC, ioc.ID, ioc.Type+": "+ioc.Value)
+	}
+	return c
+}
+
+func (c *SourceCatalog) add(prefix, refType, refID, summary string) {
+	if refID == "" {
+		return
+	}
+	n := 1
+	for _, s := range c.Sources {
+		if s.RefType == refType {
+			n++
+		}
+	}
+	src := GroundingSource{Label: prefix + strconv.Itoa(n), RefType: refType, RefID: refID, Summary: summary}
+	c.Sources = append(c.Sources, src)
+	c.byLabel[src.Label] = src
+}
+
+func (c *SourceCatalog) Lookup(label string) (GroundingSource, bool) {
+	if c == nil {
+		return GroundingSource{}, false
+	}
+	s, ok := c.byLabel[strings.ToUpper(label)]
+	return s, ok
+}
+
+func (c *SourceCatalog) Len() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.Sources)
+}
+
+// ClaimRef is a resolved citation on a drafted sentence.
+type ClaimRef struct {
+	Label   string `json:"label"`
+	RefType string `json:"ref_type"`
+	RefID   string `json:"ref_id"`
+}
+
+// DraftClaim is one sentence of an AI draft together with what supports it.
+type DraftClaim struct {
+	Text      string     `json:"text"`
+	Refs      []ClaimRef `json:"refs"`
+	Supported bool       `json:"supported"`
+	Issue     string     `json:"issue,omitempty"`
+
+	unknown []string
+}
+
+var (
+	claimMarkerRe = regexp.MustCompile(`\[\s*([A-Za-z]\d+(?:\s*[,;]\s*[A-Za-z]\d+)*)\s*\]`)
+	claimLabelRe  = regexp.MustCompile(`[A-Za-z]\d+`)
+	bulletRe      = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s+`)
+)
+
+// ParseDraft splits model output into sentences and resolves the [E1]-style
+// markers on each against catalog. Markers that follow the full stop are
+// attached to the sentence before them.
+func ParseDraft(text string, catalog *SourceCatalog) []DraftClaim {
+	var claims []DraftClaim
+	for _, line := range strings.Split(text, "\n") {
+		line = bulletRe.ReplaceAllString(line, "")
+		for _, sentence := range splitClaimSentences(line) {
+			labels := claimLabels(sentence)
+			body := strings.Join(strings.Fields(claimMarkerRe.ReplaceAllString(sentence, " ")), " ")
+			body = strings.TrimSpace(strings.ReplaceAll(body, " .", "."))
+			if strings.Trim(body, ".!? ") == "" {
+				if len(claims) > 0 && len(labels) > 0 {
+					resolveLabels(&claims[len(claims)-1], labels, catalog)
+				}
+				continue
+			}
+			claim := DraftClaim{Text: body}
+			resolveLabels(&claim, labels, catalog)
+			claims = append(claims, claim)
+		}
+	}
+	return claims
+}
+
+func claimLabels(s string) []string {
+	var out []string
+	for _, m := range claimMarkerRe.FindAllStringSubmatch(s, -1) {
+		out = append(out, claimLabelRe.FindAllString(m[1], -1)...)
+	}
+	return out
+}
+
+func resolveLabels(claim *DraftClaim, labels []string, catalog *SourceCatalog) {
+	for _, label := range labels {
+		src, ok := catalog.Lookup(label)
+		if !ok {
+			claim.unknown = append(claim.unknown, strings.ToUpper(label))
+			continue
+		}
+		dup := false
+		for _, r := range claim.Refs {
+			if r.Label == src.Label {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			claim.Refs = append(claim.Refs, ClaimRef{Label: src.Label, RefType: src.RefType, RefID: src.RefID})
+		}
+	}
+}
+
+// splitClaimSentences splits on ., ! or ? followed by whitespace, so dotted
+// values such as IP addresses and file names stay intact.
+func splitClaimSentences(line string) []string {
+	var out []string
+	start := 0
+	for i := 0; i < len(line)-1; i++ {
+		switch line[i] {
+		case '.', '!', '?':
+			if line[i+1] == ' ' || line[i+1] == '\t' {
+				out = append(out, line[start:i+1])
+				start = i + 1
+			}
+		}
+	}
+	if rest := strings.TrimSpace(line[start:]); rest != "" {
+		out = append(out, rest)
+	}
+	return out
+}
+
+// ValidateClaims marks each claim supported only when it cites at least one
+// known source and no invented ones, and returns how many failed.
+func ValidateClaims(claims []DraftClaim) (unsupported int) {
+	for i := range claims {
+		c := &claims[i]
+		switch {
+		case len(c.unknown) > 0:
+			c.Supported = false
+			c.Issue = "cites unknown source " + strings.Join(c.unknown, ", ")
+		case len(c.Refs) == 0:
+			c.Supported = false
+			c.Issue = "no supporting reference"
+		default:
+			c.Supported = true
+			c.Issue = ""
+		}
+		if !c.Supported {
+			unsupported++
+		}
+	}
+	return unsupported
+}
+
+// SupportedText joins the validated claims into the suggestion text shown
+// to the analyst, with each sentence's references kept inline.
+func SupportedText(claims []DraftClaim) string {
+	var parts []string
+	for _, c := range claims {
+		if !c.Supported {
+			continue
+		}
+		parts = append(parts, c.Text+" "+refSuffix(c.Refs))
+	}
+	return strings.Join(parts, "\n")
+}
+
+func refSuffix(refs []ClaimRef) string {
+	var ids []string
+	for _, r := range refs {
+		ids = append(ids, r.RefType+" "+shortID(r.RefID))
+	}
+	return "[" + strings.Join(ids, "; ") + "]"
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// RenderDraftHTML renders accepted claims as a block marked as machine-drafted,
+// with every paragraph carrying the references it was based on.
+func RenderDraftHTML(suggestionID string, claims []DraftClaim) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<div data-origin="ai_draft" data-suggestion-id="%s">`, html.EscapeString(suggestionID))
+	for _, c := range claims {
+		if !c.Supported {
+			continue
+		}
+		var refs []string
+		for _, r := range c.Refs {
+			refs = append(refs, r.RefType+":"+r.RefID)
+		}
+		fmt.Fprintf(&b, `<p data-refs="%s">%s <sup class="source-ref">%s</sup></p>`,
+			html.EscapeString(strings.Join(refs, " ")), html.EscapeString(c.Text), html.EscapeString(refSuffix(c.Refs)))
+	}
+	b.WriteString(`</div>`)
+	return b.String()
+}
+
+// provenanceClaims converts supported claims to the form stored on the section.
+func provenanceClaims(claims []DraftClaim) []report.ProvenanceClaim {
+	var out []report.ProvenanceClaim
+	for _, c := range claims {
+		if !c.Supported {
+			continue
+		}
+		pc := report.ProvenanceClaim{Text: c.Text}
+		for _, r := range c.Refs {
+			pc.Refs = append(pc.Refs, report.ProvenanceRef{Type: r.RefType, ID: r.RefID})
+		}
+		out = append(out, pc)
+	}
+	return out
+}
@@ -0,0 +1,120 @@
+package report_ai_assistance_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	graphicalmapping "aegis-api/services_/GraphicalMapping"
+	"aegis-api/services_/evidence/metadata"
+	"aegis-api/services_/llm"
+	"aegis-api/services_/report/report_ai_assistance"
+	reportshared "aegis-api/services_/report/shared"
+	"aegis-api/services_/timeline"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func testCatalog() (*report_ai_assistance.SourceCatalog, uuid.UUID) {
+	evidenceID := uuid.New()
+	return report_ai_assistance.NewSourceCatalog(
+		[]metadata.Evidence{{ID: evidenceID, Filename: "WS-042.E01", FileType: "disk_image", Checksum: "ab12"}},
+		[]timeline.TimelineEvent{
+			{ID: "11111111-1111-1111-1111-111111111111", Description: "mimikatz executed on WS-042"},
+			{ID: "22222222-2222-2222-2222-222222222222", Description: "outbound connection to 203.0.113.9"},
+		},
+		[]graphicalmapping.IOC{{ID: "33333333-3333-3333-3333-333333333333", Type: "ip", Value: "203.0.113.9"}},
+	), evidenceID
+}
+
+func TestSourceCatalog_LabelsMapToRecordIDs(t *testing.T) {
+	catalog, evidenceID := testCatalog()
+	require.Equal(t, 4, catalog.Len())
+
+	src, ok := catalog.Lookup("E1")
+	require.True(t, ok)
+	require.Equal(t, report_ai_assistance.RefEvidence, src.RefType)
+	require.Equal(t, evidenceID.String(), src.RefID)
+
+	src, ok = catalog.Lookup("t2")
+	require.True(t, ok)
+	require.Equal(t, "22222222-2222-2222-2222-222222222222", src.RefID)
+
+	_, ok = catalog.Lookup("T3")
+	require.False(t, ok)
+}
+
+func TestParseDraft_ValidatesEverySentence(t *testing.T) {
+	catalog, evidenceID := testCatalog()
+	draft := "- The disk image of WS-042 was acquired [E1].\n" +
+		"- Mimikatz ran on WS-042 and the host then contacted 203.0.113.9. [T1, T2; I1]\n" +
+		"The attacker is clearly a nation-state actor.\n" +
+		"Data was exfiltrated to a cloud bucket [T9]."
+
+	claims := report_ai_assistance.ParseDraft(draft, catalog)
+	require.Len(t, claims, 4)
+	unsupported := report_ai_assistance.ValidateClaims(claims)
+	require.Equal(t, 2, unsupported)
+
+	require.Equal(t, "The disk image of WS-042 was acquired.", claims[0].Text)
+	require.True(t, claims[0].Supported)
+	require.Equal(t, evidenceID.String(), claims[0].Refs[0].RefID)
+
+	// Dotted values do not split sentences and trailing markers attach to
+	// the sentence before them.
+	require.Equal(t, "Mimikatz ran on WS-042 and the host then contacted 203.0.113.9.", claims[1].Text)
+	require.Len(t, claims[1].Refs, 3)
+	require.Equal(t, report_ai_assistance.RefIOC, claims[1].Refs[2].RefType)
+
+	require.False(t, claims[2].Supported)
+	require.Equal(t, "no supporting reference", claims[2].Issue)
+
+	require.False(t, claims[3].Supported)
+	require.Contains(t, claims[3].Issue, "T9")
+
+	text := report_ai_assistance.SupportedText(claims)
+	require.NotContains(t, text, "nation-state")
+	require.NotContains(t, text, "exfiltrated")
+	require.Contains(t, text, "[evidence "+evidenceID.String()[:8]+"]")
+}
+
+func TestRenderDraftHTML_MarksOriginAndEscapes(t *testing.T) {
+	catalog, evidenceID := testCatalog()
+	claims := report_ai_assistance.ParseDraft("Image <WS-042> & memory were acquired [E1].\nUnsupported claim.", catalog)
+	report_ai_assistance.ValidateClaims(claims)
+
+	out := report_ai_assistance.RenderDraftHTML("sugg-1", claims)
+	require.True(t, strings.HasPrefix(out, `<div data-origin="ai_draft" data-suggestion-id="sugg-1">`))
+	require.Contains(t, out, `data-refs="evidence:`+evidenceID.String()+`"`)
+	require.Contains(t, out, "Image &lt;WS-042&gt; &amp; memory were acquired.")
+	require.NotContains(t, out, "Unsupported claim")
+}
+
+func TestGenerateSuggestion_PromptListsLabelledSources(t *testing.T) {
+	catalog, _ := testCatalog()
+	fake := llm.NewFakeProvider(map[string]string{"Sources:": "Mimikatz ran on WS-042 [T1]."})
+	client := report_ai_assistance.NewAIClientLLM(fake)
+
+	out, err := client.GenerateSuggestion(context.Background(), report_ai_assistance.AISuggestionInput{
+		Section: &reportshared.ReportSection{Title: "Findings"},
+		Sources: catalog,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Mimikatz ran on WS-042 [T1].", out)
+
+	prompt := fake.Calls()[0].Prompt
+	require.Contains(t, prompt, "[E1] evidence: WS-042.E01 (disk_image), checksum ab12")
+	require.Contains(t, prompt, "[I1] ioc: ip: 203.0.113.9")
+	require.Contains(t, prompt, "[T2, I1]")
+}
+
+func TestPostProcessEnhancedSummary_DoesNotRewordOrTruncate(t *testing.T) {
+	in := "The evidence was collected and stored securely as per protocol. " +
+		"A connection to 203.0.113.9 was observed from the workstation belonging to the finance department during the period under review, well after hours. " +
+		"Please review and edit as needed."
+
+	out := report_ai_assistance.PostProcessEnhancedSummary(in)
+	require.Equal(t, "The evidence was collected and stored securely as per protocol. "+
+		"A connection to 203.0.113.9 was observed from the workstation belonging to the finance department during the period under review, well after hours.", out)
+}
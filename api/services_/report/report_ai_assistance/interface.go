@@ -7,12 +7,15 @@ import (
 	reportshared "aegis-api/services_/report/shared"
 	"aegis-api/services_/timeline"
 	"context"
+	"time"
 )
 
 type AISuggestionRepository interface {
 	GetSuggestionByID(ctx context.Context, id string) (*AISuggestion, error)
 	CreateSuggestion(ctx context.Context, suggestion *AISuggestion) error
 	ListSuggestionsBySection(ctx context.Context, sectionID string) ([]*AISuggestion, error)
+	MarkAccepted(ctx context.Context, id, userID string, at time.Time) error
+	ReopenSuggestion(ctx context.Context, id, status string) error
 }
 
 type SectionRefsRepository interface {
@@ -39,4 +42,8 @@ type ReportAIService interface {
 	SaveFeedback(ctx context.Context, feedback AIFeedback) error
 	GenerateSectionReferences(ctx context.Context, sectionIDHex string, report *reportshared.Report) ([]string, error)
 	EnhanceSummary(ctx context.Context, payload map[string]interface{}) (string, error)
+	GetSuggestion(ctx context.Context, suggestionID, tenantID string) (*AISuggestion, error)
+	// AcceptSuggestion appends the grounded claims of a suggestion to its
+	// section and records their provenance and SectionRefs.
+	AcceptSuggestion(ctx context.Context, suggestionID, tenantID, userID string) (*AISuggestion, error)
 }
@@ -1,6 +1,10 @@
 package report_ai_assistance
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
 
 type ReportSection struct {
 	ID        string `gorm:"primaryKey;type:char(24)"`
@@ -11,6 +15,15 @@ type ReportSection struct {
 	UpdatedAt time.Time
 }
 
+// Suggestion statuses. A suggestion is "flagged" when the validator dropped
+// unsupported sentences and "rejected" when nothing in it was grounded.
+const (
+	SuggestionPending  = "pending"
+	SuggestionFlagged  = "flagged"
+	SuggestionRejected = "rejected"
+	SuggestionAccepted = "accepted"
+)
+
 type AISuggestion struct {
 	ID             string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
 	ReportID       string    `gorm:"type:uuid;not null;index"`
@@ -20,15 +33,31 @@ type AISuggestion struct {
 	Status         string    `gorm:"type:varchar(20);default:'pending'"`
 	CreatedByAI    bool      `gorm:"type:boolean;default:true"`
 	CreatedAt      time.Time `gorm:"type:timestamp;default:now()"`
+
+	// Claims holds every drafted sentence with its references and validation
+	// result, including the ones left out of SuggestionText.
+	Claims      datatypes.JSON `gorm:"type:jsonb;default:'[]'::jsonb"`
+	Unsupported int            `gorm:"type:int;default:0"`
+	AcceptedBy  *string        `gorm:"type:uuid"`
+	AcceptedAt  *time.Time     `gorm:"type:timestamp"`
 }
 
+func (AISuggestion) TableName() string { return "report_ai_suggestions" }
+
+// SectionRef ties text in a report section to the evidence, timeline event
+// or IOC that supports it.
 type SectionRef struct {
-	ID        string `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	SectionID string `gorm:"type:uuid;not null;index"`
-	RefType   string `gorm:"size:100;not null"` // e.g., "evidence", "IOC"
-	RefID     string `gorm:"type:uuid;not null"`
+	ID           string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	SectionID    string    `gorm:"type:char(24);not null;index"`
+	RefType      string    `gorm:"size:100;not null"` // evidence, timeline_event, ioc
+	RefID        string    `gorm:"type:varchar(64);not null"`
+	SuggestionID *string   `gorm:"type:uuid;index"` // set for machine-drafted text
+	ClaimIndex   int       `gorm:"type:int;default:0"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
 }
 
+func (SectionRef) TableName() string { return "report_section_refs" }
+
 type AIFeedback struct {
 	ID           string `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
 	SuggestionID string `gorm:"type:uuid;not null;index"`
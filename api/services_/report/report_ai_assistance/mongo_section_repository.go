@@ -3,6 +3,7 @@ package report_ai_assistance
 import (
 	"context"
 	"errors"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -40,3 +41,30 @@ func (r *MongoSectionRepository) GetSectionByID(ctx context.Context, reportID pr
 	}
 	return nil, errors.New("section not found")
 }
+
+// AppendAcceptedDraft replaces the section content and records prov, but only
+// if the section has not been edited since it was read (updated_at matches).
+func (r *MongoSectionRepository) AppendAcceptedDraft(ctx context.Context, reportID, sectionID primitive.ObjectID, readAt time.Time, content string, prov report.SectionProvenance) error {
+	match := bson.M{"_id": sectionID}
+	if !readAt.IsZero() {
+		match["updated_at"] = readAt
+	}
+	now := time.Now()
+	res, err := r.db.Collection("report_contents").UpdateOne(ctx,
+		bson.M{"_id": reportID, "sections": bson.M{"$elemMatch": match}},
+		bson.M{
+			"$set": bson.M{
+				"sections.$.content":    content,
+				"sections.$.updated_at": now,
+				"updated_at":            now,
+			},
+			"$push": bson.M{"sections.$.provenance": prov},
+		})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrSectionChanged
+	}
+	return nil
+}
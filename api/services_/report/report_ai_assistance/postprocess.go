@@ -2,46 +2,41 @@ package report_ai_assistance
 
 import (
 	"fmt"
-	"regexp"
 	"strings"
 )
 
-// PostProcessEnhancedSummary cleans up AI-generated summary output by removing boilerplate and repeated lines.
+// PostProcessEnhancedSummary cleans up AI-generated summary output by removing boilerplate lines.
 func PostProcessEnhancedSummary(text string) string {
+	return PostProcessEnhancedSummaryWithIocs(text, nil)
+}
+
+// PostProcessEnhancedSummaryWithIocs is PostProcessEnhancedSummary with the
+// case IOCs available to the evidence humanizer. Free text is never reworded
+// or truncated: in a court report "evidence" must stay "evidence", so only
+// boilerplate sentences are dropped.
+func PostProcessEnhancedSummaryWithIocs(text string, iocs []IOC) string {
 	// If input looks like structured case info, humanize it
 	if strings.Contains(text, "Case Title:") && strings.Contains(text, "Status:") {
 		return humanizeCaseInfo(text)
 	}
 	if strings.Contains(text, "Evidence:") {
-		// Try to extract IOCs from context if available
-		var iocs []IOC
-		// Try to extract from a global context (if available)
-		// This is a stub: in real use, pass iocs from payload
-		// If you want to pass iocs from the payload, update the handler to call humanizeEvidence(text, iocs)
 		return humanizeEvidence(text, iocs)
 	}
 	if strings.Contains(text, "Timeline Event") {
 		return humanizeTimeline(text)
 	}
-	// Replace technical jargon with simple terms
-	replacements := map[string]string{
-		"protocol":   "rules",
-		"securely":   "safely",
-		"procedures": "steps",
-		"collected":  "gathered",
-		"evidence":   "proof",
-		"stored":     "kept",
-		"as per":     "according to",
-	}
 	// Remove boilerplate phrases
 	boilerplate := []string{
 		"This summary was generated by AI.",
 		"Please review and edit as needed.",
 		"In summary:",
 	}
-	// Split into sentences (simple period-based split)
-	sentences := splitSentences(text)
-	result := ""
+	// Split into sentences without breaking dotted values such as IPs
+	var sentences []string
+	for _, line := range strings.Split(text, "\n") {
+		sentences = append(sentences, splitClaimSentences(line)...)
+	}
+	var kept []string
 	for _, s := range sentences {
 		trimmed := trimSpace(s)
 		if trimmed == "" {
@@ -54,20 +49,11 @@ func PostProcessEnhancedSummary(text string) string {
 				break
 			}
 		}
-		if skip {
-			continue
-		}
-		// Replace jargon
-		for k, v := range replacements {
-			trimmed = replaceWord(trimmed, k, v)
+		if !skip {
+			kept = append(kept, trimmed)
 		}
-		// Shorten sentence if possible
-		if len(trimmed) > 120 {
-			trimmed = shortenSentence(trimmed)
-		}
-		result += trimmed + ". "
 	}
-	return result
+	return strings.Join(kept, " ")
 }
 
 // humanizeCaseInfo parses key-value pairs and generates a readable summary
@@ -230,38 +216,7 @@ func humanizeTimeline(text string) string {
 	return summary
 }
 
-func splitSentences(s string) []string {
-	// Naive split by period
-	var out []string
-	curr := ""
-	for _, r := range s {
-		curr += string(r)
-		if r == '.' {
-			out = append(out, curr)
-			curr = ""
-		}
-	}
-	if curr != "" {
-		out = append(out, curr)
-	}
-	return out
-}
 func trimSpace(s string) string {
 	// Remove leading/trailing whitespace
 	return strings.TrimSpace(s)
 }
-
-func replaceWord(s, old, new string) string {
-	// Replace whole word only (case-insensitive)
-	re := regexp.MustCompile(`\b` + regexp.QuoteMeta(old) + `\b`)
-	return re.ReplaceAllString(s, new)
-}
-
-func shortenSentence(s string) string {
-	// Naive shortening: keep first 15 words
-	words := []rune(s)
-	if len(words) > 120 {
-		return string(words[:120]) + "..."
-	}
-	return s
-}
@@ -65,6 +65,10 @@ func NewGormAISuggestionRepo(db *gorm.DB) *GormAISuggestionRepo {
 	return &GormAISuggestionRepo{db: db}
 }
 
+func (r *GormAISuggestionRepo) AutoMigrate() error {
+	return r.db.AutoMigrate(&AISuggestion{})
+}
+
 func (r *GormAISuggestionRepo) GetSuggestionByID(ctx context.Context, id string) (*AISuggestion, error) {
 	var s AISuggestion
 	if err := r.db.WithContext(ctx).First(&s, "id = ?", id).Error; err != nil {
@@ -92,6 +96,27 @@ func (r *GormAISuggestionRepo) ListSuggestionsBySection(ctx context.Context, sec
 	return suggestions, nil
 }
 
+// MarkAccepted moves a pending or flagged suggestion to accepted. It fails
+// with ErrSuggestionNotAcceptable if another request got there first.
+func (r *GormAISuggestionRepo) MarkAccepted(ctx context.Context, id, userID string, at time.Time) error {
+	res := r.db.WithContext(ctx).Model(&AISuggestion{}).
+		Where("id = ? AND status IN ?", id, []string{SuggestionPending, SuggestionFlagged}).
+		Updates(map[string]interface{}{"status": SuggestionAccepted, "accepted_by": userID, "accepted_at": at})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrSuggestionNotAcceptable
+	}
+	return nil
+}
+
+// ReopenSuggestion undoes MarkAccepted when the section could not be updated.
+func (r *GormAISuggestionRepo) ReopenSuggestion(ctx context.Context, id, status string) error {
+	return r.db.WithContext(ctx).Model(&AISuggestion{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": status, "accepted_by": nil, "accepted_at": nil}).Error
+}
+
 // --------------------- SectionRefsRepository ---------------------
 type GormSectionRefsRepo struct {
 	db *gorm.DB
@@ -101,6 +126,10 @@ func NewGormSectionRefsRepo(db *gorm.DB) *GormSectionRefsRepo {
 	return &GormSectionRefsRepo{db: db}
 }
 
+func (r *GormSectionRefsRepo) AutoMigrate() error {
+	return r.db.AutoMigrate(&SectionRef{})
+}
+
 func (r *GormSectionRefsRepo) GetRefsBySection(ctx context.Context, sectionID string) ([]*SectionRef, error) {
 	var refs []*SectionRef
 	if err := r.db.WithContext(ctx).Where("section_id = ?", sectionID).Find(&refs).Error; err != nil {
@@ -8,8 +8,8 @@ import (
 	reportshared "aegis-api/services_/report/shared"
 	"aegis-api/services_/timeline"
 	"context"
+	"encoding/json"
 	"fmt"
-	"strings"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -77,19 +77,7 @@ func (s *reportService) GenerateSectionSuggestionWithContext(ctx context.Context
 		}
 	}
 
-	suggestionText, err := s.aiClient.GenerateSuggestion(ctx, aiInput)
-	if err != nil {
-		return nil, fmt.Errorf("AI engine failed: %w", err)
-	}
-	suggestion := &AISuggestion{
-		ReportID:       reportIDHex,
-		SectionID:      sectionIDHex,
-		SuggestionText: suggestionText,
-	}
-	if err := s.aiRepo.CreateSuggestion(ctx, suggestion); err != nil {
-		return nil, fmt.Errorf("failed to save suggestion: %w", err)
-	}
-	return suggestion, nil
+	return s.draftSuggestion(ctx, section.ReportID, sectionIDHex, aiInput)
 }
 
 // reportService handles AI-assisted reporting
@@ -122,60 +110,6 @@ func (s *reportService) EnhanceSummary(ctx context.Context, payload map[string]i
 	return cleaned, nil
 }
 
-// Wrapper to allow passing IOCs to evidence humanizer
-func PostProcessEnhancedSummaryWithIocs(text string, iocs []IOC) string {
-	if strings.Contains(text, "Case Title:") && strings.Contains(text, "Status:") {
-		return humanizeCaseInfo(text)
-	}
-	if strings.Contains(text, "Evidence:") {
-		return humanizeEvidence(text, iocs)
-	}
-	if strings.Contains(text, "Timeline Event") {
-		return humanizeTimeline(text)
-	}
-	// Fallback: original logic
-	replacements := map[string]string{
-		"protocol":   "rules",
-		"securely":   "safely",
-		"procedures": "steps",
-		"collected":  "gathered",
-		"evidence":   "proof",
-		"stored":     "kept",
-		"as per":     "according to",
-	}
-	boilerplate := []string{
-		"This summary was generated by AI.",
-		"Please review and edit as needed.",
-		"In summary:",
-	}
-	sentences := splitSentences(text)
-	result := ""
-	for _, s := range sentences {
-		trimmed := trimSpace(s)
-		if trimmed == "" {
-			continue
-		}
-		skip := false
-		for _, b := range boilerplate {
-			if trimmed == b {
-				skip = true
-				break
-			}
-		}
-		if skip {
-			continue
-		}
-		for k, v := range replacements {
-			trimmed = replaceWord(trimmed, k, v)
-		}
-		if len(trimmed) > 120 {
-			trimmed = shortenSentence(trimmed)
-		}
-		result += trimmed + ". "
-	}
-	return result
-}
-
 // NewReportService creates a new reportService instance
 func NewReportService(
 	sectionRepo *MongoSectionRepository,
@@ -235,11 +169,11 @@ func (s *reportService) GenerateAISuggestion(ctx context.Context, reportIDHex st
 
 	// Fetch timeline events
 	timelineRepo := timeline.NewRepository(db)
-	timelineEvents, _ := timelineRepo.ListByCase(reportUUID)
+	timelineEvents, _ := timelineRepo.ListByCase(caseID)
 
 	// Fetch IOCs
 	iocRepo := graphicalmapping.NewIOCRepository(db)
-	iocs, _ := iocRepo.ListByCase(reportUUID)
+	iocs, _ := iocRepo.ListByCase(caseID)
 
 	// Fetch evidence
 	evidenceRepo := metadata.NewGormRepository(db)
@@ -277,14 +211,44 @@ func (s *reportService) GenerateAISuggestion(ctx context.Context, reportIDHex st
 		IOCs:     iocFlat,
 		Evidence: evidenceFlat,
 	}
-	suggestionText, err := s.aiClient.GenerateSuggestion(ctx, aiInput)
+	return s.draftSuggestion(ctx, reportUUID, sectionIDHex, aiInput)
+}
+
+// draftSuggestion asks the AI engine for a draft that cites the sources in
+// input, validates every sentence against them and stores the result.
+// Sentences with no supporting reference are kept on the suggestion as
+// flagged claims but left out of SuggestionText.
+func (s *reportService) draftSuggestion(ctx context.Context, reportID, sectionIDHex string, input AISuggestionInput) (*AISuggestion, error) {
+	if input.Sources == nil {
+		input.Sources = NewSourceCatalog(input.Evidence, input.Timeline, input.IOCs)
+	}
+	raw, err := s.aiClient.GenerateSuggestion(ctx, input)
 	if err != nil {
 		return nil, fmt.Errorf("AI engine failed: %w", err)
 	}
+
+	claims := ParseDraft(raw, input.Sources)
+	unsupported := ValidateClaims(claims)
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode claims: %w", err)
+	}
+
+	status := SuggestionPending
+	switch {
+	case len(claims) == 0 || unsupported == len(claims):
+		status = SuggestionRejected
+	case unsupported > 0:
+		status = SuggestionFlagged
+	}
 	suggestion := &AISuggestion{
-		ReportID:       reportIDHex,
+		ReportID:       reportID,
 		SectionID:      sectionIDHex,
-		SuggestionText: suggestionText,
+		SuggestionText: SupportedText(claims),
+		Status:         status,
+		CreatedByAI:    true,
+		Claims:         claimsJSON,
+		Unsupported:    unsupported,
 	}
 	if err := s.aiRepo.CreateSuggestion(ctx, suggestion); err != nil {
 		return nil, fmt.Errorf("failed to save suggestion: %w", err)
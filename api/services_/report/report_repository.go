@@ -28,6 +28,8 @@ type ReportMongoRepository interface {
 	BulkUpdateSections(ctx context.Context, reportID primitive.ObjectID, sections []ReportSection) error
 	// NEW: for a batch of reports, return max(sections.updated_at) per report_id (string UUID)
 	LatestUpdateByReportIDs(ctx context.Context, reportIDs []string, tenantID, teamID string) (map[string]time.Time, error)
+	// SetFrozenFields replaces a section's frozen merge field values; nil clears them.
+	SetFrozenFields(ctx context.Context, reportID, sectionID primitive.ObjectID, frozen []FrozenField, tenantID, teamID string) error
 }
 type ReportMongoRepoImpl struct {
 	collection *mongo.Collection
@@ -171,6 +173,27 @@ func (r *ReportMongoRepoImpl) UpdateSection(ctx context.Context, reportID, secti
 	return nil
 }
 
+func (r *ReportMongoRepoImpl) SetFrozenFields(ctx context.Context, reportID, sectionID primitive.ObjectID, frozen []FrozenField, tenantID, teamID string) error {
+	filter := bson.M{"_id": reportID, "sections._id": sectionID}
+	for k, v := range ttFilter(tenantID, teamID) {
+		filter[k] = v
+	}
+
+	// Freezing does not touch updated_at: the section text is unchanged.
+	update := bson.M{"$set": bson.M{"sections.$.frozen_fields": frozen}}
+	if len(frozen) == 0 {
+		update = bson.M{"$unset": bson.M{"sections.$.frozen_fields": ""}}
+	}
+	res, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrSectionNotFound
+	}
+	return nil
+}
+
 // AddSection
 func (r *ReportMongoRepoImpl) AddSection(ctx context.Context, reportID primitive.ObjectID, section ReportSection, tenantID, teamID string) error {
 	if section.ID.IsZero() {
@@ -248,7 +271,9 @@ func (s *ReportServiceImpl) AddCustomSection(
 		UpdatedAt: time.Now(),
 	}
 	// Insert into MongoDB
-	errMongo := s.mongoRepo.AddSection(ctx, mongoID, section, tenantID, teamID)
+	errMongo := s.RecordSectionEdit(ctx, reportUUID, section.ID, RevisionCreated, func(ctx context.Context) error {
+		return s.mongoRepo.AddSection(ctx, mongoID, section, tenantID, teamID)
+	})
 	if errMongo != nil {
 		return errMongo
 	}
@@ -279,7 +304,20 @@ func (s *ReportServiceImpl) DeleteCustomSection(
 	if err != nil {
 		return err
 	}
-	return s.mongoRepo.DeleteSection(ctx, mongoID, sectionID, tenantID, teamID)
+	content, err := s.mongoRepo.GetReportContent(ctx, mongoID, tenantID, teamID)
+	if err != nil {
+		return err
+	}
+	if content != nil {
+		for _, sec := range content.Sections {
+			if sec.ID == sectionID && sec.Required {
+				return ErrSectionRequired
+			}
+		}
+	}
+	return s.RecordSectionEdit(ctx, reportUUID, sectionID, RevisionDeleted, func(ctx context.Context) error {
+		return s.mongoRepo.DeleteSection(ctx, mongoID, sectionID, tenantID, teamID)
+	})
 }
 
 func (s *ReportServiceImpl) getMongoID(
@@ -322,7 +360,9 @@ func (s *ReportServiceImpl) ReorderSection(ctx context.Context, reportUUID uuid.
 	sections := mongoDoc.Sections
 	sort.SliceStable(sections, func(i, j int) bool { return sections[i].Order < sections[j].Order })
 
-	return s.mongoRepo.UpdateSections(ctx, mongoDoc.ID, sections, mongoDoc.TenantID, mongoDoc.TeamID)
+	return s.RecordSectionEdit(ctx, reportUUID, sectionID, RevisionReordered, func(ctx context.Context) error {
+		return s.mongoRepo.UpdateSections(ctx, mongoDoc.ID, sections, mongoDoc.TenantID, mongoDoc.TeamID)
+	})
 
 }
 
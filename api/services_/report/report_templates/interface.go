@@ -0,0 +1,37 @@
+package report_templates
+
+import (
+	"aegis-api/services_/report"
+)
+
+type Repository interface {
+	AutoMigrate() error
+	// CreateTemplate inserts t and its first version in one transaction.
+	CreateTemplate(t *ReportTemplate, v *TemplateVersion) error
+	// AddVersion appends v and bumps the template's current version, failing
+	// with ErrVersionConflict if the template moved on since it was read.
+	AddVersion(t *ReportTemplate, v *TemplateVersion) error
+	GetTemplate(tenantID, id string) (*ReportTemplate, error)
+	GetVersion(templateID string, version int) (*TemplateVersion, error)
+	ListVersions(templateID string) ([]TemplateVersion, error)
+	ListTemplates(tenantID, caseType string, includeArchived bool) ([]ReportTemplate, error)
+	// GetDefault returns the tenant's default for caseType, or nil.
+	GetDefault(tenantID, caseType string) (*ReportTemplate, error)
+	// SetDefault makes id the only default for its case type.
+	SetDefault(tenantID, id, caseType string) error
+	Archive(tenantID, id string) error
+}
+
+type Service interface {
+	CreateTemplate(tenantID, userID string, in TemplateInput) (*TemplateDetail, error)
+	// UpdateTemplate records in as a new version; earlier versions are kept
+	// so reports generated from them can still be traced.
+	UpdateTemplate(tenantID, userID, id string, in TemplateInput) (*TemplateDetail, error)
+	GetTemplate(tenantID, id string, version int) (*TemplateDetail, error)
+	ListTemplates(tenantID, caseType string, includeArchived bool) ([]ReportTemplate, error)
+	ListVersions(tenantID, id string) ([]TemplateVersion, error)
+	SetDefault(tenantID, id string) error
+	ArchiveTemplate(tenantID, id string) error
+
+	report.TemplateResolver
+}
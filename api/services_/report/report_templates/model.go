@@ -0,0 +1,74 @@
+package report_templates
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Well-known case types. Tenants may use others; these are what the UI offers.
+const (
+	CaseTypeIncidentResponse = "incident_response"
+	CaseTypeEDiscovery       = "ediscovery"
+	CaseTypeExpertWitness    = "expert_witness"
+)
+
+// ReportTemplate is a tenant-managed report layout. Its sections live in
+// immutable TemplateVersion rows; editing a template adds a version.
+type ReportTemplate struct {
+	ID             string    `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID       string    `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	Name           string    `gorm:"type:varchar(255);not null" json:"name"`
+	CaseType       string    `gorm:"type:varchar(50);not null;index" json:"case_type"`
+	Description    string    `gorm:"type:text" json:"description"`
+	CurrentVersion int       `gorm:"not null;default:1" json:"current_version"`
+	IsDefault      bool      `gorm:"not null;default:false" json:"is_default"` // default for its case type
+	Archived       bool      `gorm:"not null;default:false" json:"archived"`
+	CreatedBy      string    `gorm:"type:uuid" json:"created_by"`
+	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (ReportTemplate) TableName() string { return "report_templates" }
+
+type TemplateVersion struct {
+	ID         string         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TemplateID string         `gorm:"type:uuid;not null;uniqueIndex:idx_report_template_version,priority:1" json:"template_id"`
+	Version    int            `gorm:"not null;uniqueIndex:idx_report_template_version,priority:2" json:"version"`
+	Sections   datatypes.JSON `gorm:"type:jsonb;not null" json:"sections"` // []report.TemplateSection
+	ChangeNote string         `gorm:"type:text" json:"change_note,omitempty"`
+	CreatedBy  string         `gorm:"type:uuid" json:"created_by"`
+	CreatedAt  time.Time      `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (TemplateVersion) TableName() string { return "report_template_versions" }
+
+// SectionInput is a section as submitted by a tenant admin.
+type SectionInput struct {
+	Title          string   `json:"title"`
+	DefaultContent string   `json:"default_content"`
+	Required       bool     `json:"required"`
+	Placeholders   []string `json:"placeholders"`
+}
+
+type TemplateInput struct {
+	Name        string         `json:"name"`
+	CaseType    string         `json:"case_type"`
+	Description string         `json:"description"`
+	Sections    []SectionInput `json:"sections"`
+	ChangeNote  string         `json:"change_note"`
+}
+
+// TemplateDetail is a template together with the sections of one version.
+type TemplateDetail struct {
+	ReportTemplate
+	Version  int               `json:"version"`
+	Sections []SectionResponse `json:"sections"`
+}
+
+type SectionResponse struct {
+	Title          string   `json:"title"`
+	DefaultContent string   `json:"default_content"`
+	Required       bool     `json:"required"`
+	Placeholders   []string `json:"placeholders,omitempty"`
+}
@@ -0,0 +1,115 @@
+package report_templates_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"aegis-api/services_/report"
+	"aegis-api/services_/report/report_templates"
+	"aegis-api/tests/fakes"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func ediscoveryInput() report_templates.TemplateInput {
+	return report_templates.TemplateInput{
+		Name:     "E-Discovery Production",
+		CaseType: "eDiscovery",
+		Sections: []report_templates.SectionInput{
+			{Title: "Matter Overview", DefaultContent: "<p>Matter {{ case.title }} handled by {{case.team}}.</p>", Required: true},
+			{Title: "Custodians", DefaultContent: `{{ custody.history }}`, Placeholders: []string{"evidence.count"}},
+			{Title: "Production Log"},
+		},
+	}
+}
+
+func TestCreateTemplate_NormalizesAndExtractsPlaceholders(t *testing.T) {
+	svc := report_templates.NewService(&fakes.Templates{})
+	tenant := uuid.NewString()
+
+	tmpl, err := svc.CreateTemplate(tenant, uuid.NewString(), ediscoveryInput())
+	require.NoError(t, err)
+	require.Equal(t, "ediscovery", tmpl.CaseType)
+	require.Equal(t, 1, tmpl.Version)
+	require.True(t, tmpl.IsDefault, "first template for a case type is its default")
+	require.Equal(t, []string{"case.team", "case.title"}, tmpl.Sections[0].Placeholders)
+	require.True(t, tmpl.Sections[0].Required)
+	require.Equal(t, []string{"custody.history", "evidence.count"}, tmpl.Sections[1].Placeholders)
+
+	second, err := svc.CreateTemplate(tenant, uuid.NewString(), ediscoveryInput())
+	require.NoError(t, err)
+	require.False(t, second.IsDefault)
+}
+
+func TestCreateTemplate_RejectsInvalidInput(t *testing.T) {
+	svc := report_templates.NewService(&fakes.Templates{})
+	cases := map[string]func(*report_templates.TemplateInput){
+		"no name":           func(in *report_templates.TemplateInput) { in.Name = "  " },
+		"bad case type":     func(in *report_templates.TemplateInput) { in.CaseType = "expert witness" },
+		"no sections":       func(in *report_templates.TemplateInput) { in.Sections = nil },
+		"duplicate section": func(in *report_templates.TemplateInput) { in.Sections[2].Title = "custodians" },
+		"bad placeholder":   func(in *report_templates.TemplateInput) { in.Sections[0].DefaultContent = "{{Case-Title}}" },
+		"unknown field":     func(in *report_templates.TemplateInput) { in.Sections[1].Placeholders = []string{"case.client"} },
+	}
+	for name, mutate := range cases {
+		in := ediscoveryInput()
+		mutate(&in)
+		_, err := svc.CreateTemplate(uuid.NewString(), uuid.NewString(), in)
+		require.ErrorIs(t, err, report_templates.ErrInvalidTemplate, name)
+	}
+}
+
+func TestUpdateTemplate_AddsVersionAndKeepsOldOnes(t *testing.T) {
+	svc := report_templates.NewService(&fakes.Templates{})
+	tenant, user := uuid.NewString(), uuid.NewString()
+	tmpl, err := svc.CreateTemplate(tenant, user, ediscoveryInput())
+	require.NoError(t, err)
+
+	in := ediscoveryInput()
+	in.Sections = append(in.Sections, report_templates.SectionInput{Title: "Privilege Log", Required: true})
+	in.ChangeNote = "add privilege log"
+	updated, err := svc.UpdateTemplate(tenant, user, tmpl.ID, in)
+	require.NoError(t, err)
+	require.Equal(t, 2, updated.Version)
+	require.Len(t, updated.Sections, 4)
+
+	v1, err := svc.GetTemplate(tenant, tmpl.ID, 1)
+	require.NoError(t, err)
+	require.Len(t, v1.Sections, 3)
+
+	versions, err := svc.ListVersions(tenant, tmpl.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+
+	_, err = svc.GetTemplate(uuid.NewString(), tmpl.ID, 0)
+	require.ErrorIs(t, err, report_templates.ErrTemplateNotFound, "templates are tenant scoped")
+}
+
+func TestResolveTemplate(t *testing.T) {
+	svc := report_templates.NewService(&fakes.Templates{})
+	tenant := uuid.New()
+	ctx := context.Background()
+
+	snap, err := svc.ResolveTemplate(ctx, tenant, uuid.Nil, report_templates.CaseTypeEDiscovery)
+	require.NoError(t, err)
+	require.Nil(t, snap, "no tenant default means the built-in layout")
+
+	tmpl, err := svc.CreateTemplate(tenant.String(), uuid.NewString(), ediscoveryInput())
+	require.NoError(t, err)
+
+	snap, err = svc.ResolveTemplate(ctx, tenant, uuid.Nil, report_templates.CaseTypeEDiscovery)
+	require.NoError(t, err)
+	require.Equal(t, tmpl.ID, snap.TemplateID.String())
+	require.Equal(t, 1, snap.Version)
+	require.Equal(t, "Matter Overview", snap.Sections[0].Title)
+	require.True(t, snap.Sections[0].Required)
+
+	_, err = svc.ResolveTemplate(ctx, uuid.New(), uuid.MustParse(tmpl.ID), "")
+	require.True(t, errors.Is(err, report.ErrTemplateNotFound))
+
+	require.NoError(t, svc.ArchiveTemplate(tenant.String(), tmpl.ID))
+	_, err = svc.ResolveTemplate(ctx, tenant, uuid.MustParse(tmpl.ID), "")
+	require.ErrorIs(t, err, report_templates.ErrTemplateArchived)
+}
@@ -0,0 +1,125 @@
+package report_templates
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) AutoMigrate() error {
+	return r.db.AutoMigrate(&ReportTemplate{}, &TemplateVersion{})
+}
+
+func (r *GormRepository) CreateTemplate(t *ReportTemplate, v *TemplateVersion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(t).Error; err != nil {
+			return err
+		}
+		v.TemplateID = t.ID
+		return tx.Create(v).Error
+	})
+}
+
+func (r *GormRepository) AddVersion(t *ReportTemplate, v *TemplateVersion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&ReportTemplate{}).
+			Where("id = ? AND tenant_id = ? AND current_version = ?", t.ID, t.TenantID, v.Version-1).
+			Updates(map[string]interface{}{
+				"current_version": v.Version,
+				"name":            t.Name,
+				"case_type":       t.CaseType,
+				"description":     t.Description,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		return tx.Create(v).Error
+	})
+}
+
+func (r *GormRepository) GetTemplate(tenantID, id string) (*ReportTemplate, error) {
+	var t ReportTemplate
+	err := r.db.Where("id = ? AND tenant_id = ?", id, tenantID).First(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTemplateNotFound
+	}
+	return &t, err
+}
+
+func (r *GormRepository) GetVersion(templateID string, version int) (*TemplateVersion, error) {
+	var v TemplateVersion
+	err := r.db.Where("template_id = ? AND version = ?", templateID, version).First(&v).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTemplateNotFound
+	}
+	return &v, err
+}
+
+func (r *GormRepository) ListVersions(templateID string) ([]TemplateVersion, error) {
+	var out []TemplateVersion
+	err := r.db.Where("template_id = ?", templateID).Order("version DESC").Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) ListTemplates(tenantID, caseType string, includeArchived bool) ([]ReportTemplate, error) {
+	var out []ReportTemplate
+	q := r.db.Where("tenant_id = ?", tenantID)
+	if caseType != "" {
+		q = q.Where("case_type = ?", caseType)
+	}
+	if !includeArchived {
+		q = q.Where("archived = ?", false)
+	}
+	err := q.Order("case_type, name").Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) GetDefault(tenantID, caseType string) (*ReportTemplate, error) {
+	var t ReportTemplate
+	err := r.db.Where("tenant_id = ? AND case_type = ? AND is_default = ? AND archived = ?", tenantID, caseType, true, false).
+		First(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *GormRepository) SetDefault(tenantID, id, caseType string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&ReportTemplate{}).
+			Where("tenant_id = ? AND case_type = ? AND id <> ?", tenantID, caseType, id).
+			Update("is_default", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&ReportTemplate{}).
+			Where("tenant_id = ? AND id = ?", tenantID, id).
+			Update("is_default", true).Error
+	})
+}
+
+func (r *GormRepository) Archive(tenantID, id string) error {
+	res := r.db.Model(&ReportTemplate{}).
+		Where("tenant_id = ? AND id = ?", tenantID, id).
+		Updates(map[string]interface{}{"archived": true, "is_default": false})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package report_templates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"aegis-api/services_/report"
+	"aegis-api/services_/report/merge_fields"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTemplateNotFound = report.ErrTemplateNotFound
+	ErrInvalidTemplate  = errors.New("invalid report template")
+	ErrTemplateArchived = errors.New("report template is archived")
+	ErrVersionConflict  = errors.New("report template was modified concurrently; reload and retry")
+)
+
+const (
+	maxSections     = 50
+	maxTitleLen     = 255
+	maxBoilerplate  = 64 << 10
+	maxPlaceholders = 100
+)
+
+var caseTypeRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+func (s *service) CreateTemplate(tenantID, userID string, in TemplateInput) (*TemplateDetail, error) {
+	sections, err := normalize(&in)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.repo.GetDefault(tenantID, in.CaseType)
+	if err != nil {
+		return nil, err
+	}
+	t := &ReportTemplate{
+		TenantID:       tenantID,
+		Name:           in.Name,
+		CaseType:       in.CaseType,
+		Description:    in.Description,
+		CurrentVersion: 1,
+		// The first template for a case type becomes its default.
+		IsDefault: existing == nil,
+		CreatedBy: userID,
+	}
+	v, err := newVersion(1, sections, in.ChangeNote, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateTemplate(t, v); err != nil {
+		return nil, err
+	}
+	return &TemplateDetail{ReportTemplate: *t, Version: 1, Sections: sections}, nil
+}
+
+func (s *service) UpdateTemplate(tenantID, userID, id string, in TemplateInput) (*TemplateDetail, error) {
+	sections, err := normalize(&in)
+	if err != nil {
+		return nil, err
+	}
+	t, err := s.repo.GetTemplate(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if t.Archived {
+		return nil, ErrTemplateArchived
+	}
+	caseTypeChanged := t.CaseType != in.CaseType
+	t.Name, t.CaseType, t.Description = in.Name, in.CaseType, in.Description
+
+	v, err := newVersion(t.CurrentVersion+1, sections, in.ChangeNote, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.AddVersion(t, v); err != nil {
+		return nil, err
+	}
+	t.CurrentVersion = v.Version
+	if caseTypeChanged && t.IsDefault {
+		if err := s.repo.SetDefault(tenantID, t.ID, t.CaseType); err != nil {
+			return nil, err
+		}
+	}
+	return &TemplateDetail{ReportTemplate: *t, Version: v.Version, Sections: sections}, nil
+}
+
+func (s *service) GetTemplate(tenantID, id string, version int) (*TemplateDetail, error) {
+	t, err := s.repo.GetTemplate(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if version <= 0 {
+		version = t.CurrentVersion
+	}
+	v, err := s.repo.GetVersion(t.ID, version)
+	if err != nil {
+		return nil, err
+	}
+	var sections []SectionResponse
+	if err := json.Unmarshal(v.Sections, &sections); err != nil {
+		return nil, fmt.Errorf("decode template sections: %w", err)
+	}
+	return &TemplateDetail{ReportTemplate: *t, Version: v.Version, Sections: sections}, nil
+}
+
+func (s *service) ListTemplates(tenantID, caseType string, includeArchived bool) ([]ReportTemplate, error) {
+	return s.repo.ListTemplates(tenantID, caseType, includeArchived)
+}
+
+func (s *service) ListVersions(tenantID, id string) ([]TemplateVersion, error) {
+	t, err := s.repo.GetTemplate(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListVersions(t.ID)
+}
+
+func (s *service) SetDefault(tenantID, id string) error {
+	t, err := s.repo.GetTemplate(tenantID, id)
+	if err != nil {
+		return err
+	}
+	if t.Archived {
+		return ErrTemplateArchived
+	}
+	return s.repo.SetDefault(tenantID, t.ID, t.CaseType)
+}
+
+func (s *service) ArchiveTemplate(tenantID, id string) error {
+	return s.repo.Archive(tenantID, id)
+}
+
+// ResolveTemplate implements report.TemplateResolver. An explicit template
+// must belong to the tenant and not be archived; otherwise the tenant's
+// default for caseType is used, and nil means the built-in layout.
+func (s *service) ResolveTemplate(_ context.Context, tenantID, templateID uuid.UUID, caseType string) (*report.TemplateSnapshot, error) {
+	var t *ReportTemplate
+	var err error
+	if templateID != uuid.Nil {
+		t, err = s.repo.GetTemplate(tenantID.String(), templateID.String())
+		if err != nil {
+			return nil, err
+		}
+		if t.Archived {
+			// Archived templates can no longer start reports.
+			return nil, fmt.Errorf("%w: %w", ErrTemplateNotFound, ErrTemplateArchived)
+		}
+	} else {
+		if caseType == "" {
+			return nil, nil
+		}
+		t, err = s.repo.GetDefault(tenantID.String(), caseType)
+		if err != nil || t == nil {
+			return nil, err
+		}
+	}
+
+	v, err := s.repo.GetVersion(t.ID, t.CurrentVersion)
+	if err != nil {
+		return nil, err
+	}
+	snap := &report.TemplateSnapshot{Name: t.Name, CaseType: t.CaseType, Version: v.Version}
+	if snap.TemplateID, err = uuid.Parse(t.ID); err != nil {
+		return nil, fmt.Errorf("template id: %w", err)
+	}
+	if err := json.Unmarshal(v.Sections, &snap.Sections); err != nil {
+		return nil, fmt.Errorf("decode template sections: %w", err)
+	}
+	return snap, nil
+}
+
+func newVersion(version int, sections []SectionResponse, note, userID string) (*TemplateVersion, error) {
+	raw, err := json.Marshal(sections)
+	if err != nil {
+		return nil, err
+	}
+	return &TemplateVersion{Version: version, Sections: raw, ChangeNote: note, CreatedBy: userID}, nil
+}
+
+// normalize validates in, trimming names in place, and returns its sections
+// with placeholders merged from the declared list and the boilerplate.
+func normalize(in *TemplateInput) ([]SectionResponse, error) {
+	in.Name = strings.TrimSpace(in.Name)
+	in.CaseType = strings.ToLower(strings.TrimSpace(in.CaseType))
+	switch {
+	case in.Name == "" || len(in.Name) > maxTitleLen:
+		return nil, fmt.Errorf("%w: name is required and must be at most %d characters", ErrInvalidTemplate, maxTitleLen)
+	case !caseTypeRe.MatchString(in.CaseType):
+		return nil, fmt.Errorf("%w: case_type must be lower_snake_case, e.g. %s", ErrInvalidTemplate, CaseTypeIncidentResponse)
+	case len(in.Sections) == 0 || len(in.Sections) > maxSections:
+		return nil, fmt.Errorf("%w: a template needs between 1 and %d sections", ErrInvalidTemplate, maxSections)
+	}
+
+	seen := map[string]bool{}
+	out := make([]SectionResponse, 0, len(in.Sections))
+	for i, sec := range in.Sections {
+		title := strings.TrimSpace(sec.Title)
+		if title == "" || len(title) > maxTitleLen {
+			return nil, fmt.Errorf("%w: section %d needs a title of at most %d characters", ErrInvalidTemplate, i+1, maxTitleLen)
+		}
+		if seen[strings.ToLower(title)] {
+			return nil, fmt.Errorf("%w: duplicate section %q", ErrInvalidTemplate, title)
+		}
+		seen[strings.ToLower(title)] = true
+		if len(sec.DefaultContent) > maxBoilerplate {
+			return nil, fmt.Errorf("%w: boilerplate for %q exceeds 64 KiB", ErrInvalidTemplate, title)
+		}
+
+		fields := map[string]bool{}
+		for _, p := range sec.Placeholders {
+			fields[strings.TrimSpace(p)] = true
+		}
+		for _, f := range merge_fields.Parse(sec.DefaultContent) {
+			if f.Err != "" {
+				return nil, fmt.Errorf("%w: %s in %q: %s", ErrInvalidTemplate, f.Raw, title, f.Err)
+			}
+			fields[f.Name] = true
+		}
+		if len(fields) > maxPlaceholders {
+			return nil, fmt.Errorf("%w: %q has more than %d placeholders", ErrInvalidTemplate, title, maxPlaceholders)
+		}
+		placeholders := make([]string, 0, len(fields))
+		for f := range fields {
+			if _, ok := merge_fields.Lookup(f); !ok {
+				return nil, fmt.Errorf("%w: %q in %q is not a known merge field", ErrInvalidTemplate, f, title)
+			}
+			placeholders = append(placeholders, f)
+		}
+		sort.Strings(placeholders)
+
+		out = append(out, SectionResponse{
+			Title:          title,
+			DefaultContent: sec.DefaultContent,
+			Required:       sec.Required,
+			Placeholders:   placeholders,
+		})
+	}
+	return out, nil
+}
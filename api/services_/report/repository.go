@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -21,6 +22,8 @@ type ReportRepository interface {
 	UpdateReportName(ctx context.Context, reportID uuid.UUID, name string) (*Report, error)
 	ListRecentCandidates(ctx context.Context, opts RecentReportsOptions, candidateLimit int) ([]Report, error)
 	GetReportsByTeamID(ctx context.Context, tenantID, teamID uuid.UUID) ([]ReportWithDetails, error)
+	// SetFieldsFrozenAt records when merge fields were frozen; nil thaws them.
+	SetFieldsFrozenAt(ctx context.Context, reportID uuid.UUID, at *time.Time) error
 }
 
 type ReportsRepoImpl struct {
@@ -44,7 +47,7 @@ func (repo *ReportsRepoImpl) ListRecentCandidates(
 		candidateLimit = 60
 	}
 
-	q := repo.DB.WithContext(ctx).Model(&Report{})
+	q := repo.DB.WithContext(ctx).Model(&Report{}).Where("tenant_id = ?", opts.TenantID)
 
 	// Filters
 	if opts.MineOnly && opts.ExaminerID != uuid.Nil {
@@ -192,6 +195,17 @@ func (repo *ReportsRepoImpl) DownloadReport(ctx context.Context, reportID uuid.U
 	return &report, nil
 }
 
+func (r *ReportsRepoImpl) SetFieldsFrozenAt(ctx context.Context, reportID uuid.UUID, at *time.Time) error {
+	res := r.DB.WithContext(ctx).Model(&Report{}).Where("id = ?", reportID).Update("fields_frozen_at", at)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrReportNotFound
+	}
+	return nil
+}
+
 func (r *ReportsRepoImpl) UpdateReportName(ctx context.Context, reportID uuid.UUID, name string) (*Report, error) {
 	// Bump version + touch updated_at. NOW() is Postgres/MySQL; switch to CURRENT_TIMESTAMP if you prefer.
 	res := r.DB.WithContext(ctx).Exec(`
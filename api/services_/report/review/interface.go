@@ -0,0 +1,74 @@
+package review
+
+import (
+	"context"
+
+	"aegis-api/services_/report"
+	"aegis-api/services_/report/update_status"
+
+	"github.com/google/uuid"
+)
+
+type Repository interface {
+	AutoMigrate() error
+	// GetWorkflow returns the tenant's workflow, or nil if it has none.
+	GetWorkflow(tenantID string) (*Workflow, error)
+	SaveWorkflow(w *Workflow) error
+
+	// LatestReview returns the report's most recent review, or nil.
+	LatestReview(reportID string) (*Review, error)
+	// CreateReview inserts r and its submission decision together, failing
+	// with ErrReviewConflict if the report already has an open review.
+	CreateReview(r *Review, d *Decision) error
+	// UpdateReview saves r and appends decisions, provided nobody else
+	// updated the review since r was read (r.Version); otherwise it returns
+	// ErrReviewConflict and changes nothing.
+	UpdateReview(r *Review, decisions ...*Decision) error
+	ListDecisions(reviewID string) ([]Decision, error)
+
+	CreateComment(c *Comment) error
+	GetComment(reportID, id string) (*Comment, error)
+	ListComments(reportID, sectionID string, includeResolved bool) ([]Comment, error)
+	ResolveComment(c *Comment) error
+
+	// UsersWithRoles lists the IDs of tenant users holding any of roles.
+	UsersWithRoles(tenantID string, roles []string) ([]string, error)
+}
+
+// Reports is the part of report.ReportService the workflow needs.
+type Reports interface {
+	GetReportByID(ctx context.Context, reportID string) (*report.Report, error)
+	DownloadReport(ctx context.Context, reportID uuid.UUID) (*report.ReportWithContent, error)
+	FreezeFields(ctx context.Context, reportID uuid.UUID, fields report.FieldRenderer) (*report.ReportWithContent, error)
+	PinSnapshot(ctx context.Context, reportID uuid.UUID, version int, fields report.FieldRenderer) (*report.ReportSnapshot, error)
+}
+
+// StatusUpdater moves the report's status column; update_status provides it.
+type StatusUpdater interface {
+	UpdateStatus(ctx context.Context, reportID uuid.UUID, status update_status.ReportStatus) (*update_status.Report, error)
+}
+
+// Notifier delivers a notification to a user. Delivery is best effort.
+type Notifier interface {
+	Notify(userID, tenantID, teamID, title, message string)
+}
+
+type Service interface {
+	GetWorkflow(tenantID string) ([]Stage, error)
+	SetWorkflow(tenantID, userID string, stages []Stage) ([]Stage, error)
+
+	Status(ctx context.Context, actor Actor, reportID uuid.UUID) (*Status, error)
+	// Submit moves a draft into review at the first stage.
+	Submit(ctx context.Context, actor Actor, reportID uuid.UUID) (*Status, error)
+	// Approve records the actor's approval of the current stage. Completing
+	// the last stage publishes the report and pins its snapshot.
+	Approve(ctx context.Context, actor Actor, reportID uuid.UUID, comment string, freezeFields bool) (*Status, error)
+	// RequestChanges ends the review and returns the report to draft.
+	RequestChanges(ctx context.Context, actor Actor, reportID uuid.UUID, comment string) (*Status, error)
+	// Withdraw lets the author or submitter take a report back to draft.
+	Withdraw(ctx context.Context, actor Actor, reportID uuid.UUID) (*Status, error)
+
+	AddComment(ctx context.Context, actor Actor, reportID uuid.UUID, in CommentInput) (*Comment, error)
+	ListComments(ctx context.Context, actor Actor, reportID uuid.UUID, sectionID string, includeResolved bool) ([]Comment, error)
+	ResolveComment(ctx context.Context, actor Actor, reportID uuid.UUID, commentID string) (*Comment, error)
+}
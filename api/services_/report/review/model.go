@@ -0,0 +1,132 @@
+package review
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Review states.
+const (
+	StateInReview         = "in_review"
+	StateChangesRequested = "changes_requested"
+	StateWithdrawn        = "withdrawn"
+	StatePublished        = "published"
+)
+
+// Decision kinds. Every transition is recorded as a decision.
+const (
+	DecisionSubmitted        = "submitted"
+	DecisionApproved         = "approved"
+	DecisionChangesRequested = "changes_requested"
+	DecisionWithdrawn        = "withdrawn"
+	DecisionPublished        = "published"
+)
+
+// Stage is one approval step. Reviewers must hold one of Roles; an empty
+// list accepts any role. The stage completes after MinApprovals distinct
+// approvals.
+type Stage struct {
+	Name         string   `json:"name"`
+	Roles        []string `json:"roles"`
+	MinApprovals int      `json:"min_approvals"`
+}
+
+// Workflow is a tenant's review configuration.
+type Workflow struct {
+	TenantID  string         `gorm:"type:uuid;primaryKey" json:"tenant_id"`
+	Stages    datatypes.JSON `gorm:"type:jsonb;not null" json:"stages"` // []Stage
+	UpdatedBy string         `gorm:"type:uuid" json:"updated_by,omitempty"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (Workflow) TableName() string { return "report_review_workflows" }
+
+// DefaultStages apply to tenants that have not configured a workflow.
+func DefaultStages() []Stage {
+	return []Stage{{Name: "Peer review", Roles: []string{"DFIR Admin", "DFIR Manager"}, MinApprovals: 1}}
+}
+
+// Review is one round of review of a report, from submission until it is
+// published, withdrawn or sent back with change requests. The workflow's
+// stages are copied in so later workflow edits do not affect it.
+type Review struct {
+	ID           string         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ReportID     string         `gorm:"type:uuid;not null;index" json:"report_id"`
+	TenantID     string         `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	Round        int            `gorm:"not null" json:"round"`
+	Stages       datatypes.JSON `gorm:"type:jsonb;not null" json:"stages"` // []Stage
+	CurrentStage int            `gorm:"not null;default:0" json:"current_stage"`
+	State        string         `gorm:"type:varchar(20);not null" json:"state"`
+	// AuthorID is the report's examiner; neither they nor the submitter may
+	// approve.
+	AuthorID    string     `gorm:"type:uuid;not null" json:"author_id"`
+	SubmittedBy string     `gorm:"type:uuid;not null" json:"submitted_by"`
+	SubmittedAt time.Time  `gorm:"not null" json:"submitted_at"`
+	ClosedAt    *time.Time `json:"closed_at,omitempty"`
+	// Version guards concurrent decisions; every update increments it.
+	Version int `gorm:"not null;default:1" json:"version"`
+}
+
+func (Review) TableName() string { return "report_reviews" }
+
+// Decision records a transition of a review by one user.
+type Decision struct {
+	ID       string `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ReviewID string `gorm:"type:uuid;not null;index" json:"review_id"`
+	ReportID string `gorm:"type:uuid;not null;index" json:"report_id"`
+	Stage    int    `gorm:"not null" json:"stage"`
+	UserID   string `gorm:"type:uuid;not null" json:"user_id"`
+	UserRole string `gorm:"type:varchar(100)" json:"user_role"`
+	Decision string `gorm:"type:varchar(20);not null" json:"decision"`
+	Comment  string `gorm:"type:text" json:"comment,omitempty"`
+	// Snapshot is set on the decision that published the report.
+	SnapshotID *string   `gorm:"type:uuid" json:"snapshot_id,omitempty"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (Decision) TableName() string { return "report_review_decisions" }
+
+// Comment is a reviewer comment anchored to a section, optionally to a
+// quoted passage within it. Replies set ParentID.
+type Comment struct {
+	ID         string     `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ReportID   string     `gorm:"type:uuid;not null;index" json:"report_id"`
+	ReviewID   *string    `gorm:"type:uuid" json:"review_id,omitempty"`
+	SectionID  string     `gorm:"type:char(24);not null;index" json:"section_id"`
+	Quote      string     `gorm:"type:text" json:"quote,omitempty"`
+	ParentID   *string    `gorm:"type:uuid" json:"parent_id,omitempty"`
+	AuthorID   string     `gorm:"type:uuid;not null" json:"author_id"`
+	Body       string     `gorm:"type:text;not null" json:"body"`
+	Resolved   bool       `gorm:"not null;default:false" json:"resolved"`
+	ResolvedBy *string    `gorm:"type:uuid" json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (Comment) TableName() string { return "report_review_comments" }
+
+// Actor is the user performing a review action.
+type Actor struct {
+	UserID   string
+	Role     string
+	TenantID string
+}
+
+// CommentInput is a new comment or reply.
+type CommentInput struct {
+	SectionID string  `json:"section_id"`
+	Quote     string  `json:"quote"`
+	ParentID  *string `json:"parent_id"`
+	Body      string  `json:"body"`
+}
+
+// Status is a report's review state as shown to clients.
+type Status struct {
+	ReportStatus string     `json:"report_status"`
+	Review       *Review    `json:"review,omitempty"`
+	Stages       []Stage    `json:"stages,omitempty"`
+	Decisions    []Decision `json:"decisions"`
+	// CanApprove tells the caller whether they may decide on the current stage.
+	CanApprove bool `json:"can_approve"`
+}
@@ -0,0 +1,29 @@
+package review
+
+import (
+	"log"
+
+	"aegis-api/pkg/websocket"
+	"aegis-api/services_/notification"
+)
+
+// HubNotifier stores a notification and pushes it over the websocket hub.
+type HubNotifier struct {
+	hub           *websocket.Hub
+	notifications notification.NotificationServiceInterface
+}
+
+func NewHubNotifier(hub *websocket.Hub, notifications notification.NotificationServiceInterface) *HubNotifier {
+	return &HubNotifier{hub: hub, notifications: notifications}
+}
+
+func (n *HubNotifier) Notify(userID, tenantID, teamID, title, message string) {
+	if n.hub == nil || n.notifications == nil {
+		return
+	}
+	go func() {
+		if err := websocket.NotifyUser(n.hub, n.notifications, userID, tenantID, teamID, title, message); err != nil {
+			log.Printf("[review] notifying %s failed: %v", userID, err)
+		}
+	}()
+}
@@ -0,0 +1,147 @@
+package review
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) AutoMigrate() error {
+	return r.db.AutoMigrate(&Workflow{}, &Review{}, &Decision{}, &Comment{})
+}
+
+func (r *GormRepository) GetWorkflow(tenantID string) (*Workflow, error) {
+	var w Workflow
+	err := r.db.Where("tenant_id = ?", tenantID).First(&w).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &w, err
+}
+
+func (r *GormRepository) SaveWorkflow(w *Workflow) error {
+	w.UpdatedAt = time.Now()
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"stages", "updated_by", "updated_at"}),
+	}).Create(w).Error
+}
+
+func (r *GormRepository) LatestReview(reportID string) (*Review, error) {
+	var rev Review
+	err := r.db.Where("report_id = ?", reportID).Order("round DESC").First(&rev).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &rev, err
+}
+
+func (r *GormRepository) CreateReview(rev *Review, d *Decision) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Serialise submissions of the same report so two racing submits
+		// cannot both open a review.
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "review:"+rev.ReportID).Error; err != nil {
+			return err
+		}
+		var open int64
+		if err := tx.Model(&Review{}).
+			Where("report_id = ? AND state = ?", rev.ReportID, StateInReview).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return ErrReviewConflict
+		}
+		if err := tx.Create(rev).Error; err != nil {
+			return err
+		}
+		d.ReviewID = rev.ID
+		return tx.Create(d).Error
+	})
+}
+
+func (r *GormRepository) UpdateReview(rev *Review, decisions ...*Decision) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Review{}).
+			Where("id = ? AND version = ?", rev.ID, rev.Version).
+			Updates(map[string]interface{}{
+				"current_stage": rev.CurrentStage,
+				"state":         rev.State,
+				"closed_at":     rev.ClosedAt,
+				"version":       rev.Version + 1,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrReviewConflict
+		}
+		for _, d := range decisions {
+			if err := tx.Create(d).Error; err != nil {
+				return err
+			}
+		}
+		rev.Version++
+		return nil
+	})
+}
+
+func (r *GormRepository) ListDecisions(reviewID string) ([]Decision, error) {
+	var out []Decision
+	err := r.db.Where("review_id = ?", reviewID).Order("created_at").Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) CreateComment(c *Comment) error {
+	return r.db.Create(c).Error
+}
+
+func (r *GormRepository) GetComment(reportID, id string) (*Comment, error) {
+	var c Comment
+	err := r.db.Where("report_id = ? AND id = ?", reportID, id).First(&c).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCommentNotFound
+	}
+	return &c, err
+}
+
+func (r *GormRepository) ListComments(reportID, sectionID string, includeResolved bool) ([]Comment, error) {
+	q := r.db.Where("report_id = ?", reportID)
+	if sectionID != "" {
+		q = q.Where("section_id = ?", sectionID)
+	}
+	if !includeResolved {
+		q = q.Where("resolved = FALSE")
+	}
+	var out []Comment
+	err := q.Order("created_at").Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) ResolveComment(c *Comment) error {
+	return r.db.Model(&Comment{}).
+		Where("id = ? AND report_id = ?", c.ID, c.ReportID).
+		Updates(map[string]interface{}{
+			"resolved":    true,
+			"resolved_by": c.ResolvedBy,
+			"resolved_at": c.ResolvedAt,
+		}).Error
+}
+
+func (r *GormRepository) UsersWithRoles(tenantID string, roles []string) ([]string, error) {
+	var ids []string
+	err := r.db.Table("users").
+		Where("tenant_id = ? AND role IN ?", tenantID, roles).
+		Pluck("id", &ids).Error
+	return ids, err
+}
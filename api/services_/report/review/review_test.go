@@ -0,0 +1,189 @@
+package review_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"aegis-api/services_/report"
+	"aegis-api/services_/report/review"
+	"aegis-api/tests/fakes"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fixture struct {
+	svc      review.Service
+	repo     *fakes.Reviews
+	reports  *fakes.Reports
+	notifier *fakes.Notifier
+	report   *report.Report
+	reportID uuid.UUID
+	section  primitive.ObjectID
+	author   review.Actor
+}
+
+func newFixture(t *testing.T, stages ...review.Stage) *fixture {
+	t.Helper()
+	tenant, author := uuid.New(), uuid.New()
+	rep := &report.Report{ID: uuid.New(), TenantID: tenant, TeamID: uuid.New(), ExaminerID: author, Name: "Intrusion report", Status: "draft", Version: 1}
+	f := &fixture{
+		repo:     &fakes.Reviews{},
+		reports:  &fakes.Reports{},
+		notifier: &fakes.Notifier{},
+		report:   rep,
+		reportID: rep.ID,
+		author:   review.Actor{UserID: author.String(), Role: "DFIR Analyst", TenantID: tenant.String()},
+	}
+	f.section = primitive.NewObjectID()
+	f.reports.Add(rep, report.ReportSection{ID: f.section, Title: "Findings"})
+	f.svc = review.NewService(f.repo, f.reports, f.reports, nil, f.notifier)
+	if len(stages) > 0 {
+		_, err := f.svc.SetWorkflow(tenant.String(), uuid.NewString(), stages)
+		require.NoError(t, err)
+	}
+	return f
+}
+
+func (f *fixture) reviewer(role string) review.Actor {
+	id := uuid.NewString()
+	f.repo.SetRole(id, role)
+	return review.Actor{UserID: id, Role: role, TenantID: f.author.TenantID}
+}
+
+func TestAuthorCannotApproveOwnReport(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, review.Stage{Name: "QA", MinApprovals: 1})
+	// The author holds a reviewer role, but it is still their report.
+	f.author.Role = "DFIR Admin"
+
+	_, err := f.svc.Submit(ctx, f.author, f.reportID)
+	require.NoError(t, err)
+	require.Equal(t, "review", f.report.Status)
+
+	_, err = f.svc.Approve(ctx, f.author, f.reportID, "", false)
+	require.ErrorIs(t, err, review.ErrSelfApproval)
+
+	// Nor may a colleague who submitted it on the author's behalf.
+	f.report.Status = "draft"
+	f.repo.Reviews = nil
+	submitter := f.reviewer("DFIR Admin")
+	_, err = f.svc.Submit(ctx, submitter, f.reportID)
+	require.NoError(t, err)
+	_, err = f.svc.Approve(ctx, submitter, f.reportID, "", false)
+	require.ErrorIs(t, err, review.ErrSelfApproval)
+}
+
+func TestMultiStageApprovalPublishes(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t,
+		review.Stage{Name: "Peer review", Roles: []string{"DFIR Analyst", "DFIR Admin"}, MinApprovals: 2},
+		review.Stage{Name: "Sign-off", Roles: []string{"DFIR Manager"}, MinApprovals: 1},
+	)
+	peer1, peer2 := f.reviewer("DFIR Analyst"), f.reviewer("DFIR Admin")
+	manager := f.reviewer("DFIR Manager")
+
+	_, err := f.svc.Submit(ctx, f.author, f.reportID)
+	require.NoError(t, err)
+	require.True(t, f.notifier.Received(peer1.UserID, "Report awaiting your review"))
+	require.False(t, f.notifier.Received(manager.UserID, "Report awaiting your review"))
+
+	_, err = f.svc.Approve(ctx, manager, f.reportID, "", false)
+	require.ErrorIs(t, err, review.ErrNotEligible)
+
+	st, err := f.svc.Approve(ctx, peer1, f.reportID, "looks good", false)
+	require.NoError(t, err)
+	require.Equal(t, 0, st.Review.CurrentStage)
+
+	_, err = f.svc.Approve(ctx, peer1, f.reportID, "", false)
+	require.ErrorIs(t, err, review.ErrAlreadyDecided)
+
+	st, err = f.svc.Approve(ctx, peer2, f.reportID, "", false)
+	require.NoError(t, err)
+	require.Equal(t, 1, st.Review.CurrentStage)
+	require.True(t, f.notifier.Received(manager.UserID, "Report awaiting your review"))
+
+	// A peer who already approved cannot also sign off.
+	f.repo.SetRole(peer2.UserID, "DFIR Manager")
+	peer2.Role = "DFIR Manager"
+	_, err = f.svc.Approve(ctx, peer2, f.reportID, "", false)
+	require.ErrorIs(t, err, review.ErrAlreadyDecided)
+
+	st, err = f.svc.Approve(ctx, manager, f.reportID, "", true)
+	require.NoError(t, err)
+	require.Equal(t, "published", st.ReportStatus)
+	require.Equal(t, review.StatePublished, st.Review.State)
+	require.Equal(t, "published", f.report.Status)
+	require.NotNil(t, f.report.FieldsFrozenAt)
+	require.Len(t, f.reports.Snapshots, 1)
+
+	last := st.Decisions[len(st.Decisions)-1]
+	require.Equal(t, review.DecisionPublished, last.Decision)
+	require.NotNil(t, last.SnapshotID)
+
+	_, err = f.svc.AddComment(ctx, manager, f.reportID, review.CommentInput{SectionID: f.section.Hex(), Body: "late"})
+	require.ErrorIs(t, err, review.ErrReportPublished)
+	_, err = f.svc.Submit(ctx, f.author, f.reportID)
+	require.ErrorIs(t, err, review.ErrReportPublished)
+}
+
+func TestRequestChangesReturnsToDraft(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	reviewer := f.reviewer("DFIR Manager")
+
+	_, err := f.svc.Submit(ctx, f.author, f.reportID)
+	require.NoError(t, err)
+
+	_, err = f.svc.RequestChanges(ctx, reviewer, f.reportID, "  ")
+	require.ErrorIs(t, err, review.ErrCommentRequired)
+
+	st, err := f.svc.RequestChanges(ctx, reviewer, f.reportID, "Add the hash of the disk image.")
+	require.NoError(t, err)
+	require.Equal(t, "draft", f.report.Status)
+	require.Equal(t, review.StateChangesRequested, st.Review.State)
+	require.True(t, f.notifier.Received(f.author.UserID, "Changes requested on report"))
+
+	_, err = f.svc.Approve(ctx, reviewer, f.reportID, "", false)
+	require.ErrorIs(t, err, review.ErrNotInReview)
+
+	// Resubmitting opens a new round; earlier decisions do not carry over.
+	st, err = f.svc.Submit(ctx, f.author, f.reportID)
+	require.NoError(t, err)
+	require.Equal(t, 2, st.Review.Round)
+	require.False(t, st.CanApprove) // the author is asking
+	st, err = f.svc.Approve(ctx, reviewer, f.reportID, "", false)
+	require.NoError(t, err)
+	require.Equal(t, "published", st.ReportStatus)
+}
+
+func TestCommentsAnchorToSections(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	reviewer := f.reviewer("DFIR Manager")
+
+	_, err := f.svc.AddComment(ctx, reviewer, f.reportID, review.CommentInput{SectionID: primitive.NewObjectID().Hex(), Body: "?"})
+	require.True(t, errors.Is(err, report.ErrSectionNotFound))
+
+	c, err := f.svc.AddComment(ctx, reviewer, f.reportID, review.CommentInput{SectionID: f.section.Hex(), Quote: "at 10:40", Body: "Which timezone?"})
+	require.NoError(t, err)
+
+	reply, err := f.svc.AddComment(ctx, f.author, f.reportID, review.CommentInput{ParentID: &c.ID, Body: "UTC, clarified."})
+	require.NoError(t, err)
+	require.Equal(t, f.section.Hex(), reply.SectionID)
+	require.Equal(t, "at 10:40", reply.Quote)
+	require.True(t, f.notifier.Received(reviewer.UserID, "New review comment"))
+
+	_, err = f.svc.ResolveComment(ctx, f.author, f.reportID, c.ID)
+	require.NoError(t, err)
+	open, err := f.svc.ListComments(ctx, reviewer, f.reportID, f.section.Hex(), false)
+	require.NoError(t, err)
+	require.Len(t, open, 1)
+	require.Equal(t, reply.ID, open[0].ID)
+
+	other := review.Actor{UserID: reviewer.UserID, Role: reviewer.Role, TenantID: uuid.NewString()}
+	_, err = f.svc.ListComments(ctx, other, f.reportID, "", true)
+	require.ErrorIs(t, err, review.ErrReportNotFound)
+}
@@ -0,0 +1,536 @@
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"aegis-api/services_/report"
+	"aegis-api/services_/report/update_status"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+var (
+	ErrReportNotFound  = report.ErrReportNotFound
+	ErrInvalidWorkflow = errors.New("invalid review workflow")
+	ErrNotDraft        = errors.New("only draft reports can be submitted for review")
+	ErrNotInReview     = errors.New("report is not under review")
+	ErrSelfApproval    = errors.New("authors and submitters cannot review their own report")
+	ErrNotEligible     = errors.New("your role cannot review the current stage")
+	ErrAlreadyDecided  = errors.New("you already approved this report in this review round")
+	ErrNotSubmitter    = errors.New("only the author or submitter can withdraw a review")
+	ErrCommentRequired = errors.New("a comment is required")
+	ErrCommentNotFound = errors.New("comment not found")
+	ErrReportPublished = errors.New("published reports are read-only")
+	ErrReviewConflict  = errors.New("review was changed concurrently; reload and retry")
+)
+
+const (
+	maxStages   = 10
+	maxRoles    = 20
+	maxComment  = 10_000
+	maxQuoteLen = 2_000
+)
+
+type service struct {
+	repo     Repository
+	reports  Reports
+	statuses StatusUpdater
+	fields   report.FieldRenderer // expands merge fields in published snapshots
+	notifier Notifier             // nil: no notifications
+}
+
+func NewService(repo Repository, reports Reports, statuses StatusUpdater, fields report.FieldRenderer, notifier Notifier) Service {
+	return &service{repo: repo, reports: reports, statuses: statuses, fields: fields, notifier: notifier}
+}
+
+func (s *service) GetWorkflow(tenantID string) ([]Stage, error) {
+	w, err := s.repo.GetWorkflow(tenantID)
+	if err != nil || w == nil {
+		return DefaultStages(), err
+	}
+	return decodeStages(w.Stages)
+}
+
+func (s *service) SetWorkflow(tenantID, userID string, stages []Stage) ([]Stage, error) {
+	if err := normalizeStages(stages); err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(stages)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveWorkflow(&Workflow{TenantID: tenantID, Stages: datatypes.JSON(raw), UpdatedBy: userID}); err != nil {
+		return nil, err
+	}
+	return stages, nil
+}
+
+func normalizeStages(stages []Stage) error {
+	if len(stages) == 0 || len(stages) > maxStages {
+		return fmt.Errorf("%w: between 1 and %d stages are required", ErrInvalidWorkflow, maxStages)
+	}
+	for i := range stages {
+		st := &stages[i]
+		st.Name = strings.TrimSpace(st.Name)
+		if st.Name == "" {
+			return fmt.Errorf("%w: stage %d has no name", ErrInvalidWorkflow, i+1)
+		}
+		if len(st.Roles) > maxRoles {
+			return fmt.Errorf("%w: stage %q lists too many roles", ErrInvalidWorkflow, st.Name)
+		}
+		roles := st.Roles[:0]
+		for _, r := range st.Roles {
+			if r = strings.TrimSpace(r); r != "" && !slices.Contains(roles, r) {
+				roles = append(roles, r)
+			}
+		}
+		st.Roles = roles
+		if st.MinApprovals == 0 {
+			st.MinApprovals = 1
+		}
+		if st.MinApprovals < 1 || st.MinApprovals > 10 {
+			return fmt.Errorf("%w: stage %q needs 1 to 10 approvals", ErrInvalidWorkflow, st.Name)
+		}
+	}
+	return nil
+}
+
+func decodeStages(raw datatypes.JSON) ([]Stage, error) {
+	var stages []Stage
+	if err := json.Unmarshal(raw, &stages); err != nil {
+		return nil, fmt.Errorf("corrupt review stages: %w", err)
+	}
+	return stages, nil
+}
+
+// reportInTenant loads the report, hiding reports of other tenants.
+func (s *service) reportInTenant(ctx context.Context, actor Actor, reportID uuid.UUID) (*report.Report, error) {
+	rpt, err := s.reports.GetReportByID(ctx, reportID.String())
+	if err != nil || rpt == nil || rpt.TenantID.String() != actor.TenantID {
+		return nil, ErrReportNotFound
+	}
+	return rpt, nil
+}
+
+func (s *service) Status(ctx context.Context, actor Actor, reportID uuid.UUID) (*Status, error) {
+	rpt, err := s.reportInTenant(ctx, actor, reportID)
+	if err != nil {
+		return nil, err
+	}
+	return s.status(rpt.Status, actor, reportID)
+}
+
+func (s *service) status(reportStatus string, actor Actor, reportID uuid.UUID) (*Status, error) {
+	out := &Status{ReportStatus: reportStatus, Decisions: []Decision{}}
+	rev, err := s.repo.LatestReview(reportID.String())
+	if err != nil || rev == nil {
+		return out, err
+	}
+	out.Review = rev
+	if out.Stages, err = decodeStages(rev.Stages); err != nil {
+		return nil, err
+	}
+	if out.Decisions, err = s.repo.ListDecisions(rev.ID); err != nil {
+		return nil, err
+	}
+	out.CanApprove = canDecide(rev, out.Stages, actor) == nil && !approvedBy(out.Decisions, actor.UserID)
+	return out, nil
+}
+
+// canDecide reports why actor may not approve the review's current stage.
+func canDecide(rev *Review, stages []Stage, actor Actor) error {
+	if rev.State != StateInReview || rev.CurrentStage >= len(stages) {
+		return ErrNotInReview
+	}
+	if actor.UserID == rev.AuthorID || actor.UserID == rev.SubmittedBy {
+		return ErrSelfApproval
+	}
+	if roles := stages[rev.CurrentStage].Roles; len(roles) > 0 && !slices.Contains(roles, actor.Role) {
+		return ErrNotEligible
+	}
+	return nil
+}
+
+func (s *service) Submit(ctx context.Context, actor Actor, reportID uuid.UUID) (*Status, error) {
+	rpt, err := s.reportInTenant(ctx, actor, reportID)
+	if err != nil {
+		return nil, err
+	}
+	switch rpt.Status {
+	case string(update_status.ReportStatusPublished):
+		return nil, ErrReportPublished
+	case "", string(update_status.ReportStatusDraft):
+	default:
+		return nil, ErrNotDraft
+	}
+	stages, err := s.GetWorkflow(actor.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	rawStages, err := json.Marshal(stages)
+	if err != nil {
+		return nil, err
+	}
+	round := 1
+	if last, err := s.repo.LatestReview(reportID.String()); err != nil {
+		return nil, err
+	} else if last != nil {
+		round = last.Round + 1
+	}
+
+	rev := &Review{
+		ReportID:    reportID.String(),
+		TenantID:    actor.TenantID,
+		Round:       round,
+		Stages:      datatypes.JSON(rawStages),
+		State:       StateInReview,
+		AuthorID:    rpt.ExaminerID.String(),
+		SubmittedBy: actor.UserID,
+		SubmittedAt: time.Now().UTC(),
+	}
+	if err := s.repo.CreateReview(rev, s.decision(rev, actor, DecisionSubmitted, "")); err != nil {
+		return nil, err
+	}
+	if _, err := s.statuses.UpdateStatus(ctx, reportID, update_status.ReportStatusReview); err != nil {
+		s.closeAfterFailure(rev, actor, "could not move report to review: "+err.Error())
+		return nil, err
+	}
+
+	s.notifyStage(rpt, rev, stages)
+	return s.status(string(update_status.ReportStatusReview), actor, reportID)
+}
+
+// activeReview returns the review the report is in, with its stages and
+// decisions.
+func (s *service) activeReview(reportID uuid.UUID) (*Review, []Stage, []Decision, error) {
+	rev, err := s.repo.LatestReview(reportID.String())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if rev == nil || rev.State != StateInReview {
+		return nil, nil, nil, ErrNotInReview
+	}
+	stages, err := decodeStages(rev.Stages)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	decisions, err := s.repo.ListDecisions(rev.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return rev, stages, decisions, nil
+}
+
+func (s *service) Approve(ctx context.Context, actor Actor, reportID uuid.UUID, comment string, freezeFields bool) (*Status, error) {
+	rpt, err := s.reportInTenant(ctx, actor, reportID)
+	if err != nil {
+		return nil, err
+	}
+	rev, stages, decisions, err := s.activeReview(reportID)
+	if err != nil {
+		return nil, err
+	}
+	if err := canDecide(rev, stages, actor); err != nil {
+		return nil, err
+	}
+	if len(comment) > maxComment {
+		return nil, fmt.Errorf("%w: comment is too long", ErrInvalidWorkflow)
+	}
+
+	stage := rev.CurrentStage
+	approvals := approvalsAt(decisions, stage)
+	var added []*Decision
+	// Everyone approves at most one stage per round, so each stage has its
+	// own reviewers. A repeat call only retries a publish that failed.
+	if approvedBy(decisions, actor.UserID) {
+		if stage != len(stages)-1 || approvals < stages[stage].MinApprovals {
+			return nil, ErrAlreadyDecided
+		}
+	} else {
+		added = append(added, s.decision(rev, actor, DecisionApproved, comment))
+		approvals++
+	}
+
+	if approvals < stages[stage].MinApprovals {
+		if err := s.repo.UpdateReview(rev, added...); err != nil {
+			return nil, err
+		}
+		return s.status(rpt.Status, actor, reportID)
+	}
+
+	if stage < len(stages)-1 {
+		rev.CurrentStage++
+		if err := s.repo.UpdateReview(rev, added...); err != nil {
+			return nil, err
+		}
+		s.notify(rpt, []string{rev.AuthorID, rev.SubmittedBy}, actor.UserID, "Report review stage approved",
+			fmt.Sprintf("%q passed %s and moved to %s.", rpt.Name, stages[stage].Name, stages[rev.CurrentStage].Name))
+		s.notifyStage(rpt, rev, stages)
+		return s.status(rpt.Status, actor, reportID)
+	}
+
+	// Record the approval before publishing; the version check means only
+	// one concurrent approver gets to publish. A failed publish leaves the
+	// review open with the approval counted, and approving again retries.
+	if err := s.repo.UpdateReview(rev, added...); err != nil {
+		return nil, err
+	}
+	snapshotID, err := s.publish(ctx, actor, reportID, freezeFields)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	rev.State, rev.ClosedAt = StatePublished, &now
+	published := s.decision(rev, actor, DecisionPublished, "")
+	if snapshotID != "" {
+		published.SnapshotID = &snapshotID
+	}
+	if err := s.repo.UpdateReview(rev, published); err != nil {
+		return nil, err
+	}
+	s.notify(rpt, []string{rev.AuthorID, rev.SubmittedBy}, actor.UserID, "Report published",
+		fmt.Sprintf("%q completed review and was published.", rpt.Name))
+	return s.status(string(update_status.ReportStatusPublished), actor, reportID)
+}
+
+// publish freezes merge fields if asked, marks the report published and
+// pins what was signed off.
+func (s *service) publish(ctx context.Context, actor Actor, reportID uuid.UUID, freezeFields bool) (string, error) {
+	ctx = report.WithEditor(ctx, actor.UserID)
+	if freezeFields {
+		if _, err := s.reports.FreezeFields(ctx, reportID, s.fields); err != nil {
+			return "", fmt.Errorf("failed to freeze merge fields: %w", err)
+		}
+	}
+	updated, err := s.statuses.UpdateStatus(ctx, reportID, update_status.ReportStatusPublished)
+	if err != nil {
+		return "", err
+	}
+	snap, err := s.reports.PinSnapshot(ctx, reportID, updated.Version, s.fields)
+	if errors.Is(err, report.ErrRevisionsDisabled) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("report published but snapshot failed: %w", err)
+	}
+	return snap.ID, nil
+}
+
+func (s *service) RequestChanges(ctx context.Context, actor Actor, reportID uuid.UUID, comment string) (*Status, error) {
+	rpt, err := s.reportInTenant(ctx, actor, reportID)
+	if err != nil {
+		return nil, err
+	}
+	comment = strings.TrimSpace(comment)
+	if comment == "" {
+		return nil, ErrCommentRequired
+	}
+	if len(comment) > maxComment {
+		return nil, fmt.Errorf("%w: comment is too long", ErrInvalidWorkflow)
+	}
+	rev, stages, _, err := s.activeReview(reportID)
+	if err != nil {
+		return nil, err
+	}
+	if err := canDecide(rev, stages, actor); err != nil {
+		return nil, err
+	}
+
+	stage := rev.CurrentStage
+	now := time.Now().UTC()
+	rev.State, rev.ClosedAt = StateChangesRequested, &now
+	if err := s.repo.UpdateReview(rev, s.decision(rev, actor, DecisionChangesRequested, comment)); err != nil {
+		return nil, err
+	}
+	if _, err := s.statuses.UpdateStatus(ctx, reportID, update_status.ReportStatusDraft); err != nil {
+		return nil, err
+	}
+	s.notify(rpt, []string{rev.AuthorID, rev.SubmittedBy}, actor.UserID, "Changes requested on report",
+		fmt.Sprintf("%q was returned to draft at %s: %s", rpt.Name, stages[stage].Name, comment))
+	return s.status(string(update_status.ReportStatusDraft), actor, reportID)
+}
+
+func (s *service) Withdraw(ctx context.Context, actor Actor, reportID uuid.UUID) (*Status, error) {
+	if _, err := s.reportInTenant(ctx, actor, reportID); err != nil {
+		return nil, err
+	}
+	rev, _, _, err := s.activeReview(reportID)
+	if err != nil {
+		return nil, err
+	}
+	if actor.UserID != rev.AuthorID && actor.UserID != rev.SubmittedBy {
+		return nil, ErrNotSubmitter
+	}
+	now := time.Now().UTC()
+	rev.State, rev.ClosedAt = StateWithdrawn, &now
+	if err := s.repo.UpdateReview(rev, s.decision(rev, actor, DecisionWithdrawn, "")); err != nil {
+		return nil, err
+	}
+	if _, err := s.statuses.UpdateStatus(ctx, reportID, update_status.ReportStatusDraft); err != nil {
+		return nil, err
+	}
+	return s.status(string(update_status.ReportStatusDraft), actor, reportID)
+}
+
+// closeAfterFailure withdraws a review whose report could not follow it.
+func (s *service) closeAfterFailure(rev *Review, actor Actor, reason string) {
+	now := time.Now().UTC()
+	rev.State, rev.ClosedAt = StateWithdrawn, &now
+	if err := s.repo.UpdateReview(rev, s.decision(rev, actor, DecisionWithdrawn, reason)); err != nil {
+		log.Printf("[review] failed to close review %s: %v", rev.ID, err)
+	}
+}
+
+func (s *service) decision(rev *Review, actor Actor, kind, comment string) *Decision {
+	return &Decision{
+		ReviewID: rev.ID,
+		ReportID: rev.ReportID,
+		Stage:    rev.CurrentStage,
+		UserID:   actor.UserID,
+		UserRole: actor.Role,
+		Decision: kind,
+		Comment:  strings.TrimSpace(comment),
+	}
+}
+
+func approvalsAt(decisions []Decision, stage int) int {
+	n := 0
+	for _, d := range decisions {
+		if d.Decision == DecisionApproved && d.Stage == stage {
+			n++
+		}
+	}
+	return n
+}
+
+func approvedBy(decisions []Decision, userID string) bool {
+	return slices.ContainsFunc(decisions, func(d Decision) bool {
+		return d.Decision == DecisionApproved && d.UserID == userID
+	})
+}
+
+func (s *service) AddComment(ctx context.Context, actor Actor, reportID uuid.UUID, in CommentInput) (*Comment, error) {
+	rpt, err := s.reportInTenant(ctx, actor, reportID)
+	if err != nil {
+		return nil, err
+	}
+	if rpt.Status == string(update_status.ReportStatusPublished) {
+		return nil, ErrReportPublished
+	}
+	in.Body = strings.TrimSpace(in.Body)
+	if in.Body == "" {
+		return nil, ErrCommentRequired
+	}
+	if len(in.Body) > maxComment || len(in.Quote) > maxQuoteLen {
+		return nil, fmt.Errorf("%w: comment is too long", ErrInvalidWorkflow)
+	}
+
+	c := &Comment{ReportID: reportID.String(), AuthorID: actor.UserID, Body: in.Body, Quote: in.Quote}
+	var notifyUser string
+	if in.ParentID != nil {
+		parent, err := s.repo.GetComment(reportID.String(), *in.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		// Replies inherit the parent's anchor.
+		c.ParentID, c.SectionID, c.Quote, c.ReviewID = &parent.ID, parent.SectionID, parent.Quote, parent.ReviewID
+		notifyUser = parent.AuthorID
+	} else {
+		content, err := s.reports.DownloadReport(ctx, reportID)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.ContainsFunc(content.Content, func(sec report.ReportSection) bool { return sec.ID.Hex() == in.SectionID }) {
+			return nil, report.ErrSectionNotFound
+		}
+		c.SectionID = in.SectionID
+		notifyUser = rpt.ExaminerID.String()
+		if rev, err := s.repo.LatestReview(reportID.String()); err == nil && rev != nil && rev.State == StateInReview {
+			c.ReviewID = &rev.ID
+		}
+	}
+	if err := s.repo.CreateComment(c); err != nil {
+		return nil, err
+	}
+	s.notify(rpt, []string{notifyUser}, actor.UserID, "New review comment",
+		fmt.Sprintf("New comment on %q: %s", rpt.Name, truncate(c.Body, 140)))
+	return c, nil
+}
+
+func (s *service) ListComments(ctx context.Context, actor Actor, reportID uuid.UUID, sectionID string, includeResolved bool) ([]Comment, error) {
+	if _, err := s.reportInTenant(ctx, actor, reportID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListComments(reportID.String(), sectionID, includeResolved)
+}
+
+func (s *service) ResolveComment(ctx context.Context, actor Actor, reportID uuid.UUID, commentID string) (*Comment, error) {
+	rpt, err := s.reportInTenant(ctx, actor, reportID)
+	if err != nil {
+		return nil, err
+	}
+	if rpt.Status == string(update_status.ReportStatusPublished) {
+		return nil, ErrReportPublished
+	}
+	c, err := s.repo.GetComment(reportID.String(), commentID)
+	if err != nil {
+		return nil, err
+	}
+	if c.Resolved {
+		return c, nil
+	}
+	now := time.Now().UTC()
+	c.Resolved, c.ResolvedBy, c.ResolvedAt = true, &actor.UserID, &now
+	if err := s.repo.ResolveComment(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// notifyStage tells the reviewers of the current stage there is work.
+func (s *service) notifyStage(rpt *report.Report, rev *Review, stages []Stage) {
+	if s.notifier == nil || rev.CurrentStage >= len(stages) {
+		return
+	}
+	stage := stages[rev.CurrentStage]
+	if len(stage.Roles) == 0 {
+		return
+	}
+	users, err := s.repo.UsersWithRoles(rev.TenantID, stage.Roles)
+	if err != nil {
+		log.Printf("[review] failed to list reviewers for report %s: %v", rev.ReportID, err)
+		return
+	}
+	users = slices.DeleteFunc(users, func(u string) bool { return u == rev.AuthorID || u == rev.SubmittedBy })
+	s.notify(rpt, users, "", "Report awaiting your review",
+		fmt.Sprintf("%q is ready for %s.", rpt.Name, stage.Name))
+}
+
+func (s *service) notify(rpt *report.Report, users []string, except, title, message string) {
+	if s.notifier == nil {
+		return
+	}
+	seen := map[string]bool{except: true, "": true}
+	for _, u := range users {
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+		s.notifier.Notify(u, rpt.TenantID.String(), rpt.TeamID.String(), title, message)
+	}
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,173 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// RevisionRepository stores section revisions and published snapshots.
+// Rows are only ever inserted.
+type RevisionRepository interface {
+	AutoMigrate() error
+	// EnsureBaseline inserts revs as report revision 1 unless the report
+	// already has revisions.
+	EnsureBaseline(ctx context.Context, reportID string, revs []SectionRevision) error
+	// Append stores revs as the report's next revision and returns its seq.
+	Append(ctx context.Context, reportID string, revs []SectionRevision) (int64, error)
+	LatestSeq(ctx context.Context, reportID string) (int64, error)
+	ListByReport(ctx context.Context, reportID string) ([]SectionRevision, error)
+	ListBySection(ctx context.Context, reportID, sectionID string) ([]SectionRevision, error)
+	GetBySection(ctx context.Context, reportID, sectionID string, revision int) (*SectionRevision, error)
+	// AsOf returns the latest revision of each section at or before seq.
+	AsOf(ctx context.Context, reportID string, seq int64) ([]SectionRevision, error)
+	CreateSnapshot(ctx context.Context, snap *ReportSnapshot) error
+	ListSnapshots(ctx context.Context, reportID string) ([]ReportSnapshot, error)
+	GetSnapshot(ctx context.Context, reportID, snapshotID string) (*ReportSnapshot, error)
+}
+
+type GormRevisionRepository struct {
+	db *gorm.DB
+}
+
+func NewRevisionRepository(db *gorm.DB) RevisionRepository {
+	return &GormRevisionRepository{db: db}
+}
+
+func (r *GormRevisionRepository) AutoMigrate() error {
+	return r.db.AutoMigrate(&SectionRevision{}, &ReportSnapshot{})
+}
+
+func (r *GormRevisionRepository) EnsureBaseline(ctx context.Context, reportID string, revs []SectionRevision) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		seq, err := lockAndLatestSeq(tx, reportID)
+		if err != nil || seq > 0 {
+			return err
+		}
+		return insertRevisions(tx, reportID, 1, revs)
+	})
+}
+
+func (r *GormRevisionRepository) Append(ctx context.Context, reportID string, revs []SectionRevision) (int64, error) {
+	var seq int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		latest, err := lockAndLatestSeq(tx, reportID)
+		if err != nil {
+			return err
+		}
+		seq = latest + 1
+		return insertRevisions(tx, reportID, seq, revs)
+	})
+	return seq, err
+}
+
+// lockAndLatestSeq serialises revision writers per report for the rest of
+// the transaction, so sequence numbers are gap-free and never reused.
+func lockAndLatestSeq(tx *gorm.DB, reportID string) (int64, error) {
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", reportID).Error; err != nil {
+		return 0, fmt.Errorf("failed to lock report revisions: %w", err)
+	}
+	var seq int64
+	err := tx.Model(&SectionRevision{}).
+		Where("report_id = ?", reportID).
+		Select("COALESCE(MAX(report_seq), 0)").
+		Scan(&seq).Error
+	return seq, err
+}
+
+func insertRevisions(tx *gorm.DB, reportID string, seq int64, revs []SectionRevision) error {
+	if len(revs) == 0 {
+		return nil
+	}
+	for i := range revs {
+		var last int
+		if err := tx.Model(&SectionRevision{}).
+			Where("section_id = ?", revs[i].SectionID).
+			Select("COALESCE(MAX(revision), 0)").
+			Scan(&last).Error; err != nil {
+			return err
+		}
+		revs[i].ID = ""
+		revs[i].ReportID = reportID
+		revs[i].ReportSeq = seq
+		revs[i].Revision = last + 1
+	}
+	return tx.Create(&revs).Error
+}
+
+func (r *GormRevisionRepository) LatestSeq(ctx context.Context, reportID string) (int64, error) {
+	var seq int64
+	err := r.db.WithContext(ctx).Model(&SectionRevision{}).
+		Where("report_id = ?", reportID).
+		Select("COALESCE(MAX(report_seq), 0)").
+		Scan(&seq).Error
+	return seq, err
+}
+
+func (r *GormRevisionRepository) ListByReport(ctx context.Context, reportID string) ([]SectionRevision, error) {
+	var out []SectionRevision
+	err := r.db.WithContext(ctx).
+		Omit("section").
+		Where("report_id = ?", reportID).
+		Order("report_seq DESC, section_id").
+		Find(&out).Error
+	return out, err
+}
+
+func (r *GormRevisionRepository) ListBySection(ctx context.Context, reportID, sectionID string) ([]SectionRevision, error) {
+	var out []SectionRevision
+	err := r.db.WithContext(ctx).
+		Where("report_id = ? AND section_id = ?", reportID, sectionID).
+		Order("revision DESC").
+		Find(&out).Error
+	return out, err
+}
+
+func (r *GormRevisionRepository) GetBySection(ctx context.Context, reportID, sectionID string, revision int) (*SectionRevision, error) {
+	var rev SectionRevision
+	err := r.db.WithContext(ctx).
+		Where("report_id = ? AND section_id = ? AND revision = ?", reportID, sectionID, revision).
+		First(&rev).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRevisionNotFound
+	}
+	return &rev, err
+}
+
+func (r *GormRevisionRepository) AsOf(ctx context.Context, reportID string, seq int64) ([]SectionRevision, error) {
+	var out []SectionRevision
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT DISTINCT ON (section_id) *
+		  FROM report_section_revisions
+		 WHERE report_id = ? AND report_seq <= ?
+		 ORDER BY section_id, report_seq DESC`, reportID, seq).
+		Scan(&out).Error
+	return out, err
+}
+
+func (r *GormRevisionRepository) CreateSnapshot(ctx context.Context, snap *ReportSnapshot) error {
+	return r.db.WithContext(ctx).Create(snap).Error
+}
+
+func (r *GormRevisionRepository) ListSnapshots(ctx context.Context, reportID string) ([]ReportSnapshot, error) {
+	var out []ReportSnapshot
+	err := r.db.WithContext(ctx).
+		Omit("sections").
+		Where("report_id = ?", reportID).
+		Order("version DESC").
+		Find(&out).Error
+	return out, err
+}
+
+func (r *GormRevisionRepository) GetSnapshot(ctx context.Context, reportID, snapshotID string) (*ReportSnapshot, error) {
+	var snap ReportSnapshot
+	err := r.db.WithContext(ctx).
+		Where("report_id = ? AND id = ?", reportID, snapshotID).
+		First(&snap).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSnapshotNotFound
+	}
+	return &snap, err
+}
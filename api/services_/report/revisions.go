@@ -0,0 +1,594 @@
+package report
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"aegis-api/services_/report/htmldiff"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gorm.io/datatypes"
+)
+
+var (
+	ErrRevisionNotFound  = errors.New("revision not found")
+	ErrSnapshotNotFound  = errors.New("snapshot not found")
+	ErrRevisionsDisabled = errors.New("revision history is not configured")
+)
+
+// Revision actions.
+const (
+	RevisionBaseline   = "baseline" // state before the first recorded edit
+	RevisionCreated    = "created"
+	RevisionEdited     = "edited"
+	RevisionRetitled   = "retitled"
+	RevisionReordered  = "reordered"
+	RevisionDeleted    = "deleted"
+	RevisionRestored   = "restored"
+	RevisionAIAccepted = "ai_accepted"
+)
+
+// SectionRevision is an immutable copy of a section after a change. Every
+// change to a report gets the next ReportSeq, shared by all sections it
+// touched; Revision counts the changes to one section.
+type SectionRevision struct {
+	ID        string `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	ReportID  string `gorm:"type:uuid;not null;index:idx_section_revisions_report_seq,priority:1" json:"report_id"`
+	ReportSeq int64  `gorm:"not null;index:idx_section_revisions_report_seq,priority:2" json:"report_seq"`
+	SectionID string `gorm:"type:char(24);not null;uniqueIndex:uq_section_revision,priority:1" json:"section_id"`
+	Revision  int    `gorm:"not null;uniqueIndex:uq_section_revision,priority:2" json:"revision"`
+	Action    string `gorm:"type:varchar(20);not null" json:"action"`
+	Title     string `gorm:"type:text" json:"title"`
+	// RestoredFrom is the report revision a rollback copied this state from.
+	RestoredFrom *int64         `json:"restored_from,omitempty"`
+	Section      datatypes.JSON `gorm:"type:jsonb;not null" json:"section"`
+	AuthorID     *string        `gorm:"type:uuid" json:"author_id,omitempty"`
+	CreatedAt    time.Time      `gorm:"not null;default:now()" json:"created_at"`
+}
+
+func (SectionRevision) TableName() string { return "report_section_revisions" }
+
+// State decodes the stored section.
+func (r *SectionRevision) State() (ReportSection, error) {
+	var sec ReportSection
+	err := json.Unmarshal(r.Section, &sec)
+	return sec, err
+}
+
+// ReportRevision groups the section revisions written by one change.
+type ReportRevision struct {
+	Seq          int64                   `json:"seq"`
+	AuthorID     *string                 `json:"author_id,omitempty"`
+	CreatedAt    time.Time               `json:"created_at"`
+	RestoredFrom *int64                  `json:"restored_from,omitempty"`
+	Sections     []ReportRevisionSection `json:"sections"`
+}
+
+type ReportRevisionSection struct {
+	SectionID string `json:"section_id"`
+	Revision  int    `json:"revision"`
+	Action    string `json:"action"`
+	Title     string `json:"title"`
+}
+
+// ReportSnapshot pins the rendered report as it was published. It is
+// written once and never updated.
+type ReportSnapshot struct {
+	ID       string `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	ReportID string `gorm:"type:uuid;not null;uniqueIndex:uq_report_snapshot_version,priority:1" json:"report_id"`
+	Version  int    `gorm:"not null;uniqueIndex:uq_report_snapshot_version,priority:2" json:"version"`
+	// ReportSeq is the report revision the snapshot was taken at.
+	ReportSeq     int64          `gorm:"not null" json:"report_seq"`
+	Status        string         `gorm:"type:varchar(20);not null" json:"status"`
+	Name          string         `gorm:"type:varchar(255)" json:"name"`
+	Sections      datatypes.JSON `gorm:"type:jsonb;not null" json:"sections,omitempty"`
+	ContentSHA256 string         `gorm:"column:content_sha256;type:char(64);not null" json:"content_sha256"`
+	PinnedBy      *string        `gorm:"type:uuid" json:"pinned_by,omitempty"`
+	CreatedAt     time.Time      `gorm:"not null;default:now()" json:"created_at"`
+}
+
+func (ReportSnapshot) TableName() string { return "report_snapshots" }
+
+// SectionDiff compares two states of one section. A missing side means the
+// section did not exist in that state.
+type SectionDiff struct {
+	SectionID string          `json:"section_id"`
+	Status    string          `json:"status"` // added, removed, changed, unchanged
+	FromTitle string          `json:"from_title,omitempty"`
+	ToTitle   string          `json:"to_title,omitempty"`
+	Content   htmldiff.Result `json:"content"`
+}
+
+// ReportDiff compares two report revisions; To is 0 for the current state.
+type ReportDiff struct {
+	From     int64         `json:"from"`
+	To       int64         `json:"to"`
+	Sections []SectionDiff `json:"sections"`
+}
+
+type editorKey struct{}
+
+// WithEditor attaches the user making a change, so revisions recorded by
+// services that only receive a context are attributed.
+func WithEditor(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, editorKey{}, userID)
+}
+
+func EditorFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(editorKey{}).(string)
+	return v
+}
+
+// RecordSectionEdit runs apply and stores a revision of every section it
+// changed. The first recorded edit of a report also stores a baseline of
+// all sections as they were before it. Without a revision repository apply
+// simply runs.
+func (s *ReportServiceImpl) RecordSectionEdit(
+	ctx context.Context,
+	reportUUID uuid.UUID,
+	sectionID primitive.ObjectID,
+	action string,
+	apply func(ctx context.Context) error,
+) error {
+	return s.recordEdit(ctx, reportUUID, sectionID, action, nil, apply)
+}
+
+// recordEdit is RecordSectionEdit for rollbacks too. A zero sectionID
+// attributes action to every section that changed.
+func (s *ReportServiceImpl) recordEdit(
+	ctx context.Context,
+	reportUUID uuid.UUID,
+	sectionID primitive.ObjectID,
+	action string,
+	restoredFrom *int64,
+	apply func(ctx context.Context) error,
+) error {
+	if err := s.ensureEditable(ctx, reportUUID); err != nil {
+		return err
+	}
+	if s.revisions == nil {
+		return apply(ctx)
+	}
+	before, err := s.currentSections(ctx, reportUUID)
+	if err != nil {
+		return err
+	}
+	author := editorID(ctx)
+	if err := s.revisions.EnsureBaseline(ctx, reportUUID.String(), revisionsFor(before, RevisionBaseline, author, nil)); err != nil {
+		return fmt.Errorf("failed to record baseline revision: %w", err)
+	}
+
+	if err := apply(ctx); err != nil {
+		return err
+	}
+
+	after, err := s.currentSections(ctx, reportUUID)
+	if err != nil {
+		return fmt.Errorf("edit applied but revision not recorded: %w", err)
+	}
+	var changed []SectionRevision
+	for _, change := range changedSections(before, after) {
+		act := RevisionEdited
+		switch {
+		case change.deleted:
+			act = RevisionDeleted
+		case sectionID.IsZero() || change.section.ID == sectionID:
+			act = action
+		case change.orderOnly:
+			act = RevisionReordered
+		}
+		changed = append(changed, revisionsFor([]ReportSection{change.section}, act, author, restoredFrom)...)
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	if _, err := s.revisions.Append(ctx, reportUUID.String(), changed); err != nil {
+		return fmt.Errorf("edit applied but revision not recorded: %w", err)
+	}
+	return nil
+}
+
+// ensureEditable fails with ErrReportLocked unless the report is a draft.
+// Content under review must stay what reviewers see, and published
+// reports are read-only.
+func (s *ReportServiceImpl) ensureEditable(ctx context.Context, reportUUID uuid.UUID) error {
+	meta, err := s.repo.GetByID(ctx, reportUUID.String())
+	if err != nil {
+		return fmt.Errorf("%w", ErrReportNotFound)
+	}
+	switch meta.Status {
+	case "review", "published", "archived":
+		return fmt.Errorf("%w: report is %s", ErrReportLocked, meta.Status)
+	}
+	return nil
+}
+
+type sectionChange struct {
+	section   ReportSection
+	deleted   bool
+	orderOnly bool
+}
+
+// changedSections lists sections added, removed or modified between two
+// states, ignoring timestamps and frozen field values.
+func changedSections(before, after []ReportSection) []sectionChange {
+	prev := make(map[primitive.ObjectID]ReportSection, len(before))
+	for _, sec := range before {
+		prev[sec.ID] = sec
+	}
+	var out []sectionChange
+	for _, sec := range after {
+		old, ok := prev[sec.ID]
+		delete(prev, sec.ID)
+		if !ok {
+			out = append(out, sectionChange{section: sec})
+			continue
+		}
+		if sameSection(old, sec) {
+			if old.Order != sec.Order {
+				out = append(out, sectionChange{section: sec, orderOnly: true})
+			}
+			continue
+		}
+		out = append(out, sectionChange{section: sec})
+	}
+	for _, sec := range before {
+		if _, gone := prev[sec.ID]; gone {
+			out = append(out, sectionChange{section: sec, deleted: true})
+		}
+	}
+	return out
+}
+
+func sameSection(a, b ReportSection) bool {
+	return a.Title == b.Title && a.Content == b.Content && a.Required == b.Required &&
+		len(a.Provenance) == len(b.Provenance)
+}
+
+func revisionsFor(sections []ReportSection, action string, author *string, restoredFrom *int64) []SectionRevision {
+	out := make([]SectionRevision, 0, len(sections))
+	for _, sec := range sections {
+		raw, _ := json.Marshal(sec)
+		out = append(out, SectionRevision{
+			SectionID:    sec.ID.Hex(),
+			Action:       action,
+			Title:        sec.Title,
+			RestoredFrom: restoredFrom,
+			Section:      datatypes.JSON(raw),
+			AuthorID:     author,
+		})
+	}
+	return out
+}
+
+func editorID(ctx context.Context) *string {
+	id := EditorFromContext(ctx)
+	if _, err := uuid.Parse(id); err != nil {
+		return nil
+	}
+	return &id
+}
+
+func (s *ReportServiceImpl) currentSections(ctx context.Context, reportUUID uuid.UUID) ([]ReportSection, error) {
+	mongoID, tenantID, teamID, err := s.getMongoID(ctx, reportUUID)
+	if err != nil {
+		return nil, err
+	}
+	content, err := s.mongoRepo.GetReportContent(ctx, mongoID, tenantID, teamID)
+	if err != nil {
+		return nil, err
+	}
+	if content == nil {
+		return nil, ErrMongoReportNotFound
+	}
+	return content.Sections, nil
+}
+
+// ListReportRevisions returns the report's changes, newest first.
+func (s *ReportServiceImpl) ListReportRevisions(ctx context.Context, reportUUID uuid.UUID) ([]ReportRevision, error) {
+	if s.revisions == nil {
+		return nil, ErrRevisionsDisabled
+	}
+	revs, err := s.revisions.ListByReport(ctx, reportUUID.String())
+	if err != nil {
+		return nil, err
+	}
+	var out []ReportRevision
+	for _, r := range revs {
+		if n := len(out); n == 0 || out[n-1].Seq != r.ReportSeq {
+			out = append(out, ReportRevision{Seq: r.ReportSeq, AuthorID: r.AuthorID, CreatedAt: r.CreatedAt, RestoredFrom: r.RestoredFrom})
+		}
+		last := &out[len(out)-1]
+		last.Sections = append(last.Sections, ReportRevisionSection{
+			SectionID: r.SectionID, Revision: r.Revision, Action: r.Action, Title: r.Title,
+		})
+	}
+	return out, nil
+}
+
+// ListSectionRevisions returns a section's revisions, newest first.
+func (s *ReportServiceImpl) ListSectionRevisions(ctx context.Context, reportUUID uuid.UUID, sectionID primitive.ObjectID) ([]SectionRevision, error) {
+	if s.revisions == nil {
+		return nil, ErrRevisionsDisabled
+	}
+	return s.revisions.ListBySection(ctx, reportUUID.String(), sectionID.Hex())
+}
+
+func (s *ReportServiceImpl) GetSectionRevision(ctx context.Context, reportUUID uuid.UUID, sectionID primitive.ObjectID, revision int) (*SectionRevision, error) {
+	if s.revisions == nil {
+		return nil, ErrRevisionsDisabled
+	}
+	return s.revisions.GetBySection(ctx, reportUUID.String(), sectionID.Hex(), revision)
+}
+
+// DiffSection compares two revisions of a section; to == 0 compares with
+// the section as it is now.
+func (s *ReportServiceImpl) DiffSection(ctx context.Context, reportUUID uuid.UUID, sectionID primitive.ObjectID, from, to int) (*SectionDiff, error) {
+	fromRev, err := s.GetSectionRevision(ctx, reportUUID, sectionID, from)
+	if err != nil {
+		return nil, err
+	}
+	oldSec, err := revisionState(fromRev)
+	if err != nil {
+		return nil, err
+	}
+
+	var newSec *ReportSection
+	if to == 0 {
+		current, err := s.currentSections(ctx, reportUUID)
+		if err != nil {
+			return nil, err
+		}
+		if i := slices.IndexFunc(current, func(sec ReportSection) bool { return sec.ID == sectionID }); i >= 0 {
+			newSec = &current[i]
+		}
+	} else {
+		toRev, err := s.GetSectionRevision(ctx, reportUUID, sectionID, to)
+		if err != nil {
+			return nil, err
+		}
+		if newSec, err = revisionState(toRev); err != nil {
+			return nil, err
+		}
+	}
+	d := diffSections(sectionID.Hex(), oldSec, newSec)
+	return &d, nil
+}
+
+// DiffReport compares the report at two report revisions; to == 0
+// compares with the current content.
+func (s *ReportServiceImpl) DiffReport(ctx context.Context, reportUUID uuid.UUID, from, to int64) (*ReportDiff, error) {
+	oldSecs, err := s.sectionsAt(ctx, reportUUID, from)
+	if err != nil {
+		return nil, err
+	}
+	var newSecs []ReportSection
+	if to == 0 {
+		newSecs, err = s.currentSections(ctx, reportUUID)
+	} else {
+		newSecs, err = s.sectionsAt(ctx, reportUUID, to)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	oldByID := make(map[primitive.ObjectID]*ReportSection, len(oldSecs))
+	for i := range oldSecs {
+		oldByID[oldSecs[i].ID] = &oldSecs[i]
+	}
+	out := &ReportDiff{From: from, To: to, Sections: []SectionDiff{}}
+	for i := range newSecs {
+		sec := &newSecs[i]
+		out.Sections = append(out.Sections, diffSections(sec.ID.Hex(), oldByID[sec.ID], sec))
+		delete(oldByID, sec.ID)
+	}
+	for i := range oldSecs {
+		if old, ok := oldByID[oldSecs[i].ID]; ok {
+			out.Sections = append(out.Sections, diffSections(old.ID.Hex(), old, nil))
+		}
+	}
+	return out, nil
+}
+
+func diffSections(id string, old, cur *ReportSection) SectionDiff {
+	d := SectionDiff{SectionID: id}
+	var oldContent, newContent string
+	if old != nil {
+		d.FromTitle, oldContent = old.Title, old.Content
+	}
+	if cur != nil {
+		d.ToTitle, newContent = cur.Title, cur.Content
+	}
+	d.Content = htmldiff.Diff(oldContent, newContent)
+	switch {
+	case old == nil:
+		d.Status = "added"
+	case cur == nil:
+		d.Status = "removed"
+	case d.FromTitle != d.ToTitle || oldContent != newContent:
+		d.Status = "changed"
+	default:
+		d.Status = "unchanged"
+	}
+	return d
+}
+
+// revisionState returns nil for a deleted-section revision.
+func revisionState(rev *SectionRevision) (*ReportSection, error) {
+	if rev.Action == RevisionDeleted {
+		return nil, nil
+	}
+	sec, err := rev.State()
+	if err != nil {
+		return nil, fmt.Errorf("corrupt revision %s: %w", rev.ID, err)
+	}
+	return &sec, nil
+}
+
+// sectionsAt rebuilds the report's sections at report revision seq, in
+// display order.
+func (s *ReportServiceImpl) sectionsAt(ctx context.Context, reportUUID uuid.UUID, seq int64) ([]ReportSection, error) {
+	if s.revisions == nil {
+		return nil, ErrRevisionsDisabled
+	}
+	latest, err := s.revisions.LatestSeq(ctx, reportUUID.String())
+	if err != nil {
+		return nil, err
+	}
+	if seq < 1 || seq > latest {
+		return nil, ErrRevisionNotFound
+	}
+	revs, err := s.revisions.AsOf(ctx, reportUUID.String(), seq)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ReportSection, 0, len(revs))
+	for i := range revs {
+		sec, err := revisionState(&revs[i])
+		if err != nil {
+			return nil, err
+		}
+		if sec != nil {
+			out = append(out, *sec)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Order < out[j].Order })
+	return out, nil
+}
+
+// RollbackSection restores a section's title and content from one of its
+// revisions, re-adding the section if it was deleted since. Rolling back
+// to a deletion deletes it. The rollback is itself a new revision.
+func (s *ReportServiceImpl) RollbackSection(ctx context.Context, reportUUID uuid.UUID, sectionID primitive.ObjectID, revision int) error {
+	rev, err := s.GetSectionRevision(ctx, reportUUID, sectionID, revision)
+	if err != nil {
+		return err
+	}
+	target, err := revisionState(rev)
+	if err != nil {
+		return err
+	}
+	mongoID, tenantID, teamID, err := s.getMongoID(ctx, reportUUID)
+	if err != nil {
+		return err
+	}
+	seq := rev.ReportSeq
+
+	return s.recordEdit(ctx, reportUUID, sectionID, RevisionRestored, &seq, func(ctx context.Context) error {
+		current, err := s.currentSections(ctx, reportUUID)
+		if err != nil {
+			return err
+		}
+		exists := slices.ContainsFunc(current, func(sec ReportSection) bool { return sec.ID == sectionID })
+		switch {
+		case target == nil && exists:
+			return s.mongoRepo.DeleteSection(ctx, mongoID, sectionID, tenantID, teamID)
+		case target == nil:
+			return nil
+		case !exists:
+			return s.mongoRepo.AddSection(ctx, mongoID, *target, tenantID, teamID)
+		}
+		if err := s.mongoRepo.UpdateSection(ctx, mongoID, sectionID, target.Content, tenantID, teamID); err != nil {
+			return err
+		}
+		return s.mongoRepo.UpdateSectionTitle(ctx, mongoID, sectionID, target.Title, tenantID, teamID)
+	})
+}
+
+// RollbackReport replaces all sections with the report as it was at
+// report revision seq. Frozen merge field values are kept for sections
+// that still exist.
+func (s *ReportServiceImpl) RollbackReport(ctx context.Context, reportUUID uuid.UUID, seq int64) error {
+	target, err := s.sectionsAt(ctx, reportUUID, seq)
+	if err != nil {
+		return err
+	}
+	mongoID, tenantID, teamID, err := s.getMongoID(ctx, reportUUID)
+	if err != nil {
+		return err
+	}
+
+	return s.recordEdit(ctx, reportUUID, primitive.NilObjectID, RevisionRestored, &seq, func(ctx context.Context) error {
+		current, err := s.currentSections(ctx, reportUUID)
+		if err != nil {
+			return err
+		}
+		frozen := make(map[primitive.ObjectID][]FrozenField, len(current))
+		for _, sec := range current {
+			frozen[sec.ID] = sec.FrozenFields
+		}
+		for i := range target {
+			if f, ok := frozen[target[i].ID]; ok {
+				target[i].FrozenFields = f
+			}
+		}
+		return s.mongoRepo.UpdateSections(ctx, mongoID, target, tenantID, teamID)
+	})
+}
+
+// PinSnapshot stores the rendered report as published at version. Merge
+// fields are expanded (from frozen values where present) so the snapshot
+// does not change with the case either.
+func (s *ReportServiceImpl) PinSnapshot(ctx context.Context, reportUUID uuid.UUID, version int, fields FieldRenderer) (*ReportSnapshot, error) {
+	if s.revisions == nil {
+		return nil, ErrRevisionsDisabled
+	}
+	rpt, err := s.RenderReport(ctx, reportUUID, fields)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.currentSections(ctx, reportUUID)
+	if err != nil {
+		return nil, err
+	}
+	author := editorID(ctx)
+	if err := s.revisions.EnsureBaseline(ctx, reportUUID.String(), revisionsFor(stored, RevisionBaseline, author, nil)); err != nil {
+		return nil, fmt.Errorf("failed to record baseline revision: %w", err)
+	}
+	seq, err := s.revisions.LatestSeq(ctx, reportUUID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(rpt.Content)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(raw)
+	snap := &ReportSnapshot{
+		ReportID:      reportUUID.String(),
+		Version:       version,
+		ReportSeq:     seq,
+		Status:        rpt.Metadata.Status,
+		Name:          rpt.Metadata.Name,
+		Sections:      datatypes.JSON(raw),
+		ContentSHA256: hex.EncodeToString(sum[:]),
+		PinnedBy:      author,
+	}
+	if err := s.revisions.CreateSnapshot(ctx, snap); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+func (s *ReportServiceImpl) ListSnapshots(ctx context.Context, reportUUID uuid.UUID) ([]ReportSnapshot, error) {
+	if s.revisions == nil {
+		return nil, ErrRevisionsDisabled
+	}
+	return s.revisions.ListSnapshots(ctx, reportUUID.String())
+}
+
+func (s *ReportServiceImpl) GetSnapshot(ctx context.Context, reportUUID uuid.UUID, snapshotID string) (*ReportSnapshot, error) {
+	if s.revisions == nil {
+		return nil, ErrRevisionsDisabled
+	}
+	return s.revisions.GetSnapshot(ctx, reportUUID.String(), snapshotID)
+}
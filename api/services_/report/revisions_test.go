@@ -0,0 +1,128 @@
+package report_test
+
+import (
+	"context"
+	"testing"
+
+	"aegis-api/services_/report"
+	"aegis-api/tests/fakes"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fixture struct {
+	svc       report.ReportService
+	content   *fakes.ReportContent
+	revs      *fakes.Revisions
+	reportID  uuid.UUID
+	findings  primitive.ObjectID
+	summary   primitive.ObjectID
+	editorCtx context.Context
+	editorID  string
+}
+
+func newFixture() *fixture {
+	rep := &report.Report{ID: uuid.New(), TenantID: uuid.New(), TeamID: uuid.New(), MongoID: primitive.NewObjectID().Hex(), Status: "draft"}
+	f := &fixture{
+		content:  &fakes.ReportContent{},
+		revs:     &fakes.Revisions{},
+		reportID: rep.ID,
+		summary:  primitive.NewObjectID(),
+		findings: primitive.NewObjectID(),
+		editorID: uuid.NewString(),
+	}
+	f.content.Doc.Sections = []report.ReportSection{
+		{ID: f.summary, Title: "Summary", Content: "<p>Initial triage.</p>", Order: 1, Required: true},
+		{ID: f.findings, Title: "Findings", Content: "<p>Host WS-042 was infected.</p>", Order: 2},
+	}
+	f.svc = report.NewReportService(&fakes.ReportRows{Rows: []*report.Report{rep}}, f.content, nil, nil, f.revs, nil)
+	f.editorCtx = report.WithEditor(context.Background(), f.editorID)
+	return f
+}
+
+func TestSectionEdits_AreRecordedAsRevisions(t *testing.T) {
+	f := newFixture()
+	require.NoError(t, f.svc.UpdateSectionContent(f.editorCtx, f.reportID, f.findings, "<p>Host WS-042 and FS-01 were infected.</p>"))
+	require.NoError(t, f.svc.UpdateSectionTitle(f.editorCtx, f.reportID, f.findings, "Key Findings"))
+
+	history, err := f.svc.ListReportRevisions(context.Background(), f.reportID)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	require.Equal(t, int64(1), history[2].Seq)
+	require.Len(t, history[2].Sections, 2, "baseline covers every section")
+	require.Equal(t, report.RevisionRetitled, history[0].Sections[0].Action)
+	require.Equal(t, f.editorID, *history[0].AuthorID)
+
+	revs, err := f.svc.ListSectionRevisions(context.Background(), f.reportID, f.findings)
+	require.NoError(t, err)
+	require.Equal(t, []int{3, 2, 1}, []int{revs[0].Revision, revs[1].Revision, revs[2].Revision})
+
+	diff, err := f.svc.DiffSection(context.Background(), f.reportID, f.findings, 1, 2)
+	require.NoError(t, err)
+	require.Equal(t, "changed", diff.Status)
+	require.Contains(t, diff.Content.HTML, `<ins class="diff-ins">and FS-01 were</ins>`)
+}
+
+func TestRollbackSection_RestoresContentAndDeletedSections(t *testing.T) {
+	f := newFixture()
+	require.NoError(t, f.svc.UpdateSectionContent(f.editorCtx, f.reportID, f.findings, "<p>Rewritten.</p>"))
+	require.NoError(t, f.svc.DeleteCustomSection(f.editorCtx, f.reportID, f.findings))
+	require.Nil(t, f.content.Section(f.findings))
+
+	require.NoError(t, f.svc.RollbackSection(f.editorCtx, f.reportID, f.findings, 1))
+	restored := f.content.Section(f.findings)
+	require.NotNil(t, restored)
+	require.Equal(t, "<p>Host WS-042 was infected.</p>", restored.Content)
+
+	revs, err := f.svc.ListSectionRevisions(context.Background(), f.reportID, f.findings)
+	require.NoError(t, err)
+	require.Equal(t, report.RevisionRestored, revs[0].Action)
+	require.Equal(t, int64(1), *revs[0].RestoredFrom)
+	require.Equal(t, report.RevisionDeleted, revs[1].Action)
+}
+
+func TestRollbackReport_AndDiffAcrossRevisions(t *testing.T) {
+	f := newFixture()
+	require.NoError(t, f.svc.UpdateSectionContent(f.editorCtx, f.reportID, f.summary, "<p>Ransomware confirmed.</p>"))
+	require.NoError(t, f.svc.AddCustomSection(f.editorCtx, f.reportID, "Appendix", "<p>Hashes</p>", 3))
+	require.NoError(t, f.svc.DeleteCustomSection(f.editorCtx, f.reportID, f.findings))
+
+	diff, err := f.svc.DiffReport(context.Background(), f.reportID, 1, 0)
+	require.NoError(t, err)
+	statuses := map[string]string{}
+	for _, d := range diff.Sections {
+		statuses[d.ToTitle+d.FromTitle] = d.Status
+	}
+	require.Equal(t, map[string]string{"SummarySummary": "changed", "Appendix": "added", "Findings": "removed"}, statuses)
+
+	require.NoError(t, f.svc.RollbackReport(f.editorCtx, f.reportID, 1))
+	require.Len(t, f.content.Doc.Sections, 2)
+	require.Equal(t, "<p>Initial triage.</p>", f.content.Section(f.summary).Content)
+	require.NotNil(t, f.content.Section(f.findings))
+
+	after, err := f.svc.DiffReport(context.Background(), f.reportID, 1, 0)
+	require.NoError(t, err)
+	for _, d := range after.Sections {
+		require.Equal(t, "unchanged", d.Status, d.ToTitle)
+	}
+
+	_, err = f.svc.DiffReport(context.Background(), f.reportID, 1, 99)
+	require.ErrorIs(t, err, report.ErrRevisionNotFound)
+}
+
+func TestPinSnapshot_IsUnaffectedByLaterEdits(t *testing.T) {
+	f := newFixture()
+	snap, err := f.svc.PinSnapshot(f.editorCtx, f.reportID, 2, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), snap.ReportSeq)
+	require.Len(t, snap.ContentSHA256, 64)
+
+	require.NoError(t, f.svc.UpdateSectionContent(f.editorCtx, f.reportID, f.summary, "<p>Changed after sign-off.</p>"))
+
+	got, err := f.svc.GetSnapshot(context.Background(), f.reportID, snap.ID)
+	require.NoError(t, err)
+	require.Contains(t, string(got.Sections), "Initial triage.")
+	require.NotContains(t, string(got.Sections), "Changed after sign-off.")
+}
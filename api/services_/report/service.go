@@ -5,28 +5,24 @@ import (
 	"log"
 
 	// ...existing imports...
-	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"image/jpeg"
-	"image/png"
-	"regexp"
 	"strings"
 	"time"
 	"unicode/utf8"
 
 	"github.com/google/uuid"
-	"github.com/jung-kurt/gofpdf"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	//"github.com/SebastiaanKlippert/go-wkhtmltopdf"
 )
 
 // ReportService defines the business logic for managing reports.
 type ReportService interface {
-	GenerateReport(ctx context.Context, caseID, examinerID, tenantID, teamID uuid.UUID) (*Report, error)
+	// GenerateReport lays out a new report from templateID, or from the
+	// tenant's default template for caseType when templateID is uuid.Nil.
+	GenerateReport(ctx context.Context, caseID, examinerID, tenantID, teamID, templateID uuid.UUID, caseType string) (*Report, error)
 	SaveReport(ctx context.Context, report *Report) error
 	GetReportByID(ctx context.Context, reportID string) (*Report, error)
 	UpdateReport(ctx context.Context, report *Report) error
@@ -35,8 +31,15 @@ type ReportService interface {
 	GetReportsByEvidenceID(ctx context.Context, evidenceID uuid.UUID) ([]Report, error)
 	DeleteReportByID(ctx context.Context, reportID uuid.UUID) error
 	DownloadReport(ctx context.Context, reportID uuid.UUID) (*ReportWithContent, error)
-	DownloadReportAsPDF(ctx context.Context, reportID uuid.UUID) ([]byte, error)
-	DownloadReportAsJSON(ctx context.Context, reportID uuid.UUID) ([]byte, error)
+	// Exports expand merge fields with fields; nil exports the stored content.
+	DownloadReportAsPDF(ctx context.Context, reportID uuid.UUID, fields FieldRenderer) ([]byte, error)
+	DownloadReportAsPDFWithOptions(ctx context.Context, reportID uuid.UUID, fields FieldRenderer, opts ExportOptions) ([]byte, error)
+	DownloadReportAsJSON(ctx context.Context, reportID uuid.UUID, fields FieldRenderer) ([]byte, error)
+	DownloadReportAsDOCX(ctx context.Context, reportID uuid.UUID, fields FieldRenderer, opts ExportOptions) ([]byte, error)
+	DownloadReportAsODT(ctx context.Context, reportID uuid.UUID, fields FieldRenderer, opts ExportOptions) ([]byte, error)
+	RenderReport(ctx context.Context, reportID uuid.UUID, fields FieldRenderer) (*ReportWithContent, error)
+	FreezeFields(ctx context.Context, reportID uuid.UUID, fields FieldRenderer) (*ReportWithContent, error)
+	ThawFields(ctx context.Context, reportID uuid.UUID) error
 	UpdateCustomSectionContent(ctx context.Context, reportUUID uuid.UUID, sectionID primitive.ObjectID, newContent string) error
 	//AddSection(ctx context.Context, reportID primitive.ObjectID, section ReportSection) error
 	ReorderSection(ctx context.Context, reportUUID uuid.UUID, sectionID primitive.ObjectID, newOrder int) error
@@ -49,6 +52,19 @@ type ReportService interface {
 	ListRecentReports(ctx context.Context, opts RecentReportsOptions) ([]RecentReport, error)
 	UpdateReportName(ctx context.Context, reportID uuid.UUID, name string) (*Report, error) // NEW
 	GetReportsByTeamID(ctx context.Context, tenantID, teamID uuid.UUID) ([]ReportWithDetails, error)
+
+	// Revision history. Edits are attributed to the user set with WithEditor.
+	RecordSectionEdit(ctx context.Context, reportUUID uuid.UUID, sectionID primitive.ObjectID, action string, apply func(ctx context.Context) error) error
+	ListReportRevisions(ctx context.Context, reportUUID uuid.UUID) ([]ReportRevision, error)
+	ListSectionRevisions(ctx context.Context, reportUUID uuid.UUID, sectionID primitive.ObjectID) ([]SectionRevision, error)
+	GetSectionRevision(ctx context.Context, reportUUID uuid.UUID, sectionID primitive.ObjectID, revision int) (*SectionRevision, error)
+	DiffSection(ctx context.Context, reportUUID uuid.UUID, sectionID primitive.ObjectID, from, to int) (*SectionDiff, error)
+	DiffReport(ctx context.Context, reportUUID uuid.UUID, from, to int64) (*ReportDiff, error)
+	RollbackSection(ctx context.Context, reportUUID uuid.UUID, sectionID primitive.ObjectID, revision int) error
+	RollbackReport(ctx context.Context, reportUUID uuid.UUID, seq int64) error
+	PinSnapshot(ctx context.Context, reportUUID uuid.UUID, version int, fields FieldRenderer) (*ReportSnapshot, error)
+	ListSnapshots(ctx context.Context, reportUUID uuid.UUID) ([]ReportSnapshot, error)
+	GetSnapshot(ctx context.Context, reportUUID uuid.UUID, snapshotID string) (*ReportSnapshot, error)
 }
 
 // ReportServiceImpl is the concrete implementation of ReportService.
@@ -56,6 +72,9 @@ type ReportServiceImpl struct {
 	repo          ReportRepository
 	mongoRepo     ReportMongoRepository
 	pgSectionRepo reportshared.ReportSectionRepository // Postgres section repository
+	templates     TemplateResolver                     // nil: always use the built-in layout
+	revisions     RevisionRepository                   // nil: edits are not versioned
+	markings      MarkingResolver                      // nil: every export is DefaultClassification
 	// artifactsRepo   ReportArtifactsRepository
 	// auditLogger AuditLogger
 	// authorizer  Authorizer
@@ -66,6 +85,9 @@ func NewReportService(
 	repo ReportRepository,
 	mongoRepo ReportMongoRepository,
 	pgSectionRepo reportshared.ReportSectionRepository,
+	templates TemplateResolver,
+	revisions RevisionRepository,
+	markings MarkingResolver,
 	// storage Storage,
 	// auditLogger AuditLogger,
 	// authorizer Authorizer,
@@ -75,6 +97,9 @@ func NewReportService(
 		repo:          repo,
 		mongoRepo:     mongoRepo,
 		pgSectionRepo: pgSectionRepo,
+		templates:     templates,
+		revisions:     revisions,
+		markings:      markings,
 		// storage:     storage,
 		// auditLogger: auditLogger,
 		// authorizer:  authorizer,
@@ -87,21 +112,36 @@ func NewReportService(
 // services_/report/service_impl.go
 func (s *ReportServiceImpl) GenerateReport(
 	ctx context.Context,
-	caseID, examinerID, tenantID, teamID uuid.UUID,
+	caseID, examinerID, tenantID, teamID, templateID uuid.UUID,
+	caseType string,
 ) (*Report, error) {
 	now := time.Now()
 
+	// 0) Resolve the template before writing anything
+	tmpl, err := s.resolveTemplate(ctx, tenantID, templateID, caseType)
+	if err != nil {
+		return nil, err
+	}
+
 	// 1) Create Postgres report metadata (includes tenant/team)
+	id := uuid.New()
 	report := &Report{
-		ID:         uuid.New(),
-		TenantID:   tenantID, // NEW
-		TeamID:     teamID,   // NEW
-		CaseID:     caseID,
-		ExaminerID: examinerID,
-		Status:     "draft",
-		Version:    1,
-		CreatedAt:  now,
-		UpdatedAt:  now,
+		ID: id,
+		// report_number is unique, so every report needs its own.
+		ReportNumber:    fmt.Sprintf("RPT-%s-%s", now.UTC().Format("20060102"), strings.ToUpper(id.String()[:8])),
+		TenantID:        tenantID, // NEW
+		TeamID:          teamID,   // NEW
+		CaseID:          caseID,
+		ExaminerID:      examinerID,
+		Status:          "draft",
+		Version:         1,
+		TemplateVersion: tmpl.Version,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if tmpl.TemplateID != uuid.Nil {
+		id := tmpl.TemplateID
+		report.TemplateID = &id
 	}
 
 	// 2) Generate MongoID for content
@@ -112,18 +152,18 @@ func (s *ReportServiceImpl) GenerateReport(
 		return nil, fmt.Errorf("failed to generate report metadata: %w", err)
 	}
 
-	// 4) Default sections (ensure timestamps)
-	defaultSections := []ReportSection{
-		{ID: primitive.NewObjectID(), Title: "Case Identification", Content: "", Order: 1, CreatedAt: now, UpdatedAt: now},
-		{ID: primitive.NewObjectID(), Title: "Scope and Objectives", Content: "", Order: 2, CreatedAt: now, UpdatedAt: now},
-		{ID: primitive.NewObjectID(), Title: "Evidence Summary", Content: "", Order: 3, CreatedAt: now, UpdatedAt: now},
-		{ID: primitive.NewObjectID(), Title: "Tools and Methodologies", Content: "", Order: 4, CreatedAt: now, UpdatedAt: now},
-		{ID: primitive.NewObjectID(), Title: "Findings", Content: "", Order: 5, CreatedAt: now, UpdatedAt: now},
-		{ID: primitive.NewObjectID(), Title: "Interpretation and Analysis", Content: "", Order: 6, CreatedAt: now, UpdatedAt: now},
-		{ID: primitive.NewObjectID(), Title: "Limitations", Content: "", Order: 7, CreatedAt: now, UpdatedAt: now},
-		{ID: primitive.NewObjectID(), Title: "Conclusion", Content: "", Order: 8, CreatedAt: now, UpdatedAt: now},
-		{ID: primitive.NewObjectID(), Title: "Appendices", Content: "", Order: 9, CreatedAt: now, UpdatedAt: now},
-		{ID: primitive.NewObjectID(), Title: "Certification", Content: "", Order: 10, CreatedAt: now, UpdatedAt: now},
+	// 4) Template sections (ensure timestamps)
+	defaultSections := make([]ReportSection, 0, len(tmpl.Sections))
+	for i, ts := range tmpl.Sections {
+		defaultSections = append(defaultSections, ReportSection{
+			ID:        primitive.NewObjectID(),
+			Title:     ts.Title,
+			Content:   ts.DefaultContent,
+			Order:     i + 1,
+			Required:  ts.Required,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
 	}
 
 	// 5) Save content in Mongo WITH tenant/team
@@ -166,6 +206,26 @@ func (s *ReportServiceImpl) GenerateReport(
 
 	// SaveReport persists a report to the repository.
 }
+
+// resolveTemplate falls back to the built-in layout when no resolver is
+// configured or the tenant has no default; an explicit templateID that
+// cannot be resolved is an error.
+func (s *ReportServiceImpl) resolveTemplate(ctx context.Context, tenantID, templateID uuid.UUID, caseType string) (*TemplateSnapshot, error) {
+	if s.templates == nil {
+		if templateID != uuid.Nil {
+			return nil, ErrTemplateNotFound
+		}
+		return BuiltinTemplate(), nil
+	}
+	tmpl, err := s.templates.ResolveTemplate(ctx, tenantID, templateID, caseType)
+	if err != nil {
+		return nil, err
+	}
+	if tmpl == nil || len(tmpl.Sections) == 0 {
+		return BuiltinTemplate(), nil
+	}
+	return tmpl, nil
+}
 func (s *ReportServiceImpl) SaveReport(ctx context.Context, report *Report) error {
 	if report.ID == uuid.Nil {
 		report.ID = uuid.New()
@@ -219,6 +279,9 @@ func (s *ReportServiceImpl) GetReportsByEvidenceID(ctx context.Context, evidence
 
 // DeleteReportByID deletes a report by ID.
 func (s *ReportServiceImpl) DeleteReportByID(ctx context.Context, reportID uuid.UUID) error {
+	if err := s.ensureEditable(ctx, reportID); err != nil {
+		return err
+	}
 	return s.repo.DeleteReportByID(ctx, reportID)
 }
 
@@ -266,130 +329,54 @@ func (s *ReportServiceImpl) UpdateReportSection(
 	return s.UpdateCustomSectionContent(ctx, reportUUID, sectionID, newContent)
 }
 
-func (s *ReportServiceImpl) DownloadReportAsJSON(ctx context.Context, reportID uuid.UUID) ([]byte, error) {
-	report, err := s.DownloadReport(ctx, reportID)
+func (s *ReportServiceImpl) DownloadReportAsJSON(ctx context.Context, reportID uuid.UUID, fields FieldRenderer) ([]byte, error) {
+	report, err := s.RenderReport(ctx, reportID, fields)
 	if err != nil {
 		return nil, err
 	}
 	return json.Marshal(report)
 }
 
-// at file scope (or inside the same file above DownloadReportAsPDF)
-type embeddedImage struct {
-	Mimetype string
-	Data     []byte
-}
-
-func extractDataURLImages(html string) (cleanHTML string, imgs []embeddedImage) {
-	// local regex so it’s not a global unused symbol
-	imgTagRe := regexp.MustCompile(`(?i)<img[^>]+src=["']data:(image/(?:png|jpeg|jpg));base64,([^"']+)["'][^>]*>`)
-	out := imgTagRe.ReplaceAllStringFunc(html, func(tag string) string {
-		m := imgTagRe.FindStringSubmatch(tag)
-		if len(m) != 3 {
-			return ""
-		}
-		mime := m[1]
-		b64 := strings.NewReplacer(" ", "", "\n", "").Replace(m[2])
-		data, err := base64.StdEncoding.DecodeString(b64)
-		if err != nil {
-			return ""
-		}
-		imgs = append(imgs, embeddedImage{Mimetype: mime, Data: data})
-		return "" // strip <img> from HTML; we’ll render images separately
-	})
-	return out, imgs
+func (s *ReportServiceImpl) DownloadReportAsPDF(ctx context.Context, reportID uuid.UUID, fields FieldRenderer) ([]byte, error) {
+	return s.DownloadReportAsPDFWithOptions(ctx, reportID, fields, ExportOptions{})
 }
 
-func (s *ReportServiceImpl) DownloadReportAsPDF(ctx context.Context, reportID uuid.UUID) ([]byte, error) {
-	rpt, err := s.DownloadReport(ctx, reportID)
+func (s *ReportServiceImpl) DownloadReportAsPDFWithOptions(ctx context.Context, reportID uuid.UUID, fields FieldRenderer, opts ExportOptions) ([]byte, error) {
+	rpt, err := s.RenderReport(ctx, reportID, fields)
 	if err != nil {
 		return nil, err
 	}
-
-	pdf := gofpdf.New("P", "mm", "A4", "")
-	pdf.SetMargins(15, 15, 15)
-	pdf.SetAutoPageBreak(true, 15)
-	pdf.AddPage()
-
-	pdf.SetFont("Arial", "B", 16)
-	pdf.Cell(0, 10, fmt.Sprintf("Report: %s", rpt.Metadata.Name))
-	pdf.Ln(12)
-
-	// If you’re using gofpdf’s HTML renderer:
-	html := pdf.HTMLBasicNew()
-
-	for _, sec := range rpt.Content {
-		// title...
-		pdf.SetFont("Arial", "B", 12)
-		pdf.Cell(0, 8, sec.Title)
-		pdf.Ln(8)
-
-		// (optional) sanitize first; otherwise use sec.Content directly
-		// sanitized := policy.Sanitize(sec.Content)
-		sanitized := sec.Content
-
-		// ⬇️ call the extractor so the function & regex are USED
-		cleaned, images := extractDataURLImages(sanitized)
-
-		// text rendering
-		pdf.SetFont("Arial", "", 11)
-		trimmed := strings.TrimSpace(strings.ToLower(strings.ReplaceAll(cleaned, " ", "")))
-		if trimmed == "" || trimmed == "<p><br></p>" || trimmed == "<p></p>" {
-			pdf.MultiCell(0, 6, "(No content provided)", "", "", false)
-		} else {
-			html.Write(5, cleaned)
-		}
-		pdf.Ln(4)
-
-		// image rendering (the block you already have)
-		for _, im := range images {
-			imgType := strings.ToUpper(strings.TrimPrefix(im.Mimetype, "image/"))
-			if imgType == "JPEG" {
-				imgType = "JPG"
-			}
-
-			r := bytes.NewReader(im.Data)
-			if imgType == "PNG" && len(im.Data) > 1_000_000 {
-				if pngImg, err := png.Decode(bytes.NewReader(im.Data)); err == nil {
-					var buf bytes.Buffer
-					_ = jpeg.Encode(&buf, pngImg, &jpeg.Options{Quality: 85})
-					r = bytes.NewReader(buf.Bytes())
-					imgType = "JPG"
-				}
-			}
-
-			name := fmt.Sprintf("sec-%v-%d", sec.ID, time.Now().UnixNano()) // use sec.ID or sec.ID.Hex()
-			opts := gofpdf.ImageOptions{ImageType: imgType, ReadDpi: true}
-			info := pdf.RegisterImageOptionsReader(name, opts, r)
-
-			w, h := info.Width(), info.Height()
-			maxW := 180.0
-			if w > maxW {
-				scale := maxW / w
-				w = maxW
-				h *= scale
-			}
-
-			x := (210.0 - w) / 2.0
-			y := pdf.GetY()
-			pdf.ImageOptions(name, x, y, w, 0, false, opts, 0, "")
-			pdf.Ln(h + 4)
-		}
-
-		if pdf.GetY() > 260 {
-			pdf.AddPage()
-		}
+	classification, err := s.classification(ctx, reportID)
+	if err != nil {
+		return nil, err
 	}
+	caseRef, appendices := CaseExhibits(ctx, fields, rpt.Metadata, rpt.Content)
+	// Dating the document by the last edit keeps repeated downloads of an
+	// unchanged report byte-identical.
+	return RenderPDF(rpt, PDFOptions{
+		CreatedAt:      rpt.Metadata.UpdatedAt.UTC().Truncate(time.Second),
+		CaseReference:  caseRef,
+		Appendices:     appendices,
+		Classification: classification,
+		Watermark:      opts.Watermark,
+	})
+}
 
-	var buf bytes.Buffer
-	if err := pdf.Output(&buf); err != nil {
-		return nil, fmt.Errorf("pdf output: %w", err)
+// classification is the marking exports of the report carry.
+func (s *ReportServiceImpl) classification(ctx context.Context, reportID uuid.UUID) (string, error) {
+	if s.markings == nil {
+		return DefaultClassification, nil
+	}
+	marking, err := s.markings.ReportMarking(ctx, reportID)
+	if err != nil {
+		return "", fmt.Errorf("resolve classification: %w", err)
+	}
+	if marking == "" {
+		return DefaultClassification, nil
 	}
-	return buf.Bytes(), nil
+	return marking, nil
 }
 
-
-
 func (s *ReportServiceImpl) UpdateCustomSectionContent(
 	ctx context.Context,
 	reportUUID uuid.UUID,
@@ -400,7 +387,9 @@ func (s *ReportServiceImpl) UpdateCustomSectionContent(
 	if err != nil {
 		return err
 	}
-	return s.mongoRepo.UpdateSection(ctx, mongoID, sectionID, newContent, tenantID, teamID)
+	return s.RecordSectionEdit(ctx, reportUUID, sectionID, RevisionEdited, func(ctx context.Context) error {
+		return s.mongoRepo.UpdateSection(ctx, mongoID, sectionID, newContent, tenantID, teamID)
+	})
 }
 
 func (s *ReportServiceImpl) UpdateSectionContent(
@@ -422,7 +411,9 @@ func (s *ReportServiceImpl) UpdateSectionTitle(
 	if err != nil {
 		return err
 	}
-	return s.mongoRepo.UpdateSectionTitle(ctx, mongoID, sectionID, newTitle, tenantID, teamID)
+	return s.RecordSectionEdit(ctx, reportUUID, sectionID, RevisionRetitled, func(ctx context.Context) error {
+		return s.mongoRepo.UpdateSectionTitle(ctx, mongoID, sectionID, newTitle, tenantID, teamID)
+	})
 }
 
 func (s *ReportServiceImpl) ReorderCustomSection(
@@ -435,7 +426,9 @@ func (s *ReportServiceImpl) ReorderCustomSection(
 	if err != nil {
 		return err
 	}
-	return s.mongoRepo.ReorderSection(ctx, mongoID, sectionID, newOrder, tenantID, teamID)
+	return s.RecordSectionEdit(ctx, reportUUID, sectionID, RevisionReordered, func(ctx context.Context) error {
+		return s.mongoRepo.ReorderSection(ctx, mongoID, sectionID, newOrder, tenantID, teamID)
+	})
 }
 
 var (
@@ -449,6 +442,9 @@ func (s *ReportServiceImpl) UpdateReportName(ctx context.Context, reportID uuid.
 	if l := utf8.RuneCountInString(trimmed); l == 0 || l > 255 {
 		return nil, ErrInvalidReportName
 	}
+	if err := s.ensureEditable(ctx, reportID); err != nil {
+		return nil, err
+	}
 
 	// 2) (Optional) Authorization & tenancy checks.
 	//    If you store tenant/team on Report, you can fetch first and check.
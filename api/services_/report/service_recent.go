@@ -49,6 +49,7 @@ func (s *ReportServiceImpl) ListRecentReports(ctx context.Context, opts RecentRe
 		}
 		out = append(out, RecentReport{
 			ID:           r.ID,
+			CaseID:       r.CaseID,
 			Title:        r.Name,
 			Status:       r.Status,
 			LastModified: last,
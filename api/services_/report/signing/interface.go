@@ -0,0 +1,68 @@
+package signing
+
+import (
+	"context"
+	"io"
+
+	"aegis-api/services_/report"
+
+	"github.com/google/uuid"
+)
+
+type Repository interface {
+	AutoMigrate() error
+
+	// ActiveKey returns the tenant's active key, or nil if it has none.
+	ActiveKey(tenantID string) (*SigningKey, error)
+	GetKey(tenantID, keyID string) (*SigningKey, error)
+	ListKeys(tenantID string) ([]SigningKey, error)
+	// ReplaceActiveKey retires the tenant's active key, if any, and stores k
+	// as the new one.
+	ReplaceActiveKey(k *SigningKey) error
+
+	// CreateArtifact inserts a; if the version is already sealed it returns
+	// ErrAlreadySealed.
+	CreateArtifact(a *Artifact) error
+	GetArtifact(reportID string, version int) (*Artifact, error)
+	LatestArtifact(reportID string) (*Artifact, error)
+	FindArtifacts(tenantID, sha256 string) ([]Artifact, error)
+
+	// UserName returns a user's full name, or "" if unknown.
+	UserName(userID string) (string, error)
+}
+
+// Reports is the part of report.ReportService sealing needs.
+type Reports interface {
+	GetReportByID(ctx context.Context, reportID string) (*report.Report, error)
+	ListSnapshots(ctx context.Context, reportID uuid.UUID) ([]report.ReportSnapshot, error)
+}
+
+// Cipher protects private keys at rest.
+type Cipher interface {
+	Encrypt(plaintext string) (string, error)
+	Decrypt(ciphertext string) (string, error)
+}
+
+type Service interface {
+	// Seal renders the published version of a report once, signs it with
+	// the tenant key and stores it. Sealing an already sealed version
+	// returns the stored artifact.
+	Seal(ctx context.Context, actor Actor, reportID uuid.UUID) (*Artifact, error)
+	// Artifact returns the sealed artifact of the report's current version.
+	Artifact(ctx context.Context, actor Actor, reportID uuid.UUID) (*Artifact, error)
+	// Verify checks whether pdf is a sealed report of the tenant.
+	Verify(ctx context.Context, tenantID string, pdf io.Reader) (*Verification, error)
+
+	// Enabled reports whether a cipher is configured, without which
+	// nothing can be signed.
+	Enabled() bool
+	// Sign signs payload with the tenant's active key, for artifacts other
+	// than reports such as case closure manifests.
+	Sign(tenantID, userID string, payload []byte) (*Detached, error)
+	// VerifyDetached checks a signature made by Sign against the tenant key
+	// it names.
+	VerifyDetached(tenantID string, payload []byte, d *Detached) error
+
+	PublicKeys(tenantID string) ([]PublicKey, error)
+	RotateKey(tenantID, userID string) (*PublicKey, error)
+}
@@ -0,0 +1,131 @@
+package signing
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+const (
+	// BundleFormat identifies the detached signature bundle layout.
+	BundleFormat = "aegis-report-signature/v1"
+	// AlgorithmEd25519 is the only signing algorithm in use.
+	AlgorithmEd25519 = "Ed25519"
+)
+
+// SigningKey is a tenant's report signing key. The private key is stored
+// encrypted; retired keys are kept so earlier artifacts still verify.
+type SigningKey struct {
+	ID          string     `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID    string     `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	Algorithm   string     `gorm:"type:varchar(20);not null" json:"algorithm"`
+	PublicKey   string     `gorm:"type:text;not null" json:"-"` // base64 raw public key
+	PrivateKey  string     `gorm:"type:text;not null" json:"-"` // encrypted base64 seed
+	Fingerprint string     `gorm:"type:char(64);not null" json:"fingerprint"`
+	Active      bool       `gorm:"not null;default:true" json:"active"`
+	CreatedBy   string     `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	RetiredAt   *time.Time `json:"retired_at,omitempty"`
+}
+
+func (SigningKey) TableName() string { return "report_signing_keys" }
+
+// PublicKey is a signing key as published to verifiers.
+type PublicKey struct {
+	ID          string     `json:"id"`
+	Algorithm   string     `json:"algorithm"`
+	PEM         string     `json:"public_key_pem"`
+	Fingerprint string     `json:"fingerprint"`
+	Active      bool       `json:"active"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RetiredAt   *time.Time `json:"retired_at,omitempty"`
+}
+
+// Artifact is the sealed PDF of one published report version. It is
+// written once and never updated.
+type Artifact struct {
+	ID           string         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TenantID     string         `gorm:"type:uuid;not null" json:"tenant_id"`
+	ReportID     string         `gorm:"type:uuid;not null;uniqueIndex:uq_report_artifact_version,priority:1" json:"report_id"`
+	Version      int            `gorm:"not null;uniqueIndex:uq_report_artifact_version,priority:2" json:"version"`
+	SnapshotID   string         `gorm:"type:uuid;not null" json:"snapshot_id"`
+	ReportNumber string         `gorm:"type:varchar(255)" json:"report_number"`
+	Name         string         `gorm:"type:varchar(255)" json:"name"`
+	SHA256       string         `gorm:"column:sha256;type:char(64);not null;index" json:"sha256"`
+	Size         int64          `gorm:"not null" json:"size"`
+	PDF          []byte         `gorm:"type:bytea;not null" json:"-"`
+	Bundle       datatypes.JSON `gorm:"type:jsonb;not null" json:"bundle"` // Bundle
+	KeyID        string         `gorm:"type:uuid;not null" json:"key_id"`
+	CreatedBy    string         `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt    time.Time      `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (Artifact) TableName() string { return "report_artifacts" }
+
+// Certification is the examiner's statement sealed with the report.
+type Certification struct {
+	ExaminerID   string `json:"examiner_id"`
+	ExaminerName string `json:"examiner_name"`
+	Statement    string `json:"statement"`
+}
+
+// Bundle is the detached signature over a sealed PDF. The signature covers
+// the bundle's JSON encoding with Signature left empty, so a verifier needs
+// only the PDF, the bundle and the tenant's public key.
+type Bundle struct {
+	Format         string        `json:"format"`
+	ReportID       string        `json:"report_id"`
+	ReportNumber   string        `json:"report_number"`
+	ReportName     string        `json:"report_name"`
+	Version        int           `json:"version"`
+	SnapshotID     string        `json:"snapshot_id"`
+	ContentSHA256  string        `json:"content_sha256"` // of the published snapshot
+	PDFSHA256      string        `json:"pdf_sha256"`
+	PDFSize        int64         `json:"pdf_size"`
+	Certification  Certification `json:"certification"`
+	SignedAt       time.Time     `json:"signed_at"`
+	SignedBy       string        `json:"signed_by"`
+	KeyID          string        `json:"key_id"`
+	KeyFingerprint string        `json:"key_fingerprint"`
+	Algorithm      string        `json:"algorithm"`
+	Signature      string        `json:"signature,omitempty"` // base64
+}
+
+// SigningPayload is the byte string the signature covers.
+func (b Bundle) SigningPayload() ([]byte, error) {
+	b.Signature = ""
+	return json.Marshal(b)
+}
+
+// Detached is a signature over raw bytes made with a tenant signing key.
+// The payload is signed as is, so it verifies with the public key alone.
+type Detached struct {
+	Algorithm      string    `json:"algorithm"`
+	KeyID          string    `json:"key_id"`
+	KeyFingerprint string    `json:"key_fingerprint"`
+	PublicKeyPEM   string    `json:"public_key_pem"`
+	SignedAt       time.Time `json:"signed_at"`
+	Signature      string    `json:"signature"` // base64
+}
+
+// Actor is the user sealing or verifying a report.
+type Actor struct {
+	UserID   string
+	TenantID string
+}
+
+// Verification is the outcome of checking an uploaded PDF.
+type Verification struct {
+	// Match is true when the upload is byte-for-byte a sealed report of the
+	// tenant.
+	Match  bool   `json:"match"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+	// SignatureValid reports whether the stored bundle still verifies
+	// against the tenant key that signed it.
+	SignatureValid bool    `json:"signature_valid"`
+	Bundle         *Bundle `json:"bundle,omitempty"`
+	// Superseded is set when a later version of the report was published.
+	Superseded bool `json:"superseded"`
+}
@@ -0,0 +1,116 @@
+package signing
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) AutoMigrate() error {
+	return r.db.AutoMigrate(&SigningKey{}, &Artifact{})
+}
+
+func (r *GormRepository) ActiveKey(tenantID string) (*SigningKey, error) {
+	var k SigningKey
+	err := r.db.Where("tenant_id = ? AND active", tenantID).First(&k).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &k, err
+}
+
+func (r *GormRepository) GetKey(tenantID, keyID string) (*SigningKey, error) {
+	var k SigningKey
+	err := r.db.Where("tenant_id = ? AND id = ?", tenantID, keyID).First(&k).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrKeyNotFound
+	}
+	return &k, err
+}
+
+func (r *GormRepository) ListKeys(tenantID string) ([]SigningKey, error) {
+	var out []SigningKey
+	err := r.db.Where("tenant_id = ?", tenantID).Order("created_at DESC").Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) ReplaceActiveKey(k *SigningKey) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Serialise key changes per tenant so only one key is ever active.
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "signing-key:"+k.TenantID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&SigningKey{}).
+			Where("tenant_id = ? AND active", k.TenantID).
+			Updates(map[string]interface{}{"active": false, "retired_at": time.Now().UTC()}).Error; err != nil {
+			return err
+		}
+		return tx.Create(k).Error
+	})
+}
+
+func (r *GormRepository) CreateArtifact(a *Artifact) error {
+	res := r.db.Exec(`INSERT INTO report_artifacts
+		(tenant_id, report_id, version, snapshot_id, report_number, name, sha256, size, pdf, bundle, key_id, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (report_id, version) DO NOTHING`,
+		a.TenantID, a.ReportID, a.Version, a.SnapshotID, a.ReportNumber, a.Name, a.SHA256, a.Size, a.PDF, a.Bundle, a.KeyID, a.CreatedBy)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrAlreadySealed
+	}
+	stored, err := r.GetArtifact(a.ReportID, a.Version)
+	if err != nil {
+		return err
+	}
+	a.ID, a.CreatedAt = stored.ID, stored.CreatedAt
+	return nil
+}
+
+func (r *GormRepository) GetArtifact(reportID string, version int) (*Artifact, error) {
+	var a Artifact
+	err := r.db.Where("report_id = ? AND version = ?", reportID, version).First(&a).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrArtifactNotFound
+	}
+	return &a, err
+}
+
+func (r *GormRepository) LatestArtifact(reportID string) (*Artifact, error) {
+	var a Artifact
+	err := r.db.Omit("pdf").Where("report_id = ?", reportID).Order("version DESC").First(&a).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrArtifactNotFound
+	}
+	return &a, err
+}
+
+func (r *GormRepository) FindArtifacts(tenantID, sha256 string) ([]Artifact, error) {
+	var out []Artifact
+	err := r.db.Omit("pdf").
+		Where("tenant_id = ? AND sha256 = ?", tenantID, sha256).
+		Order("created_at").Find(&out).Error
+	return out, err
+}
+
+func (r *GormRepository) UserName(userID string) (string, error) {
+	var names []string
+	if err := r.db.Table("users").Where("id = ?", userID).Pluck("full_name", &names).Error; err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", nil
+	}
+	return names[0], nil
+}
@@ -0,0 +1,380 @@
+package signing
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"aegis-api/services_/report"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+var (
+	ErrReportNotFound   = report.ErrReportNotFound
+	ErrNotPublished     = errors.New("only published reports can be sealed")
+	ErrArtifactNotFound = errors.New("report has no sealed artifact")
+	ErrAlreadySealed    = errors.New("report version is already sealed")
+	ErrKeyNotFound      = errors.New("signing key not found")
+	ErrSigningDisabled  = errors.New("report signing is not configured")
+	ErrFileTooLarge     = errors.New("file is too large to verify")
+)
+
+// MaxVerifySize bounds uploads to Verify.
+const MaxVerifySize = 200 << 20
+
+type service struct {
+	repo    Repository
+	reports Reports
+	fields  report.FieldRenderer // resolves the case reference and exhibits; may be nil
+	cipher  Cipher               // nil disables sealing; verification still works
+	now     func() time.Time
+}
+
+func NewService(repo Repository, reports Reports, fields report.FieldRenderer, cipher Cipher) Service {
+	return &service{repo: repo, reports: reports, fields: fields, cipher: cipher, now: time.Now}
+}
+
+// reportInTenant loads the report, hiding reports of other tenants.
+func (s *service) reportInTenant(ctx context.Context, actor Actor, reportID uuid.UUID) (*report.Report, error) {
+	rpt, err := s.reports.GetReportByID(ctx, reportID.String())
+	if err != nil || rpt == nil || rpt.TenantID.String() != actor.TenantID {
+		return nil, ErrReportNotFound
+	}
+	return rpt, nil
+}
+
+func (s *service) Seal(ctx context.Context, actor Actor, reportID uuid.UUID) (*Artifact, error) {
+	rpt, err := s.reportInTenant(ctx, actor, reportID)
+	if err != nil {
+		return nil, err
+	}
+	if rpt.Status != "published" {
+		return nil, ErrNotPublished
+	}
+	if a, err := s.repo.GetArtifact(rpt.ID.String(), rpt.Version); err == nil {
+		return a, nil
+	} else if !errors.Is(err, ErrArtifactNotFound) {
+		return nil, err
+	}
+	if s.cipher == nil {
+		return nil, ErrSigningDisabled
+	}
+
+	snap, err := s.publishedSnapshot(ctx, rpt)
+	if err != nil {
+		return nil, err
+	}
+	var sections []report.ReportSection
+	if err := json.Unmarshal(snap.Sections, &sections); err != nil {
+		return nil, fmt.Errorf("corrupt snapshot %s: %w", snap.ID, err)
+	}
+
+	key, priv, err := s.activeKey(actor.TenantID, actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	examiner, err := s.repo.UserName(rpt.ExaminerID.String())
+	if err != nil {
+		return nil, err
+	}
+	cert := Certification{
+		ExaminerID:   rpt.ExaminerID.String(),
+		ExaminerName: examiner,
+		Statement:    certificationStatement(examiner, rpt),
+	}
+
+	// The snapshot time fixes the PDF's dates, so re-rendering the same
+	// snapshot yields the same bytes.
+	sealedAt := snap.CreatedAt.UTC().Truncate(time.Second)
+	caseRef, appendices := report.CaseExhibits(ctx, s.fields, rpt, sections)
+	pdf, err := report.RenderPDF(&report.ReportWithContent{Metadata: rpt, Content: sections}, report.PDFOptions{
+		CreatedAt:     sealedAt,
+		CaseReference: caseRef,
+		Appendices:    appendices,
+		Footer:        fmt.Sprintf("%s - version %d - sealed %s", rpt.ReportNumber, rpt.Version, sealedAt.Format(time.RFC3339)),
+		Certification: cert.Statement,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(pdf)
+
+	bundle := Bundle{
+		Format:         BundleFormat,
+		ReportID:       rpt.ID.String(),
+		ReportNumber:   rpt.ReportNumber,
+		ReportName:     rpt.Name,
+		Version:        rpt.Version,
+		SnapshotID:     snap.ID,
+		ContentSHA256:  snap.ContentSHA256,
+		PDFSHA256:      hex.EncodeToString(sum[:]),
+		PDFSize:        int64(len(pdf)),
+		Certification:  cert,
+		SignedAt:       s.now().UTC().Truncate(time.Second),
+		SignedBy:       actor.UserID,
+		KeyID:          key.ID,
+		KeyFingerprint: key.Fingerprint,
+		Algorithm:      key.Algorithm,
+	}
+	payload, err := bundle.SigningPayload()
+	if err != nil {
+		return nil, err
+	}
+	bundle.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, payload))
+	rawBundle, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Artifact{
+		TenantID:     actor.TenantID,
+		ReportID:     rpt.ID.String(),
+		Version:      rpt.Version,
+		SnapshotID:   snap.ID,
+		ReportNumber: rpt.ReportNumber,
+		Name:         rpt.Name,
+		SHA256:       bundle.PDFSHA256,
+		Size:         bundle.PDFSize,
+		PDF:          pdf,
+		Bundle:       datatypes.JSON(rawBundle),
+		KeyID:        key.ID,
+		CreatedBy:    actor.UserID,
+	}
+	if err := s.repo.CreateArtifact(a); err != nil {
+		if errors.Is(err, ErrAlreadySealed) {
+			// Someone sealed the same version concurrently; theirs stands.
+			return s.repo.GetArtifact(rpt.ID.String(), rpt.Version)
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+// publishedSnapshot finds the snapshot pinned when the report's current
+// version was published.
+func (s *service) publishedSnapshot(ctx context.Context, rpt *report.Report) (*report.ReportSnapshot, error) {
+	snaps, err := s.reports.ListSnapshots(ctx, rpt.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range snaps {
+		if snaps[i].Version == rpt.Version {
+			return &snaps[i], nil
+		}
+	}
+	return nil, report.ErrSnapshotNotFound
+}
+
+func certificationStatement(examiner string, rpt *report.Report) string {
+	if examiner == "" {
+		examiner = "the undersigned examiner"
+	}
+	return fmt.Sprintf("I, %s, certify that report %s (version %d) is a true and accurate record of the "+
+		"examination I performed and of my findings, that the evidence it relies on was handled in "+
+		"accordance with the recorded chain of custody, and that this document has not been altered "+
+		"since it was approved for publication.", examiner, rpt.ReportNumber, rpt.Version)
+}
+
+func (s *service) Artifact(ctx context.Context, actor Actor, reportID uuid.UUID) (*Artifact, error) {
+	rpt, err := s.reportInTenant(ctx, actor, reportID)
+	if err != nil {
+		return nil, err
+	}
+	if rpt.Status != "published" {
+		return nil, ErrArtifactNotFound
+	}
+	return s.repo.GetArtifact(rpt.ID.String(), rpt.Version)
+}
+
+func (s *service) Verify(ctx context.Context, tenantID string, pdf io.Reader) (*Verification, error) {
+	h := sha256.New()
+	n, err := io.Copy(h, io.LimitReader(pdf, MaxVerifySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if n > MaxVerifySize {
+		return nil, ErrFileTooLarge
+	}
+	out := &Verification{SHA256: hex.EncodeToString(h.Sum(nil)), Size: n}
+
+	found, err := s.repo.FindArtifacts(tenantID, out.SHA256)
+	if err != nil || len(found) == 0 {
+		return out, err
+	}
+	a := found[0]
+	var bundle Bundle
+	if err := json.Unmarshal(a.Bundle, &bundle); err != nil {
+		return nil, fmt.Errorf("corrupt signature bundle for artifact %s: %w", a.ID, err)
+	}
+	out.Match = true
+	out.Bundle = &bundle
+	out.SignatureValid = s.verifyBundle(tenantID, &bundle) == nil && bundle.PDFSHA256 == out.SHA256
+
+	if latest, err := s.repo.LatestArtifact(a.ReportID); err == nil && latest.Version > a.Version {
+		out.Superseded = true
+	}
+	return out, nil
+}
+
+// verifyBundle checks bundle's signature against the tenant key it names.
+func (s *service) verifyBundle(tenantID string, bundle *Bundle) error {
+	payload, err := bundle.SigningPayload()
+	if err != nil {
+		return err
+	}
+	return s.verify(tenantID, bundle.KeyID, payload, bundle.Signature)
+}
+
+// verify checks a base64 signature over payload against a tenant key.
+func (s *service) verify(tenantID, keyID string, payload []byte, signature string) error {
+	key, err := s.repo.GetKey(tenantID, keyID)
+	if err != nil {
+		return err
+	}
+	pub, err := base64.StdEncoding.DecodeString(key.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return fmt.Errorf("corrupt public key %s", key.ID)
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(ed25519.PublicKey(pub), payload, sig) {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
+
+func (s *service) Enabled() bool { return s.cipher != nil }
+
+func (s *service) Sign(tenantID, userID string, payload []byte) (*Detached, error) {
+	if s.cipher == nil {
+		return nil, ErrSigningDisabled
+	}
+	key, priv, err := s.activeKey(tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	pk, err := publicKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Detached{
+		Algorithm:      key.Algorithm,
+		KeyID:          key.ID,
+		KeyFingerprint: key.Fingerprint,
+		PublicKeyPEM:   pk.PEM,
+		SignedAt:       s.now().UTC(),
+		Signature:      base64.StdEncoding.EncodeToString(ed25519.Sign(priv, payload)),
+	}, nil
+}
+
+func (s *service) VerifyDetached(tenantID string, payload []byte, d *Detached) error {
+	return s.verify(tenantID, d.KeyID, payload, d.Signature)
+}
+
+// activeKey returns the tenant's signing key, creating one on first use.
+func (s *service) activeKey(tenantID, userID string) (*SigningKey, ed25519.PrivateKey, error) {
+	key, err := s.repo.ActiveKey(tenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if key == nil {
+		if key, err = s.newKey(tenantID, userID); err != nil {
+			return nil, nil, err
+		}
+	}
+	seed, err := s.cipher.Decrypt(key.PrivateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to unlock signing key %s: %w", key.ID, err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(seed)
+	if err != nil || len(raw) != ed25519.SeedSize {
+		return nil, nil, fmt.Errorf("corrupt signing key %s", key.ID)
+	}
+	return key, ed25519.NewKeyFromSeed(raw), nil
+}
+
+func (s *service) newKey(tenantID, userID string) (*SigningKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.cipher.Encrypt(base64.StdEncoding.EncodeToString(priv.Seed()))
+	if err != nil {
+		return nil, err
+	}
+	fp := sha256.Sum256(pub)
+	key := &SigningKey{
+		TenantID:    tenantID,
+		Algorithm:   AlgorithmEd25519,
+		PublicKey:   base64.StdEncoding.EncodeToString(pub),
+		PrivateKey:  sealed,
+		Fingerprint: hex.EncodeToString(fp[:]),
+		Active:      true,
+		CreatedBy:   userID,
+	}
+	if err := s.repo.ReplaceActiveKey(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (s *service) PublicKeys(tenantID string) ([]PublicKey, error) {
+	keys, err := s.repo.ListKeys(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]PublicKey, 0, len(keys))
+	for i := range keys {
+		pk, err := publicKey(&keys[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *pk)
+	}
+	return out, nil
+}
+
+func (s *service) RotateKey(tenantID, userID string) (*PublicKey, error) {
+	if s.cipher == nil {
+		return nil, ErrSigningDisabled
+	}
+	key, err := s.newKey(tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return publicKey(key)
+}
+
+func publicKey(k *SigningKey) (*PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(k.PublicKey)
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("corrupt public key %s", k.ID)
+	}
+	der, err := x509.MarshalPKIXPublicKey(ed25519.PublicKey(raw))
+	if err != nil {
+		return nil, err
+	}
+	return &PublicKey{
+		ID:          k.ID,
+		Algorithm:   k.Algorithm,
+		PEM:         string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		Fingerprint: k.Fingerprint,
+		Active:      k.Active,
+		CreatedAt:   k.CreatedAt,
+		RetiredAt:   k.RetiredAt,
+	}, nil
+}
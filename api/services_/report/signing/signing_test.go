@@ -0,0 +1,163 @@
+package signing_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+
+	"aegis-api/internal/x3dh"
+	"aegis-api/services_/report"
+	"aegis-api/services_/report/signing"
+	"aegis-api/tests/fakes"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// findings is a one-section report body.
+func findings(content string) report.ReportSection {
+	return report.ReportSection{ID: primitive.NewObjectID(), Title: "Findings", Content: content}
+}
+
+func newService(t *testing.T) (signing.Service, *fakes.SigningStore, *fakes.Reports, signing.Actor) {
+	t.Helper()
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	cipher, err := x3dh.NewAESGCMCryptoService(key)
+	require.NoError(t, err)
+
+	tenant := uuid.New()
+	rep := &report.Report{ID: uuid.New(), TenantID: tenant, ExaminerID: uuid.New(), Name: "Intrusion report", ReportNumber: "RPT-0042", Status: "draft", Version: 1}
+	repo, reports := &fakes.SigningStore{Examiner: "Dana Examiner"}, &fakes.Reports{}
+	reports.Add(rep)
+	return signing.NewService(repo, reports, nil, cipher), repo, reports, signing.Actor{UserID: uuid.NewString(), TenantID: tenant.String()}
+}
+
+func verifyWithPEM(t *testing.T, pemKey string, bundle signing.Bundle) bool {
+	t.Helper()
+	block, _ := pem.Decode([]byte(pemKey))
+	require.NotNil(t, block)
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	require.NoError(t, err)
+	sig, err := base64.StdEncoding.DecodeString(bundle.Signature)
+	require.NoError(t, err)
+	payload, err := bundle.SigningPayload()
+	require.NoError(t, err)
+	return ed25519.Verify(pub.(ed25519.PublicKey), payload, sig)
+}
+
+func TestSealSignsPublishedReport(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, reports, actor := newService(t)
+	rep := reports.Items[0].Metadata
+
+	_, err := svc.Seal(ctx, actor, rep.ID)
+	require.ErrorIs(t, err, signing.ErrNotPublished)
+
+	require.NoError(t, reports.Publish(rep.ID, findings("<p>Lateral movement via RDP at 10:40 UTC.</p>")))
+	a, err := svc.Seal(ctx, actor, rep.ID)
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(a.PDF, []byte("%PDF")))
+	sum := sha256.Sum256(a.PDF)
+	require.Equal(t, hex.EncodeToString(sum[:]), a.SHA256)
+
+	var bundle signing.Bundle
+	require.NoError(t, json.Unmarshal(a.Bundle, &bundle))
+	require.Equal(t, a.SHA256, bundle.PDFSHA256)
+	require.Equal(t, "Dana Examiner", bundle.Certification.ExaminerName)
+	require.Contains(t, bundle.Certification.Statement, "RPT-0042")
+
+	keys, err := svc.PublicKeys(actor.TenantID)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.True(t, verifyWithPEM(t, keys[0].PEM, bundle))
+
+	// A tampered bundle no longer verifies.
+	bundle.Certification.ExaminerName = "Someone Else"
+	require.False(t, verifyWithPEM(t, keys[0].PEM, bundle))
+
+	// Sealing is once per version.
+	again, err := svc.Seal(ctx, actor, rep.ID)
+	require.NoError(t, err)
+	require.Equal(t, a.ID, again.ID)
+	require.Len(t, repo.Artifacts, 1)
+
+	// The same snapshot always renders to the same bytes.
+	repo.Artifacts = nil
+	rerendered, err := svc.Seal(ctx, actor, rep.ID)
+	require.NoError(t, err)
+	require.Equal(t, a.SHA256, rerendered.SHA256)
+}
+
+func TestVerifyUploadedPDF(t *testing.T) {
+	ctx := context.Background()
+	svc, _, reports, actor := newService(t)
+	rep := reports.Items[0].Metadata
+	require.NoError(t, reports.Publish(rep.ID, findings("<p>First version.</p>")))
+	v1, err := svc.Seal(ctx, actor, rep.ID)
+	require.NoError(t, err)
+
+	res, err := svc.Verify(ctx, actor.TenantID, bytes.NewReader(v1.PDF))
+	require.NoError(t, err)
+	require.True(t, res.Match)
+	require.True(t, res.SignatureValid)
+	require.False(t, res.Superseded)
+	require.Equal(t, rep.ID.String(), res.Bundle.ReportID)
+
+	altered := bytes.Clone(v1.PDF)
+	altered[len(altered)/2] ^= 0x01
+	res, err = svc.Verify(ctx, actor.TenantID, bytes.NewReader(altered))
+	require.NoError(t, err)
+	require.False(t, res.Match)
+	require.Nil(t, res.Bundle)
+
+	res, err = svc.Verify(ctx, uuid.NewString(), bytes.NewReader(v1.PDF))
+	require.NoError(t, err)
+	require.False(t, res.Match, "artifacts are only visible to their tenant")
+
+	// After a key rotation and a new version, the old PDF still verifies
+	// but is reported as superseded.
+	_, err = svc.RotateKey(actor.TenantID, actor.UserID)
+	require.NoError(t, err)
+	require.NoError(t, reports.Publish(rep.ID, findings("<p>Second version.</p>")))
+	v2, err := svc.Seal(ctx, actor, rep.ID)
+	require.NoError(t, err)
+	require.NotEqual(t, v1.KeyID, v2.KeyID)
+
+	res, err = svc.Verify(ctx, actor.TenantID, bytes.NewReader(v1.PDF))
+	require.NoError(t, err)
+	require.True(t, res.Match)
+	require.True(t, res.SignatureValid)
+	require.True(t, res.Superseded)
+}
+
+func TestSignDetachedPayload(t *testing.T) {
+	svc, _, _, actor := newService(t)
+	payload := []byte(`{"case_id":"c-1","items":[]}`)
+
+	d, err := svc.Sign(actor.TenantID, actor.UserID, payload)
+	require.NoError(t, err)
+	require.NoError(t, svc.VerifyDetached(actor.TenantID, payload, d))
+
+	// The payload is signed as is, so the embedded key alone verifies it.
+	block, _ := pem.Decode([]byte(d.PublicKeyPEM))
+	require.NotNil(t, block)
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	require.NoError(t, err)
+	sig, err := base64.StdEncoding.DecodeString(d.Signature)
+	require.NoError(t, err)
+	require.True(t, ed25519.Verify(pub.(ed25519.PublicKey), payload, sig))
+
+	require.Error(t, svc.VerifyDetached(actor.TenantID, append(payload, ' '), d))
+	require.Error(t, svc.VerifyDetached(uuid.NewString(), payload, d))
+}
@@ -0,0 +1,59 @@
+package report
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTemplateNotFound = errors.New("report template not found")
+	ErrSectionRequired  = errors.New("section is required by the report template and cannot be deleted")
+)
+
+// TemplateSection is one section a template lays out in a new report.
+type TemplateSection struct {
+	Title          string   `json:"title"`
+	DefaultContent string   `json:"default_content"`
+	Required       bool     `json:"required"`
+	Placeholders   []string `json:"placeholders,omitempty"`
+}
+
+// TemplateSnapshot is the exact template version a report is generated from.
+// TemplateID is uuid.Nil for the built-in layout.
+type TemplateSnapshot struct {
+	TemplateID uuid.UUID         `json:"template_id"`
+	Name       string            `json:"name"`
+	CaseType   string            `json:"case_type"`
+	Version    int               `json:"version"`
+	Sections   []TemplateSection `json:"sections"`
+}
+
+// TemplateResolver picks the template for a new report. A nil templateID
+// asks for the tenant's default for caseType; a nil snapshot with no error
+// means the tenant has none and the built-in layout is used.
+type TemplateResolver interface {
+	ResolveTemplate(ctx context.Context, tenantID, templateID uuid.UUID, caseType string) (*TemplateSnapshot, error)
+}
+
+// BuiltinTemplate is the layout used when a tenant has no templates.
+func BuiltinTemplate() *TemplateSnapshot {
+	titles := []string{
+		"Case Identification",
+		"Scope and Objectives",
+		"Evidence Summary",
+		"Tools and Methodologies",
+		"Findings",
+		"Interpretation and Analysis",
+		"Limitations",
+		"Conclusion",
+		"Appendices",
+		"Certification",
+	}
+	t := &TemplateSnapshot{Name: "Incident Response (built-in)", CaseType: "incident_response", Version: 1}
+	for _, title := range titles {
+		t.Sections = append(t.Sections, TemplateSection{Title: title})
+	}
+	return t
+}
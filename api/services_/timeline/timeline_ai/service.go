@@ -1,46 +1,29 @@
 package timelineai
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/http"
 	"strings"
 	"time"
+
+	"aegis-api/services_/llm"
 )
 
 type aiService struct {
 	repository AIRepository
 	config     *AIModelConfig
-	httpClient *http.Client
+	llm        llm.Provider
 }
 
-// NewAIService creates a new AI service instance
-func NewAIService(repository AIRepository, config *AIModelConfig) AIService {
+// NewAIService creates a new AI service instance backed by the given LLM provider
+func NewAIService(repository AIRepository, config *AIModelConfig, provider llm.Provider) AIService {
 	return &aiService{
 		repository: repository,
 		config:     config,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		llm:        provider,
 	}
 }
 
-// OllamaRequest represents a request to Ollama API
-type OllamaRequest struct {
-	Model   string                 `json:"model"`
-	Prompt  string                 `json:"prompt"`
-	Stream  bool                   `json:"stream"`
-	Options map[string]interface{} `json:"options,omitempty"`
-}
-
-// OllamaResponse represents a response from Ollama API
-type OllamaResponse struct {
-	Response string `json:"response"`
-	Done     bool   `json:"done"`
-}
-
 func (s *aiService) GetEventSuggestions(ctx context.Context, req *SuggestionRequest) (*AIAnalysisResult, error) {
 	if req.SuggestionType != "completion" && req.SuggestionType != "next_steps" {
 		return nil, fmt.Errorf("unsupported suggestion type: %s", req.SuggestionType)
@@ -64,27 +47,17 @@ func (s *aiService) GetEventSuggestions(ctx context.Context, req *SuggestionRequ
 		}, nil
 	}
 
-	// Hit /suggestions route
-	resp, err := s.callAIService(ctx, http.MethodPost, "suggestions", map[string]interface{}{
-		"input_text": req.InputText,
-		"case_id":    req.CaseID,
-	})
+	var suggestions []string
+	var err error
+	if req.SuggestionType == "completion" {
+		suggestions, err = s.getCompletionSuggestions(ctx, req)
+	} else {
+		suggestions, err = s.getNextStepSuggestions(ctx, req)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	raw, ok := resp["suggestions"].([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid AI response format")
-	}
-
-	var suggestions []string
-	for _, v := range raw {
-		if str, ok := v.(string); ok {
-			suggestions = append(suggestions, str)
-		}
-	}
-
 	result := &AIAnalysisResult{
 		CaseID:       req.CaseID,
 		EventID:      req.EventID,
@@ -108,20 +81,16 @@ func (s *aiService) getCompletionSuggestions(ctx context.Context, req *Suggestio
 
 	prompt := s.buildCompletionPrompt(req)
 
-	// Pass endpoint + options explicitly
-	response, err := s.callAIService(ctx, prompt, "completion", map[string]interface{}{})
+	respText, err := s.complete(ctx, "timeline.completion", prompt)
 	if err != nil {
 		return s.getPatternBasedSuggestions(ctx, req)
 	}
 
-	// response is a map[string]interface{}
-	// You need to extract the actual text field
-	respText, ok := response["generated_text"].(string)
-	if !ok {
-		return nil, fmt.Errorf("invalid response format from AI service")
+	suggestions := s.parseCompletionResponse(respText)
+	if len(suggestions) == 0 {
+		return s.getPatternBasedSuggestions(ctx, req)
 	}
-
-	return s.parseCompletionResponse(respText), nil
+	return suggestions, nil
 }
 
 func (s *aiService) getNextStepSuggestions(ctx context.Context, req *SuggestionRequest) ([]string, error) {
@@ -131,17 +100,16 @@ func (s *aiService) getNextStepSuggestions(ctx context.Context, req *SuggestionR
 
 	prompt := s.buildNextStepsPrompt(req)
 
-	response, err := s.callAIService(ctx, prompt, "next-steps", map[string]interface{}{})
+	respText, err := s.complete(ctx, "timeline.next_steps", prompt)
 	if err != nil {
 		return s.getDefaultNextSteps(req), nil
 	}
 
-	respText, ok := response["generated_text"].(string)
-	if !ok {
-		return nil, fmt.Errorf("invalid response format from AI service")
+	steps := s.parseNextStepsResponse(respText)
+	if len(steps) == 0 {
+		return s.getDefaultNextSteps(req), nil
 	}
-
-	return s.parseNextStepsResponse(respText), nil
+	return steps, nil
 }
 
 func (s *aiService) GetSeverityRecommendation(ctx context.Context, description string) (string, float64, error) {
@@ -151,24 +119,29 @@ func (s *aiService) GetSeverityRecommendation(ctx context.Context, description s
 		return keywordSeverity, 0.7, nil
 	}
 
-	resp, err := s.callAIService(ctx, http.MethodPost, "severity", map[string]interface{}{
-		"description": description,
-	})
+	prompt := fmt.Sprintf(`You are a DFIR analyst. Classify the severity of this investigation event.
+Answer with exactly one word: low, medium, high or critical.
+
+Event: %s
+
+Severity:`, description)
+
+	respText, err := s.complete(ctx, "timeline.severity", prompt)
 	if err != nil {
 		return keywordSeverity, 0.7, nil
 	}
 
-	severity, ok := resp["recommended_severity"].(string)
-	if !ok || severity == "" {
+	severity := parseSeverityResponse(respText)
+	if severity == "" {
 		return keywordSeverity, 0.7, nil
 	}
 
 	confidence := 0.8
-	if c, ok := resp["confidence"].(float64); ok {
-		confidence = c
+	if severity == keywordSeverity {
+		confidence = 0.9
 	}
 
-	return strings.ToLower(severity), confidence, nil
+	return severity, confidence, nil
 }
 
 func (s *aiService) GetTagSuggestions(ctx context.Context, description string) ([]string, error) {
@@ -179,26 +152,19 @@ func (s *aiService) GetTagSuggestions(ctx context.Context, description string) (
 		return commonTags, nil
 	}
 
-	// Call AI /tags endpoint
-	response, err := s.callAIService(ctx, http.MethodPost, "tags", map[string]interface{}{
-		"description": description,
-	})
-	if err != nil {
-		return commonTags, nil
-	}
+	prompt := fmt.Sprintf(`You are a DFIR analyst. Suggest up to 5 short lowercase tags for this investigation event.
+Return the tags as a single comma-separated line.
+
+Event: %s
+
+Tags:`, description)
 
-	// Parse tags from response
-	rawTags, ok := response["tags"].([]interface{})
-	if !ok {
+	respText, err := s.complete(ctx, "timeline.tags", prompt)
+	if err != nil {
 		return commonTags, nil
 	}
 
-	var aiTags []string
-	for _, t := range rawTags {
-		if str, ok := t.(string); ok {
-			aiTags = append(aiTags, str)
-		}
-	}
+	aiTags := s.parseTagsResponse(respText)
 
 	// Combine + deduplicate
 	combined := make(map[string]bool)
@@ -234,29 +200,20 @@ func (s *aiService) extractIOCsWithAI(ctx context.Context, text string) ([]IOCEx
 		return []IOCExtraction{}, nil
 	}
 
-	resp, err := s.callAIService(ctx, http.MethodPost, "iocs", map[string]interface{}{
-		"text": text,
-	})
+	prompt := fmt.Sprintf(`You are a DFIR analyst. List every indicator of compromise in the text below.
+Write one indicator per line as "type|value" where type is one of ip, domain, hash, url, email.
+Write "none" if there are no indicators.
+
+Text: %s
+
+Indicators:`, text)
+
+	respText, err := s.complete(ctx, "timeline.iocs", prompt)
 	if err != nil {
 		return nil, err
 	}
 
-	raw, ok := resp["iocs"].([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid response from AI IOC extractor")
-	}
-
-	var results []IOCExtraction
-	for _, v := range raw {
-		if m, ok := v.(map[string]interface{}); ok {
-			results = append(results, IOCExtraction{
-				Type:       fmt.Sprintf("%v", m["type"]),
-				Value:      fmt.Sprintf("%v", m["value"]),
-				Confidence: m["confidence"].(float64),
-			})
-		}
-	}
-	return results, nil
+	return parseIOCResponse(respText, text), nil
 }
 
 func (s *aiService) AnalyzeEventContext(ctx context.Context, caseID string, eventText string) (*AIAnalysisResult, error) {
@@ -298,33 +255,19 @@ func (s *aiService) SuggestNextSteps(ctx context.Context, caseID string) ([]stri
 	if err != nil {
 		return s.getDefaultNextSteps(&SuggestionRequest{CaseID: caseID}), nil
 	}
-	_ = history //ignore value
-
-	if !s.config.Enabled {
-		return s.getDefaultNextSteps(&SuggestionRequest{CaseID: caseID}), nil
-	}
-
-	// Call AI /cases/{case_id}/next-steps (GET)
-	endpoint := fmt.Sprintf("cases/%s/next-steps", caseID)
-	response, err := s.callAIService(ctx, http.MethodGet, endpoint, nil)
-	if err != nil {
-		return s.getDefaultNextSteps(&SuggestionRequest{CaseID: caseID}), nil
-	}
-
-	// Parse steps
-	rawSteps, ok := response["suggestions"].([]interface{})
-	if !ok {
-		return s.getDefaultNextSteps(&SuggestionRequest{CaseID: caseID}), nil
-	}
 
-	var steps []string
-	for _, sVal := range rawSteps {
-		if str, ok := sVal.(string); ok {
-			steps = append(steps, str)
+	req := &SuggestionRequest{CaseID: caseID, Context: &SuggestionContext{}}
+	for i, analysis := range history {
+		if i >= 10 {
+			break
 		}
+		req.Context.ExistingEvents = append(req.Context.ExistingEvents, TimelineEventContext{
+			Description: analysis.InputText,
+			Severity:    analysis.RecommendedSeverity,
+		})
 	}
 
-	return steps, nil
+	return s.getNextStepSuggestions(ctx, req)
 }
 
 func (s *aiService) AnalyzeCaseProgress(ctx context.Context, caseID string) (*CaseAnalysis, error) {
@@ -385,8 +328,8 @@ func (s *aiService) GetModelStatus(ctx context.Context) (*ModelStatus, error) {
 	}
 
 	start := time.Now()
-	resp, err := s.callAIService(ctx, http.MethodGet, "health", nil)
-	responseTime := time.Since(start).Milliseconds()
+	err := s.llm.Health(ctx)
+	status.ResponseTime = time.Since(start).Milliseconds()
 
 	if err != nil {
 		status.Status = "offline"
@@ -394,57 +337,27 @@ func (s *aiService) GetModelStatus(ctx context.Context) (*ModelStatus, error) {
 		return status, nil
 	}
 
-	// Parse status + model from response
-	if v, ok := resp["status"].(string); ok {
-		status.Status = v
-	}
-	if v, ok := resp["model"].(string); ok {
-		status.ModelName = v
-	}
-	status.ResponseTime = responseTime
-
+	status.Status = "online"
 	return status, nil
 }
 
-// Helper methods - all good.
-/*
-@Param method: HTTP method (GET, POST)
-@Param endpoint: API endpoint (e.g., "generate", "health")
-@Param requestData: Request payload for POST requests
-*/
-func (s *aiService) callAIService(ctx context.Context, method string, endpoint string, requestData map[string]interface{}) (map[string]interface{}, error) {
-	pythonServiceURL := "http://localhost:5000/api/v1/ai/" + endpoint
-
-	var req *http.Request
-	var err error
-
-	if method == http.MethodGet {
-		req, err = http.NewRequestWithContext(ctx, method, pythonServiceURL, nil)
-	} else {
-		jsonBody, err := json.Marshal(requestData)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal request: %w", err)
-		}
-		req, err = http.NewRequestWithContext(ctx, method, pythonServiceURL, bytes.NewBuffer(jsonBody))
-		req.Header.Set("Content-Type", "application/json")
-	}
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// complete sends a prompt through the configured LLM provider. The tenant is
+// taken from ctx (see llm.WithTenant) so per-tenant model selection and the
+// no-external-AI policy apply.
+func (s *aiService) complete(ctx context.Context, purpose, prompt string) (string, error) {
+	if s.llm == nil {
+		return "", fmt.Errorf("no AI provider configured")
 	}
-
-	resp, err := s.httpClient.Do(req)
+	resp, err := s.llm.Complete(ctx, llm.CompletionRequest{
+		Prompt:      prompt,
+		MaxTokens:   s.config.MaxTokens,
+		Temperature: s.config.Temperature,
+		Purpose:     purpose,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to call AI service: %w", err)
-	}
-	defer resp.Body.Close()
-
-	var response map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return "", err
 	}
-
-	return response, nil
+	return resp.Text, nil
 }
 
 func (s *aiService) buildCompletionPrompt(req *SuggestionRequest) string {
@@ -525,6 +438,38 @@ func (s *aiService) parseTagsResponse(response string) []string {
 	return result
 }
 
+func parseSeverityResponse(response string) string {
+	for _, word := range strings.FieldsFunc(strings.ToLower(response), func(r rune) bool {
+		return r < 'a' || r > 'z'
+	}) {
+		switch word {
+		case "low", "medium", "high", "critical":
+			return word
+		}
+	}
+	return ""
+}
+
+// parseIOCResponse reads "type|value" lines. Values the model invented (not
+// present in the source text) are dropped.
+func parseIOCResponse(response, source string) []IOCExtraction {
+	var results []IOCExtraction
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "-"))
+		typ, value, ok := strings.Cut(line, "|")
+		if !ok {
+			continue
+		}
+		typ = strings.ToLower(strings.TrimSpace(typ))
+		value = strings.TrimSpace(value)
+		if value == "" || !strings.Contains(source, value) {
+			continue
+		}
+		results = append(results, IOCExtraction{Type: typ, Value: value, Confidence: 0.75})
+	}
+	return results
+}
+
 func (s *aiService) getPatternBasedSuggestions(ctx context.Context, req *SuggestionRequest) ([]string, error) {
 	patterns, err := s.repository.GetSuggestionPatterns(ctx, "default")
 	if err != nil {
@@ -645,25 +590,21 @@ func (s *aiService) getAIRecommendations(ctx context.Context, history []*AIAnaly
 		events = append(events, analysis.InputText)
 	}
 
-	resp, err := s.callAIService(ctx, http.MethodPost, "recommendations", map[string]interface{}{
-		"events": events,
-	})
+	prompt := fmt.Sprintf(`You are a DFIR analyst reviewing case progress. Based on the events below,
+recommend 3-5 actions to move the investigation forward.
+Each recommendation should be on a new line starting with "-".
+
+Events:
+- %s
+
+Recommendations:`, strings.Join(events, "\n- "))
+
+	respText, err := s.complete(ctx, "timeline.recommendations", prompt)
 	if err != nil {
 		return []string{}, err
 	}
 
-	raw, ok := resp["recommendations"].([]interface{})
-	if !ok {
-		return []string{}, nil
-	}
-
-	var recs []string
-	for _, v := range raw {
-		if str, ok := v.(string); ok {
-			recs = append(recs, str)
-		}
-	}
-	return recs, nil
+	return s.parseCompletionResponse(respText), nil
 }
 
 func (s *aiService) assessRisk(history []*AIAnalysisResult) string {
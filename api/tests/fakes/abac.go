@@ -0,0 +1,193 @@
+package fakes
+
+import (
+	"encoding/json"
+	"slices"
+
+	"aegis-api/services_/auth/abac"
+)
+
+// ABAC keeps security labels, clearances and need-to-know groups in memory,
+// along with the tenant each user and resource belongs to.
+type ABAC struct {
+	labels     map[string]*abac.Label // type/id
+	tenants    map[string]string      // type/id -> tenant
+	checksums  map[string][]string    // checksum -> evidence
+	clearances map[string]*abac.Clearance
+	users      map[string]string // user -> tenant
+	groups     map[string]*abac.Group
+	members    map[string]*abac.GroupMember // group/user
+}
+
+func (r *ABAC) init() {
+	if r.labels == nil {
+		r.labels = map[string]*abac.Label{}
+		r.tenants = map[string]string{}
+		r.checksums = map[string][]string{}
+		r.clearances = map[string]*abac.Clearance{}
+		r.users = map[string]string{}
+		r.groups = map[string]*abac.Group{}
+		r.members = map[string]*abac.GroupMember{}
+	}
+}
+
+// AddUser makes the user a member of the tenant.
+func (r *ABAC) AddUser(tenantID, userID string) {
+	r.init()
+	r.users[userID] = tenantID
+}
+
+// Place files a resource under a tenant.
+func (r *ABAC) Place(resourceType, id, tenantID string) {
+	r.init()
+	r.tenants[resourceType+"/"+id] = tenantID
+}
+
+// AddContent records evidence items that share a checksum.
+func (r *ABAC) AddContent(checksum string, evidenceIDs ...string) {
+	r.init()
+	r.checksums[checksum] = append(r.checksums[checksum], evidenceIDs...)
+}
+
+func (r *ABAC) AutoMigrate() error { return nil }
+
+func (r *ABAC) GetLabel(resourceType, id string) (*abac.Label, error) {
+	return r.labels[resourceType+"/"+id], nil
+}
+
+func (r *ABAC) ListLabels(resourceType string, ids []string) ([]abac.Label, error) {
+	var out []abac.Label
+	for _, id := range ids {
+		if l, ok := r.labels[resourceType+"/"+id]; ok {
+			out = append(out, *l)
+		}
+	}
+	return out, nil
+}
+
+func (r *ABAC) SaveLabel(l *abac.Label) error {
+	r.init()
+	cp := *l
+	r.labels[l.ResourceType+"/"+l.ResourceID] = &cp
+	return nil
+}
+
+func (r *ABAC) DeleteLabel(resourceType, id string) error {
+	delete(r.labels, resourceType+"/"+id)
+	return nil
+}
+
+func (r *ABAC) ResourceTenant(resourceType, id string) (string, error) {
+	return r.tenants[resourceType+"/"+id], nil
+}
+
+func (r *ABAC) EvidenceByChecksum(tenantID, checksum string) ([]string, error) {
+	return r.checksums[checksum], nil
+}
+
+func (r *ABAC) GetClearance(userID string) (*abac.Clearance, error) {
+	return r.clearances[userID], nil
+}
+
+func (r *ABAC) ListClearances(tenantID string) ([]abac.Clearance, error) {
+	var out []abac.Clearance
+	for _, c := range r.clearances {
+		if c.TenantID == tenantID {
+			out = append(out, *c)
+		}
+	}
+	return out, nil
+}
+
+func (r *ABAC) SaveClearance(c *abac.Clearance) error {
+	r.init()
+	cp := *c
+	r.clearances[c.UserID] = &cp
+	return nil
+}
+
+func (r *ABAC) DeleteClearance(userID string) error {
+	delete(r.clearances, userID)
+	return nil
+}
+
+func (r *ABAC) TenantUser(tenantID, userID string) (bool, error) {
+	return r.users[userID] == tenantID, nil
+}
+
+func (r *ABAC) CreateGroup(g *abac.Group) error {
+	r.init()
+	cp := *g
+	r.groups[g.ID] = &cp
+	return nil
+}
+
+func (r *ABAC) GetGroup(tenantID, id string) (*abac.Group, error) {
+	if g, ok := r.groups[id]; ok && g.TenantID == tenantID {
+		return g, nil
+	}
+	return nil, nil
+}
+
+func (r *ABAC) ListGroups(tenantID string) ([]abac.Group, error) {
+	var out []abac.Group
+	for _, g := range r.groups {
+		if g.TenantID == tenantID {
+			out = append(out, *g)
+		}
+	}
+	return out, nil
+}
+
+func (r *ABAC) GroupInUse(id string) (bool, error) {
+	for _, l := range r.labels {
+		var groups []string
+		_ = json.Unmarshal(l.Groups, &groups)
+		if slices.Contains(groups, id) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *ABAC) DeleteGroup(id string) error {
+	delete(r.groups, id)
+	for k, m := range r.members {
+		if m.GroupID == id {
+			delete(r.members, k)
+		}
+	}
+	return nil
+}
+
+func (r *ABAC) AddMember(m *abac.GroupMember) error {
+	r.init()
+	cp := *m
+	r.members[m.GroupID+"/"+m.UserID] = &cp
+	return nil
+}
+
+func (r *ABAC) RemoveMember(groupID, userID string) error {
+	delete(r.members, groupID+"/"+userID)
+	return nil
+}
+
+func (r *ABAC) ListMembers(groupID string) ([]abac.GroupMember, error) {
+	var out []abac.GroupMember
+	for _, m := range r.members {
+		if m.GroupID == groupID {
+			out = append(out, *m)
+		}
+	}
+	return out, nil
+}
+
+func (r *ABAC) UserGroups(userID string) ([]string, error) {
+	var out []string
+	for _, m := range r.members {
+		if m.UserID == userID {
+			out = append(out, m.GroupID)
+		}
+	}
+	return out, nil
+}
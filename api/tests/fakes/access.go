@@ -0,0 +1,67 @@
+package fakes
+
+import (
+	"context"
+	"slices"
+
+	"aegis-api/middleware"
+)
+
+// CaseMembers is a case authorizer that decides on membership alone: a user
+// may do anything on the cases they were added to and nothing elsewhere.
+type CaseMembers struct {
+	members   map[string]map[string]bool // case ID -> user IDs
+	resources map[string]string          // resource ID -> case ID
+}
+
+// Add makes the user a member of the case.
+func (m *CaseMembers) Add(caseID, userID string) {
+	if m.members == nil {
+		m.members = map[string]map[string]bool{}
+	}
+	if m.members[caseID] == nil {
+		m.members[caseID] = map[string]bool{}
+	}
+	m.members[caseID][userID] = true
+}
+
+// Place files a resource, such as an evidence item, under a case.
+func (m *CaseMembers) Place(resourceID, caseID string) {
+	if m.resources == nil {
+		m.resources = map[string]string{}
+	}
+	m.resources[resourceID] = caseID
+}
+
+func (m *CaseMembers) AuthorizeCaseAccess(_ context.Context, req middleware.AccessRequest) (*middleware.AccessDecision, error) {
+	caseID := req.ResourceID
+	if req.ResourceType != middleware.ResourceCase {
+		var ok bool
+		if caseID, ok = m.resources[req.ResourceID]; !ok {
+			return &middleware.AccessDecision{NotFound: true}, nil
+		}
+	}
+	return &middleware.AccessDecision{Allowed: m.members[caseID][req.UserID], CaseID: caseID}, nil
+}
+
+// Permissions grants every role every permission.
+type Permissions struct{}
+
+func (Permissions) RoleHasPermission(string, string) (bool, error) { return true, nil }
+
+// RolePermissions grants each role the permissions listed for it.
+type RolePermissions map[string][]string
+
+func (p RolePermissions) RoleHasPermission(role, permission string) (bool, error) {
+	return slices.Contains(p[role], permission), nil
+}
+
+// MFA answers every enrollment lookup the same way.
+type MFA struct {
+	Enrolled bool
+	Required bool
+}
+
+func (m MFA) Enrollment(string, string, string) (bool, bool, error) {
+	return m.Enrolled, m.Required, nil
+}
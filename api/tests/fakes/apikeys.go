@@ -0,0 +1,154 @@
+package fakes
+
+import (
+	"time"
+
+	"aegis-api/services_/auth/apikey"
+)
+
+// APIKeys keeps service accounts and their keys in memory, along with the
+// cases and teams each tenant owns.
+type APIKeys struct {
+	accounts map[string]*apikey.Account
+	keys     map[string]*apikey.Key
+	Emails   map[string]string // account -> email
+	cases    map[string]string // case -> tenant
+	teams    map[string]string // team -> tenant
+	Touches  int
+}
+
+func (r *APIKeys) init() {
+	if r.accounts == nil {
+		r.accounts = map[string]*apikey.Account{}
+		r.keys = map[string]*apikey.Key{}
+		r.Emails = map[string]string{}
+		r.cases = map[string]string{}
+		r.teams = map[string]string{}
+	}
+}
+
+// AddCase files a case under a tenant.
+func (r *APIKeys) AddCase(tenantID, caseID string) {
+	r.init()
+	r.cases[caseID] = tenantID
+}
+
+// AddTeam files a team under a tenant.
+func (r *APIKeys) AddTeam(tenantID, teamID string) {
+	r.init()
+	r.teams[teamID] = tenantID
+}
+
+func (r *APIKeys) AutoMigrate() error { return nil }
+
+func (r *APIKeys) CreateAccount(a *apikey.Account, email string) error {
+	r.init()
+	r.Emails[a.ID] = email
+	cp := *a
+	r.accounts[a.ID] = &cp
+	return nil
+}
+
+func (r *APIKeys) GetAccount(tenantID, id string) (*apikey.Account, error) {
+	if a, ok := r.accounts[id]; ok && a.TenantID == tenantID {
+		cp := *a
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *APIKeys) ListAccounts(tenantID string) ([]apikey.Account, error) {
+	var out []apikey.Account
+	for _, a := range r.accounts {
+		if a.TenantID == tenantID {
+			out = append(out, *a)
+		}
+	}
+	return out, nil
+}
+
+func (r *APIKeys) DisableAccount(a *apikey.Account, by string, at time.Time) (int, error) {
+	r.accounts[a.ID].DisabledAt = &at
+	n := 0
+	for _, k := range r.keys {
+		if k.ServiceAccountID == a.ID && k.Active(at) {
+			k.RevokedAt = &at
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *APIKeys) CreateKey(k *apikey.Key) error {
+	r.init()
+	cp := *k
+	r.keys[k.ID] = &cp
+	return nil
+}
+
+func (r *APIKeys) GetKey(accountID, id string) (*apikey.Key, error) {
+	if k, ok := r.keys[id]; ok && k.ServiceAccountID == accountID {
+		cp := *k
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *APIKeys) GetKeyByHash(hash string) (*apikey.Key, error) {
+	for _, k := range r.keys {
+		if k.Hash == hash {
+			cp := *k
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *APIKeys) ListKeys(accountID string) ([]apikey.Key, error) {
+	var out []apikey.Key
+	for _, k := range r.keys {
+		if k.ServiceAccountID == accountID {
+			out = append(out, *k)
+		}
+	}
+	return out, nil
+}
+
+func (r *APIKeys) SaveKey(k *apikey.Key) error {
+	r.init()
+	cp := *k
+	r.keys[k.ID] = &cp
+	return nil
+}
+
+func (r *APIKeys) RotateKey(old, replacement *apikey.Key) error {
+	old.RotatedTo = &replacement.ID
+	if err := r.CreateKey(replacement); err != nil {
+		return err
+	}
+	return r.SaveKey(old)
+}
+
+func (r *APIKeys) TouchKey(id, ip string, at time.Time) error {
+	r.Touches++
+	r.keys[id].LastUsedAt, r.keys[id].LastUsedIP = &at, ip
+	return nil
+}
+
+func (r *APIKeys) Permissions() ([]string, error) {
+	return []string{"case:view", "evidence:upload", "evidence:view", "ioc:create", "ioc:view"}, nil
+}
+
+func (r *APIKeys) TenantCases(tenantID string, caseIDs []string) ([]string, error) {
+	var out []string
+	for _, id := range caseIDs {
+		if r.cases[id] == tenantID {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+func (r *APIKeys) TenantTeam(tenantID, teamID string) (bool, error) {
+	return r.teams[teamID] == tenantID, nil
+}
@@ -0,0 +1,69 @@
+package fakes
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"aegis-api/services_/auditlog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AuditTrail records the audit entries handlers write.
+type AuditTrail struct {
+	mu      sync.Mutex
+	Entries []auditlog.AuditLog
+}
+
+func (a *AuditTrail) Log(_ *gin.Context, entry auditlog.AuditLog) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.Entries = append(a.Entries, entry)
+	return nil
+}
+
+// Logger returns an audit logger that writes to the trail.
+func (a *AuditTrail) Logger() *auditlog.AuditLogger {
+	return auditlog.NewAuditLogger(a, console{})
+}
+
+type console struct{}
+
+func (console) Log(auditlog.AuditLog) {}
+
+// Actions lists the actions recorded so far, in order.
+func (a *AuditTrail) Actions() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make([]string, len(a.Entries))
+	for i, e := range a.Entries {
+		out[i] = e.Action
+	}
+	return out
+}
+
+// CaseLogs streams a case's creation, an upload and its closure, and keeps
+// the last query.
+type CaseLogs struct {
+	Query auditlog.CaseLogQuery
+}
+
+func (l *CaseLogs) StreamCaseLogs(_ context.Context, q auditlog.CaseLogQuery, fn func(auditlog.AuditLog) error) error {
+	l.Query = q
+	for _, action := range []string{"CREATE_CASE", "UPLOAD_EVIDENCE", "CLOSE_CASE"} {
+		if err := fn(auditlog.AuditLog{Action: action, Target: auditlog.Target{ID: q.CaseID}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Downloads serves every evidence item as a short text file.
+type Downloads struct{}
+
+func (Downloads) DownloadEvidence(id uuid.UUID) (string, io.ReadCloser, string, error) {
+	return id.String() + ".txt", io.NopCloser(strings.NewReader("evidence " + id.String())), "text/plain", nil
+}
@@ -0,0 +1,79 @@
+package fakes
+
+import "aegis-api/services_/auth/authz"
+
+// Authz keeps cases, the resources filed under them, case roles and
+// collaborators in memory, and records denials.
+type Authz struct {
+	cases     map[string]*authz.Case
+	resources map[string]string // type/id -> case
+	assigned  map[string]string // case/user -> role
+	collabs   map[string]*authz.Collaborator
+	Denials   []authz.Denial
+}
+
+func (r *Authz) init() {
+	if r.cases == nil {
+		r.cases = map[string]*authz.Case{}
+		r.resources = map[string]string{}
+		r.assigned = map[string]string{}
+		r.collabs = map[string]*authz.Collaborator{}
+	}
+}
+
+// AddCase stores a case.
+func (r *Authz) AddCase(c *authz.Case) {
+	r.init()
+	r.cases[c.ID] = c
+}
+
+// Place files a resource under a case.
+func (r *Authz) Place(resourceType, id, caseID string) {
+	r.init()
+	r.resources[resourceType+"/"+id] = caseID
+}
+
+// Assign gives a user a role on a case.
+func (r *Authz) Assign(caseID, userID, role string) {
+	r.init()
+	r.assigned[caseID+"/"+userID] = role
+}
+
+// AddCollaborator stores c as is, so later changes to it are seen.
+func (r *Authz) AddCollaborator(c *authz.Collaborator) {
+	r.init()
+	r.collabs[c.CaseID+"/"+c.UserID] = c
+}
+
+func (r *Authz) AutoMigrate() error { return nil }
+
+func (r *Authz) CaseOf(resourceType, id string) (string, error) {
+	return r.resources[resourceType+"/"+id], nil
+}
+
+func (r *Authz) GetCase(id string) (*authz.Case, error) {
+	return r.cases[id], nil
+}
+
+func (r *Authz) AssignedRole(caseID, userID string) (string, error) {
+	return r.assigned[caseID+"/"+userID], nil
+}
+
+func (r *Authz) GetCollaborator(caseID, userID string) (*authz.Collaborator, error) {
+	return r.collabs[caseID+"/"+userID], nil
+}
+
+func (r *Authz) CreateDenial(d *authz.Denial) error {
+	r.Denials = append(r.Denials, *d)
+	return nil
+}
+
+func (r *Authz) ListDenials(tenantID string, f authz.DenialFilter) ([]authz.Denial, error) {
+	var out []authz.Denial
+	for _, d := range r.Denials {
+		if d.TenantID != nil && *d.TenantID == tenantID && (f.UserID == "" || d.UserID == f.UserID) {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
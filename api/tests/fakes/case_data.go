@@ -0,0 +1,139 @@
+package fakes
+
+import (
+	"context"
+	"time"
+
+	graphicalmapping "aegis-api/services_/GraphicalMapping"
+	"aegis-api/services_/annotation_threads/messages"
+	annotationthreads "aegis-api/services_/annotation_threads/threads"
+	"aegis-api/services_/chain_of_custody"
+	"aegis-api/services_/timeline"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Cases serves every case with the same title and team, counting lookups.
+// A non-nil Err fails every lookup.
+type Cases struct {
+	Title, Team string
+	Err         error
+	Calls       int
+}
+
+func (c *Cases) GetCaseByID(_ context.Context, caseID string) (any, error) {
+	c.Calls++
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	return map[string]any{"id": caseID, "title": c.Title, "team_name": c.Team}, nil
+}
+
+// Timeline is an in-memory timeline service.
+type Timeline struct {
+	Events []*timeline.TimelineEvent
+}
+
+func (t *Timeline) AddEvent(e *timeline.TimelineEvent) (*timeline.TimelineEvent, error) {
+	e.ID = uuid.NewString()
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	t.Events = append(t.Events, e)
+	return e, nil
+}
+
+func (t *Timeline) GetEvent(id string) (*timeline.TimelineEvent, error) {
+	for _, e := range t.Events {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (t *Timeline) UpdateEvent(e *timeline.TimelineEvent) (*timeline.TimelineEvent, error) {
+	return e, nil
+}
+
+// ListEvents formats the case's events as the timeline service does.
+func (t *Timeline) ListEvents(caseID string) ([]*timeline.TimelineEventResponse, error) {
+	var out []*timeline.TimelineEventResponse
+	for _, e := range t.Events {
+		if e.CaseID != caseID {
+			continue
+		}
+		out = append(out, &timeline.TimelineEventResponse{
+			ID:          e.ID,
+			Description: e.Description,
+			Severity:    e.Severity,
+			AnalystName: e.AnalystName,
+			Date:        e.CreatedAt.Format("2006-01-02"),
+			Time:        e.CreatedAt.Format("15:04"),
+			Evidence:    e.Evidence,
+			Tags:        e.Tags,
+		})
+	}
+	return out, nil
+}
+
+// IOCs is an in-memory IOC store.
+type IOCs struct {
+	Items []*graphicalmapping.IOC
+}
+
+func (s *IOCs) AddIOC(ioc *graphicalmapping.IOC) (*graphicalmapping.IOC, error) {
+	s.Items = append(s.Items, ioc)
+	return ioc, nil
+}
+
+func (s *IOCs) ListIOCsByCase(caseID string) ([]*graphicalmapping.IOC, error) {
+	var out []*graphicalmapping.IOC
+	for _, ioc := range s.Items {
+		if ioc.CaseID == caseID {
+			out = append(out, ioc)
+		}
+	}
+	return out, nil
+}
+
+// Custody is an in-memory chain-of-custody log.
+type Custody struct {
+	Entries []chain_of_custody.ChainOfCustody
+}
+
+func (c *Custody) GetEntries(_ context.Context, evidenceID uuid.UUID) ([]chain_of_custody.ChainOfCustody, error) {
+	var out []chain_of_custody.ChainOfCustody
+	for _, e := range c.Entries {
+		if e.EvidenceID == evidenceID {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (c *Custody) AddEntry(_ context.Context, e *chain_of_custody.ChainOfCustody) error {
+	c.Entries = append(c.Entries, *e)
+	return nil
+}
+
+// Threads holds annotation threads and their messages.
+type Threads struct {
+	Threads  []annotationthreads.AnnotationThread
+	Messages map[uuid.UUID][]messages.ThreadMessage // thread ID -> messages
+}
+
+func (t *Threads) GetThreadsByCase(caseID uuid.UUID) ([]annotationthreads.AnnotationThread, error) {
+	var out []annotationthreads.AnnotationThread
+	for _, th := range t.Threads {
+		if th.CaseID == caseID {
+			out = append(out, th)
+		}
+	}
+	return out, nil
+}
+
+func (t *Threads) GetMessagesByThread(threadID uuid.UUID) ([]messages.ThreadMessage, error) {
+	return t.Messages[threadID], nil
+}
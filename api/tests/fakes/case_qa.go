@@ -0,0 +1,66 @@
+package fakes
+
+import (
+	"aegis-api/services_/case_qa"
+
+	"github.com/google/uuid"
+)
+
+// QAIndex is an in-memory case Q&A repository. Chunks are keyed by
+// "tenantID/caseID".
+type QAIndex struct {
+	cases     map[string]string          // case ID -> tenant ID
+	members   map[string]map[string]bool // case ID -> user IDs
+	Chunks    map[string][]*case_qa.Chunk
+	Exchanges []*case_qa.Exchange
+}
+
+// AddCase files the case under the tenant with the given members.
+func (q *QAIndex) AddCase(tenantID, caseID string, members ...string) {
+	if q.cases == nil {
+		q.cases, q.members = map[string]string{}, map[string]map[string]bool{}
+	}
+	q.cases[caseID] = tenantID
+	q.members[caseID] = map[string]bool{}
+	for _, m := range members {
+		q.members[caseID][m] = true
+	}
+}
+
+func (q *QAIndex) AutoMigrate() error { return nil }
+
+func (q *QAIndex) CaseInTenant(tenantID, caseID string) (bool, error) {
+	return q.cases[caseID] == tenantID, nil
+}
+
+func (q *QAIndex) IsCaseMember(caseID, userID string) (bool, error) {
+	return q.members[caseID][userID], nil
+}
+
+func (q *QAIndex) ReplaceChunks(tenantID, caseID string, chunks []*case_qa.Chunk) error {
+	if q.Chunks == nil {
+		q.Chunks = map[string][]*case_qa.Chunk{}
+	}
+	q.Chunks[tenantID+"/"+caseID] = chunks
+	return nil
+}
+
+func (q *QAIndex) ListChunks(tenantID, caseID string) ([]*case_qa.Chunk, error) {
+	return q.Chunks[tenantID+"/"+caseID], nil
+}
+
+func (q *QAIndex) CreateExchange(e *case_qa.Exchange) error {
+	e.ID = uuid.NewString()
+	q.Exchanges = append(q.Exchanges, e)
+	return nil
+}
+
+func (q *QAIndex) ListExchanges(tenantID, caseID string, limit int) ([]*case_qa.Exchange, error) {
+	var out []*case_qa.Exchange
+	for _, e := range q.Exchanges {
+		if e.TenantID == tenantID && e.CaseID == caseID {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,82 @@
+package fakes
+
+import (
+	"context"
+
+	"aegis-api/services_/case/case_closure"
+	"aegis-api/services_/chat"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Closures keeps closed cases and their closure packages in memory.
+type Closures struct {
+	cases    map[string]*case_closure.CaseInfo
+	Packages map[string]*case_closure.Package
+}
+
+// AddCase stores a case that packages can be built for.
+func (m *Closures) AddCase(c *case_closure.CaseInfo) {
+	if m.cases == nil {
+		m.cases = map[string]*case_closure.CaseInfo{}
+	}
+	m.cases[c.ID] = c
+}
+
+func (m *Closures) AutoMigrate() error { return nil }
+
+func (m *Closures) GetCase(tenantID, caseID string) (*case_closure.CaseInfo, error) {
+	c, ok := m.cases[caseID]
+	if !ok || c.TenantID != tenantID {
+		return nil, case_closure.ErrCaseNotFound
+	}
+	return c, nil
+}
+
+func (m *Closures) CreatePackage(p *case_closure.Package) error {
+	p.ID = uuid.NewString()
+	return m.SavePackage(p)
+}
+
+func (m *Closures) SavePackage(p *case_closure.Package) error {
+	if m.Packages == nil {
+		m.Packages = map[string]*case_closure.Package{}
+	}
+	cp := *p
+	m.Packages[p.ID] = &cp
+	return nil
+}
+
+func (m *Closures) GetPackage(tenantID, id string) (*case_closure.Package, error) {
+	p, ok := m.Packages[id]
+	if !ok || p.TenantID != tenantID {
+		return nil, case_closure.ErrPackageNotFound
+	}
+	return p, nil
+}
+
+func (m *Closures) ListPackages(tenantID, caseID string) ([]case_closure.Package, error) {
+	var out []case_closure.Package
+	for _, p := range m.Packages {
+		if p.TenantID == tenantID && p.CaseID == caseID {
+			out = append(out, *p)
+		}
+	}
+	return out, nil
+}
+
+// ChatHistory streams Messages messages from a single chat group.
+type ChatHistory struct {
+	Messages int
+}
+
+func (h ChatHistory) StreamCaseMessages(_ context.Context, _ string, fn func(*chat.ChatGroup, *chat.Message) error) error {
+	g := &chat.ChatGroup{ID: primitive.NewObjectID(), Name: "IR war room"}
+	for i := 0; i < h.Messages; i++ {
+		if err := fn(g, &chat.Message{ID: primitive.NewObjectID().Hex(), GroupID: g.ID, Content: "update"}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package fakes
+
+import (
+	"aegis-api/services_/detection_rules"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Rules is an in-memory detection rule repository. Versions are numbered
+// per rule name, as the gorm repository does.
+type Rules struct {
+	Rules   []*detection_rules.DetectionRule
+	Matches []*detection_rules.RuleMatch
+}
+
+func (m *Rules) AutoMigrate() error { return nil }
+
+func (m *Rules) CreateRuleVersion(r *detection_rules.DetectionRule) error {
+	r.ID = uuid.NewString()
+	r.Version = 1
+	for _, existing := range m.Rules {
+		if existing.TenantID == r.TenantID && existing.Name == r.Name && existing.Version >= r.Version {
+			r.Version = existing.Version + 1
+		}
+	}
+	m.Rules = append(m.Rules, r)
+	return nil
+}
+
+func (m *Rules) GetRule(tenantID, id string) (*detection_rules.DetectionRule, error) {
+	for _, r := range m.Rules {
+		if r.TenantID == tenantID && r.ID == id {
+			return r, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *Rules) ListRules(tenantID string) ([]*detection_rules.DetectionRule, error) {
+	return m.Rules, nil
+}
+
+func (m *Rules) ListRuleVersions(tenantID, name string) ([]*detection_rules.DetectionRule, error) {
+	var out []*detection_rules.DetectionRule
+	for _, r := range m.Rules {
+		if r.TenantID == tenantID && r.Name == name {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (m *Rules) ListActiveRules(tenantID string, kind detection_rules.RuleKind) ([]*detection_rules.DetectionRule, error) {
+	latest := map[string]*detection_rules.DetectionRule{}
+	for _, r := range m.Rules {
+		if r.TenantID == tenantID && (latest[r.Name] == nil || r.Version > latest[r.Name].Version) {
+			latest[r.Name] = r
+		}
+	}
+	var out []*detection_rules.DetectionRule
+	for _, r := range latest {
+		if r.Kind == kind && r.Enabled {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (m *Rules) SetRuleEnabled(tenantID, id string, enabled bool) error {
+	r, err := m.GetRule(tenantID, id)
+	if err != nil {
+		return err
+	}
+	r.Enabled = enabled
+	return nil
+}
+
+func (m *Rules) CreateMatches(matches []*detection_rules.RuleMatch) error {
+	for _, mt := range matches {
+		mt.ID = uuid.NewString()
+	}
+	m.Matches = append(m.Matches, matches...)
+	return nil
+}
+
+func (m *Rules) ListMatchesByCase(tenantID, caseID string) ([]*detection_rules.RuleMatch, error) {
+	return m.Matches, nil
+}
+
+func (m *Rules) GetMatch(tenantID, id string) (*detection_rules.RuleMatch, error) {
+	for _, mt := range m.Matches {
+		if mt.TenantID == tenantID && mt.ID == id {
+			return mt, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *Rules) UpdateMatch(*detection_rules.RuleMatch) error { return nil }
@@ -0,0 +1,85 @@
+package fakes
+
+import (
+	graphicalmapping "aegis-api/services_/GraphicalMapping"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Graph is an in-memory investigation graph repository. Deletes are not
+// faked.
+type Graph struct {
+	Entities  []*graphicalmapping.GraphEntity
+	Relations []*graphicalmapping.GraphRelation
+	evidence  map[string]string // evidence ID -> case ID
+}
+
+// PlaceEvidence files an evidence item under a case.
+func (g *Graph) PlaceEvidence(evidenceID, caseID string) {
+	if g.evidence == nil {
+		g.evidence = map[string]string{}
+	}
+	g.evidence[evidenceID] = caseID
+}
+
+func (g *Graph) AutoMigrate() error { return nil }
+
+func (g *Graph) CreateEntity(e *graphicalmapping.GraphEntity) error {
+	if e.ID == "" {
+		e.ID = uuid.NewString()
+	}
+	g.Entities = append(g.Entities, e)
+	return nil
+}
+
+func (g *Graph) GetEntity(tenantID, id string) (*graphicalmapping.GraphEntity, error) {
+	for _, e := range g.Entities {
+		if e.TenantID == tenantID && e.ID == id {
+			return e, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (g *Graph) ListEntitiesByCase(tenantID, caseID string) ([]*graphicalmapping.GraphEntity, error) {
+	var out []*graphicalmapping.GraphEntity
+	for _, e := range g.Entities {
+		if e.TenantID == tenantID && e.CaseID == caseID {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (g *Graph) DeleteEntity(tenantID, caseID, id string) error { return nil }
+
+func (g *Graph) CreateRelation(r *graphicalmapping.GraphRelation) error {
+	if r.ID == "" {
+		r.ID = uuid.NewString()
+	}
+	g.Relations = append(g.Relations, r)
+	return nil
+}
+
+func (g *Graph) ListRelationsByCase(tenantID, caseID string) ([]*graphicalmapping.GraphRelation, error) {
+	var out []*graphicalmapping.GraphRelation
+	for _, r := range g.Relations {
+		if r.TenantID == tenantID && r.CaseID == caseID {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (g *Graph) DeleteRelation(tenantID, caseID, id string) error { return nil }
+
+func (g *Graph) EvidenceInCase(tenantID, caseID string, ids []string) ([]string, error) {
+	var out []string
+	for _, id := range ids {
+		if g.evidence[id] == caseID {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
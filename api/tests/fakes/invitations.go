@@ -0,0 +1,215 @@
+package fakes
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"aegis-api/services_/case/invitations"
+)
+
+// Invitations keeps guest invitations, collaborators and access events in
+// memory, along with each case's tenant and resources.
+type Invitations struct {
+	invitations []*invitations.Invitation
+	cases       map[string]string   // case -> tenant
+	resources   map[string][]string // case/type -> ids
+	collabs     map[string]*invitations.Collaborator
+	events      []invitations.AccessEvent
+}
+
+func (r *Invitations) init() {
+	if r.cases == nil {
+		r.cases = map[string]string{}
+		r.resources = map[string][]string{}
+		r.collabs = map[string]*invitations.Collaborator{}
+	}
+}
+
+// AddCase files a case under a tenant.
+func (r *Invitations) AddCase(tenantID, caseID string) {
+	r.init()
+	r.cases[caseID] = tenantID
+}
+
+// Place files resources of the given type under a case.
+func (r *Invitations) Place(caseID, resourceType string, ids ...string) {
+	r.init()
+	key := caseID + "/" + resourceType
+	r.resources[key] = append(r.resources[key], ids...)
+}
+
+// Collaborator returns the user's collaboration on the case, or nil.
+func (r *Invitations) Collaborator(caseID, userID string) *invitations.Collaborator {
+	return r.collabs[caseID+"/"+userID]
+}
+
+func (r *Invitations) AutoMigrate() error { return nil }
+
+func (r *Invitations) CreateInvitation(inv *invitations.Invitation) error {
+	cp := *inv
+	r.invitations = append(r.invitations, &cp)
+	return nil
+}
+
+func (r *Invitations) SaveInvitation(inv *invitations.Invitation) error {
+	for i, existing := range r.invitations {
+		if existing.ID == inv.ID {
+			cp := *inv
+			r.invitations[i] = &cp
+		}
+	}
+	return nil
+}
+
+func (r *Invitations) find(match func(*invitations.Invitation) bool) *invitations.Invitation {
+	for _, inv := range r.invitations {
+		if match(inv) {
+			cp := *inv
+			return &cp
+		}
+	}
+	return nil
+}
+
+func (r *Invitations) GetInvitation(tenantID, id string) (*invitations.Invitation, error) {
+	return r.find(func(inv *invitations.Invitation) bool { return inv.TenantID == tenantID && inv.ID == id }), nil
+}
+
+func (r *Invitations) GetByToken(tokenHash string) (*invitations.Invitation, error) {
+	return r.find(func(inv *invitations.Invitation) bool { return inv.TokenHash == tokenHash }), nil
+}
+
+func (r *Invitations) ListInvitations(tenantID, caseID string) ([]invitations.Invitation, error) {
+	var out []invitations.Invitation
+	for _, inv := range r.invitations {
+		if inv.TenantID == tenantID && (caseID == "" || inv.CaseID == caseID) {
+			out = append(out, *inv)
+		}
+	}
+	return out, nil
+}
+
+func (r *Invitations) ListUserInvitations(userID string) ([]invitations.Invitation, error) {
+	var out []invitations.Invitation
+	for _, inv := range r.invitations {
+		if inv.UserID != nil && *inv.UserID == userID {
+			out = append(out, *inv)
+		}
+	}
+	return out, nil
+}
+
+func (r *Invitations) OpenInvitation(caseID, email string) (*invitations.Invitation, error) {
+	return r.find(func(inv *invitations.Invitation) bool {
+		return inv.CaseID == caseID && strings.EqualFold(inv.Email, email) &&
+			(inv.Status == invitations.StatusPending || inv.Status == invitations.StatusAccepted)
+	}), nil
+}
+
+func (r *Invitations) AcceptedInvitation(caseID, userID string) (*invitations.Invitation, error) {
+	return r.find(func(inv *invitations.Invitation) bool {
+		return inv.CaseID == caseID && inv.UserID != nil && *inv.UserID == userID && inv.Status == invitations.StatusAccepted
+	}), nil
+}
+
+func (r *Invitations) EndedInvitations(now time.Time) ([]invitations.Invitation, error) {
+	var out []invitations.Invitation
+	for _, inv := range r.invitations {
+		if (inv.Status == invitations.StatusPending || inv.Status == invitations.StatusAccepted) && !inv.EndsAt.After(now) {
+			out = append(out, *inv)
+		}
+	}
+	return out, nil
+}
+
+func (r *Invitations) CaseTenant(caseID string) (string, error) {
+	return r.cases[caseID], nil
+}
+
+func (r *Invitations) CaseResources(caseID, resourceType string, ids []string) ([]string, error) {
+	var out []string
+	for _, id := range ids {
+		if slices.Contains(r.resources[caseID+"/"+resourceType], id) {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+func (r *Invitations) SaveCollaborator(c *invitations.Collaborator) error {
+	r.init()
+	cp := *c
+	r.collabs[c.CaseID+"/"+c.UserID] = &cp
+	return nil
+}
+
+func (r *Invitations) SetCollaboratorStatus(caseID, userID, status string) error {
+	if c, ok := r.collabs[caseID+"/"+userID]; ok {
+		c.Status = status
+	}
+	return nil
+}
+
+func (r *Invitations) ActivateStarted(now time.Time) (int64, error) {
+	var n int64
+	for _, inv := range r.invitations {
+		c, ok := r.collabs[inv.CaseID+"/"+derefOr(inv.UserID)]
+		if ok && c.Status == "scheduled" && inv.Status == invitations.StatusAccepted && !inv.StartsAt.After(now) && inv.EndsAt.After(now) {
+			c.Status = "active"
+			n++
+		}
+	}
+	return n, nil
+}
+
+func derefOr(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func (r *Invitations) CreateEvent(e *invitations.AccessEvent) error {
+	r.events = append(r.events, *e)
+	return nil
+}
+
+func (r *Invitations) ListEvents(invitationID string, limit int) ([]invitations.AccessEvent, error) {
+	var out []invitations.AccessEvent
+	for _, e := range r.events {
+		if e.InvitationID == invitationID && len(out) < limit {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// Mailbox keeps the last invitation link sent to each address, or fails
+// every send when Fail is set.
+type Mailbox struct {
+	links map[string]string
+	Fail  bool
+}
+
+func (m *Mailbox) SendInvitation(to string, inv *invitations.Invitation, link string) error {
+	if m.Fail {
+		return errors.New("smtp down")
+	}
+	if m.links == nil {
+		m.links = map[string]string{}
+	}
+	m.links[to] = link
+	return nil
+}
+
+// Token returns the token in the last link sent to an address.
+func (m *Mailbox) Token(to string) string {
+	u, err := url.Parse(m.links[to])
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("token")
+}
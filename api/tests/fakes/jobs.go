@@ -0,0 +1,244 @@
+package fakes
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"aegis-api/services_/report"
+	"aegis-api/services_/report/jobs"
+
+	"github.com/google/uuid"
+)
+
+// ReportJobs keeps report jobs, runs and items in memory. Every case filter
+// matches Cases.
+type ReportJobs struct {
+	mu    sync.Mutex
+	jobs  []*jobs.Job
+	runs  []*jobs.Run
+	items []*jobs.Item
+	Cases []jobs.CaseRef
+}
+
+func (m *ReportJobs) AutoMigrate() error { return nil }
+
+func (m *ReportJobs) CreateJob(j *jobs.Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j.ID = uuid.NewString()
+	m.jobs = append(m.jobs, j)
+	return nil
+}
+
+func (m *ReportJobs) UpdateJob(j *jobs.Job) error { return nil }
+
+func (m *ReportJobs) DeleteJob(tenantID, jobID string) error { return nil }
+
+func (m *ReportJobs) GetJob(tenantID, jobID string) (*jobs.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, j := range m.jobs {
+		if j.TenantID == tenantID && j.ID == jobID {
+			cp := *j
+			return &cp, nil
+		}
+	}
+	return nil, jobs.ErrJobNotFound
+}
+
+func (m *ReportJobs) ListJobs(tenantID string) ([]jobs.Job, error) { return nil, nil }
+
+func (m *ReportJobs) DueJobs(now time.Time) ([]jobs.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []jobs.Job
+	for _, j := range m.jobs {
+		if j.Enabled && j.Schedule != "" && j.NextRunAt != nil && !j.NextRunAt.After(now) {
+			out = append(out, *j)
+		}
+	}
+	return out, nil
+}
+
+func (m *ReportJobs) AdvanceSchedule(jobID string, prev time.Time, next *time.Time, ranAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, j := range m.jobs {
+		if j.ID == jobID && j.NextRunAt != nil && j.NextRunAt.Equal(prev) {
+			j.NextRunAt, j.LastRunAt = next, &ranAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *ReportJobs) FindCases(tenantID string, f jobs.CaseFilter) ([]jobs.CaseRef, error) {
+	return m.Cases, nil
+}
+
+func (m *ReportJobs) CreateRun(r *jobs.Run, items []jobs.Item) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r.ID, r.CreatedAt = uuid.NewString(), time.Now()
+	cp := *r
+	m.runs = append(m.runs, &cp)
+	for i := range items {
+		items[i].ID, items[i].RunID = uuid.NewString(), r.ID
+		it := items[i]
+		m.items = append(m.items, &it)
+	}
+	return nil
+}
+
+func (m *ReportJobs) GetRun(tenantID, runID string) (*jobs.Run, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.runs {
+		if r.TenantID == tenantID && r.ID == runID {
+			cp := *r
+			return &cp, nil
+		}
+	}
+	return nil, jobs.ErrRunNotFound
+}
+
+func (m *ReportJobs) ListRuns(tenantID, jobID string, limit int) ([]jobs.Run, error) { return nil, nil }
+
+func (m *ReportJobs) ListItems(runID string) ([]jobs.Item, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []jobs.Item
+	for _, it := range m.items {
+		if it.RunID == runID {
+			out = append(out, *it)
+		}
+	}
+	return out, nil
+}
+
+func (m *ReportJobs) GetItem(runID, itemID string) (*jobs.Item, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, it := range m.items {
+		if it.RunID == runID && it.ID == itemID {
+			cp := *it
+			return &cp, nil
+		}
+	}
+	return nil, jobs.ErrItemNotFound
+}
+
+func (m *ReportJobs) run(id string) *jobs.Run {
+	for _, r := range m.runs {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+func (m *ReportJobs) StartRun(runID string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.run(runID).Status = jobs.RunRunning
+	return nil
+}
+
+func (m *ReportJobs) store(it *jobs.Item) {
+	for i, old := range m.items {
+		if old.ID == it.ID {
+			cp := *it
+			m.items[i] = &cp
+		}
+	}
+}
+
+func (m *ReportJobs) UpdateItem(it *jobs.Item) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.store(it)
+	return nil
+}
+
+func (m *ReportJobs) FinishItem(it *jobs.Item) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.store(it)
+	if it.Status == jobs.ItemSucceeded {
+		m.run(it.RunID).Succeeded++
+	} else {
+		m.run(it.RunID).Failed++
+	}
+	return nil
+}
+
+func (m *ReportJobs) FinishRun(runID, status string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r := m.run(runID)
+	r.Status, r.FinishedAt = status, &at
+	return nil
+}
+
+func (m *ReportJobs) FailInterrupted(at time.Time) error { return nil }
+
+// Generator generates reports, failing a case's next generations as told.
+// It records the most generations it saw running at once.
+type Generator struct {
+	mu       sync.Mutex
+	failures map[uuid.UUID]int
+	active   int32
+	peak     int32
+}
+
+// Fail makes the case's next n generations fail.
+func (g *Generator) Fail(caseID uuid.UUID, n int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.failures == nil {
+		g.failures = map[uuid.UUID]int{}
+	}
+	g.failures[caseID] = n
+}
+
+// Peak returns the most generations that ran at once.
+func (g *Generator) Peak() int32 { return atomic.LoadInt32(&g.peak) }
+
+func (g *Generator) GenerateReport(ctx context.Context, caseID, examinerID, tenantID, teamID, templateID uuid.UUID, caseType string) (*report.Report, error) {
+	n := atomic.AddInt32(&g.active, 1)
+	defer atomic.AddInt32(&g.active, -1)
+	for {
+		p := atomic.LoadInt32(&g.peak)
+		if n <= p || atomic.CompareAndSwapInt32(&g.peak, p, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.failures[caseID] > 0 {
+		g.failures[caseID]--
+		return nil, errors.New("mongo unavailable")
+	}
+	return &report.Report{ID: uuid.New(), CaseID: caseID}, nil
+}
+
+func (g *Generator) UpdateReportName(ctx context.Context, id uuid.UUID, name string) (*report.Report, error) {
+	return &report.Report{ID: id, Name: name}, nil
+}
+
+func (g *Generator) DownloadReportAsPDF(ctx context.Context, id uuid.UUID, fields report.FieldRenderer) ([]byte, error) {
+	return []byte("%PDF-" + id.String()), nil
+}
+
+func (g *Generator) DownloadReportAsDOCX(ctx context.Context, id uuid.UUID, fields report.FieldRenderer, opts report.ExportOptions) ([]byte, error) {
+	return []byte("PK docx"), nil
+}
+
+func (g *Generator) DownloadReportAsODT(ctx context.Context, id uuid.UUID, fields report.FieldRenderer, opts report.ExportOptions) ([]byte, error) {
+	return []byte("PK odt"), nil
+}
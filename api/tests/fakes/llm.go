@@ -0,0 +1,51 @@
+package fakes
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"aegis-api/services_/llm"
+)
+
+// LLMStore keeps tenant AI policies and token usage in memory. Usage is not
+// summarized.
+type LLMStore struct {
+	mu       sync.Mutex
+	policies map[string]*llm.TenantPolicy
+	records  []llm.UsageRecord
+}
+
+func (s *LLMStore) GetPolicy(_ context.Context, tenantID string) (*llm.TenantPolicy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.policies[tenantID], nil
+}
+
+func (s *LLMStore) SavePolicy(_ context.Context, p *llm.TenantPolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.policies == nil {
+		s.policies = map[string]*llm.TenantPolicy{}
+	}
+	s.policies[p.TenantID.String()] = p
+	return nil
+}
+
+func (s *LLMStore) RecordUsage(_ context.Context, rec *llm.UsageRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, *rec)
+	return nil
+}
+
+func (s *LLMStore) SummarizeUsage(context.Context, string, time.Time) ([]llm.UsageSummary, error) {
+	return nil, nil
+}
+
+// Usage returns the usage recorded so far.
+func (s *LLMStore) Usage() []llm.UsageRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]llm.UsageRecord(nil), s.records...)
+}
@@ -0,0 +1,100 @@
+package fakes
+
+import (
+	"aegis-api/services_/auth/mfa_policy"
+	mfa "aegis-api/services_/auth/verification"
+
+	"github.com/google/uuid"
+)
+
+// MFAPolicies keeps tenants' MFA policies in memory.
+type MFAPolicies struct {
+	policies map[string]*mfa_policy.Policy
+}
+
+func (m *MFAPolicies) AutoMigrate() error { return nil }
+
+func (m *MFAPolicies) GetPolicy(tenantID string) (*mfa_policy.Policy, error) {
+	p, ok := m.policies[tenantID]
+	if !ok {
+		return nil, nil
+	}
+	cp := *p
+	return &cp, nil
+}
+
+func (m *MFAPolicies) SavePolicy(p *mfa_policy.Policy) error {
+	if m.policies == nil {
+		m.policies = map[string]*mfa_policy.Policy{}
+	}
+	cp := *p
+	m.policies[p.TenantID] = &cp
+	return nil
+}
+
+// Factors accepts "123456" as the TOTP code and "654321" as the code of the
+// next time step, and keeps recovery codes in a set.
+type Factors struct {
+	enabled  map[uuid.UUID]bool
+	recovery map[uuid.UUID]map[string]bool
+}
+
+func (f *Factors) init() {
+	if f.enabled == nil {
+		f.enabled, f.recovery = map[uuid.UUID]bool{}, map[uuid.UUID]map[string]bool{}
+	}
+}
+
+func (f *Factors) GenerateSecret(userID uuid.UUID, email string) (*mfa.MFASetupResponse, error) {
+	if f.enabled[userID] {
+		return nil, mfa.ErrMFAAlreadyEnabled
+	}
+	f.init()
+	f.recovery[userID] = map[string]bool{"AAAA-BBBB": true, "CCCC-DDDD": true}
+	return &mfa.MFASetupResponse{Secret: "SECRET", BackupCodes: []string{"AAAA-BBBB", "CCCC-DDDD"}}, nil
+}
+
+func (f *Factors) VerifyAndEnableMFA(userID uuid.UUID, code string) error {
+	if code != "123456" {
+		return mfa.ErrInvalidCode
+	}
+	f.init()
+	f.enabled[userID] = true
+	return nil
+}
+
+func (f *Factors) GetMFAStatus(userID uuid.UUID) (bool, error) { return f.enabled[userID], nil }
+
+func (f *Factors) ValidateTOTP(userID uuid.UUID, code string) (int64, bool, error) {
+	if !f.enabled[userID] {
+		return 0, false, mfa.ErrMFANotEnabled
+	}
+	step, ok := map[string]int64{"123456": 1, "654321": 2}[code]
+	return step, ok, nil
+}
+
+func (f *Factors) UseRecoveryCode(userID uuid.UUID, code string) (bool, error) {
+	if f.recovery[userID][code] {
+		delete(f.recovery[userID], code)
+		return true, nil
+	}
+	return false, nil
+}
+
+func (f *Factors) RegenerateRecoveryCodes(userID uuid.UUID) ([]string, error) {
+	if !f.enabled[userID] {
+		return nil, mfa.ErrMFANotEnabled
+	}
+	f.recovery[userID] = map[string]bool{"EEEE-FFFF": true}
+	return []string{"EEEE-FFFF"}, nil
+}
+
+func (f *Factors) RecoveryCodesLeft(userID uuid.UUID) (int, error) {
+	return len(f.recovery[userID]), nil
+}
+
+func (f *Factors) DisableMFA(userID uuid.UUID) error {
+	f.init()
+	f.enabled[userID] = false
+	return nil
+}
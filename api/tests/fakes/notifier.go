@@ -0,0 +1,37 @@
+package fakes
+
+import "sync"
+
+// Notice is a notification a Notifier was asked to send.
+type Notice struct {
+	UserID, Title, Message string
+}
+
+// Notifier records the notifications it is asked to send.
+type Notifier struct {
+	mu   sync.Mutex
+	sent []Notice
+}
+
+func (n *Notifier) Notify(userID, tenantID, teamID, title, message string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, Notice{UserID: userID, Title: title, Message: message})
+}
+
+// Sent returns the notifications sent so far.
+func (n *Notifier) Sent() []Notice {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]Notice(nil), n.sent...)
+}
+
+// Received reports whether userID was sent a notification titled title.
+func (n *Notifier) Received(userID, title string) bool {
+	for _, s := range n.Sent() {
+		if s.UserID == userID && s.Title == title {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package fakes
+
+import (
+	"time"
+
+	"aegis-api/services_/auth/webauthn"
+
+	"github.com/google/uuid"
+)
+
+// Passkeys keeps WebAuthn credentials and tenants' passkey policies in
+// memory.
+type Passkeys struct {
+	creds    map[string]*webauthn.Credential
+	policies map[string]*webauthn.Policy
+}
+
+func (m *Passkeys) AutoMigrate() error { return nil }
+
+func (m *Passkeys) CreateCredential(c *webauthn.Credential) error {
+	c.ID = uuid.NewString()
+	c.CreatedAt = time.Now()
+	return m.SaveCredential(c)
+}
+
+func (m *Passkeys) GetCredential(credentialID string) (*webauthn.Credential, error) {
+	for _, c := range m.creds {
+		if c.CredentialID == credentialID {
+			cp := *c
+			return &cp, nil
+		}
+	}
+	return nil, webauthn.ErrCredentialNotFound
+}
+
+func (m *Passkeys) GetUserCredential(userID, id string) (*webauthn.Credential, error) {
+	c, ok := m.creds[id]
+	if !ok || c.UserID != userID {
+		return nil, webauthn.ErrCredentialNotFound
+	}
+	cp := *c
+	return &cp, nil
+}
+
+func (m *Passkeys) ListCredentials(userID string) ([]webauthn.Credential, error) {
+	var out []webauthn.Credential
+	for _, c := range m.creds {
+		if c.UserID == userID {
+			out = append(out, *c)
+		}
+	}
+	return out, nil
+}
+
+func (m *Passkeys) CountActive(userID string) (int, error) {
+	n := 0
+	for _, c := range m.creds {
+		if c.UserID == userID && c.RevokedAt == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *Passkeys) SaveCredential(c *webauthn.Credential) error {
+	if m.creds == nil {
+		m.creds = map[string]*webauthn.Credential{}
+	}
+	cp := *c
+	m.creds[c.ID] = &cp
+	return nil
+}
+
+func (m *Passkeys) GetPolicy(tenantID string) (*webauthn.Policy, error) {
+	p, ok := m.policies[tenantID]
+	if !ok {
+		return nil, nil
+	}
+	cp := *p
+	return &cp, nil
+}
+
+func (m *Passkeys) SavePolicy(p *webauthn.Policy) error {
+	if m.policies == nil {
+		m.policies = map[string]*webauthn.Policy{}
+	}
+	cp := *p
+	m.policies[p.TenantID] = &cp
+	return nil
+}
@@ -0,0 +1,53 @@
+package fakes
+
+import (
+	"context"
+
+	"aegis-api/services_/auth/abac"
+)
+
+// Policy is an ABAC policy that refuses the resources and content it was
+// told to and allows everything else. Label and clearance management is not
+// faked.
+type Policy struct {
+	abac.Service
+	refused   map[string]bool // resource IDs
+	checksums map[string]bool
+}
+
+// Refuse withholds the resource with the given ID.
+func (p *Policy) Refuse(id string) {
+	if p.refused == nil {
+		p.refused = map[string]bool{}
+	}
+	p.refused[id] = true
+}
+
+// RefuseContent denies content with the given checksum.
+func (p *Policy) RefuseContent(checksum string) {
+	if p.checksums == nil {
+		p.checksums = map[string]bool{}
+	}
+	p.checksums[checksum] = true
+}
+
+func (p *Policy) Decide(_ context.Context, _ abac.Subject, _, _, id string) (*abac.Decision, error) {
+	if p.refused[id] {
+		return &abac.Decision{Reason: "insufficient clearance"}, nil
+	}
+	return &abac.Decision{Allowed: true}, nil
+}
+
+func (p *Policy) Withheld(_ context.Context, _ abac.Subject, _, _ string, ids []string) (map[string]*abac.Decision, error) {
+	out := map[string]*abac.Decision{}
+	for _, id := range ids {
+		if p.refused[id] {
+			out[id] = &abac.Decision{Reason: "insufficient clearance"}
+		}
+	}
+	return out, nil
+}
+
+func (p *Policy) DecideContent(_ context.Context, _ abac.Subject, _, checksum string) (*abac.Decision, string, error) {
+	return &abac.Decision{Allowed: !p.checksums[checksum]}, "", nil
+}
@@ -0,0 +1,140 @@
+package fakes
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"aegis-api/services_/redaction"
+
+	"github.com/google/uuid"
+)
+
+// Redactions keeps redaction marks, profiles and derivatives in memory.
+type Redactions struct {
+	marks       []*redaction.Mark
+	profiles    []*redaction.Profile
+	Derivatives []*redaction.Derivative
+}
+
+func (m *Redactions) AutoMigrate() error { return nil }
+
+func (m *Redactions) CreateMark(mk *redaction.Mark) error {
+	mk.ID = uuid.NewString()
+	mk.CreatedAt = time.Now()
+	m.marks = append(m.marks, mk)
+	return nil
+}
+
+func (m *Redactions) GetMark(tenantID, markID string) (*redaction.Mark, error) {
+	for _, mk := range m.marks {
+		if mk.TenantID == tenantID && mk.ID == markID {
+			return mk, nil
+		}
+	}
+	return nil, redaction.ErrMarkNotFound
+}
+
+func (m *Redactions) ListMarks(tenantID, targetType, targetID string, withdrawn bool) ([]redaction.Mark, error) {
+	var out []redaction.Mark
+	for _, mk := range m.marks {
+		if mk.TenantID == tenantID && mk.TargetType == targetType && mk.TargetID == targetID && (withdrawn || mk.WithdrawnAt == nil) {
+			out = append(out, *mk)
+		}
+	}
+	return out, nil
+}
+
+func (m *Redactions) WithdrawMark(tenantID, markID, userID string) (*redaction.Mark, error) {
+	mk, err := m.GetMark(tenantID, markID)
+	if err != nil || mk.WithdrawnAt != nil {
+		return nil, redaction.ErrMarkNotFound
+	}
+	now := time.Now()
+	mk.WithdrawnBy, mk.WithdrawnAt = &userID, &now
+	return mk, nil
+}
+
+func (m *Redactions) CreateProfile(p *redaction.Profile) error {
+	for _, old := range m.profiles {
+		if old.TenantID == p.TenantID && old.Name == p.Name {
+			return redaction.ErrProfileExists
+		}
+	}
+	p.ID = uuid.NewString()
+	m.profiles = append(m.profiles, p)
+	return nil
+}
+
+func (m *Redactions) UpdateProfile(p *redaction.Profile) error { return nil }
+
+func (m *Redactions) DeleteProfile(tenantID, profileID string) error {
+	for i, p := range m.profiles {
+		if p.TenantID == tenantID && p.ID == profileID {
+			m.profiles = append(m.profiles[:i], m.profiles[i+1:]...)
+			return nil
+		}
+	}
+	return redaction.ErrProfileNotFound
+}
+
+func (m *Redactions) GetProfile(tenantID, profileID string) (*redaction.Profile, error) {
+	for _, p := range m.profiles {
+		if p.TenantID == tenantID && p.ID == profileID {
+			return p, nil
+		}
+	}
+	return nil, redaction.ErrProfileNotFound
+}
+
+func (m *Redactions) ListProfiles(tenantID string) ([]redaction.Profile, error) {
+	var out []redaction.Profile
+	for _, p := range m.profiles {
+		if p.TenantID == tenantID {
+			out = append(out, *p)
+		}
+	}
+	return out, nil
+}
+
+func (m *Redactions) CreateDerivative(d *redaction.Derivative) error {
+	if d.ID == "" {
+		d.ID = uuid.NewString()
+	}
+	m.Derivatives = append(m.Derivatives, d)
+	return nil
+}
+
+func (m *Redactions) ListDerivatives(tenantID, sourceType, sourceID string) ([]redaction.Derivative, error) {
+	var out []redaction.Derivative
+	for _, d := range m.Derivatives {
+		if d.TenantID == tenantID && d.SourceType == sourceType && d.SourceID == sourceID {
+			out = append(out, *d)
+		}
+	}
+	return out, nil
+}
+
+func (m *Redactions) FindDerivative(tenantID, sourceType, sourceID, sha256Source, format string, marks []byte) (*redaction.Derivative, error) {
+	for _, d := range m.Derivatives {
+		if d.TenantID == tenantID && d.SourceType == sourceType && d.SourceID == sourceID &&
+			d.SourceSHA256 == sha256Source && d.Format == format && bytes.Equal(d.Marks, marks) {
+			return d, nil
+		}
+	}
+	return nil, nil
+}
+
+// Rasterizer renders every PDF as Pages copies of the same page image.
+type Rasterizer struct {
+	Page  []byte
+	Pages int
+}
+
+func (r Rasterizer) Rasterize(context.Context, []byte, int) ([][]byte, error) {
+	out := make([][]byte, r.Pages)
+	for i := range out {
+		out[i] = r.Page
+	}
+	return out, nil
+}
@@ -0,0 +1,240 @@
+package fakes
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"aegis-api/services_/report"
+	"aegis-api/services_/report/update_status"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gorm.io/datatypes"
+)
+
+// Reports is an in-memory report service. It keeps the stored metadata
+// current, so tests can hold on to the *report.Report they added. Editing
+// and generation are not faked.
+type Reports struct {
+	report.ReportService
+	Items     []*report.ReportWithContent
+	Snapshots []report.ReportSnapshot
+}
+
+// Add stores a report with its sections.
+func (r *Reports) Add(meta *report.Report, sections ...report.ReportSection) {
+	r.Items = append(r.Items, &report.ReportWithContent{Metadata: meta, Content: sections})
+}
+
+func (r *Reports) find(id uuid.UUID) *report.ReportWithContent {
+	for _, it := range r.Items {
+		if it.Metadata.ID == id {
+			return it
+		}
+	}
+	return nil
+}
+
+func (r *Reports) GetReportsByCaseID(_ context.Context, caseID uuid.UUID) ([]report.ReportWithDetails, error) {
+	var out []report.ReportWithDetails
+	for _, it := range r.Items {
+		if m := it.Metadata; m.CaseID == caseID {
+			out = append(out, report.ReportWithDetails{
+				ID: m.ID, CaseID: m.CaseID, TeamID: m.TeamID,
+				Name: m.Name, Status: m.Status, Version: m.Version, FilePath: m.FilePath,
+			})
+		}
+	}
+	return out, nil
+}
+
+// ListRecentReports lists the tenant's reports, newest first, applying only
+// the tenant and case filters.
+func (r *Reports) ListRecentReports(_ context.Context, opts report.RecentReportsOptions) ([]report.RecentReport, error) {
+	var out []report.RecentReport
+	for i := len(r.Items) - 1; i >= 0; i-- {
+		m := r.Items[i].Metadata
+		if m.TenantID != opts.TenantID || (opts.CaseID != nil && m.CaseID != *opts.CaseID) {
+			continue
+		}
+		out = append(out, report.RecentReport{ID: m.ID, CaseID: m.CaseID, Title: m.Name, Status: m.Status, LastModified: m.UpdatedAt})
+	}
+	return out, nil
+}
+
+func (r *Reports) GetReportsByTeamID(_ context.Context, tenantID, teamID uuid.UUID) ([]report.ReportWithDetails, error) {
+	var out []report.ReportWithDetails
+	for _, it := range r.Items {
+		if m := it.Metadata; m.TenantID == tenantID && m.TeamID == teamID {
+			out = append(out, report.ReportWithDetails{
+				ID: m.ID, CaseID: m.CaseID, TeamID: m.TeamID,
+				Name: m.Name, Status: m.Status, Version: m.Version, FilePath: m.FilePath,
+			})
+		}
+	}
+	return out, nil
+}
+
+// Publish pins the sections as the report's next published version.
+func (r *Reports) Publish(id uuid.UUID, sections ...report.ReportSection) error {
+	it := r.find(id)
+	if it == nil {
+		return report.ErrReportNotFound
+	}
+	raw, err := json.Marshal(sections)
+	if err != nil {
+		return err
+	}
+	it.Metadata.Version++
+	it.Metadata.Status = "published"
+	it.Content = sections
+	r.Snapshots = append(r.Snapshots, report.ReportSnapshot{
+		ID: uuid.NewString(), ReportID: id.String(), Version: it.Metadata.Version,
+		Sections: datatypes.JSON(raw), CreatedAt: time.Now(),
+	})
+	return nil
+}
+
+func (r *Reports) DownloadReport(_ context.Context, id uuid.UUID) (*report.ReportWithContent, error) {
+	it := r.find(id)
+	if it == nil {
+		return nil, report.ErrReportNotFound
+	}
+	return it, nil
+}
+
+// DownloadReportAsPDF renders a stand-in PDF naming the report.
+func (r *Reports) DownloadReportAsPDF(_ context.Context, id uuid.UUID, _ report.FieldRenderer) ([]byte, error) {
+	if r.find(id) == nil {
+		return nil, report.ErrReportNotFound
+	}
+	return []byte("%PDF-rendered " + id.String()), nil
+}
+
+func (r *Reports) GetReportByID(_ context.Context, id string) (*report.Report, error) {
+	for _, it := range r.Items {
+		if it.Metadata.ID.String() == id {
+			cp := *it.Metadata
+			return &cp, nil
+		}
+	}
+	return nil, report.ErrReportNotFound
+}
+
+func (r *Reports) FreezeFields(_ context.Context, id uuid.UUID, _ report.FieldRenderer) (*report.ReportWithContent, error) {
+	it := r.find(id)
+	if it == nil {
+		return nil, report.ErrReportNotFound
+	}
+	now := time.Now()
+	it.Metadata.FieldsFrozenAt = &now
+	return it, nil
+}
+
+func (r *Reports) PinSnapshot(_ context.Context, id uuid.UUID, version int, _ report.FieldRenderer) (*report.ReportSnapshot, error) {
+	it := r.find(id)
+	if it == nil {
+		return nil, report.ErrReportNotFound
+	}
+	snap := report.ReportSnapshot{
+		ID: uuid.NewString(), ReportID: id.String(), Version: version,
+		Status: it.Metadata.Status, Name: it.Metadata.Name, CreatedAt: time.Now(),
+	}
+	r.Snapshots = append(r.Snapshots, snap)
+	return &snap, nil
+}
+
+func (r *Reports) UpdateStatus(_ context.Context, id uuid.UUID, status update_status.ReportStatus) (*update_status.Report, error) {
+	it := r.find(id)
+	if it == nil {
+		return nil, report.ErrReportNotFound
+	}
+	it.Metadata.Status = string(status)
+	it.Metadata.Version++
+	return &update_status.Report{ID: id, Status: status, Version: it.Metadata.Version}, nil
+}
+
+func (r *Reports) ListSnapshots(_ context.Context, reportID uuid.UUID) ([]report.ReportSnapshot, error) {
+	var out []report.ReportSnapshot
+	for _, s := range r.Snapshots {
+		if s.ReportID == reportID.String() {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// ReportRows is a report repository serving the metadata of the reports it
+// holds. Writes are not faked.
+type ReportRows struct {
+	report.ReportRepository
+	Rows []*report.Report
+}
+
+func (r *ReportRows) GetByID(_ context.Context, id string) (*report.Report, error) {
+	for _, row := range r.Rows {
+		if row.ID.String() == id {
+			cp := *row
+			return &cp, nil
+		}
+	}
+	return nil, report.ErrReportNotFound
+}
+
+// ReportContent is a content repository holding one report's document.
+// Section history kept by the Mongo repository is not faked.
+type ReportContent struct {
+	report.ReportMongoRepository
+	Doc report.ReportContentMongo
+}
+
+func (m *ReportContent) GetReportContent(context.Context, primitive.ObjectID, string, string) (*report.ReportContentMongo, error) {
+	cp := m.Doc
+	cp.Sections = slices.Clone(m.Doc.Sections)
+	return &cp, nil
+}
+
+// Section returns the section with the ID, or nil.
+func (m *ReportContent) Section(id primitive.ObjectID) *report.ReportSection {
+	for i := range m.Doc.Sections {
+		if m.Doc.Sections[i].ID == id {
+			return &m.Doc.Sections[i]
+		}
+	}
+	return nil
+}
+
+func (m *ReportContent) UpdateSection(_ context.Context, _, id primitive.ObjectID, content, _, _ string) error {
+	sec := m.Section(id)
+	if sec == nil {
+		return report.ErrSectionNotFound
+	}
+	sec.Content = content
+	return nil
+}
+
+func (m *ReportContent) UpdateSectionTitle(_ context.Context, _, id primitive.ObjectID, title, _, _ string) error {
+	sec := m.Section(id)
+	if sec == nil {
+		return report.ErrSectionNotFound
+	}
+	sec.Title = title
+	return nil
+}
+
+func (m *ReportContent) AddSection(_ context.Context, _ primitive.ObjectID, sec report.ReportSection, _, _ string) error {
+	m.Doc.Sections = append(m.Doc.Sections, sec)
+	return nil
+}
+
+func (m *ReportContent) DeleteSection(_ context.Context, _, id primitive.ObjectID, _, _ string) error {
+	m.Doc.Sections = slices.DeleteFunc(m.Doc.Sections, func(s report.ReportSection) bool { return s.ID == id })
+	return nil
+}
+
+func (m *ReportContent) UpdateSections(_ context.Context, _ primitive.ObjectID, secs []report.ReportSection, _, _ string) error {
+	m.Doc.Sections = slices.Clone(secs)
+	return nil
+}
@@ -0,0 +1,134 @@
+package fakes
+
+import (
+	"slices"
+
+	"aegis-api/services_/report/review"
+
+	"github.com/google/uuid"
+)
+
+// Reviews keeps a tenant's review workflow, reviews, decisions and comments
+// in memory.
+type Reviews struct {
+	workflow  *review.Workflow
+	Reviews   []*review.Review
+	decisions []review.Decision
+	comments  []*review.Comment
+	roles     map[string]string // user ID -> role
+}
+
+// SetRole gives the user a role for reviewer lookups.
+func (m *Reviews) SetRole(userID, role string) {
+	if m.roles == nil {
+		m.roles = map[string]string{}
+	}
+	m.roles[userID] = role
+}
+
+func (m *Reviews) AutoMigrate() error { return nil }
+
+func (m *Reviews) GetWorkflow(string) (*review.Workflow, error) { return m.workflow, nil }
+
+func (m *Reviews) SaveWorkflow(w *review.Workflow) error {
+	m.workflow = w
+	return nil
+}
+
+func (m *Reviews) LatestReview(reportID string) (*review.Review, error) {
+	for i := len(m.Reviews) - 1; i >= 0; i-- {
+		if m.Reviews[i].ReportID == reportID {
+			cp := *m.Reviews[i]
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *Reviews) CreateReview(r *review.Review, d *review.Decision) error {
+	for _, existing := range m.Reviews {
+		if existing.ReportID == r.ReportID && existing.State == review.StateInReview {
+			return review.ErrReviewConflict
+		}
+	}
+	r.ID = uuid.NewString()
+	r.Version = 1
+	cp := *r
+	m.Reviews = append(m.Reviews, &cp)
+	d.ReviewID = r.ID
+	m.decisions = append(m.decisions, *d)
+	return nil
+}
+
+func (m *Reviews) UpdateReview(r *review.Review, decisions ...*review.Decision) error {
+	for _, stored := range m.Reviews {
+		if stored.ID != r.ID {
+			continue
+		}
+		if stored.Version != r.Version {
+			return review.ErrReviewConflict
+		}
+		r.Version++
+		*stored = *r
+		for _, d := range decisions {
+			m.decisions = append(m.decisions, *d)
+		}
+		return nil
+	}
+	return review.ErrReviewConflict
+}
+
+func (m *Reviews) ListDecisions(reviewID string) ([]review.Decision, error) {
+	var out []review.Decision
+	for _, d := range m.decisions {
+		if d.ReviewID == reviewID {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func (m *Reviews) CreateComment(c *review.Comment) error {
+	c.ID = uuid.NewString()
+	m.comments = append(m.comments, c)
+	return nil
+}
+
+func (m *Reviews) GetComment(reportID, id string) (*review.Comment, error) {
+	for _, c := range m.comments {
+		if c.ReportID == reportID && c.ID == id {
+			cp := *c
+			return &cp, nil
+		}
+	}
+	return nil, review.ErrCommentNotFound
+}
+
+func (m *Reviews) ListComments(reportID, sectionID string, includeResolved bool) ([]review.Comment, error) {
+	var out []review.Comment
+	for _, c := range m.comments {
+		if c.ReportID == reportID && (sectionID == "" || c.SectionID == sectionID) && (includeResolved || !c.Resolved) {
+			out = append(out, *c)
+		}
+	}
+	return out, nil
+}
+
+func (m *Reviews) ResolveComment(c *review.Comment) error {
+	for _, stored := range m.comments {
+		if stored.ID == c.ID {
+			*stored = *c
+		}
+	}
+	return nil
+}
+
+func (m *Reviews) UsersWithRoles(_ string, roles []string) ([]string, error) {
+	var out []string
+	for id, role := range m.roles {
+		if slices.Contains(roles, role) {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,111 @@
+package fakes
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"aegis-api/services_/report"
+
+	"github.com/google/uuid"
+)
+
+// Revisions is an in-memory section revision and snapshot store.
+type Revisions struct {
+	Revs      []report.SectionRevision
+	Snapshots []report.ReportSnapshot
+}
+
+func (m *Revisions) AutoMigrate() error { return nil }
+
+func (m *Revisions) EnsureBaseline(ctx context.Context, reportID string, revs []report.SectionRevision) error {
+	if seq, _ := m.LatestSeq(ctx, reportID); seq > 0 {
+		return nil
+	}
+	_, err := m.Append(ctx, reportID, revs)
+	return err
+}
+
+func (m *Revisions) Append(ctx context.Context, reportID string, revs []report.SectionRevision) (int64, error) {
+	seq, _ := m.LatestSeq(ctx, reportID)
+	seq++
+	for _, r := range revs {
+		last := 0
+		for _, old := range m.Revs {
+			if old.SectionID == r.SectionID {
+				last = max(last, old.Revision)
+			}
+		}
+		r.ID, r.ReportID, r.ReportSeq, r.Revision = uuid.NewString(), reportID, seq, last+1
+		m.Revs = append(m.Revs, r)
+	}
+	return seq, nil
+}
+
+func (m *Revisions) LatestSeq(_ context.Context, reportID string) (int64, error) {
+	var seq int64
+	for _, r := range m.Revs {
+		if r.ReportID == reportID {
+			seq = max(seq, r.ReportSeq)
+		}
+	}
+	return seq, nil
+}
+
+func (m *Revisions) ListByReport(_ context.Context, reportID string) ([]report.SectionRevision, error) {
+	out := slices.Clone(m.Revs)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].ReportSeq > out[j].ReportSeq })
+	return out, nil
+}
+
+func (m *Revisions) ListBySection(_ context.Context, reportID, sectionID string) ([]report.SectionRevision, error) {
+	var out []report.SectionRevision
+	for _, r := range m.Revs {
+		if r.SectionID == sectionID {
+			out = append([]report.SectionRevision{r}, out...)
+		}
+	}
+	return out, nil
+}
+
+func (m *Revisions) GetBySection(_ context.Context, reportID, sectionID string, revision int) (*report.SectionRevision, error) {
+	for _, r := range m.Revs {
+		if r.SectionID == sectionID && r.Revision == revision {
+			return &r, nil
+		}
+	}
+	return nil, report.ErrRevisionNotFound
+}
+
+func (m *Revisions) AsOf(_ context.Context, reportID string, seq int64) ([]report.SectionRevision, error) {
+	latest := map[string]report.SectionRevision{}
+	for _, r := range m.Revs {
+		if r.ReportSeq <= seq {
+			latest[r.SectionID] = r
+		}
+	}
+	var out []report.SectionRevision
+	for _, r := range latest {
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+func (m *Revisions) CreateSnapshot(_ context.Context, s *report.ReportSnapshot) error {
+	s.ID = uuid.NewString()
+	m.Snapshots = append(m.Snapshots, *s)
+	return nil
+}
+
+func (m *Revisions) ListSnapshots(context.Context, string) ([]report.ReportSnapshot, error) {
+	return m.Snapshots, nil
+}
+
+func (m *Revisions) GetSnapshot(_ context.Context, _, id string) (*report.ReportSnapshot, error) {
+	for _, s := range m.Snapshots {
+		if s.ID == id {
+			return &s, nil
+		}
+	}
+	return nil, report.ErrSnapshotNotFound
+}
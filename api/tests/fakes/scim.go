@@ -0,0 +1,208 @@
+package fakes
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"aegis-api/services_/auth/registration"
+	"aegis-api/services_/auth/scim"
+
+	"github.com/google/uuid"
+)
+
+// SCIM keeps SCIM configuration, user links and groups in memory. Users
+// are looked up in, and deactivated on, Users.
+type SCIM struct {
+	configs  map[string]*scim.Config
+	links    map[string]*scim.UserLink
+	groups   map[string]*scim.Group
+	members  map[string][]string // group -> users
+	Users    *Users
+	Released map[string]string // user -> successor
+}
+
+func (r *SCIM) init() {
+	if r.configs == nil {
+		r.configs = map[string]*scim.Config{}
+		r.links = map[string]*scim.UserLink{}
+		r.groups = map[string]*scim.Group{}
+		r.members = map[string][]string{}
+		r.Released = map[string]string{}
+	}
+}
+
+func (r *SCIM) AutoMigrate() error { return nil }
+
+func (r *SCIM) GetConfig(tenantID string) (*scim.Config, error) {
+	if c, ok := r.configs[tenantID]; ok {
+		cp := *c
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *SCIM) GetConfigByTokenHash(hash string) (*scim.Config, error) {
+	for _, c := range r.configs {
+		if c.TokenHash == hash {
+			cp := *c
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *SCIM) SaveConfig(c *scim.Config) error {
+	r.init()
+	cp := *c
+	r.configs[c.TenantID] = &cp
+	return nil
+}
+
+func (r *SCIM) DeleteConfig(tenantID string) error {
+	delete(r.configs, tenantID)
+	return nil
+}
+
+func (r *SCIM) TouchToken(tenantID string, at time.Time) error {
+	r.configs[tenantID].TokenLastUsed = &at
+	return nil
+}
+
+func (r *SCIM) FindUsers(ids []string) ([]registration.User, error) {
+	var out []registration.User
+	for _, id := range ids {
+		if u, ok := r.Users.ByID[id]; ok {
+			out = append(out, *u)
+		}
+	}
+	return out, nil
+}
+
+func (r *SCIM) ListUserLinks(tenantID string) ([]scim.UserLink, error) {
+	var out []scim.UserLink
+	for _, l := range r.links {
+		if l.TenantID == tenantID {
+			out = append(out, *l)
+		}
+	}
+	slices.SortFunc(out, func(a, b scim.UserLink) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return out, nil
+}
+
+func (r *SCIM) GetUserLink(tenantID, userID string) (*scim.UserLink, error) {
+	if l, ok := r.links[userID]; ok && l.TenantID == tenantID {
+		cp := *l
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *SCIM) SaveUserLink(l *scim.UserLink) error {
+	r.init()
+	if l.CreatedAt.IsZero() {
+		l.CreatedAt = time.Now().Add(time.Duration(len(r.links)) * time.Millisecond)
+	}
+	l.UpdatedAt = time.Now()
+	cp := *l
+	r.links[l.UserID] = &cp
+	return nil
+}
+
+func (r *SCIM) DeleteUserLink(tenantID, userID string) error {
+	delete(r.links, userID)
+	for g, ids := range r.members {
+		r.members[g] = slices.DeleteFunc(ids, func(id string) bool { return id == userID })
+	}
+	return nil
+}
+
+func (r *SCIM) ListGroups(tenantID string) ([]scim.Group, error) {
+	var out []scim.Group
+	for _, g := range r.groups {
+		if g.TenantID == tenantID {
+			out = append(out, *g)
+		}
+	}
+	slices.SortFunc(out, func(a, b scim.Group) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return out, nil
+}
+
+func (r *SCIM) GetGroup(tenantID, id string) (*scim.Group, error) {
+	if g, ok := r.groups[id]; ok && g.TenantID == tenantID {
+		cp := *g
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *SCIM) CreateGroup(g *scim.Group) error {
+	g.ID = uuid.NewString()
+	g.CreatedAt = time.Now().Add(time.Duration(len(r.groups)) * time.Millisecond)
+	return r.SaveGroup(g)
+}
+
+func (r *SCIM) SaveGroup(g *scim.Group) error {
+	r.init()
+	cp := *g
+	r.groups[g.ID] = &cp
+	return nil
+}
+
+func (r *SCIM) DeleteGroup(tenantID, id string) error {
+	delete(r.groups, id)
+	delete(r.members, id)
+	return nil
+}
+
+func (r *SCIM) Members(groupIDs []string) ([]scim.GroupMember, error) {
+	var out []scim.GroupMember
+	for _, g := range groupIDs {
+		for _, u := range r.members[g] {
+			out = append(out, scim.GroupMember{GroupID: g, UserID: u})
+		}
+	}
+	return out, nil
+}
+
+func (r *SCIM) SetMembers(groupID string, userIDs []string) error {
+	r.init()
+	r.members[groupID] = slices.Clone(userIDs)
+	return nil
+}
+
+func (r *SCIM) GroupsOfUser(userID string) ([]scim.Group, error) {
+	var out []scim.Group
+	for g, ids := range r.members {
+		if slices.Contains(ids, userID) {
+			out = append(out, *r.groups[g])
+		}
+	}
+	return out, nil
+}
+
+func (r *SCIM) ReleaseUser(userID, successor string, at time.Time) (*scim.Release, error) {
+	r.init()
+	r.Released[userID] = successor
+	r.Users.ByID[userID].DeactivatedAt = &at
+	rel := &scim.Release{CaseRoles: 2, Collaborations: 1}
+	if successor != "" {
+		rel.Cases, rel.Reports = 3, 1
+	} else {
+		rel.Unassigned = 4
+	}
+	return rel, nil
+}
+
+// Revocations records the reason each user's sessions were revoked for.
+type Revocations struct {
+	Revoked map[string]string
+}
+
+func (f *Revocations) RevokeUser(_ context.Context, userID, reason string) (int, error) {
+	if f.Revoked == nil {
+		f.Revoked = map[string]string{}
+	}
+	f.Revoked[userID] = reason
+	return 2, nil
+}
@@ -0,0 +1,157 @@
+package fakes
+
+import (
+	"sync"
+	"time"
+
+	"aegis-api/services_/auth/session"
+
+	"github.com/google/uuid"
+)
+
+// Sessions keeps sessions, refresh tokens and users' token versions in
+// memory, counting session lookups.
+type Sessions struct {
+	mu       sync.Mutex
+	Sessions map[string]*session.Session
+	Tokens   map[string]*session.RefreshToken // by hash
+	versions map[string]int
+	Lookups  int // GetSession calls
+}
+
+// AddUser registers a user at token version 1.
+func (m *Sessions) AddUser(userID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.versions[userID] = 1
+}
+
+func (m *Sessions) init() {
+	if m.Sessions == nil {
+		m.Sessions = map[string]*session.Session{}
+		m.Tokens = map[string]*session.RefreshToken{}
+		m.versions = map[string]int{}
+	}
+}
+
+func (m *Sessions) AutoMigrate() error { return nil }
+
+func (m *Sessions) CreateSession(s *session.Session, t *session.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	s.ID = uuid.NewString()
+	s.CreatedAt = time.Now()
+	cp := *s
+	m.Sessions[s.ID] = &cp
+	t.ID = uuid.NewString()
+	t.SessionID = s.ID
+	tc := *t
+	m.Tokens[t.TokenHash] = &tc
+	return nil
+}
+
+func (m *Sessions) GetSession(id string) (*session.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Lookups++
+	s, ok := m.Sessions[id]
+	if !ok {
+		return nil, session.ErrSessionNotFound
+	}
+	cp := *s
+	return &cp, nil
+}
+
+func (m *Sessions) ListSessions(userID string, now time.Time) ([]session.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []session.Session
+	for _, s := range m.Sessions {
+		if s.UserID == userID && s.Active(now) {
+			out = append(out, *s)
+		}
+	}
+	return out, nil
+}
+
+func (m *Sessions) TouchSession(id string, at time.Time, c session.Client) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.Sessions[id]; ok {
+		s.LastActiveAt = at
+	}
+	return nil
+}
+
+func (m *Sessions) RevokeSession(id, reason string, at time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.Sessions[id]
+	if !ok || s.RevokedAt != nil {
+		return false, nil
+	}
+	s.RevokedAt, s.RevokedReason = &at, reason
+	return true, nil
+}
+
+func (m *Sessions) RevokeUserSessions(userID, reason string, at time.Time) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ids []string
+	for _, s := range m.Sessions {
+		if s.UserID == userID && s.RevokedAt == nil {
+			s.RevokedAt, s.RevokedReason = &at, reason
+			ids = append(ids, s.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (m *Sessions) GetRefreshToken(hash string) (*session.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.Tokens[hash]
+	if !ok {
+		return nil, session.ErrInvalidRefreshToken
+	}
+	cp := *t
+	return &cp, nil
+}
+
+func (m *Sessions) ExchangeRefreshToken(id string, at time.Time, next *session.RefreshToken) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.Tokens {
+		if t.ID == id {
+			if t.UsedAt != nil {
+				return false, nil
+			}
+			t.UsedAt = &at
+			next.ID = uuid.NewString()
+			cp := *next
+			m.Tokens[next.TokenHash] = &cp
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *Sessions) TokenVersion(userID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.versions[userID]
+	if !ok {
+		return 0, session.ErrUserNotFound
+	}
+	return v, nil
+}
+
+func (m *Sessions) BumpTokenVersion(userID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.versions[userID]++
+	return m.versions[userID], nil
+}
@@ -0,0 +1,141 @@
+package fakes
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"aegis-api/services_/report/signing"
+
+	"github.com/google/uuid"
+)
+
+// SigningStore keeps signing keys and sealed artifacts in memory. Every user
+// is named Examiner.
+type SigningStore struct {
+	keys      []*signing.SigningKey
+	Artifacts []*signing.Artifact
+	Examiner  string
+}
+
+func (m *SigningStore) AutoMigrate() error { return nil }
+
+func (m *SigningStore) ActiveKey(tenantID string) (*signing.SigningKey, error) {
+	for _, k := range m.keys {
+		if k.TenantID == tenantID && k.Active {
+			return k, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *SigningStore) GetKey(tenantID, keyID string) (*signing.SigningKey, error) {
+	for _, k := range m.keys {
+		if k.TenantID == tenantID && k.ID == keyID {
+			return k, nil
+		}
+	}
+	return nil, signing.ErrKeyNotFound
+}
+
+func (m *SigningStore) ListKeys(tenantID string) ([]signing.SigningKey, error) {
+	var out []signing.SigningKey
+	for _, k := range m.keys {
+		if k.TenantID == tenantID {
+			out = append(out, *k)
+		}
+	}
+	return out, nil
+}
+
+func (m *SigningStore) ReplaceActiveKey(k *signing.SigningKey) error {
+	now := time.Now()
+	for _, old := range m.keys {
+		if old.TenantID == k.TenantID && old.Active {
+			old.Active, old.RetiredAt = false, &now
+		}
+	}
+	k.ID = uuid.NewString()
+	m.keys = append(m.keys, k)
+	return nil
+}
+
+func (m *SigningStore) CreateArtifact(a *signing.Artifact) error {
+	if _, err := m.GetArtifact(a.ReportID, a.Version); err == nil {
+		return signing.ErrAlreadySealed
+	}
+	a.ID = uuid.NewString()
+	m.Artifacts = append(m.Artifacts, a)
+	return nil
+}
+
+func (m *SigningStore) GetArtifact(reportID string, version int) (*signing.Artifact, error) {
+	for _, a := range m.Artifacts {
+		if a.ReportID == reportID && a.Version == version {
+			return a, nil
+		}
+	}
+	return nil, signing.ErrArtifactNotFound
+}
+
+func (m *SigningStore) LatestArtifact(reportID string) (*signing.Artifact, error) {
+	var latest *signing.Artifact
+	for _, a := range m.Artifacts {
+		if a.ReportID == reportID && (latest == nil || a.Version > latest.Version) {
+			latest = a
+		}
+	}
+	if latest == nil {
+		return nil, signing.ErrArtifactNotFound
+	}
+	return latest, nil
+}
+
+func (m *SigningStore) FindArtifacts(tenantID, sum string) ([]signing.Artifact, error) {
+	var out []signing.Artifact
+	for _, a := range m.Artifacts {
+		if a.TenantID == tenantID && a.SHA256 == sum {
+			out = append(out, *a)
+		}
+	}
+	return out, nil
+}
+
+func (m *SigningStore) UserName(string) (string, error) { return m.Examiner, nil }
+
+// Signer signs with a real Ed25519 key so signatures can be checked, and
+// serves the artifacts in Sealed.
+type Signer struct {
+	Disabled bool
+	KeyID    string
+	Key      ed25519.PrivateKey
+	Sealed   map[uuid.UUID]*signing.Artifact
+}
+
+func (s *Signer) Enabled() bool { return !s.Disabled }
+
+func (s *Signer) Artifact(_ context.Context, _ signing.Actor, id uuid.UUID) (*signing.Artifact, error) {
+	if a, ok := s.Sealed[id]; ok {
+		return a, nil
+	}
+	return nil, signing.ErrArtifactNotFound
+}
+
+func (s *Signer) Sign(_, _ string, payload []byte) (*signing.Detached, error) {
+	return &signing.Detached{
+		Algorithm: signing.AlgorithmEd25519,
+		KeyID:     s.KeyID,
+		SignedAt:  time.Now().UTC(),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(s.Key, payload)),
+	}, nil
+}
+
+func (s *Signer) VerifyDetached(_ string, payload []byte, d *signing.Detached) error {
+	sig, err := base64.StdEncoding.DecodeString(d.Signature)
+	if err != nil || d.KeyID != s.KeyID || !ed25519.Verify(s.Key.Public().(ed25519.PublicKey), payload, sig) {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package fakes
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"aegis-api/services_/auth/sso"
+
+	"github.com/google/uuid"
+)
+
+// SSO keeps tenants' OpenID Connect and SAML configuration, linked
+// identities and SAML sessions in memory.
+type SSO struct {
+	configs      map[string]*sso.OIDCConfig
+	samlConfigs  map[string]*sso.SAMLConfig
+	settings     map[string]*sso.Settings
+	identities   []*sso.Identity
+	SAMLSessions []sso.SAMLSession
+}
+
+func (m *SSO) init() {
+	if m.configs == nil {
+		m.configs = map[string]*sso.OIDCConfig{}
+		m.samlConfigs = map[string]*sso.SAMLConfig{}
+		m.settings = map[string]*sso.Settings{}
+	}
+}
+
+func (m *SSO) AutoMigrate() error { return nil }
+
+func (m *SSO) GetOIDCConfig(tenantID string) (*sso.OIDCConfig, error) {
+	c, ok := m.configs[tenantID]
+	if !ok {
+		return nil, nil
+	}
+	cp := *c
+	return &cp, nil
+}
+
+func (m *SSO) SaveOIDCConfig(c *sso.OIDCConfig) error {
+	m.init()
+	cp := *c
+	m.configs[c.TenantID] = &cp
+	return nil
+}
+
+func (m *SSO) DeleteOIDCConfig(tenantID string) error {
+	delete(m.configs, tenantID)
+	return nil
+}
+
+func (m *SSO) GetSAMLConfig(tenantID string) (*sso.SAMLConfig, error) {
+	c, ok := m.samlConfigs[tenantID]
+	if !ok {
+		return nil, nil
+	}
+	cp := *c
+	return &cp, nil
+}
+
+func (m *SSO) SaveSAMLConfig(c *sso.SAMLConfig) error {
+	m.init()
+	cp := *c
+	m.samlConfigs[c.TenantID] = &cp
+	return nil
+}
+
+func (m *SSO) DeleteSAMLConfig(tenantID string) error {
+	delete(m.samlConfigs, tenantID)
+	return nil
+}
+
+func (m *SSO) FindTenantsByDomain(domain string) ([]sso.Discovery, error) {
+	var out []sso.Discovery
+	for id, c := range m.configs {
+		var domains []string
+		_ = json.Unmarshal(c.AllowedDomains, &domains)
+		if c.Enabled && slices.Contains(domains, domain) {
+			out = append(out, sso.Discovery{TenantID: id, Provider: sso.ProviderOIDC})
+		}
+	}
+	for id, c := range m.samlConfigs {
+		var domains []string
+		_ = json.Unmarshal(c.AllowedDomains, &domains)
+		if c.Enabled && slices.Contains(domains, domain) {
+			out = append(out, sso.Discovery{TenantID: id, Provider: sso.ProviderSAML})
+		}
+	}
+	return out, nil
+}
+
+func (m *SSO) GetSettings(tenantID string) (*sso.Settings, error) {
+	s, ok := m.settings[tenantID]
+	if !ok {
+		return nil, nil
+	}
+	cp := *s
+	return &cp, nil
+}
+
+func (m *SSO) SaveSettings(s *sso.Settings) error {
+	m.init()
+	cp := *s
+	m.settings[s.TenantID] = &cp
+	return nil
+}
+
+func (m *SSO) GetIdentity(issuer, subject string) (*sso.Identity, error) {
+	for _, i := range m.identities {
+		if i.Issuer == issuer && i.Subject == subject {
+			cp := *i
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *SSO) CreateIdentity(i *sso.Identity) error {
+	i.ID = uuid.NewString()
+	cp := *i
+	m.identities = append(m.identities, &cp)
+	return nil
+}
+
+func (m *SSO) SaveIdentity(i *sso.Identity) error {
+	for n, e := range m.identities {
+		if e.ID == i.ID {
+			cp := *i
+			m.identities[n] = &cp
+		}
+	}
+	return nil
+}
+
+func (m *SSO) CreateSAMLSession(s *sso.SAMLSession) error {
+	s.ID = uuid.NewString()
+	s.CreatedAt = time.Now()
+	m.SAMLSessions = append(m.SAMLSessions, *s)
+	return nil
+}
+
+func (m *SSO) LatestSAMLSession(tenantID, userID string) (*sso.SAMLSession, error) {
+	for i := len(m.SAMLSessions) - 1; i >= 0; i-- {
+		if s := m.SAMLSessions[i]; s.TenantID == tenantID && s.UserID == userID {
+			return &s, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *SSO) FindSAMLSessions(tenantID, nameID string) ([]sso.SAMLSession, error) {
+	var out []sso.SAMLSession
+	for _, s := range m.SAMLSessions {
+		if s.TenantID == tenantID && s.NameID == nameID {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (m *SSO) DeleteSAMLSessions(ids []string) error {
+	m.SAMLSessions = slices.DeleteFunc(m.SAMLSessions, func(s sso.SAMLSession) bool { return slices.Contains(ids, s.ID) })
+	return nil
+}
@@ -0,0 +1,99 @@
+package fakes
+
+import (
+	"aegis-api/services_/report/report_templates"
+
+	"github.com/google/uuid"
+)
+
+// Templates is an in-memory report template repository.
+type Templates struct {
+	templates map[string]*report_templates.ReportTemplate
+	versions  map[string][]report_templates.TemplateVersion
+}
+
+func (m *Templates) AutoMigrate() error { return nil }
+
+func (m *Templates) CreateTemplate(t *report_templates.ReportTemplate, v *report_templates.TemplateVersion) error {
+	if m.templates == nil {
+		m.templates = map[string]*report_templates.ReportTemplate{}
+		m.versions = map[string][]report_templates.TemplateVersion{}
+	}
+	t.ID = uuid.NewString()
+	v.TemplateID = t.ID
+	cp := *t
+	m.templates[t.ID] = &cp
+	m.versions[t.ID] = append(m.versions[t.ID], *v)
+	return nil
+}
+
+func (m *Templates) AddVersion(t *report_templates.ReportTemplate, v *report_templates.TemplateVersion) error {
+	cur, ok := m.templates[t.ID]
+	if !ok || cur.TenantID != t.TenantID || cur.CurrentVersion != v.Version-1 {
+		return report_templates.ErrVersionConflict
+	}
+	cur.CurrentVersion, cur.Name, cur.CaseType, cur.Description = v.Version, t.Name, t.CaseType, t.Description
+	v.TemplateID = t.ID
+	m.versions[t.ID] = append(m.versions[t.ID], *v)
+	return nil
+}
+
+func (m *Templates) GetTemplate(tenantID, id string) (*report_templates.ReportTemplate, error) {
+	t, ok := m.templates[id]
+	if !ok || t.TenantID != tenantID {
+		return nil, report_templates.ErrTemplateNotFound
+	}
+	cp := *t
+	return &cp, nil
+}
+
+func (m *Templates) GetVersion(templateID string, version int) (*report_templates.TemplateVersion, error) {
+	for _, v := range m.versions[templateID] {
+		if v.Version == version {
+			return &v, nil
+		}
+	}
+	return nil, report_templates.ErrTemplateNotFound
+}
+
+func (m *Templates) ListVersions(templateID string) ([]report_templates.TemplateVersion, error) {
+	return m.versions[templateID], nil
+}
+
+func (m *Templates) ListTemplates(tenantID, caseType string, includeArchived bool) ([]report_templates.ReportTemplate, error) {
+	var out []report_templates.ReportTemplate
+	for _, t := range m.templates {
+		if t.TenantID == tenantID && (caseType == "" || t.CaseType == caseType) && (includeArchived || !t.Archived) {
+			out = append(out, *t)
+		}
+	}
+	return out, nil
+}
+
+func (m *Templates) GetDefault(tenantID, caseType string) (*report_templates.ReportTemplate, error) {
+	for _, t := range m.templates {
+		if t.TenantID == tenantID && t.CaseType == caseType && t.IsDefault && !t.Archived {
+			cp := *t
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *Templates) SetDefault(tenantID, id, caseType string) error {
+	for _, t := range m.templates {
+		if t.TenantID == tenantID && t.CaseType == caseType {
+			t.IsDefault = t.ID == id
+		}
+	}
+	return nil
+}
+
+func (m *Templates) Archive(tenantID, id string) error {
+	t, ok := m.templates[id]
+	if !ok || t.TenantID != tenantID {
+		return report_templates.ErrTemplateNotFound
+	}
+	t.Archived, t.IsDefault = true, false
+	return nil
+}
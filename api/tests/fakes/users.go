@@ -0,0 +1,71 @@
+package fakes
+
+import (
+	"strings"
+
+	"aegis-api/services_/auth/registration"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Users is an in-memory user store that also provisions users. ByID is keyed
+// by the user's ID string.
+type Users struct {
+	ByID map[string]*registration.User
+}
+
+// Add stores a user.
+func (u *Users) Add(user *registration.User) {
+	if u.ByID == nil {
+		u.ByID = map[string]*registration.User{}
+	}
+	u.ByID[user.ID.String()] = user
+}
+
+// GetUserByID returns an empty user when the ID is unknown, as the gorm
+// repository's raw lookup does.
+func (u *Users) GetUserByID(id string) (*registration.User, error) {
+	if v, ok := u.ByID[id]; ok {
+		cp := *v
+		return &cp, nil
+	}
+	return &registration.User{}, nil
+}
+
+func (u *Users) GetUserByEmail(email string) (*registration.User, error) {
+	for _, v := range u.ByID {
+		if strings.EqualFold(v.Email, email) {
+			cp := *v
+			return &cp, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (u *Users) UpdateUser(user *registration.User) error {
+	cp := *user
+	u.Add(&cp)
+	return nil
+}
+
+func (u *Users) FindByTeamIDAndRole(teamID uuid.UUID, role string) (*registration.User, error) {
+	for _, v := range u.ByID {
+		if v.TeamID != nil && *v.TeamID == teamID && v.Role == role {
+			cp := *v
+			return &cp, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// ProvisionUser creates a verified user from the request.
+func (u *Users) ProvisionUser(req registration.ProvisionRequest) (registration.User, error) {
+	tenantID := req.TenantID
+	user := registration.User{
+		ID: uuid.New(), FullName: req.FullName, Email: req.Email, Role: req.Role,
+		TenantID: &tenantID, TeamID: req.TeamID, IsVerified: true,
+	}
+	u.Add(&user)
+	return user, nil
+}
@@ -1 +1,54 @@
 package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"aegis-api/handlers"
+	"aegis-api/middleware"
+	"aegis-api/routes"
+	"aegis-api/tests/fakes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStepUpOnlyBindsUsersWithASecondFactor(t *testing.T) {
+	evidenceID := uuid.New()
+	members := &fakes.CaseMembers{}
+	members.Add(caseA, analyst)
+	members.Place(evidenceID.String(), caseA)
+	withMembers(t, members)
+	t.Cleanup(func() { middleware.SetMFAEnrollment(nil) })
+
+	r := gin.New()
+	routes.RegisterEvidenceTransferRoutes(r.Group("/api/v1"), nil,
+		handlers.NewDownloadHandlerWithInterfaces(fakes.Downloads{}, &fakes.AuditTrail{}, nil), middleware.EndpointLimitConfig{})
+	download := func(stepUp time.Duration) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/download/"+evidenceID.String(), nil)
+		req.Header.Set("Authorization", bearer(t, analyst, stepUp))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Without MFA there is nothing to step up with.
+	middleware.SetMFAEnrollment(fakes.MFA{})
+	assert.Equal(t, http.StatusOK, download(0).Code)
+
+	// Unless the tenant requires it: the client is told to enroll.
+	middleware.SetMFAEnrollment(fakes.MFA{Required: true})
+	w := download(0)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "mfa_enrollment_required")
+
+	// Enrolled users re-verify once their window has passed.
+	middleware.SetMFAEnrollment(fakes.MFA{Enrolled: true})
+	w = download(-time.Minute)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "step_up_required")
+	assert.Equal(t, http.StatusOK, download(5*time.Minute).Code)
+}
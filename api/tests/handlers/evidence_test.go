@@ -1 +1,146 @@
 package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"aegis-api/cache"
+	"aegis-api/handlers"
+	"aegis-api/middleware"
+	"aegis-api/routes"
+	"aegis-api/services_/evidence/evidence_viewer"
+	"aegis-api/tests/fakes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	tenantID = "550e8400-e29b-41d4-a716-446655440001"
+	caseA    = "7f1c0d6e-0000-4000-8000-00000000000a"
+	caseB    = "7f1c0d6e-0000-4000-8000-00000000000b"
+	analyst  = "550e8400-e29b-41d4-a716-446655440000"
+	outsider = "550e8400-e29b-41d4-a716-4466554400ff"
+)
+
+// asUser stands in for AuthMiddleware.
+func asUser(userID string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("userID", userID)
+		c.Set("email", userID+"@example.com")
+		c.Set("userRole", "Forensic Analyst")
+		c.Set("tenantID", tenantID)
+		c.Next()
+	}
+}
+
+// bearer signs an access token for the user. A non-zero stepUp is added to
+// the token as the end of the user's step-up window.
+func bearer(t *testing.T, userID string, stepUp time.Duration) string {
+	t.Helper()
+	middleware.SetJWTSecret([]byte("handler-tests"))
+	claims := jwt.MapClaims{
+		"user_id": userID, "email": userID + "@example.com", "role": "Forensic Analyst",
+		"tenant_id": tenantID, "exp": time.Now().Add(time.Hour).Unix(),
+	}
+	if stepUp != 0 {
+		claims["step_up_exp"] = time.Now().Add(stepUp).Unix()
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("handler-tests"))
+	require.NoError(t, err)
+	return "Bearer " + signed
+}
+
+// withMembers installs members as the case authorizer for the test.
+func withMembers(t *testing.T, members *fakes.CaseMembers) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	middleware.SetCaseAuthorizer(members)
+	t.Cleanup(func() { middleware.SetCaseAuthorizer(nil) })
+}
+
+func newEvidenceRouter(repo *evidence_viewer.MockEvidenceViewer, store cache.Client, userID string) *gin.Engine {
+	r := gin.New()
+	api := r.Group("/api/v1", asUser(userID))
+	viewer := handlers.NewEvidenceViewerHandler(evidence_viewer.NewEvidenceService(repo), store, nil, nil)
+	routes.RegisterEvidenceRoutes(api, viewer, nil, nil, fakes.Permissions{})
+	return r
+}
+
+func TestSearchEvidenceOnlyReturnsHitsFromTheCallersCases(t *testing.T) {
+	members := &fakes.CaseMembers{}
+	members.Add(caseA, analyst)
+	withMembers(t, members)
+
+	repo := new(evidence_viewer.MockEvidenceViewer)
+	repo.On("SearchEvidenceFiles", tenantID, "memdump").Return([]evidence_viewer.EvidenceFile{
+		{ID: "ev-a", CaseID: caseA, Data: []byte("a")},
+		{ID: "ev-b", CaseID: caseB, Data: []byte("b")},
+	}, nil)
+
+	store := cache.NewMemory()
+	search := func(userID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		newEvidenceRouter(repo, store, userID).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/evidence/search?query=memdump", nil))
+		return w
+	}
+
+	w := search(analyst)
+	require.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Files []evidence_viewer.EvidenceFile `json:"files"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Files, 1)
+	assert.Equal(t, "ev-a", body.Files[0].ID)
+
+	// The tenant's cached hits are filtered too: a caller on neither case
+	// gets the same answer as a search with no hits.
+	w = search(outsider)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NotContains(t, w.Body.String(), "ev-")
+	repo.AssertNumberOfCalls(t, "SearchEvidenceFiles", 1)
+}
+
+func TestDownloadAppliesClassificationThroughTheRoute(t *testing.T) {
+	open, classified := uuid.New(), uuid.New()
+	members := &fakes.CaseMembers{}
+	members.Add(caseA, analyst)
+	members.Place(open.String(), caseA)
+	members.Place(classified.String(), caseA)
+	withMembers(t, members)
+
+	policy := &fakes.Policy{}
+	policy.Refuse(classified.String())
+	audit := &fakes.AuditTrail{}
+	r := gin.New()
+	routes.RegisterEvidenceTransferRoutes(r.Group("/api/v1"), nil,
+		handlers.NewDownloadHandlerWithInterfaces(fakes.Downloads{}, audit, policy), middleware.EndpointLimitConfig{})
+
+	download := func(id uuid.UUID, userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/download/"+id.String(), nil)
+		req.Header.Set("Authorization", bearer(t, userID, 5*time.Minute))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := download(open, analyst)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "evidence "+open.String(), w.Body.String())
+
+	w = download(classified, analyst)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "classified")
+	assert.Equal(t, []string{"DOWNLOAD_EVIDENCE", "CLASSIFIED_ACCESS_BLOCKED"}, audit.Actions())
+
+	// Members of other cases are refused before the label is looked at.
+	w = download(open, outsider)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
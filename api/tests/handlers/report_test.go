@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"aegis-api/handlers"
+	"aegis-api/routes"
+	"aegis-api/services_/report"
+	"aegis-api/tests/fakes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportListingsOnlyShowTheCallersCases(t *testing.T) {
+	members := &fakes.CaseMembers{}
+	members.Add(caseA, analyst)
+	withMembers(t, members)
+
+	tenant, team := uuid.MustParse(tenantID), uuid.New()
+	reports := &fakes.Reports{}
+	for _, caseID := range []string{caseA, caseB} {
+		reports.Add(&report.Report{
+			ID: uuid.New(), CaseID: uuid.MustParse(caseID), ExaminerID: uuid.MustParse(analyst),
+			TenantID: tenant, TeamID: team, Name: "Report on " + caseID,
+		})
+	}
+
+	// get returns the IDs of the reports listed at path, found under key when
+	// the listing is wrapped in an object.
+	get := func(path, key, userID string) []uuid.UUID {
+		t.Helper()
+		r := gin.New()
+		routes.RegisterReportRoutes(r.Group("/api/v1", asUser(userID)),
+			handlers.NewReportHandler(reports, (&fakes.AuditTrail{}).Logger()))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		raw := w.Body.Bytes()
+		if key != "" {
+			var wrapped map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(raw, &wrapped))
+			raw = wrapped[key]
+		}
+		var rows []struct {
+			ID uuid.UUID `json:"id"`
+		}
+		require.NoError(t, json.Unmarshal(raw, &rows))
+		ids := make([]uuid.UUID, len(rows))
+		for i, row := range rows {
+			ids[i] = row.ID
+		}
+		return ids
+	}
+
+	caseAReport := reports.Items[0].Metadata.ID
+	recent, forTeam := "/api/v1/reports/recent?mine=false", "/api/v1/reports/teams/"+team.String()
+	assert.Equal(t, []uuid.UUID{caseAReport}, get(recent, "", analyst))
+	assert.Equal(t, []uuid.UUID{caseAReport}, get(forTeam, "reports", analyst))
+	assert.Empty(t, get(recent, "", outsider))
+	assert.Empty(t, get(forTeam, "reports", outsider))
+}
@@ -63,7 +63,7 @@ func buildRouter() *gin.Engine {
 	pgRepo := report.NewReportRepository(pgDB)
 	mRepo := report.NewReportMongoRepo(mongoColl)
 	sectionRepo := reportai.NewGormReportSectionRepo(pgDB) // Use the correct constructor for sectionRepo
-	svc := report.NewReportService(pgRepo, mRepo, sectionRepo)
+	svc := report.NewReportService(pgRepo, mRepo, sectionRepo, nil, nil, nil)
 	h := handlers.NewReportHandler(svc)
 
 	r := gin.New()
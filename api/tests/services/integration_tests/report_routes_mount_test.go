@@ -16,7 +16,7 @@ func init() {
 		pgRepo := report.NewReportRepository(pgDB)
 		mRepo := report.NewReportMongoRepo(mongoColl)
 		sectionRepo := reportai.NewGormReportSectionRepo(pgDB) // Use the correct constructor for sectionRepo
-		svc := report.NewReportService(pgRepo, mRepo, sectionRepo)
+		svc := report.NewReportService(pgRepo, mRepo, sectionRepo, nil, nil, nil)
 		h := handlers.NewReportHandler(svc)
 
 		// Reuse your real routes
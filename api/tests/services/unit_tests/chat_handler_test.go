@@ -291,7 +291,7 @@ func createTestChatHandler() (*handlers.ChatHandler, *chat.ChatService, *MockCha
 
 	// FIXED: Create audit logger and use proper constructor
 	auditLogger := auditlog.NewAuditLogger(mockMongo, mockZap)
-	handler := handlers.NewChatHandler(chatService, auditLogger)
+	handler := handlers.NewChatHandler(chatService, nil, auditLogger)
 
 	return handler, chatService, mockRepo, mockMongo, mockZap
 }
@@ -102,10 +102,10 @@ func TestDownloadHandler_Download_Success(t *testing.T) {
 	}
 	mockAudit := &mockAuditLogger{}
 
-	handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit)
+	handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit, nil)
 
 	c, w := mockGinContext("GET", "/evidence/download/"+validUUID.String(), map[string]string{
-		"id": validUUID.String(),
+		"evidence_id": validUUID.String(),
 	})
 
 	// Execute
@@ -140,11 +140,11 @@ func TestDownloadHandler_Download_InvalidUUID(t *testing.T) {
 	mockService := &mockDownloadService{}
 	mockAudit := &mockAuditLogger{}
 
-	handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit)
+	handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit, nil)
 
 	invalidID := "not-a-uuid"
 	c, w := mockGinContext("GET", "/evidence/download/"+invalidID, map[string]string{
-		"id": invalidID,
+		"evidence_id": invalidID,
 	})
 
 	// Execute
@@ -197,10 +197,10 @@ func TestDownloadHandler_Download_ServiceError(t *testing.T) {
 			}
 			mockAudit := &mockAuditLogger{}
 
-			handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit)
+			handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit, nil)
 
 			c, w := mockGinContext("GET", "/evidence/download/"+validUUID.String(), map[string]string{
-				"id": validUUID.String(),
+				"evidence_id": validUUID.String(),
 			})
 
 			// Execute
@@ -252,10 +252,10 @@ func TestDownloadHandler_Download_DifferentFileTypes(t *testing.T) {
 			}
 			mockAudit := &mockAuditLogger{}
 
-			handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit)
+			handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit, nil)
 
 			c, w := mockGinContext("GET", "/evidence/download/"+validUUID.String(), map[string]string{
-				"id": validUUID.String(),
+				"evidence_id": validUUID.String(),
 			})
 
 			handler.Download(c)
@@ -284,10 +284,10 @@ func TestDownloadHandler_Download_LargeFile(t *testing.T) {
 	}
 	mockAudit := &mockAuditLogger{}
 
-	handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit)
+	handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit, nil)
 
 	c, w := mockGinContext("GET", "/evidence/download/"+validUUID.String(), map[string]string{
-		"id": validUUID.String(),
+		"evidence_id": validUUID.String(),
 	})
 
 	handler.Download(c)
@@ -309,10 +309,10 @@ func TestDownloadHandler_Download_EmptyFile(t *testing.T) {
 	}
 	mockAudit := &mockAuditLogger{}
 
-	handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit)
+	handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit, nil)
 
 	c, w := mockGinContext("GET", "/evidence/download/"+validUUID.String(), map[string]string{
-		"id": validUUID.String(),
+		"evidence_id": validUUID.String(),
 	})
 
 	handler.Download(c)
@@ -347,10 +347,10 @@ func TestDownloadHandler_Download_SpecialCharactersInFilename(t *testing.T) {
 			}
 			mockAudit := &mockAuditLogger{}
 
-			handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit)
+			handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit, nil)
 
 			c, w := mockGinContext("GET", "/evidence/download/"+validUUID.String(), map[string]string{
-				"id": validUUID.String(),
+				"evidence_id": validUUID.String(),
 			})
 
 			handler.Download(c)
@@ -374,10 +374,10 @@ func TestDownloadHandler_Download_AuditLogError(t *testing.T) {
 		err: errors.New("audit log service unavailable"),
 	}
 
-	handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit)
+	handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit, nil)
 
 	c, w := mockGinContext("GET", "/evidence/download/"+validUUID.String(), map[string]string{
-		"id": validUUID.String(),
+		"evidence_id": validUUID.String(),
 	})
 
 	handler.Download(c)
@@ -405,10 +405,10 @@ func TestDownloadHandler_Download_StreamCloseError(t *testing.T) {
 	}
 	mockAudit := &mockAuditLogger{}
 
-	handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit)
+	handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit, nil)
 
 	c, w := mockGinContext("GET", "/evidence/download/"+validUUID.String(), map[string]string{
-		"id": validUUID.String(),
+		"evidence_id": validUUID.String(),
 	})
 
 	handler.Download(c)
@@ -424,14 +424,14 @@ func TestNewDownloadHandler(t *testing.T) {
 		mockService := &mockDownloadService{}
 		mockAudit := &mockAuditLogger{}
 
-		handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit)
+		handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit, nil)
 
 		require.NotNil(t, handler, "Handler should not be nil")
 	})
 
 	t.Run("HandlerWithNilService", func(t *testing.T) {
 		mockAudit := &mockAuditLogger{}
-		handler := handlers.NewDownloadHandlerWithInterfaces(nil, mockAudit)
+		handler := handlers.NewDownloadHandlerWithInterfaces(nil, mockAudit, nil)
 
 		require.NotNil(t, handler, "Handler should not be nil even with nil service")
 
@@ -440,7 +440,7 @@ func TestNewDownloadHandler(t *testing.T) {
 
 	t.Run("HandlerWithNilAuditLogger", func(t *testing.T) {
 		mockService := &mockDownloadService{}
-		handler := handlers.NewDownloadHandlerWithInterfaces(mockService, nil)
+		handler := handlers.NewDownloadHandlerWithInterfaces(mockService, nil, nil)
 
 		require.NotNil(t, handler, "Handler should not be nil even with nil audit logger")
 	})
@@ -450,10 +450,10 @@ func TestDownloadHandler_EdgeCases(t *testing.T) {
 	t.Run("EmptyUUIDParam", func(t *testing.T) {
 		mockService := &mockDownloadService{}
 		mockAudit := &mockAuditLogger{}
-		handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit)
+		handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit, nil)
 
 		c, w := mockGinContext("GET", "/evidence/download/", map[string]string{
-			"id": "",
+			"evidence_id": "",
 		})
 
 		handler.Download(c)
@@ -465,7 +465,7 @@ func TestDownloadHandler_EdgeCases(t *testing.T) {
 	t.Run("NilUUIDParam", func(t *testing.T) {
 		mockService := &mockDownloadService{}
 		mockAudit := &mockAuditLogger{}
-		handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit)
+		handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit, nil)
 
 		c, w := mockGinContext("GET", "/evidence/download/", nil)
 
@@ -483,11 +483,11 @@ func TestDownloadHandler_EdgeCases(t *testing.T) {
 			filetype: "application/pdf",
 		}
 		mockAudit := &mockAuditLogger{}
-		handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit)
+		handler := handlers.NewDownloadHandlerWithInterfaces(mockService, mockAudit, nil)
 
 		zeroUUID := uuid.UUID{} // 00000000-0000-0000-0000-000000000000
 		c, w := mockGinContext("GET", "/evidence/download/"+zeroUUID.String(), map[string]string{
-			"id": zeroUUID.String(),
+			"evidence_id": zeroUUID.String(),
 		})
 
 		handler.Download(c)
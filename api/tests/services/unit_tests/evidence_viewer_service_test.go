@@ -24,8 +24,8 @@ func (m *MockEvidenceViewer) GetEvidenceFileByID(fileID string) (*evidence_viewe
 	return args.Get(0).(*evidence_viewer.EvidenceFile), args.Error(1)
 }
 
-func (m *MockEvidenceViewer) SearchEvidenceFiles(term string) ([]evidence_viewer.EvidenceFile, error) {
-	args := m.Called(term)
+func (m *MockEvidenceViewer) SearchEvidenceFiles(tenantID, term string) ([]evidence_viewer.EvidenceFile, error) {
+	args := m.Called(tenantID, term)
 	return args.Get(0).([]evidence_viewer.EvidenceFile), args.Error(1)
 }
 
@@ -85,9 +85,9 @@ func TestService_SearchEvidenceFiles_Success(t *testing.T) {
 		{ID: "ev001", Data: []byte("notes pdf bytes")},
 	}
 
-	mockRepo.On("SearchEvidenceFiles", "notes").Return(expected, nil)
+	mockRepo.On("SearchEvidenceFiles", "tenant-1", "notes").Return(expected, nil)
 
-	files, err := service.SearchEvidenceFiles("notes")
+	files, err := service.SearchEvidenceFiles("tenant-1", "notes")
 	assert.NoError(t, err)
 	assert.Equal(t, expected, files)
 
@@ -137,10 +137,10 @@ func TestService_SearchEvidenceFiles_Error(t *testing.T) {
 	mockRepo := new(MockEvidenceViewer)
 	service := &evidence_viewer.EvidenceService{Repo: mockRepo}
 
-	mockRepo.On("SearchEvidenceFiles", "nonexistent").
+	mockRepo.On("SearchEvidenceFiles", "tenant-1", "nonexistent").
 		Return([]evidence_viewer.EvidenceFile(nil), errors.New("search failed"))
 
-	files, err := service.SearchEvidenceFiles("nonexistent")
+	files, err := service.SearchEvidenceFiles("tenant-1", "nonexistent")
 	assert.Error(t, err)
 	assert.Nil(t, files)
 	assert.EqualError(t, err, "search failed")
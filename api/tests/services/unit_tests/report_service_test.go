@@ -82,6 +82,9 @@ func (m *MockRepo) DownloadReport(ctx context.Context, id uuid.UUID) (*report.Re
 	}
 	return nil, args.Error(1)
 }
+func (m *MockRepo) SetFieldsFrozenAt(ctx context.Context, id uuid.UUID, at *time.Time) error {
+	return m.Called(ctx, id, at).Error(0)
+}
 func (m *MockRepo) UpdateReportName(ctx context.Context, id uuid.UUID, name string) (*report.Report, error) {
 	args := m.Called(ctx, id, name)
 	if v := args.Get(0); v != nil {
@@ -139,6 +142,9 @@ func (m *MockMongo) FindByReportUUID(ctx context.Context, reportUUID uuid.UUID)
 func (m *MockMongo) UpdateSectionTitle(ctx context.Context, reportID, sectionID primitive.ObjectID, newTitle string, tenantID, teamID string) error {
 	return m.Called(ctx, reportID, sectionID, newTitle, tenantID, teamID).Error(0)
 }
+func (m *MockMongo) SetFrozenFields(ctx context.Context, reportID, sectionID primitive.ObjectID, frozen []report.FrozenField, tenantID, teamID string) error {
+	return m.Called(ctx, reportID, sectionID, frozen, tenantID, teamID).Error(0)
+}
 func (m *MockMongo) ReorderSection(ctx context.Context, reportID, sectionID primitive.ObjectID, newOrder int, tenantID, teamID string) error {
 	return m.Called(ctx, reportID, sectionID, newOrder, tenantID, teamID).Error(0)
 }
@@ -186,7 +192,7 @@ func (m *MockSectionRepo) UpdateSection(ctx context.Context, section *reportshar
 
 // newSvc wires the service under test with our mocks.
 func newSvc(repo *MockRepo, mongo *MockMongo, sectionRepo *MockSectionRepo) report.ReportService {
-	return report.NewReportService(repo, mongo, sectionRepo)
+	return report.NewReportService(repo, mongo, sectionRepo, nil, nil, nil)
 }
 
 /* ----------------------------- Tests ------------------------------ */
@@ -253,7 +259,7 @@ func TestGenerateReport_HappyPath(t *testing.T) {
 		return true
 	})).Return(nil).Once()
 
-	out, err := svc.GenerateReport(ctx, caseID, examinerID, tenantID, teamID)
+	out, err := svc.GenerateReport(ctx, caseID, examinerID, tenantID, teamID, uuid.Nil, "")
 	require.NoError(t, err)
 	require.NotNil(t, out)
 	assert.Equal(t, tenantID, out.TenantID)
@@ -275,7 +281,7 @@ func TestGenerateReport_SaveFails(t *testing.T) {
 	repo.On("SaveReport", ctx, mock.AnythingOfType("*report.Report")).
 		Return(errors.New("db down")).Once()
 
-	_, err := svc.GenerateReport(ctx, uuid.New(), uuid.New(), uuid.New(), uuid.New())
+	_, err := svc.GenerateReport(ctx, uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.Nil, "")
 	require.ErrorContains(t, err, "failed to generate report metadata")
 
 	// Ensure Mongo save is never invoked.
@@ -295,7 +301,7 @@ func TestGenerateReport_MongoSaveFails(t *testing.T) {
 	sectionRepo.On("CreateSection", ctx, mock.AnythingOfType("*reportshared.ReportSection")).Return(nil).Times(10)
 	mongo.On("SaveReportContent", ctx, mock.AnythingOfType("*report.ReportContentMongo")).Return(errors.New("mongo err")).Once()
 
-	_, err := svc.GenerateReport(ctx, uuid.New(), uuid.New(), uuid.New(), uuid.New())
+	_, err := svc.GenerateReport(ctx, uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.Nil, "")
 	require.ErrorContains(t, err, "failed to save report content in Mongo")
 
 	repo.AssertExpectations(t)
@@ -331,7 +337,7 @@ func TestGet_By_All_Delete_Passthroughs(t *testing.T) {
 	id := uuid.New()
 	rep := &report.Report{ID: id, Name: "x"}
 
-	repo.On("GetByID", ctx, id.String()).Return(rep, nil).Once()
+	repo.On("GetByID", ctx, id.String()).Return(rep, nil).Twice() // GetReportByID, then the draft check in DeleteReportByID
 	repo.On("GetAllReports", ctx).Return([]report.Report{{ID: id}}, nil).Once()
 	repo.On("GetReportsByEvidenceID", ctx, id).Return([]report.Report{{ID: id}}, nil).Once()
 	repo.On("DeleteReportByID", ctx, id).Return(nil).Once()
@@ -513,7 +519,7 @@ func TestDownloadReportAsJSON_Works(t *testing.T) {
 	mongo.On("GetReportContent", ctx, mid, tenant.String(), team.String()).
 		Return(&report.ReportContentMongo{ID: mid, Sections: []report.ReportSection{}}, nil).Once()
 
-	b, err := svc.DownloadReportAsJSON(ctx, id)
+	b, err := svc.DownloadReportAsJSON(ctx, id, nil)
 	require.NoError(t, err)
 	require.True(t, json.Valid(b))
 	require.Contains(t, string(b), `"name":"R"`)
@@ -542,7 +548,7 @@ func TestDownloadReportAsPDF_BasicPDF(t *testing.T) {
 			},
 		}, nil).Once()
 
-	pdf, err := svc.DownloadReportAsPDF(ctx, id)
+	pdf, err := svc.DownloadReportAsPDF(ctx, id, nil)
 	require.NoError(t, err)
 	require.Greater(t, len(pdf), 100)
 	require.True(t, bytes.HasPrefix(pdf, []byte("%PDF")))
@@ -568,7 +574,7 @@ func TestUpdateCustomSectionContent_Success(t *testing.T) {
 		TenantID: tenant,
 		TeamID:   team,
 		MongoID:  mid.Hex(),
-	}, nil).Once()
+	}, nil).Twice()
 
 	// Then calls UpdateSection with scoped tenant/team
 	mongo.On("UpdateSection", ctx, mid, secID, "new content", tenant.String(), team.String()).
@@ -635,7 +641,7 @@ func TestUpdateSectionContent_Delegates(t *testing.T) {
 		TenantID: tenant,
 		TeamID:   team,
 		MongoID:  mid.Hex(),
-	}, nil).Once()
+	}, nil).Twice()
 	mongo.On("UpdateSection", ctx, mid, secID, "c", tenant.String(), team.String()).
 		Return(nil).Once()
 
@@ -662,7 +668,7 @@ func TestUpdateSectionContent_Delegates(t *testing.T) {
 		TenantID: tenant,
 		TeamID:   team,
 		MongoID:  mid.Hex(),
-	}, nil).Once()
+	}, nil).Twice()
 	mongo.On("UpdateSectionTitle", ctx, mid, secID, "Title", tenant.String(), team.String()).
 		Return(nil).Once()
 
@@ -688,7 +694,7 @@ func TestReorderCustomSection_Success(t *testing.T) {
 		TenantID: tenant,
 		TeamID:   team,
 		MongoID:  mid.Hex(),
-	}, nil).Once()
+	}, nil).Twice()
 	mongo.On("ReorderSection", ctx, mid, secID, 3, tenant.String(), team.String()).
 		Return(nil).Once()
 
@@ -713,7 +719,8 @@ func TestUpdateReportName_ValidationAndRepoCall(t *testing.T) {
 	_, err = svc.UpdateReportName(ctx, id, strings.Repeat("a", 256))
 	require.ErrorIs(t, err, report.ErrInvalidReportName)
 
-	// valid path: expect repo call with trimmed name
+	// valid path: expect repo call with trimmed name on a draft report
+	repo.On("GetByID", ctx, id.String()).Return(&report.Report{ID: id, Status: "draft"}, nil).Once()
 	updated := &report.Report{ID: id, Name: "Good"}
 	repo.On("UpdateReportName", ctx, id, "Good").Return(updated, nil).Once()
 